@@ -0,0 +1,465 @@
+// Package archive implements a read only backend showing the
+// contents of a zip or tar archive stored on another remote
+//
+// Only the parts of the archive which are needed are read, using
+// ranged reads, so single files can be extracted from huge archives
+// without downloading the whole of them.
+package archive
+
+import (
+	"compress/flate"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/readers"
+)
+
+const (
+	formatZip = "zip"
+	formatTar = "tar"
+
+	// zip compression methods read directly
+	methodStore   = 0
+	methodDeflate = 8
+)
+
+var errorReadOnly = errors.New("archive remotes are read only")
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "archive",
+		Description: "Read the files in a zip or tar archive on another remote",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Archive to read.
+
+This should be the path to a zip or tar file on another remote,
+e.g. "myremote:path/to/archive.zip".`,
+		}, {
+			Name: "format",
+			Help: `Format of the archive.
+
+If empty this is worked out from the extension of the archive.`,
+			Examples: []fs.OptionExample{{
+				Value: "",
+				Help:  "Work out the format from the name of the archive.",
+			}, {
+				Value: formatZip,
+				Help:  "Zip archive, also used by .jar, .apk, .epub and others.",
+			}, {
+				Value: formatTar,
+				Help:  "Uncompressed tar archive.",
+			}},
+		}, {
+			Name: "block_size",
+			Help: `Size of the blocks read when reading the index of the archive.
+
+The index is read with ranged reads of this size. Bigger blocks mean
+fewer requests when the index is big, e.g. for tar files with many
+small files, but more data read for archives with few files.`,
+			Default:  fs.SizeSuffix(256 * fs.Kibi),
+			Advanced: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote    string        `config:"remote"`
+	Format    string        `config:"format"`
+	BlockSize fs.SizeSuffix `config:"block_size"`
+}
+
+// Fs shows the contents of an archive
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path in the archive we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	archive  fs.Object    // the archive
+	format   string       // the format of the archive
+	index    *index       // the files in the archive
+	r        *readerAt    // for reading the headers of zip files
+
+	offsetMu sync.Mutex // held while finding where data starts
+}
+
+// Object describes a file in the archive
+type Object struct {
+	fs     *Fs    // what this object is part of
+	remote string // The remote path
+	e      *entry // the file in the archive
+}
+
+// NewFs constructs an Fs from the path.
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point archive remote at itself - check the value of the remote setting")
+	}
+	if opt.BlockSize <= 0 {
+		return nil, errors.New("block_size must be positive")
+	}
+	parent, leaf, err := fspath.Split(opt.Remote)
+	if err != nil {
+		return nil, err
+	}
+	if leaf == "" {
+		return nil, fmt.Errorf("remote %q should be the path to an archive", opt.Remote)
+	}
+	format := opt.Format
+	if format == "" {
+		format, err = formatFromName(leaf)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if format != formatZip && format != formatTar {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	baseFs, err := cache.Get(ctx, parent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make remote %q: %w", parent, err)
+	}
+	archive, err := baseFs.NewObject(ctx, leaf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find archive %q: %w", opt.Remote, err)
+	}
+	f := &Fs{
+		name:    name,
+		root:    strings.Trim(root, "/"),
+		opt:     *opt,
+		archive: archive,
+		format:  format,
+	}
+	f.r = newReaderAt(ctx, archive, int64(opt.BlockSize), 16)
+	if format == formatZip {
+		f.index, err = readZip(f.r, archive.Size())
+	} else {
+		f.index, err = readTar(f.r, archive.Size())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index of %q: %w", opt.Remote, err)
+	}
+	f.index.finish(archive.ModTime(ctx))
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+		ReadMimeType:            true,
+	}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	if _, ok := f.index.files[f.root]; ok {
+		f.root = parentDir(f.root)
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("archive %s root '%s'", f.opt.Remote, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Second
+}
+
+// Hashes returns the supported hash types of the filesystem
+//
+// Zip archives store the CRC-32 of each file.
+func (f *Fs) Hashes() hash.Set {
+	if f.format == formatZip {
+		return hash.NewHashSet(hash.CRC32)
+	}
+	return hash.Set(hash.None)
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	p := path.Join(f.root, dir)
+	if p == "." {
+		p = ""
+	}
+	if _, ok := f.index.dirs[p]; !ok {
+		return nil, fs.ErrorDirNotFound
+	}
+	for _, leaf := range f.index.children[p] {
+		remote := path.Join(dir, leaf)
+		child := path.Join(p, leaf)
+		if e, ok := f.index.files[child]; ok {
+			entries = append(entries, &Object{fs: f, remote: remote, e: e})
+		} else {
+			entries = append(entries, fs.NewDir(remote, f.index.dirs[child]))
+		}
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	e, ok := f.index.files[path.Join(f.root, remote)]
+	if !ok {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return &Object{fs: f, remote: remote, e: e}, nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, errorReadOnly
+}
+
+// Mkdir makes the root directory of the Fs object
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// Rmdir removes the root directory of the Fs object
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the CRC-32 of a file in a zip archive
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t != hash.CRC32 || o.e.zf == nil {
+		return "", hash.ErrUnsupported
+	}
+	return fmt.Sprintf("%08x", o.e.zf.CRC32), nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.e.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.e.modTime
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return errorReadOnly
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType(ctx context.Context) string {
+	return fs.MimeTypeFromName(o.remote)
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errorReadOnly
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return errorReadOnly
+}
+
+// openRange opens length bytes of the archive at offset
+func (o *Object) openRange(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	return o.fs.archive.Open(ctx, &fs.RangeOption{Start: offset, End: offset + length - 1})
+}
+
+// dataOffset returns the offset of the data of a file in a zip
+// archive, reading the header of the file the first time
+func (o *Object) dataOffset(ctx context.Context) (int64, error) {
+	f := o.fs
+	f.offsetMu.Lock()
+	defer f.offsetMu.Unlock()
+	if o.e.offset >= 0 {
+		return o.e.offset, nil
+	}
+	f.r.setContext(ctx)
+	offset, err := o.e.zf.DataOffset()
+	if err != nil {
+		return -1, err
+	}
+	o.e.offset = offset
+	return offset, nil
+}
+
+// checkCRC checks the CRC-32 of the data read from in when it is all
+// read
+type checkCRC struct {
+	io.ReadCloser
+	want uint32
+	size int64
+	hash uint32
+	n    int64
+}
+
+func (c *checkCRC) Read(p []byte) (n int, err error) {
+	n, err = c.ReadCloser.Read(p)
+	c.hash = crc32.Update(c.hash, crc32.IEEETable, p[:n])
+	c.n += int64(n)
+	if err == io.EOF && c.n == c.size && c.hash != c.want {
+		err = errors.New("CRC-32 of file in zip doesn't match")
+	}
+	return n, err
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.e.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if offset > o.e.size {
+		offset = o.e.size
+	}
+	if limit < 0 || offset+limit > o.e.size {
+		limit = o.e.size - offset
+	}
+	zf := o.e.zf
+	if zf == nil {
+		// Files in tar archives are stored as they are
+		return o.openRange(ctx, o.e.offset+offset, limit)
+	}
+	if zf.Flags&0x1 != 0 {
+		return nil, errors.New("can't read encrypted files in zip archives")
+	}
+	dataOffset, err := o.dataOffset(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip file header: %w", err)
+	}
+	var rc io.ReadCloser
+	switch zf.Method {
+	case methodStore:
+		if offset == 0 && limit == o.e.size {
+			in, err = o.openRange(ctx, dataOffset, o.e.size)
+			if err != nil {
+				return nil, err
+			}
+			return &checkCRC{ReadCloser: in, want: zf.CRC32, size: o.e.size}, nil
+		}
+		return o.openRange(ctx, dataOffset+offset, limit)
+	case methodDeflate:
+		in, err = o.openRange(ctx, dataOffset, int64(zf.CompressedSize64))
+		if err != nil {
+			return nil, err
+		}
+		rc = &closers{ReadCloser: flate.NewReader(in), inner: in}
+	default:
+		return nil, fmt.Errorf("unsupported zip compression method %d", zf.Method)
+	}
+	rc = &checkCRC{ReadCloser: rc, want: zf.CRC32, size: o.e.size}
+	if offset > 0 {
+		_, err = io.CopyN(ioutil.Discard, rc, offset)
+		if err != nil {
+			_ = rc.Close()
+			return nil, err
+		}
+	}
+	return readers.NewLimitedReadCloser(rc, limit), nil
+}
+
+// closers closes the decompressor and the reader it reads from
+type closers struct {
+	io.ReadCloser
+	inner io.Closer
+}
+
+func (c *closers) Close() error {
+	err := c.ReadCloser.Close()
+	innerErr := c.inner.Close()
+	if err != nil {
+		return err
+	}
+	return innerErr
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs        = (*Fs)(nil)
+	_ fs.Object    = (*Object)(nil)
+	_ fs.MimeTyper = (*Object)(nil)
+)
@@ -0,0 +1,267 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testModTime = time.Date(2022, 5, 6, 7, 8, 10, 0, time.UTC)
+
+// testFile is a file put in the test archives
+type testFile struct {
+	name    string
+	content []byte
+	store   bool // set to store rather than deflate in zips
+}
+
+func testFiles() []testFile {
+	big := make([]byte, 3*1024*1024)
+	rand.New(rand.NewSource(1)).Read(big)
+	return []testFile{
+		{name: "hello.txt", content: []byte("hello world")},
+		{name: "big.bin", content: big, store: true},
+		{name: "dir/stored.txt", content: []byte("stored contents"), store: true},
+		{name: "dir/sub/deep.txt", content: bytes.Repeat([]byte("deep "), 1000)},
+		{name: "../evil.txt", content: []byte("ignored")},
+		{name: "dir/empty.txt", content: nil},
+	}
+}
+
+// makeZip writes a zip of the test files to dir
+func makeZip(t *testing.T, dir string) string {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range testFiles() {
+		method := zip.Deflate
+		if file.store {
+			method = zip.Store
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: method, Modified: testModTime})
+		require.NoError(t, err)
+		_, err = w.Write(file.content)
+		require.NoError(t, err)
+	}
+	_, err := zw.CreateHeader(&zip.FileHeader{Name: "emptydir/", Modified: testModTime})
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	name := filepath.Join(dir, "test.zip")
+	require.NoError(t, ioutil.WriteFile(name, buf.Bytes(), 0666))
+	return name
+}
+
+// makeTar writes a tar of the test files to dir
+func makeTar(t *testing.T, dir string) string {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "emptydir/", Typeflag: tar.TypeDir, Mode: 0777, ModTime: testModTime}))
+	for _, file := range testFiles() {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: file.name, Typeflag: tar.TypeReg, Mode: 0666, Size: int64(len(file.content)), ModTime: testModTime}))
+		_, err := tw.Write(file.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "hello.txt", ModTime: testModTime}))
+	require.NoError(t, tw.Close())
+	name := filepath.Join(dir, "test.tar")
+	require.NoError(t, ioutil.WriteFile(name, buf.Bytes(), 0666))
+	return name
+}
+
+// newTestFs makes an archive Fs for the archive at file
+func newTestFs(t *testing.T, file, root string) (fs.Fs, error) {
+	return fs.NewFs(context.Background(), fmt.Sprintf(`:archive,remote="%s":%s`, file, root))
+}
+
+// read returns the contents of o
+func read(t *testing.T, o fs.Object, options ...fs.OpenOption) []byte {
+	in, err := o.Open(context.Background(), options...)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return data
+}
+
+// list returns the entries in dir
+func list(t *testing.T, f fs.Fs, dir string) string {
+	entries, err := f.List(context.Background(), dir)
+	require.NoError(t, err)
+	return fmt.Sprint(entries)
+}
+
+func testArchive(t *testing.T, file string, isZip bool) {
+	ctx := context.Background()
+	f, err := newTestFs(t, file, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "[big.bin dir emptydir hello.txt]", list(t, f, ""))
+	assert.Equal(t, "[dir/empty.txt dir/stored.txt dir/sub]", list(t, f, "dir"))
+	assert.Equal(t, "[]", list(t, f, "emptydir"))
+	_, err = f.List(ctx, "missing")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+	_, err = f.NewObject(ctx, "evil.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	_, err = f.NewObject(ctx, "link")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	for _, file := range testFiles() {
+		if file.name == "../evil.txt" {
+			continue
+		}
+		o, err := f.NewObject(ctx, file.name)
+		require.NoError(t, err, file.name)
+		assert.Equal(t, int64(len(file.content)), o.Size(), file.name)
+		assert.True(t, testModTime.Equal(o.ModTime(ctx)), file.name)
+		assert.Equal(t, file.content, append([]byte(nil), read(t, o)...), file.name)
+		if len(file.content) > 10 {
+			assert.Equal(t, file.content[3:10], read(t, o, &fs.RangeOption{Start: 3, End: 9}), file.name)
+			assert.Equal(t, file.content[5:], read(t, o, &fs.SeekOption{Offset: 5}), file.name)
+		}
+		sum, err := o.Hash(ctx, hash.CRC32)
+		if isZip {
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("%08x", crc32.ChecksumIEEE(file.content)), sum, file.name)
+		} else {
+			assert.Equal(t, hash.ErrUnsupported, err)
+		}
+	}
+
+	// Read only
+	src := object.NewStaticObjectInfo("new.txt", time.Now(), 1, true, nil, nil)
+	_, err = f.Put(ctx, bytes.NewReader([]byte("x")), src)
+	assert.Equal(t, errorReadOnly, err)
+	assert.Equal(t, errorReadOnly, f.Mkdir(ctx, "newdir"))
+
+	// Roots in the archive
+	f, err = newTestFs(t, file, "dir/sub")
+	require.NoError(t, err)
+	assert.Equal(t, "[deep.txt]", list(t, f, ""))
+	f, err = newTestFs(t, file, "dir/stored.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+	assert.Equal(t, "dir", f.Root())
+	o, err := f.NewObject(ctx, "stored.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "stored contents", string(read(t, o)))
+}
+
+func TestZip(t *testing.T) {
+	testArchive(t, makeZip(t, t.TempDir()), true)
+}
+
+func TestTar(t *testing.T) {
+	testArchive(t, makeTar(t, t.TempDir()), false)
+}
+
+func TestBadCRC(t *testing.T) {
+	dir := t.TempDir()
+	file := makeZip(t, dir)
+	data, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	i := bytes.Index(data, []byte("stored contents"))
+	require.True(t, i > 0)
+	data[i] = 'S'
+	require.NoError(t, ioutil.WriteFile(file, data, 0666))
+
+	f, err := newTestFs(t, file, "")
+	require.NoError(t, err)
+	o, err := f.NewObject(context.Background(), "dir/stored.txt")
+	require.NoError(t, err)
+	in, err := o.Open(context.Background())
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(in)
+	assert.EqualError(t, err, "CRC-32 of file in zip doesn't match")
+	require.NoError(t, in.Close())
+}
+
+// countingObject counts the bytes read from an object
+type countingObject struct {
+	fs.Object
+	read int64
+}
+
+func (o *countingObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	in, err := o.Object.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(in)
+	_ = in.Close()
+	o.read += int64(len(data))
+	return ioutil.NopCloser(bytes.NewReader(data)), err
+}
+
+func TestIndexReadsLittle(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	for _, test := range []struct {
+		file    string
+		readIdx func(r io.ReaderAt, size int64) (*index, error)
+	}{
+		{makeZip(t, dir), readZip},
+		{makeTar(t, dir), readTar},
+	} {
+		f, err := fs.NewFs(ctx, dir)
+		require.NoError(t, err)
+		o, err := f.NewObject(ctx, filepath.Base(test.file))
+		require.NoError(t, err)
+		co := &countingObject{Object: o}
+		x, err := test.readIdx(newReaderAt(ctx, co, 16*1024, 4), o.Size())
+		require.NoError(t, err)
+		assert.Equal(t, 5, len(x.files))
+		assert.True(t, co.read < 100*1024, "read %d bytes of %d to read index of %s", co.read, o.Size(), test.file)
+	}
+}
+
+func TestFormatFromName(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"a.zip", formatZip},
+		{"A.ZIP", formatZip},
+		{"lib.jar", formatZip},
+		{"backup.tar", formatTar},
+		{"backup.tar.gz", ""},
+		{"noext", ""},
+	} {
+		got, err := formatFromName(test.in)
+		assert.Equal(t, test.want, got, test.in)
+		if test.want == "" {
+			assert.Equal(t, errUnknownFormat, err)
+		}
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"a/b.txt", "a/b.txt"},
+		{"/abs/b.txt", "abs/b.txt"},
+		{"./a//b/", "a/b"},
+		{"a\\b.txt", "a/b.txt"},
+		{"../evil", ""},
+		{"a/../../evil", ""},
+		{"a/../b", "b"},
+		{".", ""},
+	} {
+		assert.Equal(t, test.want, cleanName(test.in), test.in)
+	}
+}
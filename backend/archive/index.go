@@ -0,0 +1,195 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// entry is a file in the archive
+type entry struct {
+	size    int64     // size of the file
+	modTime time.Time // modification time of the file
+	offset  int64     // offset of the data in the archive or -1 if not known yet
+	zf      *zip.File // the file if the archive is a zip
+}
+
+// index is the list of files and directories in an archive
+type index struct {
+	files    map[string]*entry    // files by path
+	dirs     map[string]time.Time // modification time of directories by path
+	children map[string][]string  // names of the entries in each directory
+}
+
+func newIndex() *index {
+	return &index{
+		files:    map[string]*entry{},
+		dirs:     map[string]time.Time{"": {}},
+		children: map[string][]string{},
+	}
+}
+
+// cleanName turns the name of a file in an archive into a path
+//
+// It returns "" for names which can't be used.
+func cleanName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = strings.TrimLeft(name, "/")
+	name = path.Clean(name)
+	// Names which would be extracted outside the archive are ignored
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return ""
+	}
+	return name
+}
+
+// addDir adds the directory dir and its parents
+func (x *index) addDir(dir string, modTime time.Time) {
+	for dir != "" {
+		if t, ok := x.dirs[dir]; ok {
+			if t.IsZero() {
+				x.dirs[dir] = modTime
+			}
+			return
+		}
+		x.dirs[dir] = modTime
+		parent := parentDir(dir)
+		x.children[parent] = append(x.children[parent], path.Base(dir))
+		// Implied directories don't have a modification time
+		dir, modTime = parent, time.Time{}
+	}
+}
+
+// addFile adds the file at name
+func (x *index) addFile(name string, e *entry) {
+	if _, ok := x.dirs[name]; ok {
+		fs.Debugf(name, "Ignoring file in archive with the same name as a directory")
+		return
+	}
+	dir := parentDir(name)
+	x.addDir(dir, time.Time{})
+	if _, ok := x.files[name]; !ok {
+		x.children[dir] = append(x.children[dir], path.Base(name))
+	}
+	// Later files with the same name replace earlier ones as they do
+	// when the archive is extracted
+	x.files[name] = e
+}
+
+// finish sorts the entries of each directory and fills in the
+// modification times of implied directories
+func (x *index) finish(modTime time.Time) {
+	for dir, t := range x.dirs {
+		if t.IsZero() {
+			x.dirs[dir] = modTime
+		}
+	}
+	for _, names := range x.children {
+		sort.Strings(names)
+	}
+}
+
+// parentDir returns the directory p is in
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// readZip reads the index of the zip archive read by r
+func readZip(r io.ReaderAt, size int64) (*index, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip: %w", err)
+	}
+	x := newIndex()
+	for _, zf := range zr.File {
+		name := cleanName(zf.Name)
+		if name == "" {
+			fs.Debugf(nil, "Ignoring %q in archive", zf.Name)
+			continue
+		}
+		if zf.FileInfo().IsDir() {
+			x.addDir(name, zf.Modified)
+			continue
+		}
+		if !zf.Mode().IsRegular() {
+			fs.Debugf(name, "Ignoring %v in archive", zf.Mode().Type())
+			continue
+		}
+		x.addFile(name, &entry{
+			size:    int64(zf.UncompressedSize64),
+			modTime: zf.Modified,
+			offset:  -1,
+			zf:      zf,
+		})
+	}
+	return x, nil
+}
+
+// readTar reads the index of the tar archive read by r
+//
+// The data of the files is skipped over, so only the headers are
+// read.
+func readTar(r io.ReaderAt, size int64) (*index, error) {
+	sr := io.NewSectionReader(r, 0, size)
+	tr := tar.NewReader(sr)
+	x := newIndex()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar: %w", err)
+		}
+		name := cleanName(hdr.Name)
+		if name == "" {
+			fs.Debugf(nil, "Ignoring %q in archive", hdr.Name)
+			continue
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			x.addDir(name, hdr.ModTime)
+		case tar.TypeReg:
+			offset, err := sr.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+			x.addFile(name, &entry{
+				size:    hdr.Size,
+				modTime: hdr.ModTime,
+				offset:  offset,
+			})
+		default:
+			fs.Debugf(name, "Ignoring tar entry of type %q", hdr.Typeflag)
+		}
+	}
+	return x, nil
+}
+
+// errUnknownFormat is returned if the format of the archive can't be
+// worked out
+var errUnknownFormat = errors.New("can't work out the format of the archive from its name - set the format option")
+
+// formatFromName works out the format of an archive from its name
+func formatFromName(name string) (string, error) {
+	ext := strings.ToLower(path.Ext(name))
+	switch ext {
+	case ".zip", ".jar", ".war", ".apk", ".epub", ".docx", ".xlsx", ".pptx", ".odt", ".ods", ".odp", ".whl", ".nupkg":
+		return formatZip, nil
+	case ".tar":
+		return formatTar, nil
+	}
+	return "", errUnknownFormat
+}
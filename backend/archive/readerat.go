@@ -0,0 +1,103 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// readerAt reads an object with ranged reads
+//
+// The blocks read are kept so the many small reads made when parsing
+// the index of an archive only need a few requests.
+type readerAt struct {
+	ctx       context.Context
+	o         fs.Object
+	size      int64
+	blockSize int64
+	maxBlocks int
+
+	mu     sync.Mutex
+	blocks map[int64][]byte // blocks read by number
+	order  []int64          // block numbers, oldest first
+}
+
+// newReaderAt makes a readerAt for o keeping up to maxBlocks blocks
+// of blockSize
+func newReaderAt(ctx context.Context, o fs.Object, blockSize int64, maxBlocks int) *readerAt {
+	return &readerAt{
+		ctx:       ctx,
+		o:         o,
+		size:      o.Size(),
+		blockSize: blockSize,
+		maxBlocks: maxBlocks,
+		blocks:    map[int64][]byte{},
+	}
+}
+
+// setContext sets the context used for reads
+func (r *readerAt) setContext(ctx context.Context) {
+	r.mu.Lock()
+	r.ctx = ctx
+	r.mu.Unlock()
+}
+
+// block returns block n, reading it if necessary
+func (r *readerAt) block(n int64) ([]byte, error) {
+	if b, ok := r.blocks[n]; ok {
+		return b, nil
+	}
+	start := n * r.blockSize
+	end := start + r.blockSize
+	if end > r.size {
+		end = r.size
+	}
+	in, err := r.o.Open(r.ctx, &fs.RangeOption{Start: start, End: end - 1})
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, end-start)
+	_, err = io.ReadFull(in, b)
+	closeErr := in.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive at %d: %w", start, err)
+	}
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	if len(r.order) >= r.maxBlocks {
+		delete(r.blocks, r.order[0])
+		r.order = r.order[1:]
+	}
+	r.blocks[n] = b
+	r.order = append(r.order, n)
+	return b, nil
+}
+
+// ReadAt reads len(p) bytes at off into p
+func (r *readerAt) ReadAt(p []byte, off int64) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	for n < len(p) {
+		if off >= r.size {
+			return n, io.EOF
+		}
+		b, err := r.block(off / r.blockSize)
+		if err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], b[off%r.blockSize:])
+		n += copied
+		off += int64(copied)
+	}
+	return n, nil
+}
+
+// Check the interfaces are satisfied
+var _ io.ReaderAt = (*readerAt)(nil)
@@ -0,0 +1,171 @@
+// Package api contains definitions for using the REST APIs of the
+// generic repositories of JFrog Artifactory and the raw repositories
+// of Sonatype Nexus Repository 3
+//
+// See https://jfrog.com/help/r/jfrog-rest-apis/artifactory-rest-apis
+// and https://help.sonatype.com/repomanager3/integrations/rest-and-integration-api
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time formats seen from the servers
+var timeFormats = []string{
+	time.RFC3339Nano,                // 2022-06-30T10:12:42.313Z or +00:00
+	"2006-01-02T15:04:05.000-0700",  // 2022-06-30T10:12:42.313+0000
+	"2006-01-02T15:04:05.999999999", // no time zone
+}
+
+// Time is a time which may be in any of the formats the servers use
+type Time time.Time
+
+// MarshalJSON turns a Time into JSON
+func (t Time) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + time.Time(t).Format(time.RFC3339Nano) + `"`), nil
+}
+
+// UnmarshalJSON turns JSON into a Time
+func (t *Time) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*t = Time{}
+		return nil
+	}
+	var err error
+	for _, format := range timeFormats {
+		var newT time.Time
+		newT, err = time.Parse(format, s)
+		if err == nil {
+			*t = Time(newT)
+			return nil
+		}
+	}
+	return err
+}
+
+// Size is a size which Artifactory sends as a string or a number
+type Size int64
+
+// UnmarshalJSON turns JSON into a Size
+func (s *Size) UnmarshalJSON(data []byte) error {
+	str := strings.Trim(string(data), `"`)
+	if str == "" || str == "null" {
+		*s = 0
+		return nil
+	}
+	i, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return err
+	}
+	*s = Size(i)
+	return nil
+}
+
+// Error is returned by the server when a request fails
+type Error struct {
+	Errors []struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"errors"` // Artifactory
+	Message    string `json:"-"`
+	StatusCode int    `json:"-"`
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
+
+// ParseError fills in the Error from the body of a response,
+// returning false if it wasn't a recognised error
+func (e *Error) ParseError(body []byte) bool {
+	if json.Unmarshal(body, e) == nil && len(e.Errors) > 0 {
+		var messages []string
+		for _, err := range e.Errors {
+			messages = append(messages, err.Message)
+		}
+		e.Message = strings.Join(messages, ", ")
+		return true
+	}
+	// Nexus returns a list of validation errors
+	var nexusErrors []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &nexusErrors) == nil && len(nexusErrors) > 0 {
+		var messages []string
+		for _, err := range nexusErrors {
+			messages = append(messages, err.Message)
+		}
+		e.Message = strings.Join(messages, ", ")
+		return true
+	}
+	return false
+}
+
+// Checksums of an artifact
+type Checksums struct {
+	SHA1   string `json:"sha1"`
+	MD5    string `json:"md5"`
+	SHA256 string `json:"sha256"`
+}
+
+// StorageInfo describes a file or folder in Artifactory
+//
+// This is returned by GET /api/storage/{repo}/{path} and when
+// uploading a file.
+type StorageInfo struct {
+	Repo         string     `json:"repo"`
+	Path         string     `json:"path"`
+	Created      Time       `json:"created"`
+	LastModified Time       `json:"lastModified"`
+	DownloadURI  string     `json:"downloadUri"`
+	MimeType     string     `json:"mimeType"`
+	Size         Size       `json:"size"`
+	Checksums    *Checksums `json:"checksums"` // nil for folders
+	Children     []struct {
+		URI    string `json:"uri"`
+		Folder bool   `json:"folder"`
+	} `json:"children"` // folders only
+}
+
+// FileListItem is a file or folder in an Artifactory file list
+type FileListItem struct {
+	URI          string `json:"uri"` // path relative to the folder listed, starting with /
+	Size         int64  `json:"size"`
+	LastModified Time   `json:"lastModified"`
+	Folder       bool   `json:"folder"`
+	SHA1         string `json:"sha1"`
+	SHA2         string `json:"sha2"`
+}
+
+// FileList is returned by GET /api/storage/{repo}/{path}?list
+type FileList struct {
+	URI     string         `json:"uri"`
+	Created Time           `json:"created"`
+	Files   []FileListItem `json:"files"`
+}
+
+// NexusAsset is an asset in a Nexus repository
+type NexusAsset struct {
+	ID           string    `json:"id"`
+	Path         string    `json:"path"`
+	DownloadURL  string    `json:"downloadUrl"`
+	Repository   string    `json:"repository"`
+	Format       string    `json:"format"`
+	Checksum     Checksums `json:"checksum"`
+	ContentType  string    `json:"contentType"`
+	LastModified Time      `json:"lastModified"`
+	FileSize     int64     `json:"fileSize"`
+}
+
+// NexusAssetList is a page of assets returned by GET /service/rest/v1/assets
+type NexusAssetList struct {
+	Items             []NexusAsset `json:"items"`
+	ContinuationToken *string      `json:"continuationToken"`
+}
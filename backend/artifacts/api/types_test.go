@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeUnmarshal(t *testing.T) {
+	want := time.Date(2022, 6, 30, 10, 12, 42, 313000000, time.UTC)
+	for _, in := range []string{
+		`"2022-06-30T10:12:42.313Z"`,
+		`"2022-06-30T10:12:42.313+00:00"`,
+		`"2022-06-30T10:12:42.313+0000"`,
+		`"2022-06-30T11:12:42.313+01:00"`,
+	} {
+		var got Time
+		require.NoError(t, json.Unmarshal([]byte(in), &got), in)
+		assert.True(t, want.Equal(time.Time(got)), in)
+	}
+	var got Time
+	require.NoError(t, json.Unmarshal([]byte(`null`), &got))
+	assert.True(t, time.Time(got).IsZero())
+	assert.Error(t, json.Unmarshal([]byte(`"yesterday"`), &got))
+}
+
+func TestSizeUnmarshal(t *testing.T) {
+	var info StorageInfo
+	require.NoError(t, json.Unmarshal([]byte(`{"size":"1024"}`), &info))
+	assert.Equal(t, Size(1024), info.Size)
+	require.NoError(t, json.Unmarshal([]byte(`{"size":2048}`), &info))
+	assert.Equal(t, Size(2048), info.Size)
+}
+
+func TestParseError(t *testing.T) {
+	var e Error
+	assert.True(t, e.ParseError([]byte(`{"errors":[{"status":404,"message":"Unable to find item"}]}`)))
+	assert.Equal(t, "Unable to find item", e.Message)
+
+	e = Error{}
+	assert.True(t, e.ParseError([]byte(`[{"id":"*","message":"Repository not found"}]`)))
+	assert.Equal(t, "Repository not found", e.Message)
+
+	e = Error{}
+	assert.False(t, e.ParseError([]byte(`Not Found`)))
+}
@@ -0,0 +1,250 @@
+package artifacts
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rclone/rclone/backend/artifacts/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+// artifactory stores files in a generic repository in JFrog Artifactory
+type artifactory struct {
+	srv   *rest.Client // the connection to the server
+	pacer *fs.Pacer    // pacer for API calls
+	repo  string       // name of the repository
+}
+
+// newArtifactory makes an artifactory repository
+func newArtifactory(srv *rest.Client, pacer *fs.Pacer, repo string) *artifactory {
+	return &artifactory{
+		srv:   srv,
+		pacer: pacer,
+		repo:  repo,
+	}
+}
+
+// hashes returns the hash types the server stores
+//
+// The MD5 isn't in file lists so isn't used.
+func (a *artifactory) hashes() hash.Set {
+	return hash.NewHashSet(hash.SHA1, hash.SHA256)
+}
+
+// contentPath returns the path to download the file at p from
+func (a *artifactory) contentPath(p string) string {
+	return rest.URLPathEscape("/" + a.repo + "/" + p)
+}
+
+// storagePath returns the path of the storage API for p
+func (a *artifactory) storagePath(p string) string {
+	return rest.URLPathEscape("/api/storage/" + a.repo + "/" + p)
+}
+
+// infoToItem converts the storage info for p into an item
+func infoToItem(p string, info *api.StorageInfo) *item {
+	if info.Checksums == nil {
+		return &item{path: p, isDir: true}
+	}
+	return &item{
+		path:    p,
+		size:    int64(info.Size),
+		modTime: time.Time(info.LastModified),
+		hashes:  parseChecksums("", info.Checksums.SHA1, info.Checksums.SHA256),
+	}
+}
+
+// stat returns the file or directory at p
+func (a *artifactory) stat(ctx context.Context, p string) (*item, error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   a.storagePath(p),
+	}
+	var info api.StorageInfo
+	resp, err := callJSON(ctx, a.srv, a.pacer, &opts, &info)
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, err
+	}
+	return infoToItem(p, &info), nil
+}
+
+// list returns the files and folders in dir
+func (a *artifactory) list(ctx context.Context, dir string, recurse bool) ([]item, error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   a.storagePath(dir),
+		Parameters: url.Values{
+			"list":        {""},
+			"deep":        {"0"},
+			"listFolders": {"1"},
+		},
+	}
+	if recurse {
+		opts.Parameters.Set("deep", "1")
+	}
+	var result api.FileList
+	resp, err := callJSON(ctx, a.srv, a.pacer, &opts, &result)
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, fs.ErrorDirNotFound
+		}
+		return nil, err
+	}
+	items := make([]item, 0, len(result.Files))
+	for _, file := range result.Files {
+		it := item{
+			path:  joinPath(dir, file.URI),
+			isDir: file.Folder,
+		}
+		if !file.Folder {
+			it.size = file.Size
+			it.modTime = time.Time(file.LastModified)
+			it.hashes = parseChecksums("", file.SHA1, file.SHA2)
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// checksumHeaders returns headers for the checksums of the file
+func checksumHeaders(hashes map[hash.Type]string) map[string]string {
+	headers := map[string]string{}
+	if sum := hashes[hash.SHA1]; sum != "" {
+		headers["X-Checksum-Sha1"] = sum
+	}
+	if sum := hashes[hash.SHA256]; sum != "" {
+		headers["X-Checksum-Sha256"] = sum
+	}
+	return headers
+}
+
+// upload the file at p
+//
+// The checksums are sent so Artifactory can check the upload.
+func (a *artifactory) upload(ctx context.Context, p string, in io.Reader, size int64, mimeType string, hashes map[hash.Type]string) (*item, error) {
+	opts := rest.Opts{
+		Method:       "PUT",
+		Path:         a.contentPath(p),
+		Body:         in,
+		ContentType:  mimeType,
+		ExtraHeaders: checksumHeaders(hashes),
+	}
+	if size >= 0 {
+		opts.ContentLength = &size
+	}
+	var info api.StorageInfo
+	var resp *http.Response
+	err := a.pacer.CallNoRetry(func() (bool, error) {
+		var err error
+		resp, err = a.srv.CallJSON(ctx, &opts, nil, &info)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	it := infoToItem(p, &info)
+	if it.isDir {
+		// Not the expected reply so use what was sent
+		it = &item{path: p, size: -1, modTime: time.Now()}
+	}
+	return it, nil
+}
+
+// deploy makes the file at p from an artifact with the same SHA-1
+// if Artifactory has one
+func (a *artifactory) deploy(ctx context.Context, p string, hashes map[hash.Type]string) (*item, error) {
+	if hashes[hash.SHA1] == "" {
+		return nil, nil
+	}
+	headers := checksumHeaders(hashes)
+	headers["X-Checksum-Deploy"] = "true"
+	var zero int64
+	opts := rest.Opts{
+		Method:        "PUT",
+		Path:          a.contentPath(p),
+		ContentLength: &zero,
+		ExtraHeaders:  headers,
+	}
+	var info api.StorageInfo
+	resp, err := callJSON(ctx, a.srv, a.pacer, &opts, &info)
+	if err != nil {
+		if isNotFound(resp) {
+			// No artifact with this checksum
+			return nil, nil
+		}
+		return nil, err
+	}
+	it := infoToItem(p, &info)
+	if it.isDir {
+		return nil, nil
+	}
+	return it, nil
+}
+
+// mkdir makes the folder and its parents
+func (a *artifactory) mkdir(ctx context.Context, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	var zero int64
+	opts := rest.Opts{
+		Method:        "PUT",
+		Path:          a.contentPath(dir + "/"),
+		ContentLength: &zero,
+		NoResponse:    true,
+	}
+	return call(ctx, a.srv, a.pacer, &opts)
+}
+
+// rmdir removes the folder if it is empty
+//
+// The root of the repository is never removed.
+func (a *artifactory) rmdir(ctx context.Context, dir string) error {
+	items, err := a.list(ctx, dir, false)
+	if err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	if dir == "" {
+		return nil
+	}
+	return a.delete(ctx, dir)
+}
+
+// purge removes the folder and everything in it
+func (a *artifactory) purge(ctx context.Context, dir string) error {
+	if dir == "" {
+		// Don't delete the whole repository in one go
+		return fs.ErrorCantPurge
+	}
+	return a.delete(ctx, dir)
+}
+
+// delete the folder at dir and everything in it
+func (a *artifactory) delete(ctx context.Context, dir string) error {
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       a.contentPath(dir),
+		NoResponse: true,
+	}
+	var resp *http.Response
+	var err error
+	err = a.pacer.Call(func() (bool, error) {
+		resp, err = a.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if isNotFound(resp) {
+		return fs.ErrorDirNotFound
+	}
+	return err
+}
@@ -0,0 +1,658 @@
+// Package artifacts provides an interface to the generic repositories
+// of JFrog Artifactory and the raw repositories of Sonatype Nexus
+//
+// Files are artifacts in the repository. Artifactory has real folders
+// but Nexus only has the paths of the artifacts so directories are
+// implied by the / in them.
+package artifacts
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/artifacts/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+
+	providerArtifactory = "artifactory"
+	providerNexus       = "nexus"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "artifacts",
+		Description: "Artifactory and Nexus generic repositories",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:    fs.ConfigProvider,
+			Help:    "Repository manager to use.",
+			Default: providerArtifactory,
+			Examples: []fs.OptionExample{{
+				Value: providerArtifactory,
+				Help:  "JFrog Artifactory generic repository",
+			}, {
+				Value: providerNexus,
+				Help:  "Sonatype Nexus Repository 3 raw repository",
+			}},
+			Exclusive: true,
+		}, {
+			Name: "url",
+			Help: `URL of the server.
+
+For Artifactory this ends in /artifactory, e.g.
+"https://example.jfrog.io/artifactory". For Nexus it is the URL of the
+web UI, e.g. "https://nexus.example.com".`,
+			Required: true,
+		}, {
+			Name:     "repository",
+			Help:     "Name of the repository.",
+			Required: true,
+		}, {
+			Name: "user",
+			Help: "User name.\n\nLeave blank for anonymous access or to use a token.",
+		}, {
+			Name:       "pass",
+			Help:       "Password or API key.",
+			IsPassword: true,
+		}, {
+			Name:       "token",
+			Help:       "Access token.\n\nUse this instead of user and pass to authenticate with a bearer token.",
+			Provider:   providerArtifactory,
+			IsPassword: true,
+		}, {
+			Name: "checksum_deploy",
+			Help: `Try to deploy files by checksum before uploading them.
+
+If set, rclone first asks Artifactory to make the file from an
+artifact it already stores with the same SHA-1, and only uploads the
+file if there isn't one. This saves uploading files which are already
+in Artifactory in any repository, but reads the file an extra time to
+work out its SHA-1 if the source doesn't already know it.`,
+			Default:  true,
+			Provider: providerArtifactory,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			Default: (encoder.Base |
+				encoder.EncodeBackSlash |
+				encoder.EncodeSemicolon |
+				encoder.EncodeCtl |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Provider       string               `config:"provider"`
+	URL            string               `config:"url"`
+	Repository     string               `config:"repository"`
+	User           string               `config:"user"`
+	Pass           string               `config:"pass"`
+	Token          string               `config:"token"`
+	ChecksumDeploy bool                 `config:"checksum_deploy"`
+	Enc            encoder.MultiEncoder `config:"encoding"`
+}
+
+// item is a file or directory in the repository
+type item struct {
+	path    string               // path in the repository without a leading /
+	isDir   bool                 // set if this is a directory
+	size    int64                // size of the file
+	modTime time.Time            // when the file was last modified, zero if unknown
+	hashes  map[hash.Type]string // the checksums known
+}
+
+// repository is the interface to the API of a repository manager
+type repository interface {
+	// hashes returns the hash types the server stores
+	hashes() hash.Set
+	// contentPath returns the path to download the file at p from
+	contentPath(p string) string
+	// stat returns the file or directory at p, or
+	// fs.ErrorObjectNotFound if there isn't one
+	stat(ctx context.Context, p string) (*item, error)
+	// list returns the files and directories in dir, and
+	// everything below it if recurse is set, or
+	// fs.ErrorDirNotFound if it doesn't exist. It may return
+	// things below dir even if recurse isn't set.
+	list(ctx context.Context, dir string, recurse bool) ([]item, error)
+	// upload the file at p returning what was stored
+	upload(ctx context.Context, p string, in io.Reader, size int64, mimeType string, hashes map[hash.Type]string) (*item, error)
+	// deploy tries to make the file at p from an artifact with the
+	// same checksums, returning nil if there isn't one
+	deploy(ctx context.Context, p string, hashes map[hash.Type]string) (*item, error)
+	// mkdir makes the directory and its parents
+	mkdir(ctx context.Context, dir string) error
+	// rmdir removes the directory if it is empty
+	rmdir(ctx context.Context, dir string) error
+	// purge removes the directory and everything in it, or returns
+	// fs.ErrorCantPurge if it can't
+	purge(ctx context.Context, dir string) error
+}
+
+// Fs represents a repository
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	srv      *rest.Client // the connection to the server
+	pacer    *fs.Pacer    // pacer for API calls
+	repo     repository   // the provider specific parts
+}
+
+// Object describes an artifact
+type Object struct {
+	fs      *Fs                  // what this object is part of
+	remote  string               // The remote path
+	size    int64                // size of the object
+	modTime time.Time            // modification time of the object
+	hashes  map[hash.Type]string // checksums of the object
+}
+
+// ------------------------------------------------------------
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error reading error out of body: %w", err)
+	}
+	errResponse := &api.Error{}
+	if !errResponse.ParseError(body) {
+		errResponse.Message = strings.TrimSpace(string(body))
+		if errResponse.Message == "" || len(errResponse.Message) > 1024 || strings.HasPrefix(errResponse.Message, "<") {
+			errResponse.Message = resp.Status
+		}
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// isNotFound returns true if resp says there is nothing there
+func isNotFound(resp *http.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
+
+// callJSON makes the call in opts decoding the reply into result
+func callJSON(ctx context.Context, srv *rest.Client, pacer *fs.Pacer, opts *rest.Opts, result interface{}) (resp *http.Response, err error) {
+	err = pacer.Call(func() (bool, error) {
+		resp, err = srv.CallJSON(ctx, opts, nil, result)
+		return shouldRetry(ctx, resp, err)
+	})
+	return resp, err
+}
+
+// call makes the call in opts ignoring the reply
+func call(ctx context.Context, srv *rest.Client, pacer *fs.Pacer, opts *rest.Opts) error {
+	return pacer.Call(func() (bool, error) {
+		resp, err := srv.Call(ctx, opts)
+		return shouldRetry(ctx, resp, err)
+	})
+}
+
+// joinPath joins the uri of a listing to dir
+func joinPath(dir, uri string) string {
+	return strings.Trim(path.Join(dir, uri), "/")
+}
+
+// NewFs constructs an Fs from the path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.URL == "" {
+		return nil, errors.New("url must be set")
+	}
+	if opt.Repository == "" {
+		return nil, errors.New("repository must be set")
+	}
+	f := &Fs{
+		name:  name,
+		root:  strings.Trim(path.Clean("/"+root), "/"),
+		opt:   *opt,
+		srv:   rest.NewClient(fshttp.NewClient(ctx)).SetRoot(strings.TrimRight(opt.URL, "/")),
+		pacer: fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+	}
+	f.srv.SetErrorHandler(errorHandler)
+	if opt.Token != "" {
+		token, err := obscure.Reveal(opt.Token)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt token: %w", err)
+		}
+		f.srv.SetHeader("Authorization", "Bearer "+token)
+	} else if opt.User != "" || opt.Pass != "" {
+		pass, err := obscure.Reveal(opt.Pass)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt password: %w", err)
+		}
+		f.srv.SetUserPass(opt.User, pass)
+	}
+	switch opt.Provider {
+	case providerArtifactory:
+		f.repo = newArtifactory(f.srv, f.pacer, opt.Repository)
+	case providerNexus:
+		f.repo = newNexus(f.srv, f.pacer, opt.Repository)
+	default:
+		return nil, fmt.Errorf("unknown provider %q", opt.Provider)
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: opt.Provider == providerArtifactory,
+	}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	if f.root != "" {
+		it, err := f.repo.stat(ctx, f.path(""))
+		if err == nil && !it.isDir {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		} else if err != nil && err != fs.ErrorObjectNotFound {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("%s repository %s path %q", f.opt.Provider, f.opt.Repository, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return f.repo.hashes()
+}
+
+// path returns the path in the repository of remote
+func (f *Fs) path(remote string) string {
+	return f.opt.Enc.FromStandardPath(path.Join(f.root, remote))
+}
+
+// newObject makes an Object for remote from it
+func (f *Fs) newObject(remote string, it *item) *Object {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	o.setMetaData(it)
+	return o
+}
+
+// list the files in dir calling fn for each entry found
+//
+// If recurse is set the entries in subdirectories are found too.
+func (f *Fs) list(ctx context.Context, dir string, recurse bool, fn func(fs.DirEntry)) error {
+	dirPath := f.path(dir)
+	items, err := f.repo.list(ctx, dirPath, recurse)
+	if err != nil {
+		return err
+	}
+	prefix := dirPath + "/"
+	if dirPath == "" {
+		prefix = ""
+	}
+	seen := map[string]struct{}{}
+	addDir := func(remote string) {
+		if _, ok := seen[remote]; !ok {
+			seen[remote] = struct{}{}
+			fn(fs.NewDir(remote, time.Time{}))
+		}
+	}
+	for i := range items {
+		it := &items[i]
+		if !strings.HasPrefix(it.path, prefix) || it.path == prefix {
+			continue
+		}
+		parts := strings.Split(it.path[len(prefix):], "/")
+		remote := dir
+		for i, part := range parts {
+			if part == "" {
+				break
+			}
+			remote = path.Join(remote, f.opt.Enc.ToStandardName(part))
+			if i == len(parts)-1 && !it.isDir {
+				fn(f.newObject(remote, it))
+			} else {
+				addDir(remote)
+				if !recurse {
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// List the objects and directories in dir into entries. The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.list(ctx, dir, false, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	return entries, err
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+//
+// Don't implement this unless you have a more efficient way
+// of listing recursively than doing a directory traversal.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	var entries fs.DirEntries
+	err := f.list(ctx, dir, true, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return err
+	}
+	return callback(entries)
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	it, err := f.repo.stat(ctx, f.path(remote))
+	if err != nil {
+		return nil, err
+	}
+	if it.isDir {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.newObject(remote, it), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory and its parents
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.repo.mkdir(ctx, f.path(dir))
+}
+
+// Rmdir removes the directory if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.repo.rmdir(ctx, f.path(dir))
+}
+
+// Purge deletes all the files in the directory
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	return f.repo.purge(ctx, f.path(dir))
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// setMetaData sets the metadata from it
+func (o *Object) setMetaData(it *item) {
+	o.size = it.size
+	o.modTime = it.modTime
+	o.hashes = it.hashes
+}
+
+// Hash returns the checksum of the object of type t, or "" if the
+// server didn't say
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if !o.fs.Hashes().Contains(t) {
+		return "", hash.ErrUnsupported
+	}
+	return o.hashes[t], nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the time the object was last uploaded
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	fs.FixRangeOption(options, o.size)
+	opts := rest.Opts{
+		Method:  "GET",
+		Path:    o.fs.repo.contentPath(o.fs.path(o.remote)),
+		Options: options,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// If checksum_deploy is set then Artifactory is asked to make the
+// file from one it already has before uploading it.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	p := o.fs.path(o.remote)
+	types := o.fs.Hashes()
+	srcHashes := map[hash.Type]string{}
+	for _, t := range types.Array() {
+		sum, err := src.Hash(ctx, t)
+		if err == nil && sum != "" {
+			srcHashes[t] = sum
+		}
+	}
+	if o.fs.opt.ChecksumDeploy {
+		it, err := o.fs.repo.deploy(ctx, p, srcHashes)
+		if err != nil {
+			return err
+		}
+		if it != nil {
+			fs.Debugf(o, "Deployed by checksum")
+			if it.hashes == nil {
+				it.hashes = srcHashes
+			}
+			o.setMetaData(it)
+			return nil
+		}
+	}
+	hasher, err := hash.NewMultiHasherTypes(types)
+	if err != nil {
+		return err
+	}
+	it, err := o.fs.repo.upload(ctx, p, io.TeeReader(in, hasher), src.Size(), fs.MimeType(ctx, src), srcHashes)
+	if err != nil {
+		return err
+	}
+	// Fill in any checksums the server didn't return
+	sums := hasher.Sums()
+	if it.hashes == nil {
+		it.hashes = map[hash.Type]string{}
+	}
+	for t, sum := range sums {
+		if it.hashes[t] == "" {
+			it.hashes[t] = sum
+		}
+	}
+	if it.size < 0 {
+		it.size = hasher.Size()
+	}
+	o.setMetaData(it)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       o.fs.repo.contentPath(o.fs.path(o.remote)),
+		NoResponse: true,
+	}
+	var resp *http.Response
+	var err error
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if isNotFound(resp) {
+		return fs.ErrorObjectNotFound
+	}
+	return err
+}
+
+// parseChecksums returns the checksums in sums which look valid
+func parseChecksums(md5, sha1, sha256 string) map[hash.Type]string {
+	hashes := map[hash.Type]string{}
+	add := func(t hash.Type, sum string) {
+		sum = strings.ToLower(sum)
+		if _, err := hex.DecodeString(sum); err == nil && len(sum) == hash.Width(t, false) {
+			hashes[t] = sum
+		}
+	}
+	add(hash.MD5, md5)
+	add(hash.SHA1, sha1)
+	add(hash.SHA256, sha256)
+	return hashes
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs      = (*Fs)(nil)
+	_ fs.Purger  = (*Fs)(nil)
+	_ fs.ListRer = (*Fs)(nil)
+	_ fs.Object  = (*Object)(nil)
+	_ repository = (*artifactory)(nil)
+	_ repository = (*nexus)(nil)
+)
@@ -0,0 +1,502 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/artifacts/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRepo = "generic-local"
+
+// testFile is a file stored in the test server
+type testFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// sha1 returns the SHA-1 of the file
+func (tf *testFile) sha1() string {
+	sum := sha1.Sum(tf.data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sha256 returns the SHA-256 of the file
+func (tf *testFile) sha256() string {
+	sum := sha256.Sum256(tf.data)
+	return hex.EncodeToString(sum[:])
+}
+
+// testServer is an in memory Artifactory or Nexus repository
+type testServer struct {
+	mu       sync.Mutex
+	files    map[string]*testFile // files by path
+	folders  map[string]struct{}  // Artifactory folders by path
+	uploads  int                  // number of files uploaded with a body
+	deploys  int                  // number of files deployed by checksum
+	pageSize int                  // assets in each page of a Nexus listing
+}
+
+func newTestServer() *testServer {
+	return &testServer{
+		files:    map[string]*testFile{},
+		folders:  map[string]struct{}{},
+		pageSize: 3,
+	}
+}
+
+// writeJSON writes v as the response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// artifactoryError writes an error in the Artifactory format
+func artifactoryError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"errors": []map[string]interface{}{{"status": status, "message": message}},
+	})
+}
+
+// mkdirAll makes the folder p and its parents
+func (s *testServer) mkdirAll(p string) {
+	for p != "" && p != "." {
+		s.folders[p] = struct{}{}
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+}
+
+// isFolder returns whether p is a folder
+func (s *testServer) isFolder(p string) bool {
+	if p == "" {
+		return true
+	}
+	_, ok := s.folders[p]
+	return ok
+}
+
+// info returns the storage info of the file at p
+func (s *testServer) info(p string, tf *testFile) *api.StorageInfo {
+	return &api.StorageInfo{
+		Repo:         testRepo,
+		Path:         "/" + p,
+		LastModified: api.Time(tf.modTime),
+		Size:         api.Size(len(tf.data)),
+		Checksums: &api.Checksums{
+			SHA1:   tf.sha1(),
+			SHA256: tf.sha256(),
+		},
+	}
+}
+
+// artifactory serves the parts of the Artifactory API used
+func (s *testServer) artifactory(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+		artifactoryError(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/storage/"+testRepo) {
+		s.artifactoryStorage(w, r, strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/storage/"+testRepo), "/"))
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/"+testRepo+"/") {
+		artifactoryError(w, http.StatusNotFound, "Repository not found")
+		return
+	}
+	p := strings.TrimPrefix(r.URL.Path, "/"+testRepo+"/")
+	switch r.Method {
+	case "GET":
+		tf := s.files[p]
+		if tf == nil {
+			artifactoryError(w, http.StatusNotFound, "File not found")
+			return
+		}
+		w.Header().Set("X-Checksum-Sha1", tf.sha1())
+		http.ServeContent(w, r, "", tf.modTime, bytes.NewReader(tf.data))
+	case "PUT":
+		if strings.HasSuffix(p, "/") {
+			s.mkdirAll(strings.TrimSuffix(p, "/"))
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		sha1 := r.Header.Get("X-Checksum-Sha1")
+		var tf *testFile
+		if r.Header.Get("X-Checksum-Deploy") == "true" {
+			for _, existing := range s.files {
+				if existing.sha1() == sha1 {
+					tf = &testFile{data: existing.data, modTime: time.Now()}
+					break
+				}
+			}
+			if tf == nil {
+				artifactoryError(w, http.StatusNotFound, "Checksum deploy failed. No existing file with SHA1: "+sha1)
+				return
+			}
+			s.deploys++
+		} else {
+			data, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				artifactoryError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			tf = &testFile{data: data, modTime: time.Now()}
+			if sha1 != "" && sha1 != tf.sha1() {
+				artifactoryError(w, http.StatusConflict, "Checksum policy rejected the upload")
+				return
+			}
+			s.uploads++
+		}
+		i := strings.LastIndex(p, "/")
+		if i >= 0 {
+			s.mkdirAll(p[:i])
+		}
+		s.files[p] = tf
+		writeJSON(w, http.StatusCreated, s.info(p, tf))
+	case "DELETE":
+		p = strings.TrimSuffix(p, "/")
+		found := false
+		if _, ok := s.files[p]; ok {
+			delete(s.files, p)
+			found = true
+		}
+		if s.isFolder(p) {
+			found = true
+			delete(s.folders, p)
+			for fp := range s.files {
+				if strings.HasPrefix(fp, p+"/") {
+					delete(s.files, fp)
+				}
+			}
+			for dp := range s.folders {
+				if strings.HasPrefix(dp, p+"/") {
+					delete(s.folders, dp)
+				}
+			}
+		}
+		if !found {
+			artifactoryError(w, http.StatusNotFound, "Could not locate artifact")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		artifactoryError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// artifactoryStorage serves the storage API for p
+func (s *testServer) artifactoryStorage(w http.ResponseWriter, r *http.Request, p string) {
+	query := r.URL.Query()
+	if _, ok := query["list"]; !ok {
+		if tf := s.files[p]; tf != nil {
+			writeJSON(w, http.StatusOK, s.info(p, tf))
+		} else if s.isFolder(p) {
+			writeJSON(w, http.StatusOK, &api.StorageInfo{Repo: testRepo, Path: "/" + p})
+		} else {
+			artifactoryError(w, http.StatusNotFound, "Unable to find item")
+		}
+		return
+	}
+	if !s.isFolder(p) {
+		artifactoryError(w, http.StatusNotFound, "Unable to find item")
+		return
+	}
+	deep := query.Get("deep") == "1"
+	prefix := p + "/"
+	if p == "" {
+		prefix = ""
+	}
+	var result api.FileList
+	inside := func(fp string) bool {
+		return strings.HasPrefix(fp, prefix) && (deep || !strings.Contains(fp[len(prefix):], "/"))
+	}
+	for fp, tf := range s.files {
+		if inside(fp) {
+			result.Files = append(result.Files, api.FileListItem{
+				URI:          "/" + fp[len(prefix):],
+				Size:         int64(len(tf.data)),
+				LastModified: api.Time(tf.modTime),
+				SHA1:         tf.sha1(),
+				SHA2:         tf.sha256(),
+			})
+		}
+	}
+	if query.Get("listFolders") == "1" {
+		for dp := range s.folders {
+			if inside(dp) {
+				result.Files = append(result.Files, api.FileListItem{URI: "/" + dp[len(prefix):], Size: -1, Folder: true})
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, &result)
+}
+
+// nexus serves the parts of the Nexus API used
+func (s *testServer) nexus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path == "/service/rest/v1/assets" {
+		s.nexusAssets(w, r)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/repository/raw/") {
+		http.NotFound(w, r)
+		return
+	}
+	p := strings.TrimPrefix(r.URL.Path, "/repository/raw/")
+	switch r.Method {
+	case "GET", "HEAD":
+		tf := s.files[p]
+		if tf == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"{SHA1{`+tf.sha1()+`}}"`)
+		http.ServeContent(w, r, "", tf.modTime, bytes.NewReader(tf.data))
+	case "PUT":
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.files[p] = &testFile{data: data, modTime: time.Now().Truncate(time.Second)}
+		s.uploads++
+		w.WriteHeader(http.StatusCreated)
+	case "DELETE":
+		if _, ok := s.files[p]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(s.files, p)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// nexusAssets serves a page of the assets in the repository
+func (s *testServer) nexusAssets(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("repository") != "raw" {
+		writeJSON(w, http.StatusNotFound, []map[string]string{{"id": "*", "message": "Repository not found"}})
+		return
+	}
+	var paths []string
+	for p := range s.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	start := 0
+	if token := r.URL.Query().Get("continuationToken"); token != "" {
+		start, _ = strconv.Atoi(token)
+	}
+	var result api.NexusAssetList
+	for i := start; i < len(paths) && i < start+s.pageSize; i++ {
+		tf := s.files[paths[i]]
+		result.Items = append(result.Items, api.NexusAsset{
+			Path:         paths[i],
+			Repository:   "raw",
+			Format:       "raw",
+			Checksum:     api.Checksums{SHA1: tf.sha1()},
+			LastModified: api.Time(tf.modTime),
+			FileSize:     int64(len(tf.data)),
+		})
+	}
+	if start+s.pageSize < len(paths) {
+		token := strconv.Itoa(start + s.pageSize)
+		result.ContinuationToken = &token
+	}
+	writeJSON(w, http.StatusOK, &result)
+}
+
+// newTestHTTPServer starts a test server for provider
+func newTestHTTPServer(t *testing.T, provider string) (*testServer, *httptest.Server) {
+	s := newTestServer()
+	handler := s.artifactory
+	if provider == providerNexus {
+		handler = s.nexus
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// testConfig returns the config to use the test server ts
+func testConfig(provider string, ts *httptest.Server) configmap.Simple {
+	m := configmap.Simple{
+		"provider":   provider,
+		"url":        ts.URL,
+		"repository": testRepo,
+		"user":       "user",
+		"pass":       obscure.MustObscure("secret"),
+	}
+	if provider == providerNexus {
+		m["repository"] = "raw"
+	} else {
+		m["checksum_deploy"] = "true"
+	}
+	return m
+}
+
+// runLocal runs the integration tests against a test server
+func runLocal(t *testing.T, provider string) {
+	if *fstest.RemoteName != "" {
+		t.Skip("skipping as -remote is set")
+	}
+	_, ts := newTestHTTPServer(t, provider)
+	name := "TestArtifactsLocal" + provider
+	var extra []fstests.ExtraConfigItem
+	for k, v := range testConfig(provider, ts) {
+		extra = append(extra, fstests.ExtraConfigItem{Name: name, Key: k, Value: v})
+	}
+	extra = append(extra, fstests.ExtraConfigItem{Name: name, Key: "type", Value: "artifacts"})
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:  name + ":",
+		NilObject:   (*Object)(nil),
+		ExtraConfig: extra,
+		QuickTestOK: true,
+	})
+}
+
+func TestLocalArtifactory(t *testing.T) {
+	runLocal(t, providerArtifactory)
+}
+
+func TestLocalNexus(t *testing.T) {
+	runLocal(t, providerNexus)
+}
+
+// newTestFs makes an Fs using the test server
+func newTestFs(t *testing.T, provider string) (*testServer, *Fs) {
+	s, ts := newTestHTTPServer(t, provider)
+	f, err := NewFs(context.Background(), t.Name(), "dir", testConfig(provider, ts))
+	require.NoError(t, err)
+	return s, f.(*Fs)
+}
+
+// put uploads contents to remote with the SHA-1 given to the source
+func put(t *testing.T, f *Fs, remote, contents string) fs.Object {
+	sum := sha1.Sum([]byte(contents))
+	hashes := map[hash.Type]string{hash.SHA1: hex.EncodeToString(sum[:])}
+	src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, hashes, nil)
+	o, err := f.Put(context.Background(), strings.NewReader(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+func TestChecksumDeploy(t *testing.T) {
+	s, f := newTestFs(t, providerArtifactory)
+	put(t, f, "a.txt", "same contents")
+	assert.Equal(t, 1, s.uploads)
+	assert.Equal(t, 0, s.deploys)
+
+	o := put(t, f, "b.txt", "same contents")
+	assert.Equal(t, 1, s.uploads)
+	assert.Equal(t, 1, s.deploys)
+	assert.Equal(t, int64(13), o.Size())
+	assert.Equal(t, "same contents", string(s.files["dir/b.txt"].data))
+
+	put(t, f, "c.txt", "different contents")
+	assert.Equal(t, 2, s.uploads)
+	assert.Equal(t, 1, s.deploys)
+
+	f.opt.ChecksumDeploy = false
+	put(t, f, "d.txt", "same contents")
+	assert.Equal(t, 3, s.uploads)
+	assert.Equal(t, 1, s.deploys)
+}
+
+func TestArtifactoryChecksumMismatch(t *testing.T) {
+	s, f := newTestFs(t, providerArtifactory)
+	f.opt.ChecksumDeploy = false
+	hashes := map[hash.Type]string{hash.SHA1: strings.Repeat("0", 40)}
+	src := object.NewStaticObjectInfo("bad.txt", time.Now(), 5, true, hashes, nil)
+	_, err := f.Put(context.Background(), strings.NewReader("hello"), src)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Checksum policy rejected the upload")
+	assert.Equal(t, 0, s.uploads)
+}
+
+func TestNexusListPages(t *testing.T) {
+	ctx := context.Background()
+	s, f := newTestFs(t, providerNexus)
+	s.pageSize = 2
+	for i := 0; i < 7; i++ {
+		put(t, f, fmt.Sprintf("sub/file%d.txt", i), "hello")
+	}
+	put(t, f, "top.txt", "hello")
+	var entries fs.DirEntries
+	err := f.ListR(ctx, "", func(newEntries fs.DirEntries) error {
+		entries = append(entries, newEntries...)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, entries, 9)
+
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestNexusNewObject(t *testing.T) {
+	ctx := context.Background()
+	_, f := newTestFs(t, providerNexus)
+	put(t, f, "file.txt", "hello")
+	o, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), o.Size())
+	sum, err := o.Hash(ctx, hash.SHA1)
+	require.NoError(t, err)
+	assert.Equal(t, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", sum)
+}
+
+func TestParseETag(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{`"{SHA1{aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d}}"`, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{`W/"{SHA1{aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d}}"`, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{`"aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"`, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{``, ``},
+	} {
+		assert.Equal(t, test.want, parseETag(test.in), test.in)
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	got := parseChecksums("", "AAF4C61DDCC5E8A2DABEDE0F3B482CD9AEA9434D", "not a checksum")
+	assert.Equal(t, map[hash.Type]string{hash.SHA1: "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"}, got)
+}
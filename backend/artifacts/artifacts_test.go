@@ -0,0 +1,16 @@
+// Test Artifacts filesystem interface
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestArtifacts:",
+		NilObject:  (*Object)(nil),
+	})
+}
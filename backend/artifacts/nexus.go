@@ -0,0 +1,237 @@
+package artifacts
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/artifacts/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+// nexus stores files in a raw repository in Sonatype Nexus
+// Repository 3
+//
+// Nexus doesn't have directories, so the ones made with mkdir are
+// remembered until they are removed.
+type nexus struct {
+	srv   *rest.Client // the connection to the server
+	pacer *fs.Pacer    // pacer for API calls
+	repo  string       // name of the repository
+	dirs  sync.Map     // directories made by mkdir
+}
+
+// newNexus makes a nexus repository
+func newNexus(srv *rest.Client, pacer *fs.Pacer, repo string) *nexus {
+	return &nexus{
+		srv:   srv,
+		pacer: pacer,
+		repo:  repo,
+	}
+}
+
+// hashes returns the hash types the server stores
+//
+// Only the SHA-1 is returned when reading a single file.
+func (n *nexus) hashes() hash.Set {
+	return hash.Set(hash.SHA1)
+}
+
+// contentPath returns the path to download the file at p from
+func (n *nexus) contentPath(p string) string {
+	return rest.URLPathEscape("/repository/" + n.repo + "/" + p)
+}
+
+// parseETag returns the SHA-1 in an ETag from Nexus, which look like
+// "{SHA1{da39a3ee5e6b4b0d3255bfef95601890afd80709}}"
+func parseETag(etag string) string {
+	etag = strings.TrimPrefix(etag, "W/")
+	etag = strings.Trim(etag, `"`)
+	etag = strings.TrimPrefix(etag, "{SHA1{")
+	etag = strings.TrimSuffix(etag, "}}")
+	return etag
+}
+
+// stat returns the file at p
+func (n *nexus) stat(ctx context.Context, p string) (*item, error) {
+	if p == "" {
+		return &item{isDir: true}, nil
+	}
+	opts := rest.Opts{
+		Method:     "HEAD",
+		Path:       n.contentPath(p),
+		NoResponse: true,
+	}
+	var resp *http.Response
+	var err error
+	err = n.pacer.Call(func() (bool, error) {
+		resp, err = n.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, err
+	}
+	it := &item{
+		path:   p,
+		size:   resp.ContentLength,
+		hashes: parseChecksums("", parseETag(resp.Header.Get("ETag")), ""),
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		it.modTime = modTime
+	}
+	return it, nil
+}
+
+// assets returns all the assets in the repository
+//
+// Nexus can only list the whole repository.
+func (n *nexus) assets(ctx context.Context) (assets []api.NexusAsset, err error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "/service/rest/v1/assets",
+		Parameters: url.Values{
+			"repository": {n.repo},
+		},
+	}
+	for {
+		var result api.NexusAssetList
+		_, err = callJSON(ctx, n.srv, n.pacer, &opts, &result)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, result.Items...)
+		if result.ContinuationToken == nil || *result.ContinuationToken == "" {
+			return assets, nil
+		}
+		opts.Parameters.Set("continuationToken", *result.ContinuationToken)
+	}
+}
+
+// list returns all the files below dir along with the directories
+// made by mkdir
+func (n *nexus) list(ctx context.Context, dir string, recurse bool) (items []item, err error) {
+	assets, err := n.assets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := dir + "/"
+	if dir == "" {
+		prefix = ""
+	}
+	for _, asset := range assets {
+		p := strings.TrimLeft(asset.Path, "/")
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		items = append(items, item{
+			path:    p,
+			size:    asset.FileSize,
+			modTime: time.Time(asset.LastModified),
+			hashes:  parseChecksums("", asset.Checksum.SHA1, ""),
+		})
+	}
+	n.dirs.Range(func(key, _ interface{}) bool {
+		if p := key.(string); strings.HasPrefix(p, prefix) {
+			items = append(items, item{path: p, isDir: true})
+		}
+		return true
+	})
+	if len(items) == 0 && dir != "" {
+		if _, ok := n.dirs.Load(dir); !ok {
+			return nil, fs.ErrorDirNotFound
+		}
+	}
+	return items, nil
+}
+
+// upload the file at p
+func (n *nexus) upload(ctx context.Context, p string, in io.Reader, size int64, mimeType string, hashes map[hash.Type]string) (*item, error) {
+	opts := rest.Opts{
+		Method:      "PUT",
+		Path:        n.contentPath(p),
+		Body:        in,
+		ContentType: mimeType,
+		NoResponse:  true,
+	}
+	if size >= 0 {
+		opts.ContentLength = &size
+	}
+	err := n.pacer.CallNoRetry(func() (bool, error) {
+		resp, err := n.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item{path: p, size: -1, modTime: time.Now()}, nil
+}
+
+// deploy isn't supported by Nexus
+func (n *nexus) deploy(ctx context.Context, p string, hashes map[hash.Type]string) (*item, error) {
+	return nil, nil
+}
+
+// mkdir remembers the directory and its parents
+func (n *nexus) mkdir(ctx context.Context, dir string) error {
+	for dir != "" {
+		n.dirs.Store(dir, struct{}{})
+		i := strings.LastIndex(dir, "/")
+		if i < 0 {
+			break
+		}
+		dir = dir[:i]
+	}
+	return nil
+}
+
+// rmdir forgets the directory if it is empty
+func (n *nexus) rmdir(ctx context.Context, dir string) error {
+	items, err := n.list(ctx, dir, true)
+	if err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	n.dirs.Delete(dir)
+	return nil
+}
+
+// purge deletes the files in dir one by one as Nexus can't delete
+// a directory, then forgets the directories in it
+func (n *nexus) purge(ctx context.Context, dir string) error {
+	items, err := n.list(ctx, dir, true)
+	if err != nil {
+		return err
+	}
+	for _, it := range items {
+		if it.isDir {
+			continue
+		}
+		opts := rest.Opts{
+			Method:     "DELETE",
+			Path:       n.contentPath(it.path),
+			NoResponse: true,
+		}
+		err = call(ctx, n.srv, n.pacer, &opts)
+		if err != nil {
+			return err
+		}
+	}
+	n.dirs.Range(func(key, _ interface{}) bool {
+		if p := key.(string); p == dir || strings.HasPrefix(p, dir+"/") || dir == "" {
+			n.dirs.Delete(key)
+		}
+		return true
+	})
+	return nil
+}
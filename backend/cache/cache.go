@@ -1147,6 +1147,16 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	return list.Flush()
 }
 
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// Listings are read through the directory cache so this is a single
+// page.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	return fs.ListPFallback(ctx, f, dir, callback)
+}
+
 // Mkdir makes the directory (container, bucket)
 func (f *Fs) Mkdir(ctx context.Context, dir string) error {
 	fs.Debugf(f, "mkdir '%s'", dir)
@@ -1955,6 +1965,7 @@ var (
 	_ fs.UnWrapper      = (*Fs)(nil)
 	_ fs.Wrapper        = (*Fs)(nil)
 	_ fs.ListRer        = (*Fs)(nil)
+	_ fs.ListPer        = (*Fs)(nil)
 	_ fs.ChangeNotifier = (*Fs)(nil)
 	_ fs.Abouter        = (*Fs)(nil)
 	_ fs.UserInfoer     = (*Fs)(nil)
@@ -0,0 +1,827 @@
+// Package casfs implements a backend which stores files as content
+// addressed chunks with a tree of manifests on any other remote
+package casfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	gohash "hash"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+)
+
+// Layout of the wrapped remote
+const (
+	blobDir     = "blobs"     // chunks of data, named by SHA-256
+	treeDir     = "trees"     // tree objects, named by SHA-256
+	refDir      = "refs"      // names for trees
+	headRef     = "HEAD"      // the ref for the live tree
+	snapshotDir = "snapshots" // refs for snapshots, within refDir
+	treeVersion = 1
+	maxTreeSize = 64 * 1024 * 1024
+	minChunk    = 4 * 1024
+)
+
+// errReadOnly is returned when trying to change a snapshot
+var errReadOnly = errors.New("snapshots are read only")
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "casfs",
+		Description: "Content addressed store with snapshots",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to store the blobs and trees in.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name: "chunk_size",
+			Help: `Average size of the chunks files are split into.
+
+Chunk boundaries are found from the content so data shared between
+files, or between versions of a file, is stored once even if it has
+moved. Chunks are between a quarter and four times this size.
+
+Smaller chunks find more duplicate data but mean more objects on the
+wrapped remote. Changing this stops new files sharing chunks with
+files stored before.
+
+Each transfer buffers up to four chunks in memory.`,
+			Default:  fs.Mebi,
+			Advanced: true,
+		}, {
+			Name: "snapshot",
+			Help: `Name of a snapshot to read instead of the live tree.
+
+Snapshots are made with the snapshot backend command. A remote with
+this set is read only.`,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// The trees are JSON so invalid UTF-8 must be encoded
+			Default: (encoder.Base |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote    string               `config:"remote"`
+	ChunkSize fs.SizeSuffix        `config:"chunk_size"`
+	Snapshot  string               `config:"snapshot"`
+	Enc       encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a content addressed store on a wrapped remote
+type Fs struct {
+	base     fs.Fs
+	name     string
+	root     string
+	opt      Options
+	features *fs.Features // optional features
+	wrapper  fs.Fs
+	st       *store   // the store on base
+	snap     *dirNode // the snapshot being read or nil for the live tree
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point casfs remote at itself - check the value of the remote setting")
+	}
+	if opt.ChunkSize < minChunk {
+		return nil, fmt.Errorf("chunk_size must be at least %v", fs.SizeSuffix(minChunk))
+	}
+	f := &Fs{
+		name: name,
+		root: strings.Trim(rpath, "/"),
+		opt:  opt,
+	}
+	f.base, err = cache.Get(ctx, opt.Remote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", opt.Remote, err)
+	}
+	cache.PinUntilFinalized(f.base, f)
+	f.st, err = getStore(ctx, f.base)
+	if err != nil {
+		return nil, err
+	}
+	if opt.Snapshot != "" {
+		h, err := f.st.readRef(ctx, path.Join(snapshotDir, opt.Snapshot))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read snapshot %q: %w", opt.Snapshot, err)
+		}
+		if h == "" {
+			return nil, fmt.Errorf("snapshot %q not found", opt.Snapshot)
+		}
+		f.snap = &dirNode{hash: h}
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+	}).Fill(ctx, f).WrapsFs(f, f.base)
+
+	if f.root != "" {
+		if _, err := f.NewObject(ctx, ""); err == nil {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	if f.opt.Snapshot != "" {
+		return fmt.Sprintf("casfs snapshot %q root '%s'", f.opt.Snapshot, f.root)
+	}
+	return fmt.Sprintf("casfs root '%s'", f.root)
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.SHA256)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.base
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// key returns the path in the tree for remote
+func (f *Fs) key(remote string) string {
+	return strings.Trim(f.opt.Enc.FromStandardPath(path.Join(f.root, remote)), "/")
+}
+
+// splitKey returns the directory key is in and its name in there
+func splitKey(key string) (dir, leaf string) {
+	i := strings.LastIndex(key, "/")
+	if i < 0 {
+		return "", key
+	}
+	return key[:i], key[i+1:]
+}
+
+// rootDir returns the tree this Fs shows
+func (f *Fs) rootDir() *dirNode {
+	if f.snap != nil {
+		return f.snap
+	}
+	return f.st.root
+}
+
+// view calls fn with the store locked once the tree is loaded along
+// each of keys
+func (f *Fs) view(ctx context.Context, keys []string, fn func(root *dirNode) error) error {
+	root := f.rootDir()
+	return f.st.locked(ctx, root, keys, func() error {
+		return fn(root)
+	})
+}
+
+// change calls fn like view to change the live tree then saves it
+func (f *Fs) change(ctx context.Context, keys []string, fn func(root *dirNode) error) error {
+	if f.snap != nil {
+		return errReadOnly
+	}
+	if err := f.view(ctx, keys, fn); err != nil {
+		return err
+	}
+	return f.st.save(ctx)
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	key := f.key(dir)
+	err = f.view(ctx, []string{key}, func(root *dirNode) error {
+		n, _, _ := walk(root, key)
+		if n == nil || n.dir == nil {
+			return fs.ErrorDirNotFound
+		}
+		for name, child := range n.dir.entries {
+			remote := path.Join(dir, f.opt.Enc.ToStandardName(name))
+			if child.dir != nil {
+				entries = append(entries, fs.NewDir(remote, child.modTime))
+			} else {
+				entries = append(entries, f.newObject(remote, child))
+			}
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// The directory tree is held in memory so this is a single page.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	return fs.ListPFallback(ctx, f, dir, callback)
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (o fs.Object, err error) {
+	key := f.key(remote)
+	err = f.view(ctx, []string{key}, func(root *dirNode) error {
+		n, _, _ := walk(root, key)
+		if n == nil {
+			return fs.ErrorObjectNotFound
+		}
+		if n.dir != nil {
+			return fs.ErrorIsDir
+		}
+		o = f.newObject(remote, n)
+		return nil
+	})
+	return o, err
+}
+
+// storeData splits the data from in into chunks and stores them,
+// returning a node for a file with them in
+func (f *Fs) storeData(ctx context.Context, in io.Reader, src fs.ObjectInfo) (*node, error) {
+	hasher := sha256.New()
+	c := newChunker(io.TeeReader(in, hasher), int(f.opt.ChunkSize))
+	n := &node{
+		modTime:  src.ModTime(ctx),
+		mimeType: fs.MimeType(ctx, src),
+	}
+	for {
+		data, err := c.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		ref, err := f.st.putChunk(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		n.chunks = append(n.chunks, ref)
+		n.size += ref.Size
+	}
+	n.hash = hex.EncodeToString(hasher.Sum(nil))
+	if size := src.Size(); size >= 0 && size != n.size {
+		return nil, fmt.Errorf("corrupted on transfer: sizes differ src %d vs dst %d", size, n.size)
+	}
+	if h, _ := src.Hash(ctx, hash.SHA256); h != "" && h != n.hash {
+		return nil, fmt.Errorf("corrupted on transfer: sha256 hash differ src %q vs dst %q", h, n.hash)
+	}
+	return n, nil
+}
+
+// link puts the file node n at remote, replacing what was there
+func (f *Fs) link(ctx context.Context, remote string, n *node) (*Object, error) {
+	key := f.key(remote)
+	dir, leaf := splitKey(key)
+	err := f.change(ctx, []string{dir}, func(root *dirNode) error {
+		dirs, err := f.st.mkdirs(root, dir, time.Now())
+		if err != nil {
+			return err
+		}
+		entries := dirs[len(dirs)-1].entries
+		if old := entries[leaf]; old != nil && old.dir != nil {
+			return fs.ErrorIsDir
+		}
+		entries[leaf] = n
+		f.st.changed(dirs)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, n), nil
+}
+
+// put stores the data from in and links it at src.Remote()
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo) (*Object, error) {
+	if f.snap != nil {
+		return nil, errReadOnly
+	}
+	n, err := f.storeData(ctx, in, src)
+	if err != nil {
+		return nil, err
+	}
+	return f.link(ctx, src.Remote(), n)
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	key := f.key(dir)
+	return f.change(ctx, []string{key}, func(root *dirNode) error {
+		_, err := f.st.mkdirs(root, key, time.Now())
+		return err
+	})
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	key := f.key(dir)
+	return f.change(ctx, []string{key}, func(root *dirNode) error {
+		n, dirs, _ := walk(root, key)
+		if n == nil || n.dir == nil {
+			return fs.ErrorDirNotFound
+		}
+		if len(n.dir.entries) > 0 {
+			return fs.ErrorDirectoryNotEmpty
+		}
+		if key == "" {
+			return nil
+		}
+		_, leaf := splitKey(key)
+		delete(dirs[len(dirs)-1].entries, leaf)
+		f.st.changed(dirs)
+		return nil
+	})
+}
+
+// Purge deletes all the files and directories in dir
+//
+// This only changes the tree - the data is left for gc to remove
+// when it isn't in a snapshot.
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	key := f.key(dir)
+	return f.change(ctx, []string{key}, func(root *dirNode) error {
+		n, dirs, _ := walk(root, key)
+		if n == nil || n.dir == nil {
+			return fs.ErrorDirNotFound
+		}
+		if key == "" {
+			root.entries = map[string]*node{}
+			f.st.changed([]*dirNode{root})
+			return nil
+		}
+		_, leaf := splitKey(key)
+		delete(dirs[len(dirs)-1].entries, leaf)
+		f.st.changed(dirs)
+		return nil
+	})
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This only changes the tree as the data is shared. The source may
+// be in a snapshot, which restores the file.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.st != f.st {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	return f.link(ctx, remote, srcObj.node)
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This only changes the tree as the data is shared.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.st != f.st || srcObj.fs.snap != nil {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	srcKey := srcObj.fs.key(srcObj.remote)
+	dstKey := f.key(remote)
+	dstDir, dstLeaf := splitKey(dstKey)
+	var n *node
+	err := f.change(ctx, []string{srcKey, dstDir}, func(root *dirNode) error {
+		var srcDirs []*dirNode
+		n, srcDirs, _ = walk(root, srcKey)
+		if n == nil || n.dir != nil {
+			return fs.ErrorObjectNotFound
+		}
+		dstDirs, err := f.st.mkdirs(root, dstDir, time.Now())
+		if err != nil {
+			return err
+		}
+		if old := dstDirs[len(dstDirs)-1].entries[dstLeaf]; old != nil && old.dir != nil {
+			return fs.ErrorIsDir
+		}
+		_, srcLeaf := splitKey(srcKey)
+		delete(srcDirs[len(srcDirs)-1].entries, srcLeaf)
+		dstDirs[len(dstDirs)-1].entries[dstLeaf] = n
+		f.st.changed(srcDirs)
+		f.st.changed(dstDirs)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, n), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// This only changes the tree as the data is shared.
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok || srcFs.st != f.st || srcFs.snap != nil {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	srcKey := srcFs.key(srcRemote)
+	dstKey := f.key(dstRemote)
+	if srcKey == dstKey {
+		return fs.ErrorDirExists
+	}
+	if srcKey == "" || strings.HasPrefix(dstKey, srcKey+"/") {
+		return fs.ErrorCantDirMove
+	}
+	dstDir, dstLeaf := splitKey(dstKey)
+	return f.change(ctx, []string{srcKey, dstKey}, func(root *dirNode) error {
+		n, srcDirs, _ := walk(root, srcKey)
+		if n == nil || n.dir == nil {
+			return fs.ErrorDirNotFound
+		}
+		if old, _, _ := walk(root, dstKey); old != nil {
+			return fs.ErrorDirExists
+		}
+		dstDirs, err := f.st.mkdirs(root, dstDir, time.Now())
+		if err != nil {
+			return err
+		}
+		_, srcLeaf := splitKey(srcKey)
+		delete(srcDirs[len(srcDirs)-1].entries, srcLeaf)
+		dstDirs[len(dstDirs)-1].entries[dstLeaf] = n
+		f.st.changed(srcDirs)
+		f.st.changed(dstDirs)
+		return nil
+	})
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.base.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	do := f.base.Features().Shutdown
+	if do == nil {
+		return nil
+	}
+	return do(ctx)
+}
+
+// Object describes a file stored as chunks
+type Object struct {
+	fs     *Fs
+	remote string
+	node   *node
+}
+
+// newObject makes an Object for remote described by n
+func (f *Fs) newObject(remote string, n *node) *Object {
+	return &Object{
+		fs:     f,
+		remote: remote,
+		node:   n,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the modification time of the file
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.node.modTime
+}
+
+// Size returns the size of the file
+func (o *Object) Size() int64 {
+	return o.node.size
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Hash returns the SHA-256 of the file
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if ht != hash.SHA256 {
+		return "", hash.ErrUnsupported
+	}
+	return o.node.hash, nil
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	return o.node.mimeType
+}
+
+// SetModTime sets the modification time of the file
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	key := o.fs.key(o.remote)
+	return o.fs.change(ctx, []string{key}, func(root *dirNode) error {
+		n, dirs, _ := walk(root, key)
+		if n == nil || n.dir != nil {
+			return fs.ErrorObjectNotFound
+		}
+		newNode := *n
+		newNode.modTime = modTime
+		_, leaf := splitKey(key)
+		dirs[len(dirs)-1].entries[leaf] = &newNode
+		o.fs.st.changed(dirs)
+		o.node = &newNode
+		return nil
+	})
+}
+
+// Open opens the file for read.  Call Close() on the returned io.ReadCloser
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	size := o.node.size
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if limit < 0 || offset+limit > size {
+		limit = size - offset
+	}
+	return newChunkReader(ctx, o.fs.st, o.node.chunks, offset, limit), nil
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	newObj, err := o.fs.put(ctx, in, src)
+	if err != nil {
+		return err
+	}
+	o.node = newObj.node
+	return nil
+}
+
+// Remove an object
+//
+// This only changes the tree - the data is left for gc to remove
+// when it isn't in a snapshot.
+func (o *Object) Remove(ctx context.Context) error {
+	key := o.fs.key(o.remote)
+	return o.fs.change(ctx, []string{key}, func(root *dirNode) error {
+		n, dirs, _ := walk(root, key)
+		if n == nil || n.dir != nil {
+			return fs.ErrorObjectNotFound
+		}
+		_, leaf := splitKey(key)
+		delete(dirs[len(dirs)-1].entries, leaf)
+		o.fs.st.changed(dirs)
+		return nil
+	})
+}
+
+// chunkReader reads a range of a file from its chunks
+type chunkReader struct {
+	ctx    context.Context
+	st     *store
+	chunks []chunkRef
+	i      int           // the chunk being read
+	skip   int64         // where to start reading chunk i
+	limit  int64         // bytes left to read
+	in     io.ReadCloser // the open blob for chunk i or nil
+	read   int64         // bytes read from chunk i
+	hasher gohash.Hash   // hashes chunk i if it is being read from the start
+}
+
+// newChunkReader returns a reader for limit bytes from offset of the
+// file made of chunks
+func newChunkReader(ctx context.Context, st *store, chunks []chunkRef, offset, limit int64) *chunkReader {
+	r := &chunkReader{
+		ctx:    ctx,
+		st:     st,
+		chunks: chunks,
+		limit:  limit,
+	}
+	for r.i < len(chunks) && offset >= chunks[r.i].Size {
+		offset -= chunks[r.i].Size
+		r.i++
+	}
+	r.skip = offset
+	return r
+}
+
+// open opens the blob for the current chunk
+func (r *chunkReader) open() error {
+	c := r.chunks[r.i]
+	blob, err := r.st.base.NewObject(r.ctx, blobPath(c.Hash))
+	if err != nil {
+		return fmt.Errorf("failed to find blob %s: %w", c.Hash, err)
+	}
+	var options []fs.OpenOption
+	r.hasher = nil
+	if r.skip > 0 {
+		options = append(options, &fs.SeekOption{Offset: r.skip})
+	} else {
+		r.hasher = sha256.New()
+	}
+	r.in, err = blob.Open(r.ctx, options...)
+	if err != nil {
+		return fmt.Errorf("failed to open blob %s: %w", c.Hash, err)
+	}
+	r.read = r.skip
+	return nil
+}
+
+// finish closes the blob for the current chunk, checking it was
+// intact if all of it was read
+func (r *chunkReader) finish() error {
+	c := r.chunks[r.i]
+	err := r.in.Close()
+	r.in = nil
+	r.i++
+	r.skip = 0
+	if err != nil {
+		return err
+	}
+	if r.read != c.Size {
+		return fmt.Errorf("blob %s is corrupted: read %d bytes but expecting %d", c.Hash, r.read, c.Size)
+	}
+	if r.hasher != nil {
+		if got := hex.EncodeToString(r.hasher.Sum(nil)); got != c.Hash {
+			return fmt.Errorf("blob %s is corrupted: its hash is %s", c.Hash, got)
+		}
+	}
+	return nil
+}
+
+// Read reads up to len(p) bytes into p
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	for r.limit > 0 {
+		if r.in == nil {
+			if r.i >= len(r.chunks) {
+				return 0, io.ErrUnexpectedEOF
+			}
+			if err := r.open(); err != nil {
+				return 0, err
+			}
+		}
+		if int64(len(p)) > r.limit {
+			p = p[:r.limit]
+		}
+		n, err = r.in.Read(p)
+		if r.hasher != nil {
+			_, _ = r.hasher.Write(p[:n])
+		}
+		r.read += int64(n)
+		r.limit -= int64(n)
+		if err == io.EOF {
+			if err := r.finish(); err != nil {
+				return n, err
+			}
+			if n == 0 {
+				continue
+			}
+			return n, nil
+		}
+		return n, err
+	}
+	return 0, io.EOF
+}
+
+// Close closes the blob being read if any
+func (r *chunkReader) Close() error {
+	if r.in == nil {
+		return nil
+	}
+	err := r.in.Close()
+	r.in = nil
+	return err
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.ListPer     = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.Abouter     = (*Fs)(nil)
+	_ fs.Shutdowner  = (*Fs)(nil)
+	_ fs.Commander   = (*Fs)(nil)
+	_ fs.UnWrapper   = (*Fs)(nil)
+	_ fs.Wrapper     = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+	_ fs.MimeTyper   = (*Object)(nil)
+)
@@ -0,0 +1,249 @@
+package casfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs returns an empty casfs remote with small chunks so the
+// test files split into several, and the directory it stores them in
+// so tests can open other views of the store and get at its blobs
+func newTestFs(t *testing.T) (*Fs, string) {
+	f, tempRoot := fstest.NewWrappingFs(t, "casfs", "chunk_size=4k")
+	return f.(*Fs), tempRoot
+}
+
+func read(t *testing.T, o fs.Object) []byte {
+	in, err := o.Open(context.Background())
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, in.Close())
+	require.NoError(t, err)
+	return data
+}
+
+// stored returns the number of objects stored in dir on the base
+func stored(t *testing.T, f *Fs, dir string) int {
+	objs, err := f.listStored(context.Background(), dir)
+	require.NoError(t, err)
+	return len(objs)
+}
+
+// randomData returns n bytes which are the same each time
+func randomData(n int) []byte {
+	data := make([]byte, n)
+	_, _ = rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func chunks(t *testing.T, data []byte, avg int) (out [][]byte) {
+	c := newChunker(bytes.NewReader(data), avg)
+	for {
+		chunk, err := c.next()
+		if err == io.EOF {
+			return out
+		}
+		require.NoError(t, err)
+		out = append(out, append([]byte(nil), chunk...))
+	}
+}
+
+func TestChunker(t *testing.T) {
+	const avg = 16 * 1024
+	data := randomData(1024 * 1024)
+	cs := chunks(t, data, avg)
+	assert.True(t, len(cs) > 1024*1024/avg/2 && len(cs) < 1024*1024/avg*2, "got %d chunks", len(cs))
+	for i, c := range cs {
+		assert.LessOrEqual(t, len(c), 4*avg)
+		if i < len(cs)-1 {
+			assert.GreaterOrEqual(t, len(c), avg/4)
+		}
+	}
+	assert.Equal(t, data, bytes.Join(cs, nil))
+
+	// Inserting data only changes the chunks near it
+	shifted := append([]byte("inserted at the start"), data...)
+	seen := map[string]bool{}
+	for _, c := range cs {
+		seen[sha256Hex(c)] = true
+	}
+	same := 0
+	for _, c := range chunks(t, shifted, avg) {
+		if seen[sha256Hex(c)] {
+			same++
+		}
+	}
+	assert.GreaterOrEqual(t, same, len(cs)-2)
+
+	assert.Nil(t, chunks(t, nil, avg))
+}
+
+func TestSharedChunks(t *testing.T) {
+	ctx := context.Background()
+	f, _ := newTestFs(t)
+	data := randomData(256 * 1024)
+	o1 := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "a.bin", ModTime: time.Now()}, string(data), true)
+	n := stored(t, f, blobDir)
+	assert.Equal(t, len(o1.(*Object).node.chunks), n)
+
+	// The same data elsewhere stores nothing new
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/b.bin", ModTime: time.Now()}, string(data), true)
+	assert.Equal(t, n, stored(t, f, blobDir))
+
+	// Changed data only stores the changed chunks
+	changed := append([]byte("new header"), data...)
+	o3 := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "c.bin", ModTime: time.Now()}, string(changed), true)
+	assert.LessOrEqual(t, stored(t, f, blobDir), n+2)
+	assert.Equal(t, changed, read(t, o3))
+
+	// Reading part of a file
+	in, err := o3.Open(ctx, &fs.RangeOption{Start: 100000, End: 200000})
+	require.NoError(t, err)
+	part, err := io.ReadAll(in)
+	require.NoError(t, in.Close())
+	require.NoError(t, err)
+	assert.Equal(t, changed[100000:200001], part)
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	f, tempRoot := newTestFs(t)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/a.txt", ModTime: time.Now()}, "one", true)
+	_, err := f.Command(ctx, "snapshot", []string{"s1"}, nil)
+	require.NoError(t, err)
+	_, err = f.Command(ctx, "snapshot", []string{"s1"}, nil)
+	assert.EqualError(t, err, `snapshot "s1" already exists`)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/a.txt", ModTime: time.Now()}, "two", true)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "b.txt", ModTime: time.Now()}, "three", true)
+
+	out, err := f.Command(ctx, "snapshots", nil, nil)
+	require.NoError(t, err)
+	snaps := out.([]snapshotInfo)
+	require.Equal(t, 1, len(snaps))
+	assert.Equal(t, "s1", snaps[0].Name)
+
+	// The snapshot shows the tree when it was made
+	sf := fstest.NewFs(t, fmt.Sprintf(`:casfs,remote="%s",chunk_size=4k,snapshot=s1:`, tempRoot))
+	fstest.CheckListingWithPrecision(t, sf, []fstest.Item{
+		fstest.NewItem("dir/a.txt", "one", time.Now()),
+	}, []string{"dir"}, fs.ModTimeNotSupported)
+	snapObj, err := sf.NewObject(ctx, "dir/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), read(t, snapObj))
+
+	// and can't be changed
+	src := object.NewStaticObjectInfo("c.txt", time.Now(), 1, true, nil, nil)
+	_, err = sf.Put(ctx, strings.NewReader("x"), src)
+	assert.Equal(t, errReadOnly, err)
+	assert.Equal(t, errReadOnly, snapObj.Remove(ctx))
+
+	// Files can be restored from it
+	restored, err := f.Copy(ctx, snapObj, "dir/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), read(t, restored))
+
+	_, err = f.Command(ctx, "forget", []string{"s1"}, nil)
+	require.NoError(t, err)
+	_, err = fs.NewFs(ctx, fmt.Sprintf(`:casfs,remote="%s",chunk_size=4k,snapshot=s1:`, tempRoot))
+	assert.EqualError(t, err, `snapshot "s1" not found`)
+}
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+	f, tempRoot := newTestFs(t)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/sub/a.txt", ModTime: time.Now()}, "hello", true)
+	require.NoError(t, f.Mkdir(ctx, "empty"))
+
+	// A new store reads the tree back from the remote
+	storesMu.Lock()
+	stores = map[string]*store{}
+	storesMu.Unlock()
+	f2 := fstest.NewFs(t, fmt.Sprintf(`:casfs,remote="%s",chunk_size=4k:`, tempRoot))
+	assert.NotEqual(t, f.st, f2.(*Fs).st)
+	fstest.CheckListingWithPrecision(t, f2, []fstest.Item{
+		fstest.NewItem("dir/sub/a.txt", "hello", time.Now()),
+	}, []string{"dir", "dir/sub", "empty"}, fs.ModTimeNotSupported)
+
+	// and won't overwrite changes made by the first one
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "b.txt", ModTime: time.Now()}, "b", true)
+	src := object.NewStaticObjectInfo("c.txt", time.Now(), 1, true, nil, nil)
+	_, err := f2.Put(ctx, strings.NewReader("c"), src)
+	assert.Error(t, err)
+}
+
+func TestVerifyAndGC(t *testing.T) {
+	ctx := context.Background()
+	f, tempRoot := newTestFs(t)
+	keep := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "keep.bin", ModTime: time.Now()}, string(randomData(50*1024)), true)
+	gone := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "gone.bin", ModTime: time.Now()}, "only in the snapshot", true)
+	_, err := f.Command(ctx, "snapshot", []string{"s1"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, gone.Remove(ctx))
+
+	out, err := f.Command(ctx, "verify", nil, map[string]string{"download": ""})
+	require.NoError(t, err)
+	stats := out.(*verifyStats)
+	assert.Equal(t, 2, stats.Roots)
+	assert.Equal(t, 3, stats.Files)
+	assert.Equal(t, stats.Blobs, stats.Hashed)
+
+	// Nothing is removed while the snapshot needs it
+	blobs := stored(t, f, blobDir)
+	out, err = f.Command(ctx, "gc", nil, map[string]string{"min-age": "0"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, out.(*gcStats).RemovedBlobs)
+	assert.Equal(t, blobs, stored(t, f, blobDir))
+
+	// Only the data of the removed file goes with the snapshot
+	_, err = f.Command(ctx, "forget", []string{"s1"}, nil)
+	require.NoError(t, err)
+	ctxDry, ci := fs.AddConfig(ctx)
+	ci.DryRun = true
+	out, err = f.Command(ctxDry, "gc", nil, map[string]string{"min-age": "0"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, out.(*gcStats).RemovedBlobs)
+	assert.Equal(t, blobs, stored(t, f, blobDir))
+	out, err = f.Command(ctx, "gc", nil, map[string]string{"min-age": "0"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, out.(*gcStats).RemovedBlobs)
+	assert.NotZero(t, out.(*gcStats).RemovedTrees)
+	assert.Equal(t, blobs-1, stored(t, f, blobDir))
+	out, err = f.Command(ctx, "gc", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, out.(*gcStats).RemovedBlobs)
+
+	// Corrupting a blob is found by verify and when reading
+	c := keep.(*Object).node.chunks[0]
+	blobFile := filepath.Join(tempRoot, blobDir, c.Hash[:2], c.Hash)
+	require.NoError(t, os.WriteFile(blobFile, make([]byte, c.Size), 0666))
+	_, err = f.Command(ctx, "verify", nil, nil)
+	require.NoError(t, err)
+	_, err = f.Command(ctx, "verify", nil, map[string]string{"download": ""})
+	assert.EqualError(t, err, "found 1 problems")
+	in, err := keep.Open(ctx)
+	require.NoError(t, err)
+	_, err = io.ReadAll(in)
+	_ = in.Close()
+	assert.ErrorContains(t, err, "is corrupted")
+
+	// and so is a missing blob
+	require.NoError(t, os.Remove(blobFile))
+	_, err = f.Command(ctx, "verify", nil, nil)
+	assert.EqualError(t, err, "found 1 problems")
+}
@@ -0,0 +1,67 @@
+// Test Casfs filesystem interface
+package casfs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/backend/casfs"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+var unimplementableFsMethods = []string{
+	"OpenWriterAt",
+	"MergeDirs",
+	"DirCacheFlush",
+	"PutUnchecked",
+	"CleanUp",
+	"ListR",
+	"PublicLink",
+	"ChangeNotify",
+	"UserInfo",
+	"Disconnect",
+}
+
+var unimplementableObjectMethods = []string{
+	"ID",
+	"GetTier",
+	"SetTier",
+	"UnWrap",
+	"Metadata",
+}
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:                   *fstest.RemoteName,
+		NilObject:                    (*casfs.Object)(nil),
+		UnimplementableFsMethods:     unimplementableFsMethods,
+		UnimplementableObjectMethods: unimplementableObjectMethods,
+	})
+}
+
+// TestLocal runs integration tests against a local directory
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestCasfs"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:                   name + ":",
+		NilObject:                    (*casfs.Object)(nil),
+		UnimplementableFsMethods:     unimplementableFsMethods,
+		UnimplementableObjectMethods: unimplementableObjectMethods,
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "casfs"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-casfs-test")},
+			{Name: name, Key: "chunk_size", Value: "4k"},
+		},
+		QuickTestOK: true,
+	})
+}
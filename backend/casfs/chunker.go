@@ -0,0 +1,80 @@
+package casfs
+
+import (
+	"bufio"
+	"io"
+	"math/bits"
+)
+
+// gear is the table of random numbers for the rolling hash used to
+// find chunk boundaries
+//
+// It must never change or files stored before the change won't share
+// chunks with files stored after it.
+var gear [256]uint64
+
+func init() {
+	// splitmix64 from a fixed seed
+	x := uint64(0x636173667321)
+	for i := range gear {
+		x += 0x9E3779B97F4A7C15
+		z := x
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// chunker splits a stream into content defined chunks
+//
+// A boundary is put where the gear hash of the last bytes has enough
+// low zero bits, so inserting or removing data in a file only changes
+// the chunks near the change and the rest are stored once.
+type chunker struct {
+	r    *bufio.Reader
+	min  int    // smallest chunk unless at the end
+	max  int    // largest chunk
+	mask uint64 // a boundary is where the hash has these bits clear
+	buf  []byte
+}
+
+// newChunker makes a chunker reading from in whose chunks are on
+// average about avg bytes long
+func newChunker(in io.Reader, avg int) *chunker {
+	min := avg / 4
+	if min < 1 {
+		min = 1
+	}
+	return &chunker{
+		r:    bufio.NewReaderSize(in, 64*1024),
+		min:  min,
+		max:  avg * 4,
+		mask: 1<<uint(bits.Len(uint(avg-min))-1) - 1,
+		buf:  make([]byte, 0, avg*4),
+	}
+}
+
+// next returns the next chunk, which is only valid until the next
+// call, or io.EOF at the end of the stream
+func (c *chunker) next() ([]byte, error) {
+	c.buf = c.buf[:0]
+	var h uint64
+	for len(c.buf) < c.max {
+		b, err := c.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.buf = append(c.buf, b)
+		h = h<<1 + gear[b]
+		if len(c.buf) >= c.min && h&c.mask == 0 {
+			break
+		}
+	}
+	if len(c.buf) == 0 {
+		return nil, io.EOF
+	}
+	return c.buf, nil
+}
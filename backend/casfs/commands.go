@@ -0,0 +1,461 @@
+package casfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"golang.org/x/sync/errgroup"
+)
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "snapshot",
+	Short: "Make a snapshot of the store.",
+	Long: `This records the current tree of the whole store, not just the
+directory the remote points at, under the name given. It costs one
+small object as the data and the trees are shared with the live tree.
+
+Usage Example:
+
+    rclone backend snapshot casfs: 2022-10-17
+
+Read a snapshot by setting the snapshot option, e.g.
+
+    rclone ls casfs,snapshot=2022-10-17:
+`,
+}, {
+	Name:  "snapshots",
+	Short: "List the snapshots.",
+	Long: `This lists the name, tree hash and time of each snapshot.
+
+Usage Example:
+
+    rclone backend snapshots casfs:
+`,
+}, {
+	Name:  "forget",
+	Short: "Remove a snapshot.",
+	Long: `This removes the snapshot with the name given. The data only in that
+snapshot is removed by the next gc.
+
+Usage Example:
+
+    rclone backend forget casfs: 2022-10-17
+`,
+}, {
+	Name:  "verify",
+	Short: "Check the store is intact.",
+	Long: `This reads every tree reachable from the live tree and the snapshots,
+checking its hash, and checks each blob they need is there with the
+right size. With the download option each blob is read and its hash
+checked too.
+
+Usage Examples:
+
+    rclone backend verify casfs:
+    rclone backend verify casfs: -o download
+
+It returns a summary if the store is intact, otherwise it logs each
+problem and returns an error.
+`,
+	Opts: map[string]string{
+		"download": "Read every blob and check its hash",
+	},
+}, {
+	Name:  "gc",
+	Short: "Remove blobs and trees which aren't needed any more.",
+	Long: `This removes blobs and trees which can't be reached from the live
+tree or any of the snapshots. Objects younger than min-age are kept
+as they may be part of a change still being made.
+
+Usage Examples:
+
+    rclone backend gc casfs:
+    rclone backend gc casfs: --dry-run
+    rclone backend gc casfs: -o min-age=10m
+
+It returns a summary of what was done.
+`,
+	Opts: map[string]string{
+		"min-age": "Only remove objects older than this (default 1h)",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "snapshot":
+		if len(arg) != 1 {
+			return nil, errors.New("need exactly one argument - the name of the snapshot")
+		}
+		return f.snapshot(ctx, arg[0])
+	case "snapshots":
+		return f.snapshots(ctx)
+	case "forget":
+		if len(arg) != 1 {
+			return nil, errors.New("need exactly one argument - the name of the snapshot")
+		}
+		return nil, f.forget(ctx, arg[0])
+	case "verify":
+		_, download := opt["download"]
+		return f.verify(ctx, download)
+	case "gc":
+		minAge := time.Hour
+		if s, ok := opt["min-age"]; ok {
+			d, err := fs.ParseDuration(s)
+			if err != nil {
+				return nil, fmt.Errorf("bad min-age: %w", err)
+			}
+			minAge = d
+		}
+		return f.gc(ctx, minAge)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// snapshotInfo describes a snapshot
+type snapshotInfo struct {
+	Name string    `json:"name"`
+	Tree string    `json:"tree"`
+	Time time.Time `json:"time"`
+}
+
+// checkSnapshotName returns an error if name can't be a snapshot
+func checkSnapshotName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("bad snapshot name %q", name)
+	}
+	return nil
+}
+
+// snapshot saves the live tree and records it as the snapshot name
+func (f *Fs) snapshot(ctx context.Context, name string) (*snapshotInfo, error) {
+	if err := checkSnapshotName(name); err != nil {
+		return nil, err
+	}
+	ref := path.Join(snapshotDir, name)
+	if h, err := f.st.readRef(ctx, ref); err != nil {
+		return nil, err
+	} else if h != "" {
+		return nil, fmt.Errorf("snapshot %q already exists", name)
+	}
+	if err := f.st.save(ctx); err != nil {
+		return nil, err
+	}
+	f.st.saveMu.Lock()
+	h := f.st.head
+	f.st.saveMu.Unlock()
+	if h == "" {
+		return nil, errors.New("nothing to snapshot as the store is empty")
+	}
+	if err := f.st.writeRef(ctx, ref, h); err != nil {
+		return nil, fmt.Errorf("couldn't write snapshot %q: %w", name, err)
+	}
+	return &snapshotInfo{Name: name, Tree: h, Time: time.Now()}, nil
+}
+
+// snapshots lists the snapshots in name order
+func (f *Fs) snapshots(ctx context.Context) ([]snapshotInfo, error) {
+	entries, err := f.base.List(ctx, path.Join(refDir, snapshotDir))
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		return []snapshotInfo{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	infos := []snapshotInfo{}
+	for _, entry := range entries {
+		o, ok := entry.(fs.Object)
+		if !ok {
+			continue
+		}
+		name := path.Base(o.Remote())
+		h, err := f.st.readRef(ctx, path.Join(snapshotDir, name))
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, snapshotInfo{Name: name, Tree: h, Time: o.ModTime(ctx)})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+// forget removes the snapshot name
+func (f *Fs) forget(ctx context.Context, name string) error {
+	if err := checkSnapshotName(name); err != nil {
+		return err
+	}
+	o, err := f.base.NewObject(ctx, refPath(path.Join(snapshotDir, name)))
+	if errors.Is(err, fs.ErrorObjectNotFound) || errors.Is(err, fs.ErrorDirNotFound) {
+		return fmt.Errorf("snapshot %q not found", name)
+	} else if err != nil {
+		return err
+	}
+	if operations.SkipDestructive(ctx, o, "forget snapshot") {
+		return nil
+	}
+	return o.Remove(ctx)
+}
+
+// roots saves the live tree then returns the hashes of it and of all
+// the snapshots
+func (f *Fs) roots(ctx context.Context) ([]string, error) {
+	if err := f.st.save(ctx); err != nil {
+		return nil, err
+	}
+	snaps, err := f.snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.st.saveMu.Lock()
+	head := f.st.head
+	f.st.saveMu.Unlock()
+	var roots []string
+	if head != "" {
+		roots = append(roots, head)
+	}
+	for _, snap := range snaps {
+		roots = append(roots, snap.Tree)
+	}
+	return roots, nil
+}
+
+// listStored returns the objects on the base in dir keyed by name
+func (f *Fs) listStored(ctx context.Context, dir string) (map[string]fs.Object, error) {
+	objs := map[string]fs.Object{}
+	err := operations.ListFn(ctx, f.base, func(o fs.Object) {
+		if strings.HasPrefix(o.Remote(), dir+"/") {
+			objs[path.Base(o.Remote())] = o
+		}
+	})
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		err = nil
+	}
+	return objs, err
+}
+
+// verifyStats is the result of the verify command
+type verifyStats struct {
+	Roots  int   `json:"roots"`
+	Trees  int   `json:"trees"`
+	Files  int   `json:"files"`
+	Blobs  int   `json:"blobs"`
+	Bytes  int64 `json:"bytes"`
+	Hashed int   `json:"hashed"`
+}
+
+// verify checks the trees and blobs reachable from the roots
+func (f *Fs) verify(ctx context.Context, download bool) (*verifyStats, error) {
+	roots, err := f.roots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := verifyStats{Roots: len(roots)}
+	problems := 0
+	problem := func(format string, args ...interface{}) {
+		problems++
+		fs.Errorf(f, format, args...)
+	}
+
+	// Read all the trees checking their hashes
+	sizes := map[string]int64{}
+	seen := map[string]struct{}{}
+	for _, root := range roots {
+		err := f.st.forEachTree(ctx, root, seen, func(h string, t *treeObject, err error) error {
+			if err != nil {
+				problem("%v", err)
+				return nil
+			}
+			stats.Trees++
+			for _, e := range t.Entries {
+				if e.Type != entryFile {
+					continue
+				}
+				stats.Files++
+				for _, c := range e.Chunks {
+					sizes[c.Hash] = c.Size
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Check the blobs are there
+	blobs, err := f.listStored(ctx, blobDir)
+	if err != nil {
+		return nil, err
+	}
+	var check []fs.Object
+	for h, size := range sizes {
+		stats.Blobs++
+		stats.Bytes += size
+		o := blobs[h]
+		switch {
+		case o == nil:
+			problem("Blob %s is missing", h)
+		case o.Size() != size:
+			problem("Blob %s is corrupted: its size is %d but expecting %d", h, o.Size(), size)
+		default:
+			check = append(check, o)
+		}
+	}
+
+	// Check the contents of the blobs
+	if download {
+		var mu sync.Mutex
+		g, gCtx := errgroup.WithContext(ctx)
+		g.SetLimit(fs.GetConfig(ctx).Checkers)
+		for _, o := range check {
+			o := o
+			g.Go(func() error {
+				got, err := hashObject(gCtx, o)
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				stats.Hashed++
+				if h := path.Base(o.Remote()); got != h {
+					problem("Blob %s is corrupted: its hash is %s", h, got)
+				}
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+	}
+
+	if problems > 0 {
+		return nil, fmt.Errorf("found %d problems", problems)
+	}
+	return &stats, nil
+}
+
+// hashObject returns the SHA-256 of the contents of o
+func hashObject(ctx context.Context, o fs.Object) (string, error) {
+	in, err := o.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, in)
+	_ = in.Close()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// gcStats is the result of the gc command
+type gcStats struct {
+	Trees        int   `json:"trees"`
+	Blobs        int   `json:"blobs"`
+	RemovedTrees int   `json:"removedTrees"`
+	RemovedBlobs int   `json:"removedBlobs"`
+	FreedBytes   int64 `json:"freedBytes"`
+}
+
+// gc removes the blobs and trees older than minAge which can't be
+// reached from the roots
+func (f *Fs) gc(ctx context.Context, minAge time.Duration) (*gcStats, error) {
+	roots, err := f.roots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find what is in use - any error stops gc as it can't know
+	// what the unreadable trees need
+	usedBlobs := map[string]struct{}{}
+	usedTrees := map[string]struct{}{}
+	for _, root := range roots {
+		err := f.st.forEachTree(ctx, root, usedTrees, func(h string, t *treeObject, err error) error {
+			if err != nil {
+				return err
+			}
+			for _, e := range t.Entries {
+				for _, c := range e.Chunks {
+					usedBlobs[c.Hash] = struct{}{}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("gc can't continue - run verify: %w", err)
+		}
+	}
+
+	var stats gcStats
+	remove := func(o fs.Object, what string) (bool, error) {
+		if time.Since(o.ModTime(ctx)) < minAge {
+			return false, nil
+		}
+		if operations.SkipDestructive(ctx, o, "remove unused "+what) {
+			return true, nil
+		}
+		return true, o.Remove(ctx)
+	}
+	trees, err := f.listStored(ctx, treeDir)
+	if err != nil {
+		return nil, err
+	}
+	for h, o := range trees {
+		stats.Trees++
+		if _, ok := usedTrees[h]; ok {
+			continue
+		}
+		removed, err := remove(o, "tree")
+		if err != nil {
+			return nil, err
+		}
+		if removed {
+			stats.RemovedTrees++
+			stats.FreedBytes += o.Size()
+		}
+	}
+	blobs, err := f.listStored(ctx, blobDir)
+	if err != nil {
+		return nil, err
+	}
+	for h, o := range blobs {
+		stats.Blobs++
+		if _, ok := usedBlobs[h]; ok {
+			continue
+		}
+		f.st.blobsMu.Lock()
+		delete(f.st.blobs, h)
+		f.st.blobsMu.Unlock()
+		removed, err := remove(o, "blob")
+		if err != nil {
+			return nil, err
+		}
+		if removed {
+			stats.RemovedBlobs++
+			stats.FreedBytes += o.Size()
+		}
+	}
+	return &stats, nil
+}
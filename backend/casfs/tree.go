@@ -0,0 +1,508 @@
+package casfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+)
+
+// treeObject is what is stored in the trees directory for each
+// directory
+//
+// Tree objects are named by the SHA-256 of their JSON so they never
+// change. Changing a file makes a new tree object for its directory
+// and for each directory above it up to a new root.
+type treeObject struct {
+	Version int         `json:"ver"`
+	Entries []treeEntry `json:"entries"`
+}
+
+// treeEntry is a file or a directory in a tree object
+type treeEntry struct {
+	Name     string     `json:"name"`
+	Type     string     `json:"type"` // entryFile or entryDir
+	ModTime  time.Time  `json:"mtime"`
+	Tree     string     `json:"tree,omitempty"`   // directories: hash of the tree object
+	Size     int64      `json:"size,omitempty"`   // files: size of the contents
+	Hash     string     `json:"sha256,omitempty"` // files: SHA-256 of the contents
+	MimeType string     `json:"mime_type,omitempty"`
+	Chunks   []chunkRef `json:"chunks,omitempty"` // files: the blobs with the contents in order
+}
+
+// Types of treeEntry
+const (
+	entryFile = "file"
+	entryDir  = "dir"
+)
+
+// chunkRef is a part of a file stored as a blob
+type chunkRef struct {
+	Hash string `json:"sha256"`
+	Size int64  `json:"size"`
+}
+
+// node is a file or a directory in memory
+//
+// Nodes for files are never changed once made, so Objects can keep
+// them, a change to a file replaces its node.
+type node struct {
+	modTime  time.Time
+	dir      *dirNode // set for directories
+	size     int64
+	hash     string
+	mimeType string
+	chunks   []chunkRef
+}
+
+// dirNode is the contents of a directory in memory
+type dirNode struct {
+	hash    string           // hash of the tree object or "" if changed since it was made
+	entries map[string]*node // nil until the tree object is read
+}
+
+// store is the content addressed store on a wrapped remote
+//
+// All the Fs using the same wrapped remote share one of these so they
+// see each other's changes.
+type store struct {
+	base fs.Fs
+
+	mu    sync.Mutex        // protects the following
+	root  *dirNode          // the live tree
+	gen   int64             // incremented on each change to root
+	trees map[string][]byte // tree objects made but not written yet
+
+	saveMu   sync.Mutex // serialises saving the tree and protects the following
+	savedGen int64      // the value of gen when the tree was last saved
+	head     string     // what HEAD was when last read or written
+
+	blobsMu sync.Mutex
+	blobs   map[string]struct{} // blobs known to be stored
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]*store{} // loaded stores by wrapped remote
+)
+
+// getStore returns the store on base, reading HEAD if it hasn't been
+// read already
+func getStore(ctx context.Context, base fs.Fs) (*store, error) {
+	key := fs.ConfigString(base)
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if s, ok := stores[key]; ok {
+		return s, nil
+	}
+	s := &store{
+		base:  base,
+		trees: map[string][]byte{},
+		blobs: map[string]struct{}{},
+	}
+	head, err := s.readRef(ctx, headRef)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read %s: %w", headRef, err)
+	}
+	s.head = head
+	s.root = &dirNode{hash: head}
+	if head == "" {
+		fs.Debugf(base, "Content addressed store is empty")
+		s.root.entries = map[string]*node{}
+	}
+	stores[key] = s
+	return s, nil
+}
+
+// blobPath returns the path on the base of the blob with hash h
+func blobPath(h string) string {
+	return path.Join(blobDir, h[:2], h)
+}
+
+// treePath returns the path on the base of the tree object with hash h
+func treePath(h string) string {
+	return path.Join(treeDir, h[:2], h)
+}
+
+// refPath returns the path on the base of the ref called name
+func refPath(name string) string {
+	return path.Join(refDir, name)
+}
+
+// sha256Hex returns the SHA-256 of data as hex
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// isHash returns whether h looks like a SHA-256 as hex
+func isHash(h string) bool {
+	if len(h) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(h)
+	return err == nil
+}
+
+// readSmall reads the whole of the object at remote on the base
+// which must be no bigger than limit
+func (s *store) readSmall(ctx context.Context, remote string, limit int64) ([]byte, fs.Object, error) {
+	o, err := s.base.NewObject(ctx, remote)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.Size() > limit {
+		return nil, nil, fmt.Errorf("%q is too big", remote)
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(in, limit))
+	_ = in.Close()
+	return data, o, err
+}
+
+// writeSmall writes data to remote on the base
+func (s *store) writeSmall(ctx context.Context, remote string, data []byte) error {
+	info := object.NewStaticObjectInfo(remote, time.Now(), int64(len(data)), true, nil, s.base)
+	_, err := s.base.Put(ctx, bytes.NewReader(data), info)
+	return err
+}
+
+// readRef reads the tree hash in the ref called name
+//
+// It returns "" if there is no such ref.
+func (s *store) readRef(ctx context.Context, name string) (string, error) {
+	data, _, err := s.readSmall(ctx, refPath(name), 1024)
+	if errors.Is(err, fs.ErrorObjectNotFound) || errors.Is(err, fs.ErrorDirNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	h := strings.TrimSpace(string(data))
+	if !isHash(h) {
+		return "", fmt.Errorf("ref %q has bad hash %q", name, h)
+	}
+	return h, nil
+}
+
+// writeRef points the ref called name at the tree with hash h
+func (s *store) writeRef(ctx context.Context, name, h string) error {
+	return s.writeSmall(ctx, refPath(name), []byte(h+"\n"))
+}
+
+// readTree reads the tree object with hash h, checking it hasn't been
+// corrupted
+func (s *store) readTree(ctx context.Context, h string) (*treeObject, error) {
+	data, _, err := s.readSmall(ctx, treePath(h), maxTreeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree %s: %w", h, err)
+	}
+	if got := sha256Hex(data); got != h {
+		return nil, fmt.Errorf("tree %s is corrupted: its hash is %s", h, got)
+	}
+	var t treeObject
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to decode tree %s: %w", h, err)
+	}
+	if t.Version != treeVersion {
+		return nil, fmt.Errorf("tree %s has unsupported version %d", h, t.Version)
+	}
+	return &t, nil
+}
+
+// loadTree reads the tree object with hash h as directory entries
+func (s *store) loadTree(ctx context.Context, h string) (map[string]*node, error) {
+	t, err := s.readTree(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]*node, len(t.Entries))
+	for _, e := range t.Entries {
+		n := &node{modTime: e.ModTime}
+		switch e.Type {
+		case entryDir:
+			if !isHash(e.Tree) {
+				return nil, fmt.Errorf("tree %s has bad hash %q for %q", h, e.Tree, e.Name)
+			}
+			n.dir = &dirNode{hash: e.Tree}
+		case entryFile:
+			n.size = e.Size
+			n.hash = e.Hash
+			n.mimeType = e.MimeType
+			n.chunks = e.Chunks
+		default:
+			return nil, fmt.Errorf("tree %s has unknown type %q for %q", h, e.Type, e.Name)
+		}
+		entries[e.Name] = n
+	}
+	return entries, nil
+}
+
+// walk returns the node at key and the directories on the way to it
+// starting with root
+//
+// It returns a nil node if there is nothing at key. If a directory
+// which hasn't been loaded is in the way it returns that as unloaded.
+//
+// Call with s.mu held.
+func walk(root *dirNode, key string) (n *node, dirs []*dirNode, unloaded *dirNode) {
+	n = &node{dir: root}
+	if key == "" {
+		if root.entries == nil {
+			return nil, nil, root
+		}
+		return n, nil, nil
+	}
+	for _, name := range strings.Split(key, "/") {
+		if n.dir == nil {
+			return nil, dirs, nil
+		}
+		if n.dir.entries == nil {
+			return nil, dirs, n.dir
+		}
+		dirs = append(dirs, n.dir)
+		n = n.dir.entries[name]
+		if n == nil {
+			return nil, dirs, nil
+		}
+	}
+	if n.dir != nil && n.dir.entries == nil {
+		return nil, dirs, n.dir
+	}
+	return n, dirs, nil
+}
+
+// locked calls fn with s.mu held once the directories on the way to
+// each of keys, and the directories at them, are loaded from root
+func (s *store) locked(ctx context.Context, root *dirNode, keys []string, fn func() error) error {
+	for {
+		var unloaded *dirNode
+		s.mu.Lock()
+		for _, key := range keys {
+			if _, _, unloaded = walk(root, key); unloaded != nil {
+				break
+			}
+		}
+		if unloaded == nil {
+			err := fn()
+			s.mu.Unlock()
+			return err
+		}
+		h := unloaded.hash
+		s.mu.Unlock()
+		entries, err := s.loadTree(ctx, h)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		if unloaded.entries == nil {
+			unloaded.entries = entries
+		}
+		s.mu.Unlock()
+	}
+}
+
+// changed marks dirs as changed so new tree objects are made for them
+//
+// Call with s.mu held.
+func (s *store) changed(dirs []*dirNode) {
+	for _, d := range dirs {
+		d.hash = ""
+	}
+	s.gen++
+}
+
+// mkdirs makes the directories on the way to and at key in root,
+// returning them starting with root
+//
+// Call with s.mu held and the existing directories loaded.
+func (s *store) mkdirs(root *dirNode, key string, modTime time.Time) ([]*dirNode, error) {
+	dirs := []*dirNode{root}
+	if key == "" {
+		return dirs, nil
+	}
+	d := root
+	made := false
+	for _, name := range strings.Split(key, "/") {
+		n := d.entries[name]
+		if n == nil {
+			n = &node{modTime: modTime, dir: &dirNode{entries: map[string]*node{}}}
+			d.entries[name] = n
+			made = true
+		} else if n.dir == nil {
+			return nil, fs.ErrorIsFile
+		}
+		d = n.dir
+		dirs = append(dirs, d)
+	}
+	if made {
+		s.changed(dirs)
+	}
+	return dirs, nil
+}
+
+// seal makes tree objects for d and the directories in it which have
+// changed, returning the hash of the tree object for d
+//
+// Call with s.mu held.
+func (s *store) seal(d *dirNode) (string, error) {
+	if d.hash != "" {
+		return d.hash, nil
+	}
+	t := treeObject{
+		Version: treeVersion,
+		Entries: make([]treeEntry, 0, len(d.entries)),
+	}
+	for name, n := range d.entries {
+		e := treeEntry{
+			Name:    name,
+			ModTime: n.modTime,
+		}
+		if n.dir != nil {
+			h, err := s.seal(n.dir)
+			if err != nil {
+				return "", err
+			}
+			e.Type = entryDir
+			e.Tree = h
+		} else {
+			e.Type = entryFile
+			e.Size = n.size
+			e.Hash = n.hash
+			e.MimeType = n.mimeType
+			e.Chunks = n.chunks
+		}
+		t.Entries = append(t.Entries, e)
+	}
+	sort.Slice(t.Entries, func(i, j int) bool {
+		return t.Entries[i].Name < t.Entries[j].Name
+	})
+	data, err := json.Marshal(&t)
+	if err != nil {
+		return "", err
+	}
+	d.hash = sha256Hex(data)
+	s.trees[d.hash] = data
+	return d.hash, nil
+}
+
+// save writes the tree objects for the changes since the tree was
+// last saved then points HEAD at the new root
+//
+// Changes made while a save is in progress are saved together by the
+// next one.
+func (s *store) save(ctx context.Context) error {
+	s.mu.Lock()
+	gen := s.gen
+	s.mu.Unlock()
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	if s.savedGen >= gen {
+		return nil
+	}
+	s.mu.Lock()
+	h, err := s.seal(s.root)
+	gen = s.gen
+	trees := make(map[string][]byte, len(s.trees))
+	for th, data := range s.trees {
+		trees[th] = data
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for th, data := range trees {
+		if err := s.writeSmall(ctx, treePath(th), data); err != nil {
+			return fmt.Errorf("failed to write tree %s: %w", th, err)
+		}
+		s.mu.Lock()
+		delete(s.trees, th)
+		s.mu.Unlock()
+	}
+	head, err := s.readRef(ctx, headRef)
+	if err != nil {
+		return fmt.Errorf("couldn't read %s: %w", headRef, err)
+	}
+	if head != s.head {
+		return fmt.Errorf("%s was changed from %q to %q by another rclone - only one rclone may write to the store at once", headRef, s.head, head)
+	}
+	if err := s.writeRef(ctx, headRef, h); err != nil {
+		return fmt.Errorf("couldn't write %s: %w", headRef, err)
+	}
+	s.head = h
+	s.savedGen = gen
+	return nil
+}
+
+// hasBlob returns whether the blob with hash h is known to be stored
+func (s *store) hasBlob(h string) bool {
+	s.blobsMu.Lock()
+	defer s.blobsMu.Unlock()
+	_, ok := s.blobs[h]
+	return ok
+}
+
+// putChunk stores data as a blob unless it is stored already
+func (s *store) putChunk(ctx context.Context, data []byte) (chunkRef, error) {
+	c := chunkRef{
+		Hash: sha256Hex(data),
+		Size: int64(len(data)),
+	}
+	if s.hasBlob(c.Hash) {
+		return c, nil
+	}
+	remote := blobPath(c.Hash)
+	if _, err := s.base.NewObject(ctx, remote); err == nil {
+		fs.Debugf(s.base, "Reusing existing blob %s", c.Hash)
+	} else {
+		info := object.NewStaticObjectInfo(remote, time.Now(), c.Size, true, nil, s.base)
+		if _, err := s.base.Put(ctx, bytes.NewReader(data), info); err != nil {
+			return c, fmt.Errorf("failed to store blob %s: %w", c.Hash, err)
+		}
+	}
+	s.blobsMu.Lock()
+	s.blobs[c.Hash] = struct{}{}
+	s.blobsMu.Unlock()
+	return c, nil
+}
+
+// forEachTree calls fn for each tree object reachable from the tree
+// with hash h which isn't in seen, adding them to seen
+//
+// If a tree can't be read fn is called with the error and the trees
+// below it are skipped.
+func (s *store) forEachTree(ctx context.Context, h string, seen map[string]struct{}, fn func(h string, t *treeObject, err error) error) error {
+	if _, ok := seen[h]; ok {
+		return nil
+	}
+	seen[h] = struct{}{}
+	t, err := s.readTree(ctx, h)
+	if err != nil {
+		return fn(h, nil, err)
+	}
+	if err := fn(h, t, nil); err != nil {
+		return err
+	}
+	for _, e := range t.Entries {
+		if e.Type == entryDir {
+			if err := s.forEachTree(ctx, e.Tree, seen, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -24,12 +24,14 @@ import (
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/asyncreader"
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fs/config/configstruct"
 	"github.com/rclone/rclone/fs/fspath"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
+	"golang.org/x/sync/errgroup"
 )
 
 //
@@ -267,6 +269,31 @@ If meta format is set to "none", rename transactions will always be used.
 This method is EXPERIMENTAL, don't use on production systems.`,
 				},
 			},
+		}, {
+			Name:     "read_concurrency",
+			Advanced: true,
+			Default:  1,
+			Help: `Number of chunks to download at once when reading a file.
+
+If this is more than 1 then reading a chunked file opens this many
+chunks at once and reads ahead into buffers of --buffer-size for each
+chunk, returning the data in order. This turns a read of a large file
+into a download with several streams, which is faster on remotes where
+a single stream is slow.
+
+This uses up to read_concurrency * --buffer-size of memory for each
+file being read.`,
+		}, {
+			Name:     "copy_concurrency",
+			Advanced: true,
+			Default:  4,
+			Help: `Number of chunks to copy or move at once server-side.
+
+When the wrapped remote can copy or move files server-side, the chunks
+of a file are copied or moved this many at a time and the metadata
+object is written last. If any chunk fails the chunks already copied
+are removed, or moved back when moving, so the source is left as it
+was.`,
 		}},
 	})
 }
@@ -357,6 +384,8 @@ type Options struct {
 	HashType     string        `config:"hash_type"`
 	FailHard     bool          `config:"fail_hard"`
 	Transactions string        `config:"transactions"`
+	ReadConc     int           `config:"read_concurrency"`
+	CopyConc     int           `config:"copy_concurrency"`
 }
 
 // Fs represents a wrapped fs.Fs
@@ -744,6 +773,82 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	})
 }
 
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// The chunks of a composite file all have its name as a prefix so
+// when the base remote lists in sorted order a file can't have any
+// chunks still to come once a name which doesn't start with its name
+// has been seen. Objects which might still have chunks to come are
+// held over to the next page. If the base remote doesn't list in
+// sorted order then all the objects are held until the end.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	var (
+		held   fs.DirEntries // objects which may have chunks to come
+		last   string        // remote of the last object seen
+		sorted = true        // set if the objects are listed in order
+	)
+	do := f.base.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	err = do(ctx, dir, func(entries fs.DirEntries) error {
+		var ready fs.DirEntries
+		for _, entry := range entries {
+			if _, isObject := entry.(fs.Object); !isObject {
+				ready = append(ready, entry)
+				continue
+			}
+			remote := entry.Remote()
+			if remote < last {
+				sorted = false
+			}
+			last = remote
+			held = append(held, entry)
+		}
+		if sorted {
+			stillHeld := held[:0]
+			for _, entry := range held {
+				if strings.HasPrefix(last, f.groupName(entry.Remote())) {
+					stillHeld = append(stillHeld, entry)
+				} else {
+					ready = append(ready, entry)
+				}
+			}
+			held = stillHeld
+		}
+		if len(ready) == 0 {
+			return nil
+		}
+		newEntries, err := f.processEntries(ctx, ready, dir)
+		if err != nil {
+			return err
+		}
+		return callback(newEntries)
+	})
+	if err != nil || len(held) == 0 {
+		return err
+	}
+	newEntries, err := f.processEntries(ctx, held, dir)
+	if err != nil {
+		return err
+	}
+	return callback(newEntries)
+}
+
+// groupName returns the name of the composite file that the base
+// object remote belongs to
+func (f *Fs) groupName(remote string) string {
+	if mainRemote, _, _, _ := f.parseChunkName(remote); mainRemote != "" {
+		return mainRemote
+	}
+	return remote
+}
+
 // processEntries assembles chunk entries into composite entries
 func (f *Fs) processEntries(ctx context.Context, origEntries fs.DirEntries, dirPath string) (newEntries fs.DirEntries, err error) {
 	var sortedEntries fs.DirEntries
@@ -1679,35 +1784,42 @@ func (f *Fs) copyOrMove(ctx context.Context, o *Object, remote string, do copyMo
 
 	fs.Debugf(o, "%s %d data chunks...", opName, len(o.chunks))
 	mainRemote := o.remote
-	var newChunks []fs.Object
-	var err error
+	newChunks := make([]fs.Object, len(o.chunks))
 
-	// Copy/move active data chunks.
+	// Copy/move active data chunks in parallel.
 	// Ignore possible temporary chunks being created by parallel operations.
-	for _, chunk := range o.chunks {
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(f.copyConcurrency())
+	for i, chunk := range o.chunks {
+		i, chunk := i, chunk
 		chunkRemote := chunk.Remote()
 		if !strings.HasPrefix(chunkRemote, mainRemote) {
-			err = fmt.Errorf("invalid chunk name %q", chunkRemote)
+			g.Go(func() error {
+				return fmt.Errorf("invalid chunk name %q", chunkRemote)
+			})
 			break
 		}
 		chunkSuffix := chunkRemote[len(mainRemote):]
-		chunkResult, err := do(ctx, chunk, remote+chunkSuffix)
-		if err != nil {
-			break
-		}
-		newChunks = append(newChunks, chunkResult)
+		g.Go(func() error {
+			chunkResult, err := do(gCtx, chunk, remote+chunkSuffix)
+			if err != nil {
+				return err
+			}
+			newChunks[i] = chunkResult
+			return nil
+		})
 	}
+	err := g.Wait()
 
-	// Copy or move old metadata.
+	// Copy or move old metadata last so the new file doesn't appear
+	// until all its chunks are in place.
 	// There are no known control chunks to move/copy atm.
 	var metaObject fs.Object
 	if err == nil && o.main != nil {
 		metaObject, err = do(ctx, o.main, remote)
 	}
 	if err != nil {
-		for _, chunk := range newChunks {
-			silentlyRemove(ctx, chunk)
-		}
+		f.undoCopyOrMove(ctx, o, newChunks, opName)
 		return nil, err
 	}
 
@@ -1744,6 +1856,34 @@ func (f *Fs) copyOrMove(ctx context.Context, o *Object, remote string, do copyMo
 
 type copyMoveFn func(context.Context, fs.Object, string) (fs.Object, error)
 
+// copyConcurrency returns the number of chunks to copy or move at once
+func (f *Fs) copyConcurrency() int {
+	if f.opt.CopyConc < 1 {
+		return 1
+	}
+	return f.opt.CopyConc
+}
+
+// undoCopyOrMove cleans up after a failed copy or move of the chunks
+// of o, leaving the source file as it was.
+//
+// Copied chunks are removed and moved chunks are moved back.
+func (f *Fs) undoCopyOrMove(ctx context.Context, o *Object, newChunks []fs.Object, opName string) {
+	for i, chunk := range newChunks {
+		if chunk == nil {
+			continue
+		}
+		if opName != "move" {
+			silentlyRemove(ctx, chunk)
+			continue
+		}
+		origRemote := o.chunks[i].Remote()
+		if _, err := f.baseMove(ctx, chunk, origRemote, delNever); err != nil {
+			fs.Errorf(o, "failed to move chunk back to %q: %v", origRemote, err)
+		}
+	}
+}
+
 func (f *Fs) okForServerSide(ctx context.Context, src fs.Object, opName string) (obj *Object, md5, sha1 string, ok bool) {
 	var diff string
 	obj, ok = src.(*Object)
@@ -2191,6 +2331,9 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (rc io.Read
 		limit = o.size - offset
 	}
 
+	if o.f.opt.ReadConc > 1 {
+		return o.newParallelReader(ctx, offset, limit, openOptions), nil
+	}
 	return o.newLinearReader(ctx, offset, limit, openOptions)
 }
 
@@ -2299,6 +2442,165 @@ func (r *linearReader) Close() (err error) {
 	return
 }
 
+// chunkRange is the part of a chunk to be read
+type chunkRange struct {
+	chunk  fs.Object
+	offset int64
+	count  int64
+}
+
+// chunkResult is a chunk opened in the background
+type chunkResult struct {
+	rc  io.ReadCloser
+	err error
+}
+
+// parallelReader opens several file chunks at once and reads ahead
+// into buffers, returning the data in order
+type parallelReader struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	options []fs.OpenOption
+	ranges  []chunkRange       // parts of the chunks to read
+	buffers int                // number of read ahead buffers per chunk
+	pending []chan chunkResult // chunks being opened, in order
+	next    int                // index of the next range to open
+	cur     int                // index of the range being read
+	reader  io.ReadCloser      // reader for the current range
+	count   int64              // bytes left in the current range
+	err     error
+}
+
+func (o *Object) newParallelReader(ctx context.Context, offset, limit int64, options []fs.OpenOption) io.ReadCloser {
+	ctx, cancel := context.WithCancel(ctx)
+	buffers := int(fs.GetConfig(ctx).BufferSize / asyncreader.BufferSize)
+	if buffers < 1 {
+		buffers = 1
+	}
+	r := &parallelReader{
+		ctx:     ctx,
+		cancel:  cancel,
+		options: options,
+		buffers: buffers,
+		cur:     -1,
+	}
+	for _, chunk := range o.chunks {
+		if limit <= 0 {
+			break
+		}
+		size := chunk.Size()
+		if offset >= size {
+			offset -= size
+			continue
+		}
+		count := size - offset
+		if limit < count {
+			count = limit
+		}
+		r.ranges = append(r.ranges, chunkRange{chunk: chunk, offset: offset, count: count})
+		limit -= count
+		offset = 0
+	}
+	for i := 0; i < o.f.opt.ReadConc && r.next < len(r.ranges); i++ {
+		r.start()
+	}
+	return r
+}
+
+// start opening the next range in the background
+func (r *parallelReader) start() {
+	cr := r.ranges[r.next]
+	r.next++
+	result := make(chan chunkResult, 1)
+	r.pending = append(r.pending, result)
+	options := append(append([]fs.OpenOption{}, r.options...), &fs.RangeOption{Start: cr.offset, End: cr.offset + cr.count - 1})
+	go func() {
+		rc, err := cr.chunk.Open(r.ctx, options...)
+		if err == nil {
+			var in *asyncreader.AsyncReader
+			in, err = asyncreader.New(r.ctx, rc, r.buffers)
+			if err != nil {
+				_ = rc.Close()
+				rc = nil
+			} else {
+				rc = in
+			}
+		}
+		result <- chunkResult{rc: rc, err: err}
+	}()
+}
+
+// nextRange finishes with the current range and waits for the next
+// one to be opened
+func (r *parallelReader) nextRange() error {
+	if r.reader != nil {
+		err := r.reader.Close()
+		r.reader = nil
+		if err != nil {
+			return err
+		}
+		if r.next < len(r.ranges) {
+			r.start()
+		}
+	}
+	if len(r.pending) == 0 {
+		return io.EOF
+	}
+	result := <-r.pending[0]
+	r.pending = r.pending[1:]
+	r.cur++
+	if result.err != nil {
+		return result.err
+	}
+	r.reader = result.rc
+	r.count = r.ranges[r.cur].count
+	return nil
+}
+
+func (r *parallelReader) Read(p []byte) (n int, err error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	for r.reader == nil || r.count <= 0 {
+		if err = r.nextRange(); err != nil {
+			r.err = err
+			return 0, err
+		}
+	}
+	if int64(len(p)) > r.count {
+		p = p[:r.count]
+	}
+	n, err = r.reader.Read(p)
+	r.count -= int64(n)
+	if err == io.EOF {
+		if r.count > 0 {
+			err = io.ErrUnexpectedEOF
+		} else {
+			err = nil // move on to the next range
+		}
+	}
+	r.err = err
+	return n, err
+}
+
+func (r *parallelReader) Close() (err error) {
+	r.cancel()
+	if r.reader != nil {
+		err = r.reader.Close()
+		r.reader = nil
+	}
+	for _, result := range r.pending {
+		if res := <-result; res.rc != nil {
+			_ = res.rc.Close()
+		}
+	}
+	r.pending = nil
+	if r.err == nil {
+		r.err = errors.New("read on closed file")
+	}
+	return err
+}
+
 // ObjectInfo describes a wrapped fs.ObjectInfo for being the source
 type ObjectInfo struct {
 	src     fs.ObjectInfo
@@ -2556,6 +2858,7 @@ var (
 	_ fs.CleanUpper      = (*Fs)(nil)
 	_ fs.UnWrapper       = (*Fs)(nil)
 	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
 	_ fs.Wrapper         = (*Fs)(nil)
 	_ fs.ChangeNotifier  = (*Fs)(nil)
@@ -3,11 +3,13 @@ package chunker
 import (
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 	"testing"
 
@@ -899,6 +901,180 @@ func testMD5AllSlow(t *testing.T, f *Fs) {
 	require.NoError(t, operations.Purge(ctx, baseFs, ""))
 }
 
+// Test that reading several chunks at once returns the right data
+func testParallelRead(t *testing.T, f *Fs) {
+	ctx := context.Background()
+	fsResult := deriveFs(ctx, t, f, "parallel", settings{
+		"chunk_size":       "1k",
+		"name_format":      "*.#",
+		"read_concurrency": 3,
+	})
+	chunkFs, ok := fsResult.(*Fs)
+	require.True(t, ok, "fs must be a chunker remote")
+	assert.Equal(t, 3, chunkFs.opt.ReadConc)
+
+	contents := random.String(10*1024 + 123)
+	obj := testPutFile(ctx, t, chunkFs, "file", contents, "error", true)
+	o, ok := obj.(*Object)
+	require.True(t, ok, "object must be a chunker object")
+	require.Equal(t, 11, len(o.chunks))
+
+	readRange := func(options ...fs.OpenOption) string {
+		r, err := obj.Open(ctx, options...)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		return string(data)
+	}
+	assert.Equal(t, contents, readRange())
+	for _, test := range []struct {
+		start, end int64
+	}{
+		{0, 0},
+		{0, 1023},
+		{1000, 1100},
+		{1024, 3071},
+		{1500, 8000},
+		{10000, 10*1024 + 122},
+	} {
+		got := readRange(&fs.RangeOption{Start: test.start, End: test.end})
+		assert.Equal(t, contents[test.start:test.end+1], got, "range %d-%d", test.start, test.end)
+	}
+	assert.Equal(t, contents[5000:], readRange(&fs.SeekOption{Offset: 5000}))
+
+	// Closing part way through must not hang or leak the chunks
+	r, err := obj.Open(ctx)
+	require.NoError(t, err)
+	buf := make([]byte, 100)
+	_, err = r.Read(buf)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	_, err = r.Read(buf)
+	assert.Error(t, err, "read after close must fail")
+
+	require.NoError(t, operations.Purge(ctx, chunkFs.base, ""))
+}
+
+// Test that chunks are moved in parallel and that a failed move
+// leaves the source as it was
+func testParallelMove(t *testing.T, f *Fs) {
+	ctx := context.Background()
+	fsResult := deriveFs(ctx, t, f, "parallelmove", settings{
+		"chunk_size":       "1k",
+		"name_format":      "*.#",
+		"meta_format":      "simplejson",
+		"copy_concurrency": 3,
+	})
+	chunkFs, ok := fsResult.(*Fs)
+	require.True(t, ok, "fs must be a chunker remote")
+	assert.Equal(t, 3, chunkFs.copyConcurrency())
+
+	contents := random.String(5*1024 + 123)
+	obj := testPutFile(ctx, t, chunkFs, "file", contents, "error", true)
+	o, ok := obj.(*Object)
+	require.True(t, ok, "object must be a chunker object")
+	require.Equal(t, 6, len(o.chunks))
+
+	readFile := func(remote string) string {
+		obj, err := chunkFs.NewObject(ctx, remote)
+		require.NoError(t, err)
+		r, err := obj.Open(ctx)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		return string(data)
+	}
+
+	// Fail moving one of the chunks
+	errFailed := errors.New("failed to move")
+	failingMove := func(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+		if strings.HasSuffix(remote, ".4") {
+			return nil, errFailed
+		}
+		return chunkFs.baseMove(ctx, src, remote, delNever)
+	}
+	_, err := chunkFs.copyOrMove(ctx, o, "moved", failingMove, "", "", "move")
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, contents, readFile("file"), "source must be intact")
+	_, err = chunkFs.NewObject(ctx, "moved")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+	list, err := chunkFs.base.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 7, len(list), "only the source chunks and metadata must remain")
+
+	// Now move it properly
+	moved, err := chunkFs.Move(ctx, obj, "moved")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(contents)), moved.Size())
+	assert.Equal(t, contents, readFile("moved"))
+	_, err = chunkFs.NewObject(ctx, "file")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+
+	require.NoError(t, operations.Purge(ctx, chunkFs.base, ""))
+}
+
+// Test that ListP doesn't split composite files across pages
+func testListP(t *testing.T, f *Fs) {
+	ctx := context.Background()
+	fsResult := deriveFs(ctx, t, f, "listp", settings{
+		"chunk_size":  "1k",
+		"name_format": "*.rclone_chunk.###",
+		"meta_format": "simplejson",
+	})
+	chunkFs, ok := fsResult.(*Fs)
+	require.True(t, ok, "fs must be a chunker remote")
+
+	_ = testPutFile(ctx, t, chunkFs, "file", random.String(2*1024+10), "error", true)
+	_ = testPutFile(ctx, t, chunkFs, "file-x", "small", "error", true)
+	_ = testPutFile(ctx, t, chunkFs, "zzz", random.String(1024+10), "error", true)
+	want, err := chunkFs.List(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(want))
+
+	// Make the base return one sorted entry per page
+	baseFeatures := chunkFs.base.Features()
+	oldListP := baseFeatures.ListP
+	defer func() {
+		baseFeatures.ListP = oldListP
+	}()
+	var reverse bool
+	baseFeatures.ListP = func(ctx context.Context, dir string, callback fs.ListRCallback) error {
+		entries, err := chunkFs.base.List(ctx, dir)
+		if err != nil {
+			return err
+		}
+		sort.Sort(entries)
+		for i := range entries {
+			if reverse {
+				i = len(entries) - 1 - i
+			}
+			if err := callback(entries[i : i+1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, reverse = range []bool{false, true} {
+		var got fs.DirEntries
+		err = chunkFs.ListP(ctx, "", func(entries fs.DirEntries) error {
+			got = append(got, entries...)
+			return nil
+		})
+		require.NoError(t, err)
+		sort.Sort(got)
+		require.Equal(t, len(want), len(got), "reverse=%v", reverse)
+		for i := range want {
+			assert.Equal(t, want[i].Remote(), got[i].Remote(), "reverse=%v", reverse)
+			assert.Equal(t, want[i].Size(), got[i].Size(), "reverse=%v", reverse)
+		}
+	}
+
+	require.NoError(t, operations.Purge(ctx, chunkFs.base, ""))
+}
+
 // InternalTest dispatches all internal tests
 func (f *Fs) InternalTest(t *testing.T) {
 	t.Run("PutLarge", func(t *testing.T) {
@@ -934,6 +1110,15 @@ func (f *Fs) InternalTest(t *testing.T) {
 	t.Run("MD5AllSlow", func(t *testing.T) {
 		testMD5AllSlow(t, f)
 	})
+	t.Run("ParallelRead", func(t *testing.T) {
+		testParallelRead(t, f)
+	})
+	t.Run("ParallelMove", func(t *testing.T) {
+		testParallelMove(t, f)
+	})
+	t.Run("ListP", func(t *testing.T) {
+		testListP(t, f)
+	})
 }
 
 var _ fstests.InternalTester = (*Fs)(nil)
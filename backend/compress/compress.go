@@ -15,12 +15,15 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/buengese/sgzip"
 	"github.com/gabriel-vasile/mimetype"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
@@ -43,6 +46,8 @@ const (
 	minCompressionRatio = 1.1
 
 	gzFileExt           = ".gz"
+	zstdFileExt         = ".zst"
+	lz4FileExt          = ".lz4"
 	metaFileExt         = ".json"
 	uncompressedFileExt = ".bin"
 )
@@ -51,8 +56,24 @@ const (
 const (
 	Uncompressed = 0
 	Gzip         = 2
+	Zstd         = 3
+	Lz4          = 4
 )
 
+// lz4Levels maps the level option onto lz4 compression levels
+var lz4Levels = []lz4.CompressionLevel{
+	lz4.Fast, lz4.Level1, lz4.Level2, lz4.Level3, lz4.Level4,
+	lz4.Level5, lz4.Level6, lz4.Level7, lz4.Level8, lz4.Level9,
+}
+
+// defaultNoCompressExtensions are file types which are already
+// compressed so not worth compressing again
+var defaultNoCompressExtensions = fs.CommaSepList{
+	"7z", "avi", "br", "bz2", "flac", "gif", "gz", "heic", "jpeg", "jpg",
+	"lz4", "m4a", "mkv", "mov", "mp3", "mp4", "ogg", "png", "rar", "tgz",
+	"webm", "webp", "xz", "zip", "zst",
+}
+
 var nameRegexp = regexp.MustCompile(`^(.+?)\.([A-Za-z0-9-_]{11})$`)
 
 // Register with Fs
@@ -62,6 +83,12 @@ func init() {
 		{ // Default compression mode options {
 			Value: "gzip",
 			Help:  "Standard gzip compression with fastest parameters.",
+		}, {
+			Value: "zstd",
+			Help:  "Zstandard compression - better compression and faster than gzip.",
+		}, {
+			Value: "lz4",
+			Help:  "LZ4 compression - very fast with less compression than gzip.",
 		},
 	}
 
@@ -84,17 +111,34 @@ func init() {
 			Examples: compressionModeOptions,
 		}, {
 			Name: "level",
-			Help: `GZIP compression level (-2 to 9).
+			Help: `Compression level (-2 to 9 for gzip, -1 to 22 for zstd, -1 to 9 for lz4).
+
+Generally -1 (default) is recommended. This is equivalent to 5 for
+gzip, 3 for zstd and the fastest level for lz4.
 
-Generally -1 (default, equivalent to 5) is recommended.
-Levels 1 to 9 increase compression at the cost of speed. Going past 6 
-generally offers very little return.
+For gzip levels 1 to 9 increase compression at the cost of speed.
+Going past 6 generally offers very little return. Level -2 uses
+Huffmann encoding only. Only use if you know what you are doing.
+Level 0 turns off compression.
 
-Level -2 uses Huffmann encoding only. Only use if you know what you
-are doing.
-Level 0 turns off compression.`,
+For zstd levels 1 to 22 increase compression at the cost of speed.
+These are mapped onto the nearest level the zstd encoder supports.
+
+For lz4 levels 1 to 9 increase compression at the cost of speed. Level
+0 is the same as -1.`,
 			Default:  sgzip.DefaultCompression,
 			Advanced: true,
+		}, {
+			Name: "no_compress_extensions",
+			Help: `Comma separated list of file extensions never to compress.
+
+Files with these extensions (compared case insensitively, without the
+leading ".") are stored uncompressed without trying to compress them
+first. Use this for formats which are already compressed.
+
+Set to "" to try compressing every file.`,
+			Default:  defaultNoCompressExtensions,
+			Advanced: true,
 		}, {
 			Name: "ram_cache_limit",
 			Help: `Some remotes don't allow the upload of files with unknown size.
@@ -111,10 +155,11 @@ this limit will be cached on disk.`,
 
 // Options defines the configuration for this backend
 type Options struct {
-	Remote           string        `config:"remote"`
-	CompressionMode  string        `config:"mode"`
-	CompressionLevel int           `config:"level"`
-	RAMCacheLimit    fs.SizeSuffix `config:"ram_cache_limit"`
+	Remote           string          `config:"remote"`
+	CompressionMode  string          `config:"mode"`
+	CompressionLevel int             `config:"level"`
+	RAMCacheLimit    fs.SizeSuffix   `config:"ram_cache_limit"`
+	NoCompressExts   fs.CommaSepList `config:"no_compress_extensions"`
 }
 
 /*** FILESYSTEM FUNCTIONS ***/
@@ -122,12 +167,13 @@ type Options struct {
 // Fs represents a wrapped fs.Fs
 type Fs struct {
 	fs.Fs
-	wrapper  fs.Fs
-	name     string
-	root     string
-	opt      Options
-	mode     int          // compression mode id
-	features *fs.Features // optional features
+	wrapper    fs.Fs
+	name       string
+	root       string
+	opt        Options
+	mode       int                 // compression mode id
+	noCompress map[string]struct{} // extensions never to compress
+	features   *fs.Features        // optional features
 }
 
 // NewFs contstructs an Fs from the path, container:path
@@ -166,11 +212,18 @@ func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs,
 
 	// Create the wrapping fs
 	f := &Fs{
-		Fs:   wrappedFs,
-		name: name,
-		root: rpath,
-		opt:  *opt,
-		mode: compressionModeFromName(opt.CompressionMode),
+		Fs:         wrappedFs,
+		name:       name,
+		root:       rpath,
+		opt:        *opt,
+		mode:       compressionModeFromName(opt.CompressionMode),
+		noCompress: map[string]struct{}{},
+	}
+	for _, ext := range opt.NoCompressExts {
+		ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+		if ext != "" {
+			f.noCompress[ext] = struct{}{}
+		}
 	}
 	// the features here are ones we could support, and they are
 	// ANDed with the ones from wrappedFs
@@ -201,6 +254,10 @@ func compressionModeFromName(name string) int {
 	switch name {
 	case "gzip":
 		return Gzip
+	case "zstd":
+		return Zstd
+	case "lz4":
+		return Lz4
 	default:
 		return Uncompressed
 	}
@@ -243,7 +300,7 @@ func processFileName(compressedFileName string) (origFileName string, extension
 	if err != nil {
 		return "", "", 0, errors.New("could not decode size")
 	}
-	return match[1], gzFileExt, size, nil
+	return match[1], extension, size, nil
 }
 
 // Generates the file name for a metadata file
@@ -258,10 +315,15 @@ func isMetadataFile(filename string) bool {
 
 // makeDataName generates the file name for a data file with specified compression mode
 func makeDataName(remote string, size int64, mode int) (newRemote string) {
-	if mode != Uncompressed {
-		newRemote = remote + "." + int64ToBase64(size) + gzFileExt
-	} else {
+	switch mode {
+	case Uncompressed:
 		newRemote = remote + uncompressedFileExt
+	case Zstd:
+		newRemote = remote + "." + int64ToBase64(size) + zstdFileExt
+	case Lz4:
+		newRemote = remote + "." + int64ToBase64(size) + lz4FileExt
+	default:
+		newRemote = remote + "." + int64ToBase64(size) + gzFileExt
 	}
 	return newRemote
 }
@@ -360,6 +422,26 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	})
 }
 
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	do := f.Fs.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	return do(ctx, dir, func(entries fs.DirEntries) error {
+		newEntries, err := f.processEntries(entries)
+		if err != nil {
+			return err
+		}
+		return callback(newEntries)
+	})
+}
+
 // NewObject finds the Object at remote.
 func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	// Read metadata from metadata object
@@ -372,13 +454,27 @@ func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 		return nil, errors.New("error decoding metadata")
 	}
 	// Create our Object
-	o, err := f.Fs.NewObject(ctx, makeDataName(remote, meta.CompressionMetadata.Size, meta.Mode))
+	o, err := f.Fs.NewObject(ctx, makeDataName(remote, meta.Size, meta.Mode))
 	return f.newObject(o, mo, meta), err
 }
 
+// skipCompress returns true if remote has an extension which should
+// never be compressed
+func (f *Fs) skipCompress(remote string) bool {
+	if f.mode == Uncompressed {
+		return true
+	}
+	ext := path.Ext(remote)
+	if ext == "" {
+		return false
+	}
+	_, found := f.noCompress[strings.ToLower(ext[1:])]
+	return found
+}
+
 // checkCompressAndType checks if an object is compressible and determines it's mime type
 // returns a multireader with the bytes that were read to determine mime type
-func checkCompressAndType(in io.Reader) (newReader io.Reader, compressible bool, mimeType string, err error) {
+func (f *Fs) checkCompressAndType(in io.Reader, remote string) (newReader io.Reader, compressible bool, mimeType string, err error) {
 	in, wrap := accounting.UnWrap(in)
 	buf := make([]byte, heuristicBytes)
 	n, err := in.Read(buf)
@@ -387,9 +483,11 @@ func checkCompressAndType(in io.Reader) (newReader io.Reader, compressible bool,
 		return nil, false, "", err
 	}
 	mime := mimetype.Detect(buf)
-	compressible, err = isCompressible(bytes.NewReader(buf))
-	if err != nil {
-		return nil, false, "", err
+	if !f.skipCompress(remote) {
+		compressible, err = isCompressible(bytes.NewReader(buf))
+		if err != nil {
+			return nil, false, "", err
+		}
 	}
 	in = io.MultiReader(bytes.NewReader(buf), in)
 	return wrap(in), compressible, mime.String(), nil
@@ -438,6 +536,43 @@ type putFn func(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ..
 type compressionResult struct {
 	err  error
 	meta sgzip.GzipMetadata
+	size int64 // size of the uncompressed data
+}
+
+// newCompressor returns a writer which compresses to w with the
+// configured mode and level.
+//
+// It returns the GZIP metadata when closed for gzip and nothing
+// otherwise.
+func (f *Fs) newCompressor(w io.Writer) (io.WriteCloser, func() sgzip.GzipMetadata, error) {
+	level := f.opt.CompressionLevel
+	noMeta := func() sgzip.GzipMetadata { return sgzip.GzipMetadata{} }
+	switch f.mode {
+	case Zstd:
+		encoderLevel := zstd.SpeedDefault
+		if level > 0 {
+			encoderLevel = zstd.EncoderLevelFromZstd(level)
+		}
+		zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(encoderLevel))
+		return zw, noMeta, err
+	case Lz4:
+		if level >= len(lz4Levels) {
+			return nil, nil, fmt.Errorf("lz4 compression level %d is out of range -1 to %d", level, len(lz4Levels)-1)
+		}
+		lz4Level := lz4.Fast
+		if level > 0 {
+			lz4Level = lz4Levels[level]
+		}
+		lw := lz4.NewWriter(w)
+		err := lw.Apply(lz4.CompressionLevelOption(lz4Level))
+		return lw, noMeta, err
+	default:
+		gz, err := sgzip.NewWriterLevel(w, level)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gz, gz.MetaData, nil
+	}
 }
 
 // replicating some of operations.Rcat functionality because we want to support remotes without streaming
@@ -500,12 +635,13 @@ func (f *Fs) putCompress(ctx context.Context, in io.Reader, src fs.ObjectInfo, o
 	pipeReader, pipeWriter := io.Pipe()
 	results := make(chan compressionResult)
 	go func() {
-		gz, err := sgzip.NewWriterLevel(pipeWriter, f.opt.CompressionLevel)
+		gz, metaData, err := f.newCompressor(pipeWriter)
 		if err != nil {
+			_ = pipeWriter.CloseWithError(err)
 			results <- compressionResult{err: err, meta: sgzip.GzipMetadata{}}
 			return
 		}
-		_, err = io.Copy(gz, in)
+		n, err := io.Copy(gz, in)
 		gzErr := gz.Close()
 		if gzErr != nil {
 			fs.Errorf(nil, "Failed to close compress: %v", gzErr)
@@ -520,7 +656,7 @@ func (f *Fs) putCompress(ctx context.Context, in io.Reader, src fs.ObjectInfo, o
 				err = closeErr
 			}
 		}
-		results <- compressionResult{err: err, meta: gz.MetaData()}
+		results <- compressionResult{err: err, meta: metaData(), size: n}
 	}()
 	wrappedIn := wrap(bufio.NewReaderSize(pipeReader, bufferSize)) // Probably no longer needed as sgzip has it's own buffering
 
@@ -567,7 +703,7 @@ func (f *Fs) putCompress(ctx context.Context, in io.Reader, src fs.ObjectInfo, o
 	}
 
 	// Generate metadata
-	meta := newMetadata(result.meta.Size, f.mode, result.meta, hex.EncodeToString(metaHasher.Sum(nil)), mimeType)
+	meta := newMetadata(result.size, f.mode, result.meta, hex.EncodeToString(metaHasher.Sum(nil)), mimeType)
 
 	// Check the hashes of the compressed data if we were comparing them
 	if ht != hash.None && hasher != nil {
@@ -692,7 +828,7 @@ func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options .
 	o, err := f.NewObject(ctx, src.Remote())
 	if err == fs.ErrorObjectNotFound {
 		// Get our file compressibility
-		in, compressible, mimeType, err := checkCompressAndType(in)
+		in, compressible, mimeType, err := f.checkCompressAndType(in, src.Remote())
 		if err != nil {
 			return nil, err
 		}
@@ -712,7 +848,7 @@ func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, opt
 	}
 	found := err == nil
 
-	in, compressible, mimeType, err := checkCompressAndType(in)
+	in, compressible, mimeType, err := f.checkCompressAndType(in, src.Remote())
 	if err != nil {
 		return nil, err
 	}
@@ -1091,7 +1227,7 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		return o.mo, o.mo.Update(ctx, in, src, options...)
 	}
 
-	in, compressible, mimeType, err := checkCompressAndType(in)
+	in, compressible, mimeType, err := o.f.checkCompressAndType(in, o.Remote())
 	if err != nil {
 		return err
 	}
@@ -1303,13 +1439,39 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (rc io.Read
 	chunkedReader := chunkedreader.New(ctx, o.Object, initialChunkSize, maxChunkSize)
 	// Get file handle
 	var file io.Reader
-	if offset != 0 {
-		file, err = sgzip.NewReaderAt(chunkedReader, &o.meta.CompressionMetadata, offset)
-	} else {
-		file, err = sgzip.NewReader(chunkedReader)
-	}
-	if err != nil {
-		return nil, err
+	var closer io.Closer = chunkedReader
+	switch o.meta.Mode {
+	case Zstd, Lz4:
+		var decompressor io.ReadCloser
+		if o.meta.Mode == Zstd {
+			var zr *zstd.Decoder
+			zr, err = zstd.NewReader(chunkedReader, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				_ = chunkedReader.Close()
+				return nil, err
+			}
+			decompressor = zr.IOReadCloser()
+		} else {
+			decompressor = ioutil.NopCloser(lz4.NewReader(chunkedReader))
+		}
+		closer = multiCloser{decompressor, chunkedReader}
+		file = decompressor
+		// These formats can't seek so read up to the offset
+		if offset != 0 {
+			if _, err = io.CopyN(ioutil.Discard, file, offset); err != nil {
+				_ = closer.Close()
+				return nil, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+			}
+		}
+	default:
+		if offset != 0 {
+			file, err = sgzip.NewReaderAt(chunkedReader, &o.meta.CompressionMetadata, offset)
+		} else {
+			file, err = sgzip.NewReader(chunkedReader)
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var fileReader io.Reader
@@ -1319,7 +1481,20 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (rc io.Read
 		fileReader = file
 	}
 	// Return a ReadCloser
-	return ReadCloserWrapper{Reader: fileReader, Closer: chunkedReader}, nil
+	return ReadCloserWrapper{Reader: fileReader, Closer: closer}, nil
+}
+
+// multiCloser closes all the closers in order returning the first error
+type multiCloser []io.Closer
+
+// Close all the closers
+func (mc multiCloser) Close() (err error) {
+	for _, c := range mc {
+		if closeErr := c.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
 // ObjectInfo describes a wrapped fs.ObjectInfo for being the source
@@ -1471,6 +1646,7 @@ var (
 	_ fs.CleanUpper      = (*Fs)(nil)
 	_ fs.UnWrapper       = (*Fs)(nil)
 	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
 	_ fs.Wrapper         = (*Fs)(nil)
 	_ fs.MergeDirser     = (*Fs)(nil)
@@ -2,16 +2,25 @@
 package compress
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/rclone/rclone/backend/drive"
 	_ "github.com/rclone/rclone/backend/local"
 	_ "github.com/rclone/rclone/backend/s3"
 	_ "github.com/rclone/rclone/backend/swift"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestIntegration runs integration tests against the remote
@@ -64,3 +73,124 @@ func TestRemoteGzip(t *testing.T) {
 		QuickTestOK: true,
 	})
 }
+
+// TestRemoteZstd tests zstd compression
+func TestRemoteZstd(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	tempdir := filepath.Join(os.TempDir(), "rclone-compress-test-zstd")
+	name := "TestCompressZstd"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"MergeDirs",
+			"DirCacheFlush",
+			"PutUnchecked",
+			"PutStream",
+			"UserInfo",
+			"Disconnect",
+		},
+		UnimplementableObjectMethods: []string{
+			"GetTier",
+			"SetTier",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "compress"},
+			{Name: name, Key: "remote", Value: tempdir},
+			{Name: name, Key: "mode", Value: "zstd"},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// TestRemoteLz4 tests lz4 compression
+func TestRemoteLz4(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	tempdir := filepath.Join(os.TempDir(), "rclone-compress-test-lz4")
+	name := "TestCompressLz4"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"MergeDirs",
+			"DirCacheFlush",
+			"PutUnchecked",
+			"PutStream",
+			"UserInfo",
+			"Disconnect",
+		},
+		UnimplementableObjectMethods: []string{
+			"GetTier",
+			"SetTier",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "compress"},
+			{Name: name, Key: "remote", Value: tempdir},
+			{Name: name, Key: "mode", Value: "lz4"},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// TestCompressModes checks that each mode round trips compressible
+// data, including reads from an offset
+func TestCompressModes(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	contents := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 1000)
+	for _, mode := range []string{"gzip", "zstd", "lz4"} {
+		t.Run(mode, func(t *testing.T) {
+			tempdir := t.TempDir()
+			f, err := fs.NewFs(ctx, fmt.Sprintf(":compress,remote=%q,mode=%s:", tempdir, mode))
+			require.NoError(t, err)
+			src := object.NewStaticObjectInfo("file.txt", time.Now(), int64(len(contents)), true, nil, nil)
+			_, err = f.Put(ctx, strings.NewReader(contents), src)
+			require.NoError(t, err)
+
+			o, err := f.NewObject(ctx, "file.txt")
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(contents)), o.Size())
+			assert.Equal(t, compressionModeFromName(mode), o.(*Object).meta.Mode)
+
+			for _, offset := range []int64{0, 1, 12345} {
+				rc, err := o.Open(ctx, &fs.RangeOption{Start: offset, End: offset + 99})
+				require.NoError(t, err)
+				got, err := ioutil.ReadAll(rc)
+				require.NoError(t, err)
+				require.NoError(t, rc.Close())
+				assert.Equal(t, contents[offset:offset+100], string(got), "offset %d", offset)
+			}
+		})
+	}
+}
+
+func TestNoCompressExtensions(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	ctx := context.Background()
+	tempdir := t.TempDir()
+	f, err := fs.NewFs(ctx, fmt.Sprintf(":compress,remote=%q,mode=zstd,no_compress_extensions=\"LOG,.dat\":", tempdir))
+	require.NoError(t, err)
+	cf := f.(*Fs)
+	assert.True(t, cf.skipCompress("dir/file.log"))
+	assert.True(t, cf.skipCompress("file.DAT"))
+	assert.False(t, cf.skipCompress("file.txt"))
+	assert.False(t, cf.skipCompress("file"))
+
+	// compressible data with a skipped extension is stored uncompressed
+	contents := strings.Repeat("a", 10000)
+	src := object.NewStaticObjectInfo("file.log", time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader(contents), src)
+	require.NoError(t, err)
+	assert.Equal(t, Uncompressed, o.(*Object).meta.Mode)
+	assert.Equal(t, "file.log.bin", o.(*Object).Object.Remote())
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/aes"
 	gocipher "crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base32"
 	"encoding/base64"
 	"errors"
@@ -23,6 +24,8 @@ import (
 	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/lib/version"
 	"github.com/rfjakob/eme"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
 	"golang.org/x/crypto/nacl/secretbox"
 	"golang.org/x/crypto/scrypt"
 )
@@ -30,11 +33,14 @@ import (
 // Constants
 const (
 	nameCipherBlockSize = aes.BlockSize
+	maxNamePadding      = 240 // largest multiple of nameCipherBlockSize PKCS#7 can pad to
 	fileMagic           = "RCLONE\x00\x00"
 	fileMagicSize       = len(fileMagic)
 	fileNonceSize       = 24
 	fileHeaderSize      = fileMagicSize + fileNonceSize
-	blockHeaderSize     = secretbox.Overhead
+	aeadFileMagic       = "RCLONEX\x00"
+	fileKeySize         = chacha20poly1305.KeySize
+	blockHeaderSize     = secretbox.Overhead // the same as chacha20poly1305.Overhead
 	blockDataSize       = 64 * 1024
 	blockSize           = blockHeaderSize + blockDataSize
 	encryptedSuffix     = ".bin" // when file name encryption is off we add this suffix to make sure the cloud provider doesn't process the file
@@ -51,6 +57,7 @@ var (
 	ErrorEncryptedFileBadHeader  = errors.New("file has truncated block header")
 	ErrorEncryptedBadMagic       = errors.New("not an encrypted file - bad magic string")
 	ErrorEncryptedBadBlock       = errors.New("failed to authenticate decrypted block - bad password?")
+	ErrorEncryptedTruncated      = errors.New("encrypted file is truncated - final block missing")
 	ErrorBadBase32Encoding       = errors.New("bad base32 filename encoding")
 	ErrorFileClosed              = errors.New("file already closed")
 	ErrorNotAnEncryptedFile      = errors.New("not an encrypted file - no \"" + encryptedSuffix + "\" suffix")
@@ -61,7 +68,11 @@ var (
 
 // Global variables
 var (
-	fileMagicBytes = []byte(fileMagic)
+	fileMagicBytes     = []byte(fileMagic)
+	aeadFileMagicBytes = []byte(aeadFileMagic)
+	aeadBlockAD        = []byte{0} // additional data for all blocks but the last
+	aeadFinalBlockAD   = []byte{1} // additional data for the last block
+	aeadFileKeyInfo    = []byte("rclone xchacha20-poly1305 file key")
 )
 
 // ReadSeekCloser is the interface of the read handles
@@ -167,9 +178,46 @@ func NewNameEncoding(s string) (enc fileNameEncoding, err error) {
 	return enc, err
 }
 
+// DataCipher is the cipher used to encrypt the file data
+type DataCipher int
+
+// DataCipher modes
+const (
+	DataCipherSecretbox DataCipher = iota
+	DataCipherXChaCha20Poly1305
+)
+
+// NewDataCipher turns a string into a DataCipher
+func NewDataCipher(s string) (dataCipher DataCipher, err error) {
+	s = strings.ToLower(s)
+	switch s {
+	case "secretbox":
+		dataCipher = DataCipherSecretbox
+	case "xchacha20-poly1305":
+		dataCipher = DataCipherXChaCha20Poly1305
+	default:
+		err = fmt.Errorf("unknown data cipher %q", s)
+	}
+	return dataCipher, err
+}
+
+// String turns dataCipher into a human readable string
+func (dataCipher DataCipher) String() (out string) {
+	switch dataCipher {
+	case DataCipherSecretbox:
+		out = "secretbox"
+	case DataCipherXChaCha20Poly1305:
+		out = "xchacha20-poly1305"
+	default:
+		out = fmt.Sprintf("Unknown data cipher %d", int(dataCipher))
+	}
+	return out
+}
+
 // Cipher defines an encoding and decoding cipher for the crypt backend
 type Cipher struct {
 	dataKey        [32]byte                  // Key for secretbox
+	dataCipher     DataCipher                // cipher for new files
 	nameKey        [32]byte                  // 16,24 or 32 bytes
 	nameTweak      [nameCipherBlockSize]byte // used to tweak the name crypto
 	block          gocipher.Block
@@ -178,6 +226,7 @@ type Cipher struct {
 	buffers        sync.Pool // encrypt/decrypt buffers
 	cryptoRand     io.Reader // read crypto random numbers from here
 	dirNameEncrypt bool
+	namePadding    int // pad names to a multiple of this before encrypting
 }
 
 // newCipher initialises the cipher.  If salt is "" then it uses a built in salt val
@@ -187,6 +236,7 @@ func newCipher(mode NameEncryptionMode, password, salt string, dirNameEncrypt bo
 		fileNameEnc:    enc,
 		cryptoRand:     rand.Reader,
 		dirNameEncrypt: dirNameEncrypt,
+		namePadding:    nameCipherBlockSize,
 	}
 	c.buffers.New = func() interface{} {
 		return make([]byte, blockSize)
@@ -229,6 +279,28 @@ func (c *Cipher) Key(password, salt string) (err error) {
 	return err
 }
 
+// SetDataCipher sets the cipher used to encrypt the data of new files
+//
+// Files encrypted with any data cipher can always be decrypted.
+func (c *Cipher) SetDataCipher(dataCipher DataCipher) {
+	c.dataCipher = dataCipher
+}
+
+// SetNamePadding sets the multiple of bytes names are padded to
+// before they are encrypted so their lengths leak less about them.
+//
+// Names padded to any multiple can always be decrypted.
+func (c *Cipher) SetNamePadding(n int) error {
+	if n == 0 {
+		n = nameCipherBlockSize
+	}
+	if n < nameCipherBlockSize || n > maxNamePadding || n%nameCipherBlockSize != 0 {
+		return fmt.Errorf("file name padding must be a multiple of %d from %d to %d, not %d", nameCipherBlockSize, nameCipherBlockSize, maxNamePadding, n)
+	}
+	c.namePadding = n
+	return nil
+}
+
 // getBlock gets a block from the pool of size blockSize
 func (c *Cipher) getBlock() []byte {
 	return c.buffers.Get().([]byte)
@@ -260,7 +332,7 @@ func (c *Cipher) encryptSegment(plaintext string) string {
 	if plaintext == "" {
 		return ""
 	}
-	paddedPlaintext := pkcs7.Pad(nameCipherBlockSize, []byte(plaintext))
+	paddedPlaintext := pkcs7.Pad(c.namePadding, []byte(plaintext))
 	ciphertext := eme.Transform(c.block, c.nameTweak[:], paddedPlaintext, eme.DirectionEncrypt)
 	return c.fileNameEnc.EncodeToString(ciphertext)
 }
@@ -285,13 +357,30 @@ func (c *Cipher) decryptSegment(ciphertext string) (string, error) {
 		return "", ErrorTooLongAfterDecode
 	}
 	paddedPlaintext := eme.Transform(c.block, c.nameTweak[:], rawCiphertext, eme.DirectionDecrypt)
-	plaintext, err := pkcs7.Unpad(nameCipherBlockSize, paddedPlaintext)
+	plaintext, err := unpadName(paddedPlaintext)
 	if err != nil {
 		return "", err
 	}
 	return string(plaintext), err
 }
 
+// unpadName removes the PKCS#7 padding from a name which may have
+// been padded to any multiple of nameCipherBlockSize
+func unpadName(paddedPlaintext []byte) ([]byte, error) {
+	plaintext, err := pkcs7.Unpad(nameCipherBlockSize, paddedPlaintext)
+	if err != pkcs7.ErrorPaddingTooLong {
+		return plaintext, err
+	}
+	// Find the multiple the name was padded to from the padding
+	padding := int(paddedPlaintext[len(paddedPlaintext)-1])
+	for n := (padding + nameCipherBlockSize - 1) / nameCipherBlockSize * nameCipherBlockSize; n <= maxNamePadding; n += nameCipherBlockSize {
+		if len(paddedPlaintext)%n == 0 {
+			return pkcs7.Unpad(n, paddedPlaintext)
+		}
+	}
+	return nil, err
+}
+
 // Simple obfuscation routines
 func (c *Cipher) obfuscateSegment(plaintext string) string {
 	if plaintext == "" {
@@ -658,44 +747,117 @@ func (n *nonce) add(x uint64) {
 	}
 }
 
+// fileParams are the per file values needed to repeat the encryption
+// of a file exactly
+type fileParams struct {
+	dataCipher DataCipher
+	nonce      nonce
+}
+
+// fileKey derives the key the AEAD cipher uses for a file from the
+// data key and the random nonce of the file
+func (c *Cipher) fileKey(nonce *nonce) (key [fileKeySize]byte, err error) {
+	_, err = io.ReadFull(hkdf.New(sha256.New, c.dataKey[:], nonce[:], aeadFileKeyInfo), key[:])
+	if err != nil {
+		return key, fmt.Errorf("failed to derive file key: %w", err)
+	}
+	return key, nil
+}
+
+// newBlockCipher makes the cipher for the data blocks of the file
+// described by p, or returns nil if the blocks are encrypted with
+// secretbox
+func (c *Cipher) newBlockCipher(p *fileParams) (gocipher.AEAD, error) {
+	if p.dataCipher != DataCipherXChaCha20Poly1305 {
+		return nil, nil
+	}
+	key, err := c.fileKey(&p.nonce)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.NewX(key[:])
+}
+
 // encrypter encrypts an io.Reader on the fly
 type encrypter struct {
 	mu       sync.Mutex
 	in       io.Reader
 	c        *Cipher
-	nonce    nonce
+	params   fileParams    // values the encryption started with
+	nonce    nonce         // nonce for the next block
+	aead     gocipher.AEAD // block cipher if not using secretbox
 	buf      []byte
 	readBuf  []byte
 	bufIndex int
 	bufSize  int
+	peeked   bool // set if readBuf[blockDataSize] holds the first byte of the next block
 	err      error
 }
 
 // newEncrypter creates a new file handle encrypting on the fly
-func (c *Cipher) newEncrypter(in io.Reader, nonce *nonce) (*encrypter, error) {
+//
+// If params is nil then new random values are made for the cipher
+// in use.
+func (c *Cipher) newEncrypter(in io.Reader, params *fileParams) (*encrypter, error) {
 	fh := &encrypter{
 		in:      in,
 		c:       c,
 		buf:     c.getBlock(),
 		readBuf: c.getBlock(),
-		bufSize: fileHeaderSize,
 	}
 	// Initialise nonce
-	if nonce != nil {
-		fh.nonce = *nonce
+	if params != nil {
+		fh.params = *params
 	} else {
-		err := fh.nonce.fromReader(c.cryptoRand)
+		fh.params.dataCipher = c.dataCipher
+		err := fh.params.nonce.fromReader(c.cryptoRand)
 		if err != nil {
 			return nil, err
 		}
 	}
+	fh.nonce = fh.params.nonce
+	var err error
+	fh.aead, err = c.newBlockCipher(&fh.params)
+	if err != nil {
+		return nil, err
+	}
+	fh.bufSize = fileHeaderSize
 	// Copy magic into buffer
-	copy(fh.buf, fileMagicBytes)
+	if fh.aead != nil {
+		copy(fh.buf, aeadFileMagicBytes)
+	} else {
+		copy(fh.buf, fileMagicBytes)
+	}
 	// Copy nonce into buffer
 	copy(fh.buf[fileMagicSize:], fh.nonce[:])
 	return fh, nil
 }
 
+// readBlock reads the next block of data into fh.readBuf returning
+// its size and whether it is the last block of the file
+//
+// To tell whether a full block is the last one, the AEAD cipher reads
+// one byte of the next block ahead.
+func (fh *encrypter) readBlock() (n int, final bool, err error) {
+	if fh.aead == nil {
+		n, err = io.ReadFull(fh.in, fh.readBuf[:blockDataSize])
+		return n, false, err
+	}
+	readBuf := fh.readBuf[:blockDataSize+1]
+	start := 0
+	if fh.peeked {
+		readBuf[0] = readBuf[blockDataSize]
+		start = 1
+	}
+	n, err = io.ReadFull(fh.in, readBuf[start:])
+	n += start
+	fh.peeked = n > blockDataSize
+	if fh.peeked {
+		return blockDataSize, false, nil
+	}
+	return n, true, err
+}
+
 // Read as per io.Reader
 func (fh *encrypter) Read(p []byte) (n int, err error) {
 	fh.mu.Lock()
@@ -707,8 +869,8 @@ func (fh *encrypter) Read(p []byte) (n int, err error) {
 	if fh.bufIndex >= fh.bufSize {
 		// Read data
 		// FIXME should overlap the reads with a go-routine and 2 buffers?
-		readBuf := fh.readBuf[:blockDataSize]
-		n, err = io.ReadFull(fh.in, readBuf)
+		var final bool
+		n, final, err = fh.readBlock()
 		if n == 0 {
 			// err can't be nil since:
 			// n == len(buf) if and only if err == nil.
@@ -717,7 +879,16 @@ func (fh *encrypter) Read(p []byte) (n int, err error) {
 		// possibly err != nil here, but we will process the
 		// data and the next call to ReadFull will return 0, err
 		// Encrypt the block using the nonce
-		secretbox.Seal(fh.buf[:0], readBuf[:n], fh.nonce.pointer(), &fh.c.dataKey)
+		readBuf := fh.readBuf[:n]
+		if fh.aead != nil {
+			ad := aeadBlockAD
+			if final {
+				ad = aeadFinalBlockAD
+			}
+			fh.aead.Seal(fh.buf[:0], fh.nonce[:], readBuf, ad)
+		} else {
+			secretbox.Seal(fh.buf[:0], readBuf, fh.nonce.pointer(), &fh.c.dataKey)
+		}
 		fh.bufIndex = 0
 		fh.bufSize = blockHeaderSize + n
 		fh.nonce.increment()
@@ -762,6 +933,10 @@ type decrypter struct {
 	rc           io.ReadCloser
 	nonce        nonce
 	initialNonce nonce
+	params       fileParams    // values read from the file header
+	aead         gocipher.AEAD // block cipher if not using secretbox
+	blocks       int64         // blocks decrypted since the last seek
+	final        bool          // set if the final block has been decrypted
 	c            *Cipher
 	buf          []byte
 	readBuf      []byte
@@ -791,11 +966,21 @@ func (c *Cipher) newDecrypter(rc io.ReadCloser) (*decrypter, error) {
 		return nil, fh.finishAndClose(err)
 	}
 	// check the magic
-	if !bytes.Equal(readBuf[:fileMagicSize], fileMagicBytes) {
+	switch {
+	case bytes.Equal(readBuf[:fileMagicSize], fileMagicBytes):
+		fh.params.dataCipher = DataCipherSecretbox
+	case bytes.Equal(readBuf[:fileMagicSize], aeadFileMagicBytes):
+		fh.params.dataCipher = DataCipherXChaCha20Poly1305
+	default:
 		return nil, fh.finishAndClose(ErrorEncryptedBadMagic)
 	}
 	// retrieve the nonce
-	fh.nonce.fromBuf(readBuf[fileMagicSize:])
+	fh.params.nonce.fromBuf(readBuf[fileMagicSize:])
+	fh.aead, err = c.newBlockCipher(&fh.params)
+	if err != nil {
+		return nil, fh.finishAndClose(err)
+	}
+	fh.nonce = fh.params.nonce
 	fh.initialNonce = fh.nonce
 	return fh, nil
 }
@@ -849,6 +1034,9 @@ func (fh *decrypter) fillBuffer() (err error) {
 	if n == 0 {
 		// err can't be nil since:
 		// n == len(buf) if and only if err == nil.
+		if err == io.EOF && fh.aead != nil && fh.blocks > 0 && !fh.final {
+			return ErrorEncryptedTruncated
+		}
 		return err
 	}
 	// possibly err != nil here, but we will process the data and
@@ -862,19 +1050,42 @@ func (fh *decrypter) fillBuffer() (err error) {
 		return ErrorEncryptedFileBadHeader
 	}
 	// Decrypt the block using the nonce
-	_, ok := secretbox.Open(fh.buf[:0], readBuf[:n], fh.nonce.pointer(), &fh.c.dataKey)
+	var ok bool
+	if fh.aead != nil {
+		ok = fh.openAEAD(readBuf[:n])
+	} else {
+		_, ok = secretbox.Open(fh.buf[:0], readBuf[:n], fh.nonce.pointer(), &fh.c.dataKey)
+	}
 	if !ok {
 		if err != nil {
 			return err // return pending error as it is likely more accurate
 		}
 		return ErrorEncryptedBadBlock
 	}
+	fh.blocks++
 	fh.bufIndex = 0
 	fh.bufSize = n - blockHeaderSize
 	fh.nonce.increment()
 	return nil
 }
 
+// openAEAD decrypts a block with the AEAD cipher into fh.buf
+// returning false if it fails to authenticate
+//
+// Only the last block of the file is marked as final, so this fails if
+// blocks are read after it.
+func (fh *decrypter) openAEAD(block []byte) bool {
+	if fh.final {
+		return false
+	}
+	_, err := fh.aead.Open(fh.buf[:0], fh.nonce[:], block, aeadBlockAD)
+	if err != nil {
+		_, err = fh.aead.Open(fh.buf[:0], fh.nonce[:], block, aeadFinalBlockAD)
+		fh.final = err == nil
+	}
+	return err == nil
+}
+
 // Read as per io.Reader
 func (fh *decrypter) Read(p []byte) (n int, err error) {
 	fh.mu.Lock()
@@ -971,6 +1182,8 @@ func (fh *decrypter) RangeSeek(ctx context.Context, offset int64, whence int, li
 	// Move the nonce on the correct number of blocks from the start
 	fh.nonce = fh.initialNonce
 	fh.nonce.add(uint64(blocks))
+	fh.blocks = 0
+	fh.final = false
 
 	// Can we seek underlying stream directly?
 	if do, ok := fh.rc.(fs.RangeSeeker); ok {
@@ -271,6 +271,44 @@ func TestEncryptSegmentBase32768(t *testing.T) {
 	}, false)
 }
 
+func TestEncryptSegmentPadding(t *testing.T) {
+	enc, _ := NewNameEncoding("base32")
+	c, _ := newCipher(NameEncryptionStandard, "", "", true, enc)
+	unpadded := c.encryptSegment("potato")
+	require.NoError(t, c.SetNamePadding(64))
+
+	// Names shorter than the padding all encrypt to the same length
+	for _, in := range []string{"1", "potato", strings.Repeat("a", 63)} {
+		encrypted := c.encryptSegment(in)
+		assert.Equal(t, 103, len(encrypted), in)
+		decrypted, err := c.decryptSegment(encrypted)
+		require.NoError(t, err, in)
+		assert.Equal(t, in, decrypted)
+	}
+	encrypted := c.encryptSegment(strings.Repeat("a", 64))
+	assert.Equal(t, 205, len(encrypted))
+	decrypted, err := c.decryptSegment(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("a", 64), decrypted)
+
+	// Names padded differently can still be decrypted
+	assert.NotEqual(t, unpadded, c.encryptSegment("potato"))
+	decrypted, err = c.decryptSegment(unpadded)
+	require.NoError(t, err)
+	assert.Equal(t, "potato", decrypted)
+	require.NoError(t, c.SetNamePadding(0))
+	assert.Equal(t, unpadded, c.encryptSegment("potato"))
+	require.NoError(t, c.SetNamePadding(240))
+	decrypted, err = c.decryptSegment(c.encryptSegment("potato"))
+	require.NoError(t, err)
+	assert.Equal(t, "potato", decrypted)
+
+	// Bad padding values
+	for _, n := range []int{-16, 8, 17, 256} {
+		assert.Error(t, c.SetNamePadding(n), n)
+	}
+}
+
 func TestDecryptSegmentBase32(t *testing.T) {
 	// We've tested the forwards above, now concentrate on the errors
 	longName := make([]byte, 3328)
@@ -1620,3 +1658,232 @@ func TestKey(t *testing.T) {
 	assert.Equal(t, [32]byte{}, c.nameKey)
 	assert.Equal(t, [16]byte{}, c.nameTweak)
 }
+
+func TestNewDataCipher(t *testing.T) {
+	for _, test := range []struct {
+		in          string
+		expected    DataCipher
+		expectedErr string
+	}{
+		{"secretbox", DataCipherSecretbox, ""},
+		{"xchacha20-poly1305", DataCipherXChaCha20Poly1305, ""},
+		{"XChaCha20-Poly1305", DataCipherXChaCha20Poly1305, ""},
+		{"potato", DataCipherSecretbox, "unknown data cipher \"potato\""},
+	} {
+		actual, actualErr := NewDataCipher(test.in)
+		assert.Equal(t, test.expected, actual)
+		if test.expectedErr == "" {
+			assert.NoError(t, actualErr)
+		} else {
+			assert.EqualError(t, actualErr, test.expectedErr)
+		}
+	}
+}
+
+func TestDataCipherString(t *testing.T) {
+	assert.Equal(t, "secretbox", DataCipherSecretbox.String())
+	assert.Equal(t, "xchacha20-poly1305", DataCipherXChaCha20Poly1305.String())
+	assert.Equal(t, "Unknown data cipher 2", DataCipher(2).String())
+}
+
+// newAEADCipher makes a cipher with password which encrypts data
+// with XChaCha20-Poly1305
+func newAEADCipher(t *testing.T, password string) *Cipher {
+	c, err := newCipher(NameEncryptionStandard, password, "", true, nil)
+	require.NoError(t, err)
+	c.SetDataCipher(DataCipherXChaCha20Poly1305)
+	return c
+}
+
+// encryptAll encrypts plaintext with c
+func encryptAll(t *testing.T, c *Cipher, plaintext []byte) []byte {
+	encrypted, err := c.EncryptData(bytes.NewBuffer(plaintext))
+	require.NoError(t, err)
+	ciphertext, err := ioutil.ReadAll(encrypted)
+	require.NoError(t, err)
+	return ciphertext
+}
+
+// decryptAll decrypts ciphertext with c
+func decryptAll(c *Cipher, ciphertext []byte) ([]byte, error) {
+	fh, err := c.DecryptData(ioutil.NopCloser(bytes.NewBuffer(ciphertext)))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	return ioutil.ReadAll(fh)
+}
+
+func TestEncryptedSizeXChaCha20Poly1305(t *testing.T) {
+	c := newAEADCipher(t, "")
+	for _, test := range []struct {
+		in       int64
+		expected int64
+	}{
+		{0, 32},
+		{1, 32 + 16 + 1},
+		{65536, 32 + 16 + 65536},
+		{65537, 32 + 16 + 65536 + 16 + 1},
+		{1 << 20, 32 + 16*(16+65536)},
+	} {
+		actual := c.EncryptedSize(test.in)
+		assert.Equal(t, test.expected, actual, fmt.Sprintf("Testing %d", test.in))
+		recovered, err := c.DecryptedSize(test.expected)
+		assert.NoError(t, err, fmt.Sprintf("Testing reverse %d", test.expected))
+		assert.Equal(t, test.in, recovered, fmt.Sprintf("Testing reverse %d", test.expected))
+	}
+	_, err := c.DecryptedSize(31)
+	assert.Equal(t, ErrorEncryptedFileTooShort, err)
+}
+
+func TestEncryptDecryptXChaCha20Poly1305(t *testing.T) {
+	c := newAEADCipher(t, "potato")
+	for _, size := range []int{0, 1, 100, blockDataSize - 1, blockDataSize, blockDataSize + 1, 2 * blockDataSize, 3*blockDataSize + 17} {
+		plaintext, err := ioutil.ReadAll(newRandomSource(int64(size)))
+		require.NoError(t, err)
+		ciphertext := encryptAll(t, c, plaintext)
+		assert.Equal(t, c.EncryptedSize(int64(size)), int64(len(ciphertext)), "size %d", size)
+		assert.Equal(t, aeadFileMagicBytes, ciphertext[:fileMagicSize])
+
+		decrypted, err := decryptAll(c, ciphertext)
+		require.NoError(t, err, "size %d", size)
+		assert.Equal(t, plaintext, decrypted, "size %d", size)
+	}
+}
+
+func TestEncryptXChaCha20Poly1305KeyPerFile(t *testing.T) {
+	c := newAEADCipher(t, "potato")
+	plaintext := []byte("hello")
+	a := encryptAll(t, c, plaintext)
+	b := encryptAll(t, c, plaintext)
+
+	da, err := c.newDecrypter(ioutil.NopCloser(bytes.NewBuffer(a)))
+	require.NoError(t, err)
+	db, err := c.newDecrypter(ioutil.NopCloser(bytes.NewBuffer(b)))
+	require.NoError(t, err)
+	assert.Equal(t, DataCipherXChaCha20Poly1305, da.params.dataCipher)
+	keyA, err := c.fileKey(&da.params.nonce)
+	require.NoError(t, err)
+	keyB, err := c.fileKey(&db.params.nonce)
+	require.NoError(t, err)
+	assert.NotEqual(t, keyA, keyB)
+	assert.NotEqual(t, c.dataKey, keyA)
+	require.NoError(t, da.Close())
+	require.NoError(t, db.Close())
+
+	// Encrypting again with the same params makes the same file
+	enc, err := c.newEncrypter(bytes.NewBuffer(plaintext), &da.params)
+	require.NoError(t, err)
+	again, err := ioutil.ReadAll(enc)
+	require.NoError(t, err)
+	assert.Equal(t, a, again)
+}
+
+func TestDecryptMixedDataCiphers(t *testing.T) {
+	secretbox, err := newCipher(NameEncryptionStandard, "potato", "", true, nil)
+	require.NoError(t, err)
+	aead := newAEADCipher(t, "potato")
+	plaintext, err := ioutil.ReadAll(newRandomSource(100000))
+	require.NoError(t, err)
+
+	// Each cipher can read files written by the other
+	decrypted, err := decryptAll(aead, encryptAll(t, secretbox, plaintext))
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+	decrypted, err = decryptAll(secretbox, encryptAll(t, aead, plaintext))
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+}
+
+func TestDecryptXChaCha20Poly1305Tampered(t *testing.T) {
+	c := newAEADCipher(t, "potato")
+	plaintext, err := ioutil.ReadAll(newRandomSource(3 * blockDataSize))
+	require.NoError(t, err)
+	ciphertext := encryptAll(t, c, plaintext)
+	block := func(i int) int {
+		return fileHeaderSize + i*blockSize
+	}
+	modify := func(f func(b []byte) []byte) []byte {
+		return f(append([]byte(nil), ciphertext...))
+	}
+
+	for _, test := range []struct {
+		what string
+		in   []byte
+		want error
+	}{{
+		what: "wrong password",
+		in:   encryptAll(t, newAEADCipher(t, "sausage"), plaintext),
+		want: ErrorEncryptedBadBlock,
+	}, {
+		what: "corrupt nonce",
+		in:   modify(func(b []byte) []byte { b[fileMagicSize] ^= 1; return b }),
+		want: ErrorEncryptedBadBlock,
+	}, {
+		what: "corrupt data",
+		in:   modify(func(b []byte) []byte { b[block(1)+100] ^= 1; return b }),
+		want: ErrorEncryptedBadBlock,
+	}, {
+		what: "truncated at block boundary",
+		in:   ciphertext[:block(2)],
+		want: ErrorEncryptedTruncated,
+	}, {
+		what: "blocks swapped",
+		in: modify(func(b []byte) []byte {
+			copy(b[block(0):], ciphertext[block(1):block(2)])
+			copy(b[block(1):], ciphertext[block(0):block(1)])
+			return b
+		}),
+		want: ErrorEncryptedBadBlock,
+	}, {
+		what: "block appended",
+		in:   append(append([]byte(nil), ciphertext...), ciphertext[block(0):block(1)]...),
+		want: ErrorEncryptedBadBlock,
+	}} {
+		_, err := decryptAll(c, test.in)
+		assert.Equal(t, test.want, err, test.what)
+	}
+}
+
+func TestDecryptDataSeekXChaCha20Poly1305(t *testing.T) {
+	const dataSize = 150000
+	plaintext, err := ioutil.ReadAll(newRandomSource(dataSize))
+	require.NoError(t, err)
+	aead := newAEADCipher(t, "potato")
+	ciphertext := encryptAll(t, aead, plaintext)
+
+	open := func(ctx context.Context, underlyingOffset, underlyingLimit int64) (io.ReadCloser, error) {
+		end := int64(len(ciphertext))
+		if underlyingLimit >= 0 && underlyingOffset+underlyingLimit < end {
+			end = underlyingOffset + underlyingLimit
+		}
+		return ioutil.NopCloser(bytes.NewBuffer(ciphertext[underlyingOffset:end])), nil
+	}
+
+	// Read with both data ciphers configured to check the cipher
+	// is worked out from the file
+	secretbox, err := newCipher(NameEncryptionStandard, "potato", "", true, nil)
+	require.NoError(t, err)
+	for _, c := range []*Cipher{aead, secretbox} {
+		for _, offset := range []int{0, 1, 65535, 65536, 65537, 131072, dataSize - 1} {
+			for _, limit := range []int{-1, 0, 1, 65536, 65537} {
+				if offset+limit > dataSize {
+					continue
+				}
+				what := fmt.Sprintf("%v: offset = %d, limit = %d", c.dataCipher, offset, limit)
+				fh, err := c.DecryptDataSeek(context.Background(), open, int64(offset), int64(limit))
+				require.NoError(t, err, what)
+				got, err := ioutil.ReadAll(fh)
+				require.NoError(t, err, what)
+				want := plaintext[offset:]
+				if limit >= 0 {
+					want = want[:limit]
+				}
+				assert.Equal(t, want, got, what)
+				require.NoError(t, fh.Close())
+			}
+		}
+	}
+}
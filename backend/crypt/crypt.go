@@ -142,6 +142,51 @@ length and if it's case sensitve.`,
 				},
 			},
 			Advanced: true,
+		}, {
+			Name: "data_cipher",
+			Help: `Cipher used to encrypt the file data.
+
+Files encrypted with either cipher can always be read. This only
+chooses the cipher used for new files.
+
+The xchacha20-poly1305 cipher uses a different key for each file,
+derived from the key made from the password and the random nonce in
+the file header. It fails to read any file which has been tampered
+with, including files which have been truncated.
+
+Files made by the two ciphers are the same size, so this can be
+changed on an existing remote.`,
+			Default: "secretbox",
+			Examples: []fs.OptionExample{
+				{
+					Value: "secretbox",
+					Help:  "XSalsa20-Poly1305 with the same key for every file (the original format).",
+				},
+				{
+					Value: "xchacha20-poly1305",
+					Help:  "XChaCha20-Poly1305 with a different key for each file.",
+				},
+			},
+			Advanced: true,
+		}, {
+			Name: "filename_padding",
+			Help: `Pad file names to a multiple of this many bytes before encrypting them.
+
+With standard file name encryption the length of an encrypted name
+shows the length of the original name to within 16 bytes. Set this to
+a larger multiple of 16, up to 240, to pad names into bigger buckets so
+their lengths leak less about them, e.g. 64 makes all names shorter
+than 64 bytes encrypt to the same length.
+
+This makes encrypted names longer so they may hit the name length
+limits of the remote sooner. 0 uses the minimum padding of 16 bytes.
+
+Names padded to any size can be decrypted when listing, but changing
+this changes the encrypted names rclone looks for, so existing files
+can't be found by name. Only set this for a new remote or one where
+all the files will be uploaded again.`,
+			Default:  0,
+			Advanced: true,
 		}},
 	})
 }
@@ -170,10 +215,18 @@ func newCipherForConfig(opt *Options) (*Cipher, error) {
 	if err != nil {
 		return nil, err
 	}
+	dataCipher, err := NewDataCipher(opt.DataCipher)
+	if err != nil {
+		return nil, err
+	}
 	cipher, err := newCipher(mode, password, salt, opt.DirectoryNameEncryption, enc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make cipher: %w", err)
 	}
+	cipher.SetDataCipher(dataCipher)
+	if err = cipher.SetNamePadding(opt.FilenamePadding); err != nil {
+		return nil, err
+	}
 	return cipher, nil
 }
 
@@ -263,6 +316,8 @@ type Options struct {
 	ServerSideAcrossConfigs bool   `config:"server_side_across_configs"`
 	ShowMapping             bool   `config:"show_mapping"`
 	FilenameEncoding        string `config:"filename_encoding"`
+	DataCipher              string `config:"data_cipher"`
+	FilenamePadding         int    `config:"filename_padding"`
 }
 
 // Fs represents a wrapped fs.Fs
@@ -383,6 +438,26 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	})
 }
 
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	do := f.Fs.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	return do(ctx, f.cipher.EncryptDirName(dir), func(entries fs.DirEntries) error {
+		newEntries, err := f.encryptEntries(ctx, entries)
+		if err != nil {
+			return err
+		}
+		return callback(newEntries)
+	})
+}
+
 // NewObject finds the Object at remote.
 func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
 	o, err := f.Fs.NewObject(ctx, f.cipher.EncryptFileName(remote))
@@ -397,7 +472,7 @@ type putFn func(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ..
 // put implements Put or PutStream
 func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption, put putFn) (fs.Object, error) {
 	if f.opt.NoDataEncryption {
-		o, err := put(ctx, in, f.newObjectInfo(src, nonce{}), options...)
+		o, err := put(ctx, in, f.newObjectInfo(src, fileParams{}), options...)
 		if err == nil && o != nil {
 			o = f.newObject(o)
 		}
@@ -429,7 +504,7 @@ func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options [
 	}
 
 	// Transfer the data
-	o, err := put(ctx, wrappedIn, f.newObjectInfo(src, encrypter.nonce), options...)
+	o, err := put(ctx, wrappedIn, f.newObjectInfo(src, encrypter.params), options...)
 	if err != nil {
 		return nil, err
 	}
@@ -589,7 +664,7 @@ func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo,
 	if err != nil {
 		return nil, err
 	}
-	o, err := do(ctx, wrappedIn, f.newObjectInfo(src, encrypter.nonce))
+	o, err := do(ctx, wrappedIn, f.newObjectInfo(src, encrypter.params))
 	if err != nil {
 		return nil, err
 	}
@@ -642,11 +717,12 @@ func (f *Fs) DecryptFileName(encryptedFileName string) (string, error) {
 	return f.cipher.DecryptFileName(encryptedFileName)
 }
 
-// computeHashWithNonce takes the nonce and encrypts the contents of
-// src with it, and calculates the hash given by HashType on the fly
+// computeHashWithNonce takes the nonce and data cipher and encrypts
+// the contents of src with them, and calculates the hash given by
+// HashType on the fly
 //
 // Note that we break lots of encapsulation in this function.
-func (f *Fs) computeHashWithNonce(ctx context.Context, nonce nonce, src fs.Object, hashType hash.Type) (hashStr string, err error) {
+func (f *Fs) computeHashWithNonce(ctx context.Context, params fileParams, src fs.Object, hashType hash.Type) (hashStr string, err error) {
 	// Open the src for input
 	in, err := src.Open(ctx)
 	if err != nil {
@@ -655,7 +731,7 @@ func (f *Fs) computeHashWithNonce(ctx context.Context, nonce nonce, src fs.Objec
 	defer fs.CheckClose(in, &err)
 
 	// Now encrypt the src with the nonce
-	out, err := f.cipher.newEncrypter(in, &nonce)
+	out, err := f.cipher.newEncrypter(in, &params)
 	if err != nil {
 		return "", fmt.Errorf("failed to make encrypter: %w", err)
 	}
@@ -693,7 +769,8 @@ func (f *Fs) ComputeHash(ctx context.Context, o *Object, src fs.Object, hashType
 		_ = in.Close()
 		return "", fmt.Errorf("failed to open object to read nonce: %w", err)
 	}
-	nonce := d.nonce
+	params := d.params
+	nonce := params.nonce
 	// fs.Debugf(o, "Read nonce % 2x", nonce)
 
 	// Check nonce isn't all zeros
@@ -713,7 +790,7 @@ func (f *Fs) ComputeHash(ctx context.Context, o *Object, src fs.Object, hashType
 		return "", fmt.Errorf("failed to close nonce read: %w", err)
 	}
 
-	return f.computeHashWithNonce(ctx, nonce, src, hashType)
+	return f.computeHashWithNonce(ctx, params, src, hashType)
 }
 
 // MergeDirs merges the contents of all the directories passed
@@ -1005,15 +1082,15 @@ func (f *Fs) Shutdown(ctx context.Context) error {
 // This encrypts the remote name and adjusts the size
 type ObjectInfo struct {
 	fs.ObjectInfo
-	f     *Fs
-	nonce nonce
+	f      *Fs
+	params fileParams
 }
 
-func (f *Fs) newObjectInfo(src fs.ObjectInfo, nonce nonce) *ObjectInfo {
+func (f *Fs) newObjectInfo(src fs.ObjectInfo, params fileParams) *ObjectInfo {
 	return &ObjectInfo{
 		ObjectInfo: src,
 		f:          f,
-		nonce:      nonce,
+		params:     params,
 	}
 }
 
@@ -1057,7 +1134,7 @@ func (o *ObjectInfo) Hash(ctx context.Context, hash hash.Type) (string, error) {
 	if srcObj.Fs().Features().IsLocal {
 		// Read the data and encrypt it to calculate the hash
 		fs.Debugf(o, "Computing %v hash of encrypted source", hash)
-		return o.f.computeHashWithNonce(ctx, o.nonce, srcObj, hash)
+		return o.f.computeHashWithNonce(ctx, o.params, srcObj, hash)
 	}
 	return "", nil
 }
@@ -1167,6 +1244,7 @@ var (
 	_ fs.CleanUpper      = (*Fs)(nil)
 	_ fs.UnWrapper       = (*Fs)(nil)
 	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
 	_ fs.Wrapper         = (*Fs)(nil)
 	_ fs.MergeDirser     = (*Fs)(nil)
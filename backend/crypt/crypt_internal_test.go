@@ -76,7 +76,7 @@ func testObjectInfo(t *testing.T, f *Fs, wrap bool) {
 	var outBuf bytes.Buffer
 	enc, err := f.cipher.newEncrypter(inBuf, nil)
 	require.NoError(t, err)
-	nonce := enc.nonce // read the nonce at the start
+	params := enc.params // read the nonce and data cipher at the start
 	_, err = io.Copy(&outBuf, enc)
 	require.NoError(t, err)
 
@@ -88,7 +88,7 @@ func testObjectInfo(t *testing.T, f *Fs, wrap bool) {
 
 	// wrap the object in a crypt for upload using the nonce we
 	// saved from the encrypter
-	src := f.newObjectInfo(oi, nonce)
+	src := f.newObjectInfo(oi, params)
 
 	// Test ObjectInfo methods
 	if !f.opt.NoDataEncryption {
@@ -137,9 +137,40 @@ func testComputeHash(t *testing.T, f *Fs) {
 	assert.Equal(t, remoteObjHash, computedHash)
 }
 
+// Test files written with both data ciphers are listed with the
+// right sizes
+func testMixedDataCiphers(t *testing.T, f *Fs) {
+	if f.opt.NoDataEncryption {
+		t.Skip("data encryption is off")
+	}
+	ctx := context.Background()
+	defer func() {
+		_ = f.Rmdir(ctx, "mixed_data_ciphers")
+	}()
+	defer f.cipher.SetDataCipher(f.cipher.dataCipher)
+	want := map[string]int64{}
+	for _, dataCipher := range []DataCipher{DataCipherSecretbox, DataCipherXChaCha20Poly1305} {
+		f.cipher.SetDataCipher(dataCipher)
+		for _, size := range []int{0, 1, blockDataSize, blockDataSize + 1} {
+			remote := fmt.Sprintf("mixed_data_ciphers/%v-%d", dataCipher, size)
+			_, cleanup := uploadFile(t, f, remote, random.String(size))
+			defer cleanup()
+			want[remote] = int64(size)
+		}
+	}
+	entries, err := f.List(ctx, "mixed_data_ciphers")
+	require.NoError(t, err)
+	got := map[string]int64{}
+	for _, entry := range entries {
+		got[entry.Remote()] = entry.Size()
+	}
+	assert.Equal(t, want, got)
+}
+
 // InternalTest is called by fstests.Run to extra tests
 func (f *Fs) InternalTest(t *testing.T) {
 	t.Run("ObjectInfo", func(t *testing.T) { testObjectInfo(t, f, false) })
 	t.Run("ObjectInfoWrap", func(t *testing.T) { testObjectInfo(t, f, true) })
 	t.Run("ComputeHash", func(t *testing.T) { testComputeHash(t, f) })
+	t.Run("MixedDataCiphers", func(t *testing.T) { testMixedDataCiphers(t, f) })
 }
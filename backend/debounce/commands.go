@@ -0,0 +1,90 @@
+package debounce
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "flush",
+	Short: "Upload files waiting to be uploaded now.",
+	Long: `This uploads the files which are waiting for the quiet period to end
+without waiting any longer. If no paths are given all the waiting files
+are uploaded, otherwise those at or below the paths given.
+
+Usage Example:
+
+    rclone backend flush debounce: [path...]
+`,
+}, {
+	Name:  "pending",
+	Short: "List the files waiting to be uploaded.",
+	Long: `This shows the path, size, modification time and the time of the
+first write which hasn't been uploaded of each waiting file as JSON.
+
+Usage Example:
+
+    rclone backend pending debounce:
+
+Note that files are only held by the rclone process which wrote them,
+so this is most useful with the remote control, e.g.
+
+    rclone rc backend/command command=pending fs=debounce:
+`,
+}}
+
+// pendingInfo is the output of the pending command
+type pendingInfo struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Since   time.Time `json:"since"`
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "flush":
+		if len(arg) == 0 {
+			return nil, f.flushDir(ctx, "")
+		}
+		for _, remote := range arg {
+			if err := f.flush(ctx, remote); err != nil {
+				return nil, err
+			}
+			if err := f.flushDir(ctx, remote); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case "pending":
+		infos := []pendingInfo{}
+		f.mu.Lock()
+		for _, p := range f.pending {
+			infos = append(infos, pendingInfo{
+				Path:    p.remote,
+				Size:    p.size,
+				ModTime: p.modTime,
+				Since:   p.first,
+			})
+		}
+		f.mu.Unlock()
+		sort.Slice(infos, func(i, j int) bool {
+			return infos[i].Path < infos[j].Path
+		})
+		return infos, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
@@ -0,0 +1,583 @@
+// Package debounce implements a backend which delays uploads until
+// a file has stopped changing
+package debounce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/atexit"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "debounce",
+		Description: "Delay uploads until files stop changing",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			Help: `Any metadata supported by the underlying remote is read once files
+have been uploaded. Metadata isn't written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to upload the files to.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name:    "delay",
+			Default: fs.Duration(5 * time.Second),
+			Help: `Time a file must stay unchanged before it is uploaded.
+
+Each write to a file which hasn't been uploaded yet starts the wait
+again, so only the last version of a file which is written many times
+in quick succession is uploaded.`,
+		}, {
+			Name:     "max_delay",
+			Default:  fs.Duration(time.Minute),
+			Advanced: true,
+			Help: `Longest time to hold a file before uploading it.
+
+Files which keep changing are uploaded at least this often. Set to 0
+for no limit.`,
+		}, {
+			Name:     "spool_dir",
+			Advanced: true,
+			Help: `Local directory to keep files in until they are uploaded.
+
+If empty the system temporary directory (or --temp-dir) is used.`,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote   string      `config:"remote"`
+	Delay    fs.Duration `config:"delay"`
+	MaxDelay fs.Duration `config:"max_delay"`
+	SpoolDir string      `config:"spool_dir"`
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	opt      Options
+	features *fs.Features // optional features
+	wrapper  fs.Fs
+	ctx      context.Context // for background uploads
+	atexit   atexit.FnHandle
+
+	mu        sync.Mutex
+	pending   map[string]*pending      // files waiting to be uploaded by remote
+	uploading map[string]chan struct{} // closed when the upload of remote finishes
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point debounce remote at itself - check the value of the remote setting")
+	}
+	if opt.Delay < 0 || opt.MaxDelay < 0 {
+		return nil, errors.New("delay and max_delay can't be negative")
+	}
+	f := &Fs{
+		name:      name,
+		root:      rpath,
+		opt:       opt,
+		ctx:       fs.CopyConfig(context.Background(), ctx),
+		pending:   map[string]*pending{},
+		uploading: map[string]chan struct{}{},
+	}
+	remotePath := fspath.JoinRootPath(opt.Remote, rpath)
+	var baseErr error
+	f.Fs, baseErr = cache.Get(ctx, remotePath)
+	if baseErr != nil && baseErr != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", remotePath, baseErr)
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+	// ListR, Copy and PutUnchecked are left out so everything goes
+	// through the pending files
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		ReadMimeType:            true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		SetTier:                 true,
+		GetTier:                 true,
+		ReadMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, f.Fs).WrapsFs(f, f.Fs)
+	f.atexit = atexit.Register(func() {
+		f.flushAll(f.ctx)
+	})
+	return f, baseErr
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("debounce root '%s'", f.root)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// parentDir returns the parent directory of remote or "" for the root
+func parentDir(remote string) string {
+	dir := path.Dir(remote)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// isIn returns true if remote is in dir or below it
+func isIn(remote, dir string) bool {
+	return dir == "" || strings.HasPrefix(remote, dir+"/")
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, dir)
+	notFound := errors.Is(err, fs.ErrorDirNotFound)
+	if err != nil && !notFound {
+		return nil, err
+	}
+	// add the pending files and the directories they are in
+	files := map[string]*pending{}
+	dirs := map[string]struct{}{}
+	f.mu.Lock()
+	for remote, p := range f.pending {
+		if !isIn(remote, dir) {
+			continue
+		}
+		parent := parentDir(remote)
+		if parent == dir {
+			files[remote] = p
+			continue
+		}
+		for parentDir(parent) != dir {
+			parent = parentDir(parent)
+		}
+		dirs[parent] = struct{}{}
+	}
+	f.mu.Unlock()
+	if notFound && len(files) == 0 && len(dirs) == 0 {
+		return nil, fs.ErrorDirNotFound
+	}
+	out := make(fs.DirEntries, 0, len(entries)+len(files)+len(dirs))
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			if _, found := files[x.Remote()]; found {
+				continue
+			}
+			entry = f.newObject(x)
+		case fs.Directory:
+			delete(dirs, x.Remote())
+		}
+		out = append(out, entry)
+	}
+	for _, p := range files {
+		out = append(out, f.newPendingObject(p))
+	}
+	for remote := range dirs {
+		out = append(out, fs.NewDir(remote, time.Now()))
+	}
+	return out, nil
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// Files waiting to be uploaded have to be merged into the listing so
+// this reads the whole directory and returns it as a single page.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	return fs.ListPFallback(ctx, f, dir, callback)
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if p := f.getPending(remote); p != nil {
+		return f.newPendingObject(p), nil
+	}
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// The data is kept locally and uploaded once it stops changing.
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	p, err := f.spool(ctx, src.Remote(), in, src, options)
+	if err != nil {
+		return nil, err
+	}
+	return f.newPendingObject(p), nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.Fs.Mkdir(ctx, dir)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	if f.hasPending(dir) {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	return f.Fs.Rmdir(ctx, dir)
+}
+
+// Purge all files in the directory specified
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	f.discardPending(dir)
+	return do(ctx, dir)
+}
+
+// Move src to this remote using server-side move operations.
+//
+// Files which haven't been uploaded yet are renamed without
+// uploading them.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	switch srcObj := src.(type) {
+	case *pendingObject:
+		if srcObj.f != f {
+			return nil, fs.ErrorCantMove
+		}
+		return f.rename(ctx, srcObj.p, remote)
+	case *Object:
+		do := f.Fs.Features().Move
+		if do == nil {
+			return nil, fs.ErrorCantMove
+		}
+		// the moved file replaces any pending one
+		f.discard(remote)
+		o, err := do(ctx, srcObj.Object, remote)
+		if err != nil {
+			return nil, err
+		}
+		return f.newObject(o), nil
+	}
+	return nil, fs.ErrorCantMove
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Files waiting to be uploaded in the source are uploaded first.
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	if err := srcFs.flushDir(ctx, srcRemote); err != nil {
+		return err
+	}
+	return do(ctx, srcFs.Fs, srcRemote, dstRemote)
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	if do := f.Fs.Features().ChangeNotify; do != nil {
+		do(ctx, notifyFunc, pollIntervalChan)
+	}
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	do := f.Fs.Features().PublicLink
+	if do == nil {
+		return "", errors.New("PublicLink not supported")
+	}
+	if err := f.flush(ctx, remote); err != nil {
+		return "", err
+	}
+	return do(ctx, remote, expire, unlink)
+}
+
+// Shutdown the backend, uploading the files which are waiting and
+// closing any background tasks and any cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	f.flushAll(ctx)
+	atexit.Unregister(f.atexit)
+	do := f.Fs.Features().Shutdown
+	if do == nil {
+		return nil
+	}
+	return do(ctx)
+}
+
+// Object describes a file which has been uploaded to the wrapped
+// remote
+type Object struct {
+	fs.Object
+	f       *Fs
+	pending *pendingObject // set if the object has been updated
+}
+
+// newObject wraps o
+func (f *Fs) newObject(o fs.Object) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// Size returns the size of the file
+func (o *Object) Size() int64 {
+	if o.pending != nil {
+		return o.pending.Size()
+	}
+	return o.Object.Size()
+}
+
+// ModTime returns the modification time of the file
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.pending != nil {
+		return o.pending.ModTime(ctx)
+	}
+	return o.Object.ModTime(ctx)
+}
+
+// Hash returns the selected checksum of the file
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if o.pending != nil {
+		return o.pending.Hash(ctx, ht)
+	}
+	return o.Object.Hash(ctx, ht)
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	if o.pending != nil {
+		return o.pending.Open(ctx, options...)
+	}
+	return o.Object.Open(ctx, options...)
+}
+
+// SetModTime sets the modification time of the file
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	if o.pending != nil {
+		return o.pending.SetModTime(ctx, t)
+	}
+	return o.Object.SetModTime(ctx, t)
+}
+
+// Update in to the object with the modTime given of the given size
+//
+// The new data is kept locally and uploaded once it stops changing.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	p, err := o.f.spool(ctx, o.Remote(), in, src, options)
+	if err != nil {
+		return err
+	}
+	o.pending = o.f.newPendingObject(p)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	if o.pending != nil {
+		return o.pending.Remove(ctx)
+	}
+	o.f.discard(o.Remote())
+	return o.Object.Remove(ctx)
+}
+
+// MimeType returns the content type of the Object if
+// known, or "" if not
+func (o *Object) MimeType(ctx context.Context) string {
+	if do, ok := o.Object.(fs.MimeTyper); ok {
+		return do.MimeType(ctx)
+	}
+	return ""
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	if o.pending != nil {
+		return nil, nil
+	}
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	if do, ok := o.Object.(fs.IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *Object) GetTier() string {
+	if do, ok := o.Object.(fs.GetTierer); ok {
+		return do.GetTier()
+	}
+	return ""
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	if do, ok := o.Object.(fs.SetTierer); ok {
+		return do.SetTier(tier)
+	}
+	return errors.New("SetTier not supported")
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.Object          = (*Object)(nil)
+	_ fs.MimeTyper       = (*Object)(nil)
+	_ fs.Metadataer      = (*Object)(nil)
+	_ fs.IDer            = (*Object)(nil)
+	_ fs.GetTierer       = (*Object)(nil)
+	_ fs.SetTierer       = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+)
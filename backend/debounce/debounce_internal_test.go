@@ -0,0 +1,152 @@
+package debounce
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs returns a debounce remote with the delays given on an
+// empty directory, and the path of that directory so tests can see
+// when the uploads land. It is shut down at the end of the test so
+// any pending uploads are flushed before the directory is removed.
+func newTestFs(t *testing.T, delay, maxDelay string) (*Fs, string) {
+	f, tempRoot := fstest.NewWrappingFs(t, "debounce", fmt.Sprintf(`delay="%s",max_delay="%s"`, delay, maxDelay))
+	return f.(*Fs), tempRoot
+}
+
+// read returns the contents of o
+func read(t *testing.T, o fs.Object) string {
+	in, err := o.Open(context.Background())
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+// uploaded returns the contents of remote in the wrapped directory
+// or "" if it isn't there
+func uploaded(t *testing.T, dir, remote string) string {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(remote)))
+	if os.IsNotExist(err) {
+		return ""
+	}
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestCoalescesWrites(t *testing.T) {
+	ctx := context.Background()
+	f, dir := newTestFs(t, "200ms", "1m")
+
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "file.txt", ModTime: time.Now()}, "one", false)
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "file.txt", ModTime: time.Now()}, "two", false)
+	require.NoError(t, o.Update(ctx, bytes.NewBufferString("three"), object.NewStaticObjectInfo("file.txt", time.Now(), 5, true, nil, nil)))
+
+	// nothing is uploaded yet but the last version can be read
+	assert.Equal(t, "", uploaded(t, dir, "file.txt"))
+	o, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "three", read(t, o))
+	assert.Equal(t, int64(5), o.Size())
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+
+	assert.Eventually(t, func() bool {
+		return uploaded(t, dir, "file.txt") == "three"
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Nil(t, f.getPending("file.txt"))
+}
+
+func TestMaxDelay(t *testing.T) {
+	ctx := context.Background()
+	f, dir := newTestFs(t, "1h", "300ms")
+
+	start := time.Now()
+	for time.Since(start) < 2*time.Second {
+		_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "busy.txt", ModTime: time.Now()}, time.Now().String(), false)
+		if uploaded(t, dir, "busy.txt") != "" {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	assert.NotEqual(t, "", uploaded(t, dir, "busy.txt"), "file which keeps changing should be uploaded")
+}
+
+func TestMovePending(t *testing.T) {
+	ctx := context.Background()
+	f, dir := newTestFs(t, "1h", "0")
+
+	// an uploaded version of the old name should be removed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.txt"), []byte("stale"), 0666))
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "old.txt", ModTime: time.Now()}, "fresh", false)
+	moved, err := f.Move(ctx, o, "sub/new.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "sub/new.txt", moved.Remote())
+	assert.Equal(t, "", uploaded(t, dir, "old.txt"))
+	assert.Equal(t, "", uploaded(t, dir, "sub/new.txt"))
+	assert.Nil(t, f.getPending("old.txt"))
+
+	// the directory can't be removed while the file is waiting
+	assert.ErrorIs(t, f.Rmdir(ctx, "sub"), fs.ErrorDirectoryNotEmpty)
+
+	_, err = f.Command(ctx, "flush", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", uploaded(t, dir, "sub/new.txt"))
+	assert.Equal(t, "fresh", read(t, moved))
+}
+
+func TestRemovePending(t *testing.T) {
+	ctx := context.Background()
+	f, dir := newTestFs(t, "1h", "0")
+
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "gone.txt", ModTime: time.Now()}, "data", false)
+	p := f.getPending("gone.txt")
+	require.NotNil(t, p)
+	require.NoError(t, o.Remove(ctx))
+	assert.Nil(t, f.getPending("gone.txt"))
+	_, err := os.Stat(p.spool)
+	assert.True(t, os.IsNotExist(err), "spool file should be removed")
+
+	require.NoError(t, f.Shutdown(ctx))
+	assert.Equal(t, "", uploaded(t, dir, "gone.txt"))
+}
+
+func TestPendingCommand(t *testing.T) {
+	ctx := context.Background()
+	f, dir := newTestFs(t, "1h", "0")
+
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "b.txt", ModTime: time.Now()}, "bb", false)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/a.txt", ModTime: time.Now()}, "a", false)
+	out, err := f.Command(ctx, "pending", nil, nil)
+	require.NoError(t, err)
+	infos := out.([]pendingInfo)
+	require.Equal(t, 2, len(infos))
+	assert.Equal(t, "b.txt", infos[0].Path)
+	assert.Equal(t, "dir/a.txt", infos[1].Path)
+	assert.Equal(t, int64(1), infos[1].Size)
+
+	_, err = f.Command(ctx, "flush", []string{"dir"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "a", uploaded(t, dir, "dir/a.txt"))
+	assert.Equal(t, "", uploaded(t, dir, "b.txt"))
+
+	// pending files are uploaded on shutdown
+	require.NoError(t, f.Shutdown(ctx))
+	assert.Equal(t, "bb", uploaded(t, dir, "b.txt"))
+}
@@ -0,0 +1,51 @@
+// Test Debounce filesystem interface
+package debounce_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/backend/debounce"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*debounce.Object)(nil),
+	})
+}
+
+// TestLocal runs integration tests against a local directory
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestDebounce"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*debounce.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"Copy",
+			"PutUnchecked",
+			"MergeDirs",
+			"ListR",
+			"UserInfo",
+			"Disconnect",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "debounce"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-debounce-test")},
+			{Name: name, Key: "delay", Value: "100ms"},
+		},
+		QuickTestOK: true,
+	})
+}
@@ -0,0 +1,494 @@
+package debounce
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/lib/readers"
+)
+
+// pending is a file which has been written but not uploaded yet
+//
+// The fields other than modTime, timer and the flags don't change
+// after it is made. The others are protected by Fs.mu.
+type pending struct {
+	remote    string
+	spool     string // path of the local copy of the data
+	size      int64
+	hashes    map[hash.Type]string
+	options   []fs.OpenOption
+	first     time.Time // when the file was first written since the last upload
+	modTime   time.Time
+	timer     *time.Timer
+	uploading bool // set while the spool file is being uploaded
+	modified  bool // set if modTime was changed during the upload
+}
+
+// spoolDir returns the directory to keep the pending files in
+func (f *Fs) spoolDir() string {
+	if f.opt.SpoolDir != "" {
+		return f.opt.SpoolDir
+	}
+	return os.TempDir()
+}
+
+// spool copies in to a local file and schedules its upload as
+// remote, replacing any pending version of the file
+func (f *Fs) spool(ctx context.Context, remote string, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption) (*pending, error) {
+	hasher, err := hash.NewMultiHasherTypes(f.Fs.Hashes())
+	if err != nil {
+		return nil, err
+	}
+	if f.getPending(remote) == nil {
+		if err := f.mkParentDir(ctx, remote); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.CreateTemp(f.spoolDir(), "rclone-debounce-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to make spool file: %w", err)
+	}
+	size, err := io.Copy(io.MultiWriter(file, hasher), in)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to spool %q: %w", remote, err)
+	}
+	p := &pending{
+		remote:  remote,
+		spool:   file.Name(),
+		size:    size,
+		hashes:  hasher.Sums(),
+		options: options,
+		first:   time.Now(),
+		modTime: src.ModTime(ctx),
+	}
+	f.mu.Lock()
+	if old := f.pending[remote]; old != nil {
+		old.timer.Stop()
+		if !old.uploading {
+			p.first = old.first
+			f.removeSpool(old)
+		}
+		fs.Debugf(remote, "debounce: replacing version waiting to be uploaded")
+	}
+	f.pending[remote] = p
+	f.schedule(p)
+	f.mu.Unlock()
+	return p, nil
+}
+
+// mkParentDir makes the directory remote is in straight away, so it
+// can be listed and removed like the ones with uploaded files in
+func (f *Fs) mkParentDir(ctx context.Context, remote string) error {
+	return f.Fs.Mkdir(ctx, parentDir(remote))
+}
+
+// removeSpool deletes the local copy of p
+func (f *Fs) removeSpool(p *pending) {
+	if err := os.Remove(p.spool); err != nil && !os.IsNotExist(err) {
+		fs.Errorf(p.remote, "debounce: failed to remove spool file: %v", err)
+	}
+}
+
+// schedule (re)starts the timer to upload p after the quiet period
+//
+// Call with f.mu held
+func (f *Fs) schedule(p *pending) {
+	wait := time.Duration(f.opt.Delay)
+	if f.opt.MaxDelay > 0 {
+		left := time.Until(p.first.Add(time.Duration(f.opt.MaxDelay)))
+		if left < 0 {
+			left = 0
+		}
+		if left < wait {
+			wait = left
+		}
+	}
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.timer = time.AfterFunc(wait, func() {
+		if err := f.upload(f.ctx, p); err != nil {
+			fs.Errorf(p.remote, "debounce: upload failed - will retry: %v", err)
+		}
+	})
+}
+
+// upload uploads p if it is still the current version of the file
+//
+// If another version of the file is being uploaded it waits for that
+// to finish first. If the upload fails it is scheduled again.
+func (f *Fs) upload(ctx context.Context, p *pending) error {
+	f.mu.Lock()
+	for {
+		if f.pending[p.remote] != p {
+			f.mu.Unlock()
+			return nil
+		}
+		done := f.uploading[p.remote]
+		if done == nil {
+			break
+		}
+		f.mu.Unlock()
+		<-done
+		f.mu.Lock()
+	}
+	p.timer.Stop()
+	p.uploading = true
+	p.modified = false
+	done := make(chan struct{})
+	f.uploading[p.remote] = done
+	src := object.NewStaticObjectInfo(p.remote, p.modTime, p.size, true, p.hashes, f.Fs)
+	f.mu.Unlock()
+
+	fs.Debugf(p.remote, "debounce: uploading")
+	err := f.put(ctx, p, src)
+
+	f.mu.Lock()
+	delete(f.uploading, p.remote)
+	close(done)
+	p.uploading = false
+	current := f.pending[p.remote] == p
+	switch {
+	case !current:
+		f.removeSpool(p)
+	case err != nil || p.modified:
+		p.first = time.Now()
+		f.schedule(p)
+	default:
+		delete(f.pending, p.remote)
+		f.removeSpool(p)
+	}
+	f.mu.Unlock()
+	return err
+}
+
+// put uploads the spool file of p to the wrapped remote
+func (f *Fs) put(ctx context.Context, p *pending, src fs.ObjectInfo) (err error) {
+	in, err := os.Open(p.spool)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+	o, err := f.Fs.NewObject(ctx, p.remote)
+	if err == nil {
+		return o.Update(ctx, in, src, p.options...)
+	}
+	_, err = f.Fs.Put(ctx, in, src, p.options...)
+	return err
+}
+
+// pendingIn returns the pending files in dir or below it
+func (f *Fs) pendingIn(dir string) (ps []*pending) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for remote, p := range f.pending {
+		if isIn(remote, dir) {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+// getPending returns the pending version of remote or nil
+func (f *Fs) getPending(remote string) *pending {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pending[remote]
+}
+
+// hasPending returns true if there are pending files in dir or below it
+func (f *Fs) hasPending(dir string) bool {
+	return len(f.pendingIn(dir)) > 0
+}
+
+// flush uploads remote now if it is pending
+func (f *Fs) flush(ctx context.Context, remote string) error {
+	if p := f.getPending(remote); p != nil {
+		return f.upload(ctx, p)
+	}
+	return nil
+}
+
+// flushDir uploads all the pending files in dir or below it now
+func (f *Fs) flushDir(ctx context.Context, dir string) (err error) {
+	for _, p := range f.pendingIn(dir) {
+		if uploadErr := f.upload(ctx, p); uploadErr != nil {
+			fs.Errorf(p.remote, "debounce: upload failed: %v", uploadErr)
+			err = uploadErr
+		}
+	}
+	return err
+}
+
+// flushAll uploads all the pending files now
+func (f *Fs) flushAll(ctx context.Context) {
+	if ps := f.pendingIn(""); len(ps) > 0 {
+		fs.Infof(f, "debounce: uploading %d files waiting to be uploaded", len(ps))
+	}
+	_ = f.flushDir(ctx, "")
+}
+
+// waitUpload waits until remote isn't being uploaded
+//
+// Call with f.mu held
+func (f *Fs) waitUpload(remote string) {
+	for f.uploading[remote] != nil {
+		done := f.uploading[remote]
+		f.mu.Unlock()
+		<-done
+		f.mu.Lock()
+	}
+}
+
+// waitUploads waits until no uploads are running for files in dir or
+// below it
+//
+// Call with f.mu held
+func (f *Fs) waitUploads(dir string) {
+	for {
+		var done chan struct{}
+		for remote, ch := range f.uploading {
+			if isIn(remote, dir) {
+				done = ch
+				break
+			}
+		}
+		if done == nil {
+			return
+		}
+		f.mu.Unlock()
+		<-done
+		f.mu.Lock()
+	}
+}
+
+// discardLocked forgets the pending version of remote
+//
+// Call with f.mu held
+func (f *Fs) discardLocked(remote string) {
+	if p := f.pending[remote]; p != nil {
+		p.timer.Stop()
+		delete(f.pending, remote)
+		if !p.uploading {
+			f.removeSpool(p)
+		}
+	}
+}
+
+// discard forgets the pending version of remote and waits for any
+// upload of it to finish, so it doesn't come back afterwards
+func (f *Fs) discard(remote string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.discardLocked(remote)
+	f.waitUpload(remote)
+}
+
+// discardPending forgets all the pending files in dir or below it
+func (f *Fs) discardPending(dir string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for remote := range f.pending {
+		if isIn(remote, dir) {
+			f.discardLocked(remote)
+		}
+	}
+	f.waitUploads(dir)
+}
+
+// rename moves the pending file p to remote without uploading it
+//
+// If p has been uploaded already the uploaded file is moved instead.
+func (f *Fs) rename(ctx context.Context, p *pending, remote string) (fs.Object, error) {
+	if err := f.mkParentDir(ctx, remote); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.waitUpload(p.remote)
+	if f.pending[p.remote] != p {
+		f.mu.Unlock()
+		o, err := f.Fs.NewObject(ctx, p.remote)
+		if err != nil {
+			return nil, fs.ErrorCantMove
+		}
+		return f.Move(ctx, f.newObject(o), remote)
+	}
+	p.timer.Stop()
+	delete(f.pending, p.remote)
+	f.discardLocked(remote)
+	moved := &pending{
+		remote:  remote,
+		spool:   p.spool,
+		size:    p.size,
+		hashes:  p.hashes,
+		options: p.options,
+		first:   p.first,
+		modTime: p.modTime,
+	}
+	f.pending[remote] = moved
+	f.schedule(moved)
+	f.mu.Unlock()
+	// remove any previously uploaded version from the old name
+	if o, err := f.Fs.NewObject(ctx, p.remote); err == nil {
+		if err := o.Remove(ctx); err != nil {
+			return nil, fmt.Errorf("failed to remove uploaded version of %q: %w", p.remote, err)
+		}
+	}
+	return f.newPendingObject(moved), nil
+}
+
+// pendingObject describes a file which hasn't been uploaded yet
+type pendingObject struct {
+	f *Fs
+	p *pending
+}
+
+// newPendingObject makes an object for p
+func (f *Fs) newPendingObject(p *pending) *pendingObject {
+	return &pendingObject{
+		f: f,
+		p: p,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *pendingObject) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *pendingObject) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.p.remote
+}
+
+// Remote returns the remote path
+func (o *pendingObject) Remote() string {
+	return o.p.remote
+}
+
+// Size returns the size of the file
+func (o *pendingObject) Size() int64 {
+	return o.p.size
+}
+
+// ModTime returns the modification time of the file
+func (o *pendingObject) ModTime(ctx context.Context) time.Time {
+	o.f.mu.Lock()
+	defer o.f.mu.Unlock()
+	return o.p.modTime
+}
+
+// Storable returns whether this object is storable
+func (o *pendingObject) Storable() bool {
+	return true
+}
+
+// Hash returns the selected checksum of the file
+func (o *pendingObject) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if !o.f.Fs.Hashes().Contains(ht) {
+		return "", hash.ErrUnsupported
+	}
+	return o.p.hashes[ht], nil
+}
+
+// Open an object for read
+//
+// This reads the local copy, or the uploaded file if it has gone
+// since the object was made.
+func (o *pendingObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.p.size)
+		case *fs.SeekOption:
+			offset = x.Offset
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	in, err := os.Open(o.p.spool)
+	if os.IsNotExist(err) {
+		uploaded, err := o.f.Fs.NewObject(ctx, o.p.remote)
+		if err != nil {
+			return nil, err
+		}
+		return uploaded.Open(ctx, options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if _, err = in.Seek(offset, io.SeekStart); err != nil {
+			_ = in.Close()
+			return nil, err
+		}
+	}
+	if limit >= 0 {
+		return readers.NewLimitedReadCloser(in, limit), nil
+	}
+	return in, nil
+}
+
+// SetModTime sets the modification time of the file
+func (o *pendingObject) SetModTime(ctx context.Context, t time.Time) error {
+	f := o.f
+	f.mu.Lock()
+	o.p.modTime = t
+	current := f.pending[o.p.remote] == o.p
+	if current {
+		o.p.modified = o.p.uploading
+		f.mu.Unlock()
+		return nil
+	}
+	f.mu.Unlock()
+	uploaded, err := f.Fs.NewObject(ctx, o.p.remote)
+	if err != nil {
+		return err
+	}
+	return uploaded.SetModTime(ctx, t)
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *pendingObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	p, err := o.f.spool(ctx, o.p.remote, in, src, options)
+	if err != nil {
+		return err
+	}
+	o.p = p
+	return nil
+}
+
+// Remove an object
+func (o *pendingObject) Remove(ctx context.Context) error {
+	o.f.discard(o.p.remote)
+	uploaded, err := o.f.Fs.NewObject(ctx, o.p.remote)
+	if err == fs.ErrorObjectNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return uploaded.Remove(ctx)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object = (*pendingObject)(nil)
+)
@@ -0,0 +1,893 @@
+// Package dedupe implements a backend which stores files by the hash
+// of their content so identical files are only stored once
+package dedupe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/random"
+	"golang.org/x/sync/errgroup"
+)
+
+// Layout of the wrapped remote
+const (
+	treeDir         = "tree"  // manifests, one per file, in the same tree as the files
+	blobDir         = "blobs" // data, named by SHA-256
+	tmpDir          = "tmp"   // uploads in progress whose hash isn't known yet
+	refSuffix       = ".ref"  // suffix of the reference count for a blob
+	manifestVersion = 1
+	maxManifestSize = 1024 * 1024
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "dedupe",
+		Description: "Store identical files only once",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			System: map[string]fs.MetadataHelp{
+				"mtime": {
+					Help:    "Time of last modification, read from the manifest.",
+					Type:    "RFC 3339",
+					Example: "2006-01-02T15:04:05.999999999Z07:00",
+				},
+				"content-type": {
+					Help:    "MIME type, also known as media type",
+					Type:    "string",
+					Example: "text/plain",
+				},
+			},
+			Help: `All metadata is stored in the manifest so any key may be read and written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to store the blobs and manifests in.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote string `config:"remote"`
+}
+
+// Fs represents a content addressed store on a wrapped remote
+type Fs struct {
+	base     fs.Fs
+	name     string
+	root     string // root of the tree within treeDir
+	opt      Options
+	features *fs.Features // optional features
+	wrapper  fs.Fs
+	refMu    sync.Mutex // held while changing reference counts
+}
+
+// manifest describes a file stored in a blob
+type manifest struct {
+	Version  int         `json:"ver"`
+	Hash     string      `json:"sha256"`
+	Size     int64       `json:"size"`
+	ModTime  time.Time   `json:"mtime"`
+	MimeType string      `json:"mime_type,omitempty"`
+	Metadata fs.Metadata `json:"metadata,omitempty"`
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point dedupe remote at itself - check the value of the remote setting")
+	}
+	f := &Fs{
+		name: name,
+		root: strings.Trim(rpath, "/"),
+		opt:  opt,
+	}
+	f.base, err = cache.Get(ctx, opt.Remote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", opt.Remote, err)
+	}
+	cache.PinUntilFinalized(f.base, f)
+	baseFeatures := f.base.Features()
+	f.features = (&fs.Features{
+		CaseInsensitive:         baseFeatures.CaseInsensitive,
+		CanHaveEmptyDirectories: baseFeatures.CanHaveEmptyDirectories,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).WrapsFs(f, f.base)
+	if baseFeatures.DirMove == nil {
+		f.features.DirMove = nil
+	}
+
+	if f.root != "" {
+		if _, err := f.base.NewObject(ctx, f.manifestPath("")); err == nil {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// manifestPath returns the path on the base of the manifest for remote
+func (f *Fs) manifestPath(remote string) string {
+	return path.Join(treeDir, f.root, remote)
+}
+
+// blobPath returns the path on the base of the blob with hash h
+func blobPath(h string) string {
+	return path.Join(blobDir, h[:2], h)
+}
+
+// refPath returns the path on the base of the reference count for
+// the blob with hash h
+func refPath(h string) string {
+	return blobPath(h) + refSuffix
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("dedupe root '%s'", f.root)
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.SHA256)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.base
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// readManifest reads and decodes the manifest in o
+func readManifest(ctx context.Context, o fs.Object) (*manifest, error) {
+	if o.Size() > maxManifestSize {
+		return nil, fmt.Errorf("manifest %q too big", o.Remote())
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(in, maxManifestSize))
+	_ = in.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %q: %w", o.Remote(), err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest %q: %w", o.Remote(), err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("manifest %q has unsupported version %d", o.Remote(), m.Version)
+	}
+	if len(m.Hash) != hash.Width(hash.SHA256, false) {
+		return nil, fmt.Errorf("manifest %q has bad hash %q", o.Remote(), m.Hash)
+	}
+	return &m, nil
+}
+
+// readManifests reads the manifests in objs in parallel
+func (f *Fs) readManifests(ctx context.Context, objs []fs.Object) ([]*manifest, error) {
+	ms := make([]*manifest, len(objs))
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fs.GetConfig(ctx).Checkers)
+	for i := range objs {
+		i := i
+		g.Go(func() (err error) {
+			ms[i], err = readManifest(gCtx, objs[i])
+			return err
+		})
+	}
+	return ms, g.Wait()
+}
+
+// writeManifest writes m as the manifest for remote
+func (f *Fs) writeManifest(ctx context.Context, remote string, m *manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	info := object.NewStaticObjectInfo(f.manifestPath(remote), m.ModTime, int64(len(data)), true, nil, f.base)
+	if _, err := f.base.Put(ctx, bytes.NewReader(data), info); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// readRef reads the reference count for the blob with hash h
+//
+// It returns 0 if there is no reference count
+func (f *Fs) readRef(ctx context.Context, h string) (int, error) {
+	o, err := f.base.NewObject(ctx, refPath(h))
+	if err == fs.ErrorObjectNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return 0, err
+	}
+	data, err := io.ReadAll(io.LimitReader(in, 64))
+	_ = in.Close()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// writeRef sets the reference count for the blob with hash h
+func (f *Fs) writeRef(ctx context.Context, h string, n int) error {
+	data := []byte(strconv.Itoa(n) + "\n")
+	info := object.NewStaticObjectInfo(refPath(h), time.Now(), int64(len(data)), true, nil, f.base)
+	_, err := f.base.Put(ctx, bytes.NewReader(data), info)
+	return err
+}
+
+// removeBlob removes the blob with hash h and its reference count
+func (f *Fs) removeBlob(ctx context.Context, h string) error {
+	for _, remote := range []string{blobPath(h), refPath(h)} {
+		o, err := f.base.NewObject(ctx, remote)
+		if err == fs.ErrorObjectNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := o.Remove(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addRef adds delta to the reference count of the blob with hash h,
+// removing the blob if it is no longer referenced
+func (f *Fs) addRef(ctx context.Context, h string, delta int) error {
+	f.refMu.Lock()
+	defer f.refMu.Unlock()
+	if delta > 0 {
+		// The blob may have been removed since it was stored
+		if _, err := f.base.NewObject(ctx, blobPath(h)); err != nil {
+			return fserrors.RetryError(fmt.Errorf("blob %s vanished before it could be referenced: %w", h, err))
+		}
+	}
+	n, err := f.readRef(ctx, h)
+	if err != nil {
+		return fmt.Errorf("failed to read reference count for %s: %w", h, err)
+	}
+	n += delta
+	if n <= 0 {
+		fs.Debugf(f, "Removing unreferenced blob %s", h)
+		return f.removeBlob(ctx, h)
+	}
+	if err := f.writeRef(ctx, h, n); err != nil {
+		return fmt.Errorf("failed to write reference count for %s: %w", h, err)
+	}
+	return nil
+}
+
+// release drops a reference to the blob with hash h, logging errors
+// as gc will tidy up any leftovers
+func (f *Fs) release(ctx context.Context, h string) {
+	if err := f.addRef(ctx, h, -1); err != nil {
+		fs.Errorf(f, "Failed to release blob - run gc to fix: %v", err)
+	}
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	baseEntries, err := f.base.List(ctx, f.manifestPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	return f.convertEntries(ctx, baseEntries)
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// The manifests in each page are read before it is passed on.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	do := f.base.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	return do(ctx, f.manifestPath(dir), func(baseEntries fs.DirEntries) error {
+		entries, err := f.convertEntries(ctx, baseEntries)
+		if err != nil {
+			return err
+		}
+		return callback(entries)
+	})
+}
+
+// convertEntries converts a listing of manifests on the base into
+// entries on f, reading the manifests of the objects
+func (f *Fs) convertEntries(ctx context.Context, baseEntries fs.DirEntries) (entries fs.DirEntries, err error) {
+	prefix := f.manifestPath("") + "/"
+	var objs []fs.Object
+	for _, entry := range baseEntries {
+		remote := strings.TrimPrefix(entry.Remote(), prefix)
+		switch x := entry.(type) {
+		case fs.Directory:
+			entries = append(entries, fs.NewDirCopy(ctx, x).SetRemote(remote))
+		case fs.Object:
+			objs = append(objs, x)
+		}
+	}
+	ms, err := f.readManifests(ctx, objs)
+	if err != nil {
+		return nil, err
+	}
+	for i, o := range objs {
+		entries = append(entries, f.newObject(strings.TrimPrefix(o.Remote(), prefix), ms[i]))
+	}
+	return entries, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := f.base.NewObject(ctx, f.manifestPath(remote))
+	if err != nil {
+		return nil, err
+	}
+	m, err := readManifest(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, m), nil
+}
+
+// storeBlob stores the data from in as a blob, returning its hash
+// and size. If a blob with the same content is already stored the
+// data isn't uploaded again.
+func (f *Fs) storeBlob(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (h string, size int64, err error) {
+	size = src.Size()
+	hasher, err := hash.NewMultiHasherTypes(hash.Set(hash.SHA256))
+	if err != nil {
+		return "", 0, err
+	}
+
+	// If the hash is known in advance upload straight to the blob
+	if h, _ = src.Hash(ctx, hash.SHA256); h != "" && size >= 0 {
+		if _, err := f.base.NewObject(ctx, blobPath(h)); err == nil {
+			fs.Debugf(src, "Reusing existing blob %s", h)
+			return h, size, nil
+		}
+		info := object.NewStaticObjectInfo(blobPath(h), src.ModTime(ctx), size, true, nil, f.base)
+		blob, err := f.base.Put(ctx, io.TeeReader(in, hasher), info, options...)
+		if err != nil {
+			return "", 0, err
+		}
+		if got, _ := hasher.SumString(hash.SHA256, false); got != h {
+			_ = blob.Remove(ctx)
+			return "", 0, fmt.Errorf("corrupted on transfer: sha256 hash differ src %q vs dst %q", h, got)
+		}
+		return h, size, nil
+	}
+
+	// Otherwise upload to a temporary name then rename it
+	info := object.NewStaticObjectInfo(path.Join(tmpDir, random.String(16)), src.ModTime(ctx), size, true, nil, f.base)
+	var tmp fs.Object
+	if do := f.base.Features().PutStream; size < 0 && do != nil {
+		tmp, err = do(ctx, io.TeeReader(in, hasher), info, options...)
+	} else {
+		tmp, err = f.base.Put(ctx, io.TeeReader(in, hasher), info, options...)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	h, err = hasher.SumString(hash.SHA256, false)
+	if err != nil {
+		return "", 0, err
+	}
+	size = hasher.Size()
+	if _, err := f.base.NewObject(ctx, blobPath(h)); err == nil {
+		fs.Debugf(src, "Reusing existing blob %s", h)
+		return h, size, tmp.Remove(ctx)
+	}
+	if _, err := operations.Move(ctx, f.base, nil, blobPath(h), tmp); err != nil {
+		return "", 0, fmt.Errorf("failed to move blob into place: %w", err)
+	}
+	return h, size, nil
+}
+
+// put stores the data from in and writes the manifest for src,
+// releasing the blob used by any previous version of the file
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (*Object, error) {
+	remote := src.Remote()
+	meta, err := fs.GetMetadataOptions(ctx, src, options)
+	if err != nil {
+		return nil, err
+	}
+	h, size, err := f.storeBlob(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	m := &manifest{
+		Version:  manifestVersion,
+		Hash:     h,
+		Size:     size,
+		ModTime:  src.ModTime(ctx),
+		MimeType: fs.MimeType(ctx, src),
+	}
+	m.setMetadata(meta)
+	return f.link(ctx, remote, m)
+}
+
+// link takes a reference to the blob in m and writes it as the
+// manifest for remote
+func (f *Fs) link(ctx context.Context, remote string, m *manifest) (*Object, error) {
+	var old *manifest
+	if o, err := f.NewObject(ctx, remote); err == nil {
+		old = o.(*Object).m
+	}
+	if err := f.addRef(ctx, m.Hash, 1); err != nil {
+		return nil, err
+	}
+	if err := f.writeManifest(ctx, remote, m); err != nil {
+		f.release(ctx, m.Hash)
+		return nil, err
+	}
+	if old != nil {
+		f.release(ctx, old.Hash)
+	}
+	return f.newObject(remote, m), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.base.Mkdir(ctx, f.manifestPath(dir))
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.base.Rmdir(ctx, f.manifestPath(dir))
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This only needs a new manifest as the data is shared.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.f.base != f.base {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	m := *srcObj.m
+	return f.link(ctx, remote, &m)
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This only needs the manifest moving as the data is shared.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.f.base != f.base {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	dst, err := f.Copy(ctx, src, remote)
+	if err != nil {
+		return nil, err
+	}
+	if err := srcObj.Remove(ctx); err != nil {
+		return dst, err
+	}
+	return dst, nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// This only needs the manifests moving as the data is shared.
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok || srcFs.base != f.base {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	do := f.base.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	return do(ctx, f.base, srcFs.manifestPath(srcRemote), f.manifestPath(dstRemote))
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.base.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	do := f.base.Features().Shutdown
+	if do == nil {
+		return nil
+	}
+	return do(ctx)
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "gc",
+	Short: "Remove unreferenced blobs and fix reference counts.",
+	Long: `This reads every manifest in the remote, recounts the references to
+each blob and then removes blobs which aren't referenced and fixes
+any reference counts which are wrong. Leftover temporary uploads older
+than a day are removed too.
+
+This is only needed if files were changed without going through the
+dedupe remote or if an operation was interrupted.
+
+Usage Examples:
+
+    rclone backend gc dedupe:
+    rclone backend gc dedupe: --dry-run
+
+It returns a summary of what was done.
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "gc":
+		return f.gc(ctx)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// gcStats is the result of the gc command
+type gcStats struct {
+	Files      int   `json:"files"`
+	Blobs      int   `json:"blobs"`
+	Removed    int   `json:"removed"`
+	FreedBytes int64 `json:"freedBytes"`
+	RefsFixed  int   `json:"refsFixed"`
+	TmpRemoved int   `json:"tmpRemoved"`
+}
+
+// gc removes unreferenced blobs and fixes reference counts
+func (f *Fs) gc(ctx context.Context) (*gcStats, error) {
+	f.refMu.Lock()
+	defer f.refMu.Unlock()
+	var stats gcStats
+
+	// Count the references in all the manifests
+	var manifests []fs.Object
+	err := operations.ListFn(ctx, f.base, func(o fs.Object) {
+		if strings.HasPrefix(o.Remote(), treeDir+"/") {
+			manifests = append(manifests, o)
+		}
+	})
+	if err != nil && !errors.Is(err, fs.ErrorDirNotFound) {
+		return nil, err
+	}
+	ms, err := f.readManifests(ctx, manifests)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]int, len(ms))
+	for _, m := range ms {
+		refs[m.Hash]++
+	}
+	stats.Files = len(ms)
+
+	// Check each blob against the count
+	var blobs, tmps []fs.Object
+	err = operations.ListFn(ctx, f.base, func(o fs.Object) {
+		remote := o.Remote()
+		switch {
+		case strings.HasPrefix(remote, blobDir+"/") && !strings.HasSuffix(remote, refSuffix):
+			blobs = append(blobs, o)
+		case strings.HasPrefix(remote, tmpDir+"/") && time.Since(o.ModTime(ctx)) > 24*time.Hour:
+			tmps = append(tmps, o)
+		}
+	})
+	if err != nil && !errors.Is(err, fs.ErrorDirNotFound) {
+		return nil, err
+	}
+	for _, blob := range blobs {
+		h := path.Base(blob.Remote())
+		stats.Blobs++
+		n := refs[h]
+		if n == 0 {
+			stats.Removed++
+			stats.FreedBytes += blob.Size()
+			if operations.SkipDestructive(ctx, blob, "remove unreferenced blob") {
+				continue
+			}
+			if err := f.removeBlob(ctx, h); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		stored, err := f.readRef(ctx, h)
+		if err != nil || stored != n {
+			stats.RefsFixed++
+			if operations.SkipDestructive(ctx, blob, "fix reference count") {
+				continue
+			}
+			if err := f.writeRef(ctx, h, n); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, tmp := range tmps {
+		stats.TmpRemoved++
+		if operations.SkipDestructive(ctx, tmp, "remove temporary upload") {
+			continue
+		}
+		if err := tmp.Remove(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return &stats, nil
+}
+
+// Object describes a file stored in a blob
+type Object struct {
+	f      *Fs
+	remote string
+	m      *manifest
+}
+
+// newObject makes an Object for remote described by m
+func (f *Fs) newObject(remote string, m *manifest) *Object {
+	return &Object{
+		f:      f,
+		remote: remote,
+		m:      m,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the modification time of the file
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.m.ModTime
+}
+
+// Size returns the size of the file
+func (o *Object) Size() int64 {
+	return o.m.Size
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Hash returns the SHA-256 of the file
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if ht != hash.SHA256 {
+		return "", hash.ErrUnsupported
+	}
+	return o.m.Hash, nil
+}
+
+// SetModTime sets the modification time of the file
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	m := *o.m
+	m.ModTime = modTime
+	if err := o.f.writeManifest(ctx, o.remote, &m); err != nil {
+		return err
+	}
+	o.m = &m
+	return nil
+}
+
+// Open opens the file for read.  Call Close() on the returned io.ReadCloser
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	blob, err := o.f.base.NewObject(ctx, blobPath(o.m.Hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find blob for %q: %w", o.remote, err)
+	}
+	return blob.Open(ctx, options...)
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	newObj, err := o.f.put(ctx, in, src, options...)
+	if err != nil {
+		return err
+	}
+	o.m = newObj.m
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	manifestObj, err := o.f.base.NewObject(ctx, o.f.manifestPath(o.remote))
+	if err != nil {
+		return err
+	}
+	if err := manifestObj.Remove(ctx); err != nil {
+		return err
+	}
+	o.f.release(ctx, o.m.Hash)
+	return nil
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	return o.m.MimeType
+}
+
+// setMetadata stores meta in the manifest, taking the system
+// metadata out of it
+func (m *manifest) setMetadata(meta fs.Metadata) {
+	if len(meta) == 0 {
+		return
+	}
+	m.Metadata = make(fs.Metadata, len(meta))
+	for k, v := range meta {
+		switch k {
+		case "mtime":
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				m.ModTime = t
+			}
+		case "content-type":
+			m.MimeType = v
+		default:
+			m.Metadata[k] = v
+		}
+	}
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	meta := make(fs.Metadata, len(o.m.Metadata)+2)
+	for k, v := range o.m.Metadata {
+		meta[k] = v
+	}
+	meta["mtime"] = o.m.ModTime.Format(time.RFC3339Nano)
+	if o.m.MimeType != "" {
+		meta["content-type"] = o.m.MimeType
+	}
+	return meta, nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.ListPer     = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.Abouter     = (*Fs)(nil)
+	_ fs.Shutdowner  = (*Fs)(nil)
+	_ fs.Commander   = (*Fs)(nil)
+	_ fs.UnWrapper   = (*Fs)(nil)
+	_ fs.Wrapper     = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+	_ fs.MimeTyper   = (*Object)(nil)
+	_ fs.Metadataer  = (*Object)(nil)
+)
@@ -0,0 +1,128 @@
+package dedupe
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs returns an empty dedupe remote so blobs can count the
+// blobs stored by each test from scratch
+func newTestFs(t *testing.T) *Fs {
+	f, _ := fstest.NewWrappingFs(t, "dedupe", "")
+	return f.(*Fs)
+}
+
+// blobs returns the number of blobs stored
+func blobs(t *testing.T, f *Fs) (n int) {
+	err := operations.ListFn(context.Background(), f.base, func(o fs.Object) {
+		if strings.HasPrefix(o.Remote(), blobDir+"/") && !strings.HasSuffix(o.Remote(), refSuffix) {
+			n++
+		}
+	})
+	if err != fs.ErrorDirNotFound {
+		require.NoError(t, err)
+	}
+	return n
+}
+
+func TestSharing(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+
+	o1 := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "a.txt", ModTime: time.Now()}, "same", true)
+	o2 := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/b.txt", ModTime: time.Now()}, "same", true)
+	o3 := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "c.txt", ModTime: time.Now()}, "different", true)
+	assert.Equal(t, 2, blobs(t, f))
+
+	h1, err := o1.Hash(ctx, hash.SHA256)
+	require.NoError(t, err)
+	h2, err := o2.Hash(ctx, hash.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, h1, h2)
+	n, err := f.readRef(ctx, h1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// copies share the blob
+	o4, err := f.Copy(ctx, o3, "d.txt")
+	require.NoError(t, err)
+	assert.Equal(t, 2, blobs(t, f))
+
+	// blobs are removed with their last reference
+	require.NoError(t, o1.Remove(ctx))
+	assert.Equal(t, 2, blobs(t, f))
+	require.NoError(t, o2.Remove(ctx))
+	assert.Equal(t, 1, blobs(t, f))
+
+	// overwriting releases the old blob
+	src := object.NewStaticObjectInfo("c.txt", time.Now(), 3, true, nil, nil)
+	require.NoError(t, o3.Update(ctx, strings.NewReader("new"), src))
+	assert.Equal(t, 2, blobs(t, f))
+	require.NoError(t, o4.Remove(ctx))
+	assert.Equal(t, 1, blobs(t, f))
+}
+
+func TestKnownHash(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "a.txt", ModTime: time.Now()}, "hello", true)
+
+	// a source with a known hash that is already stored isn't read
+	hashes := map[hash.Type]string{hash.SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"}
+	src := object.NewStaticObjectInfo("b.txt", time.Now(), 5, true, hashes, nil)
+	in := strings.NewReader("hello")
+	_, err := f.Put(ctx, in, src)
+	require.NoError(t, err)
+	assert.Equal(t, 5, in.Len())
+	assert.Equal(t, 1, blobs(t, f))
+
+	// a wrong hash is detected
+	hashes = map[hash.Type]string{hash.SHA256: strings.Repeat("0", 64)}
+	src = object.NewStaticObjectInfo("c.txt", time.Now(), 5, true, hashes, nil)
+	_, err = f.Put(ctx, strings.NewReader("world"), src)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "corrupted on transfer")
+	assert.Equal(t, 1, blobs(t, f))
+}
+
+func TestGC(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "a.txt", ModTime: time.Now()}, "hello", true)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "b.txt", ModTime: time.Now()}, "hello", true)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "c.txt", ModTime: time.Now()}, "orphan", true)
+
+	// remove a manifest behind the back of the dedupe remote
+	manifestObj, err := f.base.NewObject(ctx, f.manifestPath("c.txt"))
+	require.NoError(t, err)
+	require.NoError(t, manifestObj.Remove(ctx))
+	// and break a reference count
+	h, err := o.Hash(ctx, hash.SHA256)
+	require.NoError(t, err)
+	require.NoError(t, f.writeRef(ctx, h, 7))
+
+	stats, err := f.gc(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &gcStats{Files: 2, Blobs: 2, Removed: 1, FreedBytes: 6, RefsFixed: 1}, stats)
+	assert.Equal(t, 1, blobs(t, f))
+	n, err := f.readRef(ctx, h)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// a second run has nothing to do
+	stats, err = f.gc(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &gcStats{Files: 2, Blobs: 1}, stats)
+}
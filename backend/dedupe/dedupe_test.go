@@ -0,0 +1,66 @@
+// Test Dedupe filesystem interface
+package dedupe_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/backend/dedupe"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+var unimplementableFsMethods = []string{
+	"OpenWriterAt",
+	"MergeDirs",
+	"DirCacheFlush",
+	"PutUnchecked",
+	"Purge",
+	"CleanUp",
+	"ListR",
+	"PublicLink",
+	"ChangeNotify",
+	"UserInfo",
+	"Disconnect",
+}
+
+var unimplementableObjectMethods = []string{
+	"ID",
+	"GetTier",
+	"SetTier",
+	"UnWrap",
+}
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:                   *fstest.RemoteName,
+		NilObject:                    (*dedupe.Object)(nil),
+		UnimplementableFsMethods:     unimplementableFsMethods,
+		UnimplementableObjectMethods: unimplementableObjectMethods,
+	})
+}
+
+// TestLocal runs integration tests against a local directory
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestDedupe"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:                   name + ":",
+		NilObject:                    (*dedupe.Object)(nil),
+		UnimplementableFsMethods:     unimplementableFsMethods,
+		UnimplementableObjectMethods: unimplementableObjectMethods,
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "dedupe"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-dedupe-test")},
+		},
+		QuickTestOK: true,
+	})
+}
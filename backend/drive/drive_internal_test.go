@@ -9,6 +9,8 @@ import (
 	"io"
 	"io/ioutil"
 	"mime"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"path/filepath"
@@ -24,6 +26,7 @@ import (
 	"github.com/rclone/rclone/fs/sync"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/rclone/rclone/lib/pacer"
 	"github.com/rclone/rclone/lib/random"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -190,6 +193,56 @@ func TestExtensionsForImportFormats(t *testing.T) {
 	}
 }
 
+func TestUploadResume(t *testing.T) {
+	ctx := context.Background()
+	const contents = "0123456789"
+	var got []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		got = append(got, r.Header.Get("Content-Range")+" "+string(body))
+		switch r.URL.Path {
+		case "/expired":
+			w.WriteHeader(http.StatusNotFound)
+		case "/session":
+			if r.Header.Get("Content-Range") == "bytes */10" {
+				w.Header().Set("Range", "bytes=0-3")
+				w.WriteHeader(statusResumeIncomplete)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"potato"}`))
+		}
+	}))
+	defer ts.Close()
+
+	f := &Fs{
+		client: ts.Client(),
+		pacer:  fs.NewPacer(ctx, pacer.NewGoogleDrive(pacer.MinSleep(time.Millisecond))),
+	}
+	f.opt.ChunkSize = fs.SizeSuffix(1024)
+
+	// Resume the session from where the server is up to
+	rx := &resumableUpload{
+		f:             f,
+		URI:           ts.URL + "/session",
+		Media:         strings.NewReader(contents),
+		ContentLength: int64(len(contents)),
+	}
+	ok, err := rx.resume(ctx)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), rx.start)
+	info, err := rx.Upload(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "potato", info.Id)
+	assert.Equal(t, []string{"bytes */10 ", "bytes 4-9/10 456789"}, got)
+
+	// Start again if the session has expired
+	rx.URI = ts.URL + "/expired"
+	ok, err = rx.resume(ctx)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
 func (f *Fs) InternalTestDocumentImport(t *testing.T) {
 	oldAllow := f.opt.AllowImportNameChange
 	f.opt.AllowImportNameChange = true
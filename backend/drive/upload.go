@@ -16,6 +16,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -45,12 +46,42 @@ type resumableUpload struct {
 	MediaType string
 	// ContentLength is the full size of the object being uploaded.
 	ContentLength int64
+	// start is where to start uploading from when resuming
+	start int64
 	// Return value
 	ret *drive.File
 }
 
 // Upload the io.Reader in of size bytes with contentType and info
-func (f *Fs) Upload(ctx context.Context, in io.Reader, size int64, contentType, fileID, remote string, info *drive.File) (*drive.File, error) {
+//
+// If options contains an fs.ResumeOption then an interrupted upload
+// of the same file is resumed if the server still has it.
+func (f *Fs) Upload(ctx context.Context, in io.Reader, size int64, contentType, fileID, remote string, info *drive.File, options ...fs.OpenOption) (*drive.File, error) {
+	rx := &resumableUpload{
+		f:             f,
+		remote:        remote,
+		Media:         in,
+		MediaType:     contentType,
+		ContentLength: size,
+	}
+	var resume *fs.ResumeOption
+	for _, option := range options {
+		if x, ok := option.(*fs.ResumeOption); ok && size >= 0 {
+			resume = x
+		}
+	}
+	if resume != nil && resume.State != "" {
+		rx.URI = resume.State
+		ok, err := rx.resume(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return rx.Upload(ctx)
+		}
+		fs.Debugf(remote, "Upload session has expired - starting again")
+	}
+
 	params := url.Values{
 		"alt":        {"json"},
 		"uploadType": {"resumable"},
@@ -99,18 +130,71 @@ func (f *Fs) Upload(ctx context.Context, in io.Reader, size int64, contentType,
 	if err != nil {
 		return nil, err
 	}
-	loc := res.Header.Get("Location")
-	rx := &resumableUpload{
-		f:             f,
-		remote:        remote,
-		URI:           loc,
-		Media:         in,
-		MediaType:     contentType,
-		ContentLength: size,
+	rx.URI = res.Header.Get("Location")
+	if resume != nil {
+		err = resume.SaveState(rx.URI)
+		if err != nil {
+			fs.Debugf(remote, "Failed to save upload session for resuming: %v", err)
+		}
 	}
 	return rx.Upload(ctx)
 }
 
+// resume finds out how much of the upload at rx.URI the server has
+// and skips that much of the input.
+//
+// It returns false if the upload can't be resumed.
+func (rx *resumableUpload) resume(ctx context.Context) (ok bool, err error) {
+	var (
+		statusCode  int
+		rangeHeader string
+	)
+	err = rx.f.pacer.Call(func() (bool, error) {
+		res, err := rx.f.client.Do(rx.makeRequest(ctx, 0, nil, 0))
+		if err != nil {
+			return rx.f.shouldRetry(ctx, err)
+		}
+		defer googleapi.CloseBody(res)
+		statusCode = res.StatusCode
+		switch statusCode {
+		case statusResumeIncomplete:
+			rangeHeader = res.Header.Get("Range")
+			return false, nil
+		case http.StatusOK, http.StatusCreated:
+			return false, json.NewDecoder(res.Body).Decode(&rx.ret)
+		case http.StatusNotFound, http.StatusGone:
+			return false, nil
+		}
+		return rx.f.shouldRetry(ctx, googleapi.CheckResponse(res))
+	})
+	if err != nil {
+		return false, err
+	}
+	if statusCode == http.StatusNotFound || statusCode == http.StatusGone {
+		return false, nil
+	}
+	if rx.ret != nil {
+		// The upload had finished, so just read the input
+		rx.start = rx.ContentLength
+	} else if rangeHeader != "" {
+		// The range the server has is "bytes=0-N"
+		var end int64
+		if _, err := fmt.Sscanf(rangeHeader, "bytes=0-%d", &end); err != nil {
+			fs.Debugf(rx.remote, "Can't parse Range %q when resuming upload: %v", rangeHeader, err)
+			return false, nil
+		}
+		rx.start = end + 1
+	}
+	fs.Debugf(rx.remote, "Resuming upload from %d of %d bytes", rx.start, rx.ContentLength)
+	if rx.start > 0 {
+		_, err = io.CopyN(ioutil.Discard, rx.Media, rx.start)
+		if err != nil {
+			return false, fmt.Errorf("failed to skip the data already uploaded: %w", err)
+		}
+	}
+	return true, nil
+}
+
 // Make an http.Request for the range passed in
 func (rx *resumableUpload) makeRequest(ctx context.Context, start int64, body io.ReadSeeker, reqSize int64) *http.Request {
 	req, _ := http.NewRequestWithContext(ctx, "POST", rx.URI, body)
@@ -165,11 +249,11 @@ func (rx *resumableUpload) transferChunk(ctx context.Context, start int64, chunk
 // Upload uploads the chunks from the input
 // It retries each chunk using the pacer and --low-level-retries
 func (rx *resumableUpload) Upload(ctx context.Context) (*drive.File, error) {
-	start := int64(0)
+	start := rx.start
 	var StatusCode int
 	var err error
 	buf := make([]byte, int(rx.f.opt.ChunkSize))
-	for finished := false; !finished; {
+	for finished := rx.ret != nil; !finished; {
 		var reqSize int64
 		var chunk io.ReadSeeker
 		if rx.ContentLength >= 0 {
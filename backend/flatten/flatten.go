@@ -0,0 +1,717 @@
+// Package flatten implements a backend which stores a directory tree
+// in a single flat directory on the wrapped remote
+package flatten
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "flatten",
+		Description: "Store a directory tree in a single directory",
+		NewFs:       NewFs,
+		MetadataInfo: &fs.MetadataInfo{
+			Help: `Any metadata supported by the underlying remote is read and written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to store the flattened files in.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name:    "encoding",
+			Default: "escape",
+			Help:    `How to encode the path of each file into a single file name.`,
+			Examples: []fs.OptionExample{{
+				Value: "escape",
+				Help:  "Replace / with %2F and % with %25 - names stay readable.",
+			}, {
+				Value: "base64",
+				Help:  "Encode the whole path with URL safe base64 - names only use [A-Za-z0-9_-].",
+			}},
+		}, {
+			Name:    "max_length",
+			Default: 0,
+			Help: `Maximum length of an encoded file name, or 0 for no limit.
+
+Files whose encoded name would be longer than this are rejected with
+an error before anything is uploaded.`,
+			Advanced: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote    string `config:"remote"`
+	Encoding  string `config:"encoding"`
+	MaxLength int    `config:"max_length"`
+}
+
+// Fs represents a directory tree stored in a flat directory
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string // root of the tree within the flat namespace
+	opt      Options
+	features *fs.Features // optional features
+	wrapper  fs.Fs
+	encode   func(string) string
+	decode   func(string) (string, error)
+}
+
+// dirMarker is appended to the path of a directory to make the name
+// of the empty object which marks its existence
+const dirMarker = "/"
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point flatten remote at itself - check the value of the remote setting")
+	}
+	f := &Fs{
+		name: name,
+		root: strings.Trim(rpath, "/"),
+		opt:  opt,
+	}
+	switch opt.Encoding {
+	case "escape":
+		f.encode, f.decode = escapeEncode, escapeDecode
+	case "base64":
+		f.encode, f.decode = base64Encode, base64Decode
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", opt.Encoding)
+	}
+	f.Fs, err = cache.Get(ctx, opt.Remote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", opt.Remote, err)
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+	f.features = (&fs.Features{
+		CaseInsensitive:         f.Fs.Features().CaseInsensitive,
+		CanHaveEmptyDirectories: true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		SetTier:                 true,
+		GetTier:                 true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, f.Fs).WrapsFs(f, f.Fs)
+	// Every listing is a full listing of the flat directory
+	f.features.ListR = f.ListR
+
+	if f.root != "" {
+		if _, err := f.Fs.NewObject(ctx, f.encode(f.root)); err == nil {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// escapeEncode replaces / with %2F and % with %25
+func escapeEncode(p string) string {
+	return strings.NewReplacer("%", "%25", "/", "%2F").Replace(p)
+}
+
+// escapeDecode reverses escapeEncode
+func escapeDecode(name string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c != '%' {
+			out.WriteByte(c)
+			continue
+		}
+		if i+2 >= len(name) {
+			return "", fmt.Errorf("bad escape at end of %q", name)
+		}
+		switch strings.ToUpper(name[i+1 : i+3]) {
+		case "25":
+			out.WriteByte('%')
+		case "2F":
+			out.WriteByte('/')
+		default:
+			return "", fmt.Errorf("bad escape %q in %q", name[i:i+3], name)
+		}
+		i += 2
+	}
+	return out.String(), nil
+}
+
+// base64Encode encodes the whole path with URL safe base64
+func base64Encode(p string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(p))
+}
+
+// base64Decode reverses base64Encode
+func base64Decode(name string) (string, error) {
+	p, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil {
+		return "", err
+	}
+	return string(p), nil
+}
+
+// fullPath returns the path of remote in the tree
+func (f *Fs) fullPath(remote string) string {
+	return path.Join(f.root, remote)
+}
+
+// flatName returns the name of the flat object for remote, checking
+// its length
+func (f *Fs) flatName(remote string) (string, error) {
+	name := f.encode(f.fullPath(remote))
+	if f.opt.MaxLength > 0 && len(name) > f.opt.MaxLength {
+		return "", fmt.Errorf("encoded name for %q is %d bytes, more than max_length %d: %w", remote, len(name), f.opt.MaxLength, fs.ErrorFileNameTooLong)
+	}
+	return name, nil
+}
+
+// markerName returns the name of the marker object for directory dir
+func (f *Fs) markerName(dir string) (string, error) {
+	name := f.encode(f.fullPath(dir) + dirMarker)
+	if f.opt.MaxLength > 0 && len(name) > f.opt.MaxLength {
+		return "", fmt.Errorf("encoded name for directory %q is too long: %w", dir, fs.ErrorFileNameTooLong)
+	}
+	return name, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("flatten root '%s'", f.root)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// list reads the flat directory and calls callback with the entries
+// in dir, recursively if recurse is set.
+func (f *Fs) list(ctx context.Context, dir string, recurse bool, callback fs.ListRCallback) error {
+	baseEntries, err := f.Fs.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	prefix := f.fullPath(dir)
+	if prefix != "" {
+		prefix += "/"
+	}
+	found := prefix == ""
+	var entries fs.DirEntries
+	dirs := map[string]struct{}{}
+	addDir := func(remote string, modTime time.Time) {
+		if _, ok := dirs[remote]; ok {
+			return
+		}
+		dirs[remote] = struct{}{}
+		entries = append(entries, fs.NewDir(remote, modTime))
+	}
+	for _, entry := range baseEntries {
+		o, ok := entry.(fs.Object)
+		if !ok {
+			continue
+		}
+		full, err := f.decode(o.Remote())
+		if err != nil {
+			fs.Debugf(f, "Ignoring %q which couldn't be decoded: %v", o.Remote(), err)
+			continue
+		}
+		if !strings.HasPrefix(full, prefix) {
+			continue
+		}
+		rel := full[len(prefix):]
+		isMarker := strings.HasSuffix(rel, dirMarker)
+		rel = strings.TrimSuffix(rel, dirMarker)
+		found = true
+		if rel == "" {
+			continue
+		}
+		if !recurse {
+			if i := strings.IndexByte(rel, '/'); i >= 0 {
+				addDir(path.Join(dir, rel[:i]), time.Time{})
+			} else if isMarker {
+				addDir(path.Join(dir, rel), o.ModTime(ctx))
+			} else {
+				entries = append(entries, f.newObject(o, path.Join(dir, rel)))
+			}
+			continue
+		}
+		parent := path.Dir(rel)
+		var parents []string
+		for parent != "." {
+			parents = append(parents, parent)
+			parent = path.Dir(parent)
+		}
+		for i := len(parents) - 1; i >= 0; i-- {
+			addDir(path.Join(dir, parents[i]), time.Time{})
+		}
+		if isMarker {
+			addDir(path.Join(dir, rel), o.ModTime(ctx))
+		} else {
+			entries = append(entries, f.newObject(o, path.Join(dir, rel)))
+		}
+	}
+	if !found {
+		return fs.ErrorDirNotFound
+	}
+	return callback(entries)
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.list(ctx, dir, false, func(dirEntries fs.DirEntries) error {
+		entries = dirEntries
+		return nil
+	})
+	return entries, err
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// As the underlying directory is flat this is a single listing.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	return f.list(ctx, dir, true, callback)
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// As the whole of the underlying directory has to be read to find
+// the entries in dir this is a single page.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	return f.list(ctx, dir, false, callback)
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	name, err := f.flatName(remote)
+	if err != nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	o, err := f.Fs.NewObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, remote), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	name, err := f.flatName(src.Remote())
+	if err != nil {
+		return nil, err
+	}
+	o, err := f.Fs.Put(ctx, in, f.newObjectInfo(src, name), options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, src.Remote()), nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutStream
+	if do == nil {
+		return nil, errors.New("can't PutStream")
+	}
+	name, err := f.flatName(src.Remote())
+	if err != nil {
+		return nil, err
+	}
+	o, err := do(ctx, in, f.newObjectInfo(src, name), options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, src.Remote()), nil
+}
+
+// Mkdir makes the directory and any missing parents
+//
+// Directories other than the root of the wrapped remote are recorded
+// with an empty marker object so they can exist while empty.
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	if err := f.Fs.Mkdir(ctx, ""); err != nil {
+		return err
+	}
+	for f.fullPath(dir) != "" {
+		name, err := f.markerName(dir)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Fs.NewObject(ctx, name); err == nil {
+			return nil
+		}
+		info := object.NewStaticObjectInfo(name, time.Now(), 0, true, nil, f.Fs)
+		if _, err = f.Fs.Put(ctx, bytes.NewReader(nil), info); err != nil {
+			return err
+		}
+		if dir == "" {
+			break
+		}
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+	}
+	return nil
+}
+
+// Rmdir removes the directory if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	entries, err := f.List(ctx, dir)
+	if err == fs.ErrorDirNotFound && dir != "" {
+		// Directories only implied by files vanish with them
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	if f.fullPath(dir) == "" {
+		return f.Fs.Rmdir(ctx, "")
+	}
+	name, err := f.markerName(dir)
+	if err != nil {
+		return err
+	}
+	marker, err := f.Fs.NewObject(ctx, name)
+	if err == fs.ErrorObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return marker.Remove(ctx)
+}
+
+// Copy src to this remote using server-side copy operations.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	do := f.Fs.Features().Copy
+	if !ok || do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	name, err := f.flatName(remote)
+	if err != nil {
+		return nil, err
+	}
+	o, err := do(ctx, srcObj.Object, name)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, remote), nil
+}
+
+// Move src to this remote using server-side move operations.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	do := f.Fs.Features().Move
+	if !ok || do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	name, err := f.flatName(remote)
+	if err != nil {
+		return nil, err
+	}
+	o, err := do(ctx, srcObj.Object, name)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, remote), nil
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	do := f.Fs.Features().ChangeNotify
+	if do == nil {
+		return
+	}
+	prefix := f.root
+	if prefix != "" {
+		prefix += "/"
+	}
+	do(ctx, func(name string, entryType fs.EntryType) {
+		full, err := f.decode(name)
+		if err != nil || !strings.HasPrefix(full, prefix) {
+			return
+		}
+		remote := full[len(prefix):]
+		if strings.HasSuffix(remote, dirMarker) {
+			remote, entryType = strings.TrimSuffix(remote, dirMarker), fs.EntryDirectory
+		}
+		notifyFunc(remote, entryType)
+	}, pollIntervalChan)
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	do := f.Fs.Features().PublicLink
+	if do == nil {
+		return "", errors.New("PublicLink not supported")
+	}
+	name, err := f.flatName(remote)
+	if err != nil {
+		return "", err
+	}
+	return do(ctx, name, expire, unlink)
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	do := f.Fs.Features().Shutdown
+	if do == nil {
+		return nil
+	}
+	return do(ctx)
+}
+
+// ObjectInfo describes a source object with its flattened name
+type ObjectInfo struct {
+	fs.ObjectInfo
+	f    *Fs
+	name string
+}
+
+// newObjectInfo wraps src so it has the flat name given
+func (f *Fs) newObjectInfo(src fs.ObjectInfo, name string) *ObjectInfo {
+	return &ObjectInfo{
+		ObjectInfo: src,
+		f:          f,
+		name:       name,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (oi *ObjectInfo) Fs() fs.Info {
+	return oi.f.Fs
+}
+
+// Remote returns the flattened name
+func (oi *ObjectInfo) Remote() string {
+	return oi.name
+}
+
+// MimeType returns the content type of the source if known
+func (oi *ObjectInfo) MimeType(ctx context.Context) string {
+	return fs.MimeType(ctx, oi.ObjectInfo)
+}
+
+// Metadata returns metadata for the source object
+func (oi *ObjectInfo) Metadata(ctx context.Context) (fs.Metadata, error) {
+	return fs.GetMetadata(ctx, oi.ObjectInfo)
+}
+
+// Object is a file stored under its flattened name
+type Object struct {
+	fs.Object
+	f      *Fs
+	remote string
+}
+
+// newObject wraps o which has the path remote in the tree
+func (f *Fs) newObject(o fs.Object, remote string) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+		remote: remote,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Remote returns the path of the object in the tree
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// String returns a description of the Object
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return o.Object.Update(ctx, in, o.f.newObjectInfo(src, o.Object.Remote()), options...)
+}
+
+// Hash returns the selected checksum of the file
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	return o.Object.Hash(ctx, ht)
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	if do, ok := o.Object.(fs.IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	if do, ok := o.Object.(fs.MimeTyper); ok {
+		return do.MimeType(ctx)
+	}
+	return ""
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	if do, ok := o.Object.(fs.SetTierer); ok {
+		return do.SetTier(tier)
+	}
+	return errors.New("SetTier not supported")
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *Object) GetTier() string {
+	if do, ok := o.Object.(fs.GetTierer); ok {
+		return do.GetTier()
+	}
+	return ""
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.FullObject      = (*Object)(nil)
+	_ fs.MimeTyper       = (*ObjectInfo)(nil)
+	_ fs.Metadataer      = (*ObjectInfo)(nil)
+)
@@ -0,0 +1,42 @@
+package flatten
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscapeEncoding(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"file.txt", "file.txt"},
+		{"a/b/c.txt", "a%2Fb%2Fc.txt"},
+		{"100%/done", "100%25%2Fdone"},
+		{"a%2Fb", "a%252Fb"},
+		{"dir/", "dir%2F"},
+	} {
+		got := escapeEncode(test.in)
+		assert.Equal(t, test.want, got, test.in)
+		back, err := escapeDecode(got)
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.in, back)
+	}
+	for _, bad := range []string{"a%", "a%2", "a%41"} {
+		_, err := escapeDecode(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestBase64Encoding(t *testing.T) {
+	for _, in := range []string{"", "file.txt", "a/b/c.txt", "ünïcödé/dir/"} {
+		got := base64Encode(in)
+		assert.NotContains(t, got, "/")
+		back, err := base64Decode(got)
+		require.NoError(t, err)
+		assert.Equal(t, in, back)
+	}
+}
@@ -0,0 +1,56 @@
+// Test Flatten filesystem interface
+package flatten_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/backend/flatten"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+var unimplementableFsMethods = []string{
+	"OpenWriterAt",
+	"MergeDirs",
+	"PutUnchecked",
+	"Purge",
+	"DirMove",
+	"UserInfo",
+	"Disconnect",
+}
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:               *fstest.RemoteName,
+		NilObject:                (*flatten.Object)(nil),
+		UnimplementableFsMethods: unimplementableFsMethods,
+	})
+}
+
+// TestLocal runs integration tests against a local directory
+//
+// Only the escape encoding is tested here as the base64 encoding
+// makes some of the test file names too long for a local disk.
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestFlatten"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:               name + ":",
+		NilObject:                (*flatten.Object)(nil),
+		UnimplementableFsMethods: unimplementableFsMethods,
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "flatten"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-flatten-test")},
+		},
+		QuickTestOK: true,
+	})
+}
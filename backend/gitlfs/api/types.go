@@ -0,0 +1,104 @@
+// Package api contains definitions for using the Git LFS batch API
+//
+// See https://github.com/git-lfs/git-lfs/blob/main/docs/api/batch.md
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// MediaType is the Content-Type and Accept header of the batch API
+const MediaType = "application/vnd.git-lfs+json"
+
+// Operations of a batch request
+const (
+	OperationDownload = "download"
+	OperationUpload   = "upload"
+)
+
+// Actions in a batch response
+const (
+	ActionDownload = "download"
+	ActionUpload   = "upload"
+	ActionVerify   = "verify"
+)
+
+// TransferBasic is the transfer adapter which uses plain HTTP requests
+const TransferBasic = "basic"
+
+// HashAlgoSHA256 is the hash used to make object IDs
+const HashAlgoSHA256 = "sha256"
+
+// Error is returned by the server when a request fails
+type Error struct {
+	Message          string `json:"message"`
+	DocumentationURL string `json:"documentation_url,omitempty"`
+	RequestID        string `json:"request_id,omitempty"`
+	StatusCode       int    `json:"-"`
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	out := fmt.Sprintf("git lfs error %d: %s", e.StatusCode, e.Message)
+	if e.RequestID != "" {
+		out += fmt.Sprintf(" (request id %s)", e.RequestID)
+	}
+	return out
+}
+
+// Pointer identifies an object by its SHA-256 and size
+type Pointer struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// Ref is the Git ref the objects belong to
+type Ref struct {
+	Name string `json:"name"`
+}
+
+// BatchRequest asks to download or upload some objects
+type BatchRequest struct {
+	Operation string    `json:"operation"`
+	Transfers []string  `json:"transfers,omitempty"`
+	Ref       *Ref      `json:"ref,omitempty"`
+	Objects   []Pointer `json:"objects"`
+	HashAlgo  string    `json:"hash_algo,omitempty"`
+}
+
+// BatchResponse says how to transfer each of the objects asked for
+type BatchResponse struct {
+	Transfer string           `json:"transfer,omitempty"`
+	Objects  []ObjectResponse `json:"objects"`
+	HashAlgo string           `json:"hash_algo,omitempty"`
+}
+
+// ObjectResponse says how to transfer one object
+//
+// An upload with no actions means the server has the object already.
+type ObjectResponse struct {
+	Pointer
+	Authenticated bool               `json:"authenticated,omitempty"`
+	Actions       map[string]*Action `json:"actions,omitempty"`
+	Error         *ObjectError       `json:"error,omitempty"`
+}
+
+// Action is a request to make to transfer an object
+type Action struct {
+	Href      string            `json:"href"`
+	Header    map[string]string `json:"header,omitempty"`
+	ExpiresIn int               `json:"expires_in,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at,omitempty"`
+}
+
+// ObjectError is the reason an object can't be transferred
+type ObjectError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error satisfies the error interface
+func (e *ObjectError) Error() string {
+	return fmt.Sprintf("git lfs object error %d: %s", e.Code, e.Message)
+}
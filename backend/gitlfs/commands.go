@@ -0,0 +1,214 @@
+package gitlfs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rclone/rclone/backend/gitlfs/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// Git LFS pointer files
+//
+// See https://github.com/git-lfs/git-lfs/blob/main/docs/spec.md
+const (
+	pointerVersion = "https://git-lfs.github.com/spec/v1"
+	maxPointerSize = 1024
+)
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "import",
+	Short: "Add the files in a Git checkout stored in LFS.",
+	Long: `This reads the Git LFS pointer files in the path given, which can be
+any rclone remote, and adds the files they point to under the same
+names. Nothing is downloaded from the server. Use it to make the
+objects stored for a repository available to rclone.
+
+The path should be a checkout made with LFS turned off, e.g. with
+GIT_LFS_SKIP_SMUDGE=1, so the pointer files are there instead of the
+files. Files which aren't pointer files are skipped. The number of
+files imported and skipped is shown as JSON.
+
+Usage Example:
+
+    rclone backend import gitlfs:path/to/dir /path/to/checkout/dir
+
+Use "-o check" to check the server has each object, skipping those it
+doesn't have.
+`,
+	Opts: map[string]string{
+		"check": "Check the server has the objects",
+	},
+}, {
+	Name:  "pointer",
+	Short: "Show the Git LFS pointer files of files.",
+	Long: `This shows the pointer file which Git would store for each of the
+files given, which can be used to add them to a repository.
+
+Usage Example:
+
+    rclone backend pointer gitlfs: path/to/file > file
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "import":
+		if len(arg) != 1 {
+			return nil, errors.New("need exactly one path to import from")
+		}
+		_, check := opt["check"]
+		return f.importPointers(ctx, arg[0], check)
+	case "pointer":
+		return f.pointers(ctx, arg)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// formatPointer returns the pointer file for ptr
+func formatPointer(ptr api.Pointer) string {
+	return fmt.Sprintf("version %s\noid %s:%s\nsize %d\n", pointerVersion, api.HashAlgoSHA256, ptr.OID, ptr.Size)
+}
+
+// parsePointer reads a pointer file from in
+func parsePointer(in io.Reader) (ptr api.Pointer, err error) {
+	scanner := bufio.NewScanner(io.LimitReader(in, maxPointerSize+1))
+	n := 0
+	version, oid, size := false, false, false
+	for scanner.Scan() {
+		n += len(scanner.Bytes()) + 1
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return ptr, fmt.Errorf("bad line %q in pointer", line)
+		}
+		key, value := line[:i], line[i+1:]
+		if !version && key != "version" {
+			return ptr, errors.New("pointer doesn't start with version")
+		}
+		switch key {
+		case "version":
+			if value != pointerVersion {
+				return ptr, fmt.Errorf("unknown pointer version %q", value)
+			}
+			version = true
+		case "oid":
+			ptr.OID = strings.TrimPrefix(value, api.HashAlgoSHA256+":")
+			if ptr.OID == value || !isOID(ptr.OID) {
+				return ptr, fmt.Errorf("bad oid %q in pointer", value)
+			}
+			oid = true
+		case "size":
+			ptr.Size, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || ptr.Size < 0 {
+				return ptr, fmt.Errorf("bad size %q in pointer", value)
+			}
+			size = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ptr, err
+	}
+	if n > maxPointerSize {
+		return ptr, errors.New("pointer too big")
+	}
+	if !version || !oid || !size {
+		return ptr, errors.New("pointer is missing version, oid or size")
+	}
+	return ptr, nil
+}
+
+// readPointer reads the pointer file o
+func readPointer(ctx context.Context, o fs.Object) (ptr api.Pointer, err error) {
+	in, err := o.Open(ctx)
+	if err != nil {
+		return ptr, err
+	}
+	ptr, err = parsePointer(in)
+	closeErr := in.Close()
+	if err != nil {
+		return ptr, err
+	}
+	return ptr, closeErr
+}
+
+// importPointers records the files in the pointer files in
+// srcPath, checking the server has them if check is set
+func (f *Fs) importPointers(ctx context.Context, srcPath string, check bool) (map[string]int, error) {
+	srcFs, err := cache.Get(ctx, srcPath)
+	if err != nil {
+		return nil, err
+	}
+	imported, skipped := 0, 0
+	err = walk.ListR(ctx, srcFs, "", true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			o, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			if o.Size() > maxPointerSize {
+				skipped++
+				continue
+			}
+			ptr, err := readPointer(ctx, o)
+			if err != nil {
+				fs.Debugf(o, "Skipping as not a pointer file: %v", err)
+				skipped++
+				continue
+			}
+			if check {
+				if _, err := f.batch(ctx, api.OperationDownload, ptr); err != nil {
+					fs.Errorf(o, "Skipping as can't find object: %v", err)
+					skipped++
+					continue
+				}
+			}
+			if err := f.setRecord(o.Remote(), ptr, o.ModTime(ctx)); err != nil {
+				return fmt.Errorf("failed to record %q: %w", o.Remote(), err)
+			}
+			fs.Debugf(o, "Imported object %s", ptr.OID)
+			imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int{
+		"imported": imported,
+		"skipped":  skipped,
+	}, nil
+}
+
+// pointers returns the pointer files of the files remotes
+func (f *Fs) pointers(ctx context.Context, remotes []string) (string, error) {
+	var out strings.Builder
+	for _, remote := range remotes {
+		rec := f.lookup(remote)
+		if !rec.isFile() {
+			return "", fmt.Errorf("%q: %w", remote, fs.ErrorObjectNotFound)
+		}
+		out.WriteString(formatPointer(api.Pointer{OID: rec.OID, Size: rec.Size}))
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,794 @@
+// Package gitlfs provides an interface to Git LFS servers
+//
+// Objects are read and written with the batch API and the basic
+// transfer adapter. The API has no way of listing or naming objects
+// so the names of the files are recorded in a local database, which
+// is what is listed.
+package gitlfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/gitlfs/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/kv"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "gitlfs",
+		Description: "Git LFS server",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		Options: []fs.Option{{
+			Name: "url",
+			Help: `URL of the Git LFS server.
+
+E.g. "https://github.com/owner/repo.git/info/lfs". If the URL of a
+repository ending in ".git" is given then "/info/lfs" is added to it.`,
+			Required: true,
+		}, {
+			Name: "user",
+			Help: `User name.
+
+For GitHub, GitLab and Gitea this is the user the access token
+belongs to.`,
+		}, {
+			Name:       "pass",
+			Help:       `Password or access token.`,
+			IsPassword: true,
+		}, {
+			Name: "ref",
+			Help: `Git ref to send with requests, e.g. "refs/heads/main".
+
+Some servers use this to decide whether the user may read or write
+the objects. If empty no ref is sent.`,
+			Advanced: true,
+		}, {
+			Name: "headers",
+			Help: `Set HTTP headers for all transactions.
+
+Use this to set additional HTTP headers for all requests to the batch
+API. They aren't sent to the URLs the server gives for transferring
+the objects, which may be on a different host.
+
+The input format is comma separated list of key,value pairs.  Standard
+[CSV encoding](https://godoc.org/encoding/csv) may be used.
+
+For example, to set a bearer token use 'Authorization,Bearer xxx'.`,
+			Default:  fs.CommaSepList{},
+			Advanced: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	URL     string          `config:"url"`
+	User    string          `config:"user"`
+	Pass    string          `config:"pass"`
+	Ref     string          `config:"ref"`
+	Headers fs.CommaSepList `config:"headers"`
+}
+
+// Fs represents files stored on a Git LFS server
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	srv      *rest.Client // the connection to the batch API
+	transfer *rest.Client // for the transfer URLs the server gives
+	pacer    *fs.Pacer    // pacer for API calls
+	endpoint string       // the URL of the server
+	db       *kv.DB       // the names of the files
+}
+
+// Object describes a file stored on a Git LFS server
+type Object struct {
+	fs      *Fs       // what this object is part of
+	remote  string    // The remote path
+	oid     string    // SHA-256 of the object
+	size    int64     // size of the object
+	modTime time.Time // modification time of the object
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("Git LFS server %s root '%s'", f.endpoint, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return hash.NewHashSet(hash.SHA256)
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error reading error out of body: %w", err)
+	}
+	errResponse := &api.Error{}
+	if json.Unmarshal(body, errResponse) != nil || errResponse.Message == "" {
+		errResponse.Message = strings.TrimSpace(string(body))
+		if errResponse.Message == "" || len(errResponse.Message) > 1024 {
+			errResponse.Message = resp.Status
+		}
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// endpointURL works out the URL of the server from the url option
+func endpointURL(URL string) (string, error) {
+	u, err := url.Parse(URL)
+	if err != nil {
+		return "", fmt.Errorf("couldn't parse url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("url must start with http:// or https:// not %q", URL)
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	if strings.HasSuffix(u.Path, ".git") {
+		u.Path += "/info/lfs"
+	}
+	return u.String(), nil
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	if !kv.Supported() {
+		return nil, errors.New("gitlfs is not supported on this OS")
+	}
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if len(opt.Headers)%2 != 0 {
+		return nil, errors.New("odd number of headers supplied")
+	}
+	endpoint, err := endpointURL(opt.URL)
+	if err != nil {
+		return nil, err
+	}
+	f := &Fs{
+		name:     name,
+		root:     strings.Trim(root, "/"),
+		opt:      *opt,
+		srv:      rest.NewClient(fshttp.NewClient(ctx)).SetRoot(endpoint),
+		transfer: rest.NewClient(fshttp.NewClient(ctx)),
+		pacer:    fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+		endpoint: endpoint,
+	}
+	f.srv.SetErrorHandler(errorHandler)
+	f.transfer.SetErrorHandler(errorHandler)
+	if opt.User != "" || opt.Pass != "" {
+		pass, err := obscure.Reveal(opt.Pass)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt password: %w", err)
+		}
+		f.srv.SetUserPass(opt.User, pass)
+	}
+	for i := 0; i < len(opt.Headers); i += 2 {
+		f.srv.SetHeader(opt.Headers[i], opt.Headers[i+1])
+	}
+	f.db, err = kv.Start(ctx, "gitlfs", f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file database: %w", err)
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	if f.root != "" && f.lookup("").isFile() {
+		f.root = path.Dir(f.root)
+		if f.root == "." {
+			f.root = ""
+		}
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// list calls fn for each file and directory in dir, recursively if
+// recurse is set
+func (f *Fs) list(dir string, recurse bool, fn func(entry fs.DirEntry)) error {
+	recs, err := f.records(dir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(recs))
+	for name := range recs {
+		if recurse || !strings.Contains(name, "/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rec := recs[name]
+		remote := path.Join(dir, name)
+		if rec.isDir() {
+			fn(fs.NewDir(remote, rec.ModTime))
+		} else if rec.isFile() {
+			fn(f.newObject(remote, rec))
+		}
+	}
+	return nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.list(dir, false, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	return entries, err
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	var entries fs.DirEntries
+	err := f.list(dir, true, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return err
+	}
+	return callback(entries)
+}
+
+// newObject makes an Object for remote from rec
+func (f *Fs) newObject(remote string, rec *record) *Object {
+	return &Object{
+		fs:      f,
+		remote:  remote,
+		oid:     rec.OID,
+		size:    rec.Size,
+		modTime: rec.ModTime,
+	}
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	rec := f.lookup(remote)
+	if !rec.isFile() {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.newObject(remote, rec), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.mkdir(dir)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.rmdir(dir)
+}
+
+// setRecord records the file remote as the object ptr
+func (f *Fs) setRecord(remote string, ptr api.Pointer, modTime time.Time) error {
+	if err := f.mkdir(path.Dir(remote)); err != nil {
+		return fmt.Errorf("failed to make directory: %w", err)
+	}
+	return f.change(remote, func(rec *record) (*record, error) {
+		if rec.isDir() {
+			return nil, fs.ErrorIsDir
+		}
+		return &record{
+			OID:     ptr.OID,
+			Size:    ptr.Size,
+			ModTime: modTime,
+		}, nil
+	})
+}
+
+// sameServer returns the Object src if it is stored on the same
+// server as f
+func (f *Fs) sameServer(src fs.Object) (*Object, bool) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.endpoint != f.endpoint {
+		return nil, false
+	}
+	return srcObj, true
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// As objects are identified by their contents this only records the
+// new name.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := f.sameServer(src)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	ptr := api.Pointer{OID: srcObj.oid, Size: srcObj.size}
+	if err := f.setRecord(remote, ptr, srcObj.modTime); err != nil {
+		return nil, err
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// Move src to this remote using server-side move operations.
+//
+// As objects are identified by their contents this only changes the
+// name recorded.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := f.sameServer(src)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	dst, err := f.Copy(ctx, src, remote)
+	if err != nil {
+		return nil, err
+	}
+	if err := srcObj.Remove(ctx); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// Shutdown the backend, closing the database
+func (f *Fs) Shutdown(ctx context.Context) error {
+	return f.db.Stop(false)
+}
+
+// batch asks the server how to do operation on the object ptr
+func (f *Fs) batch(ctx context.Context, operation string, ptr api.Pointer) (*api.ObjectResponse, error) {
+	req := api.BatchRequest{
+		Operation: operation,
+		Transfers: []string{api.TransferBasic},
+		Objects:   []api.Pointer{ptr},
+		HashAlgo:  api.HashAlgoSHA256,
+	}
+	if f.opt.Ref != "" {
+		req.Ref = &api.Ref{Name: f.opt.Ref}
+	}
+	reqBody, err := json.Marshal(&req)
+	if err != nil {
+		return nil, err
+	}
+	opts := rest.Opts{
+		Method:      "POST",
+		Path:        "/objects/batch",
+		ContentType: api.MediaType,
+		ExtraHeaders: map[string]string{
+			"Accept": api.MediaType,
+		},
+	}
+	var result api.BatchResponse
+	err = f.pacer.Call(func() (bool, error) {
+		opts.Body = bytes.NewReader(reqBody)
+		resp, err := f.srv.CallJSON(ctx, &opts, nil, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("batch %s failed: %w", operation, err)
+	}
+	if result.Transfer != "" && result.Transfer != api.TransferBasic {
+		return nil, fmt.Errorf("server wants to use the unsupported %q transfer adapter", result.Transfer)
+	}
+	for i := range result.Objects {
+		obj := &result.Objects[i]
+		if obj.OID != ptr.OID {
+			continue
+		}
+		if obj.Error != nil {
+			return nil, obj.Error
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("batch %s: server didn't return object %s", operation, ptr.OID)
+}
+
+// actionOpts makes the options to call action
+func actionOpts(method string, action *api.Action) rest.Opts {
+	opts := rest.Opts{
+		Method:       method,
+		RootURL:      action.Href,
+		ExtraHeaders: map[string]string{},
+	}
+	for k, v := range action.Header {
+		opts.ExtraHeaders[k] = v
+	}
+	return opts
+}
+
+// verify tells the server the upload of ptr is finished if it asked
+func (f *Fs) verify(ctx context.Context, action *api.Action, ptr api.Pointer) error {
+	reqBody, err := json.Marshal(&ptr)
+	if err != nil {
+		return err
+	}
+	opts := actionOpts("POST", action)
+	opts.ContentType = api.MediaType
+	opts.ExtraHeaders["Accept"] = api.MediaType
+	opts.NoResponse = true
+	err = f.pacer.Call(func() (bool, error) {
+		opts.Body = bytes.NewReader(reqBody)
+		resp, err := f.transfer.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify upload: %w", err)
+	}
+	return nil
+}
+
+// isOID returns true if oid looks like a SHA-256
+func isOID(oid string) bool {
+	if len(oid) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(oid)
+	return err == nil
+}
+
+// spool copies in to a temporary file to find its SHA-256 and size
+//
+// The caller should remove the file returned.
+func spool(in io.Reader) (*os.File, api.Pointer, error) {
+	var ptr api.Pointer
+	file, err := os.CreateTemp("", "rclone-gitlfs-")
+	if err != nil {
+		return nil, ptr, fmt.Errorf("failed to make spool file: %w", err)
+	}
+	fail := func(err error) (*os.File, api.Pointer, error) {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, ptr, err
+	}
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(hash.SHA256))
+	if err != nil {
+		return fail(err)
+	}
+	ptr.Size, err = io.Copy(io.MultiWriter(file, hasher), in)
+	if err != nil {
+		return fail(fmt.Errorf("failed to spool file: %w", err))
+	}
+	ptr.OID, err = hasher.SumString(hash.SHA256, false)
+	if err != nil {
+		return fail(err)
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return fail(err)
+	}
+	return file, ptr, nil
+}
+
+// upload sends the data in to the server returning its pointer
+//
+// The server must be told the SHA-256 and size before the data is
+// sent, so if the source doesn't know them the data is spooled to a
+// temporary file first.
+func (f *Fs) upload(ctx context.Context, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption) (ptr api.Pointer, err error) {
+	ptr.Size = src.Size()
+	ptr.OID, _ = src.Hash(ctx, hash.SHA256)
+	var hasher *hash.MultiHasher
+	if ptr.Size < 0 || !isOID(ptr.OID) {
+		file, spooled, err := spool(in)
+		if err != nil {
+			return ptr, err
+		}
+		defer func() {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}()
+		in, ptr = file, spooled
+	} else {
+		hasher, err = hash.NewMultiHasherTypes(hash.NewHashSet(hash.SHA256))
+		if err != nil {
+			return ptr, err
+		}
+		in = io.TeeReader(in, hasher)
+	}
+	obj, err := f.batch(ctx, api.OperationUpload, ptr)
+	if err != nil {
+		return ptr, err
+	}
+	action := obj.Actions[api.ActionUpload]
+	if action == nil {
+		fs.Debugf(src, "Server has object %s already", ptr.OID)
+		return ptr, nil
+	}
+	opts := actionOpts("PUT", action)
+	opts.Body = in
+	opts.ContentLength = &ptr.Size
+	opts.ContentType = "application/octet-stream"
+	opts.NoResponse = true
+	opts.Options = options
+	seeker, canRetry := in.(io.Seeker)
+	call := f.pacer.CallNoRetry
+	if canRetry {
+		call = f.pacer.Call
+	}
+	err = call(func() (bool, error) {
+		if canRetry {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return false, err
+			}
+		}
+		resp, err := f.transfer.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return ptr, fmt.Errorf("failed to upload object: %w", err)
+	}
+	if hasher != nil {
+		sum, err := hasher.SumString(hash.SHA256, false)
+		if err != nil {
+			return ptr, err
+		}
+		if sum != ptr.OID {
+			return ptr, fmt.Errorf("corrupted on transfer: SHA-256 differ %q vs %q", ptr.OID, sum)
+		}
+	}
+	if verify := obj.Actions[api.ActionVerify]; verify != nil {
+		return ptr, f.verify(ctx, verify, ptr)
+	}
+	return ptr, nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the SHA-256 of an object returning a lowercase hex string
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t != hash.SHA256 {
+		return "", hash.ErrUnsupported
+	}
+	return o.oid, nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the object
+//
+// This only changes the local record of the file.
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	err := o.fs.change(o.remote, func(rec *record) (*record, error) {
+		if !rec.isFile() {
+			return nil, fs.ErrorObjectNotFound
+		}
+		rec.ModTime = modTime
+		return rec, nil
+	})
+	if err != nil {
+		return err
+	}
+	o.modTime = modTime
+	return nil
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	obj, err := o.fs.batch(ctx, api.OperationDownload, api.Pointer{OID: o.oid, Size: o.size})
+	var objErr *api.ObjectError
+	if errors.As(err, &objErr) && (objErr.Code == http.StatusNotFound || objErr.Code == http.StatusGone) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	action := obj.Actions[api.ActionDownload]
+	if action == nil {
+		return nil, errors.New("server didn't say how to download the object")
+	}
+	fs.FixRangeOption(options, o.size)
+	opts := actionOpts("GET", action)
+	opts.Options = options
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.transfer.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object: %w", err)
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	if o.fs.lookup(o.remote).isDir() {
+		return fs.ErrorIsDir
+	}
+	ptr, err := o.fs.upload(ctx, in, src, options)
+	if err != nil {
+		return err
+	}
+	modTime := src.ModTime(ctx)
+	err = o.fs.setRecord(o.remote, ptr, modTime)
+	if err != nil {
+		return fmt.Errorf("failed to record file: %w", err)
+	}
+	o.oid = ptr.OID
+	o.size = ptr.Size
+	o.modTime = modTime
+	return nil
+}
+
+// Remove an object
+//
+// The batch API has no way of deleting objects so this only forgets
+// the file. The object stays on the server.
+func (o *Object) Remove(ctx context.Context) error {
+	return o.fs.change(o.remote, func(rec *record) (*record, error) {
+		if !rec.isFile() {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, nil
+	})
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.ListRer     = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.Shutdowner  = (*Fs)(nil)
+	_ fs.Commander   = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+)
@@ -0,0 +1,331 @@
+package gitlfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/gitlfs/api"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "transfer-token"
+
+// testServer is a Git LFS server keeping the objects in memory
+type testServer struct {
+	mu       sync.Mutex
+	url      string
+	objects  map[string][]byte
+	uploads  int      // number of PUT requests
+	verified []string // oids verified
+	requests []api.BatchRequest
+}
+
+func newTestServer(t *testing.T) (*testServer, *httptest.Server) {
+	s := &testServer{objects: map[string][]byte{}}
+	ts := httptest.NewServer(s)
+	s.url = ts.URL
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// ServeHTTP implements the batch API and the basic transfer adapter
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.URL.Path == "/repo.git/info/lfs/objects/batch" && r.Method == "POST":
+		s.batch(w, r)
+	case strings.HasPrefix(r.URL.Path, "/objects/"):
+		if r.Header.Get("X-Token") != testToken {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		oid := strings.TrimPrefix(r.URL.Path, "/objects/")
+		switch r.Method {
+		case "GET":
+			data, ok := s.objects[oid]
+			if !ok {
+				http.Error(w, "object not found", http.StatusNotFound)
+				return
+			}
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+		case "PUT":
+			data, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			sum := sha256.Sum256(data)
+			if hex.EncodeToString(sum[:]) != oid {
+				http.Error(w, "oid mismatch", http.StatusUnprocessableEntity)
+				return
+			}
+			s.uploads++
+			s.objects[oid] = data
+		default:
+			http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		}
+	case r.URL.Path == "/verify" && r.Method == "POST":
+		var ptr api.Pointer
+		if err := json.NewDecoder(r.Body).Decode(&ptr); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if data, ok := s.objects[ptr.OID]; !ok || int64(len(data)) != ptr.Size {
+			http.Error(w, "object not found", http.StatusNotFound)
+			return
+		}
+		s.verified = append(s.verified, ptr.OID)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// batch answers a batch request
+func (s *testServer) batch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != api.MediaType || r.Header.Get("Accept") != api.MediaType {
+		http.Error(w, "bad media type", http.StatusNotAcceptable)
+		return
+	}
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(api.Error{Message: "Credentials needed"})
+		return
+	}
+	var req api.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.requests = append(s.requests, req)
+	resp := api.BatchResponse{Transfer: api.TransferBasic}
+	header := map[string]string{"X-Token": testToken}
+	for _, ptr := range req.Objects {
+		obj := api.ObjectResponse{Pointer: ptr, Actions: map[string]*api.Action{}}
+		_, found := s.objects[ptr.OID]
+		switch {
+		case req.Operation == api.OperationUpload && !found:
+			obj.Actions[api.ActionUpload] = &api.Action{Href: s.url + "/objects/" + ptr.OID, Header: header}
+			obj.Actions[api.ActionVerify] = &api.Action{Href: s.url + "/verify"}
+		case req.Operation == api.OperationDownload && found:
+			obj.Actions[api.ActionDownload] = &api.Action{Href: s.url + "/objects/" + ptr.OID, Header: header}
+		case req.Operation == api.OperationDownload:
+			obj.Actions = nil
+			obj.Error = &api.ObjectError{Code: http.StatusNotFound, Message: "Object does not exist"}
+		}
+		resp.Objects = append(resp.Objects, obj)
+	}
+	w.Header().Set("Content-Type", api.MediaType)
+	_ = json.NewEncoder(w).Encode(&resp)
+}
+
+// TestLocal runs the integration tests against a test server
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("skipping as -remote is set")
+	}
+	_, ts := newTestServer(t)
+	name := "TestGitLFSLocal"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "gitlfs"},
+			{Name: name, Key: "url", Value: ts.URL + "/repo.git"},
+			{Name: name, Key: "user", Value: "user"},
+			{Name: name, Key: "pass", Value: obscure.MustObscure("secret")},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// newTestFs makes an Fs using the test server at ts
+func newTestFs(t *testing.T, ts *httptest.Server) *Fs {
+	ctx := context.Background()
+	f, err := NewFs(ctx, t.Name(), "dir", configmap.Simple{
+		"url":  ts.URL + "/repo.git/",
+		"user": "user",
+		"pass": obscure.MustObscure("secret"),
+		"ref":  "refs/heads/main",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = f.(*Fs).Shutdown(ctx)
+	})
+	return f.(*Fs)
+}
+
+// sha256Hex returns the SHA-256 of data as hex
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestEndpointURL(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "https://example.com/owner/repo.git", want: "https://example.com/owner/repo.git/info/lfs"},
+		{in: "https://example.com/owner/repo.git/", want: "https://example.com/owner/repo.git/info/lfs"},
+		{in: "https://example.com/lfs/", want: "https://example.com/lfs"},
+		{in: "ftp://example.com/repo.git", err: true},
+	} {
+		got, err := endpointURL(test.in)
+		if test.err {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestUpload(t *testing.T) {
+	ctx := context.Background()
+	s, ts := newTestServer(t)
+	f := newTestFs(t, ts)
+	data := []byte("hello world")
+	oid := sha256Hex(data)
+
+	// The source knows the hash
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), int64(len(data)), true, map[hash.Type]string{hash.SHA256: oid}, nil)
+	o, err := f.Put(ctx, bytes.NewReader(data), src)
+	require.NoError(t, err)
+	assert.Equal(t, data, s.objects[oid])
+	assert.Equal(t, []string{oid}, s.verified)
+	require.Equal(t, 1, len(s.requests))
+	assert.Equal(t, &api.Ref{Name: "refs/heads/main"}, s.requests[0].Ref)
+	assert.Equal(t, api.HashAlgoSHA256, s.requests[0].HashAlgo)
+	got, err := o.Hash(ctx, hash.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, oid, got)
+
+	// The source doesn't know the hash or size so it is spooled and
+	// not sent again as the server has it already
+	src = object.NewStaticObjectInfo("copy.txt", time.Now(), -1, true, nil, nil)
+	o, err = f.PutStream(ctx, bytes.NewReader(data), src)
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.uploads)
+	assert.Equal(t, int64(len(data)), o.Size())
+	got, err = o.Hash(ctx, hash.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, oid, got)
+}
+
+func TestOpenMissing(t *testing.T) {
+	ctx := context.Background()
+	s, ts := newTestServer(t)
+	f := newTestFs(t, ts)
+	data := []byte("gone")
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), int64(len(data)), true, nil, nil)
+	o, err := f.Put(ctx, bytes.NewReader(data), src)
+	require.NoError(t, err)
+	delete(s.objects, sha256Hex(data))
+	_, err = o.Open(ctx)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func TestBadCredentials(t *testing.T) {
+	ctx := context.Background()
+	_, ts := newTestServer(t)
+	f, err := NewFs(ctx, t.Name(), "", configmap.Simple{
+		"url": ts.URL + "/repo.git",
+	})
+	require.NoError(t, err)
+	defer func() {
+		_ = f.(*Fs).Shutdown(ctx)
+	}()
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), 1, true, nil, nil)
+	_, err = f.Put(ctx, bytes.NewReader([]byte("x")), src)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "git lfs error 401: Credentials needed")
+}
+
+func TestParsePointer(t *testing.T) {
+	oid := sha256Hex([]byte("hello"))
+	ptr := api.Pointer{OID: oid, Size: 5}
+	got, err := parsePointer(strings.NewReader(formatPointer(ptr)))
+	require.NoError(t, err)
+	assert.Equal(t, ptr, got)
+
+	// Extra keys are allowed after the version
+	got, err = parsePointer(strings.NewReader("version " + pointerVersion + "\next-0-foo sha256:abc\noid sha256:" + oid + "\nsize 5\n"))
+	require.NoError(t, err)
+	assert.Equal(t, ptr, got)
+
+	for _, in := range []string{
+		"",
+		"hello world\n",
+		"oid sha256:" + oid + "\nversion " + pointerVersion + "\nsize 5\n",
+		"version https://example.com/v2\noid sha256:" + oid + "\nsize 5\n",
+		"version " + pointerVersion + "\noid md5:" + oid + "\nsize 5\n",
+		"version " + pointerVersion + "\noid sha256:" + oid + "\n",
+		"version " + pointerVersion + "\noid sha256:" + oid + "\nsize -1\n",
+		formatPointer(ptr) + strings.Repeat("x", maxPointerSize),
+	} {
+		_, err := parsePointer(strings.NewReader(in))
+		assert.Error(t, err, in)
+	}
+}
+
+func TestImportAndPointer(t *testing.T) {
+	ctx := context.Background()
+	s, ts := newTestServer(t)
+	f := newTestFs(t, ts)
+	data := []byte("large file")
+	oid := sha256Hex(data)
+	s.objects[oid] = data
+	ptr := api.Pointer{OID: oid, Size: int64(len(data))}
+	missing := api.Pointer{OID: sha256Hex([]byte("missing")), Size: 7}
+
+	checkout := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(checkout, "assets"), 0777))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(checkout, "assets", "big.bin"), []byte(formatPointer(ptr)), 0666))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(checkout, "assets", "gone.bin"), []byte(formatPointer(missing)), 0666))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(checkout, "README.md"), []byte("# Readme\n"), 0666))
+
+	out, err := f.Command(ctx, "import", []string{checkout}, map[string]string{"check": "true"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"imported": 1, "skipped": 2}, out)
+
+	o, err := f.NewObject(ctx, "assets/big.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), o.Size())
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	got, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, data, got)
+	_, err = f.NewObject(ctx, "assets/gone.bin")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	out, err = f.Command(ctx, "pointer", []string{"assets/big.bin"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, formatPointer(ptr), out)
+	_, err = f.Command(ctx, "pointer", []string{"assets/gone.bin"}, nil)
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+}
@@ -0,0 +1,17 @@
+// Test Git LFS filesystem interface
+package gitlfs_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/gitlfs"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestGitLFS:",
+		NilObject:  (*gitlfs.Object)(nil),
+	})
+}
@@ -0,0 +1,233 @@
+package gitlfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// record is what is kept in the database for each file
+//
+// The batch API can't list the objects on the server, or give them
+// names, so the database is the only place the names are kept.
+type record struct {
+	OID     string    `json:"oid,omitempty"` // SHA-256 of the file or "" for a directory
+	Size    int64     `json:"size"`          // size of the file
+	ModTime time.Time `json:"mtime"`         // modification time of the file
+	Dir     bool      `json:"dir,omitempty"` // set if this is a directory
+}
+
+// isFile returns whether rec describes a file
+func (rec *record) isFile() bool {
+	return rec != nil && rec.OID != ""
+}
+
+// isDir returns whether rec describes a directory
+func (rec *record) isDir() bool {
+	return rec != nil && rec.Dir
+}
+
+// key returns the database key for remote
+func (f *Fs) key(remote string) string {
+	return strings.Trim(path.Join(f.root, remote), "/")
+}
+
+// lookup returns the record for remote or nil if there isn't one
+func (f *Fs) lookup(remote string) *record {
+	op := &kvGet{key: f.key(remote)}
+	if err := f.db.Do(false, op); err != nil && err != kv.ErrEmpty {
+		fs.Debugf(remote, "Failed to read file record: %v", err)
+	}
+	return op.rec
+}
+
+// change calls fn with the record for remote, which is nil if there
+// isn't one, and stores the record it returns or deletes it if that
+// is nil
+func (f *Fs) change(remote string, fn func(rec *record) (*record, error)) error {
+	return f.db.Do(true, &kvChange{key: f.key(remote), fn: fn})
+}
+
+// records returns the records in the directory dir and below keyed
+// by their path relative to dir
+//
+// It returns fs.ErrorDirNotFound if dir isn't a directory.
+func (f *Fs) records(dir string) (map[string]*record, error) {
+	op := &kvList{dir: f.key(dir), recs: map[string]*record{}}
+	err := f.db.Do(false, op)
+	if err == kv.ErrEmpty {
+		err = nil
+		if op.dir != "" {
+			err = fs.ErrorDirNotFound
+		}
+	}
+	return op.recs, err
+}
+
+// mkdir makes the directory dir and any directories it is in
+func (f *Fs) mkdir(dir string) error {
+	var keys []string
+	for key := f.key(dir); key != ""; key = parentKey(key) {
+		keys = append([]string{key}, keys...)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return f.db.Do(true, &kvMkdir{keys: keys, modTime: time.Now()})
+}
+
+// rmdir removes the directory dir if it is empty
+func (f *Fs) rmdir(dir string) error {
+	key := f.key(dir)
+	if key == "" {
+		return nil
+	}
+	err := f.db.Do(true, &kvRmdir{key: key})
+	if err == kv.ErrEmpty {
+		err = fs.ErrorDirNotFound
+	}
+	return err
+}
+
+// parentKey returns the key of the directory key is in
+func parentKey(key string) string {
+	i := strings.LastIndex(key, "/")
+	if i < 0 {
+		return ""
+	}
+	return key[:i]
+}
+
+// getRecord reads the record for key from b
+func getRecord(b kv.Bucket, key string) (*record, error) {
+	data := b.Get([]byte(key))
+	if data == nil {
+		return nil, nil
+	}
+	rec := new(record)
+	err := json.Unmarshal(data, rec)
+	return rec, err
+}
+
+// kvGet: read the record for a key
+type kvGet struct {
+	key string
+	rec *record
+}
+
+func (op *kvGet) Do(ctx context.Context, b kv.Bucket) (err error) {
+	op.rec, err = getRecord(b, op.key)
+	return err
+}
+
+// kvChange: edit or remove the record for a key
+type kvChange struct {
+	key string
+	fn  func(rec *record) (*record, error)
+}
+
+func (op *kvChange) Do(ctx context.Context, b kv.Bucket) error {
+	rec, err := getRecord(b, op.key)
+	if err != nil {
+		return err
+	}
+	rec, err = op.fn(rec)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return b.Delete([]byte(op.key))
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(op.key), data)
+}
+
+// kvList: read the records below dir keyed by their path relative to
+// dir
+type kvList struct {
+	dir  string
+	recs map[string]*record
+}
+
+func (op *kvList) Do(ctx context.Context, b kv.Bucket) error {
+	var prefix []byte
+	if op.dir != "" {
+		rec, err := getRecord(b, op.dir)
+		if err != nil {
+			return err
+		}
+		if !rec.isDir() {
+			return fs.ErrorDirNotFound
+		}
+		prefix = []byte(op.dir + "/")
+	}
+	c := b.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		rec := new(record)
+		if err := json.Unmarshal(v, rec); err != nil {
+			return err
+		}
+		op.recs[string(k[len(prefix):])] = rec
+	}
+	return nil
+}
+
+// kvMkdir: make directories at keys, the outermost first
+type kvMkdir struct {
+	keys    []string
+	modTime time.Time
+}
+
+func (op *kvMkdir) Do(ctx context.Context, b kv.Bucket) error {
+	for _, key := range op.keys {
+		rec, err := getRecord(b, key)
+		if err != nil {
+			return err
+		}
+		if rec.isDir() {
+			continue
+		}
+		if rec != nil {
+			return fs.ErrorIsFile
+		}
+		data, err := json.Marshal(&record{Dir: true, ModTime: op.modTime})
+		if err != nil {
+			return err
+		}
+		err = b.Put([]byte(key), data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kvRmdir: remove the directory at key if it is empty
+type kvRmdir struct {
+	key string
+}
+
+func (op *kvRmdir) Do(ctx context.Context, b kv.Bucket) error {
+	rec, err := getRecord(b, op.key)
+	if err != nil {
+		return err
+	}
+	if !rec.isDir() {
+		return fs.ErrorDirNotFound
+	}
+	prefix := []byte(op.key + "/")
+	c := b.Cursor()
+	if k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix) {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	return b.Delete([]byte(op.key))
+}
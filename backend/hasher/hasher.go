@@ -50,6 +50,26 @@ func init() {
 			Advanced: true,
 			Default:  fs.SizeSuffix(0),
 			Help:     "Auto-update checksum for files smaller than this size (disabled by default).",
+		}, {
+			Name:     "import_metadata",
+			Advanced: true,
+			Default:  false,
+			Help: `Seed the cache from checksums in the metadata of the remote.
+
+If set, a checksum missing from the cache is looked for in the
+metadata of the file on the remote under the name of the hash, e.g.
+"md5" or "sha1", before downloading the file.`,
+		}, {
+			Name:     "sum_files",
+			Advanced: true,
+			Default:  fs.CommaSepList{},
+			Help: `Seed the cache from checksum files with these names, e.g. MD5SUMS,*.sha1.
+
+If set, a checksum missing from the cache is looked for in checksum
+files matching these patterns in the directory of the file and the
+directories above it before downloading the file. The type of the
+checksums is worked out from the name of the checksum file. Each
+directory is only read once while rclone is running.`,
 		}},
 	})
 }
@@ -60,6 +80,9 @@ type Options struct {
 	Hashes   fs.CommaSepList `config:"hashes"`
 	AutoSize fs.SizeSuffix   `config:"auto_size"`
 	MaxAge   fs.Duration     `config:"max_age"`
+
+	ImportMetadata bool            `config:"import_metadata"`
+	SumFiles       fs.CommaSepList `config:"sum_files"`
 }
 
 // Fs represents a wrapped fs.Fs
@@ -80,6 +103,9 @@ type Fs struct {
 	slowHashes hash.Set // passed to the base and then cached
 	autoHashes hash.Set // calculated in-house and cached
 	keepHashes hash.Set // checksums to keep in cache (slow + auto)
+	// checksums read from sum files by directory
+	sumFilesMu sync.Mutex
+	sumFiles   map[string]map[string]hashMap
 }
 
 var warnExperimental sync.Once
@@ -109,10 +135,11 @@ func NewFs(ctx context.Context, fsname, rpath string, cmap configmap.Mapper) (fs
 	}
 
 	f := &Fs{
-		Fs:   baseFs,
-		name: fsname,
-		root: rpath,
-		opt:  opt,
+		Fs:       baseFs,
+		name:     fsname,
+		root:     rpath,
+		opt:      opt,
+		sumFiles: map[string]map[string]hashMap{},
 	}
 	baseFeatures := baseFs.Features()
 	f.fpTime = baseFs.Precision() != fs.ModTimeNotSupported
@@ -241,6 +268,21 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	})
 }
 
+// ListP lists the objects and directories in dir a page at a time.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	do := f.Fs.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	return do(ctx, dir, func(baseEntries fs.DirEntries) error {
+		hashEntries, err := f.wrapEntries(baseEntries)
+		if err != nil {
+			return err
+		}
+		return callback(hashEntries)
+	})
+}
+
 // Purge a directory
 func (f *Fs) Purge(ctx context.Context, dir string) error {
 	if do := f.Fs.Features().Purge; do != nil {
@@ -517,6 +559,7 @@ var (
 	_ fs.CleanUpper      = (*Fs)(nil)
 	_ fs.UnWrapper       = (*Fs)(nil)
 	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
 	_ fs.Wrapper         = (*Fs)(nil)
 	_ fs.MergeDirser     = (*Fs)(nil)
@@ -8,6 +8,7 @@ import (
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/fstests"
@@ -67,12 +68,68 @@ func (f *Fs) testUploadFromCrypt(t *testing.T) {
 	_ = operations.Purge(ctx, f, dirName)
 }
 
+func (f *Fs) testSeedFromSumFiles(t *testing.T) {
+	// make a temporary local remote with sum files in it
+	tempRoot, err := fstest.LocalRemote()
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tempRoot)
+	}()
+	ctx := context.Background()
+	localFs, err := fs.NewFs(ctx, tempRoot)
+	require.NoError(t, err)
+	putFile(ctx, t, localFs, "dir/file1", "potato")
+	putFile(ctx, t, localFs, "dir/file2", "carrot")
+	putFile(ctx, t, localFs, "MD5SUMS", "00112233445566778899aabbccddeeff  dir/file1\n")
+	putFile(ctx, t, localFs, "dir/checksums.sha1", "00112233445566778899AABBCCDDEEFF00112233 *file1\n")
+
+	remote := fmt.Sprintf(`:hasher,remote="%s",sum_files="MD5SUMS,*.sha1":`, tempRoot)
+	hasherFs, err := fs.NewFs(ctx, remote)
+	require.NoError(t, err)
+	defer func() {
+		_ = hasherFs.(*Fs).db.Stop(true)
+	}()
+
+	// The checksums come from the sum files rather than the data
+	o, err := hasherFs.NewObject(ctx, "dir/file1")
+	require.NoError(t, err)
+	md5, err := o.Hash(ctx, hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "00112233445566778899aabbccddeeff", md5)
+	sha1, err := o.Hash(ctx, hash.SHA1)
+	require.NoError(t, err)
+	assert.Equal(t, "00112233445566778899aabbccddeeff00112233", sha1)
+
+	// Files not in a sum file are hashed as normal
+	o, err = hasherFs.NewObject(ctx, "dir/file2")
+	require.NoError(t, err)
+	md5, err = o.Hash(ctx, hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "005d05de29487ec44cd07bd9d757d4e1", md5)
+}
+
+func TestSeedHelpers(t *testing.T) {
+	keep := hash.NewHashSet(hash.MD5, hash.SHA1)
+	assert.Equal(t, hash.MD5, sumFileHashType("MD5SUMS", keep))
+	assert.Equal(t, hash.SHA1, sumFileHashType("file.sha1", keep))
+	assert.Equal(t, hash.None, sumFileHashType("SHA256SUMS", keep))
+
+	assert.Equal(t, hashMap{hash.MD5: "abcd"}, metadataHashes(fs.Metadata{"md5": " ABCD ", "sha256": "ef"}, keep))
+	assert.Equal(t, hashMap{}, metadataHashes(nil, keep))
+
+	patterns := fs.CommaSepList{"MD5SUMS", "*.sha1"}
+	assert.True(t, matchSumFile(patterns, "MD5SUMS"))
+	assert.True(t, matchSumFile(patterns, "x.sha1"))
+	assert.False(t, matchSumFile(patterns, "x.md5"))
+}
+
 // InternalTest dispatches all internal tests
 func (f *Fs) InternalTest(t *testing.T) {
 	if !kv.Supported() {
 		t.Skip("hasher is not supported on this OS")
 	}
 	t.Run("UploadFromCrypt", f.testUploadFromCrypt)
+	t.Run("SeedFromSumFiles", f.testSeedFromSumFiles)
 }
 
 var _ fstests.InternalTester = (*Fs)(nil)
@@ -87,6 +87,11 @@ func (o *Object) Hash(ctx context.Context, hashType hash.Type) (hashVal string,
 		fs.Debugf(o, "cached %s = %q", hashType, hashVal)
 		return hashVal, nil
 	}
+	if f.keepHashes.Contains(hashType) {
+		if hashVal = o.seedHash(ctx, hashType); hashVal != "" {
+			return hashVal, nil
+		}
+	}
 	if f.slowHashes.Contains(hashType) {
 		fs.Debugf(o, "slow %s", hashType)
 		hashVal, err = o.Object.Hash(ctx, hashType)
@@ -0,0 +1,135 @@
+package hasher
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// seedHash looks for the checksum of the object in the metadata of
+// the base object and in the sum files alongside it, caching all the
+// checksums found. It returns "" if it wasn't found.
+func (o *Object) seedHash(ctx context.Context, hashType hash.Type) string {
+	f := o.f
+	var hashes hashMap
+	if f.opt.ImportMetadata {
+		meta, err := fs.GetMetadata(ctx, o.Object)
+		if err != nil {
+			fs.Debugf(o, "failed to read metadata: %v", err)
+		}
+		hashes = metadataHashes(meta, f.keepHashes)
+	}
+	if len(hashes) == 0 && len(f.opt.SumFiles) > 0 {
+		hashes = f.sumFileHashes(ctx, o.Remote())
+	}
+	if len(hashes) == 0 {
+		return ""
+	}
+	if err := o.putHashes(ctx, hashes); err != nil {
+		fs.Debugf(o, "putHashes: %v", err)
+	}
+	fs.Debugf(o, "seeded %s = %q", hashType, hashes[hashType])
+	return hashes[hashType]
+}
+
+// metadataHashes returns the checksums of the types in keep found in
+// metadata under the name of the hash, e.g. "md5"
+func metadataHashes(metadata fs.Metadata, keep hash.Set) hashMap {
+	hashes := hashMap{}
+	for _, hashType := range keep.Array() {
+		if hashVal := strings.ToLower(strings.TrimSpace(metadata[hashType.String()])); hashVal != "" {
+			hashes[hashType] = hashVal
+		}
+	}
+	return hashes
+}
+
+// sumFileHashType works out the type of the checksums in a sum file
+// from its name, e.g. MD5SUMS or file.sha1, or returns hash.None
+func sumFileHashType(name string, keep hash.Set) (hashType hash.Type) {
+	name = strings.ToLower(name)
+	longest := 0
+	for _, ht := range keep.Array() {
+		if htName := ht.String(); len(htName) > longest && strings.Contains(name, htName) {
+			hashType = ht
+			longest = len(htName)
+		}
+	}
+	return hashType
+}
+
+// sumFileHashes returns the checksums for remote found in the sum
+// files in its directory and the ones above it
+func (f *Fs) sumFileHashes(ctx context.Context, remote string) hashMap {
+	hashes := hashMap{}
+	dir := remote
+	for dir != "" {
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+		for hashType, hashVal := range f.readSumFiles(ctx, dir)[remote] {
+			if hashes[hashType] == "" {
+				hashes[hashType] = hashVal
+			}
+		}
+	}
+	return hashes
+}
+
+// readSumFiles reads the checksums from the sum files in dir,
+// returning them by remote. The result is kept so each directory is
+// only read once.
+func (f *Fs) readSumFiles(ctx context.Context, dir string) map[string]hashMap {
+	f.sumFilesMu.Lock()
+	defer f.sumFilesMu.Unlock()
+	if sums, found := f.sumFiles[dir]; found {
+		return sums
+	}
+	sums := map[string]hashMap{}
+	f.sumFiles[dir] = sums
+	entries, err := f.Fs.List(ctx, dir)
+	if err != nil {
+		fs.Debugf(f, "failed to list %q for sum files: %v", dir, err)
+		return sums
+	}
+	entries.ForObject(func(o fs.Object) {
+		leaf := path.Base(o.Remote())
+		if !matchSumFile(f.opt.SumFiles, leaf) {
+			return
+		}
+		hashType := sumFileHashType(leaf, f.keepHashes)
+		if hashType == hash.None {
+			fs.Debugf(o, "can't work out which checksums are in this sum file")
+			return
+		}
+		fileSums, err := operations.ParseSumFile(ctx, o)
+		if err != nil {
+			fs.Errorf(o, "failed to parse sum file: %v", err)
+			return
+		}
+		fs.Debugf(o, "read %d %s checksums", len(fileSums), hashType)
+		for file, hashVal := range fileSums {
+			remote := path.Join(dir, strings.TrimPrefix(path.Clean(file), "./"))
+			if sums[remote] == nil {
+				sums[remote] = hashMap{}
+			}
+			sums[remote][hashType] = strings.ToLower(hashVal)
+		}
+	})
+	return sums
+}
+
+// matchSumFile returns whether leaf matches one of the sum file patterns
+func matchSumFile(patterns fs.CommaSepList, leaf string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, leaf); matched {
+			return true
+		}
+	}
+	return false
+}
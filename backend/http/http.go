@@ -5,6 +5,7 @@
 package http
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -89,6 +90,52 @@ that directory listings are much quicker, but rclone won't have the times or
 sizes of any files, and some files that don't exist may be in the listing.`,
 			Default:  false,
 			Advanced: true,
+		}, {
+			Name: "list_format",
+			Help: `Format of the directory listings served by the site.
+
+By default rclone decides how to parse a listing from its
+Content-Type, reading links from HTML pages and entries from JSON.
+
+JSON listings are those produced by nginx with "autoindex_format json"
+and by Caddy's file_server browse. These include sizes and
+modification times so no HEAD requests are needed for the files.
+
+Use "sitemap" for sites which don't serve listings at all but do
+publish a sitemap.xml. The sitemap is read once and the directory
+structure is worked out from the URLs in it.`,
+			Default: listFormatAuto,
+			Examples: []fs.OptionExample{{
+				Value: listFormatAuto,
+				Help:  "Choose the parser from the Content-Type of the page.",
+			}, {
+				Value: listFormatHTML,
+				Help:  "Read links from HTML index pages, e.g. Apache, nginx or Caddy.",
+			}, {
+				Value: listFormatJSON,
+				Help:  "Read JSON autoindex pages, e.g. nginx or Caddy.",
+			}, {
+				Value: listFormatSitemap,
+				Help:  "List the files found in the sitemap.",
+			}},
+			Advanced: true,
+		}, {
+			Name: "sitemap_url",
+			Help: `URL of the sitemap to use with --http-list-format sitemap.
+
+This is resolved relative to the url so can be left as the default
+if the sitemap is in the usual place.`,
+			Default:  defaultSitemapURL,
+			Advanced: true,
+		}, {
+			Name: "max_page_size",
+			Help: `Maximum size of a directory listing page.
+
+If a listing page is bigger than this then listing the directory
+will fail rather than rclone reading an unbounded amount of data
+into memory. This also applies to the sitemap.`,
+			Default:  fs.SizeSuffix(-1),
+			Advanced: true,
 		}},
 	}
 	fs.Register(fsi)
@@ -96,10 +143,13 @@ sizes of any files, and some files that don't exist may be in the listing.`,
 
 // Options defines the configuration for this backend
 type Options struct {
-	Endpoint string          `config:"url"`
-	NoSlash  bool            `config:"no_slash"`
-	NoHead   bool            `config:"no_head"`
-	Headers  fs.CommaSepList `config:"headers"`
+	Endpoint    string          `config:"url"`
+	NoSlash     bool            `config:"no_slash"`
+	NoHead      bool            `config:"no_head"`
+	Headers     fs.CommaSepList `config:"headers"`
+	ListFormat  string          `config:"list_format"`
+	SitemapURL  string          `config:"sitemap_url"`
+	MaxPageSize fs.SizeSuffix   `config:"max_page_size"`
 }
 
 // Fs stores the interface to the remote HTTP files
@@ -112,6 +162,9 @@ type Fs struct {
 	endpoint    *url.URL
 	endpointURL string // endpoint as a string
 	httpClient  *http.Client
+	sitemapURL  string                 // URL of the sitemap if in use
+	sitemapMu   sync.Mutex             // protects sitemap
+	sitemap     map[string][]listEntry // directory listings read from the sitemap
 }
 
 // Object is a remote object that has been stat'd (so it exists, but is not necessarily open for reading)
@@ -225,6 +278,14 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		return nil, errors.New("odd number of headers supplied")
 	}
 
+	switch opt.ListFormat {
+	case "":
+		opt.ListFormat = listFormatAuto
+	case listFormatAuto, listFormatHTML, listFormatJSON, listFormatSitemap:
+	default:
+		return nil, fmt.Errorf("unknown list_format %q", opt.ListFormat)
+	}
+
 	if !strings.HasSuffix(opt.Endpoint, "/") {
 		opt.Endpoint += "/"
 	}
@@ -262,6 +323,17 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		CanHaveEmptyDirectories: true,
 	}).Fill(ctx, f)
 
+	if opt.ListFormat == listFormatSitemap {
+		if opt.SitemapURL == "" {
+			opt.SitemapURL = defaultSitemapURL
+		}
+		sitemapURL, err := rest.URLJoin(base, opt.SitemapURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sitemap_url: %w", err)
+		}
+		f.sitemapURL = sitemapURL.String()
+	}
+
 	if isFile {
 		// return an error with an fs which points to the parent
 		return f, fs.ErrorIsFile
@@ -423,7 +495,14 @@ func (f *Fs) addHeaders(req *http.Request) {
 }
 
 // Read the directory passed in
-func (f *Fs) readDir(ctx context.Context, dir string) (names []string, err error) {
+func (f *Fs) readDir(ctx context.Context, dir string) (entries []listEntry, err error) {
+	if f.opt.ListFormat == listFormatSitemap {
+		entries, err = f.sitemapDir(ctx, dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to readDir: %w", err)
+		}
+		return entries, nil
+	}
 	URL := f.url(dir)
 	u, err := url.Parse(URL)
 	if err != nil {
@@ -432,35 +511,43 @@ func (f *Fs) readDir(ctx context.Context, dir string) (names []string, err error
 	if !strings.HasSuffix(URL, "/") {
 		return nil, fmt.Errorf("internal error: readDir URL %q didn't end in /", URL)
 	}
-	// Do the request
-	req, err := http.NewRequestWithContext(ctx, "GET", URL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("readDir failed: %w", err)
-	}
-	f.addHeaders(req)
-	res, err := f.httpClient.Do(req)
-	if err == nil {
-		defer fs.CheckClose(res.Body, &err)
-		if res.StatusCode == http.StatusNotFound {
-			return nil, fs.ErrorDirNotFound
-		}
+	accept := ""
+	if f.opt.ListFormat == listFormatJSON {
+		accept = "application/json"
+	}
+	contentType, data, err := f.fetchPage(ctx, URL, accept)
+	if err == fs.ErrorDirNotFound {
+		return nil, err
 	}
-	err = statusError(res, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to readDir: %w", err)
 	}
 
-	contentType := strings.SplitN(res.Header.Get("Content-Type"), ";", 2)[0]
-	switch contentType {
-	case "text/html":
-		names, err = parse(u, res.Body)
+	format := f.opt.ListFormat
+	if format == listFormatAuto {
+		switch contentType {
+		case "text/html":
+			format = listFormatHTML
+		case "application/json":
+			format = listFormatJSON
+		default:
+			return nil, fmt.Errorf("can't parse content type %q", contentType)
+		}
+	}
+	switch format {
+	case listFormatHTML:
+		names, err := parse(u, bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("readDir: %w", err)
+		}
+		entries = namesToEntries(names)
+	case listFormatJSON:
+		entries, err = parseJSON(data)
 		if err != nil {
 			return nil, fmt.Errorf("readDir: %w", err)
 		}
-	default:
-		return nil, fmt.Errorf("can't parse content type %q", contentType)
 	}
-	return names, nil
+	return entries, nil
 }
 
 // List the objects and directories in dir into entries.  The
@@ -476,7 +563,7 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 	if !strings.HasSuffix(dir, "/") && dir != "" {
 		dir += "/"
 	}
-	names, err := f.readDir(ctx, dir)
+	listing, err := f.readDir(ctx, dir)
 	if err != nil {
 		return nil, fmt.Errorf("error listing %q: %w", dir, err)
 	}
@@ -512,13 +599,22 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 			}
 		}()
 	}
-	for _, name := range names {
-		isDir := name[len(name)-1] == '/'
-		name = strings.TrimRight(name, "/")
+	for _, item := range listing {
+		name := strings.TrimRight(item.name, "/")
 		remote := path.Join(dir, name)
-		if isDir {
-			add(fs.NewDir(remote, timeUnset))
-		} else {
+		switch {
+		case item.isDir():
+			add(fs.NewDir(remote, item.modTime))
+		case item.known:
+			// The listing has told us all we need to know
+			add(&Object{
+				fs:          f,
+				remote:      remote,
+				size:        item.size,
+				modTime:     item.modTime,
+				contentType: fs.MimeTypeFromName(remote),
+			})
+		default:
 			in <- remote
 		}
 	}
@@ -20,6 +20,7 @@ import (
 	"github.com/rclone/rclone/fs/config/configfile"
 	"github.com/rclone/rclone/fs/config/configmap"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
 	"github.com/rclone/rclone/lib/rest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -479,3 +480,201 @@ func TestFsNoSlashRoots(t *testing.T) {
 		}
 	}
 }
+
+// Load a test file and return its contents
+func loadIndexFile(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join(testPath, "index_files", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseJSONNginx(t *testing.T) {
+	entries, err := parseJSON(loadIndexFile(t, "nginx.json"))
+	require.NoError(t, err)
+	dirTime := time.Date(2017, 5, 4, 21, 37, 0, 0, time.UTC)
+	fileTime := time.Date(2017, 5, 4, 20, 42, 0, 0, time.UTC)
+	require.Equal(t, 4, len(entries))
+	assert.Equal(t, "deltas/", entries[0].name)
+	assert.True(t, entries[0].isDir())
+	assert.True(t, dirTime.Equal(entries[0].modTime))
+	assert.False(t, entries[0].known)
+	assert.Equal(t, "objects/", entries[1].name)
+	assert.Equal(t, listEntry{name: "config", size: 118, modTime: fileTime, known: true}, normaliseEntry(entries[2]))
+	assert.Equal(t, "summary", entries[3].name)
+	assert.Equal(t, int64(806), entries[3].size)
+}
+
+func TestParseJSONCaddy(t *testing.T) {
+	entries, err := parseJSON(loadIndexFile(t, "caddy.json"))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(entries))
+	assert.Equal(t, listEntry{
+		name:    "mimetype.zip",
+		size:    783696,
+		modTime: time.Date(2016, 12, 20, 12, 37, 6, 0, time.UTC),
+		known:   true,
+	}, normaliseEntry(entries[0]))
+	assert.Equal(t, "v1.36-22-g06ea13a-ssh-agentβ/", entries[1].name)
+	assert.Equal(t, int64(-1), entries[1].size)
+	assert.False(t, entries[1].known)
+}
+
+func TestParseJSONError(t *testing.T) {
+	_, err := parseJSON([]byte(`{"not":"a list"}`))
+	assert.Error(t, err)
+}
+
+// normaliseEntry makes the times in entry comparable with assert.Equal
+func normaliseEntry(entry listEntry) listEntry {
+	entry.modTime = entry.modTime.UTC()
+	return entry
+}
+
+func TestSitemapTree(t *testing.T) {
+	doc, err := parseSitemap(loadIndexFile(t, "sitemap.xml"))
+	require.NoError(t, err)
+	assert.Equal(t, 6, len(doc.URLs))
+
+	base, err := url.Parse("http://example.com/")
+	require.NoError(t, err)
+	tree := sitemapTree(base, doc.URLs)
+
+	names := func(dir string) (names []string) {
+		entries, found := tree[dir]
+		require.True(t, found, dir)
+		for _, entry := range entries {
+			names = append(names, entry.name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"one.txt", "dir/"}, names(""))
+	assert.Equal(t, []string{"sub/", "three.txt"}, names("dir/"))
+	assert.Equal(t, []string{"two words.txt"}, names("dir/sub/"))
+	assert.Equal(t, 3, len(tree))
+	assert.True(t, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC).Equal(tree[""][0].modTime))
+	assert.True(t, time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC).Equal(tree["dir/sub/"][0].modTime))
+	assert.Equal(t, timeUnset, tree["dir/"][1].modTime)
+
+	// Only the part of the sitemap under the base is used
+	base, err = url.Parse("http://example.com/dir/")
+	require.NoError(t, err)
+	tree = sitemapTree(base, doc.URLs)
+	assert.Equal(t, []string{"sub/", "three.txt"}, names(""))
+}
+
+func TestParseSitemapError(t *testing.T) {
+	_, err := parseSitemap([]byte(`<html></html>`))
+	assert.Error(t, err)
+}
+
+func TestReadPage(t *testing.T) {
+	data, err := readPage(strings.NewReader("potato"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, "potato", string(data))
+
+	data, err = readPage(strings.NewReader("potato"), 6)
+	require.NoError(t, err)
+	assert.Equal(t, "potato", string(data))
+
+	_, err = readPage(strings.NewReader("potato"), 5)
+	assert.Equal(t, errPageTooLarge, err)
+}
+
+func TestListJSON(t *testing.T) {
+	modTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method, "no HEAD requests expected")
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `[{"name":"dir","is_dir":true,"mod_time":%q},{"name":"file.txt","size":42,"mod_time":%q}]`,
+			modTime.Format(time.RFC3339), modTime.Format(time.RFC3339))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	f, err := NewFs(context.Background(), remoteName, "", configmap.Simple{
+		"type":        "http",
+		"url":         ts.URL,
+		"list_format": "json",
+	})
+	require.NoError(t, err)
+
+	entries, err := f.List(context.Background(), "")
+	require.NoError(t, err)
+	sort.Sort(entries)
+	require.Equal(t, 2, len(entries))
+	_, ok := entries[0].(fs.Directory)
+	assert.True(t, ok)
+	assert.Equal(t, "dir", entries[0].Remote())
+	o, ok := entries[1].(*Object)
+	require.True(t, ok)
+	assert.Equal(t, "file.txt", o.Remote())
+	assert.Equal(t, int64(42), o.Size())
+	assert.True(t, modTime.Equal(o.ModTime(context.Background())))
+	assert.Equal(t, "text/plain; charset=utf-8", o.MimeType(context.Background()))
+
+	// Check max_page_size is applied
+	f.(*Fs).opt.MaxPageSize = 10
+	_, err = f.List(context.Background(), "")
+	assert.ErrorIs(t, err, errPageTooLarge)
+}
+
+func TestListSitemap(t *testing.T) {
+	var ts *httptest.Server
+	sitemapReads := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			sitemapReads++
+			_, _ = fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%s/inner.xml</loc></sitemap></sitemapindex>`, ts.URL)
+		case "/inner.xml":
+			_, _ = fmt.Fprintf(w, `<urlset><url><loc>%s/files/one.txt</loc></url><url><loc>%s/files/dir/two.txt</loc></url></urlset>`, ts.URL, ts.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ts = httptest.NewServer(handler)
+	defer ts.Close()
+
+	f, err := NewFs(context.Background(), remoteName, "files/", configmap.Simple{
+		"type":        "http",
+		"url":         ts.URL,
+		"list_format": "sitemap",
+		"no_head":     "true",
+	})
+	require.NoError(t, err)
+
+	entries, err := f.List(context.Background(), "")
+	require.NoError(t, err)
+	sort.Sort(entries)
+	require.Equal(t, 2, len(entries))
+	assert.Equal(t, "dir", entries[0].Remote())
+	assert.Equal(t, "one.txt", entries[1].Remote())
+
+	entries, err = f.List(context.Background(), "dir")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "dir/two.txt", entries[0].Remote())
+
+	_, err = f.List(context.Background(), "notfound")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+
+	assert.Equal(t, 1, sitemapReads)
+}
+
+// TestIntegration runs the read only integration tests against the
+// test files
+func TestIntegration(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir(filesPath)))
+	defer ts.Close()
+	name := "TestHTTPReadOnly"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "http"},
+			{Name: name, Key: "url", Value: ts.URL},
+		},
+		QuickTestOK: true,
+	})
+}
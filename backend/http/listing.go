@@ -0,0 +1,321 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// Values for the list_format option
+const (
+	listFormatAuto    = "auto"
+	listFormatHTML    = "html"
+	listFormatJSON    = "json"
+	listFormatSitemap = "sitemap"
+)
+
+// Default location of the sitemap relative to the url
+const defaultSitemapURL = "sitemap.xml"
+
+// Maximum number of nested sitemap indexes to follow
+const maxSitemapDepth = 4
+
+// errPageTooLarge is returned if a listing page is bigger than max_page_size
+var errPageTooLarge = errors.New("listing page is larger than --http-max-page-size")
+
+// listEntry is a single entry found in a directory listing
+type listEntry struct {
+	name    string    // name relative to the directory, ending in / for directories
+	size    int64     // size of the file or -1 if not known
+	modTime time.Time // modification time or timeUnset if not known
+	known   bool      // set if size and modTime were supplied by the listing
+}
+
+// isDir returns true if the entry is a directory
+func (e *listEntry) isDir() bool {
+	return strings.HasSuffix(e.name, "/")
+}
+
+// namesToEntries converts names found in an HTML page into listEntries
+func namesToEntries(names []string) []listEntry {
+	if names == nil {
+		return nil
+	}
+	entries := make([]listEntry, len(names))
+	for i, name := range names {
+		entries[i] = listEntry{
+			name:    name,
+			size:    -1,
+			modTime: timeUnset,
+		}
+	}
+	return entries
+}
+
+// readPage reads the body of a listing page checking it isn't
+// larger than maxSize if that is set
+func readPage(in io.Reader, maxSize fs.SizeSuffix) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(in)
+	}
+	data, err := io.ReadAll(io.LimitReader(in, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > int64(maxSize) {
+		return nil, errPageTooLarge
+	}
+	return data, nil
+}
+
+// jsonItem is an entry in a JSON directory listing
+//
+// This covers the output of nginx with "autoindex_format json" and
+// of the Caddy file_server browse handler when asked for JSON.
+type jsonItem struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`     // nginx: "file", "directory" or "other"
+	Mtime   string `json:"mtime"`    // nginx: RFC1123 time
+	IsDir   bool   `json:"is_dir"`   // caddy
+	ModTime string `json:"mod_time"` // caddy: RFC3339 time
+	Size    *int64 `json:"size"`
+}
+
+// parseJSONTime parses the times found in JSON listings
+func parseJSONTime(s string) (time.Time, bool) {
+	if s == "" {
+		return timeUnset, false
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return t, true
+	}
+	return timeUnset, false
+}
+
+// parseJSON turns a JSON directory listing into entries
+func parseJSON(in []byte) (entries []listEntry, err error) {
+	var items []jsonItem
+	err = json.Unmarshal(in, &items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON listing: %w", err)
+	}
+	seen := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		name := item.Name
+		if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+			fs.Debugf(nil, "Skipping JSON listing entry %q", name)
+			continue
+		}
+		isDir := item.IsDir || item.Type == "directory"
+		if !isDir && item.Type != "" && item.Type != "file" {
+			fs.Debugf(nil, "Skipping JSON listing entry %q of type %q", name, item.Type)
+			continue
+		}
+		entry := listEntry{
+			name:    name,
+			size:    -1,
+			modTime: timeUnset,
+		}
+		if isDir {
+			entry.name += "/"
+		}
+		if _, found := seen[entry.name]; found {
+			continue
+		}
+		seen[entry.name] = struct{}{}
+		modTime, haveTime := parseJSONTime(item.Mtime)
+		if !haveTime {
+			modTime, haveTime = parseJSONTime(item.ModTime)
+		}
+		entry.modTime = modTime
+		if item.Size != nil && !isDir {
+			entry.size = *item.Size
+			entry.known = haveTime
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// sitemapDoc is a sitemap.xml file or a sitemap index
+//
+// See https://www.sitemaps.org/protocol.html
+type sitemapDoc struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+// sitemapLoc is a single location in a sitemap
+type sitemapLoc struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// parseSitemapTime parses a W3C datetime as used in sitemaps
+func parseSitemapTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return timeUnset
+}
+
+// parseSitemap reads a sitemap document
+func parseSitemap(in []byte) (*sitemapDoc, error) {
+	var doc sitemapDoc
+	err := xml.NewDecoder(bytes.NewReader(in)).Decode(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+	switch doc.XMLName.Local {
+	case "urlset", "sitemapindex":
+	default:
+		return nil, fmt.Errorf("unknown sitemap root element %q", doc.XMLName.Local)
+	}
+	return &doc, nil
+}
+
+// sitemapTree converts the locations found in a sitemap into
+// directory listings keyed on the directory with a trailing /
+//
+// Only locations under base are included and the directories above
+// each file are created as needed.
+func sitemapTree(base *url.URL, locs []sitemapLoc) map[string][]listEntry {
+	tree := map[string][]listEntry{"": nil}
+	seen := make(map[string]struct{})
+	add := func(dir string, entry listEntry) {
+		key := dir + entry.name
+		if _, found := seen[key]; found {
+			return
+		}
+		seen[key] = struct{}{}
+		tree[dir] = append(tree[dir], entry)
+	}
+	for _, loc := range locs {
+		u, err := url.Parse(strings.TrimSpace(loc.Loc))
+		if err != nil || u.RawQuery != "" || u.Host != base.Host || u.Scheme != base.Scheme {
+			continue
+		}
+		if !strings.HasPrefix(u.Path, base.Path) {
+			continue
+		}
+		remote := u.Path[len(base.Path):]
+		if remote == "" || strings.HasSuffix(remote, "/") {
+			// Pages for directories don't tell us anything
+			continue
+		}
+		dir, leaf := path.Split(remote)
+		add(dir, listEntry{
+			name:    leaf,
+			size:    -1,
+			modTime: parseSitemapTime(loc.LastMod),
+		})
+		// Make the parent directories
+		for dir != "" {
+			parent, leaf := path.Split(strings.TrimSuffix(dir, "/"))
+			add(parent, listEntry{
+				name:    leaf + "/",
+				size:    -1,
+				modTime: timeUnset,
+			})
+			dir = parent
+		}
+	}
+	return tree
+}
+
+// fetchPage reads the page at URL returning its Content-Type and
+// contents
+func (f *Fs) fetchPage(ctx context.Context, URL string, accept string) (contentType string, data []byte, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", URL, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	f.addHeaders(req)
+	res, err := f.httpClient.Do(req)
+	if err == nil {
+		defer fs.CheckClose(res.Body, &err)
+		if res.StatusCode == http.StatusNotFound {
+			return "", nil, fs.ErrorDirNotFound
+		}
+	}
+	err = statusError(res, err)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err = readPage(res.Body, f.opt.MaxPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+	contentType = strings.TrimSpace(strings.SplitN(res.Header.Get("Content-Type"), ";", 2)[0])
+	return contentType, data, nil
+}
+
+// readSitemap reads the sitemap and any sitemaps it refers to,
+// returning all the locations found
+func (f *Fs) readSitemap(ctx context.Context, URL string, depth int, seen map[string]struct{}) (locs []sitemapLoc, err error) {
+	if _, found := seen[URL]; found {
+		return nil, nil
+	}
+	seen[URL] = struct{}{}
+	_, data, err := f.fetchPage(ctx, URL, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sitemap %q: %w", URL, err)
+	}
+	doc, err := parseSitemap(data)
+	if err != nil {
+		return nil, err
+	}
+	locs = doc.URLs
+	for _, sitemap := range doc.Sitemaps {
+		if depth >= maxSitemapDepth {
+			fs.Logf(f, "Ignoring sitemap %q as sitemaps are nested too deeply", sitemap.Loc)
+			continue
+		}
+		more, err := f.readSitemap(ctx, strings.TrimSpace(sitemap.Loc), depth+1, seen)
+		if err != nil {
+			return nil, err
+		}
+		locs = append(locs, more...)
+	}
+	return locs, nil
+}
+
+// sitemapDir returns the listing of dir from the sitemap, reading
+// the sitemap if it hasn't been read yet
+func (f *Fs) sitemapDir(ctx context.Context, dir string) ([]listEntry, error) {
+	f.sitemapMu.Lock()
+	defer f.sitemapMu.Unlock()
+	if f.sitemap == nil {
+		locs, err := f.readSitemap(ctx, f.sitemapURL, 0, make(map[string]struct{}))
+		if err != nil {
+			return nil, err
+		}
+		f.sitemap = sitemapTree(f.endpoint, locs)
+	}
+	entries, found := f.sitemap[dir]
+	if !found {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
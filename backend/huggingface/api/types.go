@@ -0,0 +1,202 @@
+// Package api contains definitions for using the Hugging Face Hub API
+//
+// See https://huggingface.co/docs/hub/api and the huggingface_hub
+// library for the details of the commit and LFS APIs.
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types of entry in a repository tree
+const (
+	TypeFile      = "file"
+	TypeDirectory = "directory"
+)
+
+// Upload modes returned by the preupload API
+const (
+	UploadModeRegular = "regular"
+	UploadModeLFS     = "lfs"
+)
+
+// Keys of the lines of a commit
+const (
+	KeyHeader        = "header"
+	KeyFile          = "file"
+	KeyLFSFile       = "lfsFile"
+	KeyDeletedFile   = "deletedFile"
+	KeyDeletedFolder = "deletedFolder"
+)
+
+// LFSMediaType is the Content-Type and Accept header of the LFS batch API
+const LFSMediaType = "application/vnd.git-lfs+json"
+
+// Transfer adapters for LFS uploads
+const (
+	TransferBasic     = "basic"
+	TransferMultipart = "multipart"
+)
+
+// Error is returned by the Hub when a request fails
+type Error struct {
+	Message    string `json:"error"`
+	StatusCode int    `json:"-"`
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("hugging face error %d: %s", e.StatusCode, e.Message)
+}
+
+// LFS describes the LFS object a file is stored in
+type LFS struct {
+	OID         string `json:"oid"` // SHA-256 of the contents
+	Size        int64  `json:"size"`
+	PointerSize int64  `json:"pointerSize"`
+}
+
+// LastCommit is the last commit which changed an entry
+type LastCommit struct {
+	ID    string    `json:"id"`
+	Title string    `json:"title"`
+	Date  time.Time `json:"date"`
+}
+
+// Entry is a file or directory in a repository tree
+type Entry struct {
+	Type       string      `json:"type"`
+	OID        string      `json:"oid"` // git object ID
+	Size       int64       `json:"size"`
+	Path       string      `json:"path"`
+	LFS        *LFS        `json:"lfs,omitempty"`        // nil if not stored in LFS
+	LastCommit *LastCommit `json:"lastCommit,omitempty"` // only if expanded
+}
+
+// PreuploadFile describes a file to be uploaded
+type PreuploadFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Sample string `json:"sample"` // base64 of the first 512 bytes
+}
+
+// PreuploadRequest asks how files should be uploaded
+type PreuploadRequest struct {
+	Files []PreuploadFile `json:"files"`
+}
+
+// PreuploadResponse says how each file should be uploaded
+type PreuploadResponse struct {
+	Files []struct {
+		Path         string `json:"path"`
+		UploadMode   string `json:"uploadMode"`
+		ShouldIgnore bool   `json:"shouldIgnore"`
+	} `json:"files"`
+}
+
+// CommitLine is one line of the NDJSON body of a commit
+type CommitLine struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// CommitHeader is the value of the header line of a commit
+type CommitHeader struct {
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+}
+
+// CommitFile is a file sent in the body of a commit
+type CommitFile struct {
+	Path     string `json:"path"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+// CommitLFSFile is a file already uploaded to LFS
+type CommitLFSFile struct {
+	Path string `json:"path"`
+	Algo string `json:"algo"`
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// CommitDeleted is a file or folder deleted by a commit
+type CommitDeleted struct {
+	Path string `json:"path"`
+}
+
+// CommitResponse is returned when a commit has been made
+type CommitResponse struct {
+	CommitURL string `json:"commitUrl"`
+	CommitOID string `json:"commitOid"`
+}
+
+// Pointer identifies an LFS object by its SHA-256 and size
+type Pointer struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// Ref is the Git ref the LFS objects belong to
+type Ref struct {
+	Name string `json:"name"`
+}
+
+// BatchRequest asks to upload some LFS objects
+type BatchRequest struct {
+	Operation string    `json:"operation"`
+	Transfers []string  `json:"transfers"`
+	Objects   []Pointer `json:"objects"`
+	HashAlgo  string    `json:"hash_algo"`
+	Ref       *Ref      `json:"ref,omitempty"`
+}
+
+// BatchResponse says how to upload each of the LFS objects
+type BatchResponse struct {
+	Transfer string           `json:"transfer"`
+	Objects  []ObjectResponse `json:"objects"`
+}
+
+// ObjectResponse says how to upload one LFS object
+//
+// An object with no actions is stored already.
+type ObjectResponse struct {
+	Pointer
+	Actions map[string]*Action `json:"actions,omitempty"`
+	Error   *ObjectError       `json:"error,omitempty"`
+}
+
+// Action is a request to make to upload an LFS object
+//
+// For the multipart transfer adapter the header has the chunk size in
+// "chunk_size" and the URL to upload each part to keyed by its number,
+// e.g. "00001".
+type Action struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header,omitempty"`
+}
+
+// ObjectError is the reason an LFS object can't be uploaded
+type ObjectError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error satisfies the error interface
+func (e *ObjectError) Error() string {
+	return fmt.Sprintf("hugging face lfs error %d: %s", e.Code, e.Message)
+}
+
+// Part is a part of a multipart upload
+type Part struct {
+	PartNumber int    `json:"partNumber"`
+	ETag       string `json:"etag"`
+}
+
+// CompleteMultipart is sent to finish a multipart upload
+type CompleteMultipart struct {
+	OID   string `json:"oid"`
+	Parts []Part `json:"parts"`
+}
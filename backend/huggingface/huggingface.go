@@ -0,0 +1,1190 @@
+// Package huggingface provides an interface to the model and dataset
+// repositories of the Hugging Face Hub
+//
+// Files are read through the resolve endpoint, which follows LFS
+// pointers to the real contents, and written with the commit API,
+// uploading big and binary files to LFS first.
+package huggingface
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/huggingface/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep        = 10 * time.Millisecond
+	maxSleep        = 2 * time.Second
+	decayConstant   = 2 // bigger for slower decay, exponential
+	defaultEndpoint = "https://huggingface.co"
+	sampleSize      = 512 // bytes of each file sent to preupload
+	repoTypeModel   = "model"
+	repoTypeDataset = "dataset"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "huggingface",
+		Description: "Hugging Face Hub",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:    "repo_type",
+			Help:    "Type of the repository.",
+			Default: repoTypeModel,
+			Examples: []fs.OptionExample{{
+				Value: repoTypeModel,
+				Help:  "Model repository",
+			}, {
+				Value: repoTypeDataset,
+				Help:  "Dataset repository",
+			}},
+			Exclusive: true,
+		}, {
+			Name:     "repo",
+			Help:     `ID of the repository, e.g. "username/my-model".`,
+			Required: true,
+		}, {
+			Name: "token",
+			Help: `User access token.
+
+This is needed to read private and gated repositories and to write
+to any repository, in which case it must have write access.`,
+			IsPassword: true,
+		}, {
+			Name: "revision",
+			Help: `Branch, tag or commit to use.
+
+Files can only be written to a branch.`,
+			Default: "main",
+		}, {
+			Name:     "endpoint",
+			Help:     "URL of the Hub.\n\nChange this to use a mirror or a self hosted Hub.",
+			Default:  defaultEndpoint,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// Encode invalid UTF-8 bytes as json doesn't handle
+			// them properly.
+			Default: (encoder.Base |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	RepoType string               `config:"repo_type"`
+	Repo     string               `config:"repo"`
+	Token    string               `config:"token"`
+	Revision string               `config:"revision"`
+	Endpoint string               `config:"endpoint"`
+	Enc      encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a repository on the Hugging Face Hub
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	srv      *rest.Client // the connection to the Hub
+	transfer *rest.Client // for the LFS upload URLs, which may be elsewhere
+	pacer    *fs.Pacer    // pacer for API calls
+	dirs     sync.Map     // directories made by mkdir
+}
+
+// Object describes a file in a repository on the Hugging Face Hub
+type Object struct {
+	fs      *Fs       // what this object is part of
+	remote  string    // The remote path
+	size    int64     // size of the object
+	sha256  string    // SHA-256 of the object if stored in LFS
+	modTime time.Time // time of the last commit to the object if known
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("Hugging Face %s %s path %q", f.opt.RepoType, f.opt.Repo, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.SHA256)
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error reading error out of body: %w", err)
+	}
+	errResponse := &api.Error{}
+	if json.Unmarshal(body, errResponse) != nil || errResponse.Message == "" {
+		errResponse.Message = resp.Header.Get("X-Error-Message")
+	}
+	if errResponse.Message == "" {
+		errResponse.Message = strings.TrimSpace(string(body))
+		if errResponse.Message == "" || len(errResponse.Message) > 1024 {
+			errResponse.Message = resp.Status
+		}
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// isNotFound returns true if resp says the thing asked for isn't there
+func isNotFound(resp *http.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusNotFound
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.RepoType == "" {
+		opt.RepoType = repoTypeModel
+	}
+	if opt.RepoType != repoTypeModel && opt.RepoType != repoTypeDataset {
+		return nil, fmt.Errorf("unknown repo_type %q", opt.RepoType)
+	}
+	opt.Repo = strings.Trim(opt.Repo, "/")
+	if opt.Repo == "" {
+		return nil, errors.New("repo must be set")
+	}
+	if opt.Revision == "" {
+		opt.Revision = "main"
+	}
+	if opt.Endpoint == "" {
+		opt.Endpoint = defaultEndpoint
+	}
+	f := &Fs{
+		name:     name,
+		root:     strings.Trim(root, "/"),
+		opt:      *opt,
+		srv:      rest.NewClient(fshttp.NewClient(ctx)).SetRoot(strings.TrimRight(opt.Endpoint, "/")),
+		transfer: rest.NewClient(fshttp.NewClient(ctx)),
+		pacer:    fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+	}
+	f.srv.SetErrorHandler(errorHandler)
+	f.transfer.SetErrorHandler(errorHandler)
+	if opt.Token != "" {
+		token, err := obscure.Reveal(opt.Token)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt token: %w", err)
+		}
+		f.srv.SetHeader("Authorization", "Bearer "+token)
+	}
+	f.features = (&fs.Features{}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	if f.root != "" {
+		entry, err := f.stat(ctx, f.path(""), false)
+		if err != nil && !errors.Is(err, fs.ErrorObjectNotFound) {
+			return nil, err
+		}
+		if entry != nil && entry.Type == api.TypeFile {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// path returns the path in the repository of remote
+func (f *Fs) path(remote string) string {
+	return f.opt.Enc.FromStandardPath(path.Join(f.root, remote))
+}
+
+// remote returns the remote of the path p in the repository
+func (f *Fs) remote(p string) string {
+	p = f.opt.Enc.ToStandardPath(p)
+	if f.root == "" {
+		return p
+	}
+	return strings.TrimPrefix(p, f.root+"/")
+}
+
+// apiPath returns the path of the API for the repository
+func (f *Fs) apiPath() string {
+	return "/api/" + f.opt.RepoType + "s/" + f.opt.Repo
+}
+
+// repoPath returns the path of the repository on the Hub
+func (f *Fs) repoPath() string {
+	if f.opt.RepoType == repoTypeDataset {
+		return "/datasets/" + f.opt.Repo
+	}
+	return "/" + f.opt.Repo
+}
+
+// revision returns the revision escaped for use in a path
+func (f *Fs) revision() string {
+	return url.PathEscape(f.opt.Revision)
+}
+
+// nextLink returns the URL of the next page from the Link header of
+// resp or "" if there isn't one
+func nextLink(resp *http.Response) string {
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// tree returns the entries in the directory at p, or all the entries
+// below it if recurse is set
+func (f *Fs) tree(ctx context.Context, p string, recurse bool) (entries []api.Entry, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       f.apiPath() + "/tree/" + f.revision() + rest.URLPathEscape("/"+p),
+		Parameters: url.Values{},
+	}
+	if recurse {
+		opts.Parameters.Set("recursive", "true")
+	}
+	for {
+		var page []api.Entry
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			resp, err = f.srv.CallJSON(ctx, &opts, nil, &page)
+			return shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			if isNotFound(resp) {
+				return nil, fs.ErrorDirNotFound
+			}
+			return nil, fmt.Errorf("failed to list %q: %w", p, err)
+		}
+		entries = append(entries, page...)
+		next := nextLink(resp)
+		if next == "" {
+			return entries, nil
+		}
+		opts = rest.Opts{
+			Method:  "GET",
+			RootURL: next,
+		}
+	}
+}
+
+// stat returns the entry at p or fs.ErrorObjectNotFound, with the
+// last commit if expand is set
+func (f *Fs) stat(ctx context.Context, p string, expand bool) (*api.Entry, error) {
+	values := url.Values{
+		"paths":  {p},
+		"expand": {strconv.FormatBool(expand)},
+	}
+	opts := rest.Opts{
+		Method:      "POST",
+		Path:        f.apiPath() + "/paths-info/" + f.revision(),
+		ContentType: "application/x-www-form-urlencoded",
+	}
+	var entries []api.Entry
+	err := f.pacer.Call(func() (bool, error) {
+		opts.Body = strings.NewReader(values.Encode())
+		resp, err := f.srv.CallJSON(ctx, &opts, nil, &entries)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read info about %q: %w", p, err)
+	}
+	for i := range entries {
+		if entries[i].Path == p {
+			return &entries[i], nil
+		}
+	}
+	return nil, fs.ErrorObjectNotFound
+}
+
+// list calls fn for each file and directory in dir, recursively if
+// recurse is set
+func (f *Fs) list(ctx context.Context, dir string, recurse bool, fn func(entry fs.DirEntry)) error {
+	p := f.path(dir)
+	entries, err := f.tree(ctx, p, recurse)
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		// Directories made by mkdir don't exist until files are
+		// put in them
+		if _, ok := f.dirs.Load(p); !ok {
+			return err
+		}
+		entries, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	seen := map[string]struct{}{}
+	for i := range entries {
+		entry := &entries[i]
+		remote := f.remote(entry.Path)
+		if entry.Type == api.TypeDirectory {
+			seen[entry.Path] = struct{}{}
+			fn(fs.NewDir(remote, time.Time{}))
+		} else {
+			fn(f.newObject(remote, entry))
+		}
+	}
+	prefix := p + "/"
+	if p == "" {
+		prefix = ""
+	}
+	var dirs []string
+	f.dirs.Range(func(key, _ interface{}) bool {
+		d := key.(string)
+		if _, ok := seen[d]; !ok && strings.HasPrefix(d, prefix) && (recurse || !strings.Contains(d[len(prefix):], "/")) {
+			dirs = append(dirs, d)
+		}
+		return true
+	})
+	sort.Strings(dirs)
+	for _, d := range dirs {
+		fn(fs.NewDir(f.remote(d), time.Time{}))
+	}
+	return nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.list(ctx, dir, false, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	return entries, err
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	var entries fs.DirEntries
+	err := f.list(ctx, dir, true, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return err
+	}
+	return callback(entries)
+}
+
+// newObject makes an Object for remote from entry
+func (f *Fs) newObject(remote string, entry *api.Entry) *Object {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+		size:   entry.Size,
+	}
+	if entry.LFS != nil {
+		o.size = entry.LFS.Size
+		o.sha256 = entry.LFS.OID
+	}
+	if entry.LastCommit != nil {
+		o.modTime = entry.LastCommit.Date
+	}
+	return o
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	entry, err := f.stat(ctx, f.path(remote), true)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Type != api.TypeFile {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.newObject(remote, entry), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Git can't store empty directories so this only remembers the
+// directory and its parents until they are removed.
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	f.rememberDirs(f.path(dir))
+	return nil
+}
+
+// rememberDirs remembers the directory at p and its parents so they
+// can be listed and removed after the files in them have gone
+func (f *Fs) rememberDirs(p string) {
+	for ; p != "" && p != "."; p = path.Dir(p) {
+		f.dirs.Store(p, struct{}{})
+	}
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	empty := true
+	err := f.list(ctx, dir, false, func(entry fs.DirEntry) {
+		empty = false
+	})
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	f.dirs.Delete(f.path(dir))
+	return nil
+}
+
+// commit makes a commit with summary made of the lines given
+func (f *Fs) commit(ctx context.Context, summary string, lines ...api.CommitLine) (*api.CommitResponse, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	lines = append([]api.CommitLine{{
+		Key: api.KeyHeader,
+		Value: api.CommitHeader{
+			Summary: summary,
+		},
+	}}, lines...)
+	for _, line := range lines {
+		if err := enc.Encode(&line); err != nil {
+			return nil, err
+		}
+	}
+	opts := rest.Opts{
+		Method:      "POST",
+		Path:        f.apiPath() + "/commit/" + f.revision(),
+		ContentType: "application/x-ndjson",
+	}
+	var result api.CommitResponse
+	var resp *http.Response
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		opts.Body = bytes.NewReader(body.Bytes())
+		resp, err = f.srv.CallJSON(ctx, &opts, nil, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, fmt.Errorf("commit failed: %w", err)
+	}
+	fs.Debugf(f, "Committed %s: %s", result.CommitOID, summary)
+	return &result, nil
+}
+
+// lfsFileLine returns the commit line for the LFS object ptr at p
+func lfsFileLine(p string, ptr api.Pointer) api.CommitLine {
+	return api.CommitLine{
+		Key: api.KeyLFSFile,
+		Value: api.CommitLFSFile{
+			Path: p,
+			Algo: "sha256",
+			OID:  ptr.OID,
+			Size: ptr.Size,
+		},
+	}
+}
+
+// sameRepo returns the Object src if it is stored in the same
+// repository and revision as f
+func (f *Fs) sameRepo(src fs.Object) (*Object, bool) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, false
+	}
+	srcOpt := &srcObj.fs.opt
+	if srcOpt.Endpoint != f.opt.Endpoint || srcOpt.RepoType != f.opt.RepoType || srcOpt.Repo != f.opt.Repo || srcOpt.Revision != f.opt.Revision {
+		return nil, false
+	}
+	return srcObj, true
+}
+
+// copyOrMove copies src to remote in a single commit, deleting src as
+// well if move is set
+//
+// Only files stored in LFS can be copied as a commit can refer to an
+// LFS object already in the repository.
+func (f *Fs) copyOrMove(ctx context.Context, src fs.Object, remote string, move bool) (fs.Object, error) {
+	srcObj, ok := f.sameRepo(src)
+	if !ok || srcObj.sha256 == "" {
+		return nil, fs.ErrorCantCopy
+	}
+	srcPath := srcObj.fs.path(srcObj.remote)
+	dstPath := f.path(remote)
+	ptr := api.Pointer{OID: srcObj.sha256, Size: srcObj.size}
+	lines := []api.CommitLine{lfsFileLine(dstPath, ptr)}
+	summary := fmt.Sprintf("Copy %s to %s with rclone", srcPath, dstPath)
+	if move {
+		lines = append(lines, api.CommitLine{
+			Key:   api.KeyDeletedFile,
+			Value: api.CommitDeleted{Path: srcPath},
+		})
+		summary = fmt.Sprintf("Move %s to %s with rclone", srcPath, dstPath)
+	}
+	if _, err := f.commit(ctx, summary, lines...); err != nil {
+		return nil, err
+	}
+	f.rememberDirs(path.Dir(srcPath))
+	f.rememberDirs(path.Dir(dstPath))
+	return &Object{
+		fs:      f,
+		remote:  remote,
+		size:    srcObj.size,
+		sha256:  srcObj.sha256,
+		modTime: time.Now(),
+	}, nil
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	return f.copyOrMove(ctx, src, remote, false)
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	dst, err := f.copyOrMove(ctx, src, remote, true)
+	if errors.Is(err, fs.ErrorCantCopy) {
+		return nil, fs.ErrorCantMove
+	}
+	return dst, err
+}
+
+// Purge deletes all the files in the directory in a single commit
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	p := f.path(dir)
+	if p == "" {
+		// Don't empty the whole repository in one go
+		return fs.ErrorCantPurge
+	}
+	_, err := f.tree(ctx, p, false)
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		if _, ok := f.dirs.Load(p); ok {
+			err = nil
+		}
+	} else if err == nil {
+		_, err = f.commit(ctx, fmt.Sprintf("Delete %s with rclone", p), api.CommitLine{
+			Key:   api.KeyDeletedFolder,
+			Value: api.CommitDeleted{Path: p},
+		})
+	}
+	if err != nil {
+		return err
+	}
+	f.dirs.Range(func(key, _ interface{}) bool {
+		if d := key.(string); d == p || strings.HasPrefix(d, p+"/") {
+			f.dirs.Delete(key)
+		}
+		return true
+	})
+	return nil
+}
+
+// preupload asks the Hub whether the file at p should be stored in
+// LFS
+func (f *Fs) preupload(ctx context.Context, p string, size int64, sample []byte) (lfs bool, err error) {
+	req := api.PreuploadRequest{
+		Files: []api.PreuploadFile{{
+			Path:   p,
+			Size:   size,
+			Sample: base64.StdEncoding.EncodeToString(sample),
+		}},
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   f.apiPath() + "/preupload/" + f.revision(),
+	}
+	var result api.PreuploadResponse
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, &req, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return false, fmt.Errorf("preupload failed: %w", err)
+	}
+	for _, file := range result.Files {
+		if file.Path == p {
+			return file.UploadMode == api.UploadModeLFS, nil
+		}
+	}
+	return false, fmt.Errorf("preupload: Hub didn't return %q", p)
+}
+
+// batch asks the Hub how to upload the LFS object ptr
+func (f *Fs) batch(ctx context.Context, ptr api.Pointer) (*api.ObjectResponse, error) {
+	req := api.BatchRequest{
+		Operation: "upload",
+		Transfers: []string{api.TransferBasic, api.TransferMultipart},
+		Objects:   []api.Pointer{ptr},
+		HashAlgo:  "sha256",
+		Ref:       &api.Ref{Name: "refs/heads/" + f.opt.Revision},
+	}
+	opts := rest.Opts{
+		Method:      "POST",
+		Path:        f.repoPath() + ".git/info/lfs/objects/batch",
+		ContentType: api.LFSMediaType,
+		ExtraHeaders: map[string]string{
+			"Accept": api.LFSMediaType,
+		},
+	}
+	var result api.BatchResponse
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, &req, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lfs batch failed: %w", err)
+	}
+	for i := range result.Objects {
+		obj := &result.Objects[i]
+		if obj.OID != ptr.OID {
+			continue
+		}
+		if obj.Error != nil {
+			return nil, obj.Error
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("lfs batch: Hub didn't return object %s", ptr.OID)
+}
+
+// actionOpts makes the options to call action
+func actionOpts(method string, action *api.Action) rest.Opts {
+	opts := rest.Opts{
+		Method:       method,
+		RootURL:      action.Href,
+		ExtraHeaders: map[string]string{},
+	}
+	for k, v := range action.Header {
+		opts.ExtraHeaders[k] = v
+	}
+	return opts
+}
+
+// uploadBasic sends the LFS object in a single PUT
+func (f *Fs) uploadBasic(ctx context.Context, in io.Reader, action *api.Action, ptr api.Pointer, options []fs.OpenOption) error {
+	opts := actionOpts("PUT", action)
+	opts.Body = in
+	opts.ContentLength = &ptr.Size
+	opts.ContentType = "application/octet-stream"
+	opts.NoResponse = true
+	opts.Options = options
+	seeker, canRetry := in.(io.Seeker)
+	call := f.pacer.CallNoRetry
+	if canRetry {
+		call = f.pacer.Call
+	}
+	return call(func() (bool, error) {
+		if canRetry {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return false, err
+			}
+		}
+		resp, err := f.transfer.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+}
+
+// uploadMultipart sends the LFS object in parts to the URLs in the
+// header of action then tells the Hub the upload is complete
+func (f *Fs) uploadMultipart(ctx context.Context, in io.Reader, action *api.Action, ptr api.Pointer) error {
+	chunkSize, err := strconv.ParseInt(action.Header["chunk_size"], 10, 64)
+	if err != nil || chunkSize <= 0 {
+		return fmt.Errorf("bad chunk_size %q for multipart upload", action.Header["chunk_size"])
+	}
+	var partKeys []string
+	for key := range action.Header {
+		if _, err := strconv.Atoi(key); err == nil {
+			partKeys = append(partKeys, key)
+		}
+	}
+	sort.Strings(partKeys)
+	buf := make([]byte, chunkSize)
+	complete := api.CompleteMultipart{OID: ptr.OID}
+	for i, key := range partKeys {
+		n, err := io.ReadFull(in, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && !(err == io.EOF && i == 0) {
+			return fmt.Errorf("failed to read part %d: %w", i+1, err)
+		}
+		partSize := int64(n)
+		opts := rest.Opts{
+			Method:        "PUT",
+			RootURL:       action.Header[key],
+			ContentLength: &partSize,
+		}
+		var resp *http.Response
+		err = f.pacer.Call(func() (bool, error) {
+			opts.Body = bytes.NewReader(buf[:n])
+			resp, err = f.transfer.Call(ctx, &opts)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			return shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d: %w", i+1, err)
+		}
+		complete.Parts = append(complete.Parts, api.Part{
+			PartNumber: i + 1,
+			ETag:       resp.Header.Get("ETag"),
+		})
+	}
+	opts := actionOpts("POST", action)
+	opts.ExtraHeaders = map[string]string{
+		"Accept": api.LFSMediaType,
+	}
+	opts.ContentType = api.LFSMediaType
+	opts.NoResponse = true
+	var resp *http.Response
+	err = f.pacer.Call(func() (bool, error) {
+		resp, err = f.transfer.CallJSON(ctx, &opts, &complete, nil)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+// verify tells the Hub the upload of ptr is finished if it asked
+func (f *Fs) verify(ctx context.Context, action *api.Action, ptr api.Pointer) error {
+	opts := actionOpts("POST", action)
+	opts.ContentType = api.LFSMediaType
+	opts.ExtraHeaders["Accept"] = api.LFSMediaType
+	opts.NoResponse = true
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, &ptr, nil)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to verify upload: %w", err)
+	}
+	return nil
+}
+
+// uploadLFS sends the data in to LFS as the object ptr
+//
+// If the data was spooled then ptr is known to be right, otherwise
+// the SHA-256 is checked as the data is sent.
+func (f *Fs) uploadLFS(ctx context.Context, in io.Reader, ptr api.Pointer, spooled bool, options []fs.OpenOption) error {
+	obj, err := f.batch(ctx, ptr)
+	if err != nil {
+		return err
+	}
+	action := obj.Actions["upload"]
+	if action == nil {
+		fs.Debugf(f, "Hub has LFS object %s already", ptr.OID)
+		return nil
+	}
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(hash.SHA256))
+	if err != nil {
+		return err
+	}
+	if _, ok := action.Header["chunk_size"]; ok {
+		err = f.uploadMultipart(ctx, io.TeeReader(in, hasher), action, ptr)
+	} else if spooled {
+		err = f.uploadBasic(ctx, in, action, ptr, options)
+		hasher = nil
+	} else {
+		err = f.uploadBasic(ctx, io.TeeReader(in, hasher), action, ptr, options)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload to lfs: %w", err)
+	}
+	if hasher != nil {
+		sum, err := hasher.SumString(hash.SHA256, false)
+		if err != nil {
+			return err
+		}
+		if sum != ptr.OID {
+			return fmt.Errorf("corrupted on transfer: SHA-256 differ %q vs %q", ptr.OID, sum)
+		}
+	}
+	if verify := obj.Actions["verify"]; verify != nil {
+		return f.verify(ctx, verify, ptr)
+	}
+	return nil
+}
+
+// isOID returns true if oid looks like a SHA-256
+func isOID(oid string) bool {
+	if len(oid) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(oid)
+	return err == nil
+}
+
+// spool copies in to a temporary file to find its SHA-256 and size
+//
+// The caller should remove the file returned.
+func spool(in io.Reader) (*os.File, api.Pointer, error) {
+	var ptr api.Pointer
+	file, err := ioutil.TempFile("", "rclone-huggingface-")
+	if err != nil {
+		return nil, ptr, fmt.Errorf("failed to make spool file: %w", err)
+	}
+	fail := func(err error) (*os.File, api.Pointer, error) {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, ptr, err
+	}
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(hash.SHA256))
+	if err != nil {
+		return fail(err)
+	}
+	ptr.Size, err = io.Copy(io.MultiWriter(file, hasher), in)
+	if err != nil {
+		return fail(fmt.Errorf("failed to spool file: %w", err))
+	}
+	ptr.OID, err = hasher.SumString(hash.SHA256, false)
+	if err != nil {
+		return fail(err)
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return fail(err)
+	}
+	return file, ptr, nil
+}
+
+// upload sends the data in to the file at p in a commit, returning
+// its LFS pointer, which has an empty OID if it wasn't stored in LFS
+//
+// The Hub decides whether the file is stored in LFS from its name,
+// size and first bytes. LFS needs the SHA-256 and size before the
+// data is sent, so if the source doesn't know them the data is
+// spooled to a temporary file first.
+func (f *Fs) upload(ctx context.Context, p string, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption) (ptr api.Pointer, err error) {
+	ptr.Size = src.Size()
+	ptr.OID, _ = src.Hash(ctx, hash.SHA256)
+	var file *os.File
+	doSpool := func() error {
+		var spooled api.Pointer
+		file, spooled, err = spool(in)
+		if err != nil {
+			return err
+		}
+		in, ptr = file, spooled
+		return nil
+	}
+	defer func() {
+		if file != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
+	if ptr.Size < 0 {
+		if err = doSpool(); err != nil {
+			return ptr, err
+		}
+	}
+	sample := make([]byte, sampleSize)
+	if ptr.Size < sampleSize {
+		sample = sample[:ptr.Size]
+	}
+	if file != nil {
+		_, err = file.ReadAt(sample, 0)
+		if err == io.EOF {
+			err = nil
+		}
+	} else {
+		_, err = io.ReadFull(in, sample)
+		in = io.MultiReader(bytes.NewReader(sample), in)
+	}
+	if err != nil {
+		return ptr, fmt.Errorf("failed to read start of file: %w", err)
+	}
+	lfs, err := f.preupload(ctx, p, ptr.Size, sample)
+	if err != nil {
+		return ptr, err
+	}
+	summary := fmt.Sprintf("Upload %s with rclone", p)
+	if !lfs {
+		data, err := ioutil.ReadAll(in)
+		if err != nil {
+			return ptr, fmt.Errorf("failed to read file: %w", err)
+		}
+		if int64(len(data)) != ptr.Size {
+			return ptr, fmt.Errorf("read %d bytes expecting %d", len(data), ptr.Size)
+		}
+		_, err = f.commit(ctx, summary, api.CommitLine{
+			Key: api.KeyFile,
+			Value: api.CommitFile{
+				Path:     p,
+				Content:  base64.StdEncoding.EncodeToString(data),
+				Encoding: "base64",
+			},
+		})
+		return api.Pointer{Size: ptr.Size}, err
+	}
+	if file == nil && !isOID(ptr.OID) {
+		if err = doSpool(); err != nil {
+			return ptr, err
+		}
+	}
+	err = f.uploadLFS(ctx, in, ptr, file != nil, options)
+	if err != nil {
+		return ptr, err
+	}
+	_, err = f.commit(ctx, summary, lfsFileLine(p, ptr))
+	return ptr, err
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the SHA-256 of an object returning a lowercase hex string
+//
+// Only files stored in LFS have one.
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t != hash.SHA256 {
+		return "", hash.ErrUnsupported
+	}
+	return o.sha256, nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+//
+// This is the time of the last commit which changed the file, which
+// isn't in listings so is read the first time it is needed.
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.modTime.IsZero() {
+		entry, err := o.fs.stat(ctx, o.fs.path(o.remote), true)
+		if err != nil || entry.LastCommit == nil {
+			fs.Logf(o, "Failed to read last commit: %v", err)
+			return time.Now()
+		}
+		o.modTime = entry.LastCommit.Date
+	}
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+//
+// The resolve endpoint redirects files stored in LFS to their
+// contents.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	fs.FixRangeOption(options, o.size)
+	opts := rest.Opts{
+		Method:  "GET",
+		Path:    o.fs.repoPath() + "/resolve/" + o.fs.revision() + rest.URLPathEscape("/"+o.fs.path(o.remote)),
+		Options: options,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		if isNotFound(resp) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to download %q: %w", o.remote, err)
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// Each upload is a commit to the revision, which must be a branch.
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	p := o.fs.path(o.remote)
+	ptr, err := o.fs.upload(ctx, p, in, src, options)
+	if err != nil {
+		return err
+	}
+	o.fs.rememberDirs(path.Dir(p))
+	o.size = ptr.Size
+	o.sha256 = ptr.OID
+	o.modTime = time.Now()
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	p := o.fs.path(o.remote)
+	o.fs.rememberDirs(path.Dir(p))
+	_, err := o.fs.commit(ctx, fmt.Sprintf("Delete %s with rclone", p), api.CommitLine{
+		Key:   api.KeyDeletedFile,
+		Value: api.CommitDeleted{Path: p},
+	})
+	return err
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.ListRer     = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+)
@@ -0,0 +1,603 @@
+package huggingface
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/huggingface/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRepo    = "user/repo"
+	testToken   = "hf_test"
+	testLFSSize = 64 // files this big or bigger are stored in LFS
+)
+
+// testFile is a file in the test repository
+type testFile struct {
+	data []byte
+	lfs  bool
+	date time.Time
+}
+
+// testServer is a Hugging Face Hub keeping a model repository in memory
+type testServer struct {
+	mu        sync.Mutex
+	url       string
+	files     map[string]*testFile
+	objects   map[string][]byte // LFS objects by oid
+	parts     map[string][][]byte
+	commits   []string // summaries of the commits made
+	uploads   int      // number of LFS objects or parts uploaded
+	pageSize  int      // entries in each page of a listing if set
+	chunkSize int      // use multipart uploads with this chunk size if set
+}
+
+func newTestServer(t *testing.T) (*testServer, *httptest.Server) {
+	s := &testServer{
+		files:   map[string]*testFile{},
+		objects: map[string][]byte{},
+		parts:   map[string][][]byte{},
+	}
+	ts := httptest.NewServer(s)
+	s.url = ts.URL
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// jsonError replies with an error in the format the Hub uses
+func jsonError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(api.Error{Message: message})
+}
+
+// reply sends v as JSON
+func reply(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// ServeHTTP implements the parts of the Hub API the backend uses
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	const apiPrefix = "/api/models/" + testRepo
+	p := r.URL.Path
+	switch {
+	case strings.HasPrefix(p, "/lfs/"):
+		s.serveLFS(w, r, strings.TrimPrefix(p, "/lfs/"))
+		return
+	case strings.HasPrefix(p, "/lfs-objects/"):
+		data, ok := s.objects[strings.TrimPrefix(p, "/lfs-objects/")]
+		if !ok {
+			jsonError(w, http.StatusNotFound, "object not found")
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+testToken {
+		jsonError(w, http.StatusUnauthorized, "bad token")
+		return
+	}
+	switch {
+	case strings.HasPrefix(p, apiPrefix+"/tree/main") && r.Method == "GET":
+		s.tree(w, r, strings.Trim(strings.TrimPrefix(p, apiPrefix+"/tree/main"), "/"))
+	case p == apiPrefix+"/paths-info/main" && r.Method == "POST":
+		s.pathsInfo(w, r)
+	case p == apiPrefix+"/preupload/main" && r.Method == "POST":
+		s.preupload(w, r)
+	case p == apiPrefix+"/commit/main" && r.Method == "POST":
+		s.commit(w, r)
+	case p == "/"+testRepo+".git/info/lfs/objects/batch" && r.Method == "POST":
+		s.batch(w, r)
+	case strings.HasPrefix(p, "/"+testRepo+"/resolve/main/") && r.Method == "GET":
+		file, ok := s.files[strings.TrimPrefix(p, "/"+testRepo+"/resolve/main/")]
+		if !ok {
+			jsonError(w, http.StatusNotFound, "Entry not found")
+			return
+		}
+		if file.lfs {
+			sum := sha256.Sum256(file.data)
+			http.Redirect(w, r, s.url+"/lfs-objects/"+hex.EncodeToString(sum[:]), http.StatusFound)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(file.data))
+	default:
+		jsonError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// entry returns the tree entry for the file at p
+func (s *testServer) entry(p string, expand bool) api.Entry {
+	file := s.files[p]
+	entry := api.Entry{
+		Type: api.TypeFile,
+		OID:  "git-" + p,
+		Size: int64(len(file.data)),
+		Path: p,
+	}
+	if file.lfs {
+		sum := sha256.Sum256(file.data)
+		entry.LFS = &api.LFS{
+			OID:         hex.EncodeToString(sum[:]),
+			Size:        int64(len(file.data)),
+			PointerSize: 130,
+		}
+		entry.Size = entry.LFS.Size
+	}
+	if expand {
+		entry.LastCommit = &api.LastCommit{ID: "commit", Date: file.date}
+	}
+	return entry
+}
+
+// isDir returns true if there are files below p
+func (s *testServer) isDir(p string) bool {
+	for name := range s.files {
+		if strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// tree lists the directory at dir
+func (s *testServer) tree(w http.ResponseWriter, r *http.Request, dir string) {
+	if dir != "" && !s.isDir(dir) {
+		jsonError(w, http.StatusNotFound, "Entry not found")
+		return
+	}
+	recursive := r.URL.Query().Get("recursive") == "true"
+	prefix := dir + "/"
+	if dir == "" {
+		prefix = ""
+	}
+	dirs := map[string]struct{}{}
+	entries := []api.Entry{}
+	for name := range s.files {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		leaf := name[len(prefix):]
+		parts := strings.Split(leaf, "/")
+		for i := 1; i < len(parts) && (recursive || i == 1); i++ {
+			dirs[prefix+strings.Join(parts[:i], "/")] = struct{}{}
+		}
+		if recursive || len(parts) == 1 {
+			entries = append(entries, s.entry(name, false))
+		}
+	}
+	for d := range dirs {
+		entries = append(entries, api.Entry{Type: api.TypeDirectory, OID: "git-" + d, Path: d})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+	if s.pageSize > 0 && start+s.pageSize < len(entries) {
+		next := *r.URL
+		q := next.Query()
+		q.Set("cursor", strconv.Itoa(start+s.pageSize))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="next"`, s.url, next.String()))
+		entries = entries[start : start+s.pageSize]
+	} else {
+		entries = entries[start:]
+	}
+	reply(w, entries)
+}
+
+// pathsInfo returns the entries of the paths asked for
+func (s *testServer) pathsInfo(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	expand := r.PostForm.Get("expand") == "true"
+	entries := []api.Entry{}
+	for _, p := range r.PostForm["paths"] {
+		if _, ok := s.files[p]; ok {
+			entries = append(entries, s.entry(p, expand))
+		} else if s.isDir(p) {
+			entries = append(entries, api.Entry{Type: api.TypeDirectory, Path: p})
+		}
+	}
+	reply(w, entries)
+}
+
+// preupload stores big files and ones with zero bytes in LFS
+func (s *testServer) preupload(w http.ResponseWriter, r *http.Request) {
+	var req api.PreuploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var result api.PreuploadResponse
+	for _, file := range req.Files {
+		sample, err := base64.StdEncoding.DecodeString(file.Sample)
+		if err != nil || len(sample) > sampleSize || (int64(len(sample)) < file.Size && len(sample) != sampleSize) {
+			jsonError(w, http.StatusBadRequest, "bad sample")
+			return
+		}
+		mode := api.UploadModeRegular
+		if file.Size >= testLFSSize || bytes.IndexByte(sample, 0) >= 0 {
+			mode = api.UploadModeLFS
+		}
+		result.Files = append(result.Files, struct {
+			Path         string `json:"path"`
+			UploadMode   string `json:"uploadMode"`
+			ShouldIgnore bool   `json:"shouldIgnore"`
+		}{Path: file.Path, UploadMode: mode})
+	}
+	reply(w, result)
+}
+
+// commit applies the operations in the NDJSON body
+func (s *testServer) commit(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/x-ndjson" {
+		jsonError(w, http.StatusBadRequest, "bad content type")
+		return
+	}
+	var changes []func()
+	var summary string
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var line struct {
+			Key   string          `json:"key"`
+			Value json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			jsonError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		switch line.Key {
+		case api.KeyHeader:
+			var header api.CommitHeader
+			_ = json.Unmarshal(line.Value, &header)
+			summary = header.Summary
+		case api.KeyFile:
+			var file api.CommitFile
+			_ = json.Unmarshal(line.Value, &file)
+			data, err := base64.StdEncoding.DecodeString(file.Content)
+			if err != nil || file.Encoding != "base64" {
+				jsonError(w, http.StatusBadRequest, "bad file content")
+				return
+			}
+			changes = append(changes, func() {
+				s.files[file.Path] = &testFile{data: data, date: time.Now()}
+			})
+		case api.KeyLFSFile:
+			var file api.CommitLFSFile
+			_ = json.Unmarshal(line.Value, &file)
+			data, ok := s.objects[file.OID]
+			if !ok || int64(len(data)) != file.Size {
+				jsonError(w, http.StatusBadRequest, "lfs object not uploaded")
+				return
+			}
+			changes = append(changes, func() {
+				s.files[file.Path] = &testFile{data: data, lfs: true, date: time.Now()}
+			})
+		case api.KeyDeletedFile:
+			var file api.CommitDeleted
+			_ = json.Unmarshal(line.Value, &file)
+			if _, ok := s.files[file.Path]; !ok {
+				jsonError(w, http.StatusNotFound, "file not found")
+				return
+			}
+			changes = append(changes, func() {
+				delete(s.files, file.Path)
+			})
+		case api.KeyDeletedFolder:
+			var folder api.CommitDeleted
+			_ = json.Unmarshal(line.Value, &folder)
+			changes = append(changes, func() {
+				for name := range s.files {
+					if strings.HasPrefix(name, folder.Path+"/") {
+						delete(s.files, name)
+					}
+				}
+			})
+		default:
+			jsonError(w, http.StatusBadRequest, "unknown key "+line.Key)
+			return
+		}
+	}
+	if summary == "" {
+		jsonError(w, http.StatusBadRequest, "no summary")
+		return
+	}
+	for _, change := range changes {
+		change()
+	}
+	s.commits = append(s.commits, summary)
+	reply(w, api.CommitResponse{CommitOID: strconv.Itoa(len(s.commits))})
+}
+
+// batch says how to upload LFS objects
+func (s *testServer) batch(w http.ResponseWriter, r *http.Request) {
+	var req api.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result := api.BatchResponse{Transfer: api.TransferBasic}
+	for _, ptr := range req.Objects {
+		obj := api.ObjectResponse{Pointer: ptr}
+		if _, ok := s.objects[ptr.OID]; !ok {
+			upload := &api.Action{Href: s.url + "/lfs/upload/" + ptr.OID}
+			if s.chunkSize > 0 {
+				result.Transfer = api.TransferMultipart
+				upload.Href = s.url + "/lfs/complete/" + ptr.OID
+				upload.Header = map[string]string{"chunk_size": strconv.Itoa(s.chunkSize)}
+				for i := 0; int64(i*s.chunkSize) < ptr.Size; i++ {
+					upload.Header[fmt.Sprintf("%05d", i+1)] = fmt.Sprintf("%s/lfs/part/%s/%d", s.url, ptr.OID, i)
+				}
+			}
+			obj.Actions = map[string]*api.Action{
+				"upload": upload,
+				"verify": {Href: s.url + "/lfs/verify/" + ptr.OID},
+			}
+		}
+		result.Objects = append(result.Objects, obj)
+	}
+	w.Header().Set("Content-Type", api.LFSMediaType)
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// serveLFS stores uploaded LFS objects
+func (s *testServer) serveLFS(w http.ResponseWriter, r *http.Request, p string) {
+	if r.Header.Get("Authorization") != "" && strings.HasPrefix(p, "part/") {
+		jsonError(w, http.StatusBadRequest, "part URLs are presigned")
+		return
+	}
+	parts := strings.Split(p, "/")
+	action, oid := parts[0], parts[1]
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	store := func(data []byte) bool {
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != oid {
+			jsonError(w, http.StatusBadRequest, "sha256 mismatch")
+			return false
+		}
+		s.objects[oid] = data
+		return true
+	}
+	switch action {
+	case "upload":
+		s.uploads++
+		store(data)
+	case "part":
+		s.uploads++
+		i, _ := strconv.Atoi(parts[2])
+		for len(s.parts[oid]) <= i {
+			s.parts[oid] = append(s.parts[oid], nil)
+		}
+		s.parts[oid][i] = data
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, i))
+	case "complete":
+		var complete api.CompleteMultipart
+		if err := json.Unmarshal(data, &complete); err != nil || complete.OID != oid || len(complete.Parts) != len(s.parts[oid]) {
+			jsonError(w, http.StatusBadRequest, "bad completion")
+			return
+		}
+		for i, part := range complete.Parts {
+			if part.PartNumber != i+1 || part.ETag != fmt.Sprintf(`"etag-%d"`, i) {
+				jsonError(w, http.StatusBadRequest, "bad part")
+				return
+			}
+		}
+		store(bytes.Join(s.parts[oid], nil))
+		delete(s.parts, oid)
+	case "verify":
+		if _, ok := s.objects[oid]; !ok {
+			jsonError(w, http.StatusNotFound, "object not uploaded")
+		}
+	}
+}
+
+// testConfig returns the config to use the test server ts
+func testConfig(ts *httptest.Server) configmap.Simple {
+	return configmap.Simple{
+		"repo":     testRepo,
+		"token":    obscure.MustObscure(testToken),
+		"endpoint": ts.URL,
+	}
+}
+
+// TestLocal runs the integration tests against a test server
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("skipping as -remote is set")
+	}
+	_, ts := newTestServer(t)
+	name := "TestHuggingFaceLocal"
+	extra := []fstests.ExtraConfigItem{{Name: name, Key: "type", Value: "huggingface"}}
+	for k, v := range testConfig(ts) {
+		extra = append(extra, fstests.ExtraConfigItem{Name: name, Key: k, Value: v})
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:  name + ":",
+		NilObject:   (*Object)(nil),
+		ExtraConfig: extra,
+		QuickTestOK: true,
+	})
+}
+
+// newTestFs makes an Fs using the test server
+func newTestFs(t *testing.T) (*testServer, *Fs) {
+	s, ts := newTestServer(t)
+	f, err := NewFs(context.Background(), t.Name(), "dir", testConfig(ts))
+	require.NoError(t, err)
+	return s, f.(*Fs)
+}
+
+// put uploads contents to remote
+func put(t *testing.T, f *Fs, remote, contents string) fs.Object {
+	src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(context.Background(), strings.NewReader(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+// read returns the contents of o
+func read(t *testing.T, o fs.Object) string {
+	in, err := o.Open(context.Background())
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+func TestUploadModes(t *testing.T) {
+	ctx := context.Background()
+	s, f := newTestFs(t)
+
+	small := put(t, f, "small.txt", "hello")
+	assert.False(t, s.files["dir/small.txt"].lfs)
+	assert.Equal(t, 0, s.uploads)
+	sum, err := small.Hash(ctx, hash.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, "", sum)
+
+	big := strings.Repeat("big file ", 10)
+	o := put(t, f, "big.bin", big)
+	assert.True(t, s.files["dir/big.bin"].lfs)
+	assert.Equal(t, 1, s.uploads)
+	want := sha256.Sum256([]byte(big))
+	sum, err = o.Hash(ctx, hash.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(want[:]), sum)
+	assert.Equal(t, []string{"Upload dir/small.txt with rclone", "Upload dir/big.bin with rclone"}, s.commits)
+
+	// Read back through the resolve endpoint
+	o, err = f.NewObject(ctx, "big.bin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(big)), o.Size())
+	assert.Equal(t, big, read(t, o))
+
+	// The same contents aren't uploaded again
+	put(t, f, "copy.bin", big)
+	assert.Equal(t, 1, s.uploads)
+	assert.True(t, s.files["dir/copy.bin"].lfs)
+}
+
+func TestUploadStream(t *testing.T) {
+	ctx := context.Background()
+	s, f := newTestFs(t)
+	contents := strings.Repeat("x", 1000)
+	src := object.NewStaticObjectInfo("stream.bin", time.Now(), -1, true, nil, nil)
+	o, err := f.PutStream(ctx, strings.NewReader(contents), src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), o.Size())
+	assert.True(t, s.files["dir/stream.bin"].lfs)
+	assert.Equal(t, contents, read(t, o))
+}
+
+func TestUploadMultipart(t *testing.T) {
+	s, f := newTestFs(t)
+	s.chunkSize = 30
+	contents := strings.Repeat("0123456789", 10)
+	o := put(t, f, "parts.bin", contents)
+	assert.Equal(t, 4, s.uploads)
+	assert.Equal(t, contents, string(s.files["dir/parts.bin"].data))
+	assert.Equal(t, contents, read(t, o))
+}
+
+func TestCopyMove(t *testing.T) {
+	ctx := context.Background()
+	s, f := newTestFs(t)
+	big := put(t, f, "big.bin", strings.Repeat("b", testLFSSize))
+	small := put(t, f, "small.txt", "small")
+
+	_, err := f.Copy(ctx, small, "small2.txt")
+	assert.Equal(t, fs.ErrorCantCopy, err)
+	_, err = f.Move(ctx, small, "small2.txt")
+	assert.Equal(t, fs.ErrorCantMove, err)
+
+	commits := len(s.commits)
+	dst, err := f.Move(ctx, big, "sub/moved.bin")
+	require.NoError(t, err)
+	assert.Equal(t, commits+1, len(s.commits))
+	assert.Nil(t, s.files["dir/big.bin"])
+	assert.True(t, s.files["dir/sub/moved.bin"].lfs)
+	assert.Equal(t, strings.Repeat("b", testLFSSize), read(t, dst))
+}
+
+func TestListPages(t *testing.T) {
+	ctx := context.Background()
+	s, f := newTestFs(t)
+	for i := 0; i < 5; i++ {
+		put(t, f, fmt.Sprintf("file%d.txt", i), "contents")
+	}
+	put(t, f, "sub/file.txt", "contents")
+	s.pageSize = 2
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 6, len(entries))
+	var all fs.DirEntries
+	err = f.ListR(ctx, "", func(entries fs.DirEntries) error {
+		all = append(all, entries...)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 7, len(all))
+}
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	s, f := newTestFs(t)
+	put(t, f, "sub/a.txt", "a")
+	put(t, f, "sub/deeper/b.txt", "b")
+	put(t, f, "keep.txt", "keep")
+	commits := len(s.commits)
+	require.NoError(t, f.Purge(ctx, "sub"))
+	assert.Equal(t, commits+1, len(s.commits))
+	assert.Equal(t, 1, len(s.files))
+	assert.Equal(t, fs.ErrorDirNotFound, f.Purge(ctx, "sub"))
+}
+
+func TestNextLink(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{`<https://huggingface.co/api/models/a/b/tree/main?cursor=xyz>; rel="next"`, "https://huggingface.co/api/models/a/b/tree/main?cursor=xyz"},
+		{`<https://example.com/prev>; rel="prev", <https://example.com/next>; rel="next"`, "https://example.com/next"},
+		{`<https://example.com/prev>; rel="prev"`, ""},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Link", test.in)
+		assert.Equal(t, test.want, nextLink(resp), test.in)
+	}
+}
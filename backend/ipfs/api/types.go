@@ -0,0 +1,66 @@
+// Package api contains definitions for using the IPFS HTTP RPC API
+//
+// This is the API served by go-ipfs (Kubo) on /api/v0. All the calls
+// are POSTs with their arguments in the query string.
+package api
+
+import "fmt"
+
+// Error is returned by the API with a 500 status when a call fails
+type Error struct {
+	Message string `json:"Message"`
+	Code    int    `json:"Code"`
+	Type    string `json:"Type"`
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("ipfs error: %s", e.Message)
+}
+
+// Types of the entries returned by files/ls
+const (
+	TypeFile      = 0
+	TypeDirectory = 1
+)
+
+// Entry is an entry in a directory returned by files/ls
+type Entry struct {
+	Name string `json:"Name"`
+	Type int    `json:"Type"`
+	Size int64  `json:"Size"`
+	Hash string `json:"Hash"`
+}
+
+// ListResponse is returned by files/ls
+type ListResponse struct {
+	Entries []Entry `json:"Entries"`
+}
+
+// Stat is returned by files/stat
+type Stat struct {
+	Hash           string `json:"Hash"`
+	Size           int64  `json:"Size"`
+	CumulativeSize int64  `json:"CumulativeSize"`
+	Blocks         int    `json:"Blocks"`
+	Type           string `json:"Type"` // "file" or "directory"
+	Mtime          int64  `json:"Mtime,omitempty"`
+	MtimeNsecs     int64  `json:"MtimeNsecs,omitempty"`
+}
+
+// IsDir returns true if the stat is of a directory
+func (s *Stat) IsDir() bool {
+	return s.Type == "directory"
+}
+
+// PinResponse is returned by pin/add
+type PinResponse struct {
+	Pins []string `json:"Pins"`
+}
+
+// RepoStat is returned by repo/stat
+type RepoStat struct {
+	RepoSize   int64 `json:"RepoSize"`
+	StorageMax int64 `json:"StorageMax"`
+	NumObjects int64 `json:"NumObjects"`
+}
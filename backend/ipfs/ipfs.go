@@ -0,0 +1,773 @@
+// Package ipfs provides an interface to IPFS nodes
+//
+// Files are kept in the Mutable File System (MFS) of a go-ipfs (Kubo)
+// node and accessed through its HTTP RPC API. Uploaded files can be
+// pinned by CID and reads can fall back to an HTTP gateway if the node
+// can't serve them.
+package ipfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/ipfs/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+	apiPath       = "/api/v0"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "ipfs",
+		Description: "IPFS",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:    "api_url",
+			Help:    "URL of the HTTP RPC API of the IPFS node.",
+			Default: "http://127.0.0.1:5001",
+		}, {
+			Name: "gateway_url",
+			Help: `URL of an IPFS HTTP gateway, e.g. http://127.0.0.1:8080 or https://ipfs.io.
+
+If set, files are read from the gateway by CID when the node can't
+serve them, and public links point to it. Leave blank to only use the
+API.`,
+		}, {
+			Name: "pin",
+			Help: `Pin the CID of each file uploaded.
+
+Files in MFS are kept by the node anyway, but pinning keeps the
+content too if it is later removed from MFS, and lets pinning services
+following the node find it.`,
+			Default: true,
+		}, {
+			Name: "headers",
+			Help: `Set HTTP headers for all API calls.
+
+Use this to set an Authorization header for nodes with API
+authorization configured.
+
+The input format is comma separated list of key,value pairs.  Standard
+[CSV encoding](https://godoc.org/encoding/csv) may be used.
+
+For example, to set an Authorization header use "Authorization,Bearer secret".`,
+			Default:  fs.CommaSepList{},
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// Encode invalid UTF-8 bytes as json doesn't handle them
+			// properly, and . and .. as MFS cleans paths.
+			Default: (encoder.Base |
+				encoder.EncodeDot |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	APIURL     string               `config:"api_url"`
+	GatewayURL string               `config:"gateway_url"`
+	Pin        bool                 `config:"pin"`
+	Headers    fs.CommaSepList      `config:"headers"`
+	Enc        encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a directory in the MFS of an IPFS node
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	srv      *rest.Client // the connection to the API
+	gateway  *rest.Client // the connection to the gateway if set
+	pacer    *fs.Pacer    // pacer for API calls
+	headers  map[string]string
+}
+
+// Object describes a file in MFS
+type Object struct {
+	fs      *Fs       // what this object is part of
+	remote  string    // The remote path
+	size    int64     // size of the object
+	cid     string    // CID of the contents
+	modTime time.Time // modification time if the node stores it
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("IPFS root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// Hashes returns the supported hash types of the filesystem
+//
+// The CID of a file depends on how it was chunked, so it isn't
+// offered as a hash - it is available as the ID instead.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+//
+// 500 isn't here as the API returns it for every failed call.
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		body = nil
+	}
+	e := &api.Error{}
+	if err = json.Unmarshal(body, e); err != nil || e.Message == "" {
+		e.Message = resp.Status
+		if text := strings.TrimSpace(string(body)); text != "" {
+			e.Message += ": " + text
+		}
+	}
+	return e
+}
+
+// isNotFound returns true if err says the path doesn't exist
+//
+// The API doesn't have a code for this so the message is checked.
+func isNotFound(err error) bool {
+	var apiErr *api.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return strings.Contains(apiErr.Message, "does not exist") || strings.Contains(apiErr.Message, "not found")
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if len(opt.Headers)%2 != 0 {
+		return nil, errors.New("odd number of headers supplied")
+	}
+	client := fshttp.NewClient(ctx)
+	f := &Fs{
+		name:    name,
+		root:    strings.Trim(root, "/"),
+		opt:     *opt,
+		srv:     rest.NewClient(client).SetRoot(strings.TrimRight(opt.APIURL, "/") + apiPath),
+		pacer:   fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+		headers: map[string]string{},
+	}
+	f.srv.SetErrorHandler(errorHandler)
+	for i := 0; i < len(opt.Headers); i += 2 {
+		f.headers[opt.Headers[i]] = opt.Headers[i+1]
+	}
+	if opt.GatewayURL != "" {
+		f.gateway = rest.NewClient(client).SetRoot(strings.TrimRight(opt.GatewayURL, "/"))
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	if f.root != "" {
+		stat, err := f.stat(ctx, f.fullPath(""))
+		if err == nil && !stat.IsDir() {
+			f.root = parentDir(f.root)
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// parentDir returns the parent directory of p or "" for the root
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// fullPath returns the absolute MFS path of remote
+func (f *Fs) fullPath(remote string) string {
+	return f.opt.Enc.FromStandardPath(path.Join("/", f.root, remote))
+}
+
+// call makes an API call to command with the arguments args decoding
+// the response into result if it isn't nil
+func (f *Fs) call(ctx context.Context, command string, params url.Values, result interface{}) error {
+	opts := rest.Opts{
+		Method:       "POST",
+		Path:         "/" + command,
+		Parameters:   params,
+		ExtraHeaders: f.headers,
+	}
+	return f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, nil, result)
+		return shouldRetry(ctx, resp, err)
+	})
+}
+
+// stat reads the info about the absolute MFS path p
+func (f *Fs) stat(ctx context.Context, p string) (*api.Stat, error) {
+	var result api.Stat
+	err := f.call(ctx, "files/stat", url.Values{"arg": {p}}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// list returns the entries in the absolute MFS path dir
+func (f *Fs) list(ctx context.Context, dir string) ([]api.Entry, error) {
+	var result api.ListResponse
+	err := f.call(ctx, "files/ls", url.Values{"arg": {dir}, "long": {"true"}, "U": {"true"}}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	items, err := f.list(ctx, f.fullPath(dir))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fs.ErrorDirNotFound
+		}
+		return nil, err
+	}
+	for _, item := range items {
+		remote := path.Join(dir, f.opt.Enc.ToStandardName(item.Name))
+		if item.Type == api.TypeDirectory {
+			d := fs.NewDir(remote, time.Time{}).SetID(item.Hash)
+			entries = append(entries, d)
+			continue
+		}
+		entries = append(entries, &Object{
+			fs:     f,
+			remote: remote,
+			size:   item.Size,
+			cid:    item.Hash,
+		})
+	}
+	return entries, nil
+}
+
+// newObject makes an object from stat
+func (f *Fs) newObject(remote string, stat *api.Stat) *Object {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+		size:   stat.Size,
+		cid:    stat.Hash,
+	}
+	if stat.Mtime != 0 {
+		o.modTime = time.Unix(stat.Mtime, stat.MtimeNsecs)
+	}
+	return o
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	stat, err := f.stat(ctx, f.fullPath(remote))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, fs.ErrorIsDir
+	}
+	return f.newObject(remote, stat), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// mkdir makes the absolute MFS path p and any parents needed
+func (f *Fs) mkdir(ctx context.Context, p string) error {
+	if p == "/" {
+		return nil
+	}
+	return f.call(ctx, "files/mkdir", url.Values{"arg": {p}, "parents": {"true"}}, nil)
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.mkdir(ctx, f.fullPath(dir))
+}
+
+// remove deletes the absolute MFS path p and anything in it
+func (f *Fs) remove(ctx context.Context, p string) error {
+	return f.call(ctx, "files/rm", url.Values{"arg": {p}, "recursive": {"true"}}, nil)
+}
+
+// Rmdir deletes the root folder
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	p := f.fullPath(dir)
+	stat, err := f.stat(ctx, p)
+	if err != nil {
+		if isNotFound(err) {
+			return fs.ErrorDirNotFound
+		}
+		return err
+	}
+	if !stat.IsDir() {
+		return fs.ErrorIsFile
+	}
+	items, err := f.list(ctx, p)
+	if err != nil {
+		return err
+	}
+	if len(items) != 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	if p == "/" {
+		return nil
+	}
+	return f.remove(ctx, p)
+}
+
+// Purge deletes all the files in the directory
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	p := f.fullPath(dir)
+	if p == "/" {
+		// MFS won't remove its root
+		return fs.ErrorCantPurge
+	}
+	if _, err := f.stat(ctx, p); err != nil {
+		if isNotFound(err) {
+			return fs.ErrorDirNotFound
+		}
+		return err
+	}
+	return f.remove(ctx, p)
+}
+
+// prepareDst makes the parent of the absolute MFS path dst and removes
+// any file already there, as MFS won't copy over it
+func (f *Fs) prepareDst(ctx context.Context, dst string) error {
+	err := f.remove(ctx, dst)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return f.mkdir(ctx, path.Dir(dst))
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	if srcObj.fs.opt.APIURL != f.opt.APIURL {
+		return nil, fs.ErrorCantCopy
+	}
+	dst := f.fullPath(remote)
+	if err := f.prepareDst(ctx, dst); err != nil {
+		return nil, err
+	}
+	// copying by CID links the existing blocks so nothing is read
+	err := f.call(ctx, "files/cp", url.Values{"arg": {srcObj.fs.fullPath(srcObj.remote), dst}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	if srcObj.fs.opt.APIURL != f.opt.APIURL {
+		return nil, fs.ErrorCantMove
+	}
+	dst := f.fullPath(remote)
+	if err := f.prepareDst(ctx, dst); err != nil {
+		return nil, err
+	}
+	err := f.call(ctx, "files/mv", url.Values{"arg": {srcObj.fs.fullPath(srcObj.remote), dst}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	if srcFs.opt.APIURL != f.opt.APIURL {
+		return fs.ErrorCantDirMove
+	}
+	srcPath := srcFs.fullPath(srcRemote)
+	dstPath := f.fullPath(dstRemote)
+	if srcPath == "/" || dstPath == "/" {
+		return fs.ErrorCantDirMove
+	}
+	if _, err := f.stat(ctx, dstPath); err == nil {
+		return fs.ErrorDirExists
+	} else if !isNotFound(err) {
+		return err
+	}
+	if err := f.mkdir(ctx, path.Dir(dstPath)); err != nil {
+		return err
+	}
+	return f.call(ctx, "files/mv", url.Values{"arg": {srcPath, dstPath}}, nil)
+}
+
+// About gets quota information from the repository of the node
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	var result api.RepoStat
+	err := f.call(ctx, "repo/stat", url.Values{"size-only": {"true"}}, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repo stats: %w", err)
+	}
+	usage := &fs.Usage{
+		Used: fs.NewUsageValue(result.RepoSize),
+	}
+	if result.StorageMax > 0 {
+		usage.Total = fs.NewUsageValue(result.StorageMax)
+		if free := result.StorageMax - result.RepoSize; free >= 0 {
+			usage.Free = fs.NewUsageValue(free)
+		}
+	}
+	return usage, nil
+}
+
+// PublicLink returns a link to remote on the gateway
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (link string, err error) {
+	if f.gateway == nil {
+		return "", errors.New("set gateway_url to make public links")
+	}
+	if unlink {
+		return "", errors.New("can't remove public links - content is public by CID")
+	}
+	stat, err := f.stat(ctx, f.fullPath(remote))
+	if err != nil {
+		if isNotFound(err) {
+			return "", fs.ErrorObjectNotFound
+		}
+		return "", err
+	}
+	link = strings.TrimRight(f.opt.GatewayURL, "/") + "/ipfs/" + stat.Hash
+	if !stat.IsDir() {
+		link += "?filename=" + url.QueryEscape(path.Base(remote))
+	}
+	return link, nil
+}
+
+// pin pins cid on the node
+func (f *Fs) pin(ctx context.Context, cid string) error {
+	var result api.PinResponse
+	return f.call(ctx, "pin/add", url.Values{"arg": {"/ipfs/" + cid}}, &result)
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash is unsupported on IPFS
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+//
+// MFS only keeps modification times if they were set explicitly, so
+// the current time is returned if it doesn't have one.
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.modTime.IsZero() {
+		return time.Now()
+	}
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// ID returns the CID of the object
+func (o *Object) ID() string {
+	return o.cid
+}
+
+// Open an object for read
+//
+// The file is read through the API and if that fails and a gateway is
+// configured it is read from the gateway by CID instead.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	params := url.Values{
+		"arg":    {o.fs.fullPath(o.remote)},
+		"offset": {strconv.FormatInt(offset, 10)},
+	}
+	if limit >= 0 {
+		params.Set("count", strconv.FormatInt(limit, 10))
+	}
+	opts := rest.Opts{
+		Method:       "POST",
+		Path:         "/files/read",
+		Parameters:   params,
+		ExtraHeaders: o.fs.headers,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err == nil {
+		return resp.Body, nil
+	}
+	if o.fs.gateway == nil || o.cid == "" || ctx.Err() != nil {
+		return nil, err
+	}
+	fs.Debugf(o, "Reading from gateway as the API failed: %v", err)
+	in, gatewayErr := o.openGateway(ctx, options)
+	if gatewayErr != nil {
+		return nil, fmt.Errorf("failed to read from API: %v and from gateway: %w", err, gatewayErr)
+	}
+	return in, nil
+}
+
+// openGateway opens the object on the gateway by CID
+func (o *Object) openGateway(ctx context.Context, options []fs.OpenOption) (in io.ReadCloser, err error) {
+	fs.FixRangeOption(options, o.size)
+	opts := rest.Opts{
+		Method:  "GET",
+		Path:    "/ipfs/" + o.cid,
+		Options: options,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.gateway.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// If existing is set then it updates the object rather than creating a new one.
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	p := o.fs.fullPath(o.remote)
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/files/write",
+		Parameters: url.Values{
+			"arg":      {p},
+			"create":   {"true"},
+			"parents":  {"true"},
+			"truncate": {"true"},
+		},
+		Body:                 in,
+		Options:              options,
+		ExtraHeaders:         o.fs.headers,
+		MultipartContentName: "file",
+		MultipartFileName:    "file", // the node doesn't use the name
+	}
+	// can't retry as the body has been consumed
+	resp, err := o.fs.srv.CallJSON(ctx, &opts, nil, nil)
+	if err != nil {
+		_, err = shouldRetry(ctx, resp, err)
+		return fmt.Errorf("failed to upload %q: %w", o.remote, err)
+	}
+	stat, err := o.fs.stat(ctx, p)
+	if err != nil {
+		return fmt.Errorf("failed to read info about uploaded %q: %w", o.remote, err)
+	}
+	*o = *o.fs.newObject(o.remote, stat)
+	if o.fs.opt.Pin {
+		if err = o.fs.pin(ctx, o.cid); err != nil {
+			return fmt.Errorf("failed to pin %q: %w", o.remote, err)
+		}
+	}
+	return nil
+}
+
+// Remove an object
+//
+// Its CID stays pinned as other files may have the same contents.
+func (o *Object) Remove(ctx context.Context) error {
+	return o.fs.remove(ctx, o.fs.fullPath(o.remote))
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs           = (*Fs)(nil)
+	_ fs.Purger       = (*Fs)(nil)
+	_ fs.PutStreamer  = (*Fs)(nil)
+	_ fs.Copier       = (*Fs)(nil)
+	_ fs.Mover        = (*Fs)(nil)
+	_ fs.DirMover     = (*Fs)(nil)
+	_ fs.Abouter      = (*Fs)(nil)
+	_ fs.PublicLinker = (*Fs)(nil)
+	_ fs.Object       = (*Object)(nil)
+	_ fs.IDer         = (*Object)(nil)
+)
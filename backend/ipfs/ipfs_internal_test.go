@@ -0,0 +1,349 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/ipfs/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNode is an in memory IPFS node with just enough of the RPC API
+// and a gateway for the backend
+type fakeNode struct {
+	mu        sync.Mutex
+	api       *httptest.Server
+	gateway   *httptest.Server
+	files     map[string]*string // MFS by absolute path - nil for directories
+	blocks    map[string]string  // contents by CID
+	pins      map[string]bool
+	failReads bool // make files/read fail
+}
+
+// newFakeNode starts a fake node and gateway
+func newFakeNode(t *testing.T) *fakeNode {
+	n := &fakeNode{
+		files:  map[string]*string{"/": nil},
+		blocks: map[string]string{},
+		pins:   map[string]bool{},
+	}
+	n.api = httptest.NewServer(http.HandlerFunc(n.serveAPI))
+	t.Cleanup(n.api.Close)
+	n.gateway = httptest.NewServer(http.HandlerFunc(n.serveGateway))
+	t.Cleanup(n.gateway.Close)
+	return n
+}
+
+// cid makes a fake CID for data
+func cid(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return "bafk" + hex.EncodeToString(sum[:16])
+}
+
+// fail sends an API error
+func fail(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusInternalServerError)
+	_ = json.NewEncoder(w).Encode(api.Error{Message: message, Code: 0, Type: "error"})
+}
+
+// mkdirAll makes dir and its parents
+func (n *fakeNode) mkdirAll(dir string) bool {
+	if data, found := n.files[dir]; found {
+		return data == nil
+	}
+	if !n.mkdirAll(path.Dir(dir)) {
+		return false
+	}
+	n.files[dir] = nil
+	return true
+}
+
+// children returns the paths of the direct children of dir
+func (n *fakeNode) children(dir string) (out []string) {
+	for p := range n.files {
+		if p != "/" && path.Dir(p) == dir {
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// stat describes p
+func (n *fakeNode) stat(p string) api.Stat {
+	if data := n.files[p]; data != nil {
+		return api.Stat{Hash: cid(*data), Size: int64(len(*data)), Type: "file"}
+	}
+	return api.Stat{Hash: cid("dir:" + p), Type: "directory"}
+}
+
+// serveAPI answers the RPC calls
+func (n *fakeNode) serveAPI(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	args := q["arg"]
+	arg := ""
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	_, found := n.files[arg]
+	switch strings.TrimPrefix(r.URL.Path, "/api/v0/") {
+	case "files/stat":
+		if !found {
+			fail(w, "file does not exist")
+			return
+		}
+		_ = json.NewEncoder(w).Encode(n.stat(arg))
+	case "files/ls":
+		if !found {
+			fail(w, "file does not exist")
+			return
+		}
+		entries := []api.Entry{}
+		for _, p := range n.children(arg) {
+			stat := n.stat(p)
+			entry := api.Entry{Name: path.Base(p), Size: stat.Size, Hash: stat.Hash}
+			if stat.IsDir() {
+				entry.Type = api.TypeDirectory
+			}
+			entries = append(entries, entry)
+		}
+		if len(entries) == 0 {
+			entries = nil
+		}
+		_ = json.NewEncoder(w).Encode(api.ListResponse{Entries: entries})
+	case "files/mkdir":
+		if !n.mkdirAll(arg) {
+			fail(w, "file already exists")
+		}
+	case "files/rm":
+		if !found {
+			fail(w, "file does not exist")
+			return
+		}
+		for p := range n.files {
+			if p == arg || strings.HasPrefix(p, arg+"/") {
+				delete(n.files, p)
+			}
+		}
+	case "files/mv":
+		if !found {
+			fail(w, "file does not exist")
+			return
+		}
+		dst := args[1]
+		if _, exists := n.files[dst]; exists {
+			fail(w, "directory already has entry by that name")
+			return
+		}
+		if n.files[path.Dir(dst)] != nil {
+			fail(w, "file does not exist")
+			return
+		}
+		for p, data := range n.files {
+			if p == arg || strings.HasPrefix(p, arg+"/") {
+				delete(n.files, p)
+				n.files[dst+strings.TrimPrefix(p, arg)] = data
+			}
+		}
+	case "files/cp":
+		data := n.files[arg]
+		if data == nil {
+			fail(w, "file does not exist")
+			return
+		}
+		dst := args[1]
+		if _, exists := n.files[dst]; exists {
+			fail(w, "directory already has entry by that name")
+			return
+		}
+		copied := *data
+		n.files[dst] = &copied
+	case "files/write":
+		if q.Get("create") != "true" || q.Get("truncate") != "true" || !n.mkdirAll(path.Dir(arg)) {
+			fail(w, "bad write")
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			fail(w, err.Error())
+			return
+		}
+		data, err := io.ReadAll(file)
+		if err != nil {
+			fail(w, err.Error())
+			return
+		}
+		s := string(data)
+		n.files[arg] = &s
+		n.blocks[cid(s)] = s
+	case "files/read":
+		data := n.files[arg]
+		if data == nil || n.failReads {
+			fail(w, "file does not exist")
+			return
+		}
+		s := *data
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		if offset > len(s) {
+			offset = len(s)
+		}
+		s = s[offset:]
+		if count := q.Get("count"); count != "" {
+			i, _ := strconv.Atoi(count)
+			if i < len(s) {
+				s = s[:i]
+			}
+		}
+		_, _ = io.WriteString(w, s)
+	case "pin/add":
+		c := strings.TrimPrefix(arg, "/ipfs/")
+		if _, ok := n.blocks[c]; !ok {
+			fail(w, "block not found")
+			return
+		}
+		n.pins[c] = true
+		_ = json.NewEncoder(w).Encode(api.PinResponse{Pins: []string{c}})
+	case "repo/stat":
+		_ = json.NewEncoder(w).Encode(api.RepoStat{RepoSize: 1000, StorageMax: 10000})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveGateway serves blocks by CID
+func (n *fakeNode) serveGateway(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	data, found := n.blocks[strings.TrimPrefix(r.URL.Path, "/ipfs/")]
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, "", time.Time{}, strings.NewReader(data))
+}
+
+// TestFake runs the integration tests against the fake node
+func TestFake(t *testing.T) {
+	n := newFakeNode(t)
+	name := "TestIPFSFake"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "ipfs"},
+			{Name: name, Key: "api_url", Value: n.api.URL},
+			{Name: name, Key: "gateway_url", Value: n.gateway.URL},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// newFs makes an Fs talking to n
+func newFs(t *testing.T, n *fakeNode, extra string) fs.Fs {
+	f, err := fs.NewFs(context.Background(), fmt.Sprintf(":ipfs,api_url=%q,gateway_url=%q%s:", n.api.URL, n.gateway.URL, extra))
+	require.NoError(t, err)
+	return f
+}
+
+// put uploads contents to remote on f
+func put(t *testing.T, f fs.Fs, remote, contents string) fs.Object {
+	src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(context.Background(), strings.NewReader(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+// read returns the contents of o
+func read(t *testing.T, o fs.Object, options ...fs.OpenOption) string {
+	in, err := o.Open(context.Background(), options...)
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+func TestPin(t *testing.T) {
+	n := newFakeNode(t)
+	o := put(t, newFs(t, n, ""), "dir/file.txt", "pinned")
+	assert.Equal(t, cid("pinned"), o.(fs.IDer).ID())
+	assert.True(t, n.pins[cid("pinned")])
+
+	put(t, newFs(t, n, ",pin=false"), "dir/other.txt", "not pinned")
+	assert.False(t, n.pins[cid("not pinned")])
+}
+
+func TestGatewayFallback(t *testing.T) {
+	n := newFakeNode(t)
+	f := newFs(t, n, "")
+	o := put(t, f, "file.txt", "0123456789")
+	n.failReads = true
+	assert.Equal(t, "0123456789", read(t, o))
+	assert.Equal(t, "345", read(t, o, &fs.RangeOption{Start: 3, End: 5}))
+
+	// no fallback without a gateway
+	f, err := fs.NewFs(context.Background(), fmt.Sprintf(":ipfs,api_url=%q:", n.api.URL))
+	require.NoError(t, err)
+	o, err = f.NewObject(context.Background(), "file.txt")
+	require.NoError(t, err)
+	_, err = o.Open(context.Background())
+	assert.True(t, isNotFound(err))
+}
+
+func TestPublicLink(t *testing.T) {
+	ctx := context.Background()
+	n := newFakeNode(t)
+	f := newFs(t, n, "")
+	put(t, f, "dir/my file.txt", "linked")
+	link, err := f.Features().PublicLink(ctx, "dir/my file.txt", 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, n.gateway.URL+"/ipfs/"+cid("linked")+"?filename=my+file.txt", link)
+
+	resp, err := http.Get(link)
+	require.NoError(t, err)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "linked", string(data))
+
+	_, err = f.Features().PublicLink(ctx, "missing", 0, false)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func TestErrorHandler(t *testing.T) {
+	resp := &http.Response{
+		Status:     "500 Internal Server Error",
+		StatusCode: 500,
+		Body:       io.NopCloser(bytes.NewBufferString(`{"Message":"file does not exist","Code":0,"Type":"error"}`)),
+	}
+	err := errorHandler(resp)
+	assert.True(t, isNotFound(err))
+	assert.Equal(t, "ipfs error: file does not exist", err.Error())
+
+	resp.Body = io.NopCloser(bytes.NewBufferString("not json"))
+	assert.Equal(t, "ipfs error: 500 Internal Server Error: not json", errorHandler(resp).Error())
+}
@@ -0,0 +1,663 @@
+// Package jsonindex provides a read only filesystem built from the
+// JSON responses of a web service
+//
+// The URLs to call and where to find the names, sizes and times of
+// the files in the responses are all configured, so directory
+// listing services can be used without writing code for each one.
+package jsonindex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+var errorReadOnly = errors.New("jsonindex remotes are read only")
+
+const templateHelp = `
+
+The placeholders {path}, {dir} and {name} are replaced with the path of
+the file or directory on the server (starting with /), the directory it
+is in and its leaf name. They are URL encoded in URLs and JSON string
+encoded in request bodies.`
+
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "jsonindex",
+		Description: "JSON Index",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:     "list_url",
+			Help:     "URL to fetch the listing of a directory from.\n\nE.g. \"https://example.com/api/list?path={path}\"." + templateHelp,
+			Required: true,
+		}, {
+			Name:    "items",
+			Help:    "JSONPath of the array of entries in a directory listing.\n\nE.g. \"$.data.content\". Use \"$\" if the response is the array.",
+			Default: "$",
+		}, {
+			Name:    "item_name",
+			Help:    "JSONPath of the name of an entry, relative to the entry.",
+			Default: "$.name",
+		}, {
+			Name:    "item_size",
+			Help:    "JSONPath of the size of an entry in bytes, relative to the entry.",
+			Default: "$.size",
+		}, {
+			Name:    "item_modtime",
+			Help:    "JSONPath of the modification time of an entry, relative to the entry.\n\nLeave blank if the service doesn't supply one.",
+			Default: "$.modified",
+		}, {
+			Name: "item_is_dir",
+			Help: `JSONPath of the value saying if an entry is a directory.
+
+The entry is a directory if the value is true, a number other than 0 or
+a string such as "dir", "directory" or "folder".`,
+			Default: "$.is_dir",
+		}, {
+			Name:     "item_url",
+			Help:     "JSONPath of the download URL of a file in its entry.\n\nRelative URLs are resolved against the listing URL. If blank or missing\nfrom an entry, download_url is used.",
+			Advanced: true,
+		}, {
+			Name:     "item_md5",
+			Help:     "JSONPath of the MD5 hash of a file in its entry.",
+			Advanced: true,
+		}, {
+			Name:     "item_sha1",
+			Help:     "JSONPath of the SHA-1 hash of a file in its entry.",
+			Advanced: true,
+		}, {
+			Name: "time_format",
+			Help: `Format of the modification times.
+
+This is a Go time layout, e.g. "2006-01-02 15:04:05", or "unix" or
+"unixms" for seconds or milliseconds since 1970. If blank, strings are
+read as RFC 3339 and numbers as seconds since 1970.`,
+			Advanced: true,
+		}, {
+			Name:     "download_url",
+			Help:     "URL to download a file from.\n\nE.g. \"https://example.com/d{path}\"." + templateHelp,
+			Advanced: true,
+		}, {
+			Name:     "list_method",
+			Help:     "HTTP method to fetch listings with.",
+			Default:  "GET",
+			Advanced: true,
+		}, {
+			Name:     "list_body",
+			Help:     "Body to send with listing requests.\n\nE.g. '{\"path\":\"{path}\",\"per_page\":0}'. It is sent as JSON." + templateHelp,
+			Advanced: true,
+		}, {
+			Name: "stat_url",
+			Help: `URL to fetch the details of a single file from.
+
+If blank the file is found by listing the directory it is in.` + templateHelp,
+			Advanced: true,
+		}, {
+			Name:     "stat_method",
+			Help:     "HTTP method to fetch the details of a file with.",
+			Default:  "GET",
+			Advanced: true,
+		}, {
+			Name:     "stat_body",
+			Help:     "Body to send with requests to stat_url." + templateHelp,
+			Advanced: true,
+		}, {
+			Name:     "stat_item",
+			Help:     "JSONPath of the entry in the stat_url response.\n\nThe item_* paths are used relative to this.",
+			Default:  "$",
+			Advanced: true,
+		}, {
+			Name: "headers",
+			Help: `Set HTTP headers for all transactions.
+
+The input format is comma separated list of key,value pairs.  Standard
+[CSV encoding](https://godoc.org/encoding/csv) may be used.
+
+For example, to set an API key use '"Authorization","Bearer xxx"'.`,
+			Default:  fs.CommaSepList{},
+			Advanced: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	ListURL     string          `config:"list_url"`
+	Items       string          `config:"items"`
+	ItemName    string          `config:"item_name"`
+	ItemSize    string          `config:"item_size"`
+	ItemModTime string          `config:"item_modtime"`
+	ItemIsDir   string          `config:"item_is_dir"`
+	ItemURL     string          `config:"item_url"`
+	ItemMD5     string          `config:"item_md5"`
+	ItemSHA1    string          `config:"item_sha1"`
+	TimeFormat  string          `config:"time_format"`
+	DownloadURL string          `config:"download_url"`
+	ListMethod  string          `config:"list_method"`
+	ListBody    string          `config:"list_body"`
+	StatURL     string          `config:"stat_url"`
+	StatMethod  string          `config:"stat_method"`
+	StatBody    string          `config:"stat_body"`
+	StatItem    string          `config:"stat_item"`
+	Headers     fs.CommaSepList `config:"headers"`
+}
+
+// paths are the parsed JSONPaths from the options
+type paths struct {
+	items   jsonPath
+	name    jsonPath
+	size    jsonPath
+	modTime jsonPath
+	isDir   jsonPath
+	url     jsonPath
+	md5     jsonPath
+	sha1    jsonPath
+	stat    jsonPath
+}
+
+// Fs is a read only view of a JSON directory service
+type Fs struct {
+	name       string
+	root       string
+	opt        Options
+	features   *fs.Features // optional features
+	paths      paths
+	hashes     hash.Set
+	httpClient *http.Client
+}
+
+// Object is a file in the listing
+type Object struct {
+	fs      *Fs
+	remote  string
+	size    int64
+	modTime time.Time
+	url     string // download URL if known
+	md5     string
+	sha1    string
+}
+
+// parsePaths parses all the JSONPaths in opt
+func parsePaths(opt *Options) (p paths, err error) {
+	for _, x := range []struct {
+		out  *jsonPath
+		name string
+		in   string
+	}{
+		{&p.items, "items", opt.Items},
+		{&p.name, "item_name", opt.ItemName},
+		{&p.size, "item_size", opt.ItemSize},
+		{&p.modTime, "item_modtime", opt.ItemModTime},
+		{&p.isDir, "item_is_dir", opt.ItemIsDir},
+		{&p.url, "item_url", opt.ItemURL},
+		{&p.md5, "item_md5", opt.ItemMD5},
+		{&p.sha1, "item_sha1", opt.ItemSHA1},
+		{&p.stat, "stat_item", opt.StatItem},
+	} {
+		*x.out, err = parseJSONPath(x.in)
+		if err != nil {
+			return p, fmt.Errorf("bad %s: %w", x.name, err)
+		}
+	}
+	if p.items == nil || p.name == nil {
+		return p, errors.New("items and item_name must be set")
+	}
+	return p, nil
+}
+
+// NewFs creates a new Fs object from the name and root. It connects to
+// the host specified in the config file.
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if len(opt.Headers)%2 != 0 {
+		return nil, errors.New("odd number of headers supplied")
+	}
+	f := &Fs{
+		name:       name,
+		root:       strings.Trim(root, "/"),
+		opt:        *opt,
+		httpClient: fshttp.NewClient(ctx),
+	}
+	f.paths, err = parsePaths(opt)
+	if err != nil {
+		return nil, err
+	}
+	if f.paths.md5 != nil {
+		f.hashes.Add(hash.MD5)
+	}
+	if f.paths.sha1 != nil {
+		f.hashes.Add(hash.SHA1)
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	if f.root != "" {
+		newRoot := parentDir(f.root)
+		tempF := *f
+		tempF.root = newRoot
+		_, err := tempF.NewObject(ctx, path.Base(f.root))
+		if err == nil {
+			f.root = newRoot
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// Name returns the configured name of the file system
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root returns the root for the filesystem
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String returns the URL for the filesystem
+func (f *Fs) String() string {
+	return fmt.Sprintf("jsonindex root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+//
+// The format of the times isn't known so 1s is assumed.
+func (f *Fs) Precision() time.Duration {
+	if f.paths.modTime == nil {
+		return fs.ModTimeNotSupported
+	}
+	return time.Second
+}
+
+// Hashes returns the hashes configured
+func (f *Fs) Hashes() hash.Set {
+	return f.hashes
+}
+
+// parentDir returns the parent directory of p or "" for the root
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// serverPath returns the path of remote on the server
+func (f *Fs) serverPath(remote string) string {
+	return path.Join("/", f.root, remote)
+}
+
+// expandURL fills in the placeholders in the URL template tmpl for the
+// server path p
+//
+// Values are path escaped before the query and query escaped after it.
+func expandURL(tmpl, p string) string {
+	i := strings.IndexByte(tmpl, '?')
+	if i < 0 {
+		return expand(tmpl, p, rest.URLPathEscape)
+	}
+	return expand(tmpl[:i], p, rest.URLPathEscape) + "?" + expand(tmpl[i+1:], p, url.QueryEscape)
+}
+
+// expandBody fills in the placeholders in the body template tmpl for
+// the server path p as JSON string contents
+func expandBody(tmpl, p string) string {
+	return expand(tmpl, p, func(s string) string {
+		quoted, _ := json.Marshal(s)
+		return string(quoted[1 : len(quoted)-1])
+	})
+}
+
+// expand replaces {path}, {dir} and {name} in tmpl with the parts of
+// the server path p escaped with escape
+func expand(tmpl, p string, escape func(string) string) string {
+	dir, name := path.Split(p)
+	if dir != "/" {
+		dir = strings.TrimSuffix(dir, "/")
+	}
+	return strings.NewReplacer(
+		"{path}", escape(p),
+		"{dir}", escape(dir),
+		"{name}", escape(name),
+	).Replace(tmpl)
+}
+
+// addHeaders adds the configured headers to the request if any
+func (f *Fs) addHeaders(req *http.Request) {
+	for i := 0; i < len(f.opt.Headers); i += 2 {
+		req.Header.Add(f.opt.Headers[i], f.opt.Headers[i+1])
+	}
+}
+
+// errNotFound is returned by fetch when the server says 404
+var errNotFound = errors.New("not found")
+
+// fetch calls the templated URL for the server path p and decodes the
+// JSON response, returning it and the URL used
+func (f *Fs) fetch(ctx context.Context, method, urlTmpl, bodyTmpl, p string) (doc interface{}, u *url.URL, err error) {
+	u, err = url.Parse(expandURL(urlTmpl, p))
+	if err != nil {
+		return nil, nil, fmt.Errorf("bad URL: %w", err)
+	}
+	var body io.Reader
+	if bodyTmpl != "" {
+		body = strings.NewReader(expandBody(bodyTmpl, p))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	f.addHeaders(req)
+	res, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fs.CheckClose(res.Body, &err)
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil, errNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("HTTP Error: %s", res.Status)
+	}
+	decoder := json.NewDecoder(res.Body)
+	decoder.UseNumber()
+	if err = decoder.Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode JSON from %s: %w", u, err)
+	}
+	return doc, u, nil
+}
+
+// entry is a parsed item from a listing or stat response
+type entry struct {
+	name    string
+	isDir   bool
+	size    int64
+	modTime time.Time
+	url     string
+	md5     string
+	sha1    string
+}
+
+// parseItem reads the entry out of item using the configured paths
+//
+// base is the URL of the request, used to resolve relative URLs.
+func (f *Fs) parseItem(item interface{}, base *url.URL) (e entry, ok bool) {
+	e.name, ok = f.paths.name.getString(item)
+	if !ok || e.name == "" || e.name == "." || e.name == ".." || strings.Contains(e.name, "/") {
+		return e, false
+	}
+	e.isDir = f.paths.isDir.getBool(item)
+	if e.size, ok = f.paths.size.getInt(item); !ok {
+		e.size = -1
+	}
+	if f.paths.modTime != nil {
+		t, err := f.paths.modTime.getTime(item, f.opt.TimeFormat)
+		if err != nil {
+			fs.Debugf(f, "Couldn't read the modification time of %q", e.name)
+		}
+		e.modTime = t
+	}
+	if link, found := f.paths.url.getString(item); found && link != "" {
+		if u, err := base.Parse(link); err == nil {
+			e.url = u.String()
+		}
+	}
+	e.md5, _ = f.paths.md5.getString(item)
+	e.sha1, _ = f.paths.sha1.getString(item)
+	return e, true
+}
+
+// listDir returns the entries of the server path p
+func (f *Fs) listDir(ctx context.Context, p string) ([]entry, error) {
+	doc, u, err := f.fetch(ctx, f.opt.ListMethod, f.opt.ListURL, f.opt.ListBody, p)
+	if err != nil {
+		return nil, err
+	}
+	items, found := f.paths.items.get(doc)
+	if !found {
+		// an empty directory is often sent as null
+		return nil, nil
+	}
+	array, ok := items.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("items in listing of %q isn't an array", p)
+	}
+	entries := make([]entry, 0, len(array))
+	for _, item := range array {
+		e, ok := f.parseItem(item, u)
+		if !ok {
+			fs.Debugf(f, "Ignoring entry without a valid name in listing of %q", p)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// newObject makes an object for remote from e
+func (f *Fs) newObject(remote string, e *entry) *Object {
+	o := &Object{
+		fs:      f,
+		remote:  remote,
+		size:    e.size,
+		modTime: e.modTime,
+		url:     e.url,
+		md5:     e.md5,
+		sha1:    e.sha1,
+	}
+	if o.url == "" && f.opt.DownloadURL != "" {
+		o.url = expandURL(f.opt.DownloadURL, f.serverPath(remote))
+	}
+	return o
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	list, err := f.listDir(ctx, f.serverPath(dir))
+	if err == errNotFound {
+		return nil, fs.ErrorDirNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing %q: %w", dir, err)
+	}
+	for i := range list {
+		e := &list[i]
+		remote := path.Join(dir, e.name)
+		if e.isDir {
+			entries = append(entries, fs.NewDir(remote, e.modTime))
+		} else {
+			entries = append(entries, f.newObject(remote, e))
+		}
+	}
+	return entries, nil
+}
+
+// NewObject creates a new remote object
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	var e *entry
+	if f.opt.StatURL != "" {
+		doc, u, err := f.fetch(ctx, f.opt.StatMethod, f.opt.StatURL, f.opt.StatBody, f.serverPath(remote))
+		if err == errNotFound {
+			return nil, fs.ErrorObjectNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		item, found := f.paths.stat.get(doc)
+		if !found {
+			return nil, fs.ErrorObjectNotFound
+		}
+		parsed, ok := f.parseItem(item, u)
+		if !ok {
+			return nil, fs.ErrorObjectNotFound
+		}
+		e = &parsed
+	} else {
+		list, err := f.listDir(ctx, f.serverPath(parentDir(remote)))
+		if err == errNotFound {
+			return nil, fs.ErrorObjectNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		leaf := path.Base(remote)
+		for i := range list {
+			if list[i].name == leaf {
+				e = &list[i]
+				break
+			}
+		}
+		if e == nil {
+			return nil, fs.ErrorObjectNotFound
+		}
+	}
+	if e.isDir {
+		return nil, fs.ErrorIsDir
+	}
+	return f.newObject(remote, e), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, errorReadOnly
+}
+
+// Mkdir makes the root directory of the Fs object
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// Rmdir removes the root directory of the Fs object
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// Fs is the filesystem this remote http file object is located within
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// String returns the URL to the remote HTTP file
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote the name of the remote HTTP file, relative to the fs root
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash from the listing if there was one
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	switch {
+	case !o.fs.hashes.Contains(t):
+		return "", hash.ErrUnsupported
+	case t == hash.MD5:
+		return strings.ToLower(o.md5), nil
+	case t == hash.SHA1:
+		return strings.ToLower(o.sha1), nil
+	}
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size in bytes of the remote http file
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the remote http file
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification and access time to the specified time
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return errorReadOnly
+}
+
+// Storable returns whether this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open a remote http file object for reading. Seek is supported
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	if o.url == "" {
+		return nil, errors.New("no download URL - set item_url or download_url")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", o.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Open failed: %w", err)
+	}
+	// Add optional headers
+	for k, v := range fs.OpenOptionHeaders(options) {
+		req.Header.Add(k, v)
+	}
+	o.fs.addHeaders(req)
+	res, err := o.fs.httpClient.Do(req)
+	if err == nil && (res.StatusCode < 200 || res.StatusCode > 299) {
+		_ = res.Body.Close()
+		err = fmt.Errorf("HTTP Error: %s", res.Status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Open failed: %w", err)
+	}
+	return res.Body, nil
+}
+
+// Remove a remote http file object
+func (o *Object) Remove(ctx context.Context) error {
+	return errorReadOnly
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errorReadOnly
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs     = (*Fs)(nil)
+	_ fs.Object = (*Object)(nil)
+)
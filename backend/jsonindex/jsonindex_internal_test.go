@@ -0,0 +1,349 @@
+package jsonindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc interface{}
+	decoder := json.NewDecoder(strings.NewReader(`{
+		"data": {"content": [{"name": "a", "size": 12345678901234}, {"name": "b"}]},
+		"odd key": {"x.y": true},
+		"nothing": null
+	}`))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&doc))
+
+	for _, test := range []struct {
+		path  string
+		want  string
+		found bool
+	}{
+		{"$.data.content[0].name", "a", true},
+		{"$.data.content[-1].name", "b", true},
+		{"$['data'][\"content\"][0].size", "12345678901234", true},
+		{"$['odd key']['x.y']", "true", true},
+		{"$.data.content[2].name", "", false},
+		{"$.data.missing", "", false},
+		{"$.nothing", "", false},
+		{"", "", false},
+	} {
+		p, err := parseJSONPath(test.path)
+		require.NoError(t, err, test.path)
+		got, found := p.getString(doc)
+		assert.Equal(t, test.found, found, test.path)
+		assert.Equal(t, test.want, got, test.path)
+	}
+
+	for _, bad := range []string{"data.content", "$.", "$[0", "$[x]", "$x"} {
+		_, err := parseJSONPath(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestGetTime(t *testing.T) {
+	var doc interface{}
+	decoder := json.NewDecoder(strings.NewReader(`{"s": "2022-09-10T11:12:13Z", "n": 1662808333, "ms": 1662808333000, "custom": "2022-09-10 11:12:13"}`))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&doc))
+	want := time.Date(2022, 9, 10, 11, 12, 13, 0, time.UTC)
+	for _, test := range []struct {
+		path   string
+		layout string
+	}{
+		{"$.s", ""},
+		{"$.n", ""},
+		{"$.n", "unix"},
+		{"$.ms", "unixms"},
+		{"$.custom", "2006-01-02 15:04:05"},
+	} {
+		p, err := parseJSONPath(test.path)
+		require.NoError(t, err)
+		got, err := p.getTime(doc, test.layout)
+		require.NoError(t, err, test.path)
+		assert.True(t, want.Equal(got), "%s: got %v", test.path, got)
+	}
+}
+
+// fakeFile is a file or directory served by the fake services
+type fakeFile struct {
+	isDir bool
+	data  string
+}
+
+var fakeFiles = map[string]fakeFile{
+	"/":                   {isDir: true},
+	"/dir":                {isDir: true},
+	"/dir/file.txt":       {data: "hello world"},
+	"/dir/with space.txt": {data: "spaced out"},
+	"/dir/empty":          {isDir: true},
+	"/top.bin":            {data: "0123456789"},
+}
+
+// children returns the entries in dir
+func children(dir string) (names []string) {
+	for p := range fakeFiles {
+		if p == "/" {
+			continue
+		}
+		i := strings.LastIndexByte(p, '/')
+		parent := p[:i]
+		if parent == "" {
+			parent = "/"
+		}
+		if parent == dir {
+			names = append(names, p[i+1:])
+		}
+	}
+	return names
+}
+
+// newAlistServer serves an Alist like API where listings are POSTed
+// and files are downloaded from /d/path
+func newAlistServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "secret" {
+			http.Error(w, "no auth", http.StatusUnauthorized)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/d/") {
+			file, found := fakeFiles[strings.TrimPrefix(r.URL.Path, "/d")]
+			if !found || file.isDir {
+				http.NotFound(w, r)
+				return
+			}
+			http.ServeContent(w, r, "", time.Time{}, strings.NewReader(file.data))
+			return
+		}
+		var req struct {
+			Path string `json:"path"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		dir, found := fakeFiles[req.Path]
+		if !found || !dir.isDir {
+			_, _ = fmt.Fprint(w, `{"code":500,"message":"object not found","data":null}`)
+			return
+		}
+		content := []map[string]interface{}{}
+		for _, name := range children(req.Path) {
+			file := fakeFiles[strings.TrimSuffix(req.Path, "/")+"/"+name]
+			content = append(content, map[string]interface{}{
+				"name":      name,
+				"size":      len(file.data),
+				"is_dir":    file.isDir,
+				"modified":  "2022-09-10T11:12:13Z",
+				"hash_info": map[string]string{"md5": "ABC"},
+			})
+		}
+		if len(content) == 0 {
+			content = nil
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": 200,
+			"data": map[string]interface{}{"content": content},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newSimpleServer serves listings as a bare array from GET
+// /list?dir=path with relative download links and a stat endpoint
+func newSimpleServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/list":
+			dir := r.URL.Query().Get("dir")
+			if file, found := fakeFiles[dir]; !found || !file.isDir {
+				http.NotFound(w, r)
+				return
+			}
+			out := []map[string]interface{}{}
+			for _, name := range children(dir) {
+				p := strings.TrimSuffix(dir, "/") + "/" + name
+				file := fakeFiles[p]
+				kind := "file"
+				if file.isDir {
+					kind = "folder"
+				}
+				out = append(out, map[string]interface{}{
+					"n":     name,
+					"bytes": len(file.data),
+					"mtime": 1662808333,
+					"type":  kind,
+					"href":  "raw" + p,
+				})
+			}
+			_ = json.NewEncoder(w).Encode(out)
+		case "/stat":
+			p := r.URL.Query().Get("p")
+			file, found := fakeFiles[p]
+			if !found {
+				http.NotFound(w, r)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"entry": map[string]interface{}{
+					"n":     p[strings.LastIndexByte(p, '/')+1:],
+					"bytes": len(file.data),
+					"type":  map[bool]string{true: "folder", false: "file"}[file.isDir],
+					"href":  "/raw" + p,
+				},
+			})
+		default:
+			if strings.HasPrefix(r.URL.Path, "/raw/") {
+				file := fakeFiles[strings.TrimPrefix(r.URL.Path, "/raw")]
+				_, _ = io.WriteString(w, file.data)
+				return
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// alistRemote returns the connection string of a remote for the Alist
+// like server
+func alistRemote(srv *httptest.Server) string {
+	return fmt.Sprintf(`:jsonindex,list_url=%q,list_method=POST,list_body='{"path":"{path}"}',items="$.data.content",item_md5="$.hash_info.md5",download_url=%q,headers="Authorization,secret":`,
+		srv.URL+"/api/fs/list", srv.URL+"/d{path}")
+}
+
+// simpleRemote returns the connection string of a remote for the
+// simple server
+func simpleRemote(srv *httptest.Server, stat bool) string {
+	remote := fmt.Sprintf(`:jsonindex,list_url=%q,item_name="$.n",item_size="$.bytes",item_modtime="$.mtime",item_is_dir="$.type",item_url="$.href"`,
+		srv.URL+"/list?dir={path}")
+	if stat {
+		remote += fmt.Sprintf(`,stat_url=%q,stat_item="$.entry"`, srv.URL+"/stat?p={path}")
+	}
+	return remote + ":"
+}
+
+// read returns the contents of o
+func read(t *testing.T, o fs.Object, options ...fs.OpenOption) string {
+	in, err := o.Open(context.Background(), options...)
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+// names returns the remotes of entries
+func names(entries fs.DirEntries) (out []string) {
+	for _, entry := range entries {
+		out = append(out, entry.Remote())
+	}
+	return out
+}
+
+func TestAlistLike(t *testing.T) {
+	ctx := context.Background()
+	srv := newAlistServer(t)
+	f, err := fs.NewFs(ctx, alistRemote(srv))
+	require.NoError(t, err)
+	assert.Equal(t, hash.NewHashSet(hash.MD5), f.Hashes())
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"dir", "top.bin"}, names(entries))
+
+	entries, err = f.List(ctx, "dir")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"dir/file.txt", "dir/with space.txt", "dir/empty"}, names(entries))
+
+	entries, err = f.List(ctx, "dir/empty")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+
+	o, err := f.NewObject(ctx, "dir/with space.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), o.Size())
+	assert.True(t, time.Date(2022, 9, 10, 11, 12, 13, 0, time.UTC).Equal(o.ModTime(ctx)))
+	md5, err := o.Hash(ctx, hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", md5)
+	assert.Equal(t, "spaced out", read(t, o))
+	assert.Equal(t, "out", read(t, o, &fs.RangeOption{Start: 7, End: -1}))
+
+	_, err = f.NewObject(ctx, "dir")
+	assert.Equal(t, fs.ErrorIsDir, err)
+	_, err = f.NewObject(ctx, "dir/missing")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// the service doesn't 404 so the listing has no items
+	entries, err = f.List(ctx, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+
+	// read only
+	assert.Equal(t, errorReadOnly, f.Mkdir(ctx, "new"))
+	_, err = f.Put(ctx, bytes.NewBufferString("x"), o)
+	assert.Equal(t, errorReadOnly, err)
+	assert.Equal(t, errorReadOnly, o.Remove(ctx))
+}
+
+func TestSimple(t *testing.T) {
+	ctx := context.Background()
+	srv := newSimpleServer(t)
+	for _, stat := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stat=%v", stat), func(t *testing.T) {
+			f, err := fs.NewFs(ctx, simpleRemote(srv, stat))
+			require.NoError(t, err)
+
+			entries, err := f.List(ctx, "dir")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"dir/file.txt", "dir/with space.txt", "dir/empty"}, names(entries))
+			for _, entry := range entries {
+				if entry.Remote() == "dir/empty" {
+					_, isDir := entry.(fs.Directory)
+					assert.True(t, isDir)
+				}
+			}
+
+			_, err = f.List(ctx, "missing")
+			assert.Equal(t, fs.ErrorDirNotFound, err)
+
+			o, err := f.NewObject(ctx, "dir/file.txt")
+			require.NoError(t, err)
+			assert.Equal(t, int64(11), o.Size())
+			assert.Equal(t, "hello world", read(t, o))
+			_, err = f.NewObject(ctx, "nope.txt")
+			assert.Equal(t, fs.ErrorObjectNotFound, err)
+		})
+	}
+}
+
+func TestRootIsFile(t *testing.T) {
+	ctx := context.Background()
+	srv := newSimpleServer(t)
+	remote := simpleRemote(srv, false)
+	f, err := fs.NewFs(ctx, remote+"dir/file.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+	require.NotNil(t, f)
+	assert.Equal(t, "dir", f.Root())
+	o, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", read(t, o))
+}
+
+func TestExpand(t *testing.T) {
+	assert.Equal(t, "https://x/d/a%20b/c%3F.txt?dir=%2Fa+b&name=c%3F.txt", expandURL("https://x/d{path}?dir={dir}&name={name}", "/a b/c?.txt"))
+	assert.Equal(t, `{"path":"/say \"hi\"","dir":"/"}`, expandBody(`{"path":"{path}","dir":"{dir}"}`, `/say "hi"`))
+}
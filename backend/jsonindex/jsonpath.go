@@ -0,0 +1,209 @@
+package jsonindex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonPath is a parsed JSONPath expression
+//
+// Only the subset needed to pick single values out of a document is
+// supported: "$" for the root, ".name" and "['name']" for members of
+// objects and "[n]" for elements of arrays, with negative n counting
+// from the end.
+type jsonPath []pathStep
+
+// pathStep is one step of a jsonPath - a member name or an index
+type pathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath parses s which must start with "$"
+//
+// An empty s gives a nil path which never matches anything.
+func parseJSONPath(s string) (jsonPath, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("JSONPath %q must start with $", s)
+	}
+	p := jsonPath{}
+	rest := s[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("JSONPath %q has an empty member name", s)
+			}
+			p = append(p, pathStep{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath %q has an unclosed [", s)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				p = append(p, pathStep{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("JSONPath %q: %q isn't a quoted name or an index", s, inner)
+			}
+			p = append(p, pathStep{index: i, isIndex: true})
+		default:
+			return nil, fmt.Errorf("JSONPath %q: unexpected %q", s, rest[:1])
+		}
+	}
+	return p, nil
+}
+
+// get returns the value p points to in v, which should have been
+// decoded with UseNumber, and whether it was found
+func (p jsonPath) get(v interface{}) (interface{}, bool) {
+	if p == nil {
+		return nil, false
+	}
+	for _, step := range p {
+		if step.isIndex {
+			a, ok := v.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			i := step.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i < 0 || i >= len(a) {
+				return nil, false
+			}
+			v = a[i]
+			continue
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[step.key]; !ok {
+			return nil, false
+		}
+	}
+	return v, v != nil
+}
+
+// getString returns the value p points to in v as a string
+func (p jsonPath) getString(v interface{}) (string, bool) {
+	v, ok := p.get(v)
+	if !ok {
+		return "", false
+	}
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case json.Number:
+		return x.String(), true
+	case bool:
+		return strconv.FormatBool(x), true
+	}
+	return "", false
+}
+
+// getInt returns the value p points to in v as an integer
+func (p jsonPath) getInt(v interface{}) (int64, bool) {
+	s, ok := p.getString(v)
+	if !ok {
+		return 0, false
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return int64(f), true
+	}
+	return 0, false
+}
+
+// getBool returns the value p points to in v as a boolean
+//
+// Numbers are true if they aren't 0, and strings such as "dir" and
+// "folder" are true so the type of an item can be used to find
+// directories.
+func (p jsonPath) getBool(v interface{}) bool {
+	v, ok := p.get(v)
+	if !ok {
+		return false
+	}
+	switch x := v.(type) {
+	case bool:
+		return x
+	case json.Number:
+		f, err := x.Float64()
+		return err == nil && f != 0
+	case string:
+		switch strings.ToLower(x) {
+		case "true", "1", "yes", "d", "dir", "directory", "folder":
+			return true
+		}
+	}
+	return false
+}
+
+// errBadTime is returned when a time can't be parsed
+var errBadTime = errors.New("can't parse time")
+
+// getTime returns the value p points to in v as a time
+//
+// layout is a Go time layout or "unix" or "unixms" for seconds or
+// milliseconds since the epoch. If layout is empty RFC 3339 is used
+// for strings and seconds since the epoch for numbers.
+func (p jsonPath) getTime(v interface{}, layout string) (time.Time, error) {
+	v, ok := p.get(v)
+	if !ok {
+		return time.Time{}, errBadTime
+	}
+	s, isNumber := "", false
+	switch x := v.(type) {
+	case string:
+		s = x
+	case json.Number:
+		s, isNumber = x.String(), true
+	default:
+		return time.Time{}, errBadTime
+	}
+	if layout == "" {
+		layout = time.RFC3339
+		if isNumber {
+			layout = "unix"
+		}
+	}
+	switch layout {
+	case "unix", "unixms":
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, errBadTime
+		}
+		if layout == "unixms" {
+			return time.Unix(0, int64(f)*int64(time.Millisecond)), nil
+		}
+		return time.Unix(0, int64(f*1e9)), nil
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, errBadTime
+	}
+	return t, nil
+}
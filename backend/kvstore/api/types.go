@@ -0,0 +1,125 @@
+// Package api contains definitions for using the HTTP APIs of the
+// Consul KV store and etcd
+//
+// See https://developer.hashicorp.com/consul/api-docs/kv and
+// https://etcd.io/docs/v3.5/dev-guide/api_grpc_gateway/
+package api
+
+import (
+	"fmt"
+)
+
+// Error is returned by the server when a request fails
+type Error struct {
+	Message    string `json:"message"`
+	Code       int    `json:"code"` // gRPC status code, etcd only
+	StatusCode int    `json:"-"`
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
+
+// ConsulPair is a key and its value in the Consul KV store
+type ConsulPair struct {
+	Key         string `json:"Key"`
+	Value       []byte `json:"Value"` // base64 encoded in JSON
+	Flags       uint64 `json:"Flags"`
+	CreateIndex uint64 `json:"CreateIndex"`
+	ModifyIndex uint64 `json:"ModifyIndex"`
+}
+
+// ConsulTxnKV is a KV operation in a Consul transaction
+type ConsulTxnKV struct {
+	Verb  string `json:"Verb"`
+	Key   string `json:"Key"`
+	Value []byte `json:"Value,omitempty"`
+	Flags uint64 `json:"Flags,omitempty"`
+}
+
+// ConsulTxnOp is an operation in a Consul transaction
+type ConsulTxnOp struct {
+	KV *ConsulTxnKV `json:"KV"`
+}
+
+// ConsulTxnError is an error from an operation in a Consul transaction
+type ConsulTxnError struct {
+	OpIndex int    `json:"OpIndex"`
+	What    string `json:"What"`
+}
+
+// ConsulTxnResponse is returned from a Consul transaction
+type ConsulTxnResponse struct {
+	Errors []ConsulTxnError `json:"Errors"`
+}
+
+// EtcdKeyValue is a key and its value in etcd
+type EtcdKeyValue struct {
+	Key            []byte `json:"key"`
+	Value          []byte `json:"value"`
+	CreateRevision int64  `json:"create_revision,string"`
+	ModRevision    int64  `json:"mod_revision,string"`
+	Version        int64  `json:"version,string"`
+}
+
+// EtcdRangeRequest reads a key or a range of keys from etcd
+type EtcdRangeRequest struct {
+	Key        []byte `json:"key"`
+	RangeEnd   []byte `json:"range_end,omitempty"`
+	Limit      int64  `json:"limit,omitempty,string"`
+	SortOrder  string `json:"sort_order,omitempty"`
+	SortTarget string `json:"sort_target,omitempty"`
+	KeysOnly   bool   `json:"keys_only,omitempty"`
+}
+
+// EtcdRangeResponse is returned from a range request
+type EtcdRangeResponse struct {
+	Kvs   []EtcdKeyValue `json:"kvs"`
+	More  bool           `json:"more"`
+	Count int64          `json:"count,string"`
+}
+
+// EtcdPutRequest sets the value of a key in etcd
+type EtcdPutRequest struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// EtcdDeleteRangeRequest deletes a key or a range of keys from etcd
+type EtcdDeleteRangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+}
+
+// EtcdDeleteRangeResponse is returned from a delete range request
+type EtcdDeleteRangeResponse struct {
+	Deleted int64 `json:"deleted,string"`
+}
+
+// EtcdRequestOp is an operation in an etcd transaction
+type EtcdRequestOp struct {
+	RequestPut         *EtcdPutRequest         `json:"request_put,omitempty"`
+	RequestDeleteRange *EtcdDeleteRangeRequest `json:"request_delete_range,omitempty"`
+}
+
+// EtcdTxnRequest is an etcd transaction
+type EtcdTxnRequest struct {
+	Success []EtcdRequestOp `json:"success"`
+}
+
+// EtcdTxnResponse is returned from an etcd transaction
+type EtcdTxnResponse struct {
+	Succeeded bool `json:"succeeded"`
+}
+
+// EtcdAuthenticateRequest logs in to etcd
+type EtcdAuthenticateRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// EtcdAuthenticateResponse is returned when logging in to etcd
+type EtcdAuthenticateResponse struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,168 @@
+package kvstore
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rclone/rclone/backend/kvstore/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+// consul stores files in the Consul KV store
+//
+// The modification time of each file is kept in the flags of its key.
+type consul struct {
+	srv        *rest.Client // the connection to the server
+	pacer      *fs.Pacer    // pacer for API calls
+	datacenter string       // datacenter to use or "" for the agent's
+}
+
+// newConsul makes a consul store
+func newConsul(srv *rest.Client, pacer *fs.Pacer, opt *Options) *consul {
+	if opt.Token != "" {
+		srv.SetHeader("X-Consul-Token", opt.Token)
+	}
+	return &consul{
+		srv:        srv,
+		pacer:      pacer,
+		datacenter: opt.Datacenter,
+	}
+}
+
+// precision of the modification times stored
+func (c *consul) precision() time.Duration {
+	return time.Nanosecond
+}
+
+// opts returns the options to call method on the key
+func (c *consul) opts(method, key string) *rest.Opts {
+	opts := &rest.Opts{
+		Method:     method,
+		Path:       "/v1/kv/" + rest.URLPathEscape(key),
+		Parameters: url.Values{},
+	}
+	if c.datacenter != "" {
+		opts.Parameters.Set("dc", c.datacenter)
+	}
+	return opts
+}
+
+// consulPair converts a Consul pair
+func consulPair(p *api.ConsulPair) pair {
+	out := pair{key: p.Key, value: p.Value}
+	if p.Flags != 0 {
+		out.modTime = time.Unix(0, int64(p.Flags))
+	}
+	return out
+}
+
+// flags returns the flags to store modTime in
+func flags(modTime time.Time) uint64 {
+	if modTime.IsZero() {
+		return 0
+	}
+	return uint64(modTime.UnixNano())
+}
+
+// call makes the request in opts decoding the reply into result
+//
+// It returns isNotFound set if the server said there is nothing there
+func (c *consul) call(ctx context.Context, opts *rest.Opts, request, result interface{}) (isNotFound bool, err error) {
+	var resp *http.Response
+	err = c.pacer.Call(func() (bool, error) {
+		resp, err = c.srv.CallJSON(ctx, opts, request, result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return true, nil
+	}
+	return false, err
+}
+
+// get the key
+func (c *consul) get(ctx context.Context, key string) (*pair, error) {
+	var result []api.ConsulPair
+	isNotFound, err := c.call(ctx, c.opts("GET", key), nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	if isNotFound || len(result) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	p := consulPair(&result[0])
+	return &p, nil
+}
+
+// list all the keys starting with prefix
+func (c *consul) list(ctx context.Context, prefix string) ([]pair, error) {
+	opts := c.opts("GET", prefix)
+	opts.Parameters.Set("recurse", "true")
+	var result []api.ConsulPair
+	_, err := c.call(ctx, opts, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]pair, len(result))
+	for i := range result {
+		pairs[i] = consulPair(&result[i])
+	}
+	return pairs, nil
+}
+
+// put sets the value of the key
+func (c *consul) put(ctx context.Context, p *pair) error {
+	opts := c.opts("PUT", p.key)
+	opts.Parameters.Set("flags", strconv.FormatUint(flags(p.modTime), 10))
+	opts.Body = bytes.NewReader(p.value)
+	opts.ContentLength = int64ptr(int64(len(p.value)))
+	var ok bool
+	_, err := c.call(ctx, opts, nil, &ok)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNotStored
+	}
+	return nil
+}
+
+// move the key from to p in one transaction
+func (c *consul) move(ctx context.Context, from string, p *pair) error {
+	opts := &rest.Opts{
+		Method:     "PUT",
+		Path:       "/v1/txn",
+		Parameters: url.Values{},
+	}
+	if c.datacenter != "" {
+		opts.Parameters.Set("dc", c.datacenter)
+	}
+	ops := []api.ConsulTxnOp{{
+		KV: &api.ConsulTxnKV{Verb: "set", Key: p.key, Value: p.value, Flags: flags(p.modTime)},
+	}, {
+		KV: &api.ConsulTxnKV{Verb: "delete", Key: from},
+	}}
+	var result api.ConsulTxnResponse
+	_, err := c.call(ctx, opts, &ops, &result)
+	return err
+}
+
+// delete the key
+func (c *consul) delete(ctx context.Context, key string) error {
+	var ok bool
+	_, err := c.call(ctx, c.opts("DELETE", key), nil, &ok)
+	return err
+}
+
+// deletePrefix deletes all the keys starting with prefix
+func (c *consul) deletePrefix(ctx context.Context, prefix string) error {
+	opts := c.opts("DELETE", prefix)
+	opts.Parameters.Set("recurse", "true")
+	var ok bool
+	_, err := c.call(ctx, opts, nil, &ok)
+	return err
+}
@@ -0,0 +1,210 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/kvstore/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	etcdListChunk       = 1000 // keys to read in each range request
+	etcdUnauthenticated = 16   // gRPC code for an invalid or expired token
+)
+
+// etcd stores files in etcd using its JSON gateway
+//
+// etcd has nowhere to keep the modification times of files.
+type etcd struct {
+	srv   *rest.Client // the connection to the server
+	pacer *fs.Pacer    // pacer for API calls
+	user  string       // user to log in as, if set
+	pass  string       // password to log in with
+
+	authMu sync.Mutex // held while logging in
+}
+
+// newEtcd makes an etcd store, logging in if a user is set
+func newEtcd(ctx context.Context, srv *rest.Client, pacer *fs.Pacer, user, pass string) (*etcd, error) {
+	e := &etcd{
+		srv:   srv,
+		pacer: pacer,
+		user:  user,
+		pass:  pass,
+	}
+	if user != "" {
+		err := e.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// precision of the modification times stored
+func (e *etcd) precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// authenticate gets a new token and uses it for all requests
+func (e *etcd) authenticate(ctx context.Context) error {
+	e.authMu.Lock()
+	defer e.authMu.Unlock()
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/v3/auth/authenticate",
+	}
+	request := api.EtcdAuthenticateRequest{Name: e.user, Password: e.pass}
+	var result api.EtcdAuthenticateResponse
+	var resp *http.Response
+	var err error
+	err = e.pacer.Call(func() (bool, error) {
+		resp, err = e.srv.CallJSON(ctx, &opts, &request, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to log in: %w", err)
+	}
+	if result.Token == "" {
+		return errors.New("failed to log in: no token returned")
+	}
+	e.srv.SetHeader("Authorization", result.Token)
+	return nil
+}
+
+// call POSTs request to the method at path decoding the reply into
+// result, logging in again if the token has expired
+func (e *etcd) call(ctx context.Context, path string, request, result interface{}) error {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   path,
+	}
+	var resp *http.Response
+	var err error
+	return e.pacer.Call(func() (bool, error) {
+		resp, err = e.srv.CallJSON(ctx, &opts, request, result)
+		var apiErr *api.Error
+		if e.user != "" && errors.As(err, &apiErr) && apiErr.Code == etcdUnauthenticated {
+			fs.Debugf(nil, "etcd token expired - logging in again")
+			authErr := e.authenticate(ctx)
+			if authErr != nil {
+				return false, authErr
+			}
+			return true, err
+		}
+		return shouldRetry(ctx, resp, err)
+	})
+}
+
+// prefixEnd returns the first key after all the keys starting with
+// prefix, for use as a range end
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// All keys
+	return []byte{0}
+}
+
+// etcdPair converts an etcd key
+func etcdPair(kv *api.EtcdKeyValue) pair {
+	return pair{key: string(kv.Key), value: kv.Value}
+}
+
+// get the key
+func (e *etcd) get(ctx context.Context, key string) (*pair, error) {
+	request := api.EtcdRangeRequest{Key: []byte(key)}
+	var result api.EtcdRangeResponse
+	err := e.call(ctx, "/v3/kv/range", &request, &result)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Kvs) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	p := etcdPair(&result.Kvs[0])
+	return &p, nil
+}
+
+// list all the keys starting with prefix
+func (e *etcd) list(ctx context.Context, prefix string) (pairs []pair, err error) {
+	request := api.EtcdRangeRequest{
+		Key:        []byte(prefix),
+		RangeEnd:   prefixEnd(prefix),
+		Limit:      etcdListChunk,
+		SortOrder:  "ASCEND",
+		SortTarget: "KEY",
+	}
+	if prefix == "" {
+		request.Key = []byte{0}
+	}
+	for {
+		var result api.EtcdRangeResponse
+		err = e.call(ctx, "/v3/kv/range", &request, &result)
+		if err != nil {
+			return nil, err
+		}
+		for i := range result.Kvs {
+			pairs = append(pairs, etcdPair(&result.Kvs[i]))
+		}
+		if !result.More || len(result.Kvs) == 0 {
+			return pairs, nil
+		}
+		// Carry on from just after the last key
+		request.Key = append(result.Kvs[len(result.Kvs)-1].Key, 0)
+	}
+}
+
+// put sets the value of the key
+func (e *etcd) put(ctx context.Context, p *pair) error {
+	request := api.EtcdPutRequest{Key: []byte(p.key), Value: p.value}
+	var result struct{}
+	return e.call(ctx, "/v3/kv/put", &request, &result)
+}
+
+// move the key from to p in one transaction
+func (e *etcd) move(ctx context.Context, from string, p *pair) error {
+	request := api.EtcdTxnRequest{
+		Success: []api.EtcdRequestOp{{
+			RequestPut: &api.EtcdPutRequest{Key: []byte(p.key), Value: p.value},
+		}, {
+			RequestDeleteRange: &api.EtcdDeleteRangeRequest{Key: []byte(from)},
+		}},
+	}
+	var result api.EtcdTxnResponse
+	err := e.call(ctx, "/v3/kv/txn", &request, &result)
+	if err != nil {
+		return err
+	}
+	if !result.Succeeded {
+		return errNotStored
+	}
+	return nil
+}
+
+// delete the key
+func (e *etcd) delete(ctx context.Context, key string) error {
+	request := api.EtcdDeleteRangeRequest{Key: []byte(key)}
+	var result api.EtcdDeleteRangeResponse
+	return e.call(ctx, "/v3/kv/deleterange", &request, &result)
+}
+
+// deletePrefix deletes all the keys starting with prefix
+func (e *etcd) deletePrefix(ctx context.Context, prefix string) error {
+	request := api.EtcdDeleteRangeRequest{Key: []byte(prefix), RangeEnd: prefixEnd(prefix)}
+	if prefix == "" {
+		request.Key = []byte{0}
+	}
+	var result api.EtcdDeleteRangeResponse
+	return e.call(ctx, "/v3/kv/deleterange", &request, &result)
+}
@@ -0,0 +1,754 @@
+// Package kvstore provides an interface to the key value stores of
+// Consul and etcd
+//
+// Each file is a key whose value is the contents of the file, so
+// they can be read by anything else using the store. Directories are
+// kept as keys ending in / as the Consul UI does, and are also
+// implied by the / in the keys of files put there by other tools.
+package kvstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/kvstore/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+
+	providerConsul = "consul"
+	providerEtcd   = "etcd"
+)
+
+// defaults for each provider
+var (
+	defaultURL = map[string]string{
+		providerConsul: "http://127.0.0.1:8500",
+		providerEtcd:   "http://127.0.0.1:2379",
+	}
+	defaultMaxSize = map[string]fs.SizeSuffix{
+		providerConsul: 512 * fs.Kibi, // the limit of Consul
+		providerEtcd:   1 * fs.Mebi,   // less than 1.5 MiB once base64 encoded
+	}
+)
+
+var errNotStored = errors.New("server didn't store the value")
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "kvstore",
+		Description: "Consul or etcd key value store",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:    fs.ConfigProvider,
+			Help:    "Key value store to use.",
+			Default: providerConsul,
+			Examples: []fs.OptionExample{{
+				Value: providerConsul,
+				Help:  "Consul KV store",
+			}, {
+				Value: providerEtcd,
+				Help:  "etcd v3 using its JSON gateway",
+			}},
+			Exclusive: true,
+		}, {
+			Name: "url",
+			Help: `URL of the server.
+
+Leave blank to use the local agent, "http://127.0.0.1:8500" for Consul
+or "http://127.0.0.1:2379" for etcd.`,
+		}, {
+			Name:       "token",
+			Help:       "ACL token.",
+			Provider:   providerConsul,
+			IsPassword: true,
+		}, {
+			Name:     "datacenter",
+			Help:     "Datacenter to use.\n\nLeave blank to use the datacenter of the agent.",
+			Provider: providerConsul,
+			Advanced: true,
+		}, {
+			Name:     "user",
+			Help:     "User name.\n\nLeave blank if authentication isn't enabled.",
+			Provider: providerEtcd,
+		}, {
+			Name:       "pass",
+			Help:       "Password.",
+			Provider:   providerEtcd,
+			IsPassword: true,
+		}, {
+			Name: "max_size",
+			Help: `Size of the largest file which can be stored.
+
+Leave as 0 to use the largest value the store allows by default,
+512 KiB for Consul and 1 MiB for etcd. Only set this if the limits of
+the server have been changed.`,
+			Default:  fs.SizeSuffix(0),
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			Default: (encoder.Base |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Provider   string               `config:"provider"`
+	URL        string               `config:"url"`
+	Token      string               `config:"token"`
+	Datacenter string               `config:"datacenter"`
+	User       string               `config:"user"`
+	Pass       string               `config:"pass"`
+	MaxSize    fs.SizeSuffix        `config:"max_size"`
+	Enc        encoder.MultiEncoder `config:"encoding"`
+}
+
+// pair is a key and its value
+type pair struct {
+	key     string
+	value   []byte
+	modTime time.Time // zero if not known
+}
+
+// store is the interface to a key value store
+type store interface {
+	// precision of the modification times stored
+	precision() time.Duration
+	// get the key, returning fs.ErrorObjectNotFound if not found
+	get(ctx context.Context, key string) (*pair, error)
+	// list all the keys starting with prefix
+	list(ctx context.Context, prefix string) ([]pair, error)
+	// put sets the value of the key
+	put(ctx context.Context, p *pair) error
+	// move the key from to p in one transaction
+	move(ctx context.Context, from string, p *pair) error
+	// delete the key
+	delete(ctx context.Context, key string) error
+	// deletePrefix deletes all the keys starting with prefix
+	deletePrefix(ctx context.Context, prefix string) error
+}
+
+// Fs represents a remote key value store
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	store    store        // the store
+	dirs     sync.Map     // prefixes of directories known to exist
+}
+
+// Object describes a key in the store
+//
+// The value is read when the key is listed so it is kept in the
+// object.
+type Object struct {
+	fs      *Fs       // what this object is part of
+	remote  string    // The remote path
+	value   []byte    // the contents of the file
+	modTime time.Time // modification time of the object
+}
+
+// ------------------------------------------------------------
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+//
+// etcd returns JSON errors and Consul plain text.
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error reading error out of body: %w", err)
+	}
+	errResponse := &api.Error{}
+	if json.Unmarshal(body, errResponse) != nil || errResponse.Message == "" {
+		errResponse.Message = strings.TrimSpace(string(body))
+		if errResponse.Message == "" || len(errResponse.Message) > 1024 {
+			errResponse.Message = resp.Status
+		}
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// int64ptr returns a pointer to i
+func int64ptr(i int64) *int64 {
+	return &i
+}
+
+// NewFs constructs an Fs from the path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := defaultURL[opt.Provider]; !ok {
+		return nil, fmt.Errorf("unknown provider %q", opt.Provider)
+	}
+	if opt.URL == "" {
+		opt.URL = defaultURL[opt.Provider]
+	}
+	if opt.MaxSize <= 0 {
+		opt.MaxSize = defaultMaxSize[opt.Provider]
+	}
+	if opt.Token != "" {
+		opt.Token, err = obscure.Reveal(opt.Token)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt token: %w", err)
+		}
+	}
+	f := &Fs{
+		name: name,
+		root: strings.Trim(path.Clean("/"+root), "/"),
+		opt:  *opt,
+	}
+	srv := rest.NewClient(fshttp.NewClient(ctx)).SetRoot(strings.TrimRight(opt.URL, "/"))
+	srv.SetErrorHandler(errorHandler)
+	pacer := fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant)))
+	switch opt.Provider {
+	case providerConsul:
+		f.store = newConsul(srv, pacer, opt)
+	case providerEtcd:
+		pass := ""
+		if opt.Pass != "" {
+			pass, err = obscure.Reveal(opt.Pass)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't decrypt password: %w", err)
+			}
+		}
+		f.store, err = newEtcd(ctx, srv, pacer, opt.User, pass)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	if f.root != "" {
+		_, err := f.store.get(ctx, f.key(""))
+		if err == nil {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		} else if err != fs.ErrorObjectNotFound {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("%s KV store at %s", f.opt.Provider, f.opt.URL)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return f.store.precision()
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.MD5)
+}
+
+// key returns the key for remote
+func (f *Fs) key(remote string) string {
+	return f.opt.Enc.FromStandardPath(path.Join(f.root, remote))
+}
+
+// prefix returns the prefix of the keys in dir
+func (f *Fs) prefix(dir string) string {
+	key := f.key(dir)
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+// newObject makes an Object for remote from p
+func (f *Fs) newObject(remote string, p *pair) *Object {
+	return &Object{
+		fs:      f,
+		remote:  remote,
+		value:   p.value,
+		modTime: p.modTime,
+	}
+}
+
+// list the keys in dir calling fn for each entry found
+//
+// If recurse is set the entries in subdirectories are found too.
+func (f *Fs) list(ctx context.Context, dir string, recurse bool, fn func(fs.DirEntry)) error {
+	prefix := f.prefix(dir)
+	pairs, err := f.store.list(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	if len(pairs) == 0 && prefix != "" {
+		return fs.ErrorDirNotFound
+	}
+	seen := map[string]struct{}{}
+	addDir := func(remote string) {
+		if _, ok := seen[remote]; !ok {
+			seen[remote] = struct{}{}
+			fn(fs.NewDir(remote, time.Time{}))
+		}
+	}
+	for i := range pairs {
+		p := &pairs[i]
+		if !strings.HasPrefix(p.key, prefix) {
+			continue
+		}
+		parts := strings.Split(p.key[len(prefix):], "/")
+		remote := dir
+		for i, part := range parts {
+			if part == "" {
+				// Directory markers and keys with // in
+				break
+			}
+			remote = path.Join(remote, f.opt.Enc.ToStandardName(part))
+			if i == len(parts)-1 {
+				fn(f.newObject(remote, p))
+			} else {
+				addDir(remote)
+				if !recurse {
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// List the objects and directories in dir into entries. The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.list(ctx, dir, false, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	return entries, err
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+//
+// Don't implement this unless you have a more efficient way
+// of listing recursively than doing a directory traversal.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	var entries fs.DirEntries
+	err := f.list(ctx, dir, true, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return err
+	}
+	return callback(entries)
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	p, err := f.store.get(ctx, f.key(remote))
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, p), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory and its parents
+//
+// Directories are kept as keys ending in / so they stay when they are
+// empty.
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	for {
+		prefix := f.prefix(dir)
+		if prefix == "" {
+			return nil
+		}
+		if _, ok := f.dirs.Load(prefix); ok {
+			return nil
+		}
+		_, err := f.store.get(ctx, prefix)
+		if err == fs.ErrorObjectNotFound {
+			err = f.store.put(ctx, &pair{key: prefix})
+			if err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else {
+			// Parents will exist already
+			f.dirs.Store(prefix, struct{}{})
+			return nil
+		}
+		f.dirs.Store(prefix, struct{}{})
+		if dir == "" {
+			return nil
+		}
+		dir = path.Dir(dir)
+		if dir == "." {
+			dir = ""
+		}
+	}
+}
+
+// Rmdir removes the directory if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	prefix := f.prefix(dir)
+	pairs, err := f.store.list(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	if len(pairs) == 0 {
+		if prefix == "" {
+			return nil
+		}
+		return fs.ErrorDirNotFound
+	}
+	if len(pairs) > 1 || pairs[0].key != prefix {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	f.dirs.Delete(prefix)
+	return f.store.delete(ctx, prefix)
+}
+
+// Purge deletes all the files in the directory
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	prefix := f.prefix(dir)
+	if prefix != "" {
+		pairs, err := f.store.list(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		if len(pairs) == 0 {
+			return fs.ErrorDirNotFound
+		}
+	}
+	f.dirs.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), prefix) {
+			f.dirs.Delete(key)
+		}
+		return true
+	})
+	return f.store.deletePrefix(ctx, prefix)
+}
+
+// mkParentDir makes the directory remote is in
+func (f *Fs) mkParentDir(ctx context.Context, remote string) error {
+	dir := path.Dir(remote)
+	if dir == "." {
+		dir = ""
+	}
+	return f.Mkdir(ctx, dir)
+}
+
+// sameStore returns whether src uses the same store as f
+func (f *Fs) sameStore(src *Fs) bool {
+	return f.opt.Provider == src.opt.Provider &&
+		f.opt.URL == src.opt.URL &&
+		f.opt.Datacenter == src.opt.Datacenter
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// As the value is already known this writes it to the new key
+// without reading the source again.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || !f.sameStore(srcObj.fs) {
+		fs.Debugf(src, "Can't copy - not same store")
+		return nil, fs.ErrorCantCopy
+	}
+	err := f.mkParentDir(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	p := &pair{key: f.key(remote), value: srcObj.value, modTime: srcObj.modTime}
+	err = f.store.put(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, p), nil
+}
+
+// Move src to this remote using server-side move operations.
+//
+// The new key is written and the old one deleted in one transaction.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || !f.sameStore(srcObj.fs) {
+		fs.Debugf(src, "Can't move - not same store")
+		return nil, fs.ErrorCantMove
+	}
+	err := f.mkParentDir(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	p := &pair{key: f.key(remote), value: srcObj.value, modTime: srcObj.modTime}
+	err = f.store.move(ctx, srcObj.fs.key(srcObj.remote), p)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, p), nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the MD5 of the value
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t != hash.MD5 {
+		return "", hash.ErrUnsupported
+	}
+	sum := md5.Sum(o.value)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return int64(len(o.value))
+}
+
+// ModTime returns the modification time of the object
+//
+// If it isn't known the epoch is returned.
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.modTime.IsZero() {
+		return time.Unix(0, 0)
+	}
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	if o.fs.Precision() == fs.ModTimeNotSupported {
+		return fs.ErrorCantSetModTime
+	}
+	err := o.fs.store.put(ctx, &pair{key: o.fs.key(o.remote), value: o.value, modTime: modTime})
+	if err != nil {
+		return err
+	}
+	o.modTime = modTime
+	return nil
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.Size())
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	data := o.value
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	data = data[offset:]
+	if limit >= 0 && limit < int64(len(data)) {
+		data = data[:limit]
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The whole file is read into memory as it is stored as a single value.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	maxSize := int64(o.fs.opt.MaxSize)
+	if size := src.Size(); size > maxSize {
+		return fserrors.NoRetryError(fmt.Errorf("file too big to store: %d bytes is more than max_size %v", size, o.fs.opt.MaxSize))
+	}
+	value, err := ioutil.ReadAll(io.LimitReader(in, maxSize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(value)) > maxSize {
+		return fserrors.NoRetryError(fmt.Errorf("file too big to store: more than max_size %v", o.fs.opt.MaxSize))
+	}
+	err = o.fs.mkParentDir(ctx, o.remote)
+	if err != nil {
+		return err
+	}
+	p := &pair{key: o.fs.key(o.remote), value: value}
+	if o.fs.Precision() != fs.ModTimeNotSupported {
+		p.modTime = src.ModTime(ctx)
+	}
+	err = o.fs.store.put(ctx, p)
+	if err != nil {
+		return err
+	}
+	o.value = p.value
+	o.modTime = p.modTime
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return o.fs.store.delete(ctx, o.fs.key(o.remote))
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.ListRer     = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+	_ store          = (*consul)(nil)
+	_ store          = (*etcd)(nil)
+)
@@ -0,0 +1,380 @@
+package kvstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/kvstore/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStore is an in memory key value store
+type testStore struct {
+	mu    sync.Mutex
+	keys  map[string]api.ConsulPair
+	token string // current etcd token
+	auths int    // number of times logged in
+}
+
+func newTestStore() *testStore {
+	return &testStore{keys: map[string]api.ConsulPair{}}
+}
+
+// sorted returns the keys for which match is true in order
+func (s *testStore) sorted(match func(key string) bool) (pairs []api.ConsulPair) {
+	for key, p := range s.keys {
+		if match(key) {
+			pairs = append(pairs, p)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs
+}
+
+// deletePrefix deletes all the keys starting with prefix
+func (s *testStore) deletePrefix(prefix string) {
+	for key := range s.keys {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.keys, key)
+		}
+	}
+}
+
+// writeJSON writes v as the response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// consul serves the parts of the Consul API used
+func (s *testStore) consul(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Header.Get("X-Consul-Token") != "secret" {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+	_, recurse := r.URL.Query()["recurse"]
+	if r.URL.Path == "/v1/txn" {
+		var ops []api.ConsulTxnOp
+		if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, op := range ops {
+			switch op.KV.Verb {
+			case "set":
+				s.keys[op.KV.Key] = api.ConsulPair{Key: op.KV.Key, Value: op.KV.Value, Flags: op.KV.Flags}
+			case "delete":
+				delete(s.keys, op.KV.Key)
+			}
+		}
+		writeJSON(w, api.ConsulTxnResponse{})
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	switch r.Method {
+	case "GET":
+		pairs := s.sorted(func(k string) bool {
+			return k == key || (recurse && strings.HasPrefix(k, key))
+		})
+		if len(pairs) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, pairs)
+	case "PUT":
+		value, _ := ioutil.ReadAll(r.Body)
+		flags, _ := strconv.ParseUint(r.URL.Query().Get("flags"), 10, 64)
+		if len(value) > 512*1024 {
+			http.Error(w, "Value exceeds 524288 byte limit", http.StatusRequestEntityTooLarge)
+			return
+		}
+		s.keys[key] = api.ConsulPair{Key: key, Value: value, Flags: flags}
+		writeJSON(w, true)
+	case "DELETE":
+		if recurse {
+			s.deletePrefix(key)
+		} else {
+			delete(s.keys, key)
+		}
+		writeJSON(w, true)
+	default:
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+	}
+}
+
+// inRange returns whether key is in the range of keys given
+func inRange(key string, start, end []byte) bool {
+	if len(end) == 0 {
+		return key == string(start)
+	}
+	if key < string(start) {
+		return false
+	}
+	return (len(end) == 1 && end[0] == 0) || key < string(end)
+}
+
+// etcd serves the parts of the etcd JSON gateway used
+func (s *testStore) etcd(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path == "/v3/auth/authenticate" {
+		var req api.EtcdAuthenticateRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Name != "user" || req.Password != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, api.Error{Message: "etcdserver: authentication failed, invalid user ID or password", Code: 3})
+			return
+		}
+		s.auths++
+		s.token = "token" + strconv.Itoa(s.auths)
+		writeJSON(w, api.EtcdAuthenticateResponse{Token: s.token})
+		return
+	}
+	if r.Header.Get("Authorization") != s.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, api.Error{Message: "etcdserver: invalid auth token", Code: etcdUnauthenticated})
+		return
+	}
+	put := func(req *api.EtcdPutRequest) {
+		s.keys[string(req.Key)] = api.ConsulPair{Key: string(req.Key), Value: req.Value}
+	}
+	deleteRange := func(req *api.EtcdDeleteRangeRequest) (deleted int64) {
+		for key := range s.keys {
+			if inRange(key, req.Key, req.RangeEnd) {
+				delete(s.keys, key)
+				deleted++
+			}
+		}
+		return deleted
+	}
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		var req api.EtcdRangeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		pairs := s.sorted(func(key string) bool {
+			return inRange(key, req.Key, req.RangeEnd)
+		})
+		var resp api.EtcdRangeResponse
+		resp.Count = int64(len(pairs))
+		if req.Limit > 0 && int64(len(pairs)) > req.Limit {
+			pairs = pairs[:req.Limit]
+			resp.More = true
+		}
+		for _, p := range pairs {
+			resp.Kvs = append(resp.Kvs, api.EtcdKeyValue{Key: []byte(p.Key), Value: p.Value})
+		}
+		writeJSON(w, resp)
+	case "/v3/kv/put":
+		var req api.EtcdPutRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		put(&req)
+		writeJSON(w, struct{}{})
+	case "/v3/kv/deleterange":
+		var req api.EtcdDeleteRangeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		writeJSON(w, api.EtcdDeleteRangeResponse{Deleted: deleteRange(&req)})
+	case "/v3/kv/txn":
+		var req api.EtcdTxnRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for _, op := range req.Success {
+			if op.RequestPut != nil {
+				put(op.RequestPut)
+			}
+			if op.RequestDeleteRange != nil {
+				deleteRange(op.RequestDeleteRange)
+			}
+		}
+		writeJSON(w, api.EtcdTxnResponse{Succeeded: true})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestServer starts a test server for provider
+func newTestServer(t *testing.T, provider string) (*testStore, *httptest.Server) {
+	s := newTestStore()
+	handler := s.consul
+	if provider == providerEtcd {
+		handler = s.etcd
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// testConfig returns the config to use the test server ts
+func testConfig(provider string, ts *httptest.Server) configmap.Simple {
+	m := configmap.Simple{
+		"provider": provider,
+		"url":      ts.URL,
+	}
+	if provider == providerConsul {
+		m["token"] = obscure.MustObscure("secret")
+	} else {
+		m["user"] = "user"
+		m["pass"] = obscure.MustObscure("secret")
+	}
+	return m
+}
+
+// runLocal runs the integration tests against a test server
+func runLocal(t *testing.T, provider string) {
+	if *fstest.RemoteName != "" {
+		t.Skip("skipping as -remote is set")
+	}
+	_, ts := newTestServer(t, provider)
+	name := "TestKVStoreLocal" + provider
+	var extra []fstests.ExtraConfigItem
+	for k, v := range testConfig(provider, ts) {
+		extra = append(extra, fstests.ExtraConfigItem{Name: name, Key: k, Value: v})
+	}
+	extra = append(extra, fstests.ExtraConfigItem{Name: name, Key: "type", Value: "kvstore"})
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:  name + ":",
+		NilObject:   (*Object)(nil),
+		ExtraConfig: extra,
+		QuickTestOK: true,
+	})
+}
+
+func TestLocalConsul(t *testing.T) {
+	runLocal(t, providerConsul)
+}
+
+func TestLocalEtcd(t *testing.T) {
+	runLocal(t, providerEtcd)
+}
+
+// newTestFs makes an Fs using the test server
+func newTestFs(t *testing.T, provider string) (*testStore, *Fs) {
+	s, ts := newTestServer(t, provider)
+	f, err := NewFs(context.Background(), t.Name(), "dir", testConfig(provider, ts))
+	require.NoError(t, err)
+	return s, f.(*Fs)
+}
+
+func TestMaxSize(t *testing.T) {
+	ctx := context.Background()
+	_, f := newTestFs(t, providerConsul)
+	data := make([]byte, 512*1024+1)
+
+	src := object.NewStaticObjectInfo("big", time.Now(), int64(len(data)), true, nil, nil)
+	_, err := f.Put(ctx, bytes.NewReader(data), src)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too big")
+
+	src = object.NewStaticObjectInfo("big", time.Now(), -1, true, nil, nil)
+	_, err = f.Put(ctx, bytes.NewReader(data), src)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too big")
+
+	src = object.NewStaticObjectInfo("fits", time.Now(), -1, true, nil, nil)
+	o, err := f.Put(ctx, bytes.NewReader(data[1:]), src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)-1), o.Size())
+}
+
+func TestConsulKeys(t *testing.T) {
+	ctx := context.Background()
+	s, f := newTestFs(t, providerConsul)
+	modTime := time.Date(2022, 5, 6, 7, 8, 9, 10, time.UTC)
+	src := object.NewStaticObjectInfo("sub/cert.pem", modTime, 4, true, nil, nil)
+	_, err := f.Put(ctx, bytes.NewReader([]byte("cert")), src)
+	require.NoError(t, err)
+	require.NoError(t, f.Mkdir(ctx, "empty"))
+
+	// The value is stored as is with the time in the flags
+	p := s.keys["dir/sub/cert.pem"]
+	assert.Equal(t, "cert", string(p.Value))
+	assert.Equal(t, uint64(modTime.UnixNano()), p.Flags)
+	_, ok := s.keys["dir/empty/"]
+	assert.True(t, ok)
+
+	// Keys made by other tools have no time
+	s.keys["dir/other"] = api.ConsulPair{Key: "dir/other", Value: []byte("x")}
+	o, err := f.NewObject(ctx, "other")
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(0, 0), o.ModTime(ctx))
+	sum, err := o.Hash(ctx, hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "9dd4e461268c8034f5c8564e155c67a6", sum)
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "[empty other sub]", fmtEntries(entries))
+}
+
+func TestEtcdReauthenticate(t *testing.T) {
+	ctx := context.Background()
+	s, f := newTestFs(t, providerEtcd)
+	assert.Equal(t, 1, s.auths)
+	s.keys["dir/file"] = api.ConsulPair{Key: "dir/file"}
+
+	// Expire the token
+	s.mu.Lock()
+	s.token = "expired"
+	s.mu.Unlock()
+
+	_, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.auths)
+}
+
+func TestEtcdBadPassword(t *testing.T) {
+	_, ts := newTestServer(t, providerEtcd)
+	m := testConfig(providerEtcd, ts)
+	m["pass"] = obscure.MustObscure("wrong")
+	_, err := NewFs(context.Background(), t.Name(), "", m)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "authentication failed")
+}
+
+func TestEtcdListPages(t *testing.T) {
+	ctx := context.Background()
+	s, f := newTestFs(t, providerEtcd)
+	for i := 0; i < etcdListChunk*2+5; i++ {
+		key := "dir/file" + strconv.Itoa(i)
+		s.keys[key] = api.ConsulPair{Key: key}
+	}
+	s.keys["dir0"] = api.ConsulPair{Key: "dir0"}
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, etcdListChunk*2+5, len(entries))
+}
+
+func TestPrefixEnd(t *testing.T) {
+	assert.Equal(t, []byte("dir0"), prefixEnd("dir/"))
+	assert.Equal(t, []byte("b"), prefixEnd("a\xff"))
+	assert.Equal(t, []byte{0}, prefixEnd("\xff\xff"))
+	assert.Equal(t, []byte{0}, prefixEnd(""))
+}
+
+// fmtEntries returns the names of entries
+func fmtEntries(entries fs.DirEntries) string {
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	sort.Strings(names)
+	return "[" + strings.Join(names, " ") + "]"
+}
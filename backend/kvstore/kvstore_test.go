@@ -0,0 +1,17 @@
+// Test Consul and etcd filesystem interface
+package kvstore_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/kvstore"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestKVStore:",
+		NilObject:  (*kvstore.Object)(nil),
+	})
+}
@@ -4,6 +4,8 @@ package local
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -27,6 +29,7 @@ import (
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/file"
 	"github.com/rclone/rclone/lib/readers"
+	"github.com/rclone/rclone/lib/rollsum"
 	"golang.org/x/text/unicode/norm"
 )
 
@@ -1379,6 +1382,102 @@ func (o *Object) writeMetadata(metadata fs.Metadata) (err error) {
 	return err
 }
 
+// SetMetadata merges metadata into the metadata of the file
+func (o *Object) SetMetadata(ctx context.Context, metadata fs.Metadata) error {
+	err := o.writeMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	return o.lstat()
+}
+
+// inPlaceFile is a new local file opened by OpenInPlace
+type inPlaceFile struct {
+	*os.File
+	o *Object
+}
+
+// Close the new file and rename it over the original file
+func (f *inPlaceFile) Close() error {
+	err := f.File.Close()
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	err = os.Rename(f.Name(), f.o.path)
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	f.o.clearHashCache()
+	return nil
+}
+
+// Abort removes the new file leaving the original file unchanged
+func (f *inPlaceFile) Abort() error {
+	_ = f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// OpenInPlace opens a new empty file of size bytes for random access
+// writes. It is made in the same directory as the file, with the same
+// permissions, and renamed over it when it is closed.
+func (o *Object) OpenInPlace(ctx context.Context, size int64) (_ fs.InPlaceFile, err error) {
+	if o.translatedLink {
+		return nil, errors.New("can't open a symlink for writing in place")
+	}
+	info, err := os.Stat(o.path)
+	if err != nil {
+		return nil, err
+	}
+	out, err := ioutil.TempFile(filepath.Dir(o.path), "."+filepath.Base(o.path)+".*.partial")
+	if err != nil {
+		return nil, err
+	}
+	if err = out.Chmod(info.Mode().Perm()); err == nil {
+		err = out.Truncate(size)
+	}
+	if err != nil {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+		return nil, err
+	}
+	return &inPlaceFile{File: out, o: o}, nil
+}
+
+// BlockHashes returns the checksums of the file split into blocks of
+// blockSize bytes
+func (o *Object) BlockHashes(ctx context.Context, blockSize int64) (sums []fs.BlockSum, err error) {
+	if o.translatedLink {
+		return nil, errors.New("can't read block hashes of a symlink")
+	}
+	in, err := file.Open(o.path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	buf := make([]byte, blockSize)
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		n, err := io.ReadFull(in, buf)
+		if n > 0 {
+			sum := md5.Sum(buf[:n])
+			sums = append(sums, fs.BlockSum{
+				Weak:   rollsum.Checksum(buf[:n]),
+				Strong: hex.EncodeToString(sum[:]),
+			})
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return sums, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
 func cleanRootPath(s string, noUNC bool, enc encoder.MultiEncoder) string {
 	if runtime.GOOS == "windows" {
 		if !filepath.IsAbs(s) && !strings.HasPrefix(s, "\\") {
@@ -1419,4 +1518,7 @@ var (
 	_ fs.OpenWriterAter = &Fs{}
 	_ fs.Object         = &Object{}
 	_ fs.Metadataer     = &Object{}
+	_ fs.SetMetadataer  = &Object{}
+	_ fs.InPlaceWriter  = &Object{}
+	_ fs.BlockHasher    = &Object{}
 )
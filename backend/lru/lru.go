@@ -0,0 +1,450 @@
+// Package lru provides an in memory remote with a size limit which
+// evicts the least recently used files
+package lru
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "lru",
+		Description: "In memory storage with LRU eviction",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "max_size",
+			Help: `Maximum total size of the files stored.
+
+When storing a file would take the total over this, the least
+recently read or written files are removed until it fits. Files
+bigger than this can't be stored.
+
+Set to 0 for no limit.`,
+			Default: fs.SizeSuffix(128 * 1024 * 1024),
+		}, {
+			Name: "ttl",
+			Help: `How long files are kept for after they are written.
+
+Files are removed when they are this old, whether they have been used
+or not.
+
+Set to 0 to keep files until they are evicted.`,
+			Default: fs.Duration(0),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	MaxSize fs.SizeSuffix `config:"max_size"`
+	TTL     fs.Duration   `config:"ttl"`
+}
+
+// Fs represents a remote in memory store
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on if any
+	opt      Options      // parsed config options
+	features *fs.Features // optional features
+	store    *store       // where the files are kept
+}
+
+// Object describes a file in the store
+type Object struct {
+	fs     *Fs    // what this object is part of
+	remote string // The remote path
+	file   *file  // the file data and metadata
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("LRU memory root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// absPath returns the path in the store of remote
+func (f *Fs) absPath(remote string) string {
+	return strings.Trim(path.Join(f.root, remote), "/")
+}
+
+// NewFs constructs an Fs from the path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	root = strings.Trim(path.Clean("/"+root), "/")
+	f := &Fs{
+		name:  name,
+		root:  root,
+		opt:   *opt,
+		store: getStore(name, opt),
+	}
+	f.features = (&fs.Features{
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+	if root != "" && f.store.peek(root) != nil {
+		f.root = parent(root)
+		// return an error with an fs which points to the parent
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// newObject makes an object from a remote and a file
+func (f *Fs) newObject(remote string, file *file) *Object {
+	return &Object{fs: f, remote: remote, file: file}
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	file := f.store.peek(f.absPath(remote))
+	if file == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.newObject(remote, file), nil
+}
+
+// list the entries in dir, recursively if recurse is set
+func (f *Fs) list(ctx context.Context, dir string, recurse bool) (entries fs.DirEntries, err error) {
+	found, err := f.store.list(f.absPath(dir), recurse)
+	if err != nil {
+		return nil, err
+	}
+	prefix := f.root + "/"
+	if f.root == "" {
+		prefix = ""
+	}
+	for _, e := range found {
+		remote := e.path[len(prefix):]
+		if e.file == nil {
+			entries = append(entries, fs.NewDir(remote, time.Time{}))
+		} else {
+			entries = append(entries, f.newObject(remote, e.file))
+		}
+	}
+	return entries, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	return f.list(ctx, dir, false)
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	entries, err := f.list(ctx, dir, true)
+	if err != nil {
+		return err
+	}
+	return callback(entries)
+}
+
+// Put the object into the store
+//
+// Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := f.newObject(src.Remote(), nil)
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory and its parents
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.store.mkdir(f.absPath(dir))
+}
+
+// Rmdir removes the directory
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.store.rmdir(f.absPath(dir))
+}
+
+// Precision of the remote
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash sets.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.MD5)
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.store != f.store {
+		fs.Debugf(src, "Can't copy - not same remote")
+		return nil, fs.ErrorCantCopy
+	}
+	file, err := f.store.copy(srcObj.fs.absPath(srcObj.remote), f.absPath(remote))
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, file), nil
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.store != f.store {
+		fs.Debugf(src, "Can't move - not same remote")
+		return nil, fs.ErrorCantMove
+	}
+	file, err := f.store.move(srcObj.fs.absPath(srcObj.remote), f.absPath(remote))
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, file), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok || srcFs.store != f.store {
+		fs.Debugf(srcFs, "Can't move directory - not same remote")
+		return fs.ErrorCantDirMove
+	}
+	return f.store.moveDir(srcFs.absPath(srcRemote), f.absPath(dstRemote))
+}
+
+// Purge deletes all the files and directories in dir
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	return f.store.purge(f.absPath(dir))
+}
+
+// About gets quota information
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	size, files, maxSize := f.store.usage()
+	usage := &fs.Usage{
+		Used:    fs.NewUsageValue(size),
+		Objects: fs.NewUsageValue(files),
+	}
+	if maxSize > 0 {
+		usage.Total = fs.NewUsageValue(maxSize)
+		usage.Free = fs.NewUsageValue(maxSize - size)
+	}
+	return usage, nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Remote()
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t != hash.MD5 {
+		return "", hash.ErrUnsupported
+	}
+	return o.file.hash, nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return int64(len(o.file.data))
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.file.modTime
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	if !o.fs.store.setModTime(o.fs.absPath(o.remote), modTime) {
+		return fs.ErrorObjectNotFound
+	}
+	o.file.modTime = modTime
+	return nil
+}
+
+// Storable returns if this object is storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+//
+// This marks the object as recently used.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	file := o.fs.store.get(o.fs.absPath(o.remote))
+	if file == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	o.file = file
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.RangeOption:
+			offset, limit = x.Decode(int64(len(file.data)))
+		case *fs.SeekOption:
+			offset = x.Offset
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if offset > int64(len(file.data)) {
+		offset = int64(len(file.data))
+	}
+	data := file.data[offset:]
+	if limit >= 0 && limit < int64(len(data)) {
+		data = data[:limit]
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("failed to update lru object: %w", err)
+	}
+	sum := md5.Sum(data)
+	newFile := &file{
+		path:     o.fs.absPath(o.remote),
+		data:     data,
+		hash:     hex.EncodeToString(sum[:]),
+		modTime:  src.ModTime(ctx),
+		mimeType: fs.MimeType(ctx, src),
+	}
+	err = o.fs.store.put(newFile)
+	if err != nil {
+		return fserrors.NoRetryError(err)
+	}
+	o.file = newFile
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	if !o.fs.store.delete(o.fs.absPath(o.remote)) {
+		return fs.ErrorObjectNotFound
+	}
+	return nil
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType(ctx context.Context) string {
+	return o.file.mimeType
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = &Fs{}
+	_ fs.Copier      = &Fs{}
+	_ fs.Mover       = &Fs{}
+	_ fs.DirMover    = &Fs{}
+	_ fs.Purger      = &Fs{}
+	_ fs.PutStreamer = &Fs{}
+	_ fs.ListRer     = &Fs{}
+	_ fs.Abouter     = &Fs{}
+	_ fs.Object      = &Object{}
+	_ fs.MimeTyper   = &Object{}
+)
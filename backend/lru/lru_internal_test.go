@@ -0,0 +1,142 @@
+package lru
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs makes an Fs with its own store and a fake clock which
+// can be moved on by calling the returned function
+func newTestFs(t *testing.T, maxSize, ttl string) (f *Fs, tick func(time.Duration)) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldTimeNow := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = oldTimeNow })
+	m := configmap.Simple{"max_size": maxSize, "ttl": ttl}
+	fsys, err := NewFs(context.Background(), t.Name(), "", m)
+	require.NoError(t, err)
+	return fsys.(*Fs), func(d time.Duration) { now = now.Add(d) }
+}
+
+// put a file of size bytes at remote
+func put(t *testing.T, f *Fs, remote string, size int) error {
+	ctx := context.Background()
+	src := object.NewStaticObjectInfo(remote, time.Now(), int64(size), true, nil, nil)
+	_, err := f.Put(ctx, strings.NewReader(strings.Repeat("x", size)), src)
+	return err
+}
+
+// read the file at remote
+func read(t *testing.T, f *Fs, remote string) {
+	ctx := context.Background()
+	o, err := f.NewObject(ctx, remote)
+	require.NoError(t, err)
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+}
+
+// names returns the names of the files in the root of f
+func names(t *testing.T, f *Fs) (out []string) {
+	entries, err := f.List(context.Background(), "")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		if _, ok := entry.(fs.Object); ok {
+			out = append(out, entry.Remote())
+		}
+	}
+	return out
+}
+
+func TestEvict(t *testing.T) {
+	f, _ := newTestFs(t, "300B", "0")
+	require.NoError(t, put(t, f, "a", 100))
+	require.NoError(t, put(t, f, "b", 100))
+	require.NoError(t, put(t, f, "c", 100))
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, names(t, f))
+
+	// Reading a makes b the least recently used
+	read(t, f, "a")
+	require.NoError(t, put(t, f, "d", 100))
+	assert.ElementsMatch(t, []string{"a", "c", "d"}, names(t, f))
+
+	// A big file evicts as many as necessary
+	require.NoError(t, put(t, f, "e", 250))
+	assert.ElementsMatch(t, []string{"e"}, names(t, f))
+
+	usage, err := f.About(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(250), *usage.Used)
+	assert.Equal(t, int64(300), *usage.Total)
+	assert.Equal(t, int64(50), *usage.Free)
+	assert.Equal(t, int64(1), *usage.Objects)
+
+	// Replacing a file doesn't count it twice
+	require.NoError(t, put(t, f, "e", 200))
+	require.NoError(t, put(t, f, "f", 100))
+	assert.ElementsMatch(t, []string{"e", "f"}, names(t, f))
+}
+
+func TestTooBig(t *testing.T) {
+	f, _ := newTestFs(t, "100B", "0")
+	require.NoError(t, put(t, f, "a", 50))
+	err := put(t, f, "b", 101)
+	require.Error(t, err)
+	assert.True(t, fserrors.IsNoRetryError(err))
+	assert.ElementsMatch(t, []string{"a"}, names(t, f))
+}
+
+func TestTTL(t *testing.T) {
+	f, tick := newTestFs(t, "0", "1m")
+	require.NoError(t, put(t, f, "a", 1))
+	tick(30 * time.Second)
+	require.NoError(t, put(t, f, "b", 1))
+	tick(20 * time.Second)
+
+	// Reading doesn't extend the life of a
+	read(t, f, "a")
+	tick(10 * time.Second)
+	assert.ElementsMatch(t, []string{"b"}, names(t, f))
+	_, err := f.NewObject(context.Background(), "a")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// Rewriting does
+	tick(20 * time.Second)
+	require.NoError(t, put(t, f, "b", 1))
+	tick(50 * time.Second)
+	assert.ElementsMatch(t, []string{"b"}, names(t, f))
+	tick(10 * time.Second)
+	assert.Empty(t, names(t, f))
+}
+
+func TestEvictKeepsDirectories(t *testing.T) {
+	f, _ := newTestFs(t, "100B", "0")
+	require.NoError(t, put(t, f, "dir/a", 100))
+	require.NoError(t, put(t, f, "b", 100))
+	entries, err := f.List(context.Background(), "dir")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOpenEvicted(t *testing.T) {
+	f, _ := newTestFs(t, "100B", "0")
+	ctx := context.Background()
+	require.NoError(t, put(t, f, "a", 100))
+	o, err := f.NewObject(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, put(t, f, "b", 100))
+	_, err = o.Open(ctx)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
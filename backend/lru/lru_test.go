@@ -0,0 +1,17 @@
+// Test LRU memory filesystem interface
+package lru
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:  ":lru:",
+		NilObject:   (*Object)(nil),
+		QuickTestOK: true,
+	})
+}
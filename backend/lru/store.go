@@ -0,0 +1,436 @@
+package lru
+
+import (
+	"container/list"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// timeNow returns the current time - replaced in the tests
+var timeNow = time.Now
+
+// errTooBig is returned when a file can never fit in the store
+var errTooBig = errors.New("file is bigger than max_size")
+
+// stores holds the store for each remote name, so they persist
+// between uses of the remote in the same process
+var stores = struct {
+	mu sync.Mutex
+	m  map[string]*store
+}{
+	m: make(map[string]*store),
+}
+
+// getStore returns the store for the remote called name, making it
+// if necessary, and applies the limits in opt to it
+func getStore(name string, opt *Options) *store {
+	stores.mu.Lock()
+	defer stores.mu.Unlock()
+	s := stores.m[name]
+	if s == nil {
+		s = newStore()
+		stores.m[name] = s
+	}
+	s.setLimits(int64(opt.MaxSize), time.Duration(opt.TTL))
+	return s
+}
+
+// file is a single file in the store
+type file struct {
+	path     string        // absolute path in the store
+	data     []byte        // contents - never modified once stored
+	hash     string        // MD5 of data
+	modTime  time.Time     // modification time
+	mimeType string        // mime type if known
+	expires  time.Time     // when the file expires or zero for never
+	used     *list.Element // position in store.used
+	written  *list.Element // position in store.written
+}
+
+// store is an in memory tree of files and directories which evicts
+// the least recently used files to keep under maxSize and expires
+// files ttl after they were written
+type store struct {
+	mu      sync.Mutex
+	maxSize int64               // max total size of the files or 0 for no limit
+	ttl     time.Duration       // how long files live for or 0 for forever
+	size    int64               // total size of the files
+	files   map[string]*file    // files by absolute path
+	dirs    map[string]struct{} // directories by absolute path, not including the root
+	used    *list.List          // files, most recently used at the front
+	written *list.List          // files, most recently written at the front
+}
+
+// newStore makes an empty store
+func newStore() *store {
+	return &store{
+		files:   make(map[string]*file),
+		dirs:    make(map[string]struct{}),
+		used:    list.New(),
+		written: list.New(),
+	}
+}
+
+// setLimits changes the limits of the store, evicting files which
+// no longer fit
+func (s *store) setLimits(maxSize int64, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxSize = maxSize
+	s.ttl = ttl
+	s.evict()
+}
+
+// remove the file from the store
+//
+// Call with the lock held
+func (s *store) remove(f *file) {
+	s.used.Remove(f.used)
+	s.written.Remove(f.written)
+	delete(s.files, f.path)
+	s.size -= int64(len(f.data))
+}
+
+// expire removes files which have outlived the ttl
+//
+// Call with the lock held
+func (s *store) expire() {
+	now := timeNow()
+	for e := s.written.Back(); e != nil; e = s.written.Back() {
+		f := e.Value.(*file)
+		if f.expires.IsZero() || now.Before(f.expires) {
+			break
+		}
+		fs.Debugf(nil, "lru: expiring %q", f.path)
+		s.remove(f)
+	}
+}
+
+// evict removes the least recently used files until the store is
+// under maxSize
+//
+// Call with the lock held
+func (s *store) evict() {
+	s.expire()
+	if s.maxSize <= 0 {
+		return
+	}
+	for s.size > s.maxSize {
+		f := s.used.Back().Value.(*file)
+		fs.Debugf(nil, "lru: evicting %q to keep under max_size", f.path)
+		s.remove(f)
+	}
+}
+
+// get the file at absPath marking it as used, or return nil
+func (s *store) get(absPath string) *file {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	f := s.files[absPath]
+	if f != nil {
+		s.used.MoveToFront(f.used)
+	}
+	return f
+}
+
+// peek returns the file at absPath without marking it as used, or nil
+func (s *store) peek(absPath string) *file {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	return s.files[absPath]
+}
+
+// mkdirAll makes dir and its parents
+//
+// Call with the lock held
+func (s *store) mkdirAll(dir string) {
+	for dir != "" {
+		s.dirs[dir] = struct{}{}
+		dir = parent(dir)
+	}
+}
+
+// put stores f replacing any file at the same path, evicting files
+// to make room if necessary
+func (s *store) put(f *file) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	size := int64(len(f.data))
+	if s.maxSize > 0 && size > s.maxSize {
+		return errTooBig
+	}
+	if s.ttl > 0 {
+		f.expires = timeNow().Add(s.ttl)
+	}
+	if old := s.files[f.path]; old != nil {
+		s.remove(old)
+	}
+	s.mkdirAll(parent(f.path))
+	s.files[f.path] = f
+	f.used = s.used.PushFront(f)
+	f.written = s.written.PushFront(f)
+	s.size += size
+	s.evict()
+	return nil
+}
+
+// delete the file at absPath returning false if it wasn't found
+func (s *store) delete(absPath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	f := s.files[absPath]
+	if f == nil {
+		return false
+	}
+	s.remove(f)
+	return true
+}
+
+// setModTime sets the modification time of the file at absPath
+// returning false if it wasn't found
+func (s *store) setModTime(absPath string, modTime time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	f := s.files[absPath]
+	if f == nil {
+		return false
+	}
+	f.modTime = modTime
+	return true
+}
+
+// dirExists returns true if dir is the root, has been made or has
+// anything in it
+//
+// Call with the lock held
+func (s *store) dirExists(dir string) bool {
+	if dir == "" {
+		return true
+	}
+	if _, ok := s.dirs[dir]; ok {
+		return true
+	}
+	prefix := dir + "/"
+	for absPath := range s.files {
+		if strings.HasPrefix(absPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mkdir makes dir and its parents
+func (s *store) mkdir(dir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[dir]; ok {
+		return fs.ErrorIsFile
+	}
+	s.mkdirAll(dir)
+	return nil
+}
+
+// rmdir removes dir if it is empty
+func (s *store) rmdir(dir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	if !s.dirExists(dir) {
+		return fs.ErrorDirNotFound
+	}
+	prefix := dir + "/"
+	if dir == "" {
+		prefix = ""
+	}
+	for absPath := range s.files {
+		if strings.HasPrefix(absPath, prefix) {
+			return fs.ErrorDirectoryNotEmpty
+		}
+	}
+	for absDir := range s.dirs {
+		if strings.HasPrefix(absDir, prefix) {
+			return fs.ErrorDirectoryNotEmpty
+		}
+	}
+	delete(s.dirs, dir)
+	return nil
+}
+
+// entry is a file or directory found by list
+type entry struct {
+	path string // absolute path
+	file *file  // the file or nil for a directory
+}
+
+// list returns the files and directories in dir, or everything
+// below it if recurse is set
+func (s *store) list(dir string, recurse bool) (entries []entry, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	if !s.dirExists(dir) {
+		return nil, fs.ErrorDirNotFound
+	}
+	prefix := dir + "/"
+	if dir == "" {
+		prefix = ""
+	}
+	seen := make(map[string]struct{})
+	addDir := func(absDir string) {
+		// Add absDir and, if recursing, its parents below dir
+		for absDir != dir {
+			if _, found := seen[absDir]; found {
+				return
+			}
+			if recurse || parent(absDir) == dir {
+				seen[absDir] = struct{}{}
+				entries = append(entries, entry{path: absDir})
+			}
+			absDir = parent(absDir)
+		}
+	}
+	for absDir := range s.dirs {
+		if strings.HasPrefix(absDir, prefix) {
+			addDir(absDir)
+		}
+	}
+	for absPath, f := range s.files {
+		if !strings.HasPrefix(absPath, prefix) {
+			continue
+		}
+		if recurse || parent(absPath) == dir {
+			entries = append(entries, entry{path: absPath, file: f})
+		}
+		addDir(parent(absPath))
+	}
+	return entries, nil
+}
+
+// copy the file at srcPath to dstPath
+func (s *store) copy(srcPath, dstPath string) (*file, error) {
+	src := s.get(srcPath)
+	if src == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	dst := &file{
+		path:     dstPath,
+		data:     src.data,
+		hash:     src.hash,
+		modTime:  src.modTime,
+		mimeType: src.mimeType,
+	}
+	return dst, s.put(dst)
+}
+
+// move the file at srcPath to dstPath keeping its place in the LRU
+// and its expiry time
+func (s *store) move(srcPath, dstPath string) (*file, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	f := s.files[srcPath]
+	if f == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	if srcPath == dstPath {
+		return f, nil
+	}
+	if old := s.files[dstPath]; old != nil {
+		s.remove(old)
+	}
+	delete(s.files, srcPath)
+	f.path = dstPath
+	s.files[dstPath] = f
+	s.mkdirAll(parent(dstPath))
+	return f, nil
+}
+
+// moveDir renames the directory srcDir and everything in it to dstDir
+func (s *store) moveDir(srcDir, dstDir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	if s.dirExists(dstDir) {
+		return fs.ErrorDirExists
+	}
+	if !s.dirExists(srcDir) {
+		return fs.ErrorDirNotFound
+	}
+	srcPrefix, dstPrefix := srcDir+"/", dstDir+"/"
+	if srcDir == "" || strings.HasPrefix(dstDir, srcPrefix) {
+		return fs.ErrorCantDirMove
+	}
+	moved := make(map[string]*file)
+	for absPath, f := range s.files {
+		if strings.HasPrefix(absPath, srcPrefix) {
+			delete(s.files, absPath)
+			f.path = dstPrefix + absPath[len(srcPrefix):]
+			moved[f.path] = f
+		}
+	}
+	for absPath, f := range moved {
+		s.files[absPath] = f
+	}
+	var movedDirs []string
+	for absDir := range s.dirs {
+		if absDir == srcDir || strings.HasPrefix(absDir, srcPrefix) {
+			delete(s.dirs, absDir)
+			movedDirs = append(movedDirs, dstDir+absDir[len(srcDir):])
+		}
+	}
+	for _, absDir := range movedDirs {
+		s.dirs[absDir] = struct{}{}
+	}
+	s.mkdirAll(dstDir)
+	return nil
+}
+
+// purge removes dir and everything in it
+func (s *store) purge(dir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	if !s.dirExists(dir) {
+		return fs.ErrorDirNotFound
+	}
+	prefix := dir + "/"
+	if dir == "" {
+		prefix = ""
+	}
+	for absPath, f := range s.files {
+		if strings.HasPrefix(absPath, prefix) {
+			s.remove(f)
+		}
+	}
+	for absDir := range s.dirs {
+		if absDir == dir || strings.HasPrefix(absDir, prefix) {
+			delete(s.dirs, absDir)
+		}
+	}
+	return nil
+}
+
+// usage returns the total size and number of files stored and the limit
+func (s *store) usage() (size, files, maxSize int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+	return s.size, int64(len(s.files)), s.maxSize
+}
+
+// parent returns the parent directory of absPath or "" for the root
+func parent(absPath string) string {
+	i := strings.LastIndex(absPath, "/")
+	if i < 0 {
+		return ""
+	}
+	return absPath[:i]
+}
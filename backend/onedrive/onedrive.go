@@ -883,6 +883,7 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 		srv:       rest.NewClient(oAuthClient).SetRoot(rootURL),
 		pacer:     fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
 	}
+	f.pacer.SetCounters(fs.GetCounters(name))
 	f.features = (&fs.Features{
 		CaseInsensitive:         true,
 		ReadMimeType:            true,
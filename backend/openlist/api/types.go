@@ -0,0 +1,130 @@
+// Package api contains definitions for using the OpenList API
+//
+// OpenList is a fork of Alist and the file system part of the API
+// (/api/fs/...) is the same as the Alist v3 one.
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// Codes returned in Response.Code
+const (
+	CodeOK           = 200
+	CodeUnauthorized = 401
+	CodeForbidden    = 403
+	CodeServerError  = 500
+)
+
+// Response is returned by all the calls. The HTTP status is usually
+// 200 even for errors, with the real status in Code.
+type Response struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error satisfies the error interface
+func (e *Response) Error() string {
+	return fmt.Sprintf("openlist error %d: %s", e.Code, e.Message)
+}
+
+// AsErr returns e as an error if it isn't a success or nil
+func (e *Response) AsErr() error {
+	if e.Code != CodeOK {
+		return e
+	}
+	return nil
+}
+
+// LoginRequest is sent to /api/auth/login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	OTPCode  string `json:"otp_code,omitempty"`
+}
+
+// LoginResponse is returned by /api/auth/login
+type LoginResponse struct {
+	Response
+	Data struct {
+		Token string `json:"token"`
+	} `json:"data"`
+}
+
+// ListRequest is sent to /api/fs/list
+type ListRequest struct {
+	Path     string `json:"path"`
+	Password string `json:"password,omitempty"`
+	Page     int    `json:"page"`
+	PerPage  int    `json:"per_page"`
+	Refresh  bool   `json:"refresh"`
+}
+
+// Item is a file or directory
+type Item struct {
+	Name     string            `json:"name"`
+	Size     int64             `json:"size"`
+	IsDir    bool              `json:"is_dir"`
+	Modified time.Time         `json:"modified"`
+	Created  time.Time         `json:"created"`
+	Sign     string            `json:"sign"`
+	HashInfo map[string]string `json:"hash_info"`
+	RawURL   string            `json:"raw_url"` // only returned by /api/fs/get
+}
+
+// ListResponse is returned by /api/fs/list
+type ListResponse struct {
+	Response
+	Data struct {
+		Content []Item `json:"content"`
+		Total   int    `json:"total"`
+		Write   bool   `json:"write"`
+	} `json:"data"`
+}
+
+// GetRequest is sent to /api/fs/get
+type GetRequest struct {
+	Path     string `json:"path"`
+	Password string `json:"password,omitempty"`
+}
+
+// GetResponse is returned by /api/fs/get
+type GetResponse struct {
+	Response
+	Data Item `json:"data"`
+}
+
+// MkdirRequest is sent to /api/fs/mkdir
+type MkdirRequest struct {
+	Path string `json:"path"`
+}
+
+// RemoveRequest is sent to /api/fs/remove
+type RemoveRequest struct {
+	Dir   string   `json:"dir"`
+	Names []string `json:"names"`
+}
+
+// RenameRequest is sent to /api/fs/rename
+type RenameRequest struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+}
+
+// MoveRequest is sent to /api/fs/move and /api/fs/copy
+type MoveRequest struct {
+	SrcDir string   `json:"src_dir"`
+	DstDir string   `json:"dst_dir"`
+	Names  []string `json:"names"`
+}
+
+// MeResponse is returned by /api/me
+type MeResponse struct {
+	Response
+	Data struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+		BasePath string `json:"base_path"`
+	} `json:"data"`
+}
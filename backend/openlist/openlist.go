@@ -0,0 +1,820 @@
+// Package openlist provides an interface to OpenList servers
+//
+// OpenList (https://github.com/OpenListTeam/OpenList) is the
+// community maintained fork of Alist. It presents many storage
+// providers as one directory tree over an HTTP API.
+package openlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/openlist/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "openlist",
+		Description: "OpenList",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:     "url",
+			Help:     "URL of the OpenList server, e.g. https://openlist.example.com.",
+			Required: true,
+		}, {
+			Name: "user",
+			Help: `User name to log in with.
+
+Leave blank to use an API token or to access the server as a guest.`,
+		}, {
+			Name:       "pass",
+			Help:       "Password to log in with.",
+			IsPassword: true,
+		}, {
+			Name: "token",
+			Help: `API token to use instead of logging in.
+
+This is the token shown in the "Other" settings of the OpenList admin
+pages. If set, user and pass are ignored.`,
+			IsPassword: true,
+			Advanced:   true,
+		}, {
+			Name: "meta_pass",
+			Help: `Password for directories protected by a meta password.
+
+This is sent with every listing and download, so only one meta password
+can be used per remote.`,
+			IsPassword: true,
+			Advanced:   true,
+		}, {
+			Name:     "list_chunk",
+			Help:     "Number of entries to ask for in each page of a listing.",
+			Default:  1000,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// Encode invalid UTF-8 bytes as json doesn't handle them properly.
+			Default: (encoder.Display |
+				encoder.EncodeBackSlash |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	URL       string               `config:"url"`
+	User      string               `config:"user"`
+	Pass      string               `config:"pass"`
+	Token     string               `config:"token"`
+	MetaPass  string               `config:"meta_pass"`
+	ListChunk int                  `config:"list_chunk"`
+	Enc       encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a remote OpenList server
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	srv      *rest.Client // the connection to the server
+	pacer    *fs.Pacer    // pacer for API calls
+	pass     string       // revealed password
+	metaPass string       // revealed meta password
+
+	tokenMu sync.Mutex
+	token   string // token to send in the Authorization header
+}
+
+// Object describes an OpenList file
+type Object struct {
+	fs      *Fs       // what this object is part of
+	remote  string    // The remote path
+	size    int64     // size of the object
+	modTime time.Time // modification time of the object
+	hashes  map[string]string
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("OpenList root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+//
+// Whether modification times are kept depends on the storage behind
+// the server, so they aren't used.
+func (f *Fs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// Hashes returns the supported hash types of the filesystem
+//
+// Which hashes are available depends on the storage behind the server.
+func (f *Fs) Hashes() hash.Set {
+	return hash.NewHashSet(hash.MD5, hash.SHA1, hash.SHA256)
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	e := &api.Response{
+		Code:    resp.StatusCode,
+		Message: resp.Status,
+	}
+	body, err := rest.ReadBody(resp)
+	if err == nil && len(body) > 0 {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	return e
+}
+
+// isNotFound returns true if err says the path doesn't exist
+//
+// The server doesn't have a code for this so the message is checked.
+func isNotFound(err error) bool {
+	var apiErr *api.Response
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	msg := strings.ToLower(apiErr.Message)
+	return strings.Contains(msg, "not found") || strings.Contains(msg, "not exist")
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.ListChunk <= 0 {
+		return nil, errors.New("list_chunk must be positive")
+	}
+	f := &Fs{
+		name:  name,
+		root:  strings.Trim(root, "/"),
+		opt:   *opt,
+		srv:   rest.NewClient(fshttp.NewClient(ctx)).SetRoot(strings.TrimRight(opt.URL, "/")),
+		pacer: fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+	}
+	f.srv.SetErrorHandler(errorHandler)
+	if opt.Pass != "" {
+		f.pass, err = obscure.Reveal(opt.Pass)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt password: %w", err)
+		}
+	}
+	if opt.MetaPass != "" {
+		f.metaPass, err = obscure.Reveal(opt.MetaPass)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt meta password: %w", err)
+		}
+	}
+	if opt.Token != "" {
+		f.token, err = obscure.Reveal(opt.Token)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt token: %w", err)
+		}
+	} else if opt.User != "" {
+		if err = f.login(ctx); err != nil {
+			return nil, err
+		}
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	if f.root != "" {
+		item, err := f.getItem(ctx, f.fullPath(""))
+		if err == nil && !item.IsDir {
+			f.root = parentDir(f.root)
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// login gets a new token with the user name and password
+func (f *Fs) login(ctx context.Context) error {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/api/auth/login",
+	}
+	req := api.LoginRequest{
+		Username: f.opt.User,
+		Password: f.pass,
+	}
+	var result api.LoginResponse
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, &req, &result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err == nil {
+		err = result.AsErr()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to log in to OpenList: %w", err)
+	}
+	f.tokenMu.Lock()
+	f.token = result.Data.Token
+	f.tokenMu.Unlock()
+	return nil
+}
+
+// authorize adds the token to opts
+func (f *Fs) authorize(opts *rest.Opts) {
+	f.tokenMu.Lock()
+	token := f.token
+	f.tokenMu.Unlock()
+	if token == "" {
+		return
+	}
+	if opts.ExtraHeaders == nil {
+		opts.ExtraHeaders = map[string]string{}
+	}
+	opts.ExtraHeaders["Authorization"] = token
+}
+
+// resultError is implemented by all the API responses
+type resultError interface {
+	AsErr() error
+}
+
+// call makes an API call sending request and decoding the response
+// into result
+//
+// If the token has expired it logs in again and retries.
+func (f *Fs) call(ctx context.Context, opts *rest.Opts, request interface{}, result resultError) error {
+	loggedIn := false
+	for {
+		f.authorize(opts)
+		err := f.pacer.Call(func() (bool, error) {
+			resp, err := f.srv.CallJSON(ctx, opts, request, result)
+			return shouldRetry(ctx, resp, err)
+		})
+		if err == nil {
+			err = result.AsErr()
+		}
+		var apiErr *api.Response
+		if errors.As(err, &apiErr) && apiErr.Code == api.CodeUnauthorized && f.opt.Token == "" && f.opt.User != "" && !loggedIn {
+			fs.Debugf(f, "Token expired - logging in again")
+			if err = f.login(ctx); err != nil {
+				return err
+			}
+			loggedIn = true
+			continue
+		}
+		return err
+	}
+}
+
+// parentDir returns the parent directory of p or "" for the root
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// fullPath returns the absolute server path of remote
+func (f *Fs) fullPath(remote string) string {
+	return f.opt.Enc.FromStandardPath(path.Join("/", f.root, remote))
+}
+
+// splitPath returns the directory and leaf of the absolute server
+// path p
+func splitPath(p string) (dir, leaf string) {
+	dir, leaf = path.Split(p)
+	if dir != "/" {
+		dir = strings.TrimRight(dir, "/")
+	}
+	return dir, leaf
+}
+
+// getItem reads the info about the absolute server path p
+func (f *Fs) getItem(ctx context.Context, p string) (*api.Item, error) {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/api/fs/get",
+	}
+	req := api.GetRequest{
+		Path:     p,
+		Password: f.metaPass,
+	}
+	var result api.GetResponse
+	err := f.call(ctx, &opts, &req, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result.Data, nil
+}
+
+// listAll calls fn for each item in the absolute server path dir
+func (f *Fs) listAll(ctx context.Context, dir string, fn func(*api.Item)) error {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/api/fs/list",
+	}
+	for page := 1; ; page++ {
+		req := api.ListRequest{
+			Path:     dir,
+			Password: f.metaPass,
+			Page:     page,
+			PerPage:  f.opt.ListChunk,
+		}
+		var result api.ListResponse
+		err := f.call(ctx, &opts, &req, &result)
+		if err != nil {
+			return err
+		}
+		for i := range result.Data.Content {
+			fn(&result.Data.Content[i])
+		}
+		n := len(result.Data.Content)
+		if n < f.opt.ListChunk || page*f.opt.ListChunk >= result.Data.Total {
+			return nil
+		}
+	}
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.listAll(ctx, f.fullPath(dir), func(item *api.Item) {
+		remote := path.Join(dir, f.opt.Enc.ToStandardName(item.Name))
+		if item.IsDir {
+			entries = append(entries, fs.NewDir(remote, item.Modified))
+			return
+		}
+		entries = append(entries, f.newObject(remote, item))
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fs.ErrorDirNotFound
+		}
+		return nil, err
+	}
+	return entries, nil
+}
+
+// newObject makes an object from item
+func (f *Fs) newObject(remote string, item *api.Item) *Object {
+	return &Object{
+		fs:      f,
+		remote:  remote,
+		size:    item.Size,
+		modTime: item.Modified,
+		hashes:  item.HashInfo,
+	}
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	item, err := f.getItem(ctx, f.fullPath(remote))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, err
+	}
+	if item.IsDir {
+		return nil, fs.ErrorIsDir
+	}
+	return f.newObject(remote, item), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// mkdir makes the absolute server path p and any parents needed
+func (f *Fs) mkdir(ctx context.Context, p string) error {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/api/fs/mkdir",
+	}
+	req := api.MkdirRequest{
+		Path: p,
+	}
+	var result api.Response
+	return f.call(ctx, &opts, &req, &result)
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	p := f.fullPath(dir)
+	if p == "/" {
+		return nil
+	}
+	return f.mkdir(ctx, p)
+}
+
+// remove deletes the absolute server path p and anything in it
+func (f *Fs) remove(ctx context.Context, p string) error {
+	dir, leaf := splitPath(p)
+	if leaf == "" {
+		return errors.New("can't remove the root of the server")
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/api/fs/remove",
+	}
+	req := api.RemoveRequest{
+		Dir:   dir,
+		Names: []string{leaf},
+	}
+	var result api.Response
+	return f.call(ctx, &opts, &req, &result)
+}
+
+// Rmdir deletes the root folder
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	p := f.fullPath(dir)
+	item, err := f.getItem(ctx, p)
+	if err != nil {
+		if isNotFound(err) {
+			return fs.ErrorDirNotFound
+		}
+		return err
+	}
+	if !item.IsDir {
+		return fs.ErrorIsFile
+	}
+	empty := true
+	err = f.listAll(ctx, p, func(*api.Item) {
+		empty = false
+	})
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	if p == "/" {
+		return nil
+	}
+	return f.remove(ctx, p)
+}
+
+// Purge deletes all the files in the directory
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	p := f.fullPath(dir)
+	if _, err := f.getItem(ctx, p); err != nil {
+		if isNotFound(err) {
+			return fs.ErrorDirNotFound
+		}
+		return err
+	}
+	return f.remove(ctx, p)
+}
+
+// rename renames the absolute server path p to the leaf name in the
+// same directory
+func (f *Fs) rename(ctx context.Context, p, name string) error {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/api/fs/rename",
+	}
+	req := api.RenameRequest{
+		Path: p,
+		Name: name,
+	}
+	var result api.Response
+	return f.call(ctx, &opts, &req, &result)
+}
+
+// move moves the absolute server path src to dst, which must not exist
+//
+// The server can only move items keeping their name or rename them
+// in place. When both change the item is renamed to a temporary name
+// first so neither step can collide with an existing item.
+func (f *Fs) move(ctx context.Context, src, dst string) error {
+	srcDir, srcLeaf := splitPath(src)
+	dstDir, dstLeaf := splitPath(dst)
+	if srcDir == dstDir {
+		return f.rename(ctx, src, dstLeaf)
+	}
+	leaf := srcLeaf
+	if srcLeaf != dstLeaf {
+		leaf = "rclone-move-" + random.String(16)
+		if err := f.rename(ctx, src, leaf); err != nil {
+			return err
+		}
+	}
+	if err := f.mkdir(ctx, dstDir); err != nil {
+		return err
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/api/fs/move",
+	}
+	req := api.MoveRequest{
+		SrcDir: srcDir,
+		DstDir: dstDir,
+		Names:  []string{leaf},
+	}
+	var result api.Response
+	if err := f.call(ctx, &opts, &req, &result); err != nil {
+		if leaf != srcLeaf {
+			if renameErr := f.rename(ctx, path.Join(srcDir, leaf), srcLeaf); renameErr != nil {
+				fs.Errorf(f, "Failed to rename %q back to %q: %v", leaf, srcLeaf, renameErr)
+			}
+		}
+		return err
+	}
+	if leaf != dstLeaf {
+		return f.rename(ctx, path.Join(dstDir, leaf), dstLeaf)
+	}
+	return nil
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	if srcObj.fs.opt.URL != f.opt.URL {
+		return nil, fs.ErrorCantMove
+	}
+	dst := f.fullPath(remote)
+	// the server won't overwrite so remove the destination first
+	if existing, err := f.NewObject(ctx, remote); err == nil {
+		if err = existing.Remove(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.move(ctx, srcObj.fs.fullPath(srcObj.remote), dst); err != nil {
+		return nil, err
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	if srcFs.opt.URL != f.opt.URL {
+		return fs.ErrorCantDirMove
+	}
+	srcPath := srcFs.fullPath(srcRemote)
+	dstPath := f.fullPath(dstRemote)
+	if srcPath == "/" || dstPath == "/" {
+		return fs.ErrorCantDirMove
+	}
+	if _, err := f.getItem(ctx, dstPath); err == nil {
+		return fs.ErrorDirExists
+	} else if !isNotFound(err) {
+		return err
+	}
+	return f.move(ctx, srcPath, dstPath)
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the requested hash of the object if the server knows it
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if !o.fs.Hashes().Contains(t) {
+		return "", hash.ErrUnsupported
+	}
+	return strings.ToLower(o.hashes[t.String()]), nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	p := o.fs.fullPath(o.remote)
+	item, err := o.fs.getItem(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	fs.FixRangeOption(options, o.size)
+	opts := rest.Opts{
+		Method:  "GET",
+		Options: options,
+	}
+	if item.RawURL != "" {
+		opts.RootURL = item.RawURL
+	} else {
+		// fall back to the server's own download link
+		opts.Path = "/d" + rest.URLPathEscape(p)
+		if item.Sign != "" {
+			opts.Parameters = url.Values{"sign": {item.Sign}}
+		}
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// If existing is set then it updates the object rather than creating a new one.
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	size := src.Size()
+	if size < 0 {
+		// the server needs to know the size in advance
+		return errors.New("can't upload files of unknown size")
+	}
+	p := o.fs.fullPath(o.remote)
+	opts := rest.Opts{
+		Method:        "PUT",
+		Path:          "/api/fs/put",
+		Body:          in,
+		ContentLength: &size,
+		ContentType:   "application/octet-stream",
+		Options:       options,
+		ExtraHeaders: map[string]string{
+			"File-Path":     url.PathEscape(p),
+			"As-Task":       "false",
+			"Last-Modified": strconv.FormatInt(src.ModTime(ctx).UnixNano()/1e6, 10),
+		},
+	}
+	if md5, err := src.Hash(ctx, hash.MD5); err == nil && md5 != "" {
+		opts.ExtraHeaders["X-File-Md5"] = md5
+	}
+	o.fs.authorize(&opts)
+	var result api.Response
+	// can't retry as the body has been consumed
+	resp, err := o.fs.srv.CallJSON(ctx, &opts, nil, &result)
+	if err == nil {
+		err = result.AsErr()
+	}
+	if err != nil {
+		_, err = shouldRetry(ctx, resp, err)
+		return fmt.Errorf("failed to upload %q: %w", o.remote, err)
+	}
+	item, err := o.fs.getItem(ctx, p)
+	if err != nil {
+		return fmt.Errorf("failed to read info about uploaded %q: %w", o.remote, err)
+	}
+	*o = *o.fs.newObject(o.remote, item)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return o.fs.remove(ctx, o.fs.fullPath(o.remote))
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs       = (*Fs)(nil)
+	_ fs.Purger   = (*Fs)(nil)
+	_ fs.Mover    = (*Fs)(nil)
+	_ fs.DirMover = (*Fs)(nil)
+	_ fs.Object   = (*Object)(nil)
+)
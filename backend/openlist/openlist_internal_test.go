@@ -0,0 +1,305 @@
+package openlist
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/openlist/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFile is a file or directory in the fake server
+type fakeFile struct {
+	isDir   bool
+	data    []byte
+	modTime time.Time
+}
+
+// fakeServer is an in memory OpenList server with just enough of
+// the API for the backend
+type fakeServer struct {
+	mu     sync.Mutex
+	srv    *httptest.Server
+	user   string
+	pass   string
+	token  string
+	logins int
+	files  map[string]*fakeFile // by absolute path
+}
+
+// newFakeServer starts a fake server needing user and pass to log in
+func newFakeServer(t *testing.T, user, pass string) *fakeServer {
+	s := &fakeServer{
+		user: user,
+		pass: pass,
+		files: map[string]*fakeFile{
+			"/": {isDir: true},
+		},
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+// expireToken makes the current token invalid
+func (s *fakeServer) expireToken() {
+	s.mu.Lock()
+	s.token = "expired"
+	s.mu.Unlock()
+}
+
+// reply sends code and message with data
+func reply(w http.ResponseWriter, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    code,
+		"message": message,
+		"data":    data,
+	})
+}
+
+// item describes the file at p
+func (s *fakeServer) item(p string, file *fakeFile) map[string]interface{} {
+	sum := fmt.Sprintf("%x", sha1.Sum(file.data))
+	return map[string]interface{}{
+		"name":      path.Base(p),
+		"size":      len(file.data),
+		"is_dir":    file.isDir,
+		"modified":  file.modTime,
+		"hash_info": map[string]string{"sha1": sum},
+		"raw_url":   s.srv.URL + "/raw" + escapePath(p),
+	}
+}
+
+// escapePath escapes p for use in a URL
+func escapePath(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+// children returns the paths of the direct children of dir
+func (s *fakeServer) children(dir string) (out []string) {
+	for p := range s.files {
+		if p != "/" && path.Dir(p) == dir {
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// mkdirAll makes dir and its parents
+func (s *fakeServer) mkdirAll(dir string) bool {
+	if file, found := s.files[dir]; found {
+		return file.isDir
+	}
+	if !s.mkdirAll(path.Dir(dir)) {
+		return false
+	}
+	s.files[dir] = &fakeFile{isDir: true, modTime: time.Now()}
+	return true
+}
+
+// rename moves src and anything below it to dst
+func (s *fakeServer) rename(src, dst string) bool {
+	if _, found := s.files[dst]; found {
+		return false
+	}
+	moved := map[string]*fakeFile{}
+	for p, file := range s.files {
+		if p == src || strings.HasPrefix(p, src+"/") {
+			delete(s.files, p)
+			moved[dst+strings.TrimPrefix(p, src)] = file
+		}
+	}
+	for p, file := range moved {
+		s.files[p] = file
+	}
+	return true
+}
+
+// serve answers the API calls
+func (s *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if strings.HasPrefix(r.URL.Path, "/raw/") {
+		file, found := s.files[strings.TrimPrefix(r.URL.Path, "/raw")]
+		if !found || file.isDir {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "", file.modTime, bytes.NewReader(file.data))
+		return
+	}
+	if r.URL.Path == "/api/auth/login" {
+		var req api.LoginRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Username != s.user || req.Password != s.pass {
+			reply(w, 400, "password is incorrect", nil)
+			return
+		}
+		s.logins++
+		s.token = "token-" + strconv.Itoa(s.logins)
+		reply(w, 200, "success", map[string]string{"token": s.token})
+		return
+	}
+	if r.Header.Get("Authorization") != s.token {
+		reply(w, 401, "token is invalidated", nil)
+		return
+	}
+	if r.URL.Path == "/api/fs/put" {
+		p, err := url.PathUnescape(r.Header.Get("File-Path"))
+		if err != nil || !s.mkdirAll(path.Dir(p)) {
+			reply(w, 500, "bad path", nil)
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			reply(w, 500, "failed to read body", nil)
+			return
+		}
+		ms, _ := strconv.ParseInt(r.Header.Get("Last-Modified"), 10, 64)
+		s.files[p] = &fakeFile{data: data, modTime: time.Unix(0, ms*1e6)}
+		reply(w, 200, "success", nil)
+		return
+	}
+	var req struct {
+		Path    string   `json:"path"`
+		Page    int      `json:"page"`
+		PerPage int      `json:"per_page"`
+		Name    string   `json:"name"`
+		Dir     string   `json:"dir"`
+		SrcDir  string   `json:"src_dir"`
+		DstDir  string   `json:"dst_dir"`
+		Names   []string `json:"names"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	p := req.Path
+	switch r.URL.Path {
+	case "/api/fs/get":
+		file, found := s.files[p]
+		if !found {
+			reply(w, 500, "object not found", nil)
+			return
+		}
+		reply(w, 200, "success", s.item(p, file))
+	case "/api/fs/list":
+		file, found := s.files[p]
+		if !found || !file.isDir {
+			reply(w, 500, "failed get objs: object not found", nil)
+			return
+		}
+		all := s.children(p)
+		content := []interface{}{}
+		start := (req.Page - 1) * req.PerPage
+		for i := start; i < len(all) && i < start+req.PerPage; i++ {
+			content = append(content, s.item(all[i], s.files[all[i]]))
+		}
+		reply(w, 200, "success", map[string]interface{}{"content": content, "total": len(all)})
+	case "/api/fs/mkdir":
+		if !s.mkdirAll(p) {
+			reply(w, 500, "file exists", nil)
+			return
+		}
+		reply(w, 200, "success", nil)
+	case "/api/fs/remove":
+		for _, name := range req.Names {
+			target := path.Join(req.Dir, name)
+			for p := range s.files {
+				if p == target || strings.HasPrefix(p, target+"/") {
+					delete(s.files, p)
+				}
+			}
+		}
+		reply(w, 200, "success", nil)
+	case "/api/fs/rename":
+		if _, found := s.files[p]; !found || !s.rename(p, path.Join(path.Dir(p), req.Name)) {
+			reply(w, 500, "rename failed", nil)
+			return
+		}
+		reply(w, 200, "success", nil)
+	case "/api/fs/move":
+		for _, name := range req.Names {
+			src := path.Join(req.SrcDir, name)
+			if _, found := s.files[src]; !found || !s.rename(src, path.Join(req.DstDir, name)) {
+				reply(w, 500, "move failed", nil)
+				return
+			}
+		}
+		reply(w, 200, "success", nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// TestFake runs the integration tests against the fake server
+func TestFake(t *testing.T) {
+	s := newFakeServer(t, "admin", "secret")
+	name := "TestOpenListFake"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "openlist"},
+			{Name: name, Key: "url", Value: s.srv.URL},
+			{Name: name, Key: "user", Value: "admin"},
+			{Name: name, Key: "pass", Value: obscure.MustObscure("secret")},
+			{Name: name, Key: "list_chunk", Value: "3"},
+		},
+		QuickTestOK: true,
+	})
+}
+
+func TestLoginAgain(t *testing.T) {
+	ctx := context.Background()
+	s := newFakeServer(t, "admin", "secret")
+	f, err := fs.NewFs(ctx, fmt.Sprintf(":openlist,url=%q,user=admin,pass=%q:", s.srv.URL, obscure.MustObscure("secret")))
+	require.NoError(t, err)
+
+	contents := "hello"
+	src := object.NewStaticObjectInfo("dir/file.txt", time.Now(), int64(len(contents)), true, nil, nil)
+	_, err = f.Put(ctx, strings.NewReader(contents), src)
+	require.NoError(t, err)
+
+	s.expireToken()
+	entries, err := f.List(ctx, "dir")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, 2, s.logins)
+}
+
+func TestBadLogin(t *testing.T) {
+	ctx := context.Background()
+	s := newFakeServer(t, "admin", "secret")
+	_, err := fs.NewFs(ctx, fmt.Sprintf(":openlist,url=%q,user=admin,pass=%q:", s.srv.URL, obscure.MustObscure("wrong")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "password is incorrect")
+}
+
+func TestToken(t *testing.T) {
+	ctx := context.Background()
+	s := newFakeServer(t, "admin", "secret")
+	s.token = "api-token"
+	f, err := fs.NewFs(ctx, fmt.Sprintf(":openlist,url=%q,token=%q:", s.srv.URL, obscure.MustObscure("api-token")))
+	require.NoError(t, err)
+	require.NoError(t, f.Mkdir(ctx, "dir"))
+	assert.Equal(t, 0, s.logins)
+}
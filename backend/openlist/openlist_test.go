@@ -0,0 +1,17 @@
+// Test OpenList filesystem interface
+package openlist_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/openlist"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestOpenList:",
+		NilObject:  (*openlist.Object)(nil),
+	})
+}
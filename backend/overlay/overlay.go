@@ -0,0 +1,655 @@
+// Package overlay implements a backend which layers a writable remote
+// over a read only one
+package overlay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// opaqueName is the name of the marker, after the whiteout prefix,
+// which hides everything in the base directory of the directory it
+// is in
+const opaqueName = ".wh..opq"
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "overlay",
+		Description: "Overlay a read only remote with a writable one",
+		NewFs:       NewFs,
+		MetadataInfo: &fs.MetadataInfo{
+			Help: `Any metadata supported by the underlying remotes is read and written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "base",
+			Required: true,
+			Help: `Remote to use as the read only base layer.
+
+Files are read from here unless they have been changed. Nothing is
+ever written to it.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name:     "delta",
+			Required: true,
+			Help: `Remote to write the changes to.
+
+New and changed files are stored here, along with a marker file for
+each file or directory deleted from the base remote.`,
+		}, {
+			Name:     "whiteout_prefix",
+			Default:  ".wh.",
+			Advanced: true,
+			Help: `Prefix of the marker files which record deletions in the delta remote.
+
+Deleting "dir/file" from the base remote writes an empty file
+"dir/.wh.file" to the delta remote. Files starting with this prefix
+are hidden and can't be written.`,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Base           string `config:"base"`
+	Delta          string `config:"delta"`
+	WhiteoutPrefix string `config:"whiteout_prefix"`
+}
+
+// Fs represents a base remote overlaid by a delta remote
+type Fs struct {
+	name     string
+	root     string
+	opt      Options
+	features *fs.Features // optional features
+	base     fs.Fs        // read only lower layer
+	delta    fs.Fs        // writable upper layer
+	hashes   hash.Set     // hashes supported by both layers
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	for _, remote := range []string{opt.Base, opt.Delta} {
+		if strings.HasPrefix(remote, name+":") {
+			return nil, errors.New("can't point overlay remote at itself - check the values of the base and delta settings")
+		}
+	}
+	if opt.WhiteoutPrefix == "" || strings.Contains(opt.WhiteoutPrefix, "/") {
+		return nil, errors.New("whiteout_prefix must be set and can't contain /")
+	}
+	f := &Fs{
+		name: name,
+		root: rpath,
+		opt:  opt,
+	}
+	var baseErr, deltaErr error
+	f.base, baseErr = cache.Get(ctx, fspath.JoinRootPath(opt.Base, rpath))
+	if baseErr != nil && baseErr != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make base remote %q: %w", opt.Base, baseErr)
+	}
+	f.delta, deltaErr = cache.Get(ctx, fspath.JoinRootPath(opt.Delta, rpath))
+	if deltaErr != nil && deltaErr != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make delta remote %q: %w", opt.Delta, deltaErr)
+	}
+	// If the root points to a file in either layer, point both
+	// layers at its directory
+	isFile := baseErr == fs.ErrorIsFile || deltaErr == fs.ErrorIsFile
+	if isFile {
+		parent := path.Dir(strings.Trim(rpath, "/"))
+		if parent == "." {
+			parent = ""
+		}
+		if baseErr == nil {
+			f.base, err = cache.Get(ctx, fspath.JoinRootPath(opt.Base, parent))
+			if err != nil {
+				return nil, fmt.Errorf("failed to make base remote %q: %w", opt.Base, err)
+			}
+		}
+		if deltaErr == nil {
+			f.delta, err = cache.Get(ctx, fspath.JoinRootPath(opt.Delta, parent))
+			if err != nil {
+				return nil, fmt.Errorf("failed to make delta remote %q: %w", opt.Delta, err)
+			}
+		}
+	}
+	cache.PinUntilFinalized(f.base, f)
+	f.hashes = f.base.Hashes().Overlap(f.delta.Hashes())
+	f.features = (&fs.Features{
+		CaseInsensitive:         f.base.Features().CaseInsensitive || f.delta.Features().CaseInsensitive,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, f.delta)
+	f.features.CanHaveEmptyDirectories = f.delta.Features().CanHaveEmptyDirectories
+	if isFile {
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("overlay root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	precision := f.base.Precision()
+	if p := f.delta.Precision(); p > precision {
+		precision = p
+	}
+	return precision
+}
+
+// Hashes returns the hash types supported by both layers
+func (f *Fs) Hashes() hash.Set {
+	return f.hashes
+}
+
+// split returns the directory and leaf of remote
+func split(remote string) (dir, leaf string) {
+	dir, leaf = path.Split(remote)
+	return strings.TrimSuffix(dir, "/"), leaf
+}
+
+// isMarker returns true if leaf is a whiteout or opaque marker
+func (f *Fs) isMarker(leaf string) bool {
+	return strings.HasPrefix(leaf, f.opt.WhiteoutPrefix)
+}
+
+// whiteoutPath returns the path of the whiteout marker for remote
+func (f *Fs) whiteoutPath(remote string) string {
+	dir, leaf := split(remote)
+	return path.Join(dir, f.opt.WhiteoutPrefix+leaf)
+}
+
+// opaquePath returns the path of the opaque marker for dir
+func (f *Fs) opaquePath(dir string) string {
+	return path.Join(dir, f.opt.WhiteoutPrefix+opaqueName)
+}
+
+// exists returns true if there is an object at remote in the delta
+func (f *Fs) exists(ctx context.Context, remote string) bool {
+	_, err := f.delta.NewObject(ctx, remote)
+	return err == nil
+}
+
+// hidden returns true if the base layer's remote is hidden by a
+// whiteout of it or one of its parents, or by an opaque parent
+// directory
+func (f *Fs) hidden(ctx context.Context, remote string) bool {
+	if remote == "" {
+		return false
+	}
+	if f.exists(ctx, f.whiteoutPath(remote)) {
+		return true
+	}
+	dir, _ := split(remote)
+	if f.exists(ctx, f.opaquePath(dir)) {
+		return true
+	}
+	return f.hidden(ctx, dir)
+}
+
+// writeMarker writes an empty marker file at remote in the delta
+func (f *Fs) writeMarker(ctx context.Context, remote string) error {
+	src := object.NewStaticObjectInfo(remote, time.Now(), 0, true, nil, f.delta)
+	_, err := f.delta.Put(ctx, bytes.NewReader(nil), src)
+	if err != nil {
+		return fmt.Errorf("failed to write marker %q: %w", remote, err)
+	}
+	return nil
+}
+
+// removeMarker removes the marker file at remote in the delta if it
+// exists
+func (f *Fs) removeMarker(ctx context.Context, remote string) error {
+	o, err := f.delta.NewObject(ctx, remote)
+	if err != nil {
+		return nil
+	}
+	if err := o.Remove(ctx); err != nil {
+		return fmt.Errorf("failed to remove marker %q: %w", remote, err)
+	}
+	return nil
+}
+
+// unhide makes remote visible again before it is written to the
+// delta. If it was a whited out directory then it is made opaque so
+// the old base contents stay hidden.
+func (f *Fs) unhide(ctx context.Context, remote string, isDir bool) error {
+	wh := f.whiteoutPath(remote)
+	if !f.exists(ctx, wh) {
+		return nil
+	}
+	if isDir {
+		if err := f.delta.Mkdir(ctx, remote); err != nil {
+			return err
+		}
+		if err := f.writeMarker(ctx, f.opaquePath(remote)); err != nil {
+			return err
+		}
+	}
+	return f.removeMarker(ctx, wh)
+}
+
+// whiteout hides remote in the base layer if it is there
+func (f *Fs) whiteout(ctx context.Context, remote string, isDir bool) error {
+	if f.hidden(ctx, remote) {
+		return nil
+	}
+	var inBase bool
+	if isDir {
+		_, err := f.base.List(ctx, remote)
+		inBase = err == nil
+	} else {
+		_, err := f.base.NewObject(ctx, remote)
+		inBase = err == nil
+	}
+	if !inBase {
+		return nil
+	}
+	return f.writeMarker(ctx, f.whiteoutPath(remote))
+}
+
+// checkWritable returns an error if remote can't be written because
+// it looks like a marker
+func (f *Fs) checkWritable(remote string) error {
+	for _, part := range strings.Split(remote, "/") {
+		if f.isMarker(part) {
+			return fmt.Errorf("can't write %q: names starting with %q are reserved", remote, f.opt.WhiteoutPrefix)
+		}
+	}
+	return nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	deltaEntries, deltaErr := f.delta.List(ctx, dir)
+	if deltaErr != nil && !errors.Is(deltaErr, fs.ErrorDirNotFound) {
+		return nil, deltaErr
+	}
+	seen := map[string]struct{}{}
+	whiteouts := map[string]struct{}{}
+	opaque := false
+	for _, entry := range deltaEntries {
+		_, leaf := split(entry.Remote())
+		if f.isMarker(leaf) {
+			if _, ok := entry.(fs.Object); ok {
+				if leaf == f.opt.WhiteoutPrefix+opaqueName {
+					opaque = true
+				} else {
+					whiteouts[strings.TrimPrefix(leaf, f.opt.WhiteoutPrefix)] = struct{}{}
+				}
+			}
+			continue
+		}
+		seen[leaf] = struct{}{}
+		entries = append(entries, f.wrapEntry(entry, true))
+	}
+	var baseErr error = fs.ErrorDirNotFound
+	if !opaque && !f.hidden(ctx, dir) {
+		var baseEntries fs.DirEntries
+		baseEntries, baseErr = f.base.List(ctx, dir)
+		if baseErr != nil && !errors.Is(baseErr, fs.ErrorDirNotFound) {
+			return nil, baseErr
+		}
+		for _, entry := range baseEntries {
+			_, leaf := split(entry.Remote())
+			if _, found := seen[leaf]; found {
+				continue
+			}
+			if _, found := whiteouts[leaf]; found {
+				continue
+			}
+			entries = append(entries, f.wrapEntry(entry, false))
+		}
+	}
+	if deltaErr != nil && baseErr != nil {
+		return nil, fs.ErrorDirNotFound
+	}
+	return entries, nil
+}
+
+// wrapEntry wraps the objects in entry
+func (f *Fs) wrapEntry(entry fs.DirEntry, inDelta bool) fs.DirEntry {
+	if o, ok := entry.(fs.Object); ok {
+		return f.newObject(o, inDelta)
+	}
+	return entry
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	_, leaf := split(remote)
+	if f.isMarker(leaf) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	o, err := f.delta.NewObject(ctx, remote)
+	if err == nil {
+		return f.newObject(o, true), nil
+	} else if !errors.Is(err, fs.ErrorObjectNotFound) && !errors.Is(err, fs.ErrorNotAFile) {
+		return nil, err
+	}
+	if f.hidden(ctx, remote) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	o, err = f.base.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, false), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src, f.delta.Put, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.delta.Features().PutStream
+	if do == nil {
+		return nil, errors.New("PutStream not supported")
+	}
+	return f.put(ctx, in, src, do, options...)
+}
+
+// put uploads to the delta with do after making the remote visible
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, do func(context.Context, io.Reader, fs.ObjectInfo, ...fs.OpenOption) (fs.Object, error), options ...fs.OpenOption) (fs.Object, error) {
+	remote := src.Remote()
+	if err := f.checkWritable(remote); err != nil {
+		return nil, err
+	}
+	if err := f.unhideParents(ctx, remote); err != nil {
+		return nil, err
+	}
+	if err := f.unhide(ctx, remote, false); err != nil {
+		return nil, err
+	}
+	o, err := do(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, true), nil
+}
+
+// unhideParents makes the parent directories of remote visible
+func (f *Fs) unhideParents(ctx context.Context, remote string) error {
+	dir, _ := split(remote)
+	if dir == "" {
+		return nil
+	}
+	if err := f.unhideParents(ctx, dir); err != nil {
+		return err
+	}
+	return f.unhide(ctx, dir, true)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	if err := f.checkWritable(dir); err != nil {
+		return err
+	}
+	if dir != "" {
+		if err := f.unhideParents(ctx, dir); err != nil {
+			return err
+		}
+		if err := f.unhide(ctx, dir, true); err != nil {
+			return err
+		}
+	}
+	return f.delta.Mkdir(ctx, dir)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	entries, err := f.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	// remove the markers so the directory in the delta is empty
+	deltaEntries, err := f.delta.List(ctx, dir)
+	if err == nil {
+		for _, entry := range deltaEntries {
+			if o, ok := entry.(fs.Object); ok {
+				if err := o.Remove(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		if err := f.delta.Rmdir(ctx, dir); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrorDirNotFound) {
+		return err
+	}
+	if dir == "" {
+		return nil
+	}
+	return f.whiteout(ctx, dir, true)
+}
+
+// About gets quota information from the delta
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.delta.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by delta remote")
+	}
+	return do(ctx)
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	for _, u := range []fs.Fs{f.base, f.delta} {
+		if do := u.Features().Shutdown; do != nil {
+			if err := do(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Object describes a file in one of the layers
+type Object struct {
+	fs.Object
+	f       *Fs
+	inDelta bool // set if the object is in the delta
+}
+
+// newObject wraps o from the delta if inDelta is set or the base
+func (f *Fs) newObject(o fs.Object, inDelta bool) *Object {
+	return &Object{
+		Object:  o,
+		f:       f,
+		inDelta: inDelta,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// Hash returns the selected checksum of the file
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if !o.f.hashes.Contains(ht) {
+		return "", hash.ErrUnsupported
+	}
+	return o.Object.Hash(ctx, ht)
+}
+
+// copyUp copies the object from the base into the delta so it can
+// be changed
+func (o *Object) copyUp(ctx context.Context) error {
+	if o.inDelta {
+		return nil
+	}
+	if err := o.f.unhideParents(ctx, o.Remote()); err != nil {
+		return err
+	}
+	fs.Debugf(o, "Copying to delta remote")
+	newObj, err := operations.Copy(ctx, o.f.delta, nil, o.Remote(), o.Object)
+	if err != nil {
+		return fmt.Errorf("failed to copy to delta remote: %w", err)
+	}
+	o.Object = newObj
+	o.inDelta = true
+	return nil
+}
+
+// SetModTime sets the modification time of the file, copying it to
+// the delta first if necessary
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	if err := o.copyUp(ctx); err != nil {
+		return err
+	}
+	return o.Object.SetModTime(ctx, t)
+}
+
+// Update in to the object with the modTime given of the given size
+//
+// Objects in the base are replaced by a new object in the delta.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	if o.inDelta {
+		return o.Object.Update(ctx, in, src, options...)
+	}
+	if err := o.f.unhideParents(ctx, o.Remote()); err != nil {
+		return err
+	}
+	newObj, err := o.f.delta.Put(ctx, in, operations.NewOverrideRemote(src, o.Remote()), options...)
+	if err != nil {
+		return err
+	}
+	o.Object = newObj
+	o.inDelta = true
+	return nil
+}
+
+// Remove an object, leaving a whiteout if it is in the base
+func (o *Object) Remove(ctx context.Context) error {
+	if o.inDelta {
+		if err := o.Object.Remove(ctx); err != nil {
+			return err
+		}
+	}
+	return o.f.whiteout(ctx, o.Remote(), false)
+}
+
+// MimeType returns the content type of the Object if
+// known, or "" if not
+func (o *Object) MimeType(ctx context.Context) string {
+	if do, ok := o.Object.(fs.MimeTyper); ok {
+		return do.MimeType(ctx)
+	}
+	return ""
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	if do, ok := o.Object.(fs.IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.Object          = (*Object)(nil)
+	_ fs.MimeTyper       = (*Object)(nil)
+	_ fs.IDer            = (*Object)(nil)
+	_ fs.Metadataer      = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+)
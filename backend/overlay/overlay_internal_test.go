@@ -0,0 +1,147 @@
+package overlay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs returns an overlay remote whose base holds the files given
+// and whose delta is empty, and the paths of both so tests can check
+// which layer a change landed in
+func newTestFs(t *testing.T, files map[string]string) (f *Fs, baseRoot, deltaRoot string) {
+	baseRoot, deltaRoot = t.TempDir(), t.TempDir()
+	for name, contents := range files {
+		p := filepath.Join(baseRoot, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0777))
+		require.NoError(t, os.WriteFile(p, []byte(contents), 0666))
+	}
+	fsys := fstest.NewFs(t, fmt.Sprintf(`:overlay,base="%s",delta="%s":`, baseRoot, deltaRoot))
+	return fsys.(*Fs), baseRoot, deltaRoot
+}
+
+func read(t *testing.T, f fs.Fs, remote string) string {
+	ctx := context.Background()
+	o, err := f.NewObject(ctx, remote)
+	require.NoError(t, err, remote)
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+func list(t *testing.T, f fs.Fs, dir string) (names []string) {
+	entries, err := f.List(context.Background(), dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readBase reads a file from the base directory
+func readBase(t *testing.T, root, name string) string {
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestOverlay(t *testing.T) {
+	ctx := context.Background()
+	f, baseRoot, deltaRoot := newTestFs(t, map[string]string{
+		"a.txt":     "base a",
+		"b.txt":     "base b",
+		"dir/c.txt": "base c",
+	})
+
+	// reads fall through to the base
+	assert.Equal(t, "base a", read(t, f, "a.txt"))
+	assert.Equal(t, []string{"a.txt", "b.txt", "dir"}, list(t, f, ""))
+
+	// writes go to the delta
+	o, err := f.NewObject(ctx, "a.txt")
+	require.NoError(t, err)
+	src := object.NewStaticObjectInfo("a.txt", time.Now(), 7, true, nil, nil)
+	require.NoError(t, o.Update(ctx, strings.NewReader("delta a"), src))
+	assert.Equal(t, "delta a", read(t, f, "a.txt"))
+	assert.Equal(t, "base a", readBase(t, baseRoot, "a.txt"))
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "new.txt", ModTime: time.Now()}, "new", true)
+
+	// deletes leave a whiteout
+	o, err = f.NewObject(ctx, "b.txt")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	_, err = f.NewObject(ctx, "b.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	assert.Equal(t, "base b", readBase(t, baseRoot, "b.txt"))
+	_, err = os.Stat(filepath.Join(deltaRoot, ".wh.b.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.txt", "dir", "new.txt"}, list(t, f, ""))
+
+	// putting the file back removes the whiteout
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "b.txt", ModTime: time.Now()}, "delta b", true)
+	assert.Equal(t, "delta b", read(t, f, "b.txt"))
+	_, err = os.Stat(filepath.Join(deltaRoot, ".wh.b.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// removing a directory hides it and its contents
+	assert.Equal(t, fs.ErrorDirectoryNotEmpty, f.Rmdir(ctx, "dir"))
+	o, err = f.NewObject(ctx, "dir/c.txt")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	require.NoError(t, f.Rmdir(ctx, "dir"))
+	assert.Equal(t, []string{"a.txt", "b.txt", "new.txt"}, list(t, f, ""))
+	_, err = f.List(ctx, "dir")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	// making it again doesn't bring the old contents back
+	require.NoError(t, f.Mkdir(ctx, "dir"))
+	assert.Equal(t, []string(nil), list(t, f, "dir"))
+	_, err = f.NewObject(ctx, "dir/c.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/d.txt", ModTime: time.Now()}, "d", true)
+	assert.Equal(t, []string{"dir/d.txt"}, list(t, f, "dir"))
+	assert.Equal(t, "base c", readBase(t, baseRoot, "dir/c.txt"))
+}
+
+func TestOverlaySetModTime(t *testing.T) {
+	ctx := context.Background()
+	f, baseRoot, _ := newTestFs(t, map[string]string{"file.txt": "contents"})
+	o, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	before, err := os.Stat(filepath.Join(baseRoot, "file.txt"))
+	require.NoError(t, err)
+	newTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, o.SetModTime(ctx, newTime))
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.True(t, o.ModTime(ctx).Equal(newTime))
+	assert.Equal(t, "contents", read(t, f, "file.txt"))
+	after, err := os.Stat(filepath.Join(baseRoot, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, before.ModTime(), after.ModTime())
+}
+
+func TestOverlayReservedNames(t *testing.T) {
+	f, _, _ := newTestFs(t, nil)
+	src := object.NewStaticObjectInfo(".wh.file", time.Now(), 0, true, nil, nil)
+	_, err := f.Put(context.Background(), strings.NewReader(""), src)
+	assert.ErrorContains(t, err, "reserved")
+}
@@ -0,0 +1,47 @@
+// Test Overlay filesystem interface
+package overlay_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/backend/overlay"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*overlay.Object)(nil),
+	})
+}
+
+// TestLocal runs integration tests against a local directory
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestOverlay"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*overlay.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"UserInfo",
+			"Disconnect",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "overlay"},
+			{Name: name, Key: "base", Value: filepath.Join(os.TempDir(), "rclone-overlay-test-base")},
+			{Name: name, Key: "delta", Value: filepath.Join(os.TempDir(), "rclone-overlay-test-delta")},
+		},
+		QuickTestOK: true,
+	})
+}
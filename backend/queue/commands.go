@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rclone/rclone/fs"
+)
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "queue",
+	Short: "List the changes waiting to be made.",
+	Long: `This shows the changes waiting to be made to the wrapped remote in
+the order they will be made, as JSON. Each has the operation (put,
+delete, mkdir or rmdir), the path, when it was queued and, if it has
+failed, the number of attempts and the last error. Puts also have the
+size and modification time of the file.
+
+Usage Example:
+
+    rclone backend queue queue:
+`,
+}, {
+	Name:  "flush",
+	Short: "Make the queued changes now.",
+	Long: `This makes the queued changes without waiting for the retry interval
+and waits until they are all done. It stops with an error if one of
+them fails.
+
+Usage Example:
+
+    rclone backend flush queue:
+
+When used on a running rclone, e.g. an "rclone mount" started with
+--rc, it wakes the rclone doing the uploads
+
+    rclone rc backend/command command=flush fs=queue:
+`,
+}, {
+	Name:  "drop",
+	Short: "Remove changes from the queue without making them.",
+	Long: `This removes the queued changes to the paths given, or all the queued
+changes if "-o all" is given, without making them. The data of files
+which haven't been uploaded is lost. The changes dropped are shown as
+JSON.
+
+Usage Examples:
+
+    rclone backend drop queue: path/to/file path/to/dir
+    rclone backend drop queue: -o all
+
+A change which is being made can't be dropped.
+`,
+	Opts: map[string]string{
+		"all": "Drop all the queued changes",
+	},
+}}
+
+// entries returns the entries in the queue below the root of f with
+// their paths made relative to it
+func (f *Fs) entries(es []*entry) []entry {
+	out := []entry{}
+	f.j.mu.Lock()
+	defer f.j.mu.Unlock()
+	for _, e := range es {
+		remote, ok := f.relative(e.Path)
+		if !ok {
+			continue
+		}
+		x := *e
+		x.Path = remote
+		out = append(out, x)
+	}
+	return out
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "queue":
+		return f.entries(f.j.snapshot()), nil
+	case "flush":
+		return nil, f.j.flush(ctx)
+	case "drop":
+		_, all := opt["all"]
+		if len(arg) == 0 && !all {
+			return nil, errors.New("need paths to drop the changes to or -o all")
+		}
+		paths := make([]string, len(arg))
+		for i, remote := range arg {
+			paths[i] = f.journalPath(remote)
+		}
+		dropped := f.j.drop(func(e *entry) bool {
+			if all {
+				_, ok := f.relative(e.Path)
+				return ok
+			}
+			for _, p := range paths {
+				if e.Path == p || isIn(e.Path, p) {
+					return true
+				}
+			}
+			return false
+		})
+		return f.entries(dropped), nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
@@ -0,0 +1,654 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/lib/atexit"
+)
+
+// Operations which can be queued
+const (
+	opPut    = "put"
+	opDelete = "delete"
+	opMkdir  = "mkdir"
+	opRmdir  = "rmdir"
+)
+
+// entry is a change waiting to be made to the wrapped remote
+//
+// Seq, Op, Path, Size, Hashes and Queued don't change once the entry
+// is in the journal. The other fields are protected by journal.mu.
+type entry struct {
+	Seq       uint64            `json:"seq"`
+	Op        string            `json:"op"`
+	Path      string            `json:"path"` // relative to the root of the wrapped remote
+	Size      int64             `json:"size,omitempty"`
+	ModTime   time.Time         `json:"modTime"`
+	Hashes    map[string]string `json:"hashes,omitempty"`
+	Queued    time.Time         `json:"queued"`
+	Attempts  int               `json:"attempts,omitempty"`
+	LastError string            `json:"lastError,omitempty"`
+}
+
+// isFile returns true if the entry changes a file rather than a
+// directory
+func (e *entry) isFile() bool {
+	return e.Op == opPut || e.Op == opDelete
+}
+
+// hashes returns the hashes of the data of a put
+func (e *entry) hashes() map[hash.Type]string {
+	hashes := make(map[hash.Type]string, len(e.Hashes))
+	for name, value := range e.Hashes {
+		var ht hash.Type
+		if ht.Set(name) == nil {
+			hashes[ht] = value
+		}
+	}
+	return hashes
+}
+
+// journal is the list of changes waiting to be made to a remote,
+// kept on disk so it survives restarts
+//
+// All the Fs in the process using the same spool directory share a
+// journal, so there is only one worker making the changes.
+type journal struct {
+	dir    string
+	base   fs.Fs // the root of the wrapped remote
+	opt    *Options
+	users  int // number of Fs using the journal - protected by journalsMu
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the worker stops
+	kick   chan struct{} // wakes the worker up
+	stop   sync.Once
+	atexit atexit.FnHandle
+
+	mu      sync.Mutex
+	entries []*entry      // in the order they are to be done
+	nextSeq uint64        // sequence number of the next entry
+	running *entry        // the entry the worker is doing
+	lastErr error         // the result of the last entry done
+	changed chan struct{} // closed and remade when an entry is done
+}
+
+var (
+	journalsMu sync.Mutex
+	journals   = map[string]*journal{} // open journals by directory
+)
+
+// getJournal returns the journal kept in dir, opening it and
+// starting its worker if it isn't in use yet
+func getJournal(ctx context.Context, dir string, opt *Options) (*journal, error) {
+	journalsMu.Lock()
+	defer journalsMu.Unlock()
+	if j := journals[dir]; j != nil {
+		j.users++
+		return j, nil
+	}
+	base, err := cache.Get(ctx, opt.Remote)
+	if err == fs.ErrorIsFile {
+		return nil, fmt.Errorf("remote %q must be a directory", opt.Remote)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", opt.Remote, err)
+	}
+	j := &journal{
+		dir:     dir,
+		base:    base,
+		opt:     opt,
+		users:   1,
+		kick:    make(chan struct{}, 1),
+		nextSeq: 1,
+		changed: make(chan struct{}),
+	}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	cache.Pin(base)
+	workerCtx := fs.CopyConfig(context.Background(), ctx)
+	workerCtx, j.cancel = context.WithCancel(workerCtx)
+	j.done = make(chan struct{})
+	go j.worker(workerCtx)
+	j.atexit = atexit.Register(func() {
+		j.close(fs.CopyConfig(context.Background(), ctx))
+	})
+	journals[dir] = j
+	return j, nil
+}
+
+// release stops using the journal, closing it if nothing else is
+func (j *journal) release(ctx context.Context) {
+	journalsMu.Lock()
+	j.users--
+	last := j.users == 0
+	if last {
+		delete(journals, j.dir)
+	}
+	journalsMu.Unlock()
+	if last {
+		j.close(ctx)
+	}
+}
+
+// close stops the worker then, if upload_on_exit is set, tries to
+// make the queued changes
+func (j *journal) close(ctx context.Context) {
+	j.stop.Do(func() {
+		j.cancel()
+		<-j.done
+		atexit.Unregister(j.atexit)
+		if j.opt.UploadOnExit {
+			j.drain(ctx)
+		}
+		cache.Unpin(j.base)
+	})
+}
+
+// metaPath returns the path of the file describing e
+func (j *journal) metaPath(e *entry) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%020d.json", e.Seq))
+}
+
+// dataPath returns the path of the file holding the data for e
+func (j *journal) dataPath(e *entry) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%020d.data", e.Seq))
+}
+
+// load reads the journal from disk
+func (j *journal) load() error {
+	err := os.MkdirAll(j.dir, 0700)
+	if err != nil {
+		return fmt.Errorf("failed to make spool directory: %w", err)
+	}
+	items, err := os.ReadDir(j.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read spool directory: %w", err)
+	}
+	haveData := map[string]bool{}
+	for _, item := range items {
+		name := item.Name()
+		switch {
+		case strings.HasPrefix(name, "spool-"):
+			// left over from an interrupted write
+			_ = os.Remove(filepath.Join(j.dir, name))
+		case strings.HasSuffix(name, ".data"):
+			haveData[name] = false
+		case strings.HasSuffix(name, ".json"):
+			e, err := readEntry(filepath.Join(j.dir, name))
+			if err != nil {
+				fs.Errorf(nil, "queue: ignoring bad journal entry: %v", err)
+				continue
+			}
+			j.entries = append(j.entries, e)
+		}
+	}
+	sort.Slice(j.entries, func(i, k int) bool {
+		return j.entries[i].Seq < j.entries[k].Seq
+	})
+	entries := j.entries[:0]
+	for _, e := range j.entries {
+		if e.Seq >= j.nextSeq {
+			j.nextSeq = e.Seq + 1
+		}
+		if e.Op == opPut {
+			dataName := filepath.Base(j.dataPath(e))
+			if _, found := haveData[dataName]; !found {
+				fs.Errorf(e.Path, "queue: dropping queued upload as its data is missing")
+				j.removeFiles(e)
+				continue
+			}
+			haveData[dataName] = true
+		}
+		entries = append(entries, e)
+	}
+	j.entries = entries
+	for name, used := range haveData {
+		if !used {
+			_ = os.Remove(filepath.Join(j.dir, name))
+		}
+	}
+	if len(j.entries) > 0 {
+		fs.Infof(nil, "queue: %d changes waiting to be made to %q", len(j.entries), j.opt.Remote)
+	}
+	return nil
+}
+
+// readEntry reads the entry in the file at path
+func readEntry(path string) (*entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	e := new(entry)
+	err = json.Unmarshal(data, e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", path, err)
+	}
+	return e, nil
+}
+
+// save writes the description of e to disk
+//
+// It is written to a temporary file first so a crash never leaves a
+// half written entry.
+func (j *journal) save(e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	metaPath := j.metaPath(e)
+	tmpPath := filepath.Join(j.dir, "spool-"+filepath.Base(metaPath))
+	err = os.WriteFile(tmpPath, data, 0600)
+	if err == nil {
+		err = os.Rename(tmpPath, metaPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
+
+// removeFiles removes the files on disk for e
+func (j *journal) removeFiles(e *entry) {
+	for _, name := range []string{j.metaPath(e), j.dataPath(e)} {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			fs.Errorf(e.Path, "queue: failed to remove journal file: %v", err)
+		}
+	}
+}
+
+// spool copies in to a local file for a put to remote
+func (j *journal) spool(ctx context.Context, remote string, in io.Reader, src fs.ObjectInfo) (*entry, string, error) {
+	hasher, err := hash.NewMultiHasherTypes(j.base.Hashes())
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := os.CreateTemp(j.dir, "spool-")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to make spool file: %w", err)
+	}
+	size, err := io.Copy(io.MultiWriter(file, hasher), in)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return nil, "", fmt.Errorf("failed to spool %q: %w", remote, err)
+	}
+	hashes := map[string]string{}
+	for ht, value := range hasher.Sums() {
+		hashes[ht.String()] = value
+	}
+	e := &entry{
+		Op:      opPut,
+		Path:    remote,
+		Size:    size,
+		ModTime: src.ModTime(ctx),
+		Hashes:  hashes,
+	}
+	return e, file.Name(), nil
+}
+
+// put spools in and queues its upload to remote
+func (j *journal) put(ctx context.Context, remote string, in io.Reader, src fs.ObjectInfo) (*entry, error) {
+	e, spool, err := j.spool(ctx, remote, in, src)
+	if err != nil {
+		return nil, err
+	}
+	return e, j.add(e, spool)
+}
+
+// queue adds the change op to path to the journal
+func (j *journal) queue(op, path string) error {
+	return j.add(&entry{
+		Op:   op,
+		Path: path,
+	}, "")
+}
+
+// add puts e on the end of the journal, moving the spool file with
+// its data into place if set
+//
+// A put or delete replaces any earlier put or delete of the same file
+// which hasn't been started.
+func (j *journal) add(e *entry, spool string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	e.Seq = j.nextSeq
+	j.nextSeq++
+	e.Queued = time.Now()
+	if spool != "" {
+		if err := os.Rename(spool, j.dataPath(e)); err != nil {
+			_ = os.Remove(spool)
+			return fmt.Errorf("failed to spool %q: %w", e.Path, err)
+		}
+	}
+	if err := j.save(e); err != nil {
+		j.removeFiles(e)
+		return err
+	}
+	if e.isFile() {
+		entries := j.entries[:0]
+		for _, old := range j.entries {
+			if old.isFile() && old.Path == e.Path && old != j.running {
+				fs.Debugf(e.Path, "queue: replacing queued %s", old.Op)
+				j.removeFiles(old)
+				continue
+			}
+			entries = append(entries, old)
+		}
+		j.entries = entries
+	}
+	j.entries = append(j.entries, e)
+	j.wake()
+	return nil
+}
+
+// wake the worker if it is waiting
+func (j *journal) wake() {
+	select {
+	case j.kick <- struct{}{}:
+	default:
+	}
+}
+
+// snapshot returns a copy of the list of entries
+func (j *journal) snapshot() []*entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]*entry(nil), j.entries...)
+}
+
+// latest returns the last queued put or delete of the file at path
+// or nil if there isn't one
+func (j *journal) latest(path string) *entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		e := j.entries[i]
+		if e.isFile() && e.Path == path {
+			return e
+		}
+	}
+	return nil
+}
+
+// contains returns true if e is still in the journal
+//
+// Call with mu held
+func (j *journal) contains(e *entry) bool {
+	for _, x := range j.entries {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
+// waitRunning waits until the worker isn't doing e
+//
+// Call with mu held
+func (j *journal) waitRunning(e *entry) {
+	for j.running == e {
+		changed := j.changed
+		j.mu.Unlock()
+		<-changed
+		j.mu.Lock()
+	}
+}
+
+// setModTime sets the modification time of the queued put e
+//
+// It returns false if e has been uploaded already.
+func (j *journal) setModTime(e *entry, t time.Time) (bool, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.waitRunning(e)
+	if !j.contains(e) {
+		return false, nil
+	}
+	e.ModTime = t
+	return true, j.save(e)
+}
+
+// modTime returns the modification time of the queued put e
+func (j *journal) modTime(e *entry) time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return e.ModTime
+}
+
+// drop removes the entries for which discard returns true from the
+// journal, returning them
+//
+// The entry being worked on isn't dropped.
+func (j *journal) drop(discard func(e *entry) bool) (dropped []*entry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries := j.entries[:0]
+	for _, e := range j.entries {
+		if e != j.running && discard(e) {
+			j.removeFiles(e)
+			dropped = append(dropped, e)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	j.entries = entries
+	j.lastErr = nil
+	close(j.changed)
+	j.changed = make(chan struct{})
+	return dropped
+}
+
+// next returns the entry to do next, marking it as running, or nil
+// if there are none
+func (j *journal) next() *entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.entries) == 0 {
+		return nil
+	}
+	j.running = j.entries[0]
+	return j.running
+}
+
+// finish records the result of doing e
+//
+// If it worked e is removed from the journal, otherwise the error is
+// noted in it so it can be seen with the queue command.
+func (j *journal) finish(e *entry, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.running = nil
+	j.lastErr = err
+	if err == nil {
+		for i, x := range j.entries {
+			if x == e {
+				j.entries = append(j.entries[:i], j.entries[i+1:]...)
+				break
+			}
+		}
+		j.removeFiles(e)
+	} else {
+		e.Attempts++
+		e.LastError = err.Error()
+		if saveErr := j.save(e); saveErr != nil {
+			fs.Errorf(e.Path, "queue: %v", saveErr)
+		}
+	}
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// worker makes the queued changes in order until ctx is cancelled
+//
+// If a change fails it waits before trying again, longer each time
+// up to max_retry_interval.
+func (j *journal) worker(ctx context.Context) {
+	defer close(j.done)
+	var wait time.Duration
+	for {
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-j.kick:
+			case <-timer.C:
+			}
+			timer.Stop()
+		}
+		e := j.next()
+		if e == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-j.kick:
+			}
+			continue
+		}
+		err := j.do(ctx, e)
+		if ctx.Err() != nil {
+			j.finish(e, ctx.Err())
+			return
+		}
+		j.finish(e, err)
+		if err != nil {
+			wait = j.backoff(wait)
+			fs.Errorf(e.Path, "queue: %s failed - will retry in %v: %v", e.Op, wait, err)
+		} else {
+			wait = 0
+		}
+	}
+}
+
+// backoff returns the time to wait after a failure if the last wait
+// was wait
+func (j *journal) backoff(wait time.Duration) time.Duration {
+	minWait, maxWait := time.Duration(j.opt.RetryInterval), time.Duration(j.opt.MaxRetryInterval)
+	wait *= 2
+	if wait < minWait {
+		wait = minWait
+	}
+	if maxWait > 0 && wait > maxWait {
+		wait = maxWait
+	}
+	return wait
+}
+
+// drain makes the queued changes until one fails
+//
+// The worker must be stopped when this is called.
+func (j *journal) drain(ctx context.Context) {
+	if n := len(j.snapshot()); n > 0 {
+		fs.Infof(nil, "queue: making %d queued changes to %q", n, j.opt.Remote)
+	}
+	for {
+		e := j.next()
+		if e == nil {
+			return
+		}
+		err := j.do(ctx, e)
+		j.finish(e, err)
+		if err != nil {
+			fs.Errorf(e.Path, "queue: %s failed - %d changes left for next time: %v", e.Op, len(j.snapshot()), err)
+			return
+		}
+	}
+}
+
+// flush wakes the worker and waits for the queue to be empty
+//
+// It returns the error if one of the changes fails.
+func (j *journal) flush(ctx context.Context) error {
+	for {
+		j.mu.Lock()
+		if len(j.entries) == 0 {
+			j.mu.Unlock()
+			return nil
+		}
+		changed := j.changed
+		j.mu.Unlock()
+		j.wake()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		j.mu.Lock()
+		err := j.lastErr
+		j.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// do makes the change in e to the wrapped remote
+func (j *journal) do(ctx context.Context, e *entry) error {
+	fs.Debugf(e.Path, "queue: %s", e.Op)
+	switch e.Op {
+	case opPut:
+		return j.upload(ctx, e)
+	case opDelete:
+		o, err := j.base.NewObject(ctx, e.Path)
+		if errors.Is(err, fs.ErrorObjectNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return o.Remove(ctx)
+	case opMkdir:
+		return j.base.Mkdir(ctx, e.Path)
+	case opRmdir:
+		entries, err := j.base.List(ctx, e.Path)
+		if errors.Is(err, fs.ErrorDirNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			fs.Logf(e.Path, "queue: not removing directory as it isn't empty")
+			return nil
+		}
+		return j.base.Rmdir(ctx, e.Path)
+	}
+	return fmt.Errorf("unknown queued operation %q", e.Op)
+}
+
+// upload uploads the data of the put e
+func (j *journal) upload(ctx context.Context, e *entry) (err error) {
+	in, err := os.Open(j.dataPath(e))
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+	src := object.NewStaticObjectInfo(e.Path, e.ModTime, e.Size, true, e.hashes(), j.base)
+	o, err := j.base.NewObject(ctx, e.Path)
+	if err == nil {
+		return o.Update(ctx, in, src)
+	}
+	_, err = j.base.Put(ctx, in, src)
+	return err
+}
@@ -0,0 +1,631 @@
+// Package queue implements a backend which queues changes locally
+// and makes them to the wrapped remote in the background
+package queue
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "queue",
+		Description: "Queue changes locally and upload them when the remote is reachable",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			Help: `Any metadata supported by the underlying remote is read once files
+have been uploaded. Metadata isn't written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to upload the files to.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name:     "spool_dir",
+			Advanced: true,
+			Help: `Local directory to keep the queue in.
+
+This holds the list of queued changes and the data of the files
+waiting to be uploaded, so it must be on a disk with enough room for
+them and which keeps its contents when the computer restarts.
+
+If empty a directory under the rclone cache directory (or
+--cache-dir) named after the remote is used.
+
+Only one rclone process can use the queue in a directory at once.`,
+		}, {
+			Name:     "retry_interval",
+			Default:  fs.Duration(30 * time.Second),
+			Advanced: true,
+			Help: `Time to wait before trying again after a change fails.
+
+The wait doubles after each failure in a row up to
+max_retry_interval. Writing a new file starts an attempt straight
+away.`,
+		}, {
+			Name:     "max_retry_interval",
+			Default:  fs.Duration(10 * time.Minute),
+			Advanced: true,
+			Help:     `Longest time to wait before trying again after a change fails.`,
+		}, {
+			Name:     "upload_on_exit",
+			Default:  true,
+			Advanced: true,
+			Help: `Try to make the queued changes when rclone exits.
+
+When rclone exits it tries to make the changes left in the queue,
+stopping at the first one which fails. The rest are made the next
+time the remote is used. Set this to false to exit straight away.`,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote           string      `config:"remote"`
+	SpoolDir         string      `config:"spool_dir"`
+	RetryInterval    fs.Duration `config:"retry_interval"`
+	MaxRetryInterval fs.Duration `config:"max_retry_interval"`
+	UploadOnExit     bool        `config:"upload_on_exit"`
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	opt      Options
+	features *fs.Features // optional features
+	wrapper  fs.Fs
+	j        *journal
+	prefix   string // path of the root of this Fs in the journal
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point queue remote at itself - check the value of the remote setting")
+	}
+	if opt.RetryInterval < 0 || opt.MaxRetryInterval < 0 {
+		return nil, errors.New("retry_interval and max_retry_interval can't be negative")
+	}
+	f := &Fs{
+		name:   name,
+		root:   rpath,
+		opt:    opt,
+		prefix: strings.Trim(rpath, "/"),
+	}
+	remotePath := fspath.JoinRootPath(opt.Remote, rpath)
+	var baseErr error
+	f.Fs, baseErr = cache.Get(ctx, remotePath)
+	if baseErr != nil && baseErr != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", remotePath, baseErr)
+	}
+	if baseErr == fs.ErrorIsFile {
+		f.prefix = parentDir(f.prefix)
+	}
+	f.j, err = getJournal(ctx, f.spoolDir(), &f.opt)
+	if err != nil {
+		return nil, err
+	}
+	// The root may be a file which hasn't been uploaded yet
+	if e := f.j.latest(f.prefix); baseErr == nil && f.prefix != "" && e != nil && e.Op == opPut {
+		f.prefix = parentDir(f.prefix)
+		remotePath = fspath.JoinRootPath(opt.Remote, f.prefix)
+		f.Fs, err = cache.Get(ctx, remotePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to make remote %q to wrap: %w", remotePath, err)
+		}
+		baseErr = fs.ErrorIsFile
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+	// Copy, Move, DirMove, Purge and PutUnchecked are left out so
+	// everything goes through the queue
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		ReadMimeType:            true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		SetTier:                 true,
+		GetTier:                 true,
+		ReadMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, f.Fs).WrapsFs(f, f.Fs)
+	return f, baseErr
+}
+
+// spoolDir returns the directory to keep the queue in
+func (f *Fs) spoolDir() string {
+	if f.opt.SpoolDir != "" {
+		return f.opt.SpoolDir
+	}
+	safeName := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, strings.TrimPrefix(f.name, ":"))
+	// Remotes made on the fly have the same name so tell them apart
+	// by what they wrap
+	sum := md5.Sum([]byte(f.opt.Remote))
+	return filepath.Join(config.GetCacheDir(), "queue", fmt.Sprintf("%s-%x", safeName, sum[:4]))
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("queue root '%s'", f.root)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// parentDir returns the parent directory of remote or "" for the root
+func parentDir(remote string) string {
+	dir := path.Dir(remote)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// isIn returns true if remote is in dir or below it
+func isIn(remote, dir string) bool {
+	return dir == "" || strings.HasPrefix(remote, dir+"/")
+}
+
+// journalPath returns the path in the journal of remote
+func (f *Fs) journalPath(remote string) string {
+	return path.Join(f.prefix, remote)
+}
+
+// relative returns the path relative to the root of f of the path p
+// in the journal
+//
+// It returns false if p isn't below the root of f.
+func (f *Fs) relative(p string) (string, bool) {
+	if f.prefix == "" {
+		return p, true
+	}
+	if !isIn(p, f.prefix) {
+		return "", false
+	}
+	return p[len(f.prefix)+1:], true
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, dir)
+	notFound := errors.Is(err, fs.ErrorDirNotFound)
+	if err != nil && !notFound {
+		return nil, err
+	}
+	// Play the queue over the listing
+	full := f.journalPath(dir)
+	files := map[string]*entry{} // last put or delete of files in dir
+	dirs := map[string]bool{}    // directories in dir, false if removed
+	made, gone := false, false   // whether dir has been made or removed
+	for _, e := range f.j.snapshot() {
+		if e.Path == full || (isIn(full, e.Path) && e.Path != full) {
+			switch {
+			case e.Op == opRmdir:
+				made, gone = false, true
+			case e.Op == opMkdir && e.Path == full:
+				made, gone = true, false
+			}
+			continue
+		}
+		if !isIn(e.Path, full) || e.Op == opDelete && parentDir(e.Path) != full {
+			continue
+		}
+		if e.Op == opPut || e.Op == opMkdir {
+			made, gone = true, false
+		}
+		if e.isFile() && parentDir(e.Path) == full {
+			files[e.Path] = e
+			continue
+		}
+		child := e.Path
+		if e.isFile() {
+			child = parentDir(child)
+		}
+		for parentDir(child) != full {
+			child = parentDir(child)
+		}
+		if e.Op == opRmdir {
+			if child == e.Path {
+				dirs[child] = false
+			}
+			continue
+		}
+		dirs[child] = true
+	}
+	if gone || (notFound && !made) {
+		return nil, fs.ErrorDirNotFound
+	}
+	out := make(fs.DirEntries, 0, len(entries)+len(files)+len(dirs))
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			if _, found := files[f.journalPath(x.Remote())]; found {
+				continue
+			}
+			entry = f.newObject(x)
+		case fs.Directory:
+			p := f.journalPath(x.Remote())
+			if shown, found := dirs[p]; found {
+				delete(dirs, p)
+				if !shown {
+					continue
+				}
+			}
+		}
+		out = append(out, entry)
+	}
+	for _, e := range files {
+		if e.Op == opPut {
+			out = append(out, f.newQueuedObject(e))
+		}
+	}
+	for p, shown := range dirs {
+		if remote, ok := f.relative(p); ok && shown {
+			out = append(out, fs.NewDir(remote, time.Now()))
+		}
+	}
+	return out, nil
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// The queued operations have to be played over the whole listing so
+// this reads the whole directory and returns it as a single page.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	return fs.ListPFallback(ctx, f, dir, callback)
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if e := f.j.latest(f.journalPath(remote)); e != nil {
+		if e.Op == opDelete {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return f.newQueuedObject(e), nil
+	}
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// The data is kept locally and uploaded by the queue.
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	e, err := f.j.put(ctx, f.journalPath(src.Remote()), in, src)
+	if err != nil {
+		return nil, err
+	}
+	return f.newQueuedObject(e), nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.j.queue(opMkdir, f.journalPath(dir))
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	entries, err := f.List(ctx, dir)
+	if err == nil && len(entries) > 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		return err
+	}
+	if err != nil {
+		// Probably offline so queue it anyway - the queue
+		// checks it is empty before removing it
+		fs.Debugf(f, "Queueing removal of %q as it can't be listed: %v", dir, err)
+	}
+	return f.j.queue(opRmdir, f.journalPath(dir))
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	if do := f.Fs.Features().ChangeNotify; do != nil {
+		do(ctx, notifyFunc, pollIntervalChan)
+	}
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	do := f.Fs.Features().PublicLink
+	if do == nil {
+		return "", errors.New("PublicLink not supported")
+	}
+	if e := f.j.latest(f.journalPath(remote)); e != nil {
+		return "", fmt.Errorf("%q is waiting in the queue", remote)
+	}
+	return do(ctx, remote, expire, unlink)
+}
+
+// Shutdown the backend, trying to make the queued changes if
+// upload_on_exit is set and closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	f.j.release(ctx)
+	do := f.Fs.Features().Shutdown
+	if do == nil {
+		return nil
+	}
+	return do(ctx)
+}
+
+// Object describes a file which is on the wrapped remote
+type Object struct {
+	fs.Object
+	f      *Fs
+	queued *queuedObject // set if the object has been updated
+}
+
+// newObject wraps o
+func (f *Fs) newObject(o fs.Object) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// Size returns the size of the file
+func (o *Object) Size() int64 {
+	if o.queued != nil {
+		return o.queued.Size()
+	}
+	return o.Object.Size()
+}
+
+// ModTime returns the modification time of the file
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if o.queued != nil {
+		return o.queued.ModTime(ctx)
+	}
+	return o.Object.ModTime(ctx)
+}
+
+// Hash returns the selected checksum of the file
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if o.queued != nil {
+		return o.queued.Hash(ctx, ht)
+	}
+	return o.Object.Hash(ctx, ht)
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	if o.queued != nil {
+		return o.queued.Open(ctx, options...)
+	}
+	return o.Object.Open(ctx, options...)
+}
+
+// SetModTime sets the modification time of the file
+//
+// This is done straight away on the wrapped remote unless the object
+// has been updated.
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	if o.queued != nil {
+		return o.queued.SetModTime(ctx, t)
+	}
+	return o.Object.SetModTime(ctx, t)
+}
+
+// Update in to the object with the modTime given of the given size
+//
+// The new data is kept locally and uploaded by the queue.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	e, err := o.f.j.put(ctx, o.f.journalPath(o.Remote()), in, src)
+	if err != nil {
+		return err
+	}
+	o.queued = o.f.newQueuedObject(e)
+	return nil
+}
+
+// Remove an object
+//
+// The removal is queued.
+func (o *Object) Remove(ctx context.Context) error {
+	return o.f.j.queue(opDelete, o.f.journalPath(o.Remote()))
+}
+
+// MimeType returns the content type of the Object if
+// known, or "" if not
+func (o *Object) MimeType(ctx context.Context) string {
+	if o.queued != nil {
+		return ""
+	}
+	if do, ok := o.Object.(fs.MimeTyper); ok {
+		return do.MimeType(ctx)
+	}
+	return ""
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	if o.queued != nil {
+		return nil, nil
+	}
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	if do, ok := o.Object.(fs.IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *Object) GetTier() string {
+	if do, ok := o.Object.(fs.GetTierer); ok {
+		return do.GetTier()
+	}
+	return ""
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	if do, ok := o.Object.(fs.SetTierer); ok {
+		return do.SetTier(tier)
+	}
+	return errors.New("SetTier not supported")
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.Object          = (*Object)(nil)
+	_ fs.MimeTyper       = (*Object)(nil)
+	_ fs.Metadataer      = (*Object)(nil)
+	_ fs.IDer            = (*Object)(nil)
+	_ fs.GetTierer       = (*Object)(nil)
+	_ fs.SetTierer       = (*Object)(nil)
+	_ fs.ObjectUnWrapper = (*Object)(nil)
+)
@@ -0,0 +1,217 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDirs returns an empty directory for the files to be uploaded to
+// and a path for the queue which the backend has to create
+func testDirs(t *testing.T) (dir, spoolDir string) {
+	return t.TempDir(), filepath.Join(t.TempDir(), "spool")
+}
+
+// newTestFs makes a queue remote uploading to dir keeping its queue
+// in spoolDir
+func newTestFs(t *testing.T, dir, spoolDir string) *Fs {
+	f := fstest.NewFs(t, fmt.Sprintf(`:queue,remote="%s",spool_dir="%s",retry_interval=1h,upload_on_exit=false:`, dir, spoolDir))
+	return f.(*Fs)
+}
+
+// read returns the contents of o
+func read(t *testing.T, o fs.Object) string {
+	in, err := o.Open(context.Background())
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+// uploaded returns the contents of remote in the wrapped directory
+// or "" if it isn't there
+func uploaded(t *testing.T, dir, remote string) string {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(remote)))
+	if os.IsNotExist(err) {
+		return ""
+	}
+	require.NoError(t, err)
+	return string(data)
+}
+
+// queued returns the queued changes as "op path"
+func queued(t *testing.T, f *Fs) (out []string) {
+	res, err := f.Command(context.Background(), "queue", nil, nil)
+	require.NoError(t, err)
+	for _, e := range res.([]entry) {
+		out = append(out, e.Op+" "+e.Path)
+	}
+	return out
+}
+
+func TestUpload(t *testing.T) {
+	ctx := context.Background()
+	dir, spoolDir := testDirs(t)
+	f := newTestFs(t, dir, spoolDir)
+
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "sub/file.txt", ModTime: time.Now()}, "hello", false)
+	assert.Eventually(t, func() bool {
+		return uploaded(t, dir, "sub/file.txt") == "hello"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	o, err := f.NewObject(ctx, "sub/file.txt")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	_, err = f.NewObject(ctx, "sub/file.txt")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+	_, err = f.Command(ctx, "flush", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", uploaded(t, dir, "sub/file.txt"))
+	assert.Nil(t, queued(t, f))
+}
+
+func TestOffline(t *testing.T) {
+	ctx := context.Background()
+	dir, spoolDir := testDirs(t)
+	// Make the remote unwritable by putting a file where its
+	// directory should be
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, os.WriteFile(dir, []byte("blocker"), 0666))
+	remoteDir := filepath.Join(dir, "files")
+	f := newTestFs(t, remoteDir, spoolDir)
+
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "a.txt", ModTime: time.Now()}, "one", false)
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "a.txt", ModTime: time.Now()}, "two", false)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/b.txt", ModTime: time.Now()}, "bee", false)
+	require.NoError(t, f.Mkdir(ctx, "empty"))
+
+	// The queued changes can be seen
+	assert.Equal(t, "two", read(t, o))
+	_, err := f.Command(ctx, "flush", nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"put a.txt", "put dir/b.txt", "mkdir empty"}, queued(t, f))
+	res, err := f.Command(ctx, "queue", nil, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, 0, res.([]entry)[0].Attempts)
+	assert.NotEqual(t, "", res.([]entry)[0].LastError)
+
+	// The queue survives a restart
+	require.NoError(t, f.Shutdown(ctx))
+	f = newTestFs(t, remoteDir, spoolDir)
+	assert.Equal(t, []string{"put a.txt", "put dir/b.txt", "mkdir empty"}, queued(t, f))
+	o, err = f.NewObject(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "two", read(t, o))
+
+	// Back online
+	require.NoError(t, os.Remove(dir))
+	_, err = f.Command(ctx, "flush", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "two", uploaded(t, remoteDir, "a.txt"))
+	assert.Equal(t, "bee", uploaded(t, remoteDir, "dir/b.txt"))
+	fi, err := os.Stat(filepath.Join(remoteDir, "empty"))
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+	assert.Nil(t, queued(t, f))
+	items, err := os.ReadDir(spoolDir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(items), "spool directory should be empty")
+}
+
+func TestListOverlay(t *testing.T) {
+	ctx := context.Background()
+	dir, spoolDir := testDirs(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.txt"), []byte("old"), 0666))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "olddir"), 0777))
+	f := newTestFs(t, dir, spoolDir)
+	// Stop the worker so nothing is uploaded
+	f.j.cancel()
+	<-f.j.done
+
+	o, err := f.NewObject(ctx, "old.txt")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "new.txt", ModTime: time.Now()}, "new", false)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "deep/down/file.txt", ModTime: time.Now()}, "deep", false)
+	require.NoError(t, f.Rmdir(ctx, "olddir"))
+	assert.ErrorIs(t, f.Rmdir(ctx, "deep"), fs.ErrorDirectoryNotEmpty)
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	sort.Sort(entries)
+	assert.Equal(t, "[deep new.txt]", fmt.Sprint(entries))
+	entries, err = f.List(ctx, "deep")
+	require.NoError(t, err)
+	assert.Equal(t, "[deep/down]", fmt.Sprint(entries))
+	_, err = f.List(ctx, "olddir")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+	assert.Equal(t, "old", uploaded(t, dir, "old.txt"))
+
+	// A delete replaces a queued put
+	o, err = f.NewObject(ctx, "new.txt")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	assert.Equal(t, []string{"delete old.txt", "put deep/down/file.txt", "rmdir olddir", "delete new.txt"}, queued(t, f))
+
+	// Dropping changes
+	res, err := f.Command(ctx, "drop", []string{"deep", "new.txt"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(res.([]entry)))
+	assert.Equal(t, []string{"delete old.txt", "rmdir olddir"}, queued(t, f))
+	_, err = f.Command(ctx, "drop", nil, map[string]string{"all": "true"})
+	require.NoError(t, err)
+	assert.Nil(t, queued(t, f))
+}
+
+func TestSubdirRoot(t *testing.T) {
+	ctx := context.Background()
+	dir, spoolDir := testDirs(t)
+	root := newTestFs(t, dir, spoolDir)
+	f, err := fs.NewFs(ctx, fmt.Sprintf(`:queue,remote="%s",spool_dir="%s",retry_interval=1h,upload_on_exit=false:sub`, dir, spoolDir))
+	require.NoError(t, err)
+	defer func() {
+		_ = f.(*Fs).Shutdown(ctx)
+	}()
+	// Both share the same queue
+	assert.Equal(t, root.j, f.(*Fs).j)
+	root.j.cancel()
+	<-root.j.done
+
+	_ = fstests.PutTestContents(ctx, t, f.(*Fs), &fstest.Item{Path: "file.txt", ModTime: time.Now()}, "sub", false)
+	assert.Equal(t, []string{"put sub/file.txt"}, queued(t, root))
+	assert.Equal(t, []string{"put file.txt"}, queued(t, f.(*Fs)))
+	o, err := root.NewObject(ctx, "sub/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "sub", read(t, o))
+}
+
+func TestQueuedFileRoot(t *testing.T) {
+	ctx := context.Background()
+	dir, spoolDir := testDirs(t)
+	root := newTestFs(t, dir, spoolDir)
+	root.j.cancel()
+	<-root.j.done
+
+	_ = fstests.PutTestContents(ctx, t, root, &fstest.Item{Path: "sub/file.txt", ModTime: time.Now()}, "queued", false)
+	f, err := fs.NewFs(ctx, fmt.Sprintf(`:queue,remote="%s",spool_dir="%s",retry_interval=1h,upload_on_exit=false:sub/file.txt`, dir, spoolDir))
+	assert.Equal(t, fs.ErrorIsFile, err)
+	defer func() {
+		_ = f.(*Fs).Shutdown(ctx)
+	}()
+	o, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", read(t, o))
+}
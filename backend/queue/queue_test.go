@@ -0,0 +1,54 @@
+// Test Queue filesystem interface
+package queue_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/backend/queue"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*queue.Object)(nil),
+	})
+}
+
+// TestLocal runs integration tests against a local directory
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestQueue"
+	dir := t.TempDir()
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*queue.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"Copy",
+			"Move",
+			"DirMove",
+			"Purge",
+			"PutUnchecked",
+			"MergeDirs",
+			"ListR",
+			"UserInfo",
+			"Disconnect",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "queue"},
+			{Name: name, Key: "remote", Value: filepath.Join(dir, "remote")},
+			{Name: name, Key: "spool_dir", Value: filepath.Join(dir, "spool")},
+		},
+		QuickTestOK: true,
+	})
+}
@@ -0,0 +1,183 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/readers"
+)
+
+// queuedObject describes a file which is waiting to be uploaded
+type queuedObject struct {
+	f      *Fs
+	e      *entry
+	remote string
+}
+
+// newQueuedObject makes an object for the queued put e
+func (f *Fs) newQueuedObject(e *entry) *queuedObject {
+	remote, _ := f.relative(e.Path)
+	return &queuedObject{
+		f:      f,
+		e:      e,
+		remote: remote,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *queuedObject) Fs() fs.Info {
+	return o.f
+}
+
+// Return a string version
+func (o *queuedObject) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *queuedObject) Remote() string {
+	return o.remote
+}
+
+// Size returns the size of the file
+func (o *queuedObject) Size() int64 {
+	return o.e.Size
+}
+
+// ModTime returns the modification time of the file
+func (o *queuedObject) ModTime(ctx context.Context) time.Time {
+	return o.f.j.modTime(o.e)
+}
+
+// Storable returns whether this object is storable
+func (o *queuedObject) Storable() bool {
+	return true
+}
+
+// Hash returns the selected checksum of the file
+func (o *queuedObject) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if !o.f.Fs.Hashes().Contains(ht) {
+		return "", hash.ErrUnsupported
+	}
+	return o.e.Hashes[ht.String()], nil
+}
+
+// Open an object for read
+//
+// This reads the local copy, or the uploaded file if it has gone
+// since the object was made.
+func (o *queuedObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.e.Size)
+		case *fs.SeekOption:
+			offset = x.Offset
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	in, err := os.Open(o.f.j.dataPath(o.e))
+	if os.IsNotExist(err) {
+		uploaded, err := o.f.Fs.NewObject(ctx, o.remote)
+		if err != nil {
+			return nil, err
+		}
+		return uploaded.Open(ctx, options...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if _, err = in.Seek(offset, io.SeekStart); err != nil {
+			_ = in.Close()
+			return nil, err
+		}
+	}
+	if limit >= 0 {
+		return readers.NewLimitedReadCloser(in, limit), nil
+	}
+	return in, nil
+}
+
+// SetModTime sets the modification time of the file
+func (o *queuedObject) SetModTime(ctx context.Context, t time.Time) error {
+	queued, err := o.f.j.setModTime(o.e, t)
+	if queued || err != nil {
+		return err
+	}
+	uploaded, err := o.f.Fs.NewObject(ctx, o.remote)
+	if err != nil {
+		return err
+	}
+	return uploaded.SetModTime(ctx, t)
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *queuedObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	e, err := o.f.j.put(ctx, o.e.Path, in, src)
+	if err != nil {
+		return err
+	}
+	o.e = e
+	return nil
+}
+
+// Remove an object
+//
+// The removal is queued.
+func (o *queuedObject) Remove(ctx context.Context) error {
+	return o.f.j.queue(opDelete, o.e.Path)
+}
+
+// MimeType returns "" as it isn't known until the file is uploaded
+func (o *queuedObject) MimeType(ctx context.Context) string {
+	return ""
+}
+
+// Metadata returns nil as it isn't known until the file is uploaded
+func (o *queuedObject) Metadata(ctx context.Context) (fs.Metadata, error) {
+	return nil, nil
+}
+
+// ID returns "" as there is no ID until the file is uploaded
+func (o *queuedObject) ID() string {
+	return ""
+}
+
+// GetTier returns "" as there is no tier until the file is uploaded
+func (o *queuedObject) GetTier() string {
+	return ""
+}
+
+// SetTier returns an error as the file hasn't been uploaded yet
+func (o *queuedObject) SetTier(tier string) error {
+	return errors.New("can't set tier on a file waiting to be uploaded")
+}
+
+// UnWrap returns nil as there is no uploaded object yet
+func (o *queuedObject) UnWrap() fs.Object {
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object          = (*queuedObject)(nil)
+	_ fs.MimeTyper       = (*queuedObject)(nil)
+	_ fs.Metadataer      = (*queuedObject)(nil)
+	_ fs.IDer            = (*queuedObject)(nil)
+	_ fs.GetTierer       = (*queuedObject)(nil)
+	_ fs.SetTierer       = (*queuedObject)(nil)
+	_ fs.ObjectUnWrapper = (*queuedObject)(nil)
+)
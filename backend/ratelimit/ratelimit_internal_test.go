@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs returns a ratelimit remote with the limits in params
+// applied to an empty directory
+func newTestFs(t *testing.T, params string) *Fs {
+	f, _ := fstest.NewWrappingFs(t, "ratelimit", params)
+	return f.(*Fs)
+}
+
+func TestLimitsOperations(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t, "ops_per_second=20,burst=1")
+
+	// data transfers are not limited
+	item := fstest.NewItem("file.txt", "hello", time.Now())
+	_ = fstests.PutTestContents(ctx, t, f, &item, "hello", false)
+
+	// each of these uses a token after the first, so should take
+	// around 4/20 s
+	start := time.Now()
+	_, err := f.List(ctx, "")
+	require.NoError(t, err)
+	_, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	require.NoError(t, f.Mkdir(ctx, "dir"))
+	require.NoError(t, f.Rmdir(ctx, "dir"))
+	_, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestUnlimitedOperations(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t, "ops_per_second=1,burst=1,operations=mkdir")
+	assert.False(t, f.limited[opList])
+	assert.True(t, f.limited[opMkdir])
+
+	// listing isn't limited so shouldn't wait
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := f.List(ctx, "")
+		require.NoError(t, err)
+	}
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestWaitCancelled(t *testing.T) {
+	f := newTestFs(t, "ops_per_second=0.001,burst=1")
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := f.List(ctx, "")
+	require.NoError(t, err)
+	cancel()
+	_, err = f.List(ctx, "")
+	assert.Error(t, err)
+}
+
+func TestBadConfig(t *testing.T) {
+	ctx := context.Background()
+	_, err := fs.NewFs(ctx, `:ratelimit,remote="/tmp",ops_per_second=0:`)
+	assert.ErrorContains(t, err, "ops_per_second")
+	_, err = fs.NewFs(ctx, `:ratelimit,remote="/tmp",operations=potato:`)
+	assert.ErrorContains(t, err, "unknown operation")
+}
@@ -0,0 +1,47 @@
+// Test Ratelimit filesystem interface
+package ratelimit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/backend/ratelimit"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*ratelimit.Object)(nil),
+	})
+}
+
+// TestLocal runs integration tests against a local directory
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestRatelimit"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*ratelimit.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"UserInfo",
+			"Disconnect",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "ratelimit"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-ratelimit-test")},
+			{Name: name, Key: "ops_per_second", Value: "1000"},
+			{Name: name, Key: "burst", Value: "10"},
+		},
+		QuickTestOK: true,
+	})
+}
@@ -0,0 +1,117 @@
+// Package api contains definitions for using the remote control API
+// of rclone
+//
+// See https://rclone.org/rc/
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Error is returned by the rc server when a call fails
+type Error struct {
+	Message string `json:"error"`
+	Path    string `json:"path"`
+	Status  int    `json:"status"`
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("rc error %d from %q: %s", e.Status, e.Path, e.Message)
+}
+
+// Time is a time which may be empty
+type Time time.Time
+
+// UnmarshalJSON turns JSON into a Time
+func (t *Time) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*t = Time{}
+		return nil
+	}
+	newT, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	*t = Time(newT)
+	return nil
+}
+
+// Item is a file or directory as returned by lsjson
+type Item struct {
+	Path     string
+	Name     string
+	Size     int64
+	MimeType string
+	ModTime  Time
+	IsDir    bool
+	Hashes   map[string]string
+	ID       string
+}
+
+// ListOpt controls the listing done by operations/list and
+// operations/stat
+type ListOpt struct {
+	Recurse    bool `json:"recurse,omitempty"`
+	NoMimeType bool `json:"noMimeType,omitempty"`
+	ShowHash   bool `json:"showHash,omitempty"`
+	FilesOnly  bool `json:"filesOnly,omitempty"`
+}
+
+// ListRequest is the input to operations/list and operations/stat
+type ListRequest struct {
+	Fs     string  `json:"fs"`
+	Remote string  `json:"remote"`
+	Opt    ListOpt `json:"opt"`
+}
+
+// ListResponse is returned by operations/list
+type ListResponse struct {
+	List []Item `json:"list"`
+}
+
+// StatResponse is returned by operations/stat
+type StatResponse struct {
+	Item *Item `json:"item"` // nil if not found
+}
+
+// FsRequest is the input to the calls which take a remote and path
+type FsRequest struct {
+	Fs     string `json:"fs"`
+	Remote string `json:"remote,omitempty"`
+}
+
+// CopyFileRequest is the input to operations/copyfile and
+// operations/movefile
+type CopyFileRequest struct {
+	SrcFs     string `json:"srcFs"`
+	SrcRemote string `json:"srcRemote"`
+	DstFs     string `json:"dstFs"`
+	DstRemote string `json:"dstRemote"`
+}
+
+// PublicLinkRequest is the input to operations/publiclink
+type PublicLinkRequest struct {
+	Fs     string `json:"fs"`
+	Remote string `json:"remote"`
+	Unlink bool   `json:"unlink,omitempty"`
+	Expire string `json:"expire,omitempty"`
+}
+
+// PublicLinkResponse is returned by operations/publiclink
+type PublicLinkResponse struct {
+	URL string `json:"url"`
+}
+
+// FsInfo is returned by operations/fsinfo
+type FsInfo struct {
+	Name      string
+	Root      string
+	String    string
+	Precision time.Duration
+	Hashes    []string
+	Features  map[string]bool
+}
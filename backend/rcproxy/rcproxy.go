@@ -0,0 +1,742 @@
+// Package rcproxy provides an interface to a remote on another rclone
+// through its remote control API
+//
+// Files are listed, copied, moved and deleted with the operations/*
+// calls, uploaded with operations/uploadfile and downloaded from the
+// files the rc server serves with --rc-serve.
+package rcproxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/rcproxy/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "rcproxy",
+		Description: "Remote on another rclone using its remote control API",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "url",
+			Help: `URL of the rc server, e.g. "http://server:5572/".
+
+The server must be started with --rc-serve so files can be read.`,
+			Required: true,
+		}, {
+			Name: "user",
+			Help: "User name to log in to the rc server with.",
+		}, {
+			Name:       "pass",
+			Help:       "Password to log in to the rc server with.",
+			IsPassword: true,
+		}, {
+			Name: "remote",
+			Help: `Remote on the rc server to use, e.g. "s3:bucket" or "drive:path".
+
+This is the name of a remote in the config of the rc server, or a
+connection string. It can't contain "]".`,
+			Required: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// Control characters can't be sent as the names of
+			// uploaded files and invalid UTF-8 bytes can't be sent
+			// in JSON.
+			Default: (encoder.Base |
+				encoder.EncodeCtl |
+				encoder.EncodeDel |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	URL    string               `config:"url"`
+	User   string               `config:"user"`
+	Pass   string               `config:"pass"`
+	Remote string               `config:"remote"`
+	Enc    encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a remote on an rc server
+type Fs struct {
+	name      string        // name of this remote
+	root      string        // the path we are working on
+	opt       Options       // parsed options
+	features  *fs.Features  // optional features
+	srv       *rest.Client  // the connection to the rc server
+	pacer     *fs.Pacer     // pacer for API calls
+	precision time.Duration // precision of the remote on the server
+	hashes    hash.Set      // hashes of the remote on the server
+	slowHash  bool          // set if hashes are slow to read
+	mimeTypes bool          // set if the remote reads mime types
+}
+
+// Object describes a file on the remote of the rc server
+type Object struct {
+	fs       *Fs                  // what this object is part of
+	remote   string               // The remote path
+	size     int64                // size of the object
+	modTime  time.Time            // modification time of the object
+	mimeType string               // mime type if known
+	hashes   map[hash.Type]string // hashes of the object if read
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("rc server %s remote %q path %q", f.opt.URL, f.opt.Remote, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return f.precision
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return f.hashes
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+//
+// The rc server returns 500 Internal Server Error for any failed
+// call, so that isn't retried.
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error reading error out of body: %w", err)
+	}
+	errResponse := &api.Error{}
+	_ = json.Unmarshal(body, errResponse)
+	if errResponse.Message == "" {
+		errResponse.Message = strings.TrimSpace(string(body))
+		if errResponse.Message == "" || len(errResponse.Message) > 1024 {
+			errResponse.Message = resp.Status
+		}
+	}
+	errResponse.Status = resp.StatusCode
+	return errResponse
+}
+
+// sentinelErrors are the errors from the remote on the server which
+// are passed on as they are
+var sentinelErrors = []error{
+	fs.ErrorDirNotFound,
+	fs.ErrorObjectNotFound,
+	fs.ErrorDirExists,
+	fs.ErrorDirectoryNotEmpty,
+	fs.ErrorIsDir,
+	fs.ErrorIsFile,
+	fs.ErrorNotAFile,
+	fs.ErrorCantPurge,
+	fs.ErrorCantSetModTime,
+}
+
+// translateError turns the errors the remote on the server returned
+// back into the errors rclone knows about
+func translateError(err error) error {
+	var rcErr *api.Error
+	if !errors.As(err, &rcErr) {
+		return err
+	}
+	for _, sentinel := range sentinelErrors {
+		if strings.HasSuffix(rcErr.Message, sentinel.Error()) {
+			return sentinel
+		}
+	}
+	return err
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.URL == "" {
+		return nil, errors.New("url must be set")
+	}
+	if opt.Remote == "" {
+		return nil, errors.New("remote must be set")
+	}
+	if strings.Contains(opt.Remote, "]") {
+		return nil, errors.New(`remote can't contain "]"`)
+	}
+	f := &Fs{
+		name:  name,
+		root:  strings.Trim(root, "/"),
+		opt:   *opt,
+		srv:   rest.NewClient(fshttp.NewClient(ctx)).SetRoot(strings.TrimRight(opt.URL, "/")),
+		pacer: fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+	}
+	f.srv.SetErrorHandler(errorHandler)
+	if opt.User != "" || opt.Pass != "" {
+		pass, err := obscure.Reveal(opt.Pass)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt password: %w", err)
+		}
+		f.srv.SetUserPass(opt.User, pass)
+	}
+
+	// Find out what the remote on the server can do
+	var info api.FsInfo
+	err = f.call(ctx, "operations/fsinfo", api.FsRequest{Fs: opt.Remote}, &info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read info about %q: %w", opt.Remote, err)
+	}
+	f.precision = info.Precision
+	if f.precision == 0 {
+		f.precision = fs.ModTimeNotSupported
+	}
+	for _, name := range info.Hashes {
+		var ht hash.Type
+		if err := ht.Set(name); err == nil {
+			f.hashes.Add(ht)
+		}
+	}
+	f.slowHash = info.Features["SlowHash"]
+	f.mimeTypes = info.Features["ReadMimeType"]
+	f.features = (&fs.Features{
+		CaseInsensitive:         info.Features["CaseInsensitive"],
+		DuplicateFiles:          info.Features["DuplicateFiles"],
+		ReadMimeType:            info.Features["ReadMimeType"],
+		CanHaveEmptyDirectories: info.Features["CanHaveEmptyDirectories"],
+		BucketBased:             info.Features["BucketBased"],
+		BucketBasedRootOK:       info.Features["BucketBasedRootOK"],
+		SlowHash:                info.Features["SlowHash"],
+	}).Fill(ctx, f)
+	for _, feature := range []string{"About", "PublicLink"} {
+		if !info.Features[feature] {
+			f.features.Disable(feature)
+		}
+	}
+
+	// Check to see if the root is a file
+	if f.root != "" {
+		item, err := f.stat(ctx, f.path(""))
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// call the rc method with in returning the result in out
+func (f *Fs) call(ctx context.Context, method string, in, out interface{}) error {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/" + method,
+	}
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, in, out)
+		return shouldRetry(ctx, resp, err)
+	})
+	return translateError(err)
+}
+
+// path returns the path on the remote of the server of remote
+func (f *Fs) path(remote string) string {
+	return f.opt.Enc.FromStandardPath(path.Join(f.root, remote))
+}
+
+// remote returns the remote of the path p on the remote of the server
+func (f *Fs) remote(p string) string {
+	p = f.opt.Enc.ToStandardPath(p)
+	if f.root == "" {
+		return p
+	}
+	return strings.TrimPrefix(p, f.root+"/")
+}
+
+// listOpt returns the options for listing
+func (f *Fs) listOpt() api.ListOpt {
+	return api.ListOpt{
+		NoMimeType: !f.mimeTypes,
+		ShowHash:   !f.slowHash && f.hashes.Count() > 0,
+	}
+}
+
+// stat returns the file at p or nil if it isn't found
+func (f *Fs) stat(ctx context.Context, p string) (*api.Item, error) {
+	opt := f.listOpt()
+	opt.ShowHash = f.hashes.Count() > 0
+	opt.FilesOnly = true
+	req := api.ListRequest{
+		Fs:     f.opt.Remote,
+		Remote: p,
+		Opt:    opt,
+	}
+	var result api.StatResponse
+	err := f.call(ctx, "operations/stat", &req, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Item, nil
+}
+
+// list calls fn for each file and directory in dir, recursively if
+// recurse is set
+func (f *Fs) list(ctx context.Context, dir string, recurse bool, fn func(entry fs.DirEntry)) error {
+	req := api.ListRequest{
+		Fs:     f.opt.Remote,
+		Remote: f.path(dir),
+		Opt:    f.listOpt(),
+	}
+	req.Opt.Recurse = recurse
+	var result api.ListResponse
+	err := f.call(ctx, "operations/list", &req, &result)
+	if err != nil {
+		return err
+	}
+	for i := range result.List {
+		item := &result.List[i]
+		remote := f.remote(item.Path)
+		if item.IsDir {
+			d := fs.NewDir(remote, time.Time(item.ModTime)).SetID(item.ID)
+			if item.Size >= 0 {
+				d.SetSize(item.Size)
+			}
+			fn(d)
+		} else {
+			fn(f.newObject(remote, item))
+		}
+	}
+	return nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.list(ctx, dir, false, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	return entries, err
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	// The rc server sends the directories of bucket based remotes
+	// after the objects in them, so put them first as walk expects.
+	var dirs, objects fs.DirEntries
+	err := f.list(ctx, dir, true, func(entry fs.DirEntry) {
+		if _, isDir := entry.(fs.Directory); isDir {
+			dirs = append(dirs, entry)
+		} else {
+			objects = append(objects, entry)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return callback(append(dirs, objects...))
+}
+
+// newObject makes an Object for remote from item
+func (f *Fs) newObject(remote string, item *api.Item) *Object {
+	o := &Object{
+		fs:       f,
+		remote:   remote,
+		size:     item.Size,
+		modTime:  time.Time(item.ModTime),
+		mimeType: item.MimeType,
+	}
+	if item.Hashes != nil {
+		o.hashes = make(map[hash.Type]string, len(item.Hashes))
+		for name, sum := range item.Hashes {
+			var ht hash.Type
+			if err := ht.Set(name); err == nil {
+				o.hashes[ht] = sum
+			}
+		}
+	}
+	return o
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	item, err := f.stat(ctx, f.path(remote))
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.newObject(remote, item), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.call(ctx, "operations/mkdir", api.FsRequest{Fs: f.opt.Remote, Remote: f.path(dir)}, nil)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.call(ctx, "operations/rmdir", api.FsRequest{Fs: f.opt.Remote, Remote: f.path(dir)}, nil)
+}
+
+// Purge deletes all the files in the directory
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	return f.call(ctx, "operations/purge", api.FsRequest{Fs: f.opt.Remote, Remote: f.path(dir)}, nil)
+}
+
+// sameServer returns the Object src if it is on the same rc server
+// as f
+func (f *Fs) sameServer(src fs.Object) (*Object, bool) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.opt.URL != f.opt.URL || srcObj.fs.opt.User != f.opt.User {
+		return nil, false
+	}
+	return srcObj, true
+}
+
+// copyOrMove asks the rc server to copy or move src to remote
+func (f *Fs) copyOrMove(ctx context.Context, method string, srcObj *Object, remote string) (fs.Object, error) {
+	req := api.CopyFileRequest{
+		SrcFs:     srcObj.fs.opt.Remote,
+		SrcRemote: srcObj.fs.path(srcObj.remote),
+		DstFs:     f.opt.Remote,
+		DstRemote: f.path(remote),
+	}
+	err := f.call(ctx, method, &req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.NewObject(ctx, remote)
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// The rc server copies the file, server-side if its remotes can.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := f.sameServer(src)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	return f.copyOrMove(ctx, "operations/copyfile", srcObj, remote)
+}
+
+// Move src to this remote using server-side move operations.
+//
+// The rc server moves the file, server-side if its remotes can.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := f.sameServer(src)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	return f.copyOrMove(ctx, "operations/movefile", srcObj, remote)
+}
+
+// About gets quota information from the remote on the rc server
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	var usage fs.Usage
+	err := f.call(ctx, "operations/about", api.FsRequest{Fs: f.opt.Remote}, &usage)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	req := api.PublicLinkRequest{
+		Fs:     f.opt.Remote,
+		Remote: f.path(remote),
+		Unlink: unlink,
+	}
+	if expire < fs.DurationOff {
+		req.Expire = expire.String()
+	}
+	var result api.PublicLinkResponse
+	err := f.call(ctx, "operations/publiclink", &req, &result)
+	if err != nil {
+		return "", err
+	}
+	return result.URL, nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+//
+// If the hashes weren't read in the listing they are read now.
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if !o.fs.hashes.Contains(t) {
+		return "", hash.ErrUnsupported
+	}
+	if o.hashes == nil {
+		item, err := o.fs.stat(ctx, o.fs.path(o.remote))
+		if err != nil {
+			return "", err
+		}
+		if item == nil {
+			return "", fs.ErrorObjectNotFound
+		}
+		o.setMetadata(item)
+	}
+	return o.hashes[t], nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the object
+//
+// The rc API has no way of doing this.
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType(ctx context.Context) string {
+	return o.mimeType
+}
+
+// setMetadata sets the metadata of the object from item
+func (o *Object) setMetadata(item *api.Item) {
+	newO := o.fs.newObject(o.remote, item)
+	o.size = newO.size
+	o.modTime = newO.modTime
+	o.mimeType = newO.mimeType
+	o.hashes = newO.hashes
+	if o.hashes == nil {
+		o.hashes = map[hash.Type]string{}
+	}
+}
+
+// Open an object for read
+//
+// The file is read from the rc server as it serves it with
+// --rc-serve.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	fs.FixRangeOption(options, o.size)
+	opts := rest.Opts{
+		Method:  "GET",
+		Path:    rest.URLPathEscape("/[" + o.fs.opt.Remote + "]/" + o.fs.path(o.remote)),
+		Options: options,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	dir, leaf := path.Split(o.fs.path(o.remote))
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/operations/uploadfile",
+		Parameters: url.Values{
+			"fs":      {o.fs.opt.Remote},
+			"remote":  {strings.TrimSuffix(dir, "/")},
+			"modTime": {src.ModTime(ctx).Format(time.RFC3339Nano)},
+		},
+		Body:                 in,
+		MultipartParams:      url.Values{},
+		MultipartContentName: "file",
+		MultipartFileName:    leaf,
+		Options:              options,
+	}
+	err := o.fs.pacer.CallNoRetry(func() (bool, error) {
+		resp, err := o.fs.srv.CallJSON(ctx, &opts, nil, nil)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload: %w", translateError(err))
+	}
+	item, err := o.fs.stat(ctx, o.fs.path(o.remote))
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		return errors.New("file not found after upload")
+	}
+	o.setMetadata(item)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return o.fs.call(ctx, "operations/deletefile", api.FsRequest{Fs: o.fs.opt.Remote, Remote: o.fs.path(o.remote)}, nil)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs           = (*Fs)(nil)
+	_ fs.PutStreamer  = (*Fs)(nil)
+	_ fs.ListRer      = (*Fs)(nil)
+	_ fs.Copier       = (*Fs)(nil)
+	_ fs.Mover        = (*Fs)(nil)
+	_ fs.Purger       = (*Fs)(nil)
+	_ fs.Abouter      = (*Fs)(nil)
+	_ fs.PublicLinker = (*Fs)(nil)
+	_ fs.Object       = (*Object)(nil)
+	_ fs.MimeTyper    = (*Object)(nil)
+)
@@ -0,0 +1,171 @@
+package rcproxy
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/memory"
+	"github.com/rclone/rclone/backend/rcproxy/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	_ "github.com/rclone/rclone/fs/operations" // register the operations/* calls
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/fs/rc/rcserver"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	serverOnce sync.Once
+	serverURL  string
+	serverErr  error
+)
+
+// startServer starts the rc server serving the remotes of this
+// process
+//
+// The rc server registers itself on the default mux so it can only
+// be started once.
+func startServer(t *testing.T) string {
+	serverOnce.Do(func() {
+		opt := rc.DefaultOpt
+		opt.Enabled = true
+		opt.Serve = true
+		opt.HTTPOptions.ListenAddr = "localhost:0"
+		opt.HTTPOptions.BasicUser = "user"
+		opt.HTTPOptions.BasicPass = "secret"
+		var s *rcserver.Server
+		s, serverErr = rcserver.Start(context.Background(), &opt)
+		if serverErr == nil {
+			serverURL = s.URL()
+		}
+	})
+	require.NoError(t, serverErr)
+	return serverURL
+}
+
+// testConfig returns the config to use the rc server with remote
+func testConfig(t *testing.T, remote string) configmap.Simple {
+	return configmap.Simple{
+		"url":    startServer(t),
+		"user":   "user",
+		"pass":   obscure.MustObscure("secret"),
+		"remote": remote,
+	}
+}
+
+// TestLocal runs the integration tests against an rc server in
+// this process using a memory remote
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("skipping as -remote is set")
+	}
+	const name = "TestRCProxyLocal"
+	var extra []fstests.ExtraConfigItem
+	for k, v := range testConfig(t, ":memory:") {
+		extra = append(extra, fstests.ExtraConfigItem{Name: name, Key: k, Value: v})
+	}
+	extra = append(extra, fstests.ExtraConfigItem{Name: name, Key: "type", Value: "rcproxy"})
+	fstests.Run(t, &fstests.Opt{
+		RemoteName:  name + ":",
+		NilObject:   (*Object)(nil),
+		ExtraConfig: extra,
+		QuickTestOK: true,
+	})
+}
+
+// newTestFs makes an Fs using the rc server
+func newTestFs(t *testing.T, remote string) *Fs {
+	f, err := NewFs(context.Background(), t.Name(), "dir", testConfig(t, remote))
+	require.NoError(t, err)
+	return f.(*Fs)
+}
+
+// put uploads contents to remote
+func put(t *testing.T, f *Fs, remote, contents string, modTime time.Time) fs.Object {
+	src := object.NewStaticObjectInfo(remote, modTime, int64(len(contents)), true, nil, nil)
+	o, err := f.Put(context.Background(), strings.NewReader(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+func TestFeatures(t *testing.T) {
+	f := newTestFs(t, ":memory:features")
+	assert.Equal(t, time.Nanosecond, f.Precision())
+	assert.True(t, f.Hashes().Contains(hash.MD5))
+	assert.True(t, f.Features().BucketBased)
+	assert.Nil(t, f.Features().About)
+	assert.Nil(t, f.Features().PublicLink)
+}
+
+func TestPutOpenRange(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t, ":memory:putopen")
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	o := put(t, f, "sub/file name.txt", "hello world", modTime)
+	assert.Equal(t, int64(11), o.Size())
+	assert.True(t, modTime.Equal(o.ModTime(ctx)))
+
+	o, err := f.NewObject(ctx, "sub/file name.txt")
+	require.NoError(t, err)
+	in, err := o.Open(ctx, &fs.RangeOption{Start: 6, End: 8})
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "wor", string(data))
+
+	md5, err := o.Hash(ctx, hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", md5)
+}
+
+func TestRootIsFile(t *testing.T) {
+	f := newTestFs(t, ":memory:isfile")
+	put(t, f, "file.txt", "contents", time.Now())
+	f2, err := NewFs(context.Background(), t.Name(), "dir/file.txt", testConfig(t, ":memory:isfile"))
+	assert.Equal(t, fs.ErrorIsFile, err)
+	require.NotNil(t, f2)
+	assert.Equal(t, "dir", f2.Root())
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t, ":memory:errors")
+	_, err := f.NewObject(ctx, "missing")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	_, err = f.List(ctx, "missing")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	_, err = NewFs(ctx, t.Name(), "", configmap.Simple{"url": "http://localhost/", "remote": "[bad]"})
+	assert.Error(t, err)
+}
+
+func TestTranslateError(t *testing.T) {
+	for _, test := range []struct {
+		in   error
+		want error
+	}{
+		{&api.Error{Message: "directory not found"}, fs.ErrorDirNotFound},
+		{&api.Error{Message: `rmdir "x": directory not empty`}, fs.ErrorDirectoryNotEmpty},
+		{&api.Error{Message: "failed"}, nil},
+		{errors.New("directory not found"), nil},
+	} {
+		got := translateError(test.in)
+		if test.want == nil {
+			assert.Equal(t, test.in, got)
+		} else {
+			assert.Equal(t, test.want, got)
+		}
+	}
+}
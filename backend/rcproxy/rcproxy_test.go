@@ -0,0 +1,17 @@
+// Test rcproxy filesystem interface
+package rcproxy_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/rcproxy"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestRCProxy:",
+		NilObject:  (*rcproxy.Object)(nil),
+	})
+}
@@ -0,0 +1,80 @@
+// Package api contains definitions for using the release APIs of
+// GitHub and Gitea
+//
+// See https://docs.github.com/en/rest/releases and
+// https://gitea.com/api/swagger
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Error is returned by the server when a request fails
+type Error struct {
+	Message          string        `json:"message"`
+	DocumentationURL string        `json:"documentation_url,omitempty"` // GitHub
+	URL              string        `json:"url,omitempty"`               // Gitea
+	Errors           []ErrorDetail `json:"errors,omitempty"`
+	StatusCode       int           `json:"-"`
+}
+
+// ErrorDetail is more information about an error
+type ErrorDetail struct {
+	Resource string `json:"resource,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Error satisfies the error interface
+func (e *Error) Error() string {
+	out := fmt.Sprintf("release API error %d: %s", e.StatusCode, e.Message)
+	var details []string
+	for _, detail := range e.Errors {
+		if detail.Message != "" {
+			details = append(details, detail.Message)
+		} else if detail.Code != "" {
+			details = append(details, fmt.Sprintf("%s %s %s", detail.Resource, detail.Field, detail.Code))
+		}
+	}
+	if len(details) > 0 {
+		out += " (" + strings.Join(details, ", ") + ")"
+	}
+	return out
+}
+
+// Release is a release of a repository
+type Release struct {
+	ID              int64      `json:"id"`
+	TagName         string     `json:"tag_name"`
+	TargetCommitish string     `json:"target_commitish,omitempty"`
+	Name            string     `json:"name"`
+	Draft           bool       `json:"draft"`
+	Prerelease      bool       `json:"prerelease"`
+	CreatedAt       time.Time  `json:"created_at"`
+	PublishedAt     *time.Time `json:"published_at,omitempty"`
+	UploadURL       string     `json:"upload_url,omitempty"`
+	Assets          []Asset    `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	ID                 int64     `json:"id"`
+	Name               string    `json:"name"`
+	Size               int64     `json:"size"`
+	ContentType        string    `json:"content_type,omitempty"` // GitHub only
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at,omitempty"` // GitHub only
+	BrowserDownloadURL string    `json:"browser_download_url"`
+	Digest             string    `json:"digest,omitempty"` // GitHub only, e.g. "sha256:..."
+}
+
+// CreateRelease is the request to make a release
+type CreateRelease struct {
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	Name            string `json:"name"`
+	Draft           bool   `json:"draft"`
+}
@@ -0,0 +1,858 @@
+// Package releases provides an interface to the release assets of a
+// GitHub or Gitea repository
+//
+// Each release is a directory named after its tag and the assets
+// attached to it are the files in the directory.
+package releases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/releases/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+
+	providerGitHub = "github"
+	providerGitea  = "gitea"
+
+	gitHubAPIURL = "https://api.github.com"
+	giteaAPIPath = "/api/v1"
+
+	gitHubListChunk = 100
+	giteaListChunk  = 50
+
+	// longest wait for a rate limit to reset before giving up
+	maxRateLimitSleep = 60 * time.Second
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "releases",
+		Description: "GitHub or Gitea release assets",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:    "provider",
+			Help:    "Which service hosts the repository.",
+			Default: providerGitHub,
+			Examples: []fs.OptionExample{{
+				Value: providerGitHub,
+				Help:  "GitHub or GitHub Enterprise Server",
+			}, {
+				Value: providerGitea,
+				Help:  "Gitea or Forgejo, e.g. Codeberg",
+			}},
+		}, {
+			Name: "url",
+			Help: `URL of the server.
+
+Leave blank for github.com. For GitHub Enterprise Server use the URL
+of the API, e.g. "https://github.example.com/api/v3". For Gitea use
+the URL of the server, e.g. "https://codeberg.org".`,
+		}, {
+			Name:     "owner",
+			Help:     "User or organization the repository belongs to.",
+			Required: true,
+		}, {
+			Name:     "repo",
+			Help:     "Name of the repository.",
+			Required: true,
+		}, {
+			Name: "token",
+			Help: `Access token.
+
+This is needed to upload and delete assets, and to read the releases
+of private repositories. The token needs write access to the contents
+of the repository to change releases.`,
+			IsPassword: true,
+		}, {
+			Name: "target",
+			Help: `Branch or commit to make the tag of new releases from.
+
+Releases are made when files are uploaded to a release which doesn't
+exist. If the tag doesn't exist it is made from this, or from the
+default branch of the repository if empty.`,
+			Advanced: true,
+		}, {
+			Name: "draft",
+			Help: `Make new releases as drafts.
+
+Draft releases can only be seen by users who can write to the
+repository, so assets can be uploaded before the release is
+published.`,
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// Tags may contain slashes which must be encoded as
+			// releases are directories. Encode invalid UTF-8 bytes
+			// as json doesn't handle them properly.
+			Default: (encoder.Base |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Provider string               `config:"provider"`
+	URL      string               `config:"url"`
+	Owner    string               `config:"owner"`
+	Repo     string               `config:"repo"`
+	Token    string               `config:"token"`
+	Target   string               `config:"target"`
+	Draft    bool                 `config:"draft"`
+	Enc      encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents the releases of a repository
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	srv      *rest.Client // the connection to the server
+	pacer    *fs.Pacer    // pacer for API calls
+	gitea    bool         // set if talking to Gitea rather than GitHub
+	repoPath string       // path of the repository in the API
+}
+
+// Object describes a release asset
+type Object struct {
+	fs        *Fs       // what this object is part of
+	remote    string    // The remote path
+	releaseID int64     // ID of the release the asset is in
+	asset     api.Asset // the asset
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("releases of %s/%s root '%s'", f.opt.Owner, f.opt.Repo, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// Hashes returns the supported hash types of the filesystem
+//
+// GitHub gives the SHA-256 of assets but Gitea doesn't.
+func (f *Fs) Hashes() hash.Set {
+	if f.gitea {
+		return hash.Set(hash.None)
+	}
+	return hash.NewHashSet(hash.SHA256)
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// rateLimitWait returns how long to wait if resp says the rate limit
+// has been exceeded or 0 if it doesn't
+func rateLimitWait(resp *http.Response) time.Duration {
+	if resp == nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests) {
+		return 0
+	}
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		seconds, err := strconv.Atoi(retryAfter)
+		if err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err == nil {
+			if wait := time.Until(time.Unix(reset, 0)); wait > 0 {
+				return wait
+			}
+			return time.Second
+		}
+	}
+	return 0
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	if wait := rateLimitWait(resp); wait > 0 {
+		if wait <= maxRateLimitSleep {
+			fs.Debugf(nil, "Sleeping for %v to wait for the rate limit", wait)
+			time.Sleep(wait)
+			return true, err
+		}
+		return false, fserrors.NewErrorRetryAfter(wait)
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error reading error out of body: %w", err)
+	}
+	errResponse := &api.Error{}
+	if json.Unmarshal(body, errResponse) != nil || errResponse.Message == "" {
+		errResponse.Message = strings.TrimSpace(string(body))
+		if errResponse.Message == "" || len(errResponse.Message) > 1024 {
+			errResponse.Message = resp.Status
+		}
+	}
+	errResponse.StatusCode = resp.StatusCode
+	return errResponse
+}
+
+// isNotFound returns true if err is a 404 error
+func isNotFound(err error) bool {
+	var apiErr *api.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	f := &Fs{
+		name:     name,
+		root:     strings.Trim(root, "/"),
+		opt:      *opt,
+		srv:      rest.NewClient(fshttp.NewClient(ctx)),
+		pacer:    fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+		repoPath: "/repos/" + rest.URLPathEscape(opt.Owner) + "/" + rest.URLPathEscape(opt.Repo),
+	}
+	rootURL := strings.TrimRight(opt.URL, "/")
+	switch opt.Provider {
+	case providerGitHub:
+		if rootURL == "" {
+			rootURL = gitHubAPIURL
+		}
+		f.srv.SetHeader("Accept", "application/vnd.github+json")
+		f.srv.SetHeader("X-GitHub-Api-Version", "2022-11-28")
+	case providerGitea:
+		if rootURL == "" {
+			return nil, errors.New("url must be set for gitea")
+		}
+		f.gitea = true
+		if !strings.HasSuffix(rootURL, giteaAPIPath) {
+			rootURL += giteaAPIPath
+		}
+		f.srv.SetHeader("Accept", "application/json")
+	default:
+		return nil, fmt.Errorf("unknown provider %q", opt.Provider)
+	}
+	f.srv.SetRoot(rootURL)
+	f.srv.SetErrorHandler(errorHandler)
+	if opt.Token != "" {
+		token, err := obscure.Reveal(opt.Token)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt token: %w", err)
+		}
+		f.srv.SetHeader("Authorization", "token "+token)
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+		ReadMimeType:            true,
+		WriteMimeType:           !f.gitea,
+	}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	if _, leaf, err := f.split(""); err == nil && leaf != "" {
+		if _, err := f.NewObject(ctx, ""); err == nil {
+			f.root = path.Dir(f.root)
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// split turns remote into the tag of the release and name of the
+// asset
+//
+// The root of the repository has an empty tag and a release has an
+// empty name. It returns fs.ErrorDirNotFound if remote is too deep to
+// be either.
+func (f *Fs) split(remote string) (tag, name string, err error) {
+	p := path.Join(f.root, remote)
+	if p == "" || p == "." {
+		return "", "", nil
+	}
+	parts := strings.Split(p, "/")
+	switch len(parts) {
+	case 1:
+		return f.opt.Enc.FromStandardName(parts[0]), "", nil
+	case 2:
+		return f.opt.Enc.FromStandardName(parts[0]), f.opt.Enc.FromStandardName(parts[1]), nil
+	}
+	return "", "", fs.ErrorDirNotFound
+}
+
+// listReleases calls fn for each release
+//
+// If fn returns true then it stops.
+func (f *Fs) listReleases(ctx context.Context, fn func(release *api.Release) bool) error {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       f.repoPath + "/releases",
+		Parameters: url.Values{},
+	}
+	if f.gitea {
+		opts.Parameters.Set("limit", strconv.Itoa(giteaListChunk))
+	} else {
+		opts.Parameters.Set("per_page", strconv.Itoa(gitHubListChunk))
+	}
+	for page := 1; ; page++ {
+		opts.Parameters.Set("page", strconv.Itoa(page))
+		var releases []api.Release
+		err := f.pacer.Call(func() (bool, error) {
+			resp, err := f.srv.CallJSON(ctx, &opts, nil, &releases)
+			return shouldRetry(ctx, resp, err)
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't list releases: %w", err)
+		}
+		if len(releases) == 0 {
+			return nil
+		}
+		for i := range releases {
+			if fn(&releases[i]) {
+				return nil
+			}
+		}
+	}
+}
+
+// findRelease returns the release with tag
+//
+// It returns fs.ErrorDirNotFound if there isn't one.
+func (f *Fs) findRelease(ctx context.Context, tag string) (*api.Release, error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   f.repoPath + "/releases/tags/" + rest.URLPathEscape(tag),
+	}
+	var release api.Release
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, nil, &release)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err == nil {
+		return &release, nil
+	}
+	if !isNotFound(err) {
+		return nil, fmt.Errorf("couldn't read release: %w", err)
+	}
+	// Draft releases can't be found by tag so look for them in the
+	// listing
+	var found *api.Release
+	err = f.listReleases(ctx, func(release *api.Release) bool {
+		if release.TagName == tag {
+			found = release
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, fs.ErrorDirNotFound
+	}
+	return found, nil
+}
+
+// createRelease makes a release with tag
+func (f *Fs) createRelease(ctx context.Context, tag string) (*api.Release, error) {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   f.repoPath + "/releases",
+	}
+	req := api.CreateRelease{
+		TagName:         tag,
+		TargetCommitish: f.opt.Target,
+		Name:            tag,
+		Draft:           f.opt.Draft,
+	}
+	var release api.Release
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, &req, &release)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't make release: %w", err)
+	}
+	return &release, nil
+}
+
+// deleteRelease deletes the release with id
+//
+// This deletes the assets in the release but not its tag.
+func (f *Fs) deleteRelease(ctx context.Context, id int64) error {
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       f.repoPath + "/releases/" + strconv.FormatInt(id, 10),
+		NoResponse: true,
+	}
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't delete release: %w", err)
+	}
+	return nil
+}
+
+// releaseTime returns the modification time of release
+func releaseTime(release *api.Release) time.Time {
+	if release.PublishedAt != nil {
+		return *release.PublishedAt
+	}
+	return release.CreatedAt
+}
+
+// newObject makes an Object for asset in release
+func (f *Fs) newObject(remote string, release *api.Release, asset *api.Asset) *Object {
+	return &Object{
+		fs:        f,
+		remote:    remote,
+		releaseID: release.ID,
+		asset:     *asset,
+	}
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	tag, name, err := f.split(dir)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		return nil, fs.ErrorDirNotFound
+	}
+	if tag == "" {
+		err = f.listReleases(ctx, func(release *api.Release) bool {
+			remote := path.Join(dir, f.opt.Enc.ToStandardName(release.TagName))
+			d := fs.NewDir(remote, releaseTime(release)).SetID(strconv.FormatInt(release.ID, 10)).SetItems(int64(len(release.Assets)))
+			entries = append(entries, d)
+			return false
+		})
+		return entries, err
+	}
+	release, err := f.findRelease(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+	for i := range release.Assets {
+		asset := &release.Assets[i]
+		remote := path.Join(dir, f.opt.Enc.ToStandardName(asset.Name))
+		entries = append(entries, f.newObject(remote, release, asset))
+	}
+	return entries, nil
+}
+
+// findAsset returns the release and asset for remote
+func (f *Fs) findAsset(ctx context.Context, remote string) (*api.Release, *api.Asset, error) {
+	tag, name, err := f.split(remote)
+	if err != nil || name == "" {
+		return nil, nil, fs.ErrorObjectNotFound
+	}
+	release, err := f.findRelease(ctx, tag)
+	if err == fs.ErrorDirNotFound {
+		return nil, nil, fs.ErrorObjectNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range release.Assets {
+		if release.Assets[i].Name == name {
+			return release, &release.Assets[i], nil
+		}
+	}
+	return release, nil, fs.ErrorObjectNotFound
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	release, asset, err := f.findAsset(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(remote, release, asset), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Making a directory in the root makes a release.
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	tag, name, err := f.split(dir)
+	if err != nil || name != "" {
+		return errors.New("can't make directories in releases")
+	}
+	if tag == "" {
+		return nil
+	}
+	_, err = f.findRelease(ctx, tag)
+	if err == fs.ErrorDirNotFound {
+		_, err = f.createRelease(ctx, tag)
+	}
+	return err
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Removing a release deletes it but leaves its tag.
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	tag, name, err := f.split(dir)
+	if err != nil || name != "" {
+		return fs.ErrorDirNotFound
+	}
+	if tag == "" {
+		return nil
+	}
+	release, err := f.findRelease(ctx, tag)
+	if err != nil {
+		return err
+	}
+	if len(release.Assets) != 0 {
+		return fs.ErrorDirectoryNotEmpty
+	}
+	return f.deleteRelease(ctx, release.ID)
+}
+
+// Purge deletes all the files in the directory
+//
+// Only releases can be purged. This deletes the release and its
+// assets but leaves its tag.
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	tag, name, err := f.split(dir)
+	if err != nil || tag == "" || name != "" {
+		return fs.ErrorCantPurge
+	}
+	release, err := f.findRelease(ctx, tag)
+	if err != nil {
+		return err
+	}
+	return f.deleteRelease(ctx, release.ID)
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+//
+// This is the download URL of the asset, which is only public if
+// the repository is.
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	if unlink {
+		return "", errors.New("can't remove links to release assets")
+	}
+	_, asset, err := f.findAsset(ctx, remote)
+	if err != nil {
+		return "", err
+	}
+	return asset.BrowserDownloadURL, nil
+}
+
+// upload sends in as an asset called name to release
+func (f *Fs) upload(ctx context.Context, release *api.Release, name string, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption) (*api.Asset, error) {
+	var opts rest.Opts
+	if f.gitea {
+		opts = rest.Opts{
+			Method:               "POST",
+			Path:                 f.repoPath + "/releases/" + strconv.FormatInt(release.ID, 10) + "/assets",
+			Body:                 in,
+			MultipartParams:      url.Values{},
+			MultipartContentName: "attachment",
+			MultipartFileName:    name,
+			Parameters:           url.Values{"name": {name}},
+			Options:              options,
+		}
+	} else {
+		size := src.Size()
+		if size < 0 {
+			return nil, errors.New("can't upload files of unknown size")
+		}
+		uploadURL := release.UploadURL
+		if i := strings.IndexByte(uploadURL, '{'); i >= 0 {
+			uploadURL = uploadURL[:i]
+		}
+		if uploadURL == "" {
+			return nil, errors.New("release has no upload URL")
+		}
+		opts = rest.Opts{
+			Method:        "POST",
+			RootURL:       uploadURL,
+			Body:          in,
+			ContentType:   fs.MimeType(ctx, src),
+			ContentLength: &size,
+			Parameters:    url.Values{"name": {name}},
+			Options:       options,
+		}
+	}
+	var asset api.Asset
+	err := f.pacer.CallNoRetry(func() (bool, error) {
+		resp, err := f.srv.CallJSON(ctx, &opts, nil, &asset)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't upload asset: %w", err)
+	}
+	if asset.Name != name {
+		fs.Logf(src, "Server renamed asset to %q", asset.Name)
+	}
+	return &asset, nil
+}
+
+// deleteAsset deletes the asset with id from the release with releaseID
+func (f *Fs) deleteAsset(ctx context.Context, releaseID, id int64) error {
+	assetPath := "/releases/assets/" + strconv.FormatInt(id, 10)
+	if f.gitea {
+		assetPath = "/releases/" + strconv.FormatInt(releaseID, 10) + "/assets/" + strconv.FormatInt(id, 10)
+	}
+	opts := rest.Opts{
+		Method:     "DELETE",
+		Path:       f.repoPath + assetPath,
+		NoResponse: true,
+	}
+	err := f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't delete asset: %w", err)
+	}
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the SHA-256 of an object returning a lowercase hex string
+//
+// This is only known for assets on GitHub.
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t != hash.SHA256 {
+		return "", hash.ErrUnsupported
+	}
+	return strings.TrimPrefix(o.asset.Digest, "sha256:"), nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.asset.Size
+}
+
+// ModTime returns the modification time of the object
+//
+// This is when the asset was uploaded.
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if !o.asset.UpdatedAt.IsZero() {
+		return o.asset.UpdatedAt
+	}
+	return o.asset.CreatedAt
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType(ctx context.Context) string {
+	return o.asset.ContentType
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	return strconv.FormatInt(o.asset.ID, 10)
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	fs.FixRangeOption(options, o.asset.Size)
+	var opts rest.Opts
+	if o.fs.gitea {
+		opts = rest.Opts{
+			Method:  "GET",
+			RootURL: o.asset.BrowserDownloadURL,
+			Options: options,
+		}
+	} else {
+		// The API URL works for private repositories too and
+		// redirects to where the asset is stored
+		opts = rest.Opts{
+			Method: "GET",
+			Path:   o.fs.repoPath + "/releases/assets/" + strconv.FormatInt(o.asset.ID, 10),
+			ExtraHeaders: map[string]string{
+				"Accept": "application/octet-stream",
+			},
+			Options: options,
+		}
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if isNotFound(err) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download asset: %w", err)
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// Assets can't be replaced so any existing asset is deleted before
+// the new one is uploaded. The release is made if it doesn't exist.
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	tag, name, err := o.fs.split(o.remote)
+	if err != nil || tag == "" || name == "" {
+		return fmt.Errorf("can't upload %q: files must be in a release directory", o.remote)
+	}
+	release, asset, err := o.fs.findAsset(ctx, o.remote)
+	if err == fs.ErrorObjectNotFound {
+		err = nil
+		if release == nil {
+			release, err = o.fs.createRelease(ctx, tag)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if asset != nil {
+		err = o.fs.deleteAsset(ctx, release.ID, asset.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't replace asset: %w", err)
+		}
+	}
+	newAsset, err := o.fs.upload(ctx, release, name, in, src, options)
+	if err != nil {
+		return err
+	}
+	o.releaseID = release.ID
+	o.asset = *newAsset
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return o.fs.deleteAsset(ctx, o.releaseID, o.asset.ID)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs           = (*Fs)(nil)
+	_ fs.Purger       = (*Fs)(nil)
+	_ fs.PublicLinker = (*Fs)(nil)
+	_ fs.Object       = (*Object)(nil)
+	_ fs.MimeTyper    = (*Object)(nil)
+	_ fs.IDer         = (*Object)(nil)
+)
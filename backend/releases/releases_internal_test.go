@@ -0,0 +1,429 @@
+package releases
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/releases/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret"
+
+// testServer is a GitHub or Gitea server with one repository
+// keeping the releases in memory
+type testServer struct {
+	mu       sync.Mutex
+	gitea    bool
+	url      string
+	releases []*api.Release
+	data     map[int64][]byte // asset contents by ID
+	nextID   int64
+}
+
+func newTestServer(t *testing.T, gitea bool) (*testServer, *httptest.Server) {
+	s := &testServer{gitea: gitea, data: map[int64][]byte{}}
+	ts := httptest.NewServer(s)
+	s.url = ts.URL
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// addRelease adds a release to the server
+func (s *testServer) addRelease(tag string, draft bool) *api.Release {
+	s.nextID++
+	r := &api.Release{
+		ID:        s.nextID,
+		TagName:   tag,
+		Name:      tag,
+		Draft:     draft,
+		CreatedAt: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Assets:    []api.Asset{},
+	}
+	if !s.gitea {
+		r.UploadURL = fmt.Sprintf("%s/upload/repos/owner/repo/releases/%d/assets{?name,label}", s.url, r.ID)
+	}
+	s.releases = append(s.releases, r)
+	return r
+}
+
+// findAsset returns the release and index of the asset with id
+func (s *testServer) findAsset(id int64) (*api.Release, int) {
+	for _, r := range s.releases {
+		for i := range r.Assets {
+			if r.Assets[i].ID == id {
+				return r, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// findRelease returns the release with id
+func (s *testServer) findRelease(id int64) *api.Release {
+	for _, r := range s.releases {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, api.Error{Message: "Not Found"})
+}
+
+// ServeHTTP implements the parts of the APIs the backend uses
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := r.URL.Path
+	if strings.HasPrefix(p, "/download/") {
+		id, _ := strconv.ParseInt(strings.TrimPrefix(p, "/download/"), 10, 64)
+		data, ok := s.data[id]
+		if !ok {
+			notFound(w)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+		return
+	}
+	if r.Header.Get("Authorization") != "token "+testToken {
+		writeJSON(w, http.StatusUnauthorized, api.Error{Message: "Bad credentials"})
+		return
+	}
+	upload := strings.HasPrefix(p, "/upload/")
+	p = strings.TrimPrefix(p, "/upload")
+	if s.gitea {
+		if !strings.HasPrefix(p, giteaAPIPath+"/") {
+			notFound(w)
+			return
+		}
+		p = strings.TrimPrefix(p, giteaAPIPath)
+	}
+	if !strings.HasPrefix(p, "/repos/owner/repo/releases") {
+		notFound(w)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(p, "/repos/owner/repo/releases"), "/")[1:]
+	switch {
+	case len(parts) == 0 && r.Method == "GET":
+		chunk := gitHubListChunk
+		if s.gitea {
+			chunk = giteaListChunk
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start, end := (page-1)*chunk, page*chunk
+		if start > len(s.releases) {
+			start = len(s.releases)
+		}
+		if end > len(s.releases) {
+			end = len(s.releases)
+		}
+		writeJSON(w, http.StatusOK, s.releases[start:end])
+	case len(parts) == 0 && r.Method == "POST":
+		var req api.CreateRelease
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, api.Error{Message: err.Error()})
+			return
+		}
+		rel := s.addRelease(req.TagName, req.Draft)
+		rel.TargetCommitish = req.TargetCommitish
+		writeJSON(w, http.StatusCreated, rel)
+	case len(parts) == 2 && parts[0] == "tags" && r.Method == "GET":
+		for _, rel := range s.releases {
+			if rel.TagName == parts[1] && !rel.Draft {
+				writeJSON(w, http.StatusOK, rel)
+				return
+			}
+		}
+		notFound(w)
+	case len(parts) == 1 && r.Method == "DELETE":
+		id, _ := strconv.ParseInt(parts[0], 10, 64)
+		for i, rel := range s.releases {
+			if rel.ID == id {
+				s.releases = append(s.releases[:i], s.releases[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		notFound(w)
+	case len(parts) == 2 && parts[1] == "assets" && r.Method == "POST" && upload != s.gitea:
+		id, _ := strconv.ParseInt(parts[0], 10, 64)
+		rel := s.findRelease(id)
+		if rel == nil {
+			notFound(w)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		var data []byte
+		var err error
+		contentType := ""
+		if s.gitea {
+			file, header, err := r.FormFile("attachment")
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, api.Error{Message: err.Error()})
+				return
+			}
+			if name == "" {
+				name = header.Filename
+			}
+			data, err = ioutil.ReadAll(file)
+		} else {
+			data, err = ioutil.ReadAll(r.Body)
+			contentType = r.Header.Get("Content-Type")
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, api.Error{Message: err.Error()})
+			return
+		}
+		for _, asset := range rel.Assets {
+			if asset.Name == name {
+				writeJSON(w, http.StatusUnprocessableEntity, api.Error{
+					Message: "Validation Failed",
+					Errors:  []api.ErrorDetail{{Resource: "ReleaseAsset", Field: "name", Code: "already_exists"}},
+				})
+				return
+			}
+		}
+		s.nextID++
+		asset := api.Asset{
+			ID:                 s.nextID,
+			Name:               name,
+			Size:               int64(len(data)),
+			CreatedAt:          time.Date(2023, 1, 2, 3, 4, 6, 0, time.UTC),
+			BrowserDownloadURL: fmt.Sprintf("%s/download/%d", s.url, s.nextID),
+		}
+		if !s.gitea {
+			sum := sha256.Sum256(data)
+			asset.ContentType = contentType
+			asset.UpdatedAt = asset.CreatedAt.Add(time.Second)
+			asset.Digest = "sha256:" + hex.EncodeToString(sum[:])
+		}
+		s.data[asset.ID] = data
+		rel.Assets = append(rel.Assets, asset)
+		writeJSON(w, http.StatusCreated, asset)
+	case !s.gitea && len(parts) == 2 && parts[0] == "assets":
+		id, _ := strconv.ParseInt(parts[1], 10, 64)
+		s.assetRequest(w, r, id)
+	case s.gitea && len(parts) == 3 && parts[1] == "assets" && r.Method == "DELETE":
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		s.assetRequest(w, r, id)
+	default:
+		notFound(w)
+	}
+}
+
+// assetRequest downloads or deletes the asset with id
+func (s *testServer) assetRequest(w http.ResponseWriter, r *http.Request, id int64) {
+	rel, i := s.findAsset(id)
+	if rel == nil {
+		notFound(w)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		if r.Header.Get("Accept") != "application/octet-stream" {
+			writeJSON(w, http.StatusOK, rel.Assets[i])
+			return
+		}
+		http.Redirect(w, r, rel.Assets[i].BrowserDownloadURL, http.StatusFound)
+	case "DELETE":
+		rel.Assets = append(rel.Assets[:i], rel.Assets[i+1:]...)
+		delete(s.data, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		notFound(w)
+	}
+}
+
+// newTestFs makes an Fs using the test server at ts
+func newTestFs(t *testing.T, ts *httptest.Server, gitea bool, root string) *Fs {
+	provider := providerGitHub
+	if gitea {
+		provider = providerGitea
+	}
+	f, err := NewFs(context.Background(), t.Name(), root, configmap.Simple{
+		"provider": provider,
+		"url":      ts.URL,
+		"owner":    "owner",
+		"repo":     "repo",
+		"token":    obscure.MustObscure(testToken),
+	})
+	require.NoError(t, err)
+	return f.(*Fs)
+}
+
+// list returns the names of the entries in dir
+func list(t *testing.T, f fs.Fs, dir string) []string {
+	entries, err := f.List(context.Background(), dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// read returns the contents of o
+func read(t *testing.T, o fs.Object, options ...fs.OpenOption) string {
+	in, err := o.Open(context.Background(), options...)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+// put uploads contents as remote
+func put(t *testing.T, f fs.Fs, remote, contents string) fs.Object {
+	src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(context.Background(), strings.NewReader(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+func testReleases(t *testing.T, gitea bool) {
+	ctx := context.Background()
+	s, ts := newTestServer(t, gitea)
+	s.addRelease("v1.0", false)
+	s.addRelease("nightly/2023-01-02", false)
+	s.addRelease("v2.0-rc", true)
+	f := newTestFs(t, ts, gitea, "")
+
+	assert.Equal(t, []string{"nightly／2023-01-02", "v1.0", "v2.0-rc"}, list(t, f, ""))
+	assert.Equal(t, []string{}, list(t, f, "v2.0-rc"))
+	_, err := f.List(ctx, "v3.0")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+	_, err = f.List(ctx, "v1.0/file/deep")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	// Upload to existing releases, including a draft
+	o := put(t, f, "v1.0/app.tar.gz", "hello world")
+	put(t, f, "v2.0-rc/app.tar.gz", "draft")
+	put(t, f, "nightly／2023-01-02/app.zip", "nightly")
+	assert.Equal(t, []string{"v1.0/app.tar.gz"}, list(t, f, "v1.0"))
+	assert.Equal(t, []string{"nightly／2023-01-02/app.zip"}, list(t, f, "nightly／2023-01-02"))
+	assert.Equal(t, "hello world", read(t, o))
+	assert.Equal(t, "world", read(t, o, &fs.RangeOption{Start: 6, End: -1}))
+	sum, err := o.Hash(ctx, hash.SHA256)
+	require.NoError(t, err)
+	if gitea {
+		assert.Equal(t, "", sum)
+	} else {
+		assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", sum)
+		assert.Equal(t, "application/gzip", o.(*Object).MimeType(ctx))
+	}
+
+	// Replacing an asset
+	o = put(t, f, "v1.0/app.tar.gz", "hello again")
+	o2, err := f.NewObject(ctx, "v1.0/app.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, o.(*Object).asset.ID, o2.(*Object).asset.ID)
+	assert.Equal(t, "hello again", read(t, o2))
+	assert.Equal(t, 1, len(s.releases[0].Assets))
+
+	// Uploading to a new release makes it
+	put(t, f, "v3.0/notes.txt", "notes")
+	assert.Equal(t, []string{"nightly／2023-01-02", "v1.0", "v2.0-rc", "v3.0"}, list(t, f, ""))
+
+	// Files can't be put in the root or deeper than releases
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), 1, true, nil, nil)
+	_, err = f.Put(ctx, strings.NewReader("x"), src)
+	assert.Error(t, err)
+	src = object.NewStaticObjectInfo("v1.0/dir/file.txt", time.Now(), 1, true, nil, nil)
+	_, err = f.Put(ctx, strings.NewReader("x"), src)
+	assert.Error(t, err)
+
+	// Removing
+	assert.Equal(t, fs.ErrorDirectoryNotEmpty, f.Rmdir(ctx, "v1.0"))
+	require.NoError(t, o2.Remove(ctx))
+	_, err = f.NewObject(ctx, "v1.0/app.tar.gz")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	require.NoError(t, f.Rmdir(ctx, "v1.0"))
+	require.NoError(t, f.Purge(ctx, "v3.0"))
+	require.NoError(t, f.Mkdir(ctx, "v4.0"))
+	assert.Equal(t, []string{"nightly／2023-01-02", "v2.0-rc", "v4.0"}, list(t, f, ""))
+
+	// Root pointing at a file
+	_, err = NewFs(ctx, "test", "nightly／2023-01-02/app.zip", configmap.Simple{
+		"provider": f.opt.Provider,
+		"url":      ts.URL,
+		"owner":    "owner",
+		"repo":     "repo",
+		"token":    obscure.MustObscure(testToken),
+	})
+	assert.Equal(t, fs.ErrorIsFile, err)
+	fRelease := newTestFs(t, ts, gitea, "nightly／2023-01-02")
+	o, err = fRelease.NewObject(ctx, "app.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "nightly", read(t, o))
+}
+
+func TestGitHub(t *testing.T) {
+	testReleases(t, false)
+}
+
+func TestGitea(t *testing.T) {
+	testReleases(t, true)
+}
+
+func TestListPages(t *testing.T) {
+	s, ts := newTestServer(t, false)
+	var want []string
+	for i := 0; i < gitHubListChunk+10; i++ {
+		tag := fmt.Sprintf("v%03d", i)
+		s.addRelease(tag, false)
+		want = append(want, tag)
+	}
+	f := newTestFs(t, ts, false, "")
+	assert.Equal(t, want, list(t, f, ""))
+}
+
+func TestRateLimitWait(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}
+	assert.Equal(t, time.Duration(0), rateLimitWait(resp))
+	resp.Header.Set("X-RateLimit-Remaining", "0")
+	resp.Header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	wait := rateLimitWait(resp)
+	assert.True(t, wait > 59*time.Minute && wait <= time.Hour, wait)
+	resp.Header.Set("Retry-After", "30")
+	assert.Equal(t, 30*time.Second, rateLimitWait(resp))
+	resp.StatusCode = http.StatusNotFound
+	assert.Equal(t, time.Duration(0), rateLimitWait(resp))
+}
+
+func TestErrorHandler(t *testing.T) {
+	err := errorHandler(&http.Response{
+		Status:     "422 Unprocessable Entity",
+		StatusCode: http.StatusUnprocessableEntity,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"message":"Validation Failed","errors":[{"resource":"ReleaseAsset","code":"already_exists","field":"name"}]}`)),
+	})
+	assert.Equal(t, "release API error 422: Validation Failed (ReleaseAsset name already_exists)", err.Error())
+}
@@ -0,0 +1,254 @@
+package rename
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// rule is a single compiled rename rule
+type rule struct {
+	re      *regexp.Regexp
+	replace string
+}
+
+// ruleSep separates the pattern from the replacement in a rule
+const ruleSep = "=>"
+
+// parseRules compiles rules of the form "regexp=>replacement"
+func parseRules(rules []string) (out []rule, err error) {
+	for _, r := range rules {
+		i := strings.Index(r, ruleSep)
+		if i < 0 {
+			return nil, fmt.Errorf("rename rule %q should be of the form regexp%sreplacement", r, ruleSep)
+		}
+		pattern, replace := r[:i], r[i+len(ruleSep):]
+		if pattern == "" {
+			return nil, fmt.Errorf("empty regexp in rename rule %q", r)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad regexp in rename rule %q: %w", r, err)
+		}
+		out = append(out, rule{re: re, replace: replace})
+	}
+	return out, nil
+}
+
+// apply runs the rules over name in order
+func apply(rules []rule, name string) string {
+	for _, r := range rules {
+		name = r.re.ReplaceAllString(name, r.replace)
+	}
+	return name
+}
+
+// encodeName turns an original name into the name stored on the
+// wrapped remote
+func (f *Fs) encodeName(name string) string {
+	name = apply(f.rules, name)
+	switch f.opt.Case {
+	case caseLower:
+		name = strings.ToLower(name)
+	case caseUpper:
+		name = strings.ToUpper(name)
+	}
+	if name == "" || name == "." || name == ".." {
+		// Rules must not remove names or walk the tree
+		name = "_"
+	}
+	return name
+}
+
+// decodeName turns a name stored on the wrapped remote into the name
+// shown if there is no record of the original
+func (f *Fs) decodeName(name string) string {
+	return apply(f.reverseRules, name)
+}
+
+// encodePath encodes each element of the slash separated path p
+func (f *Fs) encodePath(p string) string {
+	if p == "" {
+		return ""
+	}
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		if segment != "" {
+			segments[i] = f.encodeName(segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// nameKey returns the database key for the remote stored as encoded
+func (f *Fs) nameKey(encoded string) string {
+	return path.Join(f.Fs.Root(), encoded)
+}
+
+// decodePaths turns paths read from the wrapped remote back into the
+// original paths, looking up any original names in the database.
+func (f *Fs) decodePaths(encoded []string) []string {
+	op := &kvLookup{names: map[string]string{}}
+	for _, p := range encoded {
+		for i := 0; i < len(p); i++ {
+			if p[i] == '/' {
+				op.names[f.nameKey(p[:i])] = ""
+			}
+		}
+		op.names[f.nameKey(p)] = ""
+	}
+	if err := f.db.Do(false, op); err != nil {
+		fs.Debugf(f, "Failed to read original names: %v", err)
+	}
+	decoded := make([]string, len(encoded))
+	for i, p := range encoded {
+		segments := strings.Split(p, "/")
+		originals := make([]string, len(segments))
+		for j, segment := range segments {
+			original := op.names[f.nameKey(strings.Join(segments[:j+1], "/"))]
+			if original == "" {
+				original = f.decodeName(segment)
+			}
+			originals[j] = original
+		}
+		decoded[i] = strings.Join(originals, "/")
+	}
+	return decoded
+}
+
+// remember records the original names of each element of remote
+// which can't be recovered with the reverse rules.
+func (f *Fs) remember(remote string) {
+	op := &kvRemember{names: map[string]string{}}
+	segments := strings.Split(remote, "/")
+	encoded := make([]string, len(segments))
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		encoded[i] = f.encodeName(segment)
+		key := f.nameKey(strings.Join(encoded[:i+1], "/"))
+		if f.decodeName(encoded[i]) == segment {
+			op.names[key] = ""
+		} else {
+			op.names[key] = segment
+		}
+	}
+	if err := f.db.Do(true, op); err != nil {
+		fs.Debugf(remote, "Failed to record original name: %v", err)
+	}
+}
+
+// forget removes the record of the original name of remote, and
+// anything below it if it is a directory.
+func (f *Fs) forget(remote string) {
+	op := &kvForget{key: f.nameKey(f.encodePath(remote))}
+	if err := f.db.Do(true, op); err != nil {
+		fs.Debugf(remote, "Failed to forget original name: %v", err)
+	}
+}
+
+// moveNames moves the records of the original names below the
+// directory srcRemote in src to dstRemote in f.
+func (f *Fs) moveNames(src *Fs, srcRemote, dstRemote string) {
+	op := &kvMove{
+		src: src.nameKey(src.encodePath(srcRemote)),
+		dst: f.nameKey(f.encodePath(dstRemote)),
+	}
+	if err := f.db.Do(true, op); err != nil {
+		fs.Debugf(dstRemote, "Failed to move original names: %v", err)
+	}
+	f.remember(dstRemote)
+}
+
+// kvLookup: read the original names for the keys in names
+type kvLookup struct {
+	names map[string]string
+}
+
+func (op *kvLookup) Do(ctx context.Context, b kv.Bucket) error {
+	for key := range op.names {
+		op.names[key] = string(b.Get([]byte(key)))
+	}
+	return nil
+}
+
+// kvRemember: store the original names in names, deleting the
+// records which are blank
+type kvRemember struct {
+	names map[string]string
+}
+
+func (op *kvRemember) Do(ctx context.Context, b kv.Bucket) error {
+	for key, name := range op.names {
+		old := string(b.Get([]byte(key)))
+		if old == name {
+			continue
+		}
+		var err error
+		if name == "" {
+			err = b.Delete([]byte(key))
+		} else {
+			err = b.Put([]byte(key), []byte(name))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keysBelow returns key and all the keys in the directory key from b
+func keysBelow(b kv.Bucket, key string) (keys [][]byte) {
+	keys = append(keys, []byte(key))
+	prefix := []byte(key + "/")
+	if key == "" {
+		prefix = nil
+	}
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	return keys
+}
+
+// kvForget: remove the record for key and everything below it
+type kvForget struct {
+	key string
+}
+
+func (op *kvForget) Do(ctx context.Context, b kv.Bucket) error {
+	for _, k := range keysBelow(b, op.key) {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kvMove: move the records below src to below dst
+type kvMove struct {
+	src string
+	dst string
+}
+
+func (op *kvMove) Do(ctx context.Context, b kv.Bucket) error {
+	// Skip src itself as its new name is recorded by the caller
+	for _, k := range keysBelow(b, op.src)[1:] {
+		v := append([]byte(nil), b.Get(k)...)
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+		newKey := op.dst + string(k[len(op.src):])
+		if err := b.Put([]byte(newKey), v); err != nil {
+			return err
+		}
+	}
+	return b.Delete([]byte(op.src))
+}
@@ -0,0 +1,708 @@
+// Package rename implements a backend which renames files and
+// directories with regular expressions as they are stored on the
+// wrapped remote
+package rename
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// Case conversions
+const (
+	caseNone  = "none"
+	caseLower = "lower"
+	caseUpper = "upper"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "rename",
+		Description: "Rename files with regular expressions as they are stored",
+		NewFs:       NewFs,
+		MetadataInfo: &fs.MetadataInfo{
+			Help: `Any metadata supported by the underlying remote is read and written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to store the renamed files on.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name: "rules",
+			Help: `Comma separated list of rules to rename names as they are stored.
+
+Each rule is of the form "regexp=>replacement" and the rules are
+applied in order to each file and directory name. The replacement can
+refer to submatches with $1 or ${name}. Put quotes around rules
+containing commas, e.g. "\[[^]]*\]=>","(\d+),(\d+)=>$1.$2".
+
+Spaces at the start and end of each rule are removed, so use [ ] to
+match a space there.`,
+		}, {
+			Name:    "case",
+			Default: caseNone,
+			Help:    `Change the case of names after applying the rules.`,
+			Examples: []fs.OptionExample{{
+				Value: caseNone,
+				Help:  "Leave the case unchanged.",
+			}, {
+				Value: caseLower,
+				Help:  "Store names in lower case.",
+			}, {
+				Value: caseUpper,
+				Help:  "Store names in upper case.",
+			}},
+		}, {
+			Name: "reverse_rules",
+			Help: `Comma separated list of rules to turn stored names back into originals.
+
+These are used for names on the remote which weren't written through
+this remote so rclone has no record of the original. They are of the
+same form as the rules.`,
+			Advanced: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote       string          `config:"remote"`
+	Rules        fs.CommaSepList `config:"rules"`
+	Case         string          `config:"case"`
+	ReverseRules fs.CommaSepList `config:"reverse_rules"`
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name         string
+	root         string
+	opt          Options
+	features     *fs.Features // optional features
+	wrapper      fs.Fs
+	rules        []rule
+	reverseRules []rule
+	db           *kv.DB // original names
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	if !kv.Supported() {
+		return nil, errors.New("rename is not supported on this OS")
+	}
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point rename remote at itself - check the value of the remote setting")
+	}
+	switch opt.Case {
+	case "", caseNone, caseLower, caseUpper:
+	default:
+		return nil, fmt.Errorf("unknown case %q", opt.Case)
+	}
+	f := &Fs{
+		name: name,
+		root: rpath,
+		opt:  opt,
+	}
+	if f.rules, err = parseRules(opt.Rules); err != nil {
+		return nil, err
+	}
+	if f.reverseRules, err = parseRules(opt.ReverseRules); err != nil {
+		return nil, err
+	}
+	remotePath := fspath.JoinRootPath(opt.Remote, f.encodePath(rpath))
+	var baseErr error
+	f.Fs, baseErr = cache.Get(ctx, remotePath)
+	if baseErr != nil && baseErr != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", remotePath, baseErr)
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+	f.db, err = kv.Start(ctx, "rename", f.Fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open names database: %w", err)
+	}
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		SetTier:                 true,
+		GetTier:                 true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, f.Fs).WrapsFs(f, f.Fs)
+	if opt.Case == caseLower || opt.Case == caseUpper {
+		// Names differing only in case are stored as the same file
+		f.features.CaseInsensitive = true
+	}
+	// Always needed to close the database
+	f.features.Shutdown = f.Shutdown
+	return f, baseErr
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("rename root '%s'", f.root)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// decodeEntries returns entries with their original names
+func (f *Fs) decodeEntries(ctx context.Context, entries fs.DirEntries) (fs.DirEntries, error) {
+	remotes := make([]string, len(entries))
+	for i, entry := range entries {
+		remotes[i] = entry.Remote()
+	}
+	remotes = f.decodePaths(remotes)
+	newEntries := make(fs.DirEntries, 0, len(entries))
+	for i, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			newEntries = append(newEntries, f.newObject(x, remotes[i]))
+		case fs.Directory:
+			newEntries = append(newEntries, fs.NewDirCopy(ctx, x).SetRemote(remotes[i]))
+		default:
+			return nil, fmt.Errorf("unknown object type %T", entry)
+		}
+	}
+	return newEntries, nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, f.encodePath(dir))
+	if err != nil {
+		return nil, err
+	}
+	return f.decodeEntries(ctx, entries)
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	return f.Fs.Features().ListR(ctx, f.encodePath(dir), func(entries fs.DirEntries) error {
+		newEntries, err := f.decodeEntries(ctx, entries)
+		if err != nil {
+			return err
+		}
+		return callback(newEntries)
+	})
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// The names in each page are decoded as it is read.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	do := f.Fs.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	return do(ctx, f.encodePath(dir), func(entries fs.DirEntries) error {
+		newEntries, err := f.decodeEntries(ctx, entries)
+		if err != nil {
+			return err
+		}
+		return callback(newEntries)
+	})
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := f.Fs.NewObject(ctx, f.encodePath(remote))
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, remote), nil
+}
+
+type putFn func(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error)
+
+// put implements Put, PutStream and PutUnchecked
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options []fs.OpenOption, put putFn) (fs.Object, error) {
+	remote := src.Remote()
+	o, err := put(ctx, in, f.newObjectInfo(src, f.encodePath(remote)), options...)
+	if err != nil {
+		return nil, err
+	}
+	f.remember(remote)
+	return f.newObject(o, remote), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src, options, f.Fs.Put)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutStream
+	if do == nil {
+		return nil, errors.New("PutStream not supported")
+	}
+	return f.put(ctx, in, src, options, do)
+}
+
+// PutUnchecked uploads the object, allowing duplicates
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutUnchecked
+	if do == nil {
+		return nil, errors.New("PutUnchecked not supported")
+	}
+	return f.put(ctx, in, src, options, do)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	err := f.Fs.Mkdir(ctx, f.encodePath(dir))
+	if err != nil {
+		return err
+	}
+	if dir != "" {
+		f.remember(dir)
+	}
+	return nil
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	err := f.Fs.Rmdir(ctx, f.encodePath(dir))
+	if err != nil {
+		return err
+	}
+	if dir != "" {
+		f.forget(dir)
+	}
+	return nil
+}
+
+// Purge all files in the directory specified
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	err := do(ctx, f.encodePath(dir))
+	if err != nil {
+		return err
+	}
+	f.forget(dir)
+	return nil
+}
+
+// Copy src to this remote using server-side copy operations.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	o, err := do(ctx, srcObj.Object, f.encodePath(remote))
+	if err != nil {
+		return nil, err
+	}
+	f.remember(remote)
+	return f.newObject(o, remote), nil
+}
+
+// Move src to this remote using server-side move operations.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	o, err := do(ctx, srcObj.Object, f.encodePath(remote))
+	if err != nil {
+		return nil, err
+	}
+	srcObj.f.forget(srcObj.remote)
+	f.remember(remote)
+	return f.newObject(o, remote), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	err := do(ctx, srcFs.Fs, srcFs.encodePath(srcRemote), f.encodePath(dstRemote))
+	if err != nil {
+		return err
+	}
+	f.moveNames(srcFs, srcRemote, dstRemote)
+	return nil
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	do := f.Fs.Features().ChangeNotify
+	if do == nil {
+		return
+	}
+	wrappedNotifyFunc := func(path string, entryType fs.EntryType) {
+		notifyFunc(f.decodePaths([]string{path})[0], entryType)
+	}
+	do(ctx, wrappedNotifyFunc, pollIntervalChan)
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	do := f.Fs.Features().PublicLink
+	if do == nil {
+		return "", errors.New("PublicLink not supported")
+	}
+	return do(ctx, f.encodePath(remote), expire, unlink)
+}
+
+// OpenWriterAt opens with a handle for random access writes
+//
+// Pass in the remote desired and the size if known.
+func (f *Fs) OpenWriterAt(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+	do := f.Fs.Features().OpenWriterAt
+	if do == nil {
+		return nil, fs.ErrorNotImplemented
+	}
+	w, err := do(ctx, f.encodePath(remote), size)
+	if err != nil {
+		return nil, err
+	}
+	f.remember(remote)
+	return w, nil
+}
+
+// UserInfo returns info about the connected user
+func (f *Fs) UserInfo(ctx context.Context) (map[string]string, error) {
+	do := f.Fs.Features().UserInfo
+	if do == nil {
+		return nil, fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Disconnect the current user
+func (f *Fs) Disconnect(ctx context.Context) error {
+	do := f.Fs.Features().Disconnect
+	if do == nil {
+		return fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) (err error) {
+	err = f.db.Stop(false)
+	if do := f.Fs.Features().Shutdown; do != nil {
+		if err2 := do(ctx); err2 != nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// ObjectInfo describes a wrapped fs.ObjectInfo for being the source
+//
+// This renames the remote
+type ObjectInfo struct {
+	fs.ObjectInfo
+	f      *Fs
+	remote string
+}
+
+func (f *Fs) newObjectInfo(src fs.ObjectInfo, remote string) *ObjectInfo {
+	return &ObjectInfo{
+		ObjectInfo: src,
+		f:          f,
+		remote:     remote,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *ObjectInfo) Fs() fs.Info {
+	return o.f
+}
+
+// Remote returns the remote path as stored on the wrapped remote
+func (o *ObjectInfo) Remote() string {
+	return o.remote
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *ObjectInfo) GetTier() string {
+	do, ok := o.ObjectInfo.(fs.GetTierer)
+	if !ok {
+		return ""
+	}
+	return do.GetTier()
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *ObjectInfo) ID() string {
+	do, ok := o.ObjectInfo.(fs.IDer)
+	if !ok {
+		return ""
+	}
+	return do.ID()
+}
+
+// MimeType returns the content type of the Object if known
+func (o *ObjectInfo) MimeType(ctx context.Context) string {
+	do, ok := o.ObjectInfo.(fs.MimeTyper)
+	if !ok {
+		return ""
+	}
+	return do.MimeType(ctx)
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *ObjectInfo) Metadata(ctx context.Context) (fs.Metadata, error) {
+	do, ok := o.ObjectInfo.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// UnWrap returns the Object that this Object is wrapping or
+// nil if it isn't wrapping anything
+func (o *ObjectInfo) UnWrap() fs.Object {
+	return fs.UnWrapObjectInfo(o.ObjectInfo)
+}
+
+// Object describes a wrapped object
+type Object struct {
+	fs.Object
+	f      *Fs
+	remote string // original name
+}
+
+// newObject wraps o which has original name remote
+func (f *Fs) newObject(o fs.Object, remote string) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+		remote: remote,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return o.Object.Update(ctx, in, o.f.newObjectInfo(src, o.Object.Remote()), options...)
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	err := o.Object.Remove(ctx)
+	if err != nil {
+		return err
+	}
+	o.f.forget(o.remote)
+	return nil
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	do, ok := o.Object.(fs.IDer)
+	if !ok {
+		return ""
+	}
+	return do.ID()
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	do, ok := o.Object.(fs.MimeTyper)
+	if !ok {
+		return ""
+	}
+	return do.MimeType(ctx)
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	do, ok := o.Object.(fs.SetTierer)
+	if !ok {
+		return errors.New("SetTier not supported")
+	}
+	return do.SetTier(tier)
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *Object) GetTier() string {
+	do, ok := o.Object.(fs.GetTierer)
+	if !ok {
+		return ""
+	}
+	return do.GetTier()
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.PutUncheckeder  = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.OpenWriterAter  = (*Fs)(nil)
+	_ fs.UserInfoer      = (*Fs)(nil)
+	_ fs.Disconnecter    = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.FullObject      = (*Object)(nil)
+)
@@ -0,0 +1,107 @@
+package rename
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs returns a rename remote with the rules in params and the
+// directory it stores the canonical names in
+func newTestFs(t *testing.T, params string) (f *Fs, base fs.Fs) {
+	newFs, tempRoot := fstest.NewWrappingFs(t, "rename", params)
+	return newFs.(*Fs), fstest.NewFs(t, tempRoot)
+}
+
+// listNames returns the sorted remotes of everything in f
+func listNames(t *testing.T, f fs.Fs, dir string) (names []string) {
+	entries, err := f.List(context.Background(), dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	return names
+}
+
+func TestParseRules(t *testing.T) {
+	for _, test := range []struct {
+		rules []string
+		in    string
+		want  string
+		err   string
+	}{
+		{rules: nil, in: "Hello.txt", want: "Hello.txt"},
+		{rules: []string{`\s*\[[^]]*\]=>`}, in: "Film [GRP].mkv", want: "Film.mkv"},
+		{rules: []string{`(\d+)x(\d+)=>S${1}E$2`, `[ ]=>.`}, in: "Show 1x02.mkv", want: "Show.S1E02.mkv"},
+		{rules: []string{`potato`}, err: "should be of the form"},
+		{rules: []string{`=>x`}, err: "empty regexp"},
+		{rules: []string{`(=>x`}, err: "bad regexp"},
+	} {
+		rules, err := parseRules(test.rules)
+		if test.err != "" {
+			assert.ErrorContains(t, err, test.err)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, test.want, apply(rules, test.in))
+	}
+}
+
+func TestRenameRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	f, base := newTestFs(t, `rules="\s*\[[^]]*\]=>",case=lower`)
+	assert.True(t, f.Features().CaseInsensitive)
+
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "My Films/Film [GRP].mkv", ModTime: time.Now()}, "hello", false)
+	assert.Equal(t, "My Films/Film [GRP].mkv", o.Remote())
+
+	// stored under the canonical name
+	assert.Equal(t, []string{"my films"}, listNames(t, base, ""))
+	assert.Equal(t, []string{"my films/film.mkv"}, listNames(t, base, "my films"))
+
+	// but shown with the original name
+	assert.Equal(t, []string{"My Films"}, listNames(t, f, ""))
+	assert.Equal(t, []string{"My Films/Film [GRP].mkv"}, listNames(t, f, "My Films"))
+
+	// and can be found by the original name
+	o, err := f.NewObject(ctx, "My Films/Film [GRP].mkv")
+	require.NoError(t, err)
+	assert.Equal(t, "My Films/Film [GRP].mkv", o.Remote())
+
+	// moving the directory keeps the original names
+	require.NoError(t, f.DirMove(ctx, f, "My Films", "Old Films"))
+	assert.Equal(t, []string{"Old Films"}, listNames(t, f, ""))
+	assert.Equal(t, []string{"Old Films/Film [GRP].mkv"}, listNames(t, f, "Old Films"))
+
+	// names written elsewhere are shown as stored
+	_ = fstests.PutTestContents(ctx, t, base, &fstest.Item{Path: "old films/other.mkv", ModTime: time.Now()}, "hello", false)
+	assert.ElementsMatch(t, []string{"Old Films/Film [GRP].mkv", "Old Films/other.mkv"}, listNames(t, f, "Old Films"))
+
+	// removing forgets the original name
+	o, err = f.NewObject(ctx, "Old Films/Film [GRP].mkv")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	_ = fstests.PutTestContents(ctx, t, base, &fstest.Item{Path: "old films/film.mkv", ModTime: time.Now()}, "hello", false)
+	assert.ElementsMatch(t, []string{"Old Films/film.mkv", "Old Films/other.mkv"}, listNames(t, f, "Old Films"))
+}
+
+func TestReverseRules(t *testing.T) {
+	ctx := context.Background()
+	f, base := newTestFs(t, `rules=":=>_",reverse_rules="_=>:"`)
+	_ = fstests.PutTestContents(ctx, t, base, &fstest.Item{Path: "a_b.txt", ModTime: time.Now()}, "hello", false)
+	assert.Equal(t, []string{"a:b.txt"}, listNames(t, f, ""))
+
+	// names the reverse rules recover aren't recorded
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "c:d.txt", ModTime: time.Now()}, "hello", false)
+	assert.ElementsMatch(t, []string{"a_b.txt", "c_d.txt"}, listNames(t, base, ""))
+	op := &kvLookup{names: map[string]string{f.nameKey("c_d.txt"): ""}}
+	require.NoError(t, f.db.Do(false, op))
+	assert.Equal(t, "", op.names[f.nameKey("c_d.txt")])
+}
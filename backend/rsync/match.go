@@ -0,0 +1,231 @@
+//go:build !plan9
+// +build !plan9
+
+package rsync
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/md4"
+)
+
+// signExtend returns b as rsync adds it to the rolling checksum,
+// which treats the bytes as signed chars
+func signExtend(b byte) uint32 {
+	return uint32(int32(int8(b)))
+}
+
+// checksum1 returns the rolling checksum of buf
+func checksum1(buf []byte) uint32 {
+	var s1, s2 uint32
+	for _, b := range buf {
+		s1 += signExtend(b)
+		s2 += s1
+	}
+	return s1&0xFFFF | s2<<16
+}
+
+// checksum2 returns the strong checksum of buf
+func checksum2(buf []byte, seed int32) []byte {
+	h := md4.New()
+	_, _ = h.Write(buf)
+	if seed != 0 {
+		_ = binary.Write(h, binary.LittleEndian, seed)
+	}
+	return h.Sum(nil)
+}
+
+// newFileSum returns the hash used to check the whole file
+func newFileSum(seed int32) hash.Hash {
+	h := md4.New()
+	_ = binary.Write(h, binary.LittleEndian, seed)
+	return h
+}
+
+// tokenWriter writes the data of a file as rsync tokens
+//
+// A positive token is followed by that many bytes of literal data, a
+// negative one -(i+1) means block i of the file the receiver already
+// has and 0 ends the file.
+type tokenWriter struct {
+	w         io.Writer
+	literal   int64 // bytes of literal data written
+	matched   int64 // bytes of data sent as block references
+	intBuffer [4]byte
+}
+
+// writeInt writes a 32 bit integer
+func (t *tokenWriter) writeInt(x int32) error {
+	binary.LittleEndian.PutUint32(t.intBuffer[:], uint32(x))
+	_, err := t.w.Write(t.intBuffer[:])
+	return err
+}
+
+// writeLiteral sends buf as literal data
+func (t *tokenWriter) writeLiteral(buf []byte) error {
+	for len(buf) > 0 {
+		n := len(buf)
+		if n > chunkSize {
+			n = chunkSize
+		}
+		err := t.writeInt(int32(n))
+		if err != nil {
+			return err
+		}
+		_, err = t.w.Write(buf[:n])
+		if err != nil {
+			return err
+		}
+		t.literal += int64(n)
+		buf = buf[n:]
+	}
+	return nil
+}
+
+// writeMatch sends a reference to block i
+func (t *tokenWriter) writeMatch(i int32, length int32) error {
+	t.matched += int64(length)
+	return t.writeInt(-(i + 1))
+}
+
+// writeEnd marks the end of the file
+func (t *tokenWriter) writeEnd() error {
+	return t.writeInt(0)
+}
+
+// writeDelta reads the new contents of a file from in and writes them
+// to t, referring to the blocks with the checksums in sums where they
+// match
+//
+// This is the rsync algorithm: a rolling checksum of the block sized
+// window is looked up in the checksums of the blocks the receiver has
+// and the strong checksum is checked if it matches. If the window
+// matches then the block is sent as a reference and the window moves
+// on a whole block. If not, the first byte of the window is added to
+// the literal data and the window moves on by one byte.
+//
+// It returns the checksum of the whole file.
+func writeDelta(t *tokenWriter, in io.Reader, sums []blockSum, head sumHead, seed int32) ([]byte, error) {
+	fileSum := newFileSum(seed)
+	r := bufio.NewReaderSize(io.TeeReader(in, fileSum), 64*1024)
+	if len(sums) == 0 || head.blockLength <= 0 {
+		buf := make([]byte, chunkSize)
+		for {
+			n, err := io.ReadFull(r, buf)
+			if literalErr := t.writeLiteral(buf[:n]); literalErr != nil {
+				return nil, literalErr
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return fileSum.Sum(nil), t.writeEnd()
+	}
+
+	// index the blocks by their rolling checksum
+	blocks := make(map[uint32][]int32, len(sums))
+	for i := range sums {
+		blocks[sums[i].sum1] = append(blocks[sums[i].sum1], int32(i))
+	}
+	// find returns the block matching window or -1
+	find := func(sum uint32, window []byte) int32 {
+		var strong []byte
+		for _, i := range blocks[sum] {
+			if int(sums[i].length) != len(window) {
+				continue
+			}
+			if strong == nil {
+				strong = checksum2(window, seed)[:head.sumLength]
+			}
+			if bytes.Equal(strong, sums[i].sum2) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// buf holds the literal data not sent yet followed by the window
+	blockLength := int(head.blockLength)
+	buf := make([]byte, 0, chunkSize+blockLength)
+	literal := 0
+	eof := false
+	var s1, s2 uint32
+
+	// fill reads a new window after the literal data
+	fill := func() error {
+		n, err := io.ReadFull(r, buf[literal:literal+blockLength])
+		buf = buf[:literal+n]
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			eof = true
+		} else if err != nil {
+			return err
+		}
+		sum := checksum1(buf[literal:])
+		s1, s2 = sum&0xFFFF, sum>>16
+		return nil
+	}
+	err := fill()
+	if err != nil {
+		return nil, err
+	}
+	for len(buf) > literal {
+		window := buf[literal:]
+		if i := find(s1&0xFFFF|s2<<16, window); i >= 0 {
+			err = t.writeLiteral(buf[:literal])
+			if err != nil {
+				return nil, err
+			}
+			err = t.writeMatch(i, sums[i].length)
+			if err != nil {
+				return nil, err
+			}
+			literal = 0
+			buf = buf[:0]
+			err = fill()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// move the first byte of the window into the literal data
+		out := signExtend(window[0])
+		s1 -= out
+		s2 -= uint32(len(window)) * out
+		literal++
+		if !eof {
+			c, err := r.ReadByte()
+			if err == io.EOF {
+				eof = true
+			} else if err != nil {
+				return nil, err
+			} else {
+				buf = append(buf, c)
+				s1 += signExtend(c)
+				s2 += s1
+			}
+		}
+
+		// send the literal data when there is a chunk of it
+		if literal >= chunkSize {
+			err = t.writeLiteral(buf[:literal])
+			if err != nil {
+				return nil, err
+			}
+			buf = buf[:copy(buf, buf[literal:])]
+			literal = 0
+		}
+	}
+	err = t.writeLiteral(buf[:literal])
+	if err != nil {
+		return nil, err
+	}
+	return fileSum.Sum(nil), t.writeEnd()
+}
@@ -0,0 +1,580 @@
+//go:build !plan9
+// +build !plan9
+
+package rsync
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// This implements the parts of version 27 of the rsync protocol
+// needed to list, fetch and send single files. This version is
+// spoken by every rsync since 2.6.0 and is much simpler than the
+// later ones which replace most of the fixed size integers with
+// variable length ones.
+//
+// The client starts "rsync --server" on the remote host and talks to
+// it over the stdin and stdout of the command. Once the versions and
+// the checksum seed have been exchanged, the output of the server is
+// multiplexed so it can send messages along with the data. What the
+// client sends isn't multiplexed.
+//
+// See https://rsync.samba.org/how-rsync-works.html for an overview.
+
+const (
+	protocolVersion    = 27
+	minProtocolVersion = 27
+
+	chunkSize  = 32 * 1024 // maximum size of a literal data token
+	md4Size    = 16        // size of the whole file checksum
+	mplexBase  = 7         // added to the message tags
+	maxMessage = 0xFFFFFF  // largest multiplexed message
+
+	// ndxDone is sent instead of a file index at the end of each
+	// phase of the transfer
+	ndxDone = -1
+)
+
+// Message tags in the multiplexed stream from the server
+const (
+	msgData        = 0
+	msgErrorXfer   = 1
+	msgInfo        = 2
+	msgError       = 3
+	msgWarning     = 4
+	msgErrorSocket = 5
+	msgLog         = 6
+	msgClient      = 7
+	msgErrorUTF8   = 8
+)
+
+// Flags for each entry in the file list
+const (
+	xmitTopDir   = 1 << 0
+	xmitSameMode = 1 << 1
+	xmitSameRdev = 1 << 2 // only for devices which we don't ask for
+	xmitSameUID  = 1 << 3
+	xmitSameGID  = 1 << 4
+	xmitSameName = 1 << 5
+	xmitLongName = 1 << 6
+	xmitSameTime = 1 << 7
+)
+
+// File types in the mode of file list entries
+const (
+	modeTypeMask = 0170000
+	modeDir      = 0040000
+	modeRegular  = 0100000
+)
+
+// errProtocol is returned when the server sends something unexpected
+var errProtocol = errors.New("rsync protocol error")
+
+// fileEntry is an entry in the file list
+type fileEntry struct {
+	name    string // path relative to the transfer root
+	size    int64
+	modTime time.Time
+	mode    uint32
+}
+
+// isDir returns whether the entry is a directory
+func (e *fileEntry) isDir() bool {
+	return e.mode&modeTypeMask == modeDir
+}
+
+// isRegular returns whether the entry is a regular file
+func (e *fileEntry) isRegular() bool {
+	return e.mode&modeTypeMask == modeRegular
+}
+
+// sumHead describes the block checksums of a file
+type sumHead struct {
+	count       int32 // number of blocks
+	blockLength int32 // length of each block
+	sumLength   int32 // number of bytes of the strong checksum sent
+	remainder   int32 // length of the last block if it is short
+}
+
+// blockSum are the checksums of one block of a file
+type blockSum struct {
+	sum1   uint32 // rolling checksum
+	sum2   []byte // strong checksum
+	length int32
+}
+
+// muxReader reads the data out of the multiplexed output of the
+// server, passing the messages to the session
+type muxReader struct {
+	in      io.Reader
+	s       *session
+	pending int // bytes of data left in the current message
+}
+
+// Read satisfies the io.Reader interface
+func (m *muxReader) Read(p []byte) (n int, err error) {
+	for m.pending == 0 {
+		var header [4]byte
+		_, err = io.ReadFull(m.in, header[:])
+		if err != nil {
+			return 0, err
+		}
+		h := binary.LittleEndian.Uint32(header[:])
+		tag := int(h>>24) - mplexBase
+		length := int(h & maxMessage)
+		if tag == msgData {
+			m.pending = length
+			continue
+		}
+		msg := make([]byte, length)
+		_, err = io.ReadFull(m.in, msg)
+		if err != nil {
+			return 0, err
+		}
+		m.s.message(tag, string(msg))
+	}
+	if len(p) > m.pending {
+		p = p[:m.pending]
+	}
+	n, err = m.in.Read(p)
+	m.pending -= n
+	return n, err
+}
+
+// session is a conversation with an rsync server
+type session struct {
+	f        *Fs
+	p        *process
+	r        *bufio.Reader
+	w        *bufio.Writer
+	seed     int32
+	errors   []string // error messages from the server
+	finished bool     // set if the process has been waited for
+}
+
+// newRsyncSession exchanges the protocol version and checksum seed
+// with the rsync server running in p
+func (f *Fs) newRsyncSession(p *process) (s *session, err error) {
+	s = &session{
+		f: f,
+		p: p,
+		w: bufio.NewWriterSize(p.stdin, 64*1024),
+	}
+	raw := bufio.NewReaderSize(p.stdout, 64*1024)
+	s.writeInt(protocolVersion)
+	err = s.w.Flush()
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	var buf [8]byte
+	_, err = io.ReadFull(raw, buf[:])
+	if err != nil {
+		return nil, s.fail(fmt.Errorf("failed to read version: %w", err))
+	}
+	remoteVersion := int32(binary.LittleEndian.Uint32(buf[0:]))
+	if remoteVersion < minProtocolVersion {
+		return nil, s.fail(fmt.Errorf("remote rsync protocol version %d too old - need %d", remoteVersion, minProtocolVersion))
+	}
+	s.seed = int32(binary.LittleEndian.Uint32(buf[4:]))
+	s.r = bufio.NewReaderSize(&muxReader{in: raw, s: s}, 64*1024)
+	return s, nil
+}
+
+// message deals with an out of band message from the server
+func (s *session) message(tag int, msg string) {
+	msg = strings.TrimSpace(msg)
+	switch tag {
+	case msgErrorXfer, msgError, msgErrorSocket, msgErrorUTF8:
+		fs.Debugf(s.f, "rsync error: %s", msg)
+		s.errors = append(s.errors, msg)
+	case msgWarning:
+		fs.Logf(s.f, "rsync: %s", msg)
+	default:
+		fs.Debugf(s.f, "rsync: %s", msg)
+	}
+}
+
+// notFound returns whether the server said the path it was asked for
+// doesn't exist
+func (s *session) notFound() bool {
+	for _, msg := range s.errors {
+		msg = strings.ToLower(msg)
+		if strings.Contains(msg, "no such file or directory") || strings.Contains(msg, "not a directory") {
+			return true
+		}
+	}
+	return false
+}
+
+// fail stops the server and returns err annotated with anything the
+// server said about what went wrong
+func (s *session) fail(err error) error {
+	why := append([]string{}, s.errors...)
+	if !s.finished {
+		s.finished = true
+		s.p.Kill()
+		if stderr := s.p.stderr.String(); stderr != "" {
+			why = append(why, stderr)
+		}
+	}
+	if len(why) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.Join(why, ": "))
+	}
+	return err
+}
+
+// close waits for the server to exit
+func (s *session) close() error {
+	if s.finished {
+		return nil
+	}
+	s.finished = true
+	return s.p.Wait()
+}
+
+// writeInt sends a 32 bit integer
+func (s *session) writeInt(x int32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(x))
+	_, _ = s.w.Write(buf[:])
+}
+
+// writeLong sends a 64 bit integer, using 32 bits if it fits
+func (s *session) writeLong(x int64) {
+	if x >= 0 && x <= 0x7FFFFFFF {
+		s.writeInt(int32(x))
+		return
+	}
+	s.writeInt(-1)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(x))
+	_, _ = s.w.Write(buf[:])
+}
+
+// writeSumHead sends the description of the block checksums
+func (s *session) writeSumHead(head sumHead) {
+	s.writeInt(head.count)
+	s.writeInt(head.blockLength)
+	s.writeInt(head.sumLength)
+	s.writeInt(head.remainder)
+}
+
+// flush sends everything written so far
+func (s *session) flush() error {
+	return s.w.Flush()
+}
+
+// readInt reads a 32 bit integer
+func (s *session) readInt() (int32, error) {
+	var buf [4]byte
+	_, err := io.ReadFull(s.r, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.LittleEndian.Uint32(buf[:])), nil
+}
+
+// readLong reads a 64 bit integer sent by writeLong
+func (s *session) readLong() (int64, error) {
+	x, err := s.readInt()
+	if err != nil || x != -1 {
+		return int64(x), err
+	}
+	var buf [8]byte
+	_, err = io.ReadFull(s.r, buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(buf[:])), nil
+}
+
+// readNdx reads a file index, checking it is ndxDone or less than n
+func (s *session) readNdx(n int) (int32, error) {
+	ndx, err := s.readInt()
+	if err != nil {
+		return 0, err
+	}
+	if ndx != ndxDone && (ndx < 0 || int(ndx) >= n) {
+		return 0, fmt.Errorf("%w: bad file index %d", errProtocol, ndx)
+	}
+	return ndx, nil
+}
+
+// readSumHead reads the description of the block checksums
+func (s *session) readSumHead() (head sumHead, err error) {
+	for _, p := range []*int32{&head.count, &head.blockLength, &head.sumLength, &head.remainder} {
+		*p, err = s.readInt()
+		if err != nil {
+			return head, err
+		}
+	}
+	if head.count < 0 || head.blockLength < 0 || head.sumLength < 0 || head.sumLength > md4Size ||
+		head.remainder < 0 || head.remainder > head.blockLength {
+		return head, fmt.Errorf("%w: bad checksum header %+v", errProtocol, head)
+	}
+	return head, nil
+}
+
+// readSums reads the block checksums described by head
+func (s *session) readSums(head sumHead) ([]blockSum, error) {
+	sums := make([]blockSum, head.count)
+	for i := range sums {
+		sum1, err := s.readInt()
+		if err != nil {
+			return nil, err
+		}
+		sum2 := make([]byte, head.sumLength)
+		_, err = io.ReadFull(s.r, sum2)
+		if err != nil {
+			return nil, err
+		}
+		sums[i] = blockSum{
+			sum1:   uint32(sum1),
+			sum2:   sum2,
+			length: head.blockLength,
+		}
+		if i == len(sums)-1 && head.remainder != 0 {
+			sums[i].length = head.remainder
+		}
+	}
+	return sums, nil
+}
+
+// sendFilterList sends the exclude rules which are always empty
+func (s *session) sendFilterList() {
+	s.writeInt(0)
+}
+
+// readFileList reads the file list sent by the server
+//
+// The list is returned sorted by name. hasIDs should be set if the
+// server was asked to send the owners and groups.
+func (s *session) readFileList(hasIDs bool) (entries []*fileEntry, err error) {
+	last := &fileEntry{}
+	for {
+		flags, err := s.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if flags == 0 {
+			break
+		}
+		e := &fileEntry{}
+		var prefix int
+		if flags&xmitSameName != 0 {
+			b, err := s.r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			prefix = int(b)
+		}
+		var length int
+		if flags&xmitLongName != 0 {
+			x, err := s.readInt()
+			if err != nil {
+				return nil, err
+			}
+			length = int(x)
+		} else {
+			b, err := s.r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			length = int(b)
+		}
+		if prefix > len(last.name) || length < 0 || length > 64*1024 {
+			return nil, fmt.Errorf("%w: bad file name length %d+%d", errProtocol, prefix, length)
+		}
+		name := make([]byte, prefix+length)
+		copy(name, last.name[:prefix])
+		_, err = io.ReadFull(s.r, name[prefix:])
+		if err != nil {
+			return nil, err
+		}
+		e.name = string(name)
+		e.size, err = s.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if flags&xmitSameTime != 0 {
+			e.modTime = last.modTime
+		} else {
+			t, err := s.readInt()
+			if err != nil {
+				return nil, err
+			}
+			e.modTime = time.Unix(int64(t), 0)
+		}
+		if flags&xmitSameMode != 0 {
+			e.mode = last.mode
+		} else {
+			mode, err := s.readInt()
+			if err != nil {
+				return nil, err
+			}
+			e.mode = uint32(mode)
+		}
+		if hasIDs {
+			// skip the owner and group
+			for _, same := range []byte{xmitSameUID, xmitSameGID} {
+				if flags&same == 0 {
+					_, err = s.readInt()
+					if err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		entries = append(entries, e)
+		last = e
+	}
+	if hasIDs {
+		// skip the lists of user and group names
+		for list := 0; list < 2; list++ {
+			err = s.skipIDList()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	ioError, err := s.readInt()
+	if err != nil {
+		return nil, err
+	}
+	if ioError != 0 {
+		fs.Errorf(s.f, "rsync server had I/O errors making the file list")
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+	return entries, nil
+}
+
+// skipIDList reads a list of user or group names
+func (s *session) skipIDList() error {
+	for {
+		id, err := s.readInt()
+		if err != nil {
+			return err
+		}
+		if id == 0 {
+			return nil
+		}
+		length, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		_, err = s.r.Discard(int(length))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeFileList sends the file list with just e in
+func (s *session) writeFileList(e *fileEntry) {
+	_ = s.w.WriteByte(xmitLongName)
+	s.writeInt(int32(len(e.name)))
+	_, _ = s.w.WriteString(e.name)
+	s.writeLong(e.size)
+	s.writeInt(int32(e.modTime.Unix()))
+	s.writeInt(int32(e.mode))
+	_ = s.w.WriteByte(0) // end of list
+	s.writeInt(0)        // no I/O errors
+}
+
+// finishReceiving finishes the protocol as the receiver once all the
+// files wanted have been asked for
+//
+// This ends both phases of the transfer, calling recv for every file
+// the server sends before the end of the first one, then reads the
+// statistics and says goodbye.
+func (s *session) finishReceiving(n int, recv func(ndx int32) error) error {
+	s.writeInt(ndxDone)
+	err := s.flush()
+	if err != nil {
+		return err
+	}
+	for phase := 0; phase < 2; phase++ {
+		for {
+			ndx, err := s.readNdx(n)
+			if err != nil {
+				return err
+			}
+			if ndx == ndxDone {
+				break
+			}
+			if phase > 0 || recv == nil {
+				return fmt.Errorf("%w: unexpected file %d", errProtocol, ndx)
+			}
+			err = recv(ndx)
+			if err != nil {
+				return err
+			}
+		}
+		if phase == 0 {
+			s.writeInt(ndxDone)
+			err = s.flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	// total bytes read, written and the total size of the files
+	for i := 0; i < 3; i++ {
+		_, err = s.readLong()
+		if err != nil {
+			return err
+		}
+	}
+	s.writeInt(ndxDone)
+	return s.flush()
+}
+
+// receiveData reads the data of the file the server is sending
+// writing it to out
+//
+// The server has been asked for the whole file so there are no
+// references to blocks of an existing file.
+func (s *session) receiveData(out io.Writer) error {
+	_, err := s.readSumHead()
+	if err != nil {
+		return err
+	}
+	h := newFileSum(s.seed)
+	w := io.MultiWriter(h, out)
+	for {
+		token, err := s.readInt()
+		if err != nil {
+			return err
+		}
+		if token == 0 {
+			break
+		}
+		if token < 0 {
+			return fmt.Errorf("%w: unexpected block reference %d", errProtocol, token)
+		}
+		_, err = io.CopyN(w, s.r, int64(token))
+		if err != nil {
+			return err
+		}
+	}
+	var sum [md4Size]byte
+	_, err = io.ReadFull(s.r, sum[:])
+	if err != nil {
+		return err
+	}
+	if string(sum[:]) != string(h.Sum(nil)) {
+		return errors.New("rsync: corrupted transfer - checksum mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,825 @@
+// Package rsync provides an interface to a remote host using the
+// rsync protocol over SSH
+//
+// Files are listed, downloaded and uploaded by running "rsync
+// --server" on the remote host. Uploads of files which already exist
+// use the rsync algorithm so only the parts of the file which have
+// changed are sent. Everything else is done with shell commands.
+
+//go:build !plan9
+// +build !plan9
+
+package rsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/walk"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/env"
+	"github.com/rclone/rclone/lib/readers"
+	"golang.org/x/crypto/ssh"
+)
+
+var currentUser = env.CurrentUser()
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "rsync",
+		Description: "Rsync over SSH",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "host",
+			Help: "SSH host to connect to.\n\nE.g. \"example.com\".",
+		}, {
+			Name:    "user",
+			Help:    "SSH username.",
+			Default: currentUser,
+		}, {
+			Name:    "port",
+			Help:    "SSH port number.",
+			Default: 22,
+		}, {
+			Name:       "pass",
+			Help:       "SSH password, leave blank to use ssh-agent.",
+			IsPassword: true,
+		}, {
+			Name: "key_file",
+			Help: "Path to PEM-encoded private key file.\n\nLeave blank or set key-use-agent to use ssh-agent." + env.ShellExpandHelp,
+		}, {
+			Name:       "key_file_pass",
+			Help:       "The passphrase to decrypt the PEM-encoded private key file.",
+			IsPassword: true,
+			Advanced:   true,
+		}, {
+			Name:     "key_use_agent",
+			Help:     "When set forces the usage of the ssh-agent.",
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name: "known_hosts_file",
+			Help: `Optional path to known_hosts file.
+
+Set this value to enable server host key validation.` + env.ShellExpandHelp,
+			Advanced: true,
+			Examples: []fs.OptionExample{{
+				Value: "~/.ssh/known_hosts",
+				Help:  "Use OpenSSH's known_hosts file.",
+			}},
+		}, {
+			Name: "ssh",
+			Help: `Path and arguments to an external ssh binary to use.
+
+Normally rclone connects with its built in SSH client using the host,
+user, port, pass and key options. If this is set then it is run
+instead with the command to run on the remote host added as the last
+argument and those options are ignored, e.g.
+
+    ssh -i /path/to/key -p 2222 user@example.com
+
+This allows everything in your ssh config to be used, e.g. jump hosts.`,
+			Advanced: true,
+		}, {
+			Name: "rsync_path",
+			Help: `Command to run rsync on the remote host.
+
+Set this if rsync isn't in the PATH on the remote host. It isn't
+quoted so can contain arguments, e.g. "sudo rsync".`,
+			Default:  "rsync",
+			Advanced: true,
+		}, {
+			Name:     "copy_links",
+			Help:     "Follow symlinks on the remote host and copy the pointed to item.",
+			Default:  false,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// The remote rsync expands wildcards in the paths it
+			// is asked to send so they must be encoded
+			Default: (encoder.Base |
+				encoder.EncodeInvalidUtf8 |
+				encoder.EncodeAsterisk |
+				encoder.EncodeQuestion |
+				encoder.EncodeSquareBracket |
+				encoder.EncodeBackSlash),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Host           string               `config:"host"`
+	User           string               `config:"user"`
+	Port           string               `config:"port"`
+	Pass           string               `config:"pass"`
+	KeyFile        string               `config:"key_file"`
+	KeyFilePass    string               `config:"key_file_pass"`
+	KeyUseAgent    bool                 `config:"key_use_agent"`
+	KnownHostsFile string               `config:"known_hosts_file"`
+	SSH            fs.SpaceSepList      `config:"ssh"`
+	RsyncPath      string               `config:"rsync_path"`
+	CopyLinks      bool                 `config:"copy_links"`
+	Enc            encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a directory on a remote host reached over SSH
+type Fs struct {
+	name     string
+	root     string
+	opt      Options        // parsed options
+	ci       *fs.ConfigInfo // global config
+	features *fs.Features   // optional features
+	config   *ssh.ClientConfig
+	clientMu sync.Mutex  // protects client
+	client   *ssh.Client // connection to the ssh server if open
+}
+
+// Object describes a file on the remote host
+type Object struct {
+	fs      *Fs
+	remote  string
+	size    int64
+	modTime time.Time
+}
+
+// ------------------------------------------------------------
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.User == "" {
+		opt.User = currentUser
+	}
+	if opt.Port == "" {
+		opt.Port = "22"
+	}
+	if opt.RsyncPath == "" {
+		opt.RsyncPath = "rsync"
+	}
+	if opt.Host == "" && len(opt.SSH) == 0 {
+		return nil, errors.New("host or ssh must be set")
+	}
+	root = path.Clean(root)
+	if root == "." {
+		root = ""
+	}
+	f := &Fs{
+		name: name,
+		root: root,
+		opt:  *opt,
+		ci:   fs.GetConfig(ctx),
+	}
+	if len(opt.SSH) == 0 {
+		f.config, err = f.sshConfig()
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+
+	if f.root != "" && f.root != "/" {
+		// Check to see if the root is a file
+		oldRoot := f.root
+		f.root = path.Dir(oldRoot)
+		if f.root == "." {
+			f.root = ""
+		}
+		_, err := f.NewObject(ctx, path.Base(oldRoot))
+		if err == nil {
+			return f, fs.ErrorIsFile
+		}
+		f.root = oldRoot
+		if err != fs.ErrorObjectNotFound && err != fs.ErrorIsDir {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	if len(f.opt.SSH) > 0 {
+		return fmt.Sprintf("rsync root '%s'", f.root)
+	}
+	return "rsync://" + f.opt.User + "@" + f.opt.Host + ":" + f.opt.Port + "/" + f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the modification times
+//
+// Version 27 of the rsync protocol sends them in seconds.
+func (f *Fs) Precision() time.Duration {
+	return time.Second
+}
+
+// Hashes returns the supported hash sets
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// remotePath returns the path on the remote host of remote
+func (f *Fs) remotePath(remote string) string {
+	p := f.opt.Enc.FromStandardPath(path.Join(f.root, remote))
+	if p == "" {
+		return "."
+	}
+	if strings.HasPrefix(p, "-") {
+		// don't let the path be mistaken for an option
+		p = "./" + p
+	}
+	return p
+}
+
+// dirPath returns p with a trailing slash so rsync sends what is in
+// it rather than the directory itself
+func dirPath(p string) string {
+	if strings.HasSuffix(p, "/") {
+		return p
+	}
+	return p + "/"
+}
+
+// shellCommand joins cmd and args into a command line for the
+// remote shell
+func shellCommand(cmd string, args ...string) string {
+	for _, arg := range args {
+		cmd += " " + shellQuote(arg)
+	}
+	return cmd
+}
+
+// startRsync starts the rsync server with args and the path p
+func (f *Fs) startRsync(ctx context.Context, p string, args ...string) (*session, error) {
+	args = append(append([]string{"--server"}, args...), ".", p)
+	proc, err := f.start(ctx, shellCommand(f.opt.RsyncPath, args...))
+	if err != nil {
+		return nil, err
+	}
+	return f.newRsyncSession(proc)
+}
+
+// startSender starts rsync sending p with flags and reads the file
+// list
+//
+// It returns fs.ErrorDirNotFound if p wasn't found.
+func (f *Fs) startSender(ctx context.Context, p string, flags string) (s *session, entries []*fileEntry, err error) {
+	// Ask for the owners and groups as some servers send their
+	// names whether asked or not
+	flags += "og"
+	if f.opt.CopyLinks {
+		flags += "L"
+	}
+	s, err = f.startRsync(ctx, p, "--sender", flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.sendFilterList()
+	err = s.flush()
+	if err == nil {
+		entries, err = s.readFileList(true)
+	}
+	if err == nil && len(entries) == 0 && s.notFound() {
+		err = fs.ErrorDirNotFound
+	}
+	if err != nil {
+		err = s.fail(err)
+		if s.notFound() {
+			err = fs.ErrorDirNotFound
+		}
+		return nil, nil, err
+	}
+	return s, entries, nil
+}
+
+// fileList returns the file list of p sent with flags
+//
+// It returns fs.ErrorDirNotFound if p wasn't found.
+func (f *Fs) fileList(ctx context.Context, p string, flags string) ([]*fileEntry, error) {
+	s, entries, err := f.startSender(ctx, p, flags)
+	if err != nil {
+		return nil, err
+	}
+	err = s.finishReceiving(len(entries), nil)
+	if err == nil {
+		err = s.close()
+	}
+	if err != nil {
+		return nil, s.fail(err)
+	}
+	return entries, nil
+}
+
+// listDir returns the entries in dir recursing into directories if
+// recurse is set
+func (f *Fs) listDir(ctx context.Context, dir string, recurse bool, fn func(fs.DirEntry) error) error {
+	flags := "-td"
+	if recurse {
+		flags = "-tr"
+	}
+	entries, err := f.fileList(ctx, dirPath(f.remotePath(dir)), flags)
+	if err != nil {
+		return err
+	}
+	foundRoot := false
+	for _, e := range entries {
+		if e.name == "." {
+			foundRoot = e.isDir()
+			continue
+		}
+		if !recurse && strings.Contains(e.name, "/") {
+			continue
+		}
+		remote := path.Join(dir, f.opt.Enc.ToStandardPath(e.name))
+		switch {
+		case e.isDir():
+			err = fn(fs.NewDir(remote, e.modTime))
+		case e.isRegular():
+			err = fn(f.newObject(remote, e))
+		default:
+			fs.Debugf(f, "Skipping non regular file %q", remote)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !foundRoot {
+		return fs.ErrorDirNotFound
+	}
+	return nil
+}
+
+// List the objects and directories in dir into entries. The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.listDir(ctx, dir, false, func(entry fs.DirEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+//
+// Don't implement this unless you have a more efficient way
+// of listing recursively than doing a directory traversal.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	list := walk.NewListRHelper(callback)
+	err := f.listDir(ctx, dir, true, list.Add)
+	if err != nil {
+		return err
+	}
+	return list.Flush()
+}
+
+// newObject makes an Object for remote from a file list entry
+func (f *Fs) newObject(remote string, e *fileEntry) *Object {
+	return &Object{
+		fs:      f,
+		remote:  remote,
+		size:    e.size,
+		modTime: e.modTime,
+	}
+}
+
+// NewObject finds the Object at remote. If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	p := f.remotePath(remote)
+	entries, err := f.fileList(ctx, p, "-td")
+	if err == fs.ErrorDirNotFound {
+		return nil, fs.ErrorObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.name != path.Base(p) {
+			continue
+		}
+		if e.isDir() {
+			return nil, fs.ErrorIsDir
+		}
+		if e.isRegular() {
+			return f.newObject(remote, e), nil
+		}
+	}
+	return nil, fs.ErrorObjectNotFound
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// mkdir makes the directory at p on the remote host and its parents
+func (f *Fs) mkdir(ctx context.Context, p string) error {
+	_, err := f.run(ctx, shellCommand("mkdir -p", p))
+	return err
+}
+
+// exists returns whether there is anything at p on the remote host
+func (f *Fs) exists(ctx context.Context, p string) (bool, error) {
+	_, err := f.run(ctx, shellCommand("test -e", p))
+	if err == nil {
+		return true, nil
+	}
+	if status, ok := exitStatus(err); ok && status == 1 {
+		return false, nil
+	}
+	return false, err
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.mkdir(ctx, f.remotePath(dir))
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	_, err := f.run(ctx, shellCommand("rmdir", f.remotePath(dir)))
+	return err
+}
+
+// Purge deletes all the files and directories including the old versions.
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	p := f.remotePath(dir)
+	ok, err := f.exists(ctx, p)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fs.ErrorDirNotFound
+	}
+	_, err = f.run(ctx, shellCommand("rm -rf", p))
+	return err
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	dstPath := f.remotePath(remote)
+	_, err := f.run(ctx, shellCommand("mkdir -p", path.Dir(dstPath))+" && "+shellCommand("mv -f", srcObj.remotePath(), dstPath))
+	if err != nil {
+		return nil, fmt.Errorf("move failed: %w", err)
+	}
+	return &Object{
+		fs:      f,
+		remote:  remote,
+		size:    srcObj.size,
+		modTime: srcObj.modTime,
+	}, nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	srcPath := srcFs.remotePath(srcRemote)
+	dstPath := f.remotePath(dstRemote)
+	ok, err := f.exists(ctx, dstPath)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return fs.ErrorDirExists
+	}
+	_, err = f.run(ctx, shellCommand("mkdir -p", path.Dir(dstPath))+" && "+shellCommand("mv", srcPath, dstPath))
+	if err != nil {
+		return fmt.Errorf("dirmove failed: %w", err)
+	}
+	return nil
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	f.clientMu.Lock()
+	defer f.clientMu.Unlock()
+	if f.client == nil {
+		return nil
+	}
+	err := f.client.Close()
+	f.client = nil
+	return err
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// remotePath returns the path of the object on the remote host
+func (o *Object) remotePath() string {
+	return o.fs.remotePath(o.remote)
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	stamp := modTime.UTC().Format("200601021504.05")
+	_, err := o.fs.run(ctx, "TZ=UTC "+shellCommand("touch -c -m -t", stamp, o.remotePath()))
+	if err != nil {
+		return err
+	}
+	o.modTime = modTime.Truncate(time.Second)
+	return nil
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	s, entries, err := o.fs.startSender(ctx, o.remotePath(), "-t")
+	if err == fs.ErrorDirNotFound {
+		return nil, fs.ErrorObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 || !entries[0].isRegular() {
+		_ = s.fail(fs.ErrorObjectNotFound)
+		return nil, fs.ErrorObjectNotFound
+	}
+
+	// Ask for the whole file as there is nothing to compare it with
+	s.writeInt(0)
+	s.writeSumHead(sumHead{})
+	pr, pw := io.Pipe()
+	go func() {
+		received := false
+		err := s.finishReceiving(len(entries), func(ndx int32) error {
+			received = true
+			return s.receiveData(pw)
+		})
+		if err == nil {
+			err = s.close()
+		}
+		if err == nil && !received {
+			err = errors.New("rsync server didn't send the file")
+		}
+		if err != nil {
+			err = s.fail(err)
+		}
+		_ = pw.CloseWithError(err)
+	}()
+	if offset > 0 {
+		_, err = io.CopyN(ioutil.Discard, pr, offset)
+		if err != nil {
+			_ = pr.Close()
+			return nil, err
+		}
+	}
+	return readers.NewLimitedReadCloser(pr, limit), nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The data is sent with the rsync algorithm, so if the file exists
+// already only the blocks which have changed are sent.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	size := src.Size()
+	if size < 0 {
+		return errors.New("can't upload files of unknown size")
+	}
+	modTime := src.ModTime(ctx)
+	dir, leaf := path.Split(o.remotePath())
+	if dir == "" {
+		dir = "./"
+	}
+	err = o.fs.mkdir(ctx, dir)
+	if err != nil {
+		return fmt.Errorf("failed to make directory: %w", err)
+	}
+
+	// -I makes the server always ask for the file as the size and
+	// modification time can be the same with different contents
+	s, err := o.fs.startRsync(ctx, dir, "-tI")
+	if err != nil {
+		return err
+	}
+	s.writeFileList(&fileEntry{
+		name:    leaf,
+		size:    size,
+		modTime: modTime,
+		mode:    modeRegular | 0644,
+	})
+	sent := false
+	err = s.flush()
+	for phase := 0; err == nil && phase < 2; {
+		var ndx int32
+		ndx, err = s.readNdx(1)
+		if err != nil {
+			break
+		}
+		if ndx == ndxDone {
+			phase++
+			s.writeInt(ndxDone)
+			err = s.flush()
+			continue
+		}
+		if sent {
+			// The receiver wants the file again as it didn't
+			// check out, but the data has gone
+			err = errors.New("rsync server asked for the file again")
+			break
+		}
+		sent = true
+		err = o.send(s, ndx, in)
+	}
+	if err == nil && !sent {
+		err = errors.New("rsync server didn't ask for the file")
+	}
+	if err == nil {
+		// read the goodbye
+		_, err = s.readNdx(0)
+	}
+	if err == nil {
+		err = s.close()
+	}
+	if err != nil {
+		return s.fail(err)
+	}
+	o.size = size
+	o.modTime = modTime.Truncate(time.Second)
+	return nil
+}
+
+// send sends the data from in to the server after reading the
+// checksums of the file it has already
+func (o *Object) send(s *session, ndx int32, in io.Reader) error {
+	head, err := s.readSumHead()
+	if err != nil {
+		return err
+	}
+	sums, err := s.readSums(head)
+	if err != nil {
+		return err
+	}
+	s.writeInt(ndx)
+	s.writeSumHead(head)
+	t := &tokenWriter{w: s.w}
+	fileSum, err := writeDelta(t, in, sums, head, s.seed)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(fileSum)
+	if err != nil {
+		return err
+	}
+	fs.Debugf(o, "Sent %d bytes of literal data and matched %d bytes in %d blocks", t.literal, t.matched, len(sums))
+	return s.flush()
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	_, err := o.fs.run(ctx, shellCommand("rm", o.remotePath()))
+	return err
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs         = &Fs{}
+	_ fs.Purger     = &Fs{}
+	_ fs.Mover      = &Fs{}
+	_ fs.DirMover   = &Fs{}
+	_ fs.ListRer    = &Fs{}
+	_ fs.Shutdowner = &Fs{}
+	_ fs.Object     = &Object{}
+)
@@ -0,0 +1,293 @@
+//go:build !plan9
+// +build !plan9
+
+package rsync
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeSums makes the block checksums of basis like the receiver does
+func makeSums(basis []byte, blockLength int, seed int32) (sumHead, []blockSum) {
+	head := sumHead{
+		blockLength: int32(blockLength),
+		sumLength:   md4Size,
+		remainder:   int32(len(basis) % blockLength),
+	}
+	var sums []blockSum
+	for i := 0; i < len(basis); i += blockLength {
+		end := i + blockLength
+		if end > len(basis) {
+			end = len(basis)
+		}
+		block := basis[i:end]
+		sums = append(sums, blockSum{
+			sum1:   checksum1(block),
+			sum2:   checksum2(block, seed),
+			length: int32(len(block)),
+		})
+	}
+	head.count = int32(len(sums))
+	return head, sums
+}
+
+// applyDelta rebuilds the file from the tokens in delta and basis
+func applyDelta(t *testing.T, delta []byte, basis []byte, blockLength int) []byte {
+	var out bytes.Buffer
+	r := bytes.NewReader(delta)
+	for {
+		var token int32
+		require.NoError(t, binary.Read(r, binary.LittleEndian, &token))
+		if token == 0 {
+			break
+		}
+		if token > 0 {
+			require.LessOrEqual(t, int(token), chunkSize)
+			_, err := io.CopyN(&out, r, int64(token))
+			require.NoError(t, err)
+			continue
+		}
+		start := int(-(token + 1)) * blockLength
+		end := start + blockLength
+		if end > len(basis) {
+			end = len(basis)
+		}
+		out.Write(basis[start:end])
+	}
+	assert.Equal(t, 0, r.Len(), "data after end token")
+	return out.Bytes()
+}
+
+func TestChecksum1(t *testing.T) {
+	assert.Equal(t, uint32(0x00000000), checksum1(nil))
+	assert.Equal(t, uint32(0x1d480488), checksum1([]byte("hello, world")))
+	// bytes over 0x7F are signed
+	assert.Equal(t, uint32(0xfffdfffe), checksum1([]byte{0xFF, 0xFF}))
+}
+
+func TestWriteDelta(t *testing.T) {
+	const blockLength = 700
+	rng := rand.New(rand.NewSource(1))
+	basis := make([]byte, 100*blockLength+123)
+	_, _ = rng.Read(basis)
+
+	for _, test := range []struct {
+		name       string
+		seed       int32
+		edit       func([]byte) []byte
+		maxLiteral int64
+	}{{
+		name:       "unchanged",
+		seed:       1234,
+		edit:       func(b []byte) []byte { return b },
+		maxLiteral: 0,
+	}, {
+		name: "changed byte",
+		seed: 1234,
+		edit: func(b []byte) []byte {
+			b[50*blockLength+10] ^= 0xFF
+			return b
+		},
+		maxLiteral: blockLength,
+	}, {
+		name: "inserted",
+		seed: 0,
+		edit: func(b []byte) []byte {
+			return append(b[:30*blockLength+7:30*blockLength+7], append([]byte("inserted"), b[30*blockLength+7:]...)...)
+		},
+		maxLiteral: blockLength + 8,
+	}, {
+		name: "truncated",
+		seed: -1,
+		edit: func(b []byte) []byte {
+			return b[:60*blockLength+10]
+		},
+		maxLiteral: 10,
+	}, {
+		name: "appended",
+		seed: 99,
+		edit: func(b []byte) []byte {
+			return append(b, make([]byte, 3*chunkSize)...)
+		},
+		maxLiteral: 3*chunkSize + blockLength,
+	}, {
+		name: "different",
+		seed: 99,
+		edit: func(b []byte) []byte {
+			c := make([]byte, len(b))
+			_, _ = rng.Read(c)
+			return c
+		},
+		maxLiteral: int64(len(basis)),
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			data := test.edit(append([]byte{}, basis...))
+			head, sums := makeSums(basis, blockLength, test.seed)
+			var delta bytes.Buffer
+			tw := &tokenWriter{w: &delta}
+			fileSum, err := writeDelta(tw, bytes.NewReader(data), sums, head, test.seed)
+			require.NoError(t, err)
+			assert.Equal(t, data, applyDelta(t, delta.Bytes(), basis, blockLength))
+			assert.LessOrEqual(t, tw.literal, test.maxLiteral)
+			assert.Equal(t, int64(len(data)), tw.literal+tw.matched)
+			h := newFileSum(test.seed)
+			_, _ = h.Write(data)
+			assert.Equal(t, h.Sum(nil), fileSum)
+		})
+	}
+}
+
+func TestWriteDeltaNoBasis(t *testing.T) {
+	data := bytes.Repeat([]byte("rsync"), chunkSize)
+	var delta bytes.Buffer
+	tw := &tokenWriter{w: &delta}
+	_, err := writeDelta(tw, bytes.NewReader(data), nil, sumHead{}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, data, applyDelta(t, delta.Bytes(), nil, 0))
+	assert.Equal(t, int64(len(data)), tw.literal)
+}
+
+// newTestSession makes a session which reads and writes buf
+func newTestSession(buf *bytes.Buffer) *session {
+	return &session{
+		f: &Fs{},
+		r: bufio.NewReader(buf),
+		w: bufio.NewWriter(buf),
+	}
+}
+
+func TestFileList(t *testing.T) {
+	var buf bytes.Buffer
+	s := newTestSession(&buf)
+	modTime := time.Unix(1662808333, 0)
+	s.writeFileList(&fileEntry{
+		name:    "file.txt",
+		size:    5 << 30,
+		modTime: modTime,
+		mode:    modeRegular | 0644,
+	})
+	require.NoError(t, s.flush())
+	entries, err := s.readFileList(false)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "file.txt", entries[0].name)
+	assert.Equal(t, int64(5<<30), entries[0].size)
+	assert.Equal(t, modTime, entries[0].modTime)
+	assert.True(t, entries[0].isRegular())
+	assert.Equal(t, 0, buf.Len())
+
+	// a list with names sharing prefixes, owners and groups as
+	// a server sends it
+	buf.Reset()
+	raw := []byte{
+		xmitTopDir | xmitLongName, 1, 0, 0, 0, '.',
+		0, 0x10, 0, 0, // size
+		1, 0, 0, 0, // mtime
+		0xED, 0x41, 0, 0, // mode 040755
+		100, 0, 0, 0, // uid
+		200, 0, 0, 0, // gid
+		xmitSameMode | xmitSameUID | xmitSameGID, 4, 'd', 'i', 'r', '1',
+		0, 0x10, 0, 0,
+		2, 0, 0, 0,
+		xmitSameName | xmitSameTime | xmitSameUID | xmitSameGID, 4, 3, '/', 'f', 'i',
+		5, 0, 0, 0,
+		0xA4, 0x81, 0, 0, // mode 0100644
+		xmitSameName | xmitSameUID | xmitSameGID, 3, 1, '2',
+		0, 0x10, 0, 0,
+		1, 0, 0, 0,
+		0xED, 0x41, 0, 0,
+		0,                                               // end of list
+		100, 0, 0, 0, 4, 'u', 's', 'e', 'r', 0, 0, 0, 0, // users
+		0, 0, 0, 0, // groups
+		0, 0, 0, 0, // I/O errors
+	}
+	buf.Write(raw)
+	s = newTestSession(&buf)
+	entries, err = s.readFileList(true)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.name)
+	}
+	assert.Equal(t, []string{".", "dir1", "dir1/fi", "dir2"}, names)
+	assert.True(t, entries[0].isDir())
+	assert.True(t, entries[1].isDir())
+	assert.Equal(t, time.Unix(2, 0), entries[1].modTime)
+	assert.Equal(t, time.Unix(2, 0), entries[2].modTime)
+	assert.Equal(t, int64(5), entries[2].size)
+	assert.True(t, entries[2].isRegular())
+	assert.True(t, entries[3].isDir())
+	assert.Equal(t, time.Unix(1, 0), entries[3].modTime)
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestMuxReader(t *testing.T) {
+	var in bytes.Buffer
+	write := func(tag int, data string) {
+		_ = binary.Write(&in, binary.LittleEndian, uint32(tag+mplexBase)<<24|uint32(len(data)))
+		in.WriteString(data)
+	}
+	write(msgData, "hello ")
+	write(msgError, "something went wrong\n")
+	write(msgInfo, "for your information\n")
+	write(msgData, "")
+	write(msgData, "world")
+	s := &session{f: &Fs{}}
+	out, err := io.ReadAll(&muxReader{in: &in, s: s})
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(out))
+	assert.Equal(t, []string{"something went wrong"}, s.errors)
+	assert.False(t, s.notFound())
+
+	s.errors = append(s.errors, `link_stat "/x" failed: No such file or directory (2)`)
+	assert.True(t, s.notFound())
+}
+
+func TestShellQuote(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"simple/path.txt", "simple/path.txt"},
+		{"with space", "'with space'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME;rm", "'$HOME;rm'"},
+		{"", "''"},
+	} {
+		assert.Equal(t, test.want, shellQuote(test.in), test.in)
+	}
+	assert.Equal(t, "rsync --server . 'a b'", shellCommand("rsync", "--server", ".", "a b"))
+}
+
+// TestLocal runs the integration tests against the rsync installed
+// on this machine, run with a local shell instead of ssh
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("skipping as -remote is set")
+	}
+	if _, err := exec.LookPath("rsync"); err != nil {
+		t.Skip("rsync not found")
+	}
+	name := "TestRsyncLocal"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":" + t.TempDir(),
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "rsync"},
+			{Name: name, Key: "ssh", Value: "sh -c"},
+		},
+		QuickTestOK: true,
+	})
+}
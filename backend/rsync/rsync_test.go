@@ -0,0 +1,21 @@
+// Test Rsync filesystem interface
+
+//go:build !plan9
+// +build !plan9
+
+package rsync_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/rsync"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestRsync:",
+		NilObject:  (*rsync.Object)(nil),
+	})
+}
@@ -0,0 +1,7 @@
+// Build for rsync for unsupported platforms to stop go complaining
+// about "no buildable Go source files "
+
+//go:build plan9
+// +build plan9
+
+package rsync
@@ -0,0 +1,326 @@
+//go:build !plan9
+// +build !plan9
+
+package rsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/lib/env"
+	sshagent "github.com/xanzy/ssh-agent"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Characters which don't need quoting in the remote shell
+var shellSafeRegex = regexp.MustCompile(`^[A-Za-z0-9_.,:/@%+=-]+$`)
+
+// shellQuote quotes s so the remote shell passes it as a single
+// argument
+func shellQuote(s string) string {
+	if shellSafeRegex.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// lockedBuffer collects the stderr of a process
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write satisfies the io.Writer interface
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// String returns the trimmed contents of the buffer
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(b.buf.String())
+}
+
+// process is a command running on the remote host
+type process struct {
+	cmd    string
+	stdin  io.WriteCloser
+	stdout io.Reader
+	stderr lockedBuffer
+	wait   func() error // wait for the command to exit
+	kill   func()       // stop the command
+}
+
+// Wait closes the input of the command and waits for it to exit
+func (p *process) Wait() error {
+	_ = p.stdin.Close()
+	err := p.wait()
+	if err != nil {
+		if stderr := p.stderr.String(); stderr != "" {
+			return fmt.Errorf("%q failed: %s: %w", p.cmd, stderr, err)
+		}
+		return fmt.Errorf("%q failed: %w", p.cmd, err)
+	}
+	return nil
+}
+
+// Kill stops the command and waits for it to exit
+func (p *process) Kill() {
+	p.kill()
+	_ = p.stdin.Close()
+	_ = p.wait()
+}
+
+// start runs cmd on the remote host returning a process to
+// communicate with it
+//
+// The process is killed if ctx is cancelled before it finishes.
+func (f *Fs) start(ctx context.Context, cmd string) (*process, error) {
+	fs.Debugf(f, "Running remote command: %s", cmd)
+	if len(f.opt.SSH) > 0 {
+		return f.startExternal(ctx, cmd)
+	}
+	session, err := f.newSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := &process{cmd: cmd}
+	session.Stderr = &p.stderr
+	p.stdin, err = session.StdinPipe()
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	p.stdout, err = session.StdoutPipe()
+	if err != nil {
+		_ = session.Close()
+		return nil, err
+	}
+	err = session.Start(cmd)
+	if err != nil {
+		_ = session.Close()
+		return nil, fmt.Errorf("failed to start %q: %w", cmd, err)
+	}
+	done := make(chan struct{})
+	var once sync.Once
+	p.kill = func() {
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+	}
+	p.wait = func() (err error) {
+		err = session.Wait()
+		_ = session.Close()
+		once.Do(func() { close(done) })
+		return err
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.kill()
+		case <-done:
+		}
+	}()
+	return p, nil
+}
+
+// startExternal runs cmd on the remote host using the ssh command
+// from the config
+func (f *Fs) startExternal(ctx context.Context, cmd string) (*process, error) {
+	args := append(append([]string{}, f.opt.SSH[1:]...), cmd)
+	c := exec.CommandContext(ctx, f.opt.SSH[0], args...)
+	p := &process{cmd: cmd}
+	c.Stderr = &p.stderr
+	var err error
+	p.stdin, err = c.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	p.stdout, err = c.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = c.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start %q: %w", f.opt.SSH[0], err)
+	}
+	p.kill = func() {
+		_ = c.Process.Kill()
+	}
+	p.wait = c.Wait
+	return p, nil
+}
+
+// run runs cmd on the remote host returning its output
+func (f *Fs) run(ctx context.Context, cmd string) ([]byte, error) {
+	p, err := f.start(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	out, err := ioutil.ReadAll(p.stdout)
+	if err != nil {
+		p.Kill()
+		return nil, err
+	}
+	err = p.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// sshConfig makes the config for the built in ssh client from the
+// options
+func (f *Fs) sshConfig() (*ssh.ClientConfig, error) {
+	opt := &f.opt
+	config := &ssh.ClientConfig{
+		User:            opt.User,
+		Auth:            []ssh.AuthMethod{},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         f.ci.ConnectTimeout,
+		ClientVersion:   "SSH-2.0-" + f.ci.UserAgent,
+	}
+	if opt.KnownHostsFile != "" {
+		hostCallback, err := knownhosts.New(env.ShellExpand(opt.KnownHostsFile))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse known_hosts_file: %w", err)
+		}
+		config.HostKeyCallback = hostCallback
+	}
+	keyFile := env.ShellExpand(opt.KeyFile)
+	if (opt.Pass == "" && keyFile == "") || opt.KeyUseAgent {
+		agent, _, err := sshagent.New()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't connect to ssh-agent: %w", err)
+		}
+		signers, err := agent.Signers()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read ssh agent signers: %w", err)
+		}
+		config.Auth = append(config.Auth, ssh.PublicKeys(signers...))
+	}
+	if keyFile != "" {
+		key, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file: %w", err)
+		}
+		var signer ssh.Signer
+		if opt.KeyFilePass == "" {
+			signer, err = ssh.ParsePrivateKey(key)
+		} else {
+			var pass string
+			pass, err = obscure.Reveal(opt.KeyFilePass)
+			if err != nil {
+				return nil, err
+			}
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(pass))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key file: %w", err)
+		}
+		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
+	}
+	if opt.Pass != "" {
+		pass, err := obscure.Reveal(opt.Pass)
+		if err != nil {
+			return nil, err
+		}
+		config.Auth = append(config.Auth,
+			ssh.Password(pass),
+			ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = pass
+				}
+				return answers, nil
+			}),
+		)
+	}
+	return config, nil
+}
+
+// sshClient returns the connection to the ssh server, dialling it if
+// necessary
+func (f *Fs) sshClient(ctx context.Context) (*ssh.Client, error) {
+	f.clientMu.Lock()
+	defer f.clientMu.Unlock()
+	if f.client != nil {
+		return f.client, nil
+	}
+	addr := f.opt.Host + ":" + f.opt.Port
+	conn, err := fshttp.NewDialer(ctx).Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, f.config)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("couldn't connect to %s: %w", addr, err)
+	}
+	fs.Debugf(f, "New connection %s->%s to %q", c.LocalAddr(), c.RemoteAddr(), c.ServerVersion())
+	f.client = ssh.NewClient(c, chans, reqs)
+	return f.client, nil
+}
+
+// closeClient closes client and forgets it if it is still the current
+// connection
+func (f *Fs) closeClient(client *ssh.Client) {
+	f.clientMu.Lock()
+	defer f.clientMu.Unlock()
+	if f.client == client {
+		f.client = nil
+	}
+	_ = client.Close()
+}
+
+// newSession opens a new session on the ssh connection, reconnecting
+// once if the connection has gone away
+func (f *Fs) newSession(ctx context.Context) (*ssh.Session, error) {
+	client, err := f.sshClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session, err := client.NewSession()
+	if err == nil {
+		return session, nil
+	}
+	fs.Debugf(f, "Reconnecting after failing to open session: %v", err)
+	f.closeClient(client)
+	client, err = f.sshClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session, err = client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open ssh session: %w", err)
+	}
+	return session, nil
+}
+
+// exitStatus returns the exit status of the process which returned
+// err and whether there was one
+func exitStatus(err error) (int, bool) {
+	var sshErr *ssh.ExitError
+	if errors.As(err, &sshErr) {
+		return sshErr.ExitStatus(), true
+	}
+	var execErr *exec.ExitError
+	if errors.As(err, &execErr) {
+		return execErr.ExitCode(), true
+	}
+	return 0, false
+}
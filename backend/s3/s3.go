@@ -1984,6 +1984,7 @@ circumstances or for testing.
 			Advanced: true,
 		},
 		}})
+	fs.RegisterCrossCopy((*Fs)(nil), (*Fs)(nil), crossCopy)
 }
 
 // Constants
@@ -2053,6 +2054,21 @@ var systemMetadataInfo = map[string]fs.MetadataHelp{
 		Example:  "2006-01-02T15:04:05.999999999Z07:00",
 		ReadOnly: true,
 	},
+	"object-lock-mode": {
+		Help:    "Object Lock retention mode: GOVERNANCE or COMPLIANCE",
+		Type:    "string",
+		Example: "GOVERNANCE",
+	},
+	"object-lock-retain-until-date": {
+		Help:    "Time the object is retained until by Object Lock",
+		Type:    "RFC 3339",
+		Example: "2006-01-02T15:04:05.999999999Z07:00",
+	},
+	"object-lock-legal-hold-status": {
+		Help:    "Object Lock legal hold: ON or OFF",
+		Type:    "string",
+		Example: "ON",
+	},
 }
 
 // Options defines the configuration for this backend
@@ -2119,6 +2135,7 @@ type Fs struct {
 	srvRest       *rest.Client     // the rest connection to the server
 	pool          *pool.Pool       // memory pool
 	etagIsNotMD5  bool             // if set ETags are not MD5s
+	crossDenied   sync.Map         // names of remotes we can't copy from server-side
 }
 
 // Object describes a s3 object
@@ -2141,6 +2158,11 @@ type Object struct {
 	contentDisposition *string // Content-Disposition: header
 	contentEncoding    *string // Content-Encoding: header
 	contentLanguage    *string // Content-Language: header
+
+	// Object Lock settings
+	objectLockMode            *string    // GOVERNANCE or COMPLIANCE
+	objectLockRetainUntilDate *time.Time // retained until this time
+	objectLockLegalHoldStatus *string    // ON or OFF
 }
 
 // ------------------------------------------------------------
@@ -3075,6 +3097,43 @@ func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (
 	return list.Flush()
 }
 
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	bucket, directory := f.split(dir)
+	if bucket == "" {
+		if directory != "" {
+			return fs.ErrorListBucketRequired
+		}
+		entries, err := f.listBuckets(ctx)
+		if err != nil {
+			return err
+		}
+		return callback(entries)
+	}
+	list := walk.NewListRHelper(callback)
+	err = f.list(ctx, bucket, directory, f.rootDirectory, f.rootBucket == "", false, func(remote string, object *s3.Object, isDirectory bool) error {
+		entry, err := f.itemToDirEntry(ctx, remote, object, isDirectory)
+		if err != nil {
+			return err
+		}
+		return list.Add(entry)
+	})
+	if err != nil {
+		return err
+	}
+	// bucket must be present if listing succeeded
+	f.cache.MarkOK(bucket)
+	return list.Flush()
+}
+
 // Put the Object into the bucket
 func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	// Temporary Object under construction
@@ -3358,6 +3417,13 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	req := s3.CopyObjectInput{
 		MetadataDirective: aws.String(s3.MetadataDirectiveCopy),
 	}
+	if fs.GetConfig(ctx).Metadata {
+		err = srcObj.readMetaData(ctx)
+		if err != nil {
+			return nil, err
+		}
+		srcObj.copyObjectLock(&req)
+	}
 	err = f.copy(ctx, &req, dstBucket, dstPath, srcBucket, srcPath, srcObj)
 	if err != nil {
 		return nil, err
@@ -3365,6 +3431,46 @@ func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object,
 	return f.NewObject(ctx, remote)
 }
 
+// sameEndpoint returns true if f and other talk to the same server
+func (f *Fs) sameEndpoint(other *Fs) bool {
+	return f.opt.Provider == other.opt.Provider &&
+		f.opt.Region == other.opt.Region &&
+		strings.TrimRight(f.opt.Endpoint, "/") == strings.TrimRight(other.opt.Endpoint, "/")
+}
+
+// crossCopy copies src to remote in fdst server-side where src is on
+// a different s3 remote.
+//
+// This works if both remotes use the same endpoint and the
+// credentials of fdst can read src, otherwise it returns
+// fs.ErrorCantCopy so the file is downloaded and uploaded instead.
+func crossCopy(ctx context.Context, fdst fs.Fs, src fs.Object, remote string) (fs.Object, error) {
+	f, ok := fdst.(*Fs)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	srcName := srcObj.fs.name
+	if !f.sameEndpoint(srcObj.fs) {
+		fs.Debugf(src, "Can't copy server-side - %q and %q use different endpoints", srcName, f.name)
+		return nil, fs.ErrorCantCopy
+	}
+	if _, denied := f.crossDenied.Load(srcName); denied {
+		return nil, fs.ErrorCantCopy
+	}
+	dstObj, err := f.Copy(ctx, src, remote)
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusForbidden {
+		fs.Debugf(src, "Can't copy server-side - %q can't read from %q: %v", f.name, srcName, err)
+		f.crossDenied.Store(srcName, struct{}{})
+		return nil, fs.ErrorCantCopy
+	}
+	return dstObj, err
+}
+
 // Hashes returns the supported hash sets.
 func (f *Fs) Hashes() hash.Set {
 	return hash.Set(hash.MD5)
@@ -3497,6 +3603,52 @@ Durations are parsed as per the rest of rclone, 2h, 7d, 7w etc.
 	Opts: map[string]string{
 		"max-age": "Max age of upload to delete",
 	},
+}, {
+	Name:  "set-retention",
+	Short: "Set the Object Lock retention or legal hold of objects",
+	Long: `This command sets the Object Lock retention period and/or legal hold
+of one or more existing objects. The bucket must have Object Lock
+enabled.
+
+Usage Examples:
+
+    rclone backend set-retention s3:bucket/path/to/object -o mode=GOVERNANCE -o retain-until-date=2030-01-01T00:00:00Z
+    rclone backend set-retention s3:bucket/path/to/directory -o mode=COMPLIANCE -o retain-until-date=365d
+    rclone backend set-retention s3:bucket -o legal-hold=ON
+
+The retain-until-date may be an RFC 3339 time or a duration from now
+such as 30d. The mode and retain-until-date must be set together.
+
+Retention in GOVERNANCE mode can only be shortened with
+-o bypass-governance by users with the s3:BypassGovernanceRetention
+permission. Retention in COMPLIANCE mode can't be shortened at all.
+
+This command also obeys the filters. Test first with -i/--interactive or --dry-run flags
+
+    rclone -i backend set-retention --include "*.txt" s3:bucket/path -o legal-hold=ON
+
+It returns a list of status dictionaries with Remote and Status
+keys. The Status will be OK if it was successful or an error message
+if not.
+
+    [
+        {
+            "Status": "OK",
+            "Remote": "test.txt"
+        },
+        {
+            "Status": "OK",
+            "Remote": "test/file4.txt"
+        }
+    ]
+
+`,
+	Opts: map[string]string{
+		"mode":              "Retention mode: GOVERNANCE|COMPLIANCE",
+		"retain-until-date": "Time to retain the objects until, RFC 3339 or a duration from now",
+		"legal-hold":        "Legal hold status: ON|OFF",
+		"bypass-governance": "Allow GOVERNANCE mode retention to be shortened",
+	},
 }}
 
 // Command the backend to run a named command
@@ -3586,6 +3738,8 @@ func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[str
 			}
 		}
 		return nil, f.cleanUp(ctx, maxAge)
+	case "set-retention":
+		return f.setRetention(ctx, opt)
 	default:
 		return nil, fs.ErrorCommandNotFound
 	}
@@ -3701,6 +3855,121 @@ func (f *Fs) cleanUp(ctx context.Context, maxAge time.Duration) (err error) {
 	return err
 }
 
+// parseRetention parses the options of the set-retention command
+//
+// retention or legalHold is nil if it isn't to be set.
+func parseRetention(opt map[string]string, now time.Time) (retention *s3.ObjectLockRetention, legalHold *s3.ObjectLockLegalHold, err error) {
+	mode := strings.ToUpper(opt["mode"])
+	retainUntil := opt["retain-until-date"]
+	if (mode == "") != (retainUntil == "") {
+		return nil, nil, errors.New("mode and retain-until-date must be set together")
+	}
+	if mode != "" {
+		if mode != s3.ObjectLockRetentionModeGovernance && mode != s3.ObjectLockRetentionModeCompliance {
+			return nil, nil, fmt.Errorf("bad mode %q: must be %s or %s", opt["mode"], s3.ObjectLockRetentionModeGovernance, s3.ObjectLockRetentionModeCompliance)
+		}
+		retainUntilDate, err := time.Parse(time.RFC3339Nano, retainUntil)
+		if err != nil {
+			d, durationErr := fs.ParseDuration(retainUntil)
+			if durationErr != nil {
+				return nil, nil, fmt.Errorf("bad retain-until-date %q: must be an RFC 3339 time or a duration", retainUntil)
+			}
+			retainUntilDate = now.Add(d)
+		}
+		retention = &s3.ObjectLockRetention{
+			Mode:            &mode,
+			RetainUntilDate: &retainUntilDate,
+		}
+	}
+	if status := strings.ToUpper(opt["legal-hold"]); status != "" {
+		if status != s3.ObjectLockLegalHoldStatusOn && status != s3.ObjectLockLegalHoldStatusOff {
+			return nil, nil, fmt.Errorf("bad legal-hold %q: must be %s or %s", opt["legal-hold"], s3.ObjectLockLegalHoldStatusOn, s3.ObjectLockLegalHoldStatusOff)
+		}
+		legalHold = &s3.ObjectLockLegalHold{
+			Status: &status,
+		}
+	}
+	if retention == nil && legalHold == nil {
+		return nil, nil, errors.New("need mode and retain-until-date or legal-hold")
+	}
+	return retention, legalHold, nil
+}
+
+// setRetention sets the Object Lock retention and legal hold of the
+// objects in f as described by opt
+func (f *Fs) setRetention(ctx context.Context, opt map[string]string) (out interface{}, err error) {
+	retention, legalHold, err := parseRetention(opt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	_, bypassGovernance := opt["bypass-governance"]
+	type status struct {
+		Status string
+		Remote string
+	}
+	var (
+		outMu    sync.Mutex
+		statuses = []status{}
+	)
+	err = operations.ListFn(ctx, f, func(obj fs.Object) {
+		// Remember this is run --checkers times concurrently
+		o, ok := obj.(*Object)
+		st := status{Status: "OK", Remote: obj.Remote()}
+		defer func() {
+			outMu.Lock()
+			statuses = append(statuses, st)
+			outMu.Unlock()
+		}()
+		if operations.SkipDestructive(ctx, obj, "set retention") {
+			return
+		}
+		if !ok {
+			st.Status = "Not an S3 object"
+			return
+		}
+		bucket, bucketPath := o.split()
+		var requestPayer *string
+		if f.opt.RequesterPays {
+			requestPayer = aws.String(s3.RequestPayerRequester)
+		}
+		var err error
+		if retention != nil {
+			req := s3.PutObjectRetentionInput{
+				Bucket:       &bucket,
+				Key:          &bucketPath,
+				Retention:    retention,
+				RequestPayer: requestPayer,
+			}
+			if bypassGovernance {
+				req.BypassGovernanceRetention = aws.Bool(true)
+			}
+			err = f.pacer.Call(func() (bool, error) {
+				_, err := f.c.PutObjectRetentionWithContext(ctx, &req)
+				return f.shouldRetry(ctx, err)
+			})
+		}
+		if err == nil && legalHold != nil {
+			req := s3.PutObjectLegalHoldInput{
+				Bucket:       &bucket,
+				Key:          &bucketPath,
+				LegalHold:    legalHold,
+				RequestPayer: requestPayer,
+			}
+			err = f.pacer.Call(func() (bool, error) {
+				_, err := f.c.PutObjectLegalHoldWithContext(ctx, &req)
+				return f.shouldRetry(ctx, err)
+			})
+		}
+		if err != nil {
+			st.Status = err.Error()
+		}
+	})
+	if err != nil {
+		return statuses, err
+	}
+	return statuses, nil
+}
+
 // CleanUp removes all pending multipart uploads older than 24 hours
 func (f *Fs) CleanUp(ctx context.Context) (err error) {
 	return f.cleanUp(ctx, 24*time.Hour)
@@ -3872,6 +4141,19 @@ func (o *Object) setMetaData(resp *s3.HeadObjectOutput) {
 	o.contentDisposition = resp.ContentDisposition
 	o.contentEncoding = resp.ContentEncoding
 	o.contentLanguage = resp.ContentLanguage
+	o.objectLockMode = resp.ObjectLockMode
+	o.objectLockRetainUntilDate = resp.ObjectLockRetainUntilDate
+	o.objectLockLegalHoldStatus = resp.ObjectLockLegalHoldStatus
+}
+
+// copyObjectLock sets the Object Lock settings of o on req
+//
+// Copies don't keep the Object Lock settings of their source unless
+// they are set in the request.
+func (o *Object) copyObjectLock(req *s3.CopyObjectInput) {
+	req.ObjectLockMode = o.objectLockMode
+	req.ObjectLockRetainUntilDate = o.objectLockRetainUntilDate
+	req.ObjectLockLegalHoldStatus = o.objectLockLegalHoldStatus
 }
 
 // ModTime returns the modification time of the object
@@ -3924,6 +4206,7 @@ func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
 	if o.fs.opt.RequesterPays {
 		req.RequestPayer = aws.String(s3.RequestPayerRequester)
 	}
+	o.copyObjectLock(&req)
 	return o.fs.copy(ctx, &req, bucket, bucketPath, bucket, bucketPath, o)
 }
 
@@ -4455,6 +4738,17 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		case "btime":
 			// write as metadata since we can't set it
 			req.Metadata[k] = pv
+		case "object-lock-mode":
+			req.ObjectLockMode = pv
+		case "object-lock-retain-until-date":
+			retainUntilDate, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				fs.Debugf(o, "failed to parse metadata %s: %q: %v", k, v, err)
+			} else {
+				req.ObjectLockRetainUntilDate = &retainUntilDate
+			}
+		case "object-lock-legal-hold-status":
+			req.ObjectLockLegalHoldStatus = pv
 		default:
 			req.Metadata[k] = pv
 		}
@@ -4538,6 +4832,17 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 			req.ContentType = aws.String(value)
 		case "x-amz-tagging":
 			req.Tagging = aws.String(value)
+		case "x-amz-object-lock-mode":
+			req.ObjectLockMode = aws.String(value)
+		case "x-amz-object-lock-retain-until-date":
+			retainUntilDate, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				fs.Errorf(o, "Failed to parse %s header %q: %v", key, value, err)
+			} else {
+				req.ObjectLockRetainUntilDate = &retainUntilDate
+			}
+		case "x-amz-object-lock-legal-hold":
+			req.ObjectLockLegalHoldStatus = aws.String(value)
 		default:
 			const amzMetaPrefix = "x-amz-meta-"
 			if strings.HasPrefix(lowerKey, amzMetaPrefix) {
@@ -4662,7 +4967,7 @@ func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error)
 	if err != nil {
 		return nil, err
 	}
-	metadata = make(fs.Metadata, len(o.meta)+7)
+	metadata = make(fs.Metadata, len(o.meta)+10)
 	for k, v := range o.meta {
 		switch k {
 		case metaMtime:
@@ -4695,6 +5000,11 @@ func (o *Object) Metadata(ctx context.Context) (metadata fs.Metadata, err error)
 	setMetadata("content-encoding", o.contentEncoding)
 	setMetadata("content-language", o.contentLanguage)
 	setMetadata("tier", o.storageClass)
+	setMetadata("object-lock-mode", o.objectLockMode)
+	if o.objectLockRetainUntilDate != nil {
+		metadata["object-lock-retain-until-date"] = o.objectLockRetainUntilDate.Format(time.RFC3339Nano)
+	}
+	setMetadata("object-lock-legal-hold-status", o.objectLockLegalHoldStatus)
 
 	return metadata, nil
 }
@@ -4705,6 +5015,7 @@ var (
 	_ fs.Copier      = &Fs{}
 	_ fs.PutStreamer = &Fs{}
 	_ fs.ListRer     = &Fs{}
+	_ fs.ListPer     = &Fs{}
 	_ fs.Commander   = &Fs{}
 	_ fs.CleanUpper  = &Fs{}
 	_ fs.Object      = &Object{}
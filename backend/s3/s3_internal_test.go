@@ -67,6 +67,84 @@ func (f *Fs) InternalTestMetadata(t *testing.T) {
 	}
 }
 
+func TestParseRetention(t *testing.T) {
+	now := time.Date(2022, 9, 10, 11, 12, 13, 0, time.UTC)
+	for _, test := range []struct {
+		opt           map[string]string
+		wantMode      string
+		wantUntil     time.Time
+		wantLegalHold string
+		wantErr       string
+	}{
+		{opt: map[string]string{}, wantErr: "need mode"},
+		{opt: map[string]string{"mode": "GOVERNANCE"}, wantErr: "set together"},
+		{opt: map[string]string{"retain-until-date": "1d"}, wantErr: "set together"},
+		{opt: map[string]string{"mode": "potato", "retain-until-date": "1d"}, wantErr: "bad mode"},
+		{opt: map[string]string{"mode": "COMPLIANCE", "retain-until-date": "potato"}, wantErr: "bad retain-until-date"},
+		{opt: map[string]string{"legal-hold": "potato"}, wantErr: "bad legal-hold"},
+		{
+			opt:       map[string]string{"mode": "governance", "retain-until-date": "2030-01-02T03:04:05Z"},
+			wantMode:  "GOVERNANCE",
+			wantUntil: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			opt:           map[string]string{"mode": "COMPLIANCE", "retain-until-date": "30d", "legal-hold": "on"},
+			wantMode:      "COMPLIANCE",
+			wantUntil:     now.Add(30 * 24 * time.Hour),
+			wantLegalHold: "ON",
+		},
+		{opt: map[string]string{"legal-hold": "OFF"}, wantLegalHold: "OFF"},
+	} {
+		what := fmt.Sprintf("%v", test.opt)
+		retention, legalHold, err := parseRetention(test.opt, now)
+		if test.wantErr != "" {
+			require.Error(t, err, what)
+			assert.Contains(t, err.Error(), test.wantErr, what)
+			continue
+		}
+		require.NoError(t, err, what)
+		if test.wantMode == "" {
+			assert.Nil(t, retention, what)
+		} else {
+			require.NotNil(t, retention, what)
+			assert.Equal(t, test.wantMode, *retention.Mode, what)
+			assert.True(t, test.wantUntil.Equal(*retention.RetainUntilDate), what)
+		}
+		if test.wantLegalHold == "" {
+			assert.Nil(t, legalHold, what)
+		} else {
+			require.NotNil(t, legalHold, what)
+			assert.Equal(t, test.wantLegalHold, *legalHold.Status, what)
+		}
+	}
+}
+
+func TestCrossCopyEndpoint(t *testing.T) {
+	ctx := context.Background()
+	newFs := func(name, provider, region, endpoint string) *Fs {
+		return &Fs{
+			name: name,
+			opt:  Options{Provider: provider, Region: region, Endpoint: endpoint},
+		}
+	}
+	f := newFs("a", "Minio", "", "https://minio.example.com")
+	assert.True(t, f.sameEndpoint(newFs("b", "Minio", "", "https://minio.example.com/")))
+	assert.False(t, f.sameEndpoint(newFs("b", "Minio", "", "https://other.example.com")))
+	assert.False(t, f.sameEndpoint(newFs("b", "Ceph", "", "https://minio.example.com")))
+	assert.False(t, newFs("a", "AWS", "eu-west-1", "").sameEndpoint(newFs("b", "AWS", "us-east-1", "")))
+
+	// Check different endpoints and remembered denials don't try the copy
+	src := &Object{fs: newFs("src", "AWS", "us-east-1", "")}
+	_, err := crossCopy(ctx, newFs("dst", "AWS", "eu-west-1", ""), src, "file")
+	assert.Equal(t, fs.ErrorCantCopy, err)
+	dst := newFs("dst", "AWS", "us-east-1", "")
+	dst.crossDenied.Store("src", struct{}{})
+	_, err = crossCopy(ctx, dst, src, "file")
+	assert.Equal(t, fs.ErrorCantCopy, err)
+
+	assert.NotNil(t, fs.FindCrossCopy(dst, src.fs))
+}
+
 func (f *Fs) InternalTest(t *testing.T) {
 	t.Run("Metadata", f.InternalTestMetadata)
 }
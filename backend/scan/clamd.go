@@ -0,0 +1,110 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of the chunks sent to clamd
+const clamdChunkSize = 64 * 1024
+
+// clamd scans files with the INSTREAM command of a clamd daemon
+type clamd struct {
+	network string
+	address string
+}
+
+// newClamd makes a scanner for the clamd daemon at address
+func newClamd(address string) *clamd {
+	network := "tcp"
+	if strings.HasPrefix(address, "/") || strings.HasPrefix(address, "unix:") {
+		network = "unix"
+		address = strings.TrimPrefix(address, "unix:")
+	}
+	return &clamd{
+		network: network,
+		address: address,
+	}
+}
+
+// scan sends in to clamd and reads the verdict
+func (c *clamd) scan(ctx context.Context, name string, in io.Reader) (clean bool, verdict string, err error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// Close the connection if ctx is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	w := bufio.NewWriterSize(conn, clamdChunkSize+4)
+	if _, err = w.WriteString("zINSTREAM\x00"); err != nil {
+		return false, "", err
+	}
+	buf := make([]byte, clamdChunkSize)
+	var size [4]byte
+	for {
+		n, readErr := io.ReadFull(in, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err = w.Write(size[:]); err != nil {
+				return false, "", err
+			}
+			if _, err = w.Write(buf[:n]); err != nil {
+				return false, "", err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		} else if readErr != nil {
+			return false, "", readErr
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err = w.Write(size[:]); err != nil {
+		return false, "", err
+	}
+	if err = w.Flush(); err != nil {
+		return false, "", fmt.Errorf("failed to send to clamd: %w", err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(err == io.EOF && reply != "") {
+		return false, "", fmt.Errorf("failed to read reply from clamd: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply parses a reply like "stream: OK" or
+// "stream: Eicar-Signature FOUND"
+func parseClamdReply(reply string) (clean bool, verdict string, err error) {
+	reply = strings.TrimRight(reply, "\x00\n")
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return true, "OK", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return false, strings.TrimSuffix(result, " FOUND"), nil
+	default:
+		return false, "", fmt.Errorf("clamd: %s", strings.TrimSuffix(result, " ERROR"))
+	}
+}
@@ -0,0 +1,147 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icapChunkSize is the size of the chunks sent to the ICAP server
+const icapChunkSize = 64 * 1024
+
+// infectionHeaders are the ICAP response headers used by common
+// servers to describe what they found
+var infectionHeaders = []string{
+	"X-Infection-Found",
+	"X-Violations-Found",
+	"X-Virus-Id",
+	"X-Blocked-Reason",
+}
+
+// icap scans files with an ICAP RESPMOD request
+type icap struct {
+	url  *url.URL
+	host string // host:port to connect to
+}
+
+// newICAP makes a scanner for the ICAP service at rawURL
+func newICAP(rawURL string) (*icap, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("bad icap_url: %w", err)
+	}
+	if u.Scheme != "icap" {
+		return nil, fmt.Errorf("bad icap_url %q: scheme must be icap", rawURL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "1344")
+	}
+	return &icap{
+		url:  u,
+		host: host,
+	}, nil
+}
+
+// scan sends in to the ICAP server and reads the verdict
+func (c *icap) scan(ctx context.Context, name string, in io.Reader) (clean bool, verdict string, err error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.host)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to connect to ICAP server: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// Close the connection if ctx is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	// The encapsulated HTTP request and response the file is
+	// pretending to be part of
+	reqHdr := "GET /" + (&url.URL{Path: path.Base(name)}).EscapedPath() + " HTTP/1.1\r\nHost: rclone\r\n\r\n"
+	resHdr := "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nTransfer-Encoding: chunked\r\n\r\n"
+	w := bufio.NewWriterSize(conn, icapChunkSize+64)
+	_, _ = fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", c.url.String())
+	_, _ = fmt.Fprintf(w, "Host: %s\r\n", c.url.Host)
+	_, _ = w.WriteString("Allow: 204\r\n")
+	_, _ = w.WriteString("Connection: close\r\n")
+	_, _ = fmt.Fprintf(w, "Encapsulated: req-hdr=0, res-hdr=%d, res-body=%d\r\n\r\n", len(reqHdr), len(reqHdr)+len(resHdr))
+	_, _ = w.WriteString(reqHdr)
+	_, _ = w.WriteString(resHdr)
+	buf := make([]byte, icapChunkSize)
+	for {
+		n, readErr := io.ReadFull(in, buf)
+		if n > 0 {
+			_, _ = fmt.Fprintf(w, "%x\r\n", n)
+			_, _ = w.Write(buf[:n])
+			if _, err = w.WriteString("\r\n"); err != nil {
+				return false, "", fmt.Errorf("failed to send to ICAP server: %w", err)
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		} else if readErr != nil {
+			return false, "", readErr
+		}
+	}
+	_, _ = w.WriteString("0\r\n\r\n")
+	if err = w.Flush(); err != nil {
+		return false, "", fmt.Errorf("failed to send to ICAP server: %w", err)
+	}
+
+	r := textproto.NewReader(bufio.NewReader(conn))
+	status, err := r.ReadLine()
+	if err != nil {
+		return false, "", fmt.Errorf("failed to read reply from ICAP server: %w", err)
+	}
+	header, err := r.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return false, "", fmt.Errorf("failed to read reply from ICAP server: %w", err)
+	}
+	return parseICAPReply(status, header)
+}
+
+// parseICAPReply interprets the status line and headers of an ICAP
+// response
+func parseICAPReply(status string, header textproto.MIMEHeader) (clean bool, verdict string, err error) {
+	parts := strings.SplitN(status, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "ICAP/") {
+		return false, "", fmt.Errorf("bad ICAP response %q", status)
+	}
+	code, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false, "", fmt.Errorf("bad ICAP response %q", status)
+	}
+	for _, name := range infectionHeaders {
+		if value := header.Get(name); value != "" {
+			return false, value, nil
+		}
+	}
+	switch {
+	case code == 204:
+		return true, "no modifications needed", nil
+	case code == 200:
+		return false, "content modified by ICAP server", nil
+	default:
+		return false, "", fmt.Errorf("ICAP server returned %q", status)
+	}
+}
@@ -0,0 +1,595 @@
+// Package scan implements a backend which passes uploads through a
+// virus scanner before storing them on the wrapped remote
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fspath"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "scan",
+		Description: "Scan uploads with a virus scanner",
+		NewFs:       NewFs,
+		MetadataInfo: &fs.MetadataInfo{
+			Help: `Any metadata supported by the underlying remote is read and written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to store the files which pass the scan in.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name: "clamd_address",
+			Help: `Address of the clamd daemon to scan with.
+
+This can be "host:port" for a TCP socket or the path of a unix socket,
+e.g. "/var/run/clamav/clamd.ctl".
+
+Set this or icap_url.`,
+		}, {
+			Name: "icap_url",
+			Help: `URL of the ICAP service to scan with.
+
+For example "icap://127.0.0.1:1344/avscan". The file is sent in a
+RESPMOD request. A 204 response means the file is clean and anything
+else that it was rejected.
+
+Set this or clamd_address.`,
+		}, {
+			Name:     "max_size",
+			Default:  fs.SizeSuffix(-1),
+			Advanced: true,
+			Help: `Largest file to scan.
+
+Files larger than this are rejected without being scanned. This should
+be no more than the limit configured in the scanner, e.g.
+StreamMaxLength for clamd.`,
+		}, {
+			Name:     "timeout",
+			Default:  fs.Duration(5 * time.Minute),
+			Advanced: true,
+			Help:     `Maximum time to wait for the scanner to scan a file.`,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote       string        `config:"remote"`
+	ClamdAddress string        `config:"clamd_address"`
+	ICAPURL      string        `config:"icap_url"`
+	MaxSize      fs.SizeSuffix `config:"max_size"`
+	Timeout      fs.Duration   `config:"timeout"`
+}
+
+// scanner checks the contents of files
+type scanner interface {
+	// scan reads in and returns whether it is clean, along with a
+	// description of the result
+	scan(ctx context.Context, name string, in io.Reader) (clean bool, verdict string, err error)
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	opt      Options
+	features *fs.Features // optional features
+	wrapper  fs.Fs
+	scanner  scanner
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point scan remote at itself - check the value of the remote setting")
+	}
+	f := &Fs{
+		name: name,
+		root: rpath,
+		opt:  opt,
+	}
+	switch {
+	case opt.ClamdAddress != "" && opt.ICAPURL != "":
+		return nil, errors.New("only one of clamd_address and icap_url may be set")
+	case opt.ClamdAddress != "":
+		f.scanner = newClamd(opt.ClamdAddress)
+	case opt.ICAPURL != "":
+		f.scanner, err = newICAP(opt.ICAPURL)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("one of clamd_address or icap_url must be set")
+	}
+	remotePath := fspath.JoinRootPath(opt.Remote, rpath)
+	var baseErr error
+	f.Fs, baseErr = cache.Get(ctx, remotePath)
+	if baseErr != nil && baseErr != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", remotePath, baseErr)
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		SetTier:                 true,
+		GetTier:                 true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, f.Fs).WrapsFs(f, f.Fs)
+	return f, baseErr
+}
+
+// errRejected is returned when the scanner rejects a file
+var errRejected = errors.New("rejected by scanner")
+
+// scanned reads in to a temporary file, scans it and, if it is
+// clean, calls upload with the contents
+func (f *Fs) scanned(ctx context.Context, in io.Reader, src fs.ObjectInfo, upload func(in io.Reader) error) (err error) {
+	tmp, err := os.CreateTemp("", "rclone-scan-")
+	if err != nil {
+		return fmt.Errorf("failed to make temporary file to scan: %w", err)
+	}
+	defer func() {
+		fs.CheckClose(tmp, &err)
+		if removeErr := os.Remove(tmp.Name()); removeErr != nil {
+			fs.Errorf(src, "Failed to remove temporary file: %v", removeErr)
+		}
+	}()
+	if f.opt.MaxSize >= 0 {
+		in = io.LimitReader(in, int64(f.opt.MaxSize)+1)
+	}
+	n, err := io.Copy(tmp, in)
+	if err != nil {
+		return err
+	}
+	if f.opt.MaxSize >= 0 && n > int64(f.opt.MaxSize) {
+		fs.Errorf(src, "Not scanning as larger than max_size %v", f.opt.MaxSize)
+		return fserrors.NoRetryError(fmt.Errorf("%w: larger than max_size", errRejected))
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	scanCtx, cancel := context.WithTimeout(ctx, time.Duration(f.opt.Timeout))
+	defer cancel()
+	clean, verdict, err := f.scanner.scan(scanCtx, src.Remote(), tmp)
+	if err != nil {
+		return fmt.Errorf("failed to scan: %w", err)
+	}
+	if !clean {
+		fs.Errorf(src, "Scan rejected: %s", verdict)
+		return fserrors.NoRetryError(fmt.Errorf("%w: %s", errRejected, verdict))
+	}
+	fs.Infof(src, "Scan passed: %s", verdict)
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return upload(tmp)
+}
+
+// put scans in and uploads it with do if it is clean
+func (f *Fs) put(ctx context.Context, do func(context.Context, io.Reader, fs.ObjectInfo, ...fs.OpenOption) (fs.Object, error), in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	var o fs.Object
+	err := f.scanned(ctx, in, src, func(in io.Reader) (err error) {
+		o, err = do(ctx, in, src, options...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("scan root '%s'", f.root)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// wrapEntries wraps the objects in entries
+func (f *Fs) wrapEntries(entries fs.DirEntries) fs.DirEntries {
+	for i, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			entries[i] = f.newObject(o)
+		}
+	}
+	return entries
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return f.wrapEntries(entries), nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	return f.Fs.Features().ListR(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.wrapEntries(entries))
+	})
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	do := f.Fs.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	return do(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.wrapEntries(entries))
+	})
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.Fs.Mkdir(ctx, dir)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.Fs.Rmdir(ctx, dir)
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, f.Fs.Put, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutStream
+	if do == nil {
+		return nil, errors.New("PutStream not supported")
+	}
+	return f.put(ctx, do, in, src, options...)
+}
+
+// PutUnchecked uploads the object, allowing duplicates
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutUnchecked
+	if do == nil {
+		return nil, errors.New("PutUnchecked not supported")
+	}
+	return f.put(ctx, do, in, src, options...)
+}
+
+// Purge all files in the directory specified
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	return do(ctx, dir)
+}
+
+// Copy src to this remote using server-side copy operations.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	o, err := do(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Move src to this remote using server-side move operations.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	o, err := do(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	return do(ctx, srcFs.Fs, srcRemote, dstRemote)
+}
+
+// MergeDirs merges the contents of all the directories passed
+// in into the first one and rmdirs the other directories.
+func (f *Fs) MergeDirs(ctx context.Context, dirs []fs.Directory) error {
+	do := f.Fs.Features().MergeDirs
+	if do == nil {
+		return errors.New("MergeDirs not supported")
+	}
+	return do(ctx, dirs)
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	if do := f.Fs.Features().ChangeNotify; do != nil {
+		do(ctx, notifyFunc, pollIntervalChan)
+	}
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	do := f.Fs.Features().PublicLink
+	if do == nil {
+		return "", errors.New("PublicLink not supported")
+	}
+	return do(ctx, remote, expire, unlink)
+}
+
+// UserInfo returns info about the connected user
+func (f *Fs) UserInfo(ctx context.Context) (map[string]string, error) {
+	do := f.Fs.Features().UserInfo
+	if do == nil {
+		return nil, fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Disconnect the current user
+func (f *Fs) Disconnect(ctx context.Context) error {
+	do := f.Fs.Features().Disconnect
+	if do == nil {
+		return fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	do := f.Fs.Features().Shutdown
+	if do == nil {
+		return nil
+	}
+	return do(ctx)
+}
+
+// Object describes a wrapped object
+type Object struct {
+	fs.Object
+	f *Fs
+}
+
+// newObject wraps o
+func (f *Fs) newObject(o fs.Object) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// Update in to the object with the modTime given of the given size,
+// scanning the data first
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return o.f.scanned(ctx, in, src, func(in io.Reader) error {
+		return o.Object.Update(ctx, in, src, options...)
+	})
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	do, ok := o.Object.(fs.IDer)
+	if !ok {
+		return ""
+	}
+	return do.ID()
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	do, ok := o.Object.(fs.MimeTyper)
+	if !ok {
+		return ""
+	}
+	return do.MimeType(ctx)
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	do, ok := o.Object.(fs.SetTierer)
+	if !ok {
+		return errors.New("SetTier not supported")
+	}
+	return do.SetTier(tier)
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *Object) GetTier() string {
+	do, ok := o.Object.(fs.GetTierer)
+	if !ok {
+		return ""
+	}
+	return do.GetTier()
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.PutUncheckeder  = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.MergeDirser     = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.UserInfoer      = (*Fs)(nil)
+	_ fs.Disconnecter    = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.FullObject      = (*Object)(nil)
+)
@@ -0,0 +1,259 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// badContents is the string the fake scanners reject
+const badContents = "EVIL"
+
+// serve runs handle for each connection to a new listener until the
+// test finishes, returning the listener's address
+func serve(t *testing.T, handle func(conn net.Conn)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() {
+					_ = conn.Close()
+				}()
+				handle(conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// StartFakeClamd starts a clamd which rejects any stream containing
+// badContents and returns its address
+func StartFakeClamd(t *testing.T) string {
+	return serve(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		cmd, err := r.ReadString(0)
+		if err != nil || cmd != "zINSTREAM\x00" {
+			_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			return
+		}
+		var data bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+				return
+			}
+		}
+		reply := "stream: OK\x00"
+		if strings.Contains(data.String(), badContents) {
+			reply = "stream: Fake-Evil-Signature FOUND\x00"
+		}
+		_, _ = conn.Write([]byte(reply))
+	})
+}
+
+// startFakeICAP starts an ICAP server which rejects any body
+// containing badContents and returns its address
+func startFakeICAP(t *testing.T) string {
+	return serve(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		tp := textproto.NewReader(r)
+		line, err := tp.ReadLine()
+		if err != nil || !strings.HasPrefix(line, "RESPMOD ") {
+			return
+		}
+		if _, err := tp.ReadMIMEHeader(); err != nil {
+			return
+		}
+		// skip the encapsulated request and response headers
+		for i := 0; i < 2; i++ {
+			if _, err := tp.ReadLine(); err != nil {
+				return
+			}
+			if _, err := tp.ReadMIMEHeader(); err != nil {
+				return
+			}
+		}
+		body, err := io.ReadAll(newChunkedReader(r))
+		if err != nil {
+			return
+		}
+		if strings.Contains(string(body), badContents) {
+			_, _ = conn.Write([]byte("ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=Fake.Evil;\r\nEncapsulated: null-body=0\r\n\r\n"))
+			return
+		}
+		_, _ = conn.Write([]byte("ICAP/1.0 204 No Content\r\nEncapsulated: null-body=0\r\n\r\n"))
+	})
+}
+
+// newChunkedReader reads an HTTP chunked body
+func newChunkedReader(r *bufio.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+			var size int64
+			if _, err := fmt.Sscanf(strings.TrimSpace(line), "%x", &size); err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+			if size == 0 {
+				_ = pw.Close()
+				return
+			}
+			if _, err := io.CopyN(pw, r, size); err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+			if _, err := r.Discard(2); err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return pr
+}
+
+// newTestFs returns a scan remote on an empty directory using the
+// scanner configured in params
+func newTestFs(t *testing.T, params string) *Fs {
+	f, _ := fstest.NewWrappingFs(t, "scan", params)
+	return f.(*Fs)
+}
+
+// tryPut uploads contents the scanner may reject, returning the error
+// for the test to check rather than failing on it
+func tryPut(f fs.Fs, remote, contents string) (fs.Object, error) {
+	src := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, nil)
+	return f.Put(context.Background(), strings.NewReader(contents), src)
+}
+
+func testScanner(t *testing.T, params string) {
+	ctx := context.Background()
+	f := newTestFs(t, params)
+
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "good.txt", ModTime: time.Now()}, "hello", true)
+	assert.Equal(t, int64(5), o.Size())
+
+	_, err := tryPut(f, "bad.txt", "hello "+badContents)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errRejected), err)
+	_, err = f.NewObject(ctx, "bad.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// updating with bad contents keeps the old contents
+	src := object.NewStaticObjectInfo("good.txt", time.Now(), 4, true, nil, nil)
+	err = o.Update(ctx, strings.NewReader(badContents), src)
+	require.Error(t, err)
+	o, err = f.NewObject(ctx, "good.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), o.Size())
+
+	// big files are scanned in chunks
+	big := strings.Repeat("x", 200*1024) + badContents
+	_, err = tryPut(f, "big.txt", big)
+	assert.True(t, errors.Is(err, errRejected), err)
+}
+
+func TestClamd(t *testing.T) {
+	testScanner(t, fmt.Sprintf(`clamd_address="%s"`, StartFakeClamd(t)))
+}
+
+func TestICAP(t *testing.T) {
+	testScanner(t, fmt.Sprintf(`icap_url="icap://%s/avscan"`, startFakeICAP(t)))
+}
+
+func TestMaxSize(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t, fmt.Sprintf(`clamd_address="%s",max_size=4B`, StartFakeClamd(t)))
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "small.txt", ModTime: time.Now()}, "1234", true)
+	_, err := tryPut(f, "large.txt", "12345")
+	assert.True(t, errors.Is(err, errRejected), err)
+}
+
+func TestScannerDown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	f := newTestFs(t, fmt.Sprintf(`clamd_address="%s"`, addr))
+	_, err = tryPut(f, "file.txt", "hello")
+	assert.ErrorContains(t, err, "failed to connect to clamd")
+	assert.False(t, errors.Is(err, errRejected))
+}
+
+func TestParseClamdReply(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		clean   bool
+		verdict string
+		err     bool
+	}{
+		{"stream: OK\x00", true, "OK", false},
+		{"stream: Win.Test.EICAR_HDB-1 FOUND\x00", false, "Win.Test.EICAR_HDB-1", false},
+		{"INSTREAM size limit exceeded. ERROR\x00", false, "", true},
+	} {
+		clean, verdict, err := parseClamdReply(test.in)
+		assert.Equal(t, test.clean, clean, test.in)
+		assert.Equal(t, test.verdict, verdict, test.in)
+		assert.Equal(t, test.err, err != nil, test.in)
+	}
+}
+
+func TestParseICAPReply(t *testing.T) {
+	clean, _, err := parseICAPReply("ICAP/1.0 204 No Content", nil)
+	require.NoError(t, err)
+	assert.True(t, clean)
+	clean, verdict, err := parseICAPReply("ICAP/1.0 200 OK", textproto.MIMEHeader{"X-Virus-Id": {"Eicar"}})
+	require.NoError(t, err)
+	assert.False(t, clean)
+	assert.Equal(t, "Eicar", verdict)
+	_, _, err = parseICAPReply("ICAP/1.0 500 Server Error", nil)
+	assert.Error(t, err)
+	_, _, err = parseICAPReply("HTTP/1.1 200 OK", nil)
+	assert.Error(t, err)
+}
+
+func TestBadConfig(t *testing.T) {
+	ctx := context.Background()
+	_, err := fs.NewFs(ctx, `:scan,remote="/tmp":`)
+	assert.ErrorContains(t, err, "must be set")
+	_, err = fs.NewFs(ctx, `:scan,remote="/tmp",clamd_address="x",icap_url="icap://x/":`)
+	assert.ErrorContains(t, err, "only one")
+	_, err = fs.NewFs(ctx, `:scan,remote="/tmp",icap_url="http://x/":`)
+	assert.ErrorContains(t, err, "scheme")
+}
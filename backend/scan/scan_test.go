@@ -0,0 +1,48 @@
+// Test Scan filesystem interface
+package scan_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/backend/scan"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*scan.Object)(nil),
+	})
+}
+
+// TestLocal runs integration tests against a local directory with a
+// fake clamd
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestScan"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*scan.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"UserInfo",
+			"Disconnect",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "scan"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-scan-test")},
+			{Name: name, Key: "clamd_address", Value: scan.StartFakeClamd(t)},
+		},
+		QuickTestOK: true,
+	})
+}
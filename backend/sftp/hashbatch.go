@@ -0,0 +1,203 @@
+//go:build !plan9
+// +build !plan9
+
+package sftp
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// How long to wait for more hash requests before running a batch
+const hashBatchWait = 50 * time.Millisecond
+
+// hashRequest is a request for the hash of a single file
+type hashRequest struct {
+	shellPath string
+	result    chan hashResult
+}
+
+// hashResult is the answer to a hashRequest
+type hashResult struct {
+	hash string
+	err  error
+}
+
+// hashBatcher collects hash requests made at around the same time
+// and runs a single hash command for all of them
+type hashBatcher struct {
+	f        *Fs
+	hashType hash.Type
+	command  string
+	size     int
+	mu       sync.Mutex
+	pending  []hashRequest
+	timer    *time.Timer
+}
+
+// newHashBatcher makes a hashBatcher running command on up to size
+// files at once
+func newHashBatcher(f *Fs, hashType hash.Type, command string, size int) *hashBatcher {
+	return &hashBatcher{
+		f:        f,
+		hashType: hashType,
+		command:  command,
+		size:     size,
+	}
+}
+
+// hash returns the hash of the file at shellPath
+func (b *hashBatcher) hash(ctx context.Context, shellPath string) (string, error) {
+	req := hashRequest{
+		shellPath: shellPath,
+		result:    make(chan hashResult, 1),
+	}
+	b.mu.Lock()
+	b.pending = append(b.pending, req)
+	if len(b.pending) >= b.size {
+		batch := b.take()
+		b.mu.Unlock()
+		go b.run(batch)
+	} else {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(hashBatchWait, b.flush)
+		}
+		b.mu.Unlock()
+	}
+	select {
+	case res := <-req.result:
+		return res.hash, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// take the pending requests - call with mu held
+func (b *hashBatcher) take() []hashRequest {
+	batch := b.pending
+	b.pending = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return batch
+}
+
+// flush runs any pending requests
+func (b *hashBatcher) flush() {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+	if len(batch) > 0 {
+		b.run(batch)
+	}
+}
+
+// run the hash command on the batch and send the results
+//
+// The batch isn't run with the context of any of the requests as it
+// would be cancelled if that one request was.
+func (b *hashBatcher) run(batch []hashRequest) {
+	ctx := context.Background()
+	args := make([]string, 0, len(batch))
+	var quoteErr error
+	for _, req := range batch {
+		arg, err := b.f.quoteOrEscapeShellPath(req.shellPath)
+		if err != nil {
+			quoteErr = err
+			break
+		}
+		args = append(args, arg)
+	}
+	var hashes map[string]string
+	if quoteErr == nil {
+		fs.Debugf(b.f, "Calculating %v hashes of %d files with one command", b.hashType, len(batch))
+		out, err := b.f.run(ctx, b.command+" "+strings.Join(args, " "))
+		if err == nil {
+			hashes = parseHashes(out)
+		} else {
+			fs.Debugf(b.f, "Batched %v hash command failed, hashing files one at a time: %v", b.hashType, err)
+		}
+	}
+	for _, req := range batch {
+		if hashString, ok := hashes[req.shellPath]; ok {
+			req.result <- hashResult{hash: hashString}
+			continue
+		}
+		// Run on its own to find the hash or the error for this file
+		hashString, err := b.f.hashFile(ctx, b.command, req.shellPath)
+		req.result <- hashResult{hash: hashString, err: err}
+	}
+}
+
+// hashFile runs the hash command on a single file
+func (f *Fs) hashFile(ctx context.Context, command string, shellPath string) (string, error) {
+	shellPathArg, err := f.quoteOrEscapeShellPath(shellPath)
+	if err != nil {
+		return "", err
+	}
+	outBytes, err := f.run(ctx, command+" "+shellPathArg)
+	if err != nil {
+		return "", err
+	}
+	return parseHash(outBytes), nil
+}
+
+// parseHashes parses the output of md5sum or sha1sum run on several
+// files into a map of hashes keyed by file path
+//
+// This understands the GNU coreutils format "hash  path", its binary
+// form "hash *path", the BSD "md5 -r" format "hash path" and the
+// escaping used by coreutils for paths containing \ or newline.
+func parseHashes(out []byte) map[string]string {
+	hashes := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimRight(line, "\r")
+		escaped := strings.HasPrefix(line, "\\")
+		if escaped {
+			line = line[1:]
+		}
+		space := strings.IndexByte(line, ' ')
+		if space <= 0 {
+			continue
+		}
+		hashString, name := strings.ToLower(line[:space]), line[space+1:]
+		if strings.HasPrefix(name, " ") || strings.HasPrefix(name, "*") {
+			name = name[1:]
+		}
+		if escaped {
+			name = unescapeHashPath(name)
+		}
+		if name != "" {
+			hashes[name] = hashString
+		}
+	}
+	return hashes
+}
+
+// unescapeHashPath undoes the escaping of \ and newline coreutils
+// applies to paths in the output of md5sum and sha1sum
+func unescapeHashPath(name string) string {
+	var out strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+1 < len(name) {
+			i++
+			switch name[i] {
+			case 'n':
+				out.WriteByte('\n')
+				continue
+			case '\\':
+				out.WriteByte('\\')
+				continue
+			}
+			out.WriteByte('\\')
+		}
+		out.WriteByte(name[i])
+	}
+	return out.String()
+}
@@ -321,6 +321,44 @@ and pass variables with spaces in in quotes, eg
 
     "VAR3=value with space" "VAR4=value with space" VAR5=nospacehere
 
+`,
+			Advanced: true,
+		}, {
+			Name:    "connections",
+			Default: 0,
+			Help: `Maximum number of SSH connections to use.
+
+Normally rclone opens an SSH connection for each transfer and checker
+running at once. If this is set then rclone opens at most this many
+connections and sends the requests of the transfers and checkers
+over them at the same time, as SFTP allows many requests to be in
+flight on one connection.
+
+This is useful for servers which limit the number of connections
+each user may open and saves the time spent setting up connections.
+
+Note that the server may limit the number of sessions on each
+connection (MaxSessions is 10 by default for OpenSSH), and each
+remote command run, for example to calculate a checksum, needs a
+session of its own.
+
+Set to 0 to use a connection for each transfer and checker.
+`,
+			Advanced: true,
+		}, {
+			Name:    "hash_batch_size",
+			Default: 0,
+			Help: `Maximum number of files to checksum with one command.
+
+Normally rclone runs a separate md5sum or sha1sum command on the
+server for each file it needs a checksum of. If this is set then
+checksums asked for at around the same time are calculated with one
+command run on up to this many files, which is much quicker when
+checking directories with lots of files.
+
+This only works with the unix shell type.
+
+Set to 0 to run a command for each file.
 `,
 			Advanced: true,
 		}},
@@ -358,6 +396,8 @@ type Options struct {
 	ChunkSize               fs.SizeSuffix   `config:"chunk_size"`
 	Concurrency             int             `config:"concurrency"`
 	SetEnv                  fs.SpaceSepList `config:"set_env"`
+	Connections             int             `config:"connections"`
+	HashBatchSize           int             `config:"hash_batch_size"`
 }
 
 // Fs stores the interface to the remote SFTP files
@@ -377,10 +417,12 @@ type Fs struct {
 	cachedHashes *hash.Set
 	poolMu       sync.Mutex
 	pool         []*conn
+	poolOpening  int         // number of shared connections being opened
 	drain        *time.Timer // used to drain the pool when we stop using the connections
 	pacer        *fs.Pacer   // pacer for operations
 	savedpswd    string
-	sessions     int32 // count in use sessions
+	sessions     int32                      // count in use sessions
+	hashBatchers map[hash.Type]*hashBatcher // batch up hash commands if set
 }
 
 // Object is a remote SFTP file that has been stat'd (so it exists, but is not necessarily open for reading)
@@ -416,6 +458,7 @@ type conn struct {
 	sshClient  *ssh.Client
 	sftpClient *sftp.Client
 	err        chan error
+	users      int // number of users of a shared connection - protected by Fs.poolMu
 }
 
 // Wait for connection to close
@@ -564,6 +607,9 @@ func (f *Fs) newSftpClient(conn *ssh.Client, opts ...sftp.ClientOption) (*sftp.C
 // Get an SFTP connection from the pool, or open a new one
 func (f *Fs) getSftpConnection(ctx context.Context) (c *conn, err error) {
 	accounting.LimitTPS(ctx)
+	if f.opt.Connections > 0 {
+		return f.getSharedSftpConnection(ctx)
+	}
 	f.poolMu.Lock()
 	for len(f.pool) > 0 {
 		c = f.pool[0]
@@ -589,6 +635,51 @@ func (f *Fs) getSftpConnection(ctx context.Context) (c *conn, err error) {
 	return c, err
 }
 
+// Get an SFTP connection to share with other users
+//
+// The connections stay in the pool while in use and the least used
+// one is returned, unless there are fewer than the maximum number of
+// connections open and all of them are busy in which case a new one
+// is opened.
+func (f *Fs) getSharedSftpConnection(ctx context.Context) (c *conn, err error) {
+	f.poolMu.Lock()
+	pool := f.pool[:0]
+	for _, pc := range f.pool {
+		if err := pc.closed(); err != nil {
+			fs.Errorf(f, "Discarding closed SSH connection: %v", err)
+			continue
+		}
+		pool = append(pool, pc)
+		if c == nil || pc.users < c.users {
+			c = pc
+		}
+	}
+	f.pool = pool
+	if c != nil && (c.users == 0 || len(f.pool)+f.poolOpening >= f.opt.Connections) {
+		c.users++
+		f.poolMu.Unlock()
+		return c, nil
+	}
+	f.poolOpening++
+	f.poolMu.Unlock()
+	err = f.pacer.Call(func() (bool, error) {
+		c, err = f.sftpConnection(ctx)
+		if err != nil {
+			return true, err
+		}
+		return false, nil
+	})
+	f.poolMu.Lock()
+	defer f.poolMu.Unlock()
+	f.poolOpening--
+	if err != nil {
+		return nil, err
+	}
+	c.users++
+	f.pool = append(f.pool, c)
+	return c, nil
+}
+
 // Return an SFTP connection to the pool
 //
 // It nils the pointed to connection out so it can't be reused
@@ -616,6 +707,9 @@ func (f *Fs) putSftpConnection(pc **conn, err error) {
 			_, nopErr := c.sftpClient.Getwd()
 			if nopErr != nil {
 				fs.Debugf(f, "Connection failed, closing: %v", nopErr)
+				if f.opt.Connections > 0 {
+					f.removeSharedSftpConnection(c)
+				}
 				_ = c.close()
 				return
 			}
@@ -623,13 +717,30 @@ func (f *Fs) putSftpConnection(pc **conn, err error) {
 		}
 	}
 	f.poolMu.Lock()
-	f.pool = append(f.pool, c)
+	if f.opt.Connections > 0 {
+		// Shared connections stay in the pool
+		c.users--
+	} else {
+		f.pool = append(f.pool, c)
+	}
 	if f.opt.IdleTimeout > 0 {
 		f.drain.Reset(time.Duration(f.opt.IdleTimeout)) // nudge on the pool emptying timer
 	}
 	f.poolMu.Unlock()
 }
 
+// Remove a failed shared connection from the pool
+func (f *Fs) removeSharedSftpConnection(c *conn) {
+	f.poolMu.Lock()
+	defer f.poolMu.Unlock()
+	for i, pc := range f.pool {
+		if pc == c {
+			f.pool = append(f.pool[:i], f.pool[i+1:]...)
+			return
+		}
+	}
+}
+
 // Drain the pool of any connections
 func (f *Fs) drainPool(ctx context.Context) (err error) {
 	f.poolMu.Lock()
@@ -644,19 +755,25 @@ func (f *Fs) drainPool(ctx context.Context) (err error) {
 	if f.opt.IdleTimeout > 0 {
 		f.drain.Stop()
 	}
-	if len(f.pool) != 0 {
-		fs.Debugf(f, "Closing %d unused connections", len(f.pool))
-	}
+	var inUse []*conn
 	for i, c := range f.pool {
+		f.pool[i] = nil
+		if c.users > 0 {
+			// Shared connection still in use
+			inUse = append(inUse, c)
+			continue
+		}
 		if cErr := c.closed(); cErr == nil {
 			cErr = c.close()
 			if cErr != nil {
 				err = cErr
 			}
 		}
-		f.pool[i] = nil
 	}
-	f.pool = nil
+	if closed := len(f.pool) - len(inUse); closed != 0 {
+		fs.Debugf(f, "Closed %d unused connections", closed)
+	}
+	f.pool = inUse
 	return err
 }
 
@@ -1399,6 +1516,16 @@ func (f *Fs) Hashes() hash.Set {
 		hashSet.Add(hash.MD5)
 	}
 
+	if f.opt.HashBatchSize > 1 && f.shellType == "unix" {
+		f.hashBatchers = make(map[hash.Type]*hashBatcher, 2)
+		if md5Works {
+			f.hashBatchers[hash.MD5] = newHashBatcher(f, hash.MD5, f.opt.Md5sumCommand, f.opt.HashBatchSize)
+		}
+		if sha1Works {
+			f.hashBatchers[hash.SHA1] = newHashBatcher(f, hash.SHA1, f.opt.Sha1sumCommand, f.opt.HashBatchSize)
+		}
+	}
+
 	return hashSet
 }
 
@@ -1551,15 +1678,16 @@ func (o *Object) Hash(ctx context.Context, r hash.Type) (string, error) {
 		return "", hash.ErrUnsupported
 	}
 
-	shellPathArg, err := o.fs.quoteOrEscapeShellPath(o.shellPath())
-	if err != nil {
-		return "", fmt.Errorf("failed to calculate %v hash: %w", r, err)
+	var hashString string
+	var err error
+	if batcher := o.fs.hashBatchers[r]; batcher != nil {
+		hashString, err = batcher.hash(ctx, o.shellPath())
+	} else {
+		hashString, err = o.fs.hashFile(ctx, hashCmd, o.shellPath())
 	}
-	outBytes, err := o.fs.run(ctx, hashCmd+" "+shellPathArg)
 	if err != nil {
 		return "", fmt.Errorf("failed to calculate %v hash: %w", r, err)
 	}
-	hashString := parseHash(outBytes)
 	fs.Debugf(o, "Parsed hash: %s", hashString)
 	if r == hash.MD5 {
 		o.md5sum = &hashString
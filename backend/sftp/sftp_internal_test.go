@@ -87,3 +87,34 @@ func TestParseUsage(t *testing.T) {
 		assert.Equal(t, test.usage, [3]int64{gotSpaceTotal, gotSpaceUsed, gotSpaceAvail}, fmt.Sprintf("Test %d sshOutput = %q", i, test.sshOutput))
 	}
 }
+
+func TestParseHashes(t *testing.T) {
+	out := []byte(`d41d8cd98f00b204e9800998ecf8427e  /home/user/empty
+B1946AC92492D2347C6235B4D2611184 *binary file
+\b1946ac92492d2347c6235b4d2611184  /with\\backslash\nand newline
+5d41402abc4b2a76b9719d911017c592 bsd style
+d41d8cd98f00b204e9800998ecf8427e
+`)
+	assert.Equal(t, map[string]string{
+		"/home/user/empty":              "d41d8cd98f00b204e9800998ecf8427e",
+		"binary file":                   "b1946ac92492d2347c6235b4d2611184",
+		"/with\\backslash\nand newline": "b1946ac92492d2347c6235b4d2611184",
+		"bsd style":                     "5d41402abc4b2a76b9719d911017c592",
+	}, parseHashes(out))
+	assert.Equal(t, map[string]string{}, parseHashes(nil))
+}
+
+func TestUnescapeHashPath(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{`back\\slash`, `back\slash`},
+		{`new\nline`, "new\nline"},
+		{`other\x`, `other\x`},
+		{`trailing\`, `trailing\`},
+	} {
+		assert.Equal(t, test.want, unescapeHashPath(test.in), test.in)
+	}
+}
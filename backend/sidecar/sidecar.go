@@ -0,0 +1,741 @@
+// Package sidecar implements a backend which stores metadata and
+// modification times in sidecar objects next to the data
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "sidecar",
+		Description: "Store metadata in sidecar files for other remotes",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			System: map[string]fs.MetadataHelp{
+				"mtime": {
+					Help:    "Time of last modification, read from the sidecar if present.",
+					Type:    "RFC 3339",
+					Example: "2006-01-02T15:04:05.999999999Z07:00",
+				},
+				"content-type": {
+					Help:    "MIME type, also known as media type",
+					Type:    "string",
+					Example: "text/plain",
+				},
+			},
+			Help: `All metadata is stored in the sidecar so any key may be read and written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to store the data and sidecars on.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name:     "suffix",
+			Default:  ".rclonemeta",
+			Help:     `Suffix added to the name of the file to make the name of its sidecar.`,
+			Advanced: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote string `config:"remote"`
+	Suffix string `config:"suffix"`
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	opt      Options
+	features *fs.Features // optional features
+	wrapper  fs.Fs
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.Suffix == "" {
+		return nil, errors.New("suffix can't be empty")
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point sidecar remote at itself - check the value of the remote setting")
+	}
+	baseFs, err := cache.Get(ctx, fspath.JoinRootPath(opt.Remote, rpath))
+	if err != nil && err != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", opt.Remote, err)
+	}
+	f := &Fs{
+		Fs:   baseFs,
+		name: name,
+		root: rpath,
+		opt:  opt,
+	}
+	if err == fs.ErrorIsFile {
+		f.root = path.Dir(rpath)
+		if f.root == "." || f.root == "/" {
+			f.root = ""
+		}
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+	f.features = (&fs.Features{
+		CaseInsensitive:         baseFs.Features().CaseInsensitive,
+		CanHaveEmptyDirectories: true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		SetTier:                 true,
+		GetTier:                 true,
+		SlowModTime:             true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, baseFs).WrapsFs(f, baseFs)
+	// We can always read and write metadata regardless of the base
+	f.features.ReadMimeType = true
+	f.features.WriteMimeType = true
+	f.features.ReadMetadata = true
+	f.features.WriteMetadata = true
+	f.features.UserMetadata = true
+	return f, err
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("sidecar root '%s'", f.root)
+}
+
+// Precision of the ModTimes in this Fs
+//
+// These are stored in the sidecar to the nanosecond
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// isSidecar returns true if remote is the name of a sidecar
+func (f *Fs) isSidecar(remote string) bool {
+	return strings.HasSuffix(remote, f.opt.Suffix)
+}
+
+// sidecarName returns the name of the sidecar for remote
+func (f *Fs) sidecarName(remote string) string {
+	return remote + f.opt.Suffix
+}
+
+// wrapEntries hides the sidecars and wraps the objects
+func (f *Fs) wrapEntries(entries fs.DirEntries) fs.DirEntries {
+	out := entries[:0]
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case fs.Object:
+			if f.isSidecar(x.Remote()) {
+				continue
+			}
+			out = append(out, f.newObject(x))
+		default:
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+// List the objects and directories in dir into entries.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return f.wrapEntries(entries), nil
+}
+
+// ListR lists the objects and directories recursively into out.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	do := f.Fs.Features().ListR
+	return do(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.wrapEntries(entries))
+	})
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// Sidecar files are removed from each page as it is read.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	do := f.Fs.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	return do(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.wrapEntries(entries))
+	})
+}
+
+// NewObject finds the Object at remote.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if f.isSidecar(remote) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// sourceMetadata works out the metadata to store for src
+func sourceMetadata(ctx context.Context, src fs.ObjectInfo, options []fs.OpenOption) (fs.Metadata, error) {
+	meta, err := fs.GetMetadataOptions(ctx, src, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata from source object: %w", err)
+	}
+	out := fs.Metadata{}
+	out.Merge(meta)
+	if _, ok := out["mtime"]; !ok {
+		out["mtime"] = src.ModTime(ctx).Format(time.RFC3339Nano)
+	}
+	if _, ok := out["content-type"]; !ok {
+		if do, ok := src.(fs.MimeTyper); ok {
+			if mimeType := do.MimeType(ctx); mimeType != "" {
+				out["content-type"] = mimeType
+			}
+		}
+	}
+	return out, nil
+}
+
+// put uploads with the put function given and then writes the sidecar
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, put func(context.Context, io.Reader, fs.ObjectInfo, ...fs.OpenOption) (fs.Object, error), options ...fs.OpenOption) (fs.Object, error) {
+	if f.isSidecar(src.Remote()) {
+		return nil, fmt.Errorf("can't upload files with the reserved suffix %q", f.opt.Suffix)
+	}
+	meta, err := sourceMetadata(ctx, src, options)
+	if err != nil {
+		return nil, err
+	}
+	baseObj, err := put(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	o := f.newObject(baseObj)
+	if err := o.writeSidecar(ctx, meta); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src, f.Fs.Put, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutStream
+	if do == nil {
+		return nil, errors.New("can't PutStream")
+	}
+	return f.put(ctx, in, src, do, options...)
+}
+
+// Purge all files in the directory specified
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	return do(ctx, dir)
+}
+
+// copyOrMove does a server-side copy or move of the data and the
+// sidecar of src using do
+func (f *Fs) copyOrMove(ctx context.Context, src fs.Object, remote string, do func(context.Context, fs.Object, string) (fs.Object, error), cantErr error) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || do == nil {
+		return nil, cantErr
+	}
+	if f.isSidecar(remote) {
+		return nil, cantErr
+	}
+	srcSidecar, err := srcObj.f.Fs.NewObject(ctx, srcObj.f.sidecarName(srcObj.Remote()))
+	if err != nil && err != fs.ErrorObjectNotFound {
+		return nil, err
+	}
+	baseObj, err := do(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	o := f.newObject(baseObj)
+	if srcSidecar != nil {
+		if _, err := do(ctx, srcSidecar, f.sidecarName(remote)); err != nil {
+			return o, fmt.Errorf("failed to transfer sidecar: %w", err)
+		}
+	} else if err := o.removeSidecar(ctx); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+// Copy src to this remote using server-side copy operations.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	return f.copyOrMove(ctx, src, remote, f.Fs.Features().Copy, fs.ErrorCantCopy)
+}
+
+// Move src to this remote using server-side move operations.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	return f.copyOrMove(ctx, src, remote, f.Fs.Features().Move, fs.ErrorCantMove)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	return do(ctx, srcFs.Fs, srcRemote, dstRemote)
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+//
+// Changes to a sidecar are reported as changes to its file.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	do := f.Fs.Features().ChangeNotify
+	if do == nil {
+		return
+	}
+	do(ctx, func(remote string, entryType fs.EntryType) {
+		if entryType == fs.EntryObject && f.isSidecar(remote) {
+			remote = strings.TrimSuffix(remote, f.opt.Suffix)
+		}
+		notifyFunc(remote, entryType)
+	}, pollIntervalChan)
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	do := f.Fs.Features().PublicLink
+	if do == nil {
+		return "", errors.New("PublicLink not supported")
+	}
+	return do(ctx, remote, expire, unlink)
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	do := f.Fs.Features().Shutdown
+	if do == nil {
+		return nil
+	}
+	return do(ctx)
+}
+
+// prune removes sidecars whose file no longer exists
+func (f *Fs) prune(ctx context.Context, dir string) (removed []string, err error) {
+	present := map[string]struct{}{}
+	var sidecars []fs.Object
+	err = operations.ListFn(ctx, f.Fs, func(o fs.Object) {
+		remote := o.Remote()
+		if dir != "" && !strings.HasPrefix(remote, dir+"/") {
+			return
+		}
+		if f.isSidecar(remote) {
+			sidecars = append(sidecars, o)
+		} else {
+			present[remote] = struct{}{}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range sidecars {
+		if _, found := present[strings.TrimSuffix(o.Remote(), f.opt.Suffix)]; found {
+			continue
+		}
+		if operations.SkipDestructive(ctx, o, "remove orphaned sidecar") {
+			continue
+		}
+		if err := o.Remove(ctx); err != nil {
+			return removed, fmt.Errorf("failed to remove orphaned sidecar %q: %w", o.Remote(), err)
+		}
+		removed = append(removed, o.Remote())
+	}
+	return removed, nil
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "prune",
+	Short: "Remove sidecars whose file no longer exists.",
+	Long: `Sidecars are left behind if files are removed from the underlying
+remote without going through the sidecar remote. This removes them.
+
+Usage Examples:
+
+    rclone backend prune sidecar:
+    rclone backend prune sidecar: path/to/dir
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "prune":
+		dir := ""
+		if len(arg) > 0 {
+			dir = strings.Trim(arg[0], "/")
+		}
+		return f.prune(ctx, dir)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// Object represents an object with its metadata in a sidecar
+type Object struct {
+	fs.Object
+	f      *Fs
+	mu     sync.Mutex
+	meta   fs.Metadata // metadata read from the sidecar
+	loaded bool        // set if meta has been read
+}
+
+// newObject wraps o
+func (f *Fs) newObject(o fs.Object) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// String returns a description of the Object
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// readSidecar returns the metadata stored in the sidecar, or nil if
+// there isn't one.
+//
+// Call with o.mu held
+func (o *Object) readSidecar(ctx context.Context) (fs.Metadata, error) {
+	if o.loaded {
+		return o.meta, nil
+	}
+	sidecar, err := o.f.Fs.NewObject(ctx, o.f.sidecarName(o.Remote()))
+	if err == fs.ErrorObjectNotFound {
+		o.loaded = true
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	in, err := sidecar.Open(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sidecar: %w", err)
+	}
+	data, err := ioutil.ReadAll(in)
+	_ = in.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sidecar: %w", err)
+	}
+	var meta fs.Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode sidecar: %w", err)
+	}
+	o.meta = meta
+	o.loaded = true
+	return meta, nil
+}
+
+// metadata returns the sidecar metadata, logging any errors
+func (o *Object) metadata(ctx context.Context) fs.Metadata {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	meta, err := o.readSidecar(ctx)
+	if err != nil {
+		fs.Errorf(o, "Failed to read sidecar: %v", err)
+	}
+	return meta
+}
+
+// writeSidecar replaces the sidecar with meta
+func (o *Object) writeSidecar(ctx context.Context, meta fs.Metadata) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	info := object.NewStaticObjectInfo(o.f.sidecarName(o.Remote()), time.Now(), int64(len(data)), true, nil, o.f.Fs)
+	if _, err := o.f.Fs.Put(ctx, bytes.NewReader(data), info); err != nil {
+		return fmt.Errorf("failed to write sidecar: %w", err)
+	}
+	o.meta = meta
+	o.loaded = true
+	return nil
+}
+
+// removeSidecar removes the sidecar if it exists
+func (o *Object) removeSidecar(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.meta, o.loaded = nil, true
+	sidecar, err := o.f.Fs.NewObject(ctx, o.f.sidecarName(o.Remote()))
+	if err == fs.ErrorObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := sidecar.Remove(ctx); err != nil {
+		return fmt.Errorf("failed to remove sidecar: %w", err)
+	}
+	return nil
+}
+
+// ModTime returns the modification date of the file from the sidecar
+// or from the underlying object if there isn't one
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if mtime, ok := o.metadata(ctx)["mtime"]; ok {
+		t, err := time.Parse(time.RFC3339Nano, mtime)
+		if err == nil {
+			return t
+		}
+		fs.Debugf(o, "Failed to parse mtime from sidecar: %v", err)
+	}
+	return o.Object.ModTime(ctx)
+}
+
+// SetModTime sets the modification time of the file in the sidecar
+func (o *Object) SetModTime(ctx context.Context, t time.Time) error {
+	meta := fs.Metadata{}
+	meta.Merge(o.metadata(ctx))
+	meta["mtime"] = t.Format(time.RFC3339Nano)
+	return o.writeSidecar(ctx, meta)
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	if mimeType, ok := o.metadata(ctx)["content-type"]; ok {
+		return mimeType
+	}
+	if do, ok := o.Object.(fs.MimeTyper); ok {
+		return do.MimeType(ctx)
+	}
+	return ""
+}
+
+// Metadata returns the metadata for the object
+//
+// Metadata from the underlying object is returned too, overridden by
+// anything in the sidecar.
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	meta := fs.Metadata{}
+	if do, ok := o.Object.(fs.Metadataer); ok {
+		baseMeta, err := do.Metadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		meta.Merge(baseMeta)
+	}
+	o.mu.Lock()
+	sidecarMeta, err := o.readSidecar(ctx)
+	o.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	meta.Merge(sidecarMeta)
+	if _, ok := meta["mtime"]; !ok {
+		meta["mtime"] = o.Object.ModTime(ctx).Format(time.RFC3339Nano)
+	}
+	return meta, nil
+}
+
+// Update in to the object with the modTime given of the given size
+//
+// The sidecar is replaced with the metadata from src.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	meta, err := sourceMetadata(ctx, src, options)
+	if err != nil {
+		return err
+	}
+	if err := o.Object.Update(ctx, in, src, options...); err != nil {
+		return err
+	}
+	return o.writeSidecar(ctx, meta)
+}
+
+// Remove an object and its sidecar
+func (o *Object) Remove(ctx context.Context) error {
+	if err := o.removeSidecar(ctx); err != nil {
+		return err
+	}
+	return o.Object.Remove(ctx)
+}
+
+// Hash returns the selected checksum of the file
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	return o.Object.Hash(ctx, ht)
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	if do, ok := o.Object.(fs.IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	if do, ok := o.Object.(fs.SetTierer); ok {
+		return do.SetTier(tier)
+	}
+	return errors.New("SetTier not supported")
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *Object) GetTier() string {
+	if do, ok := o.Object.(fs.GetTierer); ok {
+		return do.GetTier()
+	}
+	return ""
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.FullObject      = (*Object)(nil)
+)
@@ -0,0 +1,85 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (f *Fs) testSidecarContents(t *testing.T) {
+	ctx := context.Background()
+	const fileName = "sidecar-contents.txt"
+	modTime := fstest.Time("2001-02-03T04:05:06.123456789Z")
+	item := fstest.Item{Path: fileName, ModTime: modTime}
+	o := fstests.PutTestContentsMetadata(ctx, t, f, &item, "data", true, "text/plain", fs.Metadata{"potato": "jacket"})
+	defer func() {
+		require.NoError(t, o.Remove(ctx))
+		_, err := f.Fs.NewObject(ctx, f.sidecarName(fileName))
+		assert.Equal(t, fs.ErrorObjectNotFound, err, "sidecar should be removed")
+	}()
+
+	// read the sidecar directly
+	sidecar, err := f.Fs.NewObject(ctx, f.sidecarName(fileName))
+	require.NoError(t, err)
+	in, err := sidecar.Open(ctx)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	var meta fs.Metadata
+	require.NoError(t, json.Unmarshal(data, &meta))
+	assert.Equal(t, "jacket", meta["potato"])
+	assert.Equal(t, "text/plain", meta["content-type"])
+	assert.Equal(t, modTime.Format(time.RFC3339Nano), meta["mtime"])
+
+	// the sidecar is hidden
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotEqual(t, f.sidecarName(fileName), entry.Remote())
+	}
+	_, err = f.NewObject(ctx, f.sidecarName(fileName))
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// setting the modtime rewrites the sidecar but keeps the metadata
+	newTime := fstest.Time("2011-12-13T14:15:16.999999999Z")
+	require.NoError(t, o.SetModTime(ctx, newTime))
+	o2, err := f.NewObject(ctx, fileName)
+	require.NoError(t, err)
+	assert.True(t, newTime.Equal(o2.ModTime(ctx)))
+	meta, err = o2.(*Object).Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "jacket", meta["potato"])
+}
+
+func (f *Fs) testPrune(t *testing.T) {
+	ctx := context.Background()
+	const fileName = "prune/orphan.txt"
+	item := fstest.Item{Path: fileName, ModTime: time.Now()}
+	o := fstests.PutTestContents(ctx, t, f, &item, "data", true)
+	// remove the data behind the wrapper's back
+	require.NoError(t, o.(*Object).Object.Remove(ctx))
+
+	out, err := f.Command(ctx, "prune", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{f.sidecarName(fileName)}, out)
+	_, err = f.Fs.NewObject(ctx, f.sidecarName(fileName))
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	require.NoError(t, f.Rmdir(ctx, "prune"))
+}
+
+// InternalTest dispatches all internal tests
+func (f *Fs) InternalTest(t *testing.T) {
+	t.Run("SidecarContents", f.testSidecarContents)
+	t.Run("Prune", f.testPrune)
+}
+
+var _ fstests.InternalTester = (*Fs)(nil)
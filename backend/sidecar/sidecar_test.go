@@ -0,0 +1,39 @@
+// Test Sidecar filesystem interface
+package sidecar_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/backend/sidecar"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	opt := fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*sidecar.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"MergeDirs",
+			"PutUnchecked",
+			"UserInfo",
+			"Disconnect",
+		},
+		UnimplementableObjectMethods: []string{},
+	}
+	if *fstest.RemoteName == "" {
+		name := "TestSidecar"
+		opt.RemoteName = name + ":"
+		opt.ExtraConfig = []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "sidecar"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-sidecar-test")},
+		}
+		opt.QuickTestOK = true
+	}
+	fstests.Run(t, &opt)
+}
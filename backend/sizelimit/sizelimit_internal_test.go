@@ -0,0 +1,120 @@
+package sizelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/chunker"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+// newTestFs returns a sizelimit remote with a 10 byte limit applied
+// with mode, and the remote it stores its files on so the tests can
+// see how large files were stored
+func newTestFs(t *testing.T, mode string) (f fs.Fs, base fs.Fs) {
+	f, tempRoot := fstest.NewWrappingFs(t, "sizelimit", "max_size=10B,mode="+mode)
+	return f, fstest.NewFs(t, tempRoot)
+}
+
+func TestReject(t *testing.T) {
+	ctx := context.Background()
+	f, base := newTestFs(t, "reject")
+	assert.Nil(t, f.Features().PutStream)
+
+	// small files are stored
+	contents := "0123456789"
+	src := object.NewStaticObjectInfo("small.txt", time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(ctx, strings.NewReader(contents), src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), o.Size())
+
+	// large files are rejected without reading anything
+	in := &countingReader{Reader: strings.NewReader(contents + "X")}
+	src = object.NewStaticObjectInfo("large.txt", time.Now(), 11, true, nil, nil)
+	_, err = f.Put(ctx, in, src)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrorFileTooLarge))
+	assert.True(t, fserrors.IsNoRetryError(err))
+	assert.Equal(t, 0, in.n)
+	_, err = base.NewObject(ctx, "large.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// updates are rejected too
+	in = &countingReader{Reader: strings.NewReader(contents + "X")}
+	src = object.NewStaticObjectInfo("small.txt", time.Now(), 11, true, nil, nil)
+	err = o.Update(ctx, in, src)
+	assert.True(t, errors.Is(err, ErrorFileTooLarge))
+	assert.Equal(t, 0, in.n)
+	assert.Equal(t, int64(10), o.Size())
+}
+
+func TestRejectUnknownSize(t *testing.T) {
+	f, _ := newTestFs(t, "reject")
+	sf := f.(*Fs)
+
+	src := object.NewStaticObjectInfo("stream.txt", time.Now(), -1, true, nil, nil)
+	in, err := sf.limit(strings.NewReader("0123456789"), src)
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+
+	in, err = sf.limit(strings.NewReader("0123456789X"), src)
+	require.NoError(t, err)
+	_, err = io.ReadAll(in)
+	assert.True(t, errors.Is(err, ErrorFileTooLarge))
+}
+
+func TestChunk(t *testing.T) {
+	ctx := context.Background()
+	f, base := newTestFs(t, "chunk")
+
+	contents := bytes.Repeat([]byte("0123456789"), 3)
+	src := object.NewStaticObjectInfo("large.txt", time.Now(), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(ctx, bytes.NewReader(contents), src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(contents)), o.Size())
+
+	// the data is stored in chunks of max_size
+	entries, err := base.List(ctx, "")
+	require.NoError(t, err)
+	chunks := 0
+	for _, entry := range entries {
+		if strings.Contains(entry.Remote(), ".rclone_chunk.") {
+			assert.Equal(t, int64(10), entry.Size(), entry.Remote())
+			chunks++
+		}
+	}
+	assert.Equal(t, 3, chunks)
+
+	// and the file reads back as a whole
+	rc, err := o.Open(ctx)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, contents, data)
+}
@@ -0,0 +1,77 @@
+// Test Sizelimit filesystem interface
+package sizelimit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/chunker"
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/backend/sizelimit"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*sizelimit.Object)(nil),
+	})
+}
+
+// TestLocal runs integration tests against a local directory
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestSizelimit"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*sizelimit.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"UserInfo",
+			"Disconnect",
+			"PutStream",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "sizelimit"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-sizelimit-test")},
+			{Name: name, Key: "max_size", Value: "1G"},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// TestLocalChunk runs integration tests against a local directory
+// with files over max_size chunked
+func TestLocalChunk(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestSizelimitChunk"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*sizelimit.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"MergeDirs",
+			"DirCacheFlush",
+			"PutUnchecked",
+			"UserInfo",
+			"Disconnect",
+			"PublicLink",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "sizelimit"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-sizelimit-chunk-test")},
+			{Name: name, Key: "max_size", Value: "1k"},
+			{Name: name, Key: "mode", Value: "chunk"},
+		},
+		QuickTestOK: true,
+	})
+}
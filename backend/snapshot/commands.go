@@ -0,0 +1,199 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "create",
+	Short: "Take a snapshot of the remote.",
+	Long: `This records the size, modification time and hashes of every file
+in the remote. If no name is given the current UTC time is used.
+
+Usage Example:
+
+    rclone backend create snapshot: [name]
+    rclone backend create -o hashes snapshot: daily-1
+
+Hashes are recorded if they are cheap to read, or always if "-o hashes"
+is given. If "-o copy" is given a copy of every file is kept in the
+snapshot store straight away, so the snapshot can still be read after
+the files are changed by something other than this backend.
+`,
+	Opts: map[string]string{
+		"hashes": "Record hashes even if they are slow to read",
+		"copy":   "Keep a copy of every file now",
+	},
+}, {
+	Name:  "list",
+	Short: "List the snapshots.",
+	Long: `This shows the name, creation time, number of files and total size
+of each snapshot as JSON.
+
+Usage Example:
+
+    rclone backend list snapshot:
+`,
+}, {
+	Name:  "diff",
+	Short: "Show the differences between two snapshots.",
+	Long: `This shows the files which were added ("+"), removed ("-") or
+changed ("*") between snapshot a and snapshot b. If b is left out the
+remote as it is now is used.
+
+Usage Example:
+
+    rclone backend diff snapshot: a [b]
+`,
+}, {
+	Name:  "delete",
+	Short: "Delete snapshots.",
+	Long: `This deletes the named snapshots and any copies of files kept for
+them which no other snapshot needs.
+
+Usage Example:
+
+    rclone backend delete snapshot: name1 [name2...]
+`,
+}}
+
+// snapshotInfo is the output of the list and create commands
+type snapshotInfo struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Root    string    `json:"root"`
+	Files   int       `json:"files"`
+	Size    int64     `json:"size"`
+}
+
+// info returns a summary of m
+func (m *manifest) info() snapshotInfo {
+	si := snapshotInfo{
+		Name:    m.Name,
+		Created: m.Created,
+		Root:    m.Root,
+		Files:   len(m.Files),
+	}
+	for i := range m.Files {
+		si.Size += m.Files[i].Size
+	}
+	return si
+}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "create":
+		snapName := time.Now().UTC().Format("20060102-150405")
+		if len(arg) > 0 {
+			snapName = arg[0]
+		}
+		_, withHashes := opt["hashes"]
+		_, keepCopies := opt["copy"]
+		m, err := f.createSnapshot(ctx, snapName, withHashes, keepCopies)
+		if err != nil {
+			return nil, err
+		}
+		return m.info(), nil
+	case "list":
+		names, err := f.listManifests(ctx)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]snapshotInfo, 0, len(names))
+		for _, snapName := range names {
+			m, err := f.readManifest(ctx, snapName)
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, m.info())
+		}
+		return infos, nil
+	case "diff":
+		if len(arg) < 1 || len(arg) > 2 {
+			return nil, errors.New("need one or two snapshot names")
+		}
+		a, err := f.readManifest(ctx, arg[0])
+		if err != nil {
+			return nil, err
+		}
+		var b *manifest
+		if len(arg) == 2 {
+			b, err = f.readManifest(ctx, arg[1])
+		} else {
+			b, err = f.scan(ctx, "", false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return diff(a, b), nil
+	case "delete":
+		if len(arg) == 0 {
+			return nil, errors.New("need at least one snapshot name")
+		}
+		for _, snapName := range arg {
+			if err := f.deleteSnapshot(ctx, snapName); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// diff returns the files added, removed or changed going from a to b
+// one per line, sorted by path
+func diff(a, b *manifest) []string {
+	before := make(map[string]*manifestFile, len(a.Files))
+	for i := range a.Files {
+		before[a.Files[i].Path] = &a.Files[i]
+	}
+	out := []string{}
+	for i := range b.Files {
+		mf := &b.Files[i]
+		old, found := before[mf.Path]
+		delete(before, mf.Path)
+		if !found {
+			out = append(out, "+ "+mf.Path)
+		} else if changed(old, mf) {
+			out = append(out, "* "+mf.Path)
+		}
+	}
+	for p := range before {
+		out = append(out, "- "+p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i][2:] < out[j][2:]
+	})
+	return out
+}
+
+// changed returns true if a and b are different versions of a file
+//
+// Hashes are compared if both have the same type, otherwise the size
+// and modification time.
+func changed(a, b *manifestFile) bool {
+	if a.Size != b.Size {
+		return true
+	}
+	for name, sum := range a.Hashes {
+		if other, found := b.Hashes[name]; found {
+			return sum != other
+		}
+	}
+	return !a.ModTime.Equal(b.ModTime)
+}
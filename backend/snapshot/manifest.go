@@ -0,0 +1,405 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+const (
+	manifestSuffix = ".json"
+	dataDir        = "data"
+)
+
+// manifest records the state of the remote at the time a snapshot
+// was taken
+type manifest struct {
+	Name    string         `json:"name"`
+	Created time.Time      `json:"created"`
+	Root    string         `json:"root"` // directory the snapshot was taken of
+	Dirs    []string       `json:"dirs,omitempty"`
+	Files   []manifestFile `json:"files"`
+}
+
+// manifestFile is a single file in a manifest
+type manifestFile struct {
+	Path    string            `json:"path"` // relative to the root of the remote
+	Size    int64             `json:"size"`
+	ModTime time.Time         `json:"modtime"`
+	Hashes  map[string]string `json:"hashes,omitempty"`
+}
+
+// key returns the name of the version of the file described by mf
+func (mf *manifestFile) key() string {
+	return versionKey(mf.Path, mf.Size, mf.ModTime)
+}
+
+// versionKey identifies a version of the file at p by its size and
+// modification time
+func versionKey(p string, size int64, modTime time.Time) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%d", p, size, modTime.UnixNano())))
+	return hex.EncodeToString(sum[:16])
+}
+
+// validName matches the names allowed for snapshots
+var validName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]*$`)
+
+// checkName returns an error if name can't be used for a snapshot
+func checkName(name string) error {
+	if !validName.MatchString(name) || name == dataDir {
+		return fmt.Errorf("invalid snapshot name %q: use letters, numbers, '.', '_' and '-' only", name)
+	}
+	return nil
+}
+
+// manifestPath returns the path of the manifest for name
+func (f *Fs) manifestPath(name string) string {
+	return path.Join(f.opt.SnapshotDir, name+manifestSuffix)
+}
+
+// dataPath returns the path of the preserved copy of the version key
+func (f *Fs) dataPath(key string) string {
+	return path.Join(f.opt.SnapshotDir, dataDir, key[:2], key)
+}
+
+// fullPath returns the path of remote relative to the root of the
+// wrapped remote
+func (f *Fs) fullPath(remote string) string {
+	return path.Join(f.prefix, remote)
+}
+
+// isHidden returns true if remote is part of the snapshot store
+func (f *Fs) isHidden(remote string) bool {
+	p := f.fullPath(remote)
+	return p == f.opt.SnapshotDir || strings.HasPrefix(p, f.opt.SnapshotDir+"/")
+}
+
+// readManifest reads the manifest for the snapshot name
+func (f *Fs) readManifest(ctx context.Context, name string) (*manifest, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	o, err := f.base.NewObject(ctx, f.manifestPath(name))
+	if errors.Is(err, fs.ErrorObjectNotFound) {
+		return nil, fmt.Errorf("snapshot %q not found", name)
+	} else if err != nil {
+		return nil, err
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %q: %w", name, err)
+	}
+	data, err := io.ReadAll(in)
+	fs.CheckClose(in, &err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %q: %w", name, err)
+	}
+	m := new(manifest)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %q: %w", name, err)
+	}
+	return m, nil
+}
+
+// writeManifest stores m on the remote
+func (f *Fs) writeManifest(ctx context.Context, m *manifest) error {
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	src := object.NewStaticObjectInfo(f.manifestPath(m.Name), m.Created, int64(len(data)), true, nil, nil)
+	_, err = f.base.Put(ctx, bytes.NewReader(data), src)
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot %q: %w", m.Name, err)
+	}
+	return nil
+}
+
+// listManifests returns the names of the snapshots sorted by name
+func (f *Fs) listManifests(ctx context.Context) (names []string, err error) {
+	entries, err := f.base.List(ctx, f.opt.SnapshotDir)
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if _, ok := entry.(fs.Object); !ok {
+			continue
+		}
+		name := strings.TrimSuffix(path.Base(entry.Remote()), manifestSuffix)
+		if strings.HasSuffix(entry.Remote(), manifestSuffix) && checkName(name) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// loadRefs reads the version keys referenced by all the snapshots
+func (f *Fs) loadRefs(ctx context.Context) (map[string]struct{}, error) {
+	names, err := f.listManifests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	refs := map[string]struct{}{}
+	for _, name := range names {
+		m, err := f.readManifest(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for i := range m.Files {
+			refs[m.Files[i].key()] = struct{}{}
+		}
+	}
+	return refs, nil
+}
+
+// referenced returns true if the version key is in a snapshot
+//
+// The references are read again if they are older than
+// refresh_interval.
+func (f *Fs) referenced(ctx context.Context, key string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.refs == nil || time.Since(f.refsRead) >= time.Duration(f.opt.RefreshInterval) {
+		refs, err := f.loadRefs(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to read snapshots: %w", err)
+		}
+		f.refs = refs
+		f.refsRead = time.Now()
+	}
+	_, found := f.refs[key]
+	return found, nil
+}
+
+// invalidateRefs makes the references be read again before they are
+// next used
+func (f *Fs) invalidateRefs() {
+	f.mu.Lock()
+	f.refs = nil
+	f.mu.Unlock()
+}
+
+// preserve keeps a copy of o, an object on the wrapped remote, if it
+// is part of a snapshot and is about to be changed.
+//
+// If remove is set then o is about to be deleted so it may be moved
+// rather than copied. It returns true if o was moved.
+func (f *Fs) preserve(ctx context.Context, o fs.Object, remove bool) (moved bool, err error) {
+	key := versionKey(f.fullPath(o.Remote()), o.Size(), o.ModTime(ctx))
+	found, err := f.referenced(ctx, key)
+	if err != nil || !found {
+		return false, err
+	}
+	dst := f.dataPath(key)
+	if _, err := f.base.NewObject(ctx, dst); err == nil {
+		// already preserved
+		return false, nil
+	}
+	fs.Debugf(o, "Keeping copy for snapshot")
+	if do := f.base.Features().Move; remove && do != nil {
+		if _, err = do(ctx, o, dst); err == nil {
+			return true, nil
+		}
+		fs.Debugf(o, "Failed to move into snapshot store, copying instead: %v", err)
+	}
+	_, err = operations.Copy(ctx, f.base, nil, dst, o)
+	if err != nil {
+		return false, fmt.Errorf("failed to keep copy for snapshot: %w", err)
+	}
+	return false, nil
+}
+
+// preserveRemote calls preserve on the existing object at remote, if
+// any
+func (f *Fs) preserveRemote(ctx context.Context, remote string) error {
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil
+	}
+	_, err = f.preserve(ctx, o, false)
+	return err
+}
+
+// scan reads the current state of the remote into a manifest called
+// name
+func (f *Fs) scan(ctx context.Context, name string, withHashes bool) (*manifest, error) {
+	m := &manifest{
+		Name:    name,
+		Created: time.Now().UTC(),
+		Root:    f.prefix,
+		Files:   []manifestFile{},
+	}
+	var hashes []hash.Type
+	if withHashes || !f.Fs.Features().SlowHash {
+		hashes = f.Fs.Hashes().Array()
+	}
+	err := walk.ListR(ctx, f.Fs, "", true, -1, walk.ListAll, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			if f.isHidden(entry.Remote()) {
+				continue
+			}
+			switch x := entry.(type) {
+			case fs.Directory:
+				m.Dirs = append(m.Dirs, f.fullPath(x.Remote()))
+			case fs.Object:
+				mf := manifestFile{
+					Path:    f.fullPath(x.Remote()),
+					Size:    x.Size(),
+					ModTime: x.ModTime(ctx),
+				}
+				for _, ht := range hashes {
+					sum, err := x.Hash(ctx, ht)
+					if err != nil {
+						return err
+					}
+					if sum != "" {
+						if mf.Hashes == nil {
+							mf.Hashes = map[string]string{}
+						}
+						mf.Hashes[ht.String()] = sum
+					}
+				}
+				m.Files = append(m.Files, mf)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote: %w", err)
+	}
+	sort.Strings(m.Dirs)
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Path < m.Files[j].Path
+	})
+	return m, nil
+}
+
+// createSnapshot records the current state of the remote as name
+//
+// If keepCopies is set then a copy of every file is kept straight
+// away so the snapshot can be read even if the files are changed
+// without going through this backend.
+func (f *Fs) createSnapshot(ctx context.Context, name string, withHashes, keepCopies bool) (*manifest, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	if _, err := f.base.NewObject(ctx, f.manifestPath(name)); err == nil {
+		return nil, fmt.Errorf("snapshot %q already exists", name)
+	}
+	m, err := f.scan(ctx, name, withHashes)
+	if err != nil {
+		return nil, err
+	}
+	if keepCopies {
+		for i := range m.Files {
+			if err := f.keepCopy(ctx, &m.Files[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := f.writeManifest(ctx, m); err != nil {
+		return nil, err
+	}
+	f.invalidateRefs()
+	return m, nil
+}
+
+// keepCopy copies the file described by mf into the snapshot store
+// unless it is there already
+func (f *Fs) keepCopy(ctx context.Context, mf *manifestFile) error {
+	dst := f.dataPath(mf.key())
+	if _, err := f.base.NewObject(ctx, dst); err == nil {
+		return nil
+	}
+	o, err := f.base.NewObject(ctx, mf.Path)
+	if err != nil {
+		return fmt.Errorf("failed to keep copy of %q: %w", mf.Path, err)
+	}
+	if !f.matches(ctx, o, mf) {
+		return fmt.Errorf("%q changed while the snapshot was being taken", mf.Path)
+	}
+	_, err = operations.Copy(ctx, f.base, nil, dst, o)
+	if err != nil {
+		return fmt.Errorf("failed to keep copy of %q: %w", mf.Path, err)
+	}
+	return nil
+}
+
+// deleteSnapshot removes the snapshot name and any preserved files
+// which no other snapshot needs
+func (f *Fs) deleteSnapshot(ctx context.Context, name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	o, err := f.base.NewObject(ctx, f.manifestPath(name))
+	if err != nil {
+		return fmt.Errorf("snapshot %q not found: %w", name, err)
+	}
+	if err := o.Remove(ctx); err != nil {
+		return err
+	}
+	f.invalidateRefs()
+	return f.removeUnreferenced(ctx)
+}
+
+// removeUnreferenced deletes the preserved files which are in no
+// snapshot
+func (f *Fs) removeUnreferenced(ctx context.Context) error {
+	refs, err := f.loadRefs(ctx)
+	if err != nil {
+		return err
+	}
+	dir := path.Join(f.opt.SnapshotDir, dataDir)
+	err = walk.ListR(ctx, f.base, dir, true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			o, ok := entry.(fs.Object)
+			if !ok {
+				continue
+			}
+			if _, found := refs[path.Base(o.Remote())]; found {
+				continue
+			}
+			fs.Debugf(o, "Removing copy no longer in any snapshot")
+			if err := o.Remove(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		return nil
+	}
+	return err
+}
+
+// matches returns true if o is the version of the file described by
+// mf
+func (f *Fs) matches(ctx context.Context, o fs.Object, mf *manifestFile) bool {
+	if o.Size() != mf.Size {
+		return false
+	}
+	dt := o.ModTime(ctx).Sub(mf.ModTime)
+	if dt < 0 {
+		dt = -dt
+	}
+	return dt < fs.GetModifyWindow(ctx, f.base)
+}
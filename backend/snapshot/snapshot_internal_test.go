@@ -0,0 +1,153 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs returns a snapshot remote on an empty directory which
+// looks for changes on every listing, and the path of that directory
+// so tests can change it behind the backend's back
+func newTestFs(t *testing.T) (f *Fs, root string) {
+	fsys, root := fstest.NewWrappingFs(t, "snapshot", "refresh_interval=0")
+	return fsys.(*Fs), root
+}
+
+// openView opens the snapshot name of the remote in root
+func openView(t *testing.T, root, name string) fs.Fs {
+	return fstest.NewFs(t, fmt.Sprintf(`:snapshot,remote="%s",snapshot="%s":`, root, name))
+}
+
+func read(t *testing.T, f fs.Fs, remote string) (string, error) {
+	ctx := context.Background()
+	o, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return "", err
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data), nil
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	f, root := newTestFs(t)
+	t1 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "keep.txt", ModTime: t1}, "keep", true)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/change.txt", ModTime: t1}, "before", true)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/remove.txt", ModTime: t1}, "remove", true)
+
+	_, err := f.Command(ctx, "create", []string{"one"}, nil)
+	require.NoError(t, err)
+	_, err = f.Command(ctx, "create", []string{"one"}, nil)
+	assert.Error(t, err, "duplicate name")
+
+	// the snapshot store is hidden
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	// change the remote
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/change.txt", ModTime: t2}, "after!", true)
+	o, err := f.NewObject(ctx, "dir/remove.txt")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "new.txt", ModTime: t2}, "new", true)
+
+	// the view shows the remote as it was
+	v := openView(t, root, "one")
+	for remote, want := range map[string]string{
+		"keep.txt":       "keep",
+		"dir/change.txt": "before",
+		"dir/remove.txt": "remove",
+	} {
+		got, err := read(t, v, remote)
+		require.NoError(t, err, remote)
+		assert.Equal(t, want, got, remote)
+	}
+	_, err = v.NewObject(ctx, "new.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	entries, err = v.List(ctx, "dir")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Error(t, v.Mkdir(ctx, "x"))
+
+	// a view of a subdirectory
+	sub := fstest.NewFs(t, fmt.Sprintf(`:snapshot,remote="%s",snapshot="one":dir`, root))
+	got, err := read(t, sub, "change.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "before", got)
+
+	out, err := f.Command(ctx, "diff", []string{"one"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"* dir/change.txt", "- dir/remove.txt", "+ new.txt"}, out)
+
+	_, err = f.Command(ctx, "create", []string{"two"}, nil)
+	require.NoError(t, err)
+	out, err = f.Command(ctx, "list", nil, nil)
+	require.NoError(t, err)
+	infos := out.([]snapshotInfo)
+	require.Equal(t, 2, len(infos))
+	assert.Equal(t, "one", infos[0].Name)
+	assert.Equal(t, 3, infos[0].Files)
+	assert.Equal(t, int64(16), infos[0].Size)
+
+	// changing a file behind the backend's back loses it from the snapshot
+	require.NoError(t, os.WriteFile(root+"/keep.txt", []byte("KEEP!"), 0666))
+	_, err = read(t, openView(t, root, "one"), "keep.txt")
+	assert.ErrorContains(t, err, "has changed since snapshot")
+
+	// deleting a snapshot removes the copies only it needed
+	_, err = f.Command(ctx, "delete", []string{"one"}, nil)
+	require.NoError(t, err)
+	_, err = os.Stat(root + "/" + f.dataPath(versionKey("dir/remove.txt", 6, t1)))
+	assert.True(t, os.IsNotExist(err))
+	_, err = fs.NewFs(ctx, fmt.Sprintf(`:snapshot,remote="%s",snapshot="one":`, root))
+	assert.Error(t, err)
+}
+
+func TestSnapshotKeepCopies(t *testing.T) {
+	ctx := context.Background()
+	f, root := newTestFs(t)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "file.txt", ModTime: time.Now()}, "original", true)
+	_, err := f.Command(ctx, "create", []string{"copied"}, map[string]string{"copy": ""})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(root+"/file.txt", []byte("outside"), 0666))
+	got, err := read(t, openView(t, root, "copied"), "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "original", got)
+}
+
+func TestCheckName(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		ok   bool
+	}{
+		{"daily-1", true},
+		{"20200102-030405", true},
+		{"a.b_c", true},
+		{"", false},
+		{".hidden", false},
+		{"a/b", false},
+		{dataDir, false},
+	} {
+		assert.Equal(t, test.ok, checkName(test.name) == nil, test.name)
+	}
+}
@@ -0,0 +1,49 @@
+// Test Snapshot filesystem interface
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/backend/snapshot"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*snapshot.Object)(nil),
+	})
+}
+
+// TestLocal runs integration tests against the live view of a local
+// directory
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestSnapshot"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*snapshot.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"DirMove",
+			"Purge",
+			"UserInfo",
+			"Disconnect",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "snapshot"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-snapshot-test")},
+		},
+		QuickTestOK: true,
+	})
+}
@@ -0,0 +1,279 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// errReadOnly is returned when trying to change a snapshot
+var errReadOnly = errors.New("snapshots are read only")
+
+// view is a read only Fs showing the remote as it was when a snapshot
+// was taken
+type view struct {
+	live     *Fs
+	m        *manifest
+	name     string
+	root     string
+	prefix   string // path of the root of the view in the remote
+	features *fs.Features
+	files    map[string]*manifestFile // by full path
+	dirs     map[string]fs.DirEntries // entries of each directory by full path
+}
+
+// newView makes a view of the snapshot named in f.opt
+func newView(ctx context.Context, f *Fs, rpath string) (fs.Fs, error) {
+	m, err := f.readManifest(ctx, f.opt.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	v := &view{
+		live:   f,
+		m:      m,
+		name:   f.name,
+		root:   rpath,
+		prefix: strings.Trim(rpath, "/"),
+	}
+	v.index()
+	v.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, v)
+	if _, isFile := v.files[v.prefix]; isFile {
+		v.prefix = parentDir(v.prefix)
+		v.index()
+		return v, fs.ErrorIsFile
+	}
+	return v, nil
+}
+
+// index builds the directory listings of the view from the manifest
+func (v *view) index() {
+	v.files = make(map[string]*manifestFile, len(v.m.Files))
+	v.dirs = map[string]fs.DirEntries{}
+	seen := map[string]struct{}{}
+	var addDir func(full string)
+	addDir = func(full string) {
+		if full == v.prefix {
+			return
+		}
+		if _, found := seen[full]; found {
+			return
+		}
+		seen[full] = struct{}{}
+		parent := parentDir(full)
+		d := fs.NewDir(v.remote(full), v.m.Created)
+		v.dirs[parent] = append(v.dirs[parent], d)
+		addDir(parent)
+	}
+	for _, dir := range v.m.Dirs {
+		if v.inRoot(dir) {
+			addDir(dir)
+		}
+	}
+	for i := range v.m.Files {
+		mf := &v.m.Files[i]
+		v.files[mf.Path] = mf
+		if !v.inRoot(mf.Path) {
+			continue
+		}
+		parent := parentDir(mf.Path)
+		v.dirs[parent] = append(v.dirs[parent], v.newObject(mf))
+		addDir(parent)
+	}
+}
+
+// inRoot returns true if the full path p is below the root of the view
+func (v *view) inRoot(p string) bool {
+	return v.prefix == "" || strings.HasPrefix(p, v.prefix+"/")
+}
+
+// remote returns the full path p relative to the root of the view
+func (v *view) remote(p string) string {
+	if v.prefix == "" {
+		return p
+	}
+	return strings.TrimPrefix(p, v.prefix+"/")
+}
+
+// fullPath returns remote relative to the root of the remote
+func (v *view) fullPath(remote string) string {
+	return strings.Trim(path.Join(v.prefix, remote), "/")
+}
+
+// Name of the remote (as passed into NewFs)
+func (v *view) Name() string {
+	return v.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (v *view) Root() string {
+	return v.root
+}
+
+// String returns a description of the FS
+func (v *view) String() string {
+	return fmt.Sprintf("snapshot %q root '%s'", v.m.Name, v.root)
+}
+
+// Features returns the optional features of this Fs
+func (v *view) Features() *fs.Features {
+	return v.features
+}
+
+// Precision of the ModTimes in this Fs
+func (v *view) Precision() time.Duration {
+	return v.live.base.Precision()
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (v *view) Hashes() hash.Set {
+	return v.live.base.Hashes()
+}
+
+// List the objects and directories in dir into entries.
+func (v *view) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	full := v.fullPath(dir)
+	entries, found := v.dirs[full]
+	if !found {
+		if full != v.prefix {
+			return nil, fs.ErrorDirNotFound
+		}
+		return nil, nil
+	}
+	return append(fs.DirEntries(nil), entries...), nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (v *view) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	mf, found := v.files[v.fullPath(remote)]
+	if !found || !v.inRoot(mf.Path) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return v.newObject(mf), nil
+}
+
+// Put is not supported in a snapshot
+func (v *view) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, errReadOnly
+}
+
+// Mkdir is not supported in a snapshot
+func (v *view) Mkdir(ctx context.Context, dir string) error {
+	return errReadOnly
+}
+
+// Rmdir is not supported in a snapshot
+func (v *view) Rmdir(ctx context.Context, dir string) error {
+	return errReadOnly
+}
+
+// Command the backend to run a named command
+//
+// The commands are run on the live remote.
+func (v *view) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	return v.live.Command(ctx, name, arg, opt)
+}
+
+// viewObject is a file as it was when the snapshot was taken
+type viewObject struct {
+	v  *view
+	mf *manifestFile
+}
+
+// newObject makes an object for mf
+func (v *view) newObject(mf *manifestFile) *viewObject {
+	return &viewObject{
+		v:  v,
+		mf: mf,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *viewObject) Fs() fs.Info {
+	return o.v
+}
+
+// Return a string version
+func (o *viewObject) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Remote()
+}
+
+// Remote returns the remote path
+func (o *viewObject) Remote() string {
+	return o.v.remote(o.mf.Path)
+}
+
+// Size returns the size of the file
+func (o *viewObject) Size() int64 {
+	return o.mf.Size
+}
+
+// ModTime returns the modification time of the file
+func (o *viewObject) ModTime(ctx context.Context) time.Time {
+	return o.mf.ModTime
+}
+
+// Storable returns whether this object is storable
+func (o *viewObject) Storable() bool {
+	return true
+}
+
+// Hash returns the hash recorded when the snapshot was taken or ""
+// if there wasn't one
+func (o *viewObject) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if !o.v.Hashes().Contains(ht) {
+		return "", hash.ErrUnsupported
+	}
+	return o.mf.Hashes[ht.String()], nil
+}
+
+// Open an object for read
+//
+// This reads the copy kept in the snapshot store if there is one,
+// otherwise the live file provided it hasn't changed.
+func (o *viewObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	f := o.v.live
+	kept, err := f.base.NewObject(ctx, f.dataPath(o.mf.key()))
+	if err == nil {
+		return kept.Open(ctx, options...)
+	}
+	current, err := f.base.NewObject(ctx, o.mf.Path)
+	if err == nil && f.matches(ctx, current, o.mf) {
+		return current.Open(ctx, options...)
+	}
+	return nil, fmt.Errorf("%q has changed since snapshot %q was taken and no copy was kept", o.mf.Path, o.v.m.Name)
+}
+
+// SetModTime is not supported in a snapshot
+func (o *viewObject) SetModTime(ctx context.Context, t time.Time) error {
+	return errReadOnly
+}
+
+// Update is not supported in a snapshot
+func (o *viewObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errReadOnly
+}
+
+// Remove is not supported in a snapshot
+func (o *viewObject) Remove(ctx context.Context) error {
+	return errReadOnly
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs        = (*view)(nil)
+	_ fs.Commander = (*view)(nil)
+	_ fs.Object    = (*viewObject)(nil)
+)
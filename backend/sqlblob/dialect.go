@@ -0,0 +1,175 @@
+package sqlblob
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// execer is the part of *sql.DB and *sql.Tx used to change the database
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// dialect describes the differences between the supported databases
+type dialect struct {
+	driver      string   // name of the database/sql driver
+	defaultPort int      // port used if none is set
+	schema      []string // statements to create the tables
+	insertDir   string   // statement to add a directory if it doesn't exist
+	// numbered is set if placeholders are $1, $2, ... rather than ?
+	numbered bool
+	// returning is set if the id of new rows is read with RETURNING
+	returning bool
+	// concat returns SQL joining the strings a and b
+	concat func(a, b string) string
+	// dsn makes the data source name to connect with
+	dsn func(opt *Options, pass string) (string, error)
+}
+
+var dialects = map[string]*dialect{
+	"postgres": {
+		driver:      "postgres",
+		defaultPort: 5432,
+		schema: []string{
+			`CREATE TABLE IF NOT EXISTS {dirs} (
+	parent TEXT COLLATE "C" NOT NULL,
+	name TEXT COLLATE "C" NOT NULL,
+	modtime BIGINT NOT NULL,
+	PRIMARY KEY (parent, name)
+)`,
+			`CREATE TABLE IF NOT EXISTS {objects} (
+	id BIGSERIAL PRIMARY KEY,
+	dir TEXT COLLATE "C" NOT NULL,
+	name TEXT COLLATE "C" NOT NULL,
+	size BIGINT NOT NULL,
+	modtime BIGINT NOT NULL,
+	md5 TEXT NOT NULL,
+	sha1 TEXT NOT NULL,
+	chunk_size BIGINT NOT NULL,
+	UNIQUE (dir, name)
+)`,
+			`CREATE TABLE IF NOT EXISTS {chunks} (
+	object_id BIGINT NOT NULL REFERENCES {objects} (id) ON DELETE CASCADE,
+	n BIGINT NOT NULL,
+	data BYTEA NOT NULL,
+	PRIMARY KEY (object_id, n)
+)`,
+		},
+		insertDir: `INSERT INTO {dirs} (parent, name, modtime) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`,
+		numbered:  true,
+		returning: true,
+		concat: func(a, b string) string {
+			return "(" + a + " || " + b + ")"
+		},
+		dsn: func(opt *Options, pass string) (string, error) {
+			u := url.URL{
+				Scheme:   "postgres",
+				Host:     net.JoinHostPort(opt.Host, strconv.Itoa(opt.Port)),
+				Path:     "/" + opt.Database,
+				RawQuery: opt.Params,
+			}
+			if opt.User != "" {
+				u.User = url.UserPassword(opt.User, pass)
+			}
+			return u.String(), nil
+		},
+	},
+	"mysql": {
+		driver:      "mysql",
+		defaultPort: 3306,
+		schema: []string{
+			`CREATE TABLE IF NOT EXISTS {dirs} (
+	parent VARCHAR(512) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+	name VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+	modtime BIGINT NOT NULL,
+	PRIMARY KEY (parent, name)
+) ENGINE=InnoDB`,
+			`CREATE TABLE IF NOT EXISTS {objects} (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	dir VARCHAR(512) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+	name VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+	size BIGINT NOT NULL,
+	modtime BIGINT NOT NULL,
+	md5 CHAR(32) NOT NULL,
+	sha1 CHAR(40) NOT NULL,
+	chunk_size BIGINT NOT NULL,
+	UNIQUE KEY (dir, name)
+) ENGINE=InnoDB`,
+			`CREATE TABLE IF NOT EXISTS {chunks} (
+	object_id BIGINT NOT NULL,
+	n BIGINT NOT NULL,
+	data LONGBLOB NOT NULL,
+	PRIMARY KEY (object_id, n),
+	FOREIGN KEY (object_id) REFERENCES {objects} (id) ON DELETE CASCADE
+) ENGINE=InnoDB`,
+		},
+		insertDir: `INSERT IGNORE INTO {dirs} (parent, name, modtime) VALUES (?, ?, ?)`,
+		concat: func(a, b string) string {
+			return "CONCAT(" + a + ", " + b + ")"
+		},
+		dsn: func(opt *Options, pass string) (string, error) {
+			params, err := url.ParseQuery(opt.Params)
+			if err != nil {
+				return "", fmt.Errorf("failed to parse params: %w", err)
+			}
+			cfg := mysql.NewConfig()
+			cfg.User = opt.User
+			cfg.Passwd = pass
+			cfg.Net = "tcp"
+			cfg.Addr = net.JoinHostPort(opt.Host, strconv.Itoa(opt.Port))
+			cfg.DBName = opt.Database
+			for k := range params {
+				if cfg.Params == nil {
+					cfg.Params = map[string]string{}
+				}
+				cfg.Params[k] = params.Get(k)
+			}
+			return cfg.FormatDSN(), nil
+		},
+	},
+}
+
+// expand puts the table names into query and changes the ?
+// placeholders into the ones the database uses
+func (d *dialect) expand(query, table string) string {
+	query = strings.NewReplacer(
+		"{dirs}", table+"_dirs",
+		"{objects}", table+"_objects",
+		"{chunks}", table+"_chunks",
+	).Replace(query)
+	if !d.numbered {
+		return query
+	}
+	var out strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			out.WriteString("$" + strconv.Itoa(n))
+		} else {
+			out.WriteRune(c)
+		}
+	}
+	return out.String()
+}
+
+// insertObject adds a row to the objects table returning its id
+func (d *dialect) insertObject(ctx context.Context, tx execer, query string, args ...interface{}) (id int64, err error) {
+	if d.returning {
+		err = tx.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
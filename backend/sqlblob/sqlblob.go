@@ -0,0 +1,911 @@
+// Package sqlblob provides an interface to files stored in the
+// tables of a SQL database
+//
+// Each file is a row in the objects table with its data split into
+// rows of the chunks table, and directories are rows of the dirs
+// table. Changes are made in transactions so the metadata and the
+// data of a file always match.
+package sqlblob
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	_ "github.com/lib/pq" // postgres driver
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/readers"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "sqlblob",
+		Description: "SQL database (Postgres or MySQL)",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name:    "provider",
+			Help:    "Type of database.",
+			Default: "postgres",
+			Examples: []fs.OptionExample{{
+				Value: "postgres",
+				Help:  "PostgreSQL or compatible, e.g. CockroachDB",
+			}, {
+				Value: "mysql",
+				Help:  "MySQL or MariaDB",
+			}},
+			Exclusive: true,
+		}, {
+			Name:     "host",
+			Help:     "Host name of the database server.",
+			Required: true,
+		}, {
+			Name: "port",
+			Help: `Port of the database server.
+
+Leave as 0 for the default port of the database, 5432 for postgres and
+3306 for mysql.`,
+			Default: 0,
+		}, {
+			Name: "user",
+			Help: "User name.",
+		}, {
+			Name:       "pass",
+			Help:       "Password.",
+			IsPassword: true,
+		}, {
+			Name:     "database",
+			Help:     "Name of the database.",
+			Required: true,
+		}, {
+			Name: "table",
+			Help: `Prefix of the names of the tables.
+
+The files are kept in the tables PREFIX_dirs, PREFIX_objects and
+PREFIX_chunks which are made if they don't exist. Use a different
+prefix to keep several remotes in the same database.`,
+			Default: "rclone",
+		}, {
+			Name: "params",
+			Help: `Extra parameters for the connection.
+
+These are given as a URL query string which is passed to the database
+driver, e.g. "sslmode=disable" for postgres or "tls=true" for mysql.`,
+			Advanced: true,
+		}, {
+			Name: "chunk_size",
+			Help: `Size of the chunks files are stored in.
+
+Each chunk is a row of the chunks table so it needs to fit in the
+largest statement the server accepts, e.g. max_allowed_packet for
+mysql. Changing this only affects files uploaded afterwards.`,
+			Default:  fs.SizeSuffix(1 * fs.Mebi),
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// Names are stored as text so must be valid UTF-8
+			Default: (encoder.Base |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Provider  string               `config:"provider"`
+	Host      string               `config:"host"`
+	Port      int                  `config:"port"`
+	User      string               `config:"user"`
+	Pass      string               `config:"pass"`
+	Database  string               `config:"database"`
+	Table     string               `config:"table"`
+	Params    string               `config:"params"`
+	ChunkSize fs.SizeSuffix        `config:"chunk_size"`
+	Enc       encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a remote SQL database
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	d        *dialect     // the type of database
+	db       *sql.DB      // the connection pool
+}
+
+// Object describes a file stored in the database
+type Object struct {
+	fs        *Fs       // what this object is part of
+	remote    string    // The remote path
+	id        int64     // id of the row in the objects table
+	size      int64     // size of the object
+	modTime   time.Time // modification time of the object
+	md5       string    // MD5 of the object
+	sha1      string    // SHA1 of the object
+	chunkSize int64     // size of the chunks the data is stored in
+}
+
+// tableRe matches the allowed table prefixes
+var tableRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewFs constructs an Fs from the path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	d := dialects[opt.Provider]
+	if d == nil {
+		return nil, fmt.Errorf("unknown provider %q", opt.Provider)
+	}
+	if !tableRe.MatchString(opt.Table) {
+		return nil, fmt.Errorf("table prefix %q must only contain letters, numbers and _", opt.Table)
+	}
+	if opt.ChunkSize <= 0 {
+		return nil, errors.New("chunk_size must be greater than 0")
+	}
+	if opt.Port == 0 {
+		opt.Port = d.defaultPort
+	}
+	pass := ""
+	if opt.Pass != "" {
+		pass, err = obscure.Reveal(opt.Pass)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decrypt password: %w", err)
+		}
+	}
+	dsn, err := d.dsn(opt, pass)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxIdleConns(fs.GetConfig(ctx).Checkers + fs.GetConfig(ctx).Transfers)
+	f := &Fs{
+		name: name,
+		root: strings.Trim(path.Clean("/"+root), "/"),
+		opt:  *opt,
+		d:    d,
+		db:   db,
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+	for _, query := range d.schema {
+		_, err = db.ExecContext(ctx, f.q(query))
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("failed to make tables: %w", err)
+		}
+	}
+
+	// Check to see if the root is a file
+	if f.root != "" {
+		_, err := f.NewObject(ctx, "")
+		if err == nil {
+			f.root = path.Dir(f.root)
+			if f.root == "." {
+				f.root = ""
+			}
+			return f, fs.ErrorIsFile
+		} else if err != fs.ErrorObjectNotFound {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("%s database %s at %s", f.opt.Provider, f.opt.Database, f.opt.Host)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.MD5 | hash.SHA1)
+}
+
+// q prepares query for the database
+func (f *Fs) q(query string) string {
+	return f.d.expand(query, f.opt.Table)
+}
+
+// fullPath returns the path in the database of remote
+func (f *Fs) fullPath(remote string) string {
+	return f.opt.Enc.FromStandardPath(path.Join(f.root, remote))
+}
+
+// split returns the directory and the name of the full path p
+func split(p string) (dir, name string) {
+	dir, name = path.Split(p)
+	return strings.TrimSuffix(dir, "/"), name
+}
+
+// below returns the bounds of the paths inside dir, so a column
+// holding a path in dir or below is `col = dir OR (col >= lo AND col
+// < hi)`
+//
+// This relies on the columns sorting by bytes, which is why they are
+// made with a binary collation.
+func below(dir string) (lo, hi string) {
+	return dir + "/", dir + "0" // '0' is the byte after '/'
+}
+
+// dirExists returns whether the directory at the full path dir exists
+func (f *Fs) dirExists(ctx context.Context, tx execer, dir string) (bool, error) {
+	if dir == "" {
+		return true, nil
+	}
+	parent, name := split(dir)
+	var one int
+	err := tx.QueryRowContext(ctx, f.q(`SELECT 1 FROM {dirs} WHERE parent = ? AND name = ?`), parent, name).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// mkdirAll makes the directory at the full path dir and its parents
+func (f *Fs) mkdirAll(ctx context.Context, tx execer, dir string) error {
+	now := time.Now().UnixNano()
+	for dir != "" {
+		parent, name := split(dir)
+		_, err := tx.ExecContext(ctx, f.q(f.d.insertDir), parent, name, now)
+		if err != nil {
+			return fmt.Errorf("failed to make directory %q: %w", dir, err)
+		}
+		dir = parent
+	}
+	return nil
+}
+
+// inTx calls fn in a transaction, committing it if fn succeeds
+func (f *Fs) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := f.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	err = fn(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// objectColumns are the columns read into an Object by scan
+const objectColumns = `id, size, modtime, md5, sha1, chunk_size`
+
+// scan reads the columns in objectColumns into o
+func (o *Object) scan(row interface{ Scan(...interface{}) error }) error {
+	var modTime int64
+	err := row.Scan(&o.id, &o.size, &modTime, &o.md5, &o.sha1, &o.chunkSize)
+	if err != nil {
+		return err
+	}
+	o.modTime = time.Unix(0, modTime)
+	return nil
+}
+
+// List the objects and directories in dir into entries. The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	full := f.fullPath(dir)
+	ok, err := f.dirExists(ctx, f.db, full)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fs.ErrorDirNotFound
+	}
+	rows, err := f.db.QueryContext(ctx, f.q(`SELECT name, modtime FROM {dirs} WHERE parent = ? ORDER BY name`), full)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directories: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		var modTime int64
+		err = rows.Scan(&name, &modTime)
+		if err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		remote := path.Join(dir, f.opt.Enc.ToStandardName(name))
+		entries = append(entries, fs.NewDir(remote, time.Unix(0, modTime)))
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	rows, err = f.db.QueryContext(ctx, f.q(`SELECT name, `+objectColumns+` FROM {objects} WHERE dir = ? ORDER BY name`), full)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	defer fs.CheckClose(rows, &err)
+	for rows.Next() {
+		var name string
+		var modTime int64
+		o := &Object{fs: f}
+		err = rows.Scan(&name, &o.id, &o.size, &modTime, &o.md5, &o.sha1, &o.chunkSize)
+		if err != nil {
+			return nil, err
+		}
+		o.modTime = time.Unix(0, modTime)
+		o.remote = path.Join(dir, f.opt.Enc.ToStandardName(name))
+		entries = append(entries, o)
+	}
+	return entries, rows.Err()
+}
+
+// NewObject finds the Object at remote. If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	return f.newObject(ctx, f.db, remote)
+}
+
+// newObject reads the Object at remote using tx
+func (f *Fs) newObject(ctx context.Context, tx execer, remote string) (*Object, error) {
+	dir, name := split(f.fullPath(remote))
+	o := &Object{fs: f, remote: remote}
+	err := o.scan(tx.QueryRowContext(ctx, f.q(`SELECT `+objectColumns+` FROM {objects} WHERE dir = ? AND name = ?`), dir, name))
+	if err == sql.ErrNoRows {
+		return nil, fs.ErrorObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return o, nil
+}
+
+// Put the object
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.mkdirAll(ctx, f.db, f.fullPath(dir))
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	full := f.fullPath(dir)
+	return f.inTx(ctx, func(tx *sql.Tx) error {
+		ok, err := f.dirExists(ctx, tx, full)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fs.ErrorDirNotFound
+		}
+		var one int
+		err = tx.QueryRowContext(ctx, f.q(`SELECT 1 FROM {objects} WHERE dir = ? LIMIT 1`), full).Scan(&one)
+		if err == nil {
+			return fs.ErrorDirectoryNotEmpty
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+		err = tx.QueryRowContext(ctx, f.q(`SELECT 1 FROM {dirs} WHERE parent = ? LIMIT 1`), full).Scan(&one)
+		if err == nil {
+			return fs.ErrorDirectoryNotEmpty
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+		if full == "" {
+			return nil
+		}
+		parent, name := split(full)
+		_, err = tx.ExecContext(ctx, f.q(`DELETE FROM {dirs} WHERE parent = ? AND name = ?`), parent, name)
+		return err
+	})
+}
+
+// Purge deletes all the files and directories including the old versions.
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	full := f.fullPath(dir)
+	return f.inTx(ctx, func(tx *sql.Tx) error {
+		ok, err := f.dirExists(ctx, tx, full)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fs.ErrorDirNotFound
+		}
+		if full == "" {
+			for _, query := range []string{`DELETE FROM {objects}`, `DELETE FROM {dirs}`} {
+				_, err = tx.ExecContext(ctx, f.q(query))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		// The chunks are deleted with their objects
+		lo, hi := below(full)
+		_, err = tx.ExecContext(ctx, f.q(`DELETE FROM {objects} WHERE dir = ? OR (dir >= ? AND dir < ?)`), full, lo, hi)
+		if err != nil {
+			return fmt.Errorf("failed to delete objects: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, f.q(`DELETE FROM {dirs} WHERE parent = ? OR (parent >= ? AND parent < ?)`), full, lo, hi)
+		if err != nil {
+			return fmt.Errorf("failed to delete directories: %w", err)
+		}
+		parent, name := split(full)
+		_, err = tx.ExecContext(ctx, f.q(`DELETE FROM {dirs} WHERE parent = ? AND name = ?`), parent, name)
+		return err
+	})
+}
+
+// sameDatabase returns whether src uses the same tables as f
+func (f *Fs) sameDatabase(src *Fs) bool {
+	return f.opt.Provider == src.opt.Provider &&
+		f.opt.Host == src.opt.Host &&
+		f.opt.Port == src.opt.Port &&
+		f.opt.Database == src.opt.Database &&
+		f.opt.Table == src.opt.Table
+}
+
+// removeExisting deletes the object at the full path dir/name unless
+// its id is keep
+func (f *Fs) removeExisting(ctx context.Context, tx execer, dir, name string, keep int64) error {
+	_, err := tx.ExecContext(ctx, f.q(`DELETE FROM {objects} WHERE dir = ? AND name = ? AND id <> ?`), dir, name, keep)
+	if err != nil {
+		return fmt.Errorf("failed to remove existing object: %w", err)
+	}
+	return nil
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || !f.sameDatabase(srcObj.fs) {
+		fs.Debugf(src, "Can't copy - not same database")
+		return nil, fs.ErrorCantCopy
+	}
+	dir, name := split(f.fullPath(remote))
+	dstObj := &Object{
+		fs:        f,
+		remote:    remote,
+		size:      srcObj.size,
+		modTime:   srcObj.modTime,
+		md5:       srcObj.md5,
+		sha1:      srcObj.sha1,
+		chunkSize: srcObj.chunkSize,
+	}
+	err := f.inTx(ctx, func(tx *sql.Tx) (err error) {
+		err = f.mkdirAll(ctx, tx, dir)
+		if err != nil {
+			return err
+		}
+		err = f.removeExisting(ctx, tx, dir, name, srcObj.id)
+		if err != nil {
+			return err
+		}
+		dstObj.id, err = f.d.insertObject(ctx, tx,
+			f.q(`INSERT INTO {objects} (dir, name, size, modtime, md5, sha1, chunk_size) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			dir, name, dstObj.size, dstObj.modTime.UnixNano(), dstObj.md5, dstObj.sha1, dstObj.chunkSize)
+		if err != nil {
+			return fmt.Errorf("failed to copy object: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, f.q(`INSERT INTO {chunks} (object_id, n, data) SELECT ?, n, data FROM {chunks} WHERE object_id = ?`), dstObj.id, srcObj.id)
+		if err != nil {
+			return fmt.Errorf("failed to copy data: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dstObj, nil
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || !f.sameDatabase(srcObj.fs) {
+		fs.Debugf(src, "Can't move - not same database")
+		return nil, fs.ErrorCantMove
+	}
+	dir, name := split(f.fullPath(remote))
+	err := f.inTx(ctx, func(tx *sql.Tx) error {
+		err := f.mkdirAll(ctx, tx, dir)
+		if err != nil {
+			return err
+		}
+		err = f.removeExisting(ctx, tx, dir, name, srcObj.id)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, f.q(`UPDATE {objects} SET dir = ?, name = ? WHERE id = ?`), dir, name, srcObj.id)
+		if err != nil {
+			return fmt.Errorf("failed to move object: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err == nil && n == 0 {
+			return fs.ErrorObjectNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	dstObj := *srcObj
+	dstObj.fs = f
+	dstObj.remote = remote
+	return &dstObj, nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok || !f.sameDatabase(srcFs) {
+		fs.Debugf(srcFs, "Can't move directory - not same database")
+		return fs.ErrorCantDirMove
+	}
+	srcPath := srcFs.fullPath(srcRemote)
+	dstPath := f.fullPath(dstRemote)
+	return f.inTx(ctx, func(tx *sql.Tx) error {
+		ok, err := f.dirExists(ctx, tx, dstPath)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return fs.ErrorDirExists
+		}
+		// The root can't be moved, nor can a directory be moved
+		// inside itself
+		if srcPath == "" || strings.HasPrefix(dstPath+"/", srcPath+"/") {
+			return fs.ErrorCantDirMove
+		}
+		ok, err = f.dirExists(ctx, tx, srcPath)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fs.ErrorDirNotFound
+		}
+		dstParent, dstName := split(dstPath)
+		err = f.mkdirAll(ctx, tx, dstParent)
+		if err != nil {
+			return err
+		}
+		srcParent, srcName := split(srcPath)
+		_, err = tx.ExecContext(ctx, f.q(`UPDATE {dirs} SET parent = ?, name = ? WHERE parent = ? AND name = ?`), dstParent, dstName, srcParent, srcName)
+		if err != nil {
+			return fmt.Errorf("failed to move directory: %w", err)
+		}
+		// Swap srcPath for dstPath at the start of the paths of
+		// everything in the directory
+		lo, hi := below(srcPath)
+		from := utf8.RuneCountInString(srcPath) + 1
+		for _, table := range []struct{ name, col string }{{"{dirs}", "parent"}, {"{objects}", "dir"}} {
+			query := fmt.Sprintf(`UPDATE %s SET %s = %s WHERE %s = ? OR (%s >= ? AND %s < ?)`,
+				table.name, table.col, f.d.concat("?", "SUBSTR("+table.col+", ?)"), table.col, table.col, table.col)
+			_, err = tx.ExecContext(ctx, f.q(query), dstPath, from, srcPath, lo, hi)
+			if err != nil {
+				return fmt.Errorf("failed to move directory contents: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// About gets quota information
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	var objects, used int64
+	err := f.db.QueryRowContext(ctx, f.q(`SELECT COUNT(*), COALESCE(SUM(size), 0) FROM {objects}`)).Scan(&objects, &used)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	return &fs.Usage{
+		Used:    fs.NewUsageValue(used),
+		Objects: fs.NewUsageValue(objects),
+	}, nil
+}
+
+// Shutdown closes the connections to the database
+func (f *Fs) Shutdown(ctx context.Context) error {
+	return f.db.Close()
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the hash of an object returning a lowercase hex string
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	switch t {
+	case hash.MD5:
+		return o.md5, nil
+	case hash.SHA1:
+		return o.sha1, nil
+	}
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	res, err := o.fs.db.ExecContext(ctx, o.fs.q(`UPDATE {objects} SET modtime = ? WHERE id = ?`), modTime.UnixNano(), o.id)
+	if err != nil {
+		return fmt.Errorf("failed to set modification time: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fs.ErrorObjectNotFound
+	}
+	o.modTime = modTime
+	return nil
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// chunkReader reads the chunks of an object in turn
+type chunkReader struct {
+	ctx  context.Context
+	o    *Object
+	n    int64  // number of the next chunk to read
+	skip int64  // bytes to skip at the start of the next chunk
+	buf  []byte // unread data of the current chunk
+}
+
+// Read reads from the current chunk, reading the next one if it is
+// all read
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.n*r.o.chunkSize >= r.o.size {
+			return 0, io.EOF
+		}
+		var data []byte
+		err := r.o.fs.db.QueryRowContext(r.ctx, r.o.fs.q(`SELECT data FROM {chunks} WHERE object_id = ? AND n = ?`), r.o.id, r.n).Scan(&data)
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("chunk %d missing - object changed while being read", r.n)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read chunk %d: %w", r.n, err)
+		}
+		if r.skip > int64(len(data)) {
+			return 0, fmt.Errorf("chunk %d too short", r.n)
+		}
+		r.buf = data[r.skip:]
+		r.skip = 0
+		r.n++
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	r := &chunkReader{ctx: ctx, o: o}
+	if o.chunkSize > 0 {
+		r.n, r.skip = offset/o.chunkSize, offset%o.chunkSize
+	}
+	in = ioutil.NopCloser(r)
+	if limit >= 0 {
+		in = readers.NewLimitedReadCloser(in, limit)
+	}
+	return in, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The data is written in one transaction so the old object is
+// replaced only once all of the new one is stored.
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	f := o.fs
+	dir, name := split(f.fullPath(o.remote))
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(hash.MD5, hash.SHA1))
+	if err != nil {
+		return err
+	}
+	in = io.TeeReader(in, hasher)
+	chunkSize := int64(f.opt.ChunkSize)
+	modTime := src.ModTime(ctx)
+	var id, size int64
+	err = f.inTx(ctx, func(tx *sql.Tx) (err error) {
+		err = f.mkdirAll(ctx, tx, dir)
+		if err != nil {
+			return err
+		}
+		err = f.removeExisting(ctx, tx, dir, name, -1)
+		if err != nil {
+			return err
+		}
+		id, err = f.d.insertObject(ctx, tx,
+			f.q(`INSERT INTO {objects} (dir, name, size, modtime, md5, sha1, chunk_size) VALUES (?, ?, 0, ?, '', '', ?)`),
+			dir, name, modTime.UnixNano(), chunkSize)
+		if err != nil {
+			return fmt.Errorf("failed to make object: %w", err)
+		}
+		buf := make([]byte, chunkSize)
+		insertChunk := f.q(`INSERT INTO {chunks} (object_id, n, data) VALUES (?, ?, ?)`)
+		for n := int64(0); ; n++ {
+			read, err := readers.ReadFill(in, buf)
+			if read > 0 {
+				_, insertErr := tx.ExecContext(ctx, insertChunk, id, n, buf[:read])
+				if insertErr != nil {
+					return fmt.Errorf("failed to write chunk %d: %w", n, insertErr)
+				}
+				size += int64(read)
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		sums := hasher.Sums()
+		_, err = tx.ExecContext(ctx, f.q(`UPDATE {objects} SET size = ?, md5 = ?, sha1 = ? WHERE id = ?`), size, sums[hash.MD5], sums[hash.SHA1], id)
+		if err != nil {
+			return fmt.Errorf("failed to update object: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sums := hasher.Sums()
+	o.id = id
+	o.size = size
+	o.modTime = modTime
+	o.md5 = sums[hash.MD5]
+	o.sha1 = sums[hash.SHA1]
+	o.chunkSize = chunkSize
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	res, err := o.fs.db.ExecContext(ctx, o.fs.q(`DELETE FROM {objects} WHERE id = ?`), o.id)
+	if err != nil {
+		return fmt.Errorf("failed to remove object: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fs.ErrorObjectNotFound
+	}
+	return nil
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.Abouter     = (*Fs)(nil)
+	_ fs.Shutdowner  = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+)
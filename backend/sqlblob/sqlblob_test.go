@@ -0,0 +1,17 @@
+// Test SQL database filesystem interface
+package sqlblob_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/sqlblob"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestSQLBlob:",
+		NilObject:  (*sqlblob.Object)(nil),
+	})
+}
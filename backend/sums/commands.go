@@ -0,0 +1,185 @@
+package sums
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "update",
+	Short: "Add missing files to the checksum manifests.",
+	Long: `This adds the checksums of files which aren't in the manifests, and
+removes the entries of files which no longer exist. Use it after
+adding the backend to a remote which already has files on it.
+
+The checksums are read from the wrapped remote if it supports the
+hash, otherwise the files are downloaded to work them out.
+
+Usage Example:
+
+    rclone backend update sums:path/to/dir
+`,
+}, {
+	Name:  "verify",
+	Short: "Check files against the checksum manifests.",
+	Long: `This downloads every file in the manifests and checks its checksum.
+Files which differ are shown with a "*" and files which aren't in the
+manifests with a "?".
+
+Usage Example:
+
+    rclone backend verify sums:path/to/dir
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "update":
+		return f.update(ctx)
+	case "verify":
+		return f.verify(ctx)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// download reads o and returns its checksum
+func (f *Fs) download(ctx context.Context, o fs.Object) (string, error) {
+	tr := accounting.Stats(ctx).NewCheckingTransfer(o)
+	defer tr.Done(ctx, nil)
+	in, err := o.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	in = tr.Account(ctx, in).WithBuffer()
+	sums, err := hash.StreamTypes(in, hash.NewHashSet(f.hashType))
+	closeErr := in.Close()
+	if err != nil {
+		return "", err
+	}
+	if closeErr != nil {
+		return "", closeErr
+	}
+	return sums[f.hashType], nil
+}
+
+// update adds the files missing from the manifests and removes the
+// entries of files which have gone
+func (f *Fs) update(ctx context.Context) (map[string]int, error) {
+	updates := map[string]string{}
+	seen := map[string]map[string]struct{}{"": {}}
+	added := 0
+	err := walk.ListR(ctx, f, "", true, -1, walk.ListAll, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			dir, leaf := split(entry.Remote())
+			switch x := entry.(type) {
+			case fs.Directory:
+				if seen[x.Remote()] == nil {
+					seen[x.Remote()] = map[string]struct{}{}
+				}
+			case *Object:
+				if seen[dir] == nil {
+					seen[dir] = map[string]struct{}{}
+				}
+				seen[dir][leaf] = struct{}{}
+				if f.manifestSum(ctx, x.Remote()) != "" {
+					continue
+				}
+				var sum string
+				var err error
+				if f.Fs.Hashes().Contains(f.hashType) {
+					sum, err = x.Object.Hash(ctx, f.hashType)
+				}
+				if err == nil && sum == "" {
+					sum, err = f.download(ctx, x.Object)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to read checksum of %q: %w", x.Remote(), err)
+				}
+				fs.Debugf(x, "Adding to checksum manifest")
+				updates[x.Remote()] = sum
+				added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	removed := 0
+	for dir, leaves := range seen {
+		f.mu.Lock()
+		sums, err := f.readManifest(ctx, dir)
+		f.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		for leaf := range sums {
+			if _, found := leaves[leaf]; !found {
+				remote := leaf
+				if dir != "" {
+					remote = dir + "/" + leaf
+				}
+				fs.Debugf(remote, "Removing missing file from checksum manifest")
+				updates[remote] = ""
+				removed++
+			}
+		}
+	}
+	if err := f.setSums(ctx, updates); err != nil {
+		return nil, err
+	}
+	return map[string]int{
+		"added":   added,
+		"removed": removed,
+	}, nil
+}
+
+// verify checks all the files against the manifests
+func (f *Fs) verify(ctx context.Context) ([]string, error) {
+	out := []string{}
+	err := walk.ListR(ctx, f, "", true, -1, walk.ListObjects, func(entries fs.DirEntries) error {
+		for _, entry := range entries {
+			o, ok := entry.(*Object)
+			if !ok {
+				continue
+			}
+			want := f.manifestSum(ctx, o.Remote())
+			if want == "" {
+				out = append(out, "? "+o.Remote())
+				continue
+			}
+			got, err := f.download(ctx, o.Object)
+			if err != nil {
+				return fmt.Errorf("failed to read %q: %w", o.Remote(), err)
+			}
+			if got != want {
+				fs.Errorf(o, "%v differs from manifest: expected %s, got %s", f.hashType, want, got)
+				out = append(out, "* "+o.Remote())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i][2:] < out[j][2:]
+	})
+	return out, nil
+}
@@ -0,0 +1,183 @@
+package sums
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+)
+
+// split returns the directory and leaf of remote
+func split(remote string) (dir, leaf string) {
+	dir, leaf = path.Split(remote)
+	return strings.TrimSuffix(dir, "/"), leaf
+}
+
+// isManifest returns true if remote is a manifest
+func (f *Fs) isManifest(remote string) bool {
+	_, leaf := split(remote)
+	return leaf == f.opt.ManifestName
+}
+
+// manifestPath returns the path of the manifest for dir
+func (f *Fs) manifestPath(dir string) string {
+	return path.Join(dir, f.opt.ManifestName)
+}
+
+// checkWritable returns an error if remote can't be stored
+func (f *Fs) checkWritable(remote string) error {
+	if f.isManifest(remote) {
+		return fmt.Errorf("can't write %q: the name is used for the checksum manifests", remote)
+	}
+	if strings.ContainsAny(remote, "\n\r") {
+		return fmt.Errorf("can't write %q: names with line breaks can't be stored in the checksum manifests", remote)
+	}
+	return nil
+}
+
+// readManifest returns the checksums in the manifest of dir
+//
+// Call with f.mu held
+func (f *Fs) readManifest(ctx context.Context, dir string) (map[string]string, error) {
+	if sums, found := f.manifests[dir]; found {
+		return sums, nil
+	}
+	sums := map[string]string{}
+	o, err := f.Fs.NewObject(ctx, f.manifestPath(dir))
+	if err == nil {
+		sums, err = operations.ParseSumFile(ctx, o)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checksum manifest: %w", err)
+		}
+	} else if !errors.Is(err, fs.ErrorObjectNotFound) && !errors.Is(err, fs.ErrorDirNotFound) {
+		return nil, err
+	}
+	f.manifests[dir] = sums
+	return sums, nil
+}
+
+// writeManifest stores sums as the manifest of dir, removing the
+// manifest if sums is empty
+//
+// Call with f.mu held
+func (f *Fs) writeManifest(ctx context.Context, dir string, sums map[string]string) error {
+	remote := f.manifestPath(dir)
+	delete(f.manifests, dir)
+	if len(sums) == 0 {
+		o, err := f.Fs.NewObject(ctx, remote)
+		if err != nil {
+			return nil
+		}
+		return o.Remove(ctx)
+	}
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		_, _ = fmt.Fprintf(&buf, "%s  %s\n", sums[name], name)
+	}
+	src := object.NewStaticObjectInfo(remote, time.Now(), int64(buf.Len()), true, nil, f.Fs)
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err == nil {
+		err = o.Update(ctx, &buf, src)
+	} else {
+		_, err = f.Fs.Put(ctx, &buf, src)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write checksum manifest: %w", err)
+	}
+	f.manifests[dir] = sums
+	return nil
+}
+
+// manifestSum returns the checksum of remote from its manifest or ""
+// if it isn't there
+func (f *Fs) manifestSum(ctx context.Context, remote string) string {
+	dir, leaf := split(remote)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sums, err := f.readManifest(ctx, dir)
+	if err != nil {
+		fs.Errorf(remote, "%v", err)
+		return ""
+	}
+	return sums[leaf]
+}
+
+// setSum sets the checksum of remote in its manifest, or removes it
+// if sum is ""
+func (f *Fs) setSum(ctx context.Context, remote, sum string) error {
+	return f.setSums(ctx, map[string]string{remote: sum})
+}
+
+// setSums sets the checksums of the remotes in updates in their
+// manifests, removing those which are ""
+func (f *Fs) setSums(ctx context.Context, updates map[string]string) error {
+	byDir := map[string]map[string]string{}
+	for remote, sum := range updates {
+		dir, leaf := split(remote)
+		if byDir[dir] == nil {
+			byDir[dir] = map[string]string{}
+		}
+		byDir[dir][leaf] = sum
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for dir, leaves := range byDir {
+		old, err := f.readManifest(ctx, dir)
+		if err != nil {
+			return err
+		}
+		sums := make(map[string]string, len(old)+len(leaves))
+		for leaf, sum := range old {
+			sums[leaf] = sum
+		}
+		changed := false
+		for leaf, sum := range leaves {
+			if sums[leaf] == sum {
+				continue
+			}
+			changed = true
+			if sum == "" {
+				delete(sums, leaf)
+			} else {
+				sums[leaf] = sum
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := f.writeManifest(ctx, dir, sums); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeLoneManifest removes the manifest of dir if it is the only
+// thing left in it so the directory can be removed
+func (f *Fs) removeLoneManifest(ctx context.Context, dir string) error {
+	entries, err := f.Fs.List(ctx, dir)
+	if err != nil || len(entries) != 1 || !f.isManifest(entries[0].Remote()) {
+		return nil
+	}
+	o, ok := entries[0].(fs.Object)
+	if !ok {
+		return nil
+	}
+	f.mu.Lock()
+	delete(f.manifests, dir)
+	f.mu.Unlock()
+	return o.Remove(ctx)
+}
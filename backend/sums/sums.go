@@ -0,0 +1,681 @@
+// Package sums implements a backend which keeps checksum manifests
+// of the files on the wrapped remote
+package sums
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "sums",
+		Description: "Keep checksum manifests of the files on a remote",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			Help: `Any metadata supported by the underlying remote is read and written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to keep checksum manifests for.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name:    "hash",
+			Default: "md5",
+			Help:    `Type of checksum to keep.`,
+			Examples: []fs.OptionExample{{
+				Value: "md5",
+				Help:  "MD5 - manifests are called MD5SUMS",
+			}, {
+				Value: "sha1",
+				Help:  "SHA-1 - manifests are called SHA1SUMS",
+			}, {
+				Value: "sha256",
+				Help:  "SHA-256 - manifests are called SHA256SUMS",
+			}},
+		}, {
+			Name:     "manifest_name",
+			Advanced: true,
+			Help: `Name of the manifest file in each directory.
+
+If empty this is the name of the hash in upper case followed by SUMS,
+e.g. MD5SUMS. The manifests are in the same format as the output of
+md5sum and rclone md5sum and are hidden from listings.`,
+		}, {
+			Name:     "verify",
+			Default:  true,
+			Advanced: true,
+			Help: `Check files against the manifest when they are read.
+
+When a whole file is read, its checksum is worked out as it is read
+and compared with the one in the manifest. If they differ the read
+fails with an error.`,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote       string `config:"remote"`
+	Hash         string `config:"hash"`
+	ManifestName string `config:"manifest_name"`
+	Verify       bool   `config:"verify"`
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	opt      Options
+	features *fs.Features // optional features
+	wrapper  fs.Fs
+	hashType hash.Type // type of checksum in the manifests
+
+	mu        sync.Mutex
+	manifests map[string]map[string]string // cached manifests by directory
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point sums remote at itself - check the value of the remote setting")
+	}
+	f := &Fs{
+		name:      name,
+		root:      rpath,
+		opt:       opt,
+		manifests: map[string]map[string]string{},
+	}
+	if err := f.hashType.Set(opt.Hash); err != nil {
+		return nil, err
+	}
+	if f.hashType == hash.None {
+		return nil, errors.New("hash must be set")
+	}
+	if f.opt.ManifestName == "" {
+		f.opt.ManifestName = strings.ToUpper(strings.ReplaceAll(f.hashType.String(), "-", "")) + "SUMS"
+	}
+	if strings.Contains(f.opt.ManifestName, "/") {
+		return nil, errors.New("manifest_name can't contain /")
+	}
+	remotePath := fspath.JoinRootPath(opt.Remote, rpath)
+	var baseErr error
+	f.Fs, baseErr = cache.Get(ctx, remotePath)
+	if baseErr != nil && baseErr != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", remotePath, baseErr)
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		SetTier:                 true,
+		GetTier:                 true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, f.Fs).WrapsFs(f, f.Fs)
+	return f, baseErr
+}
+
+// Hashes returns the hashes of the wrapped remote plus the one in
+// the manifests
+func (f *Fs) Hashes() hash.Set {
+	set := f.Fs.Hashes()
+	return set.Add(f.hashType)
+}
+
+// put uploads with do, recording the checksum of the data in the
+// manifest
+func (f *Fs) put(ctx context.Context, do func(context.Context, io.Reader, fs.ObjectInfo, ...fs.OpenOption) (fs.Object, error), in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(f.hashType))
+	if err != nil {
+		return nil, err
+	}
+	o, err := do(ctx, io.TeeReader(in, hasher), src, options...)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.setSum(ctx, o.Remote(), hasher.Sums()[f.hashType]); err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("sums root '%s'", f.root)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// wrapEntries wraps the objects in entries, removing the manifests
+func (f *Fs) wrapEntries(entries fs.DirEntries) fs.DirEntries {
+	out := entries[:0]
+	for _, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			if f.isManifest(o.Remote()) {
+				continue
+			}
+			entry = f.newObject(o)
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return f.wrapEntries(entries), nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	return f.Fs.Features().ListR(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.wrapEntries(entries))
+	})
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// The checksums are read from the sums file as the objects are
+// wrapped so each page costs no more than List.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	do := f.Fs.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	return do(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.wrapEntries(entries))
+	})
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if f.isManifest(remote) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.Fs.Mkdir(ctx, dir)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	if err := f.removeLoneManifest(ctx, dir); err != nil {
+		return err
+	}
+	return f.Fs.Rmdir(ctx, dir)
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	if err := f.checkWritable(src.Remote()); err != nil {
+		return nil, err
+	}
+	return f.put(ctx, f.Fs.Put, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutStream
+	if do == nil {
+		return nil, errors.New("PutStream not supported")
+	}
+	if err := f.checkWritable(src.Remote()); err != nil {
+		return nil, err
+	}
+	return f.put(ctx, do, in, src, options...)
+}
+
+// PutUnchecked uploads the object, allowing duplicates
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutUnchecked
+	if do == nil {
+		return nil, errors.New("PutUnchecked not supported")
+	}
+	if err := f.checkWritable(src.Remote()); err != nil {
+		return nil, err
+	}
+	return f.put(ctx, do, in, src, options...)
+}
+
+// Purge all files in the directory specified
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	return do(ctx, dir)
+}
+
+// Copy src to this remote using server-side copy operations.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	if err := f.checkWritable(remote); err != nil {
+		return nil, err
+	}
+	o, err := do(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.setSum(ctx, remote, srcObj.f.manifestSum(ctx, srcObj.Remote())); err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Move src to this remote using server-side move operations.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	if err := f.checkWritable(remote); err != nil {
+		return nil, err
+	}
+	sum := srcObj.f.manifestSum(ctx, srcObj.Remote())
+	o, err := do(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	if err := srcObj.f.setSum(ctx, srcObj.Remote(), ""); err != nil {
+		return nil, err
+	}
+	if err := f.setSum(ctx, remote, sum); err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	return do(ctx, srcFs.Fs, srcRemote, dstRemote)
+}
+
+// MergeDirs merges the contents of all the directories passed
+// in into the first one and rmdirs the other directories.
+func (f *Fs) MergeDirs(ctx context.Context, dirs []fs.Directory) error {
+	do := f.Fs.Features().MergeDirs
+	if do == nil {
+		return errors.New("MergeDirs not supported")
+	}
+	return do(ctx, dirs)
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	if do := f.Fs.Features().ChangeNotify; do != nil {
+		do(ctx, notifyFunc, pollIntervalChan)
+	}
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	do := f.Fs.Features().PublicLink
+	if do == nil {
+		return "", errors.New("PublicLink not supported")
+	}
+	return do(ctx, remote, expire, unlink)
+}
+
+// UserInfo returns info about the connected user
+func (f *Fs) UserInfo(ctx context.Context) (map[string]string, error) {
+	do := f.Fs.Features().UserInfo
+	if do == nil {
+		return nil, fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Disconnect the current user
+func (f *Fs) Disconnect(ctx context.Context) error {
+	do := f.Fs.Features().Disconnect
+	if do == nil {
+		return fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	do := f.Fs.Features().Shutdown
+	if do == nil {
+		return nil
+	}
+	return do(ctx)
+}
+
+// Object describes a wrapped object
+type Object struct {
+	fs.Object
+	f *Fs
+}
+
+// newObject wraps o
+func (f *Fs) newObject(o fs.Object) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// Hash returns the checksum of the file from the manifest if it is
+// the type kept there, otherwise from the wrapped remote
+func (o *Object) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	if ht == o.f.hashType {
+		if sum := o.f.manifestSum(ctx, o.Remote()); sum != "" {
+			return sum, nil
+		}
+		if !o.f.Fs.Hashes().Contains(ht) {
+			return "", nil
+		}
+	}
+	return o.Object.Hash(ctx, ht)
+}
+
+// Open opens the file for read, checking it against the manifest if
+// it is read in full
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	in, err := o.Object.Open(ctx, options...)
+	if err != nil || !o.f.opt.Verify {
+		return in, err
+	}
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.RangeOption:
+			if x.Start > 0 || (x.End >= 0 && x.End < o.Size()-1) {
+				return in, nil
+			}
+		case *fs.SeekOption:
+			if x.Offset > 0 {
+				return in, nil
+			}
+		}
+	}
+	want := o.f.manifestSum(ctx, o.Remote())
+	if want == "" {
+		return in, nil
+	}
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(o.f.hashType))
+	if err != nil {
+		return nil, err
+	}
+	return &verifier{
+		ReadCloser: in,
+		o:          o,
+		hasher:     hasher,
+		want:       want,
+	}, nil
+}
+
+// verifier checks the data read against the checksum in the manifest
+type verifier struct {
+	io.ReadCloser
+	o      *Object
+	hasher *hash.MultiHasher
+	want   string
+}
+
+// Read bytes from the object, returning an error at the end if they
+// don't match the manifest
+func (v *verifier) Read(p []byte) (n int, err error) {
+	n, err = v.ReadCloser.Read(p)
+	_, _ = v.hasher.Write(p[:n])
+	if err == io.EOF {
+		got := v.hasher.Sums()[v.o.f.hashType]
+		if got != v.want {
+			fs.Errorf(v.o, "%v mismatch with manifest: expected %s, got %s", v.o.f.hashType, v.want, got)
+			err = fmt.Errorf("corrupted on transfer: %v hash differs from manifest %q vs %q", v.o.f.hashType, v.want, got)
+		}
+	}
+	return n, err
+}
+
+// Update in to the object with the modTime given of the given size,
+// recording its checksum in the manifest
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(o.f.hashType))
+	if err != nil {
+		return err
+	}
+	err = o.Object.Update(ctx, io.TeeReader(in, hasher), src, options...)
+	if err != nil {
+		return err
+	}
+	return o.f.setSum(ctx, o.Remote(), hasher.Sums()[o.f.hashType])
+}
+
+// Remove an object and its entry in the manifest
+func (o *Object) Remove(ctx context.Context) error {
+	if err := o.Object.Remove(ctx); err != nil {
+		return err
+	}
+	return o.f.setSum(ctx, o.Remote(), "")
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	do, ok := o.Object.(fs.IDer)
+	if !ok {
+		return ""
+	}
+	return do.ID()
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	do, ok := o.Object.(fs.MimeTyper)
+	if !ok {
+		return ""
+	}
+	return do.MimeType(ctx)
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	do, ok := o.Object.(fs.SetTierer)
+	if !ok {
+		return errors.New("SetTier not supported")
+	}
+	return do.SetTier(tier)
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *Object) GetTier() string {
+	do, ok := o.Object.(fs.GetTierer)
+	if !ok {
+		return ""
+	}
+	return do.GetTier()
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.PutUncheckeder  = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.MergeDirser     = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.UserInfoer      = (*Fs)(nil)
+	_ fs.Disconnecter    = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.FullObject      = (*Object)(nil)
+)
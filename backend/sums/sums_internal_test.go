@@ -0,0 +1,138 @@
+package sums
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs returns a sums remote with the extra config given on an
+// empty directory, and the path of that directory so tests can find
+// the checksum files and change data behind the backend's back
+func newTestFs(t *testing.T, params string) (*Fs, string) {
+	f, tempRoot := fstest.NewWrappingFs(t, "sums", params)
+	return f.(*Fs), tempRoot
+}
+
+func read(f fs.Fs, remote string) (string, error) {
+	ctx := context.Background()
+	o, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return "", err
+	}
+	in, err := o.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(in)
+	_ = in.Close()
+	return string(data), err
+}
+
+// helloMD5 is the MD5 of "hello"
+const helloMD5 = "5d41402abc4b2a76b9719d911017c592"
+
+func TestManifest(t *testing.T) {
+	ctx := context.Background()
+	f, root := newTestFs(t, "")
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/file.txt", ModTime: time.Now()}, "hello", true)
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/other.txt", ModTime: time.Now()}, "other", true)
+
+	data, err := os.ReadFile(filepath.Join(root, "dir", "MD5SUMS"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), helloMD5+"  file.txt\n")
+	assert.True(t, f.Hashes().Contains(hash.MD5))
+
+	sum, err := o.Hash(ctx, hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, helloMD5, sum)
+
+	// the manifest is hidden and can't be written
+	entries, err := f.List(ctx, "dir")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+	_, err = f.NewObject(ctx, "dir/MD5SUMS")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	src := object.NewStaticObjectInfo("MD5SUMS", time.Now(), 0, true, nil, nil)
+	_, err = f.Put(ctx, strings.NewReader(""), src)
+	assert.Error(t, err)
+
+	// removing the files removes the manifest and the directory
+	for _, remote := range []string{"dir/file.txt", "dir/other.txt"} {
+		o, err := f.NewObject(ctx, remote)
+		require.NoError(t, err)
+		require.NoError(t, o.Remove(ctx))
+	}
+	_, err = os.Stat(filepath.Join(root, "dir", "MD5SUMS"))
+	assert.True(t, os.IsNotExist(err))
+	require.NoError(t, f.Rmdir(ctx, "dir"))
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	f, root := newTestFs(t, "")
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "file.txt", ModTime: time.Now()}, "hello", true)
+	got, err := read(f, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", got)
+
+	// simulate bit rot
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file.txt"), []byte("hellO"), 0666))
+	_, err = read(f, "file.txt")
+	assert.ErrorContains(t, err, "differs from manifest")
+
+	out, err := f.Command(ctx, "verify", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"* file.txt"}, out)
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	f, root := newTestFs(t, "hash=sha1")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "dir"), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "file.txt"), []byte("hello"), 0666))
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "gone.txt", ModTime: time.Now()}, "gone", true)
+	require.NoError(t, os.Remove(filepath.Join(root, "gone.txt")))
+
+	out, err := f.Command(ctx, "verify", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"? dir/file.txt"}, out)
+
+	out, err = f.Command(ctx, "update", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"added": 1, "removed": 1}, out)
+	data, err := os.ReadFile(filepath.Join(root, "dir", "SHA1SUMS"))
+	require.NoError(t, err)
+	assert.Equal(t, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d  file.txt\n", string(data))
+	_, err = os.Stat(filepath.Join(root, "SHA1SUMS"))
+	assert.True(t, os.IsNotExist(err))
+
+	out, err = f.Command(ctx, "verify", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, out)
+}
+
+func TestMove(t *testing.T) {
+	ctx := context.Background()
+	f, _ := newTestFs(t, "")
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "a/file.txt", ModTime: time.Now()}, "hello", true)
+	moved, err := f.Move(ctx, o, "b/file.txt")
+	require.NoError(t, err)
+	sum, err := moved.Hash(ctx, hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, helloMD5, sum)
+	assert.Equal(t, "", f.manifestSum(ctx, "a/file.txt"))
+}
@@ -0,0 +1,111 @@
+// Package api contains definitions for using the Telegram Bot API
+//
+// See https://core.telegram.org/bots/api
+package api
+
+import (
+	"fmt"
+)
+
+// Response is returned by all the methods. Methods which succeed
+// also have a result.
+type Response struct {
+	OK          bool                `json:"ok"`
+	ErrorCode   int                 `json:"error_code,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Parameters  *ResponseParameters `json:"parameters,omitempty"`
+}
+
+// ResponseParameters describe why a request failed
+type ResponseParameters struct {
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+	RetryAfter      int   `json:"retry_after,omitempty"`
+}
+
+// Error satisfies the error interface
+func (e *Response) Error() string {
+	return fmt.Sprintf("telegram error %d: %s", e.ErrorCode, e.Description)
+}
+
+// AsErr returns e as an error if it isn't a success or nil
+func (e *Response) AsErr() error {
+	if !e.OK {
+		return e
+	}
+	return nil
+}
+
+// User is a Telegram user or bot
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
+// UserResponse is returned by getMe
+type UserResponse struct {
+	Response
+	Result User `json:"result"`
+}
+
+// Chat is a channel, group or private chat
+type Chat struct {
+	ID            int64    `json:"id"`
+	Type          string   `json:"type"`
+	Title         string   `json:"title,omitempty"`
+	Username      string   `json:"username,omitempty"`
+	PinnedMessage *Message `json:"pinned_message,omitempty"`
+}
+
+// ChatResponse is returned by getChat
+type ChatResponse struct {
+	Response
+	Result Chat `json:"result"`
+}
+
+// Document is a general file attached to a message
+type Document struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileName     string `json:"file_name,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+}
+
+// Message is a message in a chat
+type Message struct {
+	MessageID int64     `json:"message_id"`
+	Date      int64     `json:"date"`
+	Document  *Document `json:"document,omitempty"`
+}
+
+// MessageResponse is returned by sendDocument and editMessageMedia
+type MessageResponse struct {
+	Response
+	Result Message `json:"result"`
+}
+
+// File is a file ready to be downloaded
+//
+// It can be downloaded from /file/bot<token>/<file_path> for at
+// least an hour after it was returned.
+type File struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileSize     int64  `json:"file_size,omitempty"`
+	FilePath     string `json:"file_path,omitempty"`
+}
+
+// FileResponse is returned by getFile
+type FileResponse struct {
+	Response
+	Result File `json:"result"`
+}
+
+// InputMediaDocument is the new content of a message for
+// editMessageMedia
+type InputMediaDocument struct {
+	Type  string `json:"type"`  // always "document"
+	Media string `json:"media"` // file_id or attach://<part name>
+}
@@ -0,0 +1,154 @@
+package telegram
+
+import (
+	"strings"
+	"time"
+)
+
+// indexName is the file name of the index document
+const indexName = "rclone-index.json"
+
+// indexVersion is the version of the index format written
+const indexVersion = 1
+
+// index is the contents of the index document pinned in the chat
+//
+// The keys are the encoded paths from the top of the chat without
+// leading or trailing slashes. Every directory is in Dirs, including
+// the parents of all the files.
+type index struct {
+	Version int                   `json:"version"`
+	Files   map[string]*indexFile `json:"files"`
+	Dirs    map[string]time.Time  `json:"dirs"`
+}
+
+// indexFile describes a file stored as a list of chunks
+type indexFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	MD5     string    `json:"md5,omitempty"`
+	Chunks  []chunk   `json:"chunks"`
+}
+
+// chunk is a part of a file sent as a document in a message
+type chunk struct {
+	MessageID int64  `json:"message_id"`
+	FileID    string `json:"file_id"`
+	Size      int64  `json:"size"`
+}
+
+// newIndex returns an empty index
+func newIndex() *index {
+	return &index{
+		Version: indexVersion,
+		Files:   map[string]*indexFile{},
+		Dirs:    map[string]time.Time{},
+	}
+}
+
+// parentKey returns the key of the directory key is in
+func parentKey(key string) string {
+	i := strings.LastIndex(key, "/")
+	if i < 0 {
+		return ""
+	}
+	return key[:i]
+}
+
+// joinKey returns the key of leaf in the directory dir
+func joinKey(dir, leaf string) string {
+	if dir == "" {
+		return leaf
+	}
+	return dir + "/" + leaf
+}
+
+// inDir returns whether key is dir or somewhere inside it
+func inDir(key, dir string) bool {
+	return dir == "" || key == dir || strings.HasPrefix(key, dir+"/")
+}
+
+// isDir returns whether there is a directory at key
+func (idx *index) isDir(key string) bool {
+	if key == "" {
+		return true
+	}
+	_, ok := idx.Dirs[key]
+	return ok
+}
+
+// addParents adds the directories key is in which aren't there already
+func (idx *index) addParents(key string, modTime time.Time) {
+	for dir := parentKey(key); dir != ""; dir = parentKey(dir) {
+		if idx.isDir(dir) {
+			return
+		}
+		idx.Dirs[dir] = modTime
+	}
+}
+
+// isEmpty returns whether the directory dir has nothing in it
+func (idx *index) isEmpty(dir string) bool {
+	for key := range idx.Files {
+		if inDir(key, dir) {
+			return false
+		}
+	}
+	for key := range idx.Dirs {
+		if key != dir && inDir(key, dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// walk calls fn for each file and directory in dir, recursively if
+// recurse is set
+//
+// file is nil for directories.
+func (idx *index) walk(dir string, recurse bool, fn func(key string, file *indexFile, modTime time.Time)) {
+	match := func(key string) bool {
+		if recurse {
+			return key != dir && inDir(key, dir)
+		}
+		return key != "" && parentKey(key) == dir
+	}
+	for key, modTime := range idx.Dirs {
+		if match(key) {
+			fn(key, nil, modTime)
+		}
+	}
+	for key, file := range idx.Files {
+		if match(key) {
+			fn(key, file, file.ModTime)
+		}
+	}
+}
+
+// rename moves the directory src and everything in it to dst which
+// mustn't exist
+func (idx *index) rename(src, dst string) {
+	move := func(key string) string {
+		return dst + strings.TrimPrefix(key, src)
+	}
+	files := map[string]*indexFile{}
+	for key, file := range idx.Files {
+		if inDir(key, src) {
+			files[move(key)] = file
+			delete(idx.Files, key)
+		}
+	}
+	for key, file := range files {
+		idx.Files[key] = file
+	}
+	dirs := map[string]time.Time{}
+	for key, modTime := range idx.Dirs {
+		if inDir(key, src) {
+			dirs[move(key)] = modTime
+			delete(idx.Dirs, key)
+		}
+	}
+	for key, modTime := range dirs {
+		idx.Dirs[key] = modTime
+	}
+}
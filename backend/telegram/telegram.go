@@ -0,0 +1,1228 @@
+// Package telegram provides an interface to files stored in a
+// Telegram chat
+//
+// Files are split into chunks which are sent as documents to a chat
+// using the Bot API. The list of files and their chunks is kept in an
+// index document in the pinned message of the chat.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/telegram/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/encoder"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/readers"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 50 * time.Millisecond
+	maxSleep      = 5 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+
+	defaultAPIURL = "https://api.telegram.org"
+
+	// Bots can only download files up to 20 MB from the Telegram
+	// servers. A local Bot API server raises this to 2000 MB.
+	maxChunkSize      = 20 * 1000 * 1000
+	maxLocalChunkSize = 2000 * 1000 * 1000
+
+	// most messages which can be deleted in one call
+	maxDeleteMessages = 100
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "telegram",
+		Description: "Telegram",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "bot_token",
+			Help: `Token of the bot to use.
+
+Get one by making a bot with @BotFather in Telegram.`,
+			IsPassword: true,
+			Required:   true,
+		}, {
+			Name: "chat_id",
+			Help: `Chat to store the files in.
+
+Either the username of a public channel, e.g. @mychannel, or the numeric
+ID of the chat, e.g. -1001234567890. The bot must be an administrator
+of the chat allowed to post, edit, delete and pin messages.`,
+			Required: true,
+		}, {
+			Name: "chunk_size",
+			Help: `Size of the chunks files are split into.
+
+Bots can't download files bigger than 20 MB from Telegram, so this can
+only be raised above that when using a local Bot API server with
+api_url.
+
+Each transfer buffers a chunk in memory.`,
+			Default:  16 * fs.Mebi,
+			Advanced: true,
+		}, {
+			Name: "api_url",
+			Help: `URL of the Bot API server.
+
+Set this to use a local Bot API server, e.g. http://localhost:8081,
+which allows bigger chunks.`,
+			Default:  defaultAPIURL,
+			Advanced: true,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// The index is JSON so invalid UTF-8 must be encoded
+			Default: (encoder.Base |
+				encoder.EncodeInvalidUtf8),
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	BotToken  string               `config:"bot_token"`
+	ChatID    string               `config:"chat_id"`
+	ChunkSize fs.SizeSuffix        `config:"chunk_size"`
+	APIURL    string               `config:"api_url"`
+	Enc       encoder.MultiEncoder `config:"encoding"`
+}
+
+// Fs represents a remote Telegram chat
+type Fs struct {
+	name     string       // name of this remote
+	root     string       // the path we are working on
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	ch       *channel     // the chat the files are in
+}
+
+// Object describes a file stored in a Telegram chat
+type Object struct {
+	fs      *Fs       // what this object is part of
+	remote  string    // The remote path
+	size    int64     // size of the object
+	modTime time.Time // modification time of the object
+	md5     string    // MD5 of the object if known
+	chunks  []chunk   // where the data is
+}
+
+// channel is a chat holding files and its index
+//
+// All the Fs using the same chat share one of these so they see each
+// other's changes.
+type channel struct {
+	srv    *rest.Client // the connection to the Bot API server
+	pacer  *fs.Pacer    // pacer for API calls
+	token  string       // revealed bot token
+	chatID string       // chat to use
+
+	mu    sync.Mutex // protects the following
+	index *index     // the files in the chat
+	gen   int64      // incremented on each change to index
+
+	saveMu    sync.Mutex // serialises saving the index and protects the following
+	savedGen  int64      // the value of gen when the index was last saved
+	messageID int64      // the message with the index or 0 if none
+}
+
+var (
+	channelsMu sync.Mutex
+	channels   = map[string]*channel{} // loaded chats by server, token and chat
+)
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("Telegram chat %s root '%s'", f.opt.ChatID, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return hash.NewHashSet(hash.MD5)
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	var apiErr *api.Response
+	if errors.As(err, &apiErr) && apiErr.Parameters != nil && apiErr.Parameters.RetryAfter > 0 {
+		return true, pacer.RetryAfterError(err, time.Duration(apiErr.Parameters.RetryAfter)*time.Second)
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	e := &api.Response{}
+	body, err := rest.ReadBody(resp)
+	if err == nil {
+		err = json.Unmarshal(body, e)
+	}
+	if err != nil || e.Description == "" {
+		e.Description = resp.Status
+	}
+	if e.ErrorCode == 0 {
+		e.ErrorCode = resp.StatusCode
+	}
+	return e
+}
+
+// hasDescription returns true if err is an API error whose
+// description contains s
+func hasDescription(err error, s string) bool {
+	var apiErr *api.Response
+	return errors.As(err, &apiErr) && strings.Contains(strings.ToLower(apiErr.Description), s)
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	opt.APIURL = strings.TrimRight(opt.APIURL, "/")
+	if opt.ChunkSize <= 0 {
+		return nil, errors.New("chunk_size must be positive")
+	}
+	if opt.APIURL == defaultAPIURL && opt.ChunkSize > maxChunkSize {
+		return nil, fmt.Errorf("chunk_size can't be more than %v without a local Bot API server", fs.SizeSuffix(maxChunkSize))
+	}
+	if opt.ChunkSize > maxLocalChunkSize {
+		return nil, fmt.Errorf("chunk_size can't be more than %v", fs.SizeSuffix(maxLocalChunkSize))
+	}
+	token, err := obscure.Reveal(opt.BotToken)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decrypt bot token: %w", err)
+	}
+	f := &Fs{
+		name: name,
+		root: strings.Trim(root, "/"),
+		opt:  *opt,
+	}
+	f.ch, err = getChannel(ctx, opt, token)
+	if err != nil {
+		return nil, err
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	f.ch.mu.Lock()
+	_, isFile := f.ch.index.Files[f.key("")]
+	f.ch.mu.Unlock()
+	if isFile {
+		f.root = parentDir(f.root)
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// getChannel returns the chat for opt, loading its index if it hasn't
+// been loaded already
+func getChannel(ctx context.Context, opt *Options, token string) (*channel, error) {
+	key := opt.APIURL + "\x00" + token + "\x00" + opt.ChatID
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+	if ch, ok := channels[key]; ok {
+		return ch, nil
+	}
+	ch := &channel{
+		srv:    rest.NewClient(fshttp.NewClient(ctx)).SetRoot(opt.APIURL),
+		pacer:  fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+		token:  token,
+		chatID: opt.ChatID,
+	}
+	ch.srv.SetErrorHandler(errorHandler)
+	if err := ch.load(ctx); err != nil {
+		return nil, err
+	}
+	channels[key] = ch
+	return ch, nil
+}
+
+// parentDir returns the parent directory of p or "" for the root
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// key returns the index key of remote
+func (f *Fs) key(remote string) string {
+	return strings.Trim(f.opt.Enc.FromStandardPath(path.Join(f.root, remote)), "/")
+}
+
+// resultError is implemented by all the API responses
+type resultError interface {
+	AsErr() error
+}
+
+// call calls the Bot API method with params decoding the response
+// into result
+func (ch *channel) call(ctx context.Context, method string, params url.Values, result resultError) error {
+	opts := rest.Opts{
+		Method:     "POST",
+		Path:       "/bot" + ch.token + "/" + method,
+		Parameters: params,
+	}
+	err := ch.pacer.Call(func() (bool, error) {
+		resp, err := ch.srv.CallJSON(ctx, &opts, nil, result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err == nil {
+		err = result.AsErr()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
+
+// upload calls the Bot API method with params and data attached as
+// the part called name, decoding the response into result
+func (ch *channel) upload(ctx context.Context, method string, params url.Values, name, fileName string, data []byte, result resultError) error {
+	err := ch.pacer.Call(func() (bool, error) {
+		opts := rest.Opts{
+			Method:               "POST",
+			Path:                 "/bot" + ch.token + "/" + method,
+			MultipartParams:      params,
+			MultipartContentName: name,
+			MultipartFileName:    fileName,
+			Body:                 bytes.NewReader(data),
+		}
+		resp, err := ch.srv.CallJSON(ctx, &opts, nil, result)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err == nil {
+		err = result.AsErr()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
+
+// open opens count bytes of the document fileID from offset, or all
+// of it from offset if count < 0
+func (ch *channel) open(ctx context.Context, fileID string, offset, count int64) (io.ReadCloser, error) {
+	var file api.FileResponse
+	err := ch.call(ctx, "getFile", url.Values{"file_id": {fileID}}, &file)
+	if err != nil {
+		return nil, err
+	}
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "/file/bot" + ch.token + "/" + file.Result.FilePath,
+	}
+	if offset > 0 {
+		opts.Options = []fs.OpenOption{&fs.SeekOption{Offset: offset}}
+	}
+	var resp *http.Response
+	err = ch.pacer.Call(func() (bool, error) {
+		resp, err = ch.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("download: %w", err)
+	}
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		// the server ignored the Range so skip to offset
+		_, err = io.CopyN(io.Discard, resp.Body, offset)
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("download: %w", err)
+		}
+	}
+	return readers.NewLimitedReadCloser(resp.Body, count), nil
+}
+
+// load reads the index from the pinned message of the chat
+func (ch *channel) load(ctx context.Context) error {
+	var chat api.ChatResponse
+	err := ch.call(ctx, "getChat", url.Values{"chat_id": {ch.chatID}}, &chat)
+	if err != nil {
+		return fmt.Errorf("couldn't read chat %s: %w", ch.chatID, err)
+	}
+	pinned := chat.Result.PinnedMessage
+	if pinned == nil {
+		fs.Debugf(nil, "Telegram chat %s has no index yet", ch.chatID)
+		ch.index = newIndex()
+		return nil
+	}
+	if pinned.Document == nil || pinned.Document.FileName != indexName {
+		return fmt.Errorf("the pinned message in chat %s isn't an rclone index - unpin it or use another chat", ch.chatID)
+	}
+	in, err := ch.open(ctx, pinned.Document.FileID, 0, -1)
+	if err != nil {
+		return fmt.Errorf("couldn't read index: %w", err)
+	}
+	defer fs.CheckClose(in, &err)
+	idx := newIndex()
+	err = json.NewDecoder(in).Decode(idx)
+	if err != nil {
+		return fmt.Errorf("couldn't decode index: %w", err)
+	}
+	if idx.Version > indexVersion {
+		return fmt.Errorf("index version %d is newer than this rclone supports", idx.Version)
+	}
+	ch.index = idx
+	ch.messageID = pinned.MessageID
+	return nil
+}
+
+// change calls fn to change the index
+//
+// If fn returns true the index is marked as changed. The index isn't
+// saved - call save for that.
+func (ch *channel) change(fn func(idx *index) (bool, error)) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	changed, err := fn(ch.index)
+	if changed {
+		ch.gen++
+	}
+	return err
+}
+
+// save uploads the index if it has changed since it was last saved
+//
+// Changes made while a save is in progress are saved together by the
+// next one.
+func (ch *channel) save(ctx context.Context) error {
+	ch.mu.Lock()
+	gen := ch.gen
+	ch.mu.Unlock()
+	ch.saveMu.Lock()
+	defer ch.saveMu.Unlock()
+	if ch.savedGen >= gen {
+		return nil
+	}
+	ch.mu.Lock()
+	data, err := json.Marshal(ch.index)
+	gen = ch.gen
+	ch.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if ch.messageID != 0 {
+		err = ch.editIndex(ctx, data)
+		if err == nil || !hasDescription(err, "not found") {
+			if err == nil {
+				ch.savedGen = gen
+			}
+			return err
+		}
+		fs.Debugf(nil, "Index message in chat %s has gone - sending a new one", ch.chatID)
+	}
+	err = ch.sendIndex(ctx, data)
+	if err != nil {
+		return err
+	}
+	ch.savedGen = gen
+	return nil
+}
+
+// editIndex replaces the document in the index message with data
+func (ch *channel) editIndex(ctx context.Context, data []byte) error {
+	media, err := json.Marshal(api.InputMediaDocument{
+		Type:  "document",
+		Media: "attach://index",
+	})
+	if err != nil {
+		return err
+	}
+	params := url.Values{
+		"chat_id":    {ch.chatID},
+		"message_id": {strconv.FormatInt(ch.messageID, 10)},
+		"media":      {string(media)},
+	}
+	var result api.MessageResponse
+	err = ch.upload(ctx, "editMessageMedia", params, "index", indexName, data, &result)
+	if hasDescription(err, "not modified") {
+		return nil
+	}
+	return err
+}
+
+// sendIndex sends data as a new index message and pins it
+func (ch *channel) sendIndex(ctx context.Context, data []byte) error {
+	message, err := ch.sendDocument(ctx, indexName, data)
+	if err != nil {
+		return err
+	}
+	params := url.Values{
+		"chat_id":              {ch.chatID},
+		"message_id":           {strconv.FormatInt(message.MessageID, 10)},
+		"disable_notification": {"true"},
+	}
+	var result api.Response
+	err = ch.call(ctx, "pinChatMessage", params, &result)
+	if err != nil {
+		return err
+	}
+	ch.messageID = message.MessageID
+	return nil
+}
+
+// sendDocument sends data as a document called name
+func (ch *channel) sendDocument(ctx context.Context, name string, data []byte) (*api.Message, error) {
+	params := url.Values{
+		"chat_id":                        {ch.chatID},
+		"disable_notification":           {"true"},
+		"disable_content_type_detection": {"true"},
+	}
+	var result api.MessageResponse
+	err := ch.upload(ctx, "sendDocument", params, "document", name, data, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result.Result.Document == nil {
+		return nil, errors.New("sendDocument: no document in reply")
+	}
+	return &result.Result, nil
+}
+
+// sendChunk sends data as a chunk of a file
+func (ch *channel) sendChunk(ctx context.Context, name string, data []byte) (chunk, error) {
+	message, err := ch.sendDocument(ctx, name, data)
+	if err != nil {
+		return chunk{}, err
+	}
+	return chunk{
+		MessageID: message.MessageID,
+		FileID:    message.Document.FileID,
+		Size:      int64(len(data)),
+	}, nil
+}
+
+// copyChunk sends the document in c again as a new message
+func (ch *channel) copyChunk(ctx context.Context, c chunk) (chunk, error) {
+	params := url.Values{
+		"chat_id":              {ch.chatID},
+		"document":             {c.FileID},
+		"disable_notification": {"true"},
+	}
+	var result api.MessageResponse
+	err := ch.call(ctx, "sendDocument", params, &result)
+	if err != nil {
+		return chunk{}, err
+	}
+	if result.Result.Document == nil {
+		return chunk{}, errors.New("sendDocument: no document in reply")
+	}
+	return chunk{
+		MessageID: result.Result.MessageID,
+		FileID:    result.Result.Document.FileID,
+		Size:      c.Size,
+	}, nil
+}
+
+// deleteChunks deletes the messages holding chunks
+//
+// They are only deleted to save space so errors are logged rather
+// than returned. Telegram doesn't allow messages older than 48 hours
+// to be deleted.
+func (ch *channel) deleteChunks(ctx context.Context, chunks []chunk) {
+	for len(chunks) > 0 {
+		n := len(chunks)
+		if n > maxDeleteMessages {
+			n = maxDeleteMessages
+		}
+		ids := make([]int64, n)
+		for i := range ids {
+			ids[i] = chunks[i].MessageID
+		}
+		chunks = chunks[n:]
+		messageIDs, err := json.Marshal(ids)
+		if err != nil {
+			fs.Errorf(nil, "Failed to delete messages: %v", err)
+			continue
+		}
+		params := url.Values{
+			"chat_id":     {ch.chatID},
+			"message_ids": {string(messageIDs)},
+		}
+		var result api.Response
+		err = ch.call(ctx, "deleteMessages", params, &result)
+		if err != nil {
+			fs.Errorf(nil, "Failed to delete %d messages from chat %s: %v", n, ch.chatID, err)
+		}
+	}
+}
+
+// list calls fn for each entry in dir, recursively if recurse is set
+func (f *Fs) list(dir string, recurse bool, fn func(entry fs.DirEntry)) error {
+	dirKey := f.key(dir)
+	rootKey := f.key("")
+	f.ch.mu.Lock()
+	defer f.ch.mu.Unlock()
+	if !f.ch.index.isDir(dirKey) {
+		return fs.ErrorDirNotFound
+	}
+	f.ch.index.walk(dirKey, recurse, func(key string, file *indexFile, modTime time.Time) {
+		remote := f.opt.Enc.ToStandardPath(strings.TrimPrefix(key[len(rootKey):], "/"))
+		if file == nil {
+			fn(fs.NewDir(remote, modTime))
+			return
+		}
+		fn(f.newObject(remote, file))
+	})
+	return nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.list(dir, false, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	var entries fs.DirEntries
+	err := f.list(dir, true, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return err
+	}
+	return callback(entries)
+}
+
+// newObject makes an object from file
+func (f *Fs) newObject(remote string, file *indexFile) *Object {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	o.setFile(file)
+	return o
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	key := f.key(remote)
+	f.ch.mu.Lock()
+	defer f.ch.mu.Unlock()
+	file, ok := f.ch.index.Files[key]
+	if !ok {
+		if f.ch.index.isDir(key) {
+			return nil, fs.ErrorIsDir
+		}
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.newObject(remote, file), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir creates the directory if it doesn't exist
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	key := f.key(dir)
+	err := f.ch.change(func(idx *index) (bool, error) {
+		if idx.isDir(key) {
+			return false, nil
+		}
+		if _, ok := idx.Files[key]; ok {
+			return false, fs.ErrorIsFile
+		}
+		now := time.Now()
+		idx.Dirs[key] = now
+		idx.addParents(key, now)
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return f.ch.save(ctx)
+}
+
+// Rmdir deletes the root folder
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	key := f.key(dir)
+	if key == "" {
+		return nil
+	}
+	err := f.ch.change(func(idx *index) (bool, error) {
+		if !idx.isDir(key) {
+			return false, fs.ErrorDirNotFound
+		}
+		if !idx.isEmpty(key) {
+			return false, fs.ErrorDirectoryNotEmpty
+		}
+		delete(idx.Dirs, key)
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return f.ch.save(ctx)
+}
+
+// Purge deletes all the files in the directory
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	key := f.key(dir)
+	var chunks []chunk
+	err := f.ch.change(func(idx *index) (bool, error) {
+		if !idx.isDir(key) {
+			return false, fs.ErrorDirNotFound
+		}
+		for fileKey, file := range idx.Files {
+			if inDir(fileKey, key) {
+				chunks = append(chunks, file.Chunks...)
+				delete(idx.Files, fileKey)
+			}
+		}
+		for dirKey := range idx.Dirs {
+			if inDir(dirKey, key) {
+				delete(idx.Dirs, dirKey)
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	err = f.ch.save(ctx)
+	if err != nil {
+		return err
+	}
+	f.ch.deleteChunks(ctx, chunks)
+	return nil
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.ch != f.ch {
+		fs.Debugf(src, "Can't copy - not same remote type")
+		return nil, fs.ErrorCantCopy
+	}
+	file := &indexFile{
+		Size:    srcObj.size,
+		ModTime: srcObj.modTime,
+		MD5:     srcObj.md5,
+	}
+	for _, c := range srcObj.chunks {
+		newChunk, err := f.ch.copyChunk(ctx, c)
+		if err != nil {
+			f.ch.deleteChunks(ctx, file.Chunks)
+			return nil, err
+		}
+		file.Chunks = append(file.Chunks, newChunk)
+	}
+	dstObj := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	err := dstObj.store(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	return dstObj, nil
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.fs.ch != f.ch {
+		fs.Debugf(src, "Can't move - not same remote type")
+		return nil, fs.ErrorCantMove
+	}
+	srcKey := srcObj.fs.key(srcObj.remote)
+	dstKey := f.key(remote)
+	var file *indexFile
+	var old []chunk
+	err := f.ch.change(func(idx *index) (bool, error) {
+		file = idx.Files[srcKey]
+		if file == nil {
+			return false, fs.ErrorObjectNotFound
+		}
+		if idx.isDir(dstKey) {
+			return false, fs.ErrorIsDir
+		}
+		if srcKey == dstKey {
+			return false, nil
+		}
+		if oldFile := idx.Files[dstKey]; oldFile != nil {
+			old = oldFile.Chunks
+		}
+		delete(idx.Files, srcKey)
+		idx.Files[dstKey] = file
+		idx.addParents(dstKey, time.Now())
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = f.ch.save(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.ch.deleteChunks(ctx, old)
+	return f.newObject(remote, file), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok || srcFs.ch != f.ch {
+		fs.Debugf(srcFs, "Can't move directory - not same remote type")
+		return fs.ErrorCantDirMove
+	}
+	srcKey := srcFs.key(srcRemote)
+	dstKey := f.key(dstRemote)
+	if srcKey == "" {
+		return fs.ErrorCantDirMove
+	}
+	err := f.ch.change(func(idx *index) (bool, error) {
+		if !idx.isDir(srcKey) {
+			return false, fs.ErrorDirNotFound
+		}
+		if _, ok := idx.Files[dstKey]; ok || idx.isDir(dstKey) {
+			return false, fs.ErrorDirExists
+		}
+		if inDir(dstKey, srcKey) {
+			return false, fs.ErrorCantDirMove
+		}
+		idx.rename(srcKey, dstKey)
+		idx.addParents(dstKey, time.Now())
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return f.ch.save(ctx)
+}
+
+// About gets quota information
+//
+// Telegram doesn't limit the space used so this just counts the files.
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	var used, objects int64
+	f.ch.mu.Lock()
+	for _, file := range f.ch.index.Files {
+		used += file.Size
+		objects++
+	}
+	f.ch.mu.Unlock()
+	return &fs.Usage{
+		Used:    fs.NewUsageValue(used),
+		Objects: fs.NewUsageValue(objects),
+	}, nil
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the MD5 of an object returning a lowercase hex string
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t != hash.MD5 {
+		return "", hash.ErrUnsupported
+	}
+	return o.md5, nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// setFile sets the metadata of the object from file
+func (o *Object) setFile(file *indexFile) {
+	o.size = file.Size
+	o.modTime = file.ModTime
+	o.md5 = file.MD5
+	o.chunks = append([]chunk(nil), file.Chunks...)
+}
+
+// SetModTime sets the modification time of the object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	key := o.fs.key(o.remote)
+	err := o.fs.ch.change(func(idx *index) (bool, error) {
+		file := idx.Files[key]
+		if file == nil {
+			return false, fs.ErrorObjectNotFound
+		}
+		file.ModTime = modTime
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	o.modTime = modTime
+	return o.fs.ch.save(ctx)
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(o.size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if limit < 0 || offset+limit > o.size {
+		limit = o.size - offset
+	}
+	return &chunkReader{
+		ctx:    ctx,
+		ch:     o.fs.ch,
+		chunks: o.chunks,
+		offset: offset,
+		limit:  limit,
+	}, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(hash.MD5))
+	if err != nil {
+		return err
+	}
+	in = io.TeeReader(in, hasher)
+	bufSize := int64(o.fs.opt.ChunkSize)
+	if size := src.Size(); size >= 0 && size < bufSize {
+		// one more than the size so the end is noticed
+		bufSize = size + 1
+	}
+	buf := make([]byte, bufSize)
+	leaf := documentName(path.Base(o.remote))
+	file := &indexFile{
+		ModTime: src.ModTime(ctx),
+	}
+	for {
+		n, err := io.ReadFull(in, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			o.fs.ch.deleteChunks(ctx, file.Chunks)
+			return err
+		}
+		name := fmt.Sprintf("%s.rclone_chunk.%03d", leaf, len(file.Chunks)+1)
+		c, err := o.fs.ch.sendChunk(ctx, name, buf[:n])
+		if err != nil {
+			o.fs.ch.deleteChunks(ctx, file.Chunks)
+			return err
+		}
+		file.Chunks = append(file.Chunks, c)
+		file.Size += int64(n)
+		if n < len(buf) {
+			break
+		}
+	}
+	file.MD5, err = hasher.SumString(hash.MD5, false)
+	if err != nil {
+		return err
+	}
+	return o.store(ctx, file)
+}
+
+// documentName makes name safe to use as the file name of a document
+//
+// The name of a chunk is only to help people looking at the chat, so
+// anything which might upset the multipart upload is replaced.
+func documentName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7F || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, strings.ToValidUTF8(name, "_"))
+}
+
+// store puts file in the index at the object's path and saves it
+//
+// The chunks of any file it replaces are deleted. If it can't be put
+// in the index the chunks of file are deleted.
+func (o *Object) store(ctx context.Context, file *indexFile) error {
+	key := o.fs.key(o.remote)
+	var old []chunk
+	err := o.fs.ch.change(func(idx *index) (bool, error) {
+		if idx.isDir(key) {
+			return false, fs.ErrorIsDir
+		}
+		if oldFile := idx.Files[key]; oldFile != nil {
+			old = oldFile.Chunks
+		}
+		idx.Files[key] = file
+		idx.addParents(key, time.Now())
+		return true, nil
+	})
+	if err != nil {
+		o.fs.ch.deleteChunks(ctx, file.Chunks)
+		return err
+	}
+	o.setFile(file)
+	err = o.fs.ch.save(ctx)
+	if err != nil {
+		return err
+	}
+	o.fs.ch.deleteChunks(ctx, old)
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	key := o.fs.key(o.remote)
+	var file *indexFile
+	err := o.fs.ch.change(func(idx *index) (bool, error) {
+		file = idx.Files[key]
+		if file == nil {
+			return false, fs.ErrorObjectNotFound
+		}
+		delete(idx.Files, key)
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	err = o.fs.ch.save(ctx)
+	if err != nil {
+		return err
+	}
+	o.fs.ch.deleteChunks(ctx, file.Chunks)
+	return nil
+}
+
+// ------------------------------------------------------------
+
+// chunkReader reads a range of a file from its chunks, opening each
+// one as it is needed
+type chunkReader struct {
+	ctx    context.Context
+	ch     *channel
+	chunks []chunk       // chunks left to read
+	offset int64         // offset to read from, relative to chunks[0]
+	limit  int64         // bytes left to read
+	in     io.ReadCloser // the chunk being read or nil
+}
+
+// Read bytes from the file
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	for r.in == nil {
+		if r.limit <= 0 || len(r.chunks) == 0 {
+			return 0, io.EOF
+		}
+		c := r.chunks[0]
+		if r.offset >= c.Size {
+			r.offset -= c.Size
+			r.chunks = r.chunks[1:]
+			continue
+		}
+		count := c.Size - r.offset
+		if count > r.limit {
+			count = r.limit
+		}
+		r.in, err = r.ch.open(r.ctx, c.FileID, r.offset, count)
+		if err != nil {
+			return 0, err
+		}
+		r.chunks = r.chunks[1:]
+		r.offset = 0
+	}
+	n, err = r.in.Read(p)
+	r.limit -= int64(n)
+	if err == io.EOF {
+		err = r.in.Close()
+		r.in = nil
+		if err == nil && n == 0 {
+			return r.Read(p)
+		}
+	}
+	return n, err
+}
+
+// Close the reader
+func (r *chunkReader) Close() error {
+	if r.in == nil {
+		return nil
+	}
+	err := r.in.Close()
+	r.in = nil
+	return err
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.Purger      = (*Fs)(nil)
+	_ fs.Copier      = (*Fs)(nil)
+	_ fs.Mover       = (*Fs)(nil)
+	_ fs.DirMover    = (*Fs)(nil)
+	_ fs.ListRer     = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.Abouter     = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+)
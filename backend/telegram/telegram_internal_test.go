@@ -0,0 +1,375 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/telegram/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testToken  = "123:secret"
+	testChatID = "@rclone"
+)
+
+// fakeMessage is a message in the fake chat
+type fakeMessage struct {
+	name   string
+	fileID string
+}
+
+// fakeServer is an in memory Bot API server with one chat and just
+// enough of the API for the backend
+type fakeServer struct {
+	mu       sync.Mutex
+	srv      *httptest.Server
+	nextID   int64
+	messages map[int64]*fakeMessage
+	files    map[string][]byte // file data by file ID
+	pinned   int64
+	calls    map[string]int // number of calls of each method
+}
+
+// newFakeServer starts a fake server
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{
+		messages: map[int64]*fakeMessage{},
+		files:    map[string][]byte{},
+		calls:    map[string]int{},
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+// reply sends result or an error if code isn't 200
+func reply(w http.ResponseWriter, code int, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if code != http.StatusOK {
+		_ = json.NewEncoder(w).Encode(api.Response{
+			ErrorCode:   code,
+			Description: fmt.Sprint(result),
+		})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     true,
+		"result": result,
+	})
+}
+
+// messageLocked returns message id as sent by the API
+func (s *fakeServer) messageLocked(id int64) *api.Message {
+	m := s.messages[id]
+	return &api.Message{
+		MessageID: id,
+		Date:      time.Now().Unix(),
+		Document: &api.Document{
+			FileID:   m.fileID,
+			FileName: m.name,
+			FileSize: int64(len(s.files[m.fileID])),
+		},
+	}
+}
+
+// addFileLocked stores data returning its new file ID
+func (s *fakeServer) addFileLocked(data []byte) string {
+	s.nextID++
+	fileID := "file" + strconv.FormatInt(s.nextID, 10)
+	s.files[fileID] = data
+	return fileID
+}
+
+// readFile reads the file part called name of the request
+func readFile(r *http.Request, name string) (fileName string, data []byte, err error) {
+	in, header, err := r.FormFile(name)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() { _ = in.Close() }()
+	data, err = io.ReadAll(in)
+	return header.Filename, data, err
+}
+
+func (s *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if strings.HasPrefix(r.URL.Path, "/file/bot"+testToken+"/") {
+		data, ok := s.files[strings.TrimPrefix(r.URL.Path, "/file/bot"+testToken+"/")]
+		if !ok {
+			reply(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+		return
+	}
+	method := strings.TrimPrefix(r.URL.Path, "/bot"+testToken+"/")
+	if method == r.URL.Path {
+		reply(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	s.calls[method]++
+	_ = r.ParseMultipartForm(1 << 30)
+	if method != "getFile" && r.FormValue("chat_id") != testChatID {
+		reply(w, http.StatusBadRequest, "Bad Request: chat not found")
+		return
+	}
+	messageID, _ := strconv.ParseInt(r.FormValue("message_id"), 10, 64)
+	switch method {
+	case "getChat":
+		chat := api.Chat{ID: -1001, Type: "channel", Username: "rclone"}
+		if s.pinned != 0 && s.messages[s.pinned] != nil {
+			chat.PinnedMessage = s.messageLocked(s.pinned)
+		}
+		reply(w, http.StatusOK, chat)
+	case "sendDocument":
+		fileID := r.FormValue("document")
+		name := ""
+		if fileID == "" {
+			var data []byte
+			var err error
+			name, data, err = readFile(r, "document")
+			if err != nil {
+				reply(w, http.StatusBadRequest, err)
+				return
+			}
+			fileID = s.addFileLocked(data)
+		} else if _, ok := s.files[fileID]; !ok {
+			reply(w, http.StatusBadRequest, "Bad Request: wrong file identifier")
+			return
+		}
+		s.nextID++
+		s.messages[s.nextID] = &fakeMessage{name: name, fileID: fileID}
+		reply(w, http.StatusOK, s.messageLocked(s.nextID))
+	case "editMessageMedia":
+		m := s.messages[messageID]
+		if m == nil {
+			reply(w, http.StatusBadRequest, "Bad Request: message to edit not found")
+			return
+		}
+		var media api.InputMediaDocument
+		if err := json.Unmarshal([]byte(r.FormValue("media")), &media); err != nil {
+			reply(w, http.StatusBadRequest, err)
+			return
+		}
+		name, data, err := readFile(r, strings.TrimPrefix(media.Media, "attach://"))
+		if err != nil {
+			reply(w, http.StatusBadRequest, err)
+			return
+		}
+		m.name, m.fileID = name, s.addFileLocked(data)
+		reply(w, http.StatusOK, s.messageLocked(messageID))
+	case "pinChatMessage":
+		if s.messages[messageID] == nil {
+			reply(w, http.StatusBadRequest, "Bad Request: message to pin not found")
+			return
+		}
+		s.pinned = messageID
+		reply(w, http.StatusOK, true)
+	case "deleteMessages":
+		var ids []int64
+		if err := json.Unmarshal([]byte(r.FormValue("message_ids")), &ids); err != nil {
+			reply(w, http.StatusBadRequest, err)
+			return
+		}
+		for _, id := range ids {
+			delete(s.messages, id)
+		}
+		reply(w, http.StatusOK, true)
+	case "getFile":
+		fileID := r.FormValue("file_id")
+		data, ok := s.files[fileID]
+		if !ok {
+			reply(w, http.StatusBadRequest, "Bad Request: invalid file_id")
+			return
+		}
+		reply(w, http.StatusOK, api.File{FileID: fileID, FileSize: int64(len(data)), FilePath: fileID})
+	default:
+		reply(w, http.StatusNotFound, "Not Found: method not found")
+	}
+}
+
+// forget makes the backend forget all the chats it has loaded
+func forget() {
+	channelsMu.Lock()
+	channels = map[string]*channel{}
+	channelsMu.Unlock()
+}
+
+// newFs makes an Fs for the fake server
+func newFs(t *testing.T, s *fakeServer, root string) (*Fs, error) {
+	f, err := fs.NewFs(context.Background(), fmt.Sprintf(":telegram,api_url='%s',bot_token='%s',chat_id='%s',chunk_size=100B:%s",
+		s.srv.URL, obscure.MustObscure(testToken), testChatID, root))
+	if f == nil {
+		return nil, err
+	}
+	return f.(*Fs), err
+}
+
+// TestFake runs the integration tests against the fake server
+func TestFake(t *testing.T) {
+	s := newFakeServer(t)
+	name := "TestTelegramFake"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "telegram"},
+			{Name: name, Key: "api_url", Value: s.srv.URL},
+			{Name: name, Key: "bot_token", Value: obscure.MustObscure(testToken)},
+			{Name: name, Key: "chat_id", Value: testChatID},
+			{Name: name, Key: "chunk_size", Value: "1000B"},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// put uploads contents to remote
+func put(t *testing.T, f fs.Fs, remote, contents string) fs.Object {
+	src := object.NewStaticObjectInfo(remote, time.Unix(1662808333, 0), int64(len(contents)), true, nil, nil)
+	o, err := f.Put(context.Background(), strings.NewReader(contents), src)
+	require.NoError(t, err)
+	return o
+}
+
+// read reads the contents of remote
+func read(t *testing.T, f fs.Fs, remote string, options ...fs.OpenOption) string {
+	o, err := f.NewObject(context.Background(), remote)
+	require.NoError(t, err)
+	in, err := o.Open(context.Background(), options...)
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+func TestChunks(t *testing.T) {
+	ctx := context.Background()
+	s := newFakeServer(t)
+	forget()
+	f, err := newFs(t, s, "")
+	require.NoError(t, err)
+
+	contents := strings.Repeat("0123456789", 25)
+	o := put(t, f, "dir/file.txt", contents)
+	assert.Equal(t, 3, len(o.(*Object).chunks))
+	assert.Equal(t, contents, read(t, f, "dir/file.txt"))
+	assert.Equal(t, contents[150:], read(t, f, "dir/file.txt", &fs.SeekOption{Offset: 150}))
+	assert.Equal(t, contents[95:205], read(t, f, "dir/file.txt", &fs.RangeOption{Start: 95, End: 204}))
+	assert.Equal(t, contents[240:], read(t, f, "dir/file.txt", &fs.RangeOption{Start: -1, End: 10}))
+
+	md5, err := o.Hash(ctx, hash.MD5)
+	require.NoError(t, err)
+	assert.Equal(t, "e34c45a67a321b62e5624eeb9a0b4005", md5)
+	_, err = o.Hash(ctx, hash.SHA1)
+	assert.Equal(t, hash.ErrUnsupported, err)
+
+	s.mu.Lock()
+	messages := len(s.messages)
+	s.mu.Unlock()
+	assert.Equal(t, 4, messages) // 3 chunks and the index
+
+	// Overwriting deletes the old chunks
+	put(t, f, "dir/file.txt", "small")
+	s.mu.Lock()
+	messages = len(s.messages)
+	s.mu.Unlock()
+	assert.Equal(t, 2, messages)
+
+	// Empty files have no chunks
+	o = put(t, f, "empty", "")
+	assert.Equal(t, 0, len(o.(*Object).chunks))
+	assert.Equal(t, "", read(t, f, "empty"))
+}
+
+func TestReload(t *testing.T) {
+	ctx := context.Background()
+	s := newFakeServer(t)
+	forget()
+	f, err := newFs(t, s, "")
+	require.NoError(t, err)
+	put(t, f, "a/b/file.txt", strings.Repeat("x", 150))
+	require.NoError(t, f.Mkdir(ctx, "empty"))
+
+	// a new Fs for the same chat shares the index
+	sub, err := newFs(t, s, "a")
+	require.NoError(t, err)
+	assert.Equal(t, f.ch, sub.ch)
+	_, err = newFs(t, s, "a/b/file.txt")
+	assert.Equal(t, fs.ErrorIsFile, err)
+
+	// read the index from the chat again
+	forget()
+	f, err = newFs(t, s, "")
+	require.NoError(t, err)
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, strings.Repeat("x", 150), read(t, f, "a/b/file.txt"))
+	o, err := f.NewObject(ctx, "a/b/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1662808333, 0), o.ModTime(ctx).Local())
+
+	// saves are edits of the same message
+	s.mu.Lock()
+	pinned := s.pinned
+	s.mu.Unlock()
+	require.NoError(t, f.Mkdir(ctx, "another"))
+	s.mu.Lock()
+	assert.Equal(t, pinned, s.pinned)
+	assert.Equal(t, 1, s.calls["pinChatMessage"])
+
+	// a new index is sent if the old one has gone
+	delete(s.messages, s.pinned)
+	s.mu.Unlock()
+	require.NoError(t, f.Mkdir(ctx, "yet another"))
+	s.mu.Lock()
+	assert.NotEqual(t, pinned, s.pinned)
+	assert.Equal(t, 2, s.calls["pinChatMessage"])
+
+	// pinned messages which aren't indexes are errors
+	s.nextID++
+	s.messages[s.nextID] = &fakeMessage{name: "hello.txt", fileID: s.addFileLocked([]byte("hello"))}
+	s.pinned = s.nextID
+	s.mu.Unlock()
+	forget()
+	_, err = newFs(t, s, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "isn't an rclone index")
+}
+
+func TestShouldRetry(t *testing.T) {
+	ctx := context.Background()
+	retry, err := shouldRetry(ctx, nil, &api.Response{
+		ErrorCode:   429,
+		Description: "Too Many Requests: retry after 5",
+		Parameters:  &api.ResponseParameters{RetryAfter: 5},
+	})
+	assert.True(t, retry)
+	retryAfter, ok := pacer.IsRetryAfter(err)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, retryAfter)
+
+	retry, _ = shouldRetry(ctx, &http.Response{StatusCode: 400}, &api.Response{ErrorCode: 400})
+	assert.False(t, retry)
+}
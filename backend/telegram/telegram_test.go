@@ -0,0 +1,17 @@
+// Test Telegram filesystem interface
+package telegram_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/telegram"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestTelegram:",
+		NilObject:  (*telegram.Object)(nil),
+	})
+}
@@ -0,0 +1,109 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/time/rate"
+)
+
+// minBurst is the smallest burst size of the limiters so a
+// reasonable amount can be read in each call
+const minBurst = 64 * 1024
+
+// limiter limits the upload and download bandwidth according to a
+// timetable
+type limiter struct {
+	mu        sync.Mutex
+	timetable fs.BwTimetable
+	now       func() time.Time // for testing
+	slot      fs.BwTimeSlot    // slot the limiters were made for
+	started   bool             // set if slot is valid
+	tx        *rate.Limiter    // upload limiter or nil for no limit
+	rx        *rate.Limiter    // download limiter or nil for no limit
+}
+
+// newLimiter makes a limiter for the timetable
+func newLimiter(timetable fs.BwTimetable) *limiter {
+	return &limiter{
+		timetable: timetable,
+		now:       time.Now,
+	}
+}
+
+// newRateLimiter makes an empty rate limiter for bandwidth, or
+// returns nil if it isn't limited
+func newRateLimiter(bandwidth fs.SizeSuffix) *rate.Limiter {
+	if bandwidth <= 0 {
+		return nil
+	}
+	burst := int(bandwidth)
+	if burst < minBurst {
+		burst = minBurst
+	}
+	tb := rate.NewLimiter(rate.Limit(bandwidth), burst)
+	// empty the bucket so the limit applies straight away
+	tb.AllowN(time.Now(), burst)
+	return tb
+}
+
+// get returns the limiter for uploads if tx is set or downloads
+// otherwise, or nil if there is no limit at the moment
+func (l *limiter) get(tx bool) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slot := l.timetable.LimitAt(l.now())
+	if !l.started || slot != l.slot {
+		if l.started {
+			fs.Debugf(nil, "throttle: bandwidth limit changed to %v", &slot.Bandwidth)
+		}
+		l.slot = slot
+		l.started = true
+		l.tx = newRateLimiter(slot.Bandwidth.Tx)
+		l.rx = newRateLimiter(slot.Bandwidth.Rx)
+	}
+	if tx {
+		return l.tx
+	}
+	return l.rx
+}
+
+// reader returns in wrapped so it is read no faster than the current
+// upload limit if tx is set or download limit otherwise
+func (l *limiter) reader(ctx context.Context, in io.Reader, tx bool) io.Reader {
+	return &limitedReader{
+		ctx: ctx,
+		in:  in,
+		l:   l,
+		tx:  tx,
+	}
+}
+
+// limitedReader is an io.Reader which is rate limited
+type limitedReader struct {
+	ctx context.Context
+	in  io.Reader
+	l   *limiter
+	tx  bool
+}
+
+// Read bytes from the wrapped reader, waiting for the limiter
+func (r *limitedReader) Read(p []byte) (n int, err error) {
+	tb := r.l.get(r.tx)
+	if tb == nil {
+		return r.in.Read(p)
+	}
+	if len(p) > tb.Burst() {
+		p = p[:tb.Burst()]
+	}
+	n, err = r.in.Read(p)
+	if n > 0 {
+		if waitErr := tb.WaitN(r.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
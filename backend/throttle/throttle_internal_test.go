@@ -0,0 +1,77 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs returns a throttle remote limited to bwlimit on an empty
+// directory
+func newTestFs(t *testing.T, bwlimit string) *Fs {
+	f, _ := fstest.NewWrappingFs(t, "throttle", fmt.Sprintf(`bwlimit="%s"`, bwlimit))
+	return f.(*Fs)
+}
+
+func TestLimitsTransfers(t *testing.T) {
+	ctx := context.Background()
+	// 256 KiB at 1 MiB/s up and 512 KiB/s down
+	f := newTestFs(t, "1M:512k")
+	contents := strings.Repeat("x", 256*1024)
+
+	start := time.Now()
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "file.bin", ModTime: time.Now()}, contents, false)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	start = time.Now()
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	got, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, string(got))
+	// the download bucket filled up a bit during the upload
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestUnlimited(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t, "off")
+	contents := strings.Repeat("x", 1024*1024)
+	start := time.Now()
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "file.bin", ModTime: time.Now()}, contents, false)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestSchedule(t *testing.T) {
+	var timetable fs.BwTimetable
+	require.NoError(t, timetable.Set("Mon-08:00,1M Mon-23:00,off"))
+	l := newLimiter(timetable)
+	now := time.Date(2022, 10, 3, 12, 0, 0, 0, time.Local) // a Monday
+	l.now = func() time.Time { return now }
+
+	tb := l.get(true)
+	require.NotNil(t, tb)
+	assert.Equal(t, float64(1024*1024), float64(tb.Limit()))
+	assert.Equal(t, tb, l.get(true), "limiter should be reused within a slot")
+
+	now = now.Add(12 * time.Hour)
+	assert.Nil(t, l.get(true))
+	assert.Nil(t, l.get(false))
+}
+
+func TestBadConfig(t *testing.T) {
+	ctx := context.Background()
+	_, err := fs.NewFs(ctx, `:throttle,remote="/tmp",bwlimit="potato":`)
+	assert.ErrorContains(t, err, "bad bwlimit")
+}
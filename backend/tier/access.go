@@ -0,0 +1,82 @@
+package tier
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// accessKey returns the database key for remote
+func (f *Fs) accessKey(remote string) string {
+	return path.Join(f.hot.Root(), remote)
+}
+
+// touch records that remote has just been accessed
+func (f *Fs) touch(remote string) {
+	if f.db == nil {
+		return
+	}
+	err := f.db.Do(true, &kvTouch{
+		key:  f.accessKey(remote),
+		when: time.Now(),
+	})
+	if err != nil {
+		fs.Debugf(remote, "Failed to record access time: %v", err)
+	}
+}
+
+// lastAccess returns when remote was last accessed or the zero time
+// if unknown
+func (f *Fs) lastAccess(remote string) time.Time {
+	if f.db == nil {
+		return time.Time{}
+	}
+	op := &kvAccessed{
+		key: f.accessKey(remote),
+	}
+	if err := f.db.Do(false, op); err != nil {
+		fs.Debugf(remote, "Failed to read access time: %v", err)
+		return time.Time{}
+	}
+	return op.when
+}
+
+// kvTouch: set the access time for a key
+type kvTouch struct {
+	key  string
+	when time.Time
+}
+
+func (op *kvTouch) Do(ctx context.Context, b kv.Bucket) error {
+	data, err := op.when.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(op.key), data)
+}
+
+// kvAccessed: read the access time for a key
+type kvAccessed struct {
+	key  string
+	when time.Time
+}
+
+func (op *kvAccessed) Do(ctx context.Context, b kv.Bucket) error {
+	data := b.Get([]byte(op.key))
+	if data == nil {
+		return nil
+	}
+	return op.when.UnmarshalBinary(data)
+}
+
+// kvForget: remove the access time for a key
+type kvForget struct {
+	key string
+}
+
+func (op *kvForget) Do(ctx context.Context, b kv.Bucket) error {
+	return b.Delete([]byte(op.key))
+}
@@ -0,0 +1,1102 @@
+// Package tier implements a backend which migrates old files to a
+// cheaper remote, leaving stubs behind
+package tier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "tier",
+		Description: "Migrate old files to a cheaper remote",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			Help: `Any metadata supported by the underlying remotes is read and written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to keep recently used files on (the hot tier).
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name:     "cold_remote",
+			Required: true,
+			Help: `Remote to migrate old files to (the cold tier).
+
+Files are stored here with the same path they had on the hot tier.`,
+		}, {
+			Name:    "min_age",
+			Default: fs.DurationOff,
+			Help: `Migrate files with a modification time older than this.
+
+Set to "off" to not migrate files based on their age.`,
+		}, {
+			Name:    "max_idle",
+			Default: fs.DurationOff,
+			Help: `Migrate files which have not been opened for this long.
+
+Access times are recorded in a local database in the cache directory
+while this is set. Files which have never been opened through the tier
+remote use their modification time instead.
+
+Set to "off" to not track access times.`,
+		}, {
+			Name:    "recall",
+			Default: true,
+			Help: `Copy migrated files back to the hot tier when they are opened.
+
+If this is false, migrated files are read directly from the cold tier.`,
+		}, {
+			Name:    "migrate_interval",
+			Default: fs.DurationOff,
+			Help: `How often to run migrations in the background.
+
+Set to "off" to only migrate when the "migrate" backend command is run.`,
+			Advanced: true,
+		}, {
+			Name:     "stub_suffix",
+			Default:  ".tierstub",
+			Help:     `Suffix for the stubs left on the hot tier for migrated files.`,
+			Advanced: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote          string      `config:"remote"`
+	ColdRemote      string      `config:"cold_remote"`
+	MinAge          fs.Duration `config:"min_age"`
+	MaxIdle         fs.Duration `config:"max_idle"`
+	Recall          bool        `config:"recall"`
+	MigrateInterval fs.Duration `config:"migrate_interval"`
+	StubSuffix      string      `config:"stub_suffix"`
+}
+
+// Fs represents a hot remote with a cold remote for migrated files
+type Fs struct {
+	name     string
+	root     string
+	opt      Options
+	features *fs.Features // optional features
+	hot      fs.Fs        // remote for recently used files
+	cold     fs.Fs        // remote for migrated files
+	wrapper  fs.Fs        // Fs wrapping this one, if any
+	hashes   hash.Set     // hashes supported by both tiers
+	db       *kv.DB       // access times, nil if not tracking
+	migrate  sync.Mutex   // held while migrating
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// stub is the content of the file left on the hot tier after migration
+type stub struct {
+	Version  int       `json:"ver"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modtime"`
+	Migrated time.Time `json:"migrated"`
+}
+
+const stubVersion = 1
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.StubSuffix == "" {
+		return nil, errors.New("stub_suffix can't be empty")
+	}
+	for _, remote := range []string{opt.Remote, opt.ColdRemote} {
+		if strings.HasPrefix(remote, name+":") {
+			return nil, errors.New("can't point tier remote at itself - check the value of the remote and cold_remote settings")
+		}
+	}
+	if opt.Remote == opt.ColdRemote {
+		return nil, errors.New("remote and cold_remote must be different")
+	}
+
+	f := &Fs{
+		name: name,
+		root: rpath,
+		opt:  opt,
+	}
+	isFile := false
+	f.hot, err = cache.Get(ctx, fspath.JoinRootPath(opt.Remote, rpath))
+	if err == fs.ErrorIsFile {
+		isFile = true
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to make remote %q: %w", opt.Remote, err)
+	} else if rpath != "" {
+		// A migrated file is represented by a stub on the hot tier
+		parent, err := cache.Get(ctx, fspath.JoinRootPath(opt.Remote, parentDir(rpath)))
+		if err == nil {
+			if _, err := parent.NewObject(ctx, path.Base(rpath)+opt.StubSuffix); err == nil {
+				isFile = true
+			}
+		}
+	}
+	if isFile {
+		f.root = parentDir(rpath)
+		f.hot, err = cache.Get(ctx, fspath.JoinRootPath(opt.Remote, f.root))
+		if err != nil {
+			return nil, fmt.Errorf("failed to make remote %q: %w", opt.Remote, err)
+		}
+	}
+	f.cold, err = cache.Get(ctx, fspath.JoinRootPath(opt.ColdRemote, f.root))
+	if err != nil && err != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make cold remote %q: %w", opt.ColdRemote, err)
+	}
+	cache.PinUntilFinalized(f.hot, f)
+
+	f.hashes = f.hot.Hashes().Overlap(f.cold.Hashes())
+
+	if opt.MaxIdle > 0 {
+		if !kv.Supported() {
+			return nil, errors.New("max_idle is not supported on this OS")
+		}
+		f.db, err = kv.Start(ctx, "tier", f.hot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open access time database: %w", err)
+		}
+	}
+
+	hotFeatures := f.hot.Features()
+	coldFeatures := f.cold.Features()
+	f.features = (&fs.Features{
+		CaseInsensitive:         hotFeatures.CaseInsensitive || coldFeatures.CaseInsensitive,
+		CanHaveEmptyDirectories: true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		SetTier:                 true,
+		GetTier:                 true,
+		SlowModTime:             hotFeatures.SlowModTime || coldFeatures.SlowModTime,
+		SlowHash:                hotFeatures.SlowHash || coldFeatures.SlowHash,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, f.hot).WrapsFs(f, f.hot)
+	// Always needed to stop background migrations and close the database
+	f.features.Shutdown = f.Shutdown
+
+	if opt.MigrateInterval > 0 {
+		f.stop = make(chan struct{})
+		f.wg.Add(1)
+		go f.migrateLoop(time.Duration(opt.MigrateInterval))
+	}
+
+	if isFile {
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// parentDir returns the parent directory of p or "" for the root
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." || dir == "/" {
+		dir = ""
+	}
+	return dir
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("tier root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	hot, cold := f.hot.Precision(), f.cold.Precision()
+	if hot == fs.ModTimeNotSupported || cold == fs.ModTimeNotSupported {
+		return fs.ModTimeNotSupported
+	}
+	if cold > hot {
+		return cold
+	}
+	return hot
+}
+
+// Hashes returns the hash types supported by both tiers
+func (f *Fs) Hashes() hash.Set {
+	return f.hashes
+}
+
+// isStub returns true if remote is the name of a stub
+func (f *Fs) isStub(remote string) bool {
+	return strings.HasSuffix(remote, f.opt.StubSuffix)
+}
+
+// stubName returns the name of the stub for remote
+func (f *Fs) stubName(remote string) string {
+	return remote + f.opt.StubSuffix
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	hotEntries, err := f.hot.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	hotObjects := map[string]struct{}{}
+	var stubs []string
+	for _, entry := range hotEntries {
+		switch x := entry.(type) {
+		case fs.Object:
+			if f.isStub(x.Remote()) {
+				stubs = append(stubs, strings.TrimSuffix(x.Remote(), f.opt.StubSuffix))
+				continue
+			}
+			hotObjects[x.Remote()] = struct{}{}
+			entries = append(entries, f.newObject(x, false))
+		default:
+			entries = append(entries, entry)
+		}
+	}
+	if len(stubs) == 0 {
+		return entries, nil
+	}
+	coldEntries, err := f.cold.List(ctx, dir)
+	if err != nil && err != fs.ErrorDirNotFound {
+		return nil, fmt.Errorf("failed to list cold tier: %w", err)
+	}
+	coldObjects := make(map[string]fs.Object, len(coldEntries))
+	for _, entry := range coldEntries {
+		if o, ok := entry.(fs.Object); ok {
+			coldObjects[o.Remote()] = o
+		}
+	}
+	for _, remote := range stubs {
+		if _, found := hotObjects[remote]; found {
+			// interrupted migration - the hot copy wins
+			continue
+		}
+		o, found := coldObjects[remote]
+		if !found {
+			fs.Logf(f, "Ignoring stub %q with no file on the cold tier", f.stubName(remote))
+			continue
+		}
+		entries = append(entries, f.newObject(o, true))
+	}
+	return entries, nil
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// Stubs on the hot tier have to be matched up with the files on the
+// cold tier so this reads the whole directory and returns it as a
+// single page.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	return fs.ListPFallback(ctx, f, dir, callback)
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if f.isStub(remote) {
+		return nil, fs.ErrorObjectNotFound
+	}
+	o, err := f.hot.NewObject(ctx, remote)
+	if err == nil {
+		return f.newObject(o, false), nil
+	}
+	if err != fs.ErrorObjectNotFound {
+		return nil, err
+	}
+	if _, err := f.hot.NewObject(ctx, f.stubName(remote)); err != nil {
+		return nil, err
+	}
+	o, err = f.cold.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o, true), nil
+}
+
+// removeCold removes the stub and the cold copy of remote if present
+func (f *Fs) removeCold(ctx context.Context, remote string) error {
+	stubObj, err := f.hot.NewObject(ctx, f.stubName(remote))
+	if err == fs.ErrorObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	coldObj, err := f.cold.NewObject(ctx, remote)
+	if err == nil {
+		err = coldObj.Remove(ctx)
+	}
+	if err != nil && err != fs.ErrorObjectNotFound {
+		return fmt.Errorf("failed to remove cold copy: %w", err)
+	}
+	return stubObj.Remove(ctx)
+}
+
+// removeHot removes the hot copy of remote if present so that a
+// migrated copy moved there isn't hidden by it
+func (f *Fs) removeHot(ctx context.Context, remote string) error {
+	hotObj, err := f.hot.NewObject(ctx, remote)
+	if err == fs.ErrorObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return hotObj.Remove(ctx)
+}
+
+// put uploads to the hot tier with the put function given then
+// removes any migrated copy
+func (f *Fs) put(ctx context.Context, in io.Reader, src fs.ObjectInfo, put func(context.Context, io.Reader, fs.ObjectInfo, ...fs.OpenOption) (fs.Object, error), options ...fs.OpenOption) (fs.Object, error) {
+	if f.isStub(src.Remote()) {
+		return nil, fmt.Errorf("can't upload files with the reserved suffix %q", f.opt.StubSuffix)
+	}
+	o, err := put(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.removeCold(ctx, src.Remote()); err != nil {
+		fs.Errorf(o, "Failed to remove migrated copy: %v", err)
+	}
+	return f.newObject(o, false), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, in, src, f.hot.Put, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.hot.Features().PutStream
+	if do == nil {
+		return nil, errors.New("can't PutStream")
+	}
+	return f.put(ctx, in, src, do, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.hot.Mkdir(ctx, dir)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	err := f.hot.Rmdir(ctx, dir)
+	if err != nil {
+		return err
+	}
+	// Tidy up the directory on the cold tier if it is empty
+	if err := f.cold.Rmdir(ctx, dir); err != nil {
+		fs.Debugf(f, "Failed to remove cold directory %q: %v", dir, err)
+	}
+	return nil
+}
+
+// Purge all files in the directory specified
+//
+// Implement this if you have a way of deleting all the files
+// quicker than just running Remove() on the result of List()
+//
+// Return an error if it doesn't exist
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	do := f.hot.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	if err := do(ctx, dir); err != nil {
+		return err
+	}
+	err := operations.Purge(ctx, f.cold, dir)
+	if err != nil && !errors.Is(err, fs.ErrorDirNotFound) {
+		return fmt.Errorf("failed to purge cold tier: %w", err)
+	}
+	return nil
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok || srcObj.cold {
+		return nil, fs.ErrorCantCopy
+	}
+	do := f.hot.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	o, err := do(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.removeCold(ctx, remote); err != nil {
+		fs.Errorf(o, "Failed to remove migrated copy: %v", err)
+	}
+	return f.newObject(o, false), nil
+}
+
+// Move src to this remote using server-side move operations.
+//
+// This is stored with the remote path given
+//
+// It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	hotMove := f.hot.Features().Move
+	if hotMove == nil {
+		return nil, fs.ErrorCantMove
+	}
+	if !srcObj.cold {
+		o, err := hotMove(ctx, srcObj.Object, remote)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.removeCold(ctx, remote); err != nil {
+			fs.Errorf(o, "Failed to remove migrated copy: %v", err)
+		}
+		return f.newObject(o, false), nil
+	}
+	// Migrated files need the cold copy and the stub moving
+	coldMove := f.cold.Features().Move
+	if coldMove == nil {
+		return nil, fs.ErrorCantMove
+	}
+	srcFs := srcObj.f
+	stubObj, err := srcFs.hot.NewObject(ctx, srcFs.stubName(srcObj.Remote()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stub: %w", err)
+	}
+	if err := f.removeHot(ctx, remote); err != nil {
+		return nil, fmt.Errorf("failed to remove existing file: %w", err)
+	}
+	o, err := coldMove(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := hotMove(ctx, stubObj, f.stubName(remote)); err != nil {
+		return nil, fmt.Errorf("failed to move stub: %w", err)
+	}
+	return f.newObject(o, true), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	hotDirMove := f.hot.Features().DirMove
+	coldDirMove := f.cold.Features().DirMove
+	if hotDirMove == nil || coldDirMove == nil {
+		return fs.ErrorCantDirMove
+	}
+	err := hotDirMove(ctx, srcFs.hot, srcRemote, dstRemote)
+	if err != nil {
+		return err
+	}
+	// Only move the cold directory if anything was migrated from it
+	if _, err := srcFs.cold.List(ctx, srcRemote); err == fs.ErrorDirNotFound {
+		return nil
+	}
+	if err := coldDirMove(ctx, srcFs.cold, srcRemote, dstRemote); err != nil {
+		return fmt.Errorf("failed to move cold directory: %w", err)
+	}
+	return nil
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.hot.Features().CleanUp
+	if do == nil {
+		return errors.New("can't CleanUp")
+	}
+	return do(ctx)
+}
+
+// About gets quota information from the hot tier
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.hot.Features().About
+	if do == nil {
+		return nil, errors.New("About not supported")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	do := f.hot.Features().ChangeNotify
+	if do == nil {
+		return
+	}
+	wrappedNotifyFunc := func(remote string, entryType fs.EntryType) {
+		if entryType == fs.EntryObject && f.isStub(remote) {
+			remote = strings.TrimSuffix(remote, f.opt.StubSuffix)
+		}
+		notifyFunc(remote, entryType)
+	}
+	do(ctx, wrappedNotifyFunc, pollIntervalChan)
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	for _, u := range []fs.Fs{f.hot, f.cold} {
+		if do := u.Features().DirCacheFlush; do != nil {
+			do()
+		}
+	}
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) (err error) {
+	if f.stop != nil {
+		close(f.stop)
+		f.wg.Wait()
+		f.stop = nil
+	}
+	if f.db != nil {
+		err = f.db.Stop(false)
+	}
+	for _, u := range []fs.Fs{f.hot, f.cold} {
+		if do := u.Features().Shutdown; do != nil {
+			if err2 := do(ctx); err2 != nil {
+				err = err2
+			}
+		}
+	}
+	return err
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.hot
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// Object describes a file on either tier
+type Object struct {
+	fs.Object      // the hot object, or the cold object if migrated
+	f         *Fs  // what this object is part of
+	cold      bool // set if the data is on the cold tier
+	mu        sync.Mutex
+}
+
+// newObject wraps o from the hot or cold tier
+func (f *Fs) newObject(o fs.Object, cold bool) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+		cold:   cold,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// String returns a description of the Object
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Migrated returns true if the data for this object is on the cold tier
+func (o *Object) Migrated() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.cold
+}
+
+// Open opens the file for read.  Call Close() on the returned io.ReadCloser
+//
+// Migrated files are recalled to the hot tier first if configured
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	o.mu.Lock()
+	if o.cold && o.f.opt.Recall {
+		if err := o.recall(ctx); err != nil {
+			fs.Errorf(o, "Failed to recall from cold tier, reading from there instead: %v", err)
+		}
+	}
+	inner := o.Object
+	o.mu.Unlock()
+	o.f.touch(o.Remote())
+	return inner.Open(ctx, options...)
+}
+
+// recall copies a migrated object back to the hot tier and removes
+// the stub and cold copy.
+//
+// Call with o.mu held
+func (o *Object) recall(ctx context.Context) error {
+	f := o.f
+	newObj, err := operations.Copy(ctx, f.hot, nil, o.Remote(), o.Object)
+	if err != nil {
+		return err
+	}
+	if newObj == nil {
+		// --dry-run
+		return nil
+	}
+	coldObj := o.Object
+	o.Object = newObj
+	o.cold = false
+	if err := f.removeCold(ctx, o.Remote()); err != nil {
+		fs.Errorf(o, "Failed to remove migrated copy after recall: %v", err)
+	}
+	fs.Infof(coldObj, "Recalled from cold tier")
+	return nil
+}
+
+// Update in to the object with the modTime given of the given size
+//
+// Migrated objects are uploaded to the hot tier
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.cold {
+		return o.Object.Update(ctx, in, src, options...)
+	}
+	newObj, err := o.f.put(ctx, in, src, o.f.hot.Put, options...)
+	if err != nil {
+		return err
+	}
+	o.Object = newObj.(*Object).Object
+	o.cold = false
+	return nil
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.f.db != nil {
+		_ = o.f.db.Do(true, &kvForget{key: o.f.accessKey(o.Remote())})
+	}
+	if !o.cold {
+		return o.Object.Remove(ctx)
+	}
+	return o.f.removeCold(ctx, o.Remote())
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	if do, ok := o.Object.(fs.IDer); ok {
+		return do.ID()
+	}
+	return ""
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	if do, ok := o.Object.(fs.MimeTyper); ok {
+		return do.MimeType(ctx)
+	}
+	return ""
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	if do, ok := o.Object.(fs.SetTierer); ok {
+		return do.SetTier(tier)
+	}
+	return errors.New("SetTier not supported")
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *Object) GetTier() string {
+	if do, ok := o.Object.(fs.GetTierer); ok {
+		return do.GetTier()
+	}
+	return ""
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// makeStub makes the content of the stub for o
+func makeStub(ctx context.Context, o fs.Object) ([]byte, error) {
+	return json.Marshal(&stub{
+		Version:  stubVersion,
+		Size:     o.Size(),
+		ModTime:  o.ModTime(ctx),
+		Migrated: time.Now(),
+	})
+}
+
+// migrateObject moves the data for o from the hot tier to the cold
+// tier and leaves a stub in its place.
+//
+// The stub is written after the data has been copied and the hot
+// copy is only removed after that, so an interrupted migration
+// leaves the file readable.
+func (f *Fs) migrateObject(ctx context.Context, o fs.Object) error {
+	remote := o.Remote()
+	if _, err := operations.Copy(ctx, f.cold, nil, remote, o); err != nil {
+		return fmt.Errorf("failed to copy to cold tier: %w", err)
+	}
+	data, err := makeStub(ctx, o)
+	if err != nil {
+		return err
+	}
+	info := object.NewStaticObjectInfo(f.stubName(remote), o.ModTime(ctx), int64(len(data)), true, nil, f.hot)
+	if _, err := f.hot.Put(ctx, bytes.NewReader(data), info); err != nil {
+		return fmt.Errorf("failed to write stub: %w", err)
+	}
+	if err := o.Remove(ctx); err != nil {
+		return fmt.Errorf("failed to remove hot copy: %w", err)
+	}
+	return nil
+}
+
+// shouldMigrate returns true if o is due to be moved to the cold tier
+func (f *Fs) shouldMigrate(ctx context.Context, o fs.Object, minAge, maxIdle time.Duration, now time.Time) bool {
+	modTime := o.ModTime(ctx)
+	if minAge > 0 && now.Sub(modTime) > minAge {
+		return true
+	}
+	if maxIdle > 0 {
+		lastAccess := f.lastAccess(o.Remote())
+		if lastAccess.IsZero() {
+			lastAccess = modTime
+		}
+		if now.Sub(lastAccess) > maxIdle {
+			return true
+		}
+	}
+	return false
+}
+
+// migrationStats summarises a migration run
+type migrationStats struct {
+	Checked  int   `json:"checked"`
+	Migrated int   `json:"migrated"`
+	Bytes    int64 `json:"bytes"`
+	Errors   int   `json:"errors"`
+}
+
+// migrateDir migrates all eligible files under dir
+func (f *Fs) migrateDir(ctx context.Context, dir string, minAge, maxIdle time.Duration) (stats migrationStats, err error) {
+	if minAge <= 0 && maxIdle <= 0 {
+		return stats, errors.New("no migration policy - set min_age or max_idle")
+	}
+	f.migrate.Lock()
+	defer f.migrate.Unlock()
+	now := time.Now()
+	var candidates []fs.Object
+	err = operations.ListFn(ctx, f.hot, func(o fs.Object) {
+		if f.isStub(o.Remote()) {
+			return
+		}
+		if dir != "" && !strings.HasPrefix(o.Remote(), dir+"/") {
+			return
+		}
+		stats.Checked++
+		if f.shouldMigrate(ctx, o, minAge, maxIdle, now) {
+			candidates = append(candidates, o)
+		}
+	})
+	if err != nil {
+		return stats, err
+	}
+	for _, o := range candidates {
+		if operations.SkipDestructive(ctx, o, "migrate to cold tier") {
+			continue
+		}
+		if err := f.migrateObject(ctx, o); err != nil {
+			fs.Errorf(o, "Migration failed: %v", err)
+			stats.Errors++
+			continue
+		}
+		fs.Infof(o, "Migrated to cold tier")
+		stats.Migrated++
+		stats.Bytes += o.Size()
+	}
+	if stats.Errors > 0 {
+		return stats, fmt.Errorf("failed to migrate %d files", stats.Errors)
+	}
+	return stats, nil
+}
+
+// migrateLoop runs migrations every interval until stopped
+func (f *Fs) migrateLoop(interval time.Duration) {
+	defer f.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			ctx := context.Background()
+			stats, err := f.migrateDir(ctx, "", time.Duration(f.opt.MinAge), time.Duration(f.opt.MaxIdle))
+			if err != nil {
+				fs.Errorf(f, "Background migration: %v", err)
+			}
+			fs.Debugf(f, "Background migration: migrated %d of %d files", stats.Migrated, stats.Checked)
+		}
+	}
+}
+
+// recallDir recalls all migrated files under dir
+func (f *Fs) recallDir(ctx context.Context, dir string) (stats migrationStats, err error) {
+	entries, err := f.List(ctx, dir)
+	if err != nil {
+		return stats, err
+	}
+	for _, entry := range entries {
+		switch x := entry.(type) {
+		case *Object:
+			stats.Checked++
+			if !x.Migrated() || operations.SkipDestructive(ctx, x, "recall from cold tier") {
+				continue
+			}
+			x.mu.Lock()
+			err := x.recall(ctx)
+			x.mu.Unlock()
+			if err != nil {
+				fs.Errorf(x, "Recall failed: %v", err)
+				stats.Errors++
+				continue
+			}
+			stats.Migrated++
+			stats.Bytes += x.Size()
+		case fs.Directory:
+			subStats, err := f.recallDir(ctx, x.Remote())
+			stats.Checked += subStats.Checked
+			stats.Migrated += subStats.Migrated
+			stats.Bytes += subStats.Bytes
+			stats.Errors += subStats.Errors
+			if err != nil && subStats.Errors == 0 {
+				return stats, err
+			}
+		}
+	}
+	if stats.Errors > 0 {
+		return stats, fmt.Errorf("failed to recall %d files", stats.Errors)
+	}
+	return stats, nil
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "migrate",
+	Short: "Migrate eligible files to the cold tier now.",
+	Long: `This runs a migration using the configured policy, moving the data for
+eligible files to the cold tier and leaving stubs in their place.
+
+Usage Examples:
+
+    rclone backend migrate tier:
+    rclone backend migrate tier: path/to/dir
+    rclone backend migrate tier: -o min-age=30d
+
+Filters are applied to the files considered for migration and
+--dry-run shows what would be migrated.
+`,
+	Opts: map[string]string{
+		"min-age":  "Override the min_age setting for this run",
+		"max-idle": "Override the max_idle setting for this run",
+	},
+}, {
+	Name:  "recall",
+	Short: "Copy migrated files back to the hot tier.",
+	Long: `This recalls all migrated files in the path given (or the whole remote)
+back to the hot tier, removing their stubs and cold copies.
+
+Usage Examples:
+
+    rclone backend recall tier:
+    rclone backend recall tier: path/to/dir
+`,
+}, {
+	Name:  "status",
+	Short: "Show where a file is stored.",
+	Long: `This shows which tier each of the files given is stored on.
+
+Usage Example:
+
+    rclone backend status tier: file1 dir/file2
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	dir := ""
+	if len(arg) > 0 {
+		dir = strings.Trim(arg[0], "/")
+	}
+	switch name {
+	case "migrate":
+		minAge, maxIdle := time.Duration(f.opt.MinAge), time.Duration(f.opt.MaxIdle)
+		if s, ok := opt["min-age"]; ok {
+			if minAge, err = fs.ParseDuration(s); err != nil {
+				return nil, fmt.Errorf("bad min-age: %w", err)
+			}
+		}
+		if s, ok := opt["max-idle"]; ok {
+			if f.db == nil {
+				return nil, errors.New("max-idle needs max_idle to be set in the config to track access times")
+			}
+			if maxIdle, err = fs.ParseDuration(s); err != nil {
+				return nil, fmt.Errorf("bad max-idle: %w", err)
+			}
+		}
+		return f.migrateDir(ctx, dir, minAge, maxIdle)
+	case "recall":
+		return f.recallDir(ctx, dir)
+	case "status":
+		status := make(map[string]string, len(arg))
+		for _, remote := range arg {
+			o, err := f.NewObject(ctx, remote)
+			switch {
+			case err != nil:
+				status[remote] = err.Error()
+			case o.(*Object).Migrated():
+				status[remote] = "cold"
+			default:
+				status[remote] = "hot"
+			}
+		}
+		return status, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.FullObject      = (*Object)(nil)
+)
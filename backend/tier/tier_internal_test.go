@@ -0,0 +1,136 @@
+package tier
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (f *Fs) testMigrateAndRecall(t *testing.T) {
+	ctx := context.Background()
+	const (
+		dir      = "migrate"
+		fileName = dir + "/old.txt"
+		contents = "some old data"
+	)
+	item := fstest.Item{Path: fileName, ModTime: fstest.Time("2001-02-03T04:05:06.499999999Z")}
+	_ = fstests.PutTestContents(ctx, t, f, &item, contents, true)
+	newItem := fstest.Item{Path: dir + "/new.txt", ModTime: time.Now()}
+	_ = fstests.PutTestContents(ctx, t, f, &newItem, "new data", true)
+	defer func() {
+		_ = operations.Purge(ctx, f, dir)
+	}()
+
+	stats, err := f.migrateDir(ctx, dir, 24*time.Hour, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Checked)
+	assert.Equal(t, 1, stats.Migrated)
+	assert.Equal(t, int64(len(contents)), stats.Bytes)
+
+	// data is on the cold tier with a stub on the hot tier
+	_, err = f.hot.NewObject(ctx, fileName)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	_, err = f.hot.NewObject(ctx, f.stubName(fileName))
+	require.NoError(t, err)
+	_, err = f.cold.NewObject(ctx, fileName)
+	require.NoError(t, err)
+
+	// the listing hides the stub and shows the real file
+	entries, err := f.List(ctx, dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	o, err := f.NewObject(ctx, fileName)
+	require.NoError(t, err)
+	assert.True(t, o.(*Object).Migrated())
+	assert.Equal(t, int64(len(contents)), o.Size())
+	fstest.AssertTimeEqualWithPrecision(t, fileName, item.ModTime, o.ModTime(ctx), f.Precision())
+
+	out, err := f.Command(ctx, "status", []string{fileName, newItem.Path}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{fileName: "cold", newItem.Path: "hot"}, out)
+
+	// opening recalls the file to the hot tier
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, contents, string(data))
+	assert.False(t, o.(*Object).Migrated())
+	_, err = f.hot.NewObject(ctx, fileName)
+	assert.NoError(t, err)
+	_, err = f.hot.NewObject(ctx, f.stubName(fileName))
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	_, err = f.cold.NewObject(ctx, fileName)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// migrate again then remove the file while migrated
+	_, err = f.migrateDir(ctx, dir, 24*time.Hour, 0)
+	require.NoError(t, err)
+	o, err = f.NewObject(ctx, fileName)
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	_, err = f.NewObject(ctx, fileName)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	_, err = f.cold.NewObject(ctx, fileName)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func (f *Fs) testMoveMigratedOverHot(t *testing.T) {
+	ctx := context.Background()
+	const (
+		dir      = "moveover"
+		srcName  = dir + "/src.txt"
+		dstName  = dir + "/dst.txt"
+		contents = "migrated data"
+	)
+	item := fstest.Item{Path: srcName, ModTime: fstest.Time("2001-02-03T04:05:06.499999999Z")}
+	_ = fstests.PutTestContents(ctx, t, f, &item, contents, true)
+	dstItem := fstest.Item{Path: dstName, ModTime: time.Now()}
+	_ = fstests.PutTestContents(ctx, t, f, &dstItem, "hot data which is longer", true)
+	defer func() {
+		_ = operations.Purge(ctx, f, dir)
+	}()
+	_, err := f.migrateDir(ctx, dir, 24*time.Hour, 0)
+	require.NoError(t, err)
+
+	src, err := f.NewObject(ctx, srcName)
+	require.NoError(t, err)
+	require.True(t, src.(*Object).Migrated())
+	o, err := f.Move(ctx, src, dstName)
+	require.NoError(t, err)
+	assert.True(t, o.(*Object).Migrated())
+
+	// the hot copy which was there has gone so the moved file is seen
+	_, err = f.hot.NewObject(ctx, dstName)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	o, err = f.NewObject(ctx, dstName)
+	require.NoError(t, err)
+	assert.True(t, o.(*Object).Migrated())
+	assert.Equal(t, int64(len(contents)), o.Size())
+	_, err = f.NewObject(ctx, srcName)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func (f *Fs) testReservedSuffix(t *testing.T) {
+	ctx := context.Background()
+	_, err := f.NewObject(ctx, "file"+f.opt.StubSuffix)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+// InternalTest dispatches all internal tests
+func (f *Fs) InternalTest(t *testing.T) {
+	t.Run("MigrateAndRecall", f.testMigrateAndRecall)
+	t.Run("MoveMigratedOverHot", f.testMoveMigratedOverHot)
+	t.Run("ReservedSuffix", f.testReservedSuffix)
+}
+
+var _ fstests.InternalTester = (*Fs)(nil)
@@ -0,0 +1,44 @@
+// Test Tier filesystem interface
+package tier_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/backend/tier"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	opt := fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*tier.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"MergeDirs",
+			"PublicLink",
+			"PutUnchecked",
+			"ListR",
+			"UserInfo",
+			"Disconnect",
+		},
+		UnimplementableObjectMethods: []string{},
+	}
+	if *fstest.RemoteName == "" {
+		tempDir := filepath.Join(os.TempDir(), "rclone-tier-test")
+		name := "TestTier"
+		opt.RemoteName = name + ":"
+		opt.ExtraConfig = []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "tier"},
+			{Name: name, Key: "remote", Value: filepath.Join(tempDir, "hot")},
+			{Name: name, Key: "cold_remote", Value: filepath.Join(tempDir, "cold")},
+			{Name: name, Key: "min_age", Value: "1000d"},
+		}
+		opt.QuickTestOK = true
+	}
+	fstests.Run(t, &opt)
+}
@@ -0,0 +1,216 @@
+package torrent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Bencoding is the serialisation used by .torrent files, trackers
+// and the extension protocol. It has integers, byte strings, lists
+// and dictionaries with byte string keys.
+//
+// Decoded values are int64, string, []interface{} and
+// map[string]interface{}.
+
+var errBadBencode = errors.New("bad bencoded data")
+
+// bdecode decodes the single value in data
+func bdecode(data []byte) (interface{}, error) {
+	v, n, err := bdecodePrefix(data)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(data) {
+		return nil, fmt.Errorf("%w: %d bytes of trailing data", errBadBencode, len(data)-n)
+	}
+	return v, nil
+}
+
+// bdecodePrefix decodes the value at the start of data returning it
+// and the number of bytes it used
+func bdecodePrefix(data []byte) (v interface{}, n int, err error) {
+	d := decoder{data: data}
+	v, err = d.value(0)
+	return v, d.pos, err
+}
+
+// bdecodeDictRaw decodes the dictionary in data returning the
+// undecoded bytes of each value
+//
+// This is used to find the exact bytes of the info dictionary of a
+// .torrent file, which its info hash is calculated from.
+func bdecodeDictRaw(data []byte) (map[string][]byte, error) {
+	d := decoder{data: data}
+	if !d.consume('d') {
+		return nil, fmt.Errorf("%w: expecting a dictionary", errBadBencode)
+	}
+	out := map[string][]byte{}
+	for !d.consume('e') {
+		key, err := d.str()
+		if err != nil {
+			return nil, err
+		}
+		start := d.pos
+		if _, err = d.value(1); err != nil {
+			return nil, err
+		}
+		out[key] = data[start:d.pos]
+	}
+	return out, nil
+}
+
+// maxDepth limits the nesting of lists and dictionaries
+const maxDepth = 64
+
+// decoder reads bencoded values from data
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+// consume skips c if it is next returning whether it was
+func (d *decoder) consume(c byte) bool {
+	if d.pos < len(d.data) && d.data[d.pos] == c {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+// until returns the bytes up to the next c and skips past it
+func (d *decoder) until(c byte) ([]byte, error) {
+	i := bytes.IndexByte(d.data[d.pos:], c)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: unterminated value at offset %d", errBadBencode, d.pos)
+	}
+	out := d.data[d.pos : d.pos+i]
+	d.pos += i + 1
+	return out, nil
+}
+
+// str reads a byte string
+func (d *decoder) str() (string, error) {
+	digits, err := d.until(':')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(string(digits))
+	if err != nil || n < 0 || n > len(d.data)-d.pos {
+		return "", fmt.Errorf("%w: bad string length at offset %d", errBadBencode, d.pos)
+	}
+	s := string(d.data[d.pos : d.pos+n])
+	d.pos += n
+	return s, nil
+}
+
+// value reads any value
+func (d *decoder) value(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", errBadBencode)
+	}
+	if d.pos >= len(d.data) {
+		return nil, fmt.Errorf("%w: unexpected end of data", errBadBencode)
+	}
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		d.pos++
+		digits, err := d.until('e')
+		if err != nil {
+			return nil, err
+		}
+		i, err := strconv.ParseInt(string(digits), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad integer %q", errBadBencode, digits)
+		}
+		return i, nil
+	case c == 'l':
+		d.pos++
+		list := []interface{}{}
+		for !d.consume('e') {
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case c == 'd':
+		d.pos++
+		dict := map[string]interface{}{}
+		for !d.consume('e') {
+			key, err := d.str()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = v
+		}
+		return dict, nil
+	case c >= '0' && c <= '9':
+		return d.str()
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q at offset %d", errBadBencode, c, d.pos)
+	}
+}
+
+// bencode encodes v which may be made of the decoded types and int
+// and []byte
+func bencode(v interface{}) []byte {
+	var buf bytes.Buffer
+	bencodeTo(&buf, v)
+	return buf.Bytes()
+}
+
+// bencodeTo writes v to buf
+func bencodeTo(buf *bytes.Buffer, v interface{}) {
+	switch x := v.(type) {
+	case int:
+		fmt.Fprintf(buf, "i%de", x)
+	case int64:
+		fmt.Fprintf(buf, "i%de", x)
+	case string:
+		fmt.Fprintf(buf, "%d:%s", len(x), x)
+	case []byte:
+		fmt.Fprintf(buf, "%d:", len(x))
+		buf.Write(x)
+	case []interface{}:
+		buf.WriteByte('l')
+		for _, item := range x {
+			bencodeTo(buf, item)
+		}
+		buf.WriteByte('e')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for key := range x {
+			keys = append(keys, key)
+		}
+		// keys must be sorted as raw strings
+		sort.Strings(keys)
+		buf.WriteByte('d')
+		for _, key := range keys {
+			bencodeTo(buf, key)
+			bencodeTo(buf, x[key])
+		}
+		buf.WriteByte('e')
+	default:
+		panic(fmt.Sprintf("can't bencode %T", v))
+	}
+}
+
+// dictString returns the string at key in d
+func dictString(d map[string]interface{}, key string) (string, bool) {
+	s, ok := d[key].(string)
+	return s, ok
+}
+
+// dictInt returns the integer at key in d
+func dictInt(d map[string]interface{}, key string) (int64, bool) {
+	i, ok := d[key].(int64)
+	return i, ok
+}
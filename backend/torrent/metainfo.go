@@ -0,0 +1,251 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// hashSize is the size of SHA-1 hashes used for info hashes and
+// pieces
+const hashSize = sha1.Size
+
+// infoHash identifies a torrent - it is the SHA-1 of its info
+// dictionary
+type infoHash [hashSize]byte
+
+// String returns the hash in hex as shown by most clients
+func (h infoHash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// fileEntry is a file in a torrent
+type fileEntry struct {
+	path   string // path of the file starting with the torrent name
+	offset int64  // offset of the file in the torrent's data
+	length int64  // size of the file
+}
+
+// metaInfo is the parsed info dictionary of a torrent
+type metaInfo struct {
+	hash        infoHash
+	name        string      // name of the file or top level directory
+	isDir       bool        // set if the torrent has a files list
+	pieceLength int64       // size of each piece except maybe the last
+	pieces      []byte      // SHA-1 of each piece, concatenated
+	length      int64       // total size of the data
+	files       []fileEntry // files in the torrent not including padding
+}
+
+// numPieces returns the number of pieces in the torrent
+func (m *metaInfo) numPieces() int {
+	return len(m.pieces) / hashSize
+}
+
+// pieceSize returns the size of piece i
+func (m *metaInfo) pieceSize(i int) int64 {
+	if i == m.numPieces()-1 {
+		return m.length - int64(i)*m.pieceLength
+	}
+	return m.pieceLength
+}
+
+// pieceHash returns the expected SHA-1 of piece i
+func (m *metaInfo) pieceHash(i int) []byte {
+	return m.pieces[i*hashSize : (i+1)*hashSize]
+}
+
+// cleanName makes a name from a torrent safe to use as a path element
+func cleanName(s string) string {
+	s = strings.ToValidUTF8(s, "�")
+	switch s {
+	case "":
+		return "_"
+	case ".":
+		return "．"
+	case "..":
+		return "．．"
+	}
+	return strings.ReplaceAll(s, "/", "／")
+}
+
+// preferUTF8 returns the key.utf-8 entry in d if present as some
+// clients put names in another encoding in key
+func preferUTF8(d map[string]interface{}, key string) interface{} {
+	if v, ok := d[key+".utf-8"]; ok {
+		return v
+	}
+	return d[key]
+}
+
+// parseInfo parses the bencoded info dictionary of a torrent
+func parseInfo(data []byte) (*metaInfo, error) {
+	v, err := bdecode(data)
+	if err != nil {
+		return nil, err
+	}
+	info, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("info isn't a dictionary")
+	}
+	m := &metaInfo{
+		hash: sha1.Sum(data),
+	}
+	name, _ := preferUTF8(info, "name").(string)
+	m.name = cleanName(name)
+	m.pieceLength, _ = dictInt(info, "piece length")
+	if m.pieceLength <= 0 {
+		return nil, errors.New("bad piece length")
+	}
+	pieces, _ := dictString(info, "pieces")
+	if len(pieces) == 0 || len(pieces)%hashSize != 0 {
+		return nil, errors.New("bad pieces")
+	}
+	m.pieces = []byte(pieces)
+	if length, ok := dictInt(info, "length"); ok {
+		if length < 0 {
+			return nil, errors.New("bad length")
+		}
+		m.length = length
+		m.files = []fileEntry{{path: m.name, length: length}}
+	} else {
+		files, ok := info["files"].([]interface{})
+		if !ok {
+			return nil, errors.New("info has neither length nor files")
+		}
+		m.isDir = true
+		for _, item := range files {
+			file, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("bad file in files")
+			}
+			length, ok := dictInt(file, "length")
+			if !ok || length < 0 {
+				return nil, errors.New("bad file length")
+			}
+			elements, ok := preferUTF8(file, "path").([]interface{})
+			if !ok || len(elements) == 0 {
+				return nil, errors.New("bad file path")
+			}
+			p := m.name
+			for _, element := range elements {
+				s, _ := element.(string)
+				p = path.Join(p, cleanName(s))
+			}
+			// padding files (BEP 47) take up space in the data but
+			// aren't shown
+			if attr, _ := dictString(file, "attr"); !strings.Contains(attr, "p") {
+				m.files = append(m.files, fileEntry{path: p, offset: m.length, length: length})
+			}
+			m.length += length
+		}
+	}
+	wantPieces := (m.length + m.pieceLength - 1) / m.pieceLength
+	if int64(m.numPieces()) != wantPieces {
+		return nil, fmt.Errorf("torrent has %d pieces but needs %d for its size", m.numPieces(), wantPieces)
+	}
+	return m, nil
+}
+
+// torrentFile is a parsed .torrent file
+type torrentFile struct {
+	info     *metaInfo
+	trackers []string
+	created  time.Time
+}
+
+// parseTorrentFile parses the contents of a .torrent file
+func parseTorrentFile(data []byte) (*torrentFile, error) {
+	raw, err := bdecodeDictRaw(data)
+	if err != nil {
+		return nil, err
+	}
+	infoData, ok := raw["info"]
+	if !ok {
+		return nil, errors.New("no info dictionary")
+	}
+	t := &torrentFile{}
+	t.info, err = parseInfo(infoData)
+	if err != nil {
+		return nil, fmt.Errorf("bad info dictionary: %w", err)
+	}
+	if announce, ok := raw["announce"]; ok {
+		if v, err := bdecode(announce); err == nil {
+			if s, ok := v.(string); ok && s != "" {
+				t.trackers = append(t.trackers, s)
+			}
+		}
+	}
+	if announceList, ok := raw["announce-list"]; ok {
+		if v, err := bdecode(announceList); err == nil {
+			tiers, _ := v.([]interface{})
+			for _, tier := range tiers {
+				urls, _ := tier.([]interface{})
+				for _, u := range urls {
+					if s, ok := u.(string); ok && s != "" {
+						t.trackers = append(t.trackers, s)
+					}
+				}
+			}
+		}
+	}
+	if created, ok := raw["creation date"]; ok {
+		if v, err := bdecode(created); err == nil {
+			if secs, ok := v.(int64); ok && secs > 0 {
+				t.created = time.Unix(secs, 0)
+			}
+		}
+	}
+	return t, nil
+}
+
+// magnet is a parsed magnet link
+type magnet struct {
+	hash     infoHash
+	name     string
+	trackers []string
+}
+
+// parseMagnet parses a magnet link with a BitTorrent info hash
+func parseMagnet(uri string) (*magnet, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "magnet" {
+		return nil, errors.New("not a magnet link")
+	}
+	q := u.Query()
+	m := &magnet{
+		name:     q.Get("dn"),
+		trackers: q["tr"],
+	}
+	for _, xt := range q["xt"] {
+		const prefix = "urn:btih:"
+		if !strings.HasPrefix(xt, prefix) {
+			continue
+		}
+		h := xt[len(prefix):]
+		var b []byte
+		switch len(h) {
+		case 2 * hashSize:
+			b, err = hex.DecodeString(h)
+		case 32:
+			b, err = base32.StdEncoding.DecodeString(strings.ToUpper(h))
+		default:
+			err = errors.New("wrong length")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad info hash %q in magnet link: %w", h, err)
+		}
+		copy(m.hash[:], b)
+		return m, nil
+	}
+	return nil, errors.New("magnet link has no BitTorrent info hash (xt=urn:btih:...)")
+}
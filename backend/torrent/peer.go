@@ -0,0 +1,371 @@
+package torrent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/rclone/rclone/fs/fshttp"
+)
+
+// Peer wire protocol (BEP 3) message IDs
+const (
+	msgChoke         = 0
+	msgUnchoke       = 1
+	msgInterested    = 2
+	msgNotInterested = 3
+	msgHave          = 4
+	msgBitfield      = 5
+	msgRequest       = 6
+	msgPiece         = 7
+	msgCancel        = 8
+	msgExtended      = 20 // BEP 10
+)
+
+const (
+	protocolName     = "BitTorrent protocol"
+	handshakeLen     = 1 + len(protocolName) + 8 + 2*hashSize
+	blockSize        = 16 * 1024 // size of each request
+	maxOutstanding   = 32        // number of requests pipelined to a peer
+	maxMessageLen    = 4 << 20   // longest message accepted
+	maxMetadataSize  = 16 << 20  // biggest info dictionary accepted
+	handshakeTimeout = 20 * time.Second
+	readTimeout      = 30 * time.Second
+	extHandshakeID   = 0 // extended message ID of the handshake
+	utMetadataID     = 1 // extended message ID we use for ut_metadata (BEP 9)
+	keepAlive        = -1
+)
+
+var (
+	errPeerLacksPiece = errors.New("peer doesn't have the piece")
+	errNoMetadata     = errors.New("peer can't send the metadata")
+)
+
+// peerConn is a connection to a peer
+//
+// It isn't safe for concurrent use - the swarm hands it to one piece
+// download at a time.
+type peerConn struct {
+	addr          string
+	conn          net.Conn
+	r             *bufio.Reader
+	hash          infoHash
+	supportsExt   bool   // peer supports the extension protocol
+	extDone       bool   // peer's extension handshake has arrived
+	metadataID    int64  // peer's ID for ut_metadata or 0
+	metadataSize  int64  // size of the info dictionary from the peer
+	choked        bool   // the peer is choking us
+	bitfield      []byte // pieces the peer has
+	bitfieldKnown bool   // set once the peer has told us what it has
+	noMetadata    bool   // set if the peer can't send the metadata
+}
+
+// dialPeer connects to the peer at addr and does the handshakes
+func dialPeer(ctx context.Context, addr string, hash infoHash, peerID [20]byte) (p *peerConn, err error) {
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+	conn, err := fshttp.NewDialer(ctx).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	p = &peerConn{
+		addr:   addr,
+		conn:   conn,
+		r:      bufio.NewReaderSize(conn, 64*1024),
+		hash:   hash,
+		choked: true,
+	}
+	defer func() {
+		if err != nil {
+			p.close()
+		}
+	}()
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	handshake := make([]byte, 0, handshakeLen)
+	handshake = append(handshake, byte(len(protocolName)))
+	handshake = append(handshake, protocolName...)
+	reserved := [8]byte{}
+	reserved[5] |= 0x10 // extension protocol
+	handshake = append(handshake, reserved[:]...)
+	handshake = append(handshake, hash[:]...)
+	handshake = append(handshake, peerID[:]...)
+	if _, err = conn.Write(handshake); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, handshakeLen)
+	if _, err = io.ReadFull(p.r, reply); err != nil {
+		return nil, fmt.Errorf("handshake: %w", err)
+	}
+	if int(reply[0]) != len(protocolName) || string(reply[1:1+len(protocolName)]) != protocolName {
+		return nil, errors.New("handshake: not a BitTorrent peer")
+	}
+	offset := 1 + len(protocolName)
+	if !bytes.Equal(reply[offset+8:offset+8+hashSize], hash[:]) {
+		return nil, errors.New("handshake: wrong info hash")
+	}
+	p.supportsExt = reply[offset+5]&0x10 != 0
+	if p.supportsExt {
+		ext := bencode(map[string]interface{}{
+			"m": map[string]interface{}{"ut_metadata": utMetadataID},
+			"v": "rclone",
+		})
+		if err = p.writeMessage(msgExtended, append([]byte{extHandshakeID}, ext...)); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.writeMessage(msgInterested, nil); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// close closes the connection
+func (p *peerConn) close() {
+	_ = p.conn.Close()
+}
+
+// writeMessage sends a message with id and payload
+func (p *peerConn) writeMessage(id byte, payload []byte) error {
+	buf := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(1+len(payload)))
+	buf[4] = id
+	copy(buf[5:], payload)
+	_ = p.conn.SetWriteDeadline(time.Now().Add(readTimeout))
+	_, err := p.conn.Write(buf)
+	return err
+}
+
+// readMessage reads the next message returning its id and payload
+func (p *peerConn) readMessage() (id int, payload []byte, err error) {
+	_ = p.conn.SetReadDeadline(time.Now().Add(readTimeout))
+	var length [4]byte
+	if _, err = io.ReadFull(p.r, length[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n == 0 {
+		return keepAlive, nil, nil
+	}
+	if n > maxMessageLen {
+		return 0, nil, fmt.Errorf("message too long (%d bytes)", n)
+	}
+	buf := make([]byte, n)
+	if _, err = io.ReadFull(p.r, buf); err != nil {
+		return 0, nil, err
+	}
+	return int(buf[0]), buf[1:], nil
+}
+
+// has returns whether the peer has piece i
+func (p *peerConn) has(i int) bool {
+	return i/8 < len(p.bitfield) && p.bitfield[i/8]&(0x80>>(i%8)) != 0
+}
+
+// mayHave returns whether the peer might have piece i
+func (p *peerConn) mayHave(i int) bool {
+	return !p.bitfieldKnown || p.has(i)
+}
+
+// handle updates the state of the connection from a message
+func (p *peerConn) handle(id int, payload []byte) error {
+	switch id {
+	case msgChoke:
+		p.choked = true
+	case msgUnchoke:
+		p.choked = false
+		// a peer which hasn't said what it has by now has nothing
+		p.bitfieldKnown = true
+	case msgHave:
+		if len(payload) != 4 {
+			return errors.New("bad have message")
+		}
+		i := int(binary.BigEndian.Uint32(payload))
+		for len(p.bitfield) <= i/8 {
+			p.bitfield = append(p.bitfield, 0)
+		}
+		p.bitfield[i/8] |= 0x80 >> (i % 8)
+	case msgBitfield:
+		p.bitfield = append([]byte(nil), payload...)
+		p.bitfieldKnown = true
+	case msgExtended:
+		if len(payload) == 0 {
+			return errors.New("bad extended message")
+		}
+		if payload[0] == extHandshakeID {
+			v, err := bdecode(payload[1:])
+			if err != nil {
+				return fmt.Errorf("bad extension handshake: %w", err)
+			}
+			d, _ := v.(map[string]interface{})
+			m, _ := d["m"].(map[string]interface{})
+			p.metadataID, _ = dictInt(m, "ut_metadata")
+			p.metadataSize, _ = dictInt(d, "metadata_size")
+			p.extDone = true
+		}
+	}
+	return nil
+}
+
+// waitFor reads and handles messages until done returns true
+func (p *peerConn) waitFor(done func() bool) error {
+	for !done() {
+		id, payload, err := p.readMessage()
+		if err != nil {
+			return err
+		}
+		if err = p.handle(id, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// request asks for a block of piece
+func (p *peerConn) request(piece, begin, length int) error {
+	var payload [12]byte
+	binary.BigEndian.PutUint32(payload[0:], uint32(piece))
+	binary.BigEndian.PutUint32(payload[4:], uint32(begin))
+	binary.BigEndian.PutUint32(payload[8:], uint32(length))
+	return p.writeMessage(msgRequest, payload[:])
+}
+
+// downloadPiece downloads piece index of size bytes
+//
+// The data isn't checked against the piece hash.
+func (p *peerConn) downloadPiece(index int, size int64) ([]byte, error) {
+	if p.bitfieldKnown && !p.has(index) {
+		return nil, errPeerLacksPiece
+	}
+	if err := p.waitFor(func() bool { return !p.choked }); err != nil {
+		return nil, err
+	}
+	if !p.has(index) {
+		return nil, errPeerLacksPiece
+	}
+	buf := make([]byte, size)
+	nBlocks := int((size + blockSize - 1) / blockSize)
+	received := make([]bool, nBlocks)
+	requested := make([]bool, nBlocks)
+	nReceived, outstanding, next := 0, 0, 0
+	for nReceived < nBlocks {
+		for !p.choked && outstanding < maxOutstanding && next < nBlocks {
+			if !received[next] && !requested[next] {
+				begin := next * blockSize
+				length := blockSize
+				if int64(begin+length) > size {
+					length = int(size) - begin
+				}
+				if err := p.request(index, begin, length); err != nil {
+					return nil, err
+				}
+				requested[next] = true
+				outstanding++
+			}
+			next++
+		}
+		id, payload, err := p.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		switch id {
+		case msgPiece:
+			if len(payload) < 8 {
+				return nil, errors.New("bad piece message")
+			}
+			pieceIndex := int(binary.BigEndian.Uint32(payload[0:]))
+			begin := int64(binary.BigEndian.Uint32(payload[4:]))
+			block := payload[8:]
+			if pieceIndex != index || begin%blockSize != 0 || begin+int64(len(block)) > size {
+				continue
+			}
+			i := int(begin / blockSize)
+			if received[i] {
+				continue
+			}
+			copy(buf[begin:], block)
+			received[i] = true
+			nReceived++
+			outstanding--
+		case msgChoke:
+			// the peer has dropped our requests so ask again when
+			// unchoked
+			p.choked = true
+			for i := range requested {
+				requested[i] = received[i]
+			}
+			outstanding, next = 0, 0
+		default:
+			if err = p.handle(id, payload); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buf, nil
+}
+
+// fetchMetadata downloads the info dictionary from the peer using
+// the metadata extension (BEP 9)
+//
+// The data isn't checked against the info hash.
+func (p *peerConn) fetchMetadata() ([]byte, error) {
+	if !p.supportsExt {
+		p.noMetadata = true
+		return nil, errNoMetadata
+	}
+	if err := p.waitFor(func() bool { return p.extDone }); err != nil {
+		return nil, err
+	}
+	if p.metadataID <= 0 || p.metadataSize <= 0 || p.metadataSize > maxMetadataSize {
+		p.noMetadata = true
+		return nil, errNoMetadata
+	}
+	data := make([]byte, p.metadataSize)
+	nPieces := int((p.metadataSize + blockSize - 1) / blockSize)
+	for piece := 0; piece < nPieces; piece++ {
+		req := bencode(map[string]interface{}{"msg_type": 0, "piece": piece})
+		if err := p.writeMessage(msgExtended, append([]byte{byte(p.metadataID)}, req...)); err != nil {
+			return nil, err
+		}
+		for {
+			id, payload, err := p.readMessage()
+			if err != nil {
+				return nil, err
+			}
+			if id != msgExtended || len(payload) == 0 || payload[0] != utMetadataID {
+				if err = p.handle(id, payload); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			v, n, err := bdecodePrefix(payload[1:])
+			if err != nil {
+				return nil, fmt.Errorf("bad metadata message: %w", err)
+			}
+			d, _ := v.(map[string]interface{})
+			msgType, _ := dictInt(d, "msg_type")
+			gotPiece, _ := dictInt(d, "piece")
+			if msgType == 2 {
+				p.noMetadata = true
+				return nil, errNoMetadata
+			}
+			if msgType != 1 || gotPiece != int64(piece) {
+				continue
+			}
+			block := payload[1+n:]
+			begin := piece * blockSize
+			if begin+len(block) > len(data) {
+				return nil, errors.New("metadata piece too long")
+			}
+			copy(data[begin:], block)
+			break
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,466 @@
+package torrent
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	announceTimeout     = 30 * time.Second
+	minAnnounceInterval = 30 * time.Second // don't ask trackers for peers more often than this
+	maxAttempts         = 10               // peers to try for each piece before giving up
+)
+
+var errBadPiece = errors.New("piece failed hash check")
+
+// torrent is a torrent being read and the peers it is read from
+type torrent struct {
+	f        *Fs
+	source   string // where the torrent came from for logging
+	hash     infoHash
+	trackers []string
+	created  time.Time
+
+	fetchMu sync.Mutex // held while fetching the metadata
+	infoMu  sync.Mutex
+	info    *metaInfo // nil until the metadata has been fetched
+
+	mu           sync.Mutex
+	addrs        []string        // peers not connected to yet
+	banned       map[string]bool // peers which sent bad data
+	conns        []*swarmConn    // connected peers
+	dialing      int             // peers being connected to
+	lastAnnounce time.Time
+	changed      chan struct{} // closed when a peer is released
+	cache        *list.List    // of *cachedPiece, most recent at the front
+	cached       map[int]*list.Element
+	inflight     map[int]*fetch
+}
+
+// swarmConn is a connected peer
+type swarmConn struct {
+	p    *peerConn
+	busy bool
+}
+
+// cachedPiece is a verified piece kept in memory
+type cachedPiece struct {
+	index int
+	data  []byte
+}
+
+// fetch is a piece being downloaded
+type fetch struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// newTorrent makes a torrent with hash
+func newTorrent(f *Fs, source string, hash infoHash, trackers []string) *torrent {
+	return &torrent{
+		f:        f,
+		source:   source,
+		hash:     hash,
+		trackers: append(trackers, f.opt.Trackers...),
+		banned:   map[string]bool{},
+		changed:  make(chan struct{}),
+		cache:    list.New(),
+		cached:   map[int]*list.Element{},
+		inflight: map[int]*fetch{},
+	}
+}
+
+// String returns a description of the torrent for logging
+func (t *torrent) String() string {
+	return t.source
+}
+
+// announce asks all the trackers for peers
+func (t *torrent) announce(ctx context.Context) error {
+	if len(t.trackers) == 0 {
+		return errors.New("no trackers to find peers with")
+	}
+	left := int64(1)
+	if info := t.getInfo(); info != nil {
+		left = info.length
+	}
+	req := announceRequest{
+		hash:   t.hash,
+		peerID: t.f.peerID,
+		left:   left,
+	}
+	ctx, cancel := context.WithTimeout(ctx, announceTimeout)
+	defer cancel()
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		lastErr error
+		found   int
+	)
+	for _, tracker := range t.trackers {
+		tracker := tracker
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			peers, err := announce(ctx, t.f.client, tracker, req)
+			if err != nil {
+				fs.Debugf(t, "Announce to %s failed: %v", tracker, err)
+				mu.Lock()
+				lastErr = err
+				mu.Unlock()
+				return
+			}
+			fs.Debugf(t, "Tracker %s gave %d peers", tracker, len(peers))
+			t.mu.Lock()
+			for _, addr := range peers {
+				if !t.knownLocked(addr) {
+					t.addrs = append(t.addrs, addr)
+				}
+			}
+			t.mu.Unlock()
+			mu.Lock()
+			found += len(peers)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if found == 0 && lastErr != nil {
+		return fmt.Errorf("failed to find peers: %w", lastErr)
+	}
+	return nil
+}
+
+// knownLocked returns true if addr is banned, connected or waiting to
+// be connected to
+//
+// Call with t.mu held
+func (t *torrent) knownLocked(addr string) bool {
+	if t.banned[addr] {
+		return true
+	}
+	for _, known := range t.addrs {
+		if known == addr {
+			return true
+		}
+	}
+	for _, c := range t.conns {
+		if c.p.addr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// signalLocked wakes anything waiting for a peer
+//
+// Call with t.mu held
+func (t *torrent) signalLocked() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// removeLocked closes and forgets c
+//
+// Call with t.mu held
+func (t *torrent) removeLocked(c *swarmConn) {
+	c.p.close()
+	for i := range t.conns {
+		if t.conns[i] == c {
+			t.conns = append(t.conns[:i], t.conns[i+1:]...)
+			break
+		}
+	}
+}
+
+// acquire returns a connected peer for which want returns true,
+// connecting to more peers and asking the trackers for them as needed
+//
+// The peer must be given back with release.
+func (t *torrent) acquire(ctx context.Context, want func(*peerConn) bool) (*peerConn, error) {
+	for {
+		t.mu.Lock()
+		var unwanted *swarmConn
+		busy := t.dialing
+		for _, c := range t.conns {
+			switch {
+			case c.busy:
+				busy++
+			case want(c.p):
+				c.busy = true
+				t.mu.Unlock()
+				return c.p, nil
+			default:
+				unwanted = c
+			}
+		}
+		if len(t.addrs) > 0 {
+			if len(t.conns)+t.dialing >= t.f.opt.MaxPeers && unwanted != nil {
+				// make room for a peer which might be wanted
+				t.removeLocked(unwanted)
+			}
+			if len(t.conns)+t.dialing < t.f.opt.MaxPeers {
+				addr := t.addrs[0]
+				t.addrs = t.addrs[1:]
+				t.dialing++
+				t.mu.Unlock()
+				p, err := dialPeer(ctx, addr, t.hash, t.f.peerID)
+				t.mu.Lock()
+				t.dialing--
+				if err != nil {
+					fs.Debugf(t, "Failed to connect to peer %s: %v", addr, err)
+					t.signalLocked()
+					t.mu.Unlock()
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					continue
+				}
+				t.conns = append(t.conns, &swarmConn{p: p, busy: true})
+				t.mu.Unlock()
+				return p, nil
+			}
+		}
+		if busy > 0 {
+			// wait for a peer to be released
+			changed := t.changed
+			t.mu.Unlock()
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if !t.lastAnnounce.IsZero() && time.Since(t.lastAnnounce) < minAnnounceInterval {
+			t.mu.Unlock()
+			return nil, errors.New("no peers available")
+		}
+		t.lastAnnounce = time.Now()
+		t.mu.Unlock()
+		if err := t.announce(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// release gives back p after use
+//
+// If err is set the connection is dropped unless it just says the
+// peer didn't have what was wanted.
+func (t *torrent) release(p *peerConn, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, c := range t.conns {
+		if c.p != p {
+			continue
+		}
+		switch {
+		case err == nil, errors.Is(err, errPeerLacksPiece), errors.Is(err, errNoMetadata):
+			c.busy = false
+		default:
+			if errors.Is(err, errBadPiece) {
+				t.banned[p.addr] = true
+			}
+			t.removeLocked(c)
+		}
+		break
+	}
+	t.signalLocked()
+}
+
+// close disconnects from all the peers
+func (t *torrent) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, c := range t.conns {
+		c.p.close()
+	}
+	t.conns = nil
+}
+
+// getInfo returns the metadata or nil if it isn't known yet
+func (t *torrent) getInfo() *metaInfo {
+	t.infoMu.Lock()
+	defer t.infoMu.Unlock()
+	return t.info
+}
+
+// ensureInfo fetches the metadata from peers if it isn't known
+func (t *torrent) ensureInfo(ctx context.Context) (*metaInfo, error) {
+	t.fetchMu.Lock()
+	defer t.fetchMu.Unlock()
+	if info := t.getInfo(); info != nil {
+		return info, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(t.f.opt.MetadataTimeout))
+	defer cancel()
+	fs.Debugf(t, "Fetching metadata from peers")
+	for attempt := 0; ; {
+		p, err := t.acquire(ctx, func(p *peerConn) bool { return !p.noMetadata })
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch metadata for %s: %w", t, err)
+		}
+		data, err := p.fetchMetadata()
+		if err == nil {
+			if sum := sha1.Sum(data); !bytes.Equal(sum[:], t.hash[:]) {
+				err = errBadPiece
+			}
+		}
+		t.release(p, err)
+		if err == nil {
+			info, err := parseInfo(data)
+			if err != nil {
+				return nil, fmt.Errorf("bad metadata for %s: %w", t, err)
+			}
+			t.infoMu.Lock()
+			t.info = info
+			t.infoMu.Unlock()
+			return info, nil
+		}
+		if !errors.Is(err, errNoMetadata) {
+			fs.Debugf(t, "Failed to fetch metadata from %s: %v", p.addr, err)
+			attempt++
+			if attempt >= maxAttempts {
+				return nil, fmt.Errorf("failed to fetch metadata for %s: %w", t, err)
+			}
+		}
+	}
+}
+
+// downloadPiece downloads and checks piece i from the peers
+func (t *torrent) downloadPiece(ctx context.Context, info *metaInfo, i int) ([]byte, error) {
+	for attempt := 0; ; {
+		p, err := t.acquire(ctx, func(p *peerConn) bool { return p.mayHave(i) })
+		if err != nil {
+			return nil, fmt.Errorf("failed to download piece %d of %s: %w", i, t, err)
+		}
+		data, err := p.downloadPiece(i, info.pieceSize(i))
+		if err == nil {
+			if sum := sha1.Sum(data); !bytes.Equal(sum[:], info.pieceHash(i)) {
+				err = errBadPiece
+			}
+		}
+		t.release(p, err)
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, errPeerLacksPiece) {
+			fs.Debugf(t, "Failed to download piece %d from %s: %v", i, p.addr, err)
+			attempt++
+			if attempt >= maxAttempts {
+				return nil, fmt.Errorf("failed to download piece %d of %s: %w", i, t, err)
+			}
+		}
+	}
+}
+
+// startFetchLocked starts downloading piece i unless it is cached
+// or already being downloaded
+//
+// Call with t.mu held
+func (t *torrent) startFetchLocked(info *metaInfo, i int) *fetch {
+	if fe, ok := t.inflight[i]; ok {
+		return fe
+	}
+	fe := &fetch{done: make(chan struct{})}
+	t.inflight[i] = fe
+	go func() {
+		// the download isn't tied to any reader as several may be
+		// waiting for it
+		fe.data, fe.err = t.downloadPiece(t.f.ctx, info, i)
+		t.mu.Lock()
+		delete(t.inflight, i)
+		if fe.err == nil {
+			t.cached[i] = t.cache.PushFront(&cachedPiece{index: i, data: fe.data})
+			for t.cache.Len() > t.f.opt.PieceCache {
+				oldest := t.cache.Remove(t.cache.Back()).(*cachedPiece)
+				delete(t.cached, oldest.index)
+			}
+		}
+		t.mu.Unlock()
+		close(fe.done)
+	}()
+	return fe
+}
+
+// piece returns the data of piece i downloading it if necessary
+func (t *torrent) piece(ctx context.Context, info *metaInfo, i int) ([]byte, error) {
+	t.mu.Lock()
+	if e, ok := t.cached[i]; ok {
+		t.cache.MoveToFront(e)
+		t.mu.Unlock()
+		return e.Value.(*cachedPiece).data, nil
+	}
+	fe := t.startFetchLocked(info, i)
+	t.mu.Unlock()
+	select {
+	case <-fe.done:
+		return fe.data, fe.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// prefetch starts downloading piece i if it isn't cached
+func (t *torrent) prefetch(info *metaInfo, i int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.cached[i]; !ok {
+		t.startFetchLocked(info, i)
+	}
+}
+
+// pieceReader reads a range of the torrent's data
+type pieceReader struct {
+	ctx  context.Context
+	t    *torrent
+	info *metaInfo
+	pos  int64  // offset of the next byte in the torrent
+	end  int64  // offset to stop at
+	buf  []byte // rest of the current piece
+}
+
+// Read reads data fetching pieces as needed
+func (r *pieceReader) Read(p []byte) (n int, err error) {
+	if len(r.buf) == 0 {
+		if r.pos >= r.end {
+			return 0, io.EOF
+		}
+		pieceLength := r.info.pieceLength
+		i := int(r.pos / pieceLength)
+		for j := i + 1; j <= i+r.t.f.opt.Readahead && int64(j)*pieceLength < r.end; j++ {
+			r.t.prefetch(r.info, j)
+		}
+		data, err := r.t.piece(r.ctx, r.info, i)
+		if err != nil {
+			return 0, err
+		}
+		start := int64(i) * pieceLength
+		stop := int64(len(data))
+		if r.end-start < stop {
+			stop = r.end - start
+		}
+		r.buf = data[r.pos-start : stop]
+	}
+	n = copy(p, r.buf)
+	r.buf = r.buf[n:]
+	r.pos += int64(n)
+	return n, nil
+}
+
+// Close the reader
+func (r *pieceReader) Close() error {
+	r.buf = nil
+	r.pos = r.end
+	return nil
+}
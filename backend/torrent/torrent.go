@@ -0,0 +1,570 @@
+// Package torrent provides a read only filesystem of the files in
+// BitTorrent torrents
+//
+// The torrents are given as .torrent files or magnet links and the
+// pieces of the files are downloaded from peers as they are read.
+package torrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/random"
+)
+
+var errorReadOnly = errors.New("torrent remotes are read only")
+
+// maxTorrentFileSize is the biggest .torrent file which will be read
+const maxTorrentFileSize = 64 << 20
+
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "torrent",
+		Description: "BitTorrent",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "torrents",
+			Help: `Torrents to read.
+
+A comma separated list of magnet links, URLs or local paths of .torrent
+files, and local directories containing .torrent files.
+
+Standard [CSV encoding](https://godoc.org/encoding/csv) may be used if
+any of them contain commas.`,
+			Required: true,
+		}, {
+			Name: "trackers",
+			Help: `Extra trackers to ask for peers.
+
+A comma separated list of http, https or udp tracker URLs which are
+used for all the torrents as well as the ones they list. Magnet links
+without trackers need these as DHT isn't supported.`,
+			Default:  fs.CommaSepList{},
+			Advanced: true,
+		}, {
+			Name:     "max_peers",
+			Help:     "Maximum number of peers to connect to for each torrent.",
+			Default:  30,
+			Advanced: true,
+		}, {
+			Name: "readahead",
+			Help: `Number of pieces to download ahead of the one being read.
+
+These are downloaded from different peers in parallel, so raising this
+can speed up reading from slow peers.`,
+			Default:  4,
+			Advanced: true,
+		}, {
+			Name: "piece_cache",
+			Help: `Number of pieces to keep in memory for each torrent.
+
+Pieces are usually between 256 KiB and 16 MiB so this controls how
+much memory is used. It is raised to readahead + 1 if it is lower.`,
+			Default:  8,
+			Advanced: true,
+		}, {
+			Name:     "metadata_timeout",
+			Help:     "How long to try to fetch the file list of a magnet link from peers.",
+			Default:  fs.Duration(2 * time.Minute),
+			Advanced: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Torrents        fs.CommaSepList `config:"torrents"`
+	Trackers        fs.CommaSepList `config:"trackers"`
+	MaxPeers        int             `config:"max_peers"`
+	Readahead       int             `config:"readahead"`
+	PieceCache      int             `config:"piece_cache"`
+	MetadataTimeout fs.Duration     `config:"metadata_timeout"`
+}
+
+// Fs stores the interface to the remote torrents
+type Fs struct {
+	name     string
+	root     string
+	opt      Options      // parsed options
+	features *fs.Features // optional features
+	ctx      context.Context
+	client   *http.Client
+	peerID   [20]byte
+	torrents []*torrent
+	loaded   time.Time // when the torrents were loaded
+
+	indexMu sync.Mutex
+	index   *index // nil until built
+}
+
+// Object is a file in a torrent
+type Object struct {
+	fs     *Fs
+	remote string
+	file   *fileRef
+}
+
+// fileRef is where a file is in a torrent
+type fileRef struct {
+	t     *torrent
+	info  *metaInfo
+	entry fileEntry
+}
+
+// index is the tree of files in all the torrents by full path
+type index struct {
+	files    map[string]*fileRef
+	children map[string][]string // paths in each directory, "" for the root
+}
+
+// NewFs creates a new Fs object from the name and root. It loads the
+// .torrent files but doesn't contact any peers.
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.MaxPeers <= 0 {
+		return nil, errors.New("max_peers must be positive")
+	}
+	if opt.Readahead < 0 {
+		opt.Readahead = 0
+	}
+	if opt.PieceCache < opt.Readahead+1 {
+		opt.PieceCache = opt.Readahead + 1
+	}
+	f := &Fs{
+		name:   name,
+		root:   strings.Trim(root, "/"),
+		opt:    *opt,
+		ctx:    fs.CopyConfig(context.Background(), ctx),
+		client: fshttp.NewClient(ctx),
+		loaded: time.Now(),
+	}
+	// Azureus style peer ID
+	copy(f.peerID[:], "-RC0001-"+random.String(12))
+	for _, source := range opt.Torrents {
+		if err = f.addSource(ctx, strings.TrimSpace(source)); err != nil {
+			return nil, err
+		}
+	}
+	if len(f.torrents) == 0 {
+		return nil, errors.New("no torrents found")
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+
+	// Check to see if the root is a file
+	if f.root != "" {
+		idx, err := f.getIndex(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := idx.files[f.root]; ok {
+			f.root = parentDir(f.root)
+			return f, fs.ErrorIsFile
+		}
+	}
+	return f, nil
+}
+
+// parentDir returns the parent directory of p or "" for the root
+func parentDir(p string) string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// addSource adds the torrents from source
+func (f *Fs) addSource(ctx context.Context, source string) error {
+	switch {
+	case source == "":
+		return nil
+	case strings.HasPrefix(source, "magnet:"):
+		m, err := parseMagnet(source)
+		if err != nil {
+			return err
+		}
+		if len(m.trackers)+len(f.opt.Trackers) == 0 {
+			return fmt.Errorf("magnet link for %s has no trackers (tr=) - add some with the trackers option as DHT isn't supported", m.hash)
+		}
+		name := "magnet " + m.hash.String()
+		if m.name != "" {
+			name += " (" + m.name + ")"
+		}
+		f.torrents = append(f.torrents, newTorrent(f, name, m.hash, m.trackers))
+		return nil
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		data, err := f.download(ctx, source)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %q: %w", source, err)
+		}
+		return f.addTorrentFile(source, data)
+	}
+	fi, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		return f.addTorrentFile(source, data)
+	}
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".torrent") {
+			continue
+		}
+		p := filepath.Join(source, entry.Name())
+		data, err := os.ReadFile(p)
+		if err == nil {
+			err = f.addTorrentFile(p, data)
+		}
+		if err != nil {
+			fs.Errorf(nil, "torrent: skipping %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// download reads a .torrent file from url
+func (f *Fs) download(ctx context.Context, url string) (data []byte, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP error %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxTorrentFileSize))
+}
+
+// addTorrentFile adds the torrent in the .torrent file data
+func (f *Fs) addTorrentFile(source string, data []byte) error {
+	tf, err := parseTorrentFile(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	t := newTorrent(f, source, tf.info.hash, tf.trackers)
+	t.info = tf.info
+	t.created = tf.created
+	f.torrents = append(f.torrents, t)
+	return nil
+}
+
+// getIndex returns the tree of files in all the torrents, fetching
+// the metadata of magnet links if necessary
+func (f *Fs) getIndex(ctx context.Context) (*index, error) {
+	f.indexMu.Lock()
+	defer f.indexMu.Unlock()
+	if f.index != nil {
+		return f.index, nil
+	}
+	infos := make([]*metaInfo, len(f.torrents))
+	errs := make([]error, len(f.torrents))
+	var wg sync.WaitGroup
+	for i, t := range f.torrents {
+		i, t := i, t
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			infos[i], errs[i] = t.ensureInfo(ctx)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	idx := &index{
+		files:    map[string]*fileRef{},
+		children: map[string][]string{"": nil},
+	}
+	for i, t := range f.torrents {
+		info := infos[i]
+		if _, used := idx.children[info.name]; used || idx.files[info.name] != nil {
+			fs.Errorf(nil, "torrent: ignoring %s as %q is already used by another torrent", t, info.name)
+			continue
+		}
+		for _, entry := range info.files {
+			idx.addDir(parentDir(entry.path))
+			idx.children[parentDir(entry.path)] = append(idx.children[parentDir(entry.path)], entry.path)
+			idx.files[entry.path] = &fileRef{t: t, info: info, entry: entry}
+		}
+	}
+	for _, children := range idx.children {
+		sort.Strings(children)
+	}
+	f.index = idx
+	return idx, nil
+}
+
+// addDir adds dir and its parents to the index
+func (idx *index) addDir(dir string) {
+	if _, ok := idx.children[dir]; ok {
+		return
+	}
+	idx.children[dir] = nil
+	parent := parentDir(dir)
+	idx.addDir(parent)
+	idx.children[parent] = append(idx.children[parent], dir)
+}
+
+// Name returns the configured name of the file system
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root returns the root for the filesystem
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String returns a description of the Fs
+func (f *Fs) String() string {
+	return fmt.Sprintf("torrent root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// Hashes returns the supported hash types of the filesystem
+//
+// The piece hashes span files so they can't be used as file hashes.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// fullPath returns the path in the index of remote
+func (f *Fs) fullPath(remote string) string {
+	return strings.Trim(path.Join(f.root, remote), "/")
+}
+
+// relPath returns the remote for the index path p
+func (f *Fs) relPath(p string) string {
+	if f.root == "" {
+		return p
+	}
+	return strings.TrimPrefix(p, f.root+"/")
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	idx, err := f.getIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children, ok := idx.children[f.fullPath(dir)]
+	if !ok {
+		return nil, fs.ErrorDirNotFound
+	}
+	for _, p := range children {
+		remote := f.relPath(p)
+		if file, ok := idx.files[p]; ok {
+			entries = append(entries, f.newObject(remote, file))
+		} else {
+			entries = append(entries, fs.NewDir(remote, f.loaded))
+		}
+	}
+	return entries, nil
+}
+
+// newObject makes an object for file
+func (f *Fs) newObject(remote string, file *fileRef) *Object {
+	return &Object{
+		fs:     f,
+		remote: remote,
+		file:   file,
+	}
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	idx, err := f.getIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := f.fullPath(remote)
+	if file, ok := idx.files[p]; ok {
+		return f.newObject(remote, file), nil
+	}
+	if _, ok := idx.children[p]; ok {
+		return nil, fs.ErrorIsDir
+	}
+	return nil, fs.ErrorObjectNotFound
+}
+
+// Put in to the remote path with the modTime given of the given size
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, errorReadOnly
+}
+
+// Mkdir makes the root directory of the Fs object
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// Rmdir removes the root directory of the Fs object
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return errorReadOnly
+}
+
+// Shutdown disconnects from the peers
+func (f *Fs) Shutdown(ctx context.Context) error {
+	for _, t := range f.torrents {
+		t.close()
+	}
+	return nil
+}
+
+// Fs is the filesystem this remote http file object is located within
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// String returns the remote path
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote the name of the remote file
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash is unsupported
+func (o *Object) Hash(ctx context.Context, r hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size in bytes of the remote file
+func (o *Object) Size() int64 {
+	return o.file.entry.length
+}
+
+// ModTime returns the creation date of the torrent if it has one or
+// the time it was loaded
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	if !o.file.t.created.IsZero() {
+		return o.file.t.created
+	}
+	return o.fs.loaded
+}
+
+// SetModTime sets the modification and access time to the specified time
+//
+// it also updates the info field
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return errorReadOnly
+}
+
+// Storable returns whether the remote file is a regular file
+func (o *Object) Storable() bool {
+	return true
+}
+
+// ID returns the info hash of the torrent and the path of the file in
+// it
+func (o *Object) ID() string {
+	return o.file.info.hash.String() + "/" + o.file.entry.path
+}
+
+// Open a remote file object for reading. Seek is supported
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	size := o.file.entry.length
+	var offset, limit int64 = 0, -1
+	for _, option := range options {
+		switch x := option.(type) {
+		case *fs.SeekOption:
+			offset = x.Offset
+		case *fs.RangeOption:
+			offset, limit = x.Decode(size)
+		default:
+			if option.Mandatory() {
+				fs.Logf(o, "Unsupported mandatory option: %v", option)
+			}
+		}
+	}
+	if offset > size {
+		offset = size
+	}
+	end := size
+	if limit >= 0 && offset+limit < size {
+		end = offset + limit
+	}
+	start := o.file.entry.offset
+	return &pieceReader{
+		ctx:  ctx,
+		t:    o.file.t,
+		info: o.file.info,
+		pos:  start + offset,
+		end:  start + end,
+	}, nil
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return errorReadOnly
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	return errorReadOnly
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs         = &Fs{}
+	_ fs.Shutdowner = &Fs{}
+	_ fs.Object     = &Object{}
+	_ fs.IDer       = &Object{}
+)
@@ -0,0 +1,442 @@
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBencode(t *testing.T) {
+	in := map[string]interface{}{
+		"int":  int64(-42),
+		"str":  "spam",
+		"list": []interface{}{int64(1), "two", []interface{}{}},
+		"dict": map[string]interface{}{"b": "x", "a": "y"},
+	}
+	data := bencode(in)
+	assert.Equal(t, "d4:dictd1:a1:y1:b1:xe3:inti-42e4:listli1e3:twolee3:str4:spame", string(data))
+	out, err := bdecode(data)
+	require.NoError(t, err)
+	assert.Equal(t, in, out)
+
+	raw, err := bdecodeDictRaw(data)
+	require.NoError(t, err)
+	assert.Equal(t, "d1:a1:y1:b1:xe", string(raw["dict"]))
+
+	for _, bad := range []string{"", "i12", "ixe", "5:abc", "l", "d1:ae", "x", "i1ei2e", "-1:"} {
+		_, err := bdecode([]byte(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseMagnet(t *testing.T) {
+	const hexHash = "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+	m, err := parseMagnet("magnet:?xt=urn:btih:" + hexHash + "&dn=Some+Name&tr=http%3A%2F%2Ftracker%2Fannounce&tr=udp%3A%2F%2Fother%3A80")
+	require.NoError(t, err)
+	assert.Equal(t, hexHash, m.hash.String())
+	assert.Equal(t, "Some Name", m.name)
+	assert.Equal(t, []string{"http://tracker/announce", "udp://other:80"}, m.trackers)
+
+	m, err = parseMagnet("magnet:?xt=urn:btih:YEX6DQDLXISUVHOJ6UM3GNNKPQJWPKEK")
+	require.NoError(t, err)
+	assert.Equal(t, hexHash, m.hash.String())
+
+	for _, bad := range []string{"http://example.com", "magnet:?xt=urn:sha1:abc", "magnet:?xt=urn:btih:abc"} {
+		_, err := parseMagnet(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	assert.Equal(t, "_", cleanName(""))
+	assert.Equal(t, "．", cleanName("."))
+	assert.Equal(t, "．．", cleanName(".."))
+	assert.Equal(t, "a／b", cleanName("a/b"))
+	assert.Equal(t, "bad�", cleanName("bad\xff"))
+}
+
+// testFile is a file in the test torrent
+type testFile struct {
+	path    []string
+	size    int
+	padding bool
+}
+
+// testTorrent is a torrent made for the tests
+type testTorrent struct {
+	info    []byte // bencoded info dictionary
+	hash    infoHash
+	data    []byte // all the data
+	pieces  [][]byte
+	content map[string][]byte // contents of each file by path
+}
+
+// makeTorrent makes a multi file torrent called name
+func makeTorrent(name string, pieceLength int, files []testFile) *testTorrent {
+	tt := &testTorrent{content: map[string][]byte{}}
+	rng := rand.New(rand.NewSource(1))
+	var fileList []interface{}
+	for _, file := range files {
+		data := make([]byte, file.size)
+		if !file.padding {
+			_, _ = rng.Read(data)
+		}
+		tt.data = append(tt.data, data...)
+		path := []interface{}{}
+		for _, element := range file.path {
+			path = append(path, element)
+		}
+		entry := map[string]interface{}{"length": file.size, "path": path}
+		if file.padding {
+			entry["attr"] = "p"
+		} else {
+			key := name
+			for _, element := range file.path {
+				key += "/" + element
+			}
+			tt.content[key] = data
+		}
+		fileList = append(fileList, entry)
+	}
+	var hashes []byte
+	for start := 0; start < len(tt.data); start += pieceLength {
+		end := start + pieceLength
+		if end > len(tt.data) {
+			end = len(tt.data)
+		}
+		piece := tt.data[start:end]
+		tt.pieces = append(tt.pieces, piece)
+		sum := sha1.Sum(piece)
+		hashes = append(hashes, sum[:]...)
+	}
+	tt.info = bencode(map[string]interface{}{
+		"name":         name,
+		"piece length": pieceLength,
+		"pieces":       hashes,
+		"files":        fileList,
+	})
+	tt.hash = sha1.Sum(tt.info)
+	return tt
+}
+
+// torrentFile returns the contents of a .torrent file for tt
+func (tt *testTorrent) torrentFile(tracker string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("d8:announce")
+	bencodeTo(&buf, tracker)
+	buf.WriteString("13:creation datei1662808333e4:info")
+	buf.Write(tt.info)
+	buf.WriteString("e")
+	return buf.Bytes()
+}
+
+// seeder is a fake peer with all of a torrent
+type seeder struct {
+	tt       *testTorrent
+	listener net.Listener
+	corrupt  bool // send bad data
+	chokeAt  int  // choke after this many requests if > 0
+
+	mu       sync.Mutex
+	requests int // blocks requested
+}
+
+// newSeeder starts a seeder for tt
+func newSeeder(t *testing.T, tt *testTorrent) *seeder {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &seeder{tt: tt, listener: l}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// addr returns the compact address of the seeder
+func (s *seeder) compactAddr() []byte {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	out := append([]byte(nil), addr.IP.To4()...)
+	return append(out, byte(addr.Port>>8), byte(addr.Port))
+}
+
+// send writes a message
+func send(w io.Writer, id byte, payload []byte) {
+	buf := make([]byte, 5+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(1+len(payload)))
+	buf[4] = id
+	copy(buf[5:], payload)
+	_, _ = w.Write(buf)
+}
+
+// serve talks to one peer
+func (s *seeder) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	handshake := make([]byte, handshakeLen)
+	if _, err := io.ReadFull(conn, handshake); err != nil {
+		return
+	}
+	reply := append([]byte(nil), handshake...)
+	copy(reply[1+len(protocolName)+8:], s.tt.hash[:])
+	copy(reply[handshakeLen-20:], "-FAKE0-seeder-------")
+	_, _ = conn.Write(reply)
+
+	const ourMetadataID = 3
+	send(conn, msgExtended, append([]byte{0}, bencode(map[string]interface{}{
+		"m":             map[string]interface{}{"ut_metadata": ourMetadataID},
+		"metadata_size": len(s.tt.info),
+	})...))
+	bitfield := make([]byte, (len(s.tt.pieces)+7)/8)
+	for i := range s.tt.pieces {
+		bitfield[i/8] |= 0x80 >> (i % 8)
+	}
+	send(conn, msgBitfield, bitfield)
+
+	theirMetadataID := int64(0)
+	var length [4]byte
+	for {
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		msg := make([]byte, binary.BigEndian.Uint32(length[:]))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+		if len(msg) == 0 {
+			continue
+		}
+		payload := msg[1:]
+		switch msg[0] {
+		case msgInterested:
+			send(conn, msgUnchoke, nil)
+		case msgRequest:
+			index := binary.BigEndian.Uint32(payload[0:])
+			begin := binary.BigEndian.Uint32(payload[4:])
+			n := binary.BigEndian.Uint32(payload[8:])
+			s.mu.Lock()
+			s.requests++
+			choke := s.chokeAt > 0 && s.requests == s.chokeAt
+			s.mu.Unlock()
+			if choke {
+				// drop this and any queued requests then carry on
+				send(conn, msgChoke, nil)
+				send(conn, msgUnchoke, nil)
+				continue
+			}
+			block := append([]byte(nil), s.tt.pieces[index][begin:begin+n]...)
+			if s.corrupt {
+				block[0] ^= 0xFF
+			}
+			out := make([]byte, 8+len(block))
+			copy(out, payload[:8])
+			copy(out[8:], block)
+			send(conn, msgPiece, out)
+		case msgExtended:
+			v, n, err := bdecodePrefix(payload[1:])
+			if err != nil || n != len(payload)-1 {
+				return
+			}
+			d := v.(map[string]interface{})
+			if payload[0] == 0 {
+				m, _ := d["m"].(map[string]interface{})
+				theirMetadataID, _ = dictInt(m, "ut_metadata")
+				continue
+			}
+			if payload[0] != ourMetadataID {
+				continue
+			}
+			piece, _ := dictInt(d, "piece")
+			start := int(piece) * blockSize
+			end := start + blockSize
+			if end > len(s.tt.info) {
+				end = len(s.tt.info)
+			}
+			out := append([]byte{byte(theirMetadataID)}, bencode(map[string]interface{}{
+				"msg_type":   1,
+				"piece":      piece,
+				"total_size": len(s.tt.info),
+			})...)
+			send(conn, msgExtended, append(out, s.tt.info[start:end]...))
+		}
+	}
+}
+
+// newTracker starts an HTTP tracker which returns seeders
+func newTracker(t *testing.T, hash infoHash, seeders ...*seeder) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("info_hash") != string(hash[:]) {
+			_, _ = w.Write(bencode(map[string]interface{}{"failure reason": "unknown torrent"}))
+			return
+		}
+		var peers []byte
+		for _, s := range seeders {
+			peers = append(peers, s.compactAddr()...)
+		}
+		_, _ = w.Write(bencode(map[string]interface{}{"interval": 1800, "peers": peers}))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testFiles are the files in the test torrent - the pieces span the
+// files and there is a padding file
+var testFiles = []testFile{
+	{path: []string{"a.txt"}, size: 1000},
+	{path: []string{".pad", "48"}, size: 48, padding: true},
+	{path: []string{"sub", "b.bin"}, size: 150000},
+	{path: []string{"sub", "empty"}, size: 0},
+}
+
+// setup makes the test torrent and a tracker returning seeders for it
+// returning the path of the .torrent file
+func setup(t *testing.T, seeders int) (*testTorrent, string, *httptest.Server, []*seeder) {
+	tt := makeTorrent("album", 2*blockSize, testFiles)
+	var ss []*seeder
+	for i := 0; i < seeders; i++ {
+		ss = append(ss, newSeeder(t, tt))
+	}
+	tracker := newTracker(t, tt.hash, ss...)
+	p := filepath.Join(t.TempDir(), "album.torrent")
+	require.NoError(t, os.WriteFile(p, tt.torrentFile(tracker.URL+"/announce"), 0666))
+	return tt, p, tracker, ss
+}
+
+// read returns the contents of o
+func read(t *testing.T, o fs.Object, options ...fs.OpenOption) []byte {
+	in, err := o.Open(context.Background(), options...)
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return data
+}
+
+// checkTorrent checks the files in f are those of tt
+func checkTorrent(t *testing.T, f fs.Fs, tt *testTorrent) {
+	ctx := context.Background()
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "album", entries[0].Remote())
+
+	entries, err = f.List(ctx, "album")
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	assert.Equal(t, []string{"album/a.txt", "album/sub"}, names)
+
+	_, err = f.List(ctx, "album/missing")
+	assert.Equal(t, fs.ErrorDirNotFound, err)
+
+	for p, want := range tt.content {
+		o, err := f.NewObject(ctx, p)
+		require.NoError(t, err, p)
+		assert.Equal(t, int64(len(want)), o.Size())
+		assert.True(t, bytes.Equal(want, read(t, o)), p)
+	}
+	o, err := f.NewObject(ctx, "album/sub/b.bin")
+	require.NoError(t, err)
+	want := tt.content["album/sub/b.bin"]
+	assert.True(t, bytes.Equal(want[40000:40100], read(t, o, &fs.RangeOption{Start: 40000, End: 40099})))
+	assert.True(t, bytes.Equal(want[149990:], read(t, o, &fs.SeekOption{Offset: 149990})))
+
+	_, err = f.NewObject(ctx, "album/sub")
+	assert.Equal(t, fs.ErrorIsDir, err)
+	_, err = f.NewObject(ctx, "album/.pad/48")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func TestTorrentFile(t *testing.T) {
+	tt, p, _, _ := setup(t, 1)
+	f, err := fs.NewFs(context.Background(), fmt.Sprintf(":torrent,torrents=%q:", p))
+	require.NoError(t, err)
+	defer func() { _ = f.Features().Shutdown(context.Background()) }()
+	checkTorrent(t, f, tt)
+
+	o, err := f.NewObject(context.Background(), "album/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1662808333), o.ModTime(context.Background()).Unix())
+	assert.Equal(t, tt.hash.String()+"/album/a.txt", o.(fs.IDer).ID())
+
+	// read only
+	assert.Equal(t, errorReadOnly, f.Mkdir(context.Background(), "dir"))
+	assert.Equal(t, errorReadOnly, o.Remove(context.Background()))
+}
+
+func TestTorrentDir(t *testing.T) {
+	tt, p, _, _ := setup(t, 1)
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(p), "bad.torrent"), []byte("nonsense"), 0666))
+	f, err := fs.NewFs(context.Background(), fmt.Sprintf(":torrent,torrents=%q:", filepath.Dir(p)))
+	require.NoError(t, err)
+	defer func() { _ = f.Features().Shutdown(context.Background()) }()
+	checkTorrent(t, f, tt)
+}
+
+func TestMagnet(t *testing.T) {
+	tt, _, tracker, _ := setup(t, 1)
+	magnet := "magnet:?xt=urn:btih:" + tt.hash.String() + "&tr=" + url.QueryEscape(tracker.URL+"/announce")
+	f, err := fs.NewFs(context.Background(), fmt.Sprintf(":torrent,torrents=%q:", magnet))
+	require.NoError(t, err)
+	defer func() { _ = f.Features().Shutdown(context.Background()) }()
+	checkTorrent(t, f, tt)
+
+	_, err = fs.NewFs(context.Background(), fmt.Sprintf(":torrent,torrents=%q:", "magnet:?xt=urn:btih:"+tt.hash.String()))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no trackers")
+}
+
+func TestBadPeers(t *testing.T) {
+	tt := makeTorrent("album", 2*blockSize, testFiles)
+	bad := newSeeder(t, tt)
+	bad.corrupt = true
+	choker := newSeeder(t, tt)
+	choker.chokeAt = 3
+	tracker := newTracker(t, tt.hash, bad, choker)
+	p := filepath.Join(t.TempDir(), "album.torrent")
+	require.NoError(t, os.WriteFile(p, tt.torrentFile(tracker.URL+"/announce"), 0666))
+
+	f, err := fs.NewFs(context.Background(), fmt.Sprintf(":torrent,torrents=%q,readahead=0:", p))
+	require.NoError(t, err)
+	defer func() { _ = f.Features().Shutdown(context.Background()) }()
+	checkTorrent(t, f, tt)
+	tor := f.(*Fs).torrents[0]
+	tor.mu.Lock()
+	defer tor.mu.Unlock()
+	assert.Equal(t, map[string]bool{bad.listener.Addr().String(): true}, tor.banned)
+}
+
+func TestRootIsFile(t *testing.T) {
+	tt, p, _, _ := setup(t, 1)
+	f, err := fs.NewFs(context.Background(), fmt.Sprintf(":torrent,torrents=%q:album/a.txt", p))
+	assert.Equal(t, fs.ErrorIsFile, err)
+	require.NotNil(t, f)
+	defer func() { _ = f.Features().Shutdown(context.Background()) }()
+	assert.Equal(t, "album", f.Root())
+	o, err := f.NewObject(context.Background(), "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, tt.content["album/a.txt"], read(t, o))
+}
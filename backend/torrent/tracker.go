@@ -0,0 +1,205 @@
+package torrent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fshttp"
+)
+
+// announcePort is the port sent to trackers
+//
+// Rclone doesn't accept connections from peers but trackers insist
+// on a port.
+const announcePort = 6881
+
+// udpTimeout is how long to wait for each reply from a UDP tracker
+const udpTimeout = 15 * time.Second
+
+// announceRequest is what is sent to a tracker
+type announceRequest struct {
+	hash   infoHash
+	peerID [20]byte
+	left   int64
+}
+
+// announce asks the tracker at trackerURL for peers returning their
+// addresses
+func announce(ctx context.Context, client *http.Client, trackerURL string, req announceRequest) ([]string, error) {
+	u, err := url.Parse(trackerURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return announceHTTP(ctx, client, u, req)
+	case "udp":
+		return announceUDP(ctx, u, req)
+	}
+	return nil, fmt.Errorf("unsupported tracker scheme %q", u.Scheme)
+}
+
+// announceHTTP announces to an HTTP tracker
+func announceHTTP(ctx context.Context, client *http.Client, u *url.URL, req announceRequest) ([]string, error) {
+	q := u.Query()
+	q.Set("info_hash", string(req.hash[:]))
+	q.Set("peer_id", string(req.peerID[:]))
+	q.Set("port", strconv.Itoa(announcePort))
+	q.Set("uploaded", "0")
+	q.Set("downloaded", "0")
+	q.Set("left", strconv.FormatInt(req.left, 10))
+	q.Set("compact", "1")
+	q.Set("event", "started")
+	u.RawQuery = q.Encode()
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(resp.Body, &err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tracker returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<24))
+	if err != nil {
+		return nil, err
+	}
+	v, err := bdecode(body)
+	if err != nil {
+		return nil, fmt.Errorf("bad tracker response: %w", err)
+	}
+	d, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("bad tracker response: not a dictionary")
+	}
+	if reason, ok := dictString(d, "failure reason"); ok {
+		return nil, fmt.Errorf("tracker failed: %s", reason)
+	}
+	var peers []string
+	switch x := d["peers"].(type) {
+	case string:
+		peers = append(peers, compactPeers([]byte(x), net.IPv4len)...)
+	case []interface{}:
+		for _, item := range x {
+			peer, _ := item.(map[string]interface{})
+			ip, _ := dictString(peer, "ip")
+			port, _ := dictInt(peer, "port")
+			if ip != "" && port > 0 {
+				peers = append(peers, net.JoinHostPort(ip, strconv.FormatInt(port, 10)))
+			}
+		}
+	}
+	if peers6, ok := dictString(d, "peers6"); ok {
+		peers = append(peers, compactPeers([]byte(peers6), net.IPv6len)...)
+	}
+	return peers, nil
+}
+
+// compactPeers decodes peers as addresses of ipLen bytes followed by
+// a 2 byte port
+func compactPeers(data []byte, ipLen int) (peers []string) {
+	size := ipLen + 2
+	for i := 0; i+size <= len(data); i += size {
+		ip := net.IP(data[i : i+ipLen])
+		port := binary.BigEndian.Uint16(data[i+ipLen:])
+		peers = append(peers, net.JoinHostPort(ip.String(), strconv.Itoa(int(port))))
+	}
+	return peers
+}
+
+// UDP tracker protocol (BEP 15) constants
+const (
+	udpProtocolID     = 0x41727101980
+	udpActionConnect  = 0
+	udpActionAnnounce = 1
+	udpActionError    = 3
+)
+
+// announceUDP announces to a UDP tracker
+func announceUDP(ctx context.Context, u *url.URL, req announceRequest) ([]string, error) {
+	conn, err := fshttp.NewDialer(ctx).DialContext(ctx, "udp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// connect to get a connection ID
+	packet := make([]byte, 16)
+	binary.BigEndian.PutUint64(packet[0:], udpProtocolID)
+	binary.BigEndian.PutUint32(packet[8:], udpActionConnect)
+	reply, err := udpRoundTrip(conn, packet, udpActionConnect, 16)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	connectionID := binary.BigEndian.Uint64(reply[8:])
+
+	// then announce with it
+	packet = make([]byte, 98)
+	binary.BigEndian.PutUint64(packet[0:], connectionID)
+	binary.BigEndian.PutUint32(packet[8:], udpActionAnnounce)
+	copy(packet[16:], req.hash[:])
+	copy(packet[36:], req.peerID[:])
+	binary.BigEndian.PutUint64(packet[56:], 0)                // downloaded
+	binary.BigEndian.PutUint64(packet[64:], uint64(req.left)) // left
+	binary.BigEndian.PutUint64(packet[72:], 0)                // uploaded
+	binary.BigEndian.PutUint32(packet[80:], 2)                // event: started
+	binary.BigEndian.PutUint32(packet[84:], 0)                // IP: default
+	_, _ = rand.Read(packet[88:92])                           // key
+	binary.BigEndian.PutUint32(packet[92:], 0xFFFFFFFF)       // num_want: default
+	binary.BigEndian.PutUint16(packet[96:], announcePort)
+	reply, err = udpRoundTrip(conn, packet, udpActionAnnounce, 20)
+	if err != nil {
+		return nil, fmt.Errorf("announce: %w", err)
+	}
+	return compactPeers(reply[20:], net.IPv4len), nil
+}
+
+// udpRoundTrip sends packet with a new transaction ID and reads the
+// reply to it which must be for action and at least minSize bytes
+func udpRoundTrip(conn net.Conn, packet []byte, action uint32, minSize int) ([]byte, error) {
+	_, _ = rand.Read(packet[12:16])
+	transactionID := binary.BigEndian.Uint32(packet[12:])
+	if _, err := conn.Write(packet); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65536)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(udpTimeout))
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		reply := buf[:n]
+		if n < 8 || binary.BigEndian.Uint32(reply[4:]) != transactionID {
+			continue
+		}
+		switch binary.BigEndian.Uint32(reply) {
+		case action:
+			if n < minSize {
+				return nil, errors.New("short reply")
+			}
+			return reply, nil
+		case udpActionError:
+			return nil, fmt.Errorf("tracker failed: %s", reply[8:])
+		}
+		return nil, errors.New("unexpected reply")
+	}
+}
@@ -0,0 +1,953 @@
+// Package tus provides an interface to servers which accept uploads
+// with the tus resumable upload protocol
+//
+// See https://tus.io/protocols/resumable-upload for the protocol.
+//
+// The protocol has no way of listing or naming files so the uploads
+// are recorded in a local database, which is what is listed.
+package tus
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	gohash "hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/kv"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep      = 10 * time.Millisecond
+	maxSleep      = 2 * time.Second
+	decayConstant = 2 // bigger for slower decay, exponential
+
+	tusVersion = "1.0.0"
+
+	// extensions of the protocol which are used if the server
+	// supports them
+	extCreation    = "creation"
+	extDeferLength = "creation-defer-length"
+	extTermination = "termination"
+	extChecksum    = "checksum"
+)
+
+// checksums are the algorithms of the checksum extension which can be
+// used in order of preference
+var checksums = []struct {
+	name string
+	new  func() gohash.Hash
+}{
+	{"sha256", sha256.New},
+	{"sha1", sha1.New},
+	{"md5", md5.New},
+}
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "tus",
+		Description: "TUS resumable upload server",
+		NewFs:       NewFs,
+		Options: []fs.Option{{
+			Name: "url",
+			Help: `URL of the endpoint to create uploads at.
+
+E.g. "https://tusd.tusdemo.net/files/".`,
+			Required: true,
+		}, {
+			Name: "headers",
+			Help: `Set HTTP headers for all transactions.
+
+Use this to set additional HTTP headers for all transactions, e.g. to
+authenticate with the server.
+
+The input format is comma separated list of key,value pairs.  Standard
+[CSV encoding](https://godoc.org/encoding/csv) may be used.
+
+For example, to set a bearer token use 'Authorization,Bearer xxx'.`,
+			Default:  fs.CommaSepList{},
+			Advanced: true,
+		}, {
+			Name: "chunk_size",
+			Help: `Size of the parts files are sent in.
+
+Each part is sent with a PATCH request. If a request fails then only
+the rest of that part is sent again, so smaller parts waste less after
+an error but mean more requests.
+
+Each transfer buffers a part in memory.`,
+			Default:  8 * fs.Mebi,
+			Advanced: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	URL       string          `config:"url"`
+	Headers   fs.CommaSepList `config:"headers"`
+	ChunkSize fs.SizeSuffix   `config:"chunk_size"`
+}
+
+// Fs represents uploads to a TUS server
+type Fs struct {
+	name       string             // name of this remote
+	root       string             // the path we are working on
+	opt        Options            // parsed options
+	features   *fs.Features       // optional features
+	srv        *rest.Client       // the connection to the server
+	pacer      *fs.Pacer          // pacer for API calls
+	endpoint   *url.URL           // where uploads are created
+	extensions map[string]bool    // extensions the server supports
+	checksum   string             // checksum algorithm to send or ""
+	newSum     func() gohash.Hash // makes a hash for checksum or nil
+	maxSize    int64              // largest upload the server allows or -1
+	db         *kv.DB             // the uploads which have been made
+}
+
+// Object describes a file uploaded to a TUS server
+type Object struct {
+	fs      *Fs       // what this object is part of
+	remote  string    // The remote path
+	url     string    // where the upload is
+	size    int64     // size of the object
+	modTime time.Time // modification time of the object
+	md5     string    // MD5 of the object if known
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("TUS server %s root '%s'", f.opt.URL, f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// Precision of the ModTimes in this Fs
+func (f *Fs) Precision() time.Duration {
+	return time.Nanosecond
+}
+
+// Hashes returns the supported hash types of the filesystem
+func (f *Fs) Hashes() hash.Set {
+	return hash.NewHashSet(hash.MD5)
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	423, // Locked - the upload is being written by another request
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler turns a non 2xx response into an error
+//
+// TUS servers send plain text errors.
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		return fmt.Errorf("error reading error out of body: %w", err)
+	}
+	message := strings.TrimSpace(string(body))
+	if message == "" || len(message) > 1024 {
+		return fmt.Errorf("HTTP error %s", resp.Status)
+	}
+	return fmt.Errorf("HTTP error %s: %s", resp.Status, message)
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	if !kv.Supported() {
+		return nil, errors.New("tus is not supported on this OS")
+	}
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.ChunkSize <= 0 {
+		return nil, errors.New("chunk_size must be positive")
+	}
+	if len(opt.Headers)%2 != 0 {
+		return nil, errors.New("odd number of headers supplied")
+	}
+	endpoint, err := url.Parse(opt.URL)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse url: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("url must start with http:// or https:// not %q", opt.URL)
+	}
+	f := &Fs{
+		name:       name,
+		root:       strings.Trim(root, "/"),
+		opt:        *opt,
+		srv:        rest.NewClient(fshttp.NewClient(ctx)),
+		pacer:      fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+		endpoint:   endpoint,
+		extensions: map[string]bool{},
+		maxSize:    -1,
+	}
+	f.srv.SetErrorHandler(errorHandler)
+	f.srv.SetHeader("Tus-Resumable", tusVersion)
+	for i := 0; i < len(opt.Headers); i += 2 {
+		f.srv.SetHeader(opt.Headers[i], opt.Headers[i+1])
+	}
+	err = f.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.db, err = kv.Start(ctx, "tus", f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploads database: %w", err)
+	}
+	f.features = (&fs.Features{
+		CanHaveEmptyDirectories: true,
+	}).Fill(ctx, f)
+	if !f.extensions[extDeferLength] {
+		f.features.PutStream = nil
+	}
+	if !f.extensions[extTermination] {
+		f.features.CleanUp = nil
+	}
+
+	// Check to see if the root is a file
+	if f.root != "" && f.lookup("").isFile() {
+		f.root = path.Dir(f.root)
+		if f.root == "." {
+			f.root = ""
+		}
+		return f, fs.ErrorIsFile
+	}
+	return f, nil
+}
+
+// discover asks the server which extensions of the protocol it
+// supports
+//
+// Not all servers answer, in which case only the creation extension
+// is assumed.
+func (f *Fs) discover(ctx context.Context) error {
+	opts := rest.Opts{
+		Method:     "OPTIONS",
+		RootURL:    f.endpoint.String(),
+		NoResponse: true,
+	}
+	var resp *http.Response
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		resp, err = f.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil || resp.Header.Get("Tus-Version") == "" {
+		fs.Debugf(f, "Server didn't say which TUS extensions it supports: %v", err)
+		f.extensions[extCreation] = true
+		return nil
+	}
+	versions := strings.Split(resp.Header.Get("Tus-Version"), ",")
+	found := false
+	for _, version := range versions {
+		if strings.TrimSpace(version) == tusVersion {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("server doesn't support TUS version %s, only %q", tusVersion, resp.Header.Get("Tus-Version"))
+	}
+	for _, ext := range strings.Split(resp.Header.Get("Tus-Extension"), ",") {
+		f.extensions[strings.TrimSpace(ext)] = true
+	}
+	if !f.extensions[extCreation] {
+		return errors.New("server doesn't support the TUS creation extension needed to upload files")
+	}
+	if maxSize := resp.Header.Get("Tus-Max-Size"); maxSize != "" {
+		f.maxSize, err = strconv.ParseInt(maxSize, 10, 64)
+		if err != nil {
+			return fmt.Errorf("bad Tus-Max-Size %q: %w", maxSize, err)
+		}
+	}
+	if f.extensions[extChecksum] {
+		algorithms := map[string]bool{}
+		for _, algorithm := range strings.Split(resp.Header.Get("Tus-Checksum-Algorithm"), ",") {
+			algorithms[strings.ToLower(strings.TrimSpace(algorithm))] = true
+		}
+		for _, c := range checksums {
+			if algorithms[c.name] {
+				f.checksum, f.newSum = c.name, c.new
+				break
+			}
+		}
+	}
+	fs.Debugf(f, "Server supports TUS extensions %q, checksum %q", resp.Header.Get("Tus-Extension"), f.checksum)
+	return nil
+}
+
+// list calls fn for each file and directory in dir, recursively if
+// recurse is set
+func (f *Fs) list(dir string, recurse bool, fn func(entry fs.DirEntry)) error {
+	recs, err := f.records(dir)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(recs))
+	for name := range recs {
+		if recurse || !strings.Contains(name, "/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rec := recs[name]
+		remote := path.Join(dir, name)
+		if rec.isDir() {
+			fn(fs.NewDir(remote, rec.ModTime))
+		} else if rec.isFile() {
+			fn(f.newObject(remote, rec))
+		}
+	}
+	return nil
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	err = f.list(dir, false, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	return entries, err
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	var entries fs.DirEntries
+	err := f.list(dir, true, func(entry fs.DirEntry) {
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return err
+	}
+	return callback(entries)
+}
+
+// newObject makes an Object for remote from rec
+func (f *Fs) newObject(remote string, rec *record) *Object {
+	return &Object{
+		fs:      f,
+		remote:  remote,
+		url:     rec.URL,
+		size:    rec.Size,
+		modTime: rec.ModTime,
+		md5:     rec.MD5,
+	}
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	rec := f.lookup(remote)
+	if !rec.isFile() {
+		return nil, fs.ErrorObjectNotFound
+	}
+	return f.newObject(remote, rec), nil
+}
+
+// Put the object
+//
+// Copy the reader in to the new object which is returned.
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: src.Remote(),
+	}
+	return o, o.Update(ctx, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.Put(ctx, in, src, options...)
+}
+
+// Mkdir makes the directory (container, bucket)
+//
+// Shouldn't return an error if it already exists
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	return f.mkdir(dir)
+}
+
+// Rmdir removes the directory (container, bucket) if empty
+//
+// Return an error if it doesn't exist or isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	return f.rmdir(dir)
+}
+
+// CleanUp cancels the uploads which were interrupted and won't be
+// resumed
+func (f *Fs) CleanUp(ctx context.Context) error {
+	recs, err := f.records("")
+	if err == fs.ErrorDirNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for remote, rec := range recs {
+		if rec.Pending == nil {
+			continue
+		}
+		fs.Infof(remote, "Cancelling unfinished upload")
+		err = f.terminate(ctx, rec.Pending.URL)
+		if err != nil {
+			fs.Errorf(remote, "Failed to cancel unfinished upload: %v", err)
+			continue
+		}
+		err = f.change(remote, func(rec *record) (*record, error) {
+			if rec == nil {
+				return nil, nil
+			}
+			rec.Pending = nil
+			if !rec.isFile() {
+				return nil, nil
+			}
+			return rec, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown the backend, closing the database
+func (f *Fs) Shutdown(ctx context.Context) error {
+	return f.db.Stop(false)
+}
+
+// uploadURL returns the absolute URL of the upload at location
+func (f *Fs) uploadURL(location string) (string, error) {
+	u, err := f.endpoint.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("bad upload location %q: %w", location, err)
+	}
+	return u.String(), nil
+}
+
+// encodeMetadata makes the Upload-Metadata header from the key value
+// pairs in kvs
+func encodeMetadata(kvs ...string) string {
+	var out []string
+	for i := 0; i < len(kvs); i += 2 {
+		if kvs[i+1] == "" {
+			continue
+		}
+		out = append(out, kvs[i]+" "+base64.StdEncoding.EncodeToString([]byte(kvs[i+1])))
+	}
+	return strings.Join(out, ",")
+}
+
+// create makes a new upload for remote returning its URL
+//
+// size is -1 if it isn't known.
+func (f *Fs) create(ctx context.Context, remote string, size int64, mimeType string) (string, error) {
+	opts := rest.Opts{
+		Method:        "POST",
+		RootURL:       f.endpoint.String(),
+		ContentLength: new(int64),
+		NoResponse:    true,
+		ExtraHeaders: map[string]string{
+			"Upload-Metadata": encodeMetadata(
+				"filename", path.Base(remote),
+				"filetype", mimeType,
+				"relativePath", f.key(remote),
+			),
+		},
+	}
+	if size >= 0 {
+		opts.ExtraHeaders["Upload-Length"] = strconv.FormatInt(size, 10)
+	} else {
+		opts.ExtraHeaders["Upload-Defer-Length"] = "1"
+	}
+	var resp *http.Response
+	err := f.pacer.Call(func() (bool, error) {
+		var err error
+		resp, err = f.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload: %w", err)
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", errors.New("server didn't return the location of the upload")
+	}
+	return f.uploadURL(location)
+}
+
+// offsetOf reads the Upload-Offset header of resp
+func offsetOf(resp *http.Response) (int64, error) {
+	offset, err := strconv.ParseInt(resp.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("bad Upload-Offset %q", resp.Header.Get("Upload-Offset"))
+	}
+	return offset, nil
+}
+
+// head reads how much of the upload at uploadURL the server has
+//
+// This doesn't use the pacer so it can be called from inside a paced
+// call.
+func (f *Fs) head(ctx context.Context, uploadURL string) (resp *http.Response, offset int64, err error) {
+	opts := rest.Opts{
+		Method:     "HEAD",
+		RootURL:    uploadURL,
+		NoResponse: true,
+	}
+	resp, err = f.srv.Call(ctx, &opts)
+	if err != nil {
+		return resp, 0, err
+	}
+	offset, err = offsetOf(resp)
+	return resp, offset, err
+}
+
+// offset returns how much of the upload at uploadURL the server has
+func (f *Fs) offset(ctx context.Context, uploadURL string) (offset int64, err error) {
+	err = f.pacer.Call(func() (bool, error) {
+		var resp *http.Response
+		resp, offset, err = f.head(ctx, uploadURL)
+		return shouldRetry(ctx, resp, err)
+	})
+	return offset, err
+}
+
+// patch sends data which starts at offset in the upload at uploadURL
+// returning the new offset
+//
+// If length isn't -1 it is the size of the file, sent when it wasn't
+// known when the upload was created.
+//
+// If sending fails, the server is asked how much of data it received
+// and only the rest is sent again.
+func (f *Fs) patch(ctx context.Context, uploadURL string, data []byte, offset int64, length int64) (int64, error) {
+	end := offset + int64(len(data))
+	start := offset
+	first := true
+	err := f.pacer.Call(func() (bool, error) {
+		if !first {
+			resp, serverOffset, err := f.head(ctx, uploadURL)
+			if err != nil {
+				return shouldRetry(ctx, resp, err)
+			}
+			if serverOffset < offset || serverOffset > end {
+				return false, fmt.Errorf("server has %d bytes of upload, expecting %d to %d", serverOffset, offset, end)
+			}
+			start = serverOffset
+			if start == end && length < 0 {
+				return false, nil
+			}
+			fs.Debugf(f, "Resending upload from offset %d", start)
+		}
+		first = false
+		part := data[start-offset:]
+		partLength := int64(len(part))
+		opts := rest.Opts{
+			Method:        "PATCH",
+			RootURL:       uploadURL,
+			Body:          bytes.NewReader(part),
+			ContentLength: &partLength,
+			ContentType:   "application/offset+octet-stream",
+			NoResponse:    true,
+			ExtraHeaders: map[string]string{
+				"Upload-Offset": strconv.FormatInt(start, 10),
+			},
+		}
+		if length >= 0 {
+			opts.ExtraHeaders["Upload-Length"] = strconv.FormatInt(length, 10)
+		}
+		if f.newSum != nil && partLength > 0 {
+			sum := f.newSum()
+			_, _ = sum.Write(part)
+			opts.ExtraHeaders["Upload-Checksum"] = f.checksum + " " + base64.StdEncoding.EncodeToString(sum.Sum(nil))
+		}
+		resp, err := f.srv.Call(ctx, &opts)
+		if err != nil {
+			retry, err := shouldRetry(ctx, resp, err)
+			// 409 is a mismatched offset and 460 a mismatched
+			// checksum which a fresh try might fix
+			if resp != nil && (resp.StatusCode == http.StatusConflict || resp.StatusCode == 460) {
+				retry = true
+			}
+			return retry, err
+		}
+		newOffset, err := offsetOf(resp)
+		if err != nil {
+			return false, err
+		}
+		start = newOffset
+		if start != end {
+			return true, fmt.Errorf("server stored upload up to %d, expecting %d", start, end)
+		}
+		return false, nil
+	})
+	return start, err
+}
+
+// upload sends the data from in to the upload at uploadURL starting
+// at offset returning the number of bytes in the upload
+//
+// size is -1 if it isn't known.
+func (f *Fs) upload(ctx context.Context, uploadURL string, in io.Reader, offset int64, size int64) (int64, error) {
+	bufSize := int64(f.opt.ChunkSize)
+	if size >= 0 && size-offset < bufSize {
+		bufSize = size - offset
+	}
+	buf := make([]byte, bufSize)
+	for {
+		want := len(buf)
+		if size >= 0 && size-offset < int64(want) {
+			want = int(size - offset)
+		}
+		n, err := io.ReadFull(in, buf[:want])
+		final := false
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			if size >= 0 {
+				return offset, fmt.Errorf("file was %d bytes, expecting %d", offset+int64(n), size)
+			}
+			final = true
+		case err != nil:
+			return offset, err
+		case size >= 0 && offset+int64(n) == size:
+			final = true
+		}
+		length := int64(-1)
+		if final && size < 0 {
+			length = offset + int64(n)
+		}
+		if n > 0 || length >= 0 {
+			offset, err = f.patch(ctx, uploadURL, buf[:n], offset, length)
+			if err != nil {
+				return offset, err
+			}
+		}
+		if final {
+			return offset, nil
+		}
+	}
+}
+
+// terminate deletes the upload at uploadURL if the server allows it
+func (f *Fs) terminate(ctx context.Context, uploadURL string) error {
+	if !f.extensions[extTermination] {
+		return nil
+	}
+	opts := rest.Opts{
+		Method:     "DELETE",
+		RootURL:    uploadURL,
+		NoResponse: true,
+	}
+	return f.pacer.Call(func() (bool, error) {
+		resp, err := f.srv.Call(ctx, &opts)
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone) {
+			return false, nil
+		}
+		return shouldRetry(ctx, resp, err)
+	})
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the MD5 of an object returning a lowercase hex string
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	if t != hash.MD5 {
+		return "", hash.ErrUnsupported
+	}
+	return o.md5, nil
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	return o.size
+}
+
+// ModTime returns the modification time of the object
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the object
+//
+// This only changes the local record of the upload.
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	err := o.fs.change(o.remote, func(rec *record) (*record, error) {
+		if !rec.isFile() {
+			return nil, fs.ErrorObjectNotFound
+		}
+		rec.ModTime = modTime
+		return rec, nil
+	})
+	if err != nil {
+		return err
+	}
+	o.modTime = modTime
+	return nil
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+//
+// The protocol has no way of downloading so this only works with
+// servers which return the upload to a GET request, like tusd does.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	fs.FixRangeOption(options, o.size)
+	opts := rest.Opts{
+		Method:  "GET",
+		RootURL: o.url,
+		Options: options,
+	}
+	var resp *http.Response
+	err = o.fs.pacer.Call(func() (bool, error) {
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone) {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// A new upload is made each time. If an earlier upload of the same
+// file was interrupted then it is resumed instead.
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	f := o.fs
+	size := src.Size()
+	modTime := src.ModTime(ctx)
+	if size < 0 && !f.extensions[extDeferLength] {
+		return errors.New("can't upload files of unknown size as the server doesn't support the creation-defer-length extension")
+	}
+	if f.maxSize >= 0 && size > f.maxSize {
+		return fmt.Errorf("file is %v which is bigger than the largest upload the server allows of %v", fs.SizeSuffix(size), fs.SizeSuffix(f.maxSize))
+	}
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(hash.MD5))
+	if err != nil {
+		return err
+	}
+	in = io.TeeReader(in, hasher)
+
+	// See if there is an interrupted upload of the same file to
+	// resume
+	var uploadURL string
+	var offset int64
+	rec := f.lookup(o.remote)
+	if p := rec.pendingFor(size, modTime); p != nil {
+		offset, err = f.offset(ctx, p.URL)
+		if err != nil || offset > size {
+			fs.Debugf(o, "Can't resume interrupted upload, starting again: %v", err)
+		} else {
+			fs.Debugf(o, "Resuming interrupted upload from offset %d", offset)
+			_, err = io.CopyN(ioutil.Discard, in, offset)
+			if err != nil {
+				return fmt.Errorf("failed to skip the uploaded part of the file: %w", err)
+			}
+			uploadURL = p.URL
+		}
+	}
+	if uploadURL == "" {
+		if rec.isDir() {
+			return fs.ErrorIsDir
+		}
+		err = f.mkdir(path.Dir(o.remote))
+		if err != nil {
+			return fmt.Errorf("failed to make directory: %w", err)
+		}
+		offset = 0
+		uploadURL, err = f.create(ctx, o.remote, size, fs.MimeType(ctx, src))
+		if err != nil {
+			return err
+		}
+		if size >= 0 {
+			err = f.change(o.remote, func(rec *record) (*record, error) {
+				if rec.isDir() {
+					return nil, fs.ErrorIsDir
+				}
+				if rec == nil {
+					rec = new(record)
+				}
+				rec.Pending = &pending{
+					URL:     uploadURL,
+					Size:    size,
+					ModTime: modTime,
+				}
+				return rec, nil
+			})
+			if err != nil {
+				fs.Debugf(o, "Failed to record upload so it can be resumed: %v", err)
+			}
+		}
+	}
+
+	size, err = f.upload(ctx, uploadURL, in, offset, size)
+	if err != nil {
+		return err
+	}
+
+	// Replace the record with the finished upload
+	newRec := &record{
+		URL:     uploadURL,
+		Size:    size,
+		ModTime: modTime,
+	}
+	newRec.MD5, err = hasher.SumString(hash.MD5, false)
+	if err != nil {
+		return err
+	}
+	var oldURL string
+	err = f.change(o.remote, func(rec *record) (*record, error) {
+		if rec.isDir() {
+			return nil, fs.ErrorIsDir
+		}
+		if rec.isFile() {
+			oldURL = rec.URL
+		}
+		return newRec, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record upload: %w", err)
+	}
+	o.url = newRec.URL
+	o.size = newRec.Size
+	o.modTime = newRec.ModTime
+	o.md5 = newRec.MD5
+	if oldURL != "" && oldURL != uploadURL {
+		if err := f.terminate(ctx, oldURL); err != nil {
+			fs.Debugf(o, "Failed to delete replaced upload: %v", err)
+		}
+	}
+	return nil
+}
+
+// Remove an object
+//
+// The upload is deleted from the server if it supports the
+// termination extension, otherwise it is only forgotten.
+func (o *Object) Remove(ctx context.Context) error {
+	err := o.fs.terminate(ctx, o.url)
+	if err != nil {
+		return err
+	}
+	return o.fs.change(o.remote, func(rec *record) (*record, error) {
+		if !rec.isFile() {
+			return nil, fs.ErrorObjectNotFound
+		}
+		if rec.Pending != nil {
+			rec.URL = ""
+			return rec, nil
+		}
+		return nil, nil
+	})
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs          = (*Fs)(nil)
+	_ fs.PutStreamer = (*Fs)(nil)
+	_ fs.ListRer     = (*Fs)(nil)
+	_ fs.CleanUpper  = (*Fs)(nil)
+	_ fs.Shutdowner  = (*Fs)(nil)
+	_ fs.Object      = (*Object)(nil)
+)
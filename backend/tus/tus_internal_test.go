@@ -0,0 +1,304 @@
+package tus
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUpload is an upload held by testServer
+type testUpload struct {
+	data     []byte
+	length   int64 // -1 if deferred
+	metadata string
+}
+
+// testServer is a TUS server keeping the uploads in memory
+type testServer struct {
+	mu        sync.Mutex
+	uploads   map[string]*testUpload
+	n         int
+	received  int64               // bytes received in PATCH requests
+	failPatch func(n int64) int64 // if set, how many bytes to keep before failing
+	failCode  int                 // status to fail with if not 500
+}
+
+func newTestServer(t *testing.T) (*testServer, *httptest.Server) {
+	s := &testServer{uploads: map[string]*testUpload{}}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// ServeHTTP implements the parts of the protocol the backend uses
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if r.Method != "OPTIONS" && r.Method != "GET" && r.Header.Get("Tus-Resumable") != tusVersion {
+		http.Error(w, "bad Tus-Resumable", http.StatusPreconditionFailed)
+		return
+	}
+	if r.URL.Path == "/files/" {
+		switch r.Method {
+		case "OPTIONS":
+			w.Header().Set("Tus-Version", "1.0.0")
+			w.Header().Set("Tus-Extension", "creation,creation-defer-length,termination,checksum")
+			w.Header().Set("Tus-Checksum-Algorithm", "md5,sha256")
+			w.WriteHeader(http.StatusNoContent)
+		case "POST":
+			u := &testUpload{length: -1, metadata: r.Header.Get("Upload-Metadata")}
+			if r.Header.Get("Upload-Defer-Length") != "1" {
+				var err error
+				u.length, err = strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+				if err != nil {
+					http.Error(w, "bad Upload-Length", http.StatusBadRequest)
+					return
+				}
+			}
+			s.n++
+			name := fmt.Sprintf("upload%d", s.n)
+			s.uploads[name] = u
+			w.Header().Set("Location", name)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, "bad method", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+	u := s.uploads[strings.TrimPrefix(r.URL.Path, "/files/")]
+	if u == nil {
+		http.Error(w, "upload not found", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case "HEAD":
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(u.data)))
+		w.WriteHeader(http.StatusOK)
+	case "GET":
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(u.data))
+	case "DELETE":
+		delete(s.uploads, strings.TrimPrefix(r.URL.Path, "/files/"))
+		w.WriteHeader(http.StatusNoContent)
+	case "PATCH":
+		offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil || offset != int64(len(u.data)) {
+			http.Error(w, "offset mismatch", http.StatusConflict)
+			return
+		}
+		if length := r.Header.Get("Upload-Length"); length != "" {
+			u.length, _ = strconv.ParseInt(length, 10, 64)
+		}
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.received += int64(len(data))
+		if checksum := r.Header.Get("Upload-Checksum"); checksum != "" {
+			sum := sha256.Sum256(data)
+			if checksum != "sha256 "+base64.StdEncoding.EncodeToString(sum[:]) {
+				http.Error(w, "checksum mismatch", 460)
+				return
+			}
+		}
+		if s.failPatch != nil {
+			if keep := s.failPatch(int64(len(data))); keep >= 0 {
+				u.data = append(u.data, data[:keep]...)
+				code := http.StatusInternalServerError
+				if s.failCode != 0 {
+					code = s.failCode
+				}
+				http.Error(w, "something went wrong", code)
+				return
+			}
+		}
+		if u.length >= 0 && int64(len(u.data)+len(data)) > u.length {
+			http.Error(w, "upload too long", http.StatusRequestEntityTooLarge)
+			return
+		}
+		u.data = append(u.data, data...)
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(u.data)))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "bad method", http.StatusMethodNotAllowed)
+	}
+}
+
+// TestLocal runs the integration tests against a test server
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("skipping as -remote is set")
+	}
+	_, ts := newTestServer(t)
+	name := "TestTUSLocal"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*Object)(nil),
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "tus"},
+			{Name: name, Key: "url", Value: ts.URL + "/files/"},
+			{Name: name, Key: "chunk_size", Value: "100b"},
+		},
+		QuickTestOK: true,
+	})
+}
+
+// newTestFs makes an Fs using the test server at ts
+func newTestFs(t *testing.T, ts *httptest.Server) *Fs {
+	ctx := context.Background()
+	f, err := NewFs(ctx, t.Name(), "dir", configmap.Simple{
+		"url":        ts.URL + "/files/",
+		"chunk_size": "1000b",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = f.(*Fs).Shutdown(ctx)
+	})
+	return f.(*Fs)
+}
+
+func TestDiscover(t *testing.T) {
+	_, ts := newTestServer(t)
+	f := newTestFs(t, ts)
+	assert.True(t, f.extensions[extCreation])
+	assert.True(t, f.extensions[extTermination])
+	assert.Equal(t, "sha256", f.checksum)
+	assert.Equal(t, int64(-1), f.maxSize)
+	assert.NotNil(t, f.Features().PutStream)
+}
+
+func TestResumeInterruptedPatch(t *testing.T) {
+	ctx := context.Background()
+	s, ts := newTestServer(t)
+	f := newTestFs(t, ts)
+	data := bytes.Repeat([]byte("0123456789"), 250)
+	failed := false
+	s.failPatch = func(n int64) int64 {
+		if failed {
+			return -1
+		}
+		failed = true
+		return n / 2
+	}
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), int64(len(data)), true, nil, nil)
+	o, err := f.Put(ctx, bytes.NewReader(data), src)
+	require.NoError(t, err)
+	assert.True(t, failed)
+	// Only the half of the first part which didn't get through is
+	// sent again
+	assert.Equal(t, int64(len(data)+500), s.received)
+	assert.Equal(t, data, s.uploads["upload1"].data)
+	assert.Equal(t, `filename ZmlsZS50eHQ=,filetype dGV4dC9wbGFpbjsgY2hhcnNldD11dGYtOA==,relativePath ZGlyL2ZpbGUudHh0`, s.uploads["upload1"].metadata)
+	assert.Equal(t, int64(len(data)), o.Size())
+	rec := f.lookup("file.txt")
+	require.NotNil(t, rec)
+	assert.Nil(t, rec.Pending)
+}
+
+func TestResumeInterruptedUpload(t *testing.T) {
+	ctx := context.Background()
+	s, ts := newTestServer(t)
+	f := newTestFs(t, ts)
+	data := bytes.Repeat([]byte("0123456789"), 250)
+	modTime := time.Date(2022, 9, 10, 11, 12, 13, 0, time.UTC)
+	src := object.NewStaticObjectInfo("file.txt", modTime, int64(len(data)), true, nil, nil)
+
+	// the server breaks after the first part
+	s.failCode = http.StatusBadRequest
+	s.failPatch = func(n int64) int64 {
+		if len(s.uploads["upload1"].data) > 0 {
+			return 0
+		}
+		return -1
+	}
+	_, err := f.Put(ctx, bytes.NewReader(data), src)
+	require.Error(t, err)
+	_, err = f.NewObject(ctx, "file.txt")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	require.Equal(t, 1000, len(s.uploads["upload1"].data))
+
+	// the next upload of the same file carries on from there
+	s.failPatch = nil
+	s.received = 0
+	o, err := f.Put(ctx, bytes.NewReader(data), src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)-1000), s.received)
+	assert.Equal(t, data, s.uploads["upload1"].data)
+	assert.Equal(t, 1, s.n)
+	// the skipped part is included in the hash
+	sum := md5.Sum(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), o.(*Object).md5)
+
+	// a different file starts a new upload and replaces the old one
+	data = []byte("hello")
+	src = object.NewStaticObjectInfo("file.txt", modTime, int64(len(data)), true, nil, nil)
+	_, err = f.Put(ctx, bytes.NewReader(data), src)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.n)
+	assert.Nil(t, s.uploads["upload1"])
+	assert.Equal(t, data, s.uploads["upload2"].data)
+}
+
+func TestCleanUp(t *testing.T) {
+	ctx := context.Background()
+	s, ts := newTestServer(t)
+	f := newTestFs(t, ts)
+	s.failCode = http.StatusBadRequest
+	s.failPatch = func(n int64) int64 { return 0 }
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), 10, true, nil, nil)
+	_, err := f.Put(ctx, bytes.NewReader([]byte("0123456789")), src)
+	require.Error(t, err)
+	require.NotNil(t, s.uploads["upload1"])
+	require.NoError(t, f.CleanUp(ctx))
+	assert.Nil(t, s.uploads["upload1"])
+	assert.Nil(t, f.lookup("file.txt"))
+}
+
+func TestUnknownSize(t *testing.T) {
+	ctx := context.Background()
+	s, ts := newTestServer(t)
+	f := newTestFs(t, ts)
+	for _, size := range []int{0, 999, 1000, 2500} {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			data := bytes.Repeat([]byte("x"), size)
+			src := object.NewStaticObjectInfo("stream.txt", time.Now(), -1, true, nil, nil)
+			o, err := f.PutStream(ctx, bytes.NewReader(data), src)
+			require.NoError(t, err)
+			assert.Equal(t, int64(size), o.Size())
+			u := s.uploads[fmt.Sprintf("upload%d", s.n)]
+			assert.Equal(t, int64(size), u.length)
+			assert.True(t, bytes.Equal(data, u.data))
+		})
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	err := errorHandler(&http.Response{
+		Status:     "404 Not Found",
+		StatusCode: http.StatusNotFound,
+		Body:       ioutil.NopCloser(strings.NewReader("upload not found\n")),
+	})
+	assert.Equal(t, errors.New("HTTP error 404 Not Found: upload not found"), err)
+}
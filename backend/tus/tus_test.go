@@ -0,0 +1,17 @@
+// Test TUS filesystem interface
+package tus_test
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/backend/tus"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: "TestTUS:",
+		NilObject:  (*tus.Object)(nil),
+	})
+}
@@ -0,0 +1,113 @@
+package policy
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rclone/rclone/backend/union/upstream"
+	"github.com/rclone/rclone/fs"
+)
+
+func init() {
+	registerPolicy("qmfs", &Qmfs{})
+}
+
+// Qmfs stands for quota, most free space
+// Search category: same as epmfs.
+// Action category: same as epmfs.
+// Create category: Pick the upstream with the most free space as reported
+// by About, ignoring upstreams which don't report it or which have less
+// than min_free_space free.
+type Qmfs struct {
+	EpMfs
+}
+
+var errNoQuotaFound = errors.New("no upstreams found which report their free space")
+
+// quotaFree returns the free space of u or false if it shouldn't be used
+//
+// It returns an error if u doesn't report its free space.
+func quotaFree(u *upstream.Fs) (int64, bool, error) {
+	space, err := u.GetQuotaFreeSpace()
+	if err != nil {
+		fs.Debugf(nil, "Free space is not reported for upstream %s, ignoring it", u.Name())
+		return 0, false, err
+	}
+	if space <= int64(u.Opt.MinFreeSpace) {
+		fs.Debugf(nil, "Upstream %s has %v free which is less than min_free_space", u.Name(), fs.SizeSuffix(space))
+		return space, false, nil
+	}
+	return space, true, nil
+}
+
+func (p *Qmfs) qmfs(upstreams []*upstream.Fs) (*upstream.Fs, error) {
+	var maxFreeSpace int64 = -1
+	var qmfsupstream *upstream.Fs
+	quotaFound := false
+	for _, u := range upstreams {
+		space, ok, err := quotaFree(u)
+		if err == nil {
+			quotaFound = true
+		}
+		if ok && space > maxFreeSpace {
+			maxFreeSpace = space
+			qmfsupstream = u
+		}
+	}
+	if qmfsupstream == nil {
+		if !quotaFound {
+			return nil, errNoQuotaFound
+		}
+		return nil, errNoUpstreamsFound
+	}
+	return qmfsupstream, nil
+}
+
+func (p *Qmfs) qmfsEntries(entries []upstream.Entry) (upstream.Entry, error) {
+	var maxFreeSpace int64 = -1
+	var qmfsEntry upstream.Entry
+	quotaFound := false
+	for _, e := range entries {
+		space, ok, err := quotaFree(e.UpstreamFs())
+		if err == nil {
+			quotaFound = true
+		}
+		if ok && space > maxFreeSpace {
+			maxFreeSpace = space
+			qmfsEntry = e
+		}
+	}
+	if qmfsEntry == nil {
+		if !quotaFound {
+			return nil, errNoQuotaFound
+		}
+		return nil, errNoUpstreamsFound
+	}
+	return qmfsEntry, nil
+}
+
+// Create category policy, governing the creation of files and directories
+func (p *Qmfs) Create(ctx context.Context, upstreams []*upstream.Fs, path string) ([]*upstream.Fs, error) {
+	if len(upstreams) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	upstreams = filterNC(upstreams)
+	if len(upstreams) == 0 {
+		return nil, fs.ErrorPermissionDenied
+	}
+	u, err := p.qmfs(upstreams)
+	return []*upstream.Fs{u}, err
+}
+
+// CreateEntries is CREATE category policy but receiving a set of candidate entries
+func (p *Qmfs) CreateEntries(entries ...upstream.Entry) ([]upstream.Entry, error) {
+	if len(entries) == 0 {
+		return nil, fs.ErrorObjectNotFound
+	}
+	entries = filterNCEntries(entries)
+	if len(entries) == 0 {
+		return nil, fs.ErrorPermissionDenied
+	}
+	e, err := p.qmfsEntries(entries)
+	return []upstream.Entry{e}, err
+}
@@ -55,10 +55,10 @@ func init() {
 			Default: 120,
 		}, {
 			Name: "min_free_space",
-			Help: `Minimum viable free space for lfs/eplfs policies.
+			Help: `Minimum viable free space for lfs/eplfs/qmfs policies.
 
 If a remote has less than this much free space then it won't be
-considered for use in lfs or eplfs policies.`,
+considered for use in lfs, eplfs or qmfs policies.`,
 			Advanced: true,
 			Default:  fs.Gibi,
 		}},
@@ -641,6 +641,39 @@ func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err e
 	return f.mergeDirEntries(entriesList)
 }
 
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// Entries from different upstreams with the same name have to be
+// merged so the listing can only be paged through when there is a
+// single upstream which supports ListP. Otherwise the whole directory
+// is listed and returned as one page.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	if len(f.upstreams) != 1 || f.upstreams[0].Features().ListP == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	u := f.upstreams[0]
+	return u.Features().ListP(ctx, dir, func(entries fs.DirEntries) error {
+		newEntries := make(fs.DirEntries, 0, len(entries))
+		for _, e := range entries {
+			uEntry, err := u.WrapEntry(e)
+			if err != nil {
+				return err
+			}
+			entry, err := f.wrapEntries(uEntry)
+			if err != nil {
+				return err
+			}
+			newEntries = append(newEntries, entry)
+		}
+		return callback(newEntries)
+	})
+}
+
 // ListR lists the objects and directories of the Fs starting
 // from dir recursively into out.
 //
@@ -963,5 +996,6 @@ var (
 	_ fs.ChangeNotifier  = (*Fs)(nil)
 	_ fs.Abouter         = (*Fs)(nil)
 	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
 	_ fs.Shutdowner      = (*Fs)(nil)
 )
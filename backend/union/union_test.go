@@ -154,3 +154,26 @@ func TestPolicy3(t *testing.T) {
 		QuickTestOK:                  true,
 	})
 }
+
+func TestPolicy4(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	dirs := union.MakeTestDirs(t, 3)
+	upstreams := dirs[0] + " " + dirs[1] + " " + dirs[2]
+	name := "TestUnionPolicy4"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "union"},
+			{Name: name, Key: "upstreams", Value: upstreams},
+			{Name: name, Key: "action_policy", Value: "epall"},
+			{Name: name, Key: "create_policy", Value: "qmfs"},
+			{Name: name, Key: "search_policy", Value: "ff"},
+			{Name: name, Key: "min_free_space", Value: "1M"},
+		},
+		UnimplementableFsMethods:     []string{"OpenWriterAt", "DuplicateFiles"},
+		UnimplementableObjectMethods: []string{"MimeType"},
+		QuickTestOK:                  true,
+	})
+}
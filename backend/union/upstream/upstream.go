@@ -326,6 +326,34 @@ func (f *Fs) GetFreeSpace() (int64, error) {
 	return *f.usage.Free, nil
 }
 
+// GetQuotaFreeSpace get the free space of the fs as reported by
+// About, working it out from the total and used space if the free
+// space isn't reported
+//
+// Unlike GetFreeSpace this returns an error rather than a sentinel if
+// the free space can't be found.
+func (f *Fs) GetQuotaFreeSpace() (int64, error) {
+	if atomic.LoadInt64(&f.cacheExpiry) <= time.Now().Unix() {
+		err := f.updateUsage()
+		if err != nil {
+			return 0, ErrUsageFieldNotSupported
+		}
+	}
+	f.cacheMutex.RLock()
+	defer f.cacheMutex.RUnlock()
+	switch {
+	case f.usage.Free != nil:
+		return *f.usage.Free, nil
+	case f.usage.Total != nil && f.usage.Used != nil:
+		free := *f.usage.Total - *f.usage.Used
+		if free < 0 {
+			free = 0
+		}
+		return free, nil
+	}
+	return 0, ErrUsageFieldNotSupported
+}
+
 // GetUsedSpace get the used space of the fs
 //
 // This is returned as 0..math.MaxInt64-1 leaving math.MaxInt64 as a sentinel
@@ -0,0 +1,149 @@
+package warmer
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"path"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// record is what is kept in the database for each file
+type record struct {
+	Score  float64   `json:"score"`  // decayed number of accesses as of Last
+	Last   time.Time `json:"last"`   // time of the last access
+	Pinned bool      `json:"pinned"` // set if the file is pinned in the cache
+}
+
+// scoreAt returns the score of the record decayed to now
+func (r *record) scoreAt(now time.Time, halfLife time.Duration) float64 {
+	if r.Last.IsZero() || halfLife <= 0 {
+		return r.Score
+	}
+	elapsed := now.Sub(r.Last)
+	if elapsed <= 0 {
+		return r.Score
+	}
+	return r.Score * math.Pow(0.5, float64(elapsed)/float64(halfLife))
+}
+
+// accessKey returns the database key for remote
+func (f *Fs) accessKey(remote string) string {
+	return path.Join(f.Fs.Root(), remote)
+}
+
+// touch records that remote has just been accessed and returns its
+// new score and whether it is pinned
+func (f *Fs) touch(remote string) (rec record) {
+	op := &kvTouch{
+		key:      f.accessKey(remote),
+		now:      time.Now(),
+		halfLife: time.Duration(f.opt.HalfLife),
+	}
+	if err := f.db.Do(true, op); err != nil {
+		fs.Debugf(remote, "Failed to record access: %v", err)
+	}
+	return op.rec
+}
+
+// lookup returns the record for remote
+func (f *Fs) lookup(remote string) (rec record) {
+	op := &kvGet{
+		key: f.accessKey(remote),
+	}
+	if err := f.db.Do(false, op); err != nil {
+		fs.Debugf(remote, "Failed to read access record: %v", err)
+	}
+	return op.rec
+}
+
+// setPinned sets or clears the pinned flag for remote
+func (f *Fs) setPinned(remote string, pinned bool) error {
+	return f.db.Do(true, &kvPin{
+		key:    f.accessKey(remote),
+		pinned: pinned,
+	})
+}
+
+// forget removes the record for remote
+func (f *Fs) forget(remote string) {
+	if err := f.db.Do(true, &kvForget{key: f.accessKey(remote)}); err != nil {
+		fs.Debugf(remote, "Failed to forget access record: %v", err)
+	}
+}
+
+// getRecord reads the record for key from b
+func getRecord(b kv.Bucket, key string) (rec record, err error) {
+	data := b.Get([]byte(key))
+	if data == nil {
+		return rec, nil
+	}
+	err = json.Unmarshal(data, &rec)
+	return rec, err
+}
+
+// putRecord writes rec for key to b
+func putRecord(b kv.Bucket, key string, rec record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), data)
+}
+
+// kvTouch: count an access to a key
+type kvTouch struct {
+	key      string
+	now      time.Time
+	halfLife time.Duration
+	rec      record
+}
+
+func (op *kvTouch) Do(ctx context.Context, b kv.Bucket) error {
+	rec, err := getRecord(b, op.key)
+	if err != nil {
+		return err
+	}
+	rec.Score = rec.scoreAt(op.now, op.halfLife) + 1
+	rec.Last = op.now
+	op.rec = rec
+	return putRecord(b, op.key, rec)
+}
+
+// kvGet: read the record for a key
+type kvGet struct {
+	key string
+	rec record
+}
+
+func (op *kvGet) Do(ctx context.Context, b kv.Bucket) (err error) {
+	op.rec, err = getRecord(b, op.key)
+	return err
+}
+
+// kvPin: set the pinned flag for a key
+type kvPin struct {
+	key    string
+	pinned bool
+}
+
+func (op *kvPin) Do(ctx context.Context, b kv.Bucket) error {
+	rec, err := getRecord(b, op.key)
+	if err != nil {
+		return err
+	}
+	rec.Pinned = op.pinned
+	return putRecord(b, op.key, rec)
+}
+
+// kvForget: remove the record for a key
+type kvForget struct {
+	key string
+}
+
+func (op *kvForget) Do(ctx context.Context, b kv.Bucket) error {
+	return b.Delete([]byte(op.key))
+}
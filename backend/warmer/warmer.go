@@ -0,0 +1,821 @@
+// Package warmer implements a backend which keeps copies of
+// frequently used files on a faster remote
+package warmer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "warmer",
+		Description: "Keep frequently used files on a faster remote",
+		NewFs:       NewFs,
+		CommandHelp: commandHelp,
+		MetadataInfo: &fs.MetadataInfo{
+			Help: `Any metadata supported by the underlying remote is read and written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote which holds the files (the origin).
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name:     "cache_remote",
+			Required: true,
+			Help:     `Faster remote to keep copies of hot files on, e.g. a local directory.`,
+		}, {
+			Name:    "hot_accesses",
+			Default: 3,
+			Help: `Number of recent opens which make a file hot.
+
+Hot files are copied to the cache remote in the background.
+Set to 0 to only cache pinned files.`,
+		}, {
+			Name:    "max_cache_size",
+			Default: fs.SizeSuffix(10 * 1024 * 1024 * 1024),
+			Help: `Maximum total size of the files kept on the cache remote.
+
+When this is exceeded the least used files which aren't pinned are
+removed from the cache. Set to "off" for no limit.`,
+		}, {
+			Name:     "half_life",
+			Default:  fs.Duration(24 * time.Hour),
+			Advanced: true,
+			Help: `Time for the count of accesses to a file to fall by half.
+
+Files need to be opened more often than this to stay hot.`,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote       string        `config:"remote"`
+	CacheRemote  string        `config:"cache_remote"`
+	HotAccesses  int           `config:"hot_accesses"`
+	MaxCacheSize fs.SizeSuffix `config:"max_cache_size"`
+	HalfLife     fs.Duration   `config:"half_life"`
+}
+
+// Fs represents an origin remote with hot files copied to a cache remote
+type Fs struct {
+	fs.Fs          // the origin
+	cache    fs.Fs // the cache remote
+	name     string
+	root     string
+	opt      Options
+	features *fs.Features // optional features
+	wrapper  fs.Fs
+	db       *kv.DB // access records
+
+	mu       sync.Mutex
+	inflight map[string]struct{} // files being copied to the cache
+	wg       sync.WaitGroup      // background copies running
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	if !kv.Supported() {
+		return nil, errors.New("warmer is not supported on this OS")
+	}
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") || strings.HasPrefix(opt.CacheRemote, name+":") {
+		return nil, errors.New("can't point warmer remote at itself - check the value of the remote settings")
+	}
+	f := &Fs{
+		name:     name,
+		root:     rpath,
+		opt:      opt,
+		inflight: map[string]struct{}{},
+	}
+	var baseErr error
+	f.Fs, baseErr = cache.Get(ctx, fspath.JoinRootPath(opt.Remote, rpath))
+	if baseErr != nil && baseErr != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", opt.Remote, baseErr)
+	}
+	cacheRoot := rpath
+	if baseErr == fs.ErrorIsFile {
+		// The origin points at the parent directory of the file
+		cacheRoot = parentDir(rpath)
+	}
+	f.cache, err = cache.Get(ctx, fspath.JoinRootPath(opt.CacheRemote, cacheRoot))
+	if err != nil && err != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make cache remote %q: %w", opt.CacheRemote, err)
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+	f.db, err = kv.Start(ctx, "warmer", f.Fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access database: %w", err)
+	}
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		SetTier:                 true,
+		GetTier:                 true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, f.Fs).WrapsFs(f, f.Fs)
+	// Always needed to wait for background copies and close the database
+	f.features.Shutdown = f.Shutdown
+	return f, baseErr
+}
+
+// parentDir returns the parent directory of p or "" if none
+func parentDir(p string) string {
+	p = strings.Trim(p, "/")
+	i := strings.LastIndex(p, "/")
+	if i < 0 {
+		return ""
+	}
+	return p[:i]
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("warmer root '%s'", f.root)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// cached returns the copy of o in the cache if it is up to date
+func (f *Fs) cached(ctx context.Context, o fs.Object) fs.Object {
+	c, err := f.cache.NewObject(ctx, o.Remote())
+	if err != nil {
+		return nil
+	}
+	if c.Size() != o.Size() {
+		return nil
+	}
+	dt := c.ModTime(ctx).Sub(o.ModTime(ctx))
+	if dt < 0 {
+		dt = -dt
+	}
+	if dt > fs.GetModifyWindow(ctx, f.cache, f.Fs) {
+		return nil
+	}
+	return c
+}
+
+// isHot returns true if rec has had enough recent accesses to be
+// cached
+func (f *Fs) isHot(rec record) bool {
+	// Round the score as a run of accesses will have decayed a
+	// little by the time of the last one
+	return f.opt.HotAccesses > 0 && math.Round(rec.Score) >= float64(f.opt.HotAccesses)
+}
+
+// warm copies o to the cache, returning the cached object
+func (f *Fs) warm(ctx context.Context, o fs.Object) (fs.Object, error) {
+	if f.opt.MaxCacheSize >= 0 && o.Size() > int64(f.opt.MaxCacheSize) {
+		return nil, fmt.Errorf("file is bigger than max_cache_size %v", f.opt.MaxCacheSize)
+	}
+	if c := f.cached(ctx, o); c != nil {
+		return c, nil
+	}
+	c, err := operations.Copy(ctx, f.cache, nil, o.Remote(), o)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.evict(ctx); err != nil {
+		fs.Errorf(f, "Failed to trim cache: %v", err)
+	}
+	return c, nil
+}
+
+// warmInBackground copies o to the cache without waiting for it
+func (f *Fs) warmInBackground(ctx context.Context, o fs.Object) {
+	remote := o.Remote()
+	f.mu.Lock()
+	if _, found := f.inflight[remote]; found {
+		f.mu.Unlock()
+		return
+	}
+	f.inflight[remote] = struct{}{}
+	f.mu.Unlock()
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		defer func() {
+			f.mu.Lock()
+			delete(f.inflight, remote)
+			f.mu.Unlock()
+		}()
+		// Don't let the background copy be cancelled with the
+		// request which started it
+		bgCtx := fs.CopyConfig(context.Background(), ctx)
+		fs.Debugf(o, "Copying hot file to cache")
+		if _, err := f.warm(bgCtx, o); err != nil {
+			fs.Errorf(o, "Failed to copy hot file to cache: %v", err)
+		}
+	}()
+}
+
+// evict removes the least used files which aren't pinned from the
+// cache until it is smaller than max_cache_size
+func (f *Fs) evict(ctx context.Context) error {
+	if f.opt.MaxCacheSize < 0 {
+		return nil
+	}
+	type item struct {
+		o     fs.Object
+		score float64
+	}
+	var (
+		items []item
+		total int64
+		now   = time.Now()
+	)
+	err := operations.ListFn(ctx, f.cache, func(o fs.Object) {
+		total += o.Size()
+		rec := f.lookup(o.Remote())
+		if !rec.Pinned {
+			items = append(items, item{o: o, score: rec.scoreAt(now, time.Duration(f.opt.HalfLife))})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].score < items[j].score
+	})
+	for _, it := range items {
+		if total <= int64(f.opt.MaxCacheSize) {
+			break
+		}
+		fs.Debugf(it.o, "Removing from cache")
+		if err := it.o.Remove(ctx); err != nil {
+			return err
+		}
+		total -= it.o.Size()
+	}
+	return nil
+}
+
+// invalidate removes any cached copy of remote
+func (f *Fs) invalidate(ctx context.Context, remote string) {
+	c, err := f.cache.NewObject(ctx, remote)
+	if err != nil {
+		return
+	}
+	if err := c.Remove(ctx); err != nil {
+		fs.Errorf(c, "Failed to remove stale copy from cache: %v", err)
+	}
+}
+
+// wrapEntries wraps the objects in entries
+func (f *Fs) wrapEntries(entries fs.DirEntries) fs.DirEntries {
+	for i, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			entries[i] = f.newObject(o)
+		}
+	}
+	return entries
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return f.wrapEntries(entries), nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	return f.Fs.Features().ListR(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.wrapEntries(entries))
+	})
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+//
+// Objects in the pages are only warmed when they are opened.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	do := f.Fs.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	return do(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.wrapEntries(entries))
+	})
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	f.invalidate(ctx, src.Remote())
+	o, err := f.Fs.Put(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutStream
+	if do == nil {
+		return nil, errors.New("PutStream not supported")
+	}
+	f.invalidate(ctx, src.Remote())
+	o, err := do(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// PutUnchecked uploads the object, allowing duplicates
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutUnchecked
+	if do == nil {
+		return nil, errors.New("PutUnchecked not supported")
+	}
+	f.invalidate(ctx, src.Remote())
+	o, err := do(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Purge all files in the directory specified
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	if err := do(ctx, dir); err != nil {
+		return err
+	}
+	if err := operations.Purge(ctx, f.cache, dir); err != nil && !errors.Is(err, fs.ErrorDirNotFound) {
+		fs.Errorf(f, "Failed to purge %q from cache: %v", dir, err)
+	}
+	return nil
+}
+
+// Copy src to this remote using server-side copy operations.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	f.invalidate(ctx, remote)
+	o, err := do(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// Move src to this remote using server-side move operations.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	f.invalidate(ctx, remote)
+	o, err := do(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	srcObj.f.invalidate(ctx, src.Remote())
+	srcObj.f.forget(src.Remote())
+	return f.newObject(o), nil
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	if err := do(ctx, srcFs.Fs, srcRemote, dstRemote); err != nil {
+		return err
+	}
+	if err := operations.Purge(ctx, srcFs.cache, srcRemote); err != nil && !errors.Is(err, fs.ErrorDirNotFound) {
+		fs.Errorf(srcFs, "Failed to purge %q from cache: %v", srcRemote, err)
+	}
+	return nil
+}
+
+// MergeDirs merges the contents of all the directories passed
+// in into the first one and rmdirs the other directories.
+func (f *Fs) MergeDirs(ctx context.Context, dirs []fs.Directory) error {
+	do := f.Fs.Features().MergeDirs
+	if do == nil {
+		return errors.New("MergeDirs not supported")
+	}
+	return do(ctx, dirs)
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	if do := f.Fs.Features().ChangeNotify; do != nil {
+		do(ctx, notifyFunc, pollIntervalChan)
+	}
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	do := f.Fs.Features().PublicLink
+	if do == nil {
+		return "", errors.New("PublicLink not supported")
+	}
+	return do(ctx, remote, expire, unlink)
+}
+
+// UserInfo returns info about the connected user
+func (f *Fs) UserInfo(ctx context.Context) (map[string]string, error) {
+	do := f.Fs.Features().UserInfo
+	if do == nil {
+		return nil, fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Disconnect the current user
+func (f *Fs) Disconnect(ctx context.Context) error {
+	do := f.Fs.Features().Disconnect
+	if do == nil {
+		return fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) (err error) {
+	f.wg.Wait()
+	err = f.db.Stop(false)
+	for _, u := range []fs.Fs{f.Fs, f.cache} {
+		if do := u.Features().Shutdown; do != nil {
+			if err2 := do(ctx); err2 != nil {
+				err = err2
+			}
+		}
+	}
+	return err
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "pin",
+	Short: "Copy files to the cache and keep them there.",
+	Long: `Pinned files are copied to the cache remote now and are never
+removed from it to make space.
+
+Usage Example:
+
+    rclone backend pin warmer: file1 dir/file2
+`,
+}, {
+	Name:  "unpin",
+	Short: "Allow files to be removed from the cache again.",
+	Long: `Usage Example:
+
+    rclone backend unpin warmer: file1 dir/file2
+`,
+}, {
+	Name:  "status",
+	Short: "Show the cache status of files.",
+	Long: `This shows whether each file is in the cache, whether it is pinned
+and its access score. Files are hot when their score reaches
+hot_accesses.
+
+Usage Example:
+
+    rclone backend status warmer: file1 dir/file2
+`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "pin":
+		for _, remote := range arg {
+			o, err := f.Fs.NewObject(ctx, remote)
+			if err != nil {
+				return nil, fmt.Errorf("failed to pin %q: %w", remote, err)
+			}
+			if err := f.setPinned(remote, true); err != nil {
+				return nil, err
+			}
+			if _, err := f.warm(ctx, o); err != nil {
+				return nil, fmt.Errorf("failed to pin %q: %w", remote, err)
+			}
+		}
+		return nil, nil
+	case "unpin":
+		for _, remote := range arg {
+			if err := f.setPinned(remote, false); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case "status":
+		return f.status(ctx, arg)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// fileStatus is the output of the status command for a file
+type fileStatus struct {
+	Cached bool    `json:"cached"`
+	Pinned bool    `json:"pinned"`
+	Score  float64 `json:"score"`
+}
+
+// status returns the cache status of the files in arg
+func (f *Fs) status(ctx context.Context, arg []string) (map[string]fileStatus, error) {
+	out := make(map[string]fileStatus, len(arg))
+	now := time.Now()
+	for _, remote := range arg {
+		o, err := f.Fs.NewObject(ctx, remote)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find %q: %w", remote, err)
+		}
+		rec := f.lookup(remote)
+		out[remote] = fileStatus{
+			Cached: f.cached(ctx, o) != nil,
+			Pinned: rec.Pinned,
+			Score:  rec.scoreAt(now, time.Duration(f.opt.HalfLife)),
+		}
+	}
+	return out, nil
+}
+
+// Object describes a file on the origin which may have a copy in
+// the cache
+type Object struct {
+	fs.Object
+	f *Fs
+}
+
+// newObject wraps o
+func (f *Fs) newObject(o fs.Object) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// Open opens the file for read, from the cache if it is there
+//
+// Files which have become hot are copied to the cache in the
+// background.
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	rec := o.f.touch(o.Remote())
+	if c := o.f.cached(ctx, o.Object); c != nil {
+		in, err := c.Open(ctx, options...)
+		if err == nil {
+			fs.Debugf(o, "Reading from cache")
+			return in, nil
+		}
+		fs.Debugf(o, "Failed to open cached copy - reading from origin: %v", err)
+	}
+	if rec.Pinned || o.f.isHot(rec) {
+		o.f.warmInBackground(ctx, o.Object)
+	}
+	return o.Object.Open(ctx, options...)
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	o.f.invalidate(ctx, o.Remote())
+	return o.Object.Update(ctx, in, src, options...)
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	err := o.Object.Remove(ctx)
+	if err != nil {
+		return err
+	}
+	o.f.invalidate(ctx, o.Remote())
+	o.f.forget(o.Remote())
+	return nil
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	do, ok := o.Object.(fs.IDer)
+	if !ok {
+		return ""
+	}
+	return do.ID()
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	do, ok := o.Object.(fs.MimeTyper)
+	if !ok {
+		return ""
+	}
+	return do.MimeType(ctx)
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	do, ok := o.Object.(fs.SetTierer)
+	if !ok {
+		return errors.New("SetTier not supported")
+	}
+	return do.SetTier(tier)
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *Object) GetTier() string {
+	do, ok := o.Object.(fs.GetTierer)
+	if !ok {
+		return ""
+	}
+	return do.GetTier()
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.PutUncheckeder  = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.MergeDirser     = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.UserInfoer      = (*Fs)(nil)
+	_ fs.Disconnecter    = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.FullObject      = (*Object)(nil)
+)
@@ -0,0 +1,127 @@
+package warmer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs returns a warmer remote with the extra config given whose
+// origin and cache are both empty. It is shut down at the end of the
+// test so the background copies finish and the database is closed
+// before the directories are removed.
+func newTestFs(t *testing.T, config string) *Fs {
+	f, _ := fstest.NewWrappingFs(t, "warmer", fmt.Sprintf(`cache_remote="%s",%s`, t.TempDir(), config))
+	return f.(*Fs)
+}
+
+func read(t *testing.T, o fs.Object) string {
+	in, err := o.Open(context.Background())
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	return string(data)
+}
+
+func isCached(f *Fs, remote string) bool {
+	_, err := f.cache.NewObject(context.Background(), remote)
+	return err == nil
+}
+
+func TestHotFiles(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t, "hot_accesses=2")
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/file.txt", ModTime: time.Now()}, "hello", true)
+
+	assert.Equal(t, "hello", read(t, o))
+	f.wg.Wait()
+	assert.False(t, isCached(f, "dir/file.txt"))
+
+	// the second read makes it hot
+	assert.Equal(t, "hello", read(t, o))
+	f.wg.Wait()
+	assert.True(t, isCached(f, "dir/file.txt"))
+	assert.Equal(t, "hello", read(t, o))
+
+	// updating the file removes the stale copy
+	src := object.NewStaticObjectInfo("dir/file.txt", time.Now(), 7, true, nil, nil)
+	require.NoError(t, o.Update(context.Background(), strings.NewReader("goodbye"), src))
+	assert.False(t, isCached(f, "dir/file.txt"))
+	assert.Equal(t, "goodbye", read(t, o))
+	f.wg.Wait()
+	assert.True(t, isCached(f, "dir/file.txt"))
+
+	// as does removing it
+	require.NoError(t, o.Remove(context.Background()))
+	assert.False(t, isCached(f, "dir/file.txt"))
+}
+
+func TestStaleCopyIgnored(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t, "hot_accesses=0")
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "file.txt", ModTime: time.Now()}, "origin", true)
+
+	// a copy in the cache which doesn't match isn't used
+	_ = fstests.PutTestContents(ctx, t, f.cache, &fstest.Item{Path: "file.txt", ModTime: time.Now()}, "stale!", true)
+	assert.Equal(t, "origin", read(t, o))
+
+	// but one which matches is
+	src := object.NewStaticObjectInfo("file.txt", o.ModTime(ctx), 6, true, nil, nil)
+	_, err := f.cache.Put(ctx, strings.NewReader("cached"), src)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", read(t, o))
+}
+
+func TestEviction(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t, "hot_accesses=1,max_cache_size=10B")
+	a := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "a.txt", ModTime: time.Now()}, "aaaaaa", true)
+	b := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "b.txt", ModTime: time.Now()}, "bbbbbb", true)
+
+	// a is read more so b is evicted when both don't fit
+	read(t, a)
+	f.wg.Wait()
+	read(t, a)
+	f.wg.Wait()
+	read(t, b)
+	f.wg.Wait()
+	assert.True(t, isCached(f, "a.txt"))
+	assert.False(t, isCached(f, "b.txt"))
+
+	// pinned files stay put
+	_, err := f.Command(ctx, "pin", []string{"b.txt"}, nil)
+	require.NoError(t, err)
+	assert.True(t, isCached(f, "b.txt"))
+	assert.False(t, isCached(f, "a.txt"))
+
+	out, err := f.Command(ctx, "status", []string{"a.txt", "b.txt"}, nil)
+	require.NoError(t, err)
+	status := out.(map[string]fileStatus)
+	assert.False(t, status["a.txt"].Cached)
+	assert.True(t, status["b.txt"].Cached)
+	assert.True(t, status["b.txt"].Pinned)
+	assert.InDelta(t, 2, status["a.txt"].Score, 0.01)
+
+	_, err = f.Command(ctx, "unpin", []string{"b.txt"}, nil)
+	require.NoError(t, err)
+	assert.False(t, f.lookup("b.txt").Pinned)
+}
+
+func TestScoreDecay(t *testing.T) {
+	now := time.Now()
+	rec := record{Score: 4, Last: now.Add(-2 * time.Hour)}
+	assert.InDelta(t, 1, rec.scoreAt(now, time.Hour), 1e-9)
+	assert.InDelta(t, 4, rec.scoreAt(now, 0), 1e-9)
+}
@@ -0,0 +1,49 @@
+// Test Warmer filesystem interface
+package warmer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/backend/warmer"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+)
+
+// TestIntegration runs integration tests against the remote
+func TestIntegration(t *testing.T) {
+	if *fstest.RemoteName == "" {
+		t.Skip("Skipping as -remote not set")
+	}
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: *fstest.RemoteName,
+		NilObject:  (*warmer.Object)(nil),
+	})
+}
+
+// TestLocal runs integration tests against a local directory with
+// every file opened more than once cached
+func TestLocal(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping as -remote set")
+	}
+	name := "TestWarmer"
+	fstests.Run(t, &fstests.Opt{
+		RemoteName: name + ":",
+		NilObject:  (*warmer.Object)(nil),
+		UnimplementableFsMethods: []string{
+			"OpenWriterAt",
+			"UserInfo",
+			"Disconnect",
+		},
+		ExtraConfig: []fstests.ExtraConfigItem{
+			{Name: name, Key: "type", Value: "warmer"},
+			{Name: name, Key: "remote", Value: filepath.Join(os.TempDir(), "rclone-warmer-test")},
+			{Name: name, Key: "cache_remote", Value: filepath.Join(os.TempDir(), "rclone-warmer-test-cache")},
+			{Name: name, Key: "hot_accesses", Value: "2"},
+		},
+		QuickTestOK: true,
+	})
+}
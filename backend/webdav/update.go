@@ -0,0 +1,274 @@
+package webdav
+
+// Conditional and partial updates
+//
+// Partial updates use the SabreDAV extension described at
+// https://sabre.io/dav/http-patch/
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/webdav/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+// Values for the conditional_updates option
+const (
+	condOff     = "off"
+	condIfMatch = "if-match"
+	condIf      = "if"
+)
+
+const (
+	partialUpdateContentType = "application/x-sabredav-partialupdate"
+	partialUpdateBufferSize  = 8 * 1024 * 1024 // buffer WriteAt calls into PATCHes of this size
+)
+
+var (
+	errModified        = errors.New("file was changed on the server by someone else")
+	errPartialDisabled = errors.New("partial updates are not enabled - set --webdav-partial-updates")
+)
+
+// isPreconditionFailed returns true if err is a 412 from the server
+func isPreconditionFailed(err error) bool {
+	var apiErr *api.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// addConditions adds headers to make an upload conditional on the
+// file not having been changed since rclone read it
+//
+// create should be set if the object is believed not to exist yet
+func (o *Object) addConditions(headers map[string]string, create bool) {
+	cond := o.fs.opt.ConditionalUpdates
+	if cond == condOff || cond == "" {
+		return
+	}
+	if create {
+		headers["If-None-Match"] = "*"
+		return
+	}
+	if o.etag == "" {
+		fs.Debugf(o, "Can't make update conditional as no ETag was read")
+		return
+	}
+	if cond == condIf {
+		headers["If"] = "([" + o.etag + "])"
+	} else {
+		headers["If-Match"] = o.etag
+	}
+}
+
+// patch writes size bytes from in to the object at offset
+//
+// If offset is negative the data is appended to the object. If
+// conditional is set then the patch obeys conditional_updates.
+//
+// This doesn't update the metadata of the object.
+func (o *Object) patch(ctx context.Context, in io.ReadSeeker, offset, size int64, conditional bool) error {
+	if !o.fs.opt.PartialUpdates {
+		return errPartialDisabled
+	}
+	updateRange := "append"
+	if offset >= 0 {
+		if size <= 0 {
+			return nil
+		}
+		updateRange = fmt.Sprintf("bytes=%d-%d", offset, offset+size-1)
+	}
+	opts := rest.Opts{
+		Method:        "PATCH",
+		Path:          o.filePath(),
+		Body:          in,
+		NoResponse:    true,
+		ContentLength: &size,
+		ContentType:   partialUpdateContentType,
+		ExtraHeaders: map[string]string{
+			"X-Update-Range": updateRange,
+		},
+	}
+	if conditional {
+		o.addConditions(opts.ExtraHeaders, false)
+	}
+	var resp *http.Response
+	err := o.fs.pacer.Call(func() (bool, error) {
+		_, _ = in.Seek(0, io.SeekStart)
+		var err error
+		resp, err = o.fs.srv.Call(ctx, &opts)
+		return o.fs.shouldRetry(ctx, resp, err)
+	})
+	if isPreconditionFailed(err) {
+		return fmt.Errorf("%w: %v", errModified, err)
+	}
+	if err != nil {
+		return fmt.Errorf("partial update failed: %w", err)
+	}
+	return nil
+}
+
+// partialWriter writes to an object with partial updates
+//
+// WriteAt calls are usually small so they are collected into runs
+// of contiguous data which are sent when they are big enough or on
+// Close.
+type partialWriter struct {
+	ctx     context.Context
+	o       *Object
+	mu      sync.Mutex
+	pending map[int64]*bytes.Buffer // runs of data keyed on the offset they end at
+	start   map[*bytes.Buffer]int64 // offset of the start of each run
+}
+
+// OpenWriterAt opens with a handle for random access writes
+//
+// Pass in the remote desired and the size if known.
+//
+// It truncates any existing object
+func (f *Fs) OpenWriterAt(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+	if !f.opt.PartialUpdates {
+		return nil, errPartialDisabled
+	}
+	// Create the file empty so it can be patched
+	o := f.createObject(remote, time.Now(), 0)
+	src := object.NewStaticObjectInfo(remote, o.modTime, 0, true, nil, f)
+	err := o.update(ctx, bytes.NewReader(nil), src, false)
+	if err != nil {
+		return nil, fmt.Errorf("OpenWriterAt: failed to create file: %w", err)
+	}
+	return &partialWriter{
+		ctx:     ctx,
+		o:       o,
+		pending: make(map[int64]*bytes.Buffer),
+		start:   make(map[*bytes.Buffer]int64),
+	}, nil
+}
+
+// WriteAt writes len(p) bytes from p at offset off
+func (w *partialWriter) WriteAt(p []byte, off int64) (n int, err error) {
+	w.mu.Lock()
+	buf, found := w.pending[off]
+	if found {
+		delete(w.pending, off)
+	} else {
+		buf = new(bytes.Buffer)
+		w.start[buf] = off
+	}
+	_, _ = buf.Write(p)
+	start := w.start[buf]
+	if buf.Len() < partialUpdateBufferSize {
+		w.pending[start+int64(buf.Len())] = buf
+		w.mu.Unlock()
+		return len(p), nil
+	}
+	delete(w.start, buf)
+	w.mu.Unlock()
+	err = w.flush(buf, start)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush sends the run in buf to the server at offset start
+//
+// The writes aren't conditional as the runs are written concurrently
+// each changing the ETag.
+func (w *partialWriter) flush(buf *bytes.Buffer, start int64) error {
+	return w.o.patch(w.ctx, bytes.NewReader(buf.Bytes()), start, int64(buf.Len()), false)
+}
+
+// Close sends any data not yet written
+func (w *partialWriter) Close() (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for end, buf := range w.pending {
+		delete(w.pending, end)
+		start := w.start[buf]
+		delete(w.start, buf)
+		if flushErr := w.flush(buf, start); flushErr != nil && err == nil {
+			err = flushErr
+		}
+	}
+	return err
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "patch",
+	Short: "Write the contents of a local file into part of a remote file.",
+	Long: `This writes the contents of the local file given into the remote file
+at the offset given, or on the end of the remote file if no offset is
+given, without uploading the rest of the remote file. The remote file
+is relative to the remote and must exist. It needs a server
+supporting SabreDAV partial updates and --webdav-partial-updates to
+be set.
+
+Usage Examples:
+
+    rclone backend patch remote: path/to/file /path/to/local/data -o offset=1024
+    rclone backend patch remote: path/to/log /path/to/new/lines
+
+This obeys --webdav-conditional-updates so the patch will be refused
+if the remote file changes while the command is running.
+`,
+	Opts: map[string]string{
+		"offset": "Offset in bytes to write the data at (default append)",
+	},
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out interface{}, err error) {
+	switch name {
+	case "patch":
+		if len(arg) != 2 {
+			return nil, errors.New("need exactly two arguments - the remote file and the local file to read the data from")
+		}
+		offset := int64(-1)
+		if s, ok := opt["offset"]; ok {
+			offset, err = strconv.ParseInt(s, 10, 64)
+			if err != nil || offset < 0 {
+				return nil, fmt.Errorf("bad offset %q", s)
+			}
+		}
+		return nil, f.patchFromFile(ctx, arg[0], arg[1], offset)
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// patchFromFile writes the contents of localPath into the object at
+// remote at offset
+func (f *Fs) patchFromFile(ctx context.Context, remote, localPath string, offset int64) (err error) {
+	o, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(in, &err)
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	return o.(*Object).patch(ctx, in, offset, fi.Size(), true)
+}
@@ -0,0 +1,212 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+)
+
+func TestAddConditions(t *testing.T) {
+	f := &Fs{}
+	o := &Object{fs: f, remote: "file.txt", etag: `"1234"`}
+	for _, test := range []struct {
+		cond   string
+		create bool
+		etag   string
+		want   map[string]string
+	}{
+		{condOff, false, `"1234"`, map[string]string{}},
+		{condOff, true, `"1234"`, map[string]string{}},
+		{condIfMatch, false, `"1234"`, map[string]string{"If-Match": `"1234"`}},
+		{condIfMatch, true, "", map[string]string{"If-None-Match": "*"}},
+		{condIfMatch, false, "", map[string]string{}},
+		{condIf, false, `"1234"`, map[string]string{"If": `(["1234"])`}},
+		{condIf, true, "", map[string]string{"If-None-Match": "*"}},
+	} {
+		f.opt.ConditionalUpdates = test.cond
+		o.etag = test.etag
+		headers := map[string]string{}
+		o.addConditions(headers, test.create)
+		assert.Equal(t, test.want, headers, test)
+	}
+}
+
+// newPatchServer makes a WebDAV server which supports SabreDAV style
+// partial updates and conditional PUTs
+func newPatchServer(t *testing.T) (*httptest.Server, webdav.FileSystem) {
+	ctx := context.Background()
+	memFS := webdav.NewMemFS()
+	handler := &webdav.Handler{
+		FileSystem: memFS,
+		LockSystem: webdav.NewMemLS(),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+				fi, err := memFS.Stat(ctx, r.URL.Path)
+				if err != nil || etagFor(fi) != ifMatch {
+					w.WriteHeader(http.StatusPreconditionFailed)
+					return
+				}
+			}
+			if r.Header.Get("If-None-Match") == "*" {
+				if _, err := memFS.Stat(ctx, r.URL.Path); err == nil {
+					w.WriteHeader(http.StatusPreconditionFailed)
+					return
+				}
+			}
+		}
+		if r.Method != "PATCH" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		assert.Equal(t, partialUpdateContentType, r.Header.Get("Content-Type"))
+		fh, err := memFS.OpenFile(ctx, r.URL.Path, os.O_RDWR, 0)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		defer func() { _ = fh.Close() }()
+		updateRange := r.Header.Get("X-Update-Range")
+		if updateRange == "append" {
+			_, err = fh.Seek(0, io.SeekEnd)
+		} else {
+			start, _ := strconv.ParseInt(strings.SplitN(strings.TrimPrefix(updateRange, "bytes="), "-", 2)[0], 10, 64)
+			_, err = fh.Seek(start, io.SeekStart)
+		}
+		require.NoError(t, err)
+		_, err = io.Copy(fh, r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return ts, memFS
+}
+
+// etagFor returns the ETag the x/net/webdav server uses for fi
+func etagFor(fi os.FileInfo) string {
+	return `"` + strconv.FormatInt(fi.ModTime().UnixNano(), 16) + strconv.FormatInt(fi.Size(), 16) + `"`
+}
+
+// readMemFile returns the contents of name in memFS
+func readMemFile(t *testing.T, memFS webdav.FileSystem, name string) string {
+	fh, err := memFS.OpenFile(context.Background(), name, os.O_RDONLY, 0)
+	require.NoError(t, err)
+	defer func() { _ = fh.Close() }()
+	data, err := io.ReadAll(fh)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func newPatchFs(t *testing.T, url string, m configmap.Simple) *Fs {
+	m["type"] = "webdav"
+	m["url"] = url
+	f, err := NewFs(context.Background(), "TestWebDAVPatch", "", m)
+	require.NoError(t, err)
+	return f.(*Fs)
+}
+
+func TestOpenWriterAt(t *testing.T) {
+	ctx := context.Background()
+	ts, memFS := newPatchServer(t)
+	defer ts.Close()
+
+	f := newPatchFs(t, ts.URL, configmap.Simple{})
+	assert.Nil(t, f.Features().OpenWriterAt)
+	_, err := f.OpenWriterAt(ctx, "file.txt", 10)
+	assert.Equal(t, errPartialDisabled, err)
+
+	f = newPatchFs(t, ts.URL, configmap.Simple{"partial_updates": "true"})
+	require.NotNil(t, f.Features().OpenWriterAt)
+	w, err := f.OpenWriterAt(ctx, "file.txt", 10)
+	require.NoError(t, err)
+	// Two interleaved streams of contiguous writes
+	for _, write := range []struct {
+		off  int64
+		data string
+	}{
+		{0, "01"}, {5, "56"}, {2, "234"}, {7, "789"},
+	} {
+		n, err := w.WriteAt([]byte(write.data), write.off)
+		require.NoError(t, err)
+		assert.Equal(t, len(write.data), n)
+	}
+	pw := w.(*partialWriter)
+	assert.Equal(t, 2, len(pw.pending))
+	require.NoError(t, w.Close())
+	assert.Equal(t, "0123456789", readMemFile(t, memFS, "/file.txt"))
+}
+
+func TestPatchCommand(t *testing.T) {
+	ctx := context.Background()
+	ts, memFS := newPatchServer(t)
+	defer ts.Close()
+	f := newPatchFs(t, ts.URL, configmap.Simple{"partial_updates": "true"})
+
+	_, err := f.Put(ctx, bytes.NewBufferString("hello world"), objectInfo("file.txt", 11))
+	require.NoError(t, err)
+
+	local := filepath.Join(t.TempDir(), "data")
+	require.NoError(t, os.WriteFile(local, []byte("WORLD"), 0600))
+
+	_, err = f.Command(ctx, "patch", []string{"file.txt", local}, map[string]string{"offset": "6"})
+	require.NoError(t, err)
+	assert.Equal(t, "hello WORLD", readMemFile(t, memFS, "/file.txt"))
+
+	_, err = f.Command(ctx, "patch", []string{"file.txt", local}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hello WORLDWORLD", readMemFile(t, memFS, "/file.txt"))
+
+	_, err = f.Command(ctx, "patch", []string{"file.txt"}, nil)
+	assert.Error(t, err)
+	_, err = f.Command(ctx, "patch", []string{"file.txt", local}, map[string]string{"offset": "-1"})
+	assert.Error(t, err)
+}
+
+func TestConditionalUpdate(t *testing.T) {
+	ctx := context.Background()
+	ts, _ := newPatchServer(t)
+	defer ts.Close()
+	f := newPatchFs(t, ts.URL, configmap.Simple{"conditional_updates": condIfMatch})
+
+	o, err := f.Put(ctx, bytes.NewBufferString("one"), objectInfo("file.txt", 3))
+	require.NoError(t, err)
+	assert.NotEqual(t, "", o.(*Object).etag)
+
+	// Putting a file which has appeared since is refused
+	_, err = f.Put(ctx, bytes.NewBufferString("two"), objectInfo("file.txt", 3))
+	assert.ErrorIs(t, err, errModified)
+
+	// Someone else updates the file
+	other, err := f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	require.NoError(t, other.Update(ctx, bytes.NewBufferString("other"), objectInfo("file.txt", 5)))
+
+	// So our update of the stale object is refused
+	err = o.Update(ctx, bytes.NewBufferString("three"), objectInfo("file.txt", 5))
+	assert.ErrorIs(t, err, errModified)
+
+	// And the file is left alone
+	o, err = f.NewObject(ctx, "file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), o.Size())
+}
+
+// objectInfo makes an fs.ObjectInfo for uploading
+func objectInfo(remote string, size int64) fs.ObjectInfo {
+	return object.NewStaticObjectInfo(remote, time.Now(), size, true, nil, nil)
+}
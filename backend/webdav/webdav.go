@@ -68,6 +68,7 @@ func init() {
 		Name:        "webdav",
 		Description: "WebDAV",
 		NewFs:       NewFs,
+		CommandHelp: commandHelp,
 		Options: []fs.Option{{
 			Name:     "url",
 			Help:     "URL of http host to connect to.\n\nE.g. https://example.com.",
@@ -124,6 +125,42 @@ You can set multiple headers, e.g. '"Cookie","name=value","Authorization","xxx"'
 `,
 			Default:  fs.CommaSepList{},
 			Advanced: true,
+		}, {
+			Name: "conditional_updates",
+			Help: `Make uploads conditional so concurrent writers don't clobber each other.
+
+If this is set then rclone sends the ETag it read for the file with
+each upload so the server refuses the upload if the file was changed
+by someone else in the meantime. Uploads of new files are refused if
+the file has appeared since rclone looked.
+
+A refused upload returns an error and leaves the file on the server
+alone.`,
+			Default: condOff,
+			Examples: []fs.OptionExample{{
+				Value: condOff,
+				Help:  "Don't send any conditions.",
+			}, {
+				Value: condIfMatch,
+				Help:  "Use the HTTP If-Match and If-None-Match headers.",
+			}, {
+				Value: condIf,
+				Help:  "Use the WebDAV If header for servers which ignore If-Match.",
+			}},
+			Advanced: true,
+		}, {
+			Name: "partial_updates",
+			Help: `Use partial updates to write parts of files.
+
+Set this if the server supports the SabreDAV partial update
+extension (PATCH with X-Update-Range), for example servers built on
+SabreDAV with the PartialUpdate plugin enabled.
+
+This lets rclone write large files with --multi-thread-streams and
+enables the "patch" backend command to change part of a file without
+uploading all of it again.`,
+			Default:  false,
+			Advanced: true,
 		}},
 	})
 }
@@ -138,6 +175,8 @@ type Options struct {
 	BearerTokenCommand string               `config:"bearer_token_command"`
 	Enc                encoder.MultiEncoder `config:"encoding"`
 	Headers            fs.CommaSepList      `config:"headers"`
+	ConditionalUpdates string               `config:"conditional_updates"`
+	PartialUpdates     bool                 `config:"partial_updates"`
 }
 
 // Fs represents a remote webdav
@@ -171,6 +210,7 @@ type Object struct {
 	modTime     time.Time // modification time of the object
 	sha1        string    // SHA-1 of the object content if known
 	md5         string    // MD5 of the object content if known
+	etag        string    // ETag of the object if known
 }
 
 // ------------------------------------------------------------
@@ -377,6 +417,14 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	}
 	fs.Debugf(nil, "found headers: %v", opt.Headers)
 
+	switch opt.ConditionalUpdates {
+	case "":
+		opt.ConditionalUpdates = condOff
+	case condOff, condIfMatch, condIf:
+	default:
+		return nil, fmt.Errorf("unknown conditional_updates %q", opt.ConditionalUpdates)
+	}
+
 	rootIsDir := strings.HasSuffix(root, "/")
 	root = strings.Trim(root, "/")
 
@@ -436,6 +484,9 @@ func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, e
 	f.features = (&fs.Features{
 		CanHaveEmptyDirectories: true,
 	}).Fill(ctx, f)
+	if !opt.PartialUpdates {
+		f.features.OpenWriterAt = nil
+	}
 	if opt.User != "" || opt.Pass != "" {
 		f.srv.SetUserPass(opt.User, opt.Pass)
 	} else if opt.BearerToken != "" {
@@ -643,6 +694,7 @@ var owncloudProps = []byte(`<?xml version="1.0"?>
   <d:getcontentlength />
   <d:resourcetype />
   <d:getcontenttype />
+  <d:getetag />
   <oc:checksums />
  </d:prop>
 </d:propfind>
@@ -805,7 +857,7 @@ func (f *Fs) createObject(remote string, modTime time.Time, size int64) (o *Obje
 // The new object may have been created if an error is returned
 func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
 	o := f.createObject(src.Remote(), src.ModTime(ctx), src.Size())
-	return o, o.Update(ctx, in, src, options...)
+	return o, o.update(ctx, in, src, true, options...)
 }
 
 // PutStream uploads to the remote path with the modTime given of indeterminate size
@@ -1218,6 +1270,7 @@ func (o *Object) setMetaData(info *api.Prop) (err error) {
 	o.hasMetaData = true
 	o.size = info.Size
 	o.modTime = time.Time(info.Modified)
+	o.etag = info.ETag
 	if o.fs.hasMD5 || o.fs.hasSHA1 {
 		hashes := info.Hashes()
 		if o.fs.hasSHA1 {
@@ -1295,6 +1348,13 @@ func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.Read
 //
 // The new object may have been created if an error is returned
 func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	return o.update(ctx, in, src, false, options...)
+}
+
+// update the object with the contents of the io.Reader
+//
+// create should be set if the object is believed not to exist yet
+func (o *Object) update(ctx context.Context, in io.Reader, src fs.ObjectInfo, create bool, options ...fs.OpenOption) (err error) {
 	err = o.fs.mkParentDir(ctx, o.filePath())
 	if err != nil {
 		return fmt.Errorf("Update mkParentDir failed: %w", err)
@@ -1310,9 +1370,10 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		ContentLength: &size, // FIXME this isn't necessary with owncloud - See https://github.com/nextcloud/nextcloud-snap/issues/365
 		ContentType:   fs.MimeType(ctx, src),
 		Options:       options,
+		ExtraHeaders:  map[string]string{},
 	}
+	o.addConditions(opts.ExtraHeaders, create)
 	if o.fs.useOCMtime || o.fs.hasMD5 || o.fs.hasSHA1 {
-		opts.ExtraHeaders = map[string]string{}
 		if o.fs.useOCMtime {
 			opts.ExtraHeaders["X-OC-Mtime"] = fmt.Sprintf("%d", src.ModTime(ctx).Unix())
 		}
@@ -1334,6 +1395,10 @@ func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, op
 		resp, err = o.fs.srv.Call(ctx, &opts)
 		return o.fs.shouldRetry(ctx, resp, err)
 	})
+	if isPreconditionFailed(err) {
+		// Somebody else has changed the file so leave it alone
+		return fserrors.NoRetryError(fmt.Errorf("%w: %v", errModified, err))
+	}
 	if err != nil {
 		// Give the WebDAV server a chance to get its internal state in order after the
 		// error.  The error may have been local in which case we closed the connection.
@@ -1365,12 +1430,14 @@ func (o *Object) Remove(ctx context.Context) error {
 
 // Check the interfaces are satisfied
 var (
-	_ fs.Fs          = (*Fs)(nil)
-	_ fs.Purger      = (*Fs)(nil)
-	_ fs.PutStreamer = (*Fs)(nil)
-	_ fs.Copier      = (*Fs)(nil)
-	_ fs.Mover       = (*Fs)(nil)
-	_ fs.DirMover    = (*Fs)(nil)
-	_ fs.Abouter     = (*Fs)(nil)
-	_ fs.Object      = (*Object)(nil)
+	_ fs.Fs             = (*Fs)(nil)
+	_ fs.Purger         = (*Fs)(nil)
+	_ fs.PutStreamer    = (*Fs)(nil)
+	_ fs.Copier         = (*Fs)(nil)
+	_ fs.Mover          = (*Fs)(nil)
+	_ fs.DirMover       = (*Fs)(nil)
+	_ fs.Abouter        = (*Fs)(nil)
+	_ fs.Commander      = (*Fs)(nil)
+	_ fs.OpenWriterAter = (*Fs)(nil)
+	_ fs.Object         = (*Object)(nil)
 )
@@ -0,0 +1,733 @@
+// Package webhook implements a backend which calls HTTP webhooks when
+// files on the wrapped remote change
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/cache"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+const (
+	minSleep = 250 * time.Millisecond // first retry sleep - successful calls don't sleep
+	maxSleep = 30 * time.Second
+
+	signatureHeader = "X-Rclone-Signature"
+	eventHeader     = "X-Rclone-Event"
+)
+
+// Events which can be sent
+const (
+	EventPut    = "put"
+	EventDelete = "delete"
+	EventRename = "rename"
+)
+
+// Register with Fs
+func init() {
+	fs.Register(&fs.RegInfo{
+		Name:        "webhook",
+		Description: "Call HTTP webhooks when files change",
+		NewFs:       NewFs,
+		MetadataInfo: &fs.MetadataInfo{
+			Help: `Any metadata supported by the underlying remote is read and written.`,
+		},
+		Options: []fs.Option{{
+			Name:     "remote",
+			Required: true,
+			Help: `Remote to watch for changes.
+
+Normally should contain a ':' and a path, e.g. "myremote:path/to/dir",
+"myremote:bucket" or maybe "myremote:" (not recommended).`,
+		}, {
+			Name:     "url",
+			Required: true,
+			Help:     `URL to POST the events to.`,
+		}, {
+			Name:       "secret",
+			IsPassword: true,
+			Help: `Secret used to sign the events.
+
+If set, each request has an X-Rclone-Signature header containing
+"sha256=" followed by the hex encoded HMAC-SHA256 of the request
+body using this secret.`,
+		}, {
+			Name:    "events",
+			Default: fs.CommaSepList{EventPut, EventDelete, EventRename},
+			Help: `Comma separated list of events to send.
+
+Possible events are "put", "delete" and "rename".`,
+		}, {
+			Name:     "retries",
+			Default:  3,
+			Advanced: true,
+			Help: `Number of times to retry a webhook which fails.
+
+Retries are made with exponential backoff for network errors and
+429 and 5xx responses.`,
+		}, {
+			Name:     "timeout",
+			Default:  fs.Duration(10 * time.Second),
+			Advanced: true,
+			Help:     `Timeout for each webhook request.`,
+		}, {
+			Name:     "fail_on_error",
+			Default:  false,
+			Advanced: true,
+			Help: `Return an error from the operation if its webhook can't be delivered.
+
+The operation itself will have completed on the wrapped remote. If
+this is not set, failed webhooks are logged as errors only.`,
+		}, {
+			Name: "headers",
+			Help: `Set HTTP headers for the webhook requests.
+
+The input format is comma separated list of key,value pairs.  Standard
+[CSV encoding](https://godoc.org/encoding/csv) may be used.
+
+For example, to set an Authorization header use '"Authorization","Bearer xxx"'.`,
+			Default:  fs.CommaSepList{},
+			Advanced: true,
+		}},
+	})
+}
+
+// Options defines the configuration for this backend
+type Options struct {
+	Remote      string          `config:"remote"`
+	URL         string          `config:"url"`
+	Secret      string          `config:"secret"`
+	Events      fs.CommaSepList `config:"events"`
+	Retries     int             `config:"retries"`
+	Timeout     fs.Duration     `config:"timeout"`
+	FailOnError bool            `config:"fail_on_error"`
+	Headers     fs.CommaSepList `config:"headers"`
+}
+
+// Event is the JSON body POSTed to the webhook URL
+type Event struct {
+	Event   string     `json:"event"`              // "put", "delete" or "rename"
+	Remote  string     `json:"remote"`             // the webhook remote, e.g. "hook:path"
+	Path    string     `json:"path"`               // path of the file or directory relative to the remote
+	OldPath string     `json:"old_path,omitempty"` // previous path for "rename"
+	IsDir   bool       `json:"is_dir,omitempty"`   // set if the event is for a directory
+	Size    int64      `json:"size,omitempty"`     // size of the file for "put"
+	ModTime *time.Time `json:"mod_time,omitempty"` // modification time of the file for "put"
+	Time    time.Time  `json:"time"`               // when the event happened
+}
+
+// Fs represents a wrapped fs.Fs
+type Fs struct {
+	fs.Fs
+	name     string
+	root     string
+	opt      Options
+	features *fs.Features // optional features
+	wrapper  fs.Fs
+	srv      *rest.Client
+	pacer    *fs.Pacer
+	secret   []byte
+	events   map[string]bool
+}
+
+// NewFs constructs an Fs from the path.
+//
+// The returned Fs is the actual Fs, referenced by remote in the config
+func NewFs(ctx context.Context, name, rpath string, m configmap.Mapper) (fs.Fs, error) {
+	opt := Options{}
+	err := configstruct.Set(m, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(opt.Remote, name+":") {
+		return nil, errors.New("can't point webhook remote at itself - check the value of the remote setting")
+	}
+	if opt.URL == "" {
+		return nil, errors.New("url must be set")
+	}
+	if len(opt.Headers)%2 != 0 {
+		return nil, errors.New("odd number of headers supplied")
+	}
+	f := &Fs{
+		name:   name,
+		root:   rpath,
+		opt:    opt,
+		events: map[string]bool{},
+	}
+	for _, event := range opt.Events {
+		switch event {
+		case EventPut, EventDelete, EventRename:
+			f.events[event] = true
+		default:
+			return nil, fmt.Errorf("unknown event %q in events", event)
+		}
+	}
+	if opt.Secret != "" {
+		secret, err := obscure.Reveal(opt.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+		}
+		f.secret = []byte(secret)
+	}
+
+	// Make the HTTP client with its own timeout
+	newCtx, ci := fs.AddConfig(ctx)
+	ci.Timeout = time.Duration(opt.Timeout)
+	f.srv = rest.NewClient(fshttp.NewClient(newCtx))
+	f.pacer = fs.NewPacer(ctx, pacer.NewS3(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep)))
+	f.pacer.SetRetries(opt.Retries + 1)
+
+	remotePath := fspath.JoinRootPath(opt.Remote, rpath)
+	var baseErr error
+	f.Fs, baseErr = cache.Get(ctx, remotePath)
+	if baseErr != nil && baseErr != fs.ErrorIsFile {
+		return nil, fmt.Errorf("failed to make remote %q to wrap: %w", remotePath, baseErr)
+	}
+	cache.PinUntilFinalized(f.Fs, f)
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		DuplicateFiles:          true,
+		ReadMimeType:            true,
+		WriteMimeType:           true,
+		CanHaveEmptyDirectories: true,
+		BucketBased:             true,
+		SetTier:                 true,
+		GetTier:                 true,
+		ReadMetadata:            true,
+		WriteMetadata:           true,
+		UserMetadata:            true,
+	}).Fill(ctx, f).Mask(ctx, f.Fs).WrapsFs(f, f.Fs)
+	return f, baseErr
+}
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// String returns a description of the FS
+func (f *Fs) String() string {
+	return fmt.Sprintf("webhook root '%s'", f.root)
+}
+
+// UnWrap returns the Fs that this Fs is wrapping
+func (f *Fs) UnWrap() fs.Fs {
+	return f.Fs
+}
+
+// WrapFs returns the Fs that is wrapping this Fs
+func (f *Fs) WrapFs() fs.Fs {
+	return f.wrapper
+}
+
+// SetWrapper sets the Fs that is wrapping this Fs
+func (f *Fs) SetWrapper(wrapper fs.Fs) {
+	f.wrapper = wrapper
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	503, // Service Unavailable
+	504, // Gateway Timeout
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// sign returns the signature for body
+func (f *Fs) sign(body []byte) string {
+	mac := hmac.New(sha256.New, f.secret)
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// send POSTs the event to the webhook URL, retrying if necessary
+func (f *Fs) send(ctx context.Context, event *Event) error {
+	event.Remote = fs.ConfigString(f)
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{
+		eventHeader: event.Event,
+	}
+	for i := 0; i < len(f.opt.Headers); i += 2 {
+		headers[f.opt.Headers[i]] = f.opt.Headers[i+1]
+	}
+	if f.secret != nil {
+		headers[signatureHeader] = f.sign(body)
+	}
+	return f.pacer.Call(func() (bool, error) {
+		opts := rest.Opts{
+			Method:       "POST",
+			RootURL:      f.opt.URL,
+			Body:         bytes.NewReader(body),
+			ContentType:  "application/json",
+			ExtraHeaders: headers,
+			NoResponse:   true,
+		}
+		resp, err := f.srv.Call(ctx, &opts)
+		return shouldRetry(ctx, resp, err)
+	})
+}
+
+// notify sends the event if it is enabled, returning an error only
+// if fail_on_error is set
+func (f *Fs) notify(ctx context.Context, event *Event) error {
+	if !f.events[event.Event] {
+		return nil
+	}
+	err := f.send(ctx, event)
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("failed to send %s webhook for %q: %w", event.Event, event.Path, err)
+	if f.opt.FailOnError {
+		return err
+	}
+	fs.Errorf(f, "%v", err)
+	return nil
+}
+
+// notifyPut sends a put event for o
+func (f *Fs) notifyPut(ctx context.Context, o fs.Object) error {
+	modTime := o.ModTime(ctx)
+	return f.notify(ctx, &Event{
+		Event:   EventPut,
+		Path:    o.Remote(),
+		Size:    o.Size(),
+		ModTime: &modTime,
+	})
+}
+
+// wrapEntries wraps the objects in entries
+func (f *Fs) wrapEntries(entries fs.DirEntries) fs.DirEntries {
+	for i, entry := range entries {
+		if o, ok := entry.(fs.Object); ok {
+			entries[i] = f.newObject(o)
+		}
+	}
+	return entries
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	entries, err = f.Fs.List(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	return f.wrapEntries(entries), nil
+}
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	return f.Fs.Features().ListR(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.wrapEntries(entries))
+	})
+}
+
+// ListP lists the objects and directories of the Fs in dir non
+// recursively, calling callback with each page of entries as it is
+// read.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	do := f.Fs.Features().ListP
+	if do == nil {
+		return fs.ListPFallback(ctx, f, dir, callback)
+	}
+	return do(ctx, dir, func(entries fs.DirEntries) error {
+		return callback(f.wrapEntries(entries))
+	})
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := f.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), nil
+}
+
+// put uploads with do and sends a put event
+func (f *Fs) put(ctx context.Context, do func(context.Context, io.Reader, fs.ObjectInfo, ...fs.OpenOption) (fs.Object, error), in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	o, err := do(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	obj := f.newObject(o)
+	return obj, f.notifyPut(ctx, obj)
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// May create the object even if it returns an error - if so
+// will return the object and the error, otherwise will return
+// nil and the error
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return f.put(ctx, f.Fs.Put, in, src, options...)
+}
+
+// PutStream uploads to the remote path with the modTime given of indeterminate size
+func (f *Fs) PutStream(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutStream
+	if do == nil {
+		return nil, errors.New("PutStream not supported")
+	}
+	return f.put(ctx, do, in, src, options...)
+}
+
+// PutUnchecked uploads the object, allowing duplicates
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	do := f.Fs.Features().PutUnchecked
+	if do == nil {
+		return nil, errors.New("PutUnchecked not supported")
+	}
+	return f.put(ctx, do, in, src, options...)
+}
+
+// Purge all files in the directory specified
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	do := f.Fs.Features().Purge
+	if do == nil {
+		return fs.ErrorCantPurge
+	}
+	err := do(ctx, dir)
+	if err != nil {
+		return err
+	}
+	return f.notify(ctx, &Event{
+		Event: EventDelete,
+		Path:  dir,
+		IsDir: true,
+	})
+}
+
+// Copy src to this remote using server-side copy operations.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Copy
+	if do == nil {
+		return nil, fs.ErrorCantCopy
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantCopy
+	}
+	o, err := do(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	obj := f.newObject(o)
+	return obj, f.notifyPut(ctx, obj)
+}
+
+// Move src to this remote using server-side move operations.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	do := f.Fs.Features().Move
+	if do == nil {
+		return nil, fs.ErrorCantMove
+	}
+	srcObj, ok := src.(*Object)
+	if !ok {
+		return nil, fs.ErrorCantMove
+	}
+	o, err := do(ctx, srcObj.Object, remote)
+	if err != nil {
+		return nil, err
+	}
+	return f.newObject(o), f.notify(ctx, &Event{
+		Event:   EventRename,
+		Path:    remote,
+		OldPath: srcObj.f.relative(f, src.Remote()),
+	})
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	do := f.Fs.Features().DirMove
+	if do == nil {
+		return fs.ErrorCantDirMove
+	}
+	srcFs, ok := src.(*Fs)
+	if !ok {
+		return fs.ErrorCantDirMove
+	}
+	err := do(ctx, srcFs.Fs, srcRemote, dstRemote)
+	if err != nil {
+		return err
+	}
+	return f.notify(ctx, &Event{
+		Event:   EventRename,
+		Path:    dstRemote,
+		OldPath: srcFs.relative(f, srcRemote),
+		IsDir:   true,
+	})
+}
+
+// relative returns remote on f as a path relative to the root of
+// dst if they share a root, otherwise as a full config string.
+func (f *Fs) relative(dst *Fs, remote string) string {
+	if f == dst || fs.ConfigString(f) == fs.ConfigString(dst) {
+		return remote
+	}
+	return fs.ConfigString(f) + "/" + path.Clean(remote)
+}
+
+// MergeDirs merges the contents of all the directories passed
+// in into the first one and rmdirs the other directories.
+func (f *Fs) MergeDirs(ctx context.Context, dirs []fs.Directory) error {
+	do := f.Fs.Features().MergeDirs
+	if do == nil {
+		return errors.New("MergeDirs not supported")
+	}
+	return do(ctx, dirs)
+}
+
+// CleanUp the trash in the Fs
+func (f *Fs) CleanUp(ctx context.Context) error {
+	do := f.Fs.Features().CleanUp
+	if do == nil {
+		return errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// About gets quota information from the Fs
+func (f *Fs) About(ctx context.Context) (*fs.Usage, error) {
+	do := f.Fs.Features().About
+	if do == nil {
+		return nil, errors.New("not supported by underlying remote")
+	}
+	return do(ctx)
+}
+
+// ChangeNotify calls the passed function with a path that has had changes.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	if do := f.Fs.Features().ChangeNotify; do != nil {
+		do(ctx, notifyFunc, pollIntervalChan)
+	}
+}
+
+// DirCacheFlush resets the directory cache - used in testing
+// as an optional interface
+func (f *Fs) DirCacheFlush() {
+	if do := f.Fs.Features().DirCacheFlush; do != nil {
+		do()
+	}
+}
+
+// PublicLink generates a public link to the remote path (usually readable by anyone)
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	do := f.Fs.Features().PublicLink
+	if do == nil {
+		return "", errors.New("PublicLink not supported")
+	}
+	return do(ctx, remote, expire, unlink)
+}
+
+// UserInfo returns info about the connected user
+func (f *Fs) UserInfo(ctx context.Context) (map[string]string, error) {
+	do := f.Fs.Features().UserInfo
+	if do == nil {
+		return nil, fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Disconnect the current user
+func (f *Fs) Disconnect(ctx context.Context) error {
+	do := f.Fs.Features().Disconnect
+	if do == nil {
+		return fs.ErrorNotImplemented
+	}
+	return do(ctx)
+}
+
+// Shutdown the backend, closing any background tasks and any
+// cached connections.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	do := f.Fs.Features().Shutdown
+	if do == nil {
+		return nil
+	}
+	return do(ctx)
+}
+
+// Object describes a wrapped object
+type Object struct {
+	fs.Object
+	f *Fs
+}
+
+// newObject wraps o
+func (f *Fs) newObject(o fs.Object) *Object {
+	return &Object{
+		Object: o,
+		f:      f,
+	}
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *Object) Fs() fs.Info {
+	return o.f
+}
+
+// UnWrap returns the wrapped Object
+func (o *Object) UnWrap() fs.Object {
+	return o.Object
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.Object.String()
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	err := o.Object.Update(ctx, in, src, options...)
+	if err != nil {
+		return err
+	}
+	return o.f.notifyPut(ctx, o)
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) error {
+	err := o.Object.Remove(ctx)
+	if err != nil {
+		return err
+	}
+	return o.f.notify(ctx, &Event{
+		Event: EventDelete,
+		Path:  o.Remote(),
+	})
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	do, ok := o.Object.(fs.IDer)
+	if !ok {
+		return ""
+	}
+	return do.ID()
+}
+
+// MimeType returns the content type of the Object if known
+func (o *Object) MimeType(ctx context.Context) string {
+	do, ok := o.Object.(fs.MimeTyper)
+	if !ok {
+		return ""
+	}
+	return do.MimeType(ctx)
+}
+
+// SetTier performs changing storage tier of the Object if
+// multiple storage classes supported
+func (o *Object) SetTier(tier string) error {
+	do, ok := o.Object.(fs.SetTierer)
+	if !ok {
+		return errors.New("SetTier not supported")
+	}
+	return do.SetTier(tier)
+}
+
+// GetTier returns storage tier or class of the Object
+func (o *Object) GetTier() string {
+	do, ok := o.Object.(fs.GetTierer)
+	if !ok {
+		return ""
+	}
+	return do.GetTier()
+}
+
+// Metadata returns metadata for an object
+//
+// It should return nil if there is no Metadata
+func (o *Object) Metadata(ctx context.Context) (fs.Metadata, error) {
+	do, ok := o.Object.(fs.Metadataer)
+	if !ok {
+		return nil, nil
+	}
+	return do.Metadata(ctx)
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.PutStreamer     = (*Fs)(nil)
+	_ fs.PutUncheckeder  = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.MergeDirser     = (*Fs)(nil)
+	_ fs.CleanUpper      = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.ChangeNotifier  = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.UserInfoer      = (*Fs)(nil)
+	_ fs.Disconnecter    = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.UnWrapper       = (*Fs)(nil)
+	_ fs.Wrapper         = (*Fs)(nil)
+	_ fs.FullObject      = (*Object)(nil)
+)
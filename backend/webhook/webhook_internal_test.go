@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/fstests"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer records the events POSTed to it, failing the first
+// fail requests
+type testServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	fail   int
+	calls  int
+	events []Event
+	sigs   []string
+}
+
+func newTestServer(t *testing.T, fail int) *testServer {
+	s := &testServer{fail: fail}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.calls++
+		if s.fail > 0 {
+			s.fail--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var event Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, event.Event, r.Header.Get(eventHeader))
+		if sig := r.Header.Get(signatureHeader); sig != "" {
+			mac := hmac.New(sha256.New, []byte("potato"))
+			_, _ = mac.Write(body)
+			assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), sig)
+		}
+		s.events = append(s.events, event)
+		s.sigs = append(s.sigs, r.Header.Get(signatureHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// newTestFs returns a webhook remote with the extra config given,
+// retrying failed webhooks without waiting
+func newTestFs(t *testing.T, config string) *Fs {
+	f, _ := fstest.NewWrappingFs(t, "webhook", config)
+	wf := f.(*Fs)
+	wf.pacer.SetCalculator(pacer.NewS3(pacer.MinSleep(time.Millisecond), pacer.MaxSleep(time.Millisecond)))
+	return wf
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t, 0)
+	f := newTestFs(t, fmt.Sprintf(`url="%s",secret="%s"`, s.URL, obscure.MustObscure("potato")))
+
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "dir/file.txt", ModTime: time.Now()}, "hello", true)
+	o2, err := f.Move(ctx, o, "dir/moved.txt")
+	require.NoError(t, err)
+	require.NoError(t, o2.Remove(ctx))
+
+	require.Len(t, s.events, 3)
+	assert.Equal(t, EventPut, s.events[0].Event)
+	assert.Equal(t, "dir/file.txt", s.events[0].Path)
+	assert.Equal(t, int64(5), s.events[0].Size)
+	assert.NotNil(t, s.events[0].ModTime)
+	assert.Equal(t, EventRename, s.events[1].Event)
+	assert.Equal(t, "dir/moved.txt", s.events[1].Path)
+	assert.Equal(t, "dir/file.txt", s.events[1].OldPath)
+	assert.Equal(t, EventDelete, s.events[2].Event)
+	assert.Equal(t, "dir/moved.txt", s.events[2].Path)
+	for _, sig := range s.sigs {
+		assert.True(t, strings.HasPrefix(sig, "sha256="))
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t, 0)
+	f := newTestFs(t, fmt.Sprintf(`url="%s",events=delete`, s.URL))
+
+	o := fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "file.txt", ModTime: time.Now()}, "hello", true)
+	require.NoError(t, o.Remove(ctx))
+	require.Len(t, s.events, 1)
+	assert.Equal(t, EventDelete, s.events[0].Event)
+	assert.Equal(t, "", s.sigs[0])
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t, 2)
+	f := newTestFs(t, fmt.Sprintf(`url="%s"`, s.URL))
+
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "file.txt", ModTime: time.Now()}, "hello", true)
+	assert.Equal(t, 3, s.calls)
+	assert.Len(t, s.events, 1)
+}
+
+func TestFailOnError(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t, 100)
+
+	// errors are only logged by default
+	f := newTestFs(t, fmt.Sprintf(`url="%s",retries=1`, s.URL))
+	_ = fstests.PutTestContents(ctx, t, f, &fstest.Item{Path: "file.txt", ModTime: time.Now()}, "hello", true)
+	assert.Equal(t, 2, s.calls)
+
+	// but returned with fail_on_error after the operation is done
+	f = newTestFs(t, fmt.Sprintf(`url="%s",retries=0,fail_on_error=true`, s.URL))
+	src := object.NewStaticObjectInfo("file.txt", time.Now(), 5, true, nil, nil)
+	_, err := f.Put(ctx, strings.NewReader("hello"), src)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to send put webhook")
+	assert.Equal(t, 3, s.calls)
+	_, err = f.Fs.NewObject(ctx, "file.txt")
+	assert.NoError(t, err)
+}
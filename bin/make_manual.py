@@ -30,19 +30,28 @@ docs = [
     "fichier.md",
     "alias.md",
     "amazonclouddrive.md",
+    "archive.md",
+    "artifacts.md",
     "s3.md",
     "b2.md",
+    "torrent.md",
     "box.md",
     "cache.md",
+    "casfs.md",
     "chunker.md",
     "sharefile.md",
     "crypt.md",
     "compress.md",
     "combine.md",
+    "kvstore.md",
+    "debounce.md",
+    "dedupe.md",
     "discord.md",
     "dropbox.md",
     "filefabric.md",
+    "flatten.md",
     "ftp.md",
+    "gitlfs.md",
     "googlecloudstorage.md",
     "drive.md",
     "googlephotos.md",
@@ -51,18 +60,36 @@ docs = [
     "hidrive.md",
     "http.md",
     "hubic.md",
+    "huggingface.md",
     "internetarchive.md",
+    "ipfs.md",
     "jottacloud.md",
+    "jsonindex.md",
     "koofr.md",
     "mailru.md",
     "mega.md",
     "memory.md",
+    "lru.md",
     "netstorage.md",
     "azureblob.md",
     "onedrive.md",
     "opendrive.md",
+    "openlist.md",
+    "overlay.md",
     "qingstor.md",
+    "queue.md",
+    "ratelimit.md",
+    "rcproxy.md",
+    "releases.md",
+    "rename.md",
+    "rsync.md",
+    "scan.md",
     "sia.md",
+    "sidecar.md",
+    "sizelimit.md",
+    "snapshot.md",
+    "sqlblob.md",
+    "sums.md",
     "swift.md",
     "pcloud.md",
     "premiumizeme.md",
@@ -72,9 +99,15 @@ docs = [
     "storj.md",
     "sugarsync.md",
     "tardigrade.md",            # stub only to redirect to storj.md
+    "telegram.md",
+    "throttle.md",
+    "tier.md",
+    "tus.md",
     "uptobox.md",
     "union.md",
+    "warmer.md",
     "webdav.md",
+    "webhook.md",
     "yandex.md",
     "zoho.md",
 
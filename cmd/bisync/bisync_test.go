@@ -629,6 +629,9 @@ func (b *bisyncTest) runBisync(ctx context.Context, args []string) (err error) {
 		case "max-delete":
 			opt.MaxDelete, err = strconv.Atoi(val)
 			require.NoError(b.t, err, "parsing max-delete=%q", val)
+		case "compare-sample":
+			opt.CompareSample, err = strconv.Atoi(val)
+			require.NoError(b.t, err, "parsing compare-sample=%q", val)
 		case "size-only":
 			ci.SizeOnly = true
 		case "subdir":
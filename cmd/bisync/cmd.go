@@ -39,6 +39,7 @@ type Options struct {
 	Workdir         string
 	DryRun          bool
 	NoCleanup       bool
+	CompareSample   int  // number of ranges to compare for conflicts
 	SaveQueues      bool // save extra debugging files (test only flag)
 }
 
@@ -48,6 +49,9 @@ const (
 	DefaultCheckFilename string = "RCLONE_TEST"
 )
 
+// compareSampleSize is the size of each range read by --compare-sample
+const compareSampleSize = 64 * 1024
+
 // DefaultWorkdir is default working directory
 var DefaultWorkdir = filepath.Join(config.GetCacheDir(), "bisync")
 
@@ -109,6 +113,7 @@ func init() {
 	flags.StringVarP(cmdFlags, &Opt.Workdir, "workdir", "", Opt.Workdir, makeHelp("Use custom working dir - useful for testing. (default: {WORKDIR})"))
 	flags.BoolVarP(cmdFlags, &tzLocal, "localtime", "", tzLocal, "Use local time in listings (default: UTC)")
 	flags.BoolVarP(cmdFlags, &Opt.NoCleanup, "no-cleanup", "", Opt.NoCleanup, "Retain working files (useful for troubleshooting and testing).")
+	flags.IntVarP(cmdFlags, &Opt.CompareSample, "compare-sample", "", Opt.CompareSample, "Check files changed on both paths are different before treating them as conflicts, comparing N ranges if there is no common hash")
 }
 
 // bisync command definition
@@ -10,6 +10,7 @@ import (
 
 	"github.com/rclone/rclone/cmd/bisync/bilib"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 )
 
@@ -28,7 +29,7 @@ const (
 
 const (
 	deltaModified delta = deltaNewer | deltaOlder | deltaSize | deltaHash | deltaDeleted
-	deltaOther    delta = deltaNew | deltaNewer | deltaOlder
+	deltaOther    delta = deltaNew | deltaNewer | deltaOlder | deltaSize
 )
 
 func (d delta) is(cond delta) bool {
@@ -136,8 +137,12 @@ func (b *bisyncRun) findDeltas(fctx context.Context, f fs.Fs, oldListing, newLis
 					b.indent(msg, file, "File is OLDER")
 					d |= deltaOlder
 				}
+			} else if old.getSize(file) != now.getSize(file) {
+				// Same modification time but the size changed
+				b.indent(msg, file, "File size differs")
+				d |= deltaSize
 			}
-			// TODO Compare sizes and hashes
+			// TODO Compare hashes
 		}
 
 		if d.is(deltaModified) {
@@ -197,6 +202,9 @@ func (b *bisyncRun) applyDeltas(ctx context.Context, ds1, ds2 *deltaSet) (change
 				b.indent("Path1", p2, "Queue copy to Path2")
 				copy1to2.Add(file)
 				handled.Add(file)
+			} else if d2.is(deltaOther) && b.identical(ctx, file) {
+				b.indent("Path1", file, "Identical in both paths")
+				handled.Add(file)
 			} else if d2.is(deltaOther) {
 				b.indent("!WARNING", file, "New or changed in both paths")
 				b.indent("!Path1", p1+"..path1", "Renaming Path1 copy")
@@ -290,6 +298,43 @@ func (b *bisyncRun) applyDeltas(ctx context.Context, ds1, ds2 *deltaSet) (change
 	return
 }
 
+// identical returns true if file is the same on Path1 and Path2 so
+// changing it on both paths isn't a conflict.
+//
+// This is only checked if --compare-sample is set. The files must be
+// the same size. If the paths have a common hash then the hashes must
+// match, otherwise --compare-sample ranges of the files are compared.
+func (b *bisyncRun) identical(ctx context.Context, file string) bool {
+	if b.opt.CompareSample <= 0 {
+		return false
+	}
+	o1, err := b.fs1.NewObject(ctx, file)
+	if err != nil {
+		return false
+	}
+	o2, err := b.fs2.NewObject(ctx, file)
+	if err != nil {
+		return false
+	}
+	if o1.Size() < 0 || o1.Size() != o2.Size() {
+		return false
+	}
+	ci := fs.GetConfig(ctx)
+	if ht := b.fs1.Hashes().Overlap(b.fs2.Hashes()).GetOne(); ht != hash.None && !ci.IgnoreChecksum {
+		sum1, err1 := o1.Hash(ctx, ht)
+		sum2, err2 := o2.Hash(ctx, ht)
+		if err1 == nil && err2 == nil && sum1 != "" && sum2 != "" {
+			return sum1 == sum2
+		}
+	}
+	differ, err := operations.CheckIdenticalSample(ctx, o1, o2, b.opt.CompareSample, compareSampleSize)
+	if err != nil {
+		fs.Errorf(file, "Failed to compare samples: %v", err)
+		return false
+	}
+	return !differ
+}
+
 // exccessDeletes checks whether number of deletes is within allowed range
 func (ds *deltaSet) excessDeletes() bool {
 	maxDelete := ds.opt.MaxDelete
@@ -34,6 +34,9 @@ var rcHelp = makeHelp(`This takes the following parameters
 - filtersFile - read filtering patterns from a file
 - workdir - server directory for history files (default: {WORKDIR})
 - noCleanup - retain working files
+- compareSample - check files changed on both paths are different before
+  treating them as conflicts, comparing this many ranges of them if there
+  is no common hash
 
 See [bisync command help](https://rclone.org/commands/rclone_bisync/)
 and [full bisync description](https://rclone.org/bisync/)
@@ -93,6 +93,14 @@ func (ls *fileList) put(file string, size int64, time time.Time, hash, id string
 	}
 }
 
+func (ls *fileList) getSize(file string) int64 {
+	fi := ls.get(file)
+	if fi == nil {
+		return -1
+	}
+	return fi.size
+}
+
 func (ls *fileList) getTime(file string) time.Time {
 	fi := ls.get(file)
 	if fi == nil {
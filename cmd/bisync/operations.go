@@ -16,6 +16,7 @@ import (
 	"github.com/rclone/rclone/cmd/bisync/bilib"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/sync"
 	"github.com/rclone/rclone/lib/atexit"
@@ -253,6 +254,9 @@ func (b *bisyncRun) runLocked(octx context.Context, listing1, listing2 string) (
 		fs.Infof(nil, "No changes found")
 	} else {
 		fs.Infof(nil, "Applying changes")
+		if b.fs1.Hashes().Overlap(b.fs2.Hashes()) == hash.Set(hash.None) && opt.CompareSample <= 0 {
+			fs.Infof(nil, "Path1 and Path2 have no common hash so files changed on both paths will be treated as conflicts unless --compare-sample is set")
+		}
 		changes1, changes2, err = b.applyDeltas(octx, ds1, ds2)
 		if err != nil {
 			b.critical = true
@@ -7,6 +7,7 @@ import (
 
 	"github.com/rclone/rclone/cmd/bisync/bilib"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/rc"
 )
 
@@ -21,6 +22,9 @@ func init() {
 }
 
 func rcBisync(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	if err := operations.CheckDeleteConfirm(ctx); err != nil {
+		return nil, err
+	}
 	opt := &Options{}
 	octx, ci := fs.AddConfig(ctx)
 
@@ -55,6 +59,12 @@ func rcBisync(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 		return
 	}
 
+	if compareSample, err := in.GetInt64("compareSample"); err == nil {
+		opt.CompareSample = int(compareSample)
+	} else if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+
 	if opt.CheckFilename, err = in.GetString("checkFilename"); rc.NotErrParamNotFound(err) {
 		return
 	}
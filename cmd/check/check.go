@@ -18,21 +18,25 @@ import (
 
 // Globals
 var (
-	download          = false
-	oneway            = false
-	combined          = ""
-	missingOnSrc      = ""
-	missingOnDst      = ""
-	match             = ""
-	differ            = ""
-	errFile           = ""
-	checkFileHashType = ""
+	download           = false
+	downloadSample     = 0
+	downloadSampleSize = fs.Mebi
+	oneway             = false
+	combined           = ""
+	missingOnSrc       = ""
+	missingOnDst       = ""
+	match              = ""
+	differ             = ""
+	errFile            = ""
+	checkFileHashType  = ""
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &download, "download", "", download, "Check by downloading rather than with hash")
+	flags.IntVarP(cmdFlags, &downloadSample, "download-sample", "", downloadSample, "Check by downloading this many ranges of each file rather than with hash")
+	flags.FVarP(cmdFlags, &downloadSampleSize, "download-sample-size", "", "Size of each range downloaded with --download-sample")
 	flags.StringVarP(cmdFlags, &checkFileHashType, "checkfile", "C", checkFileHashType, "Treat source:path as a SUM file with hashes of given type")
 	AddFlags(cmdFlags)
 }
@@ -151,6 +155,16 @@ both remotes and check them against each other on the fly.  This can
 be useful for remotes that don't support hashes or if you really want
 to check all the data.
 
+If you supply the |--download-sample N| flag, instead of downloading
+the whole of each file it will download |N| ranges of
+|--download-sample-size| (default 1 MiB) from the same places in both
+files and check them against each other. The first and last ranges of
+each file are always checked and the rest are chosen at random. This
+gives a probabilistic check of the contents of large files which is
+much quicker than |--download| on slow remotes, though it may miss
+differences outside the ranges checked. Files smaller than the ranges
+are downloaded in full.
+
 If you supply the |--checkfile HASH| flag with a valid hash name,
 the |source:path| must point to a text file in the SUM format.
 `, "|", "`") + FlagsHelp,
@@ -183,6 +197,9 @@ the |source:path| must point to a text file in the SUM format.
 				return operations.CheckSum(context.Background(), fsrc, fsum, sumFile, hashType, opt, download)
 			}
 
+			if downloadSample > 0 {
+				return operations.CheckDownloadSample(context.Background(), opt, downloadSample, int64(downloadSampleSize))
+			}
 			if download {
 				return operations.CheckDownload(context.Background(), opt)
 			}
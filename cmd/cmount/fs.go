@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -22,10 +23,14 @@ import (
 
 const fhUnset = ^uint64(0)
 
+// linkSuffix is the suffix of the files shown as symlinks with --symlinks
+const linkSuffix = ".rclonelink"
+
 // FS represents the top level filing system
 type FS struct {
 	VFS       *vfs.VFS
 	f         fs.Fs
+	opt       *mountlib.Options
 	ready     chan (struct{})
 	mu        sync.Mutex // to protect the below
 	handles   []vfs.Handle
@@ -33,10 +38,11 @@ type FS struct {
 }
 
 // NewFS makes a new FS
-func NewFS(VFS *vfs.VFS) *FS {
+func NewFS(VFS *vfs.VFS, opt *mountlib.Options) *FS {
 	fsys := &FS{
 		VFS:   VFS,
 		f:     VFS.Fs(),
+		opt:   opt,
 		ready: make(chan (struct{})),
 	}
 	return fsys
@@ -96,10 +102,30 @@ func (fsys *FS) closeHandle(fh uint64) (errc int) {
 
 // lookup a Node given a path
 func (fsys *FS) lookupNode(path string) (node vfs.Node, errc int) {
-	node, err := fsys.VFS.Stat(path)
+	node, err := fsys.VFS.Stat(fsys.linkPath(path))
 	return node, translateError(err)
 }
 
+// linkPath returns the path of the file holding the symlink at path
+// if --symlinks is set and there is one, otherwise path
+func (fsys *FS) linkPath(path string) string {
+	if !fsys.opt.Symlinks || strings.HasSuffix(path, linkSuffix) {
+		return path
+	}
+	if _, err := fsys.VFS.Stat(path); err == nil {
+		return path
+	}
+	if _, err := fsys.VFS.Stat(path + linkSuffix); err == nil {
+		return path + linkSuffix
+	}
+	return path
+}
+
+// isLink returns true if node should be shown as a symlink
+func (fsys *FS) isLink(node vfs.Node) bool {
+	return fsys.opt.Symlinks && node.IsFile() && strings.HasSuffix(node.Name(), linkSuffix)
+}
+
 // lookup a Dir given a path
 func (fsys *FS) lookupDir(path string) (dir *vfs.Dir, errc int) {
 	node, errc := fsys.lookupNode(path)
@@ -156,6 +182,8 @@ func (fsys *FS) stat(node vfs.Node, stat *fuse.Stat_t) (errc int) {
 	Mode := node.Mode().Perm()
 	if node.IsDir() {
 		Mode |= fuse.S_IFDIR
+	} else if fsys.isLink(node) {
+		Mode |= fuse.S_IFLNK
 	} else {
 		Mode |= fuse.S_IFREG
 	}
@@ -253,6 +281,9 @@ func (fsys *FS) Readdir(dirPath string,
 	fill("..", nil, 0)
 	for _, node := range nodes {
 		name := node.Name()
+		if fsys.isLink(node) {
+			name = strings.TrimSuffix(name, linkSuffix)
+		}
 		if len(name) > mountlib.MaxLeafSize {
 			fs.Errorf(dirPath, "Name too long (%d bytes) for FUSE, skipping: %s", len(name), name)
 			continue
@@ -425,7 +456,7 @@ func (fsys *FS) Release(path string, fh uint64) (errc int) {
 // Unlink removes a file.
 func (fsys *FS) Unlink(filePath string) (errc int) {
 	defer log.Trace(filePath, "")("errc=%d", &errc)
-	leaf, parentDir, errc := fsys.lookupParentDir(filePath)
+	leaf, parentDir, errc := fsys.lookupParentDir(fsys.linkPath(filePath))
 	if errc != 0 {
 		return errc
 	}
@@ -456,6 +487,9 @@ func (fsys *FS) Rmdir(dirPath string) (errc int) {
 // Rename renames a file.
 func (fsys *FS) Rename(oldPath string, newPath string) (errc int) {
 	defer log.Trace(oldPath, "newPath=%q", newPath)("errc=%d", &errc)
+	if linkPath := fsys.linkPath(oldPath); linkPath != oldPath {
+		oldPath, newPath = linkPath, newPath+linkSuffix
+	}
 	return translateError(fsys.VFS.Rename(oldPath, newPath))
 }
 
@@ -504,15 +538,47 @@ func (fsys *FS) Link(oldpath string, newpath string) (errc int) {
 }
 
 // Symlink creates a symbolic link.
+//
+// With --symlinks this is stored as a file with the .rclonelink suffix
+// containing the target.
 func (fsys *FS) Symlink(target string, newpath string) (errc int) {
 	defer log.Trace(target, "newpath=%q", newpath)("errc=%d", &errc)
-	return -fuse.ENOSYS
+	if !fsys.opt.Symlinks {
+		return -fuse.ENOSYS
+	}
+	if _, err := fsys.VFS.Stat(newpath); err == nil {
+		return -fuse.EEXIST
+	}
+	handle, err := fsys.VFS.OpenFile(newpath+linkSuffix, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return translateError(err)
+	}
+	_, err = handle.WriteString(target)
+	closeErr := handle.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return translateError(err)
 }
 
 // Readlink reads the target of a symbolic link.
 func (fsys *FS) Readlink(path string) (errc int, linkPath string) {
 	defer log.Trace(path, "")("linkPath=%q, errc=%d", &linkPath, &errc)
-	return -fuse.ENOSYS, ""
+	if !fsys.opt.Symlinks {
+		return -fuse.ENOSYS, ""
+	}
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc, ""
+	}
+	if !fsys.isLink(node) {
+		return -fuse.EINVAL, ""
+	}
+	target, err := fsys.VFS.ReadFile(node.Path())
+	if err != nil {
+		return translateError(err), ""
+	}
+	return 0, string(target)
 }
 
 // Chmod changes the permission bits of a file.
@@ -546,25 +612,61 @@ func (fsys *FS) Fsyncdir(path string, datasync bool, fh uint64) (errc int) {
 // Setxattr sets extended attributes.
 func (fsys *FS) Setxattr(path string, name string, value []byte, flags int) (errc int) {
 	defer log.Trace(path, "name=%q, value=%q, flags=%d", name, value, flags)("errc=%d", &errc)
-	return -fuse.ENOSYS
+	if !fsys.opt.XattrMetadata {
+		return -fuse.ENOSYS
+	}
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc
+	}
+	return translateError(mountlib.SetXattr(node, name, value))
 }
 
 // Getxattr gets extended attributes.
 func (fsys *FS) Getxattr(path string, name string) (errc int, value []byte) {
 	defer log.Trace(path, "name=%q", name)("errc=%d, value=%q", &errc, &value)
-	return -fuse.ENOSYS, nil
+	if !fsys.opt.XattrMetadata {
+		return -fuse.ENOSYS, nil
+	}
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc, nil
+	}
+	value, err := mountlib.GetXattr(node, name)
+	return translateError(err), value
 }
 
 // Removexattr removes extended attributes.
+//
+// Metadata can't be removed so this is never supported.
 func (fsys *FS) Removexattr(path string, name string) (errc int) {
 	defer log.Trace(path, "name=%q", name)("errc=%d", &errc)
-	return -fuse.ENOSYS
+	if !fsys.opt.XattrMetadata {
+		return -fuse.ENOSYS
+	}
+	return -fuse.ENOTSUP
 }
 
 // Listxattr lists extended attributes.
 func (fsys *FS) Listxattr(path string, fill func(name string) bool) (errc int) {
 	defer log.Trace(path, "fill=%p", fill)("errc=%d", &errc)
-	return -fuse.ENOSYS
+	if !fsys.opt.XattrMetadata {
+		return -fuse.ENOSYS
+	}
+	node, errc := fsys.lookupNode(path)
+	if errc != 0 {
+		return errc
+	}
+	names, err := mountlib.ListXattrs(node)
+	if err != nil {
+		return translateError(err)
+	}
+	for _, name := range names {
+		if !fill(name) {
+			return -fuse.ERANGE
+		}
+	}
+	return 0
 }
 
 // Translate errors from mountlib
@@ -596,6 +698,10 @@ func translateError(err error) (errc int) {
 		return -fuse.ENOSYS
 	case vfs.EINVAL:
 		return -fuse.EINVAL
+	case mountlib.ErrNoXattr:
+		return -fuse.ENOATTR
+	case mountlib.ErrXattrNotSupported:
+		return -fuse.ENOTSUP
 	}
 	fs.Errorf(nil, "IO error: %v", err)
 	return -fuse.EIO
@@ -161,9 +161,12 @@ func mount(VFS *vfs.VFS, mountPath string, opt *mountlib.Options) (<-chan error,
 
 	// Create underlying FS
 	f := VFS.Fs()
-	fsys := NewFS(VFS)
+	fsys := NewFS(VFS, opt)
 	host := fuse.NewFileSystemHost(fsys)
 	host.SetCapReaddirPlus(true) // only works on Windows
+	if opt.CaseSensitive && f.Features().CaseInsensitive {
+		fs.Logf(f, "--case-sensitive ignored as the remote is case insensitive")
+	}
 	host.SetCapCaseInsensitive(f.Features().CaseInsensitive)
 
 	// Create options
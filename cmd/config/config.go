@@ -200,6 +200,16 @@ in with each invocation of |--continue|.
 At the end of the non interactive process, rclone will return a result
 with |State| as empty string.
 
+If |--oauth-proxy| is passed along with |--non-interactive| then
+rclone won't run a local webserver to receive the OAuth token, which
+can't work on a headless machine. Instead it returns a question with
+|AuthURL| set to the URL the user should visit to authorize rclone.
+After authorizing, the user's browser is redirected to a page at
+|http://127.0.0.1:53682/| which won't load. The address of that page
+should be returned to rclone as the |--result| and rclone will
+fetch the token with the code in it. This lets a web UI configure
+remotes using OAuth for a headless rclone.
+
 If |--all| is passed then rclone will ask all the config questions,
 not just the post config questions. Any parameters are used as
 defaults for questions as usual.
@@ -267,6 +277,7 @@ func init() {
 		flags.BoolVarP(cmdFlags, &updateRemoteOpt.All, "all", "", false, "Ask the full set of config questions")
 		flags.StringVarP(cmdFlags, &updateRemoteOpt.State, "state", "", "", "State - use with --continue")
 		flags.StringVarP(cmdFlags, &updateRemoteOpt.Result, "result", "", "", "Result - use with --continue")
+		flags.BoolVarP(cmdFlags, &updateRemoteOpt.OAuthProxy, "oauth-proxy", "", false, "Return the URL to authorize with instead of running a webserver - use with --non-interactive")
 	}
 }
 
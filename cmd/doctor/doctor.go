@@ -0,0 +1,353 @@
+// Package doctor provides the doctor command.
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/spf13/cobra"
+)
+
+var (
+	noWrite   = false
+	probeSize = fs.SizeSuffix(1024)
+)
+
+const (
+	clockWarn = time.Minute     // warn if the server time is this far out
+	clockFail = 5 * time.Minute // fail if the server time is this far out
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &noWrite, "no-write", "", noWrite, "Don't write, read and delete a probe file on the remote")
+	flags.FVarP(cmdFlags, &probeSize, "probe-size", "", "Size of the probe file to write")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "doctor remote:path",
+	Short: `Check a remote is working and print a report of any problems.`,
+	Long: `
+Runs a series of checks against remote:path and prints a report with
+advice on fixing any problems found. This is useful for finding out
+why a remote isn't working and for attaching to support requests.
+
+The checks are
+
+- ` + "`remote`" + ` - the remote is configured and can be created
+- ` + "`list`" + ` - the path can be listed, which checks connectivity and authentication
+- ` + "`token`" + ` - the authentication token hasn't expired, if the remote reports it
+- ` + "`clock`" + ` - the time of the servers is close to the time of this computer
+- ` + "`features`" + ` - the optional features, hashes and modification time precision of the remote
+- ` + "`probe`" + ` - a small file can be written, read back, checked and deleted
+
+The probe writes a file of ` + "`--probe-size`" + ` bytes in a new directory
+under remote:path and removes them afterwards. Use ` + "`--no-write`" + ` to
+skip it if the remote should not be written to.
+
+Each check is reported as ` + "`OK`" + `, ` + "`WARN`" + `, ` + "`FAIL`" + ` or
+` + "`SKIP`" + ` followed by advice for the ones which didn't pass, e.g.
+
+    rclone doctor report for "drive:"
+    rclone v1.60.0 on linux/amd64
+
+    [ OK ] remote: created drive backend in 12ms
+    [FAIL] list: couldn't list: oauth2: token expired
+           -> Reauthorize the remote with: rclone config reconnect drive:
+    ...
+
+The command exits with an error if any of the checks failed. Use
+` + "`-vv`" + ` to see the details of what rclone did as well.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		cmd.Run(false, false, command, func() error {
+			failed := doctor(context.Background(), args[0], os.Stdout)
+			if failed > 0 {
+				return fmt.Errorf("%d checks failed", failed)
+			}
+			return nil
+		})
+	},
+}
+
+// Status of a check
+const (
+	statusOK   = " OK "
+	statusWarn = "WARN"
+	statusFail = "FAIL"
+	statusSkip = "SKIP"
+)
+
+// report prints the results of the checks
+type report struct {
+	out    io.Writer
+	failed int
+}
+
+// check prints the result of a check with advice on fixing it
+func (r *report) check(status, name, detail, advice string) {
+	if status == statusFail {
+		r.failed++
+	}
+	_, _ = fmt.Fprintf(r.out, "[%s] %s: %s\n", status, name, detail)
+	if advice != "" {
+		_, _ = fmt.Fprintf(r.out, "       -> %s\n", advice)
+	}
+}
+
+// doctor checks remote writing a report to out and returning the
+// number of failed checks
+func doctor(ctx context.Context, remote string, out io.Writer) (failed int) {
+	r := &report{out: out}
+	_, _ = fmt.Fprintf(out, "rclone doctor report for %q\n", remote)
+	_, _ = fmt.Fprintf(out, "rclone %s on %s/%s\n\n", fs.Version, runtime.GOOS, runtime.GOARCH)
+
+	f := checkRemote(ctx, r, remote)
+	if f == nil {
+		return r.failed
+	}
+	listOK := checkList(ctx, r, f)
+	checkToken(r, f)
+	checkClock(r)
+	checkFeatures(r, f)
+	switch {
+	case noWrite:
+		r.check(statusSkip, "probe", "not writing to the remote as --no-write is set", "")
+	case !listOK:
+		r.check(statusSkip, "probe", "not writing to the remote as it couldn't be listed", "")
+	default:
+		checkProbe(ctx, r, f)
+	}
+	if retries := fs.LookupCounters(f.Name()).Stats().Retries; retries > 0 {
+		r.check(statusWarn, "retries", fmt.Sprintf("%d low level retries were needed", retries),
+			"The remote may be overloaded or rate limiting rclone - run with -vv to see why")
+	}
+	_, _ = fmt.Fprintf(out, "\n%d checks failed\n", r.failed)
+	return r.failed
+}
+
+// checkRemote makes the Fs for remote
+func checkRemote(ctx context.Context, r *report, remote string) fs.Fs {
+	const name = "remote"
+	fsInfo, configName, _, _, err := fs.ParseRemote(remote)
+	if err != nil {
+		r.check(statusFail, name, fmt.Sprintf("couldn't find remote: %v", err),
+			"Check the name of the remote with: rclone listremotes - or make it with: rclone config")
+		return nil
+	}
+	start := time.Now()
+	f, err := fs.NewFs(ctx, remote)
+	if err != nil && err != fs.ErrorIsFile {
+		r.check(statusFail, name, fmt.Sprintf("couldn't create %s backend: %v", fsInfo.Name, err),
+			fmt.Sprintf("Check the configuration with: rclone config show %s", configName))
+		return nil
+	}
+	r.check(statusOK, name, fmt.Sprintf("created %s backend in %v", fsInfo.Name, time.Since(start).Round(time.Millisecond)), "")
+	return f
+}
+
+// errorAdvice returns advice for fixing err from the remote f
+func errorAdvice(f fs.Fs, err error) string {
+	var netErr net.Error
+	msg := strings.ToLower(err.Error())
+	switch {
+	case errors.As(err, &netErr) || strings.Contains(msg, "no such host") || strings.Contains(msg, "connection refused"):
+		return "Check this computer can reach the server - look at the network, DNS, proxy and firewall settings"
+	case strings.Contains(msg, "token") || strings.Contains(msg, "unauthorized") || strings.Contains(msg, "401") || strings.Contains(msg, "invalid_grant"):
+		return fmt.Sprintf("Reauthorize the remote with: rclone config reconnect %s:", f.Name())
+	case strings.Contains(msg, "forbidden") || strings.Contains(msg, "403") || strings.Contains(msg, "permission") || strings.Contains(msg, "access denied"):
+		return "Check the credentials of the remote are allowed to access this path"
+	}
+	return "Run with -vv to see more details of the error"
+}
+
+// checkList lists the root of f
+func checkList(ctx context.Context, r *report, f fs.Fs) (ok bool) {
+	const name = "list"
+	start := time.Now()
+	entries, err := f.List(ctx, "")
+	dt := time.Since(start).Round(time.Millisecond)
+	if err == fs.ErrorDirNotFound {
+		r.check(statusWarn, name, fmt.Sprintf("the directory doesn't exist (found in %v)", dt),
+			"This is fine if it will be created - otherwise check the path")
+		return true
+	}
+	if err != nil {
+		r.check(statusFail, name, fmt.Sprintf("couldn't list: %v", err), errorAdvice(f, err))
+		return false
+	}
+	r.check(statusOK, name, fmt.Sprintf("listed %d entries in %v", len(entries), dt), "")
+	return true
+}
+
+// checkToken checks the authentication token if the remote reports it
+func checkToken(r *report, f fs.Fs) {
+	const name = "token"
+	expiry := fs.LookupCounters(f.Name()).Stats().TokenExpiry
+	if expiry == nil {
+		r.check(statusSkip, name, "the remote doesn't report when its token expires", "")
+		return
+	}
+	left := time.Until(*expiry).Round(time.Second)
+	if left <= 0 {
+		r.check(statusFail, name, fmt.Sprintf("the token expired at %v", expiry.Local()),
+			fmt.Sprintf("Reauthorize the remote with: rclone config reconnect %s:", f.Name()))
+		return
+	}
+	r.check(statusOK, name, fmt.Sprintf("the token expires in %v", left), "")
+}
+
+// checkClock checks the time of the servers talked to over HTTP
+func checkClock(r *report) {
+	const name = "clock"
+	offsets := fshttp.ServerTimeOffsets()
+	if len(offsets) == 0 {
+		r.check(statusSkip, name, "no server times were seen to compare with", "")
+		return
+	}
+	hosts := make([]string, 0, len(offsets))
+	for host := range offsets {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		offset := offsets[host].Round(time.Second)
+		abs := offset
+		if abs < 0 {
+			abs = -abs
+		}
+		detail := fmt.Sprintf("time of %s is %v from this computer", host, offset)
+		const advice = "Check the time and time zone of this computer are set correctly, e.g. with NTP"
+		switch {
+		case abs >= clockFail:
+			r.check(statusFail, name, detail, advice)
+		case abs >= clockWarn:
+			r.check(statusWarn, name, detail, advice)
+		default:
+			r.check(statusOK, name, detail, "")
+		}
+	}
+}
+
+// checkFeatures reports the optional features of f
+func checkFeatures(r *report, f fs.Fs) {
+	var enabled []string
+	for feature, ok := range f.Features().Enabled() {
+		if ok {
+			enabled = append(enabled, feature)
+		}
+	}
+	sort.Strings(enabled)
+	precision := "not supported"
+	if p := f.Precision(); p != fs.ModTimeNotSupported {
+		precision = p.String()
+	}
+	r.check(statusOK, "features", fmt.Sprintf("hashes %v, modtime precision %s, features %s",
+		f.Hashes(), precision, strings.Join(enabled, ", ")), "")
+}
+
+// checkProbe writes, reads back and deletes a file in a new directory
+func checkProbe(ctx context.Context, r *report, f fs.Fs) {
+	const name = "probe"
+	const writeAdvice = "Check the credentials of the remote are allowed to write to this path"
+	dir := "rclone-doctor-" + random.String(8)
+	remote := path.Join(dir, "probe.txt")
+	contents := []byte(random.String(int(probeSize)))
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	if err := f.Mkdir(ctx, dir); err != nil {
+		r.check(statusFail, name, fmt.Sprintf("couldn't make directory %q: %v", dir, err), writeAdvice)
+		return
+	}
+	defer func() {
+		if err := f.Rmdir(ctx, dir); err != nil {
+			r.check(statusWarn, name, fmt.Sprintf("couldn't remove directory %q: %v", dir, err),
+				"Remove the directory by hand")
+		}
+	}()
+
+	// Write
+	start := time.Now()
+	info := object.NewStaticObjectInfo(remote, modTime, int64(len(contents)), true, nil, f)
+	o, err := f.Put(ctx, bytes.NewReader(contents), info)
+	if err != nil {
+		r.check(statusFail, name, fmt.Sprintf("couldn't write %q: %v", remote, err), writeAdvice)
+		return
+	}
+	writeTime := time.Since(start).Round(time.Millisecond)
+	defer func() {
+		if err := o.Remove(ctx); err != nil {
+			r.check(statusFail, name, fmt.Sprintf("couldn't delete %q: %v", remote, err),
+				"Check the credentials of the remote are allowed to delete - and remove the file by hand")
+		}
+	}()
+
+	// Read back
+	start = time.Now()
+	o, err = f.NewObject(ctx, remote)
+	if err != nil {
+		r.check(statusFail, name, fmt.Sprintf("couldn't find %q after writing it: %v", remote, err),
+			"The remote may be eventually consistent - try again later")
+		return
+	}
+	in, err := o.Open(ctx)
+	if err == nil {
+		var got []byte
+		got, err = ioutil.ReadAll(in)
+		closeErr := in.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err == nil && !bytes.Equal(got, contents) {
+			err = fmt.Errorf("read %d bytes which differ from the %d bytes written", len(got), len(contents))
+		}
+	}
+	if err != nil {
+		r.check(statusFail, name, fmt.Sprintf("couldn't read back %q: %v", remote, err), errorAdvice(f, err))
+		return
+	}
+	readTime := time.Since(start).Round(time.Millisecond)
+
+	// Check the metadata
+	var problems []string
+	if o.Size() >= 0 && o.Size() != int64(len(contents)) {
+		problems = append(problems, fmt.Sprintf("size is %d not %d", o.Size(), len(contents)))
+	}
+	if ht := f.Hashes().GetOne(); ht != hash.None {
+		want, _ := hash.StreamTypes(bytes.NewReader(contents), hash.NewHashSet(ht))
+		if got, err := o.Hash(ctx, ht); err == nil && got != "" && got != want[ht] {
+			problems = append(problems, fmt.Sprintf("%v hash is %q not %q", ht, got, want[ht]))
+		}
+	}
+	if precision := f.Precision(); precision != fs.ModTimeNotSupported {
+		if dt := o.ModTime(ctx).Sub(modTime); dt > precision || dt < -precision {
+			problems = append(problems, fmt.Sprintf("modification time is %v out", dt))
+		}
+	}
+	if len(problems) > 0 {
+		r.check(statusWarn, name, "file written and read back but "+strings.Join(problems, ", "),
+			"Run with -vv and report this as it may be a bug")
+		return
+	}
+	r.check(statusOK, name, fmt.Sprintf("wrote %v in %v, read it back in %v", probeSize, writeTime, readTime), "")
+}
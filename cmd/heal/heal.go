@@ -0,0 +1,368 @@
+// Package heal provides the heal command.
+package heal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checksum   = false
+	reportFile = ""
+	reportOK   = false
+	stateFile  = ""
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &checksum, "checksum", "", checksum, "Check the data read matches the hash stored on the remote")
+	flags.StringVarP(cmdFlags, &reportFile, "report", "", reportFile, "Write the report to this file rather than the terminal")
+	flags.BoolVarP(cmdFlags, &reportOK, "report-ok", "", reportOK, "Include the objects which were read correctly in the report")
+	flags.StringVarP(cmdFlags, &stateFile, "state", "", stateFile, "Record the objects read in this file so an interrupted run can be resumed")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "heal remote:path",
+	Short: `Read every object to find the corrupted and unreadable ones.`,
+	Long: `
+Reads every object in the path from start to end to find the ones
+which can't be read or which are corrupted, writing a report with a
+line for each.
+
+Each object is read again if there is any error reading it. This is
+done up to ` + "`--low-level-retries`" + ` times with an increasing
+sleep between the tries, so only objects which fail every time are
+reported.
+
+If ` + "`--checksum`" + ` is set then the hash of the data read is
+checked against the hash stored on the remote, and the object is
+reported as corrupted if they differ. Objects without a stored hash
+are only checked for being readable. The size of the data read is
+always checked against the size of the object.
+
+The report has one JSON object per line, like this
+
+    {"Path":"dir/file.txt","Size":1234,"Status":"corrupt","Error":"md5 hash differs","HashType":"md5","Hash":"...","Expected":"...","Tries":10}
+
+Where Status is one of
+
+- ` + "`ok`" + ` - the object was read correctly (only with ` + "`--report-ok`" + `)
+- ` + "`unreadable`" + ` - the object couldn't be read
+- ` + "`corrupt`" + ` - the data read had the wrong size or hash
+- ` + "`missing`" + ` - the object disappeared after it was listed
+
+The report is written to the terminal unless ` + "`--report`" + ` is
+given.
+
+To make a long run resumable use ` + "`--state file`" + `. The path
+of each object is appended to the file once it has been read, and
+objects already in the file are skipped when the command is run again.
+When resuming, the report is appended to rather than replaced.
+
+Use the [filters](/filtering/) to choose which objects to read and
+` + "`--checkers`" + ` to set how many are read at once.
+
+The command exits with an error if any objects were damaged.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(1, 1, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		cmd.Run(false, true, command, func() error {
+			return run(context.Background(), fsrc)
+		})
+		return nil
+	},
+}
+
+// run opens the report and state files from the flags and heals f
+func run(ctx context.Context, f fs.Fs) (err error) {
+	opt := &options{
+		checksum: checksum,
+		reportOK: reportOK,
+		report:   os.Stdout,
+	}
+	if stateFile != "" {
+		opt.done, err = readState(stateFile)
+		if err != nil {
+			return err
+		}
+		var state *os.File
+		state, err = os.OpenFile(stateFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+		if err != nil {
+			return fmt.Errorf("failed to open state file: %w", err)
+		}
+		defer fs.CheckClose(state, &err)
+		opt.state = state
+	}
+	if reportFile != "" {
+		mode := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if len(opt.done) > 0 {
+			// append to the report of the previous run
+			mode = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		var report *os.File
+		report, err = os.OpenFile(reportFile, mode, 0666)
+		if err != nil {
+			return fmt.Errorf("failed to open report file: %w", err)
+		}
+		defer fs.CheckClose(report, &err)
+		opt.report = report
+	}
+	return heal(ctx, f, opt)
+}
+
+// Status values for Result
+const (
+	StatusOK         = "ok"
+	StatusUnreadable = "unreadable"
+	StatusCorrupt    = "corrupt"
+	StatusMissing    = "missing"
+)
+
+// Result is a line of the report
+type Result struct {
+	Path     string
+	Size     int64
+	Status   string
+	Error    string `json:",omitempty"`
+	HashType string `json:",omitempty"`
+	Hash     string `json:",omitempty"` // hash of the data read
+	Expected string `json:",omitempty"` // hash stored on the remote
+	Tries    int
+}
+
+// options for heal
+type options struct {
+	checksum bool                // check the hashes
+	reportOK bool                // report the objects which are OK
+	report   io.Writer           // write the report here
+	state    io.Writer           // append the paths of the objects read here if set
+	done     map[string]struct{} // paths of the objects read by a previous run
+}
+
+// readState reads the paths of the objects read by a previous run
+// from the state file, if it exists.
+func readState(path string) (done map[string]struct{}, err error) {
+	done = make(map[string]struct{})
+	in, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file: %w", err)
+	}
+	defer fs.CheckClose(in, &err)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var remote string
+		if err := json.Unmarshal(scanner.Bytes(), &remote); err != nil {
+			// ignore a line partially written when interrupted
+			fs.Debugf(nil, "Ignoring bad line in state file: %q", scanner.Text())
+			continue
+		}
+		done[remote] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	return done, nil
+}
+
+// damageError is returned when the data read is wrong
+type damageError struct {
+	error
+}
+
+// readObject reads o from start to end returning the number of bytes
+// read and their hash if ht isn't hash.None.
+func readObject(ctx context.Context, o fs.Object, ht hash.Type) (n int64, sum string, err error) {
+	tr := accounting.Stats(ctx).NewTransfer(o)
+	defer func() {
+		tr.Done(ctx, nil) // errors are counted when the object is reported
+	}()
+	var options []fs.OpenOption
+	for _, option := range fs.GetConfig(ctx).DownloadHeaders {
+		options = append(options, option)
+	}
+	in0, err := o.Open(ctx, options...)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open: %w", err)
+	}
+	in := tr.Account(ctx, in0)
+	defer fs.CheckClose(in, &err)
+	var (
+		w      io.Writer = ioutil.Discard
+		hasher *hash.MultiHasher
+	)
+	if ht != hash.None {
+		hasher, err = hash.NewMultiHasherTypes(hash.NewHashSet(ht))
+		if err != nil {
+			return 0, "", err
+		}
+		w = hasher
+	}
+	n, err = io.Copy(w, in)
+	if err != nil {
+		return n, "", fmt.Errorf("failed to read: %w", err)
+	}
+	if hasher != nil {
+		sum, err = hasher.SumString(ht, false)
+		if err != nil {
+			return n, "", err
+		}
+	}
+	return n, sum, nil
+}
+
+// healObject reads o, retrying on any error, and returns the result
+func healObject(ctx context.Context, o fs.Object, ht hash.Type) (res Result) {
+	res = Result{
+		Path:   o.Remote(),
+		Size:   o.Size(),
+		Status: StatusOK,
+	}
+	if ht != hash.None {
+		expected, err := o.Hash(ctx, ht)
+		if err != nil {
+			fs.Debugf(o, "Not checking hash as failed to read it: %v", err)
+		}
+		if err != nil || expected == "" {
+			ht = hash.None
+		} else {
+			res.HashType = ht.String()
+			res.Expected = expected
+		}
+	}
+	retryOpt := fs.NewRetryOptions(ctx)
+	retryOpt.Sleep = time.Second
+	retryOpt.MaxSleep = time.Minute
+	err := fs.Retry(ctx, o, retryOpt, func() error {
+		res.Tries++
+		n, sum, err := readObject(ctx, o, ht)
+		if errors.Is(err, fs.ErrorObjectNotFound) {
+			return err
+		}
+		if err != nil {
+			return fserrors.RetryError(err)
+		}
+		res.Hash = sum
+		if res.Size >= 0 && n != res.Size {
+			return fserrors.RetryError(damageError{fmt.Errorf("read %d bytes but size is %d", n, res.Size)})
+		}
+		if res.Expected != "" && sum != res.Expected {
+			return fserrors.RetryError(damageError{fmt.Errorf("%v hash differs", ht)})
+		}
+		return nil
+	})
+	if err != nil {
+		var damage damageError
+		switch {
+		case errors.Is(err, fs.ErrorObjectNotFound):
+			res.Status = StatusMissing
+		case errors.As(err, &damage):
+			res.Status = StatusCorrupt
+		default:
+			res.Status = StatusUnreadable
+		}
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// heal reads every object in f writing the results to the report
+func heal(ctx context.Context, f fs.Fs, opt *options) error {
+	ci := fs.GetConfig(ctx)
+	ht := hash.None
+	if opt.checksum {
+		ht = f.Hashes().GetOne()
+		if ht == hash.None {
+			fs.Logf(f, "No hashes supported - only checking objects can be read")
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		report   = json.NewEncoder(opt.report)
+		checked  int64
+		skipped  int64
+		damaged  int64
+		outErr   error
+		wg       sync.WaitGroup
+		objects  = make(chan fs.Object, ci.Checkers)
+		checkers = ci.Checkers
+	)
+	if checkers < 1 {
+		checkers = 1
+	}
+	for i := 0; i < checkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for o := range objects {
+				res := healObject(ctx, o, ht)
+				if res.Status != StatusOK {
+					err := fs.CountError(errors.New(res.Error))
+					fs.Errorf(o, "%s: %v", res.Status, err)
+				}
+				mu.Lock()
+				checked++
+				if res.Status != StatusOK {
+					damaged++
+				}
+				if res.Status != StatusOK || opt.reportOK {
+					if err := report.Encode(res); err != nil && outErr == nil {
+						outErr = fmt.Errorf("failed to write report: %w", err)
+					}
+				}
+				if opt.state != nil && ctx.Err() == nil {
+					line, _ := json.Marshal(res.Path)
+					if _, err := fmt.Fprintf(opt.state, "%s\n", line); err != nil && outErr == nil {
+						outErr = fmt.Errorf("failed to write state file: %w", err)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	err := operations.ListFn(ctx, f, func(o fs.Object) {
+		if _, found := opt.done[o.Remote()]; found {
+			mu.Lock()
+			skipped++
+			mu.Unlock()
+			return
+		}
+		objects <- o
+	})
+	close(objects)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	if outErr != nil {
+		return outErr
+	}
+	fs.Logf(f, "%d objects read, %d damaged, %d skipped as read by a previous run", checked, damaged, skipped)
+	if damaged > 0 {
+		return fmt.Errorf("%d damaged objects found", damaged)
+	}
+	return nil
+}
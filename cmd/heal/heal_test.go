@@ -0,0 +1,128 @@
+package heal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t1 = fstest.Time("2017-02-03T04:05:06.499999999Z")
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+// damagedObject is an object with a wrong hash or which can't be opened
+type damagedObject struct {
+	fs.Object
+	hash    string
+	openErr error
+}
+
+func (o damagedObject) Hash(ctx context.Context, ht hash.Type) (string, error) {
+	return o.hash, nil
+}
+
+func (o damagedObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	if o.openErr != nil {
+		return nil, o.openErr
+	}
+	return o.Object.Open(ctx, options...)
+}
+
+func TestHealObject(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.LowLevelRetries = 2
+	content := []byte("hello")
+	o := mockobject.New("file.txt").WithContent(content, mockobject.SeekModeNone)
+
+	res := healObject(ctx, damagedObject{Object: o, hash: "5d41402abc4b2a76b9719d911017c592"}, hash.MD5)
+	assert.Equal(t, Result{Path: "file.txt", Size: 5, Status: StatusOK, HashType: "md5", Hash: "5d41402abc4b2a76b9719d911017c592", Expected: "5d41402abc4b2a76b9719d911017c592", Tries: 1}, res)
+
+	res = healObject(ctx, damagedObject{Object: o, hash: "potato"}, hash.MD5)
+	assert.Equal(t, StatusCorrupt, res.Status)
+	assert.Equal(t, "md5 hash differs", res.Error)
+	assert.Equal(t, 2, res.Tries)
+
+	res = healObject(ctx, damagedObject{Object: o}, hash.MD5)
+	assert.Equal(t, StatusOK, res.Status)
+	assert.Equal(t, "", res.HashType)
+
+	res = healObject(ctx, damagedObject{Object: o, openErr: errors.New("bad sector")}, hash.None)
+	assert.Equal(t, StatusUnreadable, res.Status)
+	assert.Equal(t, "failed to open: bad sector", res.Error)
+	assert.Equal(t, 2, res.Tries)
+
+	res = healObject(ctx, damagedObject{Object: o, openErr: fs.ErrorObjectNotFound}, hash.None)
+	assert.Equal(t, StatusMissing, res.Status)
+	assert.Equal(t, 1, res.Tries)
+
+	short := mockobject.New("short.txt").WithContent(content, mockobject.SeekModeNone)
+	short.SetUnknownSize(true)
+	res = healObject(ctx, short, hash.None)
+	assert.Equal(t, StatusOK, res.Status)
+}
+
+func TestHeal(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject(ctx, "file1.txt", "hello", t1)
+	file2 := r.WriteObject(ctx, "dir/file2.txt", "potato", t1)
+	r.CheckRemoteItems(t, file1, file2)
+
+	var report, state bytes.Buffer
+	opt := &options{
+		checksum: true,
+		reportOK: true,
+		report:   &report,
+		state:    &state,
+	}
+	require.NoError(t, heal(ctx, r.Fremote, opt))
+
+	var results []Result
+	decoder := json.NewDecoder(&report)
+	for decoder.More() {
+		var res Result
+		require.NoError(t, decoder.Decode(&res))
+		results = append(results, res)
+	}
+	require.Len(t, results, 2)
+	for _, res := range results {
+		assert.Equal(t, StatusOK, res.Status)
+		if r.Fremote.Hashes().Contains(hash.MD5) {
+			assert.Equal(t, "md5", res.HashType)
+			assert.Equal(t, res.Expected, res.Hash)
+		}
+	}
+
+	// Resume from the state
+	stateFile := filepath.Join(t.TempDir(), "state")
+	state.WriteString(`"partial`)
+	require.NoError(t, ioutil.WriteFile(stateFile, state.Bytes(), 0666))
+	done, err := readState(stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]struct{}{"file1.txt": {}, "dir/file2.txt": {}}, done)
+	report.Reset()
+	opt.done = done
+	require.NoError(t, heal(ctx, r.Fremote, opt))
+	assert.Equal(t, "", report.String())
+
+	done, err = readState(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, done)
+}
@@ -140,6 +140,12 @@ those only (without traversing the whole directory structure):
     rclone lsf --absolute --files-only --max-age 1d /path/to/local > new_files
     rclone copy --files-from-raw new_files /path/to/local remote:path
 
+Each line is written as soon as the item is read from the remote, so
+very large listings can be processed while they are still being
+listed. On remotes which support it (e.g. s3) a directory listed
+without ` + "`--recursive`" + ` is read a page at a time rather than all
+at once.
+
 ` + lshelp.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
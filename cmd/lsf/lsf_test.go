@@ -9,6 +9,9 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/list"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/mockdir"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -223,3 +226,28 @@ subdir/file3_+_111_+_`+expectedOutput[6]+`
 	recurse = false
 	dirSlash = false
 }
+
+func TestListP(t *testing.T) {
+	ctx := context.Background()
+	buf := new(bytes.Buffer)
+	format = "p"
+	separator = ""
+	dirSlash = true
+	defer func() {
+		format = ""
+		dirSlash = false
+	}()
+	f := mockfs.NewFs(ctx, "mock", "/")
+	f.Features().ListP = func(ctx context.Context, dir string, callback fs.ListRCallback) error {
+		if err := callback(fs.DirEntries{mockobject.Object("a"), mockobject.Object("b")}); err != nil {
+			return err
+		}
+		// The first page should have been written already
+		assert.Equal(t, "a\nb\n", buf.String())
+		return callback(fs.DirEntries{mockdir.New("dir"), mockobject.Object("c")})
+	}
+
+	err := Lsf(ctx, f, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\ndir/\nc\n", buf.String())
+}
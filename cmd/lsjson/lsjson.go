@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/rclone/rclone/cmd"
@@ -17,6 +18,7 @@ import (
 var (
 	opt      operations.ListJSONOpt
 	statOnly bool
+	ndjson   bool
 )
 
 func init() {
@@ -33,6 +35,7 @@ func init() {
 	flags.BoolVarP(cmdFlags, &opt.Metadata, "metadata", "M", false, "Add metadata to the listing")
 	flags.StringArrayVarP(cmdFlags, &opt.HashTypes, "hash-type", "", nil, "Show only this hash type (may be repeated)")
 	flags.BoolVarP(cmdFlags, &statOnly, "stat", "", false, "Just return the info for the pointed to file")
+	flags.BoolVarP(cmdFlags, &ndjson, "ndjson", "", false, "Output one JSON object per line without the enclosing array")
 }
 
 var commandDefinition = &cobra.Command{
@@ -110,6 +113,18 @@ will be shown ("2017-05-31T16:15:57+01:00").
 
 The whole output can be processed as a JSON blob, or alternatively it
 can be processed line by line as each item is written one to a line.
+
+If ` + "`--ndjson`" + ` is set then the output is in [newline delimited
+JSON](http://ndjson.org/) format instead. Each item is written as a
+JSON object on its own line with no enclosing array or separating
+commas, so each line can be parsed on its own.
+
+Items are written as soon as they are read from the remote, so very
+large listings can be processed while they are still being listed
+without holding them all in memory. The listing proceeds only as fast
+as the output is read. On remotes which support it (e.g. s3) a
+directory listed without ` + "`--recursive`" + ` is read a page at a
+time rather than all at once.
 ` + lshelp.Help,
 	RunE: func(command *cobra.Command, args []string) error {
 		cmd.CheckArgs(1, 1, command, args)
@@ -135,6 +150,8 @@ can be processed line by line as each item is written one to a line.
 					return fmt.Errorf("failed to write to output: %w", err)
 				}
 				fmt.Println()
+			} else if ndjson {
+				return listNDJSON(context.Background(), fsrc, remote, os.Stdout)
 			} else {
 				fmt.Println("[")
 				first := true
@@ -167,3 +184,15 @@ can be processed line by line as each item is written one to a line.
 		return nil
 	},
 }
+
+// listNDJSON writes the listing to out with one JSON object per line
+func listNDJSON(ctx context.Context, fsrc fs.Fs, remote string, out io.Writer) error {
+	enc := json.NewEncoder(out)
+	return operations.ListJSON(ctx, fsrc, remote, &opt, func(item *operations.ListJSONItem) error {
+		err := enc.Encode(item)
+		if err != nil {
+			return fmt.Errorf("failed to write to output: %w", err)
+		}
+		return nil
+	})
+}
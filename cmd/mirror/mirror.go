@@ -0,0 +1,59 @@
+// Package mirror provides the mirror command.
+package mirror
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "mirror source:path dest:path [dest:path...]",
+	Short: `Make several destinations the same as the source, reading it once.`,
+	// Note: "|" will be replaced by backticks below
+	Long: strings.ReplaceAll(`
+Make each of the destinations identical to the source, reading each
+file from the source only once. This is like running
+[sync](/commands/rclone_sync/) to each destination in turn, but the
+data only crosses the network from the source once.
+
+Files which need copying to more than one destination are read from
+the source and uploaded to all of them at the same time. If an upload
+to one of the destinations fails then it is retried on its own, so a
+slow or broken destination doesn't stop the others being updated.
+Destinations which can copy the file server-side from the source do
+so rather than uploading it.
+
+Files on a destination which aren't in the source are deleted, unless
+there were errors copying files to that destination. Errors are
+tracked for each destination separately and the counts of files
+transferred, deleted and errors are logged for each one at the end.
+
+**Important**: Since this can cause data loss, test first with the
+|--dry-run| or the |--interactive|/|-i| flag.
+
+Note that unlike sync, empty directories are not created or removed
+on the destinations.
+
+Use |--transfers| to set how many files are mirrored at once.
+`, "|", "`"),
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 1e6, command, args)
+		fsrc := cmd.NewFsSrc(args)
+		fdsts := make([]fs.Fs, len(args)-1)
+		for i := range fdsts {
+			fdsts[i] = cmd.NewFsDir(args[i+1:])
+		}
+		cmd.Run(true, true, command, func() error {
+			return operations.Mirror(context.Background(), fdsts, fsrc)
+		})
+	},
+}
@@ -10,6 +10,7 @@ import (
 
 	"bazil.org/fuse"
 	fusefs "bazil.org/fuse/fs"
+	"github.com/rclone/rclone/cmd/mountlib"
 	"github.com/rclone/rclone/fs/log"
 	"github.com/rclone/rclone/vfs"
 )
@@ -97,23 +98,38 @@ func (f *File) Fsync(ctx context.Context, req *fuse.FsyncRequest) (err error) {
 // node.
 //
 // If there is no xattr by that name, returns fuse.ErrNoXattr.
-func (f *File) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) error {
-	return syscall.ENOSYS // we never implement this
+func (f *File) Getxattr(ctx context.Context, req *fuse.GetxattrRequest, resp *fuse.GetxattrResponse) (err error) {
+	if !f.fsys.opt.XattrMetadata {
+		return syscall.ENOSYS
+	}
+	defer log.Trace(f, "name=%q", req.Name)("err=%v", &err)
+	resp.Xattr, err = mountlib.GetXattr(f.File, req.Name)
+	return translateError(err)
 }
 
 var _ fusefs.NodeGetxattrer = (*File)(nil)
 
 // Listxattr lists the extended attributes recorded for the node.
-func (f *File) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) error {
-	return syscall.ENOSYS // we never implement this
+func (f *File) Listxattr(ctx context.Context, req *fuse.ListxattrRequest, resp *fuse.ListxattrResponse) (err error) {
+	if !f.fsys.opt.XattrMetadata {
+		return syscall.ENOSYS
+	}
+	defer log.Trace(f, "")("err=%v", &err)
+	names, err := mountlib.ListXattrs(f.File)
+	resp.Append(names...)
+	return translateError(err)
 }
 
 var _ fusefs.NodeListxattrer = (*File)(nil)
 
 // Setxattr sets an extended attribute with the given name and
 // value for the node.
-func (f *File) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) error {
-	return syscall.ENOSYS // we never implement this
+func (f *File) Setxattr(ctx context.Context, req *fuse.SetxattrRequest) (err error) {
+	if !f.fsys.opt.XattrMetadata {
+		return syscall.ENOSYS
+	}
+	defer log.Trace(f, "name=%q, value=%q", req.Name, req.Xattr)("err=%v", &err)
+	return translateError(mountlib.SetXattr(f.File, req.Name, req.Xattr))
 }
 
 var _ fusefs.NodeSetxattrer = (*File)(nil)
@@ -121,8 +137,13 @@ var _ fusefs.NodeSetxattrer = (*File)(nil)
 // Removexattr removes an extended attribute for the name.
 //
 // If there is no xattr by that name, returns fuse.ErrNoXattr.
+//
+// Metadata can't be removed so this is never supported.
 func (f *File) Removexattr(ctx context.Context, req *fuse.RemovexattrRequest) error {
-	return syscall.ENOSYS // we never implement this
+	if !f.fsys.opt.XattrMetadata {
+		return syscall.ENOSYS
+	}
+	return fuse.Errno(syscall.ENOTSUP)
 }
 
 var _ fusefs.NodeRemovexattrer = (*File)(nil)
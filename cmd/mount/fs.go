@@ -101,6 +101,10 @@ func translateError(err error) error {
 		return syscall.ENOSYS
 	case vfs.EINVAL:
 		return fuse.Errno(syscall.EINVAL)
+	case mountlib.ErrNoXattr:
+		return fuse.ErrNoXattr
+	case mountlib.ErrXattrNotSupported:
+		return fuse.Errno(syscall.ENOTSUP)
 	}
 	fs.Errorf(nil, "IO error: %v", err)
 	return err
@@ -128,6 +128,10 @@ func translateError(err error) syscall.Errno {
 		return syscall.ENOSYS
 	case vfs.EINVAL:
 		return syscall.EINVAL
+	case mountlib.ErrNoXattr:
+		return syscall.Errno(fuse.ENOATTR)
+	case mountlib.ErrXattrNotSupported:
+		return syscall.ENOTSUP
 	}
 	fs.Errorf(nil, "IO error: %v", err)
 	return syscall.EIO
@@ -30,7 +30,7 @@ func mountOptions(fsys *FS, f fs.Fs, opt *mountlib.Options) (mountOpts *fuse.Mou
 		AllowOther:    fsys.opt.AllowOther,
 		FsName:        opt.DeviceName,
 		Name:          "rclone",
-		DisableXAttrs: true,
+		DisableXAttrs: !opt.XattrMetadata,
 		Debug:         fsys.opt.DebugFUSE,
 		MaxReadAhead:  int(fsys.opt.MaxReadAhead),
 
@@ -405,3 +405,68 @@ func (n *Node) Rename(ctx context.Context, oldName string, newParent fusefs.Inod
 }
 
 var _ = (fusefs.NodeRenamer)((*Node)(nil))
+
+// copyXattr copies value into dest returning the size of value.
+//
+// If dest is empty the caller only wants the size and if it is too
+// small it returns ERANGE.
+func copyXattr(value []byte, dest []byte) (uint32, syscall.Errno) {
+	if len(dest) == 0 {
+		return uint32(len(value)), 0
+	}
+	if len(dest) < len(value) {
+		return uint32(len(value)), syscall.ERANGE
+	}
+	return uint32(copy(dest, value)), 0
+}
+
+// Getxattr should read data for the given attribute into
+// `dest` and return the number of bytes. If `dest` is too
+// small, it should return ERANGE and the size of the attribute.
+func (n *Node) Getxattr(ctx context.Context, attr string, dest []byte) (size uint32, errno syscall.Errno) {
+	defer log.Trace(n, "attr=%q", attr)("size=%d, errno=%v", &size, &errno)
+	value, err := mountlib.GetXattr(n.node, attr)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return copyXattr(value, dest)
+}
+
+var _ = (fusefs.NodeGetxattrer)((*Node)(nil))
+
+// Setxattr should store data for the given attribute.
+func (n *Node) Setxattr(ctx context.Context, attr string, data []byte, flags uint32) (errno syscall.Errno) {
+	defer log.Trace(n, "attr=%q, data=%q, flags=%d", attr, data, flags)("errno=%v", &errno)
+	return translateError(mountlib.SetXattr(n.node, attr, data))
+}
+
+var _ = (fusefs.NodeSetxattrer)((*Node)(nil))
+
+// Removexattr should delete the given attribute.
+//
+// Metadata can't be removed so this is never supported.
+func (n *Node) Removexattr(ctx context.Context, attr string) (errno syscall.Errno) {
+	defer log.Trace(n, "attr=%q", attr)("errno=%v", &errno)
+	return syscall.ENOTSUP
+}
+
+var _ = (fusefs.NodeRemovexattrer)((*Node)(nil))
+
+// Listxattr should read all attributes (null terminated) into
+// `dest`. If the `dest` buffer is too small, it should return ERANGE
+// and the correct size.
+func (n *Node) Listxattr(ctx context.Context, dest []byte) (size uint32, errno syscall.Errno) {
+	defer log.Trace(n, "")("size=%d, errno=%v", &size, &errno)
+	names, err := mountlib.ListXattrs(n.node)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	var value []byte
+	for _, name := range names {
+		value = append(value, name...)
+		value = append(value, 0)
+	}
+	return copyXattr(value, dest)
+}
+
+var _ = (fusefs.NodeListxattrer)((*Node)(nil))
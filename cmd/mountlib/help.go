@@ -227,6 +227,18 @@ Read more in the [install documentation](https://rclone.org/install/).
 Note that mapping to a directory path, instead of a drive letter,
 does not suffer from the same limitations.
 
+#### Case sensitivity on Windows
+
+File names on a mount on Windows are case insensitive by default, as
+Windows applications expect. If a file isn't found rclone looks for
+one whose name differs only in case (see |--vfs-case-insensitive|).
+
+Some tools, such as |git| and build systems used for development,
+rely on names which differ only in case being different files. Add
+the |--case-sensitive| flag to turn off the case insensitive matching
+so the mount behaves like the remote. This has no effect if the remote
+is case insensitive itself, such as a local Windows disk.
+
 ### Limitations
 
 Without the use of |--vfs-cache-mode| this can only write files
@@ -290,6 +302,39 @@ then there is no chance of corruption.
 
 This is the same as setting the attr_timeout option in mount.fuse.
 
+### Metadata as extended attributes
+
+If the |--xattr-metadata| flag is set then the [metadata](/docs/#metadata)
+of each file is shown as extended attributes called |user.rclone.KEY|,
+so desktop tools can view it. For example on Linux
+
+    getfattr -d -m user.rclone /path/to/mountpoint/file.txt
+    setfattr -n user.rclone.content-type -v text/plain /path/to/mountpoint/file.txt
+
+Extended attributes can only be set if the backend can set the
+metadata of an existing object without uploading it again, which at
+the moment is only the [local](/local/#metadata) backend. On other
+backends the extended attributes are read only and setting them fails
+with "Operation not supported". Metadata can't be set on a file while
+it is being written to, and it can't be removed.
+
+Reading the extended attributes may need a call to the remote for
+each file, so this is off by default.
+
+### Symlinks
+
+If the |--symlinks| flag is set then files on the remote with the
+|.rclonelink| extension, as made by |rclone copy --links| from a local
+disk, are shown as symbolic links with the extension removed. The
+target of the link is the content of the file. Making a symbolic link
+in the mount uploads a file like this.
+
+On Windows the links are shown as reparse points by WinFsp, so they
+can be followed by applications and |dir| shows them as |<SYMLINK>|.
+
+This is only supported by the cmount implementation of @, which is
+the one used on Windows.
+
 ### Filters
 
 Note that all the rclone filters can be used to select a subset of the
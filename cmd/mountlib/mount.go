@@ -47,6 +47,9 @@ type Options struct {
 	DaemonTimeout      time.Duration // OSXFUSE only
 	AsyncRead          bool
 	NetworkMode        bool // Windows only
+	XattrMetadata      bool // show metadata as extended attributes
+	CaseSensitive      bool // Windows only
+	Symlinks           bool // show .rclonelink files as symlinks
 }
 
 // DefaultOpt is the default values for creating the mount
@@ -138,6 +141,8 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.FVarP(flagSet, &Opt.MaxReadAhead, "max-read-ahead", "", "The number of bytes that can be prefetched for sequential reads (not supported on Windows)")
 	flags.BoolVarP(flagSet, &Opt.WritebackCache, "write-back-cache", "", Opt.WritebackCache, "Makes kernel buffer writes before sending them to rclone (without this, writethrough caching is used) (not supported on Windows)")
 	flags.StringVarP(flagSet, &Opt.DeviceName, "devname", "", Opt.DeviceName, "Set the device name - default is remote:path")
+	flags.BoolVarP(flagSet, &Opt.XattrMetadata, "xattr-metadata", "", Opt.XattrMetadata, "Show metadata as "+XattrPrefix+"* extended attributes on files (only settable on local)")
+	flags.BoolVarP(flagSet, &Opt.Symlinks, "symlinks", "", Opt.Symlinks, "Show files with a '.rclonelink' extension as symlinks (cmount only)")
 	// Windows and OSX
 	flags.StringVarP(flagSet, &Opt.VolumeName, "volname", "", Opt.VolumeName, "Set the volume name (supported on Windows and OSX only)")
 	// OSX only
@@ -145,6 +150,7 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &Opt.NoAppleXattr, "noapplexattr", "", Opt.NoAppleXattr, "Ignore all \"com.apple.*\" extended attributes (supported on OSX only)")
 	// Windows only
 	flags.BoolVarP(flagSet, &Opt.NetworkMode, "network-mode", "", Opt.NetworkMode, "Mount as remote network drive, instead of fixed disk drive (supported on Windows only)")
+	flags.BoolVarP(flagSet, &Opt.CaseSensitive, "case-sensitive", "", Opt.CaseSensitive, "Make file names case sensitive, disabling --vfs-case-insensitive (supported on Windows only)")
 	// Unix only
 	flags.DurationVarP(flagSet, &Opt.DaemonWait, "daemon-wait", "", Opt.DaemonWait, "Time to wait for ready mount from daemon (maximum time on Linux, constant sleep time on OSX/BSD) (not supported on Windows)")
 }
@@ -251,6 +257,9 @@ func (m *MountPoint) Mount() (daemon *os.Process, err error) {
 		}
 	}
 
+	if m.MountOpt.CaseSensitive && runtime.GOOS == "windows" {
+		m.VFSOpt.CaseInsensitive = false
+	}
 	m.VFS = vfs.New(m.Fs, &m.VFSOpt)
 
 	m.ErrChan, m.UnmountFn, err = m.MountFn(m.VFS, m.MountPoint, &m.MountOpt)
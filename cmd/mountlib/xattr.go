@@ -0,0 +1,82 @@
+// Extended attributes showing the metadata of files
+
+package mountlib
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+)
+
+// XattrPrefix starts the names of the extended attributes which show
+// the metadata of files with --xattr-metadata, eg
+// "user.rclone.content-type"
+const XattrPrefix = "user.rclone."
+
+// Errors returned by the extended attribute functions which the mounts
+// translate into their own error numbers
+var (
+	ErrNoXattr           = errors.New("no such extended attribute")
+	ErrXattrNotSupported = errors.New("extended attribute not supported")
+)
+
+// xattrFile returns node as a *vfs.File if it is one
+func xattrFile(node vfs.Node) (file *vfs.File, ok bool) {
+	file, ok = node.(*vfs.File)
+	return file, ok
+}
+
+// ListXattrs returns the names of the extended attributes of node
+func ListXattrs(node vfs.Node) (names []string, err error) {
+	file, ok := xattrFile(node)
+	if !ok {
+		return nil, nil
+	}
+	metadata, err := file.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	for key := range metadata {
+		names = append(names, XattrPrefix+key)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetXattr returns the value of the extended attribute name of node
+//
+// It returns ErrNoXattr if there isn't one.
+func GetXattr(node vfs.Node, name string) (value []byte, err error) {
+	file, ok := xattrFile(node)
+	if !ok || !strings.HasPrefix(name, XattrPrefix) {
+		return nil, ErrNoXattr
+	}
+	metadata, err := file.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	v, found := metadata[name[len(XattrPrefix):]]
+	if !found {
+		return nil, ErrNoXattr
+	}
+	return []byte(v), nil
+}
+
+// SetXattr sets the extended attribute name of node to value
+//
+// Only the attributes starting with XattrPrefix can be set and only
+// on files on remotes which can write metadata.
+func SetXattr(node vfs.Node, name string, value []byte) error {
+	file, ok := xattrFile(node)
+	if !ok || !strings.HasPrefix(name, XattrPrefix) || len(name) == len(XattrPrefix) {
+		return ErrXattrNotSupported
+	}
+	err := file.SetMetadata(fs.Metadata{name[len(XattrPrefix):]: string(value)})
+	if err == vfs.ENOSYS {
+		return ErrXattrNotSupported
+	}
+	return err
+}
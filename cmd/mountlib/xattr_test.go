@@ -0,0 +1,54 @@
+package mountlib_test
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/cmd/mountlib"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXattr(t *testing.T) {
+	ctx := context.Background()
+	localDir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(localDir, "file.txt"), []byte("hello"), 0666))
+	f, err := fs.NewFs(ctx, localDir)
+	require.NoError(t, err)
+	if !f.Features().ReadMetadata || !f.Features().WriteMetadata {
+		t.Skip("metadata not supported")
+	}
+	VFS := vfs.New(f, nil)
+	defer VFS.Shutdown()
+
+	file, err := VFS.Stat("file.txt")
+	require.NoError(t, err)
+	root, err := VFS.Root()
+	require.NoError(t, err)
+
+	names, err := mountlib.ListXattrs(file)
+	require.NoError(t, err)
+	assert.Contains(t, names, mountlib.XattrPrefix+"mode")
+
+	names, err = mountlib.ListXattrs(root)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	_, err = mountlib.GetXattr(file, "user.potato")
+	assert.Equal(t, mountlib.ErrNoXattr, err)
+	_, err = mountlib.GetXattr(file, mountlib.XattrPrefix+"potato")
+	assert.Equal(t, mountlib.ErrNoXattr, err)
+
+	require.NoError(t, mountlib.SetXattr(file, mountlib.XattrPrefix+"mode", []byte("100600")))
+	value, err := mountlib.GetXattr(file, mountlib.XattrPrefix+"mode")
+	require.NoError(t, err)
+	assert.Equal(t, "100600", string(value))
+
+	assert.Equal(t, mountlib.ErrXattrNotSupported, mountlib.SetXattr(file, "security.capability", nil))
+	assert.Equal(t, mountlib.ErrXattrNotSupported, mountlib.SetXattr(file, mountlib.XattrPrefix, nil))
+	assert.Equal(t, mountlib.ErrXattrNotSupported, mountlib.SetXattr(root, mountlib.XattrPrefix+"mode", nil))
+}
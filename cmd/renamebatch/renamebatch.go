@@ -0,0 +1,234 @@
+// Package renamebatch provides the rename-batch command.
+package renamebatch
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/walk"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "rename-batch remote:path rules.txt",
+	Short: `Rename the files in remote:path using regular expression rules.`,
+	Long: `
+Renames the files in remote:path according to the rules in a local
+file, using server-side moves where the remote supports them.
+
+Each line of the rules file has a [regular
+expression](https://golang.org/pkg/regexp/syntax/) and its
+replacement, separated by white space. The regular expression is
+matched against the path of each file relative to remote:path and the
+first rule that matches renames the file to its replacement, in which
+` + "`$1`" + ` or ` + "`${1}`" + ` is the text matched by the first
+group and so on. Blank lines and lines starting with ` + "`#`" + `
+are ignored. Use ` + "`\\s`" + ` to match a space.
+
+For example this rules file
+
+    # Photos from the camera
+    ^IMG_(\d{4})(\d{2})\d{2}_(.*)\.jpg$  photos/$1/$2/$3.jpg
+    \.jpeg$                               .jpg
+
+moves ` + "`IMG_20230401_120000.jpg`" + ` to
+` + "`photos/2023/04/120000.jpg`" + ` and renames the ` + "`.jpeg`" + `
+files anywhere to ` + "`.jpg`" + `.
+
+Nothing is renamed if two files would be renamed to the same name, or
+a file would be renamed to the name of another file, so the rules can
+be corrected first. A file isn't renamed if a file with its new name
+appeared since the listing.
+
+Use ` + "`--dry-run`" + ` to preview the renames. Files are renamed
+in parallel according to ` + "`--transfers`" + ` and the renames can
+be restricted to some files with the [filters](/filtering/).
+
+Directories emptied by the renames are left behind - use ` + "`rclone rmdirs`" + `
+to remove them.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(2, 2, command, args)
+		fsrc := cmd.NewFsSrc(args[:1])
+		cmd.Run(true, true, command, func() error {
+			rules, err := readRules(args[1])
+			if err != nil {
+				return err
+			}
+			return renameBatch(context.Background(), fsrc, rules)
+		})
+	},
+}
+
+// rule renames the files matching re to replacement
+type rule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// readRules reads the rename rules from the file at path
+func readRules(path string) (rules []rule, err error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	scanner := bufio.NewScanner(in)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expecting a regular expression and a replacement", path, lineNumber)
+		}
+		re, err := regexp.Compile(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		rules = append(rules, rule{re: re, replacement: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%s: no rules found", path)
+	}
+	return rules, nil
+}
+
+// newName returns the name remote should be renamed to by the first
+// rule which matches it, or remote if none do.
+func newName(rules []rule, remote string) string {
+	for _, rule := range rules {
+		if rule.re.MatchString(remote) {
+			return rule.re.ReplaceAllString(remote, rule.replacement)
+		}
+	}
+	return remote
+}
+
+// rename is a file to rename to remote
+type rename struct {
+	src    fs.Object
+	remote string
+}
+
+// plan lists f and works out the renames the rules make, checking
+// they don't conflict.
+func plan(ctx context.Context, f fs.Fs, rules []rule) (renames []rename, err error) {
+	ci := fs.GetConfig(ctx)
+	existing := map[string]struct{}{}
+	err = walk.ListR(ctx, f, "", true, ci.MaxDepth, walk.ListObjects, func(entries fs.DirEntries) error {
+		entries.ForObject(func(o fs.Object) {
+			existing[o.Remote()] = struct{}{}
+			if remote := newName(rules, o.Remote()); remote != o.Remote() {
+				renames = append(renames, rename{src: o, remote: remote})
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(renames, func(i, j int) bool {
+		return renames[i].src.Remote() < renames[j].src.Remote()
+	})
+	conflicts := 0
+	targets := make(map[string]string, len(renames))
+	for _, r := range renames {
+		if r.remote == "" || strings.HasSuffix(r.remote, "/") {
+			fs.Errorf(r.src, "Can't rename to %q: not a file name", r.remote)
+			conflicts++
+		} else if other, found := targets[r.remote]; found {
+			fs.Errorf(r.src, "Can't rename to %q: %q is being renamed to it too", r.remote, other)
+			conflicts++
+		} else if _, found := existing[r.remote]; found {
+			fs.Errorf(r.src, "Can't rename to %q: it already exists", r.remote)
+			conflicts++
+		}
+		targets[r.remote] = r.src.Remote()
+	}
+	if conflicts > 0 {
+		return nil, fmt.Errorf("%d conflicting renames found - nothing renamed", conflicts)
+	}
+	return renames, nil
+}
+
+// renameBatch renames the files in f according to rules
+func renameBatch(ctx context.Context, f fs.Fs, rules []rule) error {
+	ci := fs.GetConfig(ctx)
+	renames, err := plan(ctx, f, rules)
+	if err != nil {
+		return err
+	}
+	fs.Infof(f, "Renaming %d files", len(renames))
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		errs    int
+		lastErr error
+		in      = make(chan rename, ci.Transfers)
+	)
+	for i := 0; i < ci.Transfers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range in {
+				err := renameFile(ctx, f, r)
+				if err != nil {
+					fs.Errorf(r.src, "Failed to rename to %q: %v", r.remote, err)
+					err = fs.CountError(err)
+					mu.Lock()
+					errs++
+					lastErr = err
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, r := range renames {
+		in <- r
+	}
+	close(in)
+	wg.Wait()
+	if errs > 0 {
+		return fmt.Errorf("failed to rename %d files: last error: %w", errs, lastErr)
+	}
+	return nil
+}
+
+// renameFile does the rename r in f
+func renameFile(ctx context.Context, f fs.Fs, r rename) error {
+	if fs.GetConfig(ctx).DryRun {
+		fs.Logf(r.src, "Not renaming to %q as --dry-run is set", r.remote)
+		return nil
+	}
+	// Check the destination hasn't appeared since the listing,
+	// allowing for changes of case on case insensitive remotes
+	dst, err := f.NewObject(ctx, r.remote)
+	if err == nil {
+		if !operations.SameObject(r.src, dst) {
+			return errors.New("destination already exists")
+		}
+	} else if err != fs.ErrorObjectNotFound {
+		return err
+	}
+	_, err = operations.Move(ctx, f, nil, r.remote, r.src)
+	return err
+}
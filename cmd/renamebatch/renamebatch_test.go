@@ -0,0 +1,84 @@
+package renamebatch
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t1 = fstest.Time("2017-02-03T04:05:06.499999999Z")
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+// writeRules writes the rules file and reads it back
+func writeRules(t *testing.T, rules string) ([]rule, error) {
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte(rules), 0666))
+	return readRules(path)
+}
+
+func TestReadRules(t *testing.T) {
+	rules, err := writeRules(t, `
+# comment
+^a(\d+)\.txt$  b$1.txt
+
+\.jpeg$ .jpg
+`)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "b12.txt", newName(rules, "a12.txt"))
+	assert.Equal(t, "dir/photo.jpg", newName(rules, "dir/photo.jpeg"))
+	assert.Equal(t, "a.txt", newName(rules, "a.txt"))
+
+	_, err = writeRules(t, "# nothing\n")
+	assert.Error(t, err)
+	_, err = writeRules(t, "a b c\n")
+	assert.Error(t, err)
+	_, err = writeRules(t, "a(  b\n")
+	assert.Error(t, err)
+}
+
+func TestRenameBatch(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	file1 := r.WriteObject(ctx, "IMG_1.jpeg", "one", t1)
+	file2 := r.WriteObject(ctx, "dir/IMG_2.jpeg", "two", t1)
+	file3 := r.WriteObject(ctx, "other.txt", "three", t1)
+
+	rules, err := writeRules(t, `^(.*/)?IMG_(\d+)\.jpeg$ photos/$2.jpg`)
+	require.NoError(t, err)
+
+	// Dry run doesn't rename
+	dryCtx, ci := fs.AddConfig(ctx)
+	ci.DryRun = true
+	require.NoError(t, renameBatch(dryCtx, r.Fremote, rules))
+	fstest.CheckItems(t, r.Fremote, file1, file2, file3)
+
+	require.NoError(t, renameBatch(ctx, r.Fremote, rules))
+	file1.Path = "photos/1.jpg"
+	file2.Path = "photos/2.jpg"
+	fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2, file3}, []string{"dir", "photos"}, fs.GetModifyWindow(ctx, r.Fremote))
+
+	// Renaming to the same name or an existing file fails
+	for _, rules := range []string{
+		`\d\.jpg$ 0.jpg`,
+		`^photos/1\.jpg$ other.txt`,
+	} {
+		rules, err := writeRules(t, rules)
+		require.NoError(t, err)
+		err = renameBatch(ctx, r.Fremote, rules)
+		assert.Error(t, err)
+		fstest.CheckListingWithPrecision(t, r.Fremote, []fstest.Item{file1, file2, file3}, []string{"dir", "photos"}, fs.GetModifyWindow(ctx, r.Fremote))
+	}
+}
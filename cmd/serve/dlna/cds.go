@@ -90,6 +90,18 @@ func (cds *contentDirectoryService) cdsObjectToUpnpavObject(cdsObject object, fi
 		Size: uint64(fileInfo.Size()),
 	})
 
+	// Offer a transcoded version too for players which can't play it
+	if cds.transcodes.enabled(cdsObject.Path) {
+		item.Res = append(item.Res, upnpav.Resource{
+			URL: (&url.URL{
+				Scheme: "http",
+				Host:   host,
+				Path:   path.Join(transcodePath, cdsObject.Path),
+			}).String(),
+			ProtocolInfo: fmt.Sprintf("http-get:*:%s:%s", cds.opt.TranscodeMimeType, transcodeContentFeatures),
+		})
+	}
+
 	for _, resource := range resources {
 		subtitleURL := (&url.URL{
 			Scheme: "http",
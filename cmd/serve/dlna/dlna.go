@@ -41,9 +41,10 @@ supported. Service discovery uses UDP multicast packets (SSDP) and
 will thus only work on LANs.
 
 Rclone will list all files present in the remote, without filtering
-based on media formats or file extensions. Additionally, there is no
-media transcoding support. This means that some players might show
-files that they are not able to play back correctly.
+based on media formats or file extensions. Files are served as they
+are unless transcoding is set up with ` + "`--transcode-ext`" + `, so
+some players might show files that they are not able to play back
+correctly.
 
 ` + dlnaflags.Help + vfs.Help,
 	Run: func(command *cobra.Command, args []string) {
@@ -88,8 +89,10 @@ type server struct {
 	// Time interval between SSPD announces
 	AnnounceInterval time.Duration
 
-	f   fs.Fs
-	vfs *vfs.VFS
+	f          fs.Fs
+	vfs        *vfs.VFS
+	opt        *dlnaflags.Options
+	transcodes *transcodes
 }
 
 func newServer(f fs.Fs, opt *dlnaflags.Options) *server {
@@ -108,7 +111,9 @@ func newServer(f fs.Fs, opt *dlnaflags.Options) *server {
 
 		f:   f,
 		vfs: vfs.New(f, &vfsflags.Opt),
+		opt: opt,
 	}
+	s.transcodes = newTranscodes(s)
 
 	s.services = map[string]UPnPService{
 		"ContentDirectory": &contentDirectoryService{
@@ -126,6 +131,8 @@ func newServer(f fs.Fs, opt *dlnaflags.Options) *server {
 	r := http.NewServeMux()
 	r.Handle(resPath, http.StripPrefix(resPath,
 		http.HandlerFunc(s.resourceHandler)))
+	r.Handle(transcodePath, http.StripPrefix(transcodePath,
+		http.HandlerFunc(s.transcodeHandler)))
 	if opt.LogTrace {
 		r.Handle(rootDescPath, traceLogging(http.HandlerFunc(s.rootDescHandler)))
 		r.Handle(serviceControlURL, traceLogging(http.HandlerFunc(s.serviceControlHandler)))
@@ -293,6 +300,7 @@ func (s *server) Close() {
 		fs.Errorf(s.f, "Error closing HTTP server: %v", err)
 		return
 	}
+	s.transcodes.close()
 	close(s.waitChan)
 }
 
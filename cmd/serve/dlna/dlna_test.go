@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -203,3 +204,78 @@ func TestContentDirectoryBrowseDirectChildren(t *testing.T) {
 	require.Contains(t, string(body), "/r/subdir/video.mp4")
 	require.Contains(t, string(body), "/r/subdir/video.srt")
 }
+
+func TestParseNPT(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"12.5", 12.5, false},
+		{"1:02:03.5", 3723.5, false},
+		{"01:00", 60, false},
+		{"potato", 0, true},
+	} {
+		got, err := parseNPT(test.in)
+		assert.Equal(t, test.wantErr, err != nil, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+// Check the transcoded files are served
+func TestTranscode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	f, err := fs.NewFs(context.Background(), "testdata/files")
+	require.NoError(t, err)
+	opt := dlnaflags.DefaultOpt
+	opt.ListenAddr = testBindAddress
+	opt.TranscodeExts = fs.CommaSepList{"MP4"}
+	opt.TranscodeCommand = fs.SpaceSepList{"sh", "-c", "echo transcoded {start} {url}"}
+	s := newServer(f, &opt)
+	require.NoError(t, s.Serve())
+	defer s.transcodes.close()
+	base := "http://" + s.HTTPConn.Addr().String()
+
+	assert.True(t, s.transcodes.enabled("video.mp4"))
+	assert.False(t, s.transcodes.enabled("video.srt"))
+
+	get := func(path string, header http.Header) (*http.Response, string) {
+		req, err := http.NewRequest("GET", base+path, nil)
+		require.NoError(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(body)
+	}
+
+	want := "transcoded 0 " + s.transcodes.sourceURL("video.mp4") + "\n"
+	assert.Contains(t, want, "://127.0.0.1:")
+	assert.Contains(t, want, resPath+"video.mp4")
+
+	resp, body := get(transcodePath+"video.mp4", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "video/mpeg", resp.Header.Get("Content-Type"))
+	assert.Equal(t, want, body)
+
+	// Ranges of the finished transcode
+	resp, body = get(transcodePath+"video.mp4", http.Header{"Range": {"bytes=2-5"}})
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, want[2:6], body)
+
+	// Seeking by time
+	resp, body = get(transcodePath+"video.mp4", http.Header{"Timeseekrange.dlna.org": {"npt=1:30-"}})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "transcoded 90 "+s.transcodes.sourceURL("video.mp4")+"\n", body)
+
+	// Files which aren't transcoded
+	resp, _ = get(transcodePath+"video.srt", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -1,6 +1,7 @@
 package dlnaflags
 
 import (
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/flags"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/spf13/pflag"
@@ -19,13 +20,41 @@ default "rclone (hostname)".
 
 Use ` + "`--log-trace` in conjunction with `-vv`" + ` to enable additional debug
 logging of all UPNP traffic.
+
+### Transcoding
+
+Use ` + "`--transcode-ext`" + ` to offer a transcoded version of the
+media files with these extensions, e.g. ` + "`--transcode-ext mkv,avi,flac`" + `,
+alongside the original for players which can't play them.
+
+The files are transcoded by running ` + "`--transcode-command`" + `
+which should write the transcoded file to its standard output. In
+its arguments ` + "`{url}`" + ` is replaced with the URL of the
+original file on this server, which supports range requests, and
+` + "`{start}`" + ` with the time in seconds to start from. The
+default uses [ffmpeg](https://ffmpeg.org/) to make an MPEG transport
+stream, which most TVs can play:
+
+    ffmpeg -hide_banner -loglevel error -ss {start} -i {url} -c:v libx264 -preset veryfast -c:a aac -f mpegts pipe:1
+
+Use ` + "`--transcode-mime-type`" + ` to set the MIME type of the
+output if the command is changed to make a different format.
+
+The output is kept in temporary files for the last few files
+transcoded so players can seek in the part transcoded so far with
+range requests, or anywhere once it has finished. Players which seek
+by time (with the DLNA ` + "`TimeSeekRange.dlna.org`" + ` header) start
+a new transcode from that time.
 `
 
 // Options is the type for DLNA serving options.
 type Options struct {
-	ListenAddr   string
-	FriendlyName string
-	LogTrace     bool
+	ListenAddr        string
+	FriendlyName      string
+	LogTrace          bool
+	TranscodeExts     fs.CommaSepList
+	TranscodeCommand  fs.SpaceSepList
+	TranscodeMimeType string
 }
 
 // DefaultOpt contains the defaults options for DLNA serving.
@@ -33,6 +62,13 @@ var DefaultOpt = Options{
 	ListenAddr:   ":7879",
 	FriendlyName: "",
 	LogTrace:     false,
+	TranscodeCommand: fs.SpaceSepList{
+		"ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-ss", "{start}", "-i", "{url}",
+		"-c:v", "libx264", "-preset", "veryfast", "-c:a", "aac",
+		"-f", "mpegts", "pipe:1",
+	},
+	TranscodeMimeType: "video/mpeg",
 }
 
 // Opt contains the options for DLNA serving.
@@ -45,6 +81,9 @@ func addFlagsPrefix(flagSet *pflag.FlagSet, prefix string, Opt *Options) {
 	flags.StringVarP(flagSet, &Opt.ListenAddr, prefix+"addr", "", Opt.ListenAddr, "The ip:port or :port to bind the DLNA http server to")
 	flags.StringVarP(flagSet, &Opt.FriendlyName, prefix+"name", "", Opt.FriendlyName, "Name of DLNA server")
 	flags.BoolVarP(flagSet, &Opt.LogTrace, prefix+"log-trace", "", Opt.LogTrace, "Enable trace logging of SOAP traffic")
+	flags.FVarP(flagSet, &Opt.TranscodeExts, prefix+"transcode-ext", "", "Offer files with these extensions transcoded too, e.g. mkv,avi")
+	flags.FVarP(flagSet, &Opt.TranscodeCommand, prefix+"transcode-command", "", "Command to transcode {url} from {start} seconds to its output")
+	flags.StringVarP(flagSet, &Opt.TranscodeMimeType, prefix+"transcode-mime-type", "", Opt.TranscodeMimeType, "MIME type of the output of the transcode command")
 }
 
 // AddFlags add the command line flags for DLNA serving.
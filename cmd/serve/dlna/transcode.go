@@ -0,0 +1,368 @@
+package dlna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	dms_dlna "github.com/anacrolix/dms/dlna"
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	transcodePath = "/t/"
+	maxTranscodes = 4 // number of transcoded files to keep
+)
+
+// transcodes runs the transcode command and caches its output
+type transcodes struct {
+	mu      sync.Mutex
+	s       *server
+	running map[string]*transcode // transcodes by path
+	order   []string              // paths in the order they were transcoded
+}
+
+// transcode is the output of the transcode command for a file which
+// is written to a temporary file as it is produced
+type transcode struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	file   *os.File
+	size   int64 // bytes written to file so far
+	done   bool  // set when the transcode command has finished
+	err    error // error from the transcode command
+	cancel context.CancelFunc
+}
+
+// newTranscodes makes a cache of transcoded files for s
+func newTranscodes(s *server) *transcodes {
+	return &transcodes{
+		s:       s,
+		running: map[string]*transcode{},
+	}
+}
+
+// enabled returns whether the file at remotePath should be offered
+// transcoded
+func (ts *transcodes) enabled(remotePath string) bool {
+	opt := ts.s.opt
+	if len(opt.TranscodeCommand) == 0 {
+		return false
+	}
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(remotePath)), ".")
+	for _, transcodeExt := range opt.TranscodeExts {
+		if ext != "" && strings.ToLower(strings.TrimPrefix(transcodeExt, ".")) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceURL returns the URL the transcode command reads remotePath from
+func (ts *transcodes) sourceURL(remotePath string) string {
+	addr := ts.s.HTTPConn.Addr().(*net.TCPAddr)
+	ip := addr.IP
+	if ip == nil || ip.IsUnspecified() {
+		ip = net.IPv4(127, 0, 0, 1)
+	}
+	return (&url.URL{
+		Scheme: "http",
+		Host:   (&net.TCPAddr{IP: ip, Port: addr.Port}).String(),
+		Path:   path.Join(resPath, remotePath),
+	}).String()
+}
+
+// command makes the transcode command for remotePath starting start
+// seconds in
+func (ts *transcodes) command(ctx context.Context, remotePath string, start float64) *exec.Cmd {
+	replacer := strings.NewReplacer(
+		"{url}", ts.sourceURL(remotePath),
+		"{start}", strconv.FormatFloat(start, 'f', -1, 64),
+	)
+	args := make([]string, len(ts.s.opt.TranscodeCommand))
+	for i, arg := range ts.s.opt.TranscodeCommand {
+		args[i] = replacer.Replace(arg)
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// get returns the transcode of remotePath, starting it if necessary
+func (ts *transcodes) get(remotePath string) (*transcode, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if t := ts.running[remotePath]; t != nil {
+		return t, nil
+	}
+	file, err := ioutil.TempFile("", "rclone-dlna-transcode-")
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &transcode{
+		file:   file,
+		cancel: cancel,
+	}
+	t.cond = sync.NewCond(&t.mu)
+	cmd := ts.command(ctx, remotePath, 0)
+	out, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		cancel()
+		t.remove()
+		return nil, fmt.Errorf("failed to start transcode command: %w", err)
+	}
+	fs.Infof(remotePath, "Transcoding with %q", cmd.Args)
+	go t.copy(out, cmd)
+
+	// Remove the oldest transcodes
+	ts.running[remotePath] = t
+	ts.order = append(ts.order, remotePath)
+	for len(ts.order) > maxTranscodes {
+		old := ts.order[0]
+		ts.order = ts.order[1:]
+		ts.running[old].remove()
+		delete(ts.running, old)
+	}
+	return t, nil
+}
+
+// close stops all the transcodes and removes their files
+func (ts *transcodes) close() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for remotePath, t := range ts.running {
+		t.remove()
+		delete(ts.running, remotePath)
+	}
+	ts.order = nil
+}
+
+// copy the output of cmd to the file
+func (t *transcode) copy(out io.Reader, cmd *exec.Cmd) {
+	buf := make([]byte, 64*1024)
+	var err error
+	for {
+		n, readErr := out.Read(buf)
+		if n > 0 {
+			t.mu.Lock()
+			_, err = t.file.WriteAt(buf[:n], t.size)
+			if err == nil {
+				t.size += int64(n)
+			}
+			t.mu.Unlock()
+			t.cond.Broadcast()
+		}
+		if readErr != nil || err != nil {
+			if readErr != io.EOF && err == nil {
+				err = readErr
+			}
+			break
+		}
+	}
+	// Drain the output so the command can finish
+	_, _ = io.Copy(ioutil.Discard, out)
+	if waitErr := cmd.Wait(); err == nil {
+		err = waitErr
+	}
+	t.mu.Lock()
+	t.done = true
+	t.err = err
+	t.mu.Unlock()
+	t.cond.Broadcast()
+	if err != nil {
+		fs.Errorf(nil, "Transcode command %q failed: %v", cmd.Args, err)
+	}
+}
+
+// remove stops the transcode and removes its file
+func (t *transcode) remove() {
+	if t.cancel != nil {
+		t.cancel()
+	}
+	_ = t.file.Close()
+	_ = os.Remove(t.file.Name())
+}
+
+// wait until more than off bytes have been written or the transcode
+// is done, returning the size written so far and whether it is done
+func (t *transcode) wait(off int64) (size int64, done bool, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.size <= off && !t.done {
+		t.cond.Wait()
+	}
+	return t.size, t.done, t.err
+}
+
+// ReadAt reads the transcoded output at off, waiting for it to be
+// written if necessary
+func (t *transcode) ReadAt(p []byte, off int64) (n int, err error) {
+	size, done, err := t.wait(off)
+	if off >= size {
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	if int64(len(p)) > size-off {
+		p = p[:size-off]
+	}
+	n, err = t.file.ReadAt(p, off)
+	if err == io.EOF && !done {
+		err = nil
+	}
+	return n, err
+}
+
+// contentFeatures of a transcoded file
+var transcodeContentFeatures = dms_dlna.ContentFeatures{
+	SupportTimeSeek: true,
+	SupportRange:    true,
+	Transcoded:      true,
+}.String()
+
+var (
+	rangeRegexp    = regexp.MustCompile(`^bytes=(\d+)-(\d*)$`)
+	timeSeekRegexp = regexp.MustCompile(`^npt=([\d.:]+)-`)
+)
+
+// parseNPT parses a DLNA normal play time of seconds or hh:mm:ss.sss
+func parseNPT(npt string) (seconds float64, err error) {
+	for _, part := range strings.Split(npt, ":") {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad time %q: %w", npt, err)
+		}
+		seconds = seconds*60 + value
+	}
+	return seconds, nil
+}
+
+// Serves transcoded media files.
+func (s *server) transcodeHandler(w http.ResponseWriter, r *http.Request) {
+	remotePath := r.URL.Path
+	if !s.transcodes.enabled(remotePath) {
+		http.NotFound(w, r)
+		return
+	}
+	node, err := s.vfs.Stat(remotePath)
+	if err != nil || node.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", s.opt.TranscodeMimeType)
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	if r.Header.Get("getContentFeatures.dlna.org") != "" {
+		w.Header().Set("contentFeatures.dlna.org", transcodeContentFeatures)
+	}
+
+	// Seeking by time runs a new transcode from that time
+	if match := timeSeekRegexp.FindStringSubmatch(r.Header.Get("TimeSeekRange.dlna.org")); match != nil {
+		start, err := parseNPT(match[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if start > 0 {
+			s.serveTranscodeFrom(w, r, remotePath, start)
+			return
+		}
+	}
+
+	t, err := s.transcodes.get(remotePath)
+	if err != nil {
+		serveError(node, w, "Could not transcode resource", err)
+		return
+	}
+
+	// Once the transcode is complete, serve it like any other file
+	size, done, err := t.wait(0)
+	if done {
+		if err != nil && size == 0 {
+			serveError(node, w, "Transcode failed", err)
+			return
+		}
+		http.ServeContent(w, r, remotePath, node.ModTime(), io.NewSectionReader(t, 0, size))
+		return
+	}
+
+	// Otherwise serve the ranges which have been transcoded so far,
+	// or stream it all if no range was asked for
+	var start, end int64 = 0, -1
+	if match := rangeRegexp.FindStringSubmatch(r.Header.Get("Range")); match != nil {
+		start, _ = strconv.ParseInt(match[1], 10, 64)
+		size, done, _ = t.wait(start)
+		if start >= size {
+			w.Header().Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+			http.Error(w, "requested range not transcoded", http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		end = size - 1
+		if match[2] != "" {
+			if last, _ := strconv.ParseInt(match[2], 10, 64); last < end {
+				end = last
+			}
+		}
+		total := "*"
+		if done {
+			total = strconv.FormatInt(size, 10)
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%s", start, end, total))
+		w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	var in io.Reader = &transcodeReader{t: t, off: start}
+	if end >= 0 {
+		in = io.LimitReader(in, end-start+1)
+	}
+	if _, err := io.Copy(w, in); err != nil && !errors.Is(err, io.EOF) {
+		fs.Debugf(remotePath, "Error writing transcode: %v", err)
+	}
+}
+
+// serveTranscodeFrom streams a new transcode of remotePath starting
+// start seconds in
+func (s *server) serveTranscodeFrom(w http.ResponseWriter, r *http.Request, remotePath string, start float64) {
+	cmd := s.transcodes.command(r.Context(), remotePath, start)
+	cmd.Stdout = w
+	w.Header().Set("TimeSeekRange.dlna.org", fmt.Sprintf("npt=%s-", strconv.FormatFloat(start, 'f', -1, 64)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	if err := cmd.Run(); err != nil && r.Context().Err() == nil {
+		fs.Errorf(remotePath, "Transcode command %q failed: %v", cmd.Args, err)
+	}
+}
+
+// transcodeReader reads a transcode from off until it is done
+type transcodeReader struct {
+	t   *transcode
+	off int64
+}
+
+// Read reads the transcode waiting for it to be written
+func (tr *transcodeReader) Read(p []byte) (n int, err error) {
+	n, err = tr.t.ReadAt(p, tr.off)
+	tr.off += int64(n)
+	return n, err
+}
@@ -228,12 +228,16 @@ func getVFSOption(vfsOpt *vfscommon.Options, opt rc.Params, key string) (ok bool
 	// options prefixed with "vfs-"
 	case "vfs-cache-mode":
 		err = getFVarP(&vfsOpt.CacheMode, opt, key)
+	case "vfs-cache-mode-dirs":
+		err = getFVarP(&vfsOpt.CacheModeDirs, opt, key)
 	case "vfs-cache-poll-interval":
 		vfsOpt.CachePollInterval, err = opt.GetDuration(key)
 	case "vfs-cache-max-age":
 		vfsOpt.CacheMaxAge, err = opt.GetDuration(key)
 	case "vfs-cache-max-size":
 		err = getFVarP(&vfsOpt.CacheMaxSize, opt, key)
+	case "vfs-cache-max-size-remote":
+		err = getFVarP(&vfsOpt.CacheMaxSizeRemote, opt, key)
 	case "vfs-read-chunk-size":
 		err = getFVarP(&vfsOpt.ChunkSize, opt, key)
 	case "vfs-read-chunk-size-limit":
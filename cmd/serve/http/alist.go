@@ -0,0 +1,252 @@
+// Alist compatible JSON API
+
+package http
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rclone/rclone/fs"
+	httplib "github.com/rclone/rclone/lib/http"
+	"github.com/rclone/rclone/lib/rest"
+	"github.com/rclone/rclone/vfs"
+)
+
+// Types of object in the Alist API
+const (
+	alistTypeUnknown = 0
+	alistTypeFolder  = 1
+	alistTypeVideo   = 2
+	alistTypeAudio   = 3
+	alistTypeText    = 4
+	alistTypeImage   = 5
+)
+
+// alistResponse is the envelope of every Alist API response
+type alistResponse struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+}
+
+// alistListRequest is the body of /api/fs/list
+type alistListRequest struct {
+	Path     string `json:"path"`
+	Password string `json:"password"`
+	Page     int    `json:"page"`
+	PerPage  int    `json:"per_page"`
+	Refresh  bool   `json:"refresh"`
+}
+
+// alistGetRequest is the body of /api/fs/get
+type alistGetRequest struct {
+	Path     string `json:"path"`
+	Password string `json:"password"`
+}
+
+// alistObject describes a file or directory
+type alistObject struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	IsDir    bool      `json:"is_dir"`
+	Modified time.Time `json:"modified"`
+	Created  time.Time `json:"created"`
+	Sign     string    `json:"sign"`
+	Thumb    string    `json:"thumb"`
+	Type     int       `json:"type"`
+}
+
+// alistListResponse is the data returned by /api/fs/list
+type alistListResponse struct {
+	Content  []alistObject `json:"content"`
+	Total    int           `json:"total"`
+	Readme   string        `json:"readme"`
+	Header   string        `json:"header"`
+	Write    bool          `json:"write"`
+	Provider string        `json:"provider"`
+}
+
+// alistGetResponse is the data returned by /api/fs/get
+type alistGetResponse struct {
+	alistObject
+	RawURL   string        `json:"raw_url"`
+	Readme   string        `json:"readme"`
+	Header   string        `json:"header"`
+	Provider string        `json:"provider"`
+	Related  []alistObject `json:"related"`
+}
+
+// bindAlist adds the Alist API to the router
+func (s *server) bindAlist(router chi.Router) {
+	router.Post("/api/fs/list", s.alistList)
+	router.Post("/api/fs/get", s.alistGet)
+}
+
+// alistReply writes an Alist API response
+func alistReply(w http.ResponseWriter, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	err := json.NewEncoder(w).Encode(alistResponse{
+		Code:    code,
+		Message: message,
+		Data:    data,
+	})
+	if err != nil {
+		fs.Errorf(nil, "alist: failed to write response: %v", err)
+	}
+}
+
+// alistError writes an Alist API error response
+//
+// Like Alist the HTTP status is always 200 with the error in the body.
+func alistError(w http.ResponseWriter, remote string, err error) {
+	code, message := http.StatusInternalServerError, err.Error()
+	if err == vfs.ENOENT {
+		message = "object not found"
+	}
+	fs.Debugf(remote, "alist: %s", message)
+	alistReply(w, code, message, nil)
+}
+
+// alistType returns the Alist type of node
+func alistType(node vfs.Node) int {
+	if node.IsDir() {
+		return alistTypeFolder
+	}
+	mimeType := mime.TypeByExtension(path.Ext(node.Name()))
+	switch {
+	case strings.HasPrefix(mimeType, "video/"):
+		return alistTypeVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return alistTypeAudio
+	case strings.HasPrefix(mimeType, "text/"):
+		return alistTypeText
+	case strings.HasPrefix(mimeType, "image/"):
+		return alistTypeImage
+	}
+	return alistTypeUnknown
+}
+
+// alistNewObject describes node for the Alist API
+func alistNewObject(node vfs.Node) alistObject {
+	o := alistObject{
+		Name:  node.Name(),
+		IsDir: node.IsDir(),
+		Type:  alistType(node),
+	}
+	if !o.IsDir {
+		o.Size = node.Size()
+	}
+	if !node.VFS().Opt.NoModTime {
+		o.Modified = node.ModTime().UTC()
+		o.Created = o.Modified
+	}
+	return o
+}
+
+// alistRemote turns an Alist path into a VFS path
+func alistRemote(p string) string {
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+// alistList serves /api/fs/list
+func (s *server) alistList(w http.ResponseWriter, r *http.Request) {
+	var req alistListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		alistReply(w, http.StatusBadRequest, "failed to parse request: "+err.Error(), nil)
+		return
+	}
+	remote := alistRemote(req.Path)
+	node, err := s.vfs.Stat(remote)
+	if err != nil {
+		alistError(w, remote, err)
+		return
+	}
+	if !node.IsDir() {
+		alistReply(w, http.StatusInternalServerError, "not a folder", nil)
+		return
+	}
+	dir := node.(*vfs.Dir)
+	if req.Refresh {
+		dir.ForgetAll()
+	}
+	nodes, err := dir.ReadDirAll()
+	if err != nil {
+		alistError(w, remote, err)
+		return
+	}
+	total := len(nodes)
+
+	// Return the page asked for, or everything if per_page isn't set
+	if req.PerPage > 0 {
+		page := req.Page
+		if page < 1 {
+			page = 1
+		}
+		start := (page - 1) * req.PerPage
+		if start > len(nodes) {
+			start = len(nodes)
+		}
+		end := start + req.PerPage
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+		nodes = nodes[start:end]
+	}
+
+	content := make([]alistObject, 0, len(nodes))
+	for _, node := range nodes {
+		content = append(content, alistNewObject(node))
+	}
+	alistReply(w, http.StatusOK, "success", alistListResponse{
+		Content:  content,
+		Total:    total,
+		Write:    false,
+		Provider: "rclone",
+	})
+}
+
+// alistRawURL returns the URL to download remote from this server
+func alistRawURL(r *http.Request, remote string) string {
+	base, err := url.Parse(httplib.URL())
+	if err != nil {
+		base = &url.URL{Path: "/"}
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + strings.TrimSuffix(base.Path, "/") + "/" + rest.URLPathEscape(remote)
+}
+
+// alistGet serves /api/fs/get
+func (s *server) alistGet(w http.ResponseWriter, r *http.Request) {
+	var req alistGetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		alistReply(w, http.StatusBadRequest, "failed to parse request: "+err.Error(), nil)
+		return
+	}
+	remote := alistRemote(req.Path)
+	node, err := s.vfs.Stat(remote)
+	if err != nil {
+		alistError(w, remote, err)
+		return
+	}
+	resp := alistGetResponse{
+		alistObject: alistNewObject(node),
+		Provider:    "rclone",
+		Related:     []alistObject{},
+	}
+	if remote == "" {
+		resp.Name = "root"
+	}
+	if node.IsFile() {
+		resp.RawURL = alistRawURL(r, remote)
+	}
+	alistReply(w, http.StatusOK, "success", resp)
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/rclone/rclone/cmd/serve/http/data"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/config/flags"
 	httplib "github.com/rclone/rclone/lib/http"
 	"github.com/rclone/rclone/lib/http/auth"
 	"github.com/rclone/rclone/lib/http/serve"
@@ -28,6 +29,7 @@ import (
 // Options required for http server
 type Options struct {
 	data.Options
+	Alist bool // serve the Alist compatible JSON API
 }
 
 // DefaultOpt is the default values used for Options
@@ -38,6 +40,7 @@ var Opt = DefaultOpt
 
 func init() {
 	data.AddFlags(Command.Flags(), "", &Opt.Options)
+	flags.BoolVarP(Command.Flags(), &Opt.Alist, "alist", "", Opt.Alist, "Serve an Alist compatible JSON API on /api/fs")
 	httplib.AddFlags(Command.Flags())
 	auth.AddFlags(Command.Flags())
 	vfsflags.AddFlags(Command.Flags())
@@ -58,12 +61,21 @@ The server will log errors.  Use ` + "`-v`" + ` to see access logs.
 
 ` + "`--bwlimit`" + ` will be respected for file transfers.  Use ` + "`--stats`" + ` to
 control the stats printing.
+
+### Alist API
+
+If ` + "`--alist`" + ` is set then the server also answers the
+` + "`/api/fs/list`" + ` and ` + "`/api/fs/get`" + ` endpoints of the
+[Alist](https://alist.nn.ci/) API, so it can be used by Alist clients
+as if it were an Alist server. Only listing and reading are supported
+and the password in requests is ignored, so use the authentication
+flags below to protect the server.
 ` + httplib.Help + data.Help + auth.Help + vfs.Help,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(1, 1, command, args)
 		f := cmd.NewFsSrc(args)
 		cmd.Run(false, true, command, func() error {
-			s := newServer(f, Opt.Template)
+			s := newServer(f, &Opt)
 			router, err := httplib.Router()
 			if err != nil {
 				return err
@@ -79,17 +91,19 @@ control the stats printing.
 type server struct {
 	f            fs.Fs
 	vfs          *vfs.VFS
+	opt          Options
 	HTMLTemplate *template.Template // HTML template for web interface
 }
 
-func newServer(f fs.Fs, templatePath string) *server {
-	htmlTemplate, templateErr := data.GetTemplate(templatePath)
+func newServer(f fs.Fs, opt *Options) *server {
+	htmlTemplate, templateErr := data.GetTemplate(opt.Template)
 	if templateErr != nil {
 		log.Fatalf(templateErr.Error())
 	}
 	s := &server{
 		f:            f,
 		vfs:          vfs.New(f, &vfsflags.Opt),
+		opt:          *opt,
 		HTMLTemplate: htmlTemplate,
 	}
 	return s
@@ -103,6 +117,9 @@ func (s *server) Bind(router chi.Router) {
 		middleware.SetHeader("Accept-Ranges", "bytes"),
 		middleware.SetHeader("Server", "rclone/"+fs.Version),
 	)
+	if s.opt.Alist {
+		s.bindAlist(router)
+	}
 	router.Get("/*", s.handler)
 	router.Head("/*", s.handler)
 }
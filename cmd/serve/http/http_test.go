@@ -1,7 +1,9 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"flag"
 	"io/ioutil"
 	"net/http"
@@ -10,6 +12,7 @@ import (
 	"time"
 
 	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/cmd/serve/http/data"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config/configfile"
 	"github.com/rclone/rclone/fs/filter"
@@ -32,7 +35,10 @@ const (
 func startServer(t *testing.T, f fs.Fs) {
 	opt := httplib.DefaultOpt
 	opt.ListenAddr = testBindAddress
-	httpServer = newServer(f, testTemplate)
+	httpServer = newServer(f, &Options{
+		Options: data.Options{Template: testTemplate},
+		Alist:   true,
+	})
 	router, err := httplib.Router()
 	if err != nil {
 		t.Fatal(err.Error())
@@ -229,6 +235,96 @@ func TestGET(t *testing.T) {
 	}
 }
 
+// alistPost posts in to the Alist API endpoint and decodes the reply
+// into out
+func alistPost(t *testing.T, endpoint string, in interface{}, out interface{}) {
+	body, err := json.Marshal(in)
+	require.NoError(t, err)
+	resp, err := http.Post(testURL+endpoint, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+}
+
+func TestAlist(t *testing.T) {
+	type listResponse struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Content []alistObject `json:"content"`
+			Total   int           `json:"total"`
+		} `json:"data"`
+	}
+	type getResponse struct {
+		Code    int               `json:"code"`
+		Message string            `json:"message"`
+		Data    *alistGetResponse `json:"data"`
+	}
+
+	// List the root
+	var list listResponse
+	alistPost(t, "api/fs/list", alistListRequest{Path: "/"}, &list)
+	assert.Equal(t, http.StatusOK, list.Code)
+	assert.Equal(t, "success", list.Message)
+	assert.Equal(t, 3, list.Data.Total)
+	var names []string
+	for _, item := range list.Data.Content {
+		names = append(names, item.Name)
+		if item.Name == "three" {
+			assert.True(t, item.IsDir)
+			assert.Equal(t, alistTypeFolder, item.Type)
+		} else {
+			assert.False(t, item.IsDir)
+			assert.Equal(t, alistTypeText, item.Type)
+		}
+	}
+	assert.Equal(t, []string{"one%.txt", "three", "two.txt"}, names)
+
+	// List a page of a directory
+	list = listResponse{}
+	alistPost(t, "api/fs/list", alistListRequest{Path: "/three", Page: 2, PerPage: 1}, &list)
+	assert.Equal(t, http.StatusOK, list.Code)
+	assert.Equal(t, 2, list.Data.Total)
+	require.Len(t, list.Data.Content, 1)
+	assert.Equal(t, "b.txt", list.Data.Content[0].Name)
+
+	// List something which doesn't exist
+	list = listResponse{}
+	alistPost(t, "api/fs/list", alistListRequest{Path: "/hidden"}, &list)
+	assert.Equal(t, http.StatusInternalServerError, list.Code)
+	assert.Equal(t, "object not found", list.Message)
+
+	// Get a file
+	var get getResponse
+	alistPost(t, "api/fs/get", alistGetRequest{Path: "/" + datedObject}, &get)
+	assert.Equal(t, http.StatusOK, get.Code)
+	require.NotNil(t, get.Data)
+	assert.Equal(t, datedObject, get.Data.Name)
+	assert.Equal(t, int64(11), get.Data.Size)
+	assert.True(t, expectedTime.Equal(get.Data.Modified))
+	assert.Equal(t, testURL+datedObject, get.Data.RawURL)
+
+	// Get a file which needs escaping and read it with the raw URL
+	get = getResponse{}
+	alistPost(t, "api/fs/get", alistGetRequest{Path: "one%.txt"}, &get)
+	assert.Equal(t, http.StatusOK, get.Code)
+	require.NotNil(t, get.Data)
+	assert.Equal(t, testURL+"one%25.txt", get.Data.RawURL)
+	resp, err := http.Get(get.Data.RawURL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	// Get something which doesn't exist
+	get = getResponse{}
+	alistPost(t, "api/fs/get", alistGetRequest{Path: "/notfound"}, &get)
+	assert.Equal(t, http.StatusInternalServerError, get.Code)
+	assert.Nil(t, get.Data)
+}
+
 func TestFinalise(t *testing.T) {
 	_ = httplib.Shutdown()
 }
@@ -0,0 +1,91 @@
+//go:build go1.17
+// +build go1.17
+
+package restic
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/rc"
+)
+
+// maintenance is the window in which the append-only restrictions
+// are lifted so the repository can be pruned
+var maintenance struct {
+	mu    sync.Mutex
+	until time.Time // the window is open until this time
+}
+
+// openMaintenance opens the maintenance window for d, or closes it
+// if d is 0, returning when it closes.
+func openMaintenance(d time.Duration) time.Time {
+	maintenance.mu.Lock()
+	defer maintenance.mu.Unlock()
+	if d <= 0 {
+		maintenance.until = time.Time{}
+		fs.Logf(nil, "serve restic: maintenance window closed")
+	} else {
+		maintenance.until = time.Now().Add(d)
+		fs.Logf(nil, "serve restic: maintenance window open until %v", maintenance.until.Format(time.RFC3339))
+	}
+	return maintenance.until
+}
+
+// inMaintenance returns true if the maintenance window is open
+func inMaintenance() bool {
+	maintenance.mu.Lock()
+	defer maintenance.mu.Unlock()
+	return time.Now().Before(maintenance.until)
+}
+
+// appendOnlyEnforced returns true if append-only mode is on and the
+// maintenance window isn't open
+func appendOnlyEnforced() bool {
+	return appendOnly && !inMaintenance()
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:         "serve/restic/maintenance",
+		AuthRequired: true,
+		Fn:           rcMaintenance,
+		Title:        "Open or close the maintenance window of serve restic --append-only",
+		Help: `This opens a window in which "rclone serve restic --append-only"
+allows deleting and overwriting repository data, so that "restic
+prune" and "restic forget" can be run. The window closes by itself
+once the duration has passed.
+
+This takes the following parameters:
+
+- duration - how long to open the window for, eg "1h" - 0 closes it (default 1h)
+
+It returns
+
+- until - the time the window closes, or the zero time if closed
+
+Example:
+
+    rclone rc serve/restic/maintenance duration=30m
+`,
+	})
+}
+
+// rcMaintenance opens or closes the maintenance window
+func rcMaintenance(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	d, err := in.GetDuration("duration")
+	if rc.IsErrParamNotFound(err) {
+		d, err = time.Hour, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if d < 0 {
+		return nil, rc.NewErrParamInvalid(errors.New("duration must not be negative"))
+	}
+	until := openMaintenance(d)
+	return rc.Params{"until": until}, nil
+}
@@ -127,6 +127,24 @@ these **must** end with /.  Eg
     $ export RESTIC_REPOSITORY=rest:http://localhost:8080/user2repo/
     # backup user2 stuff
 
+#### Append only mode ####
+
+Use the ` + "`--append-only`" + ` flag to protect the backups from a
+compromised client. In this mode new data can be added to the
+repository, but existing data can't be deleted or overwritten,
+except for the lock files.
+
+As this stops ` + "`restic forget`" + ` and ` + "`restic prune`" + `
+working, a maintenance window can be opened in which deletes and
+overwrites are allowed. This is done with the remote control
+(` + "`--rc`" + ` must be given to the server) from a trusted machine
+rather than by the client, for example
+
+    rclone rc serve/restic/maintenance duration=1h
+
+The window closes by itself once the duration has passed, or can be
+closed early with ` + "`duration=0`" + `.
+
 #### Private repositories ####
 
 The` + "`--private-repos`" + ` flag can be used to limit users to repositories starting
@@ -288,7 +306,7 @@ func (s *Server) serveObject(w http.ResponseWriter, r *http.Request, remote stri
 
 // postObject posts an object to the repository
 func (s *Server) postObject(w http.ResponseWriter, r *http.Request, remote string) {
-	if appendOnly {
+	if appendOnlyEnforced() {
 		// make sure the file does not exist yet
 		_, err := s.newObject(r.Context(), remote)
 		if err == nil {
@@ -314,7 +332,7 @@ func (s *Server) postObject(w http.ResponseWriter, r *http.Request, remote strin
 
 // delete the remote
 func (s *Server) deleteObject(w http.ResponseWriter, r *http.Request, remote string) {
-	if appendOnly {
+	if appendOnlyEnforced() {
 		parts := strings.Split(r.URL.Path, "/")
 
 		// if path doesn't end in "/locks/:name", disallow the operation
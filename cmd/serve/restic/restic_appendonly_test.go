@@ -4,16 +4,20 @@
 package restic
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/cmd/serve/httplib/httpflags"
 	"github.com/rclone/rclone/fs/config/configfile"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -138,3 +142,60 @@ func TestResticHandler(t *testing.T) {
 		})
 	}
 }
+
+// TestResticHandlerMaintenance checks the append-only restrictions are
+// lifted while the maintenance window is open.
+func TestResticHandlerMaintenance(t *testing.T) {
+	configfile.Install()
+	prev := appendOnly
+	appendOnly = true
+	defer func() {
+		appendOnly = prev
+		openMaintenance(0)
+	}()
+
+	f := cmd.NewFsSrc([]string{t.TempDir()})
+	srv := NewServer(f, &httpflags.Opt)
+	checkRequest(t, srv.ServeHTTP,
+		newRequest(t, "POST", "/?create=true", nil),
+		[]wantFunc{wantCode(http.StatusOK)})
+	checkRequest(t, srv.ServeHTTP,
+		newRequest(t, "POST", "/config", strings.NewReader("config")),
+		[]wantFunc{wantCode(http.StatusOK)})
+
+	// open the window
+	call := rc.Calls.Get("serve/restic/maintenance")
+	require.NotNil(t, call)
+	out, err := call.Fn(context.Background(), rc.Params{"duration": "1h"})
+	require.NoError(t, err)
+	assert.True(t, inMaintenance())
+	assert.False(t, out["until"].(time.Time).IsZero())
+
+	checkRequest(t, srv.ServeHTTP,
+		newRequest(t, "POST", "/config", strings.NewReader("new config")),
+		[]wantFunc{wantCode(http.StatusOK)})
+	checkRequest(t, srv.ServeHTTP,
+		newRequest(t, "GET", "/config", nil),
+		[]wantFunc{wantCode(http.StatusOK), wantBody("new config")})
+
+	// close the window
+	_, err = call.Fn(context.Background(), rc.Params{"duration": "0"})
+	require.NoError(t, err)
+	assert.False(t, inMaintenance())
+
+	checkRequest(t, srv.ServeHTTP,
+		newRequest(t, "DELETE", "/config", nil),
+		[]wantFunc{wantCode(http.StatusForbidden)})
+
+	// delete in the window
+	openMaintenance(time.Hour)
+	checkRequest(t, srv.ServeHTTP,
+		newRequest(t, "DELETE", "/config", nil),
+		[]wantFunc{wantCode(http.StatusOK)})
+	checkRequest(t, srv.ServeHTTP,
+		newRequest(t, "GET", "/config", nil),
+		[]wantFunc{wantCode(http.StatusNotFound)})
+
+	_, err = call.Fn(context.Background(), rc.Params{"duration": "-1s"})
+	assert.Error(t, err)
+}
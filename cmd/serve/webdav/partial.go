@@ -0,0 +1,225 @@
+// Partial updates of files
+
+package webdav
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+)
+
+// appendOffset is the offset used to mean the end of the file
+const appendOffset = -1
+
+// errPartialNotSupported is returned if the VFS can't write to the
+// middle of a file
+var errPartialNotSupported = errors.New("partial updates need --vfs-cache-mode writes or full")
+
+// parseContentRange parses the Content-Range header of a PUT of the
+// form "bytes start-end/total" returning the start and end offsets.
+//
+// total may be "*" if it isn't known.
+func parseContentRange(s string) (start, end int64, err error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "bytes ") {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", s)
+	}
+	s = strings.TrimSpace(s[len("bytes "):])
+	slash := strings.IndexByte(s, '/')
+	if slash < 0 {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", s)
+	}
+	if total := s[slash+1:]; total != "*" {
+		if _, err := strconv.ParseInt(total, 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid Content-Range total %q", total)
+		}
+	}
+	dash := strings.IndexByte(s[:slash], '-')
+	if dash < 0 {
+		return 0, 0, fmt.Errorf("invalid Content-Range %q", s)
+	}
+	start, err = strconv.ParseInt(s[:dash], 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("invalid Content-Range start %q", s[:dash])
+	}
+	end, err = strconv.ParseInt(s[dash+1:slash], 10, 64)
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid Content-Range end %q", s[dash+1:slash])
+	}
+	return start, end, nil
+}
+
+// parseUpdateRange parses the X-Update-Range header of a SabreDAV
+// style PATCH which is one of "append", "bytes=start-end",
+// "bytes=start-" or "bytes=-n" where the last means n bytes from the
+// end of the file.
+//
+// It returns the offset to write at which is appendOffset for
+// "append", or the negative of n+1 for "bytes=-n".
+func parseUpdateRange(s string) (offset int64, err error) {
+	s = strings.TrimSpace(s)
+	if s == "append" {
+		return appendOffset, nil
+	}
+	if !strings.HasPrefix(s, "bytes=") {
+		return 0, fmt.Errorf("invalid X-Update-Range %q", s)
+	}
+	s = s[len("bytes="):]
+	dash := strings.IndexByte(s, '-')
+	if dash < 0 {
+		return 0, fmt.Errorf("invalid X-Update-Range %q", s)
+	}
+	if dash == 0 {
+		n, err := strconv.ParseInt(s[1:], 10, 64)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid X-Update-Range %q", s)
+		}
+		return -n - 1, nil
+	}
+	offset, err = strconv.ParseInt(s[:dash], 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid X-Update-Range %q", s)
+	}
+	if end := s[dash+1:]; end != "" {
+		if e, err := strconv.ParseInt(end, 10, 64); err != nil || e < offset {
+			return 0, fmt.Errorf("invalid X-Update-Range %q", s)
+		}
+	}
+	return offset, nil
+}
+
+// isPartialUpdate returns true if r updates part of a file
+func isPartialUpdate(r *http.Request) bool {
+	switch r.Method {
+	case "PUT":
+		return r.Header.Get("Content-Range") != ""
+	case "PATCH":
+		return r.Header.Get("X-Update-Range") != ""
+	}
+	return false
+}
+
+// servePartialUpdate writes the body of r into remote at the offset
+// given by the Content-Range of a PUT or the X-Update-Range of a
+// PATCH.
+//
+// This needs a VFS cache mode which supports writing to the middle of
+// a file.
+func (w *WebDAV) servePartialUpdate(rw http.ResponseWriter, r *http.Request, remote string) {
+	var (
+		offset int64
+		length int64 = -1
+		err    error
+	)
+	if r.Method == "PUT" {
+		var end int64
+		offset, end, err = parseContentRange(r.Header.Get("Content-Range"))
+		length = end - offset + 1
+		if err == nil && r.ContentLength >= 0 && r.ContentLength != length {
+			err = fmt.Errorf("Content-Length %d doesn't match Content-Range length %d", r.ContentLength, length)
+		}
+	} else {
+		offset, err = parseUpdateRange(r.Header.Get("X-Update-Range"))
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	VFS, err := w.getVFS(r.Context())
+	if err != nil {
+		http.Error(rw, "Root directory not found", http.StatusNotFound)
+		fs.Errorf(nil, "Failed to update file: %v", err)
+		return
+	}
+	status, err := partialUpdate(VFS, remote, offset, length, r.Body)
+	if err != nil {
+		fs.Errorf(remote, "Partial %s failed: %v", r.Method, err)
+		http.Error(rw, err.Error(), status)
+		return
+	}
+	rw.WriteHeader(status)
+}
+
+// partialUpdate writes length bytes from in to remote at offset, or
+// all of in if length is -1, returning the HTTP status to reply with.
+func partialUpdate(VFS *vfs.VFS, remote string, offset, length int64, in io.Reader) (status int, err error) {
+	status = http.StatusNoContent
+	if _, err := VFS.Stat(remote); err == vfs.ENOENT {
+		status = http.StatusCreated
+	}
+	handle, err := VFS.OpenFile(remote, os.O_RDWR|os.O_CREATE, 0666)
+	switch err {
+	case nil:
+	case vfs.ENOENT:
+		return http.StatusConflict, err
+	case vfs.EROFS:
+		return http.StatusForbidden, err
+	case vfs.EPERM:
+		return http.StatusNotImplemented, errPartialNotSupported
+	default:
+		return http.StatusInternalServerError, err
+	}
+	defer func() {
+		closeErr := handle.Close()
+		if err == nil && closeErr != nil {
+			status, err = http.StatusInternalServerError, closeErr
+		}
+	}()
+
+	// Work out where to write
+	if offset < 0 {
+		fi, err := handle.Stat()
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		if offset == appendOffset {
+			offset = fi.Size()
+		} else {
+			offset = fi.Size() + offset + 1
+			if offset < 0 {
+				offset = 0
+			}
+		}
+	}
+	if _, ok := handle.(*vfs.RWFileHandle); !ok && offset != 0 {
+		if status == http.StatusCreated {
+			// don't leave an empty file behind
+			_ = handle.Close()
+			_ = VFS.Remove(remote)
+		}
+		return http.StatusNotImplemented, errPartialNotSupported
+	}
+
+	// Copy the data into the file
+	if length >= 0 {
+		in = io.LimitReader(in, length)
+	}
+	buf := make([]byte, 64*1024)
+	var written int64
+	for {
+		n, readErr := in.Read(buf)
+		if n > 0 {
+			if _, err := handle.WriteAt(buf[:n], offset+written); err != nil {
+				return http.StatusInternalServerError, err
+			}
+			written += int64(n)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return http.StatusBadRequest, readErr
+		}
+	}
+	if length >= 0 && written != length {
+		return http.StatusBadRequest, fmt.Errorf("short body: got %d bytes, expected %d", written, length)
+	}
+	return status, nil
+}
@@ -0,0 +1,155 @@
+package webdav
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContentRange(t *testing.T) {
+	for _, test := range []struct {
+		in         string
+		start, end int64
+		wantErr    bool
+	}{
+		{in: "bytes 0-9/10", start: 0, end: 9},
+		{in: "bytes 5-9/*", start: 5, end: 9},
+		{in: " bytes 100-199/1000 ", start: 100, end: 199},
+		{in: "bytes 9-5/10", wantErr: true},
+		{in: "bytes 0-9", wantErr: true},
+		{in: "bytes 0-9/potato", wantErr: true},
+		{in: "bytes -5/10", wantErr: true},
+		{in: "bits 0-9/10", wantErr: true},
+	} {
+		start, end, err := parseContentRange(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		assert.NoError(t, err, test.in)
+		assert.Equal(t, test.start, start, test.in)
+		assert.Equal(t, test.end, end, test.in)
+	}
+}
+
+func TestParseUpdateRange(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		offset  int64
+		wantErr bool
+	}{
+		{in: "append", offset: appendOffset},
+		{in: "bytes=5-9", offset: 5},
+		{in: "bytes=5-", offset: 5},
+		{in: "bytes=-3", offset: -4},
+		{in: "bytes=9-5", wantErr: true},
+		{in: "bytes=-0", wantErr: true},
+		{in: "bytes=potato", wantErr: true},
+		{in: "prepend", wantErr: true},
+	} {
+		offset, err := parseUpdateRange(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		assert.NoError(t, err, test.in)
+		assert.Equal(t, test.offset, offset, test.in)
+	}
+}
+
+// newPartialTestVFS makes a VFS on a temporary directory containing
+// file.txt
+func newPartialTestVFS(t *testing.T, cacheMode vfscommon.CacheMode) *vfs.VFS {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(dir+"/file.txt", []byte("0123456789"), 0666))
+	f, err := fs.NewFs(context.Background(), dir)
+	require.NoError(t, err)
+	opt := vfscommon.DefaultOpt
+	opt.CacheMode = cacheMode
+	VFS := vfs.New(f, &opt)
+	t.Cleanup(func() {
+		VFS.WaitForWriters(10 * time.Second)
+		assert.NoError(t, VFS.CleanUp())
+		VFS.Shutdown()
+	})
+	return VFS
+}
+
+// partialTestRequest runs a partial update with the headers given and
+// returns the status code
+func partialTestRequest(t *testing.T, VFS *vfs.VFS, method, remote, header, value, body string) int {
+	w := &WebDAV{_vfs: VFS}
+	r := httptest.NewRequest(method, "/"+remote, strings.NewReader(body))
+	r.Header.Set(header, value)
+	require.True(t, isPartialUpdate(r))
+	rw := httptest.NewRecorder()
+	w.servePartialUpdate(rw, r, remote)
+	return rw.Code
+}
+
+// partialTestRead reads remote from VFS
+func partialTestRead(t *testing.T, VFS *vfs.VFS, remote string) string {
+	handle, err := VFS.OpenFile(remote, os.O_RDONLY, 0)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(handle)
+	require.NoError(t, err)
+	require.NoError(t, handle.Close())
+	return string(data)
+}
+
+func TestPartialUpdate(t *testing.T) {
+	VFS := newPartialTestVFS(t, vfscommon.CacheModeWrites)
+
+	code := partialTestRequest(t, VFS, "PUT", "file.txt", "Content-Range", "bytes 2-4/10", "abc")
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Equal(t, "01abc56789", partialTestRead(t, VFS, "file.txt"))
+
+	code = partialTestRequest(t, VFS, "PATCH", "file.txt", "X-Update-Range", "append", "XYZ")
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Equal(t, "01abc56789XYZ", partialTestRead(t, VFS, "file.txt"))
+
+	code = partialTestRequest(t, VFS, "PATCH", "file.txt", "X-Update-Range", "bytes=-2", "yz")
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Equal(t, "01abc56789Xyz", partialTestRead(t, VFS, "file.txt"))
+
+	code = partialTestRequest(t, VFS, "PUT", "new.txt", "Content-Range", "bytes 3-5/6", "def")
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "\x00\x00\x00def", partialTestRead(t, VFS, "new.txt"))
+
+	// Body doesn't match the range
+	code = partialTestRequest(t, VFS, "PUT", "file.txt", "Content-Range", "bytes 0-9/10", "short")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// Directory doesn't exist
+	code = partialTestRequest(t, VFS, "PUT", "dir/file.txt", "Content-Range", "bytes 0-2/3", "abc")
+	assert.Equal(t, http.StatusConflict, code)
+}
+
+func TestPartialUpdateCacheModeOff(t *testing.T) {
+	VFS := newPartialTestVFS(t, vfscommon.CacheModeOff)
+
+	code := partialTestRequest(t, VFS, "PUT", "file.txt", "Content-Range", "bytes 2-4/10", "abc")
+	assert.Equal(t, http.StatusNotImplemented, code)
+	assert.Equal(t, "0123456789", partialTestRead(t, VFS, "file.txt"))
+
+	code = partialTestRequest(t, VFS, "PUT", "new.txt", "Content-Range", "bytes 3-5/6", "def")
+	assert.Equal(t, http.StatusNotImplemented, code)
+	_, err := VFS.Stat("new.txt")
+	assert.Equal(t, vfs.ENOENT, err)
+
+	code = partialTestRequest(t, VFS, "PUT", "new2.txt", "Content-Range", "bytes 0-2/6", "abc")
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "abc", partialTestRead(t, VFS, "new2.txt"))
+}
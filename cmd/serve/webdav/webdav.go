@@ -60,6 +60,19 @@ supported hash on the backend or you can use a named hash such as
 "MD5" or "SHA-1". Use the [hashsum](/commands/rclone_hashsum/) command
 to see the full list.
 
+### Partial updates
+
+Clients can update part of a file without uploading all of it again,
+either with a PUT with a ` + "`Content-Range: bytes start-end/total`" + `
+header, or with a PATCH with an ` + "`X-Update-Range`" + ` header as used by
+SabreDAV, which may be ` + "`append`" + `, ` + "`bytes=start-end`" + `,
+` + "`bytes=start-`" + ` or ` + "`bytes=-n`" + ` for the last n bytes of the file.
+
+This needs ` + "`--vfs-cache-mode writes`" + ` or ` + "`full`" + ` as the file is
+modified in the VFS cache and uploaded when it is complete. Without it
+only writes to the start of a new file are allowed and anything else
+will get a 501 Not Implemented error.
+
 ` + httplib.Help + vfs.Help + proxy.Help,
 	RunE: func(command *cobra.Command, args []string) error {
 		var f fs.Fs
@@ -180,6 +193,10 @@ func (w *WebDAV) handler(rw http.ResponseWriter, r *http.Request) {
 		w.serveDir(rw, r, remote)
 		return
 	}
+	if !isDir && isPartialUpdate(r) {
+		w.servePartialUpdate(rw, r, remote)
+		return
+	}
 	w.webdavhandler.ServeHTTP(rw, r)
 }
 
@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"errors"
 
 	"github.com/rclone/rclone/cmd"
 	"github.com/rclone/rclone/fs/config/flags"
@@ -12,12 +13,16 @@ import (
 
 var (
 	createEmptySrcDirs = false
+	threeWaySnapshot   = ""
+	threeWayConflict   = sync.ConflictModeNone
 )
 
 func init() {
 	cmd.Root.AddCommand(commandDefinition)
 	cmdFlags := commandDefinition.Flags()
 	flags.BoolVarP(cmdFlags, &createEmptySrcDirs, "create-empty-src-dirs", "", createEmptySrcDirs, "Create empty source dirs on destination after sync")
+	flags.StringVarP(cmdFlags, &threeWaySnapshot, "three-way-snapshot", "", threeWaySnapshot, "Sync in both directions keeping a snapshot of the files in this local file between runs")
+	flags.FVarP(cmdFlags, &threeWayConflict, "three-way-conflict", "", "How to resolve files changed on both sides with --three-way-snapshot none|newer|path1|path2")
 }
 
 var commandDefinition = &cobra.Command{
@@ -58,11 +63,49 @@ destination that is inside the source directory.
 
 **Note**: Use the ` + "`rclone dedupe`" + ` command to deal with "Duplicate object/directory found in source/destination - ignoring" errors.
 See [this forum post](https://forum.rclone.org/t/sync-not-clearing-duplicates/14372) for more info.
+
+### Three way sync
+
+If ` + "`--three-way-snapshot FILE`" + ` is set then the sync is done in both
+directions. At the end of each run rclone writes a snapshot of the
+size, modification time and, where they can be read cheaply, the hash
+of every file on both sides to FILE. On the next run each side is
+compared with the snapshot to find out which side each file has been
+changed on since the last run. This means that deletions are synced
+too, and a file changed on one side is never overwritten by an old
+copy from the other side.
+
+- Changed or new on one side only - copied to the other side.
+- Deleted on one side only - deleted from the other side.
+- Deleted on one side and changed on the other - the change is kept.
+- Changed on both sides - a conflict unless the files are now identical.
+
+Conflicts are resolved with ` + "`--three-way-conflict`" + `. The default
+` + "`none`" + ` leaves both files alone and reports an error so the
+conflict is found again on the next run. ` + "`newer`" + ` keeps the
+file with the most recent modification time and ` + "`path1`" + ` or
+` + "`path2`" + ` keep the file from source:path or dest:path.
+
+On the first run there is no snapshot so files which are only on one
+side are copied to the other and files which differ are conflicts.
+Use a different snapshot file for each pair of paths. Directories are
+not synced and the snapshot isn't updated with ` + "`--dry-run`" + `.
+
+    rclone sync --three-way-snapshot ~/.cache/rclone/docs.snap ~/docs remote:docs
 `,
 	Run: func(command *cobra.Command, args []string) {
 		cmd.CheckArgs(2, 2, command, args)
 		fsrc, srcFileName, fdst := cmd.NewFsSrcFileDst(args)
 		cmd.Run(true, true, command, func() error {
+			if threeWaySnapshot != "" {
+				if srcFileName != "" {
+					return errors.New("can't use --three-way-snapshot with a file")
+				}
+				return sync.ThreeWay(context.Background(), fsrc, fdst, sync.ThreeWayOpt{
+					Snapshot: threeWaySnapshot,
+					Conflict: threeWayConflict,
+				})
+			}
 			if srcFileName == "" {
 				return sync.Sync(context.Background(), fdst, fsrc, createEmptySrcDirs)
 			}
@@ -0,0 +1,545 @@
+// Package verify provides the verify command.
+package verify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	compare       = false
+	download      = false
+	update        = false
+	key           = ""
+	hashType      = hash.None
+	reverifyAfter = fs.Duration(0)
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.BoolVarP(cmdFlags, &compare, "compare", "", compare, "Compare two manifests rather than a remote with a manifest")
+	flags.BoolVarP(cmdFlags, &download, "download", "", download, "Download the objects and hash them locally rather than asking the remote for the hashes")
+	flags.BoolVarP(cmdFlags, &update, "update", "", update, "Accept the new, changed and missing objects into the manifest")
+	flags.StringVarP(cmdFlags, &key, "key", "", key, "Sign the manifest with this key and check its signature")
+	flags.FVarP(cmdFlags, &hashType, "hash", "", "Hash to use for a new manifest MD5|SHA-1|... (default the first the remote supports)")
+	flags.FVarP(cmdFlags, &reverifyAfter, "reverify-after", "", "Only hash unchanged objects verified longer ago than this")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "verify remote:path manifest",
+	Short: `Verify a remote against a signed manifest of its hashes.`,
+	Long: `
+Checks the objects in the path against a manifest file holding the
+size, modification time and hash of each one, writing a line for each
+difference found.
+
+If the manifest doesn't exist then it is built from the objects in the
+path. Run the command again to check nothing has changed since.
+
+The differences have one JSON object per line, like this
+
+    {"Path":"dir/file.txt","Status":"corrupt","Size":1234,"Hash":"...","Expected":"..."}
+
+Where Status is one of
+
+- ` + "`new`" + ` - the object isn't in the manifest
+- ` + "`missing`" + ` - the object in the manifest doesn't exist
+- ` + "`changed`" + ` - the object has a different size, modification time and hash
+- ` + "`corrupt`" + ` - the object has the same size and modification time but a different hash
+
+Objects are only reported as ` + "`corrupt`" + ` if the remote or the
+data has changed behind rclone's back, which is what to look out for
+in archival storage.
+
+The manifest is rewritten after each run to record when each object
+was verified. Normally the new, changed and missing objects are left
+as they were in the manifest so they are reported again next time. Use
+` + "`--update`" + ` to accept them into the manifest once the
+differences have been checked. Corrupt objects are never accepted.
+
+Hashes are read from the remote unless ` + "`--download`" + ` is
+given, in which case the objects are read and hashed locally. This is
+needed for remotes which don't store hashes. The hash used for a new
+manifest can be set with ` + "`--hash`" + `.
+
+Hashing every object on each run can be expensive for big archives,
+so use ` + "`--reverify-after`" + ` to verify them incrementally.
+Objects whose size and modification time are unchanged and which were
+verified more recently than this aren't hashed again. For example
+running this daily
+
+    rclone verify --download --reverify-after 30d remote:archive manifest.json
+
+checks for new and changed objects every day but only reads each
+unchanged object once a month.
+
+Use ` + "`--key`" + ` to sign the manifest with an HMAC-SHA256 of its
+contents so it can't be changed without the key. A signed manifest
+can't be used without the key. Note that the key can also be given
+in the ` + "`RCLONE_KEY`" + ` environment variable.
+
+With ` + "`--compare`" + ` the two arguments are manifests, for example
+one built from a local directory and one from its copy on a remote,
+and the differences between them are written without reading any
+objects. Differences are reported as if the first was the manifest
+and the second was the remote.
+
+The command exits with an error if there were any differences.
+`,
+	RunE: func(command *cobra.Command, args []string) error {
+		cmd.CheckArgs(2, 2, command, args)
+		if compare {
+			cmd.Run(false, false, command, func() error {
+				return runCompare(args[0], args[1], os.Stdout)
+			})
+			return nil
+		}
+		fsrc := cmd.NewFsSrc(args[:1])
+		cmd.Run(false, true, command, func() error {
+			return run(context.Background(), fsrc, args[1], os.Stdout)
+		})
+		return nil
+	},
+}
+
+// manifestVersion is the version of the manifest format
+const manifestVersion = 1
+
+// Entry is the record of an object in the manifest
+type Entry struct {
+	Path     string
+	Size     int64
+	ModTime  time.Time
+	Hash     string
+	Verified time.Time // when the hash was last checked
+}
+
+// Manifest is the record of the objects in a remote
+type Manifest struct {
+	Version   int
+	HashType  string
+	Updated   time.Time
+	Entries   []Entry // sorted by Path
+	Signature string  `json:",omitempty"` // HMAC-SHA256 of the manifest without the signature
+}
+
+// sum returns the HMAC-SHA256 of the manifest without its signature
+func (m *Manifest) sum(key string) (string, error) {
+	unsigned := *m
+	unsigned.Signature = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	_, _ = mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// check checks the signature of the manifest
+func (m *Manifest) check(key string) error {
+	switch {
+	case m.Signature == "" && key == "":
+		return nil
+	case m.Signature == "":
+		return errors.New("manifest isn't signed")
+	case key == "":
+		return errors.New("manifest is signed - need --key to use it")
+	}
+	sum, err := m.sum(key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(sum), []byte(m.Signature)) {
+		return errors.New("manifest signature doesn't match - it has been changed or the key is wrong")
+	}
+	return nil
+}
+
+// readManifest reads the manifest at path checking its signature
+// with key. It returns nil if the manifest doesn't exist.
+func readManifest(path, key string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	m := new(Manifest)
+	err = json.Unmarshal(data, m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %q: %w", path, err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("manifest %q has unknown version %d", path, m.Version)
+	}
+	err = m.check(key)
+	if err != nil {
+		return nil, fmt.Errorf("manifest %q: %w", path, err)
+	}
+	return m, nil
+}
+
+// writeManifest signs the manifest with key if set and writes it to
+// path, replacing it atomically.
+func writeManifest(path, key string, m *Manifest) error {
+	m.Version = manifestVersion
+	m.Signature = ""
+	if key != "" {
+		sum, err := m.sum(key)
+		if err != nil {
+			return err
+		}
+		m.Signature = sum
+	}
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Status values for Diff
+const (
+	StatusNew     = "new"
+	StatusMissing = "missing"
+	StatusChanged = "changed"
+	StatusCorrupt = "corrupt"
+)
+
+// Diff is a difference between the manifest and the remote
+type Diff struct {
+	Path     string
+	Status   string
+	Size     int64  `json:",omitempty"`
+	Hash     string `json:",omitempty"` // hash of the object
+	Expected string `json:",omitempty"` // hash in the manifest
+	Error    string `json:",omitempty"`
+}
+
+// writeDiffs writes the diffs sorted by path to out
+func writeDiffs(out io.Writer, diffs []Diff) error {
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	enc := json.NewEncoder(out)
+	for _, diff := range diffs {
+		if err := enc.Encode(diff); err != nil {
+			return fmt.Errorf("failed to write differences: %w", err)
+		}
+	}
+	return nil
+}
+
+// options for verify
+type options struct {
+	ht            hash.Type     // hash to use
+	download      bool          // hash the data rather than asking the remote
+	update        bool          // accept the differences into the manifest
+	reverifyAfter time.Duration // hash objects verified longer ago than this
+}
+
+// objectHash returns the hash of o
+func objectHash(ctx context.Context, o fs.Object, opt *options) (sum string, err error) {
+	if !opt.download {
+		tr := accounting.Stats(ctx).NewCheckingTransfer(o)
+		defer func() {
+			tr.Done(ctx, err)
+		}()
+		return o.Hash(ctx, opt.ht)
+	}
+	tr := accounting.Stats(ctx).NewTransfer(o)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
+	var options []fs.OpenOption
+	for _, option := range fs.GetConfig(ctx).DownloadHeaders {
+		options = append(options, option)
+	}
+	in0, err := operations.NewReOpen(ctx, o, fs.GetConfig(ctx).LowLevelRetries, options...)
+	if err != nil {
+		return "", fmt.Errorf("failed to open: %w", err)
+	}
+	in := tr.Account(ctx, in0).WithBuffer()
+	defer fs.CheckClose(in, &err)
+	hasher, err := hash.NewMultiHasherTypes(hash.NewHashSet(opt.ht))
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(hasher, in)
+	if err != nil {
+		return "", fmt.Errorf("failed to read: %w", err)
+	}
+	return hasher.SumString(opt.ht, false)
+}
+
+// verifyObject checks o against its entry in the manifest, if any,
+// returning the entry to record for it and the difference found if
+// there is one.
+func verifyObject(ctx context.Context, o fs.Object, old *Entry, opt *options, window time.Duration) (entry Entry, diff *Diff) {
+	now := time.Now()
+	entry = Entry{
+		Path:    o.Remote(),
+		Size:    o.Size(),
+		ModTime: o.ModTime(ctx),
+	}
+	unchanged := old != nil && old.Size == entry.Size && equalTime(old.ModTime, entry.ModTime, window)
+	if unchanged && opt.reverifyAfter > 0 && now.Sub(old.Verified) < opt.reverifyAfter {
+		fs.Debugf(o, "Not hashing as verified at %v", old.Verified)
+		return *old, nil
+	}
+	sum, err := objectHash(ctx, o, opt)
+	if err == nil && sum == "" {
+		err = fmt.Errorf("no %v hash available - try --download", opt.ht)
+	}
+	if err != nil {
+		err = fs.CountError(err)
+		fs.Errorf(o, "Failed to hash: %v", err)
+		if old != nil {
+			entry = *old
+		}
+		return entry, &Diff{Path: entry.Path, Status: StatusCorrupt, Size: entry.Size, Expected: entry.Hash, Error: err.Error()}
+	}
+	entry.Hash = sum
+	entry.Verified = now
+	switch {
+	case old == nil:
+		return entry, &Diff{Path: entry.Path, Status: StatusNew, Size: entry.Size, Hash: sum}
+	case sum == old.Hash:
+		if !unchanged {
+			// Only the modification time changed
+			return entry, nil
+		}
+		entry = *old
+		entry.Verified = now
+		return entry, nil
+	case unchanged:
+		return *old, &Diff{Path: entry.Path, Status: StatusCorrupt, Size: entry.Size, Hash: sum, Expected: old.Hash}
+	default:
+		return entry, &Diff{Path: entry.Path, Status: StatusChanged, Size: entry.Size, Hash: sum, Expected: old.Hash}
+	}
+}
+
+// equalTime returns whether a and b are equal within window
+func equalTime(a, b time.Time, window time.Duration) bool {
+	dt := a.Sub(b)
+	return dt >= -window && dt <= window
+}
+
+// verify checks the objects in f against the manifest m, which may be
+// nil, returning the new manifest and the differences found.
+func verify(ctx context.Context, f fs.Fs, m *Manifest, opt *options) (newManifest *Manifest, diffs []Diff, err error) {
+	ci := fs.GetConfig(ctx)
+	old := make(map[string]*Entry)
+	if m != nil {
+		for i := range m.Entries {
+			old[m.Entries[i].Path] = &m.Entries[i]
+		}
+	}
+	window := fs.GetModifyWindow(ctx, f)
+	newManifest = &Manifest{
+		HashType: opt.ht.String(),
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		seen     = make(map[string]struct{})
+		objects  = make(chan fs.Object, ci.Checkers)
+		checkers = ci.Checkers
+	)
+	if checkers < 1 {
+		checkers = 1
+	}
+	for i := 0; i < checkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for o := range objects {
+				entry, diff := verifyObject(ctx, o, old[o.Remote()], opt, window)
+				mu.Lock()
+				if diff != nil {
+					diffs = append(diffs, *diff)
+				}
+				switch {
+				case entry.Hash == "":
+					// failed to hash a new object
+				case diff == nil || diff.Status == StatusCorrupt:
+					newManifest.Entries = append(newManifest.Entries, entry)
+				case opt.update:
+					newManifest.Entries = append(newManifest.Entries, entry)
+				case diff.Status == StatusChanged:
+					newManifest.Entries = append(newManifest.Entries, *old[o.Remote()])
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	err = operations.ListFn(ctx, f, func(o fs.Object) {
+		// seen is only accessed here so needs no locking
+		seen[o.Remote()] = struct{}{}
+		objects <- o
+	})
+	close(objects)
+	wg.Wait()
+	if err != nil {
+		return nil, nil, err
+	}
+	for path, entry := range old {
+		if _, found := seen[path]; found {
+			continue
+		}
+		diffs = append(diffs, Diff{Path: path, Status: StatusMissing, Size: entry.Size, Expected: entry.Hash})
+		if !opt.update {
+			newManifest.Entries = append(newManifest.Entries, *entry)
+		}
+	}
+	sort.Slice(newManifest.Entries, func(i, j int) bool {
+		return newManifest.Entries[i].Path < newManifest.Entries[j].Path
+	})
+	return newManifest, diffs, nil
+}
+
+// run verifies f against the manifest at path writing the differences
+// to out
+func run(ctx context.Context, f fs.Fs, path string, out io.Writer) error {
+	m, err := readManifest(path, key)
+	if err != nil {
+		return err
+	}
+	opt := &options{
+		ht:            hashType,
+		download:      download,
+		update:        update,
+		reverifyAfter: time.Duration(reverifyAfter),
+	}
+	if m != nil {
+		var ht hash.Type
+		err = ht.Set(m.HashType)
+		if err != nil {
+			return fmt.Errorf("manifest %q: %w", path, err)
+		}
+		if opt.ht != hash.None && opt.ht != ht {
+			return fmt.Errorf("manifest uses %v hashes not %v", ht, opt.ht)
+		}
+		opt.ht = ht
+	}
+	if opt.ht == hash.None {
+		opt.ht = f.Hashes().GetOne()
+		if opt.ht == hash.None {
+			return errors.New("remote doesn't support any hashes - use --hash with --download")
+		}
+	}
+	if m == nil {
+		// building a new manifest
+		opt.update = true
+	}
+	if !opt.download && !f.Hashes().Contains(opt.ht) {
+		return fmt.Errorf("remote doesn't support %v hashes - use --download", opt.ht)
+	}
+	newManifest, diffs, err := verify(ctx, f, m, opt)
+	if err != nil {
+		return err
+	}
+	newManifest.Updated = time.Now()
+	err = writeManifest(path, key, newManifest)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		fs.Logf(f, "Created manifest %q of %d objects", path, len(newManifest.Entries))
+		return nil
+	}
+	err = writeDiffs(out, diffs)
+	if err != nil {
+		return err
+	}
+	fs.Logf(f, "%d objects verified, %d differences", len(newManifest.Entries), len(diffs))
+	if len(diffs) > 0 {
+		return fmt.Errorf("%d differences found", len(diffs))
+	}
+	return nil
+}
+
+// compareManifests returns the differences between the manifests
+// treating a as the manifest and b as the remote.
+func compareManifests(a, b *Manifest) (diffs []Diff) {
+	entries := make(map[string]*Entry, len(a.Entries))
+	for i := range a.Entries {
+		entries[a.Entries[i].Path] = &a.Entries[i]
+	}
+	for _, entry := range b.Entries {
+		old, found := entries[entry.Path]
+		delete(entries, entry.Path)
+		switch {
+		case !found:
+			diffs = append(diffs, Diff{Path: entry.Path, Status: StatusNew, Size: entry.Size, Hash: entry.Hash})
+		case old.Hash == entry.Hash && old.Size == entry.Size:
+		case old.Size == entry.Size && old.ModTime.Equal(entry.ModTime):
+			diffs = append(diffs, Diff{Path: entry.Path, Status: StatusCorrupt, Size: entry.Size, Hash: entry.Hash, Expected: old.Hash})
+		default:
+			diffs = append(diffs, Diff{Path: entry.Path, Status: StatusChanged, Size: entry.Size, Hash: entry.Hash, Expected: old.Hash})
+		}
+	}
+	for _, old := range entries {
+		diffs = append(diffs, Diff{Path: old.Path, Status: StatusMissing, Size: old.Size, Expected: old.Hash})
+	}
+	return diffs
+}
+
+// runCompare compares the manifests at pathA and pathB writing the
+// differences to out
+func runCompare(pathA, pathB string, out io.Writer) error {
+	var ms [2]*Manifest
+	for i, path := range []string{pathA, pathB} {
+		m, err := readManifest(path, key)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			return fmt.Errorf("manifest %q not found", path)
+		}
+		ms[i] = m
+	}
+	if ms[0].HashType != ms[1].HashType {
+		return fmt.Errorf("can't compare manifests using %v and %v hashes", ms[0].HashType, ms[1].HashType)
+	}
+	diffs := compareManifests(ms[0], ms[1])
+	err := writeDiffs(out, diffs)
+	if err != nil {
+		return err
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%d differences found", len(diffs))
+	}
+	return nil
+}
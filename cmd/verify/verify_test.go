@@ -0,0 +1,160 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t1 = fstest.Time("2017-02-03T04:05:06.499999999Z")
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+// writeFile writes a file in dir with content and modification time t1
+func writeFile(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0666))
+	require.NoError(t, os.Chtimes(path, t1, t1))
+}
+
+// statuses returns the status of each diff by path
+func statuses(diffs []Diff) map[string]string {
+	out := make(map[string]string, len(diffs))
+	for _, diff := range diffs {
+		out[diff.Path] = diff.Status
+	}
+	return out
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	f, err := fs.NewFs(ctx, dir)
+	require.NoError(t, err)
+	writeFile(t, dir, "a.txt", "hello")
+	writeFile(t, dir, "b.txt", "world")
+	writeFile(t, dir, "c.txt", "potato")
+	opt := &options{ht: hash.MD5, update: true}
+
+	// Build
+	m, diffs, err := verify(ctx, f, nil, opt)
+	require.NoError(t, err)
+	assert.Len(t, diffs, 3)
+	require.Len(t, m.Entries, 3)
+	assert.Equal(t, "a.txt", m.Entries[0].Path)
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", m.Entries[0].Hash)
+
+	// Nothing changed
+	opt.update = false
+	m, diffs, err = verify(ctx, f, m, opt)
+	require.NoError(t, err)
+	assert.Len(t, diffs, 0)
+	assert.Len(t, m.Entries, 3)
+
+	// Corrupt, changed, missing and new
+	writeFile(t, dir, "a.txt", "HELLO")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("world!"), 0666))
+	require.NoError(t, os.Remove(filepath.Join(dir, "c.txt")))
+	writeFile(t, dir, "d.txt", "new")
+	m2, diffs, err := verify(ctx, f, m, opt)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"a.txt": StatusCorrupt,
+		"b.txt": StatusChanged,
+		"c.txt": StatusMissing,
+		"d.txt": StatusNew,
+	}, statuses(diffs))
+	assert.Equal(t, m.Entries, m2.Entries, "differences not accepted without update")
+
+	// Accept the differences apart from the corruption
+	opt.update = true
+	m2, _, err = verify(ctx, f, m, opt)
+	require.NoError(t, err)
+	require.Len(t, m2.Entries, 3)
+	assert.Equal(t, m.Entries[0], m2.Entries[0])
+	assert.Equal(t, "b.txt", m2.Entries[1].Path)
+	assert.Equal(t, int64(6), m2.Entries[1].Size)
+	assert.Equal(t, "d.txt", m2.Entries[2].Path)
+
+	// Recently verified unchanged objects aren't hashed
+	opt.update = false
+	opt.reverifyAfter = time.Hour
+	m2.Entries[0].Hash = "recently verified"
+	_, diffs, err = verify(ctx, f, m2, opt)
+	require.NoError(t, err)
+	assert.Len(t, diffs, 0)
+}
+
+func TestManifestSignature(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	m := &Manifest{
+		HashType: "md5",
+		Entries:  []Entry{{Path: "a.txt", Size: 5, ModTime: t1, Hash: "5d41402abc4b2a76b9719d911017c592"}},
+	}
+
+	// Not found
+	got, err := readManifest(path, "")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	// Unsigned
+	require.NoError(t, writeManifest(path, "", m))
+	got, err = readManifest(path, "")
+	require.NoError(t, err)
+	assert.Equal(t, "", got.Signature)
+	_, err = readManifest(path, "key")
+	assert.EqualError(t, err, `manifest "`+path+`": manifest isn't signed`)
+
+	// Signed
+	require.NoError(t, writeManifest(path, "key", m))
+	got, err = readManifest(path, "key")
+	require.NoError(t, err)
+	assert.Equal(t, m.Entries[0].Hash, got.Entries[0].Hash)
+	_, err = readManifest(path, "")
+	assert.Error(t, err)
+	_, err = readManifest(path, "wrong")
+	assert.Error(t, err)
+
+	// Tampered
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	data = bytes.Replace(data, []byte("5d41402abc4b2a76b9719d911017c592"), []byte("00000000000000000000000000000000"), 1)
+	require.NoError(t, ioutil.WriteFile(path, data, 0666))
+	_, err = readManifest(path, "key")
+	assert.Error(t, err)
+}
+
+func TestCompareManifests(t *testing.T) {
+	a := &Manifest{Entries: []Entry{
+		{Path: "same", Size: 1, ModTime: t1, Hash: "1"},
+		{Path: "corrupt", Size: 1, ModTime: t1, Hash: "1"},
+		{Path: "changed", Size: 1, ModTime: t1, Hash: "1"},
+		{Path: "missing", Size: 1, ModTime: t1, Hash: "1"},
+	}}
+	b := &Manifest{Entries: []Entry{
+		{Path: "same", Size: 1, ModTime: t1.Add(time.Hour), Hash: "1"},
+		{Path: "corrupt", Size: 1, ModTime: t1, Hash: "2"},
+		{Path: "changed", Size: 2, ModTime: t1, Hash: "2"},
+		{Path: "new", Size: 1, ModTime: t1, Hash: "1"},
+	}}
+	assert.Equal(t, map[string]string{
+		"corrupt": StatusCorrupt,
+		"changed": StatusChanged,
+		"missing": StatusMissing,
+		"new":     StatusNew,
+	}, statuses(compareManifests(a, b)))
+}
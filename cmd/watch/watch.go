@@ -0,0 +1,232 @@
+// Package watch provides the watch command.
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/cmd"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/walk"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pollInterval = time.Minute
+	forcePoll    = false
+	execCommand  fs.SpaceSepList
+)
+
+func init() {
+	cmd.Root.AddCommand(commandDefinition)
+	cmdFlags := commandDefinition.Flags()
+	flags.DurationVarP(cmdFlags, &pollInterval, "poll-interval", "", pollInterval, "Time to wait between polling for changes")
+	flags.BoolVarP(cmdFlags, &forcePoll, "force-poll", "", forcePoll, "List the remote to find changes even if it supports change notifications")
+	flags.FVarP(cmdFlags, &execCommand, "exec", "", "Command to run for each change instead of printing it")
+}
+
+var commandDefinition = &cobra.Command{
+	Use:   "watch remote:path",
+	Short: `Print or run a command for the changes to a remote.`,
+	Long: `
+Watches remote:path for changes and prints a line of JSON for each
+change, which can be used to drive other programs without a mount.
+
+    {"Time":"2023-04-01T12:00:00.123456789Z","Event":"changed","Path":"dir/file.txt","IsDir":false}
+
+If the remote supports change notifications (see the [overview](/overview/#optional-features))
+then these are used, otherwise the remote is listed every
+` + "`--poll-interval`" + ` and compared with the last listing. Use
+` + "`--force-poll`" + ` to list the remote even if it supports change
+notifications.
+
+The ` + "`Event`" + ` is one of
+
+- ` + "`changed`" + ` - something changed at ` + "`Path`" + ` (from change notifications)
+- ` + "`created`" + ` - a new file or directory appeared
+- ` + "`modified`" + ` - a file changed size or modification time
+- ` + "`deleted`" + ` - a file or directory disappeared
+
+Change notifications only say that something changed, so ` + "`Path`" + `
+may have been created, modified or deleted. Some remotes only send
+notifications for directories. Listing the remote sees the first
+listing as the starting point, so it doesn't print the existing files.
+
+Use ` + "`--exec`" + ` to run a command for each change instead of
+printing it. The change is passed to it in the environment variables
+` + "`RCLONE_WATCH_EVENT`" + `, ` + "`RCLONE_WATCH_PATH`" + `,
+` + "`RCLONE_WATCH_IS_DIR`" + ` and ` + "`RCLONE_WATCH_TIME`" + `,
+and the JSON line on its standard input. The command is run once for
+each change in turn, and errors from it are logged but don't stop the
+watch.
+
+    rclone watch remote:incoming --exec "/usr/local/bin/process-upload"
+
+The command runs until it is interrupted.
+`,
+	Run: func(command *cobra.Command, args []string) {
+		cmd.CheckArgs(1, 1, command, args)
+		f := cmd.NewFsSrc(args)
+		cmd.Run(false, false, command, func() error {
+			w := newWatcher(os.Stdout, execCommand)
+			return w.watch(context.Background(), f, pollInterval, forcePoll)
+		})
+	},
+}
+
+// Event describes a change to the remote
+type Event struct {
+	Time  time.Time
+	Event string
+	Path  string
+	IsDir bool
+}
+
+// watcher reports the changes to a remote
+type watcher struct {
+	mu   sync.Mutex
+	out  io.Writer       // where to write the changes
+	exec fs.SpaceSepList // command to run for each change if set
+}
+
+// newWatcher makes a watcher which writes the changes to out or runs
+// command for them if set
+func newWatcher(out io.Writer, command fs.SpaceSepList) *watcher {
+	return &watcher{
+		out:  out,
+		exec: command,
+	}
+}
+
+// emit reports the change e
+func (w *watcher) emit(e Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data, err := json.Marshal(e)
+	if err != nil {
+		fs.Errorf(e.Path, "Failed to encode change: %v", err)
+		return
+	}
+	data = append(data, '\n')
+	if len(w.exec) == 0 {
+		if _, err := w.out.Write(data); err != nil {
+			fs.Errorf(e.Path, "Failed to write change: %v", err)
+		}
+		return
+	}
+	c := exec.Command(w.exec[0], w.exec[1:]...)
+	c.Env = append(os.Environ(),
+		"RCLONE_WATCH_EVENT="+e.Event,
+		"RCLONE_WATCH_PATH="+e.Path,
+		fmt.Sprintf("RCLONE_WATCH_IS_DIR=%v", e.IsDir),
+		"RCLONE_WATCH_TIME="+e.Time.Format(time.RFC3339Nano),
+	)
+	c.Stdin = bytes.NewReader(data)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		fs.Errorf(e.Path, "Failed to run %q for %s change: %v", w.exec, e.Event, err)
+	}
+}
+
+// watch reports the changes to f until ctx is cancelled
+func (w *watcher) watch(ctx context.Context, f fs.Fs, interval time.Duration, forcePoll bool) error {
+	if do := f.Features().ChangeNotify; do != nil && !forcePoll {
+		pollChan := make(chan time.Duration)
+		do(ctx, func(remote string, entryType fs.EntryType) {
+			w.emit(Event{
+				Time:  time.Now(),
+				Event: "changed",
+				Path:  remote,
+				IsDir: entryType == fs.EntryDirectory,
+			})
+		}, pollChan)
+		pollChan <- interval
+		fs.Infof(f, "Waiting for change notifications, polling every %v", interval)
+		<-ctx.Done()
+		close(pollChan)
+		return nil
+	}
+	fs.Infof(f, "Listing the remote every %v to find changes", interval)
+	old, err := snapshot(ctx, f)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		current, err := snapshot(ctx, f)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			fs.Errorf(f, "Failed to list remote - will retry: %v", err)
+			continue
+		}
+		for _, e := range diff(old, current, time.Now()) {
+			w.emit(e)
+		}
+		old = current
+	}
+}
+
+// entry is the state of a file or directory in a listing
+type entry struct {
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+// snapshot lists f recursively
+func snapshot(ctx context.Context, f fs.Fs) (entries map[string]entry, err error) {
+	entries = map[string]entry{}
+	err = walk.ListR(ctx, f, "", true, fs.GetConfig(ctx).MaxDepth, walk.ListAll, func(dirEntries fs.DirEntries) error {
+		for _, dirEntry := range dirEntries {
+			switch x := dirEntry.(type) {
+			case fs.Object:
+				entries[x.Remote()] = entry{size: x.Size(), modTime: x.ModTime(ctx)}
+			case fs.Directory:
+				entries[x.Remote()] = entry{isDir: true}
+			}
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// diff returns the changes between the listings old and current
+// sorted by path, with deletions first if a path changes type
+func diff(old, current map[string]entry, now time.Time) (events []Event) {
+	for remote, o := range old {
+		if e, found := current[remote]; !found || o.isDir != e.isDir {
+			events = append(events, Event{Time: now, Event: "deleted", Path: remote, IsDir: o.isDir})
+		}
+	}
+	for remote, e := range current {
+		o, found := old[remote]
+		switch {
+		case !found || o.isDir != e.isDir:
+			events = append(events, Event{Time: now, Event: "created", Path: remote, IsDir: e.isDir})
+		case !e.isDir && (o.size != e.size || !o.modTime.Equal(e.modTime)):
+			events = append(events, Event{Time: now, Event: "modified", Path: remote})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	return events
+}
@@ -0,0 +1,114 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	t1 = fstest.Time("2017-02-03T04:05:06.499999999Z")
+	t2 = fstest.Time("2018-02-03T04:05:06.499999999Z")
+)
+
+// TestMain drives the tests
+func TestMain(m *testing.M) {
+	fstest.TestMain(m)
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Now()
+	old := map[string]entry{
+		"dir":          {isDir: true},
+		"dir/same":     {size: 1, modTime: t1},
+		"dir/size":     {size: 1, modTime: t1},
+		"dir/modtime":  {size: 1, modTime: t1},
+		"dir/deleted":  {size: 1, modTime: t1},
+		"becomes-dir":  {size: 1, modTime: t1},
+		"deleted-dir":  {isDir: true},
+		"unchangeddir": {isDir: true},
+	}
+	current := map[string]entry{
+		"dir":          {isDir: true},
+		"dir/same":     {size: 1, modTime: t1},
+		"dir/size":     {size: 2, modTime: t1},
+		"dir/modtime":  {size: 1, modTime: t2},
+		"dir/created":  {size: 1, modTime: t1},
+		"becomes-dir":  {isDir: true},
+		"unchangeddir": {isDir: true},
+	}
+	var got []string
+	for _, e := range diff(old, current, now) {
+		assert.Equal(t, now, e.Time)
+		got = append(got, e.Event+" "+e.Path)
+	}
+	assert.Equal(t, []string{
+		"deleted becomes-dir",
+		"created becomes-dir",
+		"deleted deleted-dir",
+		"created dir/created",
+		"deleted dir/deleted",
+		"modified dir/modtime",
+		"modified dir/size",
+	}, got)
+	assert.Nil(t, diff(current, current, now))
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchPoll(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	r.WriteFile("existing", "existing", t1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out syncBuffer
+	w := newWatcher(&out, nil)
+	done := make(chan error)
+	go func() {
+		done <- w.watch(ctx, r.Flocal, 10*time.Millisecond, true)
+	}()
+
+	// Wait for the first listing before making a change
+	time.Sleep(100 * time.Millisecond)
+	r.WriteFile("new", "new", t1)
+	var lines []string
+	for i := 0; i < 100 && len(lines) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		lines = strings.Fields(out.String())
+	}
+	cancel()
+	require.NoError(t, <-done)
+
+	require.Equal(t, 1, len(lines), out.String())
+	var e Event
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &e))
+	assert.Equal(t, "created", e.Event)
+	assert.Equal(t, "new", e.Path)
+	assert.False(t, e.IsDir)
+}
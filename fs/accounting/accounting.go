@@ -15,6 +15,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/asyncreader"
 	"github.com/rclone/rclone/fs/fserrors"
+	"golang.org/x/time/rate"
 )
 
 // ErrorMaxTransferLimitReached defines error when transfer limit is reached.
@@ -39,6 +40,12 @@ func Start(ctx context.Context) {
 
 	// Start the transactions per second limiter
 	StartLimitTPS(ctx)
+
+	// Start the deletions per second limiter
+	StartLimitDeletes(ctx)
+
+	// Start the bandwidth class limiters
+	StartBwClasses(ctx)
 }
 
 // Account limits and accounts for one transfer
@@ -61,7 +68,8 @@ type Account struct {
 	exit    chan struct{} // channel that will be closed when transfer is finished
 	withBuf bool          // is using a buffered in
 
-	tokenBucket buckets // per file bandwidth limiter (may be nil)
+	tokenBucket buckets       // per file bandwidth limiter (may be nil)
+	classBucket *rate.Limiter // bandwidth limiter for the file's class (may be nil)
 
 	values accountValues
 }
@@ -106,6 +114,7 @@ func newAccountSizeName(ctx context.Context, stats *StatsInfo, in io.ReadCloser,
 		fs.Debugf(acc.name, "Limiting file transfer to %v", currLimit.Bandwidth)
 		acc.tokenBucket = newTokenBucket(currLimit.Bandwidth)
 	}
+	acc.classBucket = findBwClass(name)
 
 	go acc.averageLoop()
 	stats.inProgress.set(acc.name, acc)
@@ -314,6 +323,16 @@ func (acc *Account) limitPerFileBandwidth(n int) {
 	}
 }
 
+// Account for n bytes from the bandwidth limit of the file's class (if any)
+func (acc *Account) limitClassBandwidth(n int) {
+	if acc.classBucket != nil {
+		err := acc.classBucket.WaitN(context.Background(), n)
+		if err != nil {
+			fs.Errorf(nil, "Token bucket error: %v", err)
+		}
+	}
+}
+
 // Account the read and limit bandwidth
 func (acc *Account) accountRead(n int) {
 	// Update Stats
@@ -325,7 +344,9 @@ func (acc *Account) accountRead(n int) {
 	acc.stats.Bytes(int64(n))
 
 	TokenBucket.LimitBandwidth(TokenBucketSlotAccounting, n)
+	acc.stats.limitBandwidth(n)
 	acc.limitPerFileBandwidth(n)
+	acc.limitClassBandwidth(n)
 }
 
 // read bytes from the io.Reader passed in and account them
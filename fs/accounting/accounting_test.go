@@ -385,3 +385,31 @@ func TestShortenName(t *testing.T) {
 		})
 	}
 }
+
+func TestAccountBwClass(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.BwLimitClasses = []fs.BwClass{
+		{Glob: "*.jpg", Bandwidth: 2 * fs.Mebi},
+		{Glob: "*.mkv", Bandwidth: 20 * fs.Mebi},
+	}
+	StartBwClasses(ctx)
+	defer func() {
+		bwClasses = nil
+	}()
+	stats := NewStats(ctx)
+	newAccount := func(name string) *Account {
+		in := ioutil.NopCloser(bytes.NewBuffer([]byte{1}))
+		acc := newAccountSizeName(ctx, stats, in, 1, name)
+		t.Cleanup(acc.Done)
+		return acc
+	}
+
+	a, b := newAccount("a.jpg"), newAccount("dir/b.jpg")
+	require.NotNil(t, a.classBucket)
+	assert.Same(t, a.classBucket, b.classBucket, "files in a class share a limit")
+	c := newAccount("c.mkv")
+	require.NotNil(t, c.classBucket)
+	assert.NotSame(t, a.classBucket, c.classBucket)
+	assert.Equal(t, float64(20*fs.Mebi), float64(c.classBucket.Limit()))
+	assert.Nil(t, newAccount("d.txt").classBucket)
+}
@@ -0,0 +1,54 @@
+// Bandwidth limits for classes of files set with --bwlimit-class
+
+package accounting
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+	"golang.org/x/time/rate"
+)
+
+// bwClass is a bandwidth limit shared by the files whose names match re
+type bwClass struct {
+	re          *regexp.Regexp
+	bandwidth   fs.SizeSuffix
+	tokenBucket *rate.Limiter
+}
+
+var bwClasses []bwClass // set up by StartBwClasses
+
+// StartBwClasses starts the token buckets for the bandwidth classes
+// set with --bwlimit-class if necessary
+func StartBwClasses(ctx context.Context) {
+	ci := fs.GetConfig(ctx)
+	fi := filter.GetConfig(ctx)
+	bwClasses = nil
+	for _, class := range ci.BwLimitClasses {
+		re, err := filter.GlobToRegexp(class.Glob, fi.Opt.IgnoreCase)
+		if err != nil {
+			fs.Errorf(nil, "Ignoring --bwlimit-class %q: %v", class.Glob, err)
+			continue
+		}
+		bwClasses = append(bwClasses, bwClass{
+			re:          re,
+			bandwidth:   class.Bandwidth,
+			tokenBucket: newEmptyTokenBucket(class.Bandwidth),
+		})
+		fs.Infof(nil, "Starting bandwidth limiter for %q at %s", class.Glob, class.Bandwidth.ByteRateUnit())
+	}
+}
+
+// findBwClass returns the token bucket of the first bandwidth class
+// that name matches or nil if there isn't one
+func findBwClass(name string) *rate.Limiter {
+	for _, class := range bwClasses {
+		if class.re.MatchString(name) {
+			fs.Debugf(name, "Limiting transfer to %s shared with the other files in its bandwidth class", class.bandwidth.ByteRateUnit())
+			return class.tokenBucket
+		}
+	}
+	return nil
+}
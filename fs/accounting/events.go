@@ -0,0 +1,114 @@
+// Events sent when transfers start and finish
+
+package accounting
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Types of TransferEvent
+const (
+	EventStart    = "start"
+	EventProgress = "progress"
+	EventFinish   = "finish"
+)
+
+// TransferEvent is a change in the state of a transfer
+type TransferEvent struct {
+	Type     string           `json:"type"`
+	Time     time.Time        `json:"time"`
+	Transfer TransferSnapshot `json:"transfer"`
+}
+
+// transferEvents keeps track of the transfers in progress and the
+// subscribers to their events
+type transferEvents struct {
+	mu          sync.Mutex
+	inProgress  map[*Transfer]struct{}
+	subscribers map[chan TransferEvent]struct{}
+}
+
+var events = &transferEvents{
+	inProgress:  make(map[*Transfer]struct{}),
+	subscribers: make(map[chan TransferEvent]struct{}),
+}
+
+// publish sends an event for tr to the subscribers, dropping it for
+// any which aren't keeping up so transfers are never held up.
+func (te *transferEvents) publish(eventType string, tr *Transfer) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	if len(te.subscribers) == 0 {
+		return
+	}
+	event := TransferEvent{
+		Type:     eventType,
+		Time:     time.Now(),
+		Transfer: tr.Snapshot(),
+	}
+	for ch := range te.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// start records tr as in progress and sends its start event
+func (te *transferEvents) start(tr *Transfer) {
+	te.mu.Lock()
+	te.inProgress[tr] = struct{}{}
+	te.mu.Unlock()
+	te.publish(EventStart, tr)
+}
+
+// finish records tr as finished and sends its finish event if it
+// was in progress
+func (te *transferEvents) finish(tr *Transfer) {
+	te.mu.Lock()
+	_, found := te.inProgress[tr]
+	delete(te.inProgress, tr)
+	te.mu.Unlock()
+	if found {
+		te.publish(EventFinish, tr)
+	}
+}
+
+// SubscribeTransfers returns a channel which receives an event as
+// each transfer starts and finishes and a function to call to stop
+// receiving them.
+//
+// Events are dropped if the channel, which has a buffer of size, is
+// full.
+func SubscribeTransfers(size int) (ch <-chan TransferEvent, unsubscribe func()) {
+	c := make(chan TransferEvent, size)
+	events.mu.Lock()
+	events.subscribers[c] = struct{}{}
+	events.mu.Unlock()
+	return c, func() {
+		events.mu.Lock()
+		delete(events.subscribers, c)
+		events.mu.Unlock()
+	}
+}
+
+// TransfersInProgress returns progress events for all the transfers
+// in progress sorted by name.
+func TransfersInProgress() (progress []TransferEvent) {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	now := time.Now()
+	for tr := range events.inProgress {
+		progress = append(progress, TransferEvent{
+			Type:     EventProgress,
+			Time:     now,
+			Transfer: tr.Snapshot(),
+		})
+	}
+	sort.Slice(progress, func(i, j int) bool {
+		return progress[i].Transfer.Name < progress[j].Transfer.Name
+	})
+	return progress
+}
@@ -13,11 +13,13 @@ import (
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/lib/terminal"
+	"golang.org/x/time/rate"
 )
 
 const (
 	averagePeriodLength = time.Second
 	averageStopAfter    = time.Minute
+	etaPeriod           = 30 // number of seconds of recent speeds to base the ETA on
 )
 
 // MaxCompletedTransfers specifies maximum number of completed transfers in startedTransfers list
@@ -49,6 +51,8 @@ type StatsInfo struct {
 	renameQueueSize   int64
 	deletes           int64
 	deletedDirs       int64
+	listedDirs        int64
+	listedObjects     int64
 	inProgress        *inProgress
 	startedTransfers  []*Transfer   // currently active transfers
 	oldTimeRanges     timeRanges    // a merged list of time ranges for the transfers
@@ -56,6 +60,8 @@ type StatsInfo struct {
 	group             string
 	startTime         time.Time // the moment these stats were initialized or reset
 	average           averageValues
+	remotes           map[string]*remoteStats // per remote stats
+	bwLimit           *rate.Limiter           // bandwidth limit for this group only, if set
 }
 
 type averageValues struct {
@@ -63,6 +69,8 @@ type averageValues struct {
 	lpBytes   int64
 	lpTime    time.Time
 	speed     float64
+	recent    []float64 // speeds of the last etaPeriod seconds, oldest first
+	etaSpeed  float64   // weighted average of recent for the ETA
 	stop      chan bool
 	stopped   sync.WaitGroup
 	startOnce sync.Once
@@ -107,8 +115,10 @@ func (s *StatsInfo) RemoteStats() (out rc.Params, err error) {
 	out["deletes"] = s.deletes
 	out["deletedDirs"] = s.deletedDirs
 	out["renames"] = s.renames
+	out["listedDirs"] = s.listedDirs
+	out["listedObjects"] = s.listedObjects
 	out["elapsedTime"] = time.Since(s.startTime).Seconds()
-	eta, etaOK := eta(s.bytes, ts.totalBytes, ts.speed)
+	eta, etaOK := eta(s.bytes, ts.totalBytes, ts.etaSpeed)
 	if etaOK {
 		out["eta"] = eta.Seconds()
 	} else {
@@ -116,6 +126,9 @@ func (s *StatsInfo) RemoteStats() (out rc.Params, err error) {
 	}
 	s.mu.RUnlock()
 
+	if remotes := s.Remotes(); len(remotes) > 0 {
+		out["remotes"] = remotes
+	}
 	if !s.checking.empty() {
 		out["checking"] = s.checking.remotes()
 	}
@@ -247,6 +260,22 @@ func eta(size, total int64, rate float64) (eta time.Duration, ok bool) {
 	return time.Second * time.Duration(seconds), true
 }
 
+// weightedSpeed returns the average of the speeds, oldest first,
+// weighting each one by how recent it is so the ETA follows changes
+// in speed quickly without jumping about on every sample.
+func weightedSpeed(speeds []float64) float64 {
+	var sum, weights float64
+	for i, speed := range speeds {
+		weight := float64(i + 1)
+		sum += speed * weight
+		weights += weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return sum / weights
+}
+
 // etaString returns the ETA of the current operation,
 // rounded to full seconds.
 // If the ETA cannot be determined it returns "-"
@@ -276,6 +305,7 @@ type transferStats struct {
 	totalBytes     int64
 	transferTime   float64
 	speed          float64
+	etaSpeed       float64
 }
 
 // calculateTransferStats calculates some addtional transfer stats not
@@ -295,6 +325,7 @@ func (s *StatsInfo) calculateTransferStats() (ts transferStats) {
 	// we take it off here to avoid double counting
 	ts.totalBytes = s.transferQueueSize + s.bytes + transferringBytesTotal - transferringBytesDone
 	ts.speed = s.average.speed
+	ts.etaSpeed = s.average.etaSpeed
 
 	return ts
 }
@@ -326,6 +357,11 @@ func (s *StatsInfo) averageLoop() {
 				period++
 			}
 			a.speed = (avg + a.speed*(period-1)) / period
+			a.recent = append(a.recent, avg)
+			if len(a.recent) > etaPeriod {
+				a.recent = a.recent[len(a.recent)-etaPeriod:]
+			}
+			a.etaSpeed = weightedSpeed(a.recent)
 			a.lpBytes = 0
 			a.lpTime = now
 			a.mu.Unlock()
@@ -398,13 +434,13 @@ func (s *StatsInfo) String() string {
 		fs.SizeSuffix(ts.totalBytes).ByteUnit(),
 		percent(s.bytes, ts.totalBytes),
 		displaySpeedString,
-		etaString(s.bytes, ts.totalBytes, ts.speed),
+		etaString(s.bytes, ts.totalBytes, ts.etaSpeed),
 		xfrchkString,
 	)
 
 	if s.ci.ProgressTerminalTitle {
 		// Writes ETA to the terminal title
-		terminal.WriteTerminalTitle("ETA: " + etaString(s.bytes, ts.totalBytes, ts.speed))
+		terminal.WriteTerminalTitle("ETA: " + etaString(s.bytes, ts.totalBytes, ts.etaSpeed))
 	}
 
 	if !s.ci.StatsOneLine {
@@ -435,6 +471,9 @@ func (s *StatsInfo) String() string {
 		if s.renames != 0 {
 			_, _ = fmt.Fprintf(buf, "Renamed:       %10d\n", s.renames)
 		}
+		if s.listedDirs != 0 {
+			_, _ = fmt.Fprintf(buf, "Listed:        %10d (dirs), %d (objects)\n", s.listedDirs, s.listedObjects)
+		}
 		if s.transfers != 0 || ts.totalTransfers != 0 {
 			_, _ = fmt.Fprintf(buf, "Transferred:   %10d / %d, %s\n",
 				s.transfers, ts.totalTransfers, percent(s.transfers, ts.totalTransfers))
@@ -454,6 +493,9 @@ func (s *StatsInfo) String() string {
 		if !s.transferring.empty() {
 			_, _ = fmt.Fprintf(buf, "Transferring:\n%s\n", s.transferring.String(s.ctx, s.inProgress, nil))
 		}
+		if remotes := s.remotesString(); remotes != "" {
+			_, _ = fmt.Fprintf(buf, "Remotes:\n%s", remotes)
+		}
 	}
 
 	return buf.String()
@@ -592,6 +634,15 @@ func (s *StatsInfo) DeletedDirs(deletedDirs int64) int64 {
 	return s.deletedDirs
 }
 
+// Listed updates the stats for the directories listed and the
+// objects found in them while scanning
+func (s *StatsInfo) Listed(dirs, objects int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listedDirs += dirs
+	s.listedObjects += objects
+}
+
 // Renames updates the stats for renames
 func (s *StatsInfo) Renames(renames int64) int64 {
 	s.mu.Lock()
@@ -600,6 +651,40 @@ func (s *StatsInfo) Renames(renames int64) int64 {
 	return s.renames
 }
 
+// SetBwLimit limits the bandwidth of the transfers accounted in these
+// stats to the larger of bandwidth.Tx and bandwidth.Rx, or removes
+// the limit if bandwidth isn't set.
+//
+// This applies as well as the global --bwlimit.
+func (s *StatsInfo) SetBwLimit(bandwidth fs.BwPair) {
+	limit := bandwidth.Tx
+	if bandwidth.Rx > limit {
+		limit = bandwidth.Rx
+	}
+	var tb *rate.Limiter
+	if limit > 0 {
+		tb = newEmptyTokenBucket(limit)
+	}
+	s.mu.Lock()
+	s.bwLimit = tb
+	s.mu.Unlock()
+}
+
+// limitBandwidth sleeps for the passage of n bytes according to the
+// bandwidth limit set with SetBwLimit
+func (s *StatsInfo) limitBandwidth(n int) {
+	s.mu.RLock()
+	tb := s.bwLimit
+	s.mu.RUnlock()
+	if tb == nil {
+		return
+	}
+	err := tb.WaitN(context.Background(), n)
+	if err != nil {
+		fs.Errorf(nil, "Token bucket error: %v", err)
+	}
+}
+
 // ResetCounters sets the counters (bytes, checks, errors, transfers, deletes, renames) to 0 and resets lastError, fatalError and retryError
 func (s *StatsInfo) ResetCounters() {
 	s.mu.Lock()
@@ -615,8 +700,11 @@ func (s *StatsInfo) ResetCounters() {
 	s.deletes = 0
 	s.deletedDirs = 0
 	s.renames = 0
+	s.listedDirs = 0
+	s.listedObjects = 0
 	s.startedTransfers = nil
 	s.oldDuration = 0
+	s.remotes = nil
 
 	s.stopAverageLoop()
 	s.average = averageValues{stop: make(chan bool)}
@@ -701,6 +789,7 @@ func (s *StatsInfo) NewTransfer(obj fs.Object) *Transfer {
 	tr := newTransfer(s, obj)
 	s.transferring.add(tr)
 	s.startAverageLoop()
+	events.start(tr)
 	return tr
 }
 
@@ -709,6 +798,7 @@ func (s *StatsInfo) NewTransferRemoteSize(remote string, size int64) *Transfer {
 	tr := newTransferRemoteSize(s, remote, size, false)
 	s.transferring.add(tr)
 	s.startAverageLoop()
+	events.start(tr)
 	return tr
 }
 
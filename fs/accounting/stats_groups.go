@@ -91,9 +91,11 @@ Returns the following values:
 	"deletes" : number of files deleted,
 	"elapsedTime": time in floating point seconds since rclone was started,
 	"errors": number of errors,
-	"eta": estimated time in seconds until the group completes,
+	"eta": estimated time in seconds until the group completes, from the recent speed,
 	"fatalError": boolean whether there has been at least one fatal error,
 	"lastError": last error string,
+	"listedDirs": number of directories listed while scanning,
+	"listedObjects": number of objects found in the directories listed,
 	"renames" : number of files renamed,
 	"retryError": boolean showing whether there has been at least one non-NoRetryError,
 	"speed": average speed in bytes per second since start of the group,
@@ -116,9 +118,30 @@ Returns the following values:
 		],
 	"checking": an array of names of currently active file checks
 		[]
+	"remotes": an object with the stats of each remote files have been transferred to or from:
+		{
+			"remote name": {
+				"bytes": total transferred bytes to or from this remote,
+				"errors": number of failed transfers,
+				"retries": number of low level retries made by the remote,
+				"speed": average speed of the transfers in bytes per second,
+				"transfers": number of completed transfers,
+				"latency": a histogram of the API call latency of the remote, if known:
+					{
+						"count": number of API calls timed,
+						"mean": mean latency in seconds,
+						"buckets": [
+							{
+								"le": upper bound of the bucket in seconds, missing for the last bucket,
+								"count": number of calls in this bucket
+							}
+						]
+					}
+			}
+		}
 }
 ` + "```" + `
-Values for "transferring", "checking" and "lastError" are only assigned if data is available.
+Values for "transferring", "checking", "remotes" and "lastError" are only assigned if data is available.
 The value for "eta" is null if an eta cannot be determined.
 `,
 	})
@@ -380,12 +403,16 @@ func (sg *statsGroups) sum(ctx context.Context) *StatsInfo {
 			sum.renameQueueSize += stats.renameQueueSize
 			sum.deletes += stats.deletes
 			sum.deletedDirs += stats.deletedDirs
+			sum.listedDirs += stats.listedDirs
+			sum.listedObjects += stats.listedObjects
 			sum.inProgress.merge(stats.inProgress)
 			sum.startedTransfers = append(sum.startedTransfers, stats.startedTransfers...)
 			sum.oldTimeRanges = append(sum.oldTimeRanges, stats.oldTimeRanges...)
 			sum.oldDuration += stats.oldDuration
+			sum.mergeRemotes(stats)
 			stats.average.mu.Lock()
 			sum.average.speed += stats.average.speed
+			sum.average.etaSpeed += stats.average.etaSpeed
 			stats.average.mu.Unlock()
 		}
 		stats.mu.RUnlock()
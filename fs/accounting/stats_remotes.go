@@ -0,0 +1,144 @@
+// Per remote transfer statistics
+
+package accounting
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// remoteStats are the transfer statistics of a single remote
+type remoteStats struct {
+	bytes     int64
+	errors    int64
+	transfers int64
+	duration  time.Duration // total time spent transferring
+}
+
+// RemoteStatsSnapshot is a snapshot of the statistics of a single
+// remote
+type RemoteStatsSnapshot struct {
+	Bytes     int64   `json:"bytes"`
+	Errors    int64   `json:"errors"`
+	Transfers int64   `json:"transfers"`
+	Retries   int64   `json:"retries"`
+	Speed     float64 `json:"speed"`
+
+	Latency *fs.LatencyStats `json:"latency,omitempty"`
+}
+
+// remoteName returns the name the stats for f are kept under
+func remoteName(f fs.Info) string {
+	if f == nil {
+		return ""
+	}
+	return f.Name()
+}
+
+// doneRemote records a finished transfer of n bytes taking d to or
+// from the remote called name
+func (s *StatsInfo) doneRemote(name string, n int64, d time.Duration, err error) {
+	if name == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.remotes == nil {
+		s.remotes = make(map[string]*remoteStats)
+	}
+	rs := s.remotes[name]
+	if rs == nil {
+		rs = new(remoteStats)
+		s.remotes[name] = rs
+	}
+	rs.bytes += n
+	rs.duration += d
+	if err != nil {
+		rs.errors++
+	} else {
+		rs.transfers++
+	}
+}
+
+// mergeRemotes adds the remote stats in other to s
+//
+// s.mu and other.mu must be held
+func (s *StatsInfo) mergeRemotes(other *StatsInfo) {
+	for name, ors := range other.remotes {
+		if s.remotes == nil {
+			s.remotes = make(map[string]*remoteStats)
+		}
+		rs := s.remotes[name]
+		if rs == nil {
+			rs = new(remoteStats)
+			s.remotes[name] = rs
+		}
+		rs.bytes += ors.bytes
+		rs.errors += ors.errors
+		rs.transfers += ors.transfers
+		rs.duration += ors.duration
+	}
+}
+
+// Remotes returns a snapshot of the statistics of each remote files
+// have been transferred to or from, keyed by remote name.
+//
+// The retries and the latency histogram of the API calls are for the
+// whole time rclone has been running.
+func (s *StatsInfo) Remotes() map[string]RemoteStatsSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]RemoteStatsSnapshot, len(s.remotes))
+	for name, rs := range s.remotes {
+		counters := fs.LookupCounters(name).Stats()
+		snap := RemoteStatsSnapshot{
+			Bytes:     rs.bytes,
+			Errors:    rs.errors,
+			Transfers: rs.transfers,
+			Retries:   counters.Retries,
+			Latency:   counters.Latency,
+		}
+		if rs.duration > 0 {
+			snap.Speed = float64(rs.bytes) / rs.duration.Seconds()
+		}
+		out[name] = snap
+	}
+	return out
+}
+
+// remotesString returns the per remote statistics for --stats or ""
+// if fewer than two remotes have been used
+func (s *StatsInfo) remotesString() string {
+	remotes := s.Remotes()
+	if len(remotes) < 2 {
+		return ""
+	}
+	names := make([]string, 0, len(remotes))
+	for name := range remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	for _, name := range names {
+		rs := remotes[name]
+		var speed string
+		if s.ci.DataRateUnit == "bits" {
+			speed = fs.SizeSuffix(rs.Speed * 8).BitRateUnit()
+		} else {
+			speed = fs.SizeSuffix(rs.Speed).ByteRateUnit()
+		}
+		_, _ = fmt.Fprintf(buf, " * %s: %s, %s, %d transfers, %d errors, %d retries\n",
+			name,
+			fs.SizeSuffix(rs.Bytes).ByteUnit(),
+			speed,
+			rs.Transfers,
+			rs.Errors,
+			rs.Retries,
+		)
+	}
+	return buf.String()
+}
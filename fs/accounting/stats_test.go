@@ -1,14 +1,19 @@
 package accounting
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"testing"
 	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -52,6 +57,16 @@ func TestETA(t *testing.T) {
 	}
 }
 
+func TestWeightedSpeed(t *testing.T) {
+	assert.Equal(t, 0.0, weightedSpeed(nil))
+	assert.Equal(t, 100.0, weightedSpeed([]float64{100}))
+	assert.Equal(t, 100.0, weightedSpeed([]float64{100, 100, 100}))
+	// Recent speeds count for more than old ones
+	assert.InDelta(t, 250.0/3, weightedSpeed([]float64{50, 100}), 1e-9)
+	assert.Greater(t, weightedSpeed([]float64{0, 0, 0, 100}), 25.0)
+	assert.Less(t, weightedSpeed([]float64{100, 0, 0, 0}), 25.0)
+}
+
 func TestPercentage(t *testing.T) {
 	assert.Equal(t, percent(0, 1000), "0%")
 	assert.Equal(t, percent(1, 1000), "0%")
@@ -435,3 +450,54 @@ func TestPruneTransfers(t *testing.T) {
 		})
 	}
 }
+
+func TestStatsRemotes(t *testing.T) {
+	ctx := context.Background()
+	fs.ResetCounters()
+	defer fs.ResetCounters()
+	s := NewStats(ctx)
+	src := mockfs.NewFs(ctx, "src", "")
+	dst := mockfs.NewFs(ctx, "dst", "")
+	fs.GetCounters("dst").Retry()
+	fs.GetCounters("dst").Latency(20 * time.Millisecond)
+
+	// transfer copies contents from src to dst returning err
+	transfer := func(contents string, err error) {
+		o := mockobject.New("file").WithContent([]byte(contents), mockobject.SeekModeNone)
+		o.SetFs(src)
+		tr := s.NewTransfer(o)
+		tr.AddRemote(dst)
+		tr.AddRemote(src)
+		in := tr.Account(ctx, ioutil.NopCloser(bytes.NewBufferString(contents)))
+		_, _ = io.Copy(ioutil.Discard, in)
+		tr.Done(ctx, err)
+	}
+	transfer("hello", nil)
+	transfer("potato", errors.New("failed"))
+
+	remotes := s.Remotes()
+	require.Len(t, remotes, 2)
+	assert.Equal(t, int64(11), remotes["src"].Bytes)
+	assert.Equal(t, int64(1), remotes["src"].Transfers)
+	assert.Equal(t, int64(1), remotes["src"].Errors)
+	assert.Equal(t, int64(0), remotes["src"].Retries)
+	assert.Equal(t, int64(1), remotes["dst"].Retries)
+	assert.Nil(t, remotes["src"].Latency)
+	require.NotNil(t, remotes["dst"].Latency)
+	assert.Equal(t, int64(1), remotes["dst"].Latency.Count)
+	assert.InDelta(t, 0.02, remotes["dst"].Latency.Mean, 1e-9)
+	assert.Equal(t, remotes["src"].Bytes, remotes["dst"].Bytes)
+
+	out, err := s.RemoteStats()
+	require.NoError(t, err)
+	assert.Equal(t, remotes, out["remotes"])
+	assert.Contains(t, s.String(), " * dst: 11 B, ")
+
+	sum := NewStats(ctx)
+	sum.mergeRemotes(s)
+	sum.mergeRemotes(s)
+	assert.Equal(t, 2*remotes["src"].Bytes, sum.Remotes()["src"].Bytes)
+
+	s.ResetCounters()
+	assert.Len(t, s.Remotes(), 0)
+}
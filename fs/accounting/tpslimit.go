@@ -8,7 +8,8 @@ import (
 )
 
 var (
-	tpsBucket *rate.Limiter // for limiting number of http transactions per second
+	tpsBucket    *rate.Limiter // for limiting number of http transactions per second
+	deleteBucket *rate.Limiter // for limiting number of deletions per second
 )
 
 // StartLimitTPS starts the token bucket for transactions per second
@@ -35,3 +36,29 @@ func LimitTPS(ctx context.Context) {
 		}
 	}
 }
+
+// StartLimitDeletes starts the token bucket for deletions per second
+// limiting if necessary
+func StartLimitDeletes(ctx context.Context) {
+	ci := fs.GetConfig(ctx)
+	if ci.DeleteTPS > 0 {
+		deleteBurst := ci.DeleteBurst
+		if deleteBurst < 1 {
+			deleteBurst = 1
+		}
+		deleteBucket = rate.NewLimiter(rate.Limit(ci.DeleteTPS), deleteBurst)
+		fs.Infof(nil, "Starting deletion limiter: max %g deletions/s with burst %d", ci.DeleteTPS, deleteBurst)
+	}
+}
+
+// LimitDeletes limits the number of deletions per second if enabled.
+// It should be called once before each file is deleted or directory
+// removed.
+func LimitDeletes(ctx context.Context) {
+	if deleteBucket != nil {
+		tbErr := deleteBucket.Wait(ctx)
+		if tbErr != nil && tbErr != context.Canceled {
+			fs.Errorf(nil, "Deletion token bucket error: %v", tbErr)
+		}
+	}
+}
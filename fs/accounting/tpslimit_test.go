@@ -37,3 +37,32 @@ func TestLimitTPS(t *testing.T) {
 		timeTransactions(100, 900*time.Millisecond, 5000*time.Millisecond)
 	})
 }
+
+func TestLimitDeletes(t *testing.T) {
+	timeDeletes := func(n int, minTime, maxTime time.Duration) {
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			LimitDeletes(context.Background())
+		}
+		dt := time.Since(start)
+		assert.True(t, dt >= minTime && dt <= maxTime, "Expecting time between %v and %v, got %v", minTime, maxTime, dt)
+	}
+
+	t.Run("Off", func(t *testing.T) {
+		assert.Nil(t, deleteBucket)
+		timeDeletes(100, 0*time.Millisecond, 100*time.Millisecond)
+	})
+
+	t.Run("On", func(t *testing.T) {
+		ctx, ci := fs.AddConfig(context.Background())
+		ci.DeleteTPS = 100.0
+		ci.DeleteBurst = 10
+		StartLimitDeletes(ctx)
+		assert.NotNil(t, deleteBucket)
+		defer func() {
+			deleteBucket = nil
+		}()
+
+		timeDeletes(100, 800*time.Millisecond, 5000*time.Millisecond)
+	})
+}
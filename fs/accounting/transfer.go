@@ -60,16 +60,21 @@ type Transfer struct {
 	acc         *Account
 	err         error
 	completedAt time.Time
+	remotes     []string // names of the remotes transferred to or from
 }
 
 // newCheckingTransfer instantiates new checking of the object.
 func newCheckingTransfer(stats *StatsInfo, obj fs.Object) *Transfer {
-	return newTransferRemoteSize(stats, obj.Remote(), obj.Size(), true)
+	tr := newTransferRemoteSize(stats, obj.Remote(), obj.Size(), true)
+	tr.AddRemote(obj.Fs())
+	return tr
 }
 
 // newTransfer instantiates new transfer.
 func newTransfer(stats *StatsInfo, obj fs.Object) *Transfer {
-	return newTransferRemoteSize(stats, obj.Remote(), obj.Size(), false)
+	tr := newTransferRemoteSize(stats, obj.Remote(), obj.Size(), false)
+	tr.AddRemote(obj.Fs())
+	return tr
 }
 
 func newTransferRemoteSize(stats *StatsInfo, remote string, size int64, checking bool) *Transfer {
@@ -84,6 +89,23 @@ func newTransferRemoteSize(stats *StatsInfo, remote string, size int64, checking
 	return tr
 }
 
+// AddRemote records that the transfer is to or from f so it is
+// counted in the stats for f as well.
+func (tr *Transfer) AddRemote(f fs.Info) {
+	name := remoteName(f)
+	if name == "" {
+		return
+	}
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for _, remote := range tr.remotes {
+		if remote == name {
+			return
+		}
+	}
+	tr.remotes = append(tr.remotes, name)
+}
+
 // Done ends the transfer.
 // Must be called after transfer is finished to run proper cleanups.
 func (tr *Transfer) Done(ctx context.Context, err error) {
@@ -97,9 +119,11 @@ func (tr *Transfer) Done(ctx context.Context, err error) {
 
 	tr.mu.RLock()
 	acc := tr.acc
+	remotes := tr.remotes
 	tr.mu.RUnlock()
 
 	ci := fs.GetConfig(ctx)
+	n, _ := acc.progress()
 	if acc != nil {
 		// Close the file if it is still open
 		if err := acc.Close(); err != nil {
@@ -111,16 +135,26 @@ func (tr *Transfer) Done(ctx context.Context, err error) {
 		acc = nil
 	}
 
+	completedAt := time.Now()
 	tr.mu.Lock()
-	tr.completedAt = time.Now()
+	tr.completedAt = completedAt
 	tr.mu.Unlock()
 
+	if !tr.checking || err != nil {
+		for _, remote := range remotes {
+			tr.stats.doneRemote(remote, n, completedAt.Sub(tr.startedAt), err)
+		}
+	}
+
 	if tr.checking {
 		tr.stats.DoneChecking(tr.remote)
 	} else {
 		tr.stats.DoneTransferring(tr.remote, err == nil)
 	}
 	tr.stats.PruneTransfers()
+	if !tr.checking {
+		events.finish(tr)
+	}
 }
 
 // Reset allows to switch the Account to another transfer method.
@@ -74,12 +74,14 @@ type ConfigIn struct {
 // OAuth is a special value set by oauthutil.ConfigOAuth
 // Error is displayed to the user before asking a question
 // Result is passed to the next call to Config if Option/OAuth isn't set
+// AuthURL is set with the question asked by a ConfigOAuthProxy OAuth
 type ConfigOut struct {
-	State  string      // State to jump to after this
-	Option *Option     // Option to query user about
-	OAuth  interface{} `json:"-"` // Do OAuth if set
-	Error  string      // error to be displayed to the user
-	Result string      // if Option/OAuth not set then this is passed to the next state
+	State   string      // State to jump to after this
+	Option  *Option     // Option to query user about
+	OAuth   interface{} `json:"-"` // Do OAuth if set
+	Error   string      // error to be displayed to the user
+	Result  string      // if Option/OAuth not set then this is passed to the next state
+	AuthURL string      // if set the URL the user should visit to authorize rclone
 }
 
 // ConfigInputOptional asks the user for a string which may be empty
@@ -319,6 +321,23 @@ func isConfigOAuthOnly(ctx context.Context) bool {
 	return ctx.Value(configOAuthKey) != nil
 }
 
+type configOAuthProxyKeyType struct{}
+
+// OAuth proxy key for config
+var configOAuthProxyKey = configOAuthProxyKeyType{}
+
+// ConfigOAuthProxy marks the ctx so that OAuth is done by returning
+// the URL for the user to visit then accepting the code they were
+// redirected with, rather than running a local webserver.
+func ConfigOAuthProxy(ctx context.Context) context.Context {
+	return context.WithValue(ctx, configOAuthProxyKey, struct{}{})
+}
+
+// IsConfigOAuthProxy returns true if ctx is marked as ConfigOAuthProxy
+func IsConfigOAuthProxy(ctx context.Context) bool {
+	return ctx.Value(configOAuthProxyKey) != nil
+}
+
 // StatePop pops a state from the front of the config string
 // It returns the new state and the value popped
 func StatePop(state string) (newState string, value string) {
@@ -0,0 +1,63 @@
+// Limits on the concurrent calls to all the backends of a type
+
+package fs
+
+import (
+	"context"
+	"sync"
+)
+
+// backendLimit is a semaphore limiting the number of concurrent API
+// calls made by all the backends of a type
+type backendLimit chan struct{}
+
+// acquire waits for a call to be allowed
+func (l backendLimit) acquire() {
+	l <- struct{}{}
+}
+
+// release finishes a call acquired with acquire
+func (l backendLimit) release() {
+	<-l
+}
+
+var (
+	backendLimitsMu sync.Mutex
+	backendLimits   = map[string]backendLimit{}
+)
+
+type backendTypeContextKeyType struct{}
+
+// Context key for the type of backend being made
+var backendTypeContextKey = backendTypeContextKeyType{}
+
+// withBackendType returns a context for making a backend of type
+// backendType
+func withBackendType(ctx context.Context, backendType string) context.Context {
+	return context.WithValue(ctx, backendTypeContextKey, backendType)
+}
+
+// getBackendLimit returns the limit set with --backend-limit for the
+// type of backend being made with ctx or nil if there isn't one.
+//
+// All the backends of the type share the same limit which is made
+// when the first is.
+func getBackendLimit(ctx context.Context) backendLimit {
+	backendType, _ := ctx.Value(backendTypeContextKey).(string)
+	if backendType == "" {
+		return nil
+	}
+	n := GetConfig(ctx).BackendLimits[backendType]
+	if n <= 0 {
+		return nil
+	}
+	backendLimitsMu.Lock()
+	defer backendLimitsMu.Unlock()
+	l := backendLimits[backendType]
+	if l == nil {
+		Debugf(nil, "Limiting %s backends to %d concurrent calls", backendType, n)
+		l = make(backendLimit, n)
+		backendLimits[backendType] = l
+	}
+	return l
+}
@@ -0,0 +1,54 @@
+package fs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackendLimit(t *testing.T) {
+	ctx, ci := AddConfig(context.Background())
+	ci.BackendLimits = map[string]int{"limited": 2}
+
+	assert.Nil(t, getBackendLimit(ctx))
+	assert.Nil(t, getBackendLimit(withBackendType(ctx, "unlimited")))
+	limitedCtx := withBackendType(ctx, "limited")
+	l := getBackendLimit(limitedCtx)
+	assert.Equal(t, 2, cap(l))
+	assert.Equal(t, l, getBackendLimit(limitedCtx), "limit shared by all backends of the type")
+
+	// Check the calls of several pacers are limited together
+	var (
+		wg       sync.WaitGroup
+		running  int32
+		maxCalls int32
+	)
+	for i := 0; i < 3; i++ {
+		p := NewPacer(limitedCtx, pacer.NewDefault(pacer.MinSleep(0)))
+		for j := 0; j < 3; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = p.Call(func() (bool, error) {
+					n := atomic.AddInt32(&running, 1)
+					for {
+						old := atomic.LoadInt32(&maxCalls)
+						if n <= old || atomic.CompareAndSwapInt32(&maxCalls, old, n) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					atomic.AddInt32(&running, -1)
+					return false, nil
+				})
+			}()
+		}
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), maxCalls)
+}
@@ -54,6 +54,31 @@ func (bp *BwPair) IsSet() bool {
 	return bp.Tx > 0 || bp.Rx > 0
 }
 
+// BwClass is a bandwidth limit shared by the files matching a filter
+// glob
+type BwClass struct {
+	Glob      string
+	Bandwidth SizeSuffix
+}
+
+// ParseBwClass parses a BwClass from a string of the form GLOB=RATE,
+// e.g. "*.jpg=2M"
+func ParseBwClass(s string) (class BwClass, err error) {
+	equal := strings.LastIndex(s, "=")
+	if equal <= 0 {
+		return class, fmt.Errorf("failed to parse %q as glob=rate", s)
+	}
+	class.Glob = s[:equal]
+	err = class.Bandwidth.Set(s[equal+1:])
+	if err != nil {
+		return class, fmt.Errorf("bad rate in %q: %w", s, err)
+	}
+	if class.Bandwidth <= 0 {
+		return class, fmt.Errorf("rate must be positive in %q", s)
+	}
+	return class, nil
+}
+
 // BwTimeSlot represents a bandwidth configuration at a point in time.
 type BwTimeSlot struct {
 	DayOfTheWeek int
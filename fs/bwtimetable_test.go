@@ -563,3 +563,26 @@ func TestBwTimetableMarshalJSON(t *testing.T) {
 		assert.Equal(t, test.want, string(got))
 	}
 }
+
+func TestParseBwClass(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    BwClass
+		wantErr bool
+	}{
+		{in: "*.jpg=2M", want: BwClass{Glob: "*.jpg", Bandwidth: 2 * Mebi}},
+		{in: "{a=b}/**=10k", want: BwClass{Glob: "{a=b}/**", Bandwidth: 10 * Kibi}},
+		{in: "*.jpg", wantErr: true},
+		{in: "=2M", wantErr: true},
+		{in: "*.jpg=potato", wantErr: true},
+		{in: "*.jpg=off", wantErr: true},
+	} {
+		got, err := ParseBwClass(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+			assert.Equal(t, test.want, got, test.in)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package fs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompareMode describes how sync decides whether files differ
+type CompareMode byte
+
+// CompareMode constants
+const (
+	CompareModeDefault      CompareMode = iota // size, modtime and hash as set by the other flags
+	CompareModeSampledBytes                    // size then hash if available otherwise sampled byte ranges
+)
+
+var compareModeToString = []string{
+	CompareModeDefault:      "default",
+	CompareModeSampledBytes: "sampled-bytes",
+}
+
+// String turns a CompareMode into a string
+func (m CompareMode) String() string {
+	if m >= CompareMode(len(compareModeToString)) {
+		return fmt.Sprintf("CompareMode(%d)", m)
+	}
+	return compareModeToString[m]
+}
+
+// Set a CompareMode
+func (m *CompareMode) Set(s string) error {
+	for n, name := range compareModeToString {
+		if s != "" && name == strings.ToLower(s) {
+			*m = CompareMode(n)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown compare mode %q", s)
+}
+
+// Type of the value
+func (m *CompareMode) Type() string {
+	return "string"
+}
+
+// UnmarshalJSON makes sure the value can be parsed as a string or integer in JSON
+func (m *CompareMode) UnmarshalJSON(in []byte) error {
+	return UnmarshalJSONFlag(in, m, func(i int64) error {
+		if i < 0 || i >= int64(len(compareModeToString)) {
+			return fmt.Errorf("out of range compare mode %d", i)
+		}
+		*m = (CompareMode)(i)
+		return nil
+	})
+}
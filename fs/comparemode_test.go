@@ -0,0 +1,76 @@
+package fs
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check it satisfies the interface
+var _ flagger = (*CompareMode)(nil)
+
+func TestCompareModeString(t *testing.T) {
+	for _, test := range []struct {
+		in   CompareMode
+		want string
+	}{
+		{CompareModeDefault, "default"},
+		{CompareModeSampledBytes, "sampled-bytes"},
+		{99, "CompareMode(99)"},
+	} {
+		cm := test.in
+		got := cm.String()
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestCompareModeSet(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want CompareMode
+		err  bool
+	}{
+		{"default", CompareModeDefault, false},
+		{"sampled-bytes", CompareModeSampledBytes, false},
+		{"Sampled-Bytes", CompareModeSampledBytes, false},
+		{"Potato", 0, true},
+		{"", 0, true},
+	} {
+		cm := CompareMode(0)
+		err := cm.Set(test.in)
+		if test.err {
+			require.Error(t, err, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+		}
+		assert.Equal(t, test.want, cm, test.in)
+	}
+}
+
+func TestCompareModeUnmarshalJSON(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want CompareMode
+		err  bool
+	}{
+		{`"default"`, CompareModeDefault, false},
+		{`"sampled-bytes"`, CompareModeSampledBytes, false},
+		{`"Potato"`, 0, true},
+		{strconv.Itoa(int(CompareModeDefault)), CompareModeDefault, false},
+		{strconv.Itoa(int(CompareModeSampledBytes)), CompareModeSampledBytes, false},
+		{`99`, 0, true},
+		{`-99`, 0, true},
+	} {
+		var cm CompareMode
+		err := json.Unmarshal([]byte(test.in), &cm)
+		if test.err {
+			require.Error(t, err, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+		}
+		assert.Equal(t, test.want, cm, test.in)
+	}
+}
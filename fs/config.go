@@ -29,6 +29,12 @@ var (
 		return errors.New("no config file set handler")
 	}
 
+	// Check a section exists in the config file
+	//
+	// This is a function pointer to decouple the config
+	// implementation from the fs
+	ConfigFileHasSection = func(section string) bool { return false }
+
 	// CountError counts an error.  If any errors have been
 	// counted then rclone will exit with a non zero error code.
 	//
@@ -62,9 +68,12 @@ type ConfigInfo struct {
 	Timeout                 time.Duration // Data channel timeout
 	ExpectContinueTimeout   time.Duration
 	Dump                    DumpFlags
+	RecordHTTP              string
 	InsecureSkipVerify      bool // Skip server certificate verification
 	DeleteMode              DeleteMode
 	MaxDelete               int64
+	DeleteThreshold         int64  // don't delete anything if more than this many files would be deleted
+	DeleteConfirm           bool   // ask before deleting files at the end of a sync
 	TrackRenames            bool   // Track file renames.
 	TrackRenamesStrategy    string // Comma separated list of strategies used to track renames
 	LowLevelRetries         int
@@ -76,6 +85,8 @@ type ConfigInfo struct {
 	IgnoreCaseSync          bool
 	NoTraverse              bool
 	CheckFirst              bool
+	CheckAfter              bool // read back and hash each object after it is copied
+	CheckAfterRetries       int  // number of times to copy again if the check after fails
 	NoCheckDest             bool
 	NoUnicodeNormalization  bool
 	NoUpdateModTime         bool
@@ -120,6 +131,7 @@ type ConfigInfo struct {
 	MultiThreadCutoff       SizeSuffix
 	MultiThreadStreams      int
 	MultiThreadSet          bool   // whether MultiThreadStreams was set (set in fs/config/configflags)
+	MultiThreadTempFile     bool   // use a local temporary file for multi-thread downloads if the destination can't write at offsets
 	OrderBy                 string // instructions on how to order the transfer
 	UploadHeaders           []*HTTPOption
 	DownloadHeaders         []*HTTPOption
@@ -136,6 +148,19 @@ type ConfigInfo struct {
 	DisableHTTPKeepAlives   bool
 	Metadata                bool
 	ServerSideAcrossConfigs bool
+	DeltaTransfer           bool
+	DeltaBlockSize          SizeSuffix
+	ResumeUploads           bool
+	DeleteTPS               float64
+	DeleteBurst             int
+	BackendLimits           map[string]int
+	Proxy                   string
+	ListCheckers            int
+	BwLimitClasses          []BwClass
+	TrackRenamesSample      SizeSuffix
+	TransferPriorities      []TransferPriority
+	Compare                 CompareMode // how to decide whether files differ
+	CompareSampleSize       SizeSuffix  // size of each range read with --compare sampled-bytes
 }
 
 // NewConfig creates a new config with everything set to the default
@@ -155,6 +180,7 @@ func NewConfig() *ConfigInfo {
 	c.ExpectContinueTimeout = 1 * time.Second
 	c.DeleteMode = DeleteModeDefault
 	c.MaxDelete = -1
+	c.DeleteThreshold = -1
 	c.LowLevelRetries = 10
 	c.MaxDepth = -1
 	c.DataRateUnit = "bytes"
@@ -165,12 +191,15 @@ func NewConfig() *ConfigInfo {
 	c.StatsFileNameLength = 45
 	c.AskPassword = true
 	c.TPSLimitBurst = 1
+	c.DeleteBurst = 1
 	c.MaxTransfer = -1
 	c.MaxBacklog = 10000
 	// We do not want to set the default here. We use this variable being empty as part of the fall-through of options.
 	//	c.StatsOneLineDateFormat = "2006/01/02 15:04:05 - "
 	c.MultiThreadCutoff = SizeSuffix(250 * 1024 * 1024)
+	c.DeltaBlockSize = SizeSuffix(1024 * 1024)
 	c.MultiThreadStreams = 4
+	c.CompareSampleSize = SizeSuffix(64 * 1024)
 
 	c.TrackRenamesStrategy = "hash"
 	c.FsCacheExpireDuration = 300 * time.Second
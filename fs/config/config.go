@@ -117,6 +117,7 @@ func init() {
 	// Set the function pointers up in fs
 	fs.ConfigFileGet = FileGetFlag
 	fs.ConfigFileSet = SetValueAndSave
+	fs.ConfigFileHasSection = func(section string) bool { return LoadedData().HasSection(section) }
 	configPath = makeConfigPath()
 	cacheDir = makeCacheDir() // Has fallback to tempDir, so set that first
 	data = newDefaultStorage()
@@ -424,6 +425,9 @@ type UpdateRemoteOpt struct {
 	Result string `json:"result"`
 	// If set then edit existing values
 	Edit bool `json:"edit"`
+	// If set then do OAuth by returning the URL to visit and accepting
+	// the address redirected to - used with NonInteractive
+	OAuthProxy bool `json:"oauthProxy"`
 }
 
 func updateRemote(ctx context.Context, name string, keyValues rc.Params, opt UpdateRemoteOpt) (out *fs.ConfigOut, err error) {
@@ -439,6 +443,9 @@ func updateRemote(ctx context.Context, name string, keyValues rc.Params, opt Upd
 	if interactive && !opt.All {
 		ctx = suppressConfirm(ctx)
 	}
+	if opt.OAuthProxy {
+		ctx = fs.ConfigOAuthProxy(ctx)
+	}
 
 	fsType := FileGet(name, "type")
 	if fsType == "" {
@@ -630,7 +637,7 @@ var matchEnv = regexp.MustCompile(`^RCLONE_CONFIG_(.*?)_TYPE=.*$`)
 // FileSections returns the sections in the config file
 // including any defined by environment variables.
 func FileSections() []string {
-	sections := LoadedData().GetSectionList()
+	sections := remoteSections()
 	for _, item := range os.Environ() {
 		matches := matchEnv.FindStringSubmatch(item)
 		if len(matches) == 2 {
@@ -640,6 +647,19 @@ func FileSections() []string {
 	return sections
 }
 
+// remoteSections returns the names of the sections in the config
+// file which are remotes rather than option profiles
+func remoteSections() []string {
+	sections := LoadedData().GetSectionList()
+	remotes := make([]string, 0, len(sections))
+	for _, section := range sections {
+		if !strings.HasPrefix(section, fs.ProfilePrefix) {
+			remotes = append(remotes, section)
+		}
+	}
+	return remotes
+}
+
 // DumpRcRemote dumps the config for a single remote
 func DumpRcRemote(name string) (dump rc.Params) {
 	params := rc.Params{}
@@ -13,6 +13,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config"
 	"github.com/rclone/rclone/fs/config/flags"
+	"github.com/rclone/rclone/fs/filter"
 	fsLog "github.com/rclone/rclone/fs/log"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/sirupsen/logrus"
@@ -38,6 +39,9 @@ var (
 	downloadHeaders []string
 	headers         []string
 	metadataSet     []string
+	backendLimits   []string
+	bwLimitClasses  []string
+	priorities      []string
 )
 
 // AddFlags adds the non filing system specific flags to the command
@@ -54,6 +58,8 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.StringVarP(flagSet, &tempDir, "temp-dir", "", os.TempDir(), "Directory rclone will use for temporary files")
 	flags.BoolVarP(flagSet, &ci.CheckSum, "checksum", "c", ci.CheckSum, "Skip based on checksum (if available) & size, not mod-time & size")
 	flags.BoolVarP(flagSet, &ci.SizeOnly, "size-only", "", ci.SizeOnly, "Skip based on size only, not mod-time or checksum")
+	flags.FVarP(flagSet, &ci.Compare, "compare", "", "How to decide whether files differ default|sampled-bytes")
+	flags.FVarP(flagSet, &ci.CompareSampleSize, "compare-sample-size", "", "Size of each byte range read with --compare sampled-bytes")
 	flags.BoolVarP(flagSet, &ci.IgnoreTimes, "ignore-times", "I", ci.IgnoreTimes, "Don't skip files that match size and time - transfer all files")
 	flags.BoolVarP(flagSet, &ci.IgnoreExisting, "ignore-existing", "", ci.IgnoreExisting, "Skip all files that exist on destination")
 	flags.BoolVarP(flagSet, &ci.IgnoreErrors, "ignore-errors", "", ci.IgnoreErrors, "Delete even if there are I/O errors")
@@ -71,8 +77,11 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &deleteDuring, "delete-during", "", false, "When synchronizing, delete files during transfer")
 	flags.BoolVarP(flagSet, &deleteAfter, "delete-after", "", false, "When synchronizing, delete files on destination after transferring (default)")
 	flags.Int64VarP(flagSet, &ci.MaxDelete, "max-delete", "", -1, "When synchronizing, limit the number of deletes")
+	flags.Int64VarP(flagSet, &ci.DeleteThreshold, "delete-threshold", "", -1, "When synchronizing, don't delete anything if more than this many files would be deleted")
+	flags.BoolVarP(flagSet, &ci.DeleteConfirm, "delete-confirm", "", false, "When synchronizing, ask for confirmation before deleting files")
 	flags.BoolVarP(flagSet, &ci.TrackRenames, "track-renames", "", ci.TrackRenames, "When synchronizing, track file renames and do a server-side move if possible")
-	flags.StringVarP(flagSet, &ci.TrackRenamesStrategy, "track-renames-strategy", "", ci.TrackRenamesStrategy, "Strategies to use when synchronizing using track-renames hash|modtime|leaf")
+	flags.StringVarP(flagSet, &ci.TrackRenamesStrategy, "track-renames-strategy", "", ci.TrackRenamesStrategy, "Strategies to use when synchronizing using track-renames hash|modtime|leaf|similar")
+	flags.FVarP(flagSet, &ci.TrackRenamesSample, "track-renames-sample", "", "Compare this many bytes sampled from the files before renaming with --track-renames-strategy similar")
 	flags.IntVarP(flagSet, &ci.LowLevelRetries, "low-level-retries", "", ci.LowLevelRetries, "Number of low level retries to do")
 	flags.BoolVarP(flagSet, &ci.UpdateOlder, "update", "u", ci.UpdateOlder, "Skip files that are newer on the destination")
 	flags.BoolVarP(flagSet, &ci.UseServerModTime, "use-server-modtime", "", ci.UseServerModTime, "Use server modified time instead of object metadata")
@@ -83,6 +92,8 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &ci.IgnoreCaseSync, "ignore-case-sync", "", ci.IgnoreCaseSync, "Ignore case when synchronizing")
 	flags.BoolVarP(flagSet, &ci.NoTraverse, "no-traverse", "", ci.NoTraverse, "Don't traverse destination file system on copy")
 	flags.BoolVarP(flagSet, &ci.CheckFirst, "check-first", "", ci.CheckFirst, "Do all the checks before starting transfers")
+	flags.BoolVarP(flagSet, &ci.CheckAfter, "check-after", "", ci.CheckAfter, "Read back and hash each file after it is copied to check it")
+	flags.IntVarP(flagSet, &ci.CheckAfterRetries, "check-after-retries", "", ci.CheckAfterRetries, "Number of times to copy a file again if --check-after fails")
 	flags.BoolVarP(flagSet, &ci.NoCheckDest, "no-check-dest", "", ci.NoCheckDest, "Don't check the destination, copy regardless")
 	flags.BoolVarP(flagSet, &ci.NoUnicodeNormalization, "no-unicode-normalization", "", ci.NoUnicodeNormalization, "Don't normalize unicode characters in filenames")
 	flags.BoolVarP(flagSet, &ci.NoUpdateModTime, "no-update-modtime", "", ci.NoUpdateModTime, "Don't update destination mod-time if files identical")
@@ -94,6 +105,8 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &ci.UseListR, "fast-list", "", ci.UseListR, "Use recursive list if available; uses more memory but fewer transactions")
 	flags.Float64VarP(flagSet, &ci.TPSLimit, "tpslimit", "", ci.TPSLimit, "Limit HTTP transactions per second to this")
 	flags.IntVarP(flagSet, &ci.TPSLimitBurst, "tpslimit-burst", "", ci.TPSLimitBurst, "Max burst of transactions for --tpslimit")
+	flags.Float64VarP(flagSet, &ci.DeleteTPS, "delete-tps", "", ci.DeleteTPS, "Limit file deletions per second to this")
+	flags.IntVarP(flagSet, &ci.DeleteBurst, "delete-burst", "", ci.DeleteBurst, "Max burst of deletions for --delete-tps")
 	flags.StringVarP(flagSet, &bindAddr, "bind", "", "", "Local address to bind to for outgoing connections, IPv4, IPv6 or name")
 	flags.StringVarP(flagSet, &disableFeatures, "disable", "", "", "Disable a comma separated list of features (use --disable help to see a list)")
 	flags.StringVarP(flagSet, &ci.UserAgent, "user-agent", "", ci.UserAgent, "Set the user-agent to a specified string")
@@ -104,9 +117,11 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.FVarP(flagSet, &ci.StatsLogLevel, "stats-log-level", "", "Log level to show --stats output DEBUG|INFO|NOTICE|ERROR")
 	flags.FVarP(flagSet, &ci.BwLimit, "bwlimit", "", "Bandwidth limit in KiB/s, or use suffix B|K|M|G|T|P or a full timetable")
 	flags.FVarP(flagSet, &ci.BwLimitFile, "bwlimit-file", "", "Bandwidth limit per file in KiB/s, or use suffix B|K|M|G|T|P or a full timetable")
+	flags.StringArrayVarP(flagSet, &bwLimitClasses, "bwlimit-class", "", nil, "Bandwidth limit shared by the files matching a glob, e.g. *.jpg=2M (may be repeated)")
 	flags.FVarP(flagSet, &ci.BufferSize, "buffer-size", "", "In memory buffer size when reading files for each --transfer")
 	flags.FVarP(flagSet, &ci.StreamingUploadCutoff, "streaming-upload-cutoff", "", "Cutoff for switching to chunked upload if file size is unknown, upload starts after reaching cutoff or when file ends")
 	flags.FVarP(flagSet, &ci.Dump, "dump", "", "List of items to dump from: "+fs.DumpFlagsList)
+	flags.StringVarP(flagSet, &ci.RecordHTTP, "record-http", "", ci.RecordHTTP, "Record HTTP requests and responses with sensitive info removed to JSON files in this directory")
 	flags.FVarP(flagSet, &ci.MaxTransfer, "max-transfer", "", "Maximum size of data to transfer")
 	flags.DurationVarP(flagSet, &ci.MaxDuration, "max-duration", "", 0, "Maximum duration rclone will transfer data for")
 	flags.FVarP(flagSet, &ci.CutoffMode, "cutoff-mode", "", "Mode to stop transfers when reaching the max transfer limit HARD|SOFT|CAUTIOUS")
@@ -125,12 +140,18 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.StringVarP(flagSet, &ci.ClientKey, "client-key", "", ci.ClientKey, "Client SSL private key (PEM) for mutual TLS auth")
 	flags.FVarP(flagSet, &ci.MultiThreadCutoff, "multi-thread-cutoff", "", "Use multi-thread downloads for files above this size")
 	flags.IntVarP(flagSet, &ci.MultiThreadStreams, "multi-thread-streams", "", ci.MultiThreadStreams, "Max number of streams to use for multi-thread downloads")
+	flags.BoolVarP(flagSet, &ci.MultiThreadTempFile, "multi-thread-temp-file", "", ci.MultiThreadTempFile, "Use multi-thread downloads via a local temporary file for any destination")
+	flags.BoolVarP(flagSet, &ci.DeltaTransfer, "delta-transfer", "", ci.DeltaTransfer, "Only transfer the changed blocks of files which can be updated in place")
+	flags.FVarP(flagSet, &ci.DeltaBlockSize, "delta-block-size", "", "Block size for --delta-transfer")
+	flags.BoolVarP(flagSet, &ci.ResumeUploads, "resume-uploads", "", ci.ResumeUploads, "Resume interrupted uploads after rclone is restarted if the backend supports it")
 	flags.BoolVarP(flagSet, &ci.UseJSONLog, "use-json-log", "", ci.UseJSONLog, "Use json log format")
 	flags.StringVarP(flagSet, &ci.OrderBy, "order-by", "", ci.OrderBy, "Instructions on how to order the transfers, e.g. 'size,descending'")
+	flags.StringArrayVarP(flagSet, &priorities, "transfer-priority", "", nil, "Transfer the files matching a glob before lower priority ones, e.g. *.conf=10 (may be repeated)")
 	flags.StringArrayVarP(flagSet, &uploadHeaders, "header-upload", "", nil, "Set HTTP header for upload transactions")
 	flags.StringArrayVarP(flagSet, &downloadHeaders, "header-download", "", nil, "Set HTTP header for download transactions")
 	flags.StringArrayVarP(flagSet, &headers, "header", "", nil, "Set HTTP header for all transactions")
 	flags.StringArrayVarP(flagSet, &metadataSet, "metadata-set", "", nil, "Add metadata key=value when uploading")
+	flags.StringArrayVarP(flagSet, &backendLimits, "backend-limit", "", nil, "Limit the concurrent calls to all backends of a type, e.g. s3=4 (may be repeated)")
 	flags.BoolVarP(flagSet, &ci.RefreshTimes, "refresh-times", "", ci.RefreshTimes, "Refresh the modtime of remote files")
 	flags.BoolVarP(flagSet, &ci.NoConsole, "no-console", "", ci.NoConsole, "Hide console window (supported on Windows only)")
 	flags.StringVarP(flagSet, &dscp, "dscp", "", "", "Set DSCP value to connections, value or name, e.g. CS1, LE, DF, AF21")
@@ -139,6 +160,8 @@ func AddFlags(ci *fs.ConfigInfo, flagSet *pflag.FlagSet) {
 	flags.BoolVarP(flagSet, &ci.DisableHTTP2, "disable-http2", "", ci.DisableHTTP2, "Disable HTTP/2 in the global transport")
 	flags.BoolVarP(flagSet, &ci.HumanReadable, "human-readable", "", ci.HumanReadable, "Print numbers in a human-readable format, sizes with suffix Ki|Mi|Gi|Ti|Pi")
 	flags.DurationVarP(flagSet, &ci.KvLockTime, "kv-lock-time", "", ci.KvLockTime, "Maximum time to keep key-value database locked by process")
+	flags.StringVarP(flagSet, &ci.Proxy, "proxy", "", ci.Proxy, "Use this HTTP or SOCKS5 proxy instead of the one set in the environment")
+	flags.IntVarP(flagSet, &ci.ListCheckers, "list-checkers", "", ci.ListCheckers, "Number of directories to list in parallel when syncing, 0 for --checkers")
 	flags.BoolVarP(flagSet, &ci.DisableHTTPKeepAlives, "disable-http-keep-alives", "", ci.DisableHTTPKeepAlives, "Disable HTTP keep-alives and use each connection once.")
 	flags.BoolVarP(flagSet, &ci.Metadata, "metadata", "M", ci.Metadata, "If set, preserve metadata when copying objects")
 	flags.BoolVarP(flagSet, &ci.ServerSideAcrossConfigs, "server-side-across-configs", "", ci.ServerSideAcrossConfigs, "Allow server-side operations (e.g. copy) to work across different configs")
@@ -289,6 +312,46 @@ func SetFlags(ci *fs.ConfigInfo) {
 		}
 		fs.Debugf(nil, "MetadataUpload %v", ci.MetadataSet)
 	}
+	if len(backendLimits) != 0 {
+		ci.BackendLimits = make(map[string]int, len(backendLimits))
+		for _, kv := range backendLimits {
+			equal := strings.IndexRune(kv, '=')
+			if equal < 0 {
+				log.Fatalf("--backend-limit: Failed to parse '%s' as type=limit.", kv)
+			}
+			limit, err := strconv.Atoi(kv[equal+1:])
+			if err != nil || limit < 1 {
+				log.Fatalf("--backend-limit: Invalid limit in '%s'.", kv)
+			}
+			ci.BackendLimits[kv[:equal]] = limit
+		}
+	}
+	if len(bwLimitClasses) != 0 {
+		ci.BwLimitClasses = make([]fs.BwClass, 0, len(bwLimitClasses))
+		for _, s := range bwLimitClasses {
+			class, err := fs.ParseBwClass(s)
+			if err != nil {
+				log.Fatalf("--bwlimit-class: %v", err)
+			}
+			if _, err = filter.GlobToRegexp(class.Glob, false); err != nil {
+				log.Fatalf("--bwlimit-class: bad glob in %q: %v", s, err)
+			}
+			ci.BwLimitClasses = append(ci.BwLimitClasses, class)
+		}
+	}
+	if len(priorities) != 0 {
+		ci.TransferPriorities = make([]fs.TransferPriority, 0, len(priorities))
+		for _, s := range priorities {
+			tp, err := fs.ParseTransferPriority(s)
+			if err != nil {
+				log.Fatalf("--transfer-priority: %v", err)
+			}
+			if _, err = filter.GlobToRegexp(tp.Glob, false); err != nil {
+				log.Fatalf("--transfer-priority: bad glob in %q: %v", s, err)
+			}
+			ci.TransferPriorities = append(ci.TransferPriorities, tp)
+		}
+	}
 	if len(dscp) != 0 {
 		if value, ok := parseDSCP(dscp); ok {
 			ci.TrafficClass = value << 2
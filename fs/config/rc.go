@@ -72,7 +72,7 @@ See the [listremotes](/commands/rclone_listremotes/) command for more informatio
 
 // Return the a list of remotes in the config file
 func rcListRemotes(ctx context.Context, in rc.Params) (out rc.Params, err error) {
-	remotes := LoadedData().GetSectionList()
+	remotes := remoteSections()
 	out = rc.Params{
 		"remotes": remotes,
 	}
@@ -118,6 +118,7 @@ func init() {
     - all - ask all the config questions not just the post config ones
     - state - state to restart with - used with continue
     - result - result to restart with - used with continue
+    - oauthProxy - do OAuth by returning the URL to visit, used with nonInteractive
 `
 		}
 		rc.Add(rc.Call{
@@ -0,0 +1,80 @@
+// AWS and GCP secrets manager providers
+
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"google.golang.org/api/secretmanager/v1"
+)
+
+// awsSecretsManager reads secrets from AWS Secrets Manager
+//
+// ref is the name or ARN of the secret with an optional field to read
+// from a JSON secret, eg "rclone/s3#secret_access_key".
+//
+// The credentials and region are read from the environment and
+// shared config files as the aws command does.
+type awsSecretsManager struct{}
+
+// GetSecret reads the secret ref from AWS Secrets Manager
+func (awsSecretsManager) GetSecret(ctx context.Context, ref string) (string, error) {
+	name, field := splitField(ref)
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to make AWS session: %w", err)
+	}
+	out, err := secretsmanager.New(sess).GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	secret := string(out.SecretBinary)
+	if out.SecretString != nil {
+		secret = *out.SecretString
+	}
+	return jsonField(secret, field)
+}
+
+// gcpSecretManager reads secrets from Google Cloud Secret Manager
+//
+// ref is the resource name of the secret with an optional version
+// and field to read from a JSON secret, eg
+// "projects/my-project/secrets/rclone#token". The latest version is
+// used if none is given.
+//
+// Application Default Credentials are used to authenticate.
+type gcpSecretManager struct{}
+
+// GetSecret reads the secret ref from Google Cloud Secret Manager
+func (gcpSecretManager) GetSecret(ctx context.Context, ref string) (string, error) {
+	name, field := splitField(ref)
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	svc, err := secretmanager.NewService(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to make secret manager client: %w", err)
+	}
+	resp, err := svc.Projects.Secrets.Versions.Access(name).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	if resp.Payload == nil {
+		return "", fmt.Errorf("secret %q has no payload", name)
+	}
+	secret, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+	return jsonField(string(secret), field)
+}
@@ -0,0 +1,88 @@
+// Package secrets implements the providers which fetch config values
+// of the form "secret:provider:ref" from outside the config file.
+//
+// Import it for its side effects of registering the providers.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+)
+
+func init() {
+	fs.RegisterSecretProvider("command", command{})
+	fs.RegisterSecretProvider("vault", vault{})
+	fs.RegisterSecretProvider("aws", awsSecretsManager{})
+	fs.RegisterSecretProvider("gcp", gcpSecretManager{})
+}
+
+// splitField splits ref into the secret name and the optional field
+// after the last "#"
+func splitField(ref string) (name, field string) {
+	if i := strings.LastIndexByte(ref, '#'); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// jsonField returns field from the JSON object in secret, or secret
+// itself if field is empty
+func jsonField(secret, field string) (string, error) {
+	if field == "" {
+		return secret, nil
+	}
+	var values map[string]interface{}
+	err := json.Unmarshal([]byte(secret), &values)
+	if err != nil {
+		return "", fmt.Errorf("can't read field %q as secret isn't a JSON object: %w", field, err)
+	}
+	return getField(values, field)
+}
+
+// getField returns field from values as a string
+func getField(values map[string]interface{}, field string) (string, error) {
+	value, ok := values[field]
+	if !ok {
+		return "", fmt.Errorf("field %q not found in secret", field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// command runs a command and returns its output as the secret
+//
+// ref is the command line, eg "pass show rclone/drive"
+//
+// Note that this runs whatever command is in the config file, so
+// anyone who can write the config file can run commands as the user
+// running rclone.
+type command struct{}
+
+// GetSecret runs the command in ref returning its output
+func (command) GetSecret(ctx context.Context, ref string) (string, error) {
+	var args fs.SpaceSepList
+	if err := args.Set(ref); err != nil {
+		return "", fmt.Errorf("failed to parse command: %w", err)
+	}
+	if len(args) == 0 {
+		return "", errors.New("no command supplied")
+	}
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("command %q failed: %w", args[0], err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
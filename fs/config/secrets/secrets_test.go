@@ -0,0 +1,101 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONField(t *testing.T) {
+	got, err := jsonField("potato", "")
+	require.NoError(t, err)
+	assert.Equal(t, "potato", got)
+
+	got, err = jsonField(`{"user":"bob","port":22}`, "user")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", got)
+
+	got, err = jsonField(`{"user":"bob","port":22}`, "port")
+	require.NoError(t, err)
+	assert.Equal(t, "22", got)
+
+	_, err = jsonField(`{"user":"bob"}`, "pass")
+	assert.EqualError(t, err, `field "pass" not found in secret`)
+
+	_, err = jsonField("potato", "pass")
+	assert.Error(t, err)
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no echo command on windows")
+	}
+	ctx := context.Background()
+	got, err := command{}.GetSecret(ctx, `echo "hello world"`)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", got)
+
+	_, err = command{}.GetSecret(ctx, "")
+	assert.Error(t, err)
+
+	_, err = command{}.GetSecret(ctx, "false")
+	assert.Error(t, err)
+}
+
+func TestVault(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv1/rclone":
+			_, _ = fmt.Fprint(w, `{"data":{"pass":"kv1-pass"}}`)
+		case "/v1/secret/data/rclone":
+			_, _ = fmt.Fprint(w, `{"data":{"data":{"pass":"kv2-pass","user":"bob"},"metadata":{"version":1}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"errors":[]}`)
+		}
+	}))
+	defer server.Close()
+
+	for _, env := range [][2]string{{"VAULT_ADDR", server.URL}, {"VAULT_TOKEN", "token"}} {
+		old, found := os.LookupEnv(env[0])
+		require.NoError(t, os.Setenv(env[0], env[1]))
+		defer func(key string) {
+			if found {
+				_ = os.Setenv(key, old)
+			} else {
+				_ = os.Unsetenv(key)
+			}
+		}(env[0])
+	}
+
+	got, err := vault{}.GetSecret(ctx, "kv1/rclone")
+	require.NoError(t, err)
+	assert.Equal(t, "kv1-pass", got)
+
+	got, err = vault{}.GetSecret(ctx, "secret/data/rclone#user")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", got)
+
+	_, err = vault{}.GetSecret(ctx, "secret/data/rclone")
+	assert.EqualError(t, err, "secret has 2 fields so add #field to choose one")
+
+	_, err = vault{}.GetSecret(ctx, "missing")
+	assert.Error(t, err)
+
+	require.NoError(t, os.Setenv("VAULT_TOKEN", "wrong"))
+	_, err = vault{}.GetSecret(ctx, "kv1/rclone")
+	assert.Contains(t, err.Error(), "permission denied")
+}
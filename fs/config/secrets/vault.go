@@ -0,0 +1,97 @@
+// HashiCorp Vault secrets provider
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rclone/rclone/fs/fshttp"
+)
+
+// vault reads secrets from HashiCorp Vault
+//
+// ref is the path of the secret and the field to read, eg
+// "secret/data/rclone#drive_token". The field may be left off if the
+// secret only has one.
+//
+// The server is read from $VAULT_ADDR, the token from $VAULT_TOKEN or
+// ~/.vault-token and the namespace from $VAULT_NAMESPACE as the vault
+// command does.
+type vault struct{}
+
+// vaultToken returns the token to authenticate to vault with
+func vaultToken() (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("no $VAULT_TOKEN: %w", err)
+	}
+	token, err := ioutil.ReadFile(filepath.Join(home, ".vault-token"))
+	if err != nil {
+		return "", fmt.Errorf("no $VAULT_TOKEN: %w", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// GetSecret reads the secret at ref from vault
+func (vault) GetSecret(ctx context.Context, ref string) (string, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", errors.New("$VAULT_ADDR not set")
+	}
+	token, err := vaultToken()
+	if err != nil {
+		return "", err
+	}
+	path, field := splitField(ref)
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(addr, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := fshttp.NewClient(ctx).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	values := result.Data
+	// Version 2 of the key value store nests the secret
+	if inner, ok := values["data"].(map[string]interface{}); ok {
+		if _, ok := values["metadata"]; ok {
+			values = inner
+		}
+	}
+	if field == "" {
+		if len(values) != 1 {
+			return "", fmt.Errorf("secret has %d fields so add #field to choose one", len(values))
+		}
+		for field = range values {
+		}
+	}
+	return getField(values, field)
+}
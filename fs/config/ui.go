@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"sort"
@@ -47,33 +48,39 @@ func ReadNonEmptyLine(prompt string) string {
 // CommandDefault - choose one.  If return is pressed then it will
 // chose the defaultIndex if it is >= 0
 func CommandDefault(commands []string, defaultIndex int) byte {
+	return CommandDefaultTo(os.Stdout, commands, defaultIndex)
+}
+
+// CommandDefaultTo is like CommandDefault but writes the choices and
+// prompts to out.
+func CommandDefaultTo(out io.Writer, commands []string, defaultIndex int) byte {
 	opts := []string{}
 	for i, text := range commands {
 		def := ""
 		if i == defaultIndex {
 			def = " (default)"
 		}
-		fmt.Printf("%c) %s%s\n", text[0], text[1:], def)
+		_, _ = fmt.Fprintf(out, "%c) %s%s\n", text[0], text[1:], def)
 		opts = append(opts, text[:1])
 	}
 	optString := strings.Join(opts, "")
 	optHelp := strings.Join(opts, "/")
 	for {
-		fmt.Printf("%s> ", optHelp)
+		_, _ = fmt.Fprintf(out, "%s> ", optHelp)
 		result := strings.ToLower(ReadLine())
 		if len(result) == 0 {
 			if defaultIndex >= 0 {
 				return optString[defaultIndex]
 			}
-			fmt.Printf("This value is required and it has no default.\n")
+			_, _ = fmt.Fprintf(out, "This value is required and it has no default.\n")
 		} else if len(result) == 1 {
 			i := strings.Index(optString, string(result[0]))
 			if i >= 0 {
 				return result[0]
 			}
-			fmt.Printf("This value must be one of the following characters: %s.\n", strings.Join(opts, ", "))
+			_, _ = fmt.Fprintf(out, "This value must be one of the following characters: %s.\n", strings.Join(opts, ", "))
 		} else {
-			fmt.Printf("This value must be a single character, one of the following: %s.\n", strings.Join(opts, ", "))
+			_, _ = fmt.Fprintf(out, "This value must be a single character, one of the following: %s.\n", strings.Join(opts, ", "))
 		}
 	}
 }
@@ -87,11 +94,16 @@ func Command(commands []string) byte {
 //
 // If the user presses enter then the Default will be used
 func Confirm(Default bool) bool {
+	return ConfirmTo(os.Stdout, Default)
+}
+
+// ConfirmTo is like Confirm but writes the prompts to out.
+func ConfirmTo(out io.Writer, Default bool) bool {
 	defaultIndex := 0
 	if !Default {
 		defaultIndex = 1
 	}
-	return CommandDefault([]string{"yYes", "nNo"}, defaultIndex) == 'y'
+	return CommandDefaultTo(out, []string{"yYes", "nNo"}, defaultIndex) == 'y'
 }
 
 // Choose one of the choices, or default, or type a new string if newOk is set
@@ -269,7 +281,7 @@ func ChooseNumber(what string, min, max int) int {
 
 // ShowRemotes shows an overview of the config file
 func ShowRemotes() {
-	remotes := LoadedData().GetSectionList()
+	remotes := remoteSections()
 	if len(remotes) == 0 {
 		return
 	}
@@ -283,7 +295,7 @@ func ShowRemotes() {
 
 // ChooseRemote chooses a remote name
 func ChooseRemote() string {
-	remotes := LoadedData().GetSectionList()
+	remotes := remoteSections()
 	sort.Strings(remotes)
 	fmt.Println("Select remote.")
 	return Choose("remote", "value", remotes, nil, "", true, false)
@@ -634,7 +646,7 @@ func ShowConfig() {
 // EditConfig edits the config file interactively
 func EditConfig(ctx context.Context) (err error) {
 	for {
-		haveRemotes := len(LoadedData().GetSectionList()) != 0
+		haveRemotes := len(remoteSections()) != 0
 		what := []string{"eEdit existing remote", "nNew remote", "dDelete remote", "rRename remote", "cCopy remote", "sSet configuration password", "qQuit config"}
 		if haveRemotes {
 			fmt.Printf("Current remotes:\n\n")
@@ -119,8 +119,13 @@ func ConfigMap(fsInfo *RegInfo, configName string, connectionStringConfig config
 		config.AddGetter(optionEnvVars{fsInfo: fsInfo}, configmap.PriorityNormal)
 	}
 
-	// config file
-	config.AddGetter(getConfigFile(configName), configmap.PriorityConfig)
+	// config file, fetching secrets from providers
+	config.AddGetter(getConfigFileSecrets{getConfigFile(configName), fsInfo}, configmap.PriorityConfig)
+
+	// profile selected by any of the above
+	if name, ok := profileName(fsInfo, config); ok {
+		config.AddGetter(getProfile{getConfigFileSecrets{getConfigFile(ProfilePrefix + name), fsInfo}}, configmap.PriorityNormal)
+	}
 
 	// default values
 	if fsInfo != nil {
@@ -128,6 +133,6 @@ func ConfigMap(fsInfo *RegInfo, configName string, connectionStringConfig config
 	}
 
 	// Set Config
-	config.AddSetter(setConfigFile(configName))
+	config.AddSetter(setConfigFileSecrets{setConfigFile(configName), fsInfo})
 	return config
 }
@@ -0,0 +1,289 @@
+// Per remote counters of API calls
+
+package fs
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counters counts the API calls a remote makes and the problems it
+// has making them, keeping the recent errors and a history of the
+// last hour.
+//
+// Backends find the Counters for their remote with GetCounters. All
+// the methods may be called on a nil *Counters in which case they do
+// nothing.
+type Counters struct {
+	calls          int64 // accessed with atomic
+	retries        int64 // accessed with atomic
+	throttles      int64 // accessed with atomic
+	tokenRefreshes int64 // accessed with atomic
+	latencyTotal   int64 // total latency of the calls in ns - accessed with atomic
+
+	latency [len(latencyBuckets) + 1]int64 // calls in each latency bucket - accessed with atomic
+
+	mu          sync.Mutex
+	tokenExpiry time.Time
+	pacerSleep  time.Duration
+	errors      []CountersError                 // most recent last
+	history     [countersHistory]CountersSample // by minute
+}
+
+const (
+	countersMaxErrors = 10 // number of recent errors to keep
+	countersHistory   = 60 // minutes of history to keep
+)
+
+// CountersError is an error from an API call
+type CountersError struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// CountersSample counts the API calls made in a minute
+type CountersSample struct {
+	Time    time.Time `json:"time"` // start of the minute
+	Calls   int64     `json:"calls"`
+	Retries int64     `json:"retries"`
+	Errors  int64     `json:"errors"`
+}
+
+// CountersStats is a snapshot of Counters
+type CountersStats struct {
+	Calls          int64            `json:"calls"`
+	Retries        int64            `json:"retries"`
+	Throttles      int64            `json:"throttles"`
+	TokenRefreshes int64            `json:"tokenRefreshes"`
+	TokenExpiry    *time.Time       `json:"tokenExpiry,omitempty"`
+	Latency        *LatencyStats    `json:"latency,omitempty"`
+	PacerSleep     float64          `json:"pacerSleep"` // current sleep between API calls in seconds
+	Errors         []CountersError  `json:"errors,omitempty"`
+	History        []CountersSample `json:"history,omitempty"`
+}
+
+// latencyBuckets are the upper bounds of the buckets of the latency
+// histogram. Calls which take longer go in an extra final bucket.
+var latencyBuckets = [...]time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencyStats is a histogram of the latency of the API calls
+type LatencyStats struct {
+	Count   int64           `json:"count"`   // number of calls
+	Mean    float64         `json:"mean"`    // mean latency in seconds
+	Buckets []LatencyBucket `json:"buckets"` // calls by latency
+}
+
+// LatencyBucket is the number of calls which took up to Le seconds
+// and longer than the Le of the bucket before. Le is 0 for the last
+// bucket which has the calls longer than all the others.
+type LatencyBucket struct {
+	Le    float64 `json:"le,omitempty"`
+	Count int64   `json:"count"`
+}
+
+var (
+	countersMu sync.Mutex
+	counters   = map[string]*Counters{}
+)
+
+// GetCounters returns the Counters for the remote called name,
+// creating them if necessary.
+func GetCounters(name string) *Counters {
+	countersMu.Lock()
+	defer countersMu.Unlock()
+	c := counters[name]
+	if c == nil {
+		c = new(Counters)
+		counters[name] = c
+	}
+	return c
+}
+
+// LookupCounters returns the Counters for the remote called name or
+// nil if there aren't any.
+func LookupCounters(name string) *Counters {
+	countersMu.Lock()
+	defer countersMu.Unlock()
+	return counters[name]
+}
+
+// CountersNames returns the names of the remotes with Counters in
+// sorted order
+func CountersNames() (names []string) {
+	countersMu.Lock()
+	for name := range counters {
+		names = append(names, name)
+	}
+	countersMu.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// ResetCounters removes all the Counters
+func ResetCounters() {
+	countersMu.Lock()
+	counters = map[string]*Counters{}
+	countersMu.Unlock()
+}
+
+// sample returns the history sample for now - call with mu held
+func (c *Counters) sample(now time.Time) *CountersSample {
+	minute := now.Truncate(time.Minute)
+	sample := &c.history[minute.Unix()/60%countersHistory]
+	if !sample.Time.Equal(minute) {
+		*sample = CountersSample{Time: minute}
+	}
+	return sample
+}
+
+// Call records an API call
+func (c *Counters) Call() {
+	if c != nil {
+		atomic.AddInt64(&c.calls, 1)
+		c.mu.Lock()
+		c.sample(time.Now()).Calls++
+		c.mu.Unlock()
+	}
+}
+
+// Retry records an API call which is going to be retried
+func (c *Counters) Retry() {
+	if c != nil {
+		atomic.AddInt64(&c.retries, 1)
+		c.mu.Lock()
+		c.sample(time.Now()).Retries++
+		c.mu.Unlock()
+	}
+}
+
+// Error records an error returned from an API call
+func (c *Counters) Error(err error) {
+	if c == nil || err == nil {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sample(now).Errors++
+	if len(c.errors) >= countersMaxErrors {
+		copy(c.errors, c.errors[1:])
+		c.errors = c.errors[:countersMaxErrors-1]
+	}
+	c.errors = append(c.errors, CountersError{Time: now, Error: err.Error()})
+}
+
+// SetPacerSleep records the time the pacer is sleeping between calls
+func (c *Counters) SetPacerSleep(sleep time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.pacerSleep = sleep
+	c.mu.Unlock()
+}
+
+// Throttle records the remote asking for API calls to slow down
+func (c *Counters) Throttle() {
+	if c != nil {
+		atomic.AddInt64(&c.throttles, 1)
+	}
+}
+
+// Latency records an API call which took d
+func (c *Counters) Latency(d time.Duration) {
+	if c == nil {
+		return
+	}
+	i := sort.Search(len(latencyBuckets), func(i int) bool {
+		return d <= latencyBuckets[i]
+	})
+	atomic.AddInt64(&c.latency[i], 1)
+	atomic.AddInt64(&c.latencyTotal, int64(d))
+}
+
+// latencyStats returns a snapshot of the latency histogram or nil if
+// no latencies have been recorded
+func (c *Counters) latencyStats() *LatencyStats {
+	stats := &LatencyStats{
+		Buckets: make([]LatencyBucket, len(c.latency)),
+	}
+	for i := range c.latency {
+		if i < len(latencyBuckets) {
+			stats.Buckets[i].Le = latencyBuckets[i].Seconds()
+		}
+		stats.Buckets[i].Count = atomic.LoadInt64(&c.latency[i])
+		stats.Count += stats.Buckets[i].Count
+	}
+	if stats.Count == 0 {
+		return nil
+	}
+	stats.Mean = time.Duration(atomic.LoadInt64(&c.latencyTotal)).Seconds() / float64(stats.Count)
+	return stats
+}
+
+// TokenRefresh records a new authentication token which expires at
+// expiry, which may be zero if it doesn't expire
+func (c *Counters) TokenRefresh(expiry time.Time) {
+	if c == nil {
+		return
+	}
+	atomic.AddInt64(&c.tokenRefreshes, 1)
+	c.SetTokenExpiry(expiry)
+}
+
+// SetTokenExpiry records when the authentication token in use
+// expires, which may be zero if it doesn't expire
+func (c *Counters) SetTokenExpiry(expiry time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.tokenExpiry = expiry
+	c.mu.Unlock()
+}
+
+// Stats returns a snapshot of the Counters
+func (c *Counters) Stats() (stats CountersStats) {
+	if c == nil {
+		return stats
+	}
+	stats.Calls = atomic.LoadInt64(&c.calls)
+	stats.Retries = atomic.LoadInt64(&c.retries)
+	stats.Throttles = atomic.LoadInt64(&c.throttles)
+	stats.TokenRefreshes = atomic.LoadInt64(&c.tokenRefreshes)
+	stats.Latency = c.latencyStats()
+	c.mu.Lock()
+	if !c.tokenExpiry.IsZero() {
+		expiry := c.tokenExpiry
+		stats.TokenExpiry = &expiry
+	}
+	stats.PacerSleep = c.pacerSleep.Seconds()
+	if len(c.errors) > 0 {
+		stats.Errors = append([]CountersError(nil), c.errors...)
+	}
+	// Return the history of the last countersHistory minutes, oldest first
+	cutoff := time.Now().Truncate(time.Minute).Add(-(countersHistory - 1) * time.Minute)
+	for _, sample := range c.history {
+		if !sample.Time.Before(cutoff) {
+			stats.History = append(stats.History, sample)
+		}
+	}
+	c.mu.Unlock()
+	sort.Slice(stats.History, func(i, j int) bool {
+		return stats.History[i].Time.Before(stats.History[j].Time)
+	})
+	return stats
+}
@@ -0,0 +1,141 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounters(t *testing.T) {
+	ResetCounters()
+	defer ResetCounters()
+
+	c := GetCounters("remote")
+	assert.Equal(t, c, GetCounters("remote"))
+	assert.Equal(t, []string{"remote"}, CountersNames())
+	assert.Equal(t, CountersStats{}, c.Stats())
+
+	c.Call()
+	c.Call()
+	c.Retry()
+	c.Throttle()
+	expiry := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	c.TokenRefresh(expiry)
+	c.SetPacerSleep(500 * time.Millisecond)
+	stats := c.Stats()
+	history := stats.History
+	stats.History = nil
+	assert.Equal(t, CountersStats{
+		Calls:          2,
+		Retries:        1,
+		Throttles:      1,
+		TokenRefreshes: 1,
+		TokenExpiry:    &expiry,
+		PacerSleep:     0.5,
+	}, stats)
+	require.Len(t, history, 1)
+	assert.Equal(t, int64(2), history[0].Calls)
+	assert.Equal(t, int64(1), history[0].Retries)
+
+	c.SetTokenExpiry(time.Time{})
+	assert.Nil(t, c.Stats().TokenExpiry)
+
+	// Check a nil Counters does nothing
+	var nilCounters *Counters
+	nilCounters.Call()
+	nilCounters.Retry()
+	nilCounters.Throttle()
+	nilCounters.TokenRefresh(expiry)
+	nilCounters.Latency(time.Second)
+	nilCounters.Error(errors.New("potato"))
+	nilCounters.SetPacerSleep(time.Second)
+	assert.Equal(t, CountersStats{}, nilCounters.Stats())
+}
+
+func TestCountersErrors(t *testing.T) {
+	c := new(Counters)
+	c.Error(nil)
+	assert.Nil(t, c.Stats().Errors)
+	for i := 0; i < countersMaxErrors+3; i++ {
+		c.Error(fmt.Errorf("error %d", i))
+	}
+	stats := c.Stats()
+	require.Len(t, stats.Errors, countersMaxErrors)
+	assert.Equal(t, "error 3", stats.Errors[0].Error)
+	assert.Equal(t, fmt.Sprintf("error %d", countersMaxErrors+2), stats.Errors[countersMaxErrors-1].Error)
+	require.Len(t, stats.History, 1)
+	assert.Equal(t, int64(countersMaxErrors+3), stats.History[0].Errors)
+}
+
+func TestCountersHistory(t *testing.T) {
+	c := new(Counters)
+	now := time.Now()
+	c.mu.Lock()
+	c.sample(now.Add(-90 * time.Minute)).Calls = 100 // too old to be returned
+	c.sample(now.Add(-time.Minute)).Calls = 5
+	c.sample(now).Calls = 7
+	c.mu.Unlock()
+	history := c.Stats().History
+	require.Len(t, history, 2)
+	assert.Equal(t, int64(5), history[0].Calls)
+	assert.Equal(t, int64(7), history[1].Calls)
+	assert.True(t, history[0].Time.Before(history[1].Time))
+
+	// A sample from a minute an hour ago gets reused
+	c.mu.Lock()
+	sample := c.sample(now.Add(time.Hour))
+	c.mu.Unlock()
+	assert.Equal(t, int64(0), sample.Calls)
+}
+
+func TestPacerCounters(t *testing.T) {
+	p := NewPacer(context.Background(), pacer.NewDefault(pacer.MinSleep(time.Microsecond), pacer.MaxSleep(time.Millisecond)))
+	p.SetRetries(3)
+
+	// Without counters set
+	assert.NoError(t, p.Call(func() (bool, error) { return false, nil }))
+
+	c := new(Counters)
+	p.SetCounters(c)
+	try := 0
+	err := p.Call(func() (bool, error) {
+		try++
+		if try < 3 {
+			return true, errors.New("rate limited")
+		}
+		return false, nil
+	})
+	assert.NoError(t, err)
+	stats := c.Stats()
+	assert.Equal(t, int64(3), stats.Calls)
+	assert.Equal(t, int64(2), stats.Retries)
+	assert.Equal(t, int64(2), stats.Throttles)
+	require.NotNil(t, stats.Latency)
+	assert.Equal(t, int64(3), stats.Latency.Count)
+}
+
+func TestCountersLatency(t *testing.T) {
+	c := new(Counters)
+	assert.Nil(t, c.Stats().Latency)
+
+	c.Latency(5 * time.Millisecond)
+	c.Latency(10 * time.Millisecond)
+	c.Latency(75 * time.Millisecond)
+	c.Latency(time.Minute - 90*time.Millisecond)
+
+	latency := c.Stats().Latency
+	require.NotNil(t, latency)
+	assert.Equal(t, int64(4), latency.Count)
+	assert.InDelta(t, 15.0, latency.Mean, 1e-9)
+	require.Equal(t, len(latencyBuckets)+1, len(latency.Buckets))
+	assert.Equal(t, LatencyBucket{Le: 0.01, Count: 2}, latency.Buckets[0])
+	assert.Equal(t, LatencyBucket{Le: 0.025, Count: 0}, latency.Buckets[1])
+	assert.Equal(t, LatencyBucket{Le: 0.1, Count: 1}, latency.Buckets[3])
+	assert.Equal(t, LatencyBucket{Le: 0, Count: 1}, latency.Buckets[len(latencyBuckets)])
+}
@@ -0,0 +1,83 @@
+// Server-side copies between different remotes
+
+package fs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CrossCopyFn copies src to remote in dst server-side where src is on
+// a different remote to dst.
+//
+// It should return ErrorCantCopy if it can't copy between these
+// particular remotes, e.g. if they are on different servers, in which
+// case the next function registered for the pair is tried and then
+// the file is downloaded and uploaded.
+type CrossCopyFn func(ctx context.Context, dst Fs, src Object, remote string) (Object, error)
+
+// crossCopy is a registered CrossCopyFn
+type crossCopy struct {
+	srcType string
+	dstType string
+	fn      CrossCopyFn
+}
+
+var (
+	crossCopiesMu sync.Mutex
+	crossCopies   []crossCopy
+)
+
+// fsType returns the type the cross copies are registered with
+func fsType(f Info) string {
+	return fmt.Sprintf("%T", f)
+}
+
+// RegisterCrossCopy registers fn to copy objects server-side from
+// remotes of the same type as src to remotes of the same type as dst,
+// which may be the same type.
+//
+// This is used when the Copy method of the destination can't be used
+// because the remotes have different configurations. Backends call it
+// in their init function, e.g.
+//
+//	fs.RegisterCrossCopy((*Fs)(nil), (*Fs)(nil), crossCopy)
+func RegisterCrossCopy(src, dst Info, fn CrossCopyFn) {
+	crossCopiesMu.Lock()
+	defer crossCopiesMu.Unlock()
+	crossCopies = append(crossCopies, crossCopy{
+		srcType: fsType(src),
+		dstType: fsType(dst),
+		fn:      fn,
+	})
+}
+
+// FindCrossCopy returns a function to copy objects server-side from
+// fsrc to fdst or nil if none have been registered.
+//
+// The function tries each CrossCopyFn registered for the pair in turn
+// returning ErrorCantCopy if none of them could copy the object.
+func FindCrossCopy(fdst, fsrc Info) CrossCopyFn {
+	srcType, dstType := fsType(fsrc), fsType(fdst)
+	var fns []CrossCopyFn
+	crossCopiesMu.Lock()
+	for _, cc := range crossCopies {
+		if cc.srcType == srcType && cc.dstType == dstType {
+			fns = append(fns, cc.fn)
+		}
+	}
+	crossCopiesMu.Unlock()
+	if len(fns) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, dst Fs, src Object, remote string) (Object, error) {
+		for _, fn := range fns {
+			dstObj, err := fn(ctx, dst, src, remote)
+			if err != ErrorCantCopy {
+				return dstObj, err
+			}
+		}
+		return nil, ErrorCantCopy
+	}
+}
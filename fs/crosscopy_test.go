@@ -0,0 +1,61 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Different types of Fs to register cross copies between
+type (
+	crossCopyFsA struct{ Fs }
+	crossCopyFsB struct{ Fs }
+)
+
+func TestCrossCopy(t *testing.T) {
+	oldCrossCopies := crossCopies
+	defer func() {
+		crossCopies = oldCrossCopies
+	}()
+	crossCopies = nil
+
+	ctx := context.Background()
+	a, b := &crossCopyFsA{}, &crossCopyFsB{}
+	assert.Nil(t, FindCrossCopy(b, a))
+
+	var calls []string
+	errCopy := errors.New("copy failed")
+	RegisterCrossCopy(a, b, func(ctx context.Context, dst Fs, src Object, remote string) (Object, error) {
+		calls = append(calls, "first "+remote)
+		if remote == "first" {
+			return nil, errCopy
+		}
+		return nil, ErrorCantCopy
+	})
+	RegisterCrossCopy(a, b, func(ctx context.Context, dst Fs, src Object, remote string) (Object, error) {
+		calls = append(calls, "second "+remote)
+		if remote == "second" {
+			return nil, nil
+		}
+		return nil, ErrorCantCopy
+	})
+
+	assert.Nil(t, FindCrossCopy(a, b), "cross copies are one way")
+	assert.Nil(t, FindCrossCopy(a, a))
+	crossCopy := FindCrossCopy(b, a)
+	assert.NotNil(t, crossCopy)
+
+	_, err := crossCopy(ctx, b, nil, "first")
+	assert.Equal(t, errCopy, err)
+	_, err = crossCopy(ctx, b, nil, "second")
+	assert.NoError(t, err)
+	_, err = crossCopy(ctx, b, nil, "neither")
+	assert.Equal(t, ErrorCantCopy, err)
+	assert.Equal(t, []string{
+		"first first",
+		"first second", "second second",
+		"first neither", "second neither",
+	}, calls)
+}
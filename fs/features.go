@@ -136,6 +136,17 @@ type Features struct {
 	// of listing recursively that doing a directory traversal.
 	ListR ListRFn
 
+	// ListP lists the objects and directories of the Fs in dir
+	// non recursively, calling callback with each page of entries
+	// as it is read.
+	//
+	// This should return ErrDirNotFound if the directory isn't
+	// found.
+	//
+	// Implement this if directories can have so many entries that
+	// reading them all into memory at once would be a problem.
+	ListP ListRFn
+
 	// About gets quota information from the Fs
 	About func(ctx context.Context) (*Usage, error)
 
@@ -275,6 +286,9 @@ func (ft *Features) Fill(ctx context.Context, f Fs) *Features {
 	if do, ok := f.(ListRer); ok {
 		ft.ListR = do.ListR
 	}
+	if do, ok := f.(ListPer); ok {
+		ft.ListP = do.ListP
+	}
 	if do, ok := f.(Abouter); ok {
 		ft.About = do.About
 	}
@@ -363,6 +377,9 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	if mask.ListR == nil {
 		ft.ListR = nil
 	}
+	if mask.ListP == nil {
+		ft.ListP = nil
+	}
 	if mask.About == nil {
 		ft.About = nil
 	}
@@ -570,6 +587,34 @@ type ListRer interface {
 	ListR(ctx context.Context, dir string, callback ListRCallback) error
 }
 
+// ListPer is an optional interfaces for Fs
+type ListPer interface {
+	// ListP lists the objects and directories of the Fs in dir
+	// non recursively, calling callback with each page of entries
+	// as it is read.
+	//
+	// This should return ErrDirNotFound if the directory isn't
+	// found.
+	//
+	// Implement this if directories can have so many entries that
+	// reading them all into memory at once would be a problem.
+	ListP(ctx context.Context, dir string, callback ListRCallback) error
+}
+
+// ListPFallback implements ListP for f by listing dir with f.List and
+// passing all the entries to callback in a single page.
+//
+// Backends which wrap an Fs can use this when the wrapped Fs doesn't
+// support ListP or when they need the whole directory to work out
+// their listing.
+func ListPFallback(ctx context.Context, f Fs, dir string, callback ListRCallback) error {
+	entries, err := f.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	return callback(entries)
+}
+
 // RangeSeeker is the interface that wraps the RangeSeek method.
 //
 // Some of the returns from Object.Open() may optionally implement
@@ -0,0 +1,159 @@
+// Filters which read the start of the object
+
+package filter
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// contentSniffSize is the number of bytes read from the start of an
+// object for the content filters
+const contentSniffSize = 4096
+
+// contentRules are the filters on the content of objects
+type contentRules struct {
+	includeMimeTypes []string // patterns of MIME types to include
+	excludeMimeTypes []string // patterns of MIME types to exclude
+	magics           [][]byte // objects must start with one of these
+	minEntropy       float64  // minimum entropy in bits per byte
+}
+
+// newContentRules parses the content filter options
+func newContentRules(opt *Opt) (cr contentRules, err error) {
+	for _, pattern := range append(append([]string(nil), opt.IncludeMimeType...), opt.ExcludeMimeType...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return cr, fmt.Errorf("bad MIME type pattern %q: %w", pattern, err)
+		}
+	}
+	cr.includeMimeTypes = opt.IncludeMimeType
+	cr.excludeMimeTypes = opt.ExcludeMimeType
+	for _, magic := range opt.IncludeMagic {
+		b, err := hex.DecodeString(strings.ReplaceAll(magic, " ", ""))
+		if err != nil || len(b) == 0 {
+			return cr, fmt.Errorf("bad magic number %q: must be hex bytes", magic)
+		}
+		if len(b) > contentSniffSize {
+			return cr, fmt.Errorf("magic number %q is longer than %d bytes", magic, contentSniffSize)
+		}
+		cr.magics = append(cr.magics, b)
+	}
+	if opt.MinEntropy < 0 || opt.MinEntropy > 8 {
+		return cr, fmt.Errorf("--min-entropy %g must be between 0 and 8", opt.MinEntropy)
+	}
+	cr.minEntropy = opt.MinEntropy
+	return cr, nil
+}
+
+// active returns true if any content rules are set
+func (cr *contentRules) active() bool {
+	return len(cr.includeMimeTypes) > 0 || len(cr.excludeMimeTypes) > 0 || len(cr.magics) > 0 || cr.minEntropy > 0
+}
+
+// mimeTypeMatches returns true if mimeType matches any of patterns
+func mimeTypeMatches(mimeType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, mimeType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// entropy returns the Shannon entropy of data in bits per byte
+func entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	var e float64
+	n := float64(len(data))
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / n
+			e -= p * math.Log2(p)
+		}
+	}
+	return e
+}
+
+// include returns whether data from the start of an object passes
+// the content rules
+func (cr *contentRules) include(data []byte) bool {
+	if len(cr.includeMimeTypes) > 0 || len(cr.excludeMimeTypes) > 0 {
+		mimeType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+		if err != nil {
+			mimeType = "application/octet-stream"
+		}
+		if mimeTypeMatches(mimeType, cr.excludeMimeTypes) {
+			return false
+		}
+		if len(cr.includeMimeTypes) > 0 && !mimeTypeMatches(mimeType, cr.includeMimeTypes) {
+			return false
+		}
+	}
+	if len(cr.magics) > 0 {
+		found := false
+		for _, magic := range cr.magics {
+			if bytes.HasPrefix(data, magic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if cr.minEntropy > 0 && entropy(data) < cr.minEntropy {
+		return false
+	}
+	return true
+}
+
+// readStart reads up to contentSniffSize bytes from the start of o
+func readStart(ctx context.Context, o fs.Object) (data []byte, err error) {
+	size := o.Size()
+	if size == 0 {
+		return nil, nil
+	}
+	var options []fs.OpenOption
+	if size < 0 || size > contentSniffSize {
+		options = append(options, &fs.RangeOption{Start: 0, End: contentSniffSize - 1})
+	}
+	in, err := o.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	return ioutil.ReadAll(io.LimitReader(in, contentSniffSize))
+}
+
+// includeContent returns whether o passes the content rules, reading
+// the start of it if necessary.
+//
+// If the object can't be read it is excluded.
+func (f *Filter) includeContent(ctx context.Context, o fs.Object) bool {
+	if !f.content.active() {
+		return true
+	}
+	data, err := readStart(ctx, o)
+	if err != nil {
+		fs.Errorf(o, "Excluding as failed to read content for filters: %v", err)
+		return false
+	}
+	return f.content.include(data)
+}
@@ -96,6 +96,11 @@ type Opt struct {
 	MinSize        fs.SizeSuffix
 	MaxSize        fs.SizeSuffix
 	IgnoreCase     bool
+
+	IncludeMimeType []string // MIME types sniffed from the content to include
+	ExcludeMimeType []string // MIME types sniffed from the content to exclude
+	IncludeMagic    []string // hex bytes objects must start with
+	MinEntropy      float64  // minimum entropy of the start of objects in bits per byte
 }
 
 // DefaultOpt is the default config for the filter
@@ -115,6 +120,7 @@ type Filter struct {
 	dirRules    rules
 	files       FilesMap // files if filesFrom
 	dirs        FilesMap // dirs from filesFrom
+	content     contentRules
 }
 
 // NewFilter parses the command line options and creates a Filter
@@ -195,6 +201,11 @@ func NewFilter(opt *Opt) (f *Filter, err error) {
 		}
 	}
 
+	f.content, err = newContentRules(&f.Opt)
+	if err != nil {
+		return nil, err
+	}
+
 	inActive := f.InActive()
 
 	for _, rule := range f.Opt.FilesFrom {
@@ -370,7 +381,8 @@ func (f *Filter) InActive() bool {
 		f.Opt.MaxSize < 0 &&
 		f.fileRules.len() == 0 &&
 		f.dirRules.len() == 0 &&
-		len(f.Opt.ExcludeFile) == 0)
+		len(f.Opt.ExcludeFile) == 0 &&
+		!f.content.active())
 }
 
 // IncludeRemote returns whether this remote passes the filter rules.
@@ -477,6 +489,9 @@ func (f *Filter) Include(remote string, size int64, modTime time.Time) bool {
 // IncludeObject returns whether this object should be included into
 // the sync or not. This is a convenience function to avoid calling
 // o.ModTime(), which is an expensive operation.
+//
+// If content filters are in use it reads the start of the object if
+// it passes the other filters.
 func (f *Filter) IncludeObject(ctx context.Context, o fs.Object) bool {
 	var modTime time.Time
 
@@ -486,7 +501,7 @@ func (f *Filter) IncludeObject(ctx context.Context, o fs.Object) bool {
 		modTime = time.Unix(0, 0)
 	}
 
-	return f.Include(o.Remote(), o.Size(), modTime)
+	return f.Include(o.Remote(), o.Size(), modTime) && f.includeContent(ctx, o)
 }
 
 // forEachLine calls fn on every line in the file pointed to by path
@@ -529,6 +544,18 @@ func (f *Filter) DumpFilters() string {
 	if !f.ModTimeTo.IsZero() {
 		rules = append(rules, fmt.Sprintf("Last-modified date must be equal or less than: %s", f.ModTimeTo.String()))
 	}
+	if len(f.content.includeMimeTypes) > 0 {
+		rules = append(rules, fmt.Sprintf("Content MIME type must match one of: %s", strings.Join(f.content.includeMimeTypes, ", ")))
+	}
+	if len(f.content.excludeMimeTypes) > 0 {
+		rules = append(rules, fmt.Sprintf("Content MIME type must not match any of: %s", strings.Join(f.content.excludeMimeTypes, ", ")))
+	}
+	if len(f.Opt.IncludeMagic) > 0 {
+		rules = append(rules, fmt.Sprintf("Content must start with one of: %s", strings.Join(f.Opt.IncludeMagic, ", ")))
+	}
+	if f.content.minEntropy > 0 {
+		rules = append(rules, fmt.Sprintf("Content entropy must be at least: %g bits per byte", f.content.minEntropy))
+	}
 	rules = append(rules, "--- File filter rules ---")
 	for _, rule := range f.fileRules.rules {
 		rules = append(rules, rule.String())
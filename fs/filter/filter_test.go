@@ -794,6 +794,72 @@ func TestNewFilterUsesDirectoryFilters(t *testing.T) {
 	}
 }
 
+func TestNewFilterContent(t *testing.T) {
+	ctx := context.Background()
+	png := append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), make([]byte, 100)...)
+	text := []byte(strings.Repeat("hello world ", 100))
+	random := make([]byte, 8192)
+	for i := range random {
+		random[i] = byte(i*7919 + i/256*31)
+	}
+	objects := map[string]fs.Object{
+		"image.txt": mockobject.New("image.txt").WithContent(png, mockobject.SeekModeNone),
+		"text.png":  mockobject.New("text.png").WithContent(text, mockobject.SeekModeNone),
+		"random":    mockobject.New("random").WithContent(random, mockobject.SeekModeNone),
+		"empty":     mockobject.New("empty").WithContent(nil, mockobject.SeekModeNone),
+	}
+	for _, test := range []struct {
+		opt  Opt
+		want []string
+	}{
+		{Opt{IncludeMimeType: []string{"image/*"}}, []string{"image.txt"}},
+		{Opt{IncludeMimeType: []string{"text/plain", "image/png"}}, []string{"image.txt", "text.png", "empty"}},
+		{Opt{ExcludeMimeType: []string{"text/*"}}, []string{"image.txt", "random"}},
+		{Opt{IncludeMagic: []string{"89504e47"}}, []string{"image.txt"}},
+		{Opt{IncludeMagic: []string{"6865 6c6c", "89"}}, []string{"image.txt", "text.png"}},
+		{Opt{MinEntropy: 7}, []string{"random"}},
+		{Opt{MinEntropy: 2, ExcludeMimeType: []string{"application/octet-stream"}}, []string{"text.png"}},
+	} {
+		what := fmt.Sprintf("%+v", test.opt)
+		opt := DefaultOpt
+		opt.IncludeMimeType = test.opt.IncludeMimeType
+		opt.ExcludeMimeType = test.opt.ExcludeMimeType
+		opt.IncludeMagic = test.opt.IncludeMagic
+		opt.MinEntropy = test.opt.MinEntropy
+		f, err := NewFilter(&opt)
+		require.NoError(t, err, what)
+		assert.False(t, f.InActive(), what)
+		var got []string
+		for _, name := range []string{"image.txt", "text.png", "random", "empty"} {
+			if f.IncludeObject(ctx, objects[name]) {
+				got = append(got, name)
+			}
+		}
+		assert.Equal(t, test.want, got, what)
+	}
+
+	// Other filters are applied first
+	opt := DefaultOpt
+	opt.IncludeMimeType = []string{"image/*"}
+	opt.MinSize = 1000
+	f, err := NewFilter(&opt)
+	require.NoError(t, err)
+	assert.False(t, f.IncludeObject(ctx, objects["image.txt"]))
+
+	// Bad options
+	for _, set := range []func(opt *Opt){
+		func(opt *Opt) { opt.IncludeMimeType = []string{"image/["} },
+		func(opt *Opt) { opt.IncludeMagic = []string{"potato"} },
+		func(opt *Opt) { opt.IncludeMagic = []string{""} },
+		func(opt *Opt) { opt.MinEntropy = 9 },
+	} {
+		opt := DefaultOpt
+		set(&opt)
+		_, err := NewFilter(&opt)
+		assert.Error(t, err, fmt.Sprintf("%+v", opt))
+	}
+}
+
 func TestGetConfig(t *testing.T) {
 	ctx := context.Background()
 
@@ -44,5 +44,9 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.FVarP(flagSet, &Opt.MinSize, "min-size", "", "Only transfer files bigger than this in KiB or suffix B|K|M|G|T|P")
 	flags.FVarP(flagSet, &Opt.MaxSize, "max-size", "", "Only transfer files smaller than this in KiB or suffix B|K|M|G|T|P")
 	flags.BoolVarP(flagSet, &Opt.IgnoreCase, "ignore-case", "", false, "Ignore case in filters (case insensitive)")
+	flags.StringArrayVarP(flagSet, &Opt.IncludeMimeType, "include-mime-type", "", nil, "Only include files whose content is detected as this MIME type (e.g. video/*)")
+	flags.StringArrayVarP(flagSet, &Opt.ExcludeMimeType, "exclude-mime-type", "", nil, "Exclude files whose content is detected as this MIME type")
+	flags.StringArrayVarP(flagSet, &Opt.IncludeMagic, "include-magic", "", nil, "Only include files starting with these bytes in hex")
+	flags.Float64VarP(flagSet, &Opt.MinEntropy, "min-entropy", "", 0, "Only include files whose start has at least this entropy in bits per byte (0-8)")
 	//cvsExclude     = BoolP("cvs-exclude", "C", false, "Exclude files in the same way CVS does")
 }
@@ -6,12 +6,14 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httputil"
+	"net/url"
 	"sync"
 	"time"
 
@@ -27,8 +29,8 @@ const (
 )
 
 var (
-	transport    http.RoundTripper
-	noTransport  = new(sync.Once)
+	transportsMu sync.Mutex
+	transports   = map[string]http.RoundTripper{} // transports by transportKey
 	cookieJar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
 	logMutex     sync.Mutex
 )
@@ -36,7 +38,42 @@ var (
 // ResetTransport resets the existing transport, allowing it to take new settings.
 // Should only be used for testing.
 func ResetTransport() {
-	noTransport = new(sync.Once)
+	transportsMu.Lock()
+	transports = map[string]http.RoundTripper{}
+	transportsMu.Unlock()
+}
+
+// proxyFunc returns the function to find the proxy for a request.
+//
+// This is the proxy set with --proxy if any, otherwise it is read
+// from the environment.
+func proxyFunc(ci *fs.ConfigInfo) (func(*http.Request) (*url.URL, error), error) {
+	if ci.Proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := ParseProxy(ci.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return http.ProxyURL(u), nil
+}
+
+// ParseProxy parses a proxy URL checking it is one the transport can
+// use.
+func ParseProxy(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", proxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy %q: scheme must be http, https or socks5", proxy)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: no host", proxy)
+	}
+	return u, nil
 }
 
 // NewTransportCustom returns an http.RoundTripper with the correct timeouts.
@@ -48,7 +85,11 @@ func NewTransportCustom(ctx context.Context, customize func(*http.Transport)) ht
 	// This also means we get new stuff when it gets added to go
 	t := new(http.Transport)
 	structs.SetDefaults(t, http.DefaultTransport.(*http.Transport))
-	t.Proxy = http.ProxyFromEnvironment
+	proxy, err := proxyFunc(ci)
+	if err != nil {
+		log.Fatalf("Failed to set --proxy: %v", err)
+	}
+	t.Proxy = proxy
 	t.MaxIdleConnsPerHost = 2 * (ci.Checkers + ci.Transfers + 1)
 	t.MaxIdleConns = 2 * t.MaxIdleConnsPerHost
 	t.TLSHandshakeTimeout = ci.ConnectTimeout
@@ -113,11 +154,25 @@ func NewTransportCustom(ctx context.Context, customize func(*http.Transport)) ht
 	return newTransport(ci, t)
 }
 
+// transportKey returns a key made from the options in ci which can
+// be set per remote in profiles and change the transport.
+func transportKey(ci *fs.ConfigInfo) string {
+	return fmt.Sprintf("%q|%v|%v|%v|%q|%v|%v", ci.Proxy, ci.ConnectTimeout, ci.Timeout, ci.ExpectContinueTimeout, ci.UserAgent, ci.DisableHTTP2, ci.DisableHTTPKeepAlives)
+}
+
 // NewTransport returns an http.RoundTripper with the correct timeouts
+//
+// Transports are shared between all the remotes which use the same
+// per remote options.
 func NewTransport(ctx context.Context) http.RoundTripper {
-	(*noTransport).Do(func() {
+	key := transportKey(fs.GetConfig(ctx))
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transport := transports[key]
+	if transport == nil {
 		transport = NewTransportCustom(ctx, nil)
-	})
+		transports[key] = transport
+	}
 	return transport
 }
 
@@ -166,8 +221,22 @@ func (t *Transport) SetRequestFilter(f func(req *http.Request)) {
 // A mutex to protect this map
 var checkedHostMu sync.RWMutex
 
-// A map of servers we have checked for time
-var checkedHost = make(map[string]struct{}, 1)
+// A map of servers we have checked for time to how far their time is
+// ahead of ours
+var checkedHost = make(map[string]time.Duration, 1)
+
+// ServerTimeOffsets returns how far the time of each server rclone has
+// talked to over HTTP is ahead of the time of this computer, as
+// measured from the Date: header of the first response from it.
+func ServerTimeOffsets() map[string]time.Duration {
+	checkedHostMu.RLock()
+	defer checkedHostMu.RUnlock()
+	offsets := make(map[string]time.Duration, len(checkedHost))
+	for host, offset := range checkedHost {
+		offsets[host] = offset
+	}
+	return offsets
+}
 
 // Check the server time is the same as ours, once for each server
 func checkServerTime(req *http.Request, resp *http.Response) {
@@ -196,7 +265,7 @@ func checkServerTime(req *http.Request, resp *http.Response) {
 		fs.Logf(nil, "Time may be set wrong - time from %q is %v different from this computer", host, dt)
 	}
 	checkedHostMu.Lock()
-	checkedHost[host] = struct{}{}
+	checkedHost[host] = -dt
 	checkedHostMu.Unlock()
 }
 
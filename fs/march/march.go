@@ -10,6 +10,7 @@ import (
 	"sync"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/dirtree"
 	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/list"
@@ -149,7 +150,7 @@ func (m *March) Run(ctx context.Context) error {
 	// Start some directory listing go routines
 	var wg sync.WaitGroup         // sync closing of go routines
 	var traversing sync.WaitGroup // running directory traversals
-	checkers := ci.Checkers
+	checkers := m.listCheckers(ctx)
 	in := make(chan listDirJob, checkers)
 	for i := 0; i < checkers; i++ {
 		wg.Add(1)
@@ -220,6 +221,33 @@ func (m *March) Run(ctx context.Context) error {
 	return jobError
 }
 
+// listCheckers returns the number of directories to list in parallel.
+//
+// This is --list-checkers or --checkers if not set, unless set in the
+// profile of the source or destination, in which case the smallest
+// of those is used.
+func (m *March) listCheckers(ctx context.Context) int {
+	ci := fs.GetConfig(ctx)
+	checkers := ci.Checkers
+	if ci.ListCheckers > 0 {
+		checkers = ci.ListCheckers
+	}
+	remotes := []fs.Fs{m.Fsrc}
+	if !m.NoTraverse {
+		remotes = append(remotes, m.Fdst)
+	}
+	profileCheckers := 0
+	for _, f := range remotes {
+		if n, ok := fs.ListCheckers(f); ok && (profileCheckers == 0 || n < profileCheckers) {
+			profileCheckers = n
+		}
+	}
+	if profileCheckers > 0 {
+		checkers = profileCheckers
+	}
+	return checkers
+}
+
 // Check to see if the context has been cancelled
 func (m *March) aborting() bool {
 	select {
@@ -374,6 +402,27 @@ func matchListings(srcListEntries, dstListEntries fs.DirEntries, transforms []ma
 	return
 }
 
+// accountListed updates the stats with the directories listed for
+// job and the objects found in them so progress is shown while
+// scanning large trees
+func (m *March) accountListed(job listDirJob, srcList, dstList fs.DirEntries) {
+	var dirs, objects int64
+	if !job.noSrc {
+		dirs++
+	}
+	if !m.NoTraverse && !job.noDst {
+		dirs++
+	}
+	for _, list := range []fs.DirEntries{srcList, dstList} {
+		for _, entry := range list {
+			if _, ok := entry.(fs.Object); ok {
+				objects++
+			}
+		}
+	}
+	accounting.Stats(m.Ctx).Listed(dirs, objects)
+}
+
 // processJob processes a listDirJob listing the source and
 // destination directories, comparing them and returning a slice of
 // more jobs
@@ -426,6 +475,7 @@ func (m *March) processJob(job listDirJob) ([]listDirJob, error) {
 		dstListErr = fs.CountError(dstListErr)
 		return nil, dstListErr
 	}
+	m.accountListed(job, srcList, dstList)
 
 	// If NoTraverse is set, then try to find a matching object
 	// for each item in the srcList to head dst object
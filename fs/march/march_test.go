@@ -12,10 +12,12 @@ import (
 
 	_ "github.com/rclone/rclone/backend/local"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/fstest/mockdir"
+	"github.com/rclone/rclone/fstest/mockfs"
 	"github.com/rclone/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -284,6 +286,48 @@ func TestMarchNoTraverse(t *testing.T) {
 	}
 }
 
+func TestMarchListed(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = accounting.WithStatsGroup(ctx, "test-march-listed")
+	r.WriteBoth(ctx, "test", "hello world", t1)
+	r.WriteBoth(ctx, "sub dir/test2", "hello world", t1)
+	r.WriteFile("srcOnly", "hello world", t1)
+
+	mt := &marchTester{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	m := &March{
+		Ctx:      ctx,
+		Fdst:     r.Fremote,
+		Fsrc:     r.Flocal,
+		Callback: mt,
+	}
+	mt.processError(m.Run(ctx))
+	mt.cancel()
+	require.NoError(t, mt.currentError())
+
+	stats, err := accounting.StatsGroup(ctx, "test-march-listed").RemoteStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stats["listedDirs"])
+	assert.Equal(t, int64(5), stats["listedObjects"])
+}
+
+func TestMarchListCheckers(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	m := &March{
+		Fdst: mockfs.NewFs(ctx, "dst", ""),
+		Fsrc: mockfs.NewFs(ctx, "src", ""),
+	}
+	ci.Checkers = 5
+	assert.Equal(t, 5, m.listCheckers(ctx))
+	ci.ListCheckers = 3
+	assert.Equal(t, 3, m.listCheckers(ctx))
+}
+
 func TestNewMatchEntries(t *testing.T) {
 	var (
 		a = mockobject.Object("path/a")
@@ -30,7 +30,18 @@ func NewFs(ctx context.Context, path string) (Fs, error) {
 	if err != nil {
 		return nil, err
 	}
+	sections := []string{configName}
 	overridden := fsInfo.Options.Overridden(config)
+	profileCtx := ctx
+	if name, ok := profileName(fsInfo, config); ok {
+		sections = append(sections, ProfilePrefix+name)
+		profileCtx, err = applyProfile(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		// Make sure the Fs is named differently to one without the profile
+		overridden[ProfileOptionAlt] = name
+	}
 	if len(overridden) > 0 {
 		extraConfig := overridden.String()
 		//Debugf(nil, "detected overriden config %q", extraConfig)
@@ -48,9 +59,14 @@ func NewFs(ctx context.Context, path string) (Fs, error) {
 		// These need to work as filesystem names as the VFS cache will use them
 		configName += suffix
 	}
-	f, err := fsInfo.NewFs(ctx, configName, fsPath, config)
+	err = fetchSecrets(ctx, fsInfo, sections...)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fsInfo.NewFs(withBackendType(profileCtx, fsInfo.Name), configName, fsPath, config)
 	if f != nil && (err == nil || err == ErrorIsFile) {
 		addReverse(f, fsInfo)
+		setListCheckers(ctx, profileCtx, f)
 	}
 	return f, err
 }
@@ -276,6 +276,43 @@ func (o MetadataOption) Mandatory() bool {
 	return false
 }
 
+// ResumeOption is passed to Put and Update with --resume-uploads.
+//
+// Backends which upload in sessions kept by the server can use it to
+// resume an upload of the same file which was interrupted, even by
+// rclone being stopped. The backend should call SaveState once it
+// knows how to resume the upload, and if State is set on the next
+// upload it should try to resume from it, skipping the data the
+// server already has from the input.
+type ResumeOption struct {
+	State string                   // state saved by an interrupted upload of this file or ""
+	Save  func(state string) error // saves the state - called by SaveState
+}
+
+// SaveState saves state to be passed back in State if the upload is
+// interrupted
+func (o *ResumeOption) SaveState(state string) error {
+	if o.Save == nil {
+		return nil
+	}
+	return o.Save(state)
+}
+
+// Header formats the option as an http header
+func (o *ResumeOption) Header() (key string, value string) {
+	return "", ""
+}
+
+// String formats the option into human-readable form
+func (o *ResumeOption) String() string {
+	return fmt.Sprintf("ResumeOption(%q)", o.State)
+}
+
+// Mandatory returns whether the option must be parsed or can be ignored
+func (o *ResumeOption) Mandatory() bool {
+	return false
+}
+
 // OpenOptionAddHeaders adds each header found in options to the
 // headers map provided the key was non empty.
 func OpenOptionAddHeaders(options []OpenOption, headers map[string]string) {
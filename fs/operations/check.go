@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
 	"regexp"
 	"strings"
@@ -360,6 +361,114 @@ func checkIdenticalDownload(ctx context.Context, dst, src fs.Object) (differ boo
 	return
 }
 
+// sampleOffsets returns the start offsets of samples ranges of
+// sampleSize bytes to check in a file of size bytes.
+//
+// The first and last ranges of the file are always included and the
+// rest are chosen at random. It returns nil if the samples would
+// cover the whole file.
+func sampleOffsets(size int64, samples int, sampleSize int64) (offsets []int64) {
+	if samples <= 0 || sampleSize <= 0 || size <= int64(samples)*sampleSize {
+		return nil
+	}
+	offsets = append(offsets, 0)
+	if samples >= 2 {
+		offsets = append(offsets, size-sampleSize)
+	}
+	for i := 2; i < samples; i++ {
+		offsets = append(offsets, rand.Int63n(size-sampleSize+1))
+	}
+	return offsets
+}
+
+// CheckIdenticalSample checks to see if dst and src are identical by
+// comparing their sizes then reading samples ranges of sampleSize
+// bytes from each of them.
+//
+// This is much quicker than CheckIdenticalDownload for large files
+// but it may not find all the differences. If the samples would cover
+// the whole file then it is downloaded in full.
+//
+// it returns true if differences were found
+func CheckIdenticalSample(ctx context.Context, dst, src fs.Object, samples int, sampleSize int64) (differ bool, err error) {
+	size := src.Size()
+	if size != dst.Size() {
+		return true, nil
+	}
+	return checkIdenticalOffsets(ctx, dst, src, sampleOffsets(size, samples, sampleSize), sampleSize)
+}
+
+// fixedSampleOffsets returns the start offsets of samples ranges of
+// sampleSize bytes spread evenly through a file of size bytes.
+//
+// Unlike sampleOffsets these are always the same for the same
+// arguments. It returns nil if the samples would cover the whole
+// file.
+func fixedSampleOffsets(size int64, samples int, sampleSize int64) (offsets []int64) {
+	if samples <= 0 || sampleSize <= 0 || size <= int64(samples)*sampleSize {
+		return nil
+	}
+	if samples == 1 {
+		return []int64{0}
+	}
+	last := size - sampleSize
+	for i := 0; i < samples; i++ {
+		offsets = append(offsets, last*int64(i)/int64(samples-1))
+	}
+	return offsets
+}
+
+// checkIdenticalOffsets checks dst and src are identical by reading
+// the ranges of sampleSize bytes starting at offsets from each of
+// them. If offsets is nil the files are downloaded in full.
+//
+// it returns true if differences were found
+func checkIdenticalOffsets(ctx context.Context, dst, src fs.Object, offsets []int64, sampleSize int64) (differ bool, err error) {
+	if offsets == nil {
+		return CheckIdenticalDownload(ctx, dst, src)
+	}
+	ci := fs.GetConfig(ctx)
+	for _, offset := range offsets {
+		option := &fs.RangeOption{Start: offset, End: offset + sampleSize - 1}
+		err = Retry(ctx, src, ci.LowLevelRetries, func() error {
+			differ, err = checkIdenticalRange(ctx, dst, src, option)
+			return err
+		})
+		if differ || err != nil {
+			return differ, err
+		}
+	}
+	return false, nil
+}
+
+// Does the work for CheckIdenticalSample for a single range
+func checkIdenticalRange(ctx context.Context, dst, src fs.Object, option *fs.RangeOption) (differ bool, err error) {
+	in1, err := dst.Open(ctx, option)
+	if err != nil {
+		return true, fmt.Errorf("failed to open %q: %w", dst, err)
+	}
+	tr1 := accounting.Stats(ctx).NewTransfer(dst)
+	defer func() {
+		tr1.Done(ctx, nil) // error handling is done by the caller
+	}()
+	in1 = tr1.Account(ctx, in1)
+
+	in2, err := src.Open(ctx, option)
+	if err != nil {
+		return true, fmt.Errorf("failed to open %q: %w", src, err)
+	}
+	tr2 := accounting.Stats(ctx).NewTransfer(dst)
+	defer func() {
+		tr2.Done(ctx, nil) // error handling is done by the caller
+	}()
+	in2 = tr2.Account(ctx, in2)
+
+	// Some backends ignore the end of the range so limit the reads
+	length := option.End - option.Start + 1
+	differ, err = CheckEqualReaders(io.LimitReader(in1, length), io.LimitReader(in2, length))
+	return
+}
+
 // CheckDownload checks the files in fsrc and fdst according to Size
 // and the actual contents of the files.
 func CheckDownload(ctx context.Context, opt *CheckOpt) error {
@@ -374,6 +483,24 @@ func CheckDownload(ctx context.Context, opt *CheckOpt) error {
 	return CheckFn(ctx, &optCopy)
 }
 
+// CheckDownloadSample checks the files in fsrc and fdst according to
+// Size and samples ranges of sampleSize bytes of the contents of the
+// files.
+//
+// This is a probabilistic check which is much quicker than
+// CheckDownload on large files.
+func CheckDownloadSample(ctx context.Context, opt *CheckOpt, samples int, sampleSize int64) error {
+	optCopy := *opt
+	optCopy.Check = func(ctx context.Context, a, b fs.Object) (differ bool, noHash bool, err error) {
+		differ, err = CheckIdenticalSample(ctx, a, b, samples, sampleSize)
+		if err != nil {
+			return true, true, fmt.Errorf("failed to download: %w", err)
+		}
+		return differ, false, nil
+	}
+	return CheckFn(ctx, &optCopy)
+}
+
 // CheckSum checks filesystem hashes against a SUM file
 func CheckSum(ctx context.Context, fsrc, fsum fs.Fs, sumFile string, hashType hash.Type, opt *CheckOpt, download bool) error {
 	var options CheckOpt
@@ -207,6 +207,54 @@ func TestCheckDownload(t *testing.T) {
 	testCheck(t, operations.CheckDownload)
 }
 
+func TestCheckDownloadSample(t *testing.T) {
+	testCheck(t, func(ctx context.Context, opt *operations.CheckOpt) error {
+		return operations.CheckDownloadSample(ctx, opt, 3, 4)
+	})
+}
+
+func TestCheckIdenticalSample(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	contents := strings.Repeat("0123456789", 10)
+	differentStart := "X" + contents[1:]
+	differentEnd := contents[:99] + "X"
+	file1 := r.WriteBoth(ctx, "same", contents, t1)
+	file2 := r.WriteFile("start", contents, t1)
+	file3 := r.WriteObject(ctx, "start", differentStart, t1)
+	file4 := r.WriteFile("end", contents, t1)
+	file5 := r.WriteObject(ctx, "end", differentEnd, t1)
+	file6 := r.WriteFile("size", contents, t1)
+	file7 := r.WriteObject(ctx, "size", contents+"X", t1)
+	r.CheckLocalItems(t, file1, file2, file4, file6)
+	r.CheckRemoteItems(t, file1, file3, file5, file7)
+
+	for _, test := range []struct {
+		remote     string
+		samples    int
+		sampleSize int64
+		want       bool
+	}{
+		{remote: "same", samples: 3, sampleSize: 10, want: false},
+		{remote: "same", samples: 20, sampleSize: 10, want: false},
+		{remote: "start", samples: 1, sampleSize: 10, want: true},
+		{remote: "end", samples: 2, sampleSize: 10, want: true},
+		{remote: "end", samples: 10, sampleSize: 10, want: true},
+		{remote: "size", samples: 3, sampleSize: 10, want: true},
+	} {
+		what := fmt.Sprintf("%s samples=%d size=%d", test.remote, test.samples, test.sampleSize)
+		src, err := r.Flocal.NewObject(ctx, test.remote)
+		require.NoError(t, err)
+		dst, err := r.Fremote.NewObject(ctx, test.remote)
+		require.NoError(t, err)
+		differ, err := operations.CheckIdenticalSample(ctx, dst, src, test.samples, test.sampleSize)
+		require.NoError(t, err, what)
+		assert.Equal(t, test.want, differ, what)
+	}
+}
+
 func TestCheckSizeOnly(t *testing.T) {
 	ctx := context.Background()
 	ci := fs.GetConfig(ctx)
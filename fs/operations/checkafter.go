@@ -0,0 +1,51 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// errCheckAfter is returned when an object read back after a copy
+// doesn't match the source
+var errCheckAfter = errors.New("corrupted on transfer: check after copy failed")
+
+// checkAfterCopy downloads dst and checks its hash matches src.
+//
+// The hash of src is read from the remote if it supports one,
+// otherwise src is downloaded and hashed with MD5 too.
+func checkAfterCopy(ctx context.Context, src fs.Object, dst fs.Object) error {
+	if dst == nil {
+		return fmt.Errorf("%w: no destination object to check", errCheckAfter)
+	}
+	ht := src.Fs().Hashes().GetOne()
+	srcSum := ""
+	if ht != hash.None {
+		sum, err := src.Hash(ctx, ht)
+		if err == nil {
+			srcSum = sum
+		} else if err != hash.ErrUnsupported {
+			return fmt.Errorf("check after copy: failed to read source hash: %w", err)
+		}
+	}
+	if srcSum == "" {
+		ht = hash.MD5
+		sum, err := hashSum(ctx, ht, false, true, src)
+		if err != nil {
+			return fmt.Errorf("check after copy: failed to read source: %w", err)
+		}
+		srcSum = sum
+	}
+	dstSum, err := hashSum(ctx, ht, false, true, dst)
+	if err != nil {
+		return fmt.Errorf("check after copy: failed to read destination: %w", err)
+	}
+	if srcSum != dstSum {
+		return fmt.Errorf("%w: %v hash differ %q vs %q", errCheckAfter, ht, srcSum, dstSum)
+	}
+	fs.Debugf(dst, "Check after copy OK (%v %s)", ht, dstSum)
+	return nil
+}
@@ -0,0 +1,35 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckAfterCopy(t *testing.T) {
+	ctx := context.Background()
+	for _, hashes := range []hash.Set{hash.NewHashSet(hash.SHA1), hash.NewHashSet()} {
+		f := mockfs.NewFs(ctx, "mock", "root")
+		f.SetHashes(hashes)
+		newObject := func(content string) *mockobject.ContentMockObject {
+			o := mockobject.New("file").WithContent([]byte(content), mockobject.SeekModeNone)
+			o.SetFs(f)
+			return o
+		}
+		src := newObject("hello world")
+
+		err := checkAfterCopy(ctx, src, newObject("hello world"))
+		assert.NoError(t, err, hashes)
+
+		err = checkAfterCopy(ctx, src, newObject("hello wurld"))
+		assert.True(t, errors.Is(err, errCheckAfter), hashes)
+
+		err = checkAfterCopy(ctx, src, nil)
+		assert.True(t, errors.Is(err, errCheckAfter), hashes)
+	}
+}
@@ -0,0 +1,323 @@
+// Delta transfers writing only the changed blocks of a file
+
+package operations
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/lib/rollsum"
+)
+
+// deltaMinBlocks is the minimum number of blocks a file must have to
+// use a delta transfer
+const deltaMinBlocks = 4
+
+// doDeltaCopy returns whether only the changed blocks of src should
+// be written to dst.
+//
+// dst must be able to supply the hashes of its blocks as otherwise it
+// would have to be read in full as well as being written, which is
+// worse than just copying src.
+func doDeltaCopy(ctx context.Context, dst, src fs.Object) bool {
+	ci := fs.GetConfig(ctx)
+	if !ci.DeltaTransfer || dst == nil || ci.DeltaBlockSize <= 0 {
+		return false
+	}
+	if _, ok := dst.(fs.InPlaceWriter); !ok {
+		return false
+	}
+	if _, ok := dst.(fs.BlockHasher); !ok {
+		return false
+	}
+	if src.Size() < deltaMinBlocks*int64(ci.DeltaBlockSize) || dst.Size() <= 0 {
+		return false
+	}
+	return true
+}
+
+// blockSum returns the hex encoded MD5 hash of block
+func blockSum(block []byte) string {
+	sum := md5.Sum(block)
+	return hex.EncodeToString(sum[:])
+}
+
+// deltaCopyState is the state of a delta transfer
+type deltaCopyState struct {
+	ctx       context.Context
+	src       fs.Object
+	dst       fs.Object
+	out       fs.InPlaceFile
+	acc       *accounting.Account
+	blockSize int64
+	dstSums   []fs.BlockSum // checksums of the blocks of the destination
+	written   int64         // bytes of the source written to the destination
+}
+
+// writeData writes data read from the source to the destination at
+// offset
+func (dc *deltaCopyState) writeData(data []byte, offset int64) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := dc.acc.AccountRead(len(data)); err != nil {
+		return err
+	}
+	_, err := dc.out.WriteAt(data, offset)
+	if err != nil {
+		return fmt.Errorf("failed to write at offset %d: %w", offset, err)
+	}
+	dc.written += int64(len(data))
+	return nil
+}
+
+// copyDstBlock copies block i of the destination to offset, reading
+// it from the unchanged destination object. The last block may be
+// short.
+func (dc *deltaCopyState) copyDstBlock(i int, offset int64, buf []byte) error {
+	start := int64(i) * dc.blockSize
+	end := start + dc.blockSize
+	if size := dc.dst.Size(); end > size {
+		end = size
+	}
+	block := buf[:end-start]
+	in, err := NewReOpen(dc.ctx, dc.dst, fs.GetConfig(dc.ctx).LowLevelRetries, &fs.RangeOption{Start: start, End: end - 1})
+	if err != nil {
+		return fmt.Errorf("failed to open destination object: %w", err)
+	}
+	_, err = io.ReadFull(in, block)
+	_ = in.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read destination block %d: %w", i, err)
+	}
+	_, err = dc.out.WriteAt(block, offset)
+	if err != nil {
+		return fmt.Errorf("failed to write at offset %d: %w", offset, err)
+	}
+	return nil
+}
+
+// copyRolling reads the whole source, looking for the blocks of the
+// destination at any offset in it using a rolling checksum as rsync
+// does. The blocks found are copied from the destination and the data
+// between them is written from the source, so data inserted into or
+// removed from the source only causes the blocks around it to be
+// transferred.
+func (dc *deltaCopyState) copyRolling() (err error) {
+	var options []fs.OpenOption
+	for _, option := range fs.GetConfig(dc.ctx).DownloadHeaders {
+		options = append(options, option)
+	}
+	in, err := NewReOpen(dc.ctx, dc.src, fs.GetConfig(dc.ctx).LowLevelRetries, options...)
+	if err != nil {
+		return fmt.Errorf("failed to open source object: %w", err)
+	}
+	defer fs.CheckClose(in, &err)
+
+	// Index the whole blocks of the destination by weak checksum
+	blockSize := int(dc.blockSize)
+	dstWhole := int(dc.dst.Size() / dc.blockSize)
+	index := make(map[uint32][]int, dstWhole)
+	for i := 0; i < dstWhole && i < len(dc.dstSums); i++ {
+		index[dc.dstSums[i].Weak] = append(index[dc.dstSums[i].Weak], i)
+	}
+
+	// findBlock returns the destination block which window at offset
+	// is a copy of or -1 if none
+	findBlock := func(weak uint32, window []byte, offset int64) int {
+		candidates := index[weak]
+		if len(candidates) == 0 {
+			return -1
+		}
+		strong := blockSum(window)
+		found := -1
+		for _, i := range candidates {
+			if dc.dstSums[i].Strong == strong {
+				found = i
+				if int64(i)*dc.blockSize == offset {
+					break
+				}
+			}
+		}
+		return found
+	}
+
+	// buf holds the source from offset. buf[:pos] is data which
+	// hasn't been found in the destination and buf[pos:pos+blockSize]
+	// is the window being checked.
+	var (
+		buf     = make([]byte, 2*blockSize)
+		dstBuf  = make([]byte, blockSize)
+		n       = 0 // bytes in buf
+		pos     = 0
+		offset  = int64(0)
+		eof     = false
+		roll    *rollsum.Rollsum
+		flushTo = func(end int) error {
+			err := dc.writeData(buf[:end], offset)
+			n = copy(buf, buf[end:n])
+			offset += int64(end)
+			pos -= end
+			return err
+		}
+	)
+	for {
+		// Make sure there is a window and the byte after it if possible
+		if n-pos <= blockSize && !eof {
+			if err = flushTo(pos); err != nil {
+				return err
+			}
+			var m int
+			m, err = io.ReadFull(in, buf[n:])
+			n += m
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				eof = true
+			} else if err != nil {
+				return fmt.Errorf("failed to read source: %w", err)
+			}
+		}
+		if n-pos < blockSize {
+			break
+		}
+		window := buf[pos : pos+blockSize]
+		if roll == nil {
+			roll = rollsum.New(window)
+		}
+		if i := findBlock(roll.Sum(), window, offset+int64(pos)); i >= 0 {
+			if err = flushTo(pos); err != nil {
+				return err
+			}
+			if err = dc.copyDstBlock(i, offset, dstBuf); err != nil {
+				return err
+			}
+			n = copy(buf, buf[blockSize:n])
+			offset += int64(blockSize)
+			roll = nil
+			continue
+		}
+		if pos+blockSize == n {
+			break
+		}
+		roll.Roll(buf[pos], buf[pos+blockSize])
+		pos++
+	}
+
+	// The rest of the source may be the short last block of the
+	// destination, which can be copied rather than written
+	tail := buf[pos:n]
+	if last := len(dc.dstSums) - 1; last >= dstWhole && len(tail) > 0 &&
+		int64(len(tail)) == dc.dst.Size()-int64(last)*dc.blockSize &&
+		blockSum(tail) == dc.dstSums[last].Strong {
+		if err = flushTo(pos); err != nil {
+			return err
+		}
+		return dc.copyDstBlock(last, offset, dstBuf)
+	}
+	return flushTo(n)
+}
+
+// copyBlocks reads only the blocks of the source whose hashes differ
+// from the destination, copying the others from the destination
+func (dc *deltaCopyState) copyBlocks(srcSums []fs.BlockSum) error {
+	size := dc.src.Size()
+	buf := make([]byte, dc.blockSize)
+	for i, sum := range srcSums {
+		start := int64(i) * dc.blockSize
+		if i < len(dc.dstSums) && dc.dstSums[i].Strong == sum.Strong {
+			if err := dc.copyDstBlock(i, start, buf); err != nil {
+				return err
+			}
+			continue
+		}
+		end := start + dc.blockSize
+		if end > size {
+			end = size
+		}
+		in, err := NewReOpen(dc.ctx, dc.src, fs.GetConfig(dc.ctx).LowLevelRetries, &fs.RangeOption{Start: start, End: end - 1})
+		if err != nil {
+			return fmt.Errorf("failed to open source object: %w", err)
+		}
+		block := buf[:end-start]
+		_, err = io.ReadFull(in, block)
+		_ = in.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read block %d: %w", i, err)
+		}
+		err = dc.writeData(block, start)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deltaCopy updates dst to be the same as src by reading only the
+// blocks of src which have changed. These and the unchanged blocks
+// copied from dst are written to a new file which replaces dst when
+// the transfer is complete. If the transfer fails dst is unchanged.
+//
+// The hashes of the blocks of dst are read from the backend. The
+// hashes of the blocks of src are read from the backend if it
+// supports it, in which case only the blocks which have changed at
+// the same offset are read, otherwise the whole of src is read and
+// the blocks of dst are looked for at any offset in it.
+func deltaCopy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Object, tr *accounting.Transfer) (newDst fs.Object, err error) {
+	ci := fs.GetConfig(ctx)
+	dc := &deltaCopyState{
+		ctx:       ctx,
+		src:       src,
+		dst:       dst,
+		blockSize: int64(ci.DeltaBlockSize),
+	}
+	dc.dstSums, err = dst.(fs.BlockHasher).BlockHashes(ctx, dc.blockSize)
+	if err != nil {
+		return nil, fmt.Errorf("delta copy: failed to read destination block hashes: %w", err)
+	}
+	var srcSums []fs.BlockSum
+	if do, ok := src.(fs.BlockHasher); ok {
+		srcSums, err = do.BlockHashes(ctx, dc.blockSize)
+		if err != nil {
+			return nil, fmt.Errorf("delta copy: failed to read source block hashes: %w", err)
+		}
+	}
+
+	dc.acc = tr.Account(ctx, nil)
+	dc.out, err = dst.(fs.InPlaceWriter).OpenInPlace(ctx, src.Size())
+	if err != nil {
+		return nil, fmt.Errorf("delta copy: failed to open destination: %w", err)
+	}
+	if srcSums != nil {
+		err = dc.copyBlocks(srcSums)
+	} else {
+		err = dc.copyRolling()
+	}
+	if err != nil {
+		if abortErr := dc.out.Abort(); abortErr != nil {
+			fs.Errorf(dst, "delta copy: failed to discard partial copy: %v", abortErr)
+		}
+		return nil, fmt.Errorf("delta copy: %w", err)
+	}
+	err = dc.out.Close()
+	if err != nil {
+		return nil, fmt.Errorf("delta copy: failed to close object after copy: %w", err)
+	}
+
+	obj, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return nil, fmt.Errorf("delta copy: failed to find object after copy: %w", err)
+	}
+	err = obj.SetModTime(ctx, src.ModTime(ctx))
+	switch err {
+	case nil, fs.ErrorCantSetModTime, fs.ErrorCantSetModTimeWithoutDelete:
+	default:
+		return nil, fmt.Errorf("delta copy: failed to set modification time: %w", err)
+	}
+
+	fs.Debugf(src, "Finished delta copy writing %v of %v", fs.SizeSuffix(dc.written), fs.SizeSuffix(src.Size()))
+	return obj, nil
+}
@@ -0,0 +1,165 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noBlockHasherObject is an fs.Object which doesn't support BlockHashes
+type noBlockHasherObject struct {
+	fs.Object
+}
+
+// inPlaceObject is an fs.Object which can be written in place but
+// doesn't support BlockHashes
+type inPlaceObject struct {
+	fs.Object
+}
+
+// OpenInPlace opens the underlying object for writing in place
+func (o inPlaceObject) OpenInPlace(ctx context.Context, size int64) (fs.InPlaceFile, error) {
+	return o.Object.(fs.InPlaceWriter).OpenInPlace(ctx, size)
+}
+
+// errorObject is an fs.Object which returns an error after reading
+// the first block
+type errorObject struct {
+	noBlockHasherObject
+}
+
+// Open the object returning an error after the first block
+func (o errorObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	in, err := o.Object.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(io.LimitReader(in, 1024), iotest.ErrReader(errors.New("read failed"))), in}, nil
+}
+
+func TestDeltaCopy(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.DeltaTransfer = true
+	ci.DeltaBlockSize = 1024
+
+	t1 := fstest.Time("2001-02-03T04:05:06.499999999Z")
+	t2 := fstest.Time("2011-12-25T12:59:59.123456789Z")
+	original := random.String(8 * 1024)
+	for _, test := range []struct {
+		name        string
+		contents    string
+		wrap        bool
+		wantWritten int64
+	}{
+		{
+			name:        "ChangedBlock",
+			contents:    original[:2048] + strings.Repeat("x", 1024) + original[3072:],
+			wantWritten: 1024,
+		},
+		{
+			name:        "ChangedBlockStream",
+			contents:    original[:2048] + strings.Repeat("x", 1024) + original[3072:],
+			wrap:        true,
+			wantWritten: 1024,
+		},
+		{
+			name:        "InsertedStream",
+			contents:    original[:2500] + strings.Repeat("i", 100) + original[2500:],
+			wrap:        true,
+			wantWritten: 3072 + 100 - 2048,
+		},
+		{
+			name:        "DeletedStream",
+			contents:    original[:2500] + original[2600:],
+			wrap:        true,
+			wantWritten: 3072 - 100 - 2048,
+		},
+		{
+			name:        "Extended",
+			contents:    original + strings.Repeat("y", 500),
+			wantWritten: 500,
+		},
+		{
+			name:        "Truncated",
+			contents:    original[:6000],
+			wantWritten: 6000 - 5*1024,
+		},
+		{
+			name:        "TruncatedStream",
+			contents:    original[:6000],
+			wrap:        true,
+			wantWritten: 6000 - 5*1024,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r.WriteFile("file1", original, t1)
+			file1 := r.WriteObject(ctx, "file1", test.contents, t2)
+			dst, err := r.Flocal.NewObject(ctx, "file1")
+			require.NoError(t, err)
+			src, err := r.Fremote.NewObject(ctx, "file1")
+			require.NoError(t, err)
+			if test.wrap {
+				src = noBlockHasherObject{Object: src}
+			}
+			assert.True(t, doDeltaCopy(ctx, dst, src))
+
+			accounting.GlobalStats().ResetCounters()
+			newDst, err := Copy(ctx, r.Flocal, dst, "file1", src)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(test.contents)), newDst.Size())
+			assert.Equal(t, test.wantWritten, accounting.GlobalStats().GetBytes())
+			r.CheckLocalItems(t, file1)
+		})
+	}
+
+	// A failed transfer leaves the destination unchanged
+	t.Run("Error", func(t *testing.T) {
+		file1 := r.WriteFile("file1", original, t1)
+		r.WriteObject(ctx, "file1", strings.Repeat("z", len(original)), t2)
+		dst, err := r.Flocal.NewObject(ctx, "file1")
+		require.NoError(t, err)
+		src, err := r.Fremote.NewObject(ctx, "file1")
+		require.NoError(t, err)
+		tr := accounting.Stats(ctx).NewTransfer(src)
+		_, err = deltaCopy(ctx, r.Flocal, dst, "file1", errorObject{noBlockHasherObject{Object: src}}, tr)
+		tr.Done(ctx, err)
+		assert.Error(t, err)
+		r.CheckLocalItems(t, file1)
+	})
+
+	// Small and new files aren't delta copied
+	r.WriteObject(ctx, "file2", "small", t1)
+	src, err := r.Fremote.NewObject(ctx, "file2")
+	require.NoError(t, err)
+	assert.False(t, doDeltaCopy(ctx, nil, src))
+	r.WriteFile("file2", "other", t2)
+	dst, err := r.Flocal.NewObject(ctx, "file2")
+	require.NoError(t, err)
+	assert.False(t, doDeltaCopy(ctx, dst, src))
+
+	// Destinations which can't hash their blocks aren't delta copied
+	r.WriteFile("file1", original, t1)
+	r.WriteObject(ctx, "file1", original+"z", t2)
+	dst, err = r.Flocal.NewObject(ctx, "file1")
+	require.NoError(t, err)
+	src, err = r.Fremote.NewObject(ctx, "file1")
+	require.NoError(t, err)
+	assert.True(t, doDeltaCopy(ctx, dst, src))
+	assert.False(t, doDeltaCopy(ctx, inPlaceObject{Object: dst}, src))
+}
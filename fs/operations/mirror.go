@@ -0,0 +1,324 @@
+// Mirror a source to many destinations reading it once
+
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/hash"
+)
+
+// mirrorDst is a destination of Mirror
+type mirrorDst struct {
+	f         fs.Fs
+	objects   map[string]fs.Object // objects on the destination before the mirror
+	transfers int64                // number of objects transferred - use atomic
+	errors    int64                // number of errors - use atomic
+	deletes   int64                // number of objects deleted - use atomic
+}
+
+// error records an error transferring o to the destination
+func (d *mirrorDst) error(o interface{}, err error) {
+	atomic.AddInt64(&d.errors, 1)
+	err = fs.CountError(err)
+	fs.Errorf(o, "Failed to mirror to %v: %v", fs.ConfigString(d.f), err)
+}
+
+// mirrorTarget is a destination an object needs transferring to
+type mirrorTarget struct {
+	d   *mirrorDst
+	dst fs.Object // existing object or nil
+}
+
+// canServerSideCopy returns whether src can be copied to f server-side
+func canServerSideCopy(ctx context.Context, f fs.Fs, src fs.Info) bool {
+	ci := fs.GetConfig(ctx)
+	if f.Features().Copy == nil {
+		return false
+	}
+	return SameConfig(src, f) || (SameRemoteType(src, f) && (f.Features().ServerSideAcrossConfigs || ci.ServerSideAcrossConfigs))
+}
+
+// listMirror lists the objects in f into a map
+func listMirror(ctx context.Context, f fs.Fs) (objects map[string]fs.Object, err error) {
+	objects = make(map[string]fs.Object)
+	var mu sync.Mutex
+	err = ListFn(ctx, f, func(o fs.Object) {
+		mu.Lock()
+		objects[o.Remote()] = o
+		mu.Unlock()
+	})
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		err = nil
+	}
+	return objects, err
+}
+
+// fanOut copies src to all the targets reading it only once.
+//
+// It returns the targets which failed.
+func fanOut(ctx context.Context, src fs.Object, targets []mirrorTarget) (failed []mirrorTarget) {
+	ci := fs.GetConfig(ctx)
+	tr := accounting.Stats(ctx).NewTransfer(src)
+	defer func() {
+		tr.Done(ctx, nil) // the failed targets are copied again which reports any errors
+	}()
+
+	// Hash the data with a hash each destination supports so the
+	// uploads can be checked
+	var hashes hash.Set
+	for _, target := range targets {
+		tr.AddRemote(target.d.f)
+		hashes.Add(target.d.f.Hashes().GetOne())
+	}
+	hasher, err := hash.NewMultiHasherTypes(hashes)
+	if err != nil {
+		return targets
+	}
+
+	var options []fs.OpenOption
+	for _, option := range ci.DownloadHeaders {
+		options = append(options, option)
+	}
+	in0, err := NewReOpen(ctx, src, ci.LowLevelRetries, options...)
+	if err != nil {
+		fs.Debugf(src, "Mirror failed to open source: %v", err)
+		return targets
+	}
+	in := tr.Account(ctx, in0)
+
+	var uploadOptions []fs.OpenOption
+	for _, option := range ci.UploadHeaders {
+		uploadOptions = append(uploadOptions, option)
+	}
+	if ci.MetadataSet != nil {
+		uploadOptions = append(uploadOptions, fs.MetadataOption(ci.MetadataSet))
+	}
+
+	// Start an upload reading from a pipe for each target
+	var (
+		wg      sync.WaitGroup
+		writers = make([]*io.PipeWriter, len(targets))
+		newDsts = make([]fs.Object, len(targets))
+		errs    = make([]error, len(targets))
+	)
+	for i := range targets {
+		i := i
+		target := targets[i]
+		pr, pw := io.Pipe()
+		writers[i] = pw
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if target.dst != nil {
+				err = target.dst.Update(ctx, pr, src, uploadOptions...)
+				newDsts[i] = target.dst
+			} else {
+				newDsts[i], err = target.d.f.Put(ctx, pr, src, uploadOptions...)
+			}
+			if err == nil {
+				err = errors.New("upload finished before reading all the data")
+				_, readErr := pr.Read(make([]byte, 1))
+				if readErr == io.EOF {
+					err = nil
+				}
+			}
+			errs[i] = err
+			// Make any more writes to this target fail
+			_ = pr.CloseWithError(fmt.Errorf("upload failed: %w", err))
+		}()
+	}
+
+	// Copy the data to the pipes dropping any which fail
+	buf := make([]byte, 32*1024)
+	live := len(writers)
+	for live > 0 {
+		n, readErr := in.Read(buf)
+		if n > 0 {
+			_, _ = hasher.Write(buf[:n])
+			for i, pw := range writers {
+				if pw == nil {
+					continue
+				}
+				if _, writeErr := pw.Write(buf[:n]); writeErr != nil {
+					writers[i] = nil
+					live--
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			err = fmt.Errorf("failed to read source: %w", readErr)
+			break
+		}
+	}
+	for _, pw := range writers {
+		if pw != nil {
+			_ = pw.CloseWithError(err) // closes with EOF if err is nil
+		}
+	}
+	wg.Wait()
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	// Check the uploads
+	sums := hasher.Sums()
+	for i, target := range targets {
+		newDst := newDsts[i]
+		if errs[i] == nil && err != nil {
+			errs[i] = err
+		}
+		if errs[i] == nil && sizeDiffers(ctx, src, newDst) {
+			errs[i] = fmt.Errorf("corrupted on transfer: sizes differ %d vs %d", src.Size(), newDst.Size())
+		}
+		if errs[i] == nil {
+			ht := target.d.f.Hashes().GetOne()
+			if ht != hash.None {
+				dstSum, hashErr := newDst.Hash(ctx, ht)
+				if hashErr == nil && dstSum != "" && dstSum != sums[ht] {
+					errs[i] = fmt.Errorf("corrupted on transfer: %v hash differ %q vs %q", ht, sums[ht], dstSum)
+				}
+			}
+		}
+		if errs[i] != nil {
+			fs.Debugf(src, "Mirror to %v failed: %v", fs.ConfigString(target.d.f), errs[i])
+			if target.dst == nil {
+				removeFailedCopy(ctx, newDst)
+			}
+			failed = append(failed, target)
+			continue
+		}
+		atomic.AddInt64(&target.d.transfers, 1)
+		fs.Infof(newDst, "Copied (mirror to %v)", fs.ConfigString(target.d.f))
+	}
+	return failed
+}
+
+// mirrorObject copies src to the destinations which need it
+func mirrorObject(ctx context.Context, src fs.Object, dsts []*mirrorDst) {
+	var fanOutTargets, copyTargets []mirrorTarget
+	for _, d := range dsts {
+		dst := d.objects[src.Remote()]
+		if !NeedTransfer(ctx, dst, src) {
+			continue
+		}
+		target := mirrorTarget{d: d, dst: dst}
+		if canServerSideCopy(ctx, d.f, src.Fs()) {
+			copyTargets = append(copyTargets, target)
+		} else {
+			fanOutTargets = append(fanOutTargets, target)
+		}
+	}
+	if len(fanOutTargets) == 0 && len(copyTargets) == 0 {
+		return
+	}
+	if SkipDestructive(ctx, src, "mirror") {
+		return
+	}
+	if len(fanOutTargets) == 1 {
+		copyTargets = append(copyTargets, fanOutTargets...)
+	} else if len(fanOutTargets) > 1 {
+		// Copy any which failed on their own which retries them
+		copyTargets = append(copyTargets, fanOut(ctx, src, fanOutTargets)...)
+	}
+	for _, target := range copyTargets {
+		_, err := Copy(ctx, target.d.f, target.dst, src.Remote(), src)
+		if err != nil {
+			target.d.error(src, err)
+			continue
+		}
+		atomic.AddInt64(&target.d.transfers, 1)
+	}
+}
+
+// Mirror makes each of fdsts the same as fsrc reading each object
+// from the source only once.
+//
+// Objects which need transferring to more than one destination are
+// read once and uploaded to all of them at the same time. If an
+// upload fails it is retried on its own. Objects on a destination
+// which aren't in the source are deleted from it, unless there were
+// errors transferring to it.
+//
+// It returns an error if there were errors with any destination.
+func Mirror(ctx context.Context, fdsts []fs.Fs, fsrc fs.Fs) error {
+	ci := fs.GetConfig(ctx)
+	dsts := make([]*mirrorDst, len(fdsts))
+	for i, f := range fdsts {
+		objects, err := listMirror(ctx, f)
+		if err != nil {
+			return fmt.Errorf("failed to list destination %v: %w", fs.ConfigString(f), err)
+		}
+		dsts[i] = &mirrorDst{f: f, objects: objects}
+	}
+
+	// Transfer the source objects
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		srcNames = make(map[string]struct{})
+		objects  = make(chan fs.Object, ci.Transfers)
+	)
+	for i := 0; i < ci.Transfers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for o := range objects {
+				mirrorObject(ctx, o, dsts)
+			}
+		}()
+	}
+	err := ListFn(ctx, fsrc, func(o fs.Object) {
+		mu.Lock()
+		srcNames[o.Remote()] = struct{}{}
+		mu.Unlock()
+		objects <- o
+	})
+	close(objects)
+	wg.Wait()
+	if err != nil {
+		return fmt.Errorf("failed to list source: %w", err)
+	}
+
+	// Delete the objects not in the source
+	for _, d := range dsts {
+		if d.errors > 0 {
+			fs.Errorf(d.f, "Not deleting files as there were errors mirroring to this destination")
+			continue
+		}
+		for remote, o := range d.objects {
+			if _, found := srcNames[remote]; found {
+				continue
+			}
+			if err := DeleteFile(ctx, o); err != nil {
+				d.error(o, err)
+				continue
+			}
+			d.deletes++
+		}
+	}
+
+	var failed int
+	for _, d := range dsts {
+		fs.Infof(d.f, "Mirror: %d transferred, %d deleted, %d errors", d.transfers, d.deletes, d.errors)
+		if d.errors > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("mirror failed to %d of %d destinations", failed, len(dsts))
+	}
+	return nil
+}
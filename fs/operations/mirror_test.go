@@ -0,0 +1,37 @@
+package operations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirror(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	fdst2, err := fs.NewFs(ctx, t.TempDir())
+	require.NoError(t, err)
+
+	file1 := r.WriteFile("file1", "hello world", t1)
+	file2 := r.WriteFile("sub dir/file2", "potato", t2)
+	r.WriteObject(ctx, "file3", "not in source", t1)
+	file2old := r.WriteObjectTo(ctx, fdst2, "sub dir/file2", "old potato", t1, false)
+	fstest.CheckItems(t, fdst2, file2old)
+
+	err = operations.Mirror(ctx, []fs.Fs{r.Fremote, fdst2}, r.Flocal)
+	require.NoError(t, err)
+	r.CheckLocalItems(t, file1, file2)
+	r.CheckRemoteItems(t, file1, file2)
+	fstest.CheckItems(t, fdst2, file1, file2)
+
+	// Nothing to do when run again
+	err = operations.Mirror(ctx, []fs.Fs{r.Fremote, fdst2}, r.Flocal)
+	require.NoError(t, err)
+	r.CheckRemoteItems(t, file1, file2)
+	fstest.CheckItems(t, fdst2, file1, file2)
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
@@ -32,10 +34,17 @@ func doMultiThreadCopy(ctx context.Context, f fs.Fs, src fs.Object) bool {
 	if src.Size() < int64(ci.MultiThreadCutoff) {
 		return false
 	}
-	// ...source doesn't support it
+	// ...destination doesn't support it and --multi-thread-temp-file
+	// isn't set
 	dstFeatures := f.Features()
 	if dstFeatures.OpenWriterAt == nil {
-		return false
+		if !ci.MultiThreadTempFile {
+			return false
+		}
+		// ...downloading a local source to a temporary file
+		if src.Fs().Features().IsLocal {
+			return false
+		}
 	}
 	// ...if --multi-thread-streams not in use and source and
 	// destination are both local
@@ -139,11 +148,90 @@ func (mc *multiThreadCopyState) calculateChunks() {
 	}
 }
 
-// Copy src to (f, remote) using streams download threads and the OpenWriterAt feature
-func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object, streams int, tr *accounting.Transfer) (newDst fs.Object, err error) {
+// tempFileWriterAt is a fs.WriterAtCloser writing to a local
+// temporary file which is uploaded when all the streams are done.
+type tempFileWriterAt struct {
+	*os.File
+}
+
+// Close does nothing as the file is closed after it has been uploaded
+func (tempFileWriterAt) Close() error {
+	return nil
+}
+
+// openTempFile opens a local temporary file to assemble the streams
+// in, returning an OpenWriterAt function writing to it.
+//
+// The returned cleanup function closes and removes the file.
+func openTempFile() (file *os.File, openWriterAt func(context.Context, string, int64) (fs.WriterAtCloser, error), cleanup func(), err error) {
+	file, err = ioutil.TempFile("", "rclone-multi-thread-")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("multi-thread copy: failed to create temporary file: %w", err)
+	}
+	cleanup = func() {
+		_ = file.Close()
+		if err := os.Remove(file.Name()); err != nil {
+			fs.Errorf(nil, "multi-thread copy: failed to remove temporary file: %v", err)
+		}
+	}
+	openWriterAt = func(ctx context.Context, remote string, size int64) (fs.WriterAtCloser, error) {
+		if err := file.Truncate(size); err != nil {
+			return nil, err
+		}
+		return tempFileWriterAt{file}, nil
+	}
+	return file, openWriterAt, cleanup, nil
+}
+
+// uploadTempFile uploads the assembled file to (f, remote) replacing
+// dst if set.
+//
+// The bytes were accounted as they were downloaded so aren't
+// accounted here.
+func uploadTempFile(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Object, file *os.File) (newDst fs.Object, err error) {
+	ci := fs.GetConfig(ctx)
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var wrappedSrc fs.ObjectInfo = src
+	if src.Remote() != remote {
+		wrappedSrc = NewOverrideRemote(src, remote)
+	}
+	var options []fs.OpenOption
+	for _, option := range ci.UploadHeaders {
+		options = append(options, option)
+	}
+	if ci.MetadataSet != nil {
+		options = append(options, fs.MetadataOption(ci.MetadataSet))
+	}
+	fs.Debugf(src, "multi-thread copy: uploading temporary file")
+	if dst != nil {
+		err = dst.Update(ctx, file, wrappedSrc, options...)
+		return dst, err
+	}
+	return f.Put(ctx, file, wrappedSrc, options...)
+}
+
+// Copy src to (f, remote) using streams download threads and the
+// OpenWriterAt feature.
+//
+// If the destination doesn't support OpenWriterAt then the streams
+// are assembled in a local temporary file which is uploaded to
+// replace dst, or as a new object if dst is nil.
+func multiThreadCopy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Object, streams int, tr *accounting.Transfer) (newDst fs.Object, err error) {
+	ci := fs.GetConfig(ctx)
 	openWriterAt := f.Features().OpenWriterAt
+	var tempFile *os.File
 	if openWriterAt == nil {
-		return nil, errors.New("multi-thread copy: OpenWriterAt not supported")
+		if !ci.MultiThreadTempFile {
+			return nil, errors.New("multi-thread copy: OpenWriterAt not supported")
+		}
+		var cleanup func()
+		tempFile, openWriterAt, cleanup, err = openTempFile()
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
 	}
 	if src.Size() < 0 {
 		return nil, errors.New("multi-thread copy: can't copy unknown sized file")
@@ -186,6 +274,15 @@ func multiThreadCopy(ctx context.Context, f fs.Fs, remote string, src fs.Object,
 		return nil, fmt.Errorf("multi-thread copy: failed to close object after copy: %w", closeErr)
 	}
 
+	if tempFile != nil {
+		obj, err := uploadTempFile(ctx, f, dst, remote, src, tempFile)
+		if err != nil {
+			return nil, fmt.Errorf("multi-thread copy: failed to upload temporary file: %w", err)
+		}
+		fs.Debugf(src, "Finished multi-thread copy via temporary file with %d parts of size %v", mc.streams, fs.SizeSuffix(mc.partSize))
+		return obj, nil
+	}
+
 	obj, err := f.NewObject(ctx, remote)
 	if err != nil {
 		return nil, fmt.Errorf("multi-thread copy: failed to find object after copy: %w", err)
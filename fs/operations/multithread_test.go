@@ -27,10 +27,12 @@ func TestDoMultiThreadCopy(t *testing.T) {
 	oldStreams := ci.MultiThreadStreams
 	oldCutoff := ci.MultiThreadCutoff
 	oldIsSet := ci.MultiThreadSet
+	oldTempFile := ci.MultiThreadTempFile
 	defer func() {
 		ci.MultiThreadStreams = oldStreams
 		ci.MultiThreadCutoff = oldCutoff
 		ci.MultiThreadSet = oldIsSet
+		ci.MultiThreadTempFile = oldTempFile
 	}()
 
 	ci.MultiThreadStreams, ci.MultiThreadCutoff = 4, 50
@@ -59,6 +61,12 @@ func TestDoMultiThreadCopy(t *testing.T) {
 
 	f.Features().OpenWriterAt = nil
 	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	ci.MultiThreadTempFile = true
+	assert.True(t, doMultiThreadCopy(ctx, f, src))
+	srcFs.Features().IsLocal = true
+	assert.False(t, doMultiThreadCopy(ctx, f, src))
+	srcFs.Features().IsLocal = false
+	ci.MultiThreadTempFile = false
 	f.Features().OpenWriterAt = nullWriterAt
 	assert.True(t, doMultiThreadCopy(ctx, f, src))
 
@@ -136,7 +144,7 @@ func TestMultithreadCopy(t *testing.T) {
 			defer func() {
 				tr.Done(ctx, err)
 			}()
-			dst, err := multiThreadCopy(ctx, r.Flocal, "file1", src, 2, tr)
+			dst, err := multiThreadCopy(ctx, r.Flocal, nil, "file1", src, 2, tr)
 			require.NoError(t, err)
 			assert.Equal(t, src.Size(), dst.Size())
 			assert.Equal(t, "file1", dst.Remote())
@@ -145,5 +153,57 @@ func TestMultithreadCopy(t *testing.T) {
 			require.NoError(t, dst.Remove(ctx))
 		})
 	}
+}
+
+// noWriterAtFs is a fs.Fs which doesn't support OpenWriterAt
+type noWriterAtFs struct {
+	fs.Fs
+	features *fs.Features
+}
+
+// Features returns the optional features of this Fs
+func (f *noWriterAtFs) Features() *fs.Features {
+	return f.features
+}
+
+func TestMultithreadCopyTempFile(t *testing.T) {
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+
+	features := *r.Flocal.Features()
+	features.OpenWriterAt = nil
+	f := &noWriterAtFs{Fs: r.Flocal, features: &features}
+
+	contents := random.String(multithreadChunkSize*2 + 1)
+	t1 := fstest.Time("2001-02-03T04:05:06.499999999Z")
+	file1 := r.WriteObject(ctx, "file1", contents, t1)
+	r.CheckRemoteItems(t, file1)
+
+	src, err := r.Fremote.NewObject(ctx, "file1")
+	require.NoError(t, err)
+	tr := accounting.GlobalStats().NewTransfer(src)
+	defer func() {
+		tr.Done(ctx, err)
+	}()
 
+	_, err = multiThreadCopy(ctx, f, nil, "file1", src, 2, tr)
+	require.Error(t, err)
+
+	ci.MultiThreadTempFile = true
+	dst, err := multiThreadCopy(ctx, f, nil, "file1", src, 2, tr)
+	require.NoError(t, err)
+	assert.Equal(t, src.Size(), dst.Size())
+	fstest.CheckListingWithPrecision(t, r.Flocal, []fstest.Item{file1}, nil, fs.GetModifyWindow(ctx, r.Flocal, r.Fremote))
+
+	// Replace the existing object
+	contents2 := random.String(multithreadChunkSize*2 + 2)
+	file2 := r.WriteObject(ctx, "file1", contents2, t1)
+	src, err = r.Fremote.NewObject(ctx, "file1")
+	require.NoError(t, err)
+	dst, err = multiThreadCopy(ctx, f, dst, "file1", src, 2, tr)
+	require.NoError(t, err)
+	assert.Equal(t, src.Size(), dst.Size())
+	fstest.CheckListingWithPrecision(t, r.Flocal, []fstest.Item{file2}, nil, fs.GetModifyWindow(ctx, r.Flocal, r.Fremote))
 }
@@ -158,6 +158,7 @@ type equalOpt struct {
 	checkSum          bool // if set check checksum+size instead of modtime+size
 	updateModTime     bool // if set update the modtime if hashes identical and checking with modtime+size
 	forceModTimeMatch bool // if set assume modtimes match
+	sampledBytes      bool // if set check hash+size or sampled bytes+size instead of modtime+size
 }
 
 // default set of options for equal()
@@ -168,6 +169,7 @@ func defaultEqualOpt(ctx context.Context) equalOpt {
 		checkSum:          ci.CheckSum,
 		updateModTime:     !ci.NoUpdateModTime,
 		forceModTimeMatch: false,
+		sampledBytes:      ci.Compare == fs.CompareModeSampledBytes,
 	}
 }
 
@@ -193,6 +195,11 @@ func equal(ctx context.Context, src fs.ObjectInfo, dst fs.Object, opt equalOpt)
 
 	// Assert: Size is equal or being ignored
 
+	// If comparing sampled bytes the modtimes aren't trusted
+	if opt.sampledBytes {
+		return equalSampledBytes(ctx, src, dst)
+	}
+
 	// If checking checksum and not modtime
 	if opt.checkSum {
 		// Check the hash
@@ -282,6 +289,45 @@ func equal(ctx context.Context, src fs.ObjectInfo, dst fs.Object, opt equalOpt)
 	return true
 }
 
+// compareSamples is the number of byte ranges read by
+// equalSampledBytes
+const compareSamples = 4
+
+// equalSampledBytes checks src and dst are the same using their
+// hashes if possible, otherwise by reading the same few byte ranges
+// from each of them.
+//
+// Sizes must have been checked already.
+func equalSampledBytes(ctx context.Context, src fs.ObjectInfo, dst fs.Object) bool {
+	same, ht, _ := CheckHashes(ctx, src, dst)
+	if !same {
+		fs.Debugf(src, "%v differ", ht)
+		return false
+	}
+	if ht != hash.None {
+		fs.Debugf(src, "Size and %v of src and dst objects identical", ht)
+		return true
+	}
+	srcObj, ok := src.(fs.Object)
+	if !ok || src.Size() < 0 || src.Size() != dst.Size() {
+		fs.Debugf(src, "Can't compare sampled bytes so treating as different")
+		return false
+	}
+	ci := fs.GetConfig(ctx)
+	differ, err := checkIdenticalOffsets(ctx, dst, srcObj, fixedSampleOffsets(src.Size(), compareSamples, int64(ci.CompareSampleSize)), int64(ci.CompareSampleSize))
+	if err != nil {
+		err = fs.CountError(err)
+		fs.Errorf(src, "Failed to compare sampled bytes: %v", err)
+		return false
+	}
+	if differ {
+		fs.Debugf(src, "Sampled bytes differ")
+		return false
+	}
+	fs.Debugf(src, "Size and sampled bytes of src and dst objects identical")
+	return true
+}
+
 // Used to remove a failed copy
 //
 // Returns whether the file was successfully removed or not
@@ -391,6 +437,7 @@ func CommonHash(ctx context.Context, fa, fb fs.Info) (hash.Type, *fs.HashesOptio
 func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Object) (newDst fs.Object, err error) {
 	ci := fs.GetConfig(ctx)
 	tr := accounting.Stats(ctx).NewTransfer(src)
+	tr.AddRemote(f)
 	defer func() {
 		tr.Done(ctx, err)
 	}()
@@ -404,8 +451,10 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 	tries := 0
 	doUpdate := dst != nil
 	hashType, hashOption := CommonHash(ctx, f, src.Fs())
+	checkTries := 0
 
 	var actionTaken string
+again:
 	for {
 		// Try server-side copy first - if has optional interface and
 		// is same underlying remote
@@ -424,7 +473,18 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 				return nil, accounting.ErrorMaxTransferLimitReachedGraceful
 			}
 		}
-		if doCopy := f.Features().Copy; doCopy != nil && (SameConfig(src.Fs(), f) || (SameRemoteType(src.Fs(), f) && (f.Features().ServerSideAcrossConfigs || ci.ServerSideAcrossConfigs))) {
+		doCopy := f.Features().Copy
+		if doCopy == nil || !(SameConfig(src.Fs(), f) || (SameRemoteType(src.Fs(), f) && (f.Features().ServerSideAcrossConfigs || ci.ServerSideAcrossConfigs))) {
+			doCopy = nil
+			// Try a server-side copy registered for this pair of remotes
+			if crossCopy := fs.FindCrossCopy(f, src.Fs()); crossCopy != nil {
+				doCopy = func(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+					return crossCopy(ctx, f, src, remote)
+				}
+				actionTaken = "Copied (cross remote server-side copy)"
+			}
+		}
+		if doCopy != nil {
 			in := tr.Account(ctx, nil) // account the transfer
 			in.ServerSideCopyStart()
 			newDst, err = doCopy(ctx, src, remote)
@@ -443,7 +503,14 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 		}
 		// If can't server-side copy, do it manually
 		if err == fs.ErrorCantCopy {
-			if doMultiThreadCopy(ctx, f, src) {
+			if doDeltaCopy(ctx, dst, src) {
+				var deltaDst fs.Object
+				deltaDst, err = deltaCopy(ctx, f, dst, remote, src, tr)
+				if err == nil {
+					dst, newDst = deltaDst, deltaDst
+				}
+				actionTaken = "Delta Copied (replaced existing)"
+			} else if doMultiThreadCopy(ctx, f, src) {
 				// Number of streams proportional to size
 				streams := src.Size() / int64(ci.MultiThreadCutoff)
 				// With maximum
@@ -453,7 +520,7 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 				if streams < 2 {
 					streams = 2
 				}
-				dst, err = multiThreadCopy(ctx, f, remote, src, int(streams), tr)
+				dst, err = multiThreadCopy(ctx, f, dst, remote, src, int(streams), tr)
 				if doUpdate {
 					actionTaken = "Multi-thread Copied (replaced existing)"
 				} else {
@@ -493,6 +560,11 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 						if ci.MetadataSet != nil {
 							options = append(options, fs.MetadataOption(ci.MetadataSet))
 						}
+						var resume *resumeState
+						if ci.ResumeUploads {
+							resume = newResumeState(f, remote, src)
+							options = append(options, resume.option())
+						}
 						if doUpdate {
 							actionTaken = "Copied (replaced existing)"
 							err = dst.Update(ctx, in, wrappedSrc, options...)
@@ -505,6 +577,9 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 							newDst = dst
 							err = closeErr
 						}
+						if err == nil && resume != nil {
+							resume.remove()
+						}
 					}
 				}
 			}
@@ -560,6 +635,29 @@ func Copy(ctx context.Context, f fs.Fs, dst fs.Object, remote string, src fs.Obj
 			return newDst, err
 		}
 	}
+
+	// Read the object back and check it if required
+	if ci.CheckAfter {
+		err = checkAfterCopy(ctx, src, dst)
+		if err != nil {
+			fs.Errorf(dst, "%v", err)
+			if checkTries < ci.CheckAfterRetries {
+				checkTries++
+				fs.Logf(src, "Copying again after failed check %d/%d", checkTries, ci.CheckAfterRetries)
+				if removeFailedCopy(ctx, dst) {
+					dst, doUpdate = nil, false
+				}
+				newDst = dst
+				tries = 0
+				tr.Reset(ctx)
+				goto again
+			}
+			err = fs.CountError(err)
+			removeFailedCopy(ctx, dst)
+			return newDst, err
+		}
+	}
+
 	if newDst != nil && src.String() != newDst.String() {
 		fs.Infof(src, "%s to: %s", actionTaken, newDst.String())
 	} else {
@@ -712,6 +810,7 @@ func DeleteFileWithBackupDir(ctx context.Context, dst fs.Object, backupDir fs.Fs
 	} else if backupDir != nil {
 		err = MoveBackupDir(ctx, backupDir, dst)
 	} else {
+		accounting.LimitDeletes(ctx)
 		err = dst.Remove(ctx)
 	}
 	if err != nil {
@@ -868,24 +967,18 @@ func SameDir(fdst, fsrc fs.Info) bool {
 }
 
 // Retry runs fn up to maxTries times if it returns a retriable error
+//
+// If maxTries is less than 1 then fn isn't called at all and nil is
+// returned.
+//
+// Errors marked with fserrors.NoLowLevelRetryError aren't retried and
+// a Retry-After error is waited for before retrying. See fs.Retry for
+// retries with backoff and statistics.
 func Retry(ctx context.Context, o interface{}, maxTries int, fn func() error) (err error) {
-	for tries := 1; tries <= maxTries; tries++ {
-		// Call the function which might error
-		err = fn()
-		if err == nil {
-			break
-		}
-		// Retry if err returned a retry error
-		if fserrors.ContextError(ctx, &err) {
-			break
-		}
-		if fserrors.IsRetryError(err) || fserrors.ShouldRetry(err) {
-			fs.Debugf(o, "Received error: %v - low level retry %d/%d", err, tries, maxTries)
-			continue
-		}
-		break
+	if maxTries < 1 {
+		return nil
 	}
-	return err
+	return fs.Retry(ctx, o, fs.RetryOptions{Retries: maxTries}, fn)
 }
 
 // ListFn lists the Fs to the supplied function
@@ -1188,6 +1281,7 @@ func TryRmdir(ctx context.Context, f fs.Fs, dir string) error {
 		return nil
 	}
 	fs.Infof(fs.LogDirName(f, dir), "Removing directory")
+	accounting.LimitDeletes(ctx)
 	return f.Rmdir(ctx, dir)
 }
 
@@ -1210,6 +1304,7 @@ func Purge(ctx context.Context, f fs.Fs, dir string) (err error) {
 		if SkipDestructive(ctx, fs.LogDirName(f, dir), "purge directory") {
 			return nil
 		}
+		accounting.LimitDeletes(ctx)
 		err = doPurge(ctx, dir)
 		if err == fs.ErrorCantPurge {
 			doFallbackPurge = true
@@ -5,11 +5,15 @@ package operations
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,3 +45,59 @@ func TestSizeDiffers(t *testing.T) {
 		assert.Equal(t, test.want, got, fmt.Sprintf("ignoreSize=%v, srcSize=%v, dstSize=%v", test.ignoreSize, test.srcSize, test.dstSize))
 	}
 }
+
+func TestFixedSampleOffsets(t *testing.T) {
+	for _, test := range []struct {
+		size       int64
+		samples    int
+		sampleSize int64
+		want       []int64
+	}{
+		{100, 4, 10, []int64{0, 30, 60, 90}},
+		{101, 3, 10, []int64{0, 45, 91}},
+		{100, 1, 10, []int64{0}},
+		{40, 4, 10, nil},
+		{100, 0, 10, nil},
+		{100, 4, 0, nil},
+	} {
+		got := fixedSampleOffsets(test.size, test.samples, test.sampleSize)
+		assert.Equal(t, test.want, got, fmt.Sprintf("size=%d, samples=%d, sampleSize=%d", test.size, test.samples, test.sampleSize))
+		assert.Equal(t, got, fixedSampleOffsets(test.size, test.samples, test.sampleSize), "must be deterministic")
+	}
+}
+
+func TestEqualSampledBytes(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	ci.CompareSampleSize = 10
+	f := mockfs.NewFs(ctx, "mock", "")
+
+	contents := []byte(strings.Repeat("0123456789", 100))
+	newObject := func(name string, edit func(b []byte)) fs.Object {
+		b := append([]byte(nil), contents...)
+		if edit != nil {
+			edit(b)
+		}
+		o := mockobject.New(name).WithContent(b, mockobject.SeekModeNone)
+		o.SetFs(f)
+		return o
+	}
+	src := newObject("src", nil)
+	for _, test := range []struct {
+		name string
+		dst  fs.Object
+		want bool
+	}{
+		{"same", newObject("same", nil), true},
+		{"start", newObject("start", func(b []byte) { b[0] = 'X' }), false},
+		{"middle", newObject("middle", func(b []byte) { b[333] = 'X' }), false},
+		{"end", newObject("end", func(b []byte) { b[len(b)-1] = 'X' }), false},
+		{"unsampled", newObject("unsampled", func(b []byte) { b[500] = 'X' }), true},
+	} {
+		assert.Equal(t, test.want, equalSampledBytes(ctx, src, test.dst), test.name)
+	}
+
+	// Check the hashes are used if available
+	f.SetHashes(hash.NewHashSet(hash.MD5))
+	assert.False(t, equalSampledBytes(ctx, src, newObject("unsampled", func(b []byte) { b[500] = 'X' })))
+}
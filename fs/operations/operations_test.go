@@ -453,6 +453,20 @@ func TestRetry(t *testing.T) {
 	assert.Equal(t, fs.ErrorObjectNotFound, operations.Retry(ctx, nil, 5, fn))
 	assert.Equal(t, 9, i)
 
+	i, err = 10, io.EOF
+	assert.Equal(t, nil, operations.Retry(ctx, nil, 0, fn))
+	assert.Equal(t, 10, i)
+
+	i, err = 10, fserrors.NoLowLevelRetryError(io.EOF)
+	assert.Equal(t, err, operations.Retry(ctx, nil, 5, fn))
+	assert.Equal(t, 9, i)
+
+	i, err = 3, fserrors.NewErrorRetryAfter(50*time.Millisecond)
+	start := time.Now()
+	assert.Equal(t, nil, operations.Retry(ctx, nil, 5, fn))
+	assert.Equal(t, 0, i)
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+
 }
 
 func TestCat(t *testing.T) {
@@ -976,6 +990,68 @@ func TestCopyFile(t *testing.T) {
 	r.CheckRemoteItems(t, file2)
 }
 
+func TestCopyFileCrossCopy(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	if r.Fremote.Features().Copy != nil {
+		t.Skip("remote can copy server-side already")
+	}
+
+	// Register a cross copy which only works while enabled
+	enabled, calls := true, 0
+	fs.RegisterCrossCopy(r.Flocal, r.Fremote, func(ctx context.Context, dst fs.Fs, src fs.Object, remote string) (dstObj fs.Object, err error) {
+		if !enabled {
+			return nil, fs.ErrorCantCopy
+		}
+		calls++
+		in, err := src.Open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer fs.CheckClose(in, &err)
+		return dst.Put(ctx, in, operations.NewOverrideRemote(src, remote))
+	})
+	defer func() {
+		enabled = false
+	}()
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	file2 := file1
+	file2.Path = "sub/file2"
+	err := operations.CopyFile(ctx, r.Fremote, r.Flocal, file2.Path, file1.Path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	r.CheckRemoteItems(t, file2)
+
+	// Check it falls back to a normal copy when the cross copy can't
+	enabled = false
+	file3 := file1
+	file3.Path = "sub/file3"
+	err = operations.CopyFile(ctx, r.Fremote, r.Flocal, file3.Path, file1.Path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	r.CheckRemoteItems(t, file2, file3)
+}
+
+func TestCopyFileCheckAfter(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	ci.CheckAfter = true
+	ci.CheckAfterRetries = 1
+
+	file1 := r.WriteFile("file1", "file1 contents", t1)
+	r.CheckLocalItems(t, file1)
+
+	err := operations.CopyFile(ctx, r.Fremote, r.Flocal, file1.Path, file1.Path)
+	require.NoError(t, err)
+	r.CheckLocalItems(t, file1)
+	r.CheckRemoteItems(t, file1)
+}
+
 func TestCopyFileBackupDir(t *testing.T) {
 	ctx := context.Background()
 	ctx, ci := fs.AddConfig(ctx)
@@ -2,6 +2,7 @@ package operations
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -207,7 +208,7 @@ func init() {
 		{name: "delete", title: "Remove files in the path", noRemote: true},
 		{name: "deletefile", title: "Remove the single file pointed to"},
 		{name: "copyurl", title: "Copy the URL to the object", help: "- url - string, URL to read from\n - autoFilename - boolean, set to true to retrieve destination file name from url\n"},
-		{name: "uploadfile", title: "Upload file using multiform/form-data", help: "- each part in body represents a file to be uploaded\n", needsRequest: true},
+		{name: "uploadfile", title: "Upload file using multiform/form-data", help: "- each part in body represents a file to be uploaded\n- modTime - modification time of the files in RFC3339 format (optional, default now)\n", needsRequest: true},
 		{name: "cleanup", title: "Remove trashed files in the remote or path", noRemote: true},
 	} {
 		op := op
@@ -233,12 +234,29 @@ See the [` + op.name + `](/commands/rclone_` + op.name + `/) command for more in
 	}
 }
 
+// CheckDeleteConfirm returns an error if --delete-confirm is set.
+//
+// The remote control calls which delete files use it as there is no
+// one to confirm the deletes.
+func CheckDeleteConfirm(ctx context.Context) error {
+	if fs.GetConfig(ctx).DeleteConfirm {
+		return errors.New("can't use --delete-confirm with the remote control as there is no one to confirm the deletes")
+	}
+	return nil
+}
+
 // Run a single command, e.g. Mkdir
 func rcSingleCommand(ctx context.Context, in rc.Params, name string, noRemote bool) (out rc.Params, err error) {
 	var (
 		f      fs.Fs
 		remote string
 	)
+	switch name {
+	case "purge", "delete", "deletefile":
+		if err := CheckDeleteConfirm(ctx); err != nil {
+			return nil, err
+		}
+	}
 	if noRemote {
 		f, err = rc.GetFs(ctx, in)
 	} else {
@@ -294,6 +312,17 @@ func rcSingleCommand(ctx context.Context, in rc.Params, name string, noRemote bo
 			return nil, err
 		}
 
+		modTime := time.Now()
+		modTimeString, err := in.GetString("modTime")
+		if err == nil {
+			modTime, err = time.Parse(time.RFC3339Nano, modTimeString)
+			if err != nil {
+				return nil, rc.NewErrParamInvalid(fmt.Errorf("bad modTime: %w", err))
+			}
+		} else if rc.NotErrParamNotFound(err) {
+			return nil, err
+		}
+
 		if strings.HasPrefix(mediaType, "multipart/") {
 			mr := multipart.NewReader(request.Body, params["boundary"])
 			for {
@@ -305,7 +334,7 @@ func rcSingleCommand(ctx context.Context, in rc.Params, name string, noRemote bo
 					return nil, err
 				}
 				if p.FileName() != "" {
-					obj, err := Rcat(ctx, f, path.Join(remote, p.FileName()), p, time.Now())
+					obj, err := Rcat(ctx, f, path.Join(remote, p.FileName()), p, modTime)
 					if err != nil {
 						return nil, err
 					}
@@ -620,3 +649,75 @@ func rcBackend(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	out["result"] = result
 	return out, nil
 }
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "backend/stats",
+		Fn:    rcBackendStats,
+		Title: "Returns counters of API calls for each remote.",
+		Help: `This takes the following parameters:
+
+- remote - a remote name e.g. "drive" to only return that one (optional)
+
+Returns:
+
+- remotes - a map of remote name to its counters
+
+The counters for each remote are
+
+- calls - number of API calls made
+- retries - number of API calls which needed retrying
+- throttles - number of times the remote asked rclone to slow down
+- tokenRefreshes - number of times the authentication token was renewed
+- tokenExpiry - when the authentication token expires, if known
+- latency - a histogram of how long the API calls took, if any were made
+    - count - number of API calls timed
+    - mean - mean time of an API call in seconds
+    - buckets - number of API calls which took up to "le" seconds and
+      longer than the bucket before, the last bucket having no "le"
+- pacerSleep - the time in seconds the remote is currently waiting between API calls
+- errors - the most recent errors from API calls with the time they happened
+- history - the number of calls, retries and errors in each minute of the last hour
+
+Counters are only kept for backends which support them.
+
+Example:
+
+    rclone rc backend/stats remote=drive
+
+Returns
+
+` + "```" + `
+{
+	"remotes": {
+		"drive": {
+			"calls": 42,
+			"retries": 1,
+			"throttles": 1,
+			"tokenRefreshes": 0,
+			"tokenExpiry": "2022-06-01T12:00:00Z"
+		}
+	}
+}
+` + "```" + `
+`,
+	})
+}
+
+// Return the counters of API calls for the remotes
+func rcBackendStats(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	remote, err := in.GetString("remote")
+	if rc.NotErrParamNotFound(err) {
+		return nil, err
+	}
+	remotes := rc.Params{}
+	for _, name := range fs.CountersNames() {
+		if remote == "" || name == remote {
+			remotes[name] = fs.GetCounters(name).Stats()
+		}
+	}
+	out = rc.Params{
+		"remotes": remotes,
+	}
+	return out, nil
+}
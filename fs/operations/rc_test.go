@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strings"
 	"testing"
 	"time"
 
@@ -176,6 +177,26 @@ func TestRcDelete(t *testing.T) {
 	r.CheckRemoteItems(t)
 }
 
+// operations/delete: --delete-confirm can't be used as there is no one to ask
+func TestRcDeleteConfirm(t *testing.T) {
+	r, call := rcNewRun(t, "operations/delete")
+	defer r.Finalise()
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.DeleteConfirm = true
+
+	file1 := r.WriteObject(ctx, "small", "1234567890", t2)
+	r.CheckRemoteItems(t, file1)
+
+	in := rc.Params{
+		"fs": r.FremoteName,
+	}
+	_, err := call.Fn(ctx, in)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--delete-confirm")
+
+	r.CheckRemoteItems(t, file1)
+}
+
 // operations/deletefile: Remove the single file pointed to
 func TestRcDeletefile(t *testing.T) {
 	r, call := rcNewRun(t, "operations/deletefile")
@@ -556,6 +577,72 @@ func TestUploadFile(t *testing.T) {
 
 }
 
+// operations/uploadfile: Tests the modification time can be set
+func TestUploadFileModTime(t *testing.T) {
+	r, call := rcNewRun(t, "operations/uploadfile")
+	defer r.Finalise()
+	ctx := context.Background()
+
+	testFileName := "test.txt"
+	testFileContent := "Hello World"
+	testItem := fstest.NewItem(testFileName, testFileContent, t2)
+
+	formReader, contentType, _, err := rest.MultipartUpload(ctx, strings.NewReader(testFileContent), url.Values{}, "file", testFileName)
+	require.NoError(t, err)
+
+	httpReq := httptest.NewRequest("POST", "/", formReader)
+	httpReq.Header.Add("Content-Type", contentType)
+
+	in := rc.Params{
+		"_request": httpReq,
+		"fs":       r.FremoteName,
+		"remote":   "",
+		"modTime":  t2.Format(time.RFC3339Nano),
+	}
+
+	_, err = call.Fn(context.Background(), in)
+	require.NoError(t, err)
+
+	r.CheckRemoteItems(t, testItem)
+
+	in["modTime"] = "yesterday"
+	_, err = call.Fn(context.Background(), in)
+	assert.True(t, rc.IsErrParamInvalid(err))
+}
+
+// backend/stats: Returns counters of API calls for each remote
+func TestRcBackendStats(t *testing.T) {
+	call := rc.Calls.Get("backend/stats")
+	require.NotNil(t, call)
+	counters := fs.GetCounters("TestRcBackendStats")
+	counters.Call()
+	counters.Call()
+	counters.Retry()
+
+	out, err := call.Fn(context.Background(), rc.Params{"remote": "TestRcBackendStats"})
+	require.NoError(t, err)
+	remotes, ok := out["remotes"].(rc.Params)
+	require.True(t, ok)
+	stats, ok := remotes["TestRcBackendStats"].(fs.CountersStats)
+	require.True(t, ok)
+	require.Len(t, stats.History, 1)
+	assert.Equal(t, int64(2), stats.History[0].Calls)
+	stats.History = nil
+	remotes["TestRcBackendStats"] = stats
+	assert.Equal(t, rc.Params{
+		"remotes": rc.Params{
+			"TestRcBackendStats": fs.CountersStats{
+				Calls:   2,
+				Retries: 1,
+			},
+		},
+	}, out)
+
+	out, err = call.Fn(context.Background(), rc.Params{})
+	require.NoError(t, err)
+	assert.Contains(t, out["remotes"], "TestRcBackendStats")
+}
+
 // operations/command: Runs a backend command
 func TestRcCommand(t *testing.T) {
 	r, call := rcNewRun(t, "backend/command")
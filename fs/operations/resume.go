@@ -0,0 +1,98 @@
+// Saving the state of uploads so they can be resumed
+
+package operations
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+)
+
+// resumeMaxAge is how long the state of an interrupted upload is kept
+const resumeMaxAge = 7 * 24 * time.Hour
+
+// resumeRecord is what is saved for an upload which can be resumed
+type resumeRecord struct {
+	Remote  string    // the remote being uploaded to
+	Size    int64     // size of the source
+	ModTime time.Time // modification time of the source
+	State   string    // state saved by the backend
+	Saved   time.Time // when the state was saved
+}
+
+// resumeState saves the state of an upload in the cache directory
+type resumeState struct {
+	path string
+	rec  resumeRecord
+}
+
+// newResumeState returns the saved state for uploading src to remote
+// on f, reading any state saved by an interrupted upload of it
+func newResumeState(f fs.Fs, remote string, src fs.ObjectInfo) *resumeState {
+	key := md5.Sum([]byte(fs.ConfigString(f) + "\x00" + remote))
+	rs := &resumeState{
+		path: filepath.Join(config.GetCacheDir(), "resume", hex.EncodeToString(key[:])+".json"),
+		rec: resumeRecord{
+			Remote:  remote,
+			Size:    src.Size(),
+			ModTime: src.ModTime(context.Background()),
+		},
+	}
+	data, err := ioutil.ReadFile(rs.path)
+	if err != nil {
+		return rs
+	}
+	var rec resumeRecord
+	err = json.Unmarshal(data, &rec)
+	switch {
+	case err != nil:
+		fs.Debugf(remote, "Ignoring bad resume state: %v", err)
+	case rec.Remote != remote || rec.Size != rs.rec.Size || !rec.ModTime.Equal(rs.rec.ModTime):
+		fs.Debugf(remote, "Not resuming upload as the source has changed")
+	case time.Since(rec.Saved) > resumeMaxAge:
+		fs.Debugf(remote, "Not resuming upload as it was interrupted more than %v ago", resumeMaxAge)
+	default:
+		rs.rec.State = rec.State
+		rs.rec.Saved = rec.Saved
+	}
+	return rs
+}
+
+// save saves state for the upload
+func (rs *resumeState) save(state string) error {
+	rs.rec.State = state
+	rs.rec.Saved = time.Now()
+	data, err := json.Marshal(&rs.rec)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(rs.path), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(rs.path, data, 0600)
+}
+
+// option returns an option to pass to Put or Update to resume the upload
+func (rs *resumeState) option() *fs.ResumeOption {
+	return &fs.ResumeOption{
+		State: rs.rec.State,
+		Save:  rs.save,
+	}
+}
+
+// remove removes the saved state once the upload is finished
+func (rs *resumeState) remove() {
+	err := os.Remove(rs.path)
+	if err != nil && !os.IsNotExist(err) {
+		fs.Debugf(rs.rec.Remote, "Failed to remove resume state: %v", err)
+	}
+}
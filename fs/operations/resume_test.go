@@ -0,0 +1,40 @@
+package operations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeState(t *testing.T) {
+	ctx := context.Background()
+	oldCacheDir := config.GetCacheDir()
+	require.NoError(t, config.SetCacheDir(t.TempDir()))
+	defer func() {
+		_ = config.SetCacheDir(oldCacheDir)
+	}()
+	f := mockfs.NewFs(ctx, "potato", "")
+	src := mockobject.New("file.txt").WithContent([]byte("hello world"), mockobject.SeekModeNone)
+
+	rs := newResumeState(f, "file.txt", src)
+	opt := rs.option()
+	assert.Equal(t, "", opt.State)
+	require.NoError(t, opt.SaveState("session"))
+
+	// State is read back for the same file
+	assert.Equal(t, "session", newResumeState(f, "file.txt", src).option().State)
+
+	// But not for a different file or a changed source
+	assert.Equal(t, "", newResumeState(f, "other.txt", src).option().State)
+	changed := mockobject.New("file.txt").WithContent([]byte("hello"), mockobject.SeekModeNone)
+	assert.Equal(t, "", newResumeState(f, "file.txt", changed).option().State)
+
+	// Nor once it is removed
+	rs.remove()
+	assert.Equal(t, "", newResumeState(f, "file.txt", src).option().State)
+}
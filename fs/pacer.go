@@ -4,6 +4,7 @@ package fs
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/rclone/rclone/fs/fserrors"
@@ -13,10 +14,13 @@ import (
 // Pacer is a simple wrapper around a pacer.Pacer with logging.
 type Pacer struct {
 	*pacer.Pacer
+	counters atomic.Value // *Counters to record calls in if set
+	limit    backendLimit // limit on the calls of all backends of this type if set
 }
 
 type logCalculator struct {
 	pacer.Calculator
+	p *Pacer
 }
 
 // NewPacer creates a Pacer for the given Fs and Calculator.
@@ -27,24 +31,43 @@ func NewPacer(ctx context.Context, c pacer.Calculator) *Pacer {
 		retries = 1
 	}
 	p := &Pacer{
-		Pacer: pacer.New(
-			pacer.InvokerOption(pacerInvoker),
-			pacer.MaxConnectionsOption(ci.Checkers+ci.Transfers),
-			pacer.RetriesOption(retries),
-			pacer.CalculatorOption(c),
-		),
+		limit: getBackendLimit(ctx),
 	}
+	p.Pacer = pacer.New(
+		pacer.InvokerOption(p.invoke),
+		pacer.MaxConnectionsOption(ci.Checkers+ci.Transfers),
+		pacer.RetriesOption(retries),
+		pacer.CalculatorOption(c),
+	)
 	p.SetCalculator(c)
 	return p
 }
 
+// SetCounters sets the Counters to record the API calls, retries and
+// throttling of this Pacer in.
+func (p *Pacer) SetCounters(c *Counters) {
+	p.counters.Store(c)
+}
+
+// getCounters returns the Counters set with SetCounters or nil
+func (p *Pacer) getCounters() *Counters {
+	c, _ := p.counters.Load().(*Counters)
+	return c
+}
+
 func (d *logCalculator) Calculate(state pacer.State) time.Duration {
 	oldSleepTime := state.SleepTime
 	newSleepTime := d.Calculator.Calculate(state)
+	if d.p != nil {
+		d.p.getCounters().SetPacerSleep(newSleepTime)
+	}
 	if state.ConsecutiveRetries > 0 {
 		if newSleepTime != oldSleepTime {
 			Debugf("pacer", "Rate limited, increasing sleep to %v", newSleepTime)
 		}
+		if newSleepTime > oldSleepTime && d.p != nil {
+			d.p.getCounters().Throttle()
+		}
 	} else {
 		if newSleepTime != oldSleepTime {
 			Debugf("pacer", "Reducing sleep to %v", newSleepTime)
@@ -62,9 +85,9 @@ func (p *Pacer) SetCalculator(c pacer.Calculator) {
 	case *logCalculator:
 		Logf("pacer", "Invalid Calculator in fs.Pacer.SetCalculator")
 	case nil:
-		c = &logCalculator{pacer.NewDefault()}
+		c = &logCalculator{Calculator: pacer.NewDefault(), p: p}
 	default:
-		c = &logCalculator{c}
+		c = &logCalculator{Calculator: c, p: p}
 	}
 
 	p.Pacer.SetCalculator(c)
@@ -84,9 +107,23 @@ func (p *Pacer) ModifyCalculator(f func(pacer.Calculator)) {
 	})
 }
 
-func pacerInvoker(try, retries int, f pacer.Paced) (retry bool, err error) {
-	retry, err = f()
+func (p *Pacer) invoke(try, retries int, f pacer.Paced) (retry bool, err error) {
+	var start time.Time
+	if p.limit != nil {
+		p.limit.acquire()
+		start = time.Now()
+		retry, err = f()
+		p.limit.release()
+	} else {
+		start = time.Now()
+		retry, err = f()
+	}
+	counters := p.getCounters()
+	counters.Call()
+	counters.Latency(time.Since(start))
+	counters.Error(err)
 	if retry {
+		counters.Retry()
 		Debugf("pacer", "low level retry %d/%d (error %v)", try, retries, err)
 		err = fserrors.RetryError(err)
 	}
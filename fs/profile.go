@@ -0,0 +1,180 @@
+// Named option profiles
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs/config/configmap"
+)
+
+const (
+	// ProfilePrefix starts the names of the config file sections
+	// which hold option profiles, eg "[profile:slow-link]"
+	ProfilePrefix = "profile:"
+
+	// ProfileOption is the option which selects a profile, eg
+	// ":alist,profile=slow-link:"
+	ProfileOption = "profile"
+
+	// ProfileOptionAlt selects a profile for backends which have
+	// their own option called "profile"
+	ProfileOptionAlt = "rclone_profile"
+)
+
+// profileGlobals are the global options which may be set in a
+// profile, with functions to set them in ci.
+//
+// These are the ones read by backends when they are created so can
+// differ between remotes.
+var profileGlobals = map[string]func(ci *ConfigInfo, value string) error{
+	"low_level_retries": func(ci *ConfigInfo, value string) (err error) {
+		ci.LowLevelRetries, err = strconv.Atoi(value)
+		return err
+	},
+	"contimeout": func(ci *ConfigInfo, value string) error {
+		return setDuration(&ci.ConnectTimeout, value)
+	},
+	"timeout": func(ci *ConfigInfo, value string) error {
+		return setDuration(&ci.Timeout, value)
+	},
+	"expect_continue_timeout": func(ci *ConfigInfo, value string) error {
+		return setDuration(&ci.ExpectContinueTimeout, value)
+	},
+	"user_agent": func(ci *ConfigInfo, value string) error {
+		ci.UserAgent = value
+		return nil
+	},
+	"disable_http2": func(ci *ConfigInfo, value string) (err error) {
+		ci.DisableHTTP2, err = strconv.ParseBool(value)
+		return err
+	},
+	"disable_http_keep_alives": func(ci *ConfigInfo, value string) (err error) {
+		ci.DisableHTTPKeepAlives, err = strconv.ParseBool(value)
+		return err
+	},
+	"list_checkers": func(ci *ConfigInfo, value string) (err error) {
+		ci.ListCheckers, err = strconv.Atoi(value)
+		return err
+	},
+	"proxy": func(ci *ConfigInfo, value string) error {
+		u, err := url.Parse(value)
+		if err != nil {
+			return err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+			return errors.New("scheme must be http, https or socks5")
+		}
+		ci.Proxy = value
+		return nil
+	},
+}
+
+// profileUnsupported are the global options which can't be set in a
+// profile with the reason why, so setting them is an error rather
+// than being silently ignored.
+var profileUnsupported = map[string]string{
+	"bwlimit":      "bandwidth limits apply to all remotes - use --bwlimit or --bwlimit-class instead",
+	"bwlimit_file": "bandwidth limits apply to all remotes - use --bwlimit-file or --bwlimit-class instead",
+}
+
+// setDuration parses value into *pd
+func setDuration(pd *time.Duration, value string) error {
+	d, err := ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*pd = d
+	return nil
+}
+
+// A configmap.Getter to read the options from a profile in the config
+// file
+type getProfile struct {
+	getConfigFileSecrets
+}
+
+// Get a config item from the profile
+func (p getProfile) Get(key string) (value string, ok bool) {
+	if key == "type" {
+		return "", false
+	}
+	return p.getConfigFileSecrets.Get(key)
+}
+
+// profileName returns the name of the profile selected in config, if
+// any.
+func profileName(fsInfo *RegInfo, config configmap.Getter) (name string, ok bool) {
+	if name, ok = config.Get(ProfileOptionAlt); ok {
+		return name, true
+	}
+	if fsInfo != nil && fsInfo.Options.Get(ProfileOption) != nil {
+		return "", false
+	}
+	return config.Get(ProfileOption)
+}
+
+// applyProfile checks the profile called name exists and returns a
+// context with the global options it sets applied.
+func applyProfile(ctx context.Context, name string) (context.Context, error) {
+	section := ProfilePrefix + name
+	if !ConfigFileHasSection(section) {
+		return ctx, fmt.Errorf("didn't find profile %q in config file - add a section called [%s]", name, section)
+	}
+	for key, reason := range profileUnsupported {
+		if _, ok := ConfigFileGet(section, key); ok {
+			return ctx, fmt.Errorf("profile %q: can't set %s in a profile as %s", name, key, reason)
+		}
+	}
+	newCtx, ci := AddConfig(ctx)
+	changed := false
+	for key, set := range profileGlobals {
+		value, ok := ConfigFileGet(section, key)
+		if !ok {
+			continue
+		}
+		if err := set(ci, value); err != nil {
+			return ctx, fmt.Errorf("profile %q: bad %s %q: %w", name, key, value, err)
+		}
+		Debugf(nil, "Setting %s=%q from profile %q", key, value, name)
+		changed = true
+	}
+	if !changed {
+		return ctx, nil
+	}
+	return newCtx, nil
+}
+
+var (
+	listCheckersMu sync.Mutex
+	listCheckers   = map[string]int{}
+)
+
+// setListCheckers records the list_checkers set by the profile
+// applied to ctx to make profileCtx for the remote f, if any.
+func setListCheckers(ctx, profileCtx context.Context, f Fs) {
+	n := GetConfig(profileCtx).ListCheckers
+	if n <= 0 || n == GetConfig(ctx).ListCheckers {
+		return
+	}
+	listCheckersMu.Lock()
+	listCheckers[f.Name()] = n
+	listCheckersMu.Unlock()
+}
+
+// ListCheckers returns the number of directories of f to list in
+// parallel as set by list_checkers in its option profile.
+//
+// ok is false if it wasn't set by a profile.
+func ListCheckers(f Fs) (n int, ok bool) {
+	listCheckersMu.Lock()
+	defer listCheckersMu.Unlock()
+	n, ok = listCheckers[f.Name()]
+	return n, ok
+}
@@ -100,6 +100,9 @@ are cached for a short while to make repeated rc calls more efficient.
 If you change the parameters of a backend then you may want to call
 this to clear an existing remote out of the cache before re-creating
 it.
+
+This also clears the cached values fetched from secrets providers so
+they are fetched again when the remotes are re-created.
 `,
 	})
 }
@@ -107,6 +110,7 @@ it.
 // Clear the fs cache
 func rcCacheClear(ctx context.Context, in Params) (out Params, err error) {
 	cache.Clear()
+	fs.ClearSecretCache()
 	return nil, nil
 }
 
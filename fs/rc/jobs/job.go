@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"runtime/debug"
 	"sync"
 	"sync/atomic"
@@ -105,6 +106,11 @@ type Jobs struct {
 var (
 	running = newJobs()
 	jobID   = int64(0)
+
+	// how often to check the bandwidth timetable of a job and the
+	// time to check it at - overridden in tests
+	bwLimitTick = time.Minute
+	timeNow     = time.Now
 )
 
 // newJobs makes a new Jobs structure
@@ -217,6 +223,58 @@ func getConfig(ctx context.Context, in rc.Params) (context.Context, error) {
 	return ctx, nil
 }
 
+// If _config changed the bandwidth limit, wrap fn to apply it to the
+// stats group of the job while it runs, as the global limit is shared
+// by all the jobs. The limit is removed when fn returns so it doesn't
+// apply to later jobs in the same group.
+//
+// If the limit is a timetable it is checked every minute, as the
+// global limit is, and changed when the next slot starts.
+func withBwLimit(ctx context.Context, group string, fn rc.Func) rc.Func {
+	bwLimit := fs.GetConfig(ctx).BwLimit
+	if reflect.DeepEqual(bwLimit, fs.GetConfig(nil).BwLimit) {
+		return fn
+	}
+	stats := accounting.StatsGroup(ctx, group)
+	return func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		limit := bwLimit.LimitAt(timeNow())
+		stats.SetBwLimit(limit.Bandwidth)
+		defer stats.SetBwLimit(fs.BwPair{})
+		if len(bwLimit) > 1 {
+			ticker := time.NewTicker(bwLimitTick)
+			done := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-ticker.C:
+						limitNow := bwLimit.LimitAt(timeNow())
+						if limitNow.Bandwidth != limit.Bandwidth {
+							if limitNow.Bandwidth.IsSet() {
+								fs.Logf(nil, "Scheduled bandwidth change for job group %q. Limit set to %v Byte/s", group, &limitNow.Bandwidth)
+							} else {
+								fs.Logf(nil, "Scheduled bandwidth change for job group %q. Bandwidth limit disabled", group)
+							}
+							stats.SetBwLimit(limitNow.Bandwidth)
+							limit = limitNow
+						}
+					case <-done:
+						return
+					}
+				}
+			}()
+			defer func() {
+				ticker.Stop()
+				close(done)
+				wg.Wait()
+			}()
+		}
+		return fn(ctx, in)
+	}
+}
+
 // See if _filter is set and if so adjust ctx to include it
 func getFilter(ctx context.Context, in rc.Params) (context.Context, error) {
 	if _, ok := in["_filter"]; !ok {
@@ -263,6 +321,8 @@ func (jobs *Jobs) NewJob(ctx context.Context, fn rc.Func, in rc.Params) (job *Jo
 		return nil, nil, err
 	}
 
+	fn = withBwLimit(ctx, group, fn)
+
 	ctx, cancel := context.WithCancel(ctx)
 	stop := func() {
 		cancel()
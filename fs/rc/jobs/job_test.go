@@ -1,9 +1,13 @@
 package jobs
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
+	"io/ioutil"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -277,6 +281,99 @@ func TestExecuteJobWithConfig(t *testing.T) {
 	assert.NotEqual(t, 42*fs.Mebi, ci.BufferSize)
 }
 
+func TestExecuteJobWithBwLimit(t *testing.T) {
+	ctx := context.Background()
+	jobID = 0
+	called := false
+	jobFn := func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		assert.Equal(t, 2, fs.GetConfig(ctx).Transfers)
+		// Read 1 MiB which should take 0.1s at 10 MiB/s
+		in0 := ioutil.NopCloser(bytes.NewReader(make([]byte, fs.Mebi)))
+		acc := accounting.Stats(ctx).NewTransferRemoteSize("file", int64(fs.Mebi)).Account(ctx, in0)
+		start := time.Now()
+		_, err := io.Copy(ioutil.Discard, acc)
+		assert.NoError(t, err)
+		assert.True(t, time.Since(start) >= 80*time.Millisecond, "not limited: %v", time.Since(start))
+		called = true
+		return nil, nil
+	}
+	_, _, err := NewJob(ctx, jobFn, rc.Params{
+		"_config": rc.Params{
+			"BwLimit":   "10M",
+			"Transfers": 2,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, true, called)
+
+	// Jobs without the limit should be unlimited
+	jobFn = func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		in0 := ioutil.NopCloser(bytes.NewReader(make([]byte, fs.Mebi)))
+		acc := accounting.Stats(ctx).NewTransferRemoteSize("file", int64(fs.Mebi)).Account(ctx, in0)
+		start := time.Now()
+		_, err := io.Copy(ioutil.Discard, acc)
+		assert.NoError(t, err)
+		assert.True(t, time.Since(start) < 80*time.Millisecond, "limited: %v", time.Since(start))
+		return nil, nil
+	}
+	jobID = 0
+	_, _, err = NewJob(ctx, jobFn, rc.Params{"_group": "unlimited"})
+	require.NoError(t, err)
+
+	// The limit is removed from the group when the job ends
+	jobID = 0
+	_, _, err = NewJob(ctx, func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		return nil, nil
+	}, rc.Params{
+		"_group":  "shared",
+		"_config": rc.Params{"BwLimit": "10M"},
+	})
+	require.NoError(t, err)
+	jobID = 0
+	_, _, err = NewJob(ctx, jobFn, rc.Params{"_group": "shared"})
+	require.NoError(t, err)
+}
+
+func TestExecuteJobWithBwLimitTimetable(t *testing.T) {
+	ctx := context.Background()
+	oldBwLimitTick, oldTimeNow := bwLimitTick, timeNow
+	defer func() {
+		bwLimitTick, timeNow = oldBwLimitTick, oldTimeNow
+	}()
+	bwLimitTick = 10 * time.Millisecond
+	var afternoon int32
+	timeNow = func() time.Time {
+		if atomic.LoadInt32(&afternoon) != 0 {
+			return time.Date(2021, 1, 4, 13, 0, 0, 0, time.Local)
+		}
+		return time.Date(2021, 1, 4, 1, 0, 0, 0, time.Local)
+	}
+	read := func(ctx context.Context) time.Duration {
+		// Read 1 MiB which should take 0.1s at 10 MiB/s
+		in0 := ioutil.NopCloser(bytes.NewReader(make([]byte, fs.Mebi)))
+		acc := accounting.Stats(ctx).NewTransferRemoteSize("file", int64(fs.Mebi)).Account(ctx, in0)
+		start := time.Now()
+		_, err := io.Copy(ioutil.Discard, acc)
+		assert.NoError(t, err)
+		return time.Since(start)
+	}
+	jobID = 0
+	_, _, err := NewJob(ctx, func(ctx context.Context, in rc.Params) (rc.Params, error) {
+		took := read(ctx)
+		assert.True(t, took >= 80*time.Millisecond, "not limited in the morning: %v", took)
+		// The limit should be lifted at the next tick
+		atomic.StoreInt32(&afternoon, 1)
+		time.Sleep(5 * bwLimitTick)
+		took = read(ctx)
+		assert.True(t, took < 80*time.Millisecond, "limited in the afternoon: %v", took)
+		return nil, nil
+	}, rc.Params{
+		"_group":  "timetable",
+		"_config": rc.Params{"BwLimit": "00:00,10M 12:00,off"},
+	})
+	require.NoError(t, err)
+}
+
 func TestExecuteJobWithFilter(t *testing.T) {
 	ctx := context.Background()
 	called := false
@@ -30,6 +30,8 @@ type Options struct {
 	WebGUIFetchURL           string // set the default url for fetching webgui
 	AccessControlAllowOrigin string // set the access control for CORS configuration
 	EnableMetrics            bool   // set to disable prometheus metrics on /metrics
+	EnableEvents             bool   // set to enable the transfer event stream on /events
+	EnableHealth             bool   // set to enable the remote health page on /health
 	JobExpireDuration        time.Duration
 	JobExpireInterval        time.Duration
 }
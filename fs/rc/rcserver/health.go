@@ -0,0 +1,138 @@
+// The remote health dashboard
+
+package rcserver
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// serveHealth serves a page showing the health of the remotes from
+// the counters returned by backend/stats
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if _, err := io.WriteString(w, healthHTML); err != nil {
+		fs.Debugf(nil, "rc: health: failed to write page: %v", err)
+	}
+}
+
+// healthHTML polls backend/stats and shows a panel for each remote
+const healthHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>rclone remote health</title>
+<style>
+body { font-family: sans-serif; margin: 1em; background: #f7f7f7; }
+.remote { background: #fff; border: 1px solid #ddd; border-radius: 4px; padding: 0.5em 1em; margin-bottom: 1em; }
+.remote h2 { margin: 0.2em 0; }
+table { border-collapse: collapse; }
+td { padding: 0.1em 1em 0.1em 0; }
+.bad { color: #c00; }
+.errors { font-family: monospace; font-size: 90%; }
+svg { background: #fafafa; border: 1px solid #eee; }
+</style>
+</head>
+<body>
+<h1>rclone remote health</h1>
+<p id="status">Loading...</p>
+<div id="remotes"></div>
+<script>
+"use strict";
+
+function text(tag, value, className) {
+  const e = document.createElement(tag);
+  e.textContent = value;
+  if (className) e.className = className;
+  return e;
+}
+
+// graph draws the calls (blue) and retries and errors (red) per minute
+function graph(history) {
+  const width = 360, height = 60, ns = "http://www.w3.org/2000/svg";
+  const svg = document.createElementNS(ns, "svg");
+  svg.setAttribute("width", width);
+  svg.setAttribute("height", height);
+  if (!history || history.length == 0) return svg;
+  const start = new Date(history[0].time).getTime();
+  const minutes = 60, step = width / minutes;
+  let max = 1;
+  for (const h of history) max = Math.max(max, h.calls);
+  const line = (value, colour) => {
+    const points = history.map(h => {
+      const x = (new Date(h.time).getTime() - start) / 60000 * step;
+      const y = height - value(h) / max * (height - 2) - 1;
+      return x.toFixed(1) + "," + y.toFixed(1);
+    });
+    const p = document.createElementNS(ns, "polyline");
+    p.setAttribute("points", points.join(" "));
+    p.setAttribute("fill", "none");
+    p.setAttribute("stroke", colour);
+    svg.appendChild(p);
+  };
+  line(h => h.calls, "#36c");
+  line(h => h.retries + h.errors, "#c33");
+  return svg;
+}
+
+function panel(name, stats) {
+  const div = document.createElement("div");
+  div.className = "remote";
+  div.appendChild(text("h2", name));
+  const table = document.createElement("table");
+  const row = (label, value, bad) => {
+    const tr = document.createElement("tr");
+    tr.appendChild(text("td", label));
+    tr.appendChild(text("td", value, bad ? "bad" : ""));
+    table.appendChild(tr);
+  };
+  row("API calls", stats.calls);
+  row("Retries", stats.retries, stats.retries > 0);
+  row("Throttles", stats.throttles, stats.throttles > 0);
+  row("Pacer sleep", stats.pacerSleep.toFixed(3) + "s", stats.pacerSleep >= 1);
+  if (stats.latency) row("Mean latency", (stats.latency.mean * 1000).toFixed(0) + "ms");
+  row("Token refreshes", stats.tokenRefreshes);
+  if (stats.tokenExpiry) {
+    const left = (new Date(stats.tokenExpiry).getTime() - Date.now()) / 1000;
+    row("Token expires", stats.tokenExpiry + " (in " + Math.round(left) + "s)", left < 300);
+  }
+  div.appendChild(table);
+  div.appendChild(text("p", "Calls (blue) and retries and errors (red) per minute over the last hour"));
+  div.appendChild(graph(stats.history));
+  if (stats.errors && stats.errors.length > 0) {
+    div.appendChild(text("h3", "Recent errors"));
+    const ul = document.createElement("ul");
+    for (const e of stats.errors.slice().reverse()) {
+      ul.appendChild(text("li", e.time + ": " + e.error, "errors bad"));
+    }
+    div.appendChild(ul);
+  }
+  return div;
+}
+
+async function update() {
+  const status = document.getElementById("status");
+  try {
+    const resp = await fetch("backend/stats", { method: "POST", credentials: "same-origin" });
+    if (!resp.ok) throw new Error(resp.status + " " + resp.statusText);
+    const out = await resp.json();
+    const remotes = document.getElementById("remotes");
+    remotes.replaceChildren();
+    const names = Object.keys(out.remotes || {}).sort();
+    for (const name of names) remotes.appendChild(panel(name, out.remotes[name]));
+    status.textContent = names.length == 0 ? "No remotes with counters have been used yet." : "Updated " + new Date().toLocaleTimeString();
+  } catch (err) {
+    status.textContent = "Failed to read backend/stats: " + err;
+    status.className = "bad";
+  }
+}
+
+update();
+setInterval(update, 5000);
+</script>
+</body>
+</html>
+`
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -111,6 +112,7 @@ func newServer(ctx context.Context, opt *rc.Options, mux *http.ServeMux) *Server
 			}
 		}
 		opt.Serve = true
+		opt.EnableHealth = true
 
 		fs.Logf(nil, "Serving Web GUI")
 		fileHandler = http.FileServer(http.Dir(extractPath))
@@ -355,6 +357,76 @@ func (s *Server) serveRemote(w http.ResponseWriter, r *http.Request, path string
 	}
 }
 
+// eventsBuffer is the number of events queued for each client of
+// /events before they are dropped
+const eventsBuffer = 1024
+
+// writeEvent writes event to w as a server-sent event
+func writeEvent(w http.ResponseWriter, event accounting.TransferEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+// serveEvents streams the transfer events to the client as
+// server-sent events until it disconnects
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	interval := time.Second
+	if value := r.URL.Query().Get("interval"); value != "" {
+		d, err := fs.ParseDuration(value)
+		if err != nil || d <= 0 {
+			writeError("events", nil, w, fmt.Errorf("invalid interval %q", value), http.StatusBadRequest)
+			return
+		}
+		interval = d
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError("events", nil, w, errors.New("streaming not supported"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Method == "HEAD" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	events, unsubscribe := accounting.SubscribeTransfers(eventsBuffer)
+	defer unsubscribe()
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			err = writeEvent(w, event)
+		case <-ticker.C:
+			progress := accounting.TransfersInProgress()
+			if len(progress) == 0 {
+				_, err = fmt.Fprint(w, ": keepalive\n\n")
+			}
+			for _, event := range progress {
+				if err = writeEvent(w, event); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			fs.Debugf(nil, "rc: events: stopping: %v", err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
 // Match URLS of the form [fs]/remote
 var fsMatch = regexp.MustCompile(`^\[(.*?)\](.*)$`)
 
@@ -370,6 +442,12 @@ func (s *Server) handleGet(w http.ResponseWriter, r *http.Request, path string)
 	case path == "metrics" && s.opt.EnableMetrics:
 		promHandler.ServeHTTP(w, r)
 		return
+	case path == "events" && s.opt.EnableEvents:
+		s.serveEvents(w, r)
+		return
+	case path == "health" && s.opt.EnableHealth:
+		s.serveHealth(w, r)
+		return
 	case path == "*" && s.opt.Serve:
 		// Serve /* as the remote listing
 		s.serveRoot(w, r)
@@ -1,8 +1,11 @@
 package rcserver
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -627,6 +630,88 @@ func makeMetricsTestCases(stats *accounting.StatsInfo) (tests []testRun) {
 	return
 }
 
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	opt := newTestOpt()
+	opt.EnableEvents = true
+	rcServer := newServer(ctx, &opt, http.NewServeMux())
+	server := httptest.NewServer(http.HandlerFunc(rcServer.handler))
+	defer server.Close()
+
+	// Not enabled
+	disabledOpt := newTestOpt()
+	testServer(t, []testRun{{
+		Name:     "events-disabled",
+		URL:      "events",
+		Status:   http.StatusNotFound,
+		Expected: "Not Found\n",
+	}}, &disabledOpt)
+
+	// Bad interval
+	resp, err := http.Get(server.URL + "/events?interval=potato")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/events?interval=10ms")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, resp.Body.Close())
+	}()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	lines := bufio.NewScanner(resp.Body)
+
+	// next reads the events until one of eventType is found
+	next := func(eventType string) (event accounting.TransferEvent) {
+		for lines.Scan() {
+			if lines.Text() != "event: "+eventType {
+				continue
+			}
+			require.True(t, lines.Scan())
+			data := strings.TrimPrefix(lines.Text(), "data: ")
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+			if event.Transfer.Name == "events-test.txt" {
+				return event
+			}
+		}
+		t.Fatalf("%s event not found: %v", eventType, lines.Err())
+		return event
+	}
+
+	tr := accounting.GlobalStats().NewTransferRemoteSize("events-test.txt", 100)
+	event := next(accounting.EventStart)
+	assert.Equal(t, int64(100), event.Transfer.Size)
+	event = next(accounting.EventProgress)
+	assert.Equal(t, accounting.EventProgress, event.Type)
+	tr.Done(ctx, errors.New("transfer failed"))
+	event = next(accounting.EventFinish)
+	assert.Equal(t, accounting.EventFinish, event.Type)
+	assert.False(t, event.Transfer.CompletedAt.IsZero())
+}
+
+func TestHealth(t *testing.T) {
+	opt := newTestOpt()
+	opt.EnableHealth = true
+	testServer(t, []testRun{{
+		Name:     "health",
+		URL:      "health",
+		Status:   http.StatusOK,
+		Contains: regexp.MustCompile(`(?s)<title>rclone remote health</title>.*fetch\("backend/stats"`),
+		Headers: map[string]string{
+			"Content-Type": "text/html; charset=utf-8",
+		},
+	}}, &opt)
+
+	disabledOpt := newTestOpt()
+	testServer(t, []testRun{{
+		Name:     "health-disabled",
+		URL:      "health",
+		Status:   http.StatusNotFound,
+		Expected: "Not Found\n",
+	}}, &disabledOpt)
+}
+
 var matchRemoteDirListing = regexp.MustCompile(`<title>Directory listing of /</title>`)
 
 func TestServingRoot(t *testing.T) {
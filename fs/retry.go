@@ -0,0 +1,73 @@
+// Low level retries
+
+package fs
+
+import (
+	"context"
+	"time"
+
+	"github.com/rclone/rclone/fs/fserrors"
+)
+
+// RetryOptions configure the low level retries done by Retry.
+type RetryOptions struct {
+	Retries  int           // number of tries to make, at least 1
+	Sleep    time.Duration // sleep before the first retry
+	MaxSleep time.Duration // maximum sleep between retries
+	Counters *Counters     // record the retries here if set
+}
+
+// NewRetryOptions returns the RetryOptions from the config in ctx.
+//
+// They make --low-level-retries tries with no sleep between them
+// unless the error says when to retry.
+func NewRetryOptions(ctx context.Context) RetryOptions {
+	return RetryOptions{
+		Retries: GetConfig(ctx).LowLevelRetries,
+	}
+}
+
+// Retry calls fn until it succeeds, returns an error which shouldn't
+// be retried or opt.Retries tries have been made.
+//
+// fn is always called at least once - if opt.Retries is less than 1
+// it is treated as 1.
+//
+// o is used for logging.
+func Retry(ctx context.Context, o interface{}, opt RetryOptions, fn func() error) (err error) {
+	if opt.Retries < 1 {
+		opt.Retries = 1
+	}
+	sleep := opt.Sleep
+	for tries := 1; ; tries++ {
+		// Call the function which might error
+		err = fn()
+		if err == nil || tries >= opt.Retries {
+			return err
+		}
+		// Retry if err returned a retry error
+		if fserrors.ContextError(ctx, &err) {
+			return err
+		}
+		if fserrors.IsNoLowLevelRetryError(err) || !(fserrors.IsRetryError(err) || fserrors.IsRetryAfterError(err) || fserrors.ShouldRetry(err)) {
+			return err
+		}
+		opt.Counters.Retry()
+		Debugf(o, "Received error: %v - low level retry %d/%d", err, tries, opt.Retries)
+		wait := sleep
+		if retryAfter := fserrors.RetryAfterErrorTime(err); !retryAfter.IsZero() {
+			wait = time.Until(retryAfter)
+		}
+		if wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return err
+			}
+		}
+		sleep *= 2
+		if opt.MaxSleep > 0 && sleep > opt.MaxSleep {
+			sleep = opt.MaxSleep
+		}
+	}
+}
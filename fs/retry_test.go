@@ -0,0 +1,64 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	errRetry := fserrors.RetryErrorf("retry me")
+	errFatal := errors.New("don't retry me")
+	counters := new(Counters)
+	opt := RetryOptions{Retries: 3, Counters: counters}
+
+	// fail returns the errors in turn then nil
+	var calls int
+	fail := func(errs ...error) func() error {
+		calls = 0
+		return func() error {
+			calls++
+			if calls <= len(errs) {
+				return errs[calls-1]
+			}
+			return nil
+		}
+	}
+
+	assert.NoError(t, Retry(ctx, nil, opt, fail(errRetry, errRetry)))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, int64(2), counters.Stats().Retries)
+
+	assert.Equal(t, errRetry, Retry(ctx, nil, opt, fail(errRetry, errRetry, errRetry)))
+	assert.Equal(t, 3, calls)
+
+	assert.Equal(t, errFatal, Retry(ctx, nil, opt, fail(errFatal)))
+	assert.Equal(t, 1, calls)
+
+	err := fserrors.NoLowLevelRetryError(errRetry)
+	assert.Equal(t, err, Retry(ctx, nil, opt, fail(err)))
+	assert.Equal(t, 1, calls)
+
+	// fn is always called at least once
+	assert.Equal(t, errRetry, Retry(ctx, nil, RetryOptions{}, fail(errRetry)))
+	assert.Equal(t, 1, calls)
+
+	// Sleeps should back off up to MaxSleep
+	opt.Retries = 4
+	opt.Sleep = 10 * time.Millisecond
+	opt.MaxSleep = 20 * time.Millisecond
+	start := time.Now()
+	assert.NoError(t, Retry(ctx, nil, opt, fail(errRetry, errRetry, errRetry)))
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+
+	// Retry-After should be obeyed
+	opt.Sleep = 0
+	start = time.Now()
+	assert.NoError(t, Retry(ctx, nil, opt, fail(fserrors.NewErrorRetryAfter(50*time.Millisecond))))
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+}
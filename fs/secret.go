@@ -0,0 +1,219 @@
+// External secrets providers
+
+package fs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs/config/obscure"
+)
+
+// SecretPrefix starts config values which should be fetched from a
+// SecretProvider, eg "secret:command:pass show rclone/drive"
+const SecretPrefix = "secret:"
+
+// SecretProvider fetches secrets from outside the config file, for
+// example from a password manager or a cloud secrets manager.
+type SecretProvider interface {
+	// GetSecret returns the secret referred to by ref in plain text
+	GetSecret(ctx context.Context, ref string) (string, error)
+}
+
+// secretCacheTTL is how long fetched secrets are kept before being
+// fetched again, so rotated secrets are picked up by long running
+// processes.
+var secretCacheTTL = 5 * time.Minute
+
+// secretCacheEntry is a secret and when it should be fetched again
+type secretCacheEntry struct {
+	secret  string
+	expires time.Time
+}
+
+var (
+	secretsMu       sync.Mutex
+	secretProviders = map[string]SecretProvider{}
+	secretCache     = map[string]secretCacheEntry{}
+)
+
+// RegisterSecretProvider registers p so config values of the form
+// "secret:name:ref" are fetched with p.GetSecret(ctx, ref).
+func RegisterSecretProvider(name string, p SecretProvider) {
+	secretsMu.Lock()
+	secretProviders[name] = p
+	secretsMu.Unlock()
+}
+
+// SecretProviders returns the names of the registered SecretProvider
+// in sorted order
+func SecretProviders() (names []string) {
+	secretsMu.Lock()
+	for name := range secretProviders {
+		names = append(names, name)
+	}
+	secretsMu.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// parseSecret returns the SecretProvider and reference if value
+// refers to a secret from a registered SecretProvider
+func parseSecret(value string) (p SecretProvider, ref string, ok bool) {
+	if !strings.HasPrefix(value, SecretPrefix) {
+		return nil, "", false
+	}
+	colon := strings.IndexRune(value[len(SecretPrefix):], ':')
+	if colon < 0 {
+		return nil, "", false
+	}
+	name := value[len(SecretPrefix) : len(SecretPrefix)+colon]
+	secretsMu.Lock()
+	p = secretProviders[name]
+	secretsMu.Unlock()
+	if p == nil {
+		return nil, "", false
+	}
+	return p, value[len(SecretPrefix)+colon+1:], true
+}
+
+// IsSecret returns true if value refers to a secret from a
+// registered SecretProvider
+func IsSecret(value string) bool {
+	_, _, ok := parseSecret(value)
+	return ok
+}
+
+// GetSecret returns the secret value refers to if it is of the form
+// "secret:name:ref" and name is a registered SecretProvider,
+// otherwise it returns value unchanged.
+//
+// Secrets are cached for a few minutes so they aren't fetched each
+// time they are read.
+func GetSecret(ctx context.Context, value string) (string, error) {
+	p, ref, ok := parseSecret(value)
+	if !ok {
+		return value, nil
+	}
+	secretsMu.Lock()
+	entry, found := secretCache[value]
+	secretsMu.Unlock()
+	if found && time.Now().Before(entry.expires) {
+		return entry.secret, nil
+	}
+	secret, err := p.GetSecret(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %q: %w", value, err)
+	}
+	cacheSecret(value, secret)
+	return secret, nil
+}
+
+// cacheSecret caches secret as the value of the reference value
+func cacheSecret(value, secret string) {
+	secretsMu.Lock()
+	secretCache[value] = secretCacheEntry{
+		secret:  secret,
+		expires: time.Now().Add(secretCacheTTL),
+	}
+	secretsMu.Unlock()
+}
+
+// ClearSecretCache forgets the cached secrets so they are fetched
+// again when next used.
+func ClearSecretCache() {
+	secretsMu.Lock()
+	secretCache = map[string]secretCacheEntry{}
+	secretsMu.Unlock()
+}
+
+// fetchSecrets fetches the secrets referred to by the config file
+// values of the options of fsInfo in each of sections.
+//
+// This is so that an error fetching a secret stops the backend being
+// created, as the configmap.Getter can't return it. The secrets are
+// cached so they aren't fetched again when the backend reads them.
+func fetchSecrets(ctx context.Context, fsInfo *RegInfo, sections ...string) error {
+	for _, section := range sections {
+		for _, opt := range fsInfo.Options {
+			value, ok := getConfigFile(section).Get(opt.Name)
+			if !ok || !IsSecret(value) {
+				continue
+			}
+			if _, err := GetSecret(ctx, value); err != nil {
+				return fmt.Errorf("failed to read %q for %q from secrets provider: %w", opt.Name, section, err)
+			}
+		}
+	}
+	return nil
+}
+
+// A configmap.Getter to fetch the secrets referred to by values in
+// the config file
+type getConfigFileSecrets struct {
+	getConfigFile
+	fsInfo *RegInfo
+}
+
+// Get a config item from the config file fetching it from a
+// SecretProvider if necessary.
+//
+// Secrets for password options are obscured as the backend expects.
+func (s getConfigFileSecrets) Get(key string) (value string, ok bool) {
+	value, ok = s.getConfigFile.Get(key)
+	if !ok || !IsSecret(value) {
+		return value, ok
+	}
+	secret, err := GetSecret(context.Background(), value)
+	if err != nil {
+		Errorf(nil, "Failed to read %q for %q from secrets provider: %v", key, string(s.getConfigFile), err)
+		return "", false
+	}
+	if s.fsInfo != nil {
+		if opt := s.fsInfo.Options.Get(key); opt != nil && opt.IsPassword {
+			secret, err = obscure.Obscure(secret)
+			if err != nil {
+				Errorf(nil, "Failed to obscure %q for %q: %v", key, string(s.getConfigFile), err)
+				return "", false
+			}
+		}
+	}
+	return secret, true
+}
+
+// A configmap.Setter to write to the config file which leaves values
+// fetched from a SecretProvider alone
+type setConfigFileSecrets struct {
+	setConfigFile
+	fsInfo *RegInfo
+}
+
+// Set a config item into the config file unless it is fetched from a
+// SecretProvider.
+//
+// If it is, for example when a backend refreshes an oauth token, the
+// reference is kept in the config file and the new value is cached
+// so it is used until it expires. It isn't saved to the provider.
+func (s setConfigFileSecrets) Set(key, value string) {
+	ref, ok := getConfigFile(s.setConfigFile).Get(key)
+	if !ok || !IsSecret(ref) {
+		s.setConfigFile.Set(key, value)
+		return
+	}
+	Logf(nil, "Not saving config %q in section %q of the config file as it is read from %q - update it there if needed", key, string(s.setConfigFile), ref)
+	if s.fsInfo != nil {
+		if opt := s.fsInfo.Options.Get(key); opt != nil && opt.IsPassword {
+			revealed, err := obscure.Reveal(value)
+			if err != nil {
+				Errorf(nil, "Failed to reveal %q for %q: %v", key, string(s.setConfigFile), err)
+				return
+			}
+			value = revealed
+		}
+	}
+	cacheSecret(ref, value)
+}
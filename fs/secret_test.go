@@ -0,0 +1,172 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rclone/rclone/fs/config/obscure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSecretProvider returns "secret-" + ref counting the calls
+type testSecretProvider struct {
+	calls int
+}
+
+func (p *testSecretProvider) GetSecret(ctx context.Context, ref string) (string, error) {
+	p.calls++
+	if ref == "error" {
+		return "", errors.New("no such secret")
+	}
+	return "secret-" + ref, nil
+}
+
+func TestGetSecret(t *testing.T) {
+	ctx := context.Background()
+	p := &testSecretProvider{}
+	RegisterSecretProvider("test", p)
+	defer func() {
+		secretsMu.Lock()
+		delete(secretProviders, "test")
+		secretsMu.Unlock()
+		ClearSecretCache()
+	}()
+	assert.Contains(t, SecretProviders(), "test")
+
+	for _, value := range []string{"potato", "secret:", "secret:unknown:ref", "secret:test"} {
+		assert.False(t, IsSecret(value), value)
+		got, err := GetSecret(ctx, value)
+		require.NoError(t, err)
+		assert.Equal(t, value, got)
+	}
+	assert.Equal(t, 0, p.calls)
+
+	assert.True(t, IsSecret("secret:test:a:b"))
+	got, err := GetSecret(ctx, "secret:test:a:b")
+	require.NoError(t, err)
+	assert.Equal(t, "secret-a:b", got)
+	_, err = GetSecret(ctx, "secret:test:a:b")
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.calls, "secret not cached")
+
+	// Cleared and expired secrets are fetched again
+	ClearSecretCache()
+	_, err = GetSecret(ctx, "secret:test:a:b")
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.calls, "cleared secret not fetched again")
+	oldSecretCacheTTL := secretCacheTTL
+	secretCacheTTL = 0
+	ClearSecretCache()
+	_, err = GetSecret(ctx, "secret:test:a:b")
+	require.NoError(t, err)
+	_, err = GetSecret(ctx, "secret:test:a:b")
+	require.NoError(t, err)
+	assert.Equal(t, 4, p.calls, "expired secret not fetched again")
+	secretCacheTTL = oldSecretCacheTTL
+
+	_, err = GetSecret(ctx, "secret:test:error")
+	assert.EqualError(t, err, `failed to get "secret:test:error": no such secret`)
+
+	// Test the config file getter
+	oldConfigFileGet := ConfigFileGet
+	ConfigFileGet = func(section, key string) (string, bool) {
+		switch key {
+		case "plain":
+			return "value", true
+		case "error":
+			return "secret:test:error", true
+		}
+		return "secret:test:" + key, true
+	}
+	defer func() {
+		ConfigFileGet = oldConfigFileGet
+	}()
+	getter := getConfigFileSecrets{
+		getConfigFile: getConfigFile("remote"),
+		fsInfo: &RegInfo{
+			Options: Options{{Name: "pass", IsPassword: true}, {Name: "user"}},
+		},
+	}
+
+	value, ok := getter.Get("plain")
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+
+	value, ok = getter.Get("user")
+	assert.True(t, ok)
+	assert.Equal(t, "secret-user", value)
+
+	value, ok = getter.Get("pass")
+	assert.True(t, ok)
+	assert.Equal(t, "secret-pass", obscure.MustReveal(value))
+
+	_, ok = getter.Get("error")
+	assert.False(t, ok)
+
+	// Fetching all the secrets of a remote returns the error
+	require.NoError(t, fetchSecrets(ctx, getter.fsInfo, "remote"))
+	fsInfo := &RegInfo{
+		Options: Options{{Name: "plain"}, {Name: "error"}},
+	}
+	err = fetchSecrets(ctx, fsInfo, "remote")
+	assert.EqualError(t, err, `failed to read "error" for "remote" from secrets provider: failed to get "secret:test:error": no such secret`)
+}
+
+func TestSetSecret(t *testing.T) {
+	ctx := context.Background()
+	p := &testSecretProvider{}
+	RegisterSecretProvider("test", p)
+	defer func() {
+		secretsMu.Lock()
+		delete(secretProviders, "test")
+		secretsMu.Unlock()
+		ClearSecretCache()
+	}()
+
+	configFile := map[string]string{
+		"user":  "secret:test:user",
+		"pass":  "secret:test:pass",
+		"plain": "value",
+	}
+	oldConfigFileGet, oldConfigFileSet := ConfigFileGet, ConfigFileSet
+	ConfigFileGet = func(section, key string) (string, bool) {
+		value, ok := configFile[key]
+		return value, ok
+	}
+	ConfigFileSet = func(section, key, value string) error {
+		configFile[key] = value
+		return nil
+	}
+	defer func() {
+		ConfigFileGet, ConfigFileSet = oldConfigFileGet, oldConfigFileSet
+	}()
+	fsInfo := &RegInfo{
+		Options: Options{{Name: "pass", IsPassword: true}, {Name: "user"}, {Name: "plain"}},
+	}
+	m := ConfigMap(fsInfo, "remote", nil)
+
+	// Values which aren't secrets are written to the config file
+	m.Set("plain", "new value")
+	assert.Equal(t, "new value", configFile["plain"])
+
+	// Secrets keep their references and the new values are used
+	m.Set("user", "new user")
+	m.Set("pass", obscure.MustObscure("new pass"))
+	assert.Equal(t, "secret:test:user", configFile["user"])
+	assert.Equal(t, "secret:test:pass", configFile["pass"])
+	value, ok := m.Get("user")
+	assert.True(t, ok)
+	assert.Equal(t, "new user", value)
+	value, ok = m.Get("pass")
+	assert.True(t, ok)
+	assert.Equal(t, "new pass", obscure.MustReveal(value))
+	assert.Equal(t, 0, p.calls)
+
+	// Until the cache expires
+	ClearSecretCache()
+	value, err := GetSecret(ctx, configFile["user"])
+	require.NoError(t, err)
+	assert.Equal(t, "secret-user", value)
+}
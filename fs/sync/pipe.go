@@ -4,33 +4,49 @@ import (
 	"context"
 	"fmt"
 	"math/bits"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/aalpar/deheap"
 	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/fserrors"
 )
 
 // compare two items for order by
 type lessFn func(a, b fs.ObjectPair) bool
 
+// priorityFn returns the priority of an item - higher priorities are
+// taken from the pipe first
+type priorityFn func(pair fs.ObjectPair) int
+
 // pipe provides an unbounded channel like experience
 //
 // Note unlike channels these aren't strictly ordered.
 type pipe struct {
 	mu        sync.Mutex
 	c         chan struct{}
-	queue     []fs.ObjectPair
+	queues    []*pipeQueue // queues for each priority, highest first
+	items     int          // number of items in all the queues
 	closed    bool
 	totalSize int64
 	stats     func(items int, totalSize int64)
 	less      lessFn
 	fraction  int
+	priority  priorityFn
+}
+
+// pipeQueue holds the items of one priority in the pipe
+type pipeQueue struct {
+	priority int
+	queue    []fs.ObjectPair
+	less     lessFn
 }
 
-func newPipe(orderBy string, stats func(items int, totalSize int64), maxBacklog int) (*pipe, error) {
+func newPipe(orderBy string, priority priorityFn, stats func(items int, totalSize int64), maxBacklog int) (*pipe, error) {
 	if maxBacklog < 0 {
 		maxBacklog = (1 << (bits.UintSize - 1)) - 1 // largest positive int
 	}
@@ -43,43 +59,75 @@ func newPipe(orderBy string, stats func(items int, totalSize int64), maxBacklog
 		stats:    stats,
 		less:     less,
 		fraction: fraction,
-	}
-	if p.less != nil {
-		deheap.Init(p)
+		priority: priority,
 	}
 	return p, nil
 }
 
 // Len satisfy heap.Interface - must be called with lock held
-func (p *pipe) Len() int {
-	return len(p.queue)
+func (q *pipeQueue) Len() int {
+	return len(q.queue)
 }
 
 // Len satisfy heap.Interface - must be called with lock held
-func (p *pipe) Less(i, j int) bool {
-	return p.less(p.queue[i], p.queue[j])
+func (q *pipeQueue) Less(i, j int) bool {
+	return q.less(q.queue[i], q.queue[j])
 }
 
 // Swap satisfy heap.Interface - must be called with lock held
-func (p *pipe) Swap(i, j int) {
-	p.queue[i], p.queue[j] = p.queue[j], p.queue[i]
+func (q *pipeQueue) Swap(i, j int) {
+	q.queue[i], q.queue[j] = q.queue[j], q.queue[i]
 }
 
 // Push satisfy heap.Interface - must be called with lock held
-func (p *pipe) Push(item interface{}) {
-	p.queue = append(p.queue, item.(fs.ObjectPair))
+func (q *pipeQueue) Push(item interface{}) {
+	q.queue = append(q.queue, item.(fs.ObjectPair))
 }
 
 // Pop satisfy heap.Interface - must be called with lock held
-func (p *pipe) Pop() interface{} {
-	old := p.queue
+func (q *pipeQueue) Pop() interface{} {
+	old := q.queue
 	n := len(old)
 	item := old[n-1]
 	old[n-1] = fs.ObjectPair{} // avoid memory leak
-	p.queue = old[0 : n-1]
+	q.queue = old[0 : n-1]
 	return item
 }
 
+// findQueue returns the queue for pair, making it if necessary - must
+// be called with lock held
+func (p *pipe) findQueue(pair fs.ObjectPair) *pipeQueue {
+	priority := 0
+	if p.priority != nil {
+		priority = p.priority(pair)
+	}
+	i := sort.Search(len(p.queues), func(i int) bool {
+		return p.queues[i].priority <= priority
+	})
+	if i < len(p.queues) && p.queues[i].priority == priority {
+		return p.queues[i]
+	}
+	q := &pipeQueue{
+		priority: priority,
+		less:     p.less,
+	}
+	p.queues = append(p.queues, nil)
+	copy(p.queues[i+1:], p.queues[i:])
+	p.queues[i] = q
+	return q
+}
+
+// nextQueue returns the highest priority queue with items in - must
+// be called with lock held and with items in the pipe
+func (p *pipe) nextQueue() *pipeQueue {
+	for _, q := range p.queues {
+		if len(q.queue) > 0 {
+			return q
+		}
+	}
+	panic("pipe: no items in queues")
+}
+
 // Put a pair into the pipe
 //
 // It returns ok = false if the context was cancelled
@@ -90,17 +138,19 @@ func (p *pipe) Put(ctx context.Context, pair fs.ObjectPair) (ok bool) {
 		return false
 	}
 	p.mu.Lock()
+	q := p.findQueue(pair)
 	if p.less == nil {
 		// no order-by
-		p.queue = append(p.queue, pair)
+		q.queue = append(q.queue, pair)
 	} else {
-		deheap.Push(p, pair)
+		deheap.Push(q, pair)
 	}
+	p.items++
 	size := pair.Src.Size()
 	if size > 0 {
 		p.totalSize += size
 	}
-	p.stats(len(p.queue), p.totalSize)
+	p.stats(p.items, p.totalSize)
 	p.mu.Unlock()
 	select {
 	case <-ctx.Done():
@@ -115,6 +165,9 @@ func (p *pipe) Put(ctx context.Context, pair fs.ObjectPair) (ok bool) {
 // If fraction is > the mixed fraction set in the pipe then it gets it
 // from the other end of the heap if order-by is in effect
 //
+// Items are always taken from the highest priority queue with items
+// in.
+//
 // It returns ok = false if the context was cancelled or Close() has
 // been called.
 func (p *pipe) GetMax(ctx context.Context, fraction int) (pair fs.ObjectPair, ok bool) {
@@ -130,16 +183,18 @@ func (p *pipe) GetMax(ctx context.Context, fraction int) (pair fs.ObjectPair, ok
 		}
 	}
 	p.mu.Lock()
+	q := p.nextQueue()
 	if p.less == nil {
 		// no order-by
-		pair = p.queue[0]
-		p.queue[0] = fs.ObjectPair{} // avoid memory leak
-		p.queue = p.queue[1:]
+		pair = q.queue[0]
+		q.queue[0] = fs.ObjectPair{} // avoid memory leak
+		q.queue = q.queue[1:]
 	} else if p.fraction < 0 || fraction < p.fraction {
-		pair = deheap.Pop(p).(fs.ObjectPair)
+		pair = deheap.Pop(q).(fs.ObjectPair)
 	} else {
-		pair = deheap.PopMax(p).(fs.ObjectPair)
+		pair = deheap.PopMax(q).(fs.ObjectPair)
 	}
+	p.items--
 	size := pair.Src.Size()
 	if size > 0 {
 		p.totalSize -= size
@@ -147,7 +202,7 @@ func (p *pipe) GetMax(ctx context.Context, fraction int) (pair fs.ObjectPair, ok
 	if p.totalSize < 0 {
 		p.totalSize = 0
 	}
-	p.stats(len(p.queue), p.totalSize)
+	p.stats(p.items, p.totalSize)
 	p.mu.Unlock()
 	return pair, true
 }
@@ -163,7 +218,7 @@ func (p *pipe) Get(ctx context.Context) (pair fs.ObjectPair, ok bool) {
 // Stats reads the number of items in the queue and the totalSize
 func (p *pipe) Stats() (items int, totalSize int64) {
 	p.mu.Lock()
-	items, totalSize = len(p.queue), p.totalSize
+	items, totalSize = p.items, p.totalSize
 	p.mu.Unlock()
 	return items, totalSize
 }
@@ -233,3 +288,34 @@ func newLess(orderBy string) (less lessFn, fraction int, err error) {
 	}
 	return less, fraction, nil
 }
+
+// newPriority returns a priority function for the pipe from the
+// --transfer-priority rules or nil if there aren't any
+func newPriority(ctx context.Context) (priority priorityFn, err error) {
+	ci := fs.GetConfig(ctx)
+	if len(ci.TransferPriorities) == 0 {
+		return nil, nil
+	}
+	fi := filter.GetConfig(ctx)
+	type rule struct {
+		re       *regexp.Regexp
+		priority int
+	}
+	rules := make([]rule, 0, len(ci.TransferPriorities))
+	for _, tp := range ci.TransferPriorities {
+		re, err := filter.GlobToRegexp(tp.Glob, fi.Opt.IgnoreCase)
+		if err != nil {
+			return nil, fmt.Errorf("bad --transfer-priority glob %q: %w", tp.Glob, err)
+		}
+		rules = append(rules, rule{re: re, priority: tp.Priority})
+	}
+	return func(pair fs.ObjectPair) int {
+		remote := pair.Src.Remote()
+		for _, r := range rules {
+			if r.re.MatchString(remote) {
+				return r.priority
+			}
+		}
+		return 0
+	}, nil
+}
@@ -14,7 +14,7 @@ import (
 )
 
 // Check interface satisfied
-var _ heap.Interface = (*pipe)(nil)
+var _ heap.Interface = (*pipeQueue)(nil)
 
 func TestPipe(t *testing.T) {
 	var queueLength int
@@ -24,7 +24,7 @@ func TestPipe(t *testing.T) {
 	}
 
 	// Make a new pipe
-	p, err := newPipe("", stats, 10)
+	p, err := newPipe("", nil, stats, 10)
 	require.NoError(t, err)
 
 	checkStats := func(expectedN int, expectedSize int64) {
@@ -66,7 +66,7 @@ func TestPipe(t *testing.T) {
 	assert.Panics(t, func() { p.Put(ctx, pair1) })
 
 	// Make a new pipe
-	p, err = newPipe("", stats, 10)
+	p, err = newPipe("", nil, stats, 10)
 	require.NoError(t, err)
 	ctx2, cancel := context.WithCancel(ctx)
 
@@ -93,7 +93,7 @@ func TestPipeConcurrent(t *testing.T) {
 	stats := func(n int, size int64) {}
 
 	// Make a new pipe
-	p, err := newPipe("", stats, 10)
+	p, err := newPipe("", nil, stats, 10)
 	require.NoError(t, err)
 
 	var wg sync.WaitGroup
@@ -159,7 +159,7 @@ func TestPipeOrderBy(t *testing.T) {
 		{"size,mixed,51", true, true, 75},
 	} {
 		t.Run(test.orderBy, func(t *testing.T) {
-			p, err := newPipe(test.orderBy, stats, 10)
+			p, err := newPipe(test.orderBy, nil, stats, 10)
 			require.NoError(t, err)
 
 			readAndCheck := func(swapped bool) {
@@ -201,6 +201,73 @@ func TestPipeOrderBy(t *testing.T) {
 	}
 }
 
+func TestPipePriority(t *testing.T) {
+	var (
+		stats = func(n int, size int64) {}
+		ctx   = context.Background()
+	)
+	ctx, ci := fs.AddConfig(ctx)
+	ci.TransferPriorities = []fs.TransferPriority{
+		{Glob: "*.conf", Priority: 10},
+		{Glob: "*.tmp", Priority: -1},
+	}
+	priority, err := newPriority(ctx)
+	require.NoError(t, err)
+
+	pair := func(name, contents string) fs.ObjectPair {
+		return fs.ObjectPair{Src: mockobject.New(name).WithContent([]byte(contents), mockobject.SeekModeNone)}
+	}
+	assert.Equal(t, 10, priority(pair("dir/app.conf", "")))
+	assert.Equal(t, 0, priority(pair("movie.mkv", "")))
+	assert.Equal(t, -1, priority(pair("file.tmp", "")))
+
+	for _, test := range []struct {
+		orderBy  string
+		fraction int
+		want     []string
+	}{
+		{"", -1, []string{"b.conf", "a.conf", "big.mkv", "small.mkv", "x.tmp"}},
+		{"size", -1, []string{"a.conf", "b.conf", "small.mkv", "big.mkv", "x.tmp"}},
+		{"size,mixed", 75, []string{"b.conf", "a.conf", "big.mkv", "small.mkv", "x.tmp"}},
+	} {
+		t.Run(test.orderBy, func(t *testing.T) {
+			p, err := newPipe(test.orderBy, priority, stats, 10)
+			require.NoError(t, err)
+			for _, item := range []fs.ObjectPair{
+				pair("x.tmp", "1"),
+				pair("big.mkv", "4444"),
+				pair("b.conf", "22"),
+				pair("small.mkv", "333"),
+				pair("a.conf", "1"),
+			} {
+				require.True(t, p.Put(ctx, item))
+			}
+			items, _ := p.Stats()
+			assert.Equal(t, 5, items)
+			var got []string
+			for range test.want {
+				pair, ok := p.GetMax(ctx, test.fraction)
+				require.True(t, ok)
+				got = append(got, pair.Src.Remote())
+			}
+			assert.Equal(t, test.want, got)
+			items, _ = p.Stats()
+			assert.Equal(t, 0, items)
+		})
+	}
+
+	// Check a bad glob is an error
+	ci.TransferPriorities = []fs.TransferPriority{{Glob: "{", Priority: 1}}
+	_, err = newPriority(ctx)
+	assert.Error(t, err)
+
+	// Check no priorities gives a nil priority function
+	ci.TransferPriorities = nil
+	priority, err = newPriority(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, priority)
+}
+
 func TestNewLess(t *testing.T) {
 	t.Run("blankOK", func(t *testing.T) {
 		less, _, err := newLess("")
@@ -3,6 +3,7 @@ package sync
 import (
 	"context"
 
+	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fs/rc"
 )
 
@@ -34,6 +35,9 @@ See the [` + name + `](/commands/rclone_` + name + `/) command for more informat
 
 // Sync/Copy/Move a file
 func rcSyncCopyMove(ctx context.Context, in rc.Params, name string) (out rc.Params, err error) {
+	if err := operations.CheckDeleteConfirm(ctx); err != nil {
+		return nil, err
+	}
 	srcFs, err := rc.GetFsNamed(ctx, in, "srcFs")
 	if err != nil {
 		return nil, err
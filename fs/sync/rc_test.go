@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/cache"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fstest"
@@ -97,3 +98,19 @@ func TestRcSync(t *testing.T) {
 	r.CheckLocalItems(t, file1, file2)
 	r.CheckRemoteItems(t, file1, file2)
 }
+
+// sync/sync: --delete-confirm can't be used as there is no one to ask
+func TestRcSyncDeleteConfirm(t *testing.T) {
+	r, call := rcNewRun(t, "sync/sync")
+	defer r.Finalise()
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.DeleteConfirm = true
+
+	in := rc.Params{
+		"srcFs": r.LocalName,
+		"dstFs": r.FremoteName,
+	}
+	_, err := call.Fn(ctx, in)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--delete-confirm")
+}
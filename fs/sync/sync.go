@@ -2,9 +2,13 @@
 package sync
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"path"
 	"sort"
 	"strings"
@@ -13,11 +17,13 @@ import (
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/accounting"
+	"github.com/rclone/rclone/fs/config"
 	"github.com/rclone/rclone/fs/filter"
 	"github.com/rclone/rclone/fs/fserrors"
 	"github.com/rclone/rclone/fs/hash"
 	"github.com/rclone/rclone/fs/march"
 	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/lib/terminal"
 )
 
 type syncCopyMove struct {
@@ -82,6 +88,7 @@ const (
 	trackRenamesStrategyHash trackRenamesStrategy = 1 << iota
 	trackRenamesStrategyModtime
 	trackRenamesStrategyLeaf
+	trackRenamesStrategySimilar
 )
 
 func (strategy trackRenamesStrategy) hash() bool {
@@ -96,6 +103,10 @@ func (strategy trackRenamesStrategy) leaf() bool {
 	return (strategy & trackRenamesStrategyLeaf) != 0
 }
 
+func (strategy trackRenamesStrategy) similar() bool {
+	return (strategy & trackRenamesStrategySimilar) != 0
+}
+
 func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.DeleteMode, DoMove bool, deleteEmptySrcDirs bool, copyEmptySrcDirs bool) (*syncCopyMove, error) {
 	if (deleteMode != fs.DeleteModeOff || DoMove) && operations.OverlappingFilterCheck(ctx, fdst, fsrc) {
 		return nil, fserrors.FatalError(fs.ErrorOverlapping)
@@ -132,16 +143,19 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 		fs.Infof(s.fdst, "Running all checks before starting transfers")
 		backlog = -1
 	}
-	var err error
-	s.toBeChecked, err = newPipe(ci.OrderBy, accounting.Stats(ctx).SetCheckQueue, backlog)
+	priority, err := newPriority(ctx)
+	if err != nil {
+		return nil, fserrors.FatalError(err)
+	}
+	s.toBeChecked, err = newPipe(ci.OrderBy, priority, accounting.Stats(ctx).SetCheckQueue, backlog)
 	if err != nil {
 		return nil, err
 	}
-	s.toBeUploaded, err = newPipe(ci.OrderBy, accounting.Stats(ctx).SetTransferQueue, backlog)
+	s.toBeUploaded, err = newPipe(ci.OrderBy, priority, accounting.Stats(ctx).SetTransferQueue, backlog)
 	if err != nil {
 		return nil, err
 	}
-	s.toBeRenamed, err = newPipe(ci.OrderBy, accounting.Stats(ctx).SetRenameQueue, backlog)
+	s.toBeRenamed, err = newPipe(ci.OrderBy, priority, accounting.Stats(ctx).SetRenameQueue, backlog)
 	if err != nil {
 		return nil, err
 	}
@@ -189,6 +203,9 @@ func newSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 			return nil, errors.New("can't use --no-check-dest with --backup-dir")
 		}
 	}
+	if ci.DeleteConfirm && !ci.DryRun && s.deleteMode != fs.DeleteModeOff && !canConfirm() {
+		return nil, fserrors.FatalError(errors.New("can't use --delete-confirm without a terminal to confirm the deletes on"))
+	}
 	if s.trackRenames {
 		// Don't track renames for remotes without server-side move support.
 		if !operations.CanServerSideMove(fdst) {
@@ -529,17 +546,27 @@ func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
 		return fs.ErrorNotDeleting
 	}
 
+	// Find the spare files
+	var spare []fs.Object
+	for remote, o := range s.dstFiles {
+		if checkSrcMap {
+			_, exists := s.srcFiles[remote]
+			if exists {
+				continue
+			}
+		}
+		spare = append(spare, o)
+	}
+	if err := s.confirmDeletes(len(spare)); err != nil {
+		fs.Errorf(s.fdst, "%v", err)
+		return err
+	}
+
 	// Delete the spare files
 	toDelete := make(fs.ObjectsChan, s.ci.Transfers)
 	go func() {
 	outer:
-		for remote, o := range s.dstFiles {
-			if checkSrcMap {
-				_, exists := s.srcFiles[remote]
-				if exists {
-					continue
-				}
-			}
+		for _, o := range spare {
 			if s.aborting() {
 				break
 			}
@@ -554,6 +581,39 @@ func (s *syncCopyMove) deleteFiles(checkSrcMap bool) error {
 	return operations.DeleteFilesWithBackupDir(s.ctx, toDelete, s.backupDir)
 }
 
+// confirm asks the user a yes/no question on stderr so it doesn't
+// get mixed up with the output - overridden in tests
+var confirm = func(question string) bool {
+	_, _ = fmt.Fprintln(os.Stderr, question)
+	return config.ConfirmTo(os.Stderr, false)
+}
+
+// canConfirm returns whether there is a terminal to ask the user
+// questions on - overridden in tests
+var canConfirm = func() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// confirmDeletes checks n, the number of files about to be deleted,
+// against --delete-threshold and asks the user whether to delete them
+// if --delete-confirm is set.
+//
+// It returns an error if the files shouldn't be deleted.
+func (s *syncCopyMove) confirmDeletes(n int) error {
+	if n == 0 {
+		return nil
+	}
+	if s.ci.DeleteThreshold >= 0 && int64(n) > s.ci.DeleteThreshold {
+		return fserrors.NoRetryError(fmt.Errorf("not deleting files as %d deletes is more than --delete-threshold %d", n, s.ci.DeleteThreshold))
+	}
+	if s.ci.DeleteConfirm && !s.ci.DryRun {
+		if !confirm(fmt.Sprintf("rclone is about to delete %d files from %v", n, s.fdst)) {
+			return fserrors.NoRetryError(fmt.Errorf("not deleting files as deleting %d files wasn't confirmed", n))
+		}
+	}
+	return nil
+}
+
 // This deletes the empty directories in the slice passed in.  It
 // ignores any errors deleting directories
 func (s *syncCopyMove) deleteEmptyDirectories(ctx context.Context, f fs.Fs, entriesMap map[string]fs.DirEntry) error {
@@ -656,6 +716,9 @@ func parseTrackRenamesStrategy(strategies string) (strategy trackRenamesStrategy
 			strategy |= trackRenamesStrategyModtime
 		case "leaf":
 			strategy |= trackRenamesStrategyLeaf
+		case "similar":
+			// similar matches on size and modtime then picks the most similar name
+			strategy |= trackRenamesStrategySimilar | trackRenamesStrategyModtime
 		case "size":
 			// ignore
 		default:
@@ -721,12 +784,21 @@ func (s *syncCopyMove) popRenameMap(hash string, src fs.Object) (dst fs.Object)
 		if s.trackRenamesStrategy.modTime() {
 			i = -1
 			srcModTime := src.ModTime(s.ctx)
+			bestSimilarity := -1.0
 			for j, dst := range dsts {
 				dstModTime := dst.ModTime(s.ctx)
 				dt := dstModTime.Sub(srcModTime)
 				if dt < s.modifyWindow && dt > -s.modifyWindow {
-					i = j
-					break
+					if !s.trackRenamesStrategy.similar() {
+						i = j
+						break
+					}
+					// Choose the candidate with the most similar name
+					similarity := leafSimilarity(path.Base(src.Remote()), path.Base(dst.Remote()))
+					if similarity > bestSimilarity {
+						i = j
+						bestSimilarity = similarity
+					}
 				}
 			}
 			// If nothing matched then return nil
@@ -747,6 +819,93 @@ func (s *syncCopyMove) popRenameMap(hash string, src fs.Object) (dst fs.Object)
 	return dst
 }
 
+// leafSimilarity returns how similar the names a and b are from 0
+// (nothing in common) to 1 (the same) using the edit distance between
+// them.
+func leafSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	// Levenshtein distance keeping only the previous row
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+// sampleEqual reads up to sample bytes from the start, middle and end
+// of src and dst, which must be the same size, and returns whether
+// they are the same.
+func sampleEqual(ctx context.Context, src, dst fs.Object, sample int64) (equal bool, err error) {
+	size := src.Size()
+	if size < 0 || size != dst.Size() {
+		return false, nil
+	}
+	var ranges []fs.RangeOption
+	if size <= sample {
+		ranges = []fs.RangeOption{{Start: 0, End: size - 1}}
+	} else {
+		chunk := sample / 3
+		if chunk <= 0 {
+			chunk = 1
+		}
+		for _, start := range []int64{0, (size - chunk) / 2, size - chunk} {
+			ranges = append(ranges, fs.RangeOption{Start: start, End: start + chunk - 1})
+		}
+	}
+	for _, r := range ranges {
+		if r.End < r.Start {
+			continue
+		}
+		srcData, err := readRange(ctx, src, r)
+		if err != nil {
+			return false, err
+		}
+		dstData, err := readRange(ctx, dst, r)
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(srcData, dstData) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// readRange reads the range r of o
+func readRange(ctx context.Context, o fs.Object, r fs.RangeOption) (data []byte, err error) {
+	in, err := o.Open(ctx, &r)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.CheckClose(in, &err)
+	return ioutil.ReadAll(io.LimitReader(in, r.End-r.Start+1))
+}
+
 // makeRenameMap builds a map of the destination files by hash that
 // match sizes in the slice of objects in s.renameCheck
 func (s *syncCopyMove) makeRenameMap() {
@@ -804,6 +963,21 @@ func (s *syncCopyMove) tryRename(src fs.Object) bool {
 		return false
 	}
 
+	// Compare samples of the contents if required
+	if sample := s.ci.TrackRenamesSample; s.trackRenamesStrategy.similar() && sample > 0 {
+		equal, err := sampleEqual(s.ctx, src, dst, int64(sample))
+		if err != nil || !equal {
+			if err != nil {
+				fs.Debugf(src, "Failed to compare samples with %q: %v", dst.Remote(), err)
+			} else {
+				fs.Debugf(src, "Not renaming from %q as the samples of their contents differ", dst.Remote())
+			}
+			// Put it back for another file to match
+			s.pushRenameMap(hash, dst)
+			return false
+		}
+	}
+
 	// Find dst object we are about to overwrite if it exists
 	dstOverwritten, _ := s.fdst.NewObject(s.ctx, src.Remote())
 
@@ -1085,6 +1259,9 @@ func runSyncCopyMove(ctx context.Context, fdst, fsrc fs.Fs, deleteMode fs.Delete
 	if deleteMode != fs.DeleteModeOff && DoMove {
 		return fserrors.FatalError(errors.New("can't delete and move at the same time"))
 	}
+	if (ci.DeleteThreshold >= 0 || ci.DeleteConfirm) && deleteMode != fs.DeleteModeOff && deleteMode != fs.DeleteModeAfter {
+		return fserrors.FatalError(errors.New("--delete-threshold and --delete-confirm can only be used with --delete-after"))
+	}
 	// Run an extra pass to delete only
 	if deleteMode == fs.DeleteModeBefore {
 		if ci.TrackRenames {
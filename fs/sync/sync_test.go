@@ -6,6 +6,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -859,6 +861,73 @@ func TestSyncDeleteAfter(t *testing.T) {
 	require.Equal(t, ci.DeleteMode, fs.DeleteModeAfter, "Didn't default to --delete-after")
 }
 
+// Sync test delete after with --delete-threshold and --delete-confirm
+func TestSyncDeleteThreshold(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	file1 := r.WriteBoth(ctx, "empty space", "-", t2)
+	file2 := r.WriteObject(ctx, "potato", "delete me", t1)
+	file3 := r.WriteObject(ctx, "potato2", "delete me too", t1)
+	file4 := r.WriteFile("new", "copy me", t1)
+	r.CheckLocalItems(t, file1, file4)
+	r.CheckRemoteItems(t, file1, file2, file3)
+
+	// Only works with --delete-after
+	ci.DeleteThreshold = 1
+	ci.DeleteMode = fs.DeleteModeDuring
+	err := Sync(ctx, r.Fremote, r.Flocal, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--delete-after")
+	assert.True(t, fserrors.IsFatalError(err))
+	r.CheckRemoteItems(t, file1, file2, file3)
+	ci.DeleteMode = fs.DeleteModeAfter
+
+	// Too many deletions - should copy but not delete
+	ci.DeleteThreshold = 1
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, r.Fremote, r.Flocal, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--delete-threshold")
+	assert.True(t, fserrors.IsNoRetryError(err))
+	r.CheckRemoteItems(t, file1, file2, file3, file4)
+
+	// No terminal to confirm deletions on - should fail before copying
+	oldCanConfirm := canConfirm
+	defer func() { canConfirm = oldCanConfirm }()
+	canConfirm = func() bool { return false }
+	r.WriteFile("new2", "don't copy me", t1)
+	ci.DeleteThreshold = 2
+	ci.DeleteConfirm = true
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, r.Fremote, r.Flocal, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--delete-confirm")
+	r.CheckRemoteItems(t, file1, file2, file3, file4)
+	require.NoError(t, os.Remove(filepath.Join(r.LocalName, "new2")))
+	canConfirm = func() bool { return true }
+
+	// Deletions not confirmed - should not delete
+	oldConfirm := confirm
+	defer func() { confirm = oldConfirm }()
+	confirmed := false
+	confirm = func(string) bool { return confirmed }
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, r.Fremote, r.Flocal, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "wasn't confirmed")
+	r.CheckRemoteItems(t, file1, file2, file3, file4)
+
+	// Deletions confirmed and within the threshold
+	confirmed = true
+	accounting.GlobalStats().ResetCounters()
+	err = Sync(ctx, r.Fremote, r.Flocal, false)
+	require.NoError(t, err)
+	r.CheckRemoteItems(t, file1, file4)
+}
+
 // Sync test delete during
 func TestSyncDeleteDuring(t *testing.T) {
 	ctx := context.Background()
@@ -1110,6 +1179,7 @@ func TestParseRenamesStrategyModtime(t *testing.T) {
 		{"size", 0, false},
 		{"modtime,hash", trackRenamesStrategyModtime | trackRenamesStrategyHash, false},
 		{"hash,modtime,size", trackRenamesStrategyModtime | trackRenamesStrategyHash, false},
+		{"similar", trackRenamesStrategySimilar | trackRenamesStrategyModtime, false},
 		{"size,boom", 0, true},
 	} {
 		got, err := parseTrackRenamesStrategy(test.in)
@@ -1203,6 +1273,74 @@ func TestSyncWithTrackRenamesStrategyLeaf(t *testing.T) {
 	}
 }
 
+func TestLeafSimilarity(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"potato", "potato", 1},
+		{"potato", "", 0},
+		{"abc", "xyz", 0},
+		{"potato.txt", "potato2.txt", 1 - 1.0/11},
+		{"kitten", "sitting", 1 - 3.0/7},
+	} {
+		assert.InDelta(t, test.want, leafSimilarity(test.a, test.b), 1e-9, test.a+" "+test.b)
+		assert.InDelta(t, test.want, leafSimilarity(test.b, test.a), 1e-9, test.b+" "+test.a)
+	}
+}
+
+func TestSyncWithTrackRenamesStrategySimilar(t *testing.T) {
+	ctx := context.Background()
+	ctx, ci := fs.AddConfig(ctx)
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+
+	ci.TrackRenames = true
+	ci.TrackRenamesStrategy = "similar"
+
+	canTrackRenames := operations.CanServerSideMove(r.Fremote) && r.Fremote.Precision() != fs.ModTimeNotSupported
+	t.Logf("Can track renames: %v", canTrackRenames)
+
+	// Files with the same size and modtime
+	f1 := r.WriteFile("potato.txt", "Potato Content", t1)
+	f2 := r.WriteFile("carrot.txt", "Carrot Content", t1)
+	f3 := r.WriteFile("turnip.txt", "Turnip Content", t2)
+
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+
+	r.CheckRemoteItems(t, f1, f2, f3)
+	r.CheckLocalItems(t, f1, f2, f3)
+
+	// Now rename locally - the most similar name should be chosen
+	f1 = r.RenameFile(f1, "potato2.txt")
+	f2 = r.RenameFile(f2, "carrot2.txt")
+
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+
+	r.CheckRemoteItems(t, f1, f2, f3)
+
+	// Check we renamed something if we should have
+	if canTrackRenames {
+		renames := accounting.GlobalStats().Renames(0)
+		assert.Equal(t, int64(2), renames)
+	}
+
+	// Change the contents of f3 but not its size or modtime and
+	// check the samples stop it being renamed
+	ci.TrackRenamesSample = 6
+	f3 = r.WriteFile("turnip2.txt", "TURNIP CONTENT", t2)
+	require.NoError(t, os.Remove(filepath.Join(r.LocalName, "turnip.txt")))
+
+	accounting.GlobalStats().ResetCounters()
+	require.NoError(t, Sync(ctx, r.Fremote, r.Flocal, false))
+
+	r.CheckRemoteItems(t, f1, f2, f3)
+	assert.Equal(t, int64(0), accounting.GlobalStats().Renames(0))
+}
+
 func toyFileTransfers(r *fstest.Run) int64 {
 	remote := r.Fremote.Name()
 	transfers := 1
@@ -0,0 +1,490 @@
+// Three way sync using a snapshot of the previous run
+
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/walk"
+)
+
+// ConflictMode says how a three way sync resolves a file which has
+// been changed on both sides since the last run
+type ConflictMode byte
+
+// ConflictMode constants
+const (
+	ConflictModeNone  ConflictMode = iota // leave both sides alone and report an error
+	ConflictModeNewer                     // the side with the newest modification time wins
+	ConflictModePath1                     // path1 wins
+	ConflictModePath2                     // path2 wins
+)
+
+var conflictModeToString = []string{
+	ConflictModeNone:  "none",
+	ConflictModeNewer: "newer",
+	ConflictModePath1: "path1",
+	ConflictModePath2: "path2",
+}
+
+// String turns a ConflictMode into a string
+func (m ConflictMode) String() string {
+	if m >= ConflictMode(len(conflictModeToString)) {
+		return fmt.Sprintf("ConflictMode(%d)", m)
+	}
+	return conflictModeToString[m]
+}
+
+// Set a ConflictMode
+func (m *ConflictMode) Set(s string) error {
+	for n, name := range conflictModeToString {
+		if s == name {
+			*m = ConflictMode(n)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown conflict mode %q", s)
+}
+
+// Type of the value
+func (m *ConflictMode) Type() string {
+	return "string"
+}
+
+// ThreeWayOpt is the options for ThreeWay
+type ThreeWayOpt struct {
+	Snapshot string       // path of the local file holding the snapshot
+	Conflict ConflictMode // how to resolve conflicts
+}
+
+// snapshotVersion is the version of the snapshot file format
+const snapshotVersion = 1
+
+// snapshotFile is the state of a file on one side of the sync
+type snapshotFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Hash    string    `json:"hash,omitempty"` // type:value if known
+}
+
+// snapshotEntry is the state of a file on both sides of the sync
+type snapshotEntry struct {
+	Path1 *snapshotFile `json:"path1"`
+	Path2 *snapshotFile `json:"path2"`
+}
+
+// snapshot is the state of both sides at the end of the last run
+type snapshot struct {
+	Version int                       `json:"version"`
+	Path1   string                    `json:"path1"`
+	Path2   string                    `json:"path2"`
+	Files   map[string]*snapshotEntry `json:"files"`
+}
+
+// readSnapshot reads the snapshot from name returning an empty one if
+// it doesn't exist
+func readSnapshot(name string, f1, f2 fs.Fs) (*snapshot, error) {
+	s := &snapshot{
+		Version: snapshotVersion,
+		Path1:   fs.ConfigString(f1),
+		Path2:   fs.ConfigString(f2),
+		Files:   map[string]*snapshotEntry{},
+	}
+	data, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		fs.Logf(nil, "No snapshot found in %q - treating all files as new", name)
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var old snapshot
+	if err = json.Unmarshal(data, &old); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %q: %w", name, err)
+	}
+	if old.Version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot %q has unsupported version %d", name, old.Version)
+	}
+	if old.Path1 != s.Path1 || old.Path2 != s.Path2 {
+		return nil, fmt.Errorf("snapshot %q is for %q and %q not %q and %q", name, old.Path1, old.Path2, s.Path1, s.Path2)
+	}
+	if old.Files != nil {
+		s.Files = old.Files
+	}
+	return s, nil
+}
+
+// write the snapshot to name atomically
+func (s *snapshot) write(name string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		return fmt.Errorf("failed to make snapshot directory: %w", err)
+	}
+	tmp := name + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err = os.Rename(tmp, name); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// threeWay holds the state of a three way sync
+type threeWay struct {
+	ctx    context.Context
+	ci     *fs.ConfigInfo
+	opt    ThreeWayOpt
+	f1, f2 fs.Fs
+	window time.Duration // modify window between f1 and f2
+	old    *snapshot     // snapshot from the last run
+	mu     sync.Mutex    // protect new
+	new    *snapshot     // snapshot for the next run
+}
+
+// listFiles lists all the objects in f
+func listFiles(ctx context.Context, f fs.Fs) (map[string]fs.Object, error) {
+	ci := fs.GetConfig(ctx)
+	objs := map[string]fs.Object{}
+	err := walk.ListR(ctx, f, "", false, ci.MaxDepth, walk.ListObjects, func(entries fs.DirEntries) error {
+		entries.ForObject(func(o fs.Object) {
+			objs[o.Remote()] = o
+		})
+		return nil
+	})
+	if errors.Is(err, fs.ErrorDirNotFound) {
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %v: %w", f, err)
+	}
+	return objs, nil
+}
+
+// hashOf returns the hash of o as type:value if it can be read
+// cheaply, or always if --checksum is set
+func (t *threeWay) hashOf(o fs.Object) string {
+	f := o.Fs()
+	ht := f.Hashes().GetOne()
+	if ht == hash.None {
+		return ""
+	}
+	if fi, ok := f.(fs.Fs); ok && fi.Features().SlowHash && !t.ci.CheckSum {
+		return ""
+	}
+	sum, err := o.Hash(t.ctx, ht)
+	if err != nil || sum == "" {
+		return ""
+	}
+	return ht.String() + ":" + sum
+}
+
+// state returns the snapshot state of o, or nil if o is nil
+func (t *threeWay) state(o fs.Object) *snapshotFile {
+	if o == nil {
+		return nil
+	}
+	return &snapshotFile{
+		Size:    o.Size(),
+		ModTime: o.ModTime(t.ctx).UTC(),
+		Hash:    t.hashOf(o),
+	}
+}
+
+// changed returns true if cur differs from old
+//
+// The hashes are used if both are known otherwise the size and
+// modification time.
+func (t *threeWay) changed(old, cur *snapshotFile) bool {
+	if old == nil || cur == nil {
+		return (old == nil) != (cur == nil)
+	}
+	if old.Size != cur.Size {
+		return true
+	}
+	if old.Hash != "" && cur.Hash != "" {
+		return old.Hash != cur.Hash
+	}
+	dt := cur.ModTime.Sub(old.ModTime)
+	return dt >= t.window || dt <= -t.window
+}
+
+// same returns true if the current files on both sides are the same
+func (t *threeWay) same(o1, o2 fs.Object) bool {
+	if o1.Size() != o2.Size() {
+		return false
+	}
+	equal, ht, err := operations.CheckHashes(t.ctx, o1, o2)
+	if err == nil && ht != hash.None {
+		return equal
+	}
+	dt := o1.ModTime(t.ctx).Sub(o2.ModTime(t.ctx))
+	return dt < t.window && dt > -t.window
+}
+
+// record sets the snapshot for remote
+//
+// Files which aren't recorded keep the snapshot from the last run so
+// they are looked at again on the next run.
+func (t *threeWay) record(remote string, s1, s2 *snapshotFile) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s1 == nil && s2 == nil {
+		delete(t.new.Files, remote)
+		return
+	}
+	t.new.Files[remote] = &snapshotEntry{Path1: s1, Path2: s2}
+}
+
+// copy src over dst (which may be nil) on fdst recording the result
+func (t *threeWay) copy(remote string, fdst fs.Fs, dst, src fs.Object, srcIsPath1 bool) error {
+	newDst, err := operations.Copy(t.ctx, fdst, dst, remote, src)
+	if err != nil || newDst == nil || t.ci.DryRun {
+		return err
+	}
+	if srcIsPath1 {
+		t.record(remote, t.state(src), t.state(newDst))
+	} else {
+		t.record(remote, t.state(newDst), t.state(src))
+	}
+	return nil
+}
+
+// remove dst recording the result
+func (t *threeWay) remove(remote string, dst fs.Object) error {
+	err := operations.DeleteFile(t.ctx, dst)
+	if err != nil || t.ci.DryRun {
+		return err
+	}
+	t.record(remote, nil, nil)
+	return nil
+}
+
+// conflict resolves remote which has changed on both sides
+func (t *threeWay) conflict(remote string, o1, o2 fs.Object) error {
+	winner := t.opt.Conflict
+	if winner == ConflictModeNewer {
+		switch {
+		case o1 == nil:
+			winner = ConflictModePath2
+		case o2 == nil:
+			winner = ConflictModePath1
+		case o1.ModTime(t.ctx).After(o2.ModTime(t.ctx)):
+			winner = ConflictModePath1
+		default:
+			winner = ConflictModePath2
+		}
+	}
+	switch winner {
+	case ConflictModePath1:
+		fs.Logf(remote, "Conflict: changed on both sides - using the version from path1")
+		if o1 == nil {
+			return t.remove(remote, o2)
+		}
+		return t.copy(remote, t.f2, o2, o1, true)
+	case ConflictModePath2:
+		fs.Logf(remote, "Conflict: changed on both sides - using the version from path2")
+		if o2 == nil {
+			return t.remove(remote, o1)
+		}
+		return t.copy(remote, t.f1, o1, o2, false)
+	}
+	return fmt.Errorf("%s: conflict: changed on both sides since the last run - resolve by hand or with --three-way-conflict", remote)
+}
+
+// syncFile does the three way sync of a single file
+func (t *threeWay) syncFile(remote string, o1, o2 fs.Object) error {
+	var old1, old2 *snapshotFile
+	if old := t.old.Files[remote]; old != nil {
+		old1, old2 = old.Path1, old.Path2
+	}
+	s1, s2 := t.state(o1), t.state(o2)
+	changed1, changed2 := t.changed(old1, s1), t.changed(old2, s2)
+	switch {
+	case !changed1 && !changed2:
+		t.record(remote, s1, s2)
+		return nil
+	case changed1 && !changed2:
+		if o1 == nil {
+			fs.Debugf(remote, "Deleted on path1")
+			return t.remove(remote, o2)
+		}
+		fs.Debugf(remote, "Changed on path1")
+		return t.copy(remote, t.f2, o2, o1, true)
+	case !changed1 && changed2:
+		if o2 == nil {
+			fs.Debugf(remote, "Deleted on path2")
+			return t.remove(remote, o1)
+		}
+		fs.Debugf(remote, "Changed on path2")
+		return t.copy(remote, t.f1, o1, o2, false)
+	}
+	// Changed on both sides
+	switch {
+	case o1 == nil && o2 == nil:
+		fs.Debugf(remote, "Deleted on both sides")
+		t.record(remote, nil, nil)
+		return nil
+	case o1 != nil && o2 != nil && t.same(o1, o2):
+		fs.Debugf(remote, "Changed identically on both sides")
+		t.record(remote, s1, s2)
+		return nil
+	case o1 == nil:
+		fs.Logf(remote, "Deleted on path1 but changed on path2 - keeping the change")
+		return t.copy(remote, t.f1, nil, o2, false)
+	case o2 == nil:
+		fs.Logf(remote, "Deleted on path2 but changed on path1 - keeping the change")
+		return t.copy(remote, t.f2, nil, o1, true)
+	}
+	return t.conflict(remote, o1, o2)
+}
+
+// ThreeWay syncs f1 and f2 in both directions.
+//
+// The state of both sides at the end of the last run is read from the
+// snapshot in opt.Snapshot and compared with their current state to
+// find out which side each file was changed on. Files changed on one
+// side are copied to or deleted from the other side. Files changed on
+// both sides are resolved as set by opt.Conflict. The snapshot is
+// updated at the end of the run.
+//
+// If there is no snapshot then files which are only on one side are
+// copied to the other and files which differ are treated as
+// conflicts.
+func ThreeWay(ctx context.Context, f1, f2 fs.Fs, opt ThreeWayOpt) error {
+	if opt.Snapshot == "" {
+		return errors.New("three way sync needs a snapshot file")
+	}
+	if operations.OverlappingFilterCheck(ctx, f1, f2) {
+		return fserrors.FatalError(fs.ErrorOverlapping)
+	}
+	old, err := readSnapshot(opt.Snapshot, f1, f2)
+	if err != nil {
+		return err
+	}
+	ci := fs.GetConfig(ctx)
+	t := &threeWay{
+		ctx:    ctx,
+		ci:     ci,
+		opt:    opt,
+		f1:     f1,
+		f2:     f2,
+		window: fs.GetModifyWindow(ctx, f1, f2),
+		old:    old,
+		new: &snapshot{
+			Version: snapshotVersion,
+			Path1:   old.Path1,
+			Path2:   old.Path2,
+			Files:   make(map[string]*snapshotEntry, len(old.Files)),
+		},
+	}
+	for remote, entry := range old.Files {
+		t.new.Files[remote] = entry
+	}
+	if t.window == fs.ModTimeNotSupported {
+		t.window = time.Second
+	}
+
+	// List both sides
+	var (
+		objs1, objs2 map[string]fs.Object
+		err1, err2   error
+		wg           sync.WaitGroup
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		objs1, err1 = listFiles(ctx, f1)
+	}()
+	go func() {
+		defer wg.Done()
+		objs2, err2 = listFiles(ctx, f2)
+	}()
+	wg.Wait()
+	if err1 != nil {
+		return err1
+	}
+	if err2 != nil {
+		return err2
+	}
+
+	// Find all the files which are or were on either side
+	remotes := make(map[string]struct{}, len(objs1)+len(old.Files))
+	for remote := range objs1 {
+		remotes[remote] = struct{}{}
+	}
+	for remote := range objs2 {
+		remotes[remote] = struct{}{}
+	}
+	fi := filter.GetConfig(ctx)
+	for remote := range old.Files {
+		if fi.IncludeRemote(remote) {
+			remotes[remote] = struct{}{}
+		}
+	}
+	sorted := make([]string, 0, len(remotes))
+	for remote := range remotes {
+		sorted = append(sorted, remote)
+	}
+	sort.Strings(sorted)
+
+	// Sync them with --transfers in parallel
+	var (
+		errMu    sync.Mutex
+		errCount int
+		lastErr  error
+	)
+	in := make(chan string, ci.Transfers)
+	wg.Add(ci.Transfers)
+	for i := 0; i < ci.Transfers; i++ {
+		go func() {
+			defer wg.Done()
+			for remote := range in {
+				err := t.syncFile(remote, objs1[remote], objs2[remote])
+				if err != nil {
+					err = fs.CountError(err)
+					fs.Errorf(remote, "Three way sync failed: %v", err)
+					errMu.Lock()
+					errCount++
+					lastErr = err
+					errMu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, remote := range sorted {
+		if ctx.Err() != nil {
+			break
+		}
+		in <- remote
+	}
+	close(in)
+	wg.Wait()
+
+	if ci.DryRun {
+		fs.Logf(nil, "Not updating snapshot as --dry-run is set")
+	} else if err := t.new.write(opt.Snapshot); err != nil {
+		return err
+	}
+	if errCount > 0 {
+		return fmt.Errorf("%d files failed to sync - last error: %w", errCount, lastErr)
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,91 @@
+// Test three way sync
+
+package sync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreeWay(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt := ThreeWayOpt{
+		Snapshot: filepath.Join(t.TempDir(), "snapshot.json"),
+	}
+
+	// First run - files on one side are copied to the other
+	a := r.WriteFile("a", "path1 only", t1)
+	b := r.WriteObject(ctx, "b", "path2 only", t1)
+	same := r.WriteBoth(ctx, "same", "on both", t1)
+	require.NoError(t, ThreeWay(ctx, r.Flocal, r.Fremote, opt))
+	r.CheckLocalItems(t, a, b, same)
+	r.CheckRemoteItems(t, a, b, same)
+
+	// Change, delete and add files on both sides
+	a = r.WriteFile("a", "path1 changed", t2)
+	require.NoError(t, os.Remove(filepath.Join(r.LocalName, "b")))
+	c := r.WriteObject(ctx, "c", "path2 new", t2)
+	require.NoError(t, ThreeWay(ctx, r.Flocal, r.Fremote, opt))
+	r.CheckLocalItems(t, a, c, same)
+	r.CheckRemoteItems(t, a, c, same)
+
+	// Nothing changed so nothing to do
+	require.NoError(t, ThreeWay(ctx, r.Flocal, r.Fremote, opt))
+	r.CheckLocalItems(t, a, c, same)
+	r.CheckRemoteItems(t, a, c, same)
+
+	// Deleted on path2 but changed on path1 keeps the change
+	a = r.WriteFile("a", "path1 changed again", t3)
+	obj, err := r.Fremote.NewObject(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, operations.DeleteFile(ctx, obj))
+	require.NoError(t, ThreeWay(ctx, r.Flocal, r.Fremote, opt))
+	r.CheckLocalItems(t, a, c, same)
+	r.CheckRemoteItems(t, a, c, same)
+
+	// Changed on both sides is a conflict which is left alone
+	same1 := r.WriteFile("same", "changed on path1", t2)
+	same2 := r.WriteObject(ctx, "same", "changed on path2!", t3)
+	err = ThreeWay(ctx, r.Flocal, r.Fremote, opt)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "conflict")
+	r.CheckLocalItems(t, a, c, same1)
+	r.CheckRemoteItems(t, a, c, same2)
+
+	// It is still a conflict on the next run and can be resolved
+	opt.Conflict = ConflictModeNewer
+	require.NoError(t, ThreeWay(ctx, r.Flocal, r.Fremote, opt))
+	r.CheckLocalItems(t, a, c, same2)
+	r.CheckRemoteItems(t, a, c, same2)
+}
+
+func TestThreeWaySnapshotMismatch(t *testing.T) {
+	ctx := context.Background()
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	opt := ThreeWayOpt{
+		Snapshot: filepath.Join(t.TempDir(), "snapshot.json"),
+	}
+	require.NoError(t, ThreeWay(ctx, r.Flocal, r.Fremote, opt))
+	err := ThreeWay(ctx, r.Fremote, r.Flocal, opt)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is for")
+}
+
+func TestConflictModeSet(t *testing.T) {
+	var m ConflictMode
+	require.NoError(t, m.Set("newer"))
+	assert.Equal(t, ConflictModeNewer, m)
+	assert.Equal(t, "newer", m.String())
+	require.Error(t, m.Set("potato"))
+	assert.Equal(t, "ConflictMode(99)", ConflictMode(99).String())
+}
@@ -0,0 +1,29 @@
+package fs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TransferPriority is the priority given to the files matching a
+// filter glob when deciding which to transfer first
+type TransferPriority struct {
+	Glob     string
+	Priority int
+}
+
+// ParseTransferPriority parses a TransferPriority from a string of
+// the form GLOB=PRIORITY, e.g. "*.conf=10"
+func ParseTransferPriority(s string) (tp TransferPriority, err error) {
+	equal := strings.LastIndex(s, "=")
+	if equal <= 0 {
+		return tp, fmt.Errorf("failed to parse %q as glob=priority", s)
+	}
+	tp.Glob = s[:equal]
+	tp.Priority, err = strconv.Atoi(strings.TrimSpace(s[equal+1:]))
+	if err != nil {
+		return tp, fmt.Errorf("bad priority in %q: %w", s, err)
+	}
+	return tp, nil
+}
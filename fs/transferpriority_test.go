@@ -0,0 +1,30 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTransferPriority(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    TransferPriority
+		wantErr bool
+	}{
+		{in: "*.conf=10", want: TransferPriority{Glob: "*.conf", Priority: 10}},
+		{in: "{a=b}/**=-5", want: TransferPriority{Glob: "{a=b}/**", Priority: -5}},
+		{in: "*.conf", wantErr: true},
+		{in: "=10", wantErr: true},
+		{in: "*.conf=high", wantErr: true},
+	} {
+		got, err := ParseTransferPriority(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+		} else {
+			require.NoError(t, err, test.in)
+			assert.Equal(t, test.want, got, test.in)
+		}
+	}
+}
@@ -191,6 +191,36 @@ type Metadataer interface {
 	Metadata(ctx context.Context) (Metadata, error)
 }
 
+// SetMetadataer is an optional interface for Object
+type SetMetadataer interface {
+	// SetMetadata merges metadata into the metadata of the object
+	// without uploading it again
+	SetMetadata(ctx context.Context, metadata Metadata) error
+}
+
+// InPlaceWriter is an optional interface for Object
+type InPlaceWriter interface {
+	// OpenInPlace opens a new empty object of size bytes for random
+	// access writes while the object can still be read. The object
+	// is only replaced with the new one when it is closed without
+	// error and is left unchanged if it is aborted.
+	OpenInPlace(ctx context.Context, size int64) (InPlaceFile, error)
+}
+
+// BlockHasher is an optional interface for Object
+type BlockHasher interface {
+	// BlockHashes returns the checksums of the object split into
+	// blocks of blockSize bytes, the last of which may be short
+	BlockHashes(ctx context.Context, blockSize int64) ([]BlockSum, error)
+}
+
+// BlockSum is the checksums of a block of an object as returned by
+// BlockHasher
+type BlockSum struct {
+	Weak   uint32 // rolling checksum as calculated by lib/rollsum
+	Strong string // hex encoded MD5 hash
+}
+
 // FullObjectInfo contains all the read-only optional interfaces
 //
 // Use for checking making wrapping ObjectInfos implement everything
@@ -281,3 +311,10 @@ type WriterAtCloser interface {
 	io.WriterAt
 	io.Closer
 }
+
+// InPlaceFile is returned by InPlaceWriter.OpenInPlace
+type InPlaceFile interface {
+	WriterAtCloser
+	// Abort discards the writes leaving the object unchanged
+	Abort() error
+}
@@ -140,7 +140,9 @@ func (l ListType) Filter(in *fs.DirEntries) {
 // rely on parents coming before children or alphabetical ordering
 //
 // This is implemented by using ListR on the backend if possible and
-// efficient, otherwise by Walk.
+// efficient, otherwise by Walk. If only a single level is being
+// listed then ListP is used if the backend has it so the directory
+// is read a page at a time.
 //
 // Note: this will flag filter-aware backends
 //
@@ -156,6 +158,11 @@ func ListR(ctx context.Context, f fs.Fs, path string, includeAll bool, maxLevel
 		maxLevel >= 0 || // ...using bounded recursion
 		len(fi.Opt.ExcludeFile) > 0 || // ...using --exclude-file
 		fi.UsesDirectoryFilters() { // ...using any directory filters
+		// Read a single directory a page at a time with ListP if possible
+		if doListP := f.Features().ListP; doListP != nil && maxLevel == 1 && !fi.HaveFilesFrom() && len(fi.Opt.ExcludeFile) == 0 {
+			ctx = filter.SetUseFilter(ctx, !includeAll) // make filter-aware backends constrain List
+			return listR(ctx, f, path, includeAll, listType, fn, doListP, false)
+		}
 		return listRwalk(ctx, f, path, includeAll, maxLevel, listType, fn)
 	}
 	ctx = filter.SetUseFilter(ctx, !includeAll) // make filter-aware backends constrain List
@@ -1045,3 +1045,54 @@ func TestDirMapSendEntries(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, []string(nil), got)
 }
+
+func TestListRListP(t *testing.T) {
+	ctx := context.Background()
+	pages := []fs.DirEntries{
+		{mockobject.Object("a"), mockobject.Object("b")},
+		{mockdir.New("dir"), mockobject.Object("c")},
+	}
+	f := mockfs.NewFs(ctx, "mock", "/")
+	f.Features().ListP = func(ctx context.Context, dir string, callback fs.ListRCallback) error {
+		assert.Equal(t, "", dir)
+		for _, page := range pages {
+			if err := callback(page); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var (
+		got   []string
+		calls int
+	)
+	callback := func(entries fs.DirEntries) error {
+		calls++
+		for _, entry := range entries {
+			got = append(got, entry.Remote())
+		}
+		return nil
+	}
+
+	// A single level is read a page at a time
+	err := ListR(ctx, f, "", true, 1, ListAll, callback)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, []string{"a", "b", "dir", "c"}, got)
+
+	// With a filter
+	fi, err := filter.NewFilter(nil)
+	require.NoError(t, err)
+	require.NoError(t, fi.AddRule("+ b"))
+	require.NoError(t, fi.AddRule("- *"))
+	got, calls = nil, 0
+	err = ListR(filter.ReplaceConfig(ctx, fi), f, "", false, 1, ListObjects, callback)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, got)
+
+	// Not used when recursing
+	got, calls = nil, 0
+	err = ListR(ctx, f, "", true, -1, ListAll, callback)
+	require.NoError(t, err)
+	assert.Equal(t, []string(nil), got)
+}
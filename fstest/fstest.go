@@ -43,6 +43,7 @@ var (
 	Individual      = flag.Bool("individual", false, "Make individual bucket/container/directory for each test - much slower")
 	LowLevelRetries = flag.Int("low-level-retries", 10, "Number of low level retries")
 	UseListR        = flag.Bool("fast-list", false, "Use recursive list if available. Uses more memory but fewer transactions.")
+	Faults          = flag.Bool("faults", false, "Run the fault injection tests")
 	// SizeLimit signals tests to skip maximum test file size and skip inappropriate runs
 	SizeLimit = flag.Int64("size-limit", 0, "Limit maximum test file size")
 	// ListRetries is the number of times to retry a listing to overcome eventual consistency
@@ -420,6 +421,38 @@ func LocalRemote() (path string, err error) {
 	return
 }
 
+// NewFs makes an Fs from fsString for a test, failing the test if it
+// can't be made.
+//
+// If the Fs can be shut down it is shut down when the test finishes.
+func NewFs(t *testing.T, fsString string) fs.Fs {
+	ctx := context.Background()
+	f, err := fs.NewFs(ctx, fsString)
+	require.NoError(t, err)
+	if shutdown := f.Features().Shutdown; shutdown != nil {
+		t.Cleanup(func() {
+			assert.NoError(t, shutdown(ctx))
+		})
+	}
+	return f
+}
+
+// NewWrappingFs makes an on the fly remote of the wrapping backend
+// called name for a test with remote set to a new temporary directory.
+//
+// params are extra comma separated parameters for the connection
+// string, eg `max_size=10B,mode=reject`.
+//
+// It returns the Fs and the temporary directory.
+func NewWrappingFs(t *testing.T, name, params string) (f fs.Fs, dir string) {
+	dir = t.TempDir()
+	fsString := fmt.Sprintf(`:%s,remote="%s"`, name, dir)
+	if params != "" {
+		fsString += "," + params
+	}
+	return NewFs(t, fsString+":"), dir
+}
+
 // RandomRemoteName makes a random bucket or subdirectory name
 //
 // Returns a random remote name plus the leaf name
@@ -0,0 +1,272 @@
+// Fault injection tests
+//
+// These wrap the remote under test in a shim which injects the sort
+// of faults seen on real networks into its transfers and check that
+// rclone recovers from them or detects them.
+
+package fstests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fault is a kind of fault to inject into a download
+type fault int
+
+// Kinds of fault
+const (
+	faultNone     fault = iota
+	faultDrop           // return an error part way through
+	faultTruncate       // return io.EOF part way through
+	faultCorrupt        // flip the bits of a byte part way through
+	faultStall          // pause before returning any data
+)
+
+// faultStallTime is how long faultStall pauses for
+const faultStallTime = 2 * time.Second
+
+var (
+	errFaultDrop = errors.New("fault injection: connection dropped")
+	errFaultPut  = errors.New("fault injection: upload failed")
+)
+
+// faultFs wraps an fs.Fs injecting faults into the objects it opens
+// and the objects put to it.
+//
+// It has a different name to the Fs it wraps and none of its
+// optional features so transfers to and from it can't be done server
+// side.
+type faultFs struct {
+	fs.Fs
+	features *fs.Features
+
+	mu        sync.Mutex
+	readFault fault // fault to inject into the next Open
+	putFaults int   // number of Puts left to fail
+}
+
+// newFaultFs makes a faultFs wrapping f
+func newFaultFs(ctx context.Context, f fs.Fs) *faultFs {
+	ff := &faultFs{Fs: f}
+	ff.features = (&fs.Features{}).Fill(ctx, ff)
+	return ff
+}
+
+// Name of the remote (as passed into NewFs)
+func (ff *faultFs) Name() string {
+	return "faults-" + ff.Fs.Name()
+}
+
+// String returns a description of the FS
+func (ff *faultFs) String() string {
+	return "faults:" + ff.Fs.String()
+}
+
+// Features returns the optional features of this Fs
+func (ff *faultFs) Features() *fs.Features {
+	return ff.features
+}
+
+// setReadFault injects fault into the next object opened
+func (ff *faultFs) setReadFault(fault fault) {
+	ff.mu.Lock()
+	ff.readFault = fault
+	ff.mu.Unlock()
+}
+
+// setPutFaults makes the next n Puts fail
+func (ff *faultFs) setPutFaults(n int) {
+	ff.mu.Lock()
+	ff.putFaults = n
+	ff.mu.Unlock()
+}
+
+// NewObject finds the Object at remote
+func (ff *faultFs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	o, err := ff.Fs.NewObject(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	return &faultObject{Object: o, ff: ff}, nil
+}
+
+// Put in to the remote path with the modTime given of the given size
+//
+// If a fault has been set up this reads half of in then returns a
+// retriable error.
+func (ff *faultFs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	ff.mu.Lock()
+	fail := ff.putFaults > 0
+	if fail {
+		ff.putFaults--
+	}
+	ff.mu.Unlock()
+	if fail {
+		_, _ = io.CopyN(ioutil.Discard, in, src.Size()/2)
+		return nil, fserrors.RetryError(errFaultPut)
+	}
+	o, err := ff.Fs.Put(ctx, in, src, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &faultObject{Object: o, ff: ff}, nil
+}
+
+// faultObject wraps an fs.Object injecting faults when it is opened
+type faultObject struct {
+	fs.Object
+	ff *faultFs
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *faultObject) Fs() fs.Info {
+	return o.ff
+}
+
+// Open an object for read injecting any fault set up
+func (o *faultObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	o.ff.mu.Lock()
+	fault := o.ff.readFault
+	o.ff.readFault = faultNone
+	o.ff.mu.Unlock()
+	in, err := o.Object.Open(ctx, options...)
+	if err != nil || fault == faultNone {
+		return in, err
+	}
+	return &faultReader{ReadCloser: in, fault: fault, at: o.Size() / 2}, nil
+}
+
+// faultReader injects fault into the stream at offset at
+type faultReader struct {
+	io.ReadCloser
+	fault fault
+	at    int64 // offset to inject the fault at
+	pos   int64 // offset of the next byte read
+}
+
+// Read from the stream injecting the fault
+func (r *faultReader) Read(p []byte) (n int, err error) {
+	switch r.fault {
+	case faultNone:
+		return r.ReadCloser.Read(p)
+	case faultStall:
+		time.Sleep(faultStallTime)
+		r.fault = faultNone
+		return r.ReadCloser.Read(p)
+	case faultDrop, faultTruncate:
+		if r.pos >= r.at {
+			fault := r.fault
+			r.fault = faultNone
+			if fault == faultDrop {
+				return 0, errFaultDrop
+			}
+			return 0, io.EOF
+		}
+		if remaining := r.at - r.pos; int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+	n, err = r.ReadCloser.Read(p)
+	if r.fault == faultCorrupt && r.pos <= r.at && r.at < r.pos+int64(n) {
+		p[r.at-r.pos] ^= 0xFF
+		r.fault = faultNone
+	}
+	r.pos += int64(n)
+	return n, err
+}
+
+// testFaults runs the fault injection tests on f
+func testFaults(ctx context.Context, t *testing.T, f fs.Fs) {
+	const (
+		srcPath = "faults-src.txt"
+		dstPath = "faults-dst.txt"
+	)
+	ff := newFaultFs(ctx, f)
+	contents := random.String(16 * 1024)
+	file := fstest.Item{
+		ModTime: fstest.Time("2001-02-03T04:05:06.499999999Z"),
+		Path:    srcPath,
+	}
+	src := PutTestContents(ctx, t, f, &file, contents, true)
+	defer func() {
+		assert.NoError(t, src.Remove(ctx))
+	}()
+
+	// copyFrom copies srcPath to dstPath reading it through ff with fault
+	copyFrom := func(t *testing.T, fault fault) error {
+		src, err := ff.NewObject(ctx, srcPath)
+		require.NoError(t, err)
+		ff.setReadFault(fault)
+		_, err = operations.Copy(ctx, f, nil, dstPath, src)
+		return err
+	}
+
+	// checkDst checks dstPath has the right contents then removes it
+	checkDst := func(t *testing.T) {
+		dst := findObject(ctx, t, f, dstPath)
+		assert.Equal(t, contents, readObject(ctx, t, dst, -1))
+		require.NoError(t, dst.Remove(ctx))
+	}
+
+	// checkDetected checks that a copy which returned err either
+	// detected the fault and didn't leave dstPath behind or
+	// recovered from it
+	checkDetected := func(t *testing.T, err error) {
+		if err == nil {
+			checkDst(t)
+			return
+		}
+		dst, err := f.NewObject(ctx, dstPath)
+		if err == nil {
+			_ = dst.Remove(ctx)
+		}
+		assert.Equal(t, fs.ErrorObjectNotFound, err, "corrupted file left behind")
+	}
+
+	// A dropped connection should be resumed
+	t.Run("Drop", func(t *testing.T) {
+		require.NoError(t, copyFrom(t, faultDrop))
+		checkDst(t)
+	})
+
+	// A stalled connection should carry on
+	t.Run("Stall", func(t *testing.T) {
+		require.NoError(t, copyFrom(t, faultStall))
+		checkDst(t)
+	})
+
+	// A truncated download should be detected or retried
+	t.Run("Truncate", func(t *testing.T) {
+		checkDetected(t, copyFrom(t, faultTruncate))
+	})
+
+	// A corrupted download should be detected or retried
+	t.Run("Corrupt", func(t *testing.T) {
+		if f.Hashes().Count() == 0 || fs.GetConfig(ctx).IgnoreChecksum {
+			t.Skip("no hash to detect corruption with")
+		}
+		checkDetected(t, copyFrom(t, faultCorrupt))
+	})
+
+	// A failed upload should be retried
+	t.Run("PutDrop", func(t *testing.T) {
+		ff.setPutFaults(1)
+		_, err := operations.Copy(ctx, ff, nil, dstPath, src)
+		require.NoError(t, err)
+		checkDst(t)
+	})
+}
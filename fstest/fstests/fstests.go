@@ -328,6 +328,8 @@ type Opt struct {
 	SkipObjectCheckWrap          bool     // if set skip ObjectCheckWrap
 	SkipInvalidUTF8              bool     // if set skip invalid UTF-8 checks
 	QuickTestOK                  bool     // if set, run this test with make quicktest
+	TestFaults                   bool     // if set, run the fault injection tests
+	ReadOnly                     bool     // if set, only run the read only tests - these are run anyway if the remote can't be written to
 }
 
 // returns true if x is found in ss
@@ -538,8 +540,14 @@ func Run(t *testing.T, opt *Opt) {
 		assert.Error(t, err, "Expecting error on Rmdir non-existent")
 	})
 
-	// Make the directory
+	// Make the directory - if this fails check whether the remote
+	// is read only and run the read only tests instead if so
 	err = f.Mkdir(ctx, "")
+	if opt.ReadOnly || (err != nil && isReadOnly(ctx, f)) {
+		t.Logf("Remote %q is read only - running the read only tests", remoteName)
+		testReadOnly(ctx, t, remoteName)
+		return
+	}
 	require.NoError(t, err)
 	fstest.CheckListing(t, f, []fstest.Item{})
 
@@ -773,6 +781,16 @@ func Run(t *testing.T, opt *Opt) {
 			})
 		})
 
+		// TestFsFaults wraps the remote in a shim which injects
+		// faults into its transfers and checks rclone recovers
+		t.Run("FsFaults", func(t *testing.T) {
+			skipIfNotOk(t)
+			if !opt.TestFaults && !*fstest.Faults {
+				t.Skip("fault injection tests not enabled - use -faults")
+			}
+			testFaults(ctx, t, f)
+		})
+
 		t.Run("FsOpenWriterAt", func(t *testing.T) {
 			skipIfNotOk(t)
 			openWriterAt := f.Features().OpenWriterAt
@@ -955,6 +973,40 @@ func Run(t *testing.T, opt *Opt) {
 				TestFsListDirFile2(t)
 			})
 
+			// TestFsListPDirFile2 tests ListP returns the same entries
+			// as List in each directory leading to file2
+			t.Run("FsListPDirFile2", func(t *testing.T) {
+				skipIfNotOk(t)
+				doListP, ok := f.(fs.ListPer)
+				if !ok {
+					t.Skip("FS has no ListP interface")
+				}
+				names := func(entries fs.DirEntries) (out []string) {
+					for _, entry := range entries {
+						out = append(out, entry.Remote())
+					}
+					sort.Strings(out)
+					return out
+				}
+				for dir := path.Dir(file2.Path); ; dir = path.Dir(dir) {
+					if dir == "." {
+						dir = ""
+					}
+					want, err := f.List(ctx, dir)
+					require.NoError(t, err)
+					var got fs.DirEntries
+					err = doListP.ListP(ctx, dir, func(entries fs.DirEntries) error {
+						got = append(got, entries...)
+						return nil
+					})
+					require.NoError(t, err)
+					assert.Equal(t, names(want), names(got), dir)
+					if dir == "" {
+						break
+					}
+				}
+			})
+
 			// Test the files are all there with walk.ListR recursive listings
 			t.Run("FsListR", func(t *testing.T) {
 				skipIfNotOk(t)
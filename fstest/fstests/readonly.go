@@ -0,0 +1,196 @@
+// Read only tests
+//
+// These are run instead of the normal tests on remotes which can't be
+// written to. They check the files already on the remote can be
+// listed, found and read back consistently and that writing fails.
+
+package fstests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fspath"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/fs/walk"
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	readOnlyMaxObjects = 10      // maximum number of objects to read in the read only tests
+	readOnlyMaxSize    = 1 << 20 // maximum size of object to read in the read only tests
+)
+
+// isReadOnly returns true if f can't be written to
+//
+// This is called when making the test directory on f has failed, so
+// it tries to write a file to confirm that f is read only rather
+// than broken.
+func isReadOnly(ctx context.Context, f fs.Fs) bool {
+	remote := "rclone-read-only-probe-" + random.String(8) + ".txt"
+	contents := []byte("read only probe")
+	info := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, f)
+	o, err := f.Put(ctx, bytes.NewReader(contents), info)
+	if err != nil {
+		fs.Debugf(f, "Remote is read only: %v", err)
+		return true
+	}
+	_ = o.Remove(ctx)
+	return false
+}
+
+// testReadOnly runs the read only tests on the root of remoteName
+//
+// The remote must have at least one file on it already.
+func testReadOnly(ctx context.Context, t *testing.T, remoteName string) {
+	f, err := fs.NewFs(ctx, remoteName)
+	if err == fs.ErrorIsFile {
+		err = nil
+	}
+	require.NoError(t, err)
+
+	var (
+		objs []fs.Object
+		dirs []string
+	)
+
+	// TestReadOnlyList checks the remote can be listed and has some
+	// files on it to read
+	t.Run("ReadOnlyList", func(t *testing.T) {
+		err := walk.Walk(ctx, f, "", true, -1, func(dirPath string, entries fs.DirEntries, err error) error {
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				switch x := entry.(type) {
+				case fs.Object:
+					objs = append(objs, x)
+				case fs.Directory:
+					dirs = append(dirs, x.Remote())
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, objs, "read only remote %q needs some files on it to test", remoteName)
+		t.Logf("Found %d files and %d directories", len(objs), len(dirs))
+		if len(objs) > readOnlyMaxObjects {
+			objs = objs[:readOnlyMaxObjects]
+		}
+	})
+	if len(objs) == 0 {
+		return
+	}
+
+	// TestReadOnlyListDir checks the directories found can be listed
+	t.Run("ReadOnlyListDir", func(t *testing.T) {
+		for _, dir := range dirs {
+			_, err := f.List(ctx, dir)
+			assert.NoError(t, err, dir)
+		}
+	})
+
+	// TestReadOnlyListDirNotFound checks listing a missing directory
+	t.Run("ReadOnlyListDirNotFound", func(t *testing.T) {
+		_, err := f.List(ctx, "directory not found "+random.String(8))
+		assert.True(t, errors.Is(err, fs.ErrorDirNotFound), "expecting directory not found, got %v", err)
+	})
+
+	// TestReadOnlyNewObject checks the objects found can be found
+	// again with the same metadata
+	t.Run("ReadOnlyNewObject", func(t *testing.T) {
+		precision := f.Precision()
+		for _, o := range objs {
+			got, err := f.NewObject(ctx, o.Remote())
+			require.NoError(t, err, o.Remote())
+			assert.Equal(t, o.Remote(), got.Remote())
+			assert.Equal(t, o.Size(), got.Size(), o.Remote())
+			if precision != fs.ModTimeNotSupported {
+				dt := got.ModTime(ctx).Sub(o.ModTime(ctx))
+				assert.False(t, dt > precision || dt < -precision, "%s: modification time differs by %v", o.Remote(), dt)
+			}
+		}
+	})
+
+	// TestReadOnlyNewObjectNotFound checks finding a missing object
+	t.Run("ReadOnlyNewObjectNotFound", func(t *testing.T) {
+		_, err := f.NewObject(ctx, "file not found "+random.String(8)+".txt")
+		assert.True(t, errors.Is(err, fs.ErrorObjectNotFound), "expecting object not found, got %v", err)
+	})
+
+	// TestReadOnlyObjectOpen checks the objects can be read and that
+	// their sizes and hashes match their contents
+	t.Run("ReadOnlyObjectOpen", func(t *testing.T) {
+		for _, o := range objs {
+			if o.Size() > readOnlyMaxSize {
+				continue
+			}
+			contents := readObject(ctx, t, o, -1)
+			if o.Size() >= 0 {
+				assert.Equal(t, o.Size(), int64(len(contents)), o.Remote())
+			}
+			sums, err := hash.StreamTypes(strings.NewReader(contents), f.Hashes())
+			require.NoError(t, err)
+			for ht, want := range sums {
+				got, err := o.Hash(ctx, ht)
+				require.NoError(t, err)
+				if got != "" {
+					assert.Equal(t, want, got, fmt.Sprintf("%s: %v hash", o.Remote(), ht))
+				}
+			}
+		}
+	})
+
+	// TestReadOnlyObjectOpenRange checks the objects can be read
+	// part of the way through
+	t.Run("ReadOnlyObjectOpenRange", func(t *testing.T) {
+		for _, o := range objs {
+			size := o.Size()
+			if size < 2 || size > readOnlyMaxSize {
+				continue
+			}
+			contents := readObject(ctx, t, o, -1)
+			half := size / 2
+			assert.Equal(t, contents[half:], readObject(ctx, t, o, -1, &fs.SeekOption{Offset: half}), o.Remote())
+			assert.Equal(t, contents[1:half], readObject(ctx, t, o, -1, &fs.RangeOption{Start: 1, End: half - 1}), o.Remote())
+			assert.Equal(t, contents[:1], readObject(ctx, t, o, 1), o.Remote())
+		}
+	})
+
+	// TestReadOnlyFsIsFile checks making an Fs pointing to a file
+	t.Run("ReadOnlyFsIsFile", func(t *testing.T) {
+		o := objs[0]
+		fileFs, err := fs.NewFs(ctx, fspath.JoinRootPath(remoteName, o.Remote()))
+		assert.Equal(t, fs.ErrorIsFile, err)
+		if fileFs != nil {
+			_, err = fileFs.NewObject(ctx, path.Base(o.Remote()))
+			assert.NoError(t, err)
+		}
+	})
+
+	// TestReadOnlyWrite checks that writing to the remote fails
+	t.Run("ReadOnlyWrite", func(t *testing.T) {
+		contents := []byte("should not be written")
+		remote := "rclone-read-only-test-" + random.String(8) + ".txt"
+		info := object.NewStaticObjectInfo(remote, time.Now(), int64(len(contents)), true, nil, f)
+		_, err := f.Put(ctx, bytes.NewReader(contents), info)
+		assert.Error(t, err, "Put")
+
+		o := objs[0]
+		info = object.NewStaticObjectInfo(o.Remote(), time.Now(), int64(len(contents)), true, nil, f)
+		assert.Error(t, o.Update(ctx, bytes.NewReader(contents), info), "Update")
+		assert.Error(t, o.Remove(ctx), "Remove")
+		_, err = f.NewObject(ctx, o.Remote())
+		assert.NoError(t, err, "object missing after failed Remove")
+	})
+}
@@ -258,6 +258,7 @@ func (ts *TokenSource) Token() (*oauth2.Token, error) {
 		if err != nil {
 			return nil, fmt.Errorf("couldn't store token: %w", err)
 		}
+		fs.GetCounters(ts.name).TokenRefresh(token.Expiry)
 	}
 	return token, nil
 }
@@ -362,6 +363,8 @@ func NewClientWithBaseClient(ctx context.Context, name string, m configmap.Mappe
 		return nil, nil, err
 	}
 
+	fs.GetCounters(name).SetTokenExpiry(token.Expiry)
+
 	// Set our own http client in the context
 	ctx = Context(ctx, baseClient)
 
@@ -479,6 +482,9 @@ func ConfigOAuth(ctx context.Context, name string, m configmap.Mapper, ri *fs.Re
 		if in.Result == "false" {
 			return fs.ConfigGoto(newState("*oauth-done"))
 		}
+		if fs.IsConfigOAuthProxy(ctx) {
+			return fs.ConfigGoto(newState("*oauth-proxy"))
+		}
 		return fs.ConfigConfirm(newState("*oauth-islocal"), true, "config_is_local", "Use auto config?\n * Say Y if not sure\n * Say N if you are working on a remote or headless machine\n")
 	case "*oauth-islocal":
 		if in.Result == "true" {
@@ -550,6 +556,52 @@ version recommended):
 			m.Set(fs.ConfigToken, code)
 		}
 		return fs.ConfigGoto(newState("*oauth-done"))
+	case "*oauth-proxy":
+		opt, err := getOAuth()
+		if err != nil {
+			return nil, err
+		}
+		oauthConfig, _ := overrideCredentials(name, m, opt.OAuth2Config)
+		authURL, authState, err := getAuthURL(name, m, fixRedirect(oauthConfig), opt)
+		if err != nil {
+			return nil, err
+		}
+		out, err := fs.ConfigInput(fs.StatePush(stateParams, "*oauth-proxy-code", authState), "config_oauth_code", fmt.Sprintf(`Go to this URL, log in and authorize rclone for access.
+
+%s
+
+Your browser will then be sent to a page at %s which won't load.
+Paste the address of that page here.
+`, authURL, RedirectURL))
+		if err != nil {
+			return nil, err
+		}
+		out.AuthURL = authURL
+		return out, nil
+	case "*oauth-proxy-code":
+		var authState string
+		stateParams, authState = fs.StatePop(stateParams)
+		opt, err := getOAuth()
+		if err != nil {
+			return nil, err
+		}
+		code, err := proxyCode(opt, authState, in.Result)
+		if err != nil {
+			return fs.ConfigError(newState("*oauth-proxy"), fmt.Sprintf("Authorization failed - try again: %v\n", err))
+		}
+		oauthConfig, _ := overrideCredentials(name, m, opt.OAuth2Config)
+		oauthConfig = fixRedirect(oauthConfig)
+		if opt.CheckAuth != nil {
+			err = opt.CheckAuth(oauthConfig, &AuthResult{OK: true, Code: code})
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = configExchange(ctx, name, m, oauthConfig, code)
+		if err != nil {
+			return nil, err
+		}
+		return fs.ConfigGoto(newState("*oauth-done"))
 	case "*oauth-do":
 		code := in.Result
 		opt, err := getOAuth()
@@ -585,6 +637,38 @@ func init() {
 	fs.ConfigOAuth = ConfigOAuth
 }
 
+// proxyCode reads the code from the address of the page the user was
+// redirected to after authorizing rclone, checking its state is
+// authState.
+//
+// A bare code is accepted too.
+func proxyCode(opt *Options, authState, redirect string) (code string, err error) {
+	redirect = strings.TrimSpace(redirect)
+	if redirect == "" {
+		return "", errors.New("no code supplied")
+	}
+	if !strings.Contains(redirect, "?") {
+		return redirect, nil
+	}
+	u, err := url.Parse(redirect)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	if errorCode := query.Get("error"); errorCode != "" {
+		return "", fmt.Errorf("%s: %s", errorCode, query.Get("error_description"))
+	}
+	state := query.Get("state")
+	if state != authState && !(state == "" && opt.StateBlankOK) {
+		return "", fmt.Errorf("auth state doesn't match: expecting %q got %q", authState, state)
+	}
+	code = query.Get("code")
+	if code == "" {
+		return "", errors.New("no code found")
+	}
+	return code, nil
+}
+
 // Return true if can run without a webserver and just entering a code
 func noWebserverNeeded(oauthConfig *oauth2.Config) bool {
 	return oauthConfig.RedirectURL == TitleBarRedirectURL
@@ -0,0 +1,108 @@
+package oauthutil
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestProxyCode(t *testing.T) {
+	opt := &Options{}
+	for _, test := range []struct {
+		redirect string
+		want     string
+		wantErr  bool
+	}{
+		{redirect: "", wantErr: true},
+		{redirect: " potato \n", want: "potato"},
+		{redirect: "http://127.0.0.1:53682/?state=STATE&code=potato", want: "potato"},
+		{redirect: "http://127.0.0.1:53682/?state=WRONG&code=potato", wantErr: true},
+		{redirect: "http://127.0.0.1:53682/?code=potato", wantErr: true},
+		{redirect: "http://127.0.0.1:53682/?state=STATE", wantErr: true},
+		{redirect: "http://127.0.0.1:53682/?state=STATE&error=access_denied", wantErr: true},
+	} {
+		got, err := proxyCode(opt, "STATE", test.redirect)
+		if test.wantErr {
+			assert.Error(t, err, test.redirect)
+		} else {
+			require.NoError(t, err, test.redirect)
+			assert.Equal(t, test.want, got, test.redirect)
+		}
+	}
+
+	opt.StateBlankOK = true
+	got, err := proxyCode(opt, "STATE", "http://127.0.0.1:53682/?code=potato")
+	require.NoError(t, err)
+	assert.Equal(t, "potato", got)
+}
+
+func TestConfigOAuthProxy(t *testing.T) {
+	ctx := fs.ConfigOAuthProxy(context.Background())
+
+	// Token server which accepts the code "potato"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.Form.Get("code") != "potato" {
+			http.Error(w, "bad code", http.StatusBadRequest)
+			return
+		}
+		assert.Equal(t, RedirectURL, r.Form.Get("redirect_uri"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"access_token":"ACCESS","token_type":"Bearer","refresh_token":"REFRESH"}`)
+	}))
+	defer ts.Close()
+
+	ri := &fs.RegInfo{
+		Name: "oauthtest",
+		Config: func(ctx context.Context, name string, m configmap.Mapper, in fs.ConfigIn) (*fs.ConfigOut, error) {
+			return ConfigOut("", &Options{
+				OAuth2Config: &oauth2.Config{
+					ClientID:    "id",
+					Endpoint:    oauth2.Endpoint{AuthURL: "https://example.com/auth", TokenURL: ts.URL},
+					RedirectURL: TitleBarRedirectURL,
+				},
+			})
+		},
+	}
+	m := configmap.Simple{}
+
+	// First we get the URL to visit
+	out, err := fs.BackendConfig(ctx, "remote", m, ri, configmap.Simple{}, fs.ConfigIn{})
+	require.NoError(t, err)
+	require.NotNil(t, out.Option)
+	assert.Equal(t, "config_oauth_code", out.Option.Name)
+	authURL, err := url.Parse(out.AuthURL)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", authURL.Host)
+	assert.Equal(t, RedirectURL, authURL.Query().Get("redirect_uri"))
+	state := authURL.Query().Get("state")
+	require.NotEqual(t, "", state)
+
+	// A bad redirect starts again
+	out2, err := fs.BackendConfig(ctx, "remote", m, ri, configmap.Simple{}, fs.ConfigIn{
+		State:  out.State,
+		Result: RedirectURL + "?state=wrong&code=potato",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, out2.Error, "auth state doesn't match")
+	assert.Equal(t, "", m["token"])
+
+	// Then return the address we were redirected to
+	redirect := RedirectURL + "?" + url.Values{"state": {state}, "code": {"potato"}}.Encode()
+	out, err = fs.BackendConfig(ctx, "remote", m, ri, configmap.Simple{}, fs.ConfigIn{
+		State:  out.State,
+		Result: redirect,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "", out.State)
+	assert.Contains(t, m["token"], `"access_token":"ACCESS"`)
+}
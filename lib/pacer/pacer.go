@@ -2,6 +2,9 @@
 package pacer
 
 import (
+	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -250,6 +253,31 @@ func RetryAfterError(err error, retryAfter time.Duration) error {
 	}
 }
 
+// ParseRetryAfter parses the value of a Retry-After header which is
+// either a number of seconds or an HTTP date, returning the time to
+// wait and whether it could be parsed.
+func ParseRetryAfter(value string) (retryAfter time.Duration, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	when, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	retryAfter = time.Until(when)
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+	return retryAfter, true
+}
+
 // IsRetryAfter returns true if the error or any of it's Cause's is an error
 // returned by RetryAfterError. It also returns the associated Duration if possible.
 func IsRetryAfter(err error) (retryAfter time.Duration, isRetryAfter bool) {
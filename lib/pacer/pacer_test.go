@@ -2,6 +2,7 @@ package pacer
 
 import (
 	"errors"
+	"net/http"
 	"sync"
 	"testing"
 	"time"
@@ -283,6 +284,50 @@ func TestS3Pacer(t *testing.T) {
 	}
 }
 
+func TestAIMDPacer(t *testing.T) {
+	c := NewAIMD(MinSleep(10*time.Millisecond), MaxSleep(2*time.Second), AdditiveIncrease(1), MultiplicativeDecrease(0.5))
+	for _, test := range []struct {
+		state State
+		want  time.Duration
+	}{
+		{State{SleepTime: 0}, 10 * time.Millisecond},                                                                                               //Never go below minSleep
+		{State{SleepTime: 0, ConsecutiveRetries: 1}, 20 * time.Millisecond},                                                                        //Throttled at minSleep - halve the rate
+		{State{SleepTime: 100 * time.Millisecond, ConsecutiveRetries: 1}, 200 * time.Millisecond},                                                  //Throttled - halve the rate
+		{State{SleepTime: 1500 * time.Millisecond, ConsecutiveRetries: 2}, 2 * time.Second},                                                        //Check maxSleep is enforced
+		{State{SleepTime: 1 * time.Second}, 500 * time.Millisecond},                                                                                //Success - rate goes from 1 to 2 calls/s
+		{State{SleepTime: 500 * time.Millisecond}, time.Second / 3},                                                                                //Success - rate goes from 2 to 3 calls/s
+		{State{SleepTime: 10100 * time.Microsecond}, 10 * time.Millisecond},                                                                        //Success near minSleep
+		{State{SleepTime: 10 * time.Second}, time.Second * 10 / 11},                                                                                //Success after a long Retry-After
+		{State{SleepTime: 10 * time.Millisecond, ConsecutiveRetries: 1, LastError: RetryAfterError(nil, 5*time.Second)}, 5 * time.Second},          //Obey Retry-After
+		{State{SleepTime: 10 * time.Millisecond, ConsecutiveRetries: 1, LastError: RetryAfterError(nil, time.Millisecond)}, 10 * time.Millisecond}, //Retry-After below minSleep
+	} {
+		got := c.Calculate(test.state)
+		assert.Equal(t, test.want, got, "test: %+v", test)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for _, test := range []struct {
+		in     string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"potato", 0, false},
+		{"-1", 0, false},
+		{"0", 0, true},
+		{" 120 ", 120 * time.Second, true},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, true},
+	} {
+		got, gotOK := ParseRetryAfter(test.in)
+		assert.Equal(t, test.wantOK, gotOK, "test: %+v", test)
+		assert.Equal(t, test.want, got, "test: %+v", test)
+	}
+	got, ok := ParseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.True(t, got > 58*time.Second && got <= time.Minute, "got %v", got)
+}
+
 func TestEndCall(t *testing.T) {
 	p := New(MaxConnectionsOption(5))
 	emptyTokens(p)
@@ -18,6 +18,12 @@ type (
 	AttackConstant uint
 	// Burst configures the number of API calls to allow without sleeping
 	Burst int
+	// AdditiveIncrease configures the calls per second added to the
+	// rate of an AIMD Calculator after each successful call
+	AdditiveIncrease float64
+	// MultiplicativeDecrease configures the factor the rate of an
+	// AIMD Calculator is multiplied by when it is throttled
+	MultiplicativeDecrease float64
 )
 
 // Default is a truncated exponential attack and decay.
@@ -362,3 +368,102 @@ func (c *S3) Calculate(state State) time.Duration {
 	}
 	return sleepTime
 }
+
+// AIMD is an additive increase, multiplicative decrease Calculator.
+//
+// It treats the sleep time as a rate of calls. After each successful
+// call the rate is increased by a fixed number of calls per second and
+// on each retry the rate is multiplied by a factor less than 1 so the
+// pacer slows down quickly when the remote starts throttling and
+// speeds up again gradually. If the remote returns a Retry-After then
+// the pacer sleeps for that long and increases the rate from there.
+//
+// This lets the pacer find the rate a remote can sustain by itself
+// rather than relying on a fixed minimum sleep.
+//
+// The sleep never goes below that set with MinSleep or above that set
+// with MaxSleep.
+type AIMD struct {
+	minSleep time.Duration // minimum sleep time
+	maxSleep time.Duration // maximum sleep time
+	increase float64       // calls per second to add to the rate on success
+	decrease float64       // factor to multiply the rate by on retry
+}
+
+// AIMDOption is the interface implemented by all options for the AIMD Calculator
+type AIMDOption interface {
+	ApplyAIMD(*AIMD)
+}
+
+// NewAIMD returns a new AIMD Calculator with default values
+func NewAIMD(opts ...AIMDOption) *AIMD {
+	c := &AIMD{
+		minSleep: 10 * time.Millisecond,
+		maxSleep: 2 * time.Second,
+		increase: 1,
+		decrease: 0.5,
+	}
+	c.Update(opts...)
+	return c
+}
+
+// Update applies the Calculator options.
+func (c *AIMD) Update(opts ...AIMDOption) {
+	for _, opt := range opts {
+		opt.ApplyAIMD(c)
+	}
+}
+
+// ApplyAIMD updates the value on the Calculator
+func (o MinSleep) ApplyAIMD(c *AIMD) {
+	c.minSleep = time.Duration(o)
+}
+
+// ApplyAIMD updates the value on the Calculator
+func (o MaxSleep) ApplyAIMD(c *AIMD) {
+	c.maxSleep = time.Duration(o)
+}
+
+// ApplyAIMD updates the value on the Calculator
+func (o AdditiveIncrease) ApplyAIMD(c *AIMD) {
+	c.increase = float64(o)
+}
+
+// ApplyAIMD updates the value on the Calculator
+func (o MultiplicativeDecrease) ApplyAIMD(c *AIMD) {
+	c.decrease = float64(o)
+}
+
+// Calculate takes the current Pacer state and return the wait time until the next try.
+func (c *AIMD) Calculate(state State) time.Duration {
+	if t, ok := IsRetryAfter(state.LastError); ok {
+		if t < c.minSleep {
+			return c.minSleep
+		}
+		return t
+	}
+
+	sleepTime := state.SleepTime
+	if sleepTime < c.minSleep {
+		sleepTime = c.minSleep
+	}
+	if state.ConsecutiveRetries > 0 {
+		if c.decrease > 0 && c.decrease < 1 {
+			sleepTime = time.Duration(float64(sleepTime) / c.decrease)
+		} else {
+			sleepTime = c.maxSleep
+		}
+		if sleepTime > c.maxSleep {
+			sleepTime = c.maxSleep
+		}
+		return sleepTime
+	}
+	// Sleeps longer than maxSleep come from a Retry-After so the
+	// rate is increased from there
+	rate := float64(time.Second)/float64(sleepTime) + c.increase
+	sleepTime = time.Duration(float64(time.Second) / rate)
+	if sleepTime < c.minSleep {
+		sleepTime = c.minSleep
+	}
+	return sleepTime
+}
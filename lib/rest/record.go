@@ -0,0 +1,228 @@
+// Recording of HTTP exchanges for bug reports
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// maxRecordBody is the maximum number of bytes of a body recorded
+const maxRecordBody = 4096
+
+// redacted replaces sensitive values in recordings
+const redacted = "REDACTED"
+
+// sensitiveName matches the names of headers, URL parameters and
+// JSON keys which might contain credentials
+var sensitiveName = regexp.MustCompile(`(?i)auth|token|secret|passw|cookie|session|signature|credential|api-?key|^key$`)
+
+// sensitiveJSON matches JSON string values with a sensitive key
+var sensitiveJSON = regexp.MustCompile(`(?i)("[^"]*(?:auth|token|secret|passw|cookie|session|signature|credential|api_?key)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// recordEntry is one line of a recording
+type recordEntry struct {
+	Time     time.Time       `json:"time"`
+	Duration float64         `json:"duration"` // seconds
+	Request  recordRequest   `json:"request"`
+	Response *recordResponse `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// recordRequest is the request part of a recordEntry
+type recordRequest struct {
+	Method    string      `json:"method"`
+	URL       string      `json:"url"`
+	Header    http.Header `json:"header"`
+	Body      string      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// recordResponse is the response part of a recordEntry
+type recordResponse struct {
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header"`
+	Body      string      `json:"body,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// recorder appends recordEntry to a file
+type recorder struct {
+	mu  sync.Mutex
+	out *os.File
+}
+
+var (
+	recordersMu sync.Mutex
+	recorders   = map[string]*recorder{}
+)
+
+// getRecorder returns the recorder for host in dir, opening its file
+// if necessary
+func getRecorder(dir, host string) (*recorder, error) {
+	name := filepath.Join(dir, strings.NewReplacer(":", "_", "/", "_").Replace(host)+".jsonl")
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+	if r := recorders[name]; r != nil {
+		return r, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	r := &recorder{out: out}
+	recorders[name] = r
+	return r, nil
+}
+
+// write e to the recording
+func (r *recorder) write(e *recordEntry) error {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	buf = append(buf, '\n')
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.out.Write(buf)
+	return err
+}
+
+// redactHeader returns a copy of h with the sensitive values redacted
+func redactHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		if sensitiveName.MatchString(k) {
+			out[k] = []string{redacted}
+		} else {
+			out[k] = append([]string(nil), vs...)
+		}
+	}
+	return out
+}
+
+// redactURL returns u with the sensitive parameters and any user
+// info redacted
+func redactURL(u *url.URL) string {
+	c := *u
+	if c.User != nil {
+		c.User = url.User(redacted)
+	}
+	if c.RawQuery != "" {
+		q := c.Query()
+		for k := range q {
+			if sensitiveName.MatchString(k) {
+				q.Set(k, redacted)
+			}
+		}
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
+
+// redactBody returns up to maxRecordBody bytes of body with sensitive
+// JSON values redacted
+func redactBody(body []byte) (string, bool) {
+	truncated := len(body) > maxRecordBody
+	if truncated {
+		body = body[:maxRecordBody]
+	}
+	return sensitiveJSON.ReplaceAllString(string(body), `$1"`+redacted+`"`), truncated
+}
+
+// peekBody reads up to maxRecordBody+1 bytes from *pBody replacing
+// it with a reader which returns the whole body
+func peekBody(pBody *io.ReadCloser) []byte {
+	body := *pBody
+	if body == nil || body == http.NoBody {
+		return nil
+	}
+	buf, err := ioutil.ReadAll(io.LimitReader(body, maxRecordBody+1))
+	*pBody = struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(buf), &errReader{err: err, in: body}),
+		Closer: body,
+	}
+	return buf
+}
+
+// errReader returns err if set or reads from in
+type errReader struct {
+	err error
+	in  io.Reader
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.in.Read(p)
+}
+
+// requestBody returns a copy of the start of the body of req if it
+// can be read without consuming it
+func requestBody(req *http.Request) []byte {
+	if req.GetBody == nil || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	buf, _ := ioutil.ReadAll(io.LimitReader(body, maxRecordBody+1))
+	_ = body.Close()
+	return buf
+}
+
+// record writes the exchange of req and resp to the recording
+// directory dir.
+//
+// It reads the start of resp.Body replacing it so the caller can
+// still read all of it.
+func record(dir string, req *http.Request, resp *http.Response, start time.Time, callErr error) {
+	r, err := getRecorder(dir, req.URL.Host)
+	if err != nil {
+		fs.Errorf(nil, "Failed to open HTTP recording: %v", err)
+		return
+	}
+	e := &recordEntry{
+		Time:     start,
+		Duration: time.Since(start).Seconds(),
+		Request: recordRequest{
+			Method: req.Method,
+			URL:    redactURL(req.URL),
+			Header: redactHeader(req.Header),
+		},
+	}
+	e.Request.Body, e.Request.Truncated = redactBody(requestBody(req))
+	if callErr != nil {
+		e.Error = callErr.Error()
+	}
+	if resp != nil {
+		e.Response = &recordResponse{
+			Status: resp.StatusCode,
+			Header: redactHeader(resp.Header),
+		}
+		e.Response.Body, e.Response.Truncated = redactBody(peekBody(&resp.Body))
+	}
+	if err := r.write(e); err != nil {
+		fs.Errorf(nil, "Failed to write HTTP recording: %v", err)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/lib/readers"
@@ -27,6 +28,7 @@ type Client struct {
 	c            *http.Client
 	rootURL      string
 	errorHandler func(resp *http.Response) error
+	newAPIError  func(resp *http.Response) APIError
 	headers      map[string]string
 	signer       SignerFn
 }
@@ -66,6 +68,48 @@ func (api *Client) SetErrorHandler(fn func(resp *http.Response) error) *Client {
 	return api
 }
 
+// APIError is an error returned in the JSON body of a response
+//
+// It is implemented by the error types of backends which use
+// SetJSONErrorDecoder.
+type APIError interface {
+	error
+	// IsError returns true if the decoded body contains an error
+	IsError() bool
+}
+
+// SetJSONErrorDecoder makes the Client decode error responses from
+// the API into the APIError returned by newError.
+//
+// The JSON body of a response where the HTTP status code is not 2xx
+// is decoded into a new APIError which is returned as the error if
+// it decodes successfully and IsError() is true, otherwise an error
+// with the body in is returned.
+//
+// When CallJSON is decoding a 2xx response it also decodes it into a
+// new APIError and returns that if IsError() is true. This is for APIs which return
+// errors with an HTTP status of 200 and an error code in the body.
+//
+// newError is passed the response so it can use the status code.
+// This replaces any handler set with SetErrorHandler.
+func (api *Client) SetJSONErrorDecoder(newError func(resp *http.Response) APIError) *Client {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.newAPIError = newError
+	api.errorHandler = func(resp *http.Response) error {
+		body, err := ReadBody(resp)
+		if err != nil {
+			return fmt.Errorf("error reading error out of body: %w", err)
+		}
+		apiErr := newError(resp)
+		if err := json.Unmarshal(body, apiErr); err == nil && apiErr.IsError() {
+			return apiErr
+		}
+		return fmt.Errorf("HTTP error %v (%v) returned body: %q", resp.StatusCode, resp.Status, body)
+	}
+	return api
+}
+
 // SetRoot sets the default RootURL.  You can override this on a per
 // call basis using the RootURL field in Opts.
 func (api *Client) SetRoot(RootURL string) *Client {
@@ -285,7 +329,11 @@ func (api *Client) Call(ctx context.Context, opts *Opts) (resp *http.Response, e
 		}
 	}
 	api.mu.RUnlock()
+	start := time.Now()
 	resp, err = c.Do(req)
+	if dir := fs.GetConfig(ctx).RecordHTTP; dir != "" {
+		record(dir, req, resp, start, err)
+	}
 	api.mu.RLock()
 	if err != nil {
 		return nil, err
@@ -490,6 +538,25 @@ func (api *Client) callCodec(ctx context.Context, opts *Opts, request interface{
 	if response == nil || opts.NoResponse {
 		return resp, nil
 	}
+	api.mu.RLock()
+	newError := api.newAPIError
+	api.mu.RUnlock()
+	if newError != nil && contentType == "application/json" {
+		return resp, decodeJSONCheckError(resp, response, newError(resp))
+	}
 	err = decode(resp, response)
 	return resp, err
 }
+
+// decodeJSONCheckError decodes resp.Body into result and apiErr,
+// closing the body, returning apiErr if it is an error.
+func decodeJSONCheckError(resp *http.Response, result interface{}, apiErr APIError) error {
+	body, err := ReadBody(resp)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, apiErr); err == nil && apiErr.IsError() {
+		return apiErr
+	}
+	return json.Unmarshal(body, result)
+}
@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAPIError is an Alist style error in the body of a response
+type testAPIError struct {
+	Status  int    `json:"-"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *testAPIError) Error() string {
+	return fmt.Sprintf("%s (code %d, status %d)", e.Message, e.Code, e.Status)
+}
+
+func (e *testAPIError) IsError() bool {
+	return e.Code != 200
+}
+
+func TestJSONErrorDecoder(t *testing.T) {
+	ctx := context.Background()
+	handler := http.NewServeMux()
+	handler.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"code":200,"message":"success","data":"potato"}`)
+	})
+	handler.HandleFunc("/error-200", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"code":500,"message":"object not found"}`)
+	})
+	handler.HandleFunc("/error-403", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprint(w, `{"code":403,"message":"permission denied"}`)
+	})
+	handler.HandleFunc("/error-html", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = fmt.Fprint(w, `<html>bad gateway</html>`)
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	api := NewClient(http.DefaultClient).SetRoot(server.URL)
+	api.SetJSONErrorDecoder(func(resp *http.Response) APIError {
+		return &testAPIError{Status: resp.StatusCode}
+	})
+
+	var result struct {
+		Data string `json:"data"`
+	}
+	opts := Opts{Method: "GET", Path: "/ok"}
+	_, err := api.CallJSON(ctx, &opts, nil, &result)
+	require.NoError(t, err)
+	assert.Equal(t, "potato", result.Data)
+
+	opts.Path = "/error-200"
+	_, err = api.CallJSON(ctx, &opts, nil, &result)
+	require.Error(t, err)
+	apiErr, ok := err.(*testAPIError)
+	require.True(t, ok, "wrong error type %T", err)
+	assert.Equal(t, 500, apiErr.Code)
+	assert.Equal(t, http.StatusOK, apiErr.Status)
+	assert.Equal(t, "object not found", apiErr.Message)
+
+	opts.Path = "/error-403"
+	_, err = api.CallJSON(ctx, &opts, nil, &result)
+	require.Error(t, err)
+	apiErr, ok = err.(*testAPIError)
+	require.True(t, ok, "wrong error type %T", err)
+	assert.Equal(t, 403, apiErr.Code)
+	assert.Equal(t, http.StatusForbidden, apiErr.Status)
+
+	opts.Path = "/error-html"
+	_, err = api.Call(ctx, &opts)
+	require.Error(t, err)
+	_, ok = err.(*testAPIError)
+	assert.False(t, ok)
+	assert.Contains(t, err.Error(), "bad gateway")
+}
@@ -0,0 +1,43 @@
+// Package rollsum provides the rolling checksum used by rsync to find
+// blocks of data at any offset in a stream.
+package rollsum
+
+// Rollsum is a rolling checksum of a window of bytes
+type Rollsum struct {
+	a, b uint32
+	n    uint32 // size of the window
+}
+
+// New returns a Rollsum of the window block
+func New(block []byte) *Rollsum {
+	r := &Rollsum{}
+	r.Reset(block)
+	return r
+}
+
+// Reset sets the window to block
+func (r *Rollsum) Reset(block []byte) {
+	r.a, r.b = 0, 0
+	r.n = uint32(len(block))
+	for i, c := range block {
+		r.a += uint32(c)
+		r.b += uint32(len(block)-i) * uint32(c)
+	}
+}
+
+// Roll moves the window on by one byte, removing out from the start
+// of it and adding in to the end
+func (r *Rollsum) Roll(out, in byte) {
+	r.a += uint32(in) - uint32(out)
+	r.b += r.a - r.n*uint32(out)
+}
+
+// Sum returns the checksum of the window
+func (r *Rollsum) Sum() uint32 {
+	return r.a&0xffff | r.b<<16
+}
+
+// Checksum returns the checksum of block
+func Checksum(block []byte) uint32 {
+	return New(block).Sum()
+}
@@ -0,0 +1,32 @@
+package rollsum
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/lib/random"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+	assert.Equal(t, uint32(0), Checksum(nil))
+	// a = 1+2+3 = 6, b = 3*1+2*2+1*3 = 10
+	assert.Equal(t, uint32(10<<16|6), Checksum([]byte{1, 2, 3}))
+	assert.NotEqual(t, Checksum([]byte("abc")), Checksum([]byte("cba")))
+}
+
+func TestRoll(t *testing.T) {
+	const window = 64
+	data := []byte(random.String(1024))
+	data = append(data, 0xff, 0xff, 0xff, 0x00) // check the high bytes
+	r := New(data[:window])
+	for i := 1; i+window <= len(data); i++ {
+		r.Roll(data[i-1], data[i+window-1])
+		assert.Equal(t, Checksum(data[i:i+window]), r.Sum(), "offset %d", i)
+	}
+}
+
+func TestReset(t *testing.T) {
+	r := New([]byte("hello"))
+	r.Reset([]byte("potato"))
+	assert.Equal(t, Checksum([]byte("potato")), r.Sum())
+}
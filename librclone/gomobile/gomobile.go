@@ -4,8 +4,9 @@ package gomobile
 import (
 	"github.com/rclone/rclone/librclone/librclone"
 
-	_ "github.com/rclone/rclone/backend/all" // import all backends
-	_ "github.com/rclone/rclone/lib/plugin"  // import plugins
+	_ "github.com/rclone/rclone/backend/all"       // import all backends
+	_ "github.com/rclone/rclone/fs/config/secrets" // import secrets providers
+	_ "github.com/rclone/rclone/lib/plugin"        // import plugins
 
 	_ "golang.org/x/mobile/event/key" // make go.mod add this as a dependency
 )
@@ -6,8 +6,9 @@ package main
 import (
 	_ "github.com/rclone/rclone/backend/all" // import all backends
 	"github.com/rclone/rclone/cmd"
-	_ "github.com/rclone/rclone/cmd/all"    // import all commands
-	_ "github.com/rclone/rclone/lib/plugin" // import plugins
+	_ "github.com/rclone/rclone/cmd/all"           // import all commands
+	_ "github.com/rclone/rclone/fs/config/secrets" // import secrets providers
+	_ "github.com/rclone/rclone/lib/plugin"        // import plugins
 )
 
 func main() {
@@ -190,6 +190,7 @@ func (d *Dir) ForgetAll() (hasVirtual bool) {
 	d._purgeVirtual()
 
 	d.read = time.Time{}
+	d.vfs.persist.forget(d.path)
 	// Check if this dir has virtual entries
 	if len(d.virtual) != 0 {
 		hasVirtual = true
@@ -224,6 +225,7 @@ func (d *Dir) invalidateDir(absPath string) {
 			fs.Debugf(dir.path, "invalidating directory cache")
 			dir.read = time.Time{}
 		}
+		d.vfs.persist.forget(dir.path)
 		dir.mu.Unlock()
 	}
 }
@@ -382,6 +384,7 @@ func (d *Dir) addObject(node Node) {
 	}
 	d.virtual[leaf] = vAdd
 	fs.Debugf(d.path, "Added virtual directory entry %v: %q", vAdd, leaf)
+	d.vfs.persist.forget(d.path)
 	d.mu.Unlock()
 }
 
@@ -426,6 +429,7 @@ func (d *Dir) delObject(leaf string) {
 	}
 	d.virtual[leaf] = vDel
 	fs.Debugf(d.path, "Added virtual directory entry %v: %q", vDel, leaf)
+	d.vfs.persist.forget(d.path)
 	d.mu.Unlock()
 }
 
@@ -450,6 +454,15 @@ func (d *Dir) _readDir() error {
 	} else {
 		return nil
 	}
+	if entries, read, found := d.vfs.persist.get(d.path, when, d.vfs.Opt.DirCacheTime); found {
+		fs.Debugf(d.path, "Read directory from persistent cache (%v old)", when.Sub(read))
+		err := d._readDirFromEntries(entries, nil, time.Time{})
+		if err != nil {
+			return err
+		}
+		d.read = read
+		return nil
+	}
 	entries, err := list.DirSorted(context.TODO(), d.f, false, d.path)
 	if err == fs.ErrorDirNotFound {
 		// We treat directory not found as empty because we
@@ -464,13 +477,18 @@ func (d *Dir) _readDir() error {
 	}
 
 	d.read = when
+	d.vfs.persist.put(d.path, entries, when)
 	return nil
 }
 
 // update d.items for each dir in the DirTree below this one and
 // set the last read time - must be called with the lock held
 func (d *Dir) _readDirFromDirTree(dirTree dirtree.DirTree, when time.Time) error {
-	return d._readDirFromEntries(dirTree[d.path], dirTree, when)
+	err := d._readDirFromEntries(dirTree[d.path], dirTree, when)
+	if err == nil {
+		d.vfs.persist.put(d.path, dirTree[d.path], when)
+	}
+	return err
 }
 
 // Remove the virtual directory entry leaf
@@ -527,7 +545,7 @@ func (d *Dir) _purgeVirtual() {
 				// if writing in progress then leave virtual
 				continue
 			}
-			if d.vfs.Opt.CacheMode >= vfscommon.CacheModeMinimal && d.vfs.cache.InUse(f.Path()) {
+			if d.vfs.cacheMode(f.Path()) >= vfscommon.CacheModeMinimal && d.vfs.cache.InUse(f.Path()) {
 				// if object in use or dirty then leave virtual
 				continue
 			}
@@ -302,6 +302,31 @@ func TestFileOpen(t *testing.T) {
 	assert.Equal(t, EPERM, err)
 }
 
+func TestFileOpenCacheModeDirs(t *testing.T) {
+	opt := vfscommon.DefaultOpt
+	opt.CacheModeDirs = "incoming=writes"
+	r, vfs, cleanup := newTestVFSOpt(t, &opt)
+	defer cleanup()
+
+	ctx := context.Background()
+	r.WriteObject(ctx, "incoming/file1", "file1 contents", t1)
+	r.WriteObject(ctx, "media/file2", "file2 contents", t2)
+
+	// Opened for write in a directory with cache mode writes
+	fd, err := vfs.OpenFile("incoming/file1", os.O_WRONLY|os.O_TRUNC, 0777)
+	require.NoError(t, err)
+	_, ok := fd.(*RWFileHandle)
+	assert.True(t, ok)
+	require.NoError(t, fd.Close())
+
+	// Opened for write elsewhere uses the default cache mode off
+	fd, err = vfs.OpenFile("media/file2", os.O_WRONLY|os.O_TRUNC, 0777)
+	require.NoError(t, err)
+	_, ok = fd.(*WriteFileHandle)
+	assert.True(t, ok)
+	require.NoError(t, fd.Close())
+}
+
 func testFileRename(t *testing.T, mode vfscommon.CacheMode, inCache bool, forceCache bool) {
 	r, vfs, file, item, cleanup := fileCreate(t, mode)
 	defer cleanup()
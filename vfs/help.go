@@ -54,6 +54,16 @@ Or individual files or directories:
 
     rclone rc vfs/forget file=path/to/file dir=path/to/dir
 
+If you use the !--vfs-persist-dir-cache! flag then rclone will keep
+the directory listings on disk as well, so when rclone is restarted it
+starts with a warm directory cache rather than listing every directory
+again. This is useful for remotes with a lot of files which are slow
+to list. Listings stored on disk are only used while they are younger
+than !--dir-cache-time!, after which the directory is read from the
+backend again.
+
+    --vfs-persist-dir-cache   Keep the directory cache on disk so it is still warm after a restart
+
 ### VFS File Buffering
 
 The !--buffer-size! flag determines the amount of memory,
@@ -85,10 +95,13 @@ find that you need one or the other or both.
 
     --cache-dir string                   Directory rclone will use for caching.
     --vfs-cache-mode CacheMode           Cache mode off|minimal|writes|full (default off)
+    --vfs-cache-mode-dirs CacheModeDirs  Override the cache mode for directories, e.g. incoming=writes,media=off
     --vfs-cache-max-age duration         Max age of objects in the cache (default 1h0m0s)
     --vfs-cache-max-size SizeSuffix      Max total size of objects in the cache (default off)
+    --vfs-cache-max-size-remote CacheMaxSizeRemote  Override the max cache size for remotes, e.g. gdrive=10G,s3=5G
     --vfs-cache-poll-interval duration   Interval to poll the cache for stale objects (default 1m0s)
     --vfs-write-back duration            Time to writeback files after last use when using cache (default 5s)
+    --vfs-write-back-windows WriteBackWindows  Only writeback files in these local time windows, e.g. 01:00-06:00
 
 If run with !-vv! rclone will print the location of the file cache.  The
 files are stored in the user cache file area which is OS dependent but
@@ -105,6 +118,16 @@ seconds. If rclone is quit or dies with files that haven't been
 uploaded, these will be uploaded next time rclone is run with the same
 flags.
 
+Use !--vfs-write-back-windows! to only upload files in some windows of
+local time, for example to use a metered or congested link only
+overnight. This takes a comma separated list of !HH:MM-HH:MM! windows,
+eg !01:00-06:00! or !22:00-02:00,12:00-13:00!. Files written outside
+the windows stay in the cache, where they can be read as normal, and
+are uploaded when the next window opens. Uploads which have started
+when a window closes carry on until they finish. The cache may grow
+beyond !--vfs-cache-max-size! as files waiting to be uploaded can't be
+evicted from it.
+
 If using !--vfs-cache-max-size! note that the cache may exceed this size
 for two reasons.  Firstly because it is only checked every
 !--vfs-cache-poll-interval!.  Secondly because open files cannot be
@@ -184,6 +207,46 @@ FAT/exFAT do not. Rclone will perform very badly if the cache
 directory is on a filesystem which doesn't support sparse files and it
 will log an ERROR message if one is detected.
 
+#### Per directory cache modes
+
+The cache mode can be changed for parts of the mount with
+!--vfs-cache-mode-dirs!. This takes a comma separated list of
+!dir=mode! pairs, where !dir! is relative to the root of the VFS and
+the mode applies to it and everything below it. The most specific
+directory wins, and !--vfs-cache-mode! is used for everything else.
+
+For example, to buffer writes to !incoming! but stream everything in
+!media! straight from the remote, use
+
+    --vfs-cache-mode-dirs incoming=writes,media=off
+
+#### Per remote cache sizes
+
+Each VFS has its own cache, so when one rclone serves several remotes,
+for example with the rc or !rclone serve docker!, each cache is kept
+under !--vfs-cache-max-size! independently. Use
+!--vfs-cache-max-size-remote! to give remotes different limits. This
+takes a comma separated list of !remote=size! pairs, where !remote! is
+the name of the remote in the config file, and
+!--vfs-cache-max-size! is used for every other remote.
+
+For example, to let !gdrive! use 10 GiB of cache but limit !s3! to
+5 GiB, use
+
+    --vfs-cache-max-size-remote gdrive=10G,s3=5G
+
+#### Pinning files in the cache
+
+Files can be pinned in the cache with the !vfs/pin! remote control
+command and unpinned again with !vfs/unpin!. Pinning a file reads all
+of it into the cache, and it is then never removed for being older than
+!--vfs-cache-max-age! or to keep the cache under
+!--vfs-cache-max-size!. Pinned files still count towards the cache
+size, so other files are removed first. Pins are stored with the cache
+metadata so they persist when rclone is restarted.
+
+Pinning needs !--vfs-cache-mode full!.
+
 #### Fingerprinting
 
 Various parts of the VFS use fingerprinting to see if a local file
@@ -243,6 +306,54 @@ When !--vfs-read-chunk-size-limit 500M! is specified, the result would be
 
 Setting !--vfs-read-chunk-size! to !0! or "off" disables chunked reading.
 
+### VFS Read Retries
+
+If reading a file from the remote fails, rclone reopens it at the
+same offset and tries again. How this is done is set with
+!--vfs-read-retry-policy!.
+
+    --vfs-read-retry-policy ReadRetryPolicy  Policy for retrying failed reads default|hard (default default)
+    --vfs-read-retries int                   Number of times to retry failed reads with --vfs-read-retry-policy hard (default 10)
+    --vfs-read-retry-sleep duration          Time to sleep before retrying a failed read with --vfs-read-retry-policy hard, doubled for each retry (default 1s)
+    --vfs-read-stall-timeout duration        Reopen reads which make no progress for this long with --vfs-read-retry-policy hard (0 to disable) (default 1m0s)
+
+With the !default! policy a failed read is retried straight away, up
+to !--low-level-retries! times.
+
+With the !hard! policy a failed read is retried !--vfs-read-retries!
+times, sleeping !--vfs-read-retry-sleep! before the first retry and
+doubling the sleep for each retry after, up to 32 times
+!--vfs-read-retry-sleep!. A read which gets no data from the remote
+for !--vfs-read-stall-timeout! is cancelled and retried in the same
+way, so a connection which hangs doesn't hang the program reading the
+file. This is useful for remotes on flaky connections, where a mount
+would otherwise return I/O errors to programs reading big files.
+
+These flags apply to files read without the VFS cache, so they have no
+effect with !--vfs-cache-mode full!.
+
+### VFS Prefetching
+
+When reading from remotes with a high latency, such as media being
+played from a slow server, a single stream may not be fast enough.
+Rclone can detect when a file is being read sequentially and fetch
+the chunks after the one being read in the background, in parallel.
+
+    --vfs-prefetch-chunks int               Number of chunks to prefetch in parallel for files read sequentially (0 to disable) (default 0)
+    --vfs-prefetch-chunk-size SizeSuffix    Size of the chunks prefetched with --vfs-prefetch-chunks (default 8Mi)
+
+Once !--vfs-prefetch-chunk-size! has been read from an open file
+without seeking, rclone keeps !--vfs-prefetch-chunks! chunks after the
+read position being fetched, each with its own ranged request. If the
+program reading the file seeks outside the chunks being fetched then
+prefetching stops until it reads sequentially again.
+
+Each open file being prefetched uses up to !--vfs-prefetch-chunks!
+times !--vfs-prefetch-chunk-size! of memory.
+
+Like the read retries, prefetching only applies to files read without
+the VFS cache.
+
 ### VFS Performance
 
 These flags may be used to enable/disable features of the VFS for
@@ -0,0 +1,47 @@
+// Reading and writing the metadata of files
+
+package vfs
+
+import (
+	"context"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// Metadata returns the metadata of the object backing the file.
+//
+// It returns nil if the file hasn't been uploaded yet or the object
+// has no metadata.
+func (f *File) Metadata() (fs.Metadata, error) {
+	o := f.getObject()
+	if o == nil {
+		return nil, nil
+	}
+	return fs.GetMetadata(context.TODO(), o)
+}
+
+// SetMetadata merges metadata into the metadata of the object backing
+// the file.
+//
+// It returns ENOSYS if the object can't set its metadata in place, as
+// uploading it again would block the caller for the whole transfer.
+func (f *File) SetMetadata(metadata fs.Metadata) error {
+	if f.d.vfs.Opt.ReadOnly {
+		return EROFS
+	}
+	if !f.Fs().Features().WriteMetadata {
+		return ENOSYS
+	}
+	if f.writingInProgress() {
+		return EPERM
+	}
+	o := f.getObject()
+	if o == nil {
+		return ENOENT
+	}
+	do, ok := o.(fs.SetMetadataer)
+	if !ok {
+		return ENOSYS
+	}
+	return do.SetMetadata(context.TODO(), metadata)
+}
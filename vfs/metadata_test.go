@@ -0,0 +1,34 @@
+package vfs
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMetadata(t *testing.T) {
+	r, vfs, file, _, cleanup := fileCreate(t, vfscommon.CacheModeOff)
+	defer cleanup()
+	features := r.Fremote.Features()
+	if !features.ReadMetadata || !features.WriteMetadata {
+		t.Skip("metadata not supported")
+	}
+	if _, ok := file.getObject().(fs.SetMetadataer); !ok {
+		t.Skip("SetMetadata not supported")
+	}
+
+	metadata, err := file.Metadata()
+	require.NoError(t, err)
+	assert.NotEmpty(t, metadata["mode"])
+
+	require.NoError(t, file.SetMetadata(fs.Metadata{"mode": "100600"}))
+	metadata, err = file.Metadata()
+	require.NoError(t, err)
+	assert.Equal(t, "100600", metadata["mode"])
+
+	vfs.Opt.ReadOnly = true
+	assert.Equal(t, EROFS, file.SetMetadata(fs.Metadata{"mode": "100600"}))
+}
@@ -0,0 +1,324 @@
+package vfs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/kv"
+)
+
+// persistDirCache keeps the directory listings read from the remote
+// in a key-value database so the VFS can start warm after a restart.
+//
+// All the methods may be called on a nil *persistDirCache, in which
+// case they do nothing.
+type persistDirCache struct {
+	f      fs.Fs
+	db     *kv.DB
+	prefix string // prefix of the keys for this Fs
+}
+
+// persistDirRecord is the listing of one directory as stored
+type persistDirRecord struct {
+	Read    time.Time           `json:"read"`
+	Entries []persistDirEntries `json:"entries"`
+}
+
+// persistDirEntries is one entry of a directory listing as stored
+type persistDirEntries struct {
+	Name    string    `json:"name"`
+	IsDir   bool      `json:"isDir,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// newPersistDirCache opens the persistent directory cache for f
+//
+// It returns nil if the cache can't be used.
+func newPersistDirCache(ctx context.Context, f fs.Fs) *persistDirCache {
+	if !kv.Supported() {
+		fs.Logf(f, "Persistent directory cache isn't supported on this OS")
+		return nil
+	}
+	db, err := kv.Start(ctx, "vfsdir", f)
+	if err != nil {
+		fs.Errorf(f, "Failed to open persistent directory cache - disabling: %v", err)
+		return nil
+	}
+	return &persistDirCache{
+		f:      f,
+		db:     db,
+		prefix: fs.ConfigString(f) + "\x00",
+	}
+}
+
+// stop closes the database
+func (p *persistDirCache) stop() {
+	if p == nil {
+		return
+	}
+	if err := p.db.Stop(false); err != nil {
+		fs.Debugf(p.f, "Failed to close persistent directory cache: %v", err)
+	}
+}
+
+// key returns the database key for dir
+func (p *persistDirCache) key(dir string) []byte {
+	return []byte(p.prefix + dir)
+}
+
+// kvGetDir: read the listing of a directory
+type kvGetDir struct {
+	key []byte
+	rec *persistDirRecord
+}
+
+func (op *kvGetDir) Do(ctx context.Context, b kv.Bucket) error {
+	data := b.Get(op.key)
+	if data == nil {
+		return nil
+	}
+	op.rec = new(persistDirRecord)
+	return json.Unmarshal(data, op.rec)
+}
+
+// kvPutDir: write the listing of a directory
+type kvPutDir struct {
+	key  []byte
+	data []byte
+}
+
+func (op *kvPutDir) Do(ctx context.Context, b kv.Bucket) error {
+	return b.Put(op.key, op.data)
+}
+
+// kvForgetDir: remove the listing of a directory
+type kvForgetDir struct {
+	key []byte
+}
+
+func (op *kvForgetDir) Do(ctx context.Context, b kv.Bucket) error {
+	return b.Delete(op.key)
+}
+
+// get returns the stored listing of dir if there is one read less
+// than maxAge before now, and the time it was read
+func (p *persistDirCache) get(dir string, now time.Time, maxAge time.Duration) (entries fs.DirEntries, read time.Time, found bool) {
+	if p == nil {
+		return nil, read, false
+	}
+	op := &kvGetDir{key: p.key(dir)}
+	err := p.db.Do(false, op)
+	if err == kv.ErrEmpty || (err == nil && op.rec == nil) {
+		return nil, read, false
+	}
+	if err != nil {
+		fs.Debugf(dir, "Failed to read persistent directory cache: %v", err)
+		return nil, read, false
+	}
+	if now.Sub(op.rec.Read) > maxAge {
+		return nil, read, false
+	}
+	entries = make(fs.DirEntries, 0, len(op.rec.Entries))
+	for _, entry := range op.rec.Entries {
+		remote := path.Join(dir, entry.Name)
+		if entry.IsDir {
+			entries = append(entries, fs.NewDir(remote, entry.ModTime).SetSize(entry.Size))
+		} else {
+			entries = append(entries, &persistObject{
+				f:       p.f,
+				remote:  remote,
+				size:    entry.Size,
+				modTime: entry.ModTime,
+			})
+		}
+	}
+	return entries, op.rec.Read, true
+}
+
+// put stores the listing of dir read at read
+func (p *persistDirCache) put(dir string, entries fs.DirEntries, read time.Time) {
+	if p == nil {
+		return
+	}
+	ctx := context.TODO()
+	rec := persistDirRecord{
+		Read:    read,
+		Entries: make([]persistDirEntries, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		_, isDir := entry.(fs.Directory)
+		rec.Entries = append(rec.Entries, persistDirEntries{
+			Name:    path.Base(entry.Remote()),
+			IsDir:   isDir,
+			Size:    entry.Size(),
+			ModTime: entry.ModTime(ctx),
+		})
+	}
+	data, err := json.Marshal(&rec)
+	if err == nil {
+		err = p.db.Do(true, &kvPutDir{key: p.key(dir), data: data})
+	}
+	if err != nil {
+		fs.Debugf(dir, "Failed to write persistent directory cache: %v", err)
+	}
+}
+
+// forget removes the stored listing of dir
+func (p *persistDirCache) forget(dir string) {
+	if p == nil {
+		return
+	}
+	err := p.db.Do(true, &kvForgetDir{key: p.key(dir)})
+	if err != nil && err != kv.ErrEmpty {
+		fs.Debugf(dir, "Failed to forget persistent directory cache: %v", err)
+	}
+}
+
+// persistObject is an object read from the persistent directory
+// cache.
+//
+// It knows the size and modification time of the object and finds
+// the object on the remote when anything else is needed.
+type persistObject struct {
+	f       fs.Fs
+	remote  string
+	size    int64
+	modTime time.Time
+
+	mu sync.Mutex
+	o  fs.Object // the object on the remote once found
+}
+
+// resolveObject returns the object on the remote for o if it was read
+// from the persistent directory cache, or o otherwise
+func resolveObject(ctx context.Context, o fs.Object) (fs.Object, error) {
+	if po, ok := o.(*persistObject); ok {
+		return po.resolve(ctx)
+	}
+	return o, nil
+}
+
+// resolve finds the object on the remote
+func (o *persistObject) resolve(ctx context.Context) (fs.Object, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.o != nil {
+		return o.o, nil
+	}
+	obj, err := o.f.NewObject(ctx, o.remote)
+	if err != nil {
+		return nil, err
+	}
+	o.o = obj
+	return obj, nil
+}
+
+// Fs returns read only access to the Fs that this object is part of
+func (o *persistObject) Fs() fs.Info {
+	return o.f
+}
+
+// String returns a description of the Object
+func (o *persistObject) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *persistObject) Remote() string {
+	return o.remote
+}
+
+// ModTime returns the modification date of the file
+func (o *persistObject) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// Size returns the size of the file
+func (o *persistObject) Size() int64 {
+	return o.size
+}
+
+// Hash returns the selected checksum of the file
+func (o *persistObject) Hash(ctx context.Context, ty hash.Type) (string, error) {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return "", err
+	}
+	return obj.Hash(ctx, ty)
+}
+
+// Storable says whether this object can be stored
+func (o *persistObject) Storable() bool {
+	return true
+}
+
+// SetModTime sets the metadata on the object to set the modification date
+func (o *persistObject) SetModTime(ctx context.Context, t time.Time) error {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.SetModTime(ctx, t)
+}
+
+// Open opens the file for read
+func (o *persistObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Open(ctx, options...)
+}
+
+// Update in to the object with the modTime given of the given size
+func (o *persistObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.Update(ctx, in, src, options...)
+}
+
+// Remove this object
+func (o *persistObject) Remove(ctx context.Context) error {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	return obj.Remove(ctx)
+}
+
+// MimeType returns the content type of the Object if known
+func (o *persistObject) MimeType(ctx context.Context) string {
+	obj, err := o.resolve(ctx)
+	if err != nil {
+		return ""
+	}
+	return fs.MimeType(ctx, obj)
+}
+
+// UnWrap returns the object on the remote or nil if it can't be found
+func (o *persistObject) UnWrap() fs.Object {
+	obj, err := o.resolve(context.TODO())
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Object          = (*persistObject)(nil)
+	_ fs.MimeTyper       = (*persistObject)(nil)
+	_ fs.ObjectUnWrapper = (*persistObject)(nil)
+)
@@ -0,0 +1,87 @@
+package vfs
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/lib/kv"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// persistTestNames returns the names of the nodes in dir
+func persistTestNames(t *testing.T, vfs *VFS, dir string) (names []string) {
+	nodes, err := vfs.ReadDir(dir)
+	require.NoError(t, err)
+	for _, node := range nodes {
+		names = append(names, node.Name())
+	}
+	return names
+}
+
+func TestVFSPersistDirCache(t *testing.T) {
+	if !kv.Supported() {
+		t.Skip("persistent directory cache not supported")
+	}
+	r := fstest.NewRun(t)
+	defer r.Finalise()
+	ctx := context.Background()
+
+	file1 := r.WriteObject(ctx, "dir/file1", "file1 contents", t1)
+	file2 := r.WriteObject(ctx, "dir/file2", "file2 contents", t2)
+	r.CheckRemoteItems(t, file1, file2)
+
+	opt := vfscommon.DefaultOpt
+	opt.PersistDirCache = true
+	opt.DirCacheTime = time.Hour
+
+	// Read the directory to fill the persistent cache
+	vfs1 := New(r.Fremote, &opt)
+	defer cleanupVFS(t, vfs1)
+	assert.Equal(t, []string{"file1", "file2"}, persistTestNames(t, vfs1, "dir"))
+
+	// Remove a file behind the VFS's back
+	obj, err := r.Fremote.NewObject(ctx, "dir/file2")
+	require.NoError(t, err)
+	require.NoError(t, obj.Remove(ctx))
+
+	// A new VFS should start with the persisted listing
+	opt2 := opt
+	opt2.DirCacheTime = time.Hour + time.Second
+	vfs2 := New(r.Fremote, &opt2)
+	defer cleanupVFS(t, vfs2)
+	require.NotEqual(t, vfs1, vfs2)
+	assert.Equal(t, []string{"file1", "file2"}, persistTestNames(t, vfs2, "dir"))
+
+	// Files from the persisted listing can be read
+	node, err := vfs2.Stat("dir/file1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(14), node.Size())
+	fh, err := node.Open(0)
+	require.NoError(t, err)
+	contents, err := ioutil.ReadAll(fh)
+	require.NoError(t, err)
+	require.NoError(t, fh.Close())
+	assert.Equal(t, "file1 contents", string(contents))
+
+	// A VFS with a short dir cache time should read the remote again
+	opt3 := opt
+	opt3.DirCacheTime = time.Nanosecond
+	vfs3 := New(r.Fremote, &opt3)
+	defer cleanupVFS(t, vfs3)
+	assert.Equal(t, []string{"file1"}, persistTestNames(t, vfs3, "dir"))
+}
+
+func TestVFSPersistDirCacheNil(t *testing.T) {
+	var p *persistDirCache
+	p.put("dir", nil, time.Now())
+	p.forget("dir")
+	entries, _, found := p.get("dir", time.Now(), time.Hour)
+	assert.False(t, found)
+	assert.Nil(t, entries)
+	p.stop()
+}
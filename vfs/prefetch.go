@@ -0,0 +1,138 @@
+// Prefetching chunks of files read sequentially
+
+package vfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/accounting"
+)
+
+// prefetchChunk is a chunk of the file being fetched
+type prefetchChunk struct {
+	done chan struct{} // closed when the fetch is finished
+	data []byte        // the data read
+	err  error         // error from the fetch
+}
+
+// prefetcher reads the chunks after the current read position of a
+// file in the background
+type prefetcher struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	o         fs.Object
+	acc       *accounting.Account      // account the data fetched here
+	chunkSize int64                    // size of each chunk
+	size      int64                    // size of the file
+	chunks    int                      // number of chunks to fetch ahead
+	fetching  map[int64]*prefetchChunk // chunks fetched or being fetched by index
+}
+
+// newPrefetcher starts fetching the chunks of o from the one
+// containing off.
+func newPrefetcher(o fs.Object, acc *accounting.Account, chunkSize int64, chunks int, off int64) *prefetcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &prefetcher{
+		ctx:       ctx,
+		cancel:    cancel,
+		o:         o,
+		acc:       acc,
+		chunkSize: chunkSize,
+		size:      o.Size(),
+		chunks:    chunks,
+		fetching:  make(map[int64]*prefetchChunk),
+	}
+	fs.Debugf(o, "Sequential read detected - prefetching %d chunks of %v", chunks, fs.SizeSuffix(chunkSize))
+	p.fetchFrom(off / chunkSize)
+	return p
+}
+
+// fetch reads chunk i into c
+func (p *prefetcher) fetch(i int64, c *prefetchChunk) {
+	defer p.wg.Done()
+	defer close(c.done)
+	start := i * p.chunkSize
+	end := start + p.chunkSize
+	if end > p.size {
+		end = p.size
+	}
+	in, err := p.o.Open(p.ctx, &fs.RangeOption{Start: start, End: end - 1})
+	if err != nil {
+		c.err = err
+		return
+	}
+	data := make([]byte, end-start)
+	_, err = io.ReadFull(in, data)
+	closeErr := in.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = p.acc.AccountRead(len(data))
+	}
+	if err != nil {
+		c.err = fmt.Errorf("prefetch of chunk at %d failed: %w", start, err)
+		return
+	}
+	c.data = data
+}
+
+// fetchFrom discards the chunks before chunk first and starts
+// fetching the chunks from it which aren't being fetched already
+func (p *prefetcher) fetchFrom(first int64) {
+	for i := range p.fetching {
+		if i < first {
+			delete(p.fetching, i)
+		}
+	}
+	for i := first; i < first+int64(p.chunks) && i*p.chunkSize < p.size; i++ {
+		if _, ok := p.fetching[i]; ok {
+			continue
+		}
+		c := &prefetchChunk{done: make(chan struct{})}
+		p.fetching[i] = c
+		p.wg.Add(1)
+		go p.fetch(i, c)
+	}
+}
+
+// read reads into buf from off using the prefetched chunks, waiting
+// for them to arrive if necessary.
+//
+// It returns false if off isn't in a chunk being fetched or a fetch
+// failed, in which case the caller should read the data some other
+// way.
+func (p *prefetcher) read(buf []byte, off int64) (n int, ok bool) {
+	if _, found := p.fetching[off/p.chunkSize]; !found || off >= p.size {
+		return 0, false
+	}
+	for n < len(buf) && off < p.size {
+		i := off / p.chunkSize
+		c, found := p.fetching[i]
+		if !found {
+			p.fetchFrom(i)
+			c = p.fetching[i]
+		}
+		<-c.done
+		if c.err != nil {
+			fs.Debugf(p.o, "%v", c.err)
+			return 0, false
+		}
+		copied := copy(buf[n:], c.data[off-i*p.chunkSize:])
+		n += copied
+		off += int64(copied)
+		p.fetchFrom(off / p.chunkSize)
+	}
+	return n, true
+}
+
+// close stops the prefetching and waits for the fetches to finish
+func (p *prefetcher) close() {
+	p.cancel()
+	p.wg.Wait()
+}
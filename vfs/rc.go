@@ -404,9 +404,11 @@ This returns stats for the selected VFS.
             "erroredFiles": 0,
             "files": 0,
             "hashType": 1,
+            "maxSize": -1,
             "outOfSpace": false,
             "path": "/home/user/.cache/rclone/vfs/local/mnt/a",
             "pathMeta": "/home/user/.cache/rclone/vfsMeta/local/mnt/a",
+            "pinnedFiles": 0,
             "uploadsInProgress": 0,
             "uploadsQueued": 0
         },
@@ -437,3 +439,102 @@ func rcStats(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	}
 	return vfs.Stats(), nil
 }
+
+// getPinFiles returns the paths passed in as file=path
+func getPinFiles(in rc.Params) (paths []string, err error) {
+	for k, v := range in {
+		path, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("value must be string %q=%v", k, v)
+		}
+		if !strings.HasPrefix(k, "file") {
+			return nil, fmt.Errorf("unknown key %q", k)
+		}
+		paths = append(paths, strings.Trim(path, "/"))
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("need at least one file=path parameter")
+	}
+	return paths, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/pin",
+		Fn:    rcPin,
+		Title: "Pin files in the VFS cache.",
+		Help: `
+This reads the files into the VFS cache and pins them there so they
+are not removed when they get older than --vfs-cache-max-age or the
+cache grows bigger than --vfs-cache-max-size. Pins are kept with the
+cache metadata so they persist if rclone is restarted.
+
+Pass files in as file=path. Any parameter key starting with file will
+pin that file, e.g.
+
+    rclone rc vfs/pin file=hello file2=films/big.mkv
+
+This needs --vfs-cache-mode full for the files pinned. Pinned files
+still count towards --vfs-cache-max-size, so other files will be
+removed first to make space.
+` + getVFSHelp,
+	})
+}
+
+func rcPin(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := getPinFiles(in)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		err = vfs.Pin(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rc.Params{
+		"pinned": paths,
+	}, nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/unpin",
+		Fn:    rcUnpin,
+		Title: "Unpin files in the VFS cache.",
+		Help: `
+This removes the pins set with vfs/pin so the files can be removed
+from the VFS cache as normal. It doesn't remove the files from the
+cache.
+
+Pass files in as file=path. Any parameter key starting with file will
+unpin that file, e.g.
+
+    rclone rc vfs/unpin file=hello file2=films/big.mkv
+` + getVFSHelp,
+	})
+}
+
+func rcUnpin(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := getPinFiles(in)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		err = vfs.Unpin(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rc.Params{
+		"unpinned": paths,
+	}, nil
+}
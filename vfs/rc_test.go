@@ -7,6 +7,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/lib/ranges"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -131,3 +132,55 @@ func TestRcStats(t *testing.T) {
 	assert.Equal(t, 1, out["metadataCache"].(rc.Params)["dirs"])
 	assert.Equal(t, vfs.Opt, out["opt"].(vfscommon.Options))
 }
+
+func TestRcPin(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping test on non local remote")
+	}
+	opt := vfscommon.DefaultOpt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.CachePollInterval = 0
+	r, vfs, cleanup := newTestVFSOpt(t, &opt)
+	defer cleanup()
+	pin := rc.Calls.Get("vfs/pin")
+	unpin := rc.Calls.Get("vfs/unpin")
+
+	file1 := r.WriteObject(context.Background(), "dir/file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+
+	_, err := pin.Fn(context.Background(), rc.Params{})
+	assert.Error(t, err)
+
+	_, err = pin.Fn(context.Background(), rc.Params{"potato": "dir/file1"})
+	assert.Error(t, err)
+
+	_, err = pin.Fn(context.Background(), rc.Params{"file": "dir"})
+	assert.Error(t, err)
+
+	_, err = pin.Fn(context.Background(), rc.Params{"file": "notfound"})
+	assert.Error(t, err)
+
+	out, err := pin.Fn(context.Background(), rc.Params{"file": "/dir/file1"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"pinned": []string{"dir/file1"}}, out)
+	assert.True(t, vfs.cache.IsPinned("dir/file1"))
+	assert.True(t, vfs.cache.Item("dir/file1").HasRange(ranges.Range{Pos: 0, Size: file1.Size}))
+
+	out, err = unpin.Fn(context.Background(), rc.Params{"file": "dir/file1"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{"unpinned": []string{"dir/file1"}}, out)
+	assert.False(t, vfs.cache.IsPinned("dir/file1"))
+}
+
+func TestRcPinNoCache(t *testing.T) {
+	r, vfs, cleanup, call := rcNewRun(t, "vfs/pin")
+	defer cleanup()
+	_ = vfs
+
+	file1 := r.WriteObject(context.Background(), "file1", "file1 contents", t1)
+	r.CheckRemoteItems(t, file1)
+
+	_, err := call.Fn(context.Background(), rc.Params{"file": "file1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "needs --vfs-cache-mode full")
+}
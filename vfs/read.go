@@ -33,6 +33,11 @@ type ReadFileHandle struct {
 	hash        *hash.MultiHasher
 	opened      bool
 	remote      string
+	ctx         context.Context    // context for reading the object
+	cancel      context.CancelFunc // cancel reads using ctx
+	prefetch    *prefetcher        // prefetcher if reading sequentially
+	seqBytes    int64              // bytes read sequentially since the last seek
+	streamLost  bool               // set if the stream isn't at offset as reads came from the prefetcher
 }
 
 // Check interfaces
@@ -74,7 +79,7 @@ func (fh *ReadFileHandle) openPending() (err error) {
 		return nil
 	}
 	o := fh.file.getObject()
-	r, err := chunkedreader.New(context.TODO(), o, int64(fh.file.VFS().Opt.ChunkSize), int64(fh.file.VFS().Opt.ChunkSizeLimit)).Open()
+	r, err := chunkedreader.New(fh.newReadContext(), o, int64(fh.file.VFS().Opt.ChunkSize), int64(fh.file.VFS().Opt.ChunkSizeLimit)).Open()
 	if err != nil {
 		return err
 	}
@@ -86,6 +91,18 @@ func (fh *ReadFileHandle) openPending() (err error) {
 	return nil
 }
 
+// newReadContext cancels any reads in progress and returns a new
+// context to read the object with
+//
+// Must be called with fh.mu held
+func (fh *ReadFileHandle) newReadContext() context.Context {
+	if fh.cancel != nil {
+		fh.cancel()
+	}
+	fh.ctx, fh.cancel = context.WithCancel(context.Background())
+	return fh.ctx
+}
+
 // String converts it to printable
 func (fh *ReadFileHandle) String() string {
 	if fh == nil {
@@ -147,7 +164,7 @@ func (fh *ReadFileHandle) seek(offset int64, reopen bool) (err error) {
 		}
 		// re-open with a seek
 		o := fh.file.getObject()
-		r = chunkedreader.New(context.TODO(), o, int64(fh.file.VFS().Opt.ChunkSize), int64(fh.file.VFS().Opt.ChunkSizeLimit))
+		r = chunkedreader.New(fh.newReadContext(), o, int64(fh.file.VFS().Opt.ChunkSize), int64(fh.file.VFS().Opt.ChunkSizeLimit))
 		_, err := r.Seek(offset, 0)
 		if err != nil {
 			fs.Debugf(fh.remote, "ReadFileHandle.Read seek failed: %v", err)
@@ -269,7 +286,14 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 	if gap := off - fh.offset; gap > 0 && gap < int64(8*maxBuf) {
 		waitSequential("read", fh.remote, fh.cond, fh.file.VFS().Opt.ReadWait, &fh.offset, off)
 	}
-	doSeek := off != fh.offset
+	if n, ok := fh.readPrefetch(p, off); ok {
+		fh.cond.Broadcast() // wake everyone up waiting for an in-sequence read
+		if n != len(p) {
+			return n, io.EOF
+		}
+		return n, nil
+	}
+	doSeek := off != fh.offset || fh.streamLost
 	if doSeek && fh.noSeek {
 		return 0, ESPIPE
 	}
@@ -277,7 +301,7 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 	retries := 0
 	reqSize := len(p)
 	doReopen := false
-	lowLevelRetries := fs.GetConfig(context.TODO()).LowLevelRetries
+	readRetry := fh.file.VFS().Opt.ReadRetry(context.TODO())
 	for {
 		if doSeek {
 			// Are we attempting to seek beyond the end of the
@@ -288,7 +312,10 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 				return 0, io.EOF
 			}
 			// Otherwise do the seek
-			err = fh.seek(off, doReopen)
+			err = fh.seek(off, doReopen || fh.streamLost)
+			if err == nil {
+				fh.streamLost = false
+			}
 		} else {
 			err = nil
 		}
@@ -296,7 +323,7 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 			if reqSize > 0 {
 				fh.readCalled = true
 			}
-			n, err = io.ReadFull(fh.r, p)
+			n, err = readRetry.ReadFull(fh.r, p, fh.cancel)
 			newOffset = fh.offset + int64(n)
 			// if err == nil && rand.Intn(10) == 0 {
 			// 	err = errors.New("random error")
@@ -314,11 +341,15 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 				break
 			}
 		}
-		if retries >= lowLevelRetries {
+		if retries >= readRetry.Retries {
 			break
 		}
 		retries++
-		fs.Errorf(fh.remote, "ReadFileHandle.Read error: low level retry %d/%d: %v", retries, lowLevelRetries, err)
+		fs.Errorf(fh.remote, "ReadFileHandle.Read error: low level retry %d/%d: %v", retries, readRetry.Retries, err)
+		if waitErr := readRetry.Wait(context.TODO(), retries); waitErr != nil {
+			err = waitErr
+			break
+		}
 		doSeek = true
 		doReopen = true
 	}
@@ -345,6 +376,51 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 	return n, err
 }
 
+// readPrefetch reads p at off from the prefetched chunks, starting
+// the prefetcher once enough has been read sequentially.
+//
+// It returns false if the data should be read from the stream instead.
+//
+// Call with fh.mu Locked
+func (fh *ReadFileHandle) readPrefetch(p []byte, off int64) (n int, ok bool) {
+	opt := &fh.file.VFS().Opt
+	if opt.PrefetchChunks <= 0 || opt.PrefetchChunkSize <= 0 || fh.sizeUnknown || fh.noSeek {
+		return 0, false
+	}
+	if off == fh.offset {
+		fh.seqBytes += int64(len(p))
+	} else {
+		fh.seqBytes = 0
+	}
+	if fh.prefetch == nil {
+		if fh.seqBytes < int64(opt.PrefetchChunkSize) {
+			return 0, false
+		}
+		fh.r.StopBuffering() // the stream isn't used while prefetching
+		fh.prefetch = newPrefetcher(fh.file.getObject(), fh.r, int64(opt.PrefetchChunkSize), opt.PrefetchChunks, off)
+	}
+	n, ok = fh.prefetch.read(p, off)
+	if !ok {
+		fs.Debugf(fh.remote, "ReadFileHandle.Read stopping prefetch as read at %d wasn't prefetched", off)
+		fh.prefetch.close()
+		fh.prefetch = nil
+		fh.seqBytes = 0
+		return 0, false
+	}
+	if off != fh.offset {
+		fh.hash = nil
+	}
+	if len(p) > 0 {
+		fh.readCalled = true
+	}
+	fh.offset = off + int64(n)
+	fh.streamLost = true
+	if fh.hash != nil {
+		_, _ = fh.hash.Write(p[:n])
+	}
+	return n, true
+}
+
 func (fh *ReadFileHandle) checkHash() error {
 	if fh.hash == nil || !fh.readCalled || fh.offset < fh.size {
 		return nil
@@ -412,6 +488,13 @@ func (fh *ReadFileHandle) close() error {
 		return ECLOSED
 	}
 	fh.closed = true
+	if fh.cancel != nil {
+		defer fh.cancel()
+	}
+	if fh.prefetch != nil {
+		fh.prefetch.close()
+		fh.prefetch = nil
+	}
 
 	if fh.opened {
 		var err error
@@ -5,8 +5,10 @@ import (
 	"io"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -232,3 +234,95 @@ func TestReadFileHandleRelease(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, fh.closed)
 }
+
+func TestReadFileHandleReadRetryPolicyHard(t *testing.T) {
+	opt := vfscommon.DefaultOpt
+	opt.ReadRetryPolicy = vfscommon.ReadRetryPolicyHard
+	opt.ReadRetrySleep = time.Millisecond
+	r, vfs, cleanup := newTestVFSOpt(t, &opt)
+	defer cleanup()
+
+	file1 := r.WriteObject(context.Background(), "file1", "0123456789abcdef", t1)
+	r.CheckRemoteItems(t, file1)
+
+	h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*ReadFileHandle)
+	require.True(t, ok)
+
+	// Read which makes the read context
+	assert.Equal(t, "0123", readString(t, fh, 4))
+	require.NotNil(t, fh.cancel)
+
+	// Seek which reopens the file with a new context
+	oldCtx := fh.ctx
+	fh.mu.Lock()
+	require.NoError(t, fh.seek(8, true))
+	fh.mu.Unlock()
+	_, err = fh.Seek(8, io.SeekStart)
+	require.NoError(t, err)
+	assert.Error(t, oldCtx.Err())
+	assert.NoError(t, fh.ctx.Err())
+	assert.Equal(t, "89abcdef", readString(t, fh, 256))
+
+	// Close cancels the read context
+	require.NoError(t, fh.Close())
+	assert.Error(t, fh.ctx.Err())
+}
+
+func TestReadFileHandlePrefetch(t *testing.T) {
+	opt := vfscommon.DefaultOpt
+	opt.PrefetchChunks = 2
+	opt.PrefetchChunkSize = 4
+	r, vfs, cleanup := newTestVFSOpt(t, &opt)
+	defer cleanup()
+
+	contents := "0123456789abcdefghijklmnopqrstuvwxyz"
+	file1 := r.WriteObject(context.Background(), "file1", contents, t1)
+	r.CheckRemoteItems(t, file1)
+
+	h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*ReadFileHandle)
+	require.True(t, ok)
+
+	// Sequential reads start the prefetcher
+	assert.Equal(t, "012", readString(t, fh, 3))
+	assert.Nil(t, fh.prefetch)
+	assert.Equal(t, "345", readString(t, fh, 3))
+	require.NotNil(t, fh.prefetch)
+	assert.Equal(t, "6789abcdefg", readString(t, fh, 11))
+	assert.True(t, fh.streamLost)
+
+	// Seeking outside the prefetched chunks stops it
+	_, err = fh.Seek(30, io.SeekStart)
+	require.NoError(t, err)
+	assert.Equal(t, "uvw", readString(t, fh, 3))
+	assert.Nil(t, fh.prefetch)
+	assert.False(t, fh.streamLost)
+
+	// Reading sequentially starts it again and reads to the end
+	assert.Equal(t, "xyz", readString(t, fh, 100))
+	require.NotNil(t, fh.prefetch)
+	buf := make([]byte, 10)
+	n, err := fh.Read(buf)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, io.EOF, err)
+
+	// Read the whole file with the prefetcher
+	_, err = fh.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	var got []byte
+	for {
+		n, err := fh.Read(buf[:5])
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	assert.Equal(t, contents, string(got))
+
+	require.NoError(t, fh.Close())
+	assert.Nil(t, fh.prefetch)
+}
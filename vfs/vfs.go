@@ -24,6 +24,7 @@ package vfs
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -168,7 +169,9 @@ type VFS struct {
 	usageTime   time.Time
 	usage       *fs.Usage
 	pollChan    chan time.Duration
-	inUse       int32 // count of number of opens accessed with atomic
+	inUse       int32                    // count of number of opens accessed with atomic
+	persist     *persistDirCache         // directory cache kept on disk if set
+	cacheModes  vfscommon.CacheModeRules // per directory overrides of Opt.CacheMode
 }
 
 // Keep track of active VFS keyed on fs.ConfigString(f)
@@ -210,6 +213,18 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 	// Put the VFS into the active cache
 	active[configName] = append(active[configName], vfs)
 
+	// Parse the per directory cache modes
+	cacheModes, err := vfscommon.ParseCacheModeRules(string(vfs.Opt.CacheModeDirs))
+	if err != nil {
+		fs.Errorf(f, "Ignoring --vfs-cache-mode-dirs: %v", err)
+	}
+	vfs.cacheModes = cacheModes
+
+	// Open the directory cache on disk
+	if vfs.Opt.PersistDirCache {
+		vfs.persist = newPersistDirCache(context.TODO(), f)
+	}
+
 	// Create root directory
 	vfs.root = newDir(vfs, f, nil, fsDir)
 
@@ -224,7 +239,7 @@ func New(f fs.Fs, opt *vfscommon.Options) *VFS {
 	}
 
 	// Warn if can't stream
-	if !vfs.Opt.ReadOnly && vfs.Opt.CacheMode < vfscommon.CacheModeWrites && features.PutStream == nil {
+	if !vfs.Opt.ReadOnly && vfs.cacheModes.Max(vfs.Opt.CacheMode) < vfscommon.CacheModeWrites && features.PutStream == nil {
 		fs.Logf(f, "--vfs-cache-mode writes or full is recommended for this remote as it can't stream")
 	}
 
@@ -287,7 +302,7 @@ func (vfs *VFS) Fs() fs.Fs {
 func (vfs *VFS) SetCacheMode(cacheMode vfscommon.CacheMode) {
 	vfs.shutdownCache()
 	vfs.cache = nil
-	if cacheMode > vfscommon.CacheModeOff {
+	if vfs.cacheModes.Max(cacheMode) > vfscommon.CacheModeOff {
 		ctx, cancel := context.WithCancel(context.Background())
 		cache, err := vfscache.New(ctx, vfs.f, &vfs.Opt, vfs.AddVirtual) // FIXME pass on context or get from Opt?
 		if err != nil {
@@ -302,6 +317,15 @@ func (vfs *VFS) SetCacheMode(cacheMode vfscommon.CacheMode) {
 	}
 }
 
+// cacheMode returns the cache mode in use for remote, taking into
+// account the per directory overrides
+func (vfs *VFS) cacheMode(remote string) vfscommon.CacheMode {
+	if vfs.cache == nil {
+		return vfscommon.CacheModeOff
+	}
+	return vfs.cacheModes.Mode(remote, vfs.Opt.CacheMode)
+}
+
 // shutdown the cache if it was running
 func (vfs *VFS) shutdownCache() {
 	if vfs.cancelCache != nil {
@@ -331,11 +355,12 @@ func (vfs *VFS) Shutdown() {
 	activeMu.Unlock()
 
 	vfs.shutdownCache()
+	vfs.persist.stop()
 }
 
 // CleanUp deletes the contents of the on disk cache
 func (vfs *VFS) CleanUp() error {
-	if vfs.Opt.CacheMode == vfscommon.CacheModeOff {
+	if vfs.cache == nil {
 		return nil
 	}
 	return vfs.cache.CleanUp()
@@ -701,6 +726,50 @@ func (vfs *VFS) ReadFile(filename string) (b []byte, err error) {
 	return ioutil.ReadAll(f)
 }
 
+// Pin reads the whole of the file name into the VFS cache and pins it
+// there so it isn't evicted by --vfs-cache-max-age or
+// --vfs-cache-max-size.
+//
+// This needs --vfs-cache-mode full for name.
+func (vfs *VFS) Pin(name string) (err error) {
+	node, err := vfs.Stat(name)
+	if err != nil {
+		return err
+	}
+	if node.IsDir() {
+		return fmt.Errorf("can't pin directory %q", name)
+	}
+	if vfs.cacheMode(node.Path()) < vfscommon.CacheModeFull {
+		return fmt.Errorf("can't pin %q: needs --vfs-cache-mode full", name)
+	}
+	fd, err := node.Open(os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	defer fs.CheckClose(fd, &err)
+	_, err = io.Copy(ioutil.Discard, fd)
+	if err != nil {
+		return fmt.Errorf("failed to read %q into the cache: %w", name, err)
+	}
+	return vfs.cache.Pin(node.Path())
+}
+
+// Unpin removes a pin set with Pin so the file name can be evicted
+// from the VFS cache again.
+func (vfs *VFS) Unpin(name string) error {
+	if vfs.cache == nil {
+		return fmt.Errorf("can't unpin %q: VFS cache not in use", name)
+	}
+	node, err := vfs.Stat(name)
+	if err != nil {
+		return err
+	}
+	if node.IsDir() {
+		return fmt.Errorf("can't unpin directory %q", name)
+	}
+	return vfs.cache.Unpin(node.Path())
+}
+
 // AddVirtual adds the object (file or dir) to the directory cache
 func (vfs *VFS) AddVirtual(remote string, size int64, isDir bool) error {
 	dir, leaf, err := vfs.StatParent(remote)
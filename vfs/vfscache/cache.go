@@ -44,6 +44,7 @@ type Cache struct {
 	fcache     fs.Fs                // fs for the cache directory
 	fcacheMeta fs.Fs                // fs for the cache metadata directory
 	opt        *vfscommon.Options   // vfs Options
+	maxSize    int64                // max size of the cache for this remote or <= 0 for no limit
 	root       string               // root of the cache directory
 	metaRoot   string               // root of the cache metadata directory
 	hashType   hash.Type            // hash to use locally and remotely
@@ -111,6 +112,9 @@ func New(ctx context.Context, fremote fs.Fs, opt *vfscommon.Options, avFn AddVir
 		return nil, err
 	}
 	hashType, hashOption := operations.CommonHash(ctx, fdata, fremote)
+	if opt.CacheMaxSizeRemote != "" {
+		fs.Debugf(nil, "vfs cache: max size for remote %q is %v", fremote.Name(), opt.CacheMaxSizeRemote.MaxSize(fremote.Name(), opt.CacheMaxSize))
+	}
 
 	// Create the cache object
 	c := &Cache{
@@ -118,6 +122,7 @@ func New(ctx context.Context, fremote fs.Fs, opt *vfscommon.Options, avFn AddVir
 		fcache:     fdata,
 		fcacheMeta: fmeta,
 		opt:        opt,
+		maxSize:    int64(opt.CacheMaxSizeRemote.MaxSize(fremote.Name(), opt.CacheMaxSize)),
 		root:       dataOSPath,
 		metaRoot:   metaOSPath,
 		item:       make(map[string]*Item),
@@ -161,7 +166,16 @@ func (c *Cache) Stats() (out rc.Params) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	pinned := 0
+	for _, item := range c.item {
+		if item.isPinned() {
+			pinned++
+		}
+	}
+
 	out["files"] = len(c.item)
+	out["pinnedFiles"] = pinned
+	out["maxSize"] = c.maxSize
 	out["erroredFiles"] = len(c.errItems)
 	out["bytesUsed"] = c.used
 	out["outOfSpace"] = c.outOfSpace
@@ -357,6 +371,34 @@ func (c *Cache) Exists(name string) bool {
 	return item.Exists()
 }
 
+// Pin marks the file name so it is kept in the cache regardless of
+// --vfs-cache-max-age and --vfs-cache-max-size.
+//
+// The pin is stored with the item metadata so persists over restarts.
+//
+// name should be a remote path not an osPath
+func (c *Cache) Pin(name string) error {
+	item, _ := c.get(name)
+	return item.setPinned(true)
+}
+
+// Unpin removes a pin set with Pin so the file name can be evicted
+// from the cache again.
+//
+// name should be a remote path not an osPath
+func (c *Cache) Unpin(name string) error {
+	item, _ := c.get(name)
+	return item.setPinned(false)
+}
+
+// IsPinned returns whether the file name is pinned in the cache
+//
+// name should be a remote path not an osPath
+func (c *Cache) IsPinned(name string) bool {
+	item, _ := c.get(name)
+	return item.isPinned()
+}
+
 // rename with os.Rename and more checking
 func rename(osOldPath, osNewPath string) error {
 	sfi, err := os.Stat(osOldPath)
@@ -617,9 +659,9 @@ func (c *Cache) purgeClean(quota int64) {
 		return
 	}
 
-	// Make a slice of clean cache files
+	// Make a slice of clean cache files which aren't pinned
 	for _, item := range c.item {
-		if !item.IsDirty() {
+		if !item.IsDirty() && !item.isPinned() {
 			items = append(items, item)
 		}
 	}
@@ -659,14 +701,29 @@ func (c *Cache) purgeOld(maxAge time.Duration) {
 	defer c.mu.Unlock()
 	// cutoff := time.Now().Add(-maxAge)
 	for _, item := range c.item {
+		if item.isPinned() {
+			continue
+		}
 		c.removeNotInUse(item, maxAge, false)
 	}
-	if c.used < int64(c.opt.CacheMaxSize) {
+	if c.used < c.maxSize {
 		c.outOfSpace = false
 		c.cond.Broadcast()
 	}
 }
 
+// pinnedUsed returns the total size of the pinned files in the cache
+func (c *Cache) pinnedUsed() (used int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, item := range c.item {
+		if item.isPinned() {
+			used += item.getDiskSize()
+		}
+	}
+	return used
+}
+
 // Purge any empty directories
 func (c *Cache) purgeEmptyDirs(dir string, leaveRoot bool) {
 	ctx := context.Background()
@@ -708,9 +765,9 @@ func (c *Cache) purgeOverQuota(quota int64) {
 
 	var items Items
 
-	// Make a slice of unused files
+	// Make a slice of unused files which aren't pinned
 	for _, item := range c.item {
-		if !item.inUse() {
+		if !item.inUse() && !item.isPinned() {
 			items = append(items, item)
 		}
 	}
@@ -744,20 +801,27 @@ func (c *Cache) clean(kicked bool) {
 		// Remove any files that are over age
 		c.purgeOld(c.opt.CacheMaxAge)
 
-		if int64(c.opt.CacheMaxSize) <= 0 {
+		if c.maxSize <= 0 {
 			break
 		}
 
 		// Now remove files not in use until cache size is below quota starting from the
 		// oldest first
-		c.purgeOverQuota(int64(c.opt.CacheMaxSize))
+		c.purgeOverQuota(c.maxSize)
 
 		// Remove cache files that are not dirty if we are still above the max cache size
-		c.purgeClean(int64(c.opt.CacheMaxSize))
+		c.purgeClean(c.maxSize)
 		c.retryFailedResets()
 
 		used := c.updateUsed()
-		if used <= int64(c.opt.CacheMaxSize) && len(c.errItems) == 0 {
+		if used <= c.maxSize && len(c.errItems) == 0 {
+			break
+		}
+
+		// Pinned files can't be evicted so give up if they are
+		// all that is left over quota
+		if pinned := c.pinnedUsed(); used <= pinned && len(c.errItems) == 0 {
+			fs.Logf(nil, "vfs cache: pinned files use %v which is over the max cache size %v", fs.SizeSuffix(pinned), fs.SizeSuffix(c.maxSize))
 			break
 		}
 	}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	_ "github.com/rclone/rclone/backend/local" // import the local backend
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
@@ -490,6 +491,72 @@ func TestCachePurgeClean(t *testing.T) {
 	assert.Equal(t, []string(nil), itemAsString(c))
 }
 
+func TestCachePin(t *testing.T) {
+	_, c, cleanup := newTestCache(t)
+	defer cleanup()
+
+	// Make some test files
+	potato := c.Item("sub/dir/potato")
+	itemWrite(t, potato, "hello")
+	require.NoError(t, potato.Close(nil))
+
+	potato2 := c.Item("sub/dir2/potato2")
+	itemWrite(t, potato2, "hello2")
+	require.NoError(t, potato2.Close(nil))
+
+	c.updateUsed()
+	assert.Equal(t, int64(11), c.used)
+
+	// Pin potato and check the pin is saved in the metadata
+	assert.False(t, c.IsPinned("sub/dir/potato"))
+	require.NoError(t, c.Pin("sub/dir/potato"))
+	assert.True(t, c.IsPinned("sub/dir/potato"))
+	assert.False(t, c.IsPinned("sub/dir2/potato2"))
+	meta, err := os.ReadFile(c.toOSPathMeta("sub/dir/potato"))
+	require.NoError(t, err)
+	assert.Contains(t, string(meta), `"Pinned": true`)
+	assert.Equal(t, 1, c.Stats()["pinnedFiles"])
+
+	// Check only potato2 removed when over quota
+	c.purgeOverQuota(1)
+	assert.Equal(t, int64(5), c.used)
+	assert.Equal(t, []string{
+		`name="sub/dir/potato" opens=0 size=5`,
+	}, itemAsString(c))
+
+	// Check potato isn't removed by the other purges
+	c.purgeClean(1)
+	c.purgeOld(-10 * time.Second)
+	assert.Equal(t, []string{
+		`name="sub/dir/potato" opens=0 size=5`,
+	}, itemAsString(c))
+	assert.Equal(t, int64(5), c.pinnedUsed())
+
+	// Unpin it and check it can be removed
+	require.NoError(t, c.Unpin("sub/dir/potato"))
+	assert.False(t, c.IsPinned("sub/dir/potato"))
+	c.purgeOverQuota(1)
+	assert.Equal(t, int64(0), c.used)
+	assert.Equal(t, []string(nil), itemAsString(c))
+}
+
+func TestCacheMaxSizeRemote(t *testing.T) {
+	opt := vfscommon.DefaultOpt
+	opt.CachePollInterval = 0
+	opt.CacheMaxSize = fs.Mebi
+	opt.CacheMaxSizeRemote = "notthisremote=1k"
+	_, c, cleanup := newTestCacheOpt(t, opt)
+	assert.Equal(t, int64(fs.Mebi), c.maxSize)
+	assert.Equal(t, int64(fs.Mebi), c.Stats()["maxSize"])
+	name := c.fremote.Name()
+	cleanup()
+
+	opt.CacheMaxSizeRemote = vfscommon.CacheMaxSizeRemote("notthisremote=1k," + name + "=2k")
+	_, c, cleanup = newTestCacheOpt(t, opt)
+	defer cleanup()
+	assert.Equal(t, int64(2*fs.Kibi), c.maxSize)
+}
+
 func TestCacheInUse(t *testing.T) {
 	_, c, cleanup := newTestCache(t)
 	defer cleanup()
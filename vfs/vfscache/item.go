@@ -78,6 +78,7 @@ type Info struct {
 	Rs          ranges.Ranges // which parts of the file are present
 	Fingerprint string        // fingerprint of remote object
 	Dirty       bool          // set if the backing file has been modified
+	Pinned      bool          // set if the file should not be evicted from the cache
 }
 
 // Items are a slice of *Item ordered by ATime
@@ -179,6 +180,28 @@ func (item *Item) getATime() time.Time {
 	return item.info.ATime
 }
 
+// isPinned returns true if the item is pinned in the cache
+func (item *Item) isPinned() bool {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	return item.info.Pinned
+}
+
+// setPinned sets whether the item is pinned in the cache and saves
+// the metadata if the item has a backing file
+func (item *Item) setPinned(pinned bool) error {
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	if item.info.Pinned == pinned {
+		return nil
+	}
+	item.info.Pinned = pinned
+	if !item._exists() {
+		return nil
+	}
+	return item._save()
+}
+
 // getDiskSize returns the size on disk (approximately) of the item
 //
 // We return the sizes of the chunks we have fetched, however there is
@@ -791,7 +814,9 @@ func (item *Item) _checkObject(o fs.Object) error {
 			if remoteFingerprint != item.info.Fingerprint {
 				if !item.info.Dirty {
 					fs.Debugf(item.name, "vfs cache: removing cached entry as stale (remote fingerprint %q != cached fingerprint %q)", remoteFingerprint, item.info.Fingerprint)
+					pinned := item.info.Pinned
 					item._remove("stale (remote is different)")
+					item.info.Pinned = pinned
 				} else {
 					fs.Debugf(item.name, "vfs cache: remote object has changed but local object modified - keeping it (remote fingerprint %q != cached fingerprint %q)", remoteFingerprint, item.info.Fingerprint)
 				}
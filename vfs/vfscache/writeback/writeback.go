@@ -34,6 +34,7 @@ type WriteBack struct {
 	timer   *time.Timer               // next scheduled time for the uploader
 	expiry  time.Time                 // time the next item expires or IsZero
 	uploads int                       // number of uploads in progress
+	windows vfscommon.TimeWindows     // time windows uploads are allowed in if set
 
 	// read and written with atomic
 	id Handle // id of the last writeBackItem created
@@ -49,6 +50,11 @@ func New(ctx context.Context, opt *vfscommon.Options) *WriteBack {
 		lookup: make(map[Handle]*writeBackItem),
 		opt:    opt,
 	}
+	windows, err := vfscommon.ParseTimeWindows(string(opt.WriteBackWindows))
+	if err != nil {
+		fs.Errorf(nil, "vfs cache: ignoring --vfs-write-back-windows: %v", err)
+	}
+	wb.windows = windows
 	heap.Init(&wb.items)
 	return wb
 }
@@ -132,6 +138,22 @@ func (wb *WriteBack) _newExpiry() time.Time {
 	return expiry
 }
 
+// return the time an item which expires at expiry may be uploaded,
+// which is delayed until the next --vfs-write-back-windows window if
+// necessary
+//
+// call with lock held
+func (wb *WriteBack) _uploadTime(expiry time.Time) time.Time {
+	if len(wb.windows) == 0 {
+		return expiry
+	}
+	now := time.Now()
+	if expiry.Before(now) {
+		expiry = now
+	}
+	return wb.windows.Next(expiry)
+}
+
 // make a new writeBackItem
 //
 // call with the lock held
@@ -220,11 +242,15 @@ func (wb *WriteBack) _resetTimer() {
 	if wbItem == nil {
 		wb._stopTimer()
 	} else {
-		if wb.expiry.Equal(wbItem.expiry) {
+		expiry := wb._uploadTime(wbItem.expiry)
+		if wb.expiry.Equal(expiry) {
 			return
 		}
-		wb.expiry = wbItem.expiry
-		dt := time.Until(wbItem.expiry)
+		if !expiry.Equal(wbItem.expiry) {
+			fs.Debugf(wbItem.name, "vfs cache: delaying writeback until %v as outside --vfs-write-back-windows", expiry.Format("15:04"))
+		}
+		wb.expiry = expiry
+		dt := time.Until(expiry)
 		if dt < 0 {
 			dt = 0
 		}
@@ -432,7 +458,7 @@ func (wb *WriteBack) processItems(ctx context.Context) {
 	}
 
 	resetTimer := true
-	for wbItem := wb._peekItem(); wbItem != nil && time.Until(wbItem.expiry) <= 0; wbItem = wb._peekItem() {
+	for wbItem := wb._peekItem(); wbItem != nil && time.Until(wb._uploadTime(wbItem.expiry)) <= 0; wbItem = wb._peekItem() {
 		// If reached transfer limit don't restart the timer
 		if wb.uploads >= fs.GetConfig(context.TODO()).Transfers {
 			fs.Debugf(wbItem.name, "vfs cache: delaying writeback as --transfers exceeded")
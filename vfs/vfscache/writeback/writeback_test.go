@@ -491,6 +491,41 @@ func TestWriteBackGetStats(t *testing.T) {
 
 }
 
+// Test uploads outside --vfs-write-back-windows are delayed
+func TestWriteBackWindows(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Now()
+	start := now.Add(2 * time.Hour)
+	opt := vfscommon.DefaultOpt
+	opt.WriteBack = 100 * time.Millisecond
+	opt.WriteBackWindows = vfscommon.WriteBackWindows(start.Format("15:04") + "-" + now.Add(3*time.Hour).Format("15:04"))
+	wb := New(ctx, &opt)
+
+	pi := newPutItem(t)
+	id := wb.Add(0, "one", true, pi.put)
+	wbItem := wb.lookup[id]
+
+	// The upload is scheduled for the start of the window
+	time.Sleep(300 * time.Millisecond)
+	checkOnHeap(t, wb, wbItem)
+	assert.False(t, pi.called)
+	wb.mu.Lock()
+	assert.Equal(t, start.Truncate(time.Minute).Format("15:04"), wb.expiry.Format("15:04"))
+	assert.True(t, wb.expiry.After(now.Add(time.Hour)))
+
+	// Open the window and check it uploads
+	wb.windows = nil
+	wb.mu.Unlock()
+	wb.processItems(ctx)
+
+	<-pi.started
+	checkNotOnHeap(t, wb, wbItem)
+	pi.finish(nil) // transfer successful
+	waitUntilNoTransfers(t, wb)
+	checkNotInLookup(t, wb, wbItem)
+}
+
 // Test queuing more than fs.Config.Transfers
 func TestWriteBackMaxQueue(t *testing.T) {
 	ctx := context.Background()
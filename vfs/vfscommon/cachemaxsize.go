@@ -0,0 +1,74 @@
+package vfscommon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// CacheMaxSizeRemote is a comma separated list of remote=size which
+// overrides the max cache size for VFSes on remote, eg
+// "gdrive=10G,s3=5G"
+type CacheMaxSizeRemote string
+
+// String returns the overrides as a string
+func (c CacheMaxSizeRemote) String() string {
+	return string(c)
+}
+
+// Set the overrides, checking they parse
+func (c *CacheMaxSizeRemote) Set(s string) error {
+	if _, err := ParseCacheMaxSizeRemote(s); err != nil {
+		return err
+	}
+	*c = CacheMaxSizeRemote(s)
+	return nil
+}
+
+// Type of the value
+func (c *CacheMaxSizeRemote) Type() string {
+	return "CacheMaxSizeRemote"
+}
+
+// ParseCacheMaxSizeRemote parses a comma separated list of remote=size
+// into a map of remote name to size
+func ParseCacheMaxSizeRemote(s string) (sizes map[string]fs.SizeSuffix, err error) {
+	sizes = map[string]fs.SizeSuffix{}
+	for _, rule := range strings.Split(s, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		equals := strings.LastIndex(rule, "=")
+		if equals < 0 {
+			return nil, fmt.Errorf("cache max size override %q should be remote=size", rule)
+		}
+		var size fs.SizeSuffix
+		if err := size.Set(strings.TrimSpace(rule[equals+1:])); err != nil {
+			return nil, fmt.Errorf("cache max size override %q: %w", rule, err)
+		}
+		remote := strings.TrimSuffix(strings.TrimSpace(rule[:equals]), ":")
+		if remote == "" {
+			return nil, fmt.Errorf("cache max size override %q: missing remote name", rule)
+		}
+		if _, found := sizes[remote]; found {
+			return nil, fmt.Errorf("cache max size override %q: duplicate remote %q", rule, remote)
+		}
+		sizes[remote] = size
+	}
+	return sizes, nil
+}
+
+// MaxSize returns the max cache size for the remote called name,
+// or def if there is no override for it
+func (c CacheMaxSizeRemote) MaxSize(name string, def fs.SizeSuffix) fs.SizeSuffix {
+	sizes, err := ParseCacheMaxSizeRemote(string(c))
+	if err != nil {
+		return def
+	}
+	if size, found := sizes[name]; found {
+		return size
+	}
+	return def
+}
@@ -0,0 +1,53 @@
+package vfscommon
+
+import (
+	"testing"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// Check CacheMaxSizeRemote it satisfies the pflag interface
+var _ pflag.Value = (*CacheMaxSizeRemote)(nil)
+
+func TestCacheMaxSizeRemoteSet(t *testing.T) {
+	var c CacheMaxSizeRemote
+	assert.NoError(t, c.Set("gdrive=10G,s3=5G"))
+	assert.Equal(t, "gdrive=10G,s3=5G", c.String())
+	assert.Equal(t, "CacheMaxSizeRemote", c.Type())
+	assert.Error(t, c.Set("gdrive=potato"))
+	assert.Equal(t, "gdrive=10G,s3=5G", c.String())
+}
+
+func TestParseCacheMaxSizeRemote(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    map[string]fs.SizeSuffix
+		wantErr bool
+	}{
+		{in: "", want: map[string]fs.SizeSuffix{}},
+		{in: "a=1k", want: map[string]fs.SizeSuffix{"a": fs.Kibi}},
+		{in: " a: = 2M , b=off,", want: map[string]fs.SizeSuffix{"a": 2 * fs.Mebi, "b": -1}},
+		{in: "a=1k,a:=2k", wantErr: true},
+		{in: "a", wantErr: true},
+		{in: "a=potato", wantErr: true},
+		{in: "=1k", wantErr: true},
+	} {
+		got, err := ParseCacheMaxSizeRemote(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		assert.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestCacheMaxSizeRemoteMaxSize(t *testing.T) {
+	c := CacheMaxSizeRemote("gdrive=10G,s3=5G")
+	assert.Equal(t, 10*fs.Gibi, c.MaxSize("gdrive", -1))
+	assert.Equal(t, 5*fs.Gibi, c.MaxSize("s3", -1))
+	assert.Equal(t, fs.SizeSuffix(-1), c.MaxSize("local", -1))
+	assert.Equal(t, fs.Mebi, CacheMaxSizeRemote("").MaxSize("gdrive", fs.Mebi))
+}
@@ -2,6 +2,9 @@ package vfscommon
 
 import (
 	"fmt"
+	"path"
+	"sort"
+	"strings"
 
 	"github.com/rclone/rclone/fs"
 )
@@ -58,3 +61,90 @@ func (l *CacheMode) UnmarshalJSON(in []byte) error {
 		return nil
 	})
 }
+
+// CacheModeDirs is a comma separated list of dir=mode which overrides
+// the cache mode for dir and everything below it, eg
+// "incoming=writes,media=off"
+type CacheModeDirs string
+
+// String returns the overrides as a string
+func (c CacheModeDirs) String() string {
+	return string(c)
+}
+
+// Set the overrides, checking they parse
+func (c *CacheModeDirs) Set(s string) error {
+	if _, err := ParseCacheModeRules(s); err != nil {
+		return err
+	}
+	*c = CacheModeDirs(s)
+	return nil
+}
+
+// Type of the value
+func (c *CacheModeDirs) Type() string {
+	return "CacheModeDirs"
+}
+
+// CacheModeRule sets the cache mode for a directory and everything
+// below it
+type CacheModeRule struct {
+	Dir  string
+	Mode CacheMode
+}
+
+// CacheModeRules are the parsed CacheModeDirs, most specific first
+type CacheModeRules []CacheModeRule
+
+// ParseCacheModeRules parses a comma separated list of dir=mode
+func ParseCacheModeRules(s string) (rules CacheModeRules, err error) {
+	seen := map[string]struct{}{}
+	for _, rule := range strings.Split(s, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		equals := strings.LastIndex(rule, "=")
+		if equals < 0 {
+			return nil, fmt.Errorf("cache mode override %q should be dir=mode", rule)
+		}
+		var mode CacheMode
+		if err := mode.Set(strings.TrimSpace(rule[equals+1:])); err != nil {
+			return nil, fmt.Errorf("cache mode override %q: %w", rule, err)
+		}
+		dir := strings.TrimPrefix(path.Clean("/"+strings.TrimSpace(rule[:equals])), "/")
+		if dir == "" {
+			return nil, fmt.Errorf("cache mode override %q: use --vfs-cache-mode to set the cache mode of the root", rule)
+		}
+		if _, found := seen[dir]; found {
+			return nil, fmt.Errorf("cache mode override %q: duplicate directory %q", rule, dir)
+		}
+		seen[dir] = struct{}{}
+		rules = append(rules, CacheModeRule{Dir: dir, Mode: mode})
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].Dir) > len(rules[j].Dir)
+	})
+	return rules, nil
+}
+
+// Mode returns the cache mode for remote, or def if no rule matches
+func (rules CacheModeRules) Mode(remote string, def CacheMode) CacheMode {
+	for _, rule := range rules {
+		if remote == rule.Dir || strings.HasPrefix(remote, rule.Dir+"/") {
+			return rule.Mode
+		}
+	}
+	return def
+}
+
+// Max returns the highest cache mode used by the rules or def
+func (rules CacheModeRules) Max(def CacheMode) CacheMode {
+	max := def
+	for _, rule := range rules {
+		if rule.Mode > max {
+			max = rule.Mode
+		}
+	}
+	return max
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Check CacheMode it satisfies the pflag interface
@@ -63,3 +64,60 @@ func TestCacheModeUnmarshalJSON(t *testing.T) {
 	err = json.Unmarshal([]byte("99"), &m)
 	assert.Error(t, err, "Unknown cache mode level")
 }
+
+// Check CacheModeDirs it satisfies the pflag interface
+var _ pflag.Value = (*CacheModeDirs)(nil)
+
+func TestCacheModeDirsSet(t *testing.T) {
+	var c CacheModeDirs
+	assert.NoError(t, c.Set("incoming=writes,media=off"))
+	assert.Equal(t, "incoming=writes,media=off", c.String())
+	assert.Equal(t, "CacheModeDirs", c.Type())
+	assert.Error(t, c.Set("incoming=potato"))
+	assert.Equal(t, "incoming=writes,media=off", c.String())
+}
+
+func TestParseCacheModeRules(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		want    CacheModeRules
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "a=writes", want: CacheModeRules{{"a", CacheModeWrites}}},
+		{in: " /a/ = full , a/b/c=off,", want: CacheModeRules{{"a/b/c", CacheModeOff}, {"a", CacheModeFull}}},
+		{in: "a=writes,a/=off", wantErr: true},
+		{in: "a", wantErr: true},
+		{in: "a=potato", wantErr: true},
+		{in: "/=writes", wantErr: true},
+	} {
+		got, err := ParseCacheModeRules(test.in)
+		if test.wantErr {
+			assert.Error(t, err, test.in)
+			continue
+		}
+		assert.NoError(t, err, test.in)
+		assert.Equal(t, test.want, got, test.in)
+	}
+}
+
+func TestCacheModeRulesMode(t *testing.T) {
+	rules, err := ParseCacheModeRules("incoming=writes,media=off,media/music=full")
+	require.NoError(t, err)
+	for _, test := range []struct {
+		remote string
+		want   CacheMode
+	}{
+		{"", CacheModeMinimal},
+		{"file", CacheModeMinimal},
+		{"incoming", CacheModeWrites},
+		{"incoming/file", CacheModeWrites},
+		{"incomingfile", CacheModeMinimal},
+		{"media/film", CacheModeOff},
+		{"media/music/song", CacheModeFull},
+	} {
+		assert.Equal(t, test.want, rules.Mode(test.remote, CacheModeMinimal), test.remote)
+	}
+	assert.Equal(t, CacheModeFull, rules.Max(CacheModeOff))
+	assert.Equal(t, CacheModeWrites, CacheModeRules(nil).Max(CacheModeWrites))
+}
@@ -24,8 +24,10 @@ type Options struct {
 	ChunkSize          fs.SizeSuffix // if > 0 read files in chunks
 	ChunkSizeLimit     fs.SizeSuffix // if > ChunkSize double the chunk size after each chunk until reached
 	CacheMode          CacheMode
+	CacheModeDirs      CacheModeDirs // per directory overrides of CacheMode
 	CacheMaxAge        time.Duration
 	CacheMaxSize       fs.SizeSuffix
+	CacheMaxSizeRemote CacheMaxSizeRemote // per remote overrides of CacheMaxSize
 	CachePollInterval  time.Duration
 	CaseInsensitive    bool
 	WriteWait          time.Duration // time to wait for in-sequence write
@@ -35,6 +37,14 @@ type Options struct {
 	UsedIsSize         bool          // if true, use the `rclone size` algorithm for Used size
 	FastFingerprint    bool          // if set use fast fingerprints
 	DiskSpaceTotalSize fs.SizeSuffix
+	ReadRetryPolicy    ReadRetryPolicy  // how to retry failed reads
+	ReadRetries        int              // number of retries with the hard read retry policy
+	ReadRetrySleep     time.Duration    // initial backoff with the hard read retry policy
+	ReadStallTimeout   time.Duration    // reopen reads making no progress for this long with the hard read retry policy
+	PersistDirCache    bool             // if set keep the directory cache on disk between runs
+	PrefetchChunks     int              // number of chunks to prefetch when reading sequentially
+	PrefetchChunkSize  fs.SizeSuffix    // size of the chunks to prefetch
+	WriteBackWindows   WriteBackWindows // only write back dirty files in these time windows if set
 }
 
 // DefaultOpt is the default values uses for Opt
@@ -51,11 +61,13 @@ var DefaultOpt = Options{
 	DirPerms:           os.FileMode(0777),
 	FilePerms:          os.FileMode(0666),
 	CacheMode:          CacheModeOff,
+	CacheModeDirs:      "",
 	CacheMaxAge:        3600 * time.Second,
 	CachePollInterval:  60 * time.Second,
 	ChunkSize:          128 * fs.Mebi,
 	ChunkSizeLimit:     -1,
 	CacheMaxSize:       -1,
+	CacheMaxSizeRemote: "",
 	CaseInsensitive:    runtime.GOOS == "windows" || runtime.GOOS == "darwin", // default to true on Windows and Mac, false otherwise
 	WriteWait:          1000 * time.Millisecond,
 	ReadWait:           20 * time.Millisecond,
@@ -63,6 +75,14 @@ var DefaultOpt = Options{
 	ReadAhead:          0 * fs.Mebi,
 	UsedIsSize:         false,
 	DiskSpaceTotalSize: -1,
+	ReadRetryPolicy:    ReadRetryPolicyDefault,
+	ReadRetries:        10,
+	ReadRetrySleep:     time.Second,
+	ReadStallTimeout:   time.Minute,
+	PersistDirCache:    false,
+	PrefetchChunks:     0,
+	PrefetchChunkSize:  8 * fs.Mebi,
+	WriteBackWindows:   "",
 }
 
 // Init the options, making sure everything is withing range
@@ -0,0 +1,159 @@
+package vfscommon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// ReadRetryPolicy controls how reads from the remote are retried
+type ReadRetryPolicy byte
+
+// ReadRetryPolicy options
+const (
+	ReadRetryPolicyDefault ReadRetryPolicy = iota // retry --low-level-retries times straight away
+	ReadRetryPolicyHard                           // retry with backoff and reopen stalled reads
+)
+
+var readRetryPolicyToString = []string{
+	ReadRetryPolicyDefault: "default",
+	ReadRetryPolicyHard:    "hard",
+}
+
+// String turns a ReadRetryPolicy into a string
+func (p ReadRetryPolicy) String() string {
+	if p >= ReadRetryPolicy(len(readRetryPolicyToString)) {
+		return fmt.Sprintf("ReadRetryPolicy(%d)", p)
+	}
+	return readRetryPolicyToString[p]
+}
+
+// Set a ReadRetryPolicy
+func (p *ReadRetryPolicy) Set(s string) error {
+	for n, name := range readRetryPolicyToString {
+		if s != "" && name == s {
+			*p = ReadRetryPolicy(n)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown read retry policy %q", s)
+}
+
+// Type of the value
+func (p *ReadRetryPolicy) Type() string {
+	return "ReadRetryPolicy"
+}
+
+// UnmarshalJSON makes sure the value can be parsed as a string or integer in JSON
+func (p *ReadRetryPolicy) UnmarshalJSON(in []byte) error {
+	return fs.UnmarshalJSONFlag(in, p, func(i int64) error {
+		if i < 0 || i >= int64(len(readRetryPolicyToString)) {
+			return fmt.Errorf("unknown read retry policy %d", i)
+		}
+		*p = ReadRetryPolicy(i)
+		return nil
+	})
+}
+
+// readRetryMaxSleepFactor is how many times --vfs-read-retry-sleep
+// the backoff between retries can grow to
+const readRetryMaxSleepFactor = 32
+
+// ErrReadStalled is returned when a read made no progress for the
+// stall timeout
+var ErrReadStalled = errors.New("read stalled")
+
+// ReadRetry is the retry policy for reading files from the remote
+type ReadRetry struct {
+	Retries      int           // number of times to retry a failed read
+	Sleep        time.Duration // time to sleep before the first retry, doubled for each one after
+	StallTimeout time.Duration // reopen reads which make no progress for this long if set
+}
+
+// ReadRetry returns the retry policy for reading files set by the
+// options
+func (opt *Options) ReadRetry(ctx context.Context) ReadRetry {
+	if opt.ReadRetryPolicy == ReadRetryPolicyHard {
+		return ReadRetry{
+			Retries:      opt.ReadRetries,
+			Sleep:        opt.ReadRetrySleep,
+			StallTimeout: opt.ReadStallTimeout,
+		}
+	}
+	return ReadRetry{
+		Retries: fs.GetConfig(ctx).LowLevelRetries,
+	}
+}
+
+// Backoff returns how long to sleep before the retry numbered retry,
+// counting from 1
+func (rr ReadRetry) Backoff(retry int) time.Duration {
+	if rr.Sleep <= 0 || retry <= 0 {
+		return 0
+	}
+	sleep := rr.Sleep
+	for i := 1; i < retry && sleep < rr.Sleep*readRetryMaxSleepFactor; i++ {
+		sleep *= 2
+	}
+	if sleep > rr.Sleep*readRetryMaxSleepFactor {
+		sleep = rr.Sleep * readRetryMaxSleepFactor
+	}
+	return sleep
+}
+
+// Wait sleeps for the backoff before the retry numbered retry,
+// returning early with an error if ctx is cancelled
+func (rr ReadRetry) Wait(ctx context.Context, retry int) error {
+	sleep := rr.Backoff(retry)
+	if sleep <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(sleep)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ReadFull reads exactly len(p) bytes from r into p like
+// io.ReadFull.
+//
+// If the stall timeout is set and no data arrives for that long then
+// cancel is called, which should make the blocked Read return, and
+// ErrReadStalled is returned.
+func (rr ReadRetry) ReadFull(r io.Reader, p []byte, cancel func()) (n int, err error) {
+	if rr.StallTimeout <= 0 {
+		return io.ReadFull(r, p)
+	}
+	var stalled int32
+	timer := time.AfterFunc(rr.StallTimeout, func() {
+		atomic.StoreInt32(&stalled, 1)
+		cancel()
+	})
+	defer timer.Stop()
+	for n < len(p) && err == nil {
+		var nn int
+		nn, err = r.Read(p[n:])
+		n += nn
+		if nn > 0 && atomic.LoadInt32(&stalled) == 0 {
+			timer.Reset(rr.StallTimeout)
+		}
+	}
+	if atomic.LoadInt32(&stalled) != 0 {
+		return n, ErrReadStalled
+	}
+	if n >= len(p) {
+		err = nil
+	} else if n > 0 && err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
@@ -0,0 +1,143 @@
+package vfscommon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// Check ReadRetryPolicy it satisfies the pflag interface
+var _ pflag.Value = (*ReadRetryPolicy)(nil)
+
+// Check ReadRetryPolicy it satisfies the json.Unmarshaller interface
+var _ json.Unmarshaler = (*ReadRetryPolicy)(nil)
+
+func TestReadRetryPolicyString(t *testing.T) {
+	assert.Equal(t, "default", ReadRetryPolicyDefault.String())
+	assert.Equal(t, "hard", ReadRetryPolicyHard.String())
+	assert.Equal(t, "ReadRetryPolicy(17)", ReadRetryPolicy(17).String())
+}
+
+func TestReadRetryPolicySet(t *testing.T) {
+	var p ReadRetryPolicy
+
+	err := p.Set("hard")
+	assert.NoError(t, err)
+	assert.Equal(t, ReadRetryPolicyHard, p)
+
+	err = p.Set("potato")
+	assert.Error(t, err)
+
+	err = p.Set("")
+	assert.Error(t, err)
+}
+
+func TestReadRetryPolicyType(t *testing.T) {
+	var p ReadRetryPolicy
+	assert.Equal(t, "ReadRetryPolicy", p.Type())
+}
+
+func TestReadRetryPolicyUnmarshalJSON(t *testing.T) {
+	var p ReadRetryPolicy
+
+	err := json.Unmarshal([]byte(`"hard"`), &p)
+	assert.NoError(t, err)
+	assert.Equal(t, ReadRetryPolicyHard, p)
+
+	err = json.Unmarshal([]byte(`"potato"`), &p)
+	assert.Error(t, err)
+
+	err = json.Unmarshal([]byte(strconv.Itoa(int(ReadRetryPolicyDefault))), &p)
+	assert.NoError(t, err)
+	assert.Equal(t, ReadRetryPolicyDefault, p)
+
+	err = json.Unmarshal([]byte("99"), &p)
+	assert.Error(t, err)
+}
+
+func TestOptionsReadRetry(t *testing.T) {
+	ctx := context.Background()
+	opt := DefaultOpt
+	rr := opt.ReadRetry(ctx)
+	assert.Equal(t, ReadRetry{Retries: fs.GetConfig(ctx).LowLevelRetries}, rr)
+
+	opt.ReadRetryPolicy = ReadRetryPolicyHard
+	rr = opt.ReadRetry(ctx)
+	assert.Equal(t, ReadRetry{Retries: 10, Sleep: time.Second, StallTimeout: time.Minute}, rr)
+}
+
+func TestReadRetryBackoff(t *testing.T) {
+	rr := ReadRetry{Sleep: time.Second}
+	for _, test := range []struct {
+		retry int
+		want  time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{6, 32 * time.Second},
+		{7, 32 * time.Second},
+		{100, 32 * time.Second},
+	} {
+		assert.Equal(t, test.want, rr.Backoff(test.retry), test.retry)
+	}
+	assert.Equal(t, time.Duration(0), ReadRetry{}.Backoff(3))
+}
+
+func TestReadRetryWait(t *testing.T) {
+	rr := ReadRetry{Sleep: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, rr.Wait(ctx, 1))
+	assert.NoError(t, ReadRetry{}.Wait(ctx, 1))
+}
+
+// stallReader returns data then blocks until it is cancelled
+type stallReader struct {
+	data      io.Reader
+	cancelled chan struct{}
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if n > 0 {
+		return n, nil
+	}
+	if err != io.EOF {
+		return 0, err
+	}
+	<-r.cancelled
+	return 0, errors.New("cancelled")
+}
+
+func TestReadRetryReadFull(t *testing.T) {
+	rr := ReadRetry{StallTimeout: 10 * time.Millisecond}
+
+	// Read which completes
+	buf := make([]byte, 5)
+	n, err := rr.ReadFull(strings.NewReader("hello world"), buf, func() {})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "hello", string(buf))
+
+	// Short read at end of file
+	n, err = rr.ReadFull(strings.NewReader("hi"), buf, func() {})
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, 2, n)
+
+	// Read which stalls
+	r := &stallReader{data: strings.NewReader("hel"), cancelled: make(chan struct{})}
+	n, err = rr.ReadFull(r, buf, func() { close(r.cancelled) })
+	assert.Equal(t, ErrReadStalled, err)
+	assert.Equal(t, 3, n)
+}
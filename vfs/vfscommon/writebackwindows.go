@@ -0,0 +1,108 @@
+package vfscommon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WriteBackWindows is a comma separated list of HH:MM-HH:MM local
+// time windows in which cached files are written back, eg
+// "01:00-06:00,12:00-13:00"
+type WriteBackWindows string
+
+// String returns the windows as a string
+func (w WriteBackWindows) String() string {
+	return string(w)
+}
+
+// Set the windows, checking they parse
+func (w *WriteBackWindows) Set(s string) error {
+	if _, err := ParseTimeWindows(s); err != nil {
+		return err
+	}
+	*w = WriteBackWindows(s)
+	return nil
+}
+
+// Type of the value
+func (w *WriteBackWindows) Type() string {
+	return "WriteBackWindows"
+}
+
+// TimeWindow is a daily window of local time from Start up to End,
+// in minutes since midnight. If End is before Start the window
+// crosses midnight and if they are equal it lasts all day.
+type TimeWindow struct {
+	Start int
+	End   int
+}
+
+// contains returns whether the minute of the day m is in the window
+func (tw TimeWindow) contains(m int) bool {
+	switch {
+	case tw.Start < tw.End:
+		return m >= tw.Start && m < tw.End
+	case tw.Start > tw.End:
+		return m >= tw.Start || m < tw.End
+	}
+	return true
+}
+
+// TimeWindows are the parsed WriteBackWindows
+type TimeWindows []TimeWindow
+
+// parseHHMM parses HH:MM into minutes since midnight
+func parseHHMM(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q - expecting HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseTimeWindows parses a comma separated list of HH:MM-HH:MM
+func ParseTimeWindows(s string) (windows TimeWindows, err error) {
+	for _, window := range strings.Split(s, ",") {
+		window = strings.TrimSpace(window)
+		if window == "" {
+			continue
+		}
+		dash := strings.Index(window, "-")
+		if dash < 0 {
+			return nil, fmt.Errorf("time window %q should be HH:MM-HH:MM", window)
+		}
+		var tw TimeWindow
+		if tw.Start, err = parseHHMM(window[:dash]); err != nil {
+			return nil, fmt.Errorf("time window %q: %w", window, err)
+		}
+		if tw.End, err = parseHHMM(window[dash+1:]); err != nil {
+			return nil, fmt.Errorf("time window %q: %w", window, err)
+		}
+		windows = append(windows, tw)
+	}
+	return windows, nil
+}
+
+// Next returns the first time at or after t which is in one of the
+// windows, or t if there are no windows.
+func (windows TimeWindows) Next(t time.Time) time.Time {
+	if len(windows) == 0 {
+		return t
+	}
+	minute := t.Hour()*60 + t.Minute()
+	var next time.Time
+	for _, tw := range windows {
+		if tw.contains(minute) {
+			return t
+		}
+		start := time.Date(t.Year(), t.Month(), t.Day(), tw.Start/60, tw.Start%60, 0, 0, t.Location())
+		if !start.After(t) {
+			start = start.AddDate(0, 0, 1)
+		}
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return next
+}
@@ -0,0 +1,71 @@
+package vfscommon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Check WriteBackWindows it satisfies the pflag interface
+var _ pflag.Value = (*WriteBackWindows)(nil)
+
+func TestWriteBackWindowsSet(t *testing.T) {
+	var w WriteBackWindows
+	require.NoError(t, w.Set("01:00-06:00, 22:30-00:15"))
+	assert.Equal(t, "01:00-06:00, 22:30-00:15", w.String())
+	assert.Equal(t, "WriteBackWindows", w.Type())
+	require.NoError(t, w.Set(""))
+	assert.Equal(t, "", w.String())
+
+	for _, bad := range []string{"01:00", "01:00-25:00", "1am-6am", "01:00-06:00,potato"} {
+		assert.Error(t, w.Set(bad), bad)
+	}
+}
+
+func TestParseTimeWindows(t *testing.T) {
+	windows, err := ParseTimeWindows("01:00-06:00, 22:30-00:15")
+	require.NoError(t, err)
+	assert.Equal(t, TimeWindows{{Start: 60, End: 360}, {Start: 1350, End: 15}}, windows)
+
+	windows, err = ParseTimeWindows("")
+	require.NoError(t, err)
+	assert.Nil(t, windows)
+}
+
+func TestTimeWindowsNext(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, 4, day, hour, minute, 0, 0, time.UTC)
+	}
+	windows, err := ParseTimeWindows("01:00-06:00,22:30-00:15")
+	require.NoError(t, err)
+	for _, test := range []struct {
+		in   time.Time
+		want time.Time
+	}{
+		{at(1, 0, 0), at(1, 0, 0)},
+		{at(1, 0, 15), at(1, 1, 0)},
+		{at(1, 1, 0), at(1, 1, 0)},
+		{at(1, 5, 59), at(1, 5, 59)},
+		{at(1, 6, 0), at(1, 22, 30)},
+		{at(1, 12, 0), at(1, 22, 30)},
+		{at(1, 23, 0), at(1, 23, 0)},
+	} {
+		assert.Equal(t, test.want, windows.Next(test.in), test.in.String())
+	}
+
+	// No windows means any time
+	assert.Equal(t, at(1, 12, 0), TimeWindows(nil).Next(at(1, 12, 0)))
+
+	// A window which doesn't cross midnight starts tomorrow
+	windows, err = ParseTimeWindows("01:00-06:00")
+	require.NoError(t, err)
+	assert.Equal(t, at(2, 1, 0), windows.Next(at(1, 7, 0)))
+
+	// A window starting and ending at the same time is all day
+	windows, err = ParseTimeWindows("03:00-03:00")
+	require.NoError(t, err)
+	assert.Equal(t, at(1, 12, 0), windows.Next(at(1, 12, 0)))
+}
@@ -25,9 +25,11 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.DurationVarP(flagSet, &Opt.PollInterval, "poll-interval", "", Opt.PollInterval, "Time to wait between polling for changes, must be smaller than dir-cache-time and only on supported remotes (set 0 to disable)")
 	flags.BoolVarP(flagSet, &Opt.ReadOnly, "read-only", "", Opt.ReadOnly, "Only allow read-only access")
 	flags.FVarP(flagSet, &Opt.CacheMode, "vfs-cache-mode", "", "Cache mode off|minimal|writes|full")
+	flags.FVarP(flagSet, &Opt.CacheModeDirs, "vfs-cache-mode-dirs", "", "Override the cache mode for directories, e.g. incoming=writes,media=off")
 	flags.DurationVarP(flagSet, &Opt.CachePollInterval, "vfs-cache-poll-interval", "", Opt.CachePollInterval, "Interval to poll the cache for stale objects")
 	flags.DurationVarP(flagSet, &Opt.CacheMaxAge, "vfs-cache-max-age", "", Opt.CacheMaxAge, "Max age of objects in the cache")
 	flags.FVarP(flagSet, &Opt.CacheMaxSize, "vfs-cache-max-size", "", "Max total size of objects in the cache")
+	flags.FVarP(flagSet, &Opt.CacheMaxSizeRemote, "vfs-cache-max-size-remote", "", "Override the max cache size for remotes, e.g. gdrive=10G,s3=5G")
 	flags.FVarP(flagSet, &Opt.ChunkSize, "vfs-read-chunk-size", "", "Read the source objects in chunks")
 	flags.FVarP(flagSet, &Opt.ChunkSizeLimit, "vfs-read-chunk-size-limit", "", "If greater than --vfs-read-chunk-size, double the chunk size after each chunk read, until the limit is reached ('off' is unlimited)")
 	flags.FVarP(flagSet, DirPerms, "dir-perms", "", "Directory permissions")
@@ -36,9 +38,17 @@ func AddFlags(flagSet *pflag.FlagSet) {
 	flags.DurationVarP(flagSet, &Opt.WriteWait, "vfs-write-wait", "", Opt.WriteWait, "Time to wait for in-sequence write before giving error")
 	flags.DurationVarP(flagSet, &Opt.ReadWait, "vfs-read-wait", "", Opt.ReadWait, "Time to wait for in-sequence read before seeking")
 	flags.DurationVarP(flagSet, &Opt.WriteBack, "vfs-write-back", "", Opt.WriteBack, "Time to writeback files after last use when using cache")
+	flags.FVarP(flagSet, &Opt.WriteBackWindows, "vfs-write-back-windows", "", "Only writeback files in these local time windows, e.g. 01:00-06:00")
 	flags.FVarP(flagSet, &Opt.ReadAhead, "vfs-read-ahead", "", "Extra read ahead over --buffer-size when using cache-mode full")
 	flags.BoolVarP(flagSet, &Opt.UsedIsSize, "vfs-used-is-size", "", Opt.UsedIsSize, "Use the `rclone size` algorithm for Used size")
 	flags.BoolVarP(flagSet, &Opt.FastFingerprint, "vfs-fast-fingerprint", "", Opt.FastFingerprint, "Use fast (less accurate) fingerprints for change detection")
 	flags.FVarP(flagSet, &Opt.DiskSpaceTotalSize, "vfs-disk-space-total-size", "", "Specify the total space of disk")
+	flags.FVarP(flagSet, &Opt.ReadRetryPolicy, "vfs-read-retry-policy", "", "Policy for retrying failed reads default|hard")
+	flags.IntVarP(flagSet, &Opt.ReadRetries, "vfs-read-retries", "", Opt.ReadRetries, "Number of times to retry failed reads with --vfs-read-retry-policy hard")
+	flags.DurationVarP(flagSet, &Opt.ReadRetrySleep, "vfs-read-retry-sleep", "", Opt.ReadRetrySleep, "Time to sleep before retrying a failed read with --vfs-read-retry-policy hard, doubled for each retry")
+	flags.DurationVarP(flagSet, &Opt.ReadStallTimeout, "vfs-read-stall-timeout", "", Opt.ReadStallTimeout, "Reopen reads which make no progress for this long with --vfs-read-retry-policy hard (0 to disable)")
+	flags.BoolVarP(flagSet, &Opt.PersistDirCache, "vfs-persist-dir-cache", "", Opt.PersistDirCache, "Keep the directory cache on disk so it is still warm after a restart")
+	flags.IntVarP(flagSet, &Opt.PrefetchChunks, "vfs-prefetch-chunks", "", Opt.PrefetchChunks, "Number of chunks to prefetch in parallel for files read sequentially (0 to disable)")
+	flags.FVarP(flagSet, &Opt.PrefetchChunkSize, "vfs-prefetch-chunk-size", "", "Size of the chunks prefetched with --vfs-prefetch-chunks")
 	platformFlags(flagSet)
 }